@@ -67,6 +67,32 @@ func RegisterAdminHandler(name string, handler interface{}) {
 	_ = instance.rpcServer.RegisterName(name, handler) // @TODO fix ignored error
 }
 
+// RegisterHTTPHandler is used by other packages to expose admin endpoints, which rpc cannot serve (e.g. streams),
+// over the unix socket based http server
+func RegisterHTTPHandler(path string, handler http.Handler) {
+	instance.httpHandlers.mu.Lock()
+	instance.httpHandlers.handlers[path] = handler
+	instance.httpHandlers.mu.Unlock()
+}
+
+// httpHandlers routes requests to the handler registered for their path, the last one registered for a path
+// replacing the previous ones
+type httpHandlers struct {
+	mu       sync.RWMutex
+	handlers map[string]http.Handler
+}
+
+func (h *httpHandlers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	handler, ok := h.handlers[r.URL.Path]
+	h.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
 // RegisterStatusHandler expects object implementing PackageStatusHandler interface
 func RegisterStatusHandler(name string, handler PackageStatusHandler) {
 	instance.statusHandlersMutex.Lock()
@@ -78,6 +104,7 @@ type Admin struct {
 	statusHandlersMutex sync.RWMutex
 	statusHandlers      map[string]PackageStatusHandler
 	rpcServer           *rpc.Server
+	httpHandlers        *httpHandlers
 }
 
 var (
@@ -89,6 +116,7 @@ func Init() {
 	instance = &Admin{
 		statusHandlers: make(map[string]PackageStatusHandler),
 		rpcServer:      rpc.NewServer(),
+		httpHandlers:   &httpHandlers{handlers: make(map[string]http.Handler)},
 	}
 	_ = instance.rpcServer.Register(instance) // @TODO fix ignored error
 	pkgLogger = logger.NewLogger().Child("admin")
@@ -272,6 +300,7 @@ func StartServer(ctx context.Context) error {
 	pkgLogger.Info("Serving on admin interface @ ", sockAddr)
 	srvMux := http.NewServeMux()
 	srvMux.Handle(rpc.DefaultRPCPath, instance.rpcServer)
+	srvMux.Handle("/", instance.httpHandlers)
 
 	srv := &http.Server{Handler: srvMux, ReadHeaderTimeout: 3 * time.Second}
 
@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"runtime/pprof"
 	"strings"
+	"time"
 
 	"github.com/rudderlabs/rudder-server/config"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
@@ -25,6 +26,8 @@ const (
 	GATEWAY   = "GATEWAY"
 	PROCESSOR = "PROCESSOR"
 	EMBEDDED  = "EMBEDDED"
+	// ALL_IN_ONE runs the gateway, the processor, the routers and the warehouse master and slaves in a single process
+	ALL_IN_ONE = "ALL_IN_ONE"
 )
 
 // App represents a rudder-server application
@@ -156,11 +159,17 @@ func getHealthVal(jobsDB jobsdb.JobsDB) string {
 		backendConfigMode = "JSON"
 	}
 
+	// the config is stale while it's served from memory or from the cache, the control plane being unreachable
+	backendConfigStaleSince := ""
+	if since, stale := backendconfig.StaleSince(); stale {
+		backendConfigStaleSince = since.Format(time.RFC3339)
+	}
+
 	appTypeStr := strings.ToUpper(config.GetString("APP_TYPE", EMBEDDED))
 	return fmt.Sprintf(
 		`{"appType":"%s","server":"UP","db":"%s","acceptingEvents":"TRUE","routingEvents":"%s","mode":"%s",`+
-			`"backendConfigMode":"%s","lastSync":"%s","lastRegulationSync":"%s"}`,
+			`"backendConfigMode":"%s","lastSync":"%s","lastRegulationSync":"%s","backendConfigStaleSince":"%s"}`,
 		appTypeStr, dbService, enabledRouter, strings.ToUpper(db.CurrentMode),
-		backendConfigMode, backendconfig.LastSync, backendconfig.LastRegulationSync,
+		backendConfigMode, backendconfig.LastSync, backendconfig.LastRegulationSync, backendConfigStaleSince,
 	)
 }
@@ -41,6 +41,12 @@ func TestAppHandlerStartSequence(t *testing.T) {
 		suite(t, h)
 	})
 
+	t.Run("all-in-one", func(t *testing.T) {
+		h, err := GetAppHandler(application, app.ALL_IN_ONE, versionHandler)
+		require.NoError(t, err)
+		suite(t, h)
+	})
+
 	t.Run("gateway", func(t *testing.T) {
 		h, err := GetAppHandler(application, app.GATEWAY, versionHandler)
 		require.NoError(t, err)
@@ -30,11 +30,13 @@ import (
 	transformationdebugger "github.com/rudderlabs/rudder-server/services/debugger/transformation"
 	"github.com/rudderlabs/rudder-server/services/fileuploader"
 	"github.com/rudderlabs/rudder-server/services/multitenant"
+	"github.com/rudderlabs/rudder-server/services/oauth"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/services/transientsource"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/types"
 	"github.com/rudderlabs/rudder-server/utils/types/servermode"
+	tenantmanager "github.com/rudderlabs/rudder-server/warehouse/multitenant"
 )
 
 // embeddedApp is the type for embedded type implementation
@@ -52,6 +54,11 @@ type embeddedApp struct {
 		batchRouterDSLimit int
 		gatewayDSLimit     int
 	}
+	appType string
+	// stopGatewayFirst stops the processor and the routers only once the web handler of the gateway has stopped on
+	// shutdown, after completing the requests in flight. The jobs the gateway stored and the processor didn't process
+	// yet are left in the gateway db, for the next start to process them.
+	stopGatewayFirst bool
 }
 
 func (a *embeddedApp) loadConfiguration() {
@@ -67,7 +74,7 @@ func (a *embeddedApp) loadConfiguration() {
 func (a *embeddedApp) Setup(options *app.Options) error {
 	a.loadConfiguration()
 
-	if err := db.HandleEmbeddedRecovery(options.NormalMode, options.DegradedMode, misc.AppStartTime, app.EMBEDDED); err != nil {
+	if err := db.HandleEmbeddedRecovery(options.NormalMode, options.DegradedMode, misc.AppStartTime, a.appType); err != nil {
 		return err
 	}
 
@@ -90,7 +97,8 @@ func (a *embeddedApp) StartRudderCore(ctx context.Context, options *app.Options)
 	}
 	a.log.Info("Embedded mode: Starting Rudder Core")
 
-	readonlyGatewayDB, err := setupReadonlyDBs()
+	fileUploaderProvider := fileuploader.NewProvider(ctx, backendconfig.DefaultBackendConfig)
+	readonlyGatewayDB, err := setupReadonlyDBs(fileUploaderProvider)
 	if err != nil {
 		return err
 	}
@@ -122,8 +130,6 @@ func (a *embeddedApp) StartRudderCore(ctx context.Context, options *app.Options)
 		prebackup.DropSourceIds(transientSources.SourceIdsSupplier()),
 	}
 
-	fileUploaderProvider := fileuploader.NewProvider(ctx, backendconfig.DefaultBackendConfig)
-
 	rsourcesService, err := NewRsourcesService(deploymentType)
 	if err != nil {
 		return err
@@ -167,14 +173,21 @@ func (a *embeddedApp) StartRudderCore(ctx context.Context, options *app.Options)
 		jobsdb.WithFileUploaderProvider(fileUploaderProvider),
 	)
 
+	tenantManager := &tenantmanager.Manager{BackendConfig: backendconfig.DefaultBackendConfig}
+	g.Go(func() error {
+		tenantManager.Run(ctx)
+		return nil
+	})
 	var tenantRouterDB jobsdb.MultiTenantJobsDB
 	var multitenantStats multitenant.MultiTenantI
 	if misc.UseFairPickup() {
 		tenantRouterDB = &jobsdb.MultiTenantHandleT{HandleT: routerDB}
-		multitenantStats = multitenant.NewStats(map[string]jobsdb.MultiTenantJobsDB{
+		fairPickupStats := multitenant.NewStats(map[string]jobsdb.MultiTenantJobsDB{
 			"rt":       tenantRouterDB,
 			"batch_rt": &jobsdb.MultiTenantLegacy{HandleT: batchRouterDB},
 		})
+		fairPickupStats.Quotas = tenantManager
+		multitenantStats = fairPickupStats
 	} else {
 		tenantRouterDB = &jobsdb.MultiTenantLegacy{HandleT: routerDB}
 		multitenantStats = multitenant.WithLegacyPickupJobs(multitenant.NewStats(map[string]jobsdb.MultiTenantJobsDB{
@@ -202,10 +215,21 @@ func (a *embeddedApp) StartRudderCore(ctx context.Context, options *app.Options)
 	}
 
 	proc := processor.New(ctx, &options.ClearDB, gwDBForProcessor, routerDB, batchRouterDB, errDB, multitenantStats, reportingI, transientSources, fileUploaderProvider, rsourcesService)
+	proc.WorkspaceQuotas = tenantManager
 	throttlerFactory, err := throttler.New(stats.Default)
 	if err != nil {
 		return fmt.Errorf("failed to create throttler factory: %w", err)
 	}
+	deliveryReceipts, err := NewDeliveryReceipts(ctx, a.log)
+	if err != nil {
+		return fmt.Errorf("failed to create delivery receipts store: %w", err)
+	}
+	if deliveryReceipts != nil {
+		g.Go(func() error {
+			deliveryReceipts.CleanupLoop(ctx)
+			return nil
+		})
+	}
 	rtFactory := &router.Factory{
 		Reporting:        reportingI,
 		Multitenant:      multitenantStats,
@@ -215,6 +239,8 @@ func (a *embeddedApp) StartRudderCore(ctx context.Context, options *app.Options)
 		TransientSources: transientSources,
 		RsourcesService:  rsourcesService,
 		ThrottlerFactory: throttlerFactory,
+		OAuth:            oauth.NewTokenService(oauth.NewOAuthErrorHandler(backendconfig.DefaultBackendConfig)),
+		DeliveryReceipts: deliveryReceipts,
 	}
 	brtFactory := &batchrouter.Factory{
 		Reporting:        reportingI,
@@ -228,15 +254,17 @@ func (a *embeddedApp) StartRudderCore(ctx context.Context, options *app.Options)
 	rt := routerManager.New(rtFactory, brtFactory, backendconfig.DefaultBackendConfig)
 
 	dm := cluster.Dynamic{
-		Provider:        modeProvider,
-		GatewayDB:       gwDBForProcessor,
-		RouterDB:        routerDB,
-		BatchRouterDB:   batchRouterDB,
-		ErrorDB:         errDB,
-		Processor:       proc,
-		Router:          rt,
-		MultiTenantStat: multitenantStats,
+		Provider:          modeProvider,
+		GatewayDB:         gwDBForProcessor,
+		RouterDB:          routerDB,
+		BatchRouterDB:     batchRouterDB,
+		ErrorDB:           errDB,
+		Processor:         proc,
+		Router:            rt,
+		MultiTenantStat:   multitenantStats,
+		WorkspaceDrainers: []cluster.WorkspaceDrainer{rt},
 	}
+	withWarehouse(&dm)
 
 	rateLimiter := ratelimiter.HandleT{}
 	rateLimiter.SetUp()
@@ -256,6 +284,7 @@ func (a *embeddedApp) StartRudderCore(ctx context.Context, options *app.Options)
 	defer gatewayDB.Stop()
 
 	gw.SetReadonlyDB(readonlyGatewayDB)
+	gw.SetWorkspaceQuotas(tenantManager)
 	err = gw.Setup(
 		ctx,
 		a.app, backendconfig.DefaultBackendConfig, gatewayDB,
@@ -273,7 +302,9 @@ func (a *embeddedApp) StartRudderCore(ctx context.Context, options *app.Options)
 	g.Go(func() error {
 		return gw.StartAdminHandler(ctx)
 	})
+	gatewayStopped := make(chan struct{})
 	g.Go(func() error {
+		defer close(gatewayStopped)
 		return gw.StartWebHandler(ctx)
 	})
 	if a.config.enableReplay {
@@ -289,11 +320,23 @@ func (a *embeddedApp) StartRudderCore(ctx context.Context, options *app.Options)
 		a.app.Features().Replay.Setup(ctx, &replayDB, gatewayDB, routerDB, batchRouterDB)
 	}
 
+	processingCtx := ctx
+	if a.stopGatewayFirst {
+		var cancelProcessing context.CancelFunc
+		processingCtx, cancelProcessing = context.WithCancel(misc.WithoutCancel(ctx))
+		defer cancelProcessing()
+		g.Go(func() error {
+			<-gatewayStopped
+			a.log.Info("Gateway stopped, stopping processor and routers")
+			cancelProcessing()
+			return nil
+		})
+	}
 	g.Go(func() error {
 		// This should happen only after setupDatabaseTables() is called and journal table migrations are done
 		// because if this start before that then there might be a case when ReadDB will try to read the owner table
 		// which gets created after either Write or ReadWrite DB is created.
-		return dm.Run(ctx)
+		return dm.Run(processingCtx)
 	})
 
 	g.Go(func() error {
@@ -22,6 +22,7 @@ import (
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/types/deployment"
 	"github.com/rudderlabs/rudder-server/utils/types/servermode"
+	tenantmanager "github.com/rudderlabs/rudder-server/warehouse/multitenant"
 )
 
 // gatewayApp is the type for Gateway type implementation
@@ -64,7 +65,8 @@ func (a *gatewayApp) StartRudderCore(ctx context.Context, options *app.Options)
 	}
 	a.log.Info("Gateway starting")
 
-	readonlyGatewayDB, err := setupReadonlyDBs()
+	fileUploaderProvider := fileuploader.NewProvider(ctx, backendconfig.DefaultBackendConfig)
+	readonlyGatewayDB, err := setupReadonlyDBs(fileUploaderProvider)
 	if err != nil {
 		return err
 	}
@@ -79,8 +81,6 @@ func (a *gatewayApp) StartRudderCore(ctx context.Context, options *app.Options)
 
 	sourcedebugger.Setup(backendconfig.DefaultBackendConfig)
 
-	fileUploaderProvider := fileuploader.NewProvider(ctx, backendconfig.DefaultBackendConfig)
-
 	gatewayDB := jobsdb.NewForWrite(
 		"gw",
 		jobsdb.WithClearDB(options.ClearDB),
@@ -126,6 +126,12 @@ func (a *gatewayApp) StartRudderCore(ctx context.Context, options *app.Options)
 
 	rateLimiter.SetUp()
 	gw.SetReadonlyDB(readonlyGatewayDB)
+	tenantManager := &tenantmanager.Manager{BackendConfig: backendconfig.DefaultBackendConfig}
+	g.Go(func() error {
+		tenantManager.Run(ctx)
+		return nil
+	})
+	gw.SetWorkspaceQuotas(tenantManager)
 	rsourcesService, err := NewRsourcesService(deploymentType)
 	if err != nil {
 		return err
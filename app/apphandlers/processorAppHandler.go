@@ -34,10 +34,12 @@ import (
 	transformationdebugger "github.com/rudderlabs/rudder-server/services/debugger/transformation"
 	"github.com/rudderlabs/rudder-server/services/fileuploader"
 	"github.com/rudderlabs/rudder-server/services/multitenant"
+	"github.com/rudderlabs/rudder-server/services/oauth"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/services/transientsource"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/types"
+	tenantmanager "github.com/rudderlabs/rudder-server/warehouse/multitenant"
 )
 
 // processorApp is the type for Processor type implemention
@@ -104,7 +106,8 @@ func (a *processorApp) StartRudderCore(ctx context.Context, options *app.Options
 	}
 	a.log.Info("Processor starting")
 
-	if _, err := setupReadonlyDBs(); err != nil {
+	fileUploaderProvider := fileuploader.NewProvider(ctx, backendconfig.DefaultBackendConfig)
+	if _, err := setupReadonlyDBs(fileUploaderProvider); err != nil {
 		return err
 	}
 	g, ctx := errgroup.WithContext(ctx)
@@ -133,8 +136,6 @@ func (a *processorApp) StartRudderCore(ctx context.Context, options *app.Options
 		prebackup.DropSourceIds(transientSources.SourceIdsSupplier()),
 	}
 
-	fileUploaderProvider := fileuploader.NewProvider(ctx, backendconfig.DefaultBackendConfig)
-
 	rsourcesService, err := NewRsourcesService(deploymentType)
 	if err != nil {
 		return err
@@ -175,14 +176,21 @@ func (a *processorApp) StartRudderCore(ctx context.Context, options *app.Options
 		jobsdb.WithDSLimit(&a.config.processorDSLimit),
 		jobsdb.WithFileUploaderProvider(fileUploaderProvider),
 	)
+	tenantManager := &tenantmanager.Manager{BackendConfig: backendconfig.DefaultBackendConfig}
+	g.Go(func() error {
+		tenantManager.Run(ctx)
+		return nil
+	})
 	var tenantRouterDB jobsdb.MultiTenantJobsDB
 	var multitenantStats multitenant.MultiTenantI
 	if misc.UseFairPickup() {
 		tenantRouterDB = &jobsdb.MultiTenantHandleT{HandleT: routerDB}
-		multitenantStats = multitenant.NewStats(map[string]jobsdb.MultiTenantJobsDB{
+		fairPickupStats := multitenant.NewStats(map[string]jobsdb.MultiTenantJobsDB{
 			"rt":       tenantRouterDB,
 			"batch_rt": &jobsdb.MultiTenantLegacy{HandleT: batchRouterDB},
 		})
+		fairPickupStats.Quotas = tenantManager
+		multitenantStats = fairPickupStats
 	} else {
 		tenantRouterDB = &jobsdb.MultiTenantLegacy{HandleT: routerDB}
 		multitenantStats = multitenant.WithLegacyPickupJobs(multitenant.NewStats(map[string]jobsdb.MultiTenantJobsDB{
@@ -210,10 +218,21 @@ func (a *processorApp) StartRudderCore(ctx context.Context, options *app.Options
 	}
 
 	p := proc.New(ctx, &options.ClearDB, gwDBForProcessor, routerDB, batchRouterDB, errDB, multitenantStats, reportingI, transientSources, fileUploaderProvider, rsourcesService)
+	p.WorkspaceQuotas = tenantManager
 	throttlerFactory, err := throttler.New(stats.Default)
 	if err != nil {
 		return fmt.Errorf("failed to create throttler factory: %w", err)
 	}
+	deliveryReceipts, err := NewDeliveryReceipts(ctx, a.log)
+	if err != nil {
+		return fmt.Errorf("failed to create delivery receipts store: %w", err)
+	}
+	if deliveryReceipts != nil {
+		g.Go(func() error {
+			deliveryReceipts.CleanupLoop(ctx)
+			return nil
+		})
+	}
 	rtFactory := &router.Factory{
 		Reporting:        reportingI,
 		Multitenant:      multitenantStats,
@@ -223,6 +242,8 @@ func (a *processorApp) StartRudderCore(ctx context.Context, options *app.Options
 		TransientSources: transientSources,
 		RsourcesService:  rsourcesService,
 		ThrottlerFactory: throttlerFactory,
+		OAuth:            oauth.NewTokenService(oauth.NewOAuthErrorHandler(backendconfig.DefaultBackendConfig)),
+		DeliveryReceipts: deliveryReceipts,
 	}
 	brtFactory := &batchrouter.Factory{
 		Reporting:        reportingI,
@@ -236,16 +257,18 @@ func (a *processorApp) StartRudderCore(ctx context.Context, options *app.Options
 	rt := routerManager.New(rtFactory, brtFactory, backendconfig.DefaultBackendConfig)
 
 	dm := cluster.Dynamic{
-		Provider:         modeProvider,
-		GatewayComponent: false,
-		GatewayDB:        gwDBForProcessor,
-		RouterDB:         routerDB,
-		BatchRouterDB:    batchRouterDB,
-		ErrorDB:          errDB,
-		Processor:        p,
-		Router:           rt,
-		MultiTenantStat:  multitenantStats,
+		Provider:          modeProvider,
+		GatewayComponent:  false,
+		GatewayDB:         gwDBForProcessor,
+		RouterDB:          routerDB,
+		BatchRouterDB:     batchRouterDB,
+		ErrorDB:           errDB,
+		Processor:         p,
+		Router:            rt,
+		MultiTenantStat:   multitenantStats,
+		WorkspaceDrainers: []cluster.WorkspaceDrainer{rt},
 	}
+	withWarehouse(&dm)
 
 	g.Go(func() error {
 		return a.startHealthWebHandler(ctx, gwDBForProcessor)
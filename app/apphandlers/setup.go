@@ -2,21 +2,27 @@ package apphandlers
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/rudderlabs/rudder-server/admin"
 	"github.com/rudderlabs/rudder-server/app"
+	"github.com/rudderlabs/rudder-server/app/cluster"
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/jobsdb"
 	"github.com/rudderlabs/rudder-server/processor"
 	"github.com/rudderlabs/rudder-server/router"
+	"github.com/rudderlabs/rudder-server/services/deliveryreceipts"
 	"github.com/rudderlabs/rudder-server/services/diagnostics"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
 	"github.com/rudderlabs/rudder-server/services/rsources"
 	"github.com/rudderlabs/rudder-server/services/validators"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/types/deployment"
+	"github.com/rudderlabs/rudder-server/warehouse"
 )
 
 // AppHandler starts the app
@@ -35,7 +41,10 @@ func GetAppHandler(application app.App, appType string, versionHandler func(w ht
 	case app.PROCESSOR:
 		return &processorApp{app: application, versionHandler: versionHandler, log: log}, nil
 	case app.EMBEDDED:
-		return &embeddedApp{app: application, versionHandler: versionHandler, log: log}, nil
+		return &embeddedApp{app: application, appType: app.EMBEDDED, versionHandler: versionHandler, log: log}, nil
+	case app.ALL_IN_ONE:
+		// the warehouse runs alongside, started by the runner once rudder core is, and stopped once it is
+		return &embeddedApp{app: application, appType: app.ALL_IN_ONE, stopGatewayFirst: true, versionHandler: versionHandler, log: log}, nil
 	default:
 		return nil, fmt.Errorf("unsupported app type %s", appType)
 	}
@@ -53,13 +62,17 @@ func rudderCoreWorkSpaceTableSetup() error {
 	return validators.CheckAndValidateWorkspaceToken()
 }
 
-func setupReadonlyDBs() (gw *jobsdb.ReadonlyHandleT, err error) {
+func setupReadonlyDBs(fileUploaderProvider fileuploader.Provider) (gw *jobsdb.ReadonlyHandleT, err error) {
 	if diagnostics.EnableServerStartMetric {
 		diagnostics.Diagnostics.Track(diagnostics.ServerStart, map[string]interface{}{
 			diagnostics.ServerStart: fmt.Sprint(time.Unix(misc.AppStartTime, 0)),
 		})
 	}
-	var gwDB, rtDB, batchrtDB, procerrDB jobsdb.ReadonlyHandleT
+	// jobs of datasets spilled to object storage are looked up through the file uploader provider
+	gwDB := jobsdb.ReadonlyHandleT{FileUploaderProvider: fileUploaderProvider}
+	rtDB := jobsdb.ReadonlyHandleT{FileUploaderProvider: fileUploaderProvider}
+	batchrtDB := jobsdb.ReadonlyHandleT{FileUploaderProvider: fileUploaderProvider}
+	procerrDB := jobsdb.ReadonlyHandleT{FileUploaderProvider: fileUploaderProvider}
 
 	if err := gwDB.Setup("gw"); err != nil {
 		return nil, fmt.Errorf("setting up gw readonly db: %w", err)
@@ -82,6 +95,22 @@ func setupReadonlyDBs() (gw *jobsdb.ReadonlyHandleT, err error) {
 	return
 }
 
+// withWarehouse has the cluster drain the warehouse running alongside rudder core of the workspaces detached, and
+// switch its mode if dynamic mode is enabled for it
+func withWarehouse(dm *cluster.Dynamic) {
+	warehouseMode := config.GetString("Warehouse.mode", config.EmbeddedMode)
+	if warehouseMode == config.OffMode {
+		return
+	}
+	dm.WorkspaceDrainers = append(dm.WorkspaceDrainers, warehouse.WorkspaceDrainer{})
+	if !warehouse.DynamicModeEnabled() {
+		return
+	}
+	dm.WarehouseMaster = warehouse.Master()
+	dm.WarehouseSlave = warehouse.Slave()
+	dm.WarehouseMode = warehouseMode
+}
+
 // NewRsourcesService produces a rsources.JobService through environment configuration (env variables & config file)
 func NewRsourcesService(deploymentType deployment.Type) (rsources.JobService, error) {
 	var rsourcesConfig rsources.JobServiceConfig
@@ -101,3 +130,20 @@ func NewRsourcesService(deploymentType deployment.Type) (rsources.JobService, er
 
 	return rsources.NewJobService(rsourcesConfig)
 }
+
+// NewDeliveryReceipts returns the store of the delivery receipts of the router, or nil if they are disabled
+func NewDeliveryReceipts(ctx context.Context, log logger.Logger) (*deliveryreceipts.Store, error) {
+	if !config.GetBool("Router.deliveryReceipts.enabled", false) {
+		return nil, nil
+	}
+	db, err := sql.Open("postgres", misc.GetConnectionString())
+	if err != nil {
+		return nil, fmt.Errorf("opening the delivery receipts database: %w", err)
+	}
+	store, err := deliveryreceipts.New(ctx, db, log.Child("deliveryreceipts"))
+	if err != nil {
+		return nil, err
+	}
+	admin.RegisterAdminHandler("DeliveryReceipts", &deliveryreceipts.DeliveryReceiptsRPCHandler{Store: store})
+	return store, nil
+}
@@ -7,6 +7,9 @@ import (
 	"sync"
 	"time"
 
+	"golang.org/x/sync/errgroup"
+
+	"github.com/rudderlabs/rudder-server/config"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/logger"
@@ -37,6 +40,15 @@ type configLifecycle interface {
 	WaitForConfig(ctx context.Context)
 }
 
+// WorkspaceDrainer finishes or checkpoints the in-flight work of workspaces before they're detached from the server
+type WorkspaceDrainer interface {
+	// Drain stops picking up work of the workspaces and waits for their in-flight work to finish, until ctx is done,
+	// returning the work they left pending
+	Drain(ctx context.Context, workspaceIDs []string) ([]workspace.PendingMarker, error)
+	// Resume picks up work of the workspaces again, if they were drained
+	Resume(workspaceIDs []string)
+}
+
 type Dynamic struct {
 	Provider ChangeEventProvider
 
@@ -52,15 +64,32 @@ type Dynamic struct {
 
 	MultiTenantStat lifecycle
 
-	currentMode         servermode.Mode
-	currentWorkspaceIDs string
+	// WorkspaceDrainers are drained of the workspaces detached from the server, for at most
+	// Cluster.workspaceDrainTimeout, before the workspaces are handed over
+	WorkspaceDrainers []WorkspaceDrainer
+
+	// WarehouseMaster and WarehouseSlave, if set, are started and stopped as the mode change events switch the warehouse
+	// from WarehouseMode, the mode it started in, to another one, e.g. promoting a slave to master
+	WarehouseMaster lifecycle
+	WarehouseSlave  lifecycle
+	WarehouseMode   string
+	// WarehouseComponent is set for nodes running the warehouse only, the server mode of the events being ignored
+	WarehouseComponent bool
+
+	currentMode          servermode.Mode
+	currentWorkspaceIDs  string
+	currentWarehouseMode string
 
 	serverStartTimeStat  stats.Measurement
 	serverStopTimeStat   stats.Measurement
 	serverStartCountStat stats.Measurement
 	serverStopCountStat  stats.Measurement
+	workspaceDrainStat   stats.Measurement
+	warehouseSwitchStat  stats.Measurement
 	BackendConfig        configLifecycle
 
+	workspaceDrainTimeout time.Duration
+
 	logger logger.Logger
 
 	once sync.Once
@@ -68,6 +97,7 @@ type Dynamic struct {
 
 func (d *Dynamic) init() {
 	d.currentMode = servermode.DegradedMode
+	d.currentWarehouseMode = d.WarehouseMode
 	d.logger = logger.NewLogger().Child("cluster")
 	tag := stats.Tags{
 		"controlled_by":   controller,
@@ -77,6 +107,9 @@ func (d *Dynamic) init() {
 	d.serverStopTimeStat = stats.Default.NewTaggedStat("cluster.server_stop_time", stats.TimerType, tag)
 	d.serverStartCountStat = stats.Default.NewTaggedStat("cluster.server_start_count", stats.CountType, tag)
 	d.serverStopCountStat = stats.Default.NewTaggedStat("cluster.server_stop_count", stats.CountType, tag)
+	d.workspaceDrainStat = stats.Default.NewTaggedStat("cluster.workspace_drain_time", stats.TimerType, tag)
+	d.warehouseSwitchStat = stats.Default.NewTaggedStat("cluster.warehouse_mode_switch_count", stats.CountType, tag)
+	config.RegisterDurationConfigVariable(60, &d.workspaceDrainTimeout, true, time.Second, "Cluster.workspaceDrainTimeout")
 
 	if d.BackendConfig == nil {
 		d.BackendConfig = backendconfig.DefaultBackendConfig
@@ -108,18 +141,24 @@ func (d *Dynamic) Run(ctx context.Context) error {
 			}
 
 			d.logger.Infof("Got trigger to change the mode, new mode: %s, old mode: %s", req.Mode(), d.currentMode)
+			if err := d.handleWarehouseModeChange(req.WarehouseMode()); err != nil {
+				d.logger.Error(err)
+				return err
+			}
 			if d.GatewayComponent {
 				d.logger.Infof("Gateway component, not changing the mode")
 				continue
 			}
-			err := d.handleModeChange(req.Mode())
-			if err != nil {
-				d.logger.Error(err)
-				return err
+			if !d.WarehouseComponent {
+				err := d.handleModeChange(req.Mode())
+				if err != nil {
+					d.logger.Error(err)
+					return err
+				}
 			}
 			d.logger.Debugf("Acknowledging the mode change")
 
-			if err := req.Ack(ctx); err != nil {
+			if err := req.AckWarehouseMode(ctx, d.appliedWarehouseMode()); err != nil {
 				return fmt.Errorf("ack mode change: %w", err)
 			}
 		case req := <-workspaceIDsChan:
@@ -129,7 +168,10 @@ func (d *Dynamic) Run(ctx context.Context) error {
 			ids := strings.Join(req.WorkspaceIDs(), ",")
 
 			d.logger.Infof("Got trigger to change workspaceIDs: %q", ids)
-			err := d.handleWorkspaceChange(ctx, ids)
+			err := d.handoverWorkspaces(ctx, req)
+			if err == nil {
+				err = d.handleWorkspaceChange(ctx, ids)
+			}
 			if ackErr := req.Ack(ctx, err); ackErr != nil {
 				return fmt.Errorf("ack workspaceIDs change with error: %v: %w", err, ackErr)
 			}
@@ -201,6 +243,65 @@ func (d *Dynamic) stop() {
 	d.serverStopCountStat.Increment()
 }
 
+// handoverWorkspaces drains the workspaces detached from the server by the request, handing over the work they left
+// pending, and resumes the ones it attaches back
+func (d *Dynamic) handoverWorkspaces(ctx context.Context, req workspace.ChangeEvent) error {
+	attached := make(map[string]struct{})
+	for _, workspaceID := range req.WorkspaceIDs() {
+		attached[workspaceID] = struct{}{}
+	}
+	var detached []string
+	for _, workspaceID := range strings.Split(d.currentWorkspaceIDs, ",") {
+		if _, ok := attached[workspaceID]; workspaceID != "" && !ok {
+			detached = append(detached, workspaceID)
+		}
+	}
+	d.resumeWorkspaces(req.WorkspaceIDs())
+	if len(detached) == 0 {
+		return nil
+	}
+
+	d.logger.Infof("Draining workspaces %q before detaching them", detached)
+	start := time.Now()
+	drainCtx, cancel := context.WithTimeout(ctx, d.workspaceDrainTimeout)
+	defer cancel()
+	var (
+		pendingMu sync.Mutex
+		pending   []workspace.PendingMarker
+	)
+	g, drainCtx := errgroup.WithContext(drainCtx)
+	for _, drainer := range d.WorkspaceDrainers {
+		drainer := drainer
+		g.Go(func() error {
+			markers, err := drainer.Drain(drainCtx, detached)
+			pendingMu.Lock()
+			pending = append(pending, markers...)
+			pendingMu.Unlock()
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		d.resumeWorkspaces(detached)
+		return fmt.Errorf("draining workspaces: %w", err)
+	}
+	d.workspaceDrainStat.Since(start)
+	d.logger.Infof("Drained workspaces %q, handing them over with %d pending markers", detached, len(pending))
+
+	if err := req.Handover(ctx, pending); err != nil {
+		// the workspaces stay attached to the server, their work being picked up again
+		d.resumeWorkspaces(detached)
+		return fmt.Errorf("handing over workspaces: %w", err)
+	}
+	return nil
+}
+
+// resumeWorkspaces resumes the workspaces in all drainers
+func (d *Dynamic) resumeWorkspaces(workspaceIDs []string) {
+	for _, drainer := range d.WorkspaceDrainers {
+		drainer.Resume(workspaceIDs)
+	}
+}
+
 func (d *Dynamic) handleWorkspaceChange(ctx context.Context, workspaces string) error {
 	d.BackendConfig.Stop()
 	d.BackendConfig.StartWithIDs(ctx, workspaces)
@@ -250,6 +351,67 @@ func (d *Dynamic) handleModeChange(newMode servermode.Mode) error {
 	return nil
 }
 
+// warehouseComponents returns whether the master and the slaves of the warehouse run in the mode
+func warehouseComponents(mode string) (master, slave bool, ok bool) {
+	switch mode {
+	case config.MasterMode, config.EmbeddedMasterMode:
+		return true, false, true
+	case config.SlaveMode:
+		return false, true, true
+	case config.MasterSlaveMode, config.EmbeddedMode:
+		return true, true, true
+	case config.OffMode:
+		return false, false, true
+	default:
+		return false, false, false
+	}
+}
+
+// handleWarehouseModeChange switches the warehouse to the mode, if any, stopping the components not running in it
+// before starting the ones running in it, for a master demoted to slave not to run both for a while
+func (d *Dynamic) handleWarehouseModeChange(newMode string) error {
+	if newMode == "" || newMode == d.currentWarehouseMode {
+		return nil
+	}
+	if d.WarehouseMaster == nil || d.WarehouseSlave == nil {
+		d.logger.Infof("Not switching the warehouse to %s mode because it is not running on this server", newMode)
+		return nil
+	}
+	master, slave, ok := warehouseComponents(newMode)
+	if !ok {
+		return fmt.Errorf("unsupported warehouse mode: %s", newMode)
+	}
+
+	d.logger.Infof("Switching the warehouse from %s to %s mode", d.currentWarehouseMode, newMode)
+	if !master {
+		d.WarehouseMaster.Stop()
+	}
+	if !slave {
+		d.WarehouseSlave.Stop()
+	}
+	if master {
+		if err := d.WarehouseMaster.Start(); err != nil {
+			return fmt.Errorf("warehouse master start: %w", err)
+		}
+	}
+	if slave {
+		if err := d.WarehouseSlave.Start(); err != nil {
+			return fmt.Errorf("warehouse slave start: %w", err)
+		}
+	}
+	d.currentWarehouseMode = newMode
+	d.warehouseSwitchStat.Increment()
+	return nil
+}
+
+// appliedWarehouseMode returns the mode the warehouse is in, empty if its mode isn't switched on this server
+func (d *Dynamic) appliedWarehouseMode() string {
+	if d.WarehouseMaster == nil || d.WarehouseSlave == nil {
+		return ""
+	}
+	return d.currentWarehouseMode
+}
+
 func (d *Dynamic) Mode() servermode.Mode {
 	return d.currentMode
 }
@@ -2,6 +2,7 @@ package cluster_test
 
 import (
 	"context"
+	"errors"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -56,6 +57,20 @@ func (m *mockLifecycle) Stop() {
 	m.status = "stop"
 }
 
+type mockDrainer struct {
+	drained []string
+	resumed []string
+}
+
+func (m *mockDrainer) Drain(_ context.Context, workspaceIDs []string) ([]workspace.PendingMarker, error) {
+	m.drained = append(m.drained, workspaceIDs...)
+	return []workspace.PendingMarker{{WorkspaceID: workspaceIDs[0], Component: "router", Ref: "GA", State: "waiting", Count: 1}}, nil
+}
+
+func (m *mockDrainer) Resume(workspaceIDs []string) {
+	m.resumed = workspaceIDs
+}
+
 func Init() {
 	config.Reset()
 	logger.Reset()
@@ -85,6 +100,7 @@ func TestDynamicCluster(t *testing.T) {
 
 	ctrl := gomock.NewController(t)
 	backendConfig := NewMockconfigLifecycle(ctrl)
+	drainer := &mockDrainer{}
 	dc := cluster.Dynamic{
 		Provider: provider,
 
@@ -96,8 +112,9 @@ func TestDynamicCluster(t *testing.T) {
 		Processor: processor,
 		Router:    router,
 
-		MultiTenantStat: mtStat,
-		BackendConfig:   backendConfig,
+		MultiTenantStat:   mtStat,
+		BackendConfig:     backendConfig,
+		WorkspaceDrainers: []cluster.WorkspaceDrainer{drainer},
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -197,6 +214,36 @@ func TestDynamicCluster(t *testing.T) {
 		}
 	})
 
+	t.Run("Detached workspaces are drained and handed over", func(t *testing.T) {
+		chACK := make(chan struct{})
+		backendConfig.EXPECT().Stop().Times(1)
+		backendConfig.EXPECT().WaitForConfig(gomock.Any()).Times(1)
+		backendConfig.EXPECT().StartWithIDs(gomock.Any(), "a,d").Times(1)
+
+		var handedOver []workspace.PendingMarker
+		provider.sendWorkspaceIDs(
+			workspace.NewWorkspacesRequest([]string{"a", "d"},
+				func(_ context.Context, err error) error {
+					close(chACK)
+					require.NoError(t, err)
+					return nil
+				},
+			).WithHandover(func(_ context.Context, pending []workspace.PendingMarker) error {
+				handedOver = pending
+				return nil
+			}),
+		)
+
+		select {
+		case <-chACK:
+		case <-time.After(time.Second):
+			t.Fatal("Did not get acknowledgement within 1 second")
+		}
+		require.Equal(t, []string{"b", "c"}, drainer.drained)
+		require.Equal(t, []string{"a", "d"}, drainer.resumed)
+		require.Equal(t, []workspace.PendingMarker{{WorkspaceID: "b", Component: "router", Ref: "GA", State: "waiting", Count: 1}}, handedOver)
+	})
+
 	t.Run("Empty workspaces triggers a reload", func(t *testing.T) {
 		chACK := make(chan struct{})
 		backendConfig.EXPECT().Stop().Times(1)
@@ -244,3 +291,164 @@ func TestDynamicCluster(t *testing.T) {
 		require.True(t, errorDB.callOrder > router.callOrder)
 	})
 }
+
+func TestDynamicCluster_WarehouseMode(t *testing.T) {
+	Init()
+
+	provider := &mockModeProvider{
+		modeCh:      make(chan servermode.ChangeEvent),
+		workspaceCh: make(chan workspace.ChangeEvent),
+	}
+
+	callCount := uint64(0)
+	master := &mockLifecycle{status: "", callCount: &callCount}
+	slave := &mockLifecycle{status: "", callCount: &callCount}
+
+	dc := cluster.Dynamic{
+		Provider: provider,
+
+		WarehouseComponent: true,
+		WarehouseMaster:    master,
+		WarehouseSlave:     slave,
+		WarehouseMode:      config.SlaveMode,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- dc.Run(ctx)
+	}()
+
+	switchMode := func(t *testing.T, warehouseMode string) {
+		t.Helper()
+
+		chACK := make(chan string, 1)
+		provider.sendMode(servermode.NewChangeEvent(servermode.NormalMode, func(_ context.Context) error {
+			t.Error("the warehouse mode should be acknowledged")
+			return nil
+		}).WithWarehouseMode(warehouseMode).WithWarehouseAck(func(_ context.Context, appliedMode string) error {
+			chACK <- appliedMode
+			return nil
+		}))
+
+		select {
+		case appliedMode := <-chACK:
+			if warehouseMode != "" {
+				require.Equal(t, warehouseMode, appliedMode, "the warehouse mode applied is acknowledged")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Did not get acknowledgement within 1 second")
+		}
+	}
+
+	t.Run("server mode is ignored", func(t *testing.T) {
+		switchMode(t, "")
+
+		require.Equal(t, "", master.status)
+		require.Equal(t, "", slave.status)
+	})
+
+	t.Run("slave -> master", func(t *testing.T) {
+		switchMode(t, config.MasterMode)
+
+		require.Equal(t, "start", master.status)
+		require.Equal(t, "stop", slave.status)
+
+		t.Log("slave should be stopped before master is started")
+		require.True(t, slave.callOrder < master.callOrder)
+	})
+
+	t.Run("master -> master_and_slave", func(t *testing.T) {
+		switchMode(t, config.MasterSlaveMode)
+
+		require.Equal(t, "start", master.status)
+		require.Equal(t, "start", slave.status)
+	})
+
+	t.Run("master_and_slave -> off", func(t *testing.T) {
+		switchMode(t, config.OffMode)
+
+		require.Equal(t, "stop", master.status)
+		require.Equal(t, "stop", slave.status)
+	})
+
+	t.Run("unsupported mode", func(t *testing.T) {
+		provider.sendMode(servermode.NewChangeEvent(servermode.NormalMode, func(_ context.Context) error {
+			t.Error("unsupported warehouse mode should not be acknowledged")
+			return nil
+		}).WithWarehouseMode("primary"))
+
+		select {
+		case err := <-errCh:
+			require.EqualError(t, err, "unsupported warehouse mode: primary")
+		case <-time.After(time.Second):
+			t.Fatal("Did not get error within 1 second")
+		}
+	})
+}
+
+func TestDynamicCluster_HandoverFailure(t *testing.T) {
+	Init()
+
+	provider := &mockModeProvider{
+		modeCh:      make(chan servermode.ChangeEvent),
+		workspaceCh: make(chan workspace.ChangeEvent),
+	}
+	ctrl := gomock.NewController(t)
+	backendConfig := NewMockconfigLifecycle(ctrl)
+	routerDrainer, warehouseDrainer := &mockDrainer{}, &mockDrainer{}
+	dc := cluster.Dynamic{
+		Provider:          provider,
+		BackendConfig:     backendConfig,
+		WorkspaceDrainers: []cluster.WorkspaceDrainer{routerDrainer, warehouseDrainer},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- dc.Run(ctx)
+	}()
+
+	backendConfig.EXPECT().Stop().Times(1)
+	backendConfig.EXPECT().WaitForConfig(gomock.Any()).Times(1)
+	backendConfig.EXPECT().StartWithIDs(gomock.Any(), "a,b").Times(1)
+	chACK := make(chan struct{})
+	provider.sendWorkspaceIDs(
+		workspace.NewWorkspacesRequest([]string{"a", "b"}, func(_ context.Context, err error) error {
+			require.NoError(t, err)
+			close(chACK)
+			return nil
+		}),
+	)
+	select {
+	case <-chACK:
+	case <-time.After(time.Second):
+		t.Fatal("Did not get acknowledgement within 1 second")
+	}
+
+	var ackErr error
+	chACK = make(chan struct{})
+	provider.sendWorkspaceIDs(
+		workspace.NewWorkspacesRequest([]string{"a"}, func(_ context.Context, err error) error {
+			ackErr = err
+			close(chACK)
+			return nil
+		}).WithHandover(func(context.Context, []workspace.PendingMarker) error {
+			return errors.New("handover failed")
+		}),
+	)
+	select {
+	case <-chACK:
+	case <-time.After(time.Second):
+		t.Fatal("Did not get acknowledgement within 1 second")
+	}
+	require.EqualError(t, ackErr, "handing over workspaces: handover failed")
+	for _, drainer := range []*mockDrainer{routerDrainer, warehouseDrainer} {
+		require.Equal(t, []string{"b"}, drainer.drained)
+		require.Equal(t, []string{"b"}, drainer.resumed, "the workspaces not handed over are resumed")
+	}
+	require.Error(t, <-errCh)
+}
@@ -49,17 +49,20 @@ type ETCDConfig struct {
 }
 
 type modeRequestValue struct {
-	Mode   servermode.Mode `json:"mode"`
-	AckKey string          `json:"ack_key"`
+	Mode          servermode.Mode `json:"mode"`
+	AckKey        string          `json:"ack_key"`
+	WarehouseMode string          `json:"warehouse_mode,omitempty"` // master, slave, master_and_slave or off, if switched
 }
 
 type modeAckValue struct {
-	Status servermode.Mode `json:"status"`
+	Status          servermode.Mode `json:"status"`
+	WarehouseStatus string          `json:"warehouse_status,omitempty"`
 }
 
 type workspacesRequestsValue struct {
-	Workspaces string `json:"workspaces"` // comma separated workspaces
-	AckKey     string `json:"ack_key"`
+	Workspaces  string `json:"workspaces"` // comma separated workspaces
+	AckKey      string `json:"ack_key"`
+	HandoverKey string `json:"handover_key,omitempty"` // signaled once the workspaces detached by the request are drained
 }
 
 type workspacesAckValue struct {
@@ -67,6 +70,11 @@ type workspacesAckValue struct {
 	Error  string `json:"error"`
 }
 
+type workspacesHandoverValue struct {
+	Status  string                    `json:"status"`
+	Pending []workspace.PendingMarker `json:"pending"`
+}
+
 func EnvETCDConfig() *ETCDConfig {
 	endpoints := strings.Split(config.GetString("ETCD_HOSTS", "127.0.0.1:2379"), `,`)
 	releaseName := config.GetReleaseName()
@@ -156,28 +164,33 @@ func (manager *ETCDManager) unmarshalMode(raw []byte) servermode.ChangeEvent {
 		return servermode.ChangeEventError(fmt.Errorf("invalid mode: %s", mode))
 	}
 
+	ack := func(ctx context.Context, warehouseMode string) error {
+		ctx, cancel := context.WithTimeout(ctx, manager.ackTimeout)
+		defer cancel()
+
+		ackValue, err := json.MarshalToString(modeAckValue{
+			Status:          mode,
+			WarehouseStatus: warehouseMode,
+		})
+		if err != nil {
+			return fmt.Errorf("marshal ack value: %w", err)
+		}
+		manager.logger.Infof("Mode Change Acknowledgement Key: %s", req.AckKey)
+		_, err = manager.Client.Put(ctx, req.AckKey, ackValue)
+		if err != nil {
+			manager.logger.Errorf("Failed to acknowledge mode change for key: %s", req.AckKey)
+			return fmt.Errorf("put value to ack key %q: %w", req.AckKey, err)
+		} else {
+			manager.logger.Debugf("Mode change for key %q acknowledged", req.AckKey)
+		}
+		return err
+	}
+	// the warehouse status acknowledged is the mode the warehouse is in once the request is handled, not the one requested
 	return servermode.NewChangeEvent(
 		mode,
 		func(ctx context.Context) error {
-			ctx, cancel := context.WithTimeout(ctx, manager.ackTimeout)
-			defer cancel()
-
-			ackValue, err := json.MarshalToString(modeAckValue{
-				Status: mode,
-			})
-			if err != nil {
-				return fmt.Errorf("marshal ack value: %w", err)
-			}
-			manager.logger.Infof("Mode Change Acknowledgement Key: %s", req.AckKey)
-			_, err = manager.Client.Put(ctx, req.AckKey, ackValue)
-			if err != nil {
-				manager.logger.Errorf("Failed to acknowledge mode change for key: %s", req.AckKey)
-				return fmt.Errorf("put value to ack key %q: %w", req.AckKey, err)
-			} else {
-				manager.logger.Debugf("Mode change for key %q acknowledged", req.AckKey)
-			}
-			return err
-		})
+			return ack(ctx, "")
+		}).WithWarehouseMode(req.WarehouseMode).WithWarehouseAck(ack)
 }
 
 func errChModeRequest(err error) <-chan servermode.ChangeEvent {
@@ -276,7 +289,30 @@ func (manager *ETCDManager) unmarshalWorkspace(raw []byte) workspace.ChangeEvent
 			}
 			return err
 		},
-	)
+	).WithHandover(func(ctx context.Context, pending []workspace.PendingMarker) error {
+		if req.HandoverKey == "" {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, manager.ackTimeout)
+		defer cancel()
+
+		if pending == nil {
+			pending = []workspace.PendingMarker{}
+		}
+		handoverValue, err := json.MarshalToString(workspacesHandoverValue{
+			Status:  "DRAINED",
+			Pending: pending,
+		})
+		if err != nil {
+			return fmt.Errorf("marshal handover value: %w", err)
+		}
+		manager.logger.Infof("Workspace Handover (pending: %d) Key: %s", len(pending), req.HandoverKey)
+		if _, err := manager.Client.Put(ctx, req.HandoverKey, handoverValue); err != nil {
+			manager.logger.Errorf("Failed to hand over workspaces for key: %s", req.HandoverKey)
+			return fmt.Errorf("put value to handover key %q: %w", req.HandoverKey, err)
+		}
+		return nil
+	})
 }
 
 func (manager *ETCDManager) WorkspaceIDs(ctx context.Context) <-chan workspace.ChangeEvent {
@@ -192,6 +192,39 @@ func Test_ServerMode(t *testing.T) {
 		require.JSONEq(t, `{"status":"NORMAL"}`, string(resp.Kvs[0].Value))
 	}
 
+	t.Log("update switching the warehouse mode should be received")
+	{
+		_, err := etcdClient.Put(ctx, modeRequestKey, `{"mode": "NORMAL", "ack_key": "test-ack/3", "warehouse_mode": "master"}`)
+		require.NoError(t, err)
+
+		m, ok := <-ch
+		require.True(t, ok)
+		require.NoError(t, m.Err())
+		require.Equal(t, servermode.NormalMode, m.Mode())
+		require.Equal(t, "master", m.WarehouseMode())
+		require.NoError(t, m.AckWarehouseMode(ctx, "master"))
+
+		resp, err := etcdClient.Get(ctx, "test-ack/3")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"status":"NORMAL","warehouse_status":"master"}`, string(resp.Kvs[0].Value))
+	}
+
+	t.Log("the warehouse mode acknowledged is the one applied")
+	{
+		_, err := etcdClient.Put(ctx, modeRequestKey, `{"mode": "NORMAL", "ack_key": "test-ack/4", "warehouse_mode": "master"}`)
+		require.NoError(t, err)
+
+		m, ok := <-ch
+		require.True(t, ok)
+		require.NoError(t, m.Err())
+		require.Equal(t, "master", m.WarehouseMode())
+		require.NoError(t, m.AckWarehouseMode(ctx, ""))
+
+		resp, err := etcdClient.Get(ctx, "test-ack/4")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"status":"NORMAL"}`, string(resp.Kvs[0].Value))
+	}
+
 	t.Log("update with invalid JSON should return error")
 	{
 		_, err := etcdClient.Put(ctx, modeRequestKey, `{"mode''`)
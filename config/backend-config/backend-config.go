@@ -9,9 +9,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	adminpkg "github.com/rudderlabs/rudder-server/admin"
@@ -22,6 +24,7 @@ import (
 	"github.com/rudderlabs/rudder-server/services/diagnostics"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/pubsub"
 	"github.com/rudderlabs/rudder-server/utils/sysUtils"
 	"github.com/rudderlabs/rudder-server/utils/types"
@@ -37,6 +40,14 @@ var (
 	configFromFile                        bool
 	maxRegulationsPerRequest              int
 	configEnvReplacementEnabled           bool
+	incrementalConfigUpdates              bool
+	diskCacheEnabled                      bool
+	diskCacheDir                          string
+	staleThreshold                        time.Duration
+
+	// staleSince is when the config served was fetched from the control plane, in unix nanoseconds, while the control
+	// plane is unreachable, zero otherwise
+	staleSince atomic.Int64
 
 	LastSync           string
 	LastRegulationSync string
@@ -87,6 +98,8 @@ type backendConfigImpl struct {
 	curSourceJSONLock sync.RWMutex
 	usingCache        bool
 	cache             cache.Cache
+	fetchedAt         time.Time // when the config served was fetched from the control plane
+	fetchedAtLock     sync.RWMutex
 }
 
 func loadConfig() {
@@ -98,6 +111,27 @@ func loadConfig() {
 	config.RegisterBoolConfigVariable(false, &configFromFile, false, "BackendConfig.configFromFile")
 	config.RegisterIntConfigVariable(1000, &maxRegulationsPerRequest, true, 1, "BackendConfig.maxRegulationsPerRequest")
 	config.RegisterBoolConfigVariable(true, &configEnvReplacementEnabled, false, "BackendConfig.envReplacementEnabled")
+	config.RegisterBoolConfigVariable(false, &incrementalConfigUpdates, false, "BackendConfig.incrementalConfigUpdates")
+	config.RegisterBoolConfigVariable(true, &diskCacheEnabled, false, "BackendConfig.diskCache.enabled")
+	config.RegisterStringConfigVariable("", &diskCacheDir, false, "BackendConfig.diskCache.dir")
+	config.RegisterDurationConfigVariable(60, &staleThreshold, true, time.Second, []string{"BackendConfig.staleThreshold", "BackendConfig.staleThresholdInS"}...)
+}
+
+// StaleSince returns when the config served was fetched from the control plane, if the control plane is unreachable
+// since, the config being served from memory or from the cache in the meantime
+func StaleSince() (time.Time, bool) {
+	if since := staleSince.Load(); since != 0 {
+		return time.Unix(0, since), true
+	}
+	return time.Time{}, false
+}
+
+// markStale marks the config served as stale since it was fetched from the control plane, unless it's marked already
+func markStale(fetchedAt time.Time) {
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+	staleSince.CompareAndSwap(0, fetchedAt.UnixNano())
 }
 
 func Init() {
@@ -127,6 +161,23 @@ func trackConfig(preConfig, curConfig ConfigT) {
 	}
 }
 
+// updateRudderStorageCredentials makes the rudder storage credentials of every workspace available to object storage clients
+func updateRudderStorageCredentials(config map[string]ConfigT) {
+	credentials := make(map[string]misc.RudderStorageCredentials)
+	for workspaceID, wConfig := range config {
+		rudderStorage := wConfig.Settings.RudderStorage
+		if rudderStorage == (RudderStorage{}) {
+			continue
+		}
+		credentials[workspaceID] = misc.RudderStorageCredentials{
+			AccessKeyID: rudderStorage.AccessKeyID,
+			AccessKey:   rudderStorage.AccessKey,
+			Prefix:      rudderStorage.Prefix,
+		}
+	}
+	misc.SetRudderStorageCredentials(credentials)
+}
+
 func filterProcessorEnabledWorkspaceConfig(config map[string]ConfigT) map[string]ConfigT {
 	filterConfig := make(map[string]ConfigT, len(config))
 	for workspaceID, wConfig := range config {
@@ -153,6 +204,27 @@ func filterProcessorEnabledDestinations(config ConfigT) ConfigT {
 	return modifiedConfig
 }
 
+// copyConfig returns a copy of the config, sharing the config of its workspaces
+func copyConfig(config map[string]ConfigT) map[string]ConfigT {
+	copied := make(map[string]ConfigT, len(config))
+	for workspaceID, wConfig := range config {
+		copied[workspaceID] = wConfig
+	}
+	return copied
+}
+
+// ChangedWorkspaces returns the IDs of the workspaces of the current config whose config differs from the previous
+// one, including the ones not in the previous config, for subscribers to only process the workspaces changed
+func ChangedWorkspaces(previous, current map[string]ConfigT) map[string]struct{} {
+	changed := make(map[string]struct{})
+	for workspaceID, wConfig := range current {
+		if previousConfig, ok := previous[workspaceID]; !ok || !reflect.DeepEqual(previousConfig, wConfig) {
+			changed[workspaceID] = struct{}{}
+		}
+	}
+	return changed
+}
+
 func (bc *backendConfigImpl) configUpdate(ctx context.Context, workspaces string) {
 	statConfigBackendError := stats.Default.NewStat("config_backend.errors", stats.CountType)
 
@@ -167,6 +239,13 @@ func (bc *backendConfigImpl) configUpdate(ctx context.Context, workspaces string
 		} else {
 			cacheConfigGauge.Gauge(0)
 		}
+		// age of the config served, since it was fetched from the control plane
+		stalenessGauge := stats.Default.NewStat("config_backend.staleness", stats.GaugeType)
+		if since, stale := StaleSince(); stale {
+			stalenessGauge.Gauge(time.Since(since).Seconds())
+		} else {
+			stalenessGauge.Gauge(0)
+		}
 	}()
 
 	sourceJSON, err = bc.workspaceConfig.Get(ctx, workspaces)
@@ -177,6 +256,7 @@ func (bc *backendConfigImpl) configUpdate(ctx context.Context, workspaces string
 		bc.initializedLock.RLock()
 		if bc.initialized {
 			bc.initializedLock.RUnlock()
+			markStale(bc.lastFetchedAt())
 			return
 		}
 		bc.initializedLock.RUnlock()
@@ -193,8 +273,17 @@ func (bc *backendConfigImpl) configUpdate(ctx context.Context, workspaces string
 			return
 		}
 		bc.usingCache = true
+		var fetchedAt time.Time
+		if timestamped, ok := bc.cache.(cache.Timestamped); ok {
+			fetchedAt, _ = timestamped.CachedAt(ctx)
+		}
+		bc.setFetchedAt(fetchedAt)
+		markStale(fetchedAt)
+		pkgLogger.Warnf("Serving cached config fetched at %v, as the control plane is unreachable", fetchedAt)
 	} else {
 		bc.usingCache = false
+		bc.setFetchedAt(time.Now())
+		staleSince.Store(0)
 	}
 
 	// sorting the sourceJSON.
@@ -219,6 +308,7 @@ func (bc *backendConfigImpl) configUpdate(ctx context.Context, workspaces string
 			}
 		}
 		filteredSourcesJSON := filterProcessorEnabledWorkspaceConfig(sourceJSON)
+		updateRudderStorageCredentials(sourceJSON)
 		bc.curSourceJSON = sourceJSON
 		bc.curSourceJSONLock.Unlock()
 		LastSync = time.Now().Format(time.RFC3339) // TODO fix concurrent access
@@ -294,6 +384,8 @@ func newForDeployment(deploymentType deployment.Type, region string, configEnvHa
 			configEnvHandler: configEnvHandler,
 			cpRouterURL:      cpRouterURL,
 			region:           region,
+
+			incrementalConfigUpdates: incrementalConfigUpdates,
 		}
 	default:
 		return nil, fmt.Errorf("deployment type %q not supported", deploymentType)
@@ -349,6 +441,14 @@ func (bc *backendConfigImpl) StartWithIDs(ctx context.Context, workspaces string
 				bc.cache = &noCache{}
 			}
 		}
+		if diskCacheEnabled {
+			diskCache, err := startDiskCache(ctx, secret, cacheKey, func() pubsub.DataChannel { return bc.Subscribe(ctx, TopicBackendConfig) })
+			if err != nil {
+				pkgLogger.Warnf("Failed to start backend config disk cache, the config won't be cached on disk: %v", err)
+			} else {
+				bc.cache = cache.Layered(bc.cache, diskCache)
+			}
+		}
 	}
 
 	rruntime.Go(func() {
@@ -357,6 +457,32 @@ func (bc *backendConfigImpl) StartWithIDs(ctx context.Context, workspaces string
 	})
 }
 
+// startDiskCache caches the config in BackendConfig.diskCache.dir, the temporary directory by default
+func startDiskCache(ctx context.Context, secret [32]byte, key string, channelProvider func() pubsub.DataChannel) (cache.Cache, error) {
+	dir := diskCacheDir
+	if dir == "" {
+		tmpDir, err := misc.CreateTMPDIR()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(tmpDir, "backend-config-cache")
+	}
+	return cache.StartDisk(ctx, dir, secret, key, channelProvider)
+}
+
+func (bc *backendConfigImpl) setFetchedAt(fetchedAt time.Time) {
+	bc.fetchedAtLock.Lock()
+	defer bc.fetchedAtLock.Unlock()
+	bc.fetchedAt = fetchedAt
+}
+
+// lastFetchedAt returns when the config served was fetched from the control plane
+func (bc *backendConfigImpl) lastFetchedAt() time.Time {
+	bc.fetchedAtLock.RLock()
+	defer bc.fetchedAtLock.RUnlock()
+	return bc.fetchedAt
+}
+
 func (bc *backendConfigImpl) Stop() {
 	if bc.cancel != nil {
 		bc.cancel()
@@ -373,6 +499,10 @@ func (bc *backendConfigImpl) WaitForConfig(ctx context.Context) {
 		bc.initializedLock.RLock()
 		if bc.initialized {
 			bc.initializedLock.RUnlock()
+			// the config served is stale only if it wasn't fetched from the control plane lately, e.g. if served from the cache
+			if fetchedAt := bc.lastFetchedAt(); time.Since(fetchedAt) > staleThreshold {
+				markStale(fetchedAt)
+			}
 			return
 		}
 		bc.initializedLock.RUnlock()
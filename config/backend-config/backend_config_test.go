@@ -256,6 +256,44 @@ func TestConfigUpdate(t *testing.T) {
 		require.Equal(t, (<-chProcess).Data, map[string]ConfigT{workspaces: sampleFilteredSources})
 		require.Equal(t, (<-chBackend).Data, map[string]ConfigT{workspaces: sampleBackendConfig})
 	})
+
+	t.Run("stale config while the control plane is unreachable", func(t *testing.T) {
+		var (
+			ctrl        = gomock.NewController(t)
+			ctx, cancel = context.WithCancel(context.Background())
+			workspaces  = "foo"
+		)
+		defer ctrl.Finish()
+		defer cancel()
+		staleSince.Store(0)
+
+		wc := NewMockworkspaceConfig(ctrl)
+		gomock.InOrder(
+			wc.EXPECT().Get(gomock.Eq(ctx), workspaces).Return(map[string]ConfigT{workspaces: sampleBackendConfig}, nil),
+			wc.EXPECT().Get(gomock.Eq(ctx), workspaces).Return(nil, errors.New("control plane down")).Times(2),
+			wc.EXPECT().Get(gomock.Eq(ctx), workspaces).Return(map[string]ConfigT{workspaces: sampleBackendConfig}, nil),
+		)
+		bc := &backendConfigImpl{
+			eb:              pubsub.New(),
+			workspaceConfig: wc,
+			cache:           cache.NewMockCache(ctrl),
+		}
+
+		bc.configUpdate(ctx, workspaces)
+		_, stale := StaleSince()
+		require.False(t, stale)
+		fetchedAt := bc.lastFetchedAt()
+
+		bc.configUpdate(ctx, workspaces)
+		bc.configUpdate(ctx, workspaces)
+		since, stale := StaleSince()
+		require.True(t, stale)
+		require.True(t, fetchedAt.Equal(since), "stale since the config was fetched")
+
+		bc.configUpdate(ctx, workspaces)
+		_, stale = StaleSince()
+		require.False(t, stale)
+	})
 }
 
 func TestFilterProcessorEnabledDestinations(t *testing.T) {
@@ -317,6 +355,25 @@ func TestWaitForConfig(t *testing.T) {
 		bc.WaitForConfig(ctx)
 	})
 
+	t.Run("stale only if fetched before the threshold", func(t *testing.T) {
+		ctx := context.Background()
+		staleThreshold = time.Minute
+		staleSince.Store(0)
+		defer staleSince.Store(0)
+
+		bc := &backendConfigImpl{initialized: true, fetchedAt: time.Now()}
+		bc.WaitForConfig(ctx)
+		_, stale := StaleSince()
+		require.False(t, stale, "fetched lately")
+
+		fetchedAt := time.Now().Add(-2 * time.Minute)
+		bc.setFetchedAt(fetchedAt)
+		bc.WaitForConfig(ctx)
+		since, stale := StaleSince()
+		require.True(t, stale)
+		require.True(t, fetchedAt.Equal(since))
+	})
+
 	t.Run("it should wait until initialized", func(t *testing.T) {
 		var (
 			ctrl = gomock.NewController(t)
@@ -351,6 +408,7 @@ func TestWaitForConfig(t *testing.T) {
 }
 
 func TestCache(t *testing.T) {
+	t.Setenv("RSERVER_BACKEND_CONFIG_DISK_CACHE_DIR", t.TempDir())
 	initBackendConfig()
 	t.Setenv("RSERVER_BACKEND_CONFIG_POLL_INTERVAL", "10ms")
 	var calls int32
@@ -9,6 +9,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/rudderlabs/rudder-server/config"
 	migrator "github.com/rudderlabs/rudder-server/services/sql-migrator"
@@ -23,6 +24,11 @@ type Cache interface {
 	Get(ctx context.Context) ([]byte, error)
 }
 
+// Timestamped is implemented by caches knowing when the config they return was cached
+type Timestamped interface {
+	CachedAt(ctx context.Context) (time.Time, error)
+}
+
 type cacheStore struct {
 	*sql.DB
 	secret [32]byte
@@ -88,7 +94,7 @@ func (db *cacheStore) set(ctx context.Context, config interface{}) error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 	// encrypt
-	encrypted, err := db.encryptAES(configBytes)
+	encrypted, err := encryptAES(db.secret, configBytes)
 	if err != nil {
 		return err
 	}
@@ -127,7 +133,18 @@ func (db *cacheStore) Get(ctx context.Context) ([]byte, error) {
 		return nil, err
 	}
 	// decrypt and return
-	return db.decryptAES(config)
+	return decryptAES(db.secret, config)
+}
+
+// CachedAt returns when the cached config was last updated
+func (db *cacheStore) CachedAt(ctx context.Context) (time.Time, error) {
+	var updatedAt time.Time
+	err := db.QueryRowContext(
+		ctx,
+		`SELECT updated_at FROM config_cache WHERE key = $1`,
+		db.key,
+	).Scan(&updatedAt)
+	return updatedAt, err
 }
 
 // setupDBConn sets up the database connection, creates the config table if it doesn't exist
@@ -163,8 +180,8 @@ func migrate(db *sql.DB) error {
 	return m.Migrate("config_cache")
 }
 
-func (db *cacheStore) encryptAES(data []byte) ([]byte, error) {
-	gcm, err := newGCM(db.secret)
+func encryptAES(secret [32]byte, data []byte) ([]byte, error) {
+	gcm, err := newGCM(secret)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create encrypt gcm: %w", err)
 	}
@@ -175,12 +192,15 @@ func (db *cacheStore) encryptAES(data []byte) ([]byte, error) {
 	return gcm.Seal(nonce, nonce, data, nil), nil
 }
 
-func (db *cacheStore) decryptAES(data []byte) ([]byte, error) {
-	gcm, err := newGCM(db.secret)
+func decryptAES(secret [32]byte, data []byte) ([]byte, error) {
+	gcm, err := newGCM(secret)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create decrypt gcm: %w", err)
 	}
 	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("failed to decrypt: %d bytes is too short", len(data))
+	}
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
 	out, err := gcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
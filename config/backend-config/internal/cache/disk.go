@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/utils/pubsub"
+)
+
+const diskFilePrefix = "config-"
+
+// diskEntry is the config cached on disk, along with when it was cached
+type diskEntry struct {
+	CachedAt time.Time       `json:"cachedAt"`
+	Config   json.RawMessage `json:"config"`
+}
+
+type diskStore struct {
+	path   string
+	secret [32]byte
+}
+
+// StartDisk returns a new Cache instance keeping the config in a file of dir, and starts a goroutine to cache the
+// config, like Start does in the database. The config of other keys cached in dir is removed.
+func StartDisk(ctx context.Context, dir string, secret [32]byte, key string, channelProvider func() pubsub.DataChannel) (Cache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+	name := fmt.Sprintf("%s%x", diskFilePrefix, sha256.Sum256([]byte(key)))
+	diskStore := diskStore{
+		path:   filepath.Join(dir, name),
+		secret: secret,
+	}
+
+	// clear config for other keys
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache directory %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), diskFilePrefix) && entry.Name() != name {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return nil, fmt.Errorf("failed to clear previous config: %w", err)
+			}
+		}
+	}
+
+	go func() {
+		// subscribe to config and write to disk
+		for config := range channelProvider() {
+			if err := diskStore.set(config.Data); err != nil {
+				pkgLogger.Errorf("failed writing config to disk: %v", err)
+			}
+		}
+	}()
+	return &diskStore, nil
+}
+
+// Encrypt and write the config to disk, replacing the previous one at once
+func (d *diskStore) set(config interface{}) error {
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	entryBytes, err := json.Marshal(diskEntry{CachedAt: time.Now(), Config: configBytes})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	encrypted, err := encryptAES(d.secret, entryBytes)
+	if err != nil {
+		return err
+	}
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, encrypted, 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return os.Rename(tmp, d.path)
+}
+
+func (d *diskStore) get() (diskEntry, error) {
+	var entry diskEntry
+	encrypted, err := os.ReadFile(d.path)
+	if err != nil {
+		return entry, err
+	}
+	entryBytes, err := decryptAES(d.secret, encrypted)
+	if err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal(entryBytes, &entry); err != nil {
+		return entry, fmt.Errorf("failed to unmarshal cache entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Get reads the cached config from disk
+func (d *diskStore) Get(context.Context) ([]byte, error) {
+	entry, err := d.get()
+	if err != nil {
+		return nil, err
+	}
+	return entry.Config, nil
+}
+
+// CachedAt returns when the config on disk was cached
+func (d *diskStore) CachedAt(context.Context) (time.Time, error) {
+	entry, err := d.get()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return entry.CachedAt, nil
+}
+
+type layered []Cache
+
+// Layered returns a Cache getting the config from the first of the caches having it
+func Layered(caches ...Cache) Cache {
+	return layered(caches)
+}
+
+// Get returns the config of the first cache having it, or the error of the first one
+func (l layered) Get(ctx context.Context) ([]byte, error) {
+	var firstErr error
+	for _, c := range l {
+		config, err := c.Get(ctx)
+		if err == nil {
+			return config, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// CachedAt returns when the config of the first cache having it was cached, the zero time if the cache doesn't know
+func (l layered) CachedAt(ctx context.Context) (time.Time, error) {
+	for _, c := range l {
+		if _, err := c.Get(ctx); err != nil {
+			continue
+		}
+		if timestamped, ok := c.(Timestamped); ok {
+			return timestamped.CachedAt(ctx)
+		}
+		return time.Time{}, nil
+	}
+	return time.Time{}, fmt.Errorf("no cached config")
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/utils/pubsub"
+)
+
+func TestDiskCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, diskFilePrefix+"previous"), []byte("previous"), 0o600))
+
+	secret := sha256.Sum256([]byte("secret"))
+	ps := pubsub.New()
+	c, err := StartDisk(ctx, dir, secret, "key", func() pubsub.DataChannel { return ps.Subscribe(ctx, "config") })
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, diskFilePrefix+"previous"))
+	require.True(t, os.IsNotExist(err), "config of other keys is removed")
+
+	_, err = c.Get(ctx)
+	require.Error(t, err, "nothing cached yet")
+
+	before := time.Now()
+	ps.Publish("config", map[string]string{"workspace": "config"})
+	require.Eventually(t, func() bool {
+		config, err := c.Get(ctx)
+		return err == nil && string(config) == `{"workspace":"config"}`
+	}, time.Second, 10*time.Millisecond)
+	cachedAt, err := c.(Timestamped).CachedAt(ctx)
+	require.NoError(t, err)
+	require.False(t, cachedAt.Before(before))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	raw, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "workspace", "config is encrypted")
+
+	other := diskStore{path: filepath.Join(dir, files[0].Name()), secret: sha256.Sum256([]byte("other"))}
+	_, err = other.Get(ctx)
+	require.Error(t, err, "config can't be decrypted with another secret")
+}
+
+func TestLayered(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	primary := NewMockCache(ctrl)
+	secondary := NewMockCache(ctrl)
+	c := Layered(primary, secondary)
+
+	primary.EXPECT().Get(ctx).Return([]byte("primary"), nil)
+	config, err := c.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "primary", string(config))
+
+	primaryErr := errors.New("primary")
+	primary.EXPECT().Get(ctx).Return(nil, primaryErr)
+	secondary.EXPECT().Get(ctx).Return([]byte("secondary"), nil)
+	config, err = c.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "secondary", string(config))
+
+	primary.EXPECT().Get(ctx).Return(nil, primaryErr)
+	secondary.EXPECT().Get(ctx).Return(nil, errors.New("secondary"))
+	_, err = c.Get(ctx)
+	require.ErrorIs(t, err, primaryErr)
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/services/controlplane/identity"
+	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/httputil"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/types"
@@ -21,6 +23,17 @@ import (
 
 var jsonfast = jsoniter.ConfigCompatibleWithStandardLibrary
 
+// The namespace config is fetched incrementally, for deployments with many workspaces not to download and parse
+// the config of all of them on every poll:
+//   - the ETag of the last config is sent in If-None-Match, the last config being returned as is if the config
+//     backend responds with 304 Not Modified
+//   - if BackendConfig.incrementalConfigUpdates, only the workspaces updated after the last update of the config are
+//     requested with updatedAfter, the config backend responding with the IDs of all the workspaces of the namespace,
+//     null for the ones not updated since, whose last config is kept
+
+// errConfigNotModified is returned by makeHTTPRequest if the config isn't modified since the ETag sent
+var errConfigNotModified = errors.New("config not modified")
+
 type namespaceConfig struct {
 	configEnvHandler types.ConfigEnvI
 	cpRouterURL      string
@@ -33,6 +46,13 @@ type namespaceConfig struct {
 	namespace        string
 	configBackendURL *url.URL
 	region           string
+
+	incrementalConfigUpdates bool
+
+	mu            sync.Mutex
+	etag          string             // ETag of the last config
+	lastConfig    map[string]ConfigT // last config, by workspace
+	lastUpdatedAt time.Time          // last update of the workspaces of the last config
 }
 
 func (nc *namespaceConfig) SetUp() (err error) {
@@ -56,9 +76,9 @@ func (nc *namespaceConfig) SetUp() (err error) {
 		}
 	}
 	if nc.client == nil {
-		nc.client = &http.Client{
+		nc.client = stats.InstrumentHTTPClient(stats.Default, &http.Client{
 			Timeout: config.GetDuration("HttpClient.backendConfig.timeout", 30, time.Second),
-		}
+		}, stats.Tags{"module": "backend-config"})
 	}
 	if nc.logger == nil {
 		nc.logger = logger.NewLogger().Child("backend-config")
@@ -81,12 +101,30 @@ func (nc *namespaceConfig) getFromAPI(ctx context.Context, _ string) (map[string
 		return config, fmt.Errorf("namespace is not configured")
 	}
 
-	var respBody []byte
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	return nc.fetch(ctx)
+}
+
+// fetch fetches the config of the namespace, incrementally from the last config if there is one
+func (nc *namespaceConfig) fetch(ctx context.Context) (map[string]ConfigT, error) {
+	config := make(map[string]ConfigT)
+	var (
+		respBody []byte
+		etag     string
+	)
 	u := *nc.configBackendURL
 	u.Path = fmt.Sprintf("/data-plane/v1/namespaces/%s/config", nc.namespace)
+	incremental := nc.incrementalConfigUpdates && nc.lastConfig != nil && !nc.lastUpdatedAt.IsZero()
+	if incremental {
+		u.RawQuery = url.Values{"updatedAfter": {nc.lastUpdatedAt.Format(time.RFC3339Nano)}}.Encode()
+	}
 	operation := func() (fetchError error) {
 		nc.logger.Debugf("Fetching config from %s", u.String())
-		respBody, fetchError = nc.makeHTTPRequest(ctx, u.String())
+		respBody, etag, fetchError = nc.makeHTTPRequest(ctx, u.String())
+		if errors.Is(fetchError, errConfigNotModified) {
+			return backoff.Permanent(fetchError)
+		}
 		return fetchError
 	}
 
@@ -94,6 +132,10 @@ func (nc *namespaceConfig) getFromAPI(ctx context.Context, _ string) (map[string
 	err := backoff.RetryNotify(operation, backoffWithMaxRetry, func(err error, t time.Duration) {
 		nc.logger.Warnf("Failed to fetch config from API with error: %v, retrying after %v", err, t)
 	})
+	if errors.Is(err, errConfigNotModified) {
+		nc.logger.Debugf("Config of namespace %s not modified", nc.namespace)
+		return copyConfig(nc.lastConfig), nil
+	}
 	if err != nil {
 		if ctx.Err() == nil {
 			nc.logger.Errorf("Error sending request to the server: %v", err)
@@ -105,30 +147,55 @@ func (nc *namespaceConfig) getFromAPI(ctx context.Context, _ string) (map[string
 		respBody = configEnvHandler.ReplaceConfigWithEnvVariables(respBody)
 	}
 
-	var workspacesConfig map[string]ConfigT
+	var workspacesConfig map[string]*ConfigT
 	err = jsonfast.Unmarshal(respBody, &workspacesConfig)
 	if err != nil {
 		nc.logger.Errorf("Error while parsing request: %v", err)
 		return config, err
 	}
 
+	var lastUpdatedAt time.Time
 	for workspaceID, wc := range workspacesConfig {
-		// always set connection flags to true for hosted and multi-tenant warehouse service
-		wc.ConnectionFlags.URL = nc.cpRouterURL
-		wc.ConnectionFlags.Services = map[string]bool{"warehouse": true}
-		workspacesConfig[workspaceID] = wc
+		if wc == nil { // not updated since the last config
+			last, ok := nc.lastConfig[workspaceID]
+			if !incremental {
+				return config, fmt.Errorf("config of workspace %s missing", workspaceID)
+			}
+			if !ok {
+				nc.logger.Warnf("Config of workspace %s missing from the incremental config, fetching the whole config", workspaceID)
+				nc.etag, nc.lastConfig, nc.lastUpdatedAt = "", nil, time.Time{}
+				return nc.fetch(ctx)
+			}
+			config[workspaceID] = last
+		} else {
+			// always set connection flags to true for hosted and multi-tenant warehouse service
+			wc.ConnectionFlags.URL = nc.cpRouterURL
+			wc.ConnectionFlags.Services = map[string]bool{"warehouse": true}
+			config[workspaceID] = *wc
+		}
+		if updatedAt := config[workspaceID].UpdatedAt; updatedAt.After(lastUpdatedAt) {
+			lastUpdatedAt = updatedAt
+		}
 	}
 
-	return workspacesConfig, nil
+	nc.etag = etag
+	nc.lastConfig = config
+	nc.lastUpdatedAt = lastUpdatedAt
+	return copyConfig(config), nil
 }
 
-func (nc *namespaceConfig) makeHTTPRequest(ctx context.Context, url string) ([]byte, error) {
+// makeHTTPRequest returns the body of the response to the request and its ETag, errConfigNotModified if the config
+// isn't modified since the last one
+func (nc *namespaceConfig) makeHTTPRequest(ctx context.Context, url string) ([]byte, string, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, http.NoBody)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	req.SetBasicAuth(nc.Identity().BasicAuth())
+	if nc.etag != "" {
+		req.Header.Set("If-None-Match", nc.etag)
+	}
 	if nc.region != "" {
 		q := req.URL.Query()
 		q.Add("region", nc.region)
@@ -136,20 +203,23 @@ func (nc *namespaceConfig) makeHTTPRequest(ctx context.Context, url string) ([]b
 	}
 	resp, err := nc.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	defer func() { httputil.CloseResponse(resp) }()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, "", errConfigNotModified
+	}
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if resp.StatusCode >= 300 {
-		return nil, getNotOKError(respBody, resp.StatusCode)
+		return nil, "", getNotOKError(respBody, resp.StatusCode)
 	}
 
-	return respBody, nil
+	return respBody, resp.Header.Get("ETag"), nil
 }
 
 func (nc *namespaceConfig) AccessToken() string {
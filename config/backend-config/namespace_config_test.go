@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -111,6 +112,94 @@ func Test_Namespace_Get(t *testing.T) {
 	})
 }
 
+func Test_Namespace_IncrementalGet(t *testing.T) {
+	config.Reset()
+	logger.Reset()
+
+	var (
+		namespace    = "free-us-1"
+		workspaceID1 = "2CCgbmvBSa8Mv81YaIgtR36M7aW"
+		workspaceID2 = "2CChLejq5aIWi3qsKVm1PjHkyTj"
+		updatedAt1   = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		updatedAt2   = updatedAt1.Add(time.Hour)
+	)
+
+	var (
+		response     string
+		updatedAfter []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/data-plane/v1/namespaces/"+namespace+"/config", r.URL.Path)
+		updatedAfter = append(updatedAfter, r.URL.Query().Get("updatedAfter"))
+		if r.Header.Get("If-None-Match") == `"`+response+`"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"`+response+`"`)
+		_, _ = w.Write([]byte(response))
+	}))
+	defer ts.Close()
+	httpSrvURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	client := &namespaceConfig{
+		logger: logger.NOP,
+
+		client:           ts.Client(),
+		configBackendURL: httpSrvURL,
+
+		namespace: namespace,
+
+		hostedServiceSecret: "service-secret",
+		cpRouterURL:         cpRouterURL,
+
+		incrementalConfigUpdates: true,
+	}
+	require.NoError(t, client.SetUp())
+
+	response = `{"` + workspaceID1 + `":{"workspaceId":"` + workspaceID1 + `","updatedAt":"` + updatedAt1.Format(time.RFC3339) + `"},` +
+		`"` + workspaceID2 + `":{"workspaceId":"` + workspaceID2 + `","updatedAt":"` + updatedAt1.Format(time.RFC3339) + `"}}`
+	c, err := client.Get(context.Background(), "")
+	require.NoError(t, err)
+	require.Len(t, c, 2)
+
+	t.Run("not modified", func(t *testing.T) {
+		notModified, err := client.Get(context.Background(), "")
+		require.NoError(t, err)
+		require.Equal(t, c, notModified)
+		require.Equal(t, updatedAt1.Format(time.RFC3339Nano), updatedAfter[len(updatedAfter)-1])
+	})
+
+	t.Run("updated workspaces only", func(t *testing.T) {
+		response = `{"` + workspaceID1 + `":null,` +
+			`"` + workspaceID2 + `":{"workspaceId":"` + workspaceID2 + `","enableMetrics":true,"updatedAt":"` + updatedAt2.Format(time.RFC3339) + `"}}`
+		updated, err := client.Get(context.Background(), "")
+		require.NoError(t, err)
+		require.Len(t, updated, 2)
+		require.Equal(t, c[workspaceID1], updated[workspaceID1], "the workspace not updated keeps its config")
+		require.True(t, updated[workspaceID2].EnableMetrics)
+		require.Equal(t, cpRouterURL, updated[workspaceID2].ConnectionFlags.URL)
+		require.Equal(t, map[string]struct{}{workspaceID2: {}}, ChangedWorkspaces(c, updated))
+		require.False(t, c[workspaceID2].EnableMetrics, "configs returned earlier are left untouched")
+	})
+
+	t.Run("deleted workspaces", func(t *testing.T) {
+		response = `{"` + workspaceID2 + `":null}`
+		updated, err := client.Get(context.Background(), "")
+		require.NoError(t, err)
+		require.Len(t, updated, 1)
+		require.Contains(t, updated, workspaceID2)
+		require.Equal(t, updatedAt2.Format(time.RFC3339Nano), updatedAfter[len(updatedAfter)-1])
+	})
+
+	t.Run("unknown workspace not updated", func(t *testing.T) {
+		response = `{"unknown":null}`
+		_, err := client.Get(context.Background(), "")
+		require.Error(t, err)
+		require.Empty(t, updatedAfter[len(updatedAfter)-1], "the whole config is fetched")
+	})
+}
+
 func Test_Namespace_Identity(t *testing.T) {
 	config.Reset()
 	logger.Reset()
@@ -3,6 +3,7 @@ package backendconfig
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/services/controlplane/identity"
+	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/httputil"
 	"github.com/rudderlabs/rudder-server/utils/types"
 )
@@ -27,6 +29,10 @@ type singleWorkspaceConfig struct {
 
 	workspaceIDOnce sync.Once
 	workspaceID     string
+
+	mu         sync.Mutex
+	etag       string             // ETag of the last config, see namespaceConfig
+	lastConfig map[string]ConfigT // last config
 }
 
 func (wc *singleWorkspaceConfig) SetUp() error {
@@ -65,14 +71,21 @@ func (wc *singleWorkspaceConfig) getFromAPI(ctx context.Context, _ string) (map[
 		return config, fmt.Errorf("single workspace: config backend url is nil")
 	}
 
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
 	var (
 		respBody []byte
+		etag     string
 		u        = fmt.Sprintf("%s/workspaceConfig?fetchAll=true", wc.configBackendURL)
 	)
 
 	operation := func() error {
 		var fetchError error
-		respBody, fetchError = wc.makeHTTPRequest(ctx, u)
+		respBody, etag, fetchError = wc.makeHTTPRequest(ctx, u)
+		if errors.Is(fetchError, errConfigNotModified) {
+			return backoff.Permanent(fetchError)
+		}
 		return fetchError
 	}
 
@@ -80,6 +93,9 @@ func (wc *singleWorkspaceConfig) getFromAPI(ctx context.Context, _ string) (map[
 	err := backoff.RetryNotify(operation, backoffWithMaxRetry, func(err error, t time.Duration) {
 		pkgLogger.Warnf("Failed to fetch config from API with error: %v, retrying after %v", err, t)
 	})
+	if errors.Is(err, errConfigNotModified) {
+		return copyConfig(wc.lastConfig), nil
+	}
 	if err != nil {
 		if ctx.Err() == nil {
 			pkgLogger.Errorf("Error sending request to the server: %v", err)
@@ -105,7 +121,9 @@ func (wc *singleWorkspaceConfig) getFromAPI(ctx context.Context, _ string) (map[
 	})
 	config[workspaceID] = sourcesJSON
 
-	return config, nil
+	wc.etag = etag
+	wc.lastConfig = config
+	return copyConfig(config), nil
 }
 
 // getFromFile reads the workspace config from JSON file
@@ -131,37 +149,45 @@ func (wc *singleWorkspaceConfig) getFromFile() (map[string]ConfigT, error) {
 	return config, nil
 }
 
-func (wc *singleWorkspaceConfig) makeHTTPRequest(ctx context.Context, url string) ([]byte, error) {
+// makeHTTPRequest returns the body of the response to the request and its ETag, errConfigNotModified if the config
+// isn't modified since the last one
+func (wc *singleWorkspaceConfig) makeHTTPRequest(ctx context.Context, url string) ([]byte, string, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, http.NoBody)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	req.SetBasicAuth(wc.token, "")
 	req.Header.Set("Content-Type", "application/json")
+	if wc.etag != "" {
+		req.Header.Set("If-None-Match", wc.etag)
+	}
 	if wc.region != "" {
 		q := req.URL.Query()
 		q.Add("region", wc.region)
 		req.URL.RawQuery = q.Encode()
 	}
 
-	client := &http.Client{Timeout: config.GetDuration("HttpClient.backendConfig.timeout", 30, time.Second)}
+	client := stats.InstrumentHTTPClient(stats.Default, &http.Client{Timeout: config.GetDuration("HttpClient.backendConfig.timeout", 30, time.Second)}, stats.Tags{"module": "backend-config"})
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	defer func() { httputil.CloseResponse(resp) }()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, "", errConfigNotModified
+	}
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if resp.StatusCode >= 300 {
-		return nil, getNotOKError(respBody, resp.StatusCode)
+		return nil, "", getNotOKError(respBody, resp.StatusCode)
 	}
 
-	return respBody, nil
+	return respBody, resp.Header.Get("ETag"), nil
 }
 
 func (wc *singleWorkspaceConfig) Identity() identity.Identifier {
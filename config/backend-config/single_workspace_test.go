@@ -51,6 +51,36 @@ func TestSingleWorkspaceGetFromAPI(t *testing.T) {
 		}, ident)
 	})
 
+	t.Run("not modified", func(t *testing.T) {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			js, err := json.Marshal(sampleBackendConfig)
+			require.NoError(t, err)
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write(js)
+		}))
+		t.Cleanup(srv.Close)
+
+		parsedSrvURL, err := url.Parse(srv.URL)
+		require.NoError(t, err)
+
+		wc := &singleWorkspaceConfig{
+			token:            "testToken",
+			configBackendURL: parsedSrvURL,
+		}
+		for i := 0; i < 2; i++ {
+			conf, err := wc.getFromAPI(context.Background(), "")
+			require.NoError(t, err)
+			require.Equal(t, map[string]ConfigT{sampleWorkspaceID: sampleBackendConfig}, conf)
+		}
+		require.Equal(t, 2, requests, "the config not modified isn't requested again")
+	})
+
 	t.Run("invalid url", func(t *testing.T) {
 		configBackendURL, err := url.Parse("")
 		require.NoError(t, err)
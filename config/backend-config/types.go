@@ -1,6 +1,8 @@
 package backendconfig
 
 import (
+	"time"
+
 	"github.com/rudderlabs/rudder-server/utils/misc"
 )
 
@@ -89,10 +91,30 @@ type ConfigT struct {
 	Libraries       LibrariesT      `json:"libraries"`
 	ConnectionFlags ConnectionFlags `json:"flags"`
 	Settings        Settings        `json:"settings"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
 }
 
 type Settings struct {
 	DataRetention DataRetention `json:"dataRetention"`
+	RudderStorage RudderStorage `json:"rudderStorage"`
+	DataResidency string        `json:"dataResidency"` // region the data of the workspace resides in, e.g. EU or US
+	Quotas        Quotas        `json:"quotas"`
+	Paused        bool          `json:"paused"` // kill switch stopping the workspace across the gateway, processor, routers and warehouse
+}
+
+// Quotas limit the resources used by a workspace, zero values meaning unlimited
+type Quotas struct {
+	EventsPerSecond            float64 `json:"eventsPerSecond"`            // events accepted by the gateway
+	ConcurrentWarehouseUploads int     `json:"concurrentWarehouseUploads"` // warehouse uploads running at once
+	RouterThroughputShare      float64 `json:"routerThroughputShare"`      // share of the jobs picked up by routers, between 0 and 1
+}
+
+// RudderStorage holds the credentials and prefix scoped to the workspace for accessing rudder managed object storage.
+// Empty fields fall back to the credentials shared by all workspaces.
+type RudderStorage struct {
+	AccessKeyID string `json:"accessKeyId"`
+	AccessKey   string `json:"accessKey"`
+	Prefix      string `json:"prefix"`
 }
 
 type DataRetention struct {
@@ -113,6 +135,7 @@ type StoragePreferences struct {
 	ProcErrorDumps   bool `json:"procErrorDumps"`
 	RouterDumps      bool `json:"routerDumps"`
 	BatchRouterDumps bool `json:"batchRouterDumps"`
+	DebuggerCaptures bool `json:"debuggerCaptures"` // mirror the captures of the debuggers, see debugger.Exporter
 }
 
 func (sp StoragePreferences) Backup(tableprefix string) bool {
@@ -32,6 +32,7 @@ type dumpsLoaderHandleT struct {
 	procError     *ProcErrorRequestHandler
 	gwReplay      *GWReplayRequestHandler
 	uploader      filemanager.FileManager
+	progress      *progressT
 }
 
 // ProcErrorRequestHandler is an empty struct to capture Proc Error re-stream request handling functionality
@@ -150,6 +151,7 @@ func (gwHandle *GWReplayRequestHandler) fetchDumpsList(ctx context.Context) {
 		}
 		if len(objects) >= int(uploadMaxItems) {
 			storeJobs(ctx, objects, gwHandle.handle.dbHandle, gwHandle.handle.log)
+			gwHandle.handle.progress.dumpListed(len(objects))
 			objects = nil
 		}
 	}
@@ -158,6 +160,7 @@ func (gwHandle *GWReplayRequestHandler) fetchDumpsList(ctx context.Context) {
 	}
 	if len(objects) != 0 {
 		storeJobs(ctx, objects, gwHandle.handle.dbHandle, gwHandle.handle.log)
+		gwHandle.handle.progress.dumpListed(len(objects))
 		objects = nil
 	}
 
@@ -206,6 +209,7 @@ func (procHandle *ProcErrorRequestHandler) fetchDumpsList(ctx context.Context) {
 		}
 		if len(objects) >= int(uploadMaxItems) {
 			storeJobs(ctx, objects, procHandle.handle.dbHandle, procHandle.handle.log)
+			procHandle.handle.progress.dumpListed(len(objects))
 			objects = nil
 		}
 
@@ -215,6 +219,7 @@ func (procHandle *ProcErrorRequestHandler) fetchDumpsList(ctx context.Context) {
 	}
 	if len(objects) != 0 {
 		storeJobs(ctx, objects, procHandle.handle.dbHandle, procHandle.handle.log)
+		procHandle.handle.progress.dumpListed(len(objects))
 	}
 
 	procHandle.handle.log.Info("Dumps loader job is done")
@@ -0,0 +1,126 @@
+package replay
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The jobs replayed from a dump are stored in chunks, each one in the same transaction as the number of jobs of the dump
+// stored so far, so that a replay interrupted in the middle of a dump resumes right after the last chunk stored, without
+// replaying its jobs twice.
+
+const progressTable = "replay_progress"
+
+// progressT tracks the progress of the replay of the dumps
+type progressT struct {
+	db *sql.DB
+
+	dumpsListed    int64
+	dumpsReplayed  int64
+	eventsReplayed int64
+	jobsReplayed   int64
+
+	mu             sync.Mutex
+	lastDump       string
+	lastReplayedAt time.Time
+}
+
+func newProgress(ctx context.Context, db *sql.DB) (*progressT, error) {
+	_, err := db.ExecContext(ctx, `create table `+progressTable+` (
+		location text primary key,
+		jobs integer not null,
+		updated_at timestamptz not null default now()
+	)`)
+	if err != nil {
+		var pqError *pq.Error
+		if !(errors.As(err, &pqError) && pqError.Code == "42P07") {
+			return nil, fmt.Errorf("creating the %s table: %w", progressTable, err)
+		}
+	}
+	return &progressT{db: db}, nil
+}
+
+// storedJobs returns the number of jobs of the dump at the given location stored so far
+func (p *progressT) storedJobs(ctx context.Context, location string) (int, error) {
+	var jobs int
+	err := p.db.QueryRowContext(ctx, `select jobs from `+progressTable+` where location = $1`, location).Scan(&jobs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return jobs, err
+}
+
+// setStoredJobs sets the number of jobs of the dump at the given location stored so far, in the given transaction
+func (*progressT) setStoredJobs(ctx context.Context, tx *sql.Tx, location string, jobs int) error {
+	_, err := tx.ExecContext(ctx, `insert into `+progressTable+` (location, jobs) values ($1, $2)
+		on conflict (location) do update set jobs = excluded.jobs, updated_at = now()`, location, jobs)
+	return err
+}
+
+func (p *progressT) dumpListed(count int) {
+	atomic.AddInt64(&p.dumpsListed, int64(count))
+}
+
+func (p *progressT) chunkReplayed(jobs, events int) {
+	atomic.AddInt64(&p.jobsReplayed, int64(jobs))
+	atomic.AddInt64(&p.eventsReplayed, int64(events))
+}
+
+func (p *progressT) dumpReplayed(location string) {
+	atomic.AddInt64(&p.dumpsReplayed, 1)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastDump = location
+	p.lastReplayedAt = time.Now()
+}
+
+// StatusT is the progress of the replay since it was started
+type StatusT struct {
+	ListingDone    bool      `json:"listingDone"`
+	DumpsListed    int64     `json:"dumpsListed"`
+	DumpsReplayed  int64     `json:"dumpsReplayed"`
+	JobsReplayed   int64     `json:"jobsReplayed"`
+	EventsReplayed int64     `json:"eventsReplayed"`
+	LastDump       string    `json:"lastDump,omitempty"`
+	LastReplayedAt time.Time `json:"lastReplayedAt,omitempty"`
+}
+
+type ReplayRPCHandler struct {
+	handler *Handler
+}
+
+// Status returns the progress of the replay
+func (h *ReplayRPCHandler) Status(_ string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.handler.log.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	p := h.handler.progress
+	p.mu.Lock()
+	status := StatusT{
+		ListingDone:    h.handler.dumpsLoader.done,
+		DumpsListed:    atomic.LoadInt64(&p.dumpsListed),
+		DumpsReplayed:  atomic.LoadInt64(&p.dumpsReplayed),
+		JobsReplayed:   atomic.LoadInt64(&p.jobsReplayed),
+		EventsReplayed: atomic.LoadInt64(&p.eventsReplayed),
+		LastDump:       p.lastDump,
+		LastReplayedAt: p.lastReplayedAt,
+	}
+	p.mu.Unlock()
+	response, err := json.MarshalIndent(status, "", " ")
+	if err != nil {
+		return err
+	}
+	*result = string(response)
+	return nil
+}
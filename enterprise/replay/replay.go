@@ -4,8 +4,11 @@ import (
 	"context"
 	"math/rand"
 	"sort"
+	"strings"
 	"time"
 
+	"golang.org/x/time/rate"
+
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/jobsdb"
 	"github.com/rudderlabs/rudder-server/processor/transformer"
@@ -25,8 +28,16 @@ type Handler struct {
 	tablePrefix              string
 	uploader                 filemanager.FileManager
 	initSourceWorkersChannel chan bool
+	progress                 *progressT
+	chunkSize                int
+	limiter                  *rate.Limiter // nil if the rate is unlimited
+	destinationIDs           []string      // the destinations replayed gateway jobs are restricted to, if any
 }
 
+// replayDestinationsParam is the parameter of replayed gateway jobs restricting them to the given destinations, read by
+// the processor
+const replayDestinationsParam = "replay_destination_ids"
+
 func (handle *Handler) generatorLoop(ctx context.Context) {
 	handle.log.Infof("generator reading from replay_jobs_* started")
 	var breakLoop bool
@@ -147,8 +158,22 @@ func (handle *Handler) initSourceWorkers(ctx context.Context) {
 	handle.initSourceWorkersChannel <- true
 }
 
-func (handle *Handler) Setup(ctx context.Context, dumpsLoader *dumpsLoaderHandleT, db, toDB *jobsdb.HandleT, tablePrefix string, uploader filemanager.FileManager, bucket string, log logger.Logger) {
+func (handle *Handler) Setup(ctx context.Context, dumpsLoader *dumpsLoaderHandleT, db, toDB *jobsdb.HandleT, tablePrefix string, uploader filemanager.FileManager, bucket string, progress *progressT, log logger.Logger) {
 	handle.log = log
+	handle.progress = progress
+	handle.chunkSize = config.GetInt("REPLAY_CHUNK_SIZE", 100)
+	if eventsPerSecond := config.GetInt("REPLAY_EVENTS_PER_SECOND", 0); eventsPerSecond > 0 {
+		handle.limiter = rate.NewLimiter(rate.Limit(eventsPerSecond), eventsPerSecond)
+	}
+	for _, destinationID := range strings.Split(config.GetString("REPLAY_DESTINATION_IDS", ""), ",") {
+		if destinationID = strings.TrimSpace(destinationID); destinationID != "" {
+			handle.destinationIDs = append(handle.destinationIDs, destinationID)
+		}
+	}
+	if len(handle.destinationIDs) > 0 && toDB.Identifier() != "gw" {
+		log.Warnf("REPLAY_DESTINATION_IDS is ignored when replaying to %s", toDB.Identifier())
+		handle.destinationIDs = nil
+	}
 	handle.db = db
 	handle.toDB = toDB
 	handle.bucket = bucket
@@ -162,3 +187,22 @@ func (handle *Handler) Setup(ctx context.Context, dumpsLoader *dumpsLoaderHandle
 	go handle.initSourceWorkers(ctx)
 	go handle.generatorLoop(ctx)
 }
+
+// waitForEvents blocks until n events can be replayed within the rate, waiting for the tokens in bursts if n exceeds
+// the burst of the limiter
+func (handle *Handler) waitForEvents(ctx context.Context, n int) error {
+	if handle.limiter == nil {
+		return nil
+	}
+	for n > 0 {
+		tokens := handle.limiter.Burst()
+		if n < tokens {
+			tokens = n
+		}
+		if err := handle.limiter.WaitN(ctx, tokens); err != nil {
+			return err
+		}
+		n -= tokens
+	}
+	return nil
+}
@@ -2,8 +2,12 @@ package replay
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"strings"
 
+	"github.com/rudderlabs/rudder-server/admin"
+
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/jobsdb"
 	"github.com/rudderlabs/rudder-server/services/filemanager"
@@ -58,6 +62,22 @@ func setup(ctx context.Context, replayDB, gwDB, routerDB, batchRouterDB *jobsdb.
 		return err
 	}
 
+	db, err := sql.Open("postgres", misc.GetConnectionString())
+	if err != nil {
+		return fmt.Errorf("opening the replay progress database: %w", err)
+	}
+	progress, err := newProgress(ctx, db)
+	if err != nil {
+		_ = db.Close()
+		return err
+	}
+	// the replay lasts as long as the server, the progress being recorded until then
+	go func() {
+		<-ctx.Done()
+		_ = db.Close()
+	}()
+
+	dumpsLoader.progress = progress
 	dumpsLoader.Setup(ctx, replayDB, tablePrefix, uploader, bucket, log)
 
 	var replayer Handler
@@ -73,7 +93,8 @@ func setup(ctx context.Context, replayDB, gwDB, routerDB, batchRouterDB *jobsdb.
 		toDB = routerDB
 	}
 	_ = toDB.Start()
-	replayer.Setup(ctx, &dumpsLoader, replayDB, toDB, tablePrefix, uploader, bucket, log)
+	replayer.Setup(ctx, &dumpsLoader, replayDB, toDB, tablePrefix, uploader, bucket, progress, log)
+	admin.RegisterAdminHandler("Replay", &ReplayRPCHandler{handler: &replayer})
 	return nil
 }
 
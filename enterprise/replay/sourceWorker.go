@@ -22,6 +22,7 @@ import (
 	"github.com/rudderlabs/rudder-server/processor/transformer"
 	"github.com/rudderlabs/rudder-server/services/filemanager"
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 type SourceWorkerT struct {
@@ -41,7 +42,11 @@ func (worker *SourceWorkerT) workerProcess(ctx context.Context) {
 	for job := range worker.channel {
 		worker.log.Debugf("job received: %s", job.EventPayload)
 
-		worker.replayJobsInFile(ctx, gjson.GetBytes(job.EventPayload, "location").String())
+		if err := worker.replayJobsInFile(ctx, gjson.GetBytes(job.EventPayload, "location").String()); err != nil {
+			// the context is cancelled, the dump is replayed from where it was left on restart
+			worker.log.Infof("worker %d stopped replaying: %v", worker.workerID, err)
+			return
+		}
 
 		status := jobsdb.JobStatusT{
 			JobID:         job.JobID,
@@ -59,7 +64,7 @@ func (worker *SourceWorkerT) workerProcess(ctx context.Context) {
 	}
 }
 
-func (worker *SourceWorkerT) replayJobsInFile(ctx context.Context, filePath string) {
+func (worker *SourceWorkerT) replayJobsInFile(ctx context.Context, filePath string) error {
 	filePathTokens := strings.Split(filePath, "/")
 
 	var err error
@@ -210,15 +215,63 @@ func (worker *SourceWorkerT) replayJobsInFile(ctx context.Context, filePath stri
 	}
 	worker.log.Infof("brt-debug: TO_DB=%s", worker.replayHandler.toDB.Identifier())
 
-	err = worker.replayHandler.toDB.Store(ctx, jobs)
+	err = os.Remove(path)
 	if err != nil {
-		panic(err)
+		worker.log.Errorf("[%s]: failed to remove file with error: %w", err)
 	}
 
-	err = os.Remove(path)
+	return worker.storeJobs(ctx, filePath, jobs)
+}
+
+// storeJobs stores the jobs replayed from the dump at the given location in chunks, at the configured rate, skipping the
+// ones stored before the replay was interrupted. It only returns an error if the context is cancelled.
+func (worker *SourceWorkerT) storeJobs(ctx context.Context, location string, jobs []*jobsdb.JobT) error {
+	handler := worker.replayHandler
+	stored, err := handler.progress.storedJobs(ctx, location)
 	if err != nil {
-		worker.log.Errorf("[%s]: failed to remove file with error: %w", err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		panic(err)
+	}
+	if stored > 0 {
+		worker.log.Infof("resuming the replay of %s after %d jobs", location, stored)
+	}
+	for start := stored; start < len(jobs); start += handler.chunkSize {
+		end := start + handler.chunkSize
+		if end > len(jobs) {
+			end = len(jobs)
+		}
+		chunk := jobs[start:end]
+		var events int
+		for _, job := range chunk {
+			if job.EventCount = int(gjson.GetBytes(job.EventPayload, "batch.#").Int()); job.EventCount == 0 {
+				job.EventCount = 1
+			}
+			events += job.EventCount
+			if len(handler.destinationIDs) > 0 {
+				job.Parameters, _ = sjson.SetBytes(job.Parameters, replayDestinationsParam, handler.destinationIDs)
+			}
+		}
+		if err := handler.waitForEvents(ctx, events); err != nil {
+			return err
+		}
+		err := handler.toDB.WithStoreSafeTx(ctx, func(tx jobsdb.StoreSafeTx) error {
+			if err := handler.toDB.StoreInTx(ctx, tx, chunk); err != nil {
+				return err
+			}
+			return handler.progress.setStoredJobs(ctx, tx.SqlTx(), location, end)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			panic(err)
+		}
+		handler.progress.chunkReplayed(len(chunk), events)
 	}
+	handler.progress.dumpReplayed(location)
+	return nil
 }
 
 const (
@@ -86,7 +86,8 @@ func (r *SqlRunner) getAvgBatchSize() (float64, error) {
 	var batchSize sql.NullFloat64
 	var avgBatchSize float64
 	var err error
-	avgBatchSizeStmt := fmt.Sprintf(`select avg(jsonb_array_length(batch)) from (select event_payload->'batch' as batch from %s) t`, r.jobTableName)
+	// the event count of gateway jobs is the size of their batch, their payloads being possibly compressed
+	avgBatchSizeStmt := fmt.Sprintf(`select avg(event_count) from %s`, r.jobTableName)
 	err = runSQL(r, avgBatchSizeStmt, &batchSize)
 	if batchSize.Valid {
 		avgBatchSize = batchSize.Float64
@@ -257,6 +258,19 @@ func (g *GatewayRPCHandler) GetJobIDStatus(arg string, result *string) (err erro
 	return err
 }
 
+// SearchJobs returns the jobs of the events matching the search given as JSON argument, along with their status history
+func (g *GatewayRPCHandler) SearchJobs(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	response, err := g.readOnlyJobsDB.SearchJobs(arg)
+	*result = response
+	return err
+}
+
 func runSQL(runner *SqlRunner, query string, receiver interface{}) error {
 	row := runner.dbHandle.QueryRow(query)
 	err := row.Scan(receiver)
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// Reasons of shedding requests
+const (
+	// admissionReasonWriteLatency sheds requests while gateway jobsdb writes are slower than Gateway.admission.maxWriteLatency
+	admissionReasonWriteLatency = "writeLatency"
+	// admissionReasonPendingJobs sheds requests while more than Gateway.admission.maxPendingJobs jobs are waiting to be written to gateway jobsdb
+	admissionReasonPendingJobs = "pendingJobs"
+)
+
+// admissionController monitors the health of gateway jobsdb writes, for requests to be shed while it is unhealthy,
+// so that clients retry them later instead of requests piling up in gateway until they time out.
+//
+// Write latency is a moving average, which only sheds requests for Gateway.admission.retryAfter after the last write:
+// requests are admitted again afterwards, for their writes to tell whether jobsdb recovered.
+type admissionController struct {
+	pendingJobs int64
+
+	latencyMu    sync.RWMutex
+	writeLatency time.Duration
+	lastWriteAt  time.Time
+}
+
+// observeWrite records the latency of a gateway jobsdb write
+func (a *admissionController) observeWrite(latency time.Duration) {
+	a.latencyMu.Lock()
+	defer a.latencyMu.Unlock()
+	if a.lastWriteAt.IsZero() {
+		a.writeLatency = latency
+	} else {
+		a.writeLatency = (7*a.writeLatency + 3*latency) / 10
+	}
+	a.lastWriteAt = time.Now()
+}
+
+// addPendingJobs adds delta to the number of jobs waiting to be written to gateway jobsdb
+func (a *admissionController) addPendingJobs(delta int) {
+	atomic.AddInt64(&a.pendingJobs, int64(delta))
+}
+
+// admit tells whether a request can be admitted, along with the reason of shedding it otherwise
+func (a *admissionController) admit() (reason string, admitted bool) {
+	if admissionMaxPendingJobs > 0 && atomic.LoadInt64(&a.pendingJobs) > int64(admissionMaxPendingJobs) {
+		return admissionReasonPendingJobs, false
+	}
+	a.latencyMu.RLock()
+	defer a.latencyMu.RUnlock()
+	if admissionMaxWriteLatency > 0 && a.writeLatency > admissionMaxWriteLatency && time.Since(a.lastWriteAt) < admissionRetryAfter {
+		return admissionReasonWriteLatency, false
+	}
+	return "", true
+}
+
+// admitRequest tells whether a request of reqType is admitted, counting it as shed otherwise
+func (gateway *HandleT) admitRequest(reqType string) bool {
+	if gateway.admission == nil {
+		return true
+	}
+	reason, admitted := gateway.admission.admit()
+	if !admitted {
+		gateway.stats.NewTaggedStat("gateway.shed_requests", stats.CountType, stats.Tags{
+			"reqType": reqType,
+			"reason":  reason,
+		}).Increment()
+	}
+	return admitted
+}
+
+// shedRequest tells whether a web request of reqType is shed, in which case its response tells the client when to retry it
+func (gateway *HandleT) shedRequest(w http.ResponseWriter, reqType string) bool {
+	if gateway.admitRequest(reqType) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(admissionRetryAfter.Seconds()))))
+	return true
+}
@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestAdmissionController(t *testing.T) {
+	prevLatency, prevPendingJobs, prevRetryAfter := admissionMaxWriteLatency, admissionMaxPendingJobs, admissionRetryAfter
+	admissionMaxWriteLatency, admissionMaxPendingJobs, admissionRetryAfter = time.Second, 10, 100*time.Millisecond
+	defer func() {
+		admissionMaxWriteLatency, admissionMaxPendingJobs, admissionRetryAfter = prevLatency, prevPendingJobs, prevRetryAfter
+	}()
+
+	t.Run("pending jobs", func(t *testing.T) {
+		a := &admissionController{}
+		a.addPendingJobs(10)
+		_, admitted := a.admit()
+		require.True(t, admitted)
+
+		a.addPendingJobs(1)
+		reason, admitted := a.admit()
+		require.False(t, admitted)
+		require.Equal(t, admissionReasonPendingJobs, reason)
+
+		a.addPendingJobs(-11)
+		_, admitted = a.admit()
+		require.True(t, admitted)
+	})
+
+	t.Run("write latency", func(t *testing.T) {
+		a := &admissionController{}
+		a.observeWrite(100 * time.Millisecond)
+		_, admitted := a.admit()
+		require.True(t, admitted)
+
+		a.observeWrite(5 * time.Second)
+		reason, admitted := a.admit()
+		require.False(t, admitted)
+		require.Equal(t, admissionReasonWriteLatency, reason)
+
+		require.Eventually(t, func() bool {
+			_, admitted := a.admit()
+			return admitted
+		}, time.Second, 10*time.Millisecond, "requests should be admitted again once retryAfter elapsed since the last write")
+
+		for i := 0; i < 10; i++ {
+			a.observeWrite(100 * time.Millisecond)
+		}
+		_, admitted = a.admit()
+		require.True(t, admitted)
+	})
+
+	t.Run("shed requests", func(t *testing.T) {
+		store := memstats.New()
+		gateway := &HandleT{stats: store, logger: logger.NOP}
+		w := httptest.NewRecorder()
+		require.False(t, gateway.shedRequest(w, "batch"), "requests are admitted without admission control")
+
+		gateway.admission = &admissionController{}
+		gateway.admission.addPendingJobs(20)
+		require.True(t, gateway.shedRequest(w, "batch"))
+		require.Equal(t, "1", w.Header().Get("Retry-After"))
+		require.False(t, gateway.admitRequest("track"))
+		require.EqualValues(t, 1, store.Get("gateway.shed_requests", stats.Tags{
+			"reqType": "batch",
+			"reason":  admissionReasonPendingJobs,
+		}).LastValue())
+	})
+}
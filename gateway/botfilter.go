@@ -0,0 +1,301 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// Modes of handling events suspected to be sent by bots
+const (
+	// botFilterModeDrop drops suspected events, storing the rest of the request
+	botFilterModeDrop = "drop"
+	// botFilterModeTag stores suspected events, tagged with the reason of the suspicion under context.botFilter
+	botFilterModeTag = "tag"
+)
+
+// Reasons of suspecting an event to be sent by a bot
+const (
+	botFilterReasonUserAgent = "userAgent"
+	botFilterReasonIP        = "ip"
+	botFilterReasonRule      = "rule"
+)
+
+// defaultBotUserAgents are the patterns of the user agents of well known crawlers, monitoring and headless browsers,
+// suspected to be bots by default. Patterns name the bots rather than match any "bot" in user agents, which would
+// suspect legit ones too, e.g. the ones of Cubot phones. HTTP client libraries aren't suspected, since server side
+// SDKs send events through them.
+var defaultBotUserAgents = []string{
+	// search engines
+	`googlebot`, `adsbot-google`, `mediapartners-google`, `google-inspectiontool`, `storebot-google`, `bingbot`,
+	`bingpreview`, `msnbot`, `yahoo! slurp`, `duckduckbot`, `baiduspider`, `yandex(bot|images|metrika|mobilebot)`,
+	`sogou (web|inst) spider`, `exabot`, `seznambot`, `naverbot`, `yeti/`, `applebot`, `petalbot`, `qwantify`,
+	// link previews of social networks and messengers
+	`facebookexternalhit`, `facebookcatalog`, `facebot`, `twitterbot`, `linkedinbot`, `pinterestbot`, `slackbot`,
+	`discordbot`, `telegrambot`, `whatsapp/`, `skypeuripreview`, `redditbot`, `embedly`,
+	// seo tools, archivers and ai crawlers
+	`ahrefsbot`, `semrushbot`, `mj12bot`, `dotbot`, `rogerbot`, `blexbot`, `seokicks`, `serpstatbot`, `dataforseobot`,
+	`ia_archiver`, `archive\.org_bot`, `ccbot`, `gptbot`, `chatgpt-user`, `claudebot`, `anthropic-ai`, `bytespider`,
+	`amazonbot`, `perplexitybot`, `diffbot`,
+	// monitoring
+	`pingdom`, `uptimerobot`, `statuscake`, `site24x7`, `newrelicpinger`, `datadog(hq)?/synthetics`,
+	// headless browsers and automation
+	`headlesschrome`, `phantomjs`, `chrome-lighthouse`, `lighthouse`, `selenium`, `puppeteer`, `playwright`,
+	// generic crawler names, as whole words
+	`\b(ro)?bot\b`, `\bcrawler\b`, `\bspider\b`,
+}
+
+// botFilter tells whether an event received from ipAddr is suspected to be sent by a bot, along with the reason of the suspicion
+type botFilter interface {
+	suspect(ipAddr string, event map[string]interface{}) (reason string, suspected bool)
+}
+
+// botFilterConfig is the per source bot filtering config, declared under the source config's botFiltering key:
+//
+//	"botFiltering": {
+//	  "enabled": true,
+//	  "mode": "drop",
+//	  "userAgents": ["headlesschrome"],
+//	  "blockedIPs": ["203.0.113.0/24"],
+//	  "rules": [{"field": "context.page.referrer", "pattern": "spam\\.example"}]
+//	}
+//
+// User agents are regular expressions matched against context.userAgent case insensitively. They and blocked IPs are
+// checked in addition to the ones configured for all sources, defaultBotUserAgents unless configured otherwise.
+type botFilterConfig struct {
+	Enabled    bool            `json:"enabled"`
+	Mode       string          `json:"mode"`
+	UserAgents []string        `json:"userAgents"`
+	BlockedIPs []string        `json:"blockedIPs"`
+	Rules      []botFilterRule `json:"rules"`
+}
+
+// botFilterRule suspects events whose field, in dot notation, matches pattern
+type botFilterRule struct {
+	Field   string `json:"field"`
+	Pattern string `json:"pattern"`
+}
+
+// sourceBotFilter runs the bot filters of a source
+type sourceBotFilter struct {
+	mode    string
+	filters []botFilter
+}
+
+// newSourceBotFilter builds the bot filters of a source, along with the user agents and blocked IPs configured for all sources.
+// It returns nil if the source doesn't enable bot filtering.
+func newSourceBotFilter(sourceConfig map[string]interface{}) (*sourceBotFilter, error) {
+	var filterConfig botFilterConfig
+	ok, err := parseSourceConfig(sourceConfig, "botFiltering", &filterConfig)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if !filterConfig.Enabled {
+		return nil, nil
+	}
+
+	botFilter := &sourceBotFilter{mode: filterConfig.Mode}
+	switch botFilter.mode {
+	case botFilterModeDrop, botFilterModeTag:
+	case "":
+		botFilter.mode = botFilterModeTag
+	default:
+		return nil, fmt.Errorf("unknown bot filtering mode: %q", filterConfig.Mode)
+	}
+
+	userAgents := make([]string, 0, len(botUserAgents)+len(filterConfig.UserAgents))
+	userAgents = append(userAgents, botUserAgents...)
+	userAgents = append(userAgents, filterConfig.UserAgents...)
+	userAgentFilter, err := newUserAgentFilter(userAgents)
+	if err != nil {
+		return nil, err
+	}
+	botFilter.filters = append(botFilter.filters, userAgentFilter)
+
+	blockedIPs := make([]string, 0, len(botBlockedIPs)+len(filterConfig.BlockedIPs))
+	blockedIPs = append(blockedIPs, botBlockedIPs...)
+	blockedIPs = append(blockedIPs, filterConfig.BlockedIPs...)
+	ipFilter, err := newIPFilter(blockedIPs)
+	if err != nil {
+		return nil, err
+	}
+	botFilter.filters = append(botFilter.filters, ipFilter)
+
+	if len(filterConfig.Rules) > 0 {
+		ruleFilter, err := newRuleFilter(filterConfig.Rules)
+		if err != nil {
+			return nil, err
+		}
+		botFilter.filters = append(botFilter.filters, ruleFilter)
+	}
+	return botFilter, nil
+}
+
+// suspect returns the reason of the first filter suspecting the event, if any
+func (f *sourceBotFilter) suspect(ipAddr string, event map[string]interface{}) (string, bool) {
+	for _, filter := range f.filters {
+		if reason, suspected := filter.suspect(ipAddr, event); suspected {
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+// userAgentFilter suspects events whose context.userAgent matches any of its patterns, case insensitively
+type userAgentFilter struct {
+	pattern *regexp.Regexp // nil without patterns
+}
+
+func newUserAgentFilter(patterns []string) (*userAgentFilter, error) {
+	alternatives := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("compiling bot user agent pattern %q: %w", pattern, err)
+		}
+		alternatives = append(alternatives, "(?:"+pattern+")")
+	}
+	f := &userAgentFilter{}
+	if len(alternatives) > 0 {
+		f.pattern = regexp.MustCompile("(?i)" + strings.Join(alternatives, "|"))
+	}
+	return f, nil
+}
+
+func (f *userAgentFilter) suspect(_ string, event map[string]interface{}) (string, bool) {
+	userAgent, _ := eventField(event, "context.userAgent").(string)
+	if userAgent == "" || f.pattern == nil {
+		return "", false
+	}
+	if f.pattern.MatchString(userAgent) {
+		return botFilterReasonUserAgent, true
+	}
+	return "", false
+}
+
+// ipFilter suspects events received from, or declaring in context.ip, addresses of its blocked networks
+type ipFilter struct {
+	networks []*net.IPNet
+}
+
+func newIPFilter(blockedIPs []string) (*ipFilter, error) {
+	f := &ipFilter{networks: make([]*net.IPNet, 0, len(blockedIPs))}
+	for _, blockedIP := range blockedIPs {
+		if !strings.Contains(blockedIP, "/") {
+			if strings.Contains(blockedIP, ":") {
+				blockedIP += "/128"
+			} else {
+				blockedIP += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(blockedIP)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocked IP %q: %w", blockedIP, err)
+		}
+		f.networks = append(f.networks, network)
+	}
+	return f, nil
+}
+
+func (f *ipFilter) suspect(ipAddr string, event map[string]interface{}) (string, bool) {
+	contextIP, _ := eventField(event, "context.ip").(string)
+	for _, addr := range []string{ipAddr, contextIP} {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+		for _, network := range f.networks {
+			if network.Contains(ip) {
+				return botFilterReasonIP, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ruleFilter suspects events matching any of the custom rules of a source
+type ruleFilter struct {
+	fields   []string
+	patterns []*regexp.Regexp
+}
+
+func newRuleFilter(rules []botFilterRule) (*ruleFilter, error) {
+	f := &ruleFilter{}
+	for _, rule := range rules {
+		if rule.Field == "" {
+			return nil, fmt.Errorf("field of bot filtering rule cannot be empty")
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compiling pattern of bot filtering rule on %q: %w", rule.Field, err)
+		}
+		f.fields = append(f.fields, rule.Field)
+		f.patterns = append(f.patterns, pattern)
+	}
+	return f, nil
+}
+
+func (f *ruleFilter) suspect(_ string, event map[string]interface{}) (string, bool) {
+	for i, field := range f.fields {
+		value := eventField(event, field)
+		if value == nil {
+			continue
+		}
+		if f.patterns[i].MatchString(fmt.Sprint(value)) {
+			return botFilterReasonRule, true
+		}
+	}
+	return "", false
+}
+
+// eventField returns the value of an event's field in dot notation, nil if it is missing
+func eventField(event map[string]interface{}, field string) interface{} {
+	var value interface{} = event
+	for _, key := range strings.Split(field, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if value, ok = m[key]; !ok {
+			return nil
+		}
+	}
+	return value
+}
+
+// filterBots runs the bot filters of a source on the events of a request received from ipAddr, tagging the suspected ones
+// with the reason of their suspicion under context.botFilter. It returns the events to be stored according to the source's mode,
+// along with the suspected events.
+func (gateway *HandleT) filterBots(botFilter *sourceBotFilter, sourceTags map[string]string, ipAddr string, events []map[string]interface{}) (kept, suspected []map[string]interface{}) {
+	kept = make([]map[string]interface{}, 0, len(events))
+	for _, event := range events {
+		reason, isBot := botFilter.suspect(ipAddr, event)
+		if !isBot {
+			kept = append(kept, event)
+			continue
+		}
+
+		gateway.stats.NewTaggedStat("gateway.bot_filtered_events", stats.CountType, stats.Tags{
+			"sourceID": sourceTags["sourceID"],
+			"writeKey": sourceTags["writeKey"],
+			"reason":   reason,
+			"mode":     botFilter.mode,
+		}).Increment()
+		eventContext, ok := event["context"].(map[string]interface{})
+		if !ok {
+			eventContext = make(map[string]interface{})
+			event["context"] = eventContext
+		}
+		eventContext["botFilter"] = map[string]interface{}{"reason": reason}
+		suspected = append(suspected, event)
+		if botFilter.mode == botFilterModeTag {
+			kept = append(kept, event)
+		}
+	}
+	return kept, suspected
+}
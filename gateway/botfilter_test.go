@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestBotFiltering(t *testing.T) {
+	prevUserAgents, prevBlockedIPs := botUserAgents, botBlockedIPs
+	botUserAgents, botBlockedIPs = defaultBotUserAgents, []string{"198.51.100.7"}
+	defer func() { botUserAgents, botBlockedIPs = prevUserAgents, prevBlockedIPs }()
+
+	filterConfig := func(mode string) map[string]interface{} {
+		return sourceConfigWith(t, "botFiltering", map[string]interface{}{
+			"enabled":    true,
+			"mode":       mode,
+			"userAgents": []interface{}{"HeadlessChrome"},
+			"blockedIPs": []interface{}{"203.0.113.0/24"},
+			"rules": []interface{}{
+				map[string]interface{}{"field": "context.page.referrer", "pattern": `spam\.example`},
+			},
+		})
+	}
+	events := func() []map[string]interface{} {
+		return []map[string]interface{}{
+			{"type": "page", "context": map[string]interface{}{"userAgent": "Mozilla/5.0 (X11; Linux x86_64) Firefox/106.0"}},
+			{"type": "page", "context": map[string]interface{}{"userAgent": "Googlebot/2.1"}},
+			{"type": "page", "context": map[string]interface{}{"userAgent": "Mozilla/5.0 HeadlessChrome/107.0"}},
+			{"type": "page", "context": map[string]interface{}{"ip": "203.0.113.10"}},
+			{"type": "page", "context": map[string]interface{}{"page": map[string]interface{}{"referrer": "https://spam.example/offer"}}},
+			{"type": "track", "event": "Product Viewed"},
+		}
+	}
+	sourceTags := map[string]string{"sourceID": "source-1", "writeKey": "write-key-1"}
+
+	t.Run("not enabled", func(t *testing.T) {
+		botFilter, err := newSourceBotFilter(sourceConfigWith(t, "botFiltering", map[string]interface{}{"mode": "drop"}))
+		require.NoError(t, err)
+		require.Nil(t, botFilter)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := newSourceBotFilter(filterConfig("ignore"))
+		require.Error(t, err)
+
+		config := filterConfig(botFilterModeDrop)
+		config["botFiltering"].(map[string]interface{})["blockedIPs"] = []interface{}{"not-an-ip"}
+		_, err = newSourceBotFilter(config)
+		require.Error(t, err)
+
+		config = filterConfig(botFilterModeDrop)
+		config["botFiltering"].(map[string]interface{})["userAgents"] = []interface{}{"HeadlessChrome/(107"}
+		_, err = newSourceBotFilter(config)
+		require.Error(t, err)
+	})
+
+	t.Run("user agents", func(t *testing.T) {
+		botFilter, err := newSourceBotFilter(filterConfig(botFilterModeDrop))
+		require.NoError(t, err)
+		suspected := func(userAgent string) bool {
+			_, ok := botFilter.suspect("192.0.2.1", map[string]interface{}{"context": map[string]interface{}{"userAgent": userAgent}})
+			return ok
+		}
+
+		require.True(t, suspected("Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)"))
+		require.True(t, suspected("Mozilla/5.0 (compatible; SemrushBot/7~bl; +http://www.semrush.com/bot.html)"))
+		require.True(t, suspected("facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)"))
+		require.True(t, suspected("Mozilla/5.0 (compatible; Some Bot 1.0)"))
+		require.True(t, suspected("Mozilla/5.0 (X11; Linux x86_64) headlesschrome/107.0"))
+		require.False(t, suspected("Mozilla/5.0 (Linux; Android 10; CUBOT_X30) AppleWebKit/537.36 Chrome/106.0 Mobile Safari/537.36"))
+		require.False(t, suspected("Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) Abbott/3.2 Mobile"))
+		require.False(t, suspected("Go-http-client/1.1"))
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		store := memstats.New()
+		gateway := &HandleT{stats: store, logger: logger.NOP}
+		botFilter, err := newSourceBotFilter(filterConfig(botFilterModeDrop))
+		require.NoError(t, err)
+
+		kept, suspected := gateway.filterBots(botFilter, sourceTags, "192.0.2.1", events())
+		require.Len(t, kept, 2)
+		require.Len(t, suspected, 4)
+		require.Equal(t, map[string]interface{}{"reason": botFilterReasonUserAgent}, suspected[0]["context"].(map[string]interface{})["botFilter"])
+		require.Equal(t, map[string]interface{}{"reason": botFilterReasonIP}, suspected[2]["context"].(map[string]interface{})["botFilter"])
+		require.Equal(t, map[string]interface{}{"reason": botFilterReasonRule}, suspected[3]["context"].(map[string]interface{})["botFilter"])
+		require.EqualValues(t, 1, store.Get("gateway.bot_filtered_events", stats.Tags{
+			"sourceID": "source-1",
+			"writeKey": "write-key-1",
+			"reason":   botFilterReasonRule,
+			"mode":     botFilterModeDrop,
+		}).LastValue())
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		gateway := &HandleT{stats: memstats.New(), logger: logger.NOP}
+		botFilter, err := newSourceBotFilter(filterConfig(""))
+		require.NoError(t, err)
+
+		kept, suspected := gateway.filterBots(botFilter, sourceTags, "192.0.2.1", events())
+		require.Len(t, kept, 6)
+		require.Len(t, suspected, 4)
+		require.Contains(t, kept[1]["context"], "botFilter")
+		require.NotContains(t, kept[0]["context"], "botFilter")
+		require.NotContains(t, kept[5], "context")
+	})
+
+	t.Run("blocked request IP", func(t *testing.T) {
+		gateway := &HandleT{stats: memstats.New(), logger: logger.NOP}
+		botFilter, err := newSourceBotFilter(filterConfig(botFilterModeDrop))
+		require.NoError(t, err)
+
+		kept, suspected := gateway.filterBots(botFilter, sourceTags, "198.51.100.7", events())
+		require.Empty(t, kept)
+		require.Len(t, suspected, 6)
+	})
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+const (
+	contentEncodingGzip = "gzip"
+	contentEncodingZstd = "zstd"
+)
+
+// decompressPayload decompresses the payload of a request according to its Content-Encoding header.
+// Payloads inflating beyond maxDecompressedReqSize are rejected, so that small compressed requests can't exhaust memory.
+func (gateway *HandleT) decompressPayload(r *http.Request, payload []byte) ([]byte, error) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+
+	var reader io.Reader
+	switch encoding {
+	case "", "identity":
+		return payload, nil
+	case contentEncodingGzip:
+		gzipReader, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			gateway.logger.Errorf("Error creating gzip reader for request body: %v", err)
+			return nil, errors.New(response.RequestBodyDecompressionFailed)
+		}
+		defer func() { _ = gzipReader.Close() }()
+		reader = gzipReader
+	case contentEncodingZstd:
+		zstdReader, err := zstd.NewReader(bytes.NewReader(payload), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			gateway.logger.Errorf("Error creating zstd reader for request body: %v", err)
+			return nil, errors.New(response.RequestBodyDecompressionFailed)
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	default:
+		return nil, errors.New(response.UnsupportedContentEncoding)
+	}
+
+	decompressed, err := io.ReadAll(io.LimitReader(reader, int64(maxDecompressedReqSize)+1))
+	if err != nil {
+		gateway.logger.Errorf("Error decompressing %s request body: %v", encoding, err)
+		return nil, errors.New(response.RequestBodyDecompressionFailed)
+	}
+	if len(decompressed) > maxDecompressedReqSize {
+		gateway.stats.NewTaggedStat("gateway.decompressed_request_too_large", stats.CountType, stats.Tags{"encoding": encoding}).Increment()
+		return nil, errors.New(response.RequestBodyTooLarge)
+	}
+
+	if len(payload) > 0 {
+		gateway.stats.NewTaggedStat("gateway.request_compression_ratio", stats.HistogramType, stats.Tags{"encoding": encoding}).Observe(float64(len(decompressed)) / float64(len(payload)))
+	}
+	return decompressed, nil
+}
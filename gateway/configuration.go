@@ -27,10 +27,50 @@ func loadConfig() {
 	config.RegisterStringConfigVariable("GW", &CustomVal, false, "Gateway.CustomVal")
 	// Maximum request size to gateway
 	config.RegisterIntConfigVariable(4000, &maxReqSize, true, 1024, "Gateway.maxReqSizeInKB")
+	// Maximum size of gzip or zstd compressed request bodies once decompressed
+	config.RegisterIntConfigVariable(4000, &maxDecompressedReqSize, true, 1024, "Gateway.maxDecompressedReqSizeInKB")
+	// Accept msgpack and protobuf request bodies, converting them to JSON
+	config.RegisterBoolConfigVariable(true, &enableBinaryPayloads, true, "Gateway.enableBinaryPayloads")
 	// Enable rate limit on incoming events. false by default
 	config.RegisterBoolConfigVariable(false, &enableRateLimit, true, "Gateway.enableRateLimit")
 	// Enable suppress user feature. false by default
 	config.RegisterBoolConfigVariable(true, &enableSuppressUserFeature, false, "Gateway.enableSuppressUserFeature")
+	// Validation of events against the schemas declared by their source. true by default, only applies to sources declaring schemas
+	config.RegisterBoolConfigVariable(true, &enableEventValidation, true, "Gateway.enableEventValidation")
+	// Filtering of events suspected to be sent by bots. true by default, only applies to sources enabling bot filtering
+	config.RegisterBoolConfigVariable(true, &enableBotFiltering, true, "Gateway.enableBotFiltering")
+	// Filtering of events older than the max event age of their source. true by default, only applies to sources declaring one
+	config.RegisterBoolConfigVariable(true, &enableMaxEventAge, true, "Gateway.enableMaxEventAge")
+	// Patterns of user agents and blocked IPs or networks suspected to be bots, for all sources enabling bot filtering
+	config.RegisterStringSliceConfigVariable(defaultBotUserAgents, &botUserAgents, false, "Gateway.botFilter.userAgents")
+	config.RegisterStringSliceConfigVariable([]string{}, &botBlockedIPs, false, "Gateway.botFilter.blockedIPs")
+	// Salt of the hashes replacing client IPs, for sources anonymizing them by hashing
+	config.RegisterStringConfigVariable("", &ipHashSalt, false, "Gateway.ipAnonymization.hashSalt")
+	// Path of the MaxMind database events are geo enriched from, for sources enabling geo enrichment
+	config.RegisterStringConfigVariable("", &geoDBPath, false, "Gateway.geoEnrichment.dbPath")
+	// Dedup of events by messageId before writing them to gateway jobsdb. false by default
+	config.RegisterBoolConfigVariable(false, &enableDedup, false, "Gateway.enableDedup")
+	config.RegisterDurationConfigVariable(3600, &dedupWindow, false, time.Second, []string{"Gateway.dedup.window", "Gateway.dedup.windowInS"}...)
+	// Ingestion of events from the kafka topics declared by sources. false by default
+	config.RegisterBoolConfigVariable(false, &enableKafkaIngestion, false, "Gateway.enableKafkaIngestion")
+	config.RegisterDurationConfigVariable(10, &kafkaDialTimeout, false, time.Second, "Gateway.kafka.dialTimeout")
+	config.RegisterDurationConfigVariable(1, &kafkaFetchRetryInterval, false, time.Second, "Gateway.kafka.fetchRetryInterval")
+	// Shedding of requests with a 503 while gateway jobsdb writes are too slow or too many. false by default
+	config.RegisterBoolConfigVariable(false, &enableAdmissionControl, false, "Gateway.enableAdmissionControl")
+	config.RegisterDurationConfigVariable(5, &admissionMaxWriteLatency, true, time.Second, "Gateway.admission.maxWriteLatency")
+	config.RegisterIntConfigVariable(100000, &admissionMaxPendingJobs, true, 1, "Gateway.admission.maxPendingJobs")
+	config.RegisterDurationConfigVariable(5, &admissionRetryAfter, true, time.Second, "Gateway.admission.retryAfter")
+	// Tracking of the throughput of each source for the admin endpoint, over a sliding window. true by default
+	config.RegisterBoolConfigVariable(true, &enableThroughputTracking, false, "Gateway.enableThroughputTracking")
+	config.RegisterDurationConfigVariable(60, &throughputWindow, false, time.Second, "Gateway.throughput.window")
+	// Sampling of request payloads to the source debugger, for sources not declaring their own. Nothing is sampled by default
+	config.RegisterFloat64ConfigVariable(0, &requestSamplingRate, true, "Gateway.requestSampling.rate")
+	config.RegisterBoolConfigVariable(false, &requestSamplingErrorsOnly, true, "Gateway.requestSampling.errorsOnly")
+	config.RegisterIntConfigVariable(64, &requestSamplingMaxPayloadSize, true, 1024, "Gateway.requestSampling.maxPayloadSizeInKB")
+	// Time browsers cache the results of CORS preflight requests for
+	config.RegisterDurationConfigVariable(900, &corsMaxAge, false, time.Second, "Gateway.cors.maxAge")
+	// Per event acceptance results in the response of batch requests asking for them. true by default
+	config.RegisterBoolConfigVariable(true, &enablePerEventResponse, true, "Gateway.enablePerEventResponse")
 	// EventSchemas feature. false by default
 	config.RegisterBoolConfigVariable(false, &enableEventSchemasFeature, false, "EventSchemas.enableEventSchemasFeature")
 	// Time period for diagnosis ticker
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mkmik/multierror"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// originMatcher matches the origins a source allows cross-origin requests from, declared under the source config's allowedOrigins key:
+//
+//	"allowedOrigins": ["https://www.example.com", "https://*.example.org"]
+//
+// Wildcards match any subdomain of their domain. Sources not declaring allowed origins, or allowing "*", allow all of them.
+type originMatcher struct {
+	origins   map[string]struct{}
+	wildcards []wildcardOrigin
+}
+
+// wildcardOrigin matches origins of scheme whose host is a subdomain of domain, e.g. https://*.example.org
+type wildcardOrigin struct {
+	scheme string
+	domain string
+}
+
+// newOriginMatcher parses the allowed origins of a source.
+// It returns nil if the source allows all origins. Invalid origins are skipped, failing closed: the returned matcher
+// only allows the valid ones, or none at all if the allowed origins can't be parsed, along with the error.
+func newOriginMatcher(sourceConfig map[string]interface{}) (*originMatcher, error) {
+	m := &originMatcher{origins: make(map[string]struct{})}
+	var origins []string
+	if declared, err := parseSourceConfig(sourceConfig, "allowedOrigins", &origins); err != nil {
+		return m, err
+	} else if !declared || len(origins) == 0 {
+		return nil, nil
+	}
+	var errs []error
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		if origin == "*" {
+			return nil, nil
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("invalid allowed origin: %q", origin))
+			continue
+		}
+		if strings.HasPrefix(u.Host, "*.") {
+			m.wildcards = append(m.wildcards, wildcardOrigin{scheme: u.Scheme, domain: strings.TrimPrefix(u.Host, "*")})
+			continue
+		}
+		if strings.Contains(u.Host, "*") {
+			errs = append(errs, fmt.Errorf("invalid allowed origin: %q, wildcards are only allowed as the leftmost label of hosts", origin))
+			continue
+		}
+		m.origins[u.Scheme+"://"+u.Host] = struct{}{}
+	}
+	if len(errs) > 0 {
+		return m, multierror.Join(errs)
+	}
+	return m, nil
+}
+
+// allows tells whether origin is one of the allowed origins
+func (m *originMatcher) allows(origin string) bool {
+	origin = strings.ToLower(origin)
+	if _, ok := m.origins[origin]; ok {
+		return true
+	}
+	for _, wildcard := range m.wildcards {
+		host := strings.TrimPrefix(origin, wildcard.scheme+"://")
+		if host != origin && strings.HasSuffix(host, wildcard.domain) && len(host) > len(wildcard.domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestWriteKey returns the write key of a request, from its basic auth or its writeKey query parameter
+func requestWriteKey(r *http.Request) string {
+	if writeKey, _, ok := r.BasicAuth(); ok && writeKey != "" {
+		return writeKey
+	}
+	return r.URL.Query().Get("writeKey")
+}
+
+// allowOrigin tells whether cross-origin requests from origin are allowed, for CORS response headers.
+// Preflight requests don't carry their write key, so they are allowed if any source allows their origin,
+// whereas requests are only allowed if their source does.
+func (gateway *HandleT) allowOrigin(r *http.Request, origin string) bool {
+	writeKey := requestWriteKey(r)
+	if r.Method != http.MethodOptions && writeKey != "" {
+		matcher := gateway.getOriginMatcher(writeKey)
+		return matcher == nil || matcher.allows(origin)
+	}
+
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	if !restrictPreflightOrigins {
+		return true
+	}
+	for _, matcher := range writeKeyOriginMatcherMap {
+		if matcher.allows(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsMiddleware rejects cross-origin requests from origins their source doesn't allow, since CORS response headers only keep browsers
+// from reading responses, not from sending requests
+func (gateway *HandleT) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeKey := requestWriteKey(r)
+		if matcher := gateway.getOriginMatcher(writeKey); matcher != nil && !matcher.allows(origin) {
+			gateway.logger.Debugf("Blocked request to %s of source %s from origin %s", r.URL.Path, gateway.getSourceIDForWriteKey(writeKey), origin)
+			gateway.stats.NewTaggedStat("gateway.cors_blocked_requests", stats.CountType, stats.Tags{
+				"sourceID": gateway.getSourceIDForWriteKey(writeKey),
+				"writeKey": writeKey,
+			}).Increment()
+			http.Error(w, response.GetStatus(response.OriginNotAllowed), response.GetErrorStatusCode(response.OriginNotAllowed))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestOriginMatcher(t *testing.T) {
+	t.Run("all origins", func(t *testing.T) {
+		matcher, err := newOriginMatcher(map[string]interface{}{})
+		require.NoError(t, err)
+		require.Nil(t, matcher)
+
+		matcher, err = newOriginMatcher(sourceConfigWith(t, "allowedOrigins", []interface{}{"https://www.example.com", "*"}))
+		require.NoError(t, err)
+		require.Nil(t, matcher)
+	})
+
+	t.Run("invalid origins", func(t *testing.T) {
+		for _, origin := range []interface{}{"www.example.com", "https://www.example.com/path", "https://www.*.example.com", 42} {
+			matcher, err := newOriginMatcher(sourceConfigWith(t, "allowedOrigins", []interface{}{origin}))
+			require.Error(t, err, origin)
+			require.NotNil(t, matcher, "invalid origins shouldn't allow all origins")
+			require.False(t, matcher.allows("https://www.example.com"), origin)
+		}
+
+		matcher, err := newOriginMatcher(sourceConfigWith(t, "allowedOrigins", []interface{}{"https://www.example.com", "www.example.org"}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "www.example.org")
+		require.True(t, matcher.allows("https://www.example.com"), "valid origins should still be allowed")
+		require.False(t, matcher.allows("https://www.example.org"))
+		require.False(t, matcher.allows("https://evil.com"))
+	})
+
+	t.Run("allowed origins", func(t *testing.T) {
+		matcher, err := newOriginMatcher(sourceConfigWith(t, "allowedOrigins", []interface{}{"https://www.Example.com/", "https://*.example.org"}))
+		require.NoError(t, err)
+		require.True(t, matcher.allows("https://www.example.com"))
+		require.True(t, matcher.allows("https://shop.example.org"))
+		require.True(t, matcher.allows("https://eu.shop.example.org"))
+		require.False(t, matcher.allows("http://www.example.com"))
+		require.False(t, matcher.allows("https://example.org"))
+		require.False(t, matcher.allows("http://shop.example.org"))
+		require.False(t, matcher.allows("https://shopexample.org"))
+		require.False(t, matcher.allows("https://www.example.com.evil.com"))
+	})
+}
+
+func TestCORS(t *testing.T) {
+	restricted, err := newOriginMatcher(sourceConfigWith(t, "allowedOrigins", []interface{}{"https://www.example.com"}))
+	require.NoError(t, err)
+	misconfigured, err := newOriginMatcher(sourceConfigWith(t, "allowedOrigins", []interface{}{"www.example.com"}))
+	require.Error(t, err)
+	configSubscriberLock.Lock()
+	prevOriginMatcherMap, prevRestrictPreflightOrigins, prevWriteKeysSourceMap := writeKeyOriginMatcherMap, restrictPreflightOrigins, writeKeysSourceMap
+	writeKeyOriginMatcherMap = map[string]*originMatcher{"restricted-write-key": restricted, "misconfigured-write-key": misconfigured}
+	writeKeysSourceMap = map[string]backendconfig.SourceT{"restricted-write-key": {ID: "restricted-source"}}
+	restrictPreflightOrigins = true
+	configSubscriberLock.Unlock()
+	defer func() {
+		configSubscriberLock.Lock()
+		writeKeyOriginMatcherMap, restrictPreflightOrigins, writeKeysSourceMap = prevOriginMatcherMap, prevRestrictPreflightOrigins, prevWriteKeysSourceMap
+		configSubscriberLock.Unlock()
+	}()
+
+	store := memstats.New()
+	gateway := &HandleT{stats: store, logger: logger.NOP}
+	newRequest := func(method, writeKey, origin string) *http.Request {
+		req := httptest.NewRequest(method, "/v1/track", http.NoBody)
+		if writeKey != "" {
+			req.SetBasicAuth(writeKey, "")
+		}
+		req.Header.Set("Origin", origin)
+		return req
+	}
+
+	t.Run("allow origin", func(t *testing.T) {
+		require.True(t, gateway.allowOrigin(newRequest(http.MethodPost, "restricted-write-key", "https://www.example.com"), "https://www.example.com"))
+		require.False(t, gateway.allowOrigin(newRequest(http.MethodPost, "restricted-write-key", "https://evil.com"), "https://evil.com"))
+		require.True(t, gateway.allowOrigin(newRequest(http.MethodPost, "other-write-key", "https://evil.com"), "https://evil.com"))
+		require.False(t, gateway.allowOrigin(newRequest(http.MethodPost, "misconfigured-write-key", "https://www.example.com"), "https://www.example.com"),
+			"sources with invalid allowed origins should fail closed")
+		require.True(t, gateway.allowOrigin(newRequest(http.MethodOptions, "", "https://www.example.com"), "https://www.example.com"))
+		require.False(t, gateway.allowOrigin(newRequest(http.MethodOptions, "", "https://evil.com"), "https://evil.com"))
+	})
+
+	t.Run("middleware", func(t *testing.T) {
+		handler := gateway.corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(response.Ok))
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, newRequest(http.MethodPost, "restricted-write-key", "https://www.example.com"))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, newRequest(http.MethodPost, "other-write-key", "https://evil.com"))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, newRequest(http.MethodPost, "misconfigured-write-key", "https://www.example.com"))
+		require.Equal(t, http.StatusForbidden, rr.Code, "sources with invalid allowed origins should fail closed")
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, newRequest(http.MethodPost, "restricted-write-key", "https://evil.com"))
+		require.Equal(t, http.StatusForbidden, rr.Code)
+		require.Equal(t, response.OriginNotAllowed+"\n", rr.Body.String())
+		require.EqualValues(t, 1, store.Get("gateway.cors_blocked_requests", stats.Tags{
+			"sourceID": "restricted-source",
+			"writeKey": "restricted-write-key",
+		}).LastValue())
+	})
+}
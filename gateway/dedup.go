@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/dedup"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+const (
+	dedupBackendBadger = "badger"
+	dedupBackendRedis  = "redis"
+)
+
+// newDedup returns the dedup handle gateway uses to drop events whose messageId was already received from the same source
+// within the dedup window, backed by either a local badger db or redis, if shared by multiple gateways.
+func newDedup() (dedup.DedupI, error) {
+	switch backend := config.GetString("Gateway.dedup.backend", dedupBackendBadger); backend {
+	case dedupBackendBadger:
+		tmpDirPath, err := misc.CreateTMPDIR()
+		if err != nil {
+			return nil, err
+		}
+		return dedup.New(tmpDirPath+"/gateway_dedup", dedup.WithWindow(dedupWindow)), nil
+	case dedupBackendRedis:
+		if !config.IsSet("Gateway.dedup.redis.addr") {
+			return nil, fmt.Errorf("redis address is required with dedup backend %s", backend)
+		}
+		client := redis.NewClient(&redis.Options{
+			Addr:     config.GetString("Gateway.dedup.redis.addr", "localhost:6379"),
+			Username: config.GetString("Gateway.dedup.redis.username", ""),
+			Password: config.GetString("Gateway.dedup.redis.password", ""),
+		})
+		return dedup.NewRedis(client, "gateway_dedup:", &dedupWindow), nil
+	default:
+		return nil, fmt.Errorf("unknown dedup backend: %s", backend)
+	}
+}
+
+// dedupKey identifies an event among the events of all sources
+func dedupKey(writeKey string, event map[string]interface{}) string {
+	messageID, _ := event["messageId"].(string)
+	return writeKey + ":" + messageID
+}
+
+// dropDuplicates removes the events already received, either within the dedup window or earlier in the same batch of requests.
+// It returns the remaining events along with their dedup keys, to be marked as processed once the events are stored.
+func (gateway *HandleT) dropDuplicates(writeKey string, sourceTags map[string]string, events []map[string]interface{}, batchDedupKeys map[string]struct{}) ([]map[string]interface{}, []string) {
+	keys := make([]string, len(events))
+	for i, event := range events {
+		keys[i] = dedupKey(writeKey, event)
+	}
+
+	duplicateIndexes := gateway.dedup.FindDuplicates(keys, batchDedupKeys)
+	if len(duplicateIndexes) > 0 {
+		gateway.stats.NewTaggedStat("gateway.dedup_hits", stats.CountType, stats.Tags{
+			"sourceID": sourceTags["sourceID"],
+			"writeKey": writeKey,
+		}).Count(len(duplicateIndexes))
+	}
+
+	duplicates := make(map[int]struct{}, len(duplicateIndexes))
+	for _, idx := range duplicateIndexes {
+		duplicates[idx] = struct{}{}
+	}
+	kept := make([]map[string]interface{}, 0, len(events)-len(duplicates))
+	keptKeys := make([]string, 0, len(events)-len(duplicates))
+	for i, event := range events {
+		if _, ok := duplicates[i]; ok {
+			continue
+		}
+		kept = append(kept, event)
+		keptKeys = append(keptKeys, keys[i])
+		batchDedupKeys[keys[i]] = struct{}{}
+	}
+	return kept, keptKeys
+}
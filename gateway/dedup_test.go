@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/services/dedup"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestDropDuplicates(t *testing.T) {
+	d := dedup.New(t.TempDir(), dedup.WithWindow(time.Hour))
+	defer d.Close()
+	store := memstats.New()
+	gateway := &HandleT{stats: store, logger: logger.NOP, dedup: d}
+	sourceTags := map[string]string{"sourceID": "source-1", "writeKey": "write-key-1"}
+
+	events := func(messageIDs ...string) []map[string]interface{} {
+		events := make([]map[string]interface{}, 0, len(messageIDs))
+		for _, messageID := range messageIDs {
+			events = append(events, map[string]interface{}{"messageId": messageID})
+		}
+		return events
+	}
+
+	kept, keys := gateway.dropDuplicates("write-key-1", sourceTags, events("m1", "m2", "m1"), map[string]struct{}{})
+	require.Equal(t, events("m1", "m2"), kept)
+	require.Equal(t, []string{"write-key-1:m1", "write-key-1:m2"}, keys)
+	require.NoError(t, d.MarkProcessed(keys))
+
+	kept, _ = gateway.dropDuplicates("write-key-1", sourceTags, events("m1", "m3"), map[string]struct{}{})
+	require.Equal(t, events("m3"), kept, "events received within the window are dropped")
+	require.EqualValues(t, 1, store.Get("gateway.dedup_hits", stats.Tags{"sourceID": "source-1", "writeKey": "write-key-1"}).LastValue())
+
+	kept, _ = gateway.dropDuplicates("write-key-2", sourceTags, events("m1"), map[string]struct{}{})
+	require.Equal(t, events("m1"), kept, "events of other sources are not duplicates")
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// Modes of handling events older than the max event age of their source
+const (
+	// eventAgeModeTag stores stale events, tagged with their age under context.staleEvent
+	eventAgeModeTag = "tag"
+	// eventAgeModeReject rejects the whole request with a 422 if any of its events is stale,
+	// or only the stale events for requests in per event response mode
+	eventAgeModeReject = "reject"
+)
+
+// eventAgeConfig is the per source max event age config, declared under the source config's maxEventAge key:
+//
+//	"maxEventAge": {
+//	  "maxAge": "30d",
+//	  "mode": "reject"
+//	}
+//
+// Events are stale if their originalTimestamp is older than maxAge, in days or as a Go duration, e.g. "720h".
+// Events without a valid originalTimestamp are never stale.
+type eventAgeConfig struct {
+	MaxAge string `json:"maxAge"`
+	Mode   string `json:"mode"`
+}
+
+// eventAgeFilter tells which events of a source are older than its max event age
+type eventAgeFilter struct {
+	mode   string
+	maxAge time.Duration
+}
+
+// newEventAgeFilter parses the max event age of a source.
+// It returns nil if the source doesn't declare a max event age.
+func newEventAgeFilter(sourceConfig map[string]interface{}) (*eventAgeFilter, error) {
+	var ageConfig eventAgeConfig
+	ok, err := parseSourceConfig(sourceConfig, "maxEventAge", &ageConfig)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if ageConfig.MaxAge == "" {
+		return nil, nil
+	}
+
+	filter := &eventAgeFilter{mode: ageConfig.Mode}
+	switch filter.mode {
+	case eventAgeModeTag, eventAgeModeReject:
+	case "":
+		filter.mode = eventAgeModeTag
+	default:
+		return nil, fmt.Errorf("unknown max event age mode: %q", ageConfig.Mode)
+	}
+	if filter.maxAge, err = parseMaxEventAge(ageConfig.MaxAge); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// parseMaxEventAge parses a max event age in days, e.g. "30d", or as a Go duration
+func parseMaxEventAge(maxAge string) (time.Duration, error) {
+	var (
+		age time.Duration
+		err error
+	)
+	if strings.HasSuffix(maxAge, "d") {
+		var n int
+		n, err = strconv.Atoi(strings.TrimSuffix(maxAge, "d"))
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		age, err = time.ParseDuration(maxAge)
+	}
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid max event age: %q", maxAge)
+	}
+	return age, nil
+}
+
+// age returns the age of event at now, telling whether it is stale
+func (f *eventAgeFilter) age(event map[string]interface{}, now time.Time) (time.Duration, bool) {
+	originalTimestamp, _ := event["originalTimestamp"].(string)
+	if originalTimestamp == "" {
+		return 0, false
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, originalTimestamp)
+	if err != nil {
+		return 0, false
+	}
+	age := now.Sub(timestamp)
+	return age, age > f.maxAge
+}
+
+// filterStaleEvents tags the events of a request older than the max event age of their source with their age under context.staleEvent.
+// It returns the events to be stored according to the source's mode, along with the stale events.
+func (gateway *HandleT) filterStaleEvents(filter *eventAgeFilter, sourceTags map[string]string, events []map[string]interface{}) (kept, stale []map[string]interface{}) {
+	now := time.Now()
+	kept = make([]map[string]interface{}, 0, len(events))
+	for _, event := range events {
+		age, isStale := filter.age(event, now)
+		if !isStale {
+			kept = append(kept, event)
+			continue
+		}
+
+		gateway.stats.NewTaggedStat("gateway.stale_events", stats.CountType, stats.Tags{
+			"sourceID": sourceTags["sourceID"],
+			"writeKey": sourceTags["writeKey"],
+			"mode":     filter.mode,
+		}).Increment()
+		eventContext, ok := event["context"].(map[string]interface{})
+		if !ok {
+			eventContext = make(map[string]interface{})
+			event["context"] = eventContext
+		}
+		eventContext["staleEvent"] = map[string]interface{}{
+			"ageInS":    int64(age.Seconds()),
+			"maxAgeInS": int64(filter.maxAge.Seconds()),
+		}
+		stale = append(stale, event)
+		if filter.mode == eventAgeModeTag {
+			kept = append(kept, event)
+		}
+	}
+	return kept, stale
+}
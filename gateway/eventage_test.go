@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestMaxEventAge(t *testing.T) {
+	ageConfig := func(maxAge, mode string) map[string]interface{} {
+		return sourceConfigWith(t, "maxEventAge", map[string]interface{}{"maxAge": maxAge, "mode": mode})
+	}
+	events := func() []map[string]interface{} {
+		now := time.Now().UTC()
+		return []map[string]interface{}{
+			{"type": "track", "originalTimestamp": now.Add(-time.Hour).Format(time.RFC3339Nano)},
+			{"type": "track", "originalTimestamp": now.Add(-40 * 24 * time.Hour).Format("2006-01-02T15:04:05.000Z")},
+			{"type": "track"},
+			{"type": "track", "originalTimestamp": "yesterday"},
+			{"type": "track", "originalTimestamp": now.Add(-31 * 24 * time.Hour).Format(time.RFC3339), "context": map[string]interface{}{"app": "ios"}},
+		}
+	}
+	sourceTags := map[string]string{"sourceID": "source-1", "writeKey": "write-key-1"}
+
+	t.Run("not declared", func(t *testing.T) {
+		filter, err := newEventAgeFilter(map[string]interface{}{})
+		require.NoError(t, err)
+		require.Nil(t, filter)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		for _, config := range []map[string]interface{}{
+			ageConfig("30d", "drop"),
+			ageConfig("thirty days", eventAgeModeTag),
+			ageConfig("0d", eventAgeModeTag),
+			ageConfig("-1h", eventAgeModeTag),
+		} {
+			_, err := newEventAgeFilter(config)
+			require.Error(t, err, config)
+		}
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		filter, err := newEventAgeFilter(ageConfig("30d", ""))
+		require.NoError(t, err)
+		require.Equal(t, 30*24*time.Hour, filter.maxAge)
+		require.Equal(t, eventAgeModeTag, filter.mode)
+
+		filter, err = newEventAgeFilter(ageConfig("720h", eventAgeModeReject))
+		require.NoError(t, err)
+		require.Equal(t, 30*24*time.Hour, filter.maxAge)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		store := memstats.New()
+		gateway := &HandleT{stats: store, logger: logger.NOP}
+		filter, err := newEventAgeFilter(ageConfig("30d", eventAgeModeReject))
+		require.NoError(t, err)
+
+		kept, stale := gateway.filterStaleEvents(filter, sourceTags, events())
+		require.Len(t, kept, 3)
+		require.Len(t, stale, 2)
+		staleEvent := stale[0]["context"].(map[string]interface{})["staleEvent"].(map[string]interface{})
+		require.InDelta(t, 40*24*3600, staleEvent["ageInS"], 5)
+		require.EqualValues(t, 30*24*3600, staleEvent["maxAgeInS"])
+		require.Equal(t, "ios", stale[1]["context"].(map[string]interface{})["app"])
+		require.EqualValues(t, 1, store.Get("gateway.stale_events", stats.Tags{
+			"sourceID": "source-1",
+			"writeKey": "write-key-1",
+			"mode":     eventAgeModeReject,
+		}).LastValue())
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		gateway := &HandleT{stats: memstats.New(), logger: logger.NOP}
+		filter, err := newEventAgeFilter(ageConfig("30d", eventAgeModeTag))
+		require.NoError(t, err)
+
+		kept, stale := gateway.filterStaleEvents(filter, sourceTags, events())
+		require.Len(t, kept, 5)
+		require.Len(t, stale, 2)
+		require.Contains(t, kept[1]["context"], "staleEvent")
+		require.NotContains(t, kept[0], "context")
+	})
+}
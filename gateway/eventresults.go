@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Batch requests with the responseModeHeader set to responseModePerEvent get the acceptance result of each of their events
+// in the response body, instead of being rejected as a whole because of some of their events.
+// This lets SDKs drop the rejected events only, instead of retrying the whole batch.
+const (
+	responseModeHeader   = "X-Rudder-Response-Mode"
+	responseModePerEvent = "perEvent"
+)
+
+// Statuses of the events of a batch request, in per event response mode
+const (
+	eventStatusAccepted = "accepted"
+	eventStatusRejected = "rejected"
+)
+
+// eventResult is the acceptance result of the event at index of a batch request. Rejected events get the reason of their rejection.
+type eventResult struct {
+	Index     int    `json:"index"`
+	MessageID string `json:"messageId,omitempty"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// eventResults is the response body of batch requests in per event response mode, e.g.
+//
+//	{
+//	  "accepted": 1,
+//	  "rejected": 1,
+//	  "results": [
+//	    {"index": 0, "messageId": "m-1", "status": "accepted"},
+//	    {"index": 1, "messageId": "m-2", "status": "rejected", "reason": "Request neither has anonymousId nor userId"}
+//	  ]
+//	}
+//
+// Accepted events may still be dropped by gateway, e.g. as duplicates, but must not be retried.
+//
+// Events of multiplexed batch requests may also have failed, see eventStatusFailed.
+type eventResults struct {
+	Accepted int           `json:"accepted"`
+	Rejected int           `json:"rejected"`
+	Failed   int           `json:"failed,omitempty"`
+	Results  []eventResult `json:"results"`
+
+	// positions in Results of the results of accepted events, by the events, see eventKey
+	acceptedEvents map[uintptr]int
+}
+
+// eventKey identifies an event by its map, events being filtered as they are after being accepted
+func eventKey(event map[string]interface{}) uintptr {
+	return reflect.ValueOf(event).Pointer()
+}
+
+func (r *eventResults) accept(index int, messageID string, event map[string]interface{}) {
+	if r.acceptedEvents == nil {
+		r.acceptedEvents = make(map[uintptr]int)
+	}
+	r.acceptedEvents[eventKey(event)] = len(r.Results)
+	r.Accepted++
+	r.Results = append(r.Results, eventResult{Index: index, MessageID: messageID, Status: eventStatusAccepted})
+}
+
+func (r *eventResults) reject(index int, messageID, reason string) {
+	r.Rejected++
+	r.Results = append(r.Results, eventResult{Index: index, MessageID: messageID, Status: eventStatusRejected, Reason: reason})
+}
+
+// rejectEvents rejects events which were accepted before, e.g. when they turn out to be violating their schema.
+// Events are matched with the results at their position in the request, so that events sharing a messageId are told apart.
+func (r *eventResults) rejectEvents(events []map[string]interface{}, reason string) {
+	for _, event := range events {
+		i, ok := r.acceptedEvents[eventKey(event)]
+		if !ok {
+			continue
+		}
+		delete(r.acceptedEvents, eventKey(event))
+		r.Results[i].Status = eventStatusRejected
+		r.Results[i].Reason = reason
+		r.Accepted--
+		r.Rejected++
+	}
+}
+
+// perEventBatchHandler handles batch requests in per event response mode.
+// Requests failing as a whole, e.g. because of an invalid write key, fail the same way as in the default mode.
+func (gateway *HandleT) perEventBatchHandler(w http.ResponseWriter, r *http.Request) {
+	const reqType = "batch"
+	webReqHandlerTime := gateway.stats.NewTaggedStat("gateway.web_req_handler_time", stats.TimerType, stats.Tags{"reqType": reqType})
+	webReqHandlerStartTime := time.Now()
+	defer webReqHandlerTime.Since(webReqHandlerStartTime)
+
+	gateway.logger.LogRequest(r)
+	atomic.AddUint64(&gateway.recvCount, 1)
+	r, span := startRequestSpan(r, reqType)
+	var (
+		errorMessage string
+		payload      []byte
+	)
+	defer func() {
+		if errorMessage != "" {
+			gateway.logger.Infof("IP: %s -- %s -- Response: %d, %s", misc.GetIPFromReq(r), r.URL.Path, response.GetErrorStatusCode(errorMessage), errorMessage)
+			http.Error(w, response.GetStatus(errorMessage), response.GetErrorStatusCode(errorMessage))
+		}
+		gateway.recordThroughput(requestWriteKey(r), errorMessage, len(payload))
+		gateway.sampleRequest(requestWriteKey(r), payload, errorMessage)
+		endSpan(span, errorMessage)
+	}()
+	if gateway.shedRequest(w, reqType) {
+		errorMessage = response.ServiceUnavailable
+		return
+	}
+	payload, writeKey, err := gateway.getPayloadAndWriteKey(w, r, reqType)
+	if err != nil {
+		errorMessage = err.Error()
+		return
+	}
+
+	done := make(chan string, 1)
+	results := &eventResults{Results: []eventResult{}}
+	start := time.Now()
+	gateway.queueWebRequest(&webRequestT{
+		done:           done,
+		reqType:        reqType,
+		requestPayload: payload,
+		writeKey:       writeKey,
+		ipAddr:         misc.GetIPFromReq(r),
+		userIDHeader:   r.Header.Get("AnonymousId"),
+		results:        results,
+		spanContext:    trace.SpanContextFromContext(r.Context()),
+	})
+	gateway.addToWebRequestQWaitTime.SendTiming(time.Since(start))
+	errorMessage = <-done
+	gateway.processRequestTime.Since(start)
+
+	atomic.AddUint64(&gateway.ackCount, 1)
+	gateway.trackRequestMetrics(errorMessage)
+	if errorMessage != "" {
+		return
+	}
+	if results.Rejected > 0 {
+		gateway.stats.NewTaggedStat("gateway.rejected_events", stats.CountType, stats.Tags{
+			"sourceID": gateway.getSourceIDForWriteKey(writeKey),
+			"writeKey": writeKey,
+		}).Count(results.Rejected)
+	}
+
+	body, _ := json.Marshal(results)
+	gateway.logger.Debugf("IP: %s -- %s -- Response: 200, %d accepted, %d rejected", misc.GetIPFromReq(r), r.URL.Path, results.Accepted, results.Rejected)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+)
+
+func TestRejectEvents(t *testing.T) {
+	events := []map[string]interface{}{
+		{"messageId": "message-1", "type": "track"},
+		{"messageId": "message-1", "type": "track"},
+		{"messageId": "message-2", "type": "page"},
+	}
+	results := &eventResults{Results: []eventResult{}}
+	results.reject(0, "message-0", response.NonIdentifiableRequest)
+	for i, event := range events {
+		results.accept(i+1, event["messageId"].(string), event)
+	}
+
+	results.rejectEvents(events[1:2], response.EventSchemaViolation)
+	results.rejectEvents(events[1:2], response.StaleEvent) // rejected already
+	results.rejectEvents([]map[string]interface{}{{"messageId": "message-2"}}, response.StaleEvent)
+
+	require.Equal(t, 2, results.Accepted)
+	require.Equal(t, 2, results.Rejected)
+	require.Equal(t, []eventResult{
+		{Index: 0, MessageID: "message-0", Status: eventStatusRejected, Reason: response.NonIdentifiableRequest},
+		{Index: 1, MessageID: "message-1", Status: eventStatusAccepted},
+		{Index: 2, MessageID: "message-1", Status: eventStatusRejected, Reason: response.EventSchemaViolation},
+		{Index: 3, MessageID: "message-2", Status: eventStatusAccepted},
+	}, results.Results)
+}
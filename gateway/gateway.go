@@ -24,6 +24,8 @@ import (
 	"github.com/rs/cors"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/rudderlabs/rudder-server/admin"
@@ -38,7 +40,9 @@ import (
 	ratelimiter "github.com/rudderlabs/rudder-server/rate-limiter"
 	"github.com/rudderlabs/rudder-server/rruntime"
 	sourcedebugger "github.com/rudderlabs/rudder-server/services/debugger/source"
+	"github.com/rudderlabs/rudder-server/services/dedup"
 	"github.com/rudderlabs/rudder-server/services/diagnostics"
+	"github.com/rudderlabs/rudder-server/services/geolocation"
 	"github.com/rudderlabs/rudder-server/services/rsources"
 	rsources_http "github.com/rudderlabs/rudder-server/services/rsources/http"
 	"github.com/rudderlabs/rudder-server/services/stats"
@@ -69,6 +73,10 @@ type webRequestT struct {
 	writeKey       string
 	ipAddr         string
 	userIDHeader   string
+	// results collects the acceptance result of each event, for requests in per event response mode. nil otherwise.
+	results *eventResults
+	// spanContext is the span of the request, which the spans of its processing are children of. Invalid if it isn't traced.
+	spanContext trace.SpanContext
 }
 
 type batchWebRequestT struct {
@@ -83,11 +91,38 @@ var (
 	enabledWriteKeyWebhookMap                                                         map[string]string
 	enabledWriteKeyWorkspaceMap                                                       map[string]string
 	sourceIDToNameMap                                                                 map[string]string
+	writeKeyEventValidatorMap                                                         map[string]*eventValidator
+	writeKeyBotFilterMap                                                              map[string]*sourceBotFilter
+	writeKeyEventAgeFilterMap                                                         map[string]*eventAgeFilter
+	writeKeyIPPrivacyMap                                                              map[string]*ipPrivacyConfig
+	writeKeyOriginMatcherMap                                                          map[string]*originMatcher
+	serviceTokenWriteKeysMap                                                          map[string]map[string]struct{}
+	writeKeyRequestSamplingMap                                                        map[string]*requestSampling
+	pausedWorkspaces                                                                  map[string]struct{}
+	requestSamplingRate                                                               float64
+	requestSamplingErrorsOnly                                                         bool
+	requestSamplingMaxPayloadSize                                                     int
+	restrictPreflightOrigins                                                          bool
 	configSubscriberLock                                                              sync.RWMutex
 	maxReqSize                                                                        int
+	maxDecompressedReqSize                                                            int
+	enableBinaryPayloads                                                              bool
 	enableRateLimit                                                                   bool
 	enableSuppressUserFeature                                                         bool
 	enableEventSchemasFeature                                                         bool
+	enableEventValidation                                                             bool
+	enableBotFiltering                                                                bool
+	enableMaxEventAge                                                                 bool
+	botUserAgents, botBlockedIPs                                                      []string
+	ipHashSalt, geoDBPath                                                             string
+	enableDedup                                                                       bool
+	dedupWindow                                                                       time.Duration
+	enableKafkaIngestion                                                              bool
+	kafkaDialTimeout, kafkaFetchRetryInterval                                         time.Duration
+	enableAdmissionControl                                                            bool
+	corsMaxAge                                                                        time.Duration
+	admissionMaxWriteLatency, admissionRetryAfter                                     time.Duration
+	admissionMaxPendingJobs                                                           int
 	diagnosisTickerTime                                                               time.Duration
 	ReadTimeout                                                                       time.Duration
 	ReadHeaderTimeout                                                                 time.Duration
@@ -95,6 +130,9 @@ var (
 	IdleTimeout                                                                       time.Duration
 	allowReqsWithoutUserIDAndAnonymousID                                              bool
 	gwAllowPartialWriteWithErrors                                                     bool
+	enablePerEventResponse                                                            bool
+	enableThroughputTracking                                                          bool
+	throughputWindow                                                                  time.Duration
 	pkgLogger                                                                         logger.Logger
 	Diagnostics                                                                       diagnostics.DiagnosticsI
 )
@@ -133,6 +171,15 @@ type sourceDebugger struct {
 	writeKey string
 }
 
+// droppedEventsDebugger returns the batch of events of a request dropped before being stored, for them to be recorded to the source debugger nonetheless
+func droppedEventsDebugger(writeKey, ipAddr string, events []map[string]interface{}) sourceDebugger {
+	batch, _ := sjson.SetBytes(BatchEvent, "batch", events)
+	batch, _ = sjson.SetBytes(batch, "requestIP", ipAddr)
+	batch, _ = sjson.SetBytes(batch, "writeKey", writeKey)
+	batch, _ = sjson.SetBytes(batch, "receivedAt", time.Now().Format(misc.RFC3339Milli))
+	return sourceDebugger{data: batch, writeKey: writeKey}
+}
+
 // Basic worker unit that works on incoming webRequests.
 //
 // Has three channels used to communicate between the two goroutines each worker runs.
@@ -157,6 +204,7 @@ type HandleT struct {
 	recvCount                    uint64
 	backendConfig                backendconfig.BackendConfig
 	rateLimiter                  ratelimiter.RateLimiter
+	workspaceQuotas              WorkspaceQuotas
 
 	stats                                         stats.Stats
 	batchSizeStat                                 stats.Measurement
@@ -178,6 +226,11 @@ type HandleT struct {
 	userWebRequestWorkers []*userWebRequestWorkerT
 	webhookHandler        *webhook.HandleT
 	suppressUserHandler   types.UserSuppression
+	dedup                 dedup.DedupI
+	geoLocator            geolocation.Locator
+	kafkaIngestion        *kafkaIngestion
+	admission             *admissionController
+	throughput            *throughputTracker
 	eventSchemaHandler    types.EventSchemasI
 	versionHandler        func(w http.ResponseWriter, r *http.Request)
 	logger                logger.Logger
@@ -335,6 +388,7 @@ func (gateway *HandleT) dbWriterWorkerProcess() {
 		}
 
 		ctx, cancel := context.WithTimeout(context.Background(), WriteTimeout)
+		writeStart := time.Now()
 		err := gateway.jobsDB.WithStoreSafeTx(ctx, func(tx jobsdb.StoreSafeTx) error {
 			if gwAllowPartialWriteWithErrors {
 				var err error
@@ -367,6 +421,9 @@ func (gateway *HandleT) dbWriterWorkerProcess() {
 		}
 		cancel()
 		gateway.dbWritesStat.Count(1)
+		if gateway.admission != nil {
+			gateway.admission.observeWrite(time.Since(writeStart))
+		}
 
 		for _, userWorkerBatchRequest := range breq.batchUserWorkerBatchRequest {
 			userWorkerBatchRequest.respChannel <- errorMessagesMap
@@ -453,6 +510,9 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 		jobIDReqMap := make(map[uuid.UUID]*webRequestT)
 		jobWriteKeyMap := make(map[uuid.UUID]string)
 		jobEventCountMap := make(map[uuid.UUID]int)
+		jobDedupKeysMap := make(map[uuid.UUID][]string)
+		jobWriteSpanMap := make(map[uuid.UUID]trace.Span)
+		batchDedupKeys := make(map[string]struct{})
 		sourceStats := make(map[string]int)
 		sourceEventStats := make(map[string]int)
 		sourceSuccessStats := make(map[string]int)
@@ -465,8 +525,18 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 		// Saving the event data read from req.request.Body to the splice.
 		// Using this to send event schema to the config backend.
 		var eventBatchesToRecord []sourceDebugger
+		// the validation span of each request ends once the next request gets validated
+		var validationSpan trace.Span
+		endValidationSpan := func() {
+			if validationSpan != nil {
+				validationSpan.End()
+				validationSpan = nil
+			}
+		}
 		userWebRequestWorker.batchTimeStat.Start()
 		for _, req := range breq.batchRequest {
+			endValidationSpan()
+			validationSpan = startSpan(req.spanContext, "gateway.validate")
 			writeKey := req.writeKey
 			sourceTag := gateway.getSourceTagFromWriteKey(writeKey)
 			sourceID := gateway.getSourceIDForWriteKey(writeKey)
@@ -540,6 +610,15 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 				continue
 			}
 
+			if gateway.isWorkspacePaused(workspaceId) {
+				sourceTagMap[sourceTag]["reason"] = "workspacePaused"
+				req.done <- response.GetStatus(response.WorkspacePaused)
+				preDbStoreCount++
+				misc.IncrementMapByKey(sourceFailStats, sourceTag, 1)
+				misc.IncrementMapByKey(sourceFailEventStats, sourceTag, totalEventsInReq)
+				continue
+			}
+
 			if enableRateLimit {
 				// In case of "batch" requests, if rate-limiter returns true for LimitReached, just drop the event batch and continue.
 				if gateway.rateLimiter.LimitReached(workspaceId) {
@@ -550,49 +629,71 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 				}
 			}
 
+			if gateway.workspaceQuotas != nil && !gateway.workspaceQuotas.AllowEvents(workspaceId, totalEventsInReq) {
+				req.done <- response.GetStatus(response.TooManyRequests)
+				preDbStoreCount++
+				misc.IncrementMapByKey(workspaceDropRequestStats, sourceTag, 1)
+				continue
+			}
+
 			// set anonymousId if not set in payload
 			result := gjson.GetBytes(body, "batch")
 			var out []map[string]interface{}
 			var builtUserID string
 			var notIdentifiable, nonRudderEvent, containsAudienceList bool
-			result.ForEach(func(_, vjson gjson.Result) bool {
+			var rejectedEvents int
+			result.ForEach(func(key, vjson gjson.Result) bool {
 				anonIDFromReq := strings.TrimSpace(vjson.Get("anonymousId").String())
 				userIDFromReq := strings.TrimSpace(vjson.Get("userId").String())
-				if builtUserID == "" {
-					if anonIDFromReq != "" {
-						builtUserID = userIDHeader + DELIMITER + anonIDFromReq + DELIMITER + userIDFromReq
-					} else {
-						// Proxy gets the userID from body if there is no anonymousId in body/header
-						builtUserID = userIDHeader + DELIMITER + userIDFromReq + DELIMITER + userIDFromReq
+				messageId := strings.TrimSpace(vjson.Get("messageId").String())
+				// in per event response mode, events which aren't identifiable or aren't rudder events are rejected on their own
+				rejectEvent := func(reason string) bool {
+					if req.results == nil {
+						return false
 					}
+					req.results.reject(int(key.Int()), messageId, reason)
+					rejectedEvents++
+					return true
 				}
 
 				eventTypeFromReq := strings.TrimSpace(vjson.Get("type").String())
 
 				if anonIDFromReq == "" {
 					if userIDFromReq == "" && !allowReqsWithoutUserIDAndAnonymousID {
-						notIdentifiable = true
-						return false
+						notIdentifiable = !rejectEvent(response.NonIdentifiableRequest)
+						return !notIdentifiable
 					}
 				}
-				if eventTypeFromReq == "audiencelist" {
-					containsAudienceList = true
-				}
 				// hashing combination of userIDFromReq + anonIDFromReq, using colon as a delimiter
 				rudderId, err := misc.GetMD5UUID(userIDFromReq + ":" + anonIDFromReq)
 				if err != nil {
-					notIdentifiable = true
-					return false
+					notIdentifiable = !rejectEvent(response.NonIdentifiableRequest)
+					return !notIdentifiable
 				}
 
 				toSet, ok := vjson.Value().(map[string]interface{})
 				if !ok {
-					nonRudderEvent = true
-					return false
+					nonRudderEvent = !rejectEvent(response.NotRudderEvent)
+					return !nonRudderEvent
+				}
+				if eventTypeFromReq == "audiencelist" {
+					containsAudienceList = true
+				}
+				if builtUserID == "" {
+					if anonIDFromReq != "" {
+						builtUserID = userIDHeader + DELIMITER + anonIDFromReq + DELIMITER + userIDFromReq
+					} else {
+						// Proxy gets the userID from body if there is no anonymousId in body/header
+						builtUserID = userIDHeader + DELIMITER + userIDFromReq + DELIMITER + userIDFromReq
+					}
 				}
 				toSet["rudderId"] = rudderId
-				if messageId := strings.TrimSpace(vjson.Get("messageId").String()); messageId == "" {
-					toSet["messageId"] = uuid.New().String()
+				if messageId == "" {
+					messageId = uuid.New().String()
+					toSet["messageId"] = messageId
+				}
+				if req.results != nil {
+					req.results.accept(int(key.Int()), messageId, toSet)
 				}
 				out = append(out, toSet)
 				return true // keep iterating
@@ -625,6 +726,109 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 				continue
 			}
 
+			if rejectedEvents > 0 {
+				misc.IncrementMapByKey(sourceFailEventStats, sourceTag, rejectedEvents)
+				if len(out) == 0 {
+					// all events of the request were rejected
+					req.done <- ""
+					preDbStoreCount++
+					continue
+				}
+				totalEventsInReq = len(out)
+			}
+
+			if eventAgeFilter := gateway.getEventAgeFilter(writeKey); eventAgeFilter != nil {
+				var stale []map[string]interface{}
+				out, stale = gateway.filterStaleEvents(eventAgeFilter, sourceTagMap[sourceTag], out)
+				if len(stale) > 0 && eventAgeFilter.mode == eventAgeModeReject && req.results != nil {
+					// in per event response mode, stale events are rejected on their own
+					req.results.rejectEvents(stale, response.StaleEvent)
+					misc.IncrementMapByKey(sourceFailEventStats, sourceTag, len(stale))
+				} else if len(stale) > 0 && eventAgeFilter.mode == eventAgeModeReject {
+					sourceTagMap[sourceTag]["reason"] = "staleEvent"
+					req.done <- response.GetStatus(response.StaleEvent)
+					preDbStoreCount++
+					misc.IncrementMapByKey(sourceFailStats, sourceTag, 1)
+					misc.IncrementMapByKey(sourceFailEventStats, sourceTag, totalEventsInReq)
+					continue
+				}
+				if len(out) == 0 {
+					// all events of the request were rejected
+					req.done <- ""
+					preDbStoreCount++
+					continue
+				}
+				totalEventsInReq = len(out)
+				body, _ = sjson.SetBytes(body, "batch", out)
+			}
+
+			ipPrivacy := gateway.getIPPrivacy(writeKey)
+			if botFilter := gateway.getBotFilter(writeKey); botFilter != nil {
+				var suspected []map[string]interface{}
+				out, suspected = gateway.filterBots(botFilter, sourceTagMap[sourceTag], ipAddr, out)
+				if len(suspected) > 0 && botFilter.mode == botFilterModeDrop {
+					// dropped events are recorded to the source debugger, tagged with the reason of their suspicion
+					suspectedIPAddr := gateway.applyIPPrivacy(ipPrivacy, sourceTagMap[sourceTag], ipAddr, suspected)
+					eventBatchesToRecord = append(eventBatchesToRecord, droppedEventsDebugger(writeKey, suspectedIPAddr, suspected))
+				}
+				if len(out) == 0 {
+					// all events of the request were dropped
+					req.done <- ""
+					preDbStoreCount++
+					continue
+				}
+				totalEventsInReq = len(out)
+				body, _ = sjson.SetBytes(body, "batch", out)
+			}
+
+			if ipPrivacy != nil {
+				// IPs are anonymized before events are recorded to the source debugger or stored, after bot filtering looked them up
+				ipAddr = gateway.applyIPPrivacy(ipPrivacy, sourceTagMap[sourceTag], ipAddr, out)
+				body, _ = sjson.SetBytes(body, "batch", out)
+			}
+
+			if validator := gateway.getEventValidator(writeKey); validator != nil {
+				var violating []map[string]interface{}
+				out, violating = gateway.validateEvents(validator, sourceTagMap[sourceTag], out)
+				if len(violating) > 0 && validator.mode != eventValidationModeAnnotate {
+					// violating events are recorded to the source debugger even if not stored
+					eventBatchesToRecord = append(eventBatchesToRecord, droppedEventsDebugger(writeKey, ipAddr, violating))
+				}
+				if len(violating) > 0 && validator.mode != eventValidationModeAnnotate && req.results != nil {
+					// in per event response mode, violating events which aren't stored are rejected on their own
+					req.results.rejectEvents(violating, response.EventSchemaViolation)
+					misc.IncrementMapByKey(sourceFailEventStats, sourceTag, len(violating))
+				} else if len(violating) > 0 && validator.mode == eventValidationModeReject {
+					sourceTagMap[sourceTag]["reason"] = "eventSchemaViolation"
+					req.done <- response.GetStatus(response.EventSchemaViolation)
+					preDbStoreCount++
+					misc.IncrementMapByKey(sourceFailStats, sourceTag, 1)
+					misc.IncrementMapByKey(sourceFailEventStats, sourceTag, totalEventsInReq)
+					continue
+				}
+				if len(out) == 0 {
+					// all events of the request were dropped
+					req.done <- ""
+					preDbStoreCount++
+					continue
+				}
+				totalEventsInReq = len(out)
+				body, _ = sjson.SetBytes(body, "batch", out)
+			}
+
+			var dedupKeys []string
+			if gateway.dedup != nil {
+				out, dedupKeys = gateway.dropDuplicates(writeKey, sourceTagMap[sourceTag], out, batchDedupKeys)
+				if len(out) == 0 {
+					// all events of the request were already received
+					req.done <- ""
+					preDbStoreCount++
+					continue
+				}
+				totalEventsInReq = len(out)
+				body, _ = sjson.SetBytes(body, "batch", out)
+			}
+
 			if enableSuppressUserFeature && gateway.suppressUserHandler != nil {
 				userID := gjson.GetBytes(body, "batch.0.userId").String()
 				if gateway.suppressUserHandler.IsSuppressedUser(workspaceId, userID, sourceID) {
@@ -648,6 +852,10 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 				"source_job_run_id":  sourcesJobRunID,
 				"source_task_run_id": sourcesTaskRunID,
 			}
+			if traceParent := traceParent(req.spanContext); traceParent != "" {
+				// the trace of the request is continued by the processing of its job
+				params["traceparent"] = traceParent
+			}
 			marshalledParams, err := json.Marshal(params)
 			if err != nil {
 				gateway.logger.Errorf("[Gateway] Failed to marshal parameters map. Parameters: %+v", params)
@@ -668,22 +876,34 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 			jobIDReqMap[newJob.UUID] = req
 			jobWriteKeyMap[newJob.UUID] = sourceTag
 			jobEventCountMap[newJob.UUID] = totalEventsInReq
+			jobDedupKeysMap[newJob.UUID] = dedupKeys
 		}
+		endValidationSpan()
 
 		errorMessagesMap := make(map[uuid.UUID]string)
 		if len(jobList) > 0 {
+			for _, job := range jobList {
+				jobWriteSpanMap[job.UUID] = startSpan(jobIDReqMap[job.UUID].spanContext, "gateway.jobsdb_write")
+			}
+			if gateway.admission != nil {
+				gateway.admission.addPendingJobs(len(jobList))
+			}
 			gateway.userWorkerBatchRequestQ <- &userWorkerBatchRequestT{
 				jobList:     jobList,
 				respChannel: userWebRequestWorker.reponseQ,
 			}
 
 			errorMessagesMap = <-userWebRequestWorker.reponseQ
+			if gateway.admission != nil {
+				gateway.admission.addPendingJobs(-len(jobList))
+			}
 		}
 
 		if preDbStoreCount+len(jobList) != len(breq.batchRequest) {
 			panic(fmt.Errorf("preDbStoreCount:%d+len(jobList):%d != len(breq.batchRequest):%d",
 				preDbStoreCount, len(jobList), len(breq.batchRequest)))
 		}
+		var storedDedupKeys []string
 		for _, job := range jobList {
 			err, found := errorMessagesMap[job.UUID]
 			if found {
@@ -692,9 +912,16 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 			} else {
 				misc.IncrementMapByKey(sourceSuccessStats, jobWriteKeyMap[job.UUID], 1)
 				misc.IncrementMapByKey(sourceSuccessEventStats, jobWriteKeyMap[job.UUID], jobEventCountMap[job.UUID])
+				storedDedupKeys = append(storedDedupKeys, jobDedupKeysMap[job.UUID]...)
 			}
+			endSpan(jobWriteSpanMap[job.UUID], err)
 			jobIDReqMap[job.UUID].done <- err
 		}
+		if len(storedDedupKeys) > 0 {
+			if err := gateway.dedup.MarkProcessed(storedDedupKeys); err != nil {
+				gateway.logger.Errorf("Error marking %d events as received for dedup: %v", len(storedDedupKeys), err)
+			}
+		}
 		// Sending events to config backend
 		for _, eventBatch := range eventBatchesToRecord {
 			sourcedebugger.RecordEvent(eventBatch.writeKey, eventBatch.data)
@@ -731,6 +958,15 @@ func (*HandleT) isWriteKeyEnabled(writeKey string) bool {
 	return writeKeysSourceMap[writeKey].Enabled
 }
 
+// isWorkspacePaused returns whether the workspace is paused by the control plane, its events being rejected
+func (*HandleT) isWorkspacePaused(workspaceID string) bool {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+
+	_, ok := pausedWorkspaces[workspaceID]
+	return ok
+}
+
 func (*HandleT) getSourceIDForWriteKey(writeKey string) string {
 	configSubscriberLock.RLock()
 	defer configSubscriberLock.RUnlock()
@@ -753,6 +989,51 @@ func (*HandleT) getSourceNameForWriteKey(writeKey string) string {
 	return "-notFound-"
 }
 
+// getOriginMatcher returns the matcher of the origins a source allows cross-origin requests from, nil if it allows all of them
+func (*HandleT) getOriginMatcher(writeKey string) *originMatcher {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	return writeKeyOriginMatcherMap[writeKey]
+}
+
+// getIPPrivacy returns the ip privacy config of a source, nil if it neither anonymizes IPs nor enriches events with their location
+func (*HandleT) getIPPrivacy(writeKey string) *ipPrivacyConfig {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	return writeKeyIPPrivacyMap[writeKey]
+}
+
+// getBotFilter returns the bot filter of a source, nil if it doesn't enable bot filtering
+func (*HandleT) getBotFilter(writeKey string) *sourceBotFilter {
+	if !enableBotFiltering {
+		return nil
+	}
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	return writeKeyBotFilterMap[writeKey]
+}
+
+// getEventAgeFilter returns the max event age filter of a source, nil if it doesn't declare a max event age
+func (*HandleT) getEventAgeFilter(writeKey string) *eventAgeFilter {
+	if !enableMaxEventAge {
+		return nil
+	}
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	return writeKeyEventAgeFilterMap[writeKey]
+}
+
+// getEventValidator returns the validator of the events of a source, nil if it doesn't declare any schemas
+func (*HandleT) getEventValidator(writeKey string) *eventValidator {
+	if !enableEventValidation {
+		return nil
+	}
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+
+	return writeKeyEventValidatorMap[writeKey]
+}
+
 func (gateway *HandleT) printStats(ctx context.Context) {
 	for {
 		select {
@@ -778,7 +1059,7 @@ func (gateway *HandleT) eventSchemaWebHandler(wrappedFunc func(http.ResponseWrit
 	}
 }
 
-func (gateway *HandleT) getPayloadFromRequest(r *http.Request) ([]byte, error) {
+func (gateway *HandleT) getPayloadFromRequest(r *http.Request, reqType string) ([]byte, error) {
 	if r.Body == nil {
 		return []byte{}, errors.New(response.RequestBodyNil)
 	}
@@ -796,7 +1077,10 @@ func (gateway *HandleT) getPayloadFromRequest(r *http.Request) ([]byte, error) {
 		)
 		return payload, errors.New(response.RequestBodyReadFailed)
 	}
-	return payload, nil
+	if payload, err = gateway.decompressPayload(r, payload); err != nil {
+		return nil, err
+	}
+	return gateway.decodePayload(r, reqType, payload)
 }
 
 func (gateway *HandleT) webImportHandler(w http.ResponseWriter, r *http.Request) {
@@ -808,6 +1092,10 @@ func (gateway *HandleT) webAudienceListHandler(w http.ResponseWriter, r *http.Re
 }
 
 func (gateway *HandleT) webBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if enablePerEventResponse && r.Header.Get(responseModeHeader) == responseModePerEvent {
+		gateway.perEventBatchHandler(w, r)
+		return
+	}
 	gateway.webHandler(w, r, "batch")
 }
 
@@ -889,10 +1177,22 @@ type RegularRequestHandler struct{}
 
 // ProcessWebRequest is an Interface wrapper for webhook
 func (gateway *HandleT) ProcessWebRequest(w *http.ResponseWriter, r *http.Request, reqType string, payload []byte, writeKey string) string {
-	return gateway.rrh.ProcessRequest(gateway, w, r, reqType, payload, writeKey)
+	r, span := startRequestSpan(r, reqType)
+	var errorMessage string
+	if gateway.shedRequest(*w, reqType) {
+		errorMessage = response.ServiceUnavailable
+	} else {
+		errorMessage = gateway.rrh.ProcessRequest(gateway, w, r, reqType, payload, writeKey)
+	}
+	gateway.recordThroughput(writeKey, errorMessage, len(payload))
+	gateway.sampleRequest(writeKey, payload, errorMessage)
+	endSpan(span, errorMessage)
+	return errorMessage
 }
 
 func (gateway *HandleT) getPayloadAndWriteKey(_ http.ResponseWriter, r *http.Request, reqType string) ([]byte, string, error) {
+	_, span := tracer.Start(r.Context(), "gateway.auth")
+	defer span.End()
 	sourceFailStats := make(map[string]int)
 	var err error
 	writeKey, _, ok := r.BasicAuth()
@@ -916,9 +1216,10 @@ func (gateway *HandleT) getPayloadAndWriteKey(_ http.ResponseWriter, r *http.Req
 			},
 		})
 
+		span.SetStatus(codes.Error, err.Error())
 		return []byte{}, "", err
 	}
-	payload, err := gateway.getPayloadFromRequest(r)
+	payload, err := gateway.getPayloadFromRequest(r, reqType)
 	if err != nil {
 		sourceTag := gateway.getSourceTagFromWriteKey(writeKey)
 		misc.IncrementMapByKey(sourceFailStats, sourceTag, 1)
@@ -937,6 +1238,7 @@ func (gateway *HandleT) getPayloadAndWriteKey(_ http.ResponseWriter, r *http.Req
 				"writeKey": writeKey,
 			},
 		})
+		span.SetStatus(codes.Error, err.Error())
 		return []byte{}, writeKey, err
 	}
 	return payload, writeKey, err
@@ -957,13 +1259,24 @@ func (gateway *HandleT) webRequestHandler(rh RequestHandler, w http.ResponseWrit
 
 	gateway.logger.LogRequest(r)
 	atomic.AddUint64(&gateway.recvCount, 1)
-	var errorMessage string
+	r, span := startRequestSpan(r, reqType)
+	var (
+		errorMessage string
+		payload      []byte
+	)
 	defer func() {
 		if errorMessage != "" {
 			gateway.logger.Infof("IP: %s -- %s -- Response: %d, %s", misc.GetIPFromReq(r), r.URL.Path, response.GetErrorStatusCode(errorMessage), errorMessage)
 			http.Error(w, response.GetStatus(errorMessage), response.GetErrorStatusCode(errorMessage))
 		}
+		gateway.recordThroughput(requestWriteKey(r), errorMessage, len(payload))
+		gateway.sampleRequest(requestWriteKey(r), payload, errorMessage)
+		endSpan(span, errorMessage)
 	}()
+	if gateway.shedRequest(w, reqType) {
+		errorMessage = response.ServiceUnavailable
+		return
+	}
 	payload, writeKey, err := gateway.getPayloadAndWriteKey(w, r, reqType)
 	if err != nil {
 		errorMessage = err.Error()
@@ -987,11 +1300,18 @@ func (gateway *HandleT) pixelWebRequestHandler(rh RequestHandler, w http.Respons
 	sendPixelResponse(w)
 	gateway.logger.LogRequest(r)
 	atomic.AddUint64(&gateway.recvCount, 1)
-	var errorMessage string
+	r, span := startRequestSpan(r, reqType)
+	var (
+		errorMessage string
+		payload      []byte
+	)
 	defer func() {
 		if errorMessage != "" {
 			gateway.logger.Info(fmt.Sprintf("IP: %s -- %s -- Error while handling request: %s", misc.GetIPFromReq(r), r.URL.Path, errorMessage))
 		}
+		gateway.recordThroughput(requestWriteKey(r), errorMessage, len(payload))
+		gateway.sampleRequest(requestWriteKey(r), payload, errorMessage)
+		endSpan(span, errorMessage)
 	}()
 	payload, writeKey, err := gateway.getPayloadAndWriteKey(w, r, reqType)
 	if err != nil {
@@ -1126,12 +1446,14 @@ func (*HandleT) setWebPayload(r *http.Request, qp url.Values, reqType string) er
 			body, _ = sjson.SetBytes(body, "name", pageName[0])
 		}
 	case "track":
-		if evName, ok := qp["event"]; ok {
-			if evName[0] == "" {
-				return errors.New("track: Mandatory field 'event' missing")
-			}
-			body, _ = sjson.SetBytes(body, "event", evName[0])
+		evName, ok := qp["event"]
+		if !ok || evName[0] == "" {
+			return errors.New("track: Mandatory field 'event' missing")
 		}
+		body, _ = sjson.SetBytes(body, "event", evName[0])
+	}
+	if !gjson.GetBytes(body, "context.userAgent").Exists() && r.Header.Get("User-Agent") != "" {
+		body, _ = sjson.SetBytes(body, "context.userAgent", r.Header.Get("User-Agent"))
 	}
 	// add body to request
 	r.Body = io.NopCloser(bytes.NewReader(body))
@@ -1140,6 +1462,10 @@ func (*HandleT) setWebPayload(r *http.Request, qp url.Values, reqType string) er
 
 func sendPixelResponse(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "image/gif")
+	// pixels must not be cached, e.g. by email clients or proxies, for each of their loads to reach gateway
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
 	_, err := w.Write([]byte(response.GetPixelResponse()))
 	if err != nil {
 		pkgLogger.Warnf("Error while sending pixel response: %v", err)
@@ -1161,8 +1487,13 @@ func (gateway *HandleT) pixelHandler(w http.ResponseWriter, r *http.Request, req
 		req.SetBasicAuth(writeKey[0], "")
 		delete(queryParams, "writeKey")
 
-		// set X-Forwarded-For header
+		// set X-Forwarded-For header, falling back to the remote address of the pixel request without a load-balancer
 		req.Header.Add("X-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+		req.RemoteAddr = r.RemoteAddr
+		req.Header.Set("User-Agent", r.UserAgent())
+		if anonymousID := queryParams.Get("anonymousId"); anonymousID != "" {
+			req.Header.Set("AnonymousId", strings.Trim(anonymousID, `"`))
+		}
 
 		// convert the pixel request(r) to a web request(req)
 		err = gateway.setWebPayload(req, queryParams, reqType)
@@ -1198,10 +1529,6 @@ func (*HandleT) robots(w http.ResponseWriter, _ *http.Request) {
 	_, _ = w.Write([]byte("User-agent: * \nDisallow: / \n"))
 }
 
-func reflectOrigin(_ string) bool {
-	return true
-}
-
 /*
 StartWebHandler starts all gateway web handlers, listening on gateway port.
 Supports CORS from all origins.
@@ -1216,6 +1543,7 @@ func (gateway *HandleT) StartWebHandler(ctx context.Context) error {
 	srvMux.Use(
 		middleware.StatMiddleware(ctx, srvMux, stats.Default, component),
 		middleware.LimitConcurrentRequests(maxConcurrentRequests),
+		gateway.corsMiddleware,
 		middleware.UncompressMiddleware,
 	)
 	srvMux.HandleFunc("/v1/batch", gateway.webBatchHandler).Methods("POST")
@@ -1229,6 +1557,7 @@ func (gateway *HandleT) StartWebHandler(ctx context.Context) error {
 	srvMux.HandleFunc("/health", WithContentType("application/json; charset=utf-8", app.LivenessHandler(gateway.jobsDB))).Methods("GET")
 	srvMux.HandleFunc("/", WithContentType("application/json; charset=utf-8", app.LivenessHandler(gateway.jobsDB))).Methods("GET")
 	srvMux.HandleFunc("/v1/import", gateway.webImportHandler).Methods("POST")
+	srvMux.HandleFunc("/v1/service/batch", gateway.multiplexedBatchHandler).Methods("POST")
 	srvMux.HandleFunc("/v1/audiencelist", gateway.webAudienceListHandler).Methods("POST")
 	srvMux.HandleFunc("/pixel/v1/track", gateway.pixelTrackHandler).Methods("GET")
 	srvMux.HandleFunc("/pixel/v1/page", gateway.pixelPageHandler).Methods("GET")
@@ -1257,10 +1586,10 @@ func (gateway *HandleT) StartWebHandler(ctx context.Context) error {
 	srvMux.PathPrefix("/v1/job-status").Handler(WithContentType("application/json; charset=utf-8", rsourcesHandler.ServeHTTP))
 
 	c := cors.New(cors.Options{
-		AllowOriginFunc:  reflectOrigin,
-		AllowCredentials: true,
-		AllowedHeaders:   []string{"*"},
-		MaxAge:           900, // 15 mins
+		AllowOriginRequestFunc: gateway.allowOrigin,
+		AllowCredentials:       true,
+		AllowedHeaders:         []string{"*"},
+		MaxAge:                 int(corsMaxAge.Seconds()),
 	})
 	if diagnostics.EnableServerStartedMetric {
 		Diagnostics.Track(diagnostics.ServerStarted, map[string]interface{}{
@@ -1291,6 +1620,7 @@ func (gateway *HandleT) StartAdminHandler(ctx context.Context) error {
 		middleware.StatMiddleware(ctx, srvMux, stats.Default, component),
 		middleware.LimitConcurrentRequests(maxConcurrentRequests),
 	)
+	srvMux.HandleFunc("/v1/sources/throughput", WithContentType("application/json; charset=utf-8", gateway.sourceThroughputHandler)).Methods("GET")
 	srv := &http.Server{
 		Addr:    ":" + strconv.Itoa(adminWebPort),
 		Handler: bugsnag.Handler(srvMux),
@@ -1308,15 +1638,91 @@ func (gateway *HandleT) backendConfigSubscriber() {
 			newEnabledWriteKeyWebhookMap   = map[string]string{}
 			newEnabledWriteKeyWorkspaceMap = map[string]string{}
 			newSourceIDToNameMap           = map[string]string{}
+			newEventValidatorMap           = map[string]*eventValidator{}
+			newBotFilterMap                = map[string]*sourceBotFilter{}
+			newEventAgeFilterMap           = map[string]*eventAgeFilter{}
+			newIPPrivacyMap                = map[string]*ipPrivacyConfig{}
+			newOriginMatcherMap            = map[string]*originMatcher{}
+			newRestrictPreflightOrigins    = true
+			newServiceTokenWriteKeysMap    = map[string]map[string]struct{}{}
+			newRequestSamplingMap          = map[string]*requestSampling{}
+			newKafkaSources                = map[string]kafkaSource{}
+			newPausedWorkspaces            = map[string]struct{}{}
 		)
 		config := data.Data.(map[string]backendconfig.ConfigT)
 		for workspaceID, wsConfig := range config {
+			if wsConfig.Settings.Paused {
+				newPausedWorkspaces[workspaceID] = struct{}{}
+			}
 			for _, source := range wsConfig.Sources {
 				newSourceIDToNameMap[source.ID] = source.Name
 				newWriteKeysSourceMap[source.WriteKey] = source
 
+				validator, err := newEventValidator(source.Config)
+				if err != nil {
+					gateway.logger.Errorf("Invalid event validation config of source %s, events won't be validated: %v", source.ID, err)
+				} else if validator != nil {
+					newEventValidatorMap[source.WriteKey] = validator
+				}
+				botFilter, err := newSourceBotFilter(source.Config)
+				if err != nil {
+					gateway.logger.Errorf("Invalid bot filtering config of source %s, events won't be filtered: %v", source.ID, err)
+				} else if botFilter != nil {
+					newBotFilterMap[source.WriteKey] = botFilter
+				}
+				eventAgeFilter, err := newEventAgeFilter(source.Config)
+				if err != nil {
+					gateway.logger.Errorf("Invalid max event age config of source %s, stale events won't be filtered: %v", source.ID, err)
+				} else if eventAgeFilter != nil {
+					newEventAgeFilterMap[source.WriteKey] = eventAgeFilter
+				}
+				ipPrivacy, err := newIPPrivacy(source.Config)
+				if err != nil {
+					// IPs of sources with an invalid config are truncated, rather than risking to store them as is
+					gateway.logger.Errorf("Invalid ip privacy config of source %s, IPs will be truncated: %v", source.ID, err)
+					ipPrivacy = &ipPrivacyConfig{Anonymization: ipAnonymizationTruncate}
+				}
+				if ipPrivacy != nil {
+					newIPPrivacyMap[source.WriteKey] = ipPrivacy
+				}
+				originMatcher, err := newOriginMatcher(source.Config)
+				if err != nil {
+					gateway.logger.Errorf("Invalid allowed origins of source %s, cross-origin requests won't be allowed from them: %v", source.ID, err)
+				}
+				if originMatcher != nil {
+					newOriginMatcherMap[source.WriteKey] = originMatcher
+				} else if source.Enabled {
+					// preflight requests are allowed from all origins as long as any source allows them
+					newRestrictPreflightOrigins = false
+				}
+
+				sampling, err := newRequestSampling(source.Config)
+				if err != nil {
+					gateway.logger.Errorf("Invalid request sampling config of source %s, requests will be sampled as the ones of other sources: %v", source.ID, err)
+				} else if sampling != nil {
+					newRequestSamplingMap[source.WriteKey] = sampling
+				}
+				serviceTokens, err := newServiceTokens(source.Config)
+				if err != nil {
+					gateway.logger.Errorf("Invalid service tokens of source %s, multiplexed requests won't be authorized: %v", source.ID, err)
+				}
+				for _, token := range serviceTokens {
+					if newServiceTokenWriteKeysMap[token] == nil {
+						newServiceTokenWriteKeysMap[token] = map[string]struct{}{}
+					}
+					newServiceTokenWriteKeysMap[token][source.WriteKey] = struct{}{}
+				}
+
 				if source.Enabled {
 					newEnabledWriteKeyWorkspaceMap[source.WriteKey] = workspaceID
+					if gateway.kafkaIngestion != nil {
+						kafkaConfig, err := newKafkaSourceConfig(source.ID, source.Config)
+						if err != nil {
+							gateway.logger.Errorf("Invalid kafka config of source %s, events won't be ingested from kafka: %v", source.ID, err)
+						} else if kafkaConfig != nil {
+							newKafkaSources[source.ID] = kafkaSource{sourceID: source.ID, writeKey: source.WriteKey, config: *kafkaConfig}
+						}
+					}
 					if source.SourceDefinition.Category == "webhook" {
 						newEnabledWriteKeyWebhookMap[source.WriteKey] = source.SourceDefinition.Name
 						gateway.webhookHandler.Register(source.SourceDefinition.Name)
@@ -1329,7 +1735,19 @@ func (gateway *HandleT) backendConfigSubscriber() {
 		enabledWriteKeyWebhookMap = newEnabledWriteKeyWebhookMap
 		enabledWriteKeyWorkspaceMap = newEnabledWriteKeyWorkspaceMap
 		sourceIDToNameMap = newSourceIDToNameMap
+		writeKeyEventValidatorMap = newEventValidatorMap
+		writeKeyBotFilterMap = newBotFilterMap
+		writeKeyEventAgeFilterMap = newEventAgeFilterMap
+		writeKeyIPPrivacyMap = newIPPrivacyMap
+		writeKeyOriginMatcherMap = newOriginMatcherMap
+		restrictPreflightOrigins = newRestrictPreflightOrigins
+		serviceTokenWriteKeysMap = newServiceTokenWriteKeysMap
+		writeKeyRequestSamplingMap = newRequestSamplingMap
+		pausedWorkspaces = newPausedWorkspaces
 		configSubscriberLock.Unlock()
+		if gateway.kafkaIngestion != nil {
+			gateway.kafkaIngestion.update(newKafkaSources)
+		}
 	}
 }
 
@@ -1343,17 +1761,32 @@ Finds the worker for a particular userID and queues the webrequest with the work
 They are further batched together in userWebRequestBatcher
 */
 func (gateway *HandleT) addToWebRequestQ(_ *http.ResponseWriter, req *http.Request, done chan string, reqType string, requestPayload []byte, writeKey string) {
-	userIDHeader := req.Header.Get("AnonymousId")
-	workerKey := userIDHeader
-	if userIDHeader == "" {
+	gateway.queueWebRequest(&webRequestT{
+		done:           done,
+		reqType:        reqType,
+		requestPayload: requestPayload,
+		writeKey:       writeKey,
+		ipAddr:         misc.GetIPFromReq(req),
+		userIDHeader:   req.Header.Get("AnonymousId"),
+		spanContext:    trace.SpanContextFromContext(req.Context()),
+	})
+}
+
+// enqueueWebRequest queues a webrequest with the worker of userIDHeader, or a random worker if it is empty
+func (gateway *HandleT) enqueueWebRequest(done chan string, reqType string, requestPayload []byte, writeKey, userIDHeader, ipAddr string) {
+	gateway.queueWebRequest(&webRequestT{done: done, reqType: reqType, requestPayload: requestPayload, writeKey: writeKey, ipAddr: ipAddr, userIDHeader: userIDHeader})
+}
+
+// queueWebRequest queues webReq with the worker of its userIDHeader, or a random worker if it is empty
+func (gateway *HandleT) queueWebRequest(webReq *webRequestT) {
+	workerKey := webReq.userIDHeader
+	if workerKey == "" {
 		// If the request comes through proxy, proxy would already send this. So this shouldn't be happening in that case
 		workerKey = uuid.New().String()
 		gateway.emptyAnonIdHeaderStat.Increment()
 	}
 	userWebRequestWorker := gateway.findUserWebRequestWorker(workerKey)
-	ipAddr := misc.GetIPFromReq(req)
-	webReq := webRequestT{done: done, reqType: reqType, requestPayload: requestPayload, writeKey: writeKey, ipAddr: ipAddr, userIDHeader: userIDHeader}
-	userWebRequestWorker.webRequestQ <- &webReq
+	userWebRequestWorker.webRequestQ <- webReq
 }
 
 // IncrementRecvCount increments the received count for gateway requests
@@ -1384,10 +1817,28 @@ func (*HandleT) GetWebhookSourceDefName(writeKey string) (name string, ok bool)
 	return
 }
 
+// GetWebhookSecret returns the secret webhooks of a source are signed with, empty if the source has none
+func (*HandleT) GetWebhookSecret(writeKey string) string {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	secret, _ := writeKeysSourceMap[writeKey].Config["webhookSecret"].(string)
+	return secret
+}
+
 func (gateway *HandleT) SetReadonlyDB(readonlyGatewayDB jobsdb.ReadonlyJobsDB) {
 	gateway.readonlyGatewayDB = readonlyGatewayDB
 }
 
+// WorkspaceQuotas limits the events accepted from workspaces
+type WorkspaceQuotas interface {
+	AllowEvents(workspaceID string, events int) bool
+}
+
+// SetWorkspaceQuotas has the requests of workspaces exceeding their events quota rejected with a 429
+func (gateway *HandleT) SetWorkspaceQuotas(workspaceQuotas WorkspaceQuotas) {
+	gateway.workspaceQuotas = workspaceQuotas
+}
+
 /*
 Setup initializes this module:
 - Monitors backend config for changes.
@@ -1464,6 +1915,35 @@ func (gateway *HandleT) Setup(
 		gateway.eventSchemaHandler = event_schema.GetInstance()
 	}
 
+	if geoDBPath != "" {
+		geoLocator, err := geolocation.NewMaxmindLocator(geoDBPath)
+		if err != nil {
+			return fmt.Errorf("could not open geoip database: %w", err)
+		}
+		gateway.geoLocator = geoLocator
+	}
+
+	if enableDedup {
+		gateway.dedup, err = newDedup()
+		if err != nil {
+			return fmt.Errorf("could not setup dedup: %w", err)
+		}
+	}
+
+	if enableAdmissionControl {
+		gateway.admission = &admissionController{}
+	}
+
+	if enableThroughputTracking {
+		gateway.throughput = newThroughputTracker(throughputWindow)
+	}
+
+	// workers are initialised before subscribing to backend config, since kafka ingestion queues requests with them as soon as it gets sources
+	gateway.initUserWebRequestWorkers()
+	if enableKafkaIngestion {
+		gateway.kafkaIngestion = newKafkaIngestion(gateway)
+	}
+
 	rruntime.Go(func() {
 		gateway.backendConfigSubscriber()
 	})
@@ -1473,7 +1953,6 @@ func (gateway *HandleT) Setup(
 
 	gateway.backgroundCancel = cancel
 	gateway.backgroundWait = g.Wait
-	gateway.initUserWebRequestWorkers()
 
 	g.Go(misc.WithBugsnag(func() error {
 		gateway.runUserWebRequestWorkers(ctx)
@@ -1499,6 +1978,10 @@ func (gateway *HandleT) Setup(
 }
 
 func (gateway *HandleT) Shutdown() error {
+	// stopping kafka ingestion first, since its consumers are feeding the user web request workers
+	if gateway.kafkaIngestion != nil {
+		gateway.kafkaIngestion.stop()
+	}
 	gateway.backgroundCancel()
 	if err := gateway.webhookHandler.Shutdown(); err != nil {
 		return err
@@ -1509,7 +1992,16 @@ func (gateway *HandleT) Shutdown() error {
 		close(worker.webRequestQ)
 	}
 
-	return gateway.backgroundWait()
+	if err := gateway.backgroundWait(); err != nil {
+		return err
+	}
+	if gateway.dedup != nil {
+		gateway.dedup.Close()
+	}
+	if gateway.geoLocator != nil {
+		return gateway.geoLocator.Close()
+	}
+	return nil
 }
 
 func WithContentType(contentType string, delegate http.HandlerFunc) http.HandlerFunc {
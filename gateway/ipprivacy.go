@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// Modes of anonymizing client IPs
+const (
+	// ipAnonymizationTruncate zeroes the last octet of IPv4 addresses and the last 80 bits of IPv6 addresses
+	ipAnonymizationTruncate = "truncate"
+	// ipAnonymizationHash replaces IPs with their salted sha256 hash
+	ipAnonymizationHash = "hash"
+)
+
+// ipPrivacyConfig is the per source config of handling client IPs, declared under the source config's ipPrivacy key:
+//
+//	"ipPrivacy": {
+//	  "anonymization": "truncate",
+//	  "geoEnrichment": true
+//	}
+//
+// Events are enriched with the location of their IP before it gets anonymized.
+type ipPrivacyConfig struct {
+	Anonymization string `json:"anonymization"`
+	GeoEnrichment bool   `json:"geoEnrichment"`
+}
+
+// newIPPrivacy parses the ip privacy config of a source.
+// It returns nil if the source neither anonymizes IPs nor enriches events with their location.
+func newIPPrivacy(sourceConfig map[string]interface{}) (*ipPrivacyConfig, error) {
+	var privacyConfig ipPrivacyConfig
+	ok, err := parseSourceConfig(sourceConfig, "ipPrivacy", &privacyConfig)
+	if err != nil || !ok {
+		return nil, err
+	}
+	switch privacyConfig.Anonymization {
+	case "", ipAnonymizationTruncate, ipAnonymizationHash:
+	default:
+		return nil, fmt.Errorf("unknown ip anonymization mode: %q", privacyConfig.Anonymization)
+	}
+	if privacyConfig.Anonymization == "" && !privacyConfig.GeoEnrichment {
+		return nil, nil
+	}
+	return &privacyConfig, nil
+}
+
+// anonymizeIP anonymizes ip according to mode. Truncating leaves values which aren't IPs untouched.
+func anonymizeIP(mode, ip string) string {
+	switch mode {
+	case ipAnonymizationTruncate:
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return ip
+		}
+		if ipv4 := parsed.To4(); ipv4 != nil {
+			return ipv4.Mask(net.CIDRMask(24, 32)).String()
+		}
+		return parsed.Mask(net.CIDRMask(48, 128)).String()
+	case ipAnonymizationHash:
+		if ip == "" {
+			return ip
+		}
+		hash := sha256.Sum256([]byte(ipHashSalt + ip))
+		return hex.EncodeToString(hash[:])
+	default:
+		return ip
+	}
+}
+
+// applyIPPrivacy enriches the events of a request received from ipAddr with their location and anonymizes their context.ip,
+// according to the ip privacy config of their source. It returns ipAddr, anonymized as well.
+func (gateway *HandleT) applyIPPrivacy(privacy *ipPrivacyConfig, sourceTags map[string]string, ipAddr string, events []map[string]interface{}) string {
+	if privacy == nil {
+		return ipAddr
+	}
+	for _, event := range events {
+		eventContext, _ := event["context"].(map[string]interface{})
+		contextIP, _ := eventContext["ip"].(string)
+
+		if privacy.GeoEnrichment && gateway.geoLocator != nil {
+			ip := net.ParseIP(contextIP)
+			if ip == nil {
+				ip = net.ParseIP(ipAddr)
+			}
+			if ip != nil {
+				gateway.enrichLocation(sourceTags, event, ip)
+			}
+		}
+
+		if privacy.Anonymization != "" && contextIP != "" {
+			eventContext["ip"] = anonymizeIP(privacy.Anonymization, contextIP)
+		}
+	}
+	return anonymizeIP(privacy.Anonymization, ipAddr)
+}
+
+// enrichLocation sets the country and region of ip under the event's context.location, unless they are already set
+func (gateway *HandleT) enrichLocation(sourceTags map[string]string, event map[string]interface{}, ip net.IP) {
+	location, err := gateway.geoLocator.Locate(ip)
+	if err != nil {
+		gateway.logger.Debugf("Error looking up location of %s: %v", ip, err)
+		gateway.stats.NewTaggedStat("gateway.geo_lookup_errors", stats.CountType, stats.Tags{
+			"sourceID": sourceTags["sourceID"],
+			"writeKey": sourceTags["writeKey"],
+		}).Increment()
+		return
+	}
+	if location.Country == "" && location.Region == "" {
+		return
+	}
+
+	eventContext, ok := event["context"].(map[string]interface{})
+	if !ok {
+		eventContext = make(map[string]interface{})
+		event["context"] = eventContext
+	}
+	eventLocation, ok := eventContext["location"].(map[string]interface{})
+	if !ok {
+		eventLocation = make(map[string]interface{})
+		eventContext["location"] = eventLocation
+	}
+	if _, ok := eventLocation["country"]; !ok && location.Country != "" {
+		eventLocation["country"] = location.Country
+	}
+	if _, ok := eventLocation["region"]; !ok && location.Region != "" {
+		eventLocation["region"] = location.Region
+	}
+}
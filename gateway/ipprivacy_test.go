@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/services/geolocation"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+type staticGeoLocator map[string]geolocation.Location
+
+func (l staticGeoLocator) Locate(ip net.IP) (geolocation.Location, error) {
+	location, ok := l[ip.String()]
+	if !ok {
+		return geolocation.Location{}, errors.New("not found")
+	}
+	return location, nil
+}
+
+func (staticGeoLocator) Close() error { return nil }
+
+func TestAnonymizeIP(t *testing.T) {
+	prevSalt := ipHashSalt
+	ipHashSalt = "salt"
+	defer func() { ipHashSalt = prevSalt }()
+
+	require.Equal(t, "192.0.2.0", anonymizeIP(ipAnonymizationTruncate, "192.0.2.123"))
+	require.Equal(t, "2001:db8:85a3::", anonymizeIP(ipAnonymizationTruncate, "2001:db8:85a3:8d3:1319:8a2e:370:7348"))
+	require.Equal(t, "not-an-ip", anonymizeIP(ipAnonymizationTruncate, "not-an-ip"))
+
+	hashed := anonymizeIP(ipAnonymizationHash, "192.0.2.123")
+	require.Len(t, hashed, 64)
+	require.Equal(t, hashed, anonymizeIP(ipAnonymizationHash, "192.0.2.123"))
+	require.NotEqual(t, hashed, anonymizeIP(ipAnonymizationHash, "192.0.2.124"))
+	require.Equal(t, "", anonymizeIP(ipAnonymizationHash, ""))
+
+	require.Equal(t, "192.0.2.123", anonymizeIP("", "192.0.2.123"))
+}
+
+func TestIPPrivacy(t *testing.T) {
+	sourceTags := map[string]string{"sourceID": "source-1", "writeKey": "write-key-1"}
+
+	t.Run("no config", func(t *testing.T) {
+		privacy, err := newIPPrivacy(sourceConfigWith(t, "ipPrivacy", map[string]interface{}{}))
+		require.NoError(t, err)
+		require.Nil(t, privacy)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		_, err := newIPPrivacy(sourceConfigWith(t, "ipPrivacy", map[string]interface{}{"anonymization": "mask"}))
+		require.Error(t, err)
+	})
+
+	t.Run("geo enrichment and truncation", func(t *testing.T) {
+		store := memstats.New()
+		gateway := &HandleT{stats: store, logger: logger.NOP, geoLocator: staticGeoLocator{
+			"192.0.2.123":  {Country: "US", Region: "California"},
+			"203.0.113.45": {Country: "DE", Region: "Berlin"},
+		}}
+		privacy, err := newIPPrivacy(sourceConfigWith(t, "ipPrivacy", map[string]interface{}{
+			"anonymization": ipAnonymizationTruncate,
+			"geoEnrichment": true,
+		}))
+		require.NoError(t, err)
+
+		events := []map[string]interface{}{
+			{"type": "track"},
+			{"type": "track", "context": map[string]interface{}{"ip": "203.0.113.45"}},
+			{"type": "track", "context": map[string]interface{}{"location": map[string]interface{}{"country": "FR"}}},
+			{"type": "track", "context": map[string]interface{}{"ip": "198.51.100.1"}},
+		}
+		ipAddr := gateway.applyIPPrivacy(privacy, sourceTags, "192.0.2.123", events)
+		require.Equal(t, "192.0.2.0", ipAddr)
+
+		require.Equal(t, map[string]interface{}{"country": "US", "region": "California"}, events[0]["context"].(map[string]interface{})["location"])
+		require.Equal(t, map[string]interface{}{
+			"ip":       "203.0.113.0",
+			"location": map[string]interface{}{"country": "DE", "region": "Berlin"},
+		}, events[1]["context"])
+		require.Equal(t, map[string]interface{}{"country": "FR", "region": "California"}, events[2]["context"].(map[string]interface{})["location"])
+		require.Equal(t, map[string]interface{}{"ip": "198.51.100.0"}, events[3]["context"])
+		require.EqualValues(t, 1, store.Get("gateway.geo_lookup_errors", stats.Tags{
+			"sourceID": "source-1",
+			"writeKey": "write-key-1",
+		}).LastValue())
+	})
+
+	t.Run("geo enrichment without database", func(t *testing.T) {
+		gateway := &HandleT{stats: memstats.New(), logger: logger.NOP}
+		privacy, err := newIPPrivacy(sourceConfigWith(t, "ipPrivacy", map[string]interface{}{"geoEnrichment": true}))
+		require.NoError(t, err)
+
+		events := []map[string]interface{}{{"type": "track"}}
+		require.Equal(t, "192.0.2.123", gateway.applyIPPrivacy(privacy, sourceTags, "192.0.2.123", events))
+		require.NotContains(t, events[0], "context")
+	})
+}
@@ -0,0 +1,320 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/streammanager/kafka/client"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// kafkaSourceConfig is the config of sources ingesting their events from a Kafka topic, declared under the source config's kafka key:
+//
+//	"kafka": {
+//	  "brokers": ["broker-1:9092", "broker-2:9092"],
+//	  "topic": "events",
+//	  "sslEnabled": true,
+//	  "useSASL": true,
+//	  "saslType": "sha512",
+//	  "username": "...",
+//	  "password": "..."
+//	}
+//
+// Each message is either a single event, carrying its type, or a batch of events under the batch key, just like gateway requests.
+type kafkaSourceConfig struct {
+	Brokers            []string `json:"brokers"`
+	Topic              string   `json:"topic"`
+	GroupID            string   `json:"groupId"`
+	StartFromBeginning bool     `json:"startFromBeginning"`
+	SslEnabled         bool     `json:"sslEnabled"`
+	CACertificate      string   `json:"caCertificate"`
+	UseSASL            bool     `json:"useSASL"`
+	SaslType           string   `json:"saslType"`
+	Username           string   `json:"username"`
+	Password           string   `json:"password"`
+}
+
+// newKafkaSourceConfig parses the kafka config of a source.
+// It returns nil if the source isn't ingested from a kafka topic.
+func newKafkaSourceConfig(sourceID string, sourceConfig map[string]interface{}) (*kafkaSourceConfig, error) {
+	var kafkaConfig kafkaSourceConfig
+	ok, err := parseSourceConfig(sourceConfig, "kafka", &kafkaConfig)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if len(kafkaConfig.Brokers) == 0 {
+		return nil, fmt.Errorf("brokers cannot be empty")
+	}
+	if kafkaConfig.Topic == "" {
+		return nil, fmt.Errorf("topic cannot be empty")
+	}
+	if kafkaConfig.GroupID == "" {
+		kafkaConfig.GroupID = "rudder-gateway-" + sourceID
+	}
+	return &kafkaConfig, nil
+}
+
+// kafkaConsumer is the subset of the kafka client's consumer used for ingesting events
+type kafkaConsumer interface {
+	Fetch(ctx context.Context) (client.Message, error)
+	Commit(ctx context.Context, msgs ...client.Message) error
+	Close(ctx context.Context) error
+}
+
+// newKafkaConsumer connects to the brokers of a source's kafka config and returns a consumer of its topic
+func newKafkaConsumer(conf *kafkaSourceConfig) (kafkaConsumer, error) {
+	clientConf := client.Config{
+		ClientID:    "rudder-gateway",
+		DialTimeout: kafkaDialTimeout,
+	}
+	if conf.SslEnabled {
+		if conf.CACertificate != "" {
+			clientConf.TLS = &client.TLS{CACertificate: []byte(conf.CACertificate)}
+		} else {
+			clientConf.TLS = &client.TLS{WithSystemCertPool: true}
+		}
+		if conf.UseSASL { // SASL is enabled only with SSL
+			clientConf.SASL = &client.SASL{
+				Username: conf.Username,
+				Password: conf.Password,
+			}
+			var err error
+			clientConf.SASL.ScramHashGen, err = client.ScramHashGeneratorFromString(conf.SaslType)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SASL type: %w", err)
+			}
+		}
+	}
+
+	c, err := client.New("tcp", conf.Brokers, clientConf)
+	if err != nil {
+		return nil, fmt.Errorf("could not create client: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaDialTimeout)
+	defer cancel()
+	if err := c.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("could not ping: %w", err)
+	}
+
+	startOffset := client.LastOffset
+	if conf.StartFromBeginning {
+		startOffset = client.FirstOffset
+	}
+	return c.NewConsumer(conf.Topic, client.ConsumerConfig{
+		GroupID:             conf.GroupID,
+		StartOffset:         startOffset,
+		FetchBatchesMaxWait: time.Second,
+	}), nil
+}
+
+// kafkaSource is a source ingesting its events from a Kafka topic
+type kafkaSource struct {
+	sourceID string
+	writeKey string
+	config   kafkaSourceConfig
+}
+
+type runningKafkaSource struct {
+	source kafkaSource
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// kafkaIngestion runs a consumer for each enabled source declaring a kafka config, feeding the consumed events
+// to the user web request workers with the write key of their source, as if they were received through http.
+// Offsets are committed once events are stored, or rejected with a non retryable error.
+type kafkaIngestion struct {
+	gateway     *HandleT
+	newConsumer func(conf *kafkaSourceConfig) (kafkaConsumer, error)
+
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running map[string]*runningKafkaSource
+	stopped bool
+}
+
+func newKafkaIngestion(gateway *HandleT) *kafkaIngestion {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &kafkaIngestion{
+		gateway:     gateway,
+		newConsumer: newKafkaConsumer,
+		ctx:         ctx,
+		cancel:      cancel,
+		running:     make(map[string]*runningKafkaSource),
+	}
+}
+
+// update starts consuming for new sources, and stops consuming for sources removed, disabled or whose config changed
+func (ingestion *kafkaIngestion) update(sources map[string]kafkaSource) {
+	ingestion.mu.Lock()
+	defer ingestion.mu.Unlock()
+	if ingestion.stopped {
+		return
+	}
+
+	for sourceID, r := range ingestion.running {
+		if source, ok := sources[sourceID]; ok && reflect.DeepEqual(source, r.source) {
+			continue
+		}
+		ingestion.gateway.logger.Infof("Stopping kafka ingestion of source %s", sourceID)
+		r.cancel()
+		<-r.done
+		delete(ingestion.running, sourceID)
+	}
+	for sourceID, source := range sources {
+		if _, ok := ingestion.running[sourceID]; ok {
+			continue
+		}
+		ingestion.gateway.logger.Infof("Starting kafka ingestion of source %s from topic %s", sourceID, source.config.Topic)
+		ctx, cancel := context.WithCancel(ingestion.ctx)
+		r := &runningKafkaSource{source: source, cancel: cancel, done: make(chan struct{})}
+		ingestion.running[sourceID] = r
+		go func() {
+			defer close(r.done)
+			ingestion.run(ctx, r.source)
+		}()
+	}
+}
+
+// stop stops consuming for all sources, waiting for in-flight messages to be handled
+func (ingestion *kafkaIngestion) stop() {
+	ingestion.mu.Lock()
+	defer ingestion.mu.Unlock()
+	ingestion.stopped = true
+	ingestion.cancel()
+	for sourceID, r := range ingestion.running {
+		<-r.done
+		delete(ingestion.running, sourceID)
+	}
+}
+
+// run connects to the source's brokers, retrying until it succeeds, and consumes its topic until ctx is canceled
+func (ingestion *kafkaIngestion) run(ctx context.Context, source kafkaSource) {
+	var consumer kafkaConsumer
+	err := backoff.RetryNotify(func() (err error) {
+		consumer, err = ingestion.newConsumer(&source.config)
+		return err
+	}, backoff.WithContext(newKafkaBackoff(), ctx), func(err error, d time.Duration) {
+		ingestion.gateway.logger.Errorf("Could not connect to kafka for source %s, retrying in %v: %v", source.sourceID, d, err)
+	})
+	if err != nil {
+		return
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), kafkaDialTimeout)
+		defer cancel()
+		if err := consumer.Close(closeCtx); err != nil {
+			ingestion.gateway.logger.Warnf("Error closing kafka consumer of source %s: %v", source.sourceID, err)
+		}
+	}()
+
+	for {
+		msg, err := consumer.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			ingestion.gateway.logger.Errorf("Error fetching kafka message of source %s: %v", source.sourceID, err)
+			if err := misc.SleepCtx(ctx, kafkaFetchRetryInterval); err != nil {
+				return
+			}
+			continue
+		}
+
+		err = backoff.Retry(func() error {
+			errorMessage, err := ingestion.gateway.ingestKafkaMessage(ctx, source, msg)
+			if err != nil {
+				return backoff.Permanent(err)
+			}
+			if errorMessage != "" && isRetryableStatusCode(response.GetErrorStatusCode(errorMessage)) {
+				return errors.New(errorMessage)
+			}
+			return nil
+		}, backoff.WithContext(newKafkaBackoff(), ctx))
+		if err != nil { // only happens once ctx is canceled, the message will be consumed again
+			return
+		}
+		if err := consumer.Commit(ctx, msg); err != nil && ctx.Err() == nil {
+			ingestion.gateway.logger.Errorf("Error committing kafka message of source %s: %v", source.sourceID, err)
+		}
+	}
+}
+
+// ingestKafkaMessage queues the events of a kafka message with the user web request workers and waits for them to be handled.
+// It returns the error message of the request, empty if the events were stored, or an error if ctx was canceled while waiting.
+func (gateway *HandleT) ingestKafkaMessage(ctx context.Context, source kafkaSource, msg client.Message) (string, error) {
+	atomic.AddUint64(&gateway.recvCount, 1)
+	var errorMessage string
+	reqType := kafkaRequestType(msg.Value)
+	switch {
+	case len(msg.Value) > maxReqSize:
+		errorMessage = response.RequestBodyTooLarge
+	case !gjson.ValidBytes(msg.Value):
+		errorMessage = response.InvalidJSON
+	case reqType == "":
+		errorMessage = response.NotRudderEvent
+	case !gateway.admitRequest(reqType):
+		errorMessage = response.ServiceUnavailable
+	default:
+		done := make(chan string, 1)
+		gateway.enqueueWebRequest(done, reqType, msg.Value, source.writeKey, string(msg.Key), "")
+		select {
+		case errorMessage = <-done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	atomic.AddUint64(&gateway.ackCount, 1)
+	gateway.trackRequestMetrics(errorMessage)
+
+	status := "success"
+	if errorMessage != "" {
+		status = "failed"
+		if isRetryableStatusCode(response.GetErrorStatusCode(errorMessage)) {
+			status = "retried"
+		}
+		gateway.logger.Debugf("Kafka message %s/%d/%d of source %s -- Response: %d, %s", msg.Topic, msg.Partition, msg.Offset,
+			source.sourceID, response.GetErrorStatusCode(errorMessage), errorMessage)
+	}
+	gateway.stats.NewTaggedStat("gateway.kafka_messages", stats.CountType, stats.Tags{
+		"sourceID": source.sourceID,
+		"writeKey": source.writeKey,
+		"topic":    msg.Topic,
+		"reqType":  reqType,
+		"status":   status,
+	}).Increment()
+	return errorMessage, nil
+}
+
+// kafkaRequestType returns the request type of a kafka message, batch if it carries a batch of events, the event's type otherwise
+func kafkaRequestType(payload []byte) string {
+	if gjson.GetBytes(payload, "batch").Exists() {
+		return "batch"
+	}
+	return gjson.GetBytes(payload, "type").String()
+}
+
+// isRetryableStatusCode is true if a request failing with statusCode might succeed later on
+func isRetryableStatusCode(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests
+}
+
+func newKafkaBackoff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = kafkaFetchRetryInterval
+	b.MaxInterval = time.Minute
+	b.MaxElapsedTime = 0
+	return b
+}
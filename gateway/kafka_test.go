@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/services/streammanager/kafka/client"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+type fakeKafkaConsumer struct {
+	mu        sync.Mutex
+	messages  []client.Message
+	committed []client.Message
+	closed    bool
+}
+
+func (c *fakeKafkaConsumer) Fetch(ctx context.Context) (client.Message, error) {
+	c.mu.Lock()
+	if len(c.messages) > 0 {
+		msg := c.messages[0]
+		c.messages = c.messages[1:]
+		c.mu.Unlock()
+		return msg, nil
+	}
+	c.mu.Unlock()
+	<-ctx.Done()
+	return client.Message{}, io.EOF
+}
+
+func (c *fakeKafkaConsumer) Commit(_ context.Context, msgs ...client.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.committed = append(c.committed, msgs...)
+	return nil
+}
+
+func (c *fakeKafkaConsumer) Close(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeKafkaConsumer) committedOffsets() []int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	offsets := make([]int64, 0, len(c.committed))
+	for _, msg := range c.committed {
+		offsets = append(offsets, msg.Offset)
+	}
+	return offsets
+}
+
+func TestKafkaSourceConfig(t *testing.T) {
+	t.Run("no kafka config", func(t *testing.T) {
+		conf, err := newKafkaSourceConfig("source-1", map[string]interface{}{})
+		require.NoError(t, err)
+		require.Nil(t, conf)
+	})
+
+	t.Run("missing topic", func(t *testing.T) {
+		_, err := newKafkaSourceConfig("source-1", sourceConfigWith(t, "kafka", map[string]interface{}{"brokers": []interface{}{"localhost:9092"}}))
+		require.Error(t, err)
+	})
+
+	t.Run("default group", func(t *testing.T) {
+		conf, err := newKafkaSourceConfig("source-1", sourceConfigWith(t, "kafka", map[string]interface{}{"brokers": []interface{}{"localhost:9092"}, "topic": "events"}))
+		require.NoError(t, err)
+		require.Equal(t, "rudder-gateway-source-1", conf.GroupID)
+		require.Equal(t, []string{"localhost:9092"}, conf.Brokers)
+	})
+}
+
+func TestKafkaRequestType(t *testing.T) {
+	require.Equal(t, "batch", kafkaRequestType([]byte(`{"batch":[{"type":"track"}]}`)))
+	require.Equal(t, "identify", kafkaRequestType([]byte(`{"type":"identify","userId":"user-1"}`)))
+	require.Equal(t, "", kafkaRequestType([]byte(`{"userId":"user-1"}`)))
+}
+
+func TestKafkaIngestion(t *testing.T) {
+	initGW()
+	store := memstats.New()
+	gateway := &HandleT{stats: store, logger: logger.NOP}
+	gateway.initUserWebRequestWorkers()
+
+	// responding to queued requests like user web request workers would, failing the first attempt of user-2 with a retryable error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan *webRequestT, 10)
+	for _, worker := range gateway.userWebRequestWorkers {
+		worker := worker
+		go func() {
+			failed := false
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case req := <-worker.webRequestQ:
+					received <- req
+					if req.userIDHeader == "user-2" && !failed {
+						failed = true
+						req.done <- response.GetStatus(response.TooManyRequests)
+						continue
+					}
+					req.done <- ""
+				}
+			}
+		}()
+	}
+
+	consumer := &fakeKafkaConsumer{messages: []client.Message{
+		{Topic: "events", Offset: 1, Key: []byte("user-1"), Value: []byte(`{"type":"identify","userId":"user-1"}`)},
+		{Topic: "events", Offset: 2, Key: []byte("user-2"), Value: []byte(`{"batch":[{"type":"track","userId":"user-2"}]}`)},
+		{Topic: "events", Offset: 3, Key: []byte("user-3"), Value: []byte(`not json`)},
+	}}
+	ingestion := newKafkaIngestion(gateway)
+	ingestion.newConsumer = func(*kafkaSourceConfig) (kafkaConsumer, error) { return consumer, nil }
+	ingestion.update(map[string]kafkaSource{
+		"source-1": {sourceID: "source-1", writeKey: "write-key-1", config: kafkaSourceConfig{Topic: "events"}},
+	})
+
+	require.Eventually(t, func() bool {
+		return len(consumer.committedOffsets()) == 3
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, []int64{1, 2, 3}, consumer.committedOffsets())
+
+	req := <-received
+	require.Equal(t, "identify", req.reqType)
+	require.Equal(t, "write-key-1", req.writeKey)
+	require.Equal(t, "user-1", req.userIDHeader)
+	for i := 0; i < 2; i++ {
+		req = <-received
+		require.Equal(t, "batch", req.reqType)
+		require.Equal(t, "user-2", req.userIDHeader)
+	}
+	require.Empty(t, received, "invalid json messages shouldn't be queued")
+
+	tags := func(reqType, status string) stats.Tags {
+		return stats.Tags{"sourceID": "source-1", "writeKey": "write-key-1", "topic": "events", "reqType": reqType, "status": status}
+	}
+	require.EqualValues(t, 1, store.Get("gateway.kafka_messages", tags("batch", "retried")).LastValue())
+	require.EqualValues(t, 1, store.Get("gateway.kafka_messages", tags("", "failed")).LastValue())
+
+	t.Run("sources removed are stopped", func(t *testing.T) {
+		ingestion.update(map[string]kafkaSource{})
+		consumer.mu.Lock()
+		defer consumer.mu.Unlock()
+		require.True(t, consumer.closed)
+	})
+
+	ingestion.stop()
+}
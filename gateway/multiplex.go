@@ -0,0 +1,218 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// eventStatusFailed is the status of the events of multiplexed batch requests which failed for transient reasons, e.g. rate limiting,
+// and can be retried, unlike rejected ones
+const eventStatusFailed = "failed"
+
+// newServiceTokens returns the service tokens a source authorizes to send events on its behalf, declared under the source config's serviceTokens key:
+//
+//	"serviceTokens": ["<token>"]
+//
+// Backend services holding a service token can send the events of all the sources authorizing it in multiplexed batch requests,
+// instead of a request per source.
+func newServiceTokens(sourceConfig map[string]interface{}) ([]string, error) {
+	var tokens []string
+	if _, err := parseSourceConfig(sourceConfig, "serviceTokens", &tokens); err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		if strings.TrimSpace(token) == "" {
+			return nil, fmt.Errorf("invalid service token")
+		}
+	}
+	return tokens, nil
+}
+
+// serviceToken returns the service token of a request, from its Authorization: Bearer header
+func serviceToken(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+}
+
+// getServiceTokenWriteKeys returns the write keys of the sources authorizing a service token, nil if none does
+func (*HandleT) getServiceTokenWriteKeys(token string) map[string]struct{} {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	return serviceTokenWriteKeysMap[token]
+}
+
+// multiplexedGroup is the events of a multiplexed batch request sent to a write key, along with their indexes in the request
+type multiplexedGroup struct {
+	writeKey string
+	indexes  []int
+	events   []json.RawMessage
+	size     int
+	done     chan string
+	results  *eventResults
+}
+
+// multiplexedBatchHandler handles batch requests of backend services authenticated with a service token, whose events declare
+// the write key they are sent to under their writeKey field:
+//
+//	{"batch": [{"writeKey": "<write key>", "type": "track", ...}]}
+//
+// Events are authorized on their own, against the sources authorizing the token, and the response carries the result of each of them,
+// the same way as batch requests in per event response mode. The events sent to each write key are processed as a batch request of their own.
+func (gateway *HandleT) multiplexedBatchHandler(w http.ResponseWriter, r *http.Request) {
+	const reqType = "batch"
+	webReqHandlerTime := gateway.stats.NewTaggedStat("gateway.web_req_handler_time", stats.TimerType, stats.Tags{"reqType": reqType})
+	webReqHandlerStartTime := time.Now()
+	defer webReqHandlerTime.Since(webReqHandlerStartTime)
+
+	gateway.logger.LogRequest(r)
+	atomic.AddUint64(&gateway.recvCount, 1)
+	r, span := startRequestSpan(r, reqType)
+	var errorMessage string
+	defer func() {
+		if errorMessage != "" {
+			gateway.logger.Infof("IP: %s -- %s -- Response: %d, %s", misc.GetIPFromReq(r), r.URL.Path, response.GetErrorStatusCode(errorMessage), errorMessage)
+			http.Error(w, response.GetStatus(errorMessage), response.GetErrorStatusCode(errorMessage))
+		}
+		endSpan(span, errorMessage)
+	}()
+	if gateway.shedRequest(w, reqType) {
+		errorMessage = response.ServiceUnavailable
+		return
+	}
+	token := serviceToken(r)
+	if token == "" {
+		errorMessage = response.NoServiceToken
+		return
+	}
+	writeKeys := gateway.getServiceTokenWriteKeys(token)
+	if writeKeys == nil {
+		gateway.stats.NewTaggedStat("gateway.multiplexed_requests", stats.CountType, stats.Tags{"status": "invalidServiceToken"}).Increment()
+		errorMessage = response.InvalidServiceToken
+		return
+	}
+	payload, err := gateway.getPayloadFromRequest(r, reqType)
+	if err != nil {
+		errorMessage = err.Error()
+		return
+	}
+	if !gjson.ValidBytes(payload) {
+		errorMessage = response.InvalidJSON
+		return
+	}
+
+	results, groups := gateway.groupMultiplexedEvents(payload, writeKeys)
+	start := time.Now()
+	for _, group := range groups {
+		body, _ := json.Marshal(map[string]interface{}{"batch": group.events})
+		group.size = len(body)
+		gateway.queueWebRequest(&webRequestT{
+			done:           group.done,
+			reqType:        reqType,
+			requestPayload: body,
+			writeKey:       group.writeKey,
+			ipAddr:         misc.GetIPFromReq(r),
+			userIDHeader:   r.Header.Get("AnonymousId"),
+			results:        group.results,
+			spanContext:    trace.SpanContextFromContext(r.Context()),
+		})
+	}
+	gateway.addToWebRequestQWaitTime.SendTiming(time.Since(start))
+	for _, group := range groups {
+		groupErrorMessage := <-group.done
+		results.merge(group, groupErrorMessage)
+		gateway.recordThroughput(group.writeKey, groupErrorMessage, group.size)
+	}
+	sort.Slice(results.Results, func(i, j int) bool { return results.Results[i].Index < results.Results[j].Index })
+	gateway.processRequestTime.Since(start)
+
+	atomic.AddUint64(&gateway.ackCount, 1)
+	gateway.trackRequestMetrics("")
+	gateway.stats.NewTaggedStat("gateway.multiplexed_requests", stats.CountType, stats.Tags{"status": "ok"}).Increment()
+	for status, count := range map[string]int{eventStatusAccepted: results.Accepted, eventStatusRejected: results.Rejected, eventStatusFailed: results.Failed} {
+		gateway.stats.NewTaggedStat("gateway.multiplexed_events", stats.CountType, stats.Tags{"status": status}).Count(count)
+	}
+
+	body, _ := json.Marshal(results)
+	gateway.logger.Debugf("IP: %s -- %s -- Response: 200, %d accepted, %d rejected, %d failed", misc.GetIPFromReq(r), r.URL.Path, results.Accepted, results.Rejected, results.Failed)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// groupMultiplexedEvents groups the events of a multiplexed batch request by their write key, rejecting the ones
+// sent to write keys which don't authorize the request's service token
+func (*HandleT) groupMultiplexedEvents(payload []byte, writeKeys map[string]struct{}) (*eventResults, []*multiplexedGroup) {
+	results := &eventResults{Results: []eventResult{}}
+	var groups []*multiplexedGroup
+	groupByWriteKey := map[string]*multiplexedGroup{}
+	index := 0
+	gjson.GetBytes(payload, "batch").ForEach(func(_, event gjson.Result) bool {
+		defer func() { index++ }()
+		writeKey := event.Get("writeKey").String()
+		if _, authorized := writeKeys[writeKey]; !authorized {
+			results.reject(index, event.Get("messageId").String(), response.WriteKeyNotAuthorized)
+			return true
+		}
+		group, ok := groupByWriteKey[writeKey]
+		if !ok {
+			group = &multiplexedGroup{writeKey: writeKey, done: make(chan string, 1), results: &eventResults{}}
+			groupByWriteKey[writeKey] = group
+			groups = append(groups, group)
+		}
+		// the write key of an event is the one of the job it is stored in
+		eventJSON, err := sjson.DeleteBytes([]byte(event.Raw), "writeKey")
+		if err != nil {
+			eventJSON = []byte(event.Raw)
+		}
+		group.indexes = append(group.indexes, index)
+		group.events = append(group.events, eventJSON)
+		return true
+	})
+	return results, groups
+}
+
+// merge merges the results of the events of group into the ones of their multiplexed batch request, by their index in the request.
+// If the group failed as a whole with errorMessage, all of its events are rejected, or failed if errorMessage is transient.
+func (r *eventResults) merge(group *multiplexedGroup, errorMessage string) {
+	if errorMessage != "" {
+		status := eventStatusRejected
+		if statusCode := response.GetErrorStatusCode(errorMessage); statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests {
+			status = eventStatusFailed
+		}
+		for i, index := range group.indexes {
+			if status == eventStatusFailed {
+				r.Failed++
+			} else {
+				r.Rejected++
+			}
+			r.Results = append(r.Results, eventResult{
+				Index:     index,
+				MessageID: gjson.GetBytes(group.events[i], "messageId").String(),
+				Status:    status,
+				Reason:    errorMessage,
+			})
+		}
+	} else {
+		for _, result := range group.results.Results {
+			result.Index = group.indexes[result.Index]
+			r.Results = append(r.Results, result)
+		}
+		r.Accepted += group.results.Accepted
+		r.Rejected += group.results.Rejected
+	}
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/vmihailenco/msgpack/v5"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	proto "github.com/rudderlabs/rudder-server/proto/gateway"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+const (
+	payloadFormatMsgpack  = "msgpack"
+	payloadFormatProtobuf = "protobuf"
+)
+
+// payloadFormats are the binary formats request bodies can be sent in, by Content-Type, instead of JSON
+var payloadFormats = map[string]string{
+	"application/msgpack":    payloadFormatMsgpack,
+	"application/x-msgpack":  payloadFormatMsgpack,
+	"application/protobuf":   payloadFormatProtobuf,
+	"application/x-protobuf": payloadFormatProtobuf,
+}
+
+// decodePayload converts the payload of a request of reqType sent in a binary format according to its Content-Type header,
+// to the JSON form gateway processes and stores. Payloads of any other Content-Type are considered to be JSON already.
+//
+// Msgpack payloads can be sent to any endpoint, whereas protobuf payloads are only supported for batch requests,
+// following the Batch message of proto/gateway/batch.proto.
+func (gateway *HandleT) decodePayload(r *http.Request, reqType string, payload []byte) ([]byte, error) {
+	if !enableBinaryPayloads {
+		return payload, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	format, ok := payloadFormats[mediaType]
+	if !ok {
+		return payload, nil
+	}
+
+	var (
+		decoded []byte
+		err     error
+	)
+	switch format {
+	case payloadFormatMsgpack:
+		decoded, err = msgpackToJSON(payload)
+	case payloadFormatProtobuf:
+		if reqType != "batch" {
+			return nil, errors.New(response.UnsupportedContentType)
+		}
+		decoded, err = protobufBatchToJSON(payload)
+	}
+	if err != nil {
+		gateway.logger.Debugf("Error decoding %s request body: %v", format, err)
+		return nil, errors.New(response.InvalidPayloadFormat)
+	}
+	gateway.stats.NewTaggedStat("gateway.binary_payload_requests", stats.CountType, stats.Tags{
+		"reqType": reqType,
+		"format":  format,
+	}).Increment()
+	return decoded, nil
+}
+
+// msgpackToJSON converts a msgpack payload to JSON. Binary values become base64 strings and timestamps RFC 3339 ones.
+func msgpackToJSON(payload []byte) ([]byte, error) {
+	reader := bytes.NewReader(payload)
+	decoder := msgpack.NewDecoder(reader)
+	decoder.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if reader.Len() > 0 {
+		return nil, fmt.Errorf("%d trailing bytes after msgpack value", reader.Len())
+	}
+	return json.Marshal(toJSONValue(value))
+}
+
+// toJSONValue converts the maps decoded from msgpack, whose keys may be of any type, to maps with string keys for them to be marshalled to JSON
+func toJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			keyString, ok := key.(string)
+			if !ok {
+				keyJSON, _ := json.Marshal(key)
+				keyString = string(keyJSON)
+			}
+			m[keyString] = toJSONValue(value)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = toJSONValue(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// protobufBatchToJSON converts a protobuf Batch payload to the JSON envelope of batch requests
+func protobufBatchToJSON(payload []byte) ([]byte, error) {
+	var batch proto.Batch
+	if err := protobuf.Unmarshal(payload, &batch); err != nil {
+		return nil, err
+	}
+	events := make([]interface{}, len(batch.GetBatch()))
+	for i, event := range batch.GetBatch() {
+		events[i] = event.AsMap()
+	}
+	envelope := map[string]interface{}{"batch": events}
+	if batch.GetSentAt() != "" {
+		envelope["sentAt"] = batch.GetSentAt()
+	}
+	return json.Marshal(envelope)
+}
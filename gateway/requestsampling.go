@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	sourcedebugger "github.com/rudderlabs/rudder-server/services/debugger/source"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// requestSampling is the per source sampling of request payloads to the source debugger, declared under the source config's requestSampling key:
+//
+//	"requestSampling": {
+//	  "rate": 0.001,
+//	  "errorsOnly": true
+//	}
+//
+// Sampled requests are recorded as is, along with the status code and error they were answered with, so that requests failing
+// before their events are recorded, e.g. as invalid JSON, can be diagnosed. Sources not declaring it are sampled according to
+// Gateway.requestSampling.rate and Gateway.requestSampling.errorsOnly.
+type requestSampling struct {
+	Rate       float64 `json:"rate"`
+	ErrorsOnly bool    `json:"errorsOnly"`
+}
+
+// newRequestSampling parses the request sampling of a source.
+// It returns nil if the source doesn't override the request sampling of all sources.
+func newRequestSampling(sourceConfig map[string]interface{}) (*requestSampling, error) {
+	var sampling requestSampling
+	ok, err := parseSourceConfig(sourceConfig, "requestSampling", &sampling)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if sampling.Rate < 0 || sampling.Rate > 1 {
+		return nil, fmt.Errorf("request sampling rate must be between 0 and 1: %v", sampling.Rate)
+	}
+	return &sampling, nil
+}
+
+// getRequestSampling returns the request sampling of a source, the one of all sources if it doesn't declare any
+func (*HandleT) getRequestSampling(writeKey string) requestSampling {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	if sampling, ok := writeKeyRequestSamplingMap[writeKey]; ok {
+		return *sampling
+	}
+	return requestSampling{Rate: requestSamplingRate, ErrorsOnly: requestSamplingErrorsOnly}
+}
+
+// sampleRequest records the payload of a request of writeKey answered with errorMessage to the source debugger, if it is sampled.
+// Payloads are truncated to Gateway.requestSampling.maxPayloadSizeInKB.
+func (gateway *HandleT) sampleRequest(writeKey string, payload []byte, errorMessage string) {
+	if writeKey == "" {
+		return
+	}
+	sampling := gateway.getRequestSampling(writeKey)
+	if sampling.Rate <= 0 || (sampling.ErrorsOnly && errorMessage == "") || rand.Float64() >= sampling.Rate { // skipcq: GSC-G404
+		return
+	}
+	sourceID := gateway.getSourceIDForWriteKey(writeKey)
+	if sourceID == "" {
+		return
+	}
+
+	statusCode := http.StatusOK
+	if errorMessage != "" {
+		statusCode = response.GetErrorStatusCode(errorMessage)
+	}
+	if len(payload) > requestSamplingMaxPayloadSize {
+		payload = payload[:requestSamplingMaxPayloadSize]
+	}
+	gateway.stats.NewTaggedStat("gateway.sampled_requests", stats.CountType, stats.Tags{
+		"sourceID":   sourceID,
+		"writeKey":   writeKey,
+		"statusCode": strconv.Itoa(statusCode),
+	}).Increment()
+	sourcedebugger.RecordRequest(writeKey, payload, statusCode, errorMessage)
+}
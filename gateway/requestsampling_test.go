@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestRequestSampling(t *testing.T) {
+	configSubscriberLock.Lock()
+	prevWriteKeysSourceMap, prevRequestSamplingMap := writeKeysSourceMap, writeKeyRequestSamplingMap
+	prevRate, prevErrorsOnly, prevMaxPayloadSize := requestSamplingRate, requestSamplingErrorsOnly, requestSamplingMaxPayloadSize
+	writeKeysSourceMap = map[string]backendconfig.SourceT{
+		"sampled-write-key": {ID: "sampled-source"},
+		"errors-write-key":  {ID: "errors-source"},
+	}
+	writeKeyRequestSamplingMap = map[string]*requestSampling{
+		"sampled-write-key": {Rate: 1},
+		"errors-write-key":  {Rate: 1, ErrorsOnly: true},
+	}
+	requestSamplingRate, requestSamplingErrorsOnly, requestSamplingMaxPayloadSize = 0, false, 16
+	configSubscriberLock.Unlock()
+	defer func() {
+		configSubscriberLock.Lock()
+		writeKeysSourceMap, writeKeyRequestSamplingMap = prevWriteKeysSourceMap, prevRequestSamplingMap
+		requestSamplingRate, requestSamplingErrorsOnly, requestSamplingMaxPayloadSize = prevRate, prevErrorsOnly, prevMaxPayloadSize
+		configSubscriberLock.Unlock()
+	}()
+
+	t.Run("config", func(t *testing.T) {
+		sampling, err := newRequestSampling(map[string]interface{}{})
+		require.NoError(t, err)
+		require.Nil(t, sampling)
+
+		sampling, err = newRequestSampling(sourceConfigWith(t, "requestSampling", map[string]interface{}{"rate": 0.01, "errorsOnly": true}))
+		require.NoError(t, err)
+		require.Equal(t, &requestSampling{Rate: 0.01, ErrorsOnly: true}, sampling)
+
+		for _, rate := range []interface{}{-0.1, 1.5, "all"} {
+			_, err = newRequestSampling(sourceConfigWith(t, "requestSampling", map[string]interface{}{"rate": rate}))
+			require.Error(t, err, rate)
+		}
+	})
+
+	t.Run("sources not declaring any", func(t *testing.T) {
+		gateway := &HandleT{stats: memstats.New(), logger: logger.NOP}
+		require.Equal(t, requestSampling{Rate: 1}, gateway.getRequestSampling("sampled-write-key"))
+		require.Equal(t, requestSampling{}, gateway.getRequestSampling("other-write-key"))
+	})
+
+	t.Run("sample", func(t *testing.T) {
+		store := memstats.New()
+		gateway := &HandleT{stats: store, logger: logger.NOP}
+
+		gateway.sampleRequest("sampled-write-key", []byte(`{"batch": [{"type": "track"}]}`), "")
+		gateway.sampleRequest("sampled-write-key", []byte(`{"batch": [`), response.InvalidJSON)
+		gateway.sampleRequest("errors-write-key", []byte(`{"batch": []}`), "")
+		gateway.sampleRequest("errors-write-key", []byte(`{"batch": []}`), response.RequestBodyTooLarge)
+		gateway.sampleRequest("other-write-key", []byte(`{"batch": []}`), response.InvalidJSON)
+
+		require.EqualValues(t, 1, store.Get("gateway.sampled_requests", stats.Tags{
+			"sourceID": "sampled-source", "writeKey": "sampled-write-key", "statusCode": "200",
+		}).LastValue())
+		require.EqualValues(t, 1, store.Get("gateway.sampled_requests", stats.Tags{
+			"sourceID": "sampled-source", "writeKey": "sampled-write-key", "statusCode": "400",
+		}).LastValue())
+		require.Nil(t, store.Get("gateway.sampled_requests", stats.Tags{
+			"sourceID": "errors-source", "writeKey": "errors-write-key", "statusCode": "200",
+		}))
+		require.EqualValues(t, 1, store.Get("gateway.sampled_requests", stats.Tags{
+			"sourceID": "errors-source", "writeKey": "errors-write-key", "statusCode": "413",
+		}).LastValue())
+	})
+}
@@ -22,16 +22,34 @@ const (
 	RequestBodyReadFailed = "Failed to read body from request"
 	// RequestBodyTooLarge - Request size exceeds max limit
 	RequestBodyTooLarge = "Request size exceeds max limit"
+	// RequestBodyDecompressionFailed - Failed to decompress body from request
+	RequestBodyDecompressionFailed = "Failed to decompress body from request"
+	// UnsupportedContentEncoding - Content-Encoding of request body is not supported
+	UnsupportedContentEncoding = "Unsupported Content-Encoding, only gzip and zstd are supported"
 	// InvalidWriteKey - Invalid Write Key
 	InvalidWriteKey = "Invalid Write Key"
+	// NoServiceToken - Failed to read service token from Authorization header
+	NoServiceToken = "Failed to read service token from Authorization header"
+	// InvalidServiceToken - No source authorizes the service token
+	InvalidServiceToken = "Invalid service token"
+	// WriteKeyNotAuthorized - Source of the event's write key doesn't authorize the request's service token
+	WriteKeyNotAuthorized = "Write key doesn't authorize the service token"
+	// UnsupportedContentType - Content-Type of request body is not supported by the endpoint
+	UnsupportedContentType = "Unsupported Content-Type, protobuf is only supported for batch requests"
+	// InvalidPayloadFormat - Request body doesn't match its Content-Type
+	InvalidPayloadFormat = "Request body doesn't match its Content-Type"
 	// InvalidJSON - Invalid JSON
 	InvalidJSON = "Invalid JSON"
 	// InvalidWebhookSource - Source does not accept webhook events
 	InvalidWebhookSource = "Source does not accept webhook events"
+	// InvalidWebhookSignature - Webhook signature doesn't match the payload, or the source has no secret to verify it with
+	InvalidWebhookSignature = "Invalid webhook signature"
 	// SourceTransformerResponseErrorReadFailed - Failed to read error from source transformer response
 	SourceTransformerResponseErrorReadFailed = "Failed to read error from source transformer response"
 	// SourceDisabled - write key is present, but the source for it is disabled.
 	SourceDisabled = "Source is disabled"
+	// WorkspacePaused - the workspace of the source is paused by the control plane
+	WorkspacePaused = "Workspace is paused"
 	// SourceTransformerFailed - Internal server error in source transformer
 	SourceTransformerFailed = "Internal server error in source transformer"
 	// SourceTransformerFailedToReadOutput - Output not found in source transformer response
@@ -44,6 +62,10 @@ const (
 	SourceTransformerInvalidOutputJSON = "Invalid output json in source transformer response"
 	// NonIdentifiableRequest - Request neither has anonymousId nor userId
 	NonIdentifiableRequest = "Request neither has anonymousId nor userId"
+	// EventSchemaViolation - Event does not match the schema declared by its source
+	EventSchemaViolation = "Event does not match the schema declared by its source"
+	// StaleEvent - Event is older than the max event age of its source
+	StaleEvent = "Event is older than the max event age of its source"
 	// ErrorInMarshal - Error while marshalling
 	ErrorInMarshal = "Error while marshalling"
 	// ErrorInParseForm - Error during parsing form
@@ -54,6 +76,10 @@ const (
 	NotRudderEvent = "Event is not a valid rudder event"
 	// ContextDeadlineExceeded - context deadline exceeded
 	ContextDeadlineExceeded = "context deadline exceeded"
+	// OriginNotAllowed - Source doesn't allow cross-origin requests from the request's origin
+	OriginNotAllowed = "Origin not allowed"
+	// ServiceUnavailable - Gateway is shedding requests until its jobsdb writes recover
+	ServiceUnavailable = "Service unavailable, retry later"
 	// GatewayTimeout - Gateway timeout
 	GatewayTimeout = "Gateway timeout"
 
@@ -62,19 +88,28 @@ const (
 )
 
 var statusMap = map[string]status{
-	Ok:                      {message: Ok, code: http.StatusOK},
-	RequestBodyNil:          {message: RequestBodyNil, code: http.StatusBadRequest},
-	InvalidRequestMethod:    {message: InvalidRequestMethod, code: http.StatusBadRequest},
-	TooManyRequests:         {message: TooManyRequests, code: http.StatusTooManyRequests},
-	NoWriteKeyInBasicAuth:   {message: NoWriteKeyInBasicAuth, code: http.StatusUnauthorized},
-	NoWriteKeyInQueryParams: {message: NoWriteKeyInQueryParams, code: http.StatusUnauthorized},
-	RequestBodyReadFailed:   {message: RequestBodyReadFailed, code: http.StatusInternalServerError},
-	RequestBodyTooLarge:     {message: RequestBodyTooLarge, code: http.StatusRequestEntityTooLarge},
-	InvalidWriteKey:         {message: InvalidWriteKey, code: http.StatusUnauthorized},
-	SourceDisabled:          {message: SourceDisabled, code: http.StatusNotFound},
-	InvalidJSON:             {message: InvalidJSON, code: http.StatusBadRequest},
+	Ok:                             {message: Ok, code: http.StatusOK},
+	RequestBodyNil:                 {message: RequestBodyNil, code: http.StatusBadRequest},
+	InvalidRequestMethod:           {message: InvalidRequestMethod, code: http.StatusBadRequest},
+	TooManyRequests:                {message: TooManyRequests, code: http.StatusTooManyRequests},
+	NoWriteKeyInBasicAuth:          {message: NoWriteKeyInBasicAuth, code: http.StatusUnauthorized},
+	NoWriteKeyInQueryParams:        {message: NoWriteKeyInQueryParams, code: http.StatusUnauthorized},
+	RequestBodyReadFailed:          {message: RequestBodyReadFailed, code: http.StatusInternalServerError},
+	RequestBodyTooLarge:            {message: RequestBodyTooLarge, code: http.StatusRequestEntityTooLarge},
+	RequestBodyDecompressionFailed: {message: RequestBodyDecompressionFailed, code: http.StatusBadRequest},
+	UnsupportedContentEncoding:     {message: UnsupportedContentEncoding, code: http.StatusUnsupportedMediaType},
+	InvalidWriteKey:                {message: InvalidWriteKey, code: http.StatusUnauthorized},
+	NoServiceToken:                 {message: NoServiceToken, code: http.StatusUnauthorized},
+	InvalidServiceToken:            {message: InvalidServiceToken, code: http.StatusUnauthorized},
+	WriteKeyNotAuthorized:          {message: WriteKeyNotAuthorized, code: http.StatusForbidden},
+	SourceDisabled:                 {message: SourceDisabled, code: http.StatusNotFound},
+	WorkspacePaused:                {message: WorkspacePaused, code: http.StatusForbidden},
+	UnsupportedContentType:         {message: UnsupportedContentType, code: http.StatusUnsupportedMediaType},
+	InvalidPayloadFormat:           {message: InvalidPayloadFormat, code: http.StatusBadRequest},
+	InvalidJSON:                    {message: InvalidJSON, code: http.StatusBadRequest},
 	// webhook specific status
 	InvalidWebhookSource:                           {message: InvalidWebhookSource, code: http.StatusNotFound},
+	InvalidWebhookSignature:                        {message: InvalidWebhookSignature, code: http.StatusUnauthorized},
 	SourceTransformerFailed:                        {message: SourceTransformerFailed, code: http.StatusBadRequest},
 	SourceTransformerResponseErrorReadFailed:       {message: SourceTransformerResponseErrorReadFailed, code: http.StatusInternalServerError},
 	SourceTransformerFailedToReadOutput:            {message: SourceTransformerFailedToReadOutput, code: http.StatusInternalServerError},
@@ -82,11 +117,15 @@ var statusMap = map[string]status{
 	SourceTransformerInvalidOutputFormatInResponse: {message: SourceTransformerInvalidOutputFormatInResponse, code: http.StatusInternalServerError},
 	SourceTransformerInvalidOutputJSON:             {message: SourceTransformerInvalidOutputJSON, code: http.StatusInternalServerError},
 	NonIdentifiableRequest:                         {message: NonIdentifiableRequest, code: http.StatusBadRequest},
+	EventSchemaViolation:                           {message: EventSchemaViolation, code: http.StatusBadRequest},
+	StaleEvent:                                     {message: StaleEvent, code: http.StatusUnprocessableEntity},
 	ErrorInMarshal:                                 {message: ErrorInMarshal, code: http.StatusBadRequest},
 	ErrorInParseForm:                               {message: ErrorInParseForm, code: http.StatusBadRequest},
 	ErrorInParseMultiform:                          {message: ErrorInParseMultiform, code: http.StatusBadRequest},
 	NotRudderEvent:                                 {message: NotRudderEvent, code: http.StatusBadRequest},
 	ContextDeadlineExceeded:                        {message: GatewayTimeout, code: http.StatusGatewayTimeout},
+	ServiceUnavailable:                             {message: ServiceUnavailable, code: http.StatusServiceUnavailable},
+	OriginNotAllowed:                               {message: OriginNotAllowed, code: http.StatusForbidden},
 }
 
 // status holds the gateway response status message and code
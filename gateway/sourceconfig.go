@@ -0,0 +1,26 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// parseSourceConfig parses the value a source's config declares under key into v, the way json.Unmarshal does.
+// It returns false, leaving v untouched, if the source doesn't declare the key.
+//
+// Features configured per source, e.g. bot filtering or event validation, parse their config with it, each of them under its own key,
+// so that an invalid config of a feature doesn't affect the other ones.
+func parseSourceConfig(sourceConfig map[string]interface{}, key string, v interface{}) (bool, error) {
+	rawConfig, ok := sourceConfig[key]
+	if !ok || rawConfig == nil {
+		return false, nil
+	}
+	configJSON, err := json.Marshal(rawConfig)
+	if err != nil {
+		return false, fmt.Errorf("marshalling %s config: %w", key, err)
+	}
+	if err := json.Unmarshal(configJSON, v); err != nil {
+		return false, fmt.Errorf("parsing %s config: %w", key, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sourceConfigWith returns the config of a source declaring value under key, as gateway gets it from the backend config,
+// i.e. through json, numbers being float64s and arrays []interface{}
+func sourceConfigWith(t testing.TB, key string, value interface{}) map[string]interface{} {
+	t.Helper()
+	configJSON, err := json.Marshal(map[string]interface{}{key: value})
+	require.NoError(t, err)
+	var sourceConfig map[string]interface{}
+	require.NoError(t, json.Unmarshal(configJSON, &sourceConfig))
+	return sourceConfig
+}
+
+func TestParseSourceConfig(t *testing.T) {
+	type featureConfig struct {
+		Mode  string  `json:"mode"`
+		Rate  float64 `json:"rate"`
+		Items []string
+	}
+
+	t.Run("not declared", func(t *testing.T) {
+		conf := featureConfig{Mode: "default"}
+		ok, err := parseSourceConfig(map[string]interface{}{"feature": nil}, "feature", &conf)
+		require.NoError(t, err)
+		require.False(t, ok)
+		ok, err = parseSourceConfig(map[string]interface{}{}, "feature", &conf)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Equal(t, featureConfig{Mode: "default"}, conf)
+	})
+
+	t.Run("declared", func(t *testing.T) {
+		var conf featureConfig
+		ok, err := parseSourceConfig(sourceConfigWith(t, "feature", map[string]interface{}{"mode": "drop", "rate": 0.5, "items": []string{"a"}}), "feature", &conf)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, featureConfig{Mode: "drop", Rate: 0.5, Items: []string{"a"}}, conf)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var conf featureConfig
+		_, err := parseSourceConfig(sourceConfigWith(t, "feature", map[string]interface{}{"rate": "half"}), "feature", &conf)
+		require.ErrorContains(t, err, "parsing feature config")
+	})
+}
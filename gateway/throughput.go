@@ -0,0 +1,254 @@
+package gateway
+
+import (
+	"encoding/json"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+)
+
+// payloadSizeBuckets is the number of buckets of payload size histograms, bucket i counting payloads of less than 2^i bytes
+const payloadSizeBuckets = 33
+
+// throughputTracker tracks the requests of each write key over a sliding window of one second buckets,
+// for operators to see from the admin endpoint which sources load gateway the most
+type throughputTracker struct {
+	window int // in seconds
+	now    func() time.Time
+
+	mu      sync.RWMutex
+	sources map[string]*sourceThroughput
+}
+
+// sourceThroughput is the ring of one second buckets of a write key, indexed by unix second modulo the window
+type sourceThroughput struct {
+	mu                sync.Mutex
+	buckets           []throughputBucket
+	lastRateLimitedAt time.Time
+}
+
+type throughputBucket struct {
+	second       int64
+	requests     int
+	statusCodes  map[int]int
+	payloadSizes [payloadSizeBuckets]int
+}
+
+func newThroughputTracker(window time.Duration) *throughputTracker {
+	seconds := int(window.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &throughputTracker{
+		window:  seconds,
+		now:     time.Now,
+		sources: map[string]*sourceThroughput{},
+	}
+}
+
+// record records a request of writeKey with a payload of payloadSize bytes, answered with statusCode
+func (t *throughputTracker) record(writeKey string, statusCode, payloadSize int) {
+	t.mu.RLock()
+	source, ok := t.sources[writeKey]
+	t.mu.RUnlock()
+	if !ok {
+		t.mu.Lock()
+		if source, ok = t.sources[writeKey]; !ok {
+			source = &sourceThroughput{buckets: make([]throughputBucket, t.window)}
+			t.sources[writeKey] = source
+		}
+		t.mu.Unlock()
+	}
+
+	now := t.now()
+	second := now.Unix()
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	bucket := &source.buckets[second%int64(t.window)]
+	if bucket.second != second {
+		*bucket = throughputBucket{second: second, statusCodes: map[int]int{}}
+	}
+	bucket.requests++
+	bucket.statusCodes[statusCode]++
+	bucket.payloadSizes[payloadSizeBucket(payloadSize)]++
+	if statusCode == http.StatusTooManyRequests {
+		source.lastRateLimitedAt = now
+	}
+}
+
+// sourceThroughputStats are the stats of a write key over the window
+type sourceThroughputStats struct {
+	WriteKey          string         `json:"writeKey"`
+	SourceID          string         `json:"sourceId,omitempty"`
+	SourceName        string         `json:"sourceName,omitempty"`
+	Requests          int            `json:"requests"`
+	RPS               float64        `json:"rps"`
+	ErrorRate         float64        `json:"errorRate"`
+	StatusCodes       map[string]int `json:"statusCodes"`
+	PayloadSize       payloadSizes   `json:"payloadSize"`
+	RateLimited       int            `json:"rateLimited"`
+	LastRateLimitedAt *time.Time     `json:"lastRateLimitedAt,omitempty"`
+}
+
+// payloadSizes are payload size percentiles in bytes, approximated by the upper bounds of their histogram buckets
+type payloadSizes struct {
+	P50 int `json:"p50"`
+	P90 int `json:"p90"`
+	P99 int `json:"p99"`
+}
+
+// stats returns the stats of the write keys with requests in the window, the busiest first.
+// Write keys without any are forgotten.
+func (t *throughputTracker) stats() []sourceThroughputStats {
+	oldest := t.now().Unix() - int64(t.window)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make([]sourceThroughputStats, 0, len(t.sources))
+	for writeKey, source := range t.sources {
+		s, ok := source.stats(oldest, t.window)
+		if !ok {
+			delete(t.sources, writeKey)
+			continue
+		}
+		s.WriteKey = writeKey
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Requests != result[j].Requests {
+			return result[i].Requests > result[j].Requests
+		}
+		return result[i].WriteKey < result[j].WriteKey
+	})
+	return result
+}
+
+// stats aggregates the buckets of the source newer than oldest, telling whether there are any requests in them
+func (source *sourceThroughput) stats(oldest int64, window int) (sourceThroughputStats, bool) {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	s := sourceThroughputStats{StatusCodes: map[string]int{}}
+	var sizes [payloadSizeBuckets]int
+	var errors int
+	for i := range source.buckets {
+		bucket := &source.buckets[i]
+		if bucket.second <= oldest || bucket.requests == 0 {
+			continue
+		}
+		s.Requests += bucket.requests
+		for statusCode, count := range bucket.statusCodes {
+			s.StatusCodes[strconv.Itoa(statusCode)] += count
+			if statusCode >= http.StatusBadRequest {
+				errors += count
+			}
+			if statusCode == http.StatusTooManyRequests {
+				s.RateLimited += count
+			}
+		}
+		for i, count := range bucket.payloadSizes {
+			sizes[i] += count
+		}
+	}
+	if s.Requests == 0 {
+		return s, false
+	}
+	s.RPS = float64(s.Requests) / float64(window)
+	s.ErrorRate = float64(errors) / float64(s.Requests)
+	s.PayloadSize = payloadSizes{
+		P50: payloadSizePercentile(sizes, s.Requests, 0.5),
+		P90: payloadSizePercentile(sizes, s.Requests, 0.9),
+		P99: payloadSizePercentile(sizes, s.Requests, 0.99),
+	}
+	if s.RateLimited > 0 {
+		lastRateLimitedAt := source.lastRateLimitedAt
+		s.LastRateLimitedAt = &lastRateLimitedAt
+	}
+	return s, true
+}
+
+// payloadSizeBucket returns the histogram bucket of a payload of size bytes
+func payloadSizeBucket(size int) int {
+	if bucket := bits.Len(uint(size)); bucket < payloadSizeBuckets {
+		return bucket
+	}
+	return payloadSizeBuckets - 1
+}
+
+// payloadSizePercentile returns the upper bound of the histogram bucket of percentile p of total payloads
+func payloadSizePercentile(sizes [payloadSizeBuckets]int, total int, p float64) int {
+	rank := int(p*float64(total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var cumulative int
+	for i, count := range sizes {
+		cumulative += count
+		if cumulative >= rank {
+			return 1<<i - 1
+		}
+	}
+	return 1<<(payloadSizeBuckets-1) - 1
+}
+
+// recordThroughput records a web request of writeKey for the throughput admin endpoint, answered with errorMessage.
+// Requests with unknown write keys aren't recorded, for them not to grow the tracked write keys unbounded.
+func (gateway *HandleT) recordThroughput(writeKey, errorMessage string, payloadSize int) {
+	if gateway.throughput == nil || writeKey == "" {
+		return
+	}
+	configSubscriberLock.RLock()
+	_, known := writeKeysSourceMap[writeKey]
+	configSubscriberLock.RUnlock()
+	if !known {
+		return
+	}
+	statusCode := http.StatusOK
+	if errorMessage != "" {
+		statusCode = response.GetErrorStatusCode(errorMessage)
+	}
+	gateway.throughput.record(writeKey, statusCode, payloadSize)
+}
+
+// sourceThroughputHandler reports the throughput, error rate, payload sizes and rate limiting of each write key
+// over the last Gateway.throughput.window, e.g.
+//
+//	{
+//	  "window": "1m0s",
+//	  "rateLimitEnabled": false,
+//	  "sources": [
+//	    {
+//	      "writeKey": "...", "sourceId": "...", "sourceName": "web",
+//	      "requests": 1200, "rps": 20, "errorRate": 0.05,
+//	      "statusCodes": {"200": 1140, "400": 60},
+//	      "payloadSize": {"p50": 1023, "p90": 4095, "p99": 16383},
+//	      "rateLimited": 0
+//	    }
+//	  ]
+//	}
+func (gateway *HandleT) sourceThroughputHandler(w http.ResponseWriter, _ *http.Request) {
+	if gateway.throughput == nil {
+		http.Error(w, response.MakeResponse("Source throughput tracking is disabled"), http.StatusNotFound)
+		return
+	}
+	sources := gateway.throughput.stats()
+	configSubscriberLock.RLock()
+	for i := range sources {
+		if source, ok := writeKeysSourceMap[sources[i].WriteKey]; ok {
+			sources[i].SourceID = source.ID
+			sources[i].SourceName = source.Name
+		}
+	}
+	configSubscriberLock.RUnlock()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"window":           (time.Duration(gateway.throughput.window) * time.Second).String(),
+		"rateLimitEnabled": enableRateLimit,
+		"sources":          sources,
+	})
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestThroughputTracker(t *testing.T) {
+	now := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+	tracker := newThroughputTracker(10 * time.Second)
+	tracker.now = func() time.Time { return now }
+
+	for i := 0; i < 8; i++ {
+		tracker.record("busy-write-key", http.StatusOK, 1000)
+	}
+	tracker.record("busy-write-key", http.StatusBadRequest, 100)
+	tracker.record("busy-write-key", http.StatusTooManyRequests, 100000)
+	now = now.Add(5 * time.Second)
+	tracker.record("quiet-write-key", http.StatusOK, 10)
+
+	sources := tracker.stats()
+	require.Len(t, sources, 2)
+
+	busy := sources[0]
+	require.Equal(t, "busy-write-key", busy.WriteKey)
+	require.Equal(t, 10, busy.Requests)
+	require.Equal(t, 1.0, busy.RPS)
+	require.Equal(t, 0.2, busy.ErrorRate)
+	require.Equal(t, map[string]int{"200": 8, "400": 1, "429": 1}, busy.StatusCodes)
+	require.Equal(t, payloadSizes{P50: 1023, P90: 1023, P99: 131071}, busy.PayloadSize)
+	require.Equal(t, 1, busy.RateLimited)
+	require.Equal(t, now.Add(-5*time.Second), *busy.LastRateLimitedAt)
+
+	quiet := sources[1]
+	require.Equal(t, "quiet-write-key", quiet.WriteKey)
+	require.Equal(t, 1, quiet.Requests)
+	require.Zero(t, quiet.ErrorRate)
+	require.Nil(t, quiet.LastRateLimitedAt)
+
+	// the requests of busy-write-key fall out of the window, and so does it
+	now = now.Add(7 * time.Second)
+	sources = tracker.stats()
+	require.Len(t, sources, 1)
+	require.Equal(t, "quiet-write-key", sources[0].WriteKey)
+	require.Len(t, tracker.sources, 1)
+
+	// buckets are reused once their second fell out of the window
+	tracker.record("quiet-write-key", http.StatusOK, 10)
+	sources = tracker.stats()
+	require.Equal(t, 2, sources[0].Requests)
+}
+
+func TestSourceThroughputHandler(t *testing.T) {
+	configSubscriberLock.Lock()
+	prevWriteKeysSourceMap := writeKeysSourceMap
+	writeKeysSourceMap = map[string]backendconfig.SourceT{"enabled-write-key": {ID: "source-id", Name: "web"}}
+	configSubscriberLock.Unlock()
+	defer func() {
+		configSubscriberLock.Lock()
+		writeKeysSourceMap = prevWriteKeysSourceMap
+		configSubscriberLock.Unlock()
+	}()
+
+	t.Run("disabled", func(t *testing.T) {
+		gateway := &HandleT{logger: logger.NOP}
+		rr := httptest.NewRecorder()
+		gateway.sourceThroughputHandler(rr, httptest.NewRequest(http.MethodGet, "/v1/sources/throughput", http.NoBody))
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		gateway := &HandleT{logger: logger.NOP, throughput: newThroughputTracker(time.Minute)}
+		gateway.recordThroughput("enabled-write-key", "", 100)
+		gateway.recordThroughput("enabled-write-key", response.InvalidJSON, 100)
+		gateway.recordThroughput("unknown-write-key", response.InvalidWriteKey, 100)
+
+		rr := httptest.NewRecorder()
+		gateway.sourceThroughputHandler(rr, httptest.NewRequest(http.MethodGet, "/v1/sources/throughput", http.NoBody))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Window           string                  `json:"window"`
+			RateLimitEnabled bool                    `json:"rateLimitEnabled"`
+			Sources          []sourceThroughputStats `json:"sources"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		require.Equal(t, "1m0s", body.Window)
+		require.Len(t, body.Sources, 1)
+		require.Equal(t, "enabled-write-key", body.Sources[0].WriteKey)
+		require.Equal(t, "source-id", body.Sources[0].SourceID)
+		require.Equal(t, "web", body.Sources[0].SourceName)
+		require.Equal(t, 2, body.Sources[0].Requests)
+		require.Equal(t, map[string]int{"200": 1, "400": 1}, body.Sources[0].StatusCodes)
+	})
+}
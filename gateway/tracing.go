@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of gateway requests, from the global tracer provider, exporting them if OpenTelemetry.traces.enabled
+var tracer = otel.Tracer("github.com/rudderlabs/rudder-server/gateway")
+
+// traceContext propagates traces in the W3C traceparent and tracestate headers
+var traceContext = propagation.TraceContext{}
+
+// startRequestSpan starts the span of a web request of reqType, as a child of the trace of its traceparent header if any.
+// It returns the request carrying the span in its context.
+func startRequestSpan(r *http.Request, reqType string) (*http.Request, trace.Span) {
+	ctx := traceContext.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer.Start(ctx, "gateway.request",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("reqType", reqType)),
+	)
+	return r.WithContext(ctx), span
+}
+
+// startSpan starts a span named name, as a child of spanContext
+func startSpan(spanContext trace.SpanContext, name string) trace.Span {
+	_, span := tracer.Start(trace.ContextWithSpanContext(context.Background(), spanContext), name)
+	return span
+}
+
+// endSpan ends span, with an error status if errorMessage isn't empty
+func endSpan(span trace.Span, errorMessage string) {
+	if errorMessage != "" {
+		span.SetStatus(codes.Error, errorMessage)
+	}
+	span.End()
+}
+
+// traceParent returns the W3C traceparent of spanContext, for the trace of a job to be continued after gateway.
+// It is empty if spanContext isn't valid, i.e. if the request isn't traced.
+func traceParent(spanContext trace.SpanContext) string {
+	if !spanContext.IsValid() {
+		return ""
+	}
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(trace.ContextWithSpanContext(context.Background(), spanContext), carrier)
+	return carrier.Get("traceparent")
+}
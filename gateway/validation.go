@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// Modes of handling events violating the schemas declared by their source
+const (
+	// eventValidationModeDrop drops violating events, storing the rest of the request
+	eventValidationModeDrop = "drop"
+	// eventValidationModeAnnotate stores violating events, annotated with their violations under context.violationErrors
+	eventValidationModeAnnotate = "annotate"
+	// eventValidationModeReject rejects the whole request with a 400 if any of its events is violating,
+	// or only the violating events for requests in per event response mode
+	eventValidationModeReject = "reject"
+)
+
+// eventValidationConfig is the per source validation config, declared under the source config's eventValidation key:
+//
+//	"eventValidation": {
+//	  "mode": "drop",
+//	  "schemas": {"identify": {...}},
+//	  "trackEvents": {"Order Completed": {...}}
+//	}
+//
+// Track events are validated against the schema of their event name if there is one, otherwise against the one of their type.
+type eventValidationConfig struct {
+	Mode        string                     `json:"mode"`
+	Schemas     map[string]json.RawMessage `json:"schemas"`
+	TrackEvents map[string]json.RawMessage `json:"trackEvents"`
+}
+
+// eventValidator validates the events of a source against the json schemas it declares
+type eventValidator struct {
+	mode         string
+	typeSchemas  map[string]*gojsonschema.Schema
+	trackSchemas map[string]*gojsonschema.Schema
+}
+
+// violation describes a way in which an event doesn't match its schema
+type violation struct {
+	Type    string                 `json:"type"`
+	Message string                 `json:"message"`
+	Meta    map[string]interface{} `json:"meta"`
+}
+
+// newEventValidator compiles the schemas declared in a source's config.
+// It returns nil if the source declares no schemas, neither per event type nor per track event.
+func newEventValidator(sourceConfig map[string]interface{}) (*eventValidator, error) {
+	var validationConfig eventValidationConfig
+	ok, err := parseSourceConfig(sourceConfig, "eventValidation", &validationConfig)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if len(validationConfig.Schemas) == 0 && len(validationConfig.TrackEvents) == 0 {
+		return nil, nil
+	}
+
+	validator := &eventValidator{mode: validationConfig.Mode}
+	switch validator.mode {
+	case eventValidationModeDrop, eventValidationModeAnnotate, eventValidationModeReject:
+	case "":
+		validator.mode = eventValidationModeAnnotate
+	default:
+		return nil, fmt.Errorf("unknown event validation mode: %q", validationConfig.Mode)
+	}
+	if validator.typeSchemas, err = compileSchemas(validationConfig.Schemas); err != nil {
+		return nil, err
+	}
+	if validator.trackSchemas, err = compileSchemas(validationConfig.TrackEvents); err != nil {
+		return nil, err
+	}
+	return validator, nil
+}
+
+func compileSchemas(rawSchemas map[string]json.RawMessage) (map[string]*gojsonschema.Schema, error) {
+	schemas := make(map[string]*gojsonschema.Schema, len(rawSchemas))
+	for name, rawSchema := range rawSchemas {
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(rawSchema))
+		if err != nil {
+			return nil, fmt.Errorf("compiling schema of %q: %w", name, err)
+		}
+		schemas[name] = schema
+	}
+	return schemas, nil
+}
+
+// validate returns the violations of event against its schema, if it has one
+func (v *eventValidator) validate(event map[string]interface{}) ([]violation, error) {
+	eventType, _ := event["type"].(string)
+	schema, ok := v.typeSchemas[eventType]
+	if eventName, _ := event["event"].(string); eventType == "track" && eventName != "" {
+		if trackSchema, found := v.trackSchemas[eventName]; found {
+			schema, ok = trackSchema, true
+		}
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	result, err := schema.Validate(gojsonschema.NewGoLoader(event))
+	if err != nil {
+		return nil, err
+	}
+	violations := make([]violation, 0, len(result.Errors()))
+	for _, resultErr := range result.Errors() {
+		violations = append(violations, violation{
+			Type:    resultErr.Type(),
+			Message: resultErr.Description(),
+			Meta:    map[string]interface{}{"field": resultErr.Field()},
+		})
+	}
+	return violations, nil
+}
+
+// validateEvents validates the events of a request against the schemas declared by their source, annotating the violating ones
+// with their violations under context.violationErrors. It returns the events to be stored according to the source's mode,
+// along with the violating events.
+func (gateway *HandleT) validateEvents(validator *eventValidator, sourceTags map[string]string, events []map[string]interface{}) (kept, violating []map[string]interface{}) {
+	kept = make([]map[string]interface{}, 0, len(events))
+	for _, event := range events {
+		violations, err := validator.validate(event)
+		if err != nil {
+			gateway.logger.Errorf("Error validating event of source %s against its schema: %v", sourceTags["sourceID"], err)
+			kept = append(kept, event)
+			continue
+		}
+		if len(violations) == 0 {
+			kept = append(kept, event)
+			continue
+		}
+
+		eventType, _ := event["type"].(string)
+		for _, v := range violations {
+			gateway.stats.NewTaggedStat("gateway.event_schema_violations", stats.CountType, stats.Tags{
+				"sourceID":      sourceTags["sourceID"],
+				"writeKey":      sourceTags["writeKey"],
+				"eventType":     eventType,
+				"violationType": v.Type,
+				"mode":          validator.mode,
+			}).Increment()
+		}
+		annotateViolations(event, violations)
+		violating = append(violating, event)
+		if validator.mode == eventValidationModeAnnotate {
+			kept = append(kept, event)
+		}
+	}
+	return kept, violating
+}
+
+func annotateViolations(event map[string]interface{}, violations []violation) {
+	eventContext, ok := event["context"].(map[string]interface{})
+	if !ok {
+		eventContext = make(map[string]interface{})
+		event["context"] = eventContext
+	}
+	eventContext["violationErrors"] = violations
+}
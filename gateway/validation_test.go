@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestEventValidation(t *testing.T) {
+	validationConfig := func(mode string) map[string]interface{} {
+		return sourceConfigWith(t, "eventValidation", map[string]interface{}{
+			"mode": mode,
+			"schemas": map[string]interface{}{
+				"identify": map[string]interface{}{
+					"type":     "object",
+					"required": []interface{}{"userId"},
+				},
+			},
+			"trackEvents": map[string]interface{}{
+				"Order Completed": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"type":     "object",
+							"required": []interface{}{"revenue"},
+						},
+					},
+				},
+			},
+		})
+	}
+	events := func() []map[string]interface{} {
+		return []map[string]interface{}{
+			{"type": "identify", "userId": "user-1"},
+			{"type": "identify", "anonymousId": "anon-1"},
+			{"type": "track", "event": "Order Completed", "properties": map[string]interface{}{"revenue": 10}},
+			{"type": "track", "event": "Order Completed", "properties": map[string]interface{}{}},
+			{"type": "track", "event": "Product Viewed"},
+		}
+	}
+	sourceTags := map[string]string{"sourceID": "source-1", "writeKey": "write-key-1"}
+
+	t.Run("no schemas", func(t *testing.T) {
+		validator, err := newEventValidator(map[string]interface{}{})
+		require.NoError(t, err)
+		require.Nil(t, validator)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		_, err := newEventValidator(validationConfig("ignore"))
+		require.Error(t, err)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		store := memstats.New()
+		gateway := &HandleT{stats: store, logger: logger.NOP}
+		validator, err := newEventValidator(validationConfig(eventValidationModeDrop))
+		require.NoError(t, err)
+
+		kept, violating := gateway.validateEvents(validator, sourceTags, events())
+		require.Len(t, kept, 3)
+		require.Len(t, violating, 2)
+		require.Equal(t, "anon-1", violating[0]["anonymousId"])
+		require.NotEmpty(t, violating[0]["context"].(map[string]interface{})["violationErrors"])
+		require.EqualValues(t, 1, store.Get("gateway.event_schema_violations", stats.Tags{
+			"sourceID":      "source-1",
+			"writeKey":      "write-key-1",
+			"eventType":     "identify",
+			"violationType": "required",
+			"mode":          eventValidationModeDrop,
+		}).LastValue())
+	})
+
+	t.Run("annotate", func(t *testing.T) {
+		gateway := &HandleT{stats: memstats.New(), logger: logger.NOP}
+		validator, err := newEventValidator(validationConfig(eventValidationModeAnnotate))
+		require.NoError(t, err)
+
+		kept, violating := gateway.validateEvents(validator, sourceTags, events())
+		require.Len(t, kept, 5)
+		require.Len(t, violating, 2)
+		require.Contains(t, kept[3]["context"], "violationErrors")
+		require.NotContains(t, kept[2], "context")
+	})
+}
@@ -27,4 +27,11 @@ func loadConfig() {
 	for i, s := range sourceListForParsingParams {
 		sourceListForParsingParams[i] = strings.ToLower(s)
 	}
+	// Sources whose webhooks are verified and mapped to rudder events by gateway, using their declarative descriptor, instead of the source transformer
+	config.RegisterStringSliceConfigVariable(make([]string, 0), &declarativeSources, false, "Gateway.webhook.declarativeSources")
+	for i, s := range declarativeSources {
+		declarativeSources[i] = strings.ToLower(s)
+	}
+	// Directory of descriptors of declarative sources, in addition to the built-in ones
+	config.RegisterStringConfigVariable("", &descriptorsPath, false, "Gateway.webhook.descriptorsPath")
 }
@@ -0,0 +1,342 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // skipcq: GSC-G505
+	"crypto/sha256"
+	"crypto/sha512"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+//go:embed descriptors/*.json
+var builtinDescriptors embed.FS
+
+// Descriptor declares how the webhooks of a third-party source are verified and mapped to rudder events,
+// for them to be handled by gateway instead of the source transformer.
+//
+// Values of the mapping are either:
+//   - a path of the event, e.g. data.object.customer, @this for the whole event
+//   - a request header, e.g. header:X-GitHub-Event
+//   - a literal, e.g. 'track'
+//
+// Rudder events are tracks unless their type is mapped, and get a random anonymousId if neither their userId nor anonymousId is mapped.
+type Descriptor struct {
+	// Source is the name of the source definition the descriptor applies to
+	Source string `json:"source"`
+	// Signature is the scheme webhooks are signed with, nil if they aren't signed
+	Signature *SignatureScheme `json:"signature"`
+	// Events is the path of the events in the payload, empty if the payload is a single event
+	Events string `json:"events"`
+	// Mapping maps fields of rudder events to values of the source's events
+	Mapping map[string]string `json:"mapping"`
+}
+
+// SignatureScheme declares how webhooks are signed with a HMAC of their payload, using the secret of their source
+type SignatureScheme struct {
+	// Header is the request header carrying the signature
+	Header string `json:"header"`
+	// Algorithm is the hash function of the HMAC, one of sha1, sha256 or sha512
+	Algorithm string `json:"algorithm"`
+	// Encoding of the signature, hex by default or base64
+	Encoding string `json:"encoding"`
+	// Prefix preceding the signature in the header, e.g. sha256=
+	Prefix string `json:"prefix"`
+	// TimestampKey and SignatureKey parse headers like t=<timestamp>,v1=<signature>, the signed payload being <timestamp>.<payload>
+	TimestampKey string `json:"timestampKey"`
+	SignatureKey string `json:"signatureKey"`
+	// ToleranceInS is the maximum age of timestamped signatures, to prevent replays. 0 doesn't check the age.
+	ToleranceInS int `json:"toleranceInS"`
+}
+
+var errInvalidSignature = errors.New("invalid signature")
+
+func (s *SignatureScheme) newHash() (func() hash.Hash, error) {
+	switch strings.ToLower(s.Algorithm) {
+	case "sha1":
+		return sha1.New, nil
+	case "sha256", "":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm: %q", s.Algorithm)
+	}
+}
+
+func (s *SignatureScheme) decode(signature string) ([]byte, error) {
+	switch strings.ToLower(s.Encoding) {
+	case "hex", "":
+		return hex.DecodeString(signature)
+	case "base64":
+		return base64.StdEncoding.DecodeString(signature)
+	default:
+		return nil, fmt.Errorf("unsupported signature encoding: %q", s.Encoding)
+	}
+}
+
+// verify checks that the signature in the request headers is the one of payload, signed with secret
+func (s *SignatureScheme) verify(secret string, header http.Header, payload []byte, now time.Time) error {
+	newHash, err := s.newHash()
+	if err != nil {
+		return err
+	}
+	value := header.Get(s.Header)
+	if value == "" {
+		return fmt.Errorf("%w: missing %s header", errInvalidSignature, s.Header)
+	}
+
+	signedPayload := payload
+	signatures := []string{strings.TrimPrefix(value, s.Prefix)}
+	if s.TimestampKey != "" {
+		var timestamp string
+		signatures = signatures[:0]
+		for _, part := range strings.Split(value, ",") {
+			key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case s.TimestampKey:
+				timestamp = val
+			case s.SignatureKey:
+				signatures = append(signatures, val)
+			}
+		}
+		if timestamp == "" {
+			return fmt.Errorf("%w: missing timestamp", errInvalidSignature)
+		}
+		if s.ToleranceInS > 0 {
+			seconds, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: invalid timestamp %q", errInvalidSignature, timestamp)
+			}
+			if math.Abs(now.Sub(time.Unix(seconds, 0)).Seconds()) > float64(s.ToleranceInS) {
+				return fmt.Errorf("%w: timestamp %s out of tolerance", errInvalidSignature, timestamp)
+			}
+		}
+		signedPayload = append([]byte(timestamp+"."), payload...)
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	_, _ = mac.Write(signedPayload)
+	expected := mac.Sum(nil)
+	for _, signature := range signatures {
+		decoded, err := s.decode(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return errInvalidSignature
+}
+
+// toRudderEvents extracts the events of a webhook payload and maps them to rudder events
+func (d *Descriptor) toRudderEvents(payload []byte, header http.Header) ([]json.RawMessage, error) {
+	if !gjson.ValidBytes(payload) {
+		return nil, errors.New("invalid json")
+	}
+	var events []gjson.Result
+	if d.Events == "" {
+		events = []gjson.Result{gjson.ParseBytes(payload)}
+	} else {
+		result := gjson.GetBytes(payload, d.Events)
+		if !result.IsArray() {
+			return nil, fmt.Errorf("no events found at %q", d.Events)
+		}
+		events = result.Array()
+	}
+
+	// fields are mapped in order, for the output not to depend on the iteration order of the mapping,
+	// e.g. when fields are nested in others
+	fields := make([]string, 0, len(d.Mapping))
+	for field := range d.Mapping {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	rudderEvents := make([]json.RawMessage, 0, len(events))
+	for _, event := range events {
+		rudderEvent := []byte(`{"type":"track"}`)
+		for _, field := range fields {
+			value, ok := d.mapValue(event, header, field, d.Mapping[field])
+			if !ok {
+				continue
+			}
+			var err error
+			if rudderEvent, err = sjson.SetBytes(rudderEvent, field, value); err != nil {
+				return nil, fmt.Errorf("mapping %s: %w", field, err)
+			}
+		}
+		if !gjson.GetBytes(rudderEvent, "userId").Exists() && !gjson.GetBytes(rudderEvent, "anonymousId").Exists() {
+			rudderEvent, _ = sjson.SetBytes(rudderEvent, "anonymousId", uuid.New().String())
+		}
+		rudderEvents = append(rudderEvents, rudderEvent)
+	}
+	return rudderEvents, nil
+}
+
+// mapValue returns the value of spec for event, false if it is missing
+func (*Descriptor) mapValue(event gjson.Result, header http.Header, field, spec string) (interface{}, bool) {
+	switch {
+	case strings.HasPrefix(spec, "header:"):
+		value := header.Get(strings.TrimPrefix(spec, "header:"))
+		return value, value != ""
+	case len(spec) >= 2 && strings.HasPrefix(spec, "'") && strings.HasSuffix(spec, "'"):
+		return spec[1 : len(spec)-1], true
+	}
+
+	result := event.Get(spec)
+	if !result.Exists() || result.Type == gjson.Null {
+		return nil, false
+	}
+	// unix timestamps are converted, for timestamps of rudder events to be RFC 3339
+	if result.Type == gjson.Number && strings.HasSuffix(strings.ToLower(field), "timestamp") {
+		return time.Unix(result.Int(), 0).UTC().Format(time.RFC3339), true
+	}
+	if result.Type == gjson.Number && (field == "userId" || field == "anonymousId" || field == "messageId") {
+		return result.String(), true
+	}
+	return json.RawMessage(result.Raw), true
+}
+
+// loadDescriptors loads the built-in descriptors along with the ones in dir, if any, keyed by the lowercased name of their source.
+// Descriptors in dir override built-in ones for the same source.
+func loadDescriptors(dir string) (map[string]*Descriptor, error) {
+	descriptors := make(map[string]*Descriptor)
+	load := func(fsys fs.FS) error {
+		paths, err := fs.Glob(fsys, "*.json")
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			data, err := fs.ReadFile(fsys, path)
+			if err != nil {
+				return err
+			}
+			var descriptor Descriptor
+			if err := json.Unmarshal(data, &descriptor); err != nil {
+				return fmt.Errorf("parsing webhook descriptor %s: %w", path, err)
+			}
+			if descriptor.Source == "" {
+				return fmt.Errorf("webhook descriptor %s doesn't declare its source", path)
+			}
+			if descriptor.Signature != nil {
+				if _, err := descriptor.Signature.newHash(); err != nil {
+					return fmt.Errorf("webhook descriptor %s: %w", path, err)
+				}
+			}
+			descriptors[strings.ToLower(descriptor.Source)] = &descriptor
+		}
+		return nil
+	}
+
+	builtin, err := fs.Sub(builtinDescriptors, "descriptors")
+	if err != nil {
+		return nil, err
+	}
+	if err := load(builtin); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := load(os.DirFS(filepath.Clean(dir))); err != nil {
+			return nil, err
+		}
+	}
+	return descriptors, nil
+}
+
+// declarativeRequestHandler verifies and maps the webhook of a declarative source to rudder events, enqueuing them in gateway as a batch
+func (webhook *HandleT) declarativeRequestHandler(w http.ResponseWriter, r *http.Request, descriptor *Descriptor, sourceDefName, writeKey string) {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		webhook.failRequest(
+			w,
+			r,
+			response.GetStatus(response.RequestBodyReadFailed),
+			response.GetErrorStatusCode(response.RequestBodyReadFailed),
+			"requestBodyReadFailed",
+		)
+		atomic.AddUint64(&webhook.ackCount, 1)
+		return
+	}
+
+	if descriptor.Signature != nil {
+		secret := webhook.gwHandle.GetWebhookSecret(writeKey)
+		if secret == "" {
+			err = errors.New("source has no webhook secret")
+		} else {
+			err = descriptor.Signature.verify(secret, r.Header, body, time.Now())
+		}
+		if err != nil {
+			pkgLogger.Debugf("Verifying %s webhook signature: %v", sourceDefName, err)
+			countWebhookErrors(sourceDefName, response.GetErrorStatusCode(response.InvalidWebhookSignature), 1)
+			webhook.failRequest(
+				w,
+				r,
+				response.GetStatus(response.InvalidWebhookSignature),
+				response.GetErrorStatusCode(response.InvalidWebhookSignature),
+				"invalidWebhookSignature",
+			)
+			atomic.AddUint64(&webhook.ackCount, 1)
+			return
+		}
+	}
+
+	events, err := descriptor.toRudderEvents(body, r.Header)
+	if err != nil {
+		pkgLogger.Debugf("Mapping %s webhook to rudder events: %v", sourceDefName, err)
+		countWebhookErrors(sourceDefName, response.GetErrorStatusCode(response.InvalidJSON), 1)
+		webhook.failRequest(
+			w,
+			r,
+			response.GetStatus(response.InvalidJSON),
+			response.GetErrorStatusCode(response.InvalidJSON),
+			"invalidJSON",
+		)
+		atomic.AddUint64(&webhook.ackCount, 1)
+		return
+	}
+
+	var errorMessage string
+	payload, err := json.Marshal(map[string]interface{}{"batch": events})
+	if err != nil {
+		errorMessage = response.ErrorInMarshal
+	} else {
+		r.SetBasicAuth(writeKey, "")
+		errorMessage = webhook.gwHandle.ProcessWebRequest(&w, r, "batch", payload, writeKey)
+	}
+	webhook.gwHandle.IncrementAckCount(1)
+	atomic.AddUint64(&webhook.ackCount, 1)
+	webhook.gwHandle.TrackRequestMetrics(errorMessage)
+
+	if errorMessage != "" {
+		code := response.GetErrorStatusCode(errorMessage)
+		countWebhookErrors(sourceDefName, code, 1)
+		pkgLogger.Infof("IP: %s -- %s -- Response: %d, %s", misc.GetIPFromReq(r), r.URL.Path, code, errorMessage)
+		http.Error(w, errorMessage, code)
+		return
+	}
+	pkgLogger.Debugf("IP: %s -- %s -- Response: 200, %s", misc.GetIPFromReq(r), r.URL.Path, response.GetStatus(response.Ok))
+	_, _ = w.Write([]byte(response.Ok))
+}
@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1" // skipcq: GSC-G505
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	mock_webhook "github.com/rudderlabs/rudder-server/mocks/gateway/webhook"
+)
+
+const webhookSecret = "whsec_test"
+
+func sign(newHash func() hash.Hash, payload string) []byte {
+	mac := hmac.New(newHash, []byte(webhookSecret))
+	_, _ = mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func TestSignatureScheme(t *testing.T) {
+	payload := []byte(`{"action":"opened"}`)
+	now := time.Unix(1666000000, 0)
+
+	t.Run("prefixed hex", func(t *testing.T) {
+		scheme := &SignatureScheme{Header: "X-Hub-Signature", Algorithm: "sha1", Prefix: "sha1="}
+		header := http.Header{}
+		header.Set("X-Hub-Signature", "sha1="+hex.EncodeToString(sign(sha1.New, string(payload))))
+		require.NoError(t, scheme.verify(webhookSecret, header, payload, now))
+		require.ErrorIs(t, scheme.verify("other", header, payload, now), errInvalidSignature)
+		require.ErrorIs(t, scheme.verify(webhookSecret, http.Header{}, payload, now), errInvalidSignature)
+	})
+
+	t.Run("base64", func(t *testing.T) {
+		scheme := &SignatureScheme{Header: "X-Signature", Algorithm: "sha256", Encoding: "base64"}
+		header := http.Header{}
+		header.Set("X-Signature", base64.StdEncoding.EncodeToString(sign(sha256.New, string(payload))))
+		require.NoError(t, scheme.verify(webhookSecret, header, payload, now))
+		require.ErrorIs(t, scheme.verify(webhookSecret, header, []byte(`{"action":"closed"}`), now), errInvalidSignature)
+	})
+
+	t.Run("timestamped", func(t *testing.T) {
+		scheme := &SignatureScheme{Header: "Stripe-Signature", TimestampKey: "t", SignatureKey: "v1", ToleranceInS: 300}
+		signature := hex.EncodeToString(sign(sha256.New, fmt.Sprintf("%d.%s", now.Unix(), payload)))
+		header := http.Header{}
+		header.Set("Stripe-Signature", fmt.Sprintf("t=%d,v1=deadbeef,v1=%s", now.Unix(), signature))
+		require.NoError(t, scheme.verify(webhookSecret, header, payload, now.Add(time.Minute)))
+		require.ErrorIs(t, scheme.verify(webhookSecret, header, payload, now.Add(10*time.Minute)), errInvalidSignature)
+
+		header.Set("Stripe-Signature", "v1="+signature)
+		require.ErrorIs(t, scheme.verify(webhookSecret, header, payload, now), errInvalidSignature)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		scheme := &SignatureScheme{Header: "X-Signature", Algorithm: "md5"}
+		err := scheme.verify(webhookSecret, http.Header{"X-Signature": []string{"abc"}}, payload, now)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, errInvalidSignature)
+	})
+}
+
+func TestLoadDescriptors(t *testing.T) {
+	descriptors, err := loadDescriptors("")
+	require.NoError(t, err)
+	require.Contains(t, descriptors, "stripe")
+	require.Contains(t, descriptors, "github")
+	require.Contains(t, descriptors, "intercom")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "github.json"), []byte(`{"source":"GitHub","mapping":{"event":"'push'"}}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "custom.json"), []byte(`{"source":"Custom","events":"items"}`), 0o600))
+	descriptors, err = loadDescriptors(dir)
+	require.NoError(t, err)
+	require.Nil(t, descriptors["github"].Signature)
+	require.Equal(t, "items", descriptors["custom"].Events)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{"mapping":{}}`), 0o600))
+	_, err = loadDescriptors(dir)
+	require.Error(t, err)
+}
+
+func TestDescriptorToRudderEvents(t *testing.T) {
+	descriptors, err := loadDescriptors("")
+	require.NoError(t, err)
+
+	t.Run("stripe", func(t *testing.T) {
+		events, err := descriptors["stripe"].toRudderEvents([]byte(`{
+			"id": "evt_1",
+			"type": "invoice.paid",
+			"created": 1666000000,
+			"data": {"object": {"id": "in_1", "customer": "cus_1", "amount_paid": 1000}}
+		}`), http.Header{})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.JSONEq(t, `{
+			"type": "track",
+			"event": "invoice.paid",
+			"messageId": "evt_1",
+			"originalTimestamp": "2022-10-17T09:46:40Z",
+			"userId": "cus_1",
+			"properties": {"id": "in_1", "customer": "cus_1", "amount_paid": 1000},
+			"context": {"integration": {"name": "Stripe"}}
+		}`, string(events[0]))
+	})
+
+	t.Run("github", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("X-GitHub-Event", "push")
+		header.Set("X-GitHub-Delivery", "delivery-1")
+		events, err := descriptors["github"].toRudderEvents([]byte(`{"ref":"refs/heads/main","sender":{"login":"octocat"}}`), header)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, "push", gjson.GetBytes(events[0], "event").String())
+		require.Equal(t, "delivery-1", gjson.GetBytes(events[0], "messageId").String())
+		require.Equal(t, "octocat", gjson.GetBytes(events[0], "userId").String())
+		require.Equal(t, "refs/heads/main", gjson.GetBytes(events[0], "properties.ref").String())
+	})
+
+	t.Run("events array", func(t *testing.T) {
+		descriptor := &Descriptor{Source: "Custom", Events: "items", Mapping: map[string]string{
+			"type":   "'identify'",
+			"userId": "user",
+			"traits": "traits",
+		}}
+		events, err := descriptor.toRudderEvents([]byte(`{"items":[{"user":42,"traits":{"plan":"pro"}},{"traits":{}}]}`), http.Header{})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.JSONEq(t, `{"type":"identify","userId":"42","traits":{"plan":"pro"}}`, string(events[0]))
+		require.Equal(t, "identify", gjson.GetBytes(events[1], "type").String())
+		require.NotEmpty(t, gjson.GetBytes(events[1], "anonymousId").String())
+
+		_, err = descriptor.toRudderEvents([]byte(`{"items":{}}`), http.Header{})
+		require.Error(t, err)
+		_, err = descriptor.toRudderEvents([]byte(`{"items":`), http.Header{})
+		require.Error(t, err)
+	})
+
+	t.Run("nested fields", func(t *testing.T) {
+		descriptor := &Descriptor{Source: "Custom", Mapping: map[string]string{
+			"properties":      "data",
+			"properties.plan": "plan",
+			"event":           "'Subscribed'",
+		}}
+		for i := 0; i < 20; i++ {
+			events, err := descriptor.toRudderEvents([]byte(`{"data":{"plan":"free","seats":2},"plan":"pro","user":"u-1"}`), http.Header{})
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.JSONEq(t, `{"plan":"pro","seats":2}`, gjson.GetBytes(events[0], "properties").Raw)
+		}
+	})
+}
+
+func TestDeclarativeRequestHandler(t *testing.T) {
+	initWebhook()
+	const sourceDefName = "GitHub"
+	payload := `{"action":"opened","sender":{"login":"octocat"}}`
+	newRequest := func(signature string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/webhook?writeKey="+sampleWriteKey, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", "issues")
+		req.Header.Set("X-Hub-Signature-256", signature)
+		return req
+	}
+	validSignature := "sha256=" + hex.EncodeToString(sign(sha256.New, payload))
+
+	ctrl := gomock.NewController(t)
+	mockGW := mock_webhook.NewMockGatewayI(ctrl)
+	webhookHandler := Setup(mockGW)
+	defer func() { _ = webhookHandler.Shutdown() }()
+	descriptors, err := loadDescriptors("")
+	require.NoError(t, err)
+	webhookHandler.descriptors = map[string]*Descriptor{"github": descriptors["github"]}
+
+	t.Run("valid signature", func(t *testing.T) {
+		mockGW.EXPECT().IncrementRecvCount(gomock.Any()).Times(1)
+		mockGW.EXPECT().IncrementAckCount(gomock.Any()).Times(1)
+		mockGW.EXPECT().GetWebhookSourceDefName(sampleWriteKey).Return(sourceDefName, true)
+		mockGW.EXPECT().GetWebhookSecret(sampleWriteKey).Return(webhookSecret)
+		mockGW.EXPECT().TrackRequestMetrics("").Times(1)
+		mockGW.EXPECT().ProcessWebRequest(gomock.Any(), gomock.Any(), "batch", gomock.Any(), sampleWriteKey).DoAndReturn(
+			func(_ *http.ResponseWriter, _ *http.Request, _ string, requestPayload []byte, _ string) string {
+				batch := gjson.GetBytes(requestPayload, "batch").Array()
+				require.Len(t, batch, 1)
+				require.Equal(t, "issues", batch[0].Get("event").String())
+				require.Equal(t, "octocat", batch[0].Get("userId").String())
+				require.Equal(t, "opened", batch[0].Get("properties.action").String())
+				return ""
+			})
+
+		w := httptest.NewRecorder()
+		webhookHandler.RequestHandler(w, newRequest(validSignature))
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.Equal(t, response.Ok, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		mockGW.EXPECT().IncrementRecvCount(gomock.Any()).Times(1)
+		mockGW.EXPECT().IncrementAckCount(gomock.Any()).Times(1)
+		mockGW.EXPECT().GetWebhookSourceDefName(sampleWriteKey).Return(sourceDefName, true)
+		mockGW.EXPECT().GetWebhookSecret(sampleWriteKey).Return(webhookSecret)
+		mockGW.EXPECT().UpdateSourceStats(gomock.Any(), "gateway.write_key_failed_requests", gomock.Any()).Times(1)
+
+		w := httptest.NewRecorder()
+		webhookHandler.RequestHandler(w, newRequest("sha256=deadbeef"))
+		require.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+		require.Equal(t, response.InvalidWebhookSignature, strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("no secret", func(t *testing.T) {
+		mockGW.EXPECT().IncrementRecvCount(gomock.Any()).Times(1)
+		mockGW.EXPECT().IncrementAckCount(gomock.Any()).Times(1)
+		mockGW.EXPECT().GetWebhookSourceDefName(sampleWriteKey).Return(sourceDefName, true)
+		mockGW.EXPECT().GetWebhookSecret(sampleWriteKey).Return("")
+		mockGW.EXPECT().UpdateSourceStats(gomock.Any(), "gateway.write_key_failed_requests", gomock.Any()).Times(1)
+
+		w := httptest.NewRecorder()
+		webhookHandler.RequestHandler(w, newRequest(validSignature))
+		require.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+}
@@ -4,6 +4,7 @@ package webhook
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -21,6 +22,7 @@ type GatewayI interface {
 	TrackRequestMetrics(errorMessage string)
 	ProcessWebRequest(writer *http.ResponseWriter, req *http.Request, reqType string, requestPayload []byte, writeKey string) string
 	GetWebhookSourceDefName(writeKey string) (name string, ok bool)
+	GetWebhookSecret(writeKey string) string
 }
 
 type WebHookI interface {
@@ -38,8 +40,33 @@ func newWebhookStats() *webhookStatsT {
 	return &wStats
 }
 
+// declarativeDescriptors returns the descriptors of the sources whose webhooks are handled declaratively, keyed by their lowercased name
+func declarativeDescriptors() map[string]*Descriptor {
+	descriptors := make(map[string]*Descriptor)
+	if len(declarativeSources) == 0 {
+		return descriptors
+	}
+	loaded, err := loadDescriptors(descriptorsPath)
+	if err != nil {
+		pkgLogger.Errorf("Error loading webhook descriptors from %q, using built-in ones: %v", descriptorsPath, err)
+		if loaded, err = loadDescriptors(""); err != nil {
+			panic(fmt.Errorf("loading built-in webhook descriptors: %w", err))
+		}
+	}
+	for _, source := range declarativeSources {
+		descriptor, ok := loaded[source]
+		if !ok {
+			pkgLogger.Warnf("No webhook descriptor found for declarative source %q", source)
+			continue
+		}
+		descriptors[source] = descriptor
+	}
+	return descriptors
+}
+
 func Setup(gwHandle GatewayI, opts ...batchTransformerOption) *HandleT {
 	webhook := &HandleT{gwHandle: gwHandle}
+	webhook.descriptors = declarativeDescriptors()
 	webhook.requestQ = make(map[string](chan *webhookT))
 	webhook.batchRequestQ = make(chan *batchWebhookT)
 	webhook.netClient = retryablehttp.NewClient()
@@ -32,6 +32,8 @@ var (
 	webhookRetryWaitMin        time.Duration
 	pkgLogger                  logger.Logger
 	sourceListForParsingParams []string
+	declarativeSources         []string
+	descriptorsPath            string
 )
 
 func Init() {
@@ -58,6 +60,7 @@ type HandleT struct {
 	batchRequestQ chan *batchWebhookT
 	netClient     *retryablehttp.Client
 	gwHandle      GatewayI
+	descriptors   map[string]*Descriptor
 	ackCount      uint64
 	recvCount     uint64
 
@@ -159,6 +162,10 @@ func (webhook *HandleT) RequestHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "GET" {
 		return
 	}
+	if descriptor, ok := webhook.descriptors[strings.ToLower(sourceDefName)]; ok {
+		webhook.declarativeRequestHandler(w, r, descriptor, sourceDefName, writeKey)
+		return
+	}
 	contentType := r.Header.Get("Content-Type")
 	if strings.Contains(strings.ToLower(contentType), "application/x-www-form-urlencoded") {
 		if err := r.ParseForm(); err != nil {
@@ -15,7 +15,14 @@ const defaultMaxCASAttemptsLimit = 100
 
 type gcra struct {
 	mu    sync.Mutex
-	store *cachettl.Cache[string, *throttled.GCRARateLimiter]
+	store *cachettl.Cache[string, *gcraLimiter]
+}
+
+// gcraLimiter is a limiter along with the quota it was created with, for it to be recreated once the quota of its key
+// changes, e.g. with adaptive limits
+type gcraLimiter struct {
+	*throttled.GCRARateLimiter
+	burst, rate, period int64
 }
 
 func (g *gcra) limit(key string, cost, burst, rate, period int64) (
@@ -34,28 +41,29 @@ func (g *gcra) limit(key string, cost, burst, rate, period int64) (
 	return !limited, nil
 }
 
-func (g *gcra) getLimiter(key string, burst, rate, period int64) (*throttled.GCRARateLimiter, error) {
+func (g *gcra) getLimiter(key string, burst, rate, period int64) (*gcraLimiter, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	if g.store == nil {
-		g.store = cachettl.New[string, *throttled.GCRARateLimiter]()
+		g.store = cachettl.New[string, *gcraLimiter]()
 	}
 
 	rl := g.store.Get(key)
-	if rl == nil {
+	if rl == nil || rl.burst != burst || rl.rate != rate || rl.period != period {
 		store, err := memstore.New(0)
 		if err != nil {
 			return nil, fmt.Errorf("could not create store: %w", err)
 		}
-		rl, err = throttled.NewGCRARateLimiter(store, throttled.RateQuota{
+		limiter, err := throttled.NewGCRARateLimiter(store, throttled.RateQuota{
 			MaxRate:  throttled.PerDuration(int(rate), time.Duration(period)*time.Second),
 			MaxBurst: int(burst),
 		})
 		if err != nil {
 			return nil, fmt.Errorf("could not create rate limiter: %w", err)
 		}
-		rl.SetMaxCASAttemptsLimit(defaultMaxCASAttemptsLimit)
+		limiter.SetMaxCASAttemptsLimit(defaultMaxCASAttemptsLimit)
+		rl = &gcraLimiter{GCRARateLimiter: limiter, burst: burst, rate: rate, period: period}
 		g.store.Put(key, rl, time.Duration(period)*time.Second)
 	}
 
@@ -27,6 +27,9 @@ func (r *goRate) limit(key string, cost, rate, periodInSecs int64) *goRateReserv
 	if l == nil {
 		l = gorate.NewLimiter(gorate.Every(window), int(rate))
 		r.store.Put(key, l, window)
+	} else if l.Burst() != int(rate) {
+		// the rate of a key can change over time, e.g. with adaptive limits
+		l.SetBurst(int(rate))
 	}
 
 	resWindow := time.Now().Add(window)
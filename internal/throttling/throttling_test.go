@@ -311,3 +311,34 @@ func TestBadData(t *testing.T) {
 func testName(name string, rate, window int64) string {
 	return fmt.Sprintf("%s/%d tokens per %ds", name, rate, window)
 }
+
+func TestInMemoryRateChange(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"go rate": WithInMemoryGoRate(),
+		"gcra":    WithInMemoryGCRA(0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				ctx          = context.Background()
+				l            = newLimiter(t, opt)
+				key          = rand.String(10)
+				window int64 = 3600
+			)
+			allowed := func(rate int64) int64 {
+				var passed int64
+				for i := int64(0); i < 20; i++ {
+					ok, _, err := l.Allow(ctx, 1, rate, window, key)
+					require.NoError(t, err)
+					if ok {
+						passed++
+					}
+				}
+				return passed
+			}
+
+			require.Positive(t, allowed(5))
+			require.Zero(t, allowed(5), "the rate of the key is exhausted")
+			require.Positive(t, allowed(15), "a greater rate of the same key is taken into account")
+		})
+	}
+}
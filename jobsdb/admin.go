@@ -2,7 +2,9 @@ package jobsdb
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/rudderlabs/rudder-server/services/metric"
@@ -13,6 +15,7 @@ func (jd *HandleT) Status() interface{} {
 		"dataset-list":    jd.getDSList(),
 		"dataset-ranges":  jd.getDSRangeList(),
 		"backups-enabled": jd.BackupSettings.isBackupEnabled(),
+		"compaction":      jd.CompactionStatus(),
 	}
 	emptyResults := make(map[string]interface{})
 	for ds, entry := range jd.dsEmptyResultCache {
@@ -183,3 +186,104 @@ func (jd *HandleT) failExecutingDSInTx(txHandler transactionHandler, ds dataSetT
 	)
 	return err
 }
+
+// rpcHandler exposes the operations of a jobsdb over the admin interface
+type rpcHandler struct {
+	jd *HandleT
+}
+
+// TriggerCompaction forces a compaction to run
+func (h *rpcHandler) TriggerCompaction(_ string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	if err := h.jd.TriggerCompaction(); err != nil {
+		return err
+	}
+	*result = "compaction triggered"
+	return nil
+}
+
+// CompactionStatus returns the status of the compactions as json
+func (h *rpcHandler) CompactionStatus(_ string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	status, err := json.Marshal(h.jd.CompactionStatus())
+	if err != nil {
+		return err
+	}
+	*result = string(status)
+	return nil
+}
+
+// Snapshot takes a snapshot of the in-flight jobs, returning it as json
+func (h *rpcHandler) Snapshot(_ string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), h.jd.snapshotTimeout)
+	defer cancel()
+	snapshot, err := h.jd.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	response, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	*result = string(response)
+	return nil
+}
+
+// Restore restores a snapshot, given as json
+func (h *rpcHandler) Restore(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	var snapshot SnapshotT
+	if err := json.Unmarshal([]byte(arg), &snapshot); err != nil {
+		return fmt.Errorf("parsing snapshot: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.jd.snapshotTimeout)
+	defer cancel()
+	if err := h.jd.Restore(ctx, snapshot); err != nil {
+		return err
+	}
+	*result = fmt.Sprintf("restored snapshot %s", snapshot.ID)
+	return nil
+}
+
+// PurgeWorkspace purges all the data of the workspace given as argument, returning what got removed as json
+func (h *rpcHandler) PurgeWorkspace(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), h.jd.purgeTimeout)
+	defer cancel()
+	purge, err := h.jd.PurgeWorkspace(ctx, strings.TrimSpace(arg))
+	if err != nil {
+		return err
+	}
+	response, err := json.Marshal(purge)
+	if err != nil {
+		return err
+	}
+	*result = string(response)
+	return nil
+}
@@ -0,0 +1,161 @@
+package jobsdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Datasets are stored by a storage backend, defining the layout of their tables and what needs to happen before jobs
+// get stored in them. New datasets are created with the backend selected by JobsDB.<prefix>.backend, so that the
+// backend can be chosen per queue, while existing datasets keep the backend they got created with, found from their
+// tables. Backends are registered by name through registerStorageBackend.
+//
+// All backends store datasets in Postgres: jobsdb transactions are shared with other stores (see Tx), which datasets
+// stored elsewhere could not take part in.
+
+const (
+	postgresBackendName    = "postgres"
+	partitionedBackendName = "postgres_partitioned" // experimental
+)
+
+// storageBackend stores the datasets of a jobsdb
+type storageBackend interface {
+	// name is the name the backend is selected with
+	name() string
+	// partitioned tells whether the jobs tables of the backend's datasets are partitioned by workspace
+	partitioned() bool
+	// jobsTableLayout returns the primary key and the partitioning clause of a dataset's jobs table, along with the
+	// constraint of the job_id column of its status table
+	jobsTableLayout(ds dataSetT) (primaryKey, partitionBy, jobIDReference string)
+	// prepareStoreInTx prepares a dataset for storing jobs of workspaces
+	prepareStoreInTx(ctx context.Context, tx *Tx, ds dataSetT, workspaceIDs []string) error
+	// prepareMigrationInTx prepares a dataset for the jobs migrated from another one
+	prepareMigrationInTx(ctx context.Context, tx *Tx, srcDS, destDS dataSetT) error
+	// dropWorkspaceJobsInTx removes all the jobs of a workspace from a dataset, along with their statuses, returning
+	// the number of jobs and statuses removed
+	dropWorkspaceJobsInTx(ctx context.Context, tx *Tx, ds dataSetT, workspaceID string) (jobs, statuses int64, err error)
+	// loadDS loads what the backend keeps about an existing dataset, once the dataset is first found stored with it
+	loadDS(ctx context.Context, q querier, ds dataSetT) error
+	// jobsRelation returns the relation the jobs of a dataset are queried from
+	jobsRelation(ds dataSetT) string
+}
+
+// querier runs queries, in a transaction or not
+type querier interface {
+	rowQuerier
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// storageBackends are the backends datasets can be stored with, by name
+var storageBackends = map[string]func(jd *HandleT) storageBackend{}
+
+// registerStorageBackend registers the backend created by newBackend with the name, failing if a backend is registered
+// with the name already
+func registerStorageBackend(name string, newBackend func(jd *HandleT) storageBackend) error {
+	if _, ok := storageBackends[name]; ok {
+		return fmt.Errorf("jobsdb backend %q is already registered", name)
+	}
+	storageBackends[name] = newBackend
+	return nil
+}
+
+func init() {
+	if err := registerStorageBackend(postgresBackendName, func(*HandleT) storageBackend { return postgresBackend{} }); err != nil {
+		panic(err)
+	}
+	if err := registerStorageBackend(partitionedBackendName, func(jd *HandleT) storageBackend { return &partitionedBackend{jd: jd} }); err != nil {
+		panic(err)
+	}
+}
+
+// newDSBackend returns the backend new datasets are created with
+func (jd *HandleT) newDSBackend() storageBackend {
+	name := jd.backendName
+	if jd.partitionByWorkspace {
+		name = partitionedBackendName
+	}
+	newBackend, ok := storageBackends[name]
+	if !ok {
+		jd.logger.Errorf("Unknown jobsdb backend %q, using %q", name, postgresBackendName)
+		newBackend = storageBackends[postgresBackendName]
+	}
+	return newBackend(jd)
+}
+
+// dsBackend returns the backend a dataset is stored with, loading the dataset into the backend the first time it is
+// found stored with it
+func (jd *HandleT) dsBackend(ctx context.Context, q querier, ds dataSetT) (storageBackend, error) {
+	jd.partitionsCacheLock.RLock()
+	_, found := jd.partitionsCache[ds.JobTable]
+	jd.partitionsCacheLock.RUnlock()
+
+	partitioned, err := jd.isPartitionedDS(ctx, q, ds)
+	if err != nil {
+		return nil, err
+	}
+	backend := storageBackends[postgresBackendName](jd)
+	if partitioned {
+		backend = storageBackends[partitionedBackendName](jd)
+	}
+	if !found {
+		if err := backend.loadDS(ctx, q, ds); err != nil {
+			return nil, fmt.Errorf("loading %q into the %s backend: %w", ds.JobTable, backend.name(), err)
+		}
+	}
+	return backend, nil
+}
+
+// prepareStoreInTx prepares a dataset for storing jobs, according to its backend
+func (jd *HandleT) prepareStoreInTx(ctx context.Context, tx *Tx, ds dataSetT, jobList []*JobT) error {
+	backend, err := jd.dsBackend(ctx, tx, ds)
+	if err != nil {
+		return err
+	}
+	return backend.prepareStoreInTx(ctx, tx, ds, jobsWorkspaces(jobList))
+}
+
+// postgresBackend stores each dataset in a jobs table and a job status table
+type postgresBackend struct{}
+
+func (postgresBackend) name() string { return postgresBackendName }
+
+func (postgresBackend) partitioned() bool { return false }
+
+func (postgresBackend) jobsTableLayout(ds dataSetT) (primaryKey, partitionBy, jobIDReference string) {
+	return "PRIMARY KEY (job_id)", "", fmt.Sprintf("REFERENCES %q(job_id)", ds.JobTable)
+}
+
+func (postgresBackend) loadDS(context.Context, querier, dataSetT) error {
+	return nil
+}
+
+func (postgresBackend) jobsRelation(ds dataSetT) string {
+	return fmt.Sprintf("%q", ds.JobTable)
+}
+
+func (postgresBackend) prepareStoreInTx(context.Context, *Tx, dataSetT, []string) error {
+	return nil
+}
+
+func (postgresBackend) prepareMigrationInTx(context.Context, *Tx, dataSetT, dataSetT) error {
+	return nil
+}
+
+func (postgresBackend) dropWorkspaceJobsInTx(ctx context.Context, tx *Tx, ds dataSetT, workspaceID string) (jobs, statuses int64, err error) {
+	result, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %q WHERE job_id IN (SELECT job_id FROM %q WHERE workspace_id = $1)`, ds.JobStatusTable, ds.JobTable), workspaceID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if statuses, err = result.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+	result, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %q WHERE workspace_id = $1`, ds.JobTable), workspaceID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if jobs, err = result.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+	return jobs, statuses, nil
+}
@@ -0,0 +1,117 @@
+package jobsdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// backlogKeyT identifies the backlog of a destination
+type backlogKeyT struct {
+	customVal     string
+	destinationID string
+}
+
+// backlogT is the number of unprocessed jobs of a destination and the creation time of the oldest one
+type backlogT struct {
+	count  int
+	oldest time.Time
+}
+
+func (jd *HandleT) startBacklogStatsLoop(ctx context.Context) {
+	jd.backgroundGroup.Go(misc.WithBugsnag(func() error {
+		jd.backlogStatsLoop(ctx)
+		return nil
+	}))
+}
+
+// backlogStatsLoop periodically reports the number of unprocessed jobs and the age of the oldest one, per destination
+func (jd *HandleT) backlogStatsLoop(ctx context.Context) {
+	reported := make(map[backlogKeyT]struct{})
+	for {
+		select {
+		case <-time.After(jd.backlogStatsInterval):
+		case <-ctx.Done():
+			return
+		}
+		if !jd.backlogStatsEnabled {
+			continue
+		}
+		backlogs, err := jd.getBacklogs(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				jd.logger.Errorf("Failed to get backlog stats: %v", err)
+			}
+			continue
+		}
+		now := time.Now()
+		for key, backlog := range backlogs {
+			tags := stats.Tags{"customVal": key.customVal, "destinationId": key.destinationID, "tablePrefix": jd.tablePrefix}
+			stats.Default.NewTaggedStat("jobsdb_backlog_jobs", stats.GaugeType, tags).Gauge(backlog.count)
+			stats.Default.NewTaggedStat("jobsdb_backlog_oldest_job_age", stats.GaugeType, tags).Gauge(now.Sub(backlog.oldest).Seconds())
+			reported[key] = struct{}{}
+		}
+		// destinations without a backlog anymore
+		for key := range reported {
+			if _, ok := backlogs[key]; ok {
+				continue
+			}
+			tags := stats.Tags{"customVal": key.customVal, "destinationId": key.destinationID, "tablePrefix": jd.tablePrefix}
+			stats.Default.NewTaggedStat("jobsdb_backlog_jobs", stats.GaugeType, tags).Gauge(0)
+			stats.Default.NewTaggedStat("jobsdb_backlog_oldest_job_age", stats.GaugeType, tags).Gauge(0)
+			delete(reported, key)
+		}
+	}
+}
+
+// getBacklogs returns the backlog of each destination with unprocessed jobs
+func (jd *HandleT) getBacklogs(ctx context.Context) (map[backlogKeyT]backlogT, error) {
+	if !jd.dsListLock.RTryLockWithCtx(ctx) {
+		return nil, fmt.Errorf("could not acquire a dslist read lock: %w", ctx.Err())
+	}
+	dsList := jd.getDSList()
+	jd.dsListLock.RUnlock()
+
+	backlogs := make(map[backlogKeyT]backlogT)
+	for _, ds := range dsList {
+		if err := jd.getDSBacklogs(ctx, ds, backlogs); err != nil {
+			return nil, fmt.Errorf("getting backlogs of %q: %w", ds.JobTable, err)
+		}
+	}
+	return backlogs, nil
+}
+
+func (jd *HandleT) getDSBacklogs(ctx context.Context, ds dataSetT, backlogs map[backlogKeyT]backlogT) error {
+	rows, err := jd.queryReplicaContext(ctx, fmt.Sprintf(`SELECT j.custom_val, COALESCE(j.parameters->>'destination_id', ''), COUNT(*), MIN(j.created_at)
+		FROM %[1]q j LEFT JOIN "v_last_%[2]s" s ON j.job_id = s.job_id
+		WHERE s.job_id IS NULL OR s.job_state = ANY($1)
+		GROUP BY 1, 2`, ds.JobTable, ds.JobStatusTable),
+		pq.Array(validNonTerminalStates),
+	)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var (
+			key     backlogKeyT
+			backlog backlogT
+		)
+		if err := rows.Scan(&key.customVal, &key.destinationID, &backlog.count, &backlog.oldest); err != nil {
+			return err
+		}
+		if existing, ok := backlogs[key]; ok {
+			backlog.count += existing.count
+			if existing.oldest.Before(backlog.oldest) {
+				backlog.oldest = existing.oldest
+			}
+		}
+		backlogs[key] = backlog
+	}
+	return rows.Err()
+}
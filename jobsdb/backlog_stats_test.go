@@ -0,0 +1,46 @@
+package jobsdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+func TestGetBacklogs(t *testing.T) {
+	_ = startPostgres(t)
+
+	jobDB := HandleT{}
+	err := jobDB.Setup(ReadWrite, true, strings.ToLower(rand.String(5)), true, []prebackup.Handler{}, fileuploader.NewDefaultProvider())
+	require.NoError(t, err)
+	defer jobDB.TearDown()
+
+	customVal := rand.String(5)
+	jobs := genJobs(defaultWorkspaceID, customVal, 10, 1)
+	for i, job := range jobs {
+		if i < 6 {
+			job.Parameters = []byte(`{"destination_id":"destination-1"}`)
+		} else {
+			job.Parameters = []byte(`{"destination_id":"destination-2"}`)
+		}
+	}
+	start := time.Now()
+	require.NoError(t, jobDB.Store(context.Background(), jobs))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[:2], Succeeded.State), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[2:3], Failed.State), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[6:], Aborted.State), []string{customVal}, []ParameterFilterT{}))
+
+	backlogs, err := jobDB.getBacklogs(context.Background())
+	require.NoError(t, err)
+	require.Len(t, backlogs, 1, "destinations without unprocessed jobs have no backlog")
+	backlog, ok := backlogs[backlogKeyT{customVal: customVal, destinationID: "destination-1"}]
+	require.True(t, ok)
+	require.Equal(t, 4, backlog.count)
+	require.WithinDuration(t, start, backlog.oldest, time.Minute)
+}
@@ -3,6 +3,7 @@ package jobsdb
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -11,6 +12,9 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/services/filemanager"
 	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
@@ -155,7 +159,11 @@ func (jd *HandleT) failedOnlyBackup(ctx context.Context, backupDSRange *dataSetR
 		return fmt.Sprintf(`%v%v_%v.%v.gz`, tmpDirPath+backupPathDirName, pathPrefix, Aborted.State, workspaceID), nil
 	}
 
-	dumps, err := jd.createTableDumps(getFailedOnlyBackupQueryFn(backupDSRange), getFileName, totalCount)
+	compressed, err := jd.isCompressedDS(ctx, jd.dbHandle, backupDSRange.ds)
+	if err != nil {
+		return err
+	}
+	dumps, err := jd.createTableDumps(getFailedOnlyBackupQueryFn(backupDSRange, compressed), getFileName, totalCount, compressed)
 	if err != nil {
 		return fmt.Errorf("error while creating table dump: %w", err)
 	}
@@ -214,7 +222,11 @@ func (jd *HandleT) backupJobsTable(ctx context.Context, backupDSRange *dataSetRa
 		), nil
 	}
 
-	dumps, err := jd.createTableDumps(getJobsBackupQueryFn(backupDSRange), getFileName, totalCount)
+	compressed, err := jd.isCompressedDS(ctx, jd.dbHandle, backupDSRange.ds)
+	if err != nil {
+		return err
+	}
+	dumps, err := jd.createTableDumps(getJobsBackupQueryFn(backupDSRange, compressed), getFileName, totalCount, compressed)
 	if err != nil {
 		return fmt.Errorf("error while creating table dump: %w", err)
 	}
@@ -267,7 +279,7 @@ func (jd *HandleT) backupStatusTable(ctx context.Context, backupDSRange *dataSet
 		return fmt.Sprintf(`%v%v.%v.gz`, tmpDirPath+backupPathDirName, pathPrefix, workspaceID), nil
 	}
 
-	dumps, err := jd.createTableDumps(getStatusBackupQueryFn(backupDSRange), getFileName, totalCount)
+	dumps, err := jd.createTableDumps(getStatusBackupQueryFn(backupDSRange), getFileName, totalCount, false)
 	if err != nil {
 		return fmt.Errorf("error while creating table dump: %w", err)
 	}
@@ -308,7 +320,15 @@ func (jd *HandleT) removeTableJSONDumps() {
 	}
 }
 
-func getFailedOnlyBackupQueryFn(backupDSRange *dataSetRangeT) func(int64) string {
+// backupPayloadColumn returns the expression dumping the payloads of a dataset, base64 encoded if they are compressed
+func backupPayloadColumn(alias string, compressed bool) string {
+	if compressed {
+		return fmt.Sprintf(`encode(%s.event_payload, 'base64')`, alias)
+	}
+	return alias + ".event_payload"
+}
+
+func getFailedOnlyBackupQueryFn(backupDSRange *dataSetRangeT, compressed bool) func(int64) string {
 	return func(offSet int64) string {
 		return fmt.Sprintf(
 			`SELECT
@@ -320,7 +340,7 @@ func getFailedOnlyBackupQueryFn(backupDSRange *dataSetRangeT) func(int64) string
 				'user_id',failed_jobs.user_id,
 				'parameters',failed_jobs.parameters,
 				'custom_val',failed_jobs.custom_val,
-				'event_payload',failed_jobs.event_payload,
+				'event_payload',%[8]s,
 				'event_count',failed_jobs.event_count,
 				'created_at',failed_jobs.created_at,
 				'expire_at',failed_jobs.expire_at,
@@ -396,11 +416,12 @@ func getFailedOnlyBackupQueryFn(backupDSRange *dataSetRangeT) func(int64) string
 			WHERE
 				subquery.running_payload_size <= %[7]d OR subquery.row_num = 1
 			) AS failed_jobs
-	  `, backupDSRange.ds.JobStatusTable, backupDSRange.ds.JobTable, Failed.State, Aborted.State, backupRowsBatchSize, offSet, backupMaxTotalPayloadSize)
+	  `, backupDSRange.ds.JobStatusTable, backupDSRange.ds.JobTable, Failed.State, Aborted.State, backupRowsBatchSize, offSet, backupMaxTotalPayloadSize,
+			backupPayloadColumn("failed_jobs", compressed))
 	}
 }
 
-func getJobsBackupQueryFn(backupDSRange *dataSetRangeT) func(int64) string {
+func getJobsBackupQueryFn(backupDSRange *dataSetRangeT, compressed bool) func(int64) string {
 	return func(offSet int64) string {
 		return fmt.Sprintf(`
 			SELECT
@@ -412,7 +433,7 @@ func getJobsBackupQueryFn(backupDSRange *dataSetRangeT) func(int64) string {
 					'user_id', dump_table.user_id,
 					'parameters', dump_table.parameters,
 					'custom_val', dump_table.custom_val,
-					'event_payload', dump_table.event_payload,
+					'event_payload', %[5]s,
 					'event_count', dump_table.event_count,
 					'created_at', dump_table.created_at,
 					'expire_at', dump_table.expire_at
@@ -453,7 +474,7 @@ func getJobsBackupQueryFn(backupDSRange *dataSetRangeT) func(int64) string {
 				WHERE
 					subquery.running_payload_size <= %[4]d OR subquery.row_num = 1
 			) AS dump_table
-			`, backupDSRange.ds.JobTable, backupRowsBatchSize, offSet, backupMaxTotalPayloadSize)
+			`, backupDSRange.ds.JobTable, backupRowsBatchSize, offSet, backupMaxTotalPayloadSize, backupPayloadColumn("dump_table", compressed))
 	}
 }
 
@@ -494,7 +515,9 @@ func getStatusBackupQueryFn(backupDSRange *dataSetRangeT) func(int64) string {
 	}
 }
 
-func (jd *HandleT) createTableDumps(queryFunc func(int64) string, pathFunc func(string) (string, error), totalCount int64) (map[string]string, error) {
+// createTableDumps dumps the rows returned by queryFunc to gzipped files per workspace.
+// If compressed is true, the base64 encoded payloads of the rows are decompressed, for dumps to always contain JSON payloads.
+func (jd *HandleT) createTableDumps(queryFunc func(int64) string, pathFunc func(string) (string, error), totalCount int64, compressed bool) (map[string]string, error) {
 	filesWriter := fileuploader.NewGzMultiFileWriter()
 	tableFileDumpTimeStat := stats.Default.NewTaggedStat("table_FileDump_TimeStat", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix})
 	tableFileDumpTimeStat.Start()
@@ -524,6 +547,11 @@ func (jd *HandleT) createTableDumps(queryFunc func(int64) string, pathFunc func(
 				offset++
 				continue
 			}
+			if compressed {
+				if rawJSONRows, err = decompressBackupPayload(rawJSONRows); err != nil {
+					return err
+				}
+			}
 			rawJSONRows = append(rawJSONRows, '\n') // appending '\n'
 			if err != nil {
 				return fmt.Errorf("error while appending '\n': %w", err)
@@ -662,3 +690,15 @@ func (jd *HandleT) getBackupDSRange() *dataSetRangeT {
 	}
 	return &backupDSRange
 }
+
+// decompressBackupPayload replaces the base64 encoded payload of a dumped row of a dataset with compressed payloads with its JSON payload
+func decompressBackupPayload(row json.RawMessage) (json.RawMessage, error) {
+	payload, err := base64.StdEncoding.DecodeString(gjson.GetBytes(row, "event_payload").String())
+	if err != nil {
+		return nil, fmt.Errorf("decoding event payload: %w", err)
+	}
+	if payload, err = decompressPayload(payload); err != nil {
+		return nil, err
+	}
+	return sjson.SetRawBytes(row, "event_payload", payload)
+}
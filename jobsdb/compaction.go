@@ -0,0 +1,184 @@
+package jobsdb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// Compaction is what the migrateDSLoop does: merging small or mostly processed datasets and cleaning up their status
+// tables. Scheduled compactions can be paced, so that they don't collide with peak ingest: they can be limited to a
+// window of hours of the day (UTC) and deferred while jobs are being stored faster than a rate. Compactions triggered
+// through the admin interface are never deferred.
+
+const (
+	compactionDeferredWindow     = "window"
+	compactionDeferredIngestRate = "ingest_rate"
+)
+
+// CompactionStatusT is the status of the compactions of a jobsdb, as reported through the admin interface
+type CompactionStatusT struct {
+	Running            bool      `json:"running"`
+	Forced             bool      `json:"forced"`
+	Runs               int       `json:"runs"`
+	LastStartedAt      time.Time `json:"lastStartedAt"`
+	LastFinishedAt     time.Time `json:"lastFinishedAt"`
+	LastError          string    `json:"lastError,omitempty"`
+	LastDeferredAt     time.Time `json:"lastDeferredAt"`
+	LastDeferredReason string    `json:"lastDeferredReason,omitempty"`
+	MigratedDatasets   int       `json:"migratedDatasets"`
+	MigratedJobs       int       `json:"migratedJobs"`
+	VacuumedTables     int       `json:"vacuumedTables"`
+}
+
+type compactionT struct {
+	// trigger forces a compaction to run, regardless of its pacing
+	trigger chan struct{}
+
+	mu     sync.Mutex
+	status CompactionStatusT
+	// the last job id stored and when it was sampled, for measuring the ingest rate
+	lastMaxJobID  int64
+	lastSampledAt time.Time
+}
+
+// inCompactionWindow returns whether an hour of the day is within the [startHour, endHour) window, which can wrap
+// around midnight. The window spans the whole day if both hours are equal.
+func inCompactionWindow(hour, startHour, endHour int) bool {
+	if startHour == endHour {
+		return true
+	}
+	if startHour < endHour {
+		return hour >= startHour && hour < endHour
+	}
+	return hour >= startHour || hour < endHour
+}
+
+// TriggerCompaction forces a compaction to run, unless one is already pending
+func (jd *HandleT) TriggerCompaction() error {
+	if jd.ownerType == Write {
+		return fmt.Errorf("datasets of %q are not compacted by a writer", jd.tablePrefix)
+	}
+	select {
+	case jd.compaction.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// CompactionStatus returns the status of the compactions
+func (jd *HandleT) CompactionStatus() CompactionStatusT {
+	jd.compaction.mu.Lock()
+	defer jd.compaction.mu.Unlock()
+	return jd.compaction.status
+}
+
+// compactionDeferReason returns why a scheduled compaction should be deferred, if it should
+func (jd *HandleT) compactionDeferReason(ctx context.Context) (string, error) {
+	if !inCompactionWindow(time.Now().UTC().Hour(), jd.compactionStartHour, jd.compactionEndHour) {
+		return compactionDeferredWindow, nil
+	}
+	if jd.compactionMaxIngestRate <= 0 {
+		return "", nil
+	}
+	rate, ok, err := jd.ingestRate(ctx)
+	if err != nil {
+		return "", err
+	}
+	if ok && rate > float64(jd.compactionMaxIngestRate) {
+		return compactionDeferredIngestRate, nil
+	}
+	return "", nil
+}
+
+// ingestRate returns the number of jobs stored per second since it was last called, going by the job ids of the
+// last dataset. The rate is unknown the first time.
+func (jd *HandleT) ingestRate(ctx context.Context) (float64, bool, error) {
+	if !jd.dsListLock.RTryLockWithCtx(ctx) {
+		return 0, false, fmt.Errorf("could not acquire a dslist read lock: %w", ctx.Err())
+	}
+	dsList := jd.getDSList()
+	jd.dsListLock.RUnlock()
+	if len(dsList) == 0 {
+		return 0, false, nil
+	}
+	var maxJobID int64
+	if err := jd.dbHandle.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT COALESCE(MAX(job_id), 0) FROM %q`, dsList[len(dsList)-1].JobTable),
+	).Scan(&maxJobID); err != nil {
+		return 0, false, err
+	}
+	now := time.Now()
+
+	jd.compaction.mu.Lock()
+	defer jd.compaction.mu.Unlock()
+	lastMaxJobID, lastSampledAt := jd.compaction.lastMaxJobID, jd.compaction.lastSampledAt
+	jd.compaction.lastMaxJobID, jd.compaction.lastSampledAt = maxJobID, now
+	if lastSampledAt.IsZero() || maxJobID < lastMaxJobID {
+		return 0, false, nil
+	}
+	elapsed := now.Sub(lastSampledAt).Seconds()
+	if elapsed <= 0 {
+		return 0, false, nil
+	}
+	return float64(maxJobID-lastMaxJobID) / elapsed, true, nil
+}
+
+func (jd *HandleT) compactionDeferred(reason string) {
+	jd.logger.Debugf("[[ migrateDSLoop ]]: Compaction deferred: %s", reason)
+	jd.compaction.mu.Lock()
+	jd.compaction.status.LastDeferredAt = time.Now()
+	jd.compaction.status.LastDeferredReason = reason
+	jd.compaction.mu.Unlock()
+	stats.Default.NewTaggedStat("jobsdb_compaction_deferred", stats.CountType, stats.Tags{"customVal": jd.tablePrefix, "reason": reason}).Increment()
+}
+
+func (jd *HandleT) compactionStarted(forced bool) {
+	jd.compaction.mu.Lock()
+	jd.compaction.status.Running = true
+	jd.compaction.status.Forced = forced
+	jd.compaction.status.LastStartedAt = time.Now()
+	jd.compaction.mu.Unlock()
+	stats.Default.NewTaggedStat("jobsdb_compaction_running", stats.GaugeType, stats.Tags{"customVal": jd.tablePrefix}).Gauge(1)
+}
+
+func (jd *HandleT) compactionFinished(err error) {
+	jd.compaction.mu.Lock()
+	jd.compaction.status.Running = false
+	jd.compaction.status.Runs++
+	jd.compaction.status.LastFinishedAt = time.Now()
+	jd.compaction.status.LastError = ""
+	if err != nil {
+		jd.compaction.status.LastError = err.Error()
+	}
+	jd.compaction.mu.Unlock()
+	stats.Default.NewTaggedStat("jobsdb_compaction_running", stats.GaugeType, stats.Tags{"customVal": jd.tablePrefix}).Gauge(0)
+}
+
+// compactionMigrated records the progress of a compaction, once datasets got migrated
+func (jd *HandleT) compactionMigrated(datasets, jobs int) {
+	jd.compaction.mu.Lock()
+	jd.compaction.status.MigratedDatasets += datasets
+	jd.compaction.status.MigratedJobs += jobs
+	jd.compaction.mu.Unlock()
+	tags := stats.Tags{"customVal": jd.tablePrefix}
+	stats.Default.NewTaggedStat("jobsdb_compaction_migrated_datasets", stats.CountType, tags).Count(datasets)
+	stats.Default.NewTaggedStat("jobsdb_compaction_migrated_jobs", stats.CountType, tags).Count(jobs)
+}
+
+// vacuumStatusTables reclaims the space of status tables that got cleaned up. VACUUM cannot run in a transaction.
+func (jd *HandleT) vacuumStatusTables(ctx context.Context, dsList []dataSetT) error {
+	for _, ds := range dsList {
+		if _, err := jd.dbHandle.ExecContext(ctx, fmt.Sprintf(`VACUUM ANALYZE %q`, ds.JobStatusTable)); err != nil {
+			return fmt.Errorf("vacuuming %q: %w", ds.JobStatusTable, err)
+		}
+	}
+	jd.compaction.mu.Lock()
+	jd.compaction.status.VacuumedTables += len(dsList)
+	jd.compaction.mu.Unlock()
+	stats.Default.NewTaggedStat("jobsdb_compaction_vacuumed_tables", stats.CountType, stats.Tags{"customVal": jd.tablePrefix}).Count(len(dsList))
+	return nil
+}
@@ -0,0 +1,36 @@
+package jobsdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInCompactionWindow(t *testing.T) {
+	for hour := 0; hour < 24; hour++ {
+		require.True(t, inCompactionWindow(hour, 3, 3), "equal hours span the whole day")
+	}
+	require.True(t, inCompactionWindow(2, 2, 6))
+	require.True(t, inCompactionWindow(5, 2, 6))
+	require.False(t, inCompactionWindow(6, 2, 6))
+	require.False(t, inCompactionWindow(1, 2, 6))
+
+	// windows wrapping around midnight
+	require.True(t, inCompactionWindow(22, 22, 4))
+	require.True(t, inCompactionWindow(0, 22, 4))
+	require.True(t, inCompactionWindow(3, 22, 4))
+	require.False(t, inCompactionWindow(4, 22, 4))
+	require.False(t, inCompactionWindow(12, 22, 4))
+}
+
+func TestTriggerCompaction(t *testing.T) {
+	jd := &HandleT{ownerType: ReadWrite}
+	jd.compaction.trigger = make(chan struct{}, 1)
+	require.NoError(t, jd.TriggerCompaction())
+	require.NoError(t, jd.TriggerCompaction(), "triggering a pending compaction doesn't block")
+	require.Len(t, jd.compaction.trigger, 1)
+
+	writer := &HandleT{ownerType: Write}
+	writer.compaction.trigger = make(chan struct{}, 1)
+	require.Error(t, writer.TriggerCompaction())
+}
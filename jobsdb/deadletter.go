@@ -0,0 +1,332 @@
+package jobsdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/config"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Jobs aborted for good, e.g. after the router gave up on them, can be exported as dead letters to object storage, so
+// that they can be recovered and resent. Aborted jobs are exported along with their final error (aborted_at, attempt,
+// error_code and error_response), to a gzipped JSON lines file per workspace, listed in the <prefix>_dead_letters table.
+// Aborted statuses are exported in (exec_time, id) order, the last one exported of each dataset being recorded in the
+// <prefix>_dead_letter_cursors table, jobs being exported at least once. Datasets are only locked against migrations
+// while their aborted jobs are being read, not while they're uploaded.
+
+// DeadLettersFileT is a file of dead letters exported to object storage
+type DeadLettersFileT struct {
+	WorkspaceID string    `json:"workspaceId"`
+	Location    string    `json:"location"`
+	ObjectName  string    `json:"objectName"`
+	JobCount    int       `json:"jobCount"`
+	MinJobID    int64     `json:"minJobId"`
+	MaxJobID    int64     `json:"maxJobId"`
+	ExportedAt  time.Time `json:"exportedAt"`
+}
+
+func (jd *HandleT) startDeadLetterLoop(ctx context.Context) {
+	jd.backgroundGroup.Go(misc.WithBugsnag(func() error {
+		jd.deadLetterLoop(ctx)
+		return nil
+	}))
+}
+
+func (jd *HandleT) deadLetterLoop(ctx context.Context) {
+	for {
+		select {
+		case <-jd.TriggerDeadLetterExport():
+		case <-ctx.Done():
+			return
+		}
+		if !jd.deadLetterEnabled {
+			continue
+		}
+		start := time.Now()
+		timeoutCtx, cancel := context.WithTimeout(ctx, jd.deadLetterTimeout)
+		err := jd.exportDeadLetters(timeoutCtx)
+		cancel()
+		if err != nil {
+			jd.logger.Errorf("Failed to export dead letters: %v", err)
+		}
+		stats.Default.NewTaggedStat("dead_letter_loop", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix, "error": strconv.FormatBool(err != nil)}).Since(start)
+	}
+}
+
+// exportDeadLetters exports the jobs aborted since the last export
+func (jd *HandleT) exportDeadLetters(ctx context.Context) error {
+	if !jd.dsListLock.RTryLockWithCtx(ctx) {
+		return fmt.Errorf("could not acquire a dslist read lock: %w", ctx.Err())
+	}
+	dsList := jd.getDSList()
+	jd.dsListLock.RUnlock()
+
+	cursors, err := jd.getDeadLetterCursors(ctx)
+	if err != nil {
+		return err
+	}
+	statusTables := make([]string, 0, len(dsList))
+	for _, ds := range dsList {
+		statusTables = append(statusTables, ds.JobStatusTable)
+		for {
+			exported, err := jd.exportDeadLettersOfDS(ctx, ds, cursors[ds.JobStatusTable])
+			if err != nil {
+				return fmt.Errorf("exporting dead letters of %q: %w", ds.JobTable, err)
+			}
+			if exported.count > 0 {
+				cursors[ds.JobStatusTable] = exported.last
+			}
+			if exported.count < jd.deadLetterBatchSize {
+				break
+			}
+		}
+	}
+	// cursors of datasets that are gone
+	_, err = jd.dbHandle.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %q WHERE job_status_table <> ALL($1)`, jd.deadLetterCursorsTable()), pq.Array(statusTables))
+	return err
+}
+
+// deadLetterCursorT is the last aborted status of a dataset exported
+type deadLetterCursorT struct {
+	execTime time.Time
+	statusID int64
+}
+
+func (jd *HandleT) getDeadLetterCursors(ctx context.Context) (map[string]deadLetterCursorT, error) {
+	rows, err := jd.dbHandle.QueryContext(ctx, fmt.Sprintf(`SELECT job_status_table, last_status_id, last_exec_time FROM %q`, jd.deadLetterCursorsTable()))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	cursors := make(map[string]deadLetterCursorT)
+	var withoutExecTime []string
+	for rows.Next() {
+		var (
+			statusTable string
+			cursor      deadLetterCursorT
+			execTime    sql.NullTime
+		)
+		if err := rows.Scan(&statusTable, &cursor.statusID, &execTime); err != nil {
+			return nil, err
+		}
+		if !execTime.Valid {
+			withoutExecTime = append(withoutExecTime, statusTable)
+		}
+		cursor.execTime = execTime.Time
+		cursors[statusTable] = cursor
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// cursors recorded before they were paged by exec_time, looked up from their last status
+	for _, statusTable := range withoutExecTime {
+		cursor := cursors[statusTable]
+		err := jd.dbHandle.QueryRowContext(ctx, fmt.Sprintf(`SELECT exec_time FROM %q WHERE id = $1`, statusTable), cursor.statusID).Scan(&cursor.execTime)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("looking up the exec time of the dead letter cursor of %q: %w", statusTable, err)
+		}
+		cursors[statusTable] = cursor
+	}
+	return cursors, nil
+}
+
+// deadLettersFileT is the file the dead letters of a workspace are written to, before being uploaded
+type deadLettersFileT struct {
+	DeadLettersFileT
+	path string
+}
+
+type exportedDeadLettersT struct {
+	count int
+	last  deadLetterCursorT
+}
+
+// exportDeadLettersOfDS exports a batch of the jobs of a dataset aborted after a job status, returning the number of
+// jobs exported. The dataset is locked against migrations while its jobs are read, the batch being skipped if the
+// dataset is gone.
+func (jd *HandleT) exportDeadLettersOfDS(ctx context.Context, ds dataSetT, after deadLetterCursorT) (exportedDeadLettersT, error) {
+	var exported exportedDeadLettersT
+	files, err := jd.readDeadLettersOfDS(ctx, ds, after, &exported)
+	defer func() {
+		for _, file := range files {
+			_ = os.Remove(file.path)
+		}
+	}()
+	if err != nil || exported.count == 0 {
+		return exported, err
+	}
+
+	for _, file := range files {
+		if err := jd.uploadDeadLetters(ctx, file); err != nil {
+			return exported, err
+		}
+	}
+	err = jd.WithTx(func(tx *Tx) error {
+		for _, file := range files {
+			if _, err := tx.ExecContext(ctx,
+				fmt.Sprintf(`INSERT INTO %q (workspace_id, object_name, location, job_count, min_job_id, max_job_id) VALUES ($1, $2, $3, $4, $5, $6)`, jd.deadLettersTable()),
+				file.WorkspaceID, file.ObjectName, file.Location, file.JobCount, file.MinJobID, file.MaxJobID,
+			); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx,
+			fmt.Sprintf(`INSERT INTO %q (job_status_table, last_status_id, last_exec_time) VALUES ($1, $2, $3)
+				ON CONFLICT (job_status_table) DO UPDATE SET last_status_id = EXCLUDED.last_status_id, last_exec_time = EXCLUDED.last_exec_time`, jd.deadLetterCursorsTable()),
+			ds.JobStatusTable, exported.last.statusID, exported.last.execTime,
+		)
+		return err
+	})
+	if err != nil {
+		return exported, err
+	}
+	stats.Default.NewTaggedStat("jobsdb_dead_letters", stats.CountType, stats.Tags{"customVal": jd.tablePrefix}).Count(exported.count)
+	return exported, nil
+}
+
+// readDeadLettersOfDS writes a batch of the jobs of a dataset aborted after a job status to a file per workspace,
+// holding a migration read lock for the dataset not to be migrated meanwhile
+func (jd *HandleT) readDeadLettersOfDS(ctx context.Context, ds dataSetT, after deadLetterCursorT, exported *exportedDeadLettersT) (map[string]*deadLettersFileT, error) {
+	if !jd.dsMigrationLock.RTryLockWithCtx(ctx) {
+		return nil, fmt.Errorf("could not acquire a migration read lock: %w", ctx.Err())
+	}
+	defer jd.dsMigrationLock.RUnlock()
+	if !jd.dsListLock.RTryLockWithCtx(ctx) {
+		return nil, fmt.Errorf("could not acquire a dslist read lock: %w", ctx.Err())
+	}
+	dsList := jd.getDSList()
+	jd.dsListLock.RUnlock()
+	if !dsListContains(dsList, ds) {
+		return nil, nil
+	}
+
+	compressed, err := jd.isCompressedDS(ctx, jd.dbHandle, ds)
+	if err != nil {
+		return nil, err
+	}
+	// statuses are only exported a while after they got created, so that statuses of transactions still in progress are not skipped
+	rows, err := jd.dbHandle.QueryContext(ctx, fmt.Sprintf(`SELECT st.id, st.exec_time, j.workspace_id, j.job_id, jsonb_build_object(
+			'job_id', j.job_id,
+			'workspace_id', j.workspace_id,
+			'uuid', j.uuid,
+			'user_id', j.user_id,
+			'custom_val', j.custom_val,
+			'parameters', j.parameters,
+			'event_payload', %[3]s,
+			'event_count', j.event_count,
+			'created_at', j.created_at,
+			'aborted_at', st.exec_time,
+			'attempt', st.attempt,
+			'error_code', st.error_code,
+			'error_response', st.error_response)
+		FROM %[1]q st JOIN %[2]q j ON j.job_id = st.job_id
+		WHERE (st.exec_time, st.id) > ($1, $7) AND st.job_state = $2 AND st.exec_time < $3 AND ($5 OR st.error_code IS DISTINCT FROM $6)
+		ORDER BY st.exec_time, st.id LIMIT $4`, ds.JobStatusTable, ds.JobTable, backupPayloadColumn("j", compressed)),
+		after.execTime, Aborted.State, time.Now().Add(-jd.deadLetterDelay), jd.deadLetterBatchSize, jd.expiryDeadLetter, expiredErrorCode, after.statusID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tmpDirPath, err := misc.CreateTMPDIR()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*deadLettersFileT)
+	writer := fileuploader.NewGzMultiFileWriter()
+	for rows.Next() {
+		var (
+			statusID    int64
+			execTime    time.Time
+			workspaceID string
+			jobID       int64
+			row         json.RawMessage
+		)
+		if err := rows.Scan(&statusID, &execTime, &workspaceID, &jobID, &row); err != nil {
+			_ = writer.Close()
+			return files, err
+		}
+		if compressed {
+			if row, err = decompressBackupPayload(row); err != nil {
+				_ = writer.Close()
+				return files, err
+			}
+		}
+		file, ok := files[workspaceID]
+		if !ok {
+			file = &deadLettersFileT{
+				DeadLettersFileT: DeadLettersFileT{WorkspaceID: workspaceID, MinJobID: jobID},
+				path:             filepath.Join(tmpDirPath, "rudder-dead-letters", fmt.Sprintf("%s.%s.%d.json.gz", ds.JobTable, workspaceID, statusID)),
+			}
+			files[workspaceID] = file
+		}
+		if jobID < file.MinJobID {
+			file.MinJobID = jobID
+		}
+		if jobID > file.MaxJobID {
+			file.MaxJobID = jobID
+		}
+		file.JobCount++
+		if _, err := writer.Write(file.path, append(row, '\n')); err != nil {
+			_ = writer.Close()
+			return files, fmt.Errorf("writing gz file %q: %w", file.path, err)
+		}
+		exported.count++
+		exported.last = deadLetterCursorT{execTime: execTime, statusID: statusID}
+	}
+	if err := rows.Err(); err != nil {
+		_ = writer.Close()
+		return files, err
+	}
+	return files, writer.Close()
+}
+
+// dsListContains returns whether the dataset is in the list
+func dsListContains(dsList []dataSetT, ds dataSetT) bool {
+	for _, d := range dsList {
+		if d.Index == ds.Index {
+			return true
+		}
+	}
+	return false
+}
+
+func (jd *HandleT) uploadDeadLetters(ctx context.Context, file *deadLettersFileT) error {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return fmt.Errorf("opening gz file %q: %w", file.path, err)
+	}
+	defer func() { _ = f.Close() }()
+	output, err := jd.backupUploadWithExponentialBackoff(ctx, f, file.WorkspaceID, jd.deadLetterPathPrefix, jd.tablePrefix, config.GetString("INSTANCE_ID", "1"))
+	if err != nil {
+		return err
+	}
+	jd.logger.Infof("[JobsDB] :: Exported %d dead letters of workspace %s at %v", file.JobCount, file.WorkspaceID, output.Location)
+	file.Location, file.ObjectName = output.Location, output.ObjectName
+	return nil
+}
+
+func (jd *HandleT) deadLettersTable() string {
+	return jd.tablePrefix + "_dead_letters"
+}
+
+func (jd *HandleT) deadLetterCursorsTable() string {
+	return jd.tablePrefix + "_dead_letter_cursors"
+}
+
+func (jd *HandleT) dropDeadLettersTables() {
+	_, err := jd.dbHandle.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %q, %q`, jd.deadLettersTable(), jd.deadLetterCursorsTable()))
+	jd.assertError(err)
+}
@@ -0,0 +1,102 @@
+package jobsdb
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/testhelper/destination"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+func TestExportDeadLetters(t *testing.T) {
+	_ = startPostgres(t)
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+	minioResource, err := destination.SetupMINIO(pool, t)
+	require.NoError(t, err)
+	t.Setenv("RUDDER_TMPDIR", t.TempDir())
+
+	provider := fileuploader.NewStaticProvider(map[string]fileuploader.StorageSettings{
+		defaultWorkspaceID: {
+			Bucket: backendconfig.StorageBucket{
+				Type: "MINIO",
+				Config: map[string]interface{}{
+					"bucketName":      minioResource.BucketName,
+					"endPoint":        minioResource.Endpoint,
+					"accessKeyID":     minioResource.AccessKey,
+					"secretAccessKey": minioResource.SecretKey,
+				},
+			},
+		},
+	})
+	tablePrefix := strings.ToLower(rand.String(5))
+	jobDB := HandleT{}
+	require.NoError(t, jobDB.Setup(ReadWrite, true, tablePrefix, true, []prebackup.Handler{}, provider))
+	defer jobDB.TearDown()
+	jobDB.deadLetterDelay = 0
+
+	customVal := rand.String(5)
+	jobs := genJobs(defaultWorkspaceID, customVal, 10, 1)
+	require.NoError(t, jobDB.Store(context.Background(), jobs))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[:3], Aborted.State), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[3:], Succeeded.State), []string{customVal}, []ParameterFilterT{}))
+
+	var readonlyDB ReadonlyHandleT
+	require.NoError(t, readonlyDB.Setup(tablePrefix))
+	defer readonlyDB.TearDown()
+	requireDeadLetters := func(expected ...int) []DeadLettersFileT {
+		t.Helper()
+		response, err := readonlyDB.GetDeadLetters(defaultWorkspaceID)
+		require.NoError(t, err)
+		var files []DeadLettersFileT
+		require.NoError(t, json.Unmarshal([]byte(response), &files))
+		require.Len(t, files, len(expected))
+		for i, file := range files {
+			require.Equal(t, expected[i], file.JobCount)
+			require.Equal(t, defaultWorkspaceID, file.WorkspaceID)
+			require.NotEmpty(t, file.ObjectName)
+		}
+		return files
+	}
+
+	require.NoError(t, jobDB.exportDeadLetters(context.Background()))
+	files := requireDeadLetters(3)
+	require.EqualValues(t, 1, files[0].MinJobID)
+	require.EqualValues(t, 3, files[0].MaxJobID)
+
+	// jobs are only exported once
+	require.NoError(t, jobDB.exportDeadLetters(context.Background()))
+	requireDeadLetters(3)
+
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[3:5], Aborted.State), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.exportDeadLetters(context.Background()))
+	requireDeadLetters(2, 3)
+
+	// statuses created before others, but still too recent to be exported, are exported once they're old enough
+	jobDB.deadLetterDelay = time.Hour
+	recent := genJobStatuses(jobs[5:6], Aborted.State)
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), recent, []string{customVal}, []ParameterFilterT{}))
+	old := genJobStatuses(jobs[6:7], Aborted.State)
+	old[0].ExecTime = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), old, []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.exportDeadLetters(context.Background()))
+	files = requireDeadLetters(1, 2, 3)
+	require.EqualValues(t, 7, files[0].MinJobID)
+	jobDB.deadLetterDelay = 0
+	require.NoError(t, jobDB.exportDeadLetters(context.Background()))
+	files = requireDeadLetters(1, 1, 2, 3)
+	require.EqualValues(t, 6, files[0].MinJobID)
+
+	response, err := readonlyDB.GetDeadLetters("other-workspace")
+	require.NoError(t, err)
+	require.JSONEq(t, `[]`, response)
+}
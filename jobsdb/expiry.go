@@ -0,0 +1,155 @@
+package jobsdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/services/metric"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Jobs older than JobsDB.<prefix>.maxJobAge which are not processed yet are aborted with the expired error code, so
+// that a long outage doesn't cause old events to be delivered once it is over. Jobs being executed or imported are
+// left to complete. Up to JobsDB.expiry.batchSize jobs are expired at a time. Expired jobs are exported as dead letters along with the other aborted jobs, unless
+// JobsDB.<prefix>.expiry.deadLetter is disabled.
+
+const expiredErrorCode = "expired"
+
+func (jd *HandleT) startExpiryLoop(ctx context.Context) {
+	jd.backgroundGroup.Go(misc.WithBugsnag(func() error {
+		jd.expiryLoop(ctx)
+		return nil
+	}))
+}
+
+func (jd *HandleT) expiryLoop(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(jd.expiryInterval):
+		case <-ctx.Done():
+			return
+		}
+		if jd.maxJobAge <= 0 {
+			continue
+		}
+		createdBefore := time.Now().Add(-jd.maxJobAge)
+		for {
+			expired, err := jd.expireJobs(ctx, createdBefore, jd.expiryBatchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					jd.logger.Errorf("Failed to expire jobs: %v", err)
+				}
+				break
+			}
+			if expired > 0 {
+				jd.logger.Infof("[JobsDB] :: Expired %d jobs older than %v", expired, jd.maxJobAge)
+				stats.Default.NewTaggedStat("jobsdb_expired_jobs", stats.CountType, stats.Tags{"customVal": jd.tablePrefix}).Count(expired)
+			}
+			if expired < jd.expiryBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// expireJobs aborts up to limit jobs created before a time which are not processed yet, returning the number of jobs
+// expired. Their statuses are written as the ones of any other update, the pending events being decreased once
+// committed.
+func (jd *HandleT) expireJobs(ctx context.Context, createdBefore time.Time, limit int) (int, error) {
+	var statuses []*JobStatusT
+	pendingJobs := make(map[string]map[string]int) // workspaceID -> customVal -> expired jobs
+	err := jd.WithUpdateSafeTx(ctx, func(tx UpdateSafeTx) error {
+		for _, ds := range jd.getDSList() {
+			if len(statuses) >= limit {
+				break
+			}
+			// datasets hold jobs created after the ones of the datasets before them
+			var firstCreatedAt time.Time
+			err := tx.Tx().QueryRowContext(ctx, fmt.Sprintf(`SELECT created_at FROM %q ORDER BY job_id LIMIT 1`, ds.JobTable)).Scan(&firstCreatedAt)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if !firstCreatedAt.Before(createdBefore) {
+				break
+			}
+			dsStatuses, err := jd.expiredJobStatusesInTx(ctx, tx.Tx(), ds, createdBefore, limit-len(statuses), pendingJobs)
+			if err != nil {
+				return fmt.Errorf("expiring jobs of %q: %w", ds.JobTable, err)
+			}
+			statuses = append(statuses, dsStatuses...)
+		}
+		if len(statuses) == 0 {
+			return nil
+		}
+
+		var customVals []string
+		for _, byCustomVal := range pendingJobs {
+			for customVal := range byCustomVal {
+				customVals = append(customVals, customVal)
+			}
+		}
+		if err := jd.UpdateJobStatusInTx(ctx, tx, statuses, misc.Unique(customVals), nil); err != nil {
+			return err
+		}
+		tx.Tx().AddSuccessListener(func() {
+			for workspaceID, byCustomVal := range pendingJobs {
+				for customVal, count := range byCustomVal {
+					metric.DecreasePendingEvents(jd.tablePrefix, workspaceID, customVal, float64(count))
+				}
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(statuses), nil
+}
+
+// expiredJobStatusesInTx returns the aborted statuses of up to limit jobs of the dataset created before a time which are
+// not processed yet, adding them to pendingJobs
+func (jd *HandleT) expiredJobStatusesInTx(ctx context.Context, tx *Tx, ds dataSetT, createdBefore time.Time, limit int, pendingJobs map[string]map[string]int) ([]*JobStatusT, error) {
+	var states []string
+	for _, state := range validNonTerminalStates {
+		if state != Executing.State && state != Importing.State {
+			states = append(states, state)
+		}
+	}
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT j.job_id, j.workspace_id, j.custom_val, COALESCE(s.attempt, 0), COALESCE(s.parameters, '{}'::JSONB)
+		FROM %[1]q j LEFT JOIN "v_last_%[2]s" s ON j.job_id = s.job_id
+		WHERE j.created_at < $1 AND (s.job_id IS NULL OR s.job_state = ANY($2))
+		ORDER BY j.job_id LIMIT $3`, ds.JobTable, ds.JobStatusTable),
+		createdBefore, pq.Array(states), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	now := time.Now()
+	errorResponse := []byte(fmt.Sprintf(`{"reason": "job expired after %v"}`, jd.maxJobAge))
+	var statuses []*JobStatusT
+	for rows.Next() {
+		var (
+			status    = JobStatusT{JobState: Aborted.State, ExecTime: now, RetryTime: now, ErrorCode: expiredErrorCode, ErrorResponse: errorResponse}
+			customVal string
+		)
+		if err := rows.Scan(&status.JobID, &status.WorkspaceId, &customVal, &status.AttemptNum, &status.Parameters); err != nil {
+			return nil, err
+		}
+		if _, ok := pendingJobs[status.WorkspaceId]; !ok {
+			pendingJobs[status.WorkspaceId] = make(map[string]int)
+		}
+		pendingJobs[status.WorkspaceId][customVal]++
+		statuses = append(statuses, &status)
+	}
+	return statuses, rows.Err()
+}
@@ -0,0 +1,59 @@
+package jobsdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/services/metric"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+func TestExpireJobs(t *testing.T) {
+	_ = startPostgres(t)
+
+	jobDB := HandleT{}
+	err := jobDB.Setup(ReadWrite, true, strings.ToLower(rand.String(5)), true, []prebackup.Handler{}, fileuploader.NewDefaultProvider())
+	require.NoError(t, err)
+	defer jobDB.TearDown()
+	jobDB.maxJobAge = time.Hour
+
+	customVal := rand.String(5)
+	jobs := genJobs(defaultWorkspaceID, customVal, 10, 1)
+	require.NoError(t, jobDB.Store(context.Background(), jobs))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[:2], Succeeded.State), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[2:4], Failed.State), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[4:5], Executing.State), []string{customVal}, []ParameterFilterT{}))
+
+	metric.IncreasePendingEvents(jobDB.tablePrefix, defaultWorkspaceID, customVal, 7)
+	expired, err := jobDB.expireJobs(context.Background(), time.Now().Add(-time.Hour), 100)
+	require.NoError(t, err)
+	require.Zero(t, expired, "jobs are not expired before their maximum age")
+
+	expired, err = jobDB.expireJobs(context.Background(), time.Now(), 5)
+	require.NoError(t, err)
+	require.Equal(t, 5, expired, "up to the limit of jobs are expired")
+	expired, err = jobDB.expireJobs(context.Background(), time.Now(), 5)
+	require.NoError(t, err)
+	require.Equal(t, 2, expired, "the rest of the unprocessed and failed jobs are expired")
+	require.Zero(t, metric.PendingEvents(jobDB.tablePrefix, defaultWorkspaceID, customVal).Value(), "pending events of the expired jobs are decreased")
+
+	unprocessed, err := jobDB.GetUnprocessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: 100})
+	require.NoError(t, err)
+	require.Empty(t, unprocessed.Jobs)
+	executing, err := jobDB.GetExecuting(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: 100})
+	require.NoError(t, err)
+	require.Len(t, executing.Jobs, 1, "jobs being executed are left to complete")
+	aborted, err := jobDB.GetProcessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, StateFilters: []string{Aborted.State}, JobsLimit: 100})
+	require.NoError(t, err)
+	require.Len(t, aborted.Jobs, 7)
+	for _, job := range aborted.Jobs {
+		require.Equal(t, expiredErrorCode, job.LastJobStatus.ErrorCode)
+	}
+	require.Equal(t, 1, aborted.Jobs[0].LastJobStatus.AttemptNum, "expired jobs keep their attempts")
+}
@@ -0,0 +1,107 @@
+package jobsdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Jobs handed off from one stage to another, e.g. from the processor to the router, can carry a handoff token
+// identifying the transition they result from. Storing a job claims its token in the <prefix>_handoff_tokens table,
+// in the same transaction, and jobs whose token is already claimed are not stored again: a stage handing off jobs
+// again after a crash, before it could record that it had already done so, doesn't duplicate them. Tokens are kept
+// for JobsDB.handoffTokens.retention, which needs to exceed the time it takes for a handoff to be retried.
+
+func (jd *HandleT) startHandoffTokensCleanupLoop(ctx context.Context) {
+	jd.backgroundGroup.Go(misc.WithBugsnag(func() error {
+		jd.handoffTokensCleanupLoop(ctx)
+		return nil
+	}))
+}
+
+// handoffTokensCleanupLoop periodically deletes the handoff tokens older than their retention
+func (jd *HandleT) handoffTokensCleanupLoop(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(jd.handoffTokensCleanupInterval):
+		case <-ctx.Done():
+			return
+		}
+		_, err := jd.dbHandle.ExecContext(ctx,
+			fmt.Sprintf(`DELETE FROM %q WHERE created_at < $1`, jd.handoffTokensTable()),
+			time.Now().Add(-jd.handoffTokensRetention),
+		)
+		if err != nil && ctx.Err() == nil {
+			jd.logger.Errorf("Failed to clean up handoff tokens: %v", err)
+		}
+	}
+}
+
+// claimHandoffTokensInTx claims the handoff tokens of jobs, returning the jobs to store: the ones without a token and
+// the first one of the jobs with each token that was not claimed yet
+func (jd *HandleT) claimHandoffTokensInTx(ctx context.Context, tx *Tx, jobList []*JobT) ([]*JobT, error) {
+	var tokens []string
+	for _, job := range jobList {
+		if job.HandoffToken != "" {
+			tokens = append(tokens, job.HandoffToken)
+		}
+	}
+	if len(tokens) == 0 {
+		return jobList, nil
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		fmt.Sprintf(`INSERT INTO %q (token) SELECT UNNEST($1::TEXT[]) ON CONFLICT DO NOTHING RETURNING token`, jd.handoffTokensTable()),
+		pq.Array(tokens),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claiming handoff tokens: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	claimed := make(map[string]struct{}, len(tokens))
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, err
+		}
+		claimed[token] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(claimed) == len(tokens) {
+		return jobList, nil
+	}
+
+	toStore := make([]*JobT, 0, len(jobList))
+	var conflicts int
+	for _, job := range jobList {
+		if job.HandoffToken == "" {
+			toStore = append(toStore, job)
+			continue
+		}
+		if _, ok := claimed[job.HandoffToken]; ok {
+			delete(claimed, job.HandoffToken)
+			toStore = append(toStore, job)
+			continue
+		}
+		conflicts++
+	}
+	jd.logger.Warnf("Skipped storing %d jobs handed off already", conflicts)
+	stats.Default.NewTaggedStat("jobsdb_handoff_token_conflicts", stats.CountType, stats.Tags{"customVal": jd.tablePrefix}).Count(conflicts)
+	return toStore, nil
+}
+
+func (jd *HandleT) handoffTokensTable() string {
+	return jd.tablePrefix + "_handoff_tokens"
+}
+
+func (jd *HandleT) dropHandoffTokensTable() {
+	_, err := jd.dbHandle.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %q`, jd.handoffTokensTable()))
+	jd.assertError(err)
+}
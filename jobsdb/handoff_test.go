@@ -0,0 +1,49 @@
+package jobsdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+func TestHandoffTokens(t *testing.T) {
+	_ = startPostgres(t)
+
+	jobDB := HandleT{}
+	err := jobDB.Setup(ReadWrite, true, strings.ToLower(rand.String(5)), true, []prebackup.Handler{}, fileuploader.NewDefaultProvider())
+	require.NoError(t, err)
+	defer jobDB.TearDown()
+
+	customVal := rand.String(5)
+	requireUnprocessed := func(expected int) {
+		t.Helper()
+		result, err := jobDB.GetUnprocessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: 100})
+		require.NoError(t, err)
+		require.Len(t, result.Jobs, expected)
+	}
+
+	jobs := genJobs(defaultWorkspaceID, customVal, 4, 1)
+	jobs[0].HandoffToken = "1/destination/message/0"
+	jobs[1].HandoffToken = "1/destination/message/1"
+	jobs[2].HandoffToken = "1/destination/message/1" // handed off twice in the same batch
+	require.NoError(t, jobDB.Store(context.Background(), jobs))
+	requireUnprocessed(3)
+
+	// the same jobs handed off again, e.g. after a crash
+	retried := genJobs(defaultWorkspaceID, customVal, 3, 1)
+	retried[0].HandoffToken = "1/destination/message/0"
+	retried[1].HandoffToken = "1/destination/message/1"
+	retried[2].HandoffToken = "1/destination/message/2"
+	require.NoError(t, jobDB.Store(context.Background(), retried))
+	requireUnprocessed(4)
+
+	errorMessages := jobDB.StoreWithRetryEach(context.Background(), retried)
+	require.Empty(t, errorMessages)
+	requireUnprocessed(4)
+}
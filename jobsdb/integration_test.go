@@ -976,6 +976,25 @@ func TestMultiTenantGetAllJobs(t *testing.T) {
 		require.NoError(t, err, "failed to get all jobs")
 		require.Equal(t, 3, len(allJobs.Jobs), "should get limit+1 jobs")
 	})
+	t.Run("GetAllJobs doesn't get paused jobs until they're resumed", func(t *testing.T) {
+		paused := genJobStatuses(workspaceAJobs[20:25], Paused.State)
+		for _, status := range paused {
+			status.RetryTime = time.Now().Add(time.Hour)
+		}
+		resumed := genJobStatuses(workspaceAJobs[25:30], Paused.State)
+		for _, status := range resumed {
+			status.RetryTime = time.Now().Add(-time.Second)
+		}
+		require.NoError(t, jobDB.UpdateJobStatus(context.Background(), append(paused, resumed...), []string{customVal}, []ParameterFilterT{}))
+
+		params := GetQueryParamsT{JobsLimit: 30}
+		allJobs, err := mtl.GetAllJobs(context.Background(), map[string]int{workspaceA: 30}, params, 100, nil)
+		require.NoError(t, err, "failed to get all jobs")
+		require.Len(t, allJobs.Jobs, 25, "should get all jobs but the paused ones")
+		for _, job := range allJobs.Jobs {
+			require.False(t, job.JobID >= workspaceAJobs[20].JobID && job.JobID <= workspaceAJobs[24].JobID, "paused job %d picked up", job.JobID)
+		}
+	})
 }
 
 func TestStoreAndUpdateStatusExceedingAnalyzeThreshold(t *testing.T) {
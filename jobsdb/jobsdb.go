@@ -28,6 +28,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
@@ -59,6 +60,7 @@ const (
 	preDropTablePrefix               = "pre_drop_"
 	pgReadonlyTableExceptionFuncName = "readonly_table_exception()"
 	pgErrorCodeTableReadonly         = "RS001"
+	pgErrorCodeCheckViolation        = "23514"
 )
 
 // QueryConditions holds jobsdb query conditions
@@ -81,6 +83,10 @@ type GetQueryParamsT struct {
 	ParameterFilters              []ParameterFilterT
 	StateFilters                  []string
 	AfterJobID                    *int64
+	// jobs of these workspaces aren't returned, e.g. while they're paused
+	ExcludeWorkspaceIDs []string
+	// jobs matching any of these parameter filters aren't returned
+	ExcludeParameterFilters []ParameterFilterT
 
 	// query limits
 
@@ -380,6 +386,8 @@ type JobT struct {
 	LastJobStatus JobStatusT      `json:"LastJobStatus"`
 	Parameters    json.RawMessage `json:"Parameters"`
 	WorkspaceId   string          `json:"WorkspaceId"`
+	// HandoffToken identifies the transition a job results from, so that it is only stored once, see claimHandoffTokensInTx
+	HandoffToken string `json:"-"`
 }
 
 func (job *JobT) String() string {
@@ -412,6 +420,8 @@ jobs. The caller must call the SetUp function on a HandleT object
 */
 type HandleT struct {
 	dbHandle                      *sql.DB
+	replicaDbHandle               *sql.DB // read replica used for read-only statistics, nil if none is configured
+	useReplica                    bool
 	ownerType                     OwnerType
 	tablePrefix                   string
 	datasetList                   []dataSetT
@@ -444,6 +454,17 @@ type HandleT struct {
 	maxWriters                    int
 	maxOpenConnections            int
 	analyzeThreshold              int
+	payloadCompression            bool
+	compressedDSCache             map[string]bool // job table -> whether its payloads are compressed, see isCompressedDS
+	compressedDSCacheLock         sync.RWMutex
+	backendName                   string
+	partitionByWorkspace          bool
+	statusCopyThreshold           int
+	statusBatchLatency            time.Duration
+	statusBatchMaxSize            int
+	statusBatcher                 *statusBatcherT
+	partitionsCache               map[string]map[string]struct{} // job table -> workspaces with a partition, nil if not partitioned, see isPartitionedDS
+	partitionsCacheLock           sync.RWMutex
 	MaxDSSize                     *int
 	backgroundCancel              context.CancelFunc
 	backgroundGroup               *errgroup.Group
@@ -463,6 +484,46 @@ type HandleT struct {
 	TriggerRefreshDS func() <-chan time.Time
 	refreshDSTimeout time.Duration
 
+	// TriggerSpillDS is useful for triggering spillDS to run from tests.
+	TriggerSpillDS  func() <-chan time.Time
+	spillDSTimeout  time.Duration
+	spillEnabled    bool
+	spillPathPrefix string
+
+	backlogStatsEnabled  bool
+	backlogStatsInterval time.Duration
+
+	// TriggerDeadLetterExport is useful for triggering the export of dead letters from tests.
+	TriggerDeadLetterExport func() <-chan time.Time
+	deadLetterEnabled       bool
+	deadLetterPathPrefix    string
+	deadLetterTimeout       time.Duration
+	deadLetterBatchSize     int
+	deadLetterDelay         time.Duration
+
+	handoffTokensRetention       time.Duration
+	handoffTokensCleanupInterval time.Duration
+
+	maxJobAge        time.Duration
+	expiryInterval   time.Duration
+	expiryBatchSize  int
+	expiryDeadLetter bool
+
+	snapshotPathPrefix       string
+	snapshotRestoreBatchSize int
+	snapshotTimeout          time.Duration
+
+	purgeTimeout time.Duration
+
+	statusTail      statusTailT
+	tailMaxDuration time.Duration
+
+	compaction              compactionT
+	compactionStartHour     int
+	compactionEndHour       int
+	compactionMaxIngestRate int
+	compactionVacuum        bool
+
 	lifecycle struct {
 		mu      sync.Mutex
 		started bool
@@ -501,6 +562,10 @@ func (jd *HandleT) registerBackUpSettings() {
 	config.RegisterDurationConfigVariable(10, &jd.maxBackupRetryTime, false, time.Minute, "JobsDB.backup.maxRetry")
 	config.RegisterDurationConfigVariable(1, &jd.refreshDSTimeout, false, time.Minute, "JobsDB.refreshDS.timeout")
 	config.RegisterDurationConfigVariable(2, &jd.migrateDSTimeout, false, time.Minute, "JobsDB.migrateDS.timeout")
+	config.RegisterDurationConfigVariable(10, &jd.spillDSTimeout, false, time.Minute, "JobsDB.spillDS.timeout")
+	config.RegisterDurationConfigVariable(10, &jd.deadLetterTimeout, false, time.Minute, "JobsDB.deadLetter.timeout")
+	config.RegisterDurationConfigVariable(30, &jd.snapshotTimeout, true, time.Minute, "JobsDB.snapshot.timeout")
+	config.RegisterDurationConfigVariable(30, &jd.purgeTimeout, true, time.Minute, "JobsDB.purge.timeout")
 
 	jd.BackupSettings.PathPrefix = strings.TrimSpace(pathPrefix)
 }
@@ -538,6 +603,7 @@ var (
 	Executing    = jobStateT{isValid: true, isTerminal: false, State: "executing"}
 	Waiting      = jobStateT{isValid: true, isTerminal: false, State: "waiting"}
 	WaitingRetry = jobStateT{isValid: true, isTerminal: false, State: "waiting_retry"}
+	Paused       = jobStateT{isValid: true, isTerminal: false, State: "paused"}
 	Migrating    = jobStateT{isValid: true, isTerminal: false, State: "migrating"}
 	Importing    = jobStateT{isValid: true, isTerminal: false, State: "importing"}
 
@@ -558,6 +624,7 @@ var jobStates = []jobStateT{
 	Executing,
 	Waiting,
 	WaitingRetry,
+	Paused,
 	Migrating,
 	Succeeded,
 	Aborted,
@@ -597,6 +664,8 @@ var (
 	jobDoneMigrateThres, jobStatusMigrateThres   float64
 	jobMinRowsMigrateThres                       float64
 	migrateDSLoopSleepDuration                   time.Duration
+	spillDSLoopSleepDuration                     time.Duration
+	deadLetterLoopSleepDuration                  time.Duration
 	addNewDSLoopSleepDuration                    time.Duration
 	refreshDSListLoopSleepDuration               time.Duration
 	backupCheckSleepDuration                     time.Duration
@@ -620,6 +689,8 @@ func loadConfig() {
 	maxMigrateOnce: Maximum number of DSs that are migrated together into one destination
 	maxMigrateDSProbe: Maximum number of DSs that are checked from left to right if they are eligible for migration
 	migrateDSLoopSleepDuration: How often is the loop (which checks for migrating DS) run
+	spillDSLoopSleepDuration: How often is the loop (which checks for spilling DS to object storage) run
+	deadLetterLoopSleepDuration: How often is the loop (which exports aborted jobs to object storage) run
 	addNewDSLoopSleepDuration: How often is the loop (which checks for adding new DS) run
 	refreshDSListLoopSleepDuration: How often is the loop (which refreshes DSList) run
 	maxTableSizeInMB: Maximum Table size in MB
@@ -634,6 +705,8 @@ func loadConfig() {
 	config.RegisterInt64ConfigVariable(10000, &backupRowsBatchSize, true, 1, "JobsDB.backupRowsBatchSize")
 	config.RegisterInt64ConfigVariable(64*bytesize.MB, &backupMaxTotalPayloadSize, true, 1, "JobsDB.maxBackupTotalPayloadSize")
 	config.RegisterDurationConfigVariable(30, &migrateDSLoopSleepDuration, true, time.Second, []string{"JobsDB.migrateDSLoopSleepDuration", "JobsDB.migrateDSLoopSleepDurationInS"}...)
+	config.RegisterDurationConfigVariable(60, &spillDSLoopSleepDuration, true, time.Second, "JobsDB.spillDSLoopSleepDuration")
+	config.RegisterDurationConfigVariable(60, &deadLetterLoopSleepDuration, true, time.Second, "JobsDB.deadLetterLoopSleepDuration")
 	config.RegisterDurationConfigVariable(5, &addNewDSLoopSleepDuration, true, time.Second, []string{"JobsDB.addNewDSLoopSleepDuration", "JobsDB.addNewDSLoopSleepDurationInS"}...)
 	config.RegisterDurationConfigVariable(5, &refreshDSListLoopSleepDuration, true, time.Second, []string{"JobsDB.refreshDSListLoopSleepDuration", "JobsDB.refreshDSListLoopSleepDurationInS"}...)
 	config.RegisterDurationConfigVariable(5, &backupCheckSleepDuration, true, time.Second, []string{"JobsDB.backupCheckSleepDuration", "JobsDB.backupCheckSleepDurationIns"}...)
@@ -731,6 +804,7 @@ func (jd *HandleT) init() {
 	jd.logger = pkgLogger.Child(jd.tablePrefix)
 	jd.dsListLock = lock.NewLocker()
 	jd.dsMigrationLock = lock.NewLocker()
+	jd.compaction.trigger = make(chan struct{}, 1)
 	if jd.MaxDSSize == nil {
 		// passing `maxDSSize` by reference, so it can be hot reloaded
 		jd.MaxDSSize = &maxDSSize
@@ -748,6 +822,18 @@ func (jd *HandleT) init() {
 		}
 	}
 
+	if jd.TriggerDeadLetterExport == nil {
+		jd.TriggerDeadLetterExport = func() <-chan time.Time {
+			return time.After(deadLetterLoopSleepDuration)
+		}
+	}
+
+	if jd.TriggerSpillDS == nil {
+		jd.TriggerSpillDS = func() <-chan time.Time {
+			return time.After(spillDSLoopSleepDuration)
+		}
+	}
+
 	if jd.TriggerRefreshDS == nil {
 		jd.TriggerRefreshDS = func() <-chan time.Time {
 			return time.After(refreshDSListLoopSleepDuration)
@@ -788,6 +874,13 @@ func (jd *HandleT) init() {
 
 		jd.dbHandle = sqlDB
 	}
+	if replicaInfo := misc.GetReplicaConnectionString(); jd.replicaDbHandle == nil && jd.useReplica && replicaInfo != "" {
+		replicaDB, err := sql.Open("postgres", replicaInfo)
+		jd.assertError(err)
+		replicaDB.SetMaxOpenConns(config.GetInt("JobsDB."+jd.tablePrefix+"."+"replica.maxOpenConnections", config.GetInt("JobsDB.replica.maxOpenConnections", 5)))
+		jd.assertError(replicaDB.Ping())
+		jd.replicaDbHandle = replicaDB
+	}
 	jd.workersAndAuxSetup()
 
 	err := jd.WithTx(func(tx *Tx) error {
@@ -847,8 +940,13 @@ func (jd *HandleT) workersAndAuxSetup() {
 	jd.assert(jd.tablePrefix != "", "tablePrefix received is empty")
 
 	jd.dsEmptyResultCache = map[dataSetT]map[string]map[string]map[string]map[string]cacheEntry{}
+	registerPrefixHandle(jd)
 	if jd.registerStatusHandler {
 		admin.RegisterStatusHandler(jd.tablePrefix+"-jobsdb", jd)
+		if jd.ownerType != Write {
+			admin.RegisterAdminHandler(jd.tablePrefix+"-jobsdb", &rpcHandler{jd: jd})
+			admin.RegisterHTTPHandler("/jobsdb/"+jd.tablePrefix+"/tail", http.HandlerFunc(jd.tailHandler))
+		}
 	}
 	jd.BackupSettings = &backupSettings{}
 	jd.registerBackUpSettings()
@@ -875,13 +973,73 @@ func (jd *HandleT) workersAndAuxSetup() {
 	config.RegisterIntConfigVariable(3, &jd.maxReaders, false, 1, maxReadersKeys...)
 	maxOpenConnectionsKeys := []string{"JobsDB." + jd.tablePrefix + "." + "maxOpenConnections", "JobsDB." + "maxOpenConnections"}
 	config.RegisterIntConfigVariable(20, &jd.maxOpenConnections, false, 1, maxOpenConnectionsKeys...)
+	// the read replica is only used if one is configured with DB.replica.host
+	useReplicaKeys := []string{"JobsDB." + jd.tablePrefix + "." + "useReplica", "JobsDB." + "useReplica"}
+	config.RegisterBoolConfigVariable(true, &jd.useReplica, false, useReplicaKeys...)
 	analyzeThresholdKeys := []string{"JobsDB." + jd.tablePrefix + "." + "analyzeThreshold", "JobsDB." + "analyzeThreshold"}
 	config.RegisterIntConfigVariable(30000, &jd.analyzeThreshold, false, 1, analyzeThresholdKeys...)
+	// payload compression only applies to datasets created while it is enabled
+	payloadCompressionKeys := []string{"JobsDB." + jd.tablePrefix + "." + "payloadCompression", "JobsDB." + "payloadCompression"}
+	config.RegisterBoolConfigVariable(false, &jd.payloadCompression, true, payloadCompressionKeys...)
+	// the storage backend only applies to datasets created while it is selected, see storageBackend
+	backendKeys := []string{"JobsDB." + jd.tablePrefix + "." + "backend", "JobsDB." + "backend"}
+	config.RegisterStringConfigVariable(postgresBackendName, &jd.backendName, true, backendKeys...)
+	// partitioning jobs tables by workspace selects the postgres_partitioned backend
+	partitionByWorkspaceKeys := []string{"JobsDB." + jd.tablePrefix + "." + "partitionByWorkspace", "JobsDB." + "partitionByWorkspace"}
+	config.RegisterBoolConfigVariable(false, &jd.partitionByWorkspace, true, partitionByWorkspaceKeys...)
+	// writing statuses with multi-row statements or COPY, and batching concurrent status updates (0 latency for no batching)
+	statusCopyThresholdKeys := []string{"JobsDB." + jd.tablePrefix + "." + "statusWrites.copyThreshold", "JobsDB." + "statusWrites.copyThreshold"}
+	config.RegisterIntConfigVariable(100, &jd.statusCopyThreshold, true, 1, statusCopyThresholdKeys...)
+	statusBatchLatencyKeys := []string{"JobsDB." + jd.tablePrefix + "." + "statusWrites.batchLatency", "JobsDB." + "statusWrites.batchLatency"}
+	config.RegisterDurationConfigVariable(0, &jd.statusBatchLatency, true, time.Millisecond, statusBatchLatencyKeys...)
+	statusBatchMaxSizeKeys := []string{"JobsDB." + jd.tablePrefix + "." + "statusWrites.maxBatchSize", "JobsDB." + "statusWrites.maxBatchSize"}
+	config.RegisterIntConfigVariable(10000, &jd.statusBatchMaxSize, true, 1, statusBatchMaxSizeKeys...)
 
 	minDSRetentionPeriodKeys := []string{"JobsDB." + jd.tablePrefix + "." + "minDSRetention", "JobsDB." + "minDSRetention"}
 	config.RegisterDurationConfigVariable(0, &jd.MinDSRetentionPeriod, true, time.Minute, minDSRetentionPeriodKeys...)
 	maxDSRetentionPeriodKeys := []string{"JobsDB." + jd.tablePrefix + "." + "maxDSRetention", "JobsDB." + "maxDSRetention"}
 	config.RegisterDurationConfigVariable(90, &jd.MaxDSRetentionPeriod, true, time.Minute, maxDSRetentionPeriodKeys...)
+	// spilling datasets retained because of minDSRetention to object storage, once all of their jobs are processed
+	spillEnabledKeys := []string{"JobsDB." + jd.tablePrefix + "." + "spill.enabled", "JobsDB." + "spill.enabled"}
+	config.RegisterBoolConfigVariable(false, &jd.spillEnabled, true, spillEnabledKeys...)
+	config.RegisterStringConfigVariable("rudder-spilled-datasets", &jd.spillPathPrefix, false, "JobsDB.spill.pathPrefix")
+	// reporting the number of unprocessed jobs and the age of the oldest one per destination
+	backlogStatsEnabledKeys := []string{"JobsDB." + jd.tablePrefix + "." + "backlogStats.enabled", "JobsDB." + "backlogStats.enabled"}
+	config.RegisterBoolConfigVariable(false, &jd.backlogStatsEnabled, true, backlogStatsEnabledKeys...)
+	backlogStatsIntervalKeys := []string{"JobsDB." + jd.tablePrefix + "." + "backlogStats.interval", "JobsDB." + "backlogStats.interval"}
+	config.RegisterDurationConfigVariable(60, &jd.backlogStatsInterval, true, time.Second, backlogStatsIntervalKeys...)
+	// exporting aborted jobs to object storage as dead letters
+	deadLetterEnabledKeys := []string{"JobsDB." + jd.tablePrefix + "." + "deadLetter.enabled", "JobsDB." + "deadLetter.enabled"}
+	config.RegisterBoolConfigVariable(false, &jd.deadLetterEnabled, true, deadLetterEnabledKeys...)
+	deadLetterPathPrefixKeys := []string{"JobsDB." + jd.tablePrefix + "." + "deadLetter.pathPrefix", "JobsDB." + "deadLetter.pathPrefix"}
+	config.RegisterStringConfigVariable("rudder-dead-letters", &jd.deadLetterPathPrefix, false, deadLetterPathPrefixKeys...)
+	config.RegisterIntConfigVariable(10000, &jd.deadLetterBatchSize, true, 1, "JobsDB.deadLetter.batchSize")
+	config.RegisterDurationConfigVariable(1, &jd.deadLetterDelay, true, time.Minute, "JobsDB.deadLetter.delay")
+	// aborting jobs not processed yet after a maximum age (0 for no maximum)
+	maxJobAgeKeys := []string{"JobsDB." + jd.tablePrefix + "." + "maxJobAge", "JobsDB." + "maxJobAge"}
+	config.RegisterDurationConfigVariable(0, &jd.maxJobAge, true, time.Hour, maxJobAgeKeys...)
+	expiryDeadLetterKeys := []string{"JobsDB." + jd.tablePrefix + "." + "expiry.deadLetter", "JobsDB." + "expiry.deadLetter"}
+	config.RegisterBoolConfigVariable(true, &jd.expiryDeadLetter, true, expiryDeadLetterKeys...)
+	config.RegisterDurationConfigVariable(5, &jd.expiryInterval, true, time.Minute, "JobsDB.expiry.interval")
+	config.RegisterIntConfigVariable(10000, &jd.expiryBatchSize, true, 1, "JobsDB.expiry.batchSize")
+	// handoff tokens need to be retained longer than it takes for a handoff to be retried
+	config.RegisterDurationConfigVariable(3, &jd.handoffTokensRetention, true, time.Hour, "JobsDB.handoffTokens.retention")
+	config.RegisterDurationConfigVariable(5, &jd.handoffTokensCleanupInterval, true, time.Minute, "JobsDB.handoffTokens.cleanupInterval")
+	// snapshots of in-flight jobs, see Snapshot
+	config.RegisterStringConfigVariable("rudder-jobsdb-snapshots", &jd.snapshotPathPrefix, false, "JobsDB.snapshot.pathPrefix")
+	config.RegisterIntConfigVariable(10000, &jd.snapshotRestoreBatchSize, true, 1, "JobsDB.snapshot.restoreBatchSize")
+	// tailing status transitions through the admin interface, see tailHandler
+	config.RegisterDurationConfigVariable(10, &jd.tailMaxDuration, true, time.Minute, "JobsDB.tail.maxDuration")
+	// pacing of scheduled compactions: a window of hours (UTC), spanning the whole day if both hours are equal, and
+	// a maximum ingest rate in jobs per second above which compactions are deferred (0 for no maximum)
+	compactionStartHourKeys := []string{"JobsDB." + jd.tablePrefix + "." + "compaction.startHour", "JobsDB." + "compaction.startHour"}
+	config.RegisterIntConfigVariable(0, &jd.compactionStartHour, true, 1, compactionStartHourKeys...)
+	compactionEndHourKeys := []string{"JobsDB." + jd.tablePrefix + "." + "compaction.endHour", "JobsDB." + "compaction.endHour"}
+	config.RegisterIntConfigVariable(0, &jd.compactionEndHour, true, 1, compactionEndHourKeys...)
+	compactionMaxIngestRateKeys := []string{"JobsDB." + jd.tablePrefix + "." + "compaction.maxIngestRate", "JobsDB." + "compaction.maxIngestRate"}
+	config.RegisterIntConfigVariable(0, &jd.compactionMaxIngestRate, true, 1, compactionMaxIngestRateKeys...)
+	compactionVacuumKeys := []string{"JobsDB." + jd.tablePrefix + "." + "compaction.vacuum", "JobsDB." + "compaction.vacuum"}
+	config.RegisterBoolConfigVariable(false, &jd.compactionVacuum, true, compactionVacuumKeys...)
 }
 
 // Start starts the jobsdb worker and housekeeping (migration, archive) threads.
@@ -947,6 +1105,11 @@ func (jd *HandleT) readerSetup(ctx context.Context, l lock.LockToken) {
 
 	jd.startBackupDSLoop(ctx)
 	jd.startMigrateDSLoop(ctx)
+	jd.startSpillDSLoop(ctx)
+	jd.startBacklogStatsLoop(ctx)
+	jd.startDeadLetterLoop(ctx)
+	jd.startExpiryLoop(ctx)
+	jd.startStatusBatchLoop(ctx)
 
 	g.Go(misc.WithBugsnag(func() error {
 		runArchiver(ctx, jd.tablePrefix, jd.dbHandle)
@@ -970,6 +1133,7 @@ func (jd *HandleT) writerSetup(ctx context.Context, l lock.LockToken) {
 		jd.addNewDSLoop(ctx)
 		return nil
 	}))
+	jd.startHandoffTokensCleanupLoop(ctx)
 }
 
 func (jd *HandleT) readerWriterSetup(ctx context.Context, l lock.LockToken) {
@@ -979,6 +1143,11 @@ func (jd *HandleT) readerWriterSetup(ctx context.Context, l lock.LockToken) {
 
 	jd.startBackupDSLoop(ctx)
 	jd.startMigrateDSLoop(ctx)
+	jd.startSpillDSLoop(ctx)
+	jd.startBacklogStatsLoop(ctx)
+	jd.startDeadLetterLoop(ctx)
+	jd.startExpiryLoop(ctx)
+	jd.startStatusBatchLoop(ctx)
 
 	jd.backgroundGroup.Go(misc.WithBugsnag(func() error {
 		runArchiver(ctx, jd.tablePrefix, jd.dbHandle)
@@ -1011,7 +1180,11 @@ func (jd *HandleT) TearDown() {
 //
 //	Stop should be called before Close.
 func (jd *HandleT) Close() {
+	unregisterPrefixHandle(jd)
 	_ = jd.dbHandle.Close()
+	if jd.replicaDbHandle != nil {
+		_ = jd.replicaDbHandle.Close()
+	}
 }
 
 /*
@@ -1112,7 +1285,9 @@ func (jd *HandleT) getTableRowCount(jobTable string) int {
 func (jd *HandleT) getTableSize(jobTable string) int64 {
 	var tableSize int64
 
-	sqlStatement := fmt.Sprintf(`SELECT PG_TOTAL_RELATION_SIZE('%s')`, jobTable)
+	// the size of jobs tables partitioned by workspace is the size of their partitions
+	sqlStatement := fmt.Sprintf(`SELECT PG_TOTAL_RELATION_SIZE('%[1]s') + COALESCE(SUM(PG_TOTAL_RELATION_SIZE(inhrelid)), 0)
+		FROM pg_catalog.pg_inherits WHERE inhparent = '%[1]s'::regclass`, jobTable)
 	row := jd.dbHandle.QueryRow(sqlStatement)
 	err := row.Scan(&tableSize)
 	jd.assertError(err)
@@ -1218,7 +1393,7 @@ func (jd *HandleT) addNewDSInTx(tx *Tx, l lock.LockToken, dsList []dataSetT, ds
 	queryStat := stats.Default.NewTaggedStat("add_new_ds", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix})
 	queryStat.Start()
 	defer queryStat.End()
-	err := jd.createDSInTx(tx, ds)
+	err := jd.createDSInTx(tx, ds, jd.payloadCompression, jd.newDSBackend())
 	if err != nil {
 		return err
 	}
@@ -1236,12 +1411,12 @@ func (jd *HandleT) addNewDSInTx(tx *Tx, l lock.LockToken, dsList []dataSetT, ds
 	return nil
 }
 
-func (jd *HandleT) addDSInTx(tx *Tx, ds dataSetT) error {
+func (jd *HandleT) addDSInTx(tx *Tx, ds dataSetT, compressed bool, backend storageBackend) error {
 	jd.logger.Infof("Creating DS %+v", ds)
 	queryStat := stats.Default.NewTaggedStat("add_new_ds", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix})
 	queryStat.Start()
 	defer queryStat.End()
-	return jd.createDSInTx(tx, ds)
+	return jd.createDSInTx(tx, ds, compressed, backend)
 }
 
 // mustDropDS drops a dataset and panics if it fails to do so
@@ -1272,13 +1447,15 @@ func (jd *HandleT) doComputeNewIdxForAppend(dList []dataSetT) string {
 type transactionHandler interface {
 	Exec(string, ...interface{}) (sql.Result, error)
 	Prepare(query string) (*sql.Stmt, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 	// If required, add other definitions that are common between *sql.DB and *sql.Tx
 	// Never include Commit and Rollback in this interface
 	// That ensures that whoever is acting on a transactionHandler can't commit or rollback
 	// Only the function that passes *sql.Tx should do the commit or rollback based on the error it receives
 }
 
-func (jd *HandleT) createDSInTx(tx *Tx, newDS dataSetT) error {
+// createDSInTx creates a dataset stored with a backend, storing compressed payloads if compressed is true
+func (jd *HandleT) createDSInTx(tx *Tx, newDS dataSetT, compressed bool, backend storageBackend) error {
 	// Mark the start of operation. If we crash somewhere here, we delete the
 	// DS being added
 	opPayload, err := json.Marshal(&journalOpPayloadT{To: newDS})
@@ -1291,23 +1468,33 @@ func (jd *HandleT) createDSInTx(tx *Tx, newDS dataSetT) error {
 		return err
 	}
 
+	payloadType := "JSONB"
+	if compressed {
+		payloadType = "BYTEA"
+	}
+	primaryKey, partitionBy, jobIDReference := backend.jobsTableLayout(newDS)
 	// Create the jobs and job_status tables
 	sqlStatement := fmt.Sprintf(`CREATE TABLE %q (
-                                      job_id BIGSERIAL PRIMARY KEY,
+                                      job_id BIGSERIAL,
 									  workspace_id TEXT NOT NULL DEFAULT '',
 									  uuid UUID NOT NULL,
 									  user_id TEXT NOT NULL,
 									  parameters JSONB NOT NULL,
                                       custom_val VARCHAR(64) NOT NULL,
-                                      event_payload JSONB NOT NULL,
+                                      event_payload %s NOT NULL,
 									  event_count INTEGER NOT NULL DEFAULT 1,
                                       created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
-                                      expire_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW());`, newDS.JobTable)
+                                      expire_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+									  %s)%s;`, newDS.JobTable, payloadType, primaryKey, partitionBy)
 
 	_, err = tx.ExecContext(context.TODO(), sqlStatement)
 	if err != nil {
 		return err
 	}
+	tx.AddSuccessListener(func() {
+		jd.setCompressedDS(newDS, compressed)
+		jd.setPartitionedDS(newDS, backend.partitioned())
+	})
 
 	// TODO : Evaluate a way to handle indexes only for particular tables
 	if jd.tablePrefix == "rt" {
@@ -1320,7 +1507,7 @@ func (jd *HandleT) createDSInTx(tx *Tx, newDS dataSetT) error {
 
 	sqlStatement = fmt.Sprintf(`CREATE TABLE %q (
                                      id BIGSERIAL,
-                                     job_id BIGINT REFERENCES %q(job_id),
+                                     job_id BIGINT %s,
                                      job_state VARCHAR(64),
                                      attempt SMALLINT,
                                      exec_time TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
@@ -1328,7 +1515,7 @@ func (jd *HandleT) createDSInTx(tx *Tx, newDS dataSetT) error {
                                      error_code VARCHAR(32),
                                      error_response JSONB DEFAULT '{}'::JSONB,
 									 parameters JSONB DEFAULT '{}'::JSONB,
-									 PRIMARY KEY (job_id, job_state, id));`, newDS.JobStatusTable, newDS.JobTable)
+									 PRIMARY KEY (job_id, job_state, id));`, newDS.JobStatusTable, jobIDReference)
 
 	_, err = tx.ExecContext(context.TODO(), sqlStatement)
 	if err != nil {
@@ -1653,6 +1840,9 @@ func (jd *HandleT) copyJobsDS(tx *Tx, ds dataSetT, jobList []*JobT) error { // W
 	tx.AddSuccessListener(func() {
 		jd.clearCache(ds, jobList)
 	})
+	if err := jd.prepareStoreInTx(context.TODO(), tx, ds, jobList); err != nil {
+		return err
+	}
 	return jd.copyJobsDSInTx(tx, ds, jobList)
 }
 
@@ -1885,6 +2075,8 @@ func (jd *HandleT) GetPileUpCounts(ctx context.Context) (map[string]map[string]i
 		  group by
 			customVal,
 			workspace;`, ds.JobTable, ds.JobStatusTable)
+		// read from the primary, since the pending events gauges are reset to these counts and a lagging replica would
+		// leave them off until the next reset
 		rows, err := jd.dbHandle.QueryContext(ctx, queryString)
 		if err != nil {
 			return nil, err
@@ -1910,10 +2102,29 @@ func (jd *HandleT) GetPileUpCounts(ctx context.Context) (map[string]map[string]i
 	return statMap, nil
 }
 
-func (*HandleT) copyJobsDSInTx(txHandler transactionHandler, ds dataSetT, jobList []*JobT) error {
+// queryReplicaContext runs a read-only query against the read replica if there is one, falling back to the primary
+// for datasets the replica isn't aware of yet
+func (jd *HandleT) queryReplicaContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if jd.replicaDbHandle == nil {
+		return jd.dbHandle.QueryContext(ctx, query, args...)
+	}
+	rows, err := jd.replicaDbHandle.QueryContext(ctx, query, args...)
+	var e *pq.Error
+	if errors.As(err, &e) && e.Code == pq.ErrorCode("42P01") {
+		jd.logger.Debugf("[%s] falling back to the primary for a table missing in the replica: %v", jd.tablePrefix, err)
+		return jd.dbHandle.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+func (jd *HandleT) copyJobsDSInTx(txHandler transactionHandler, ds dataSetT, jobList []*JobT) error {
 	var stmt *sql.Stmt
 	var err error
 
+	payloads, err := jd.payloadValues(context.TODO(), txHandler, ds, jobList)
+	if err != nil {
+		return err
+	}
 	stmt, err = txHandler.Prepare(pq.CopyIn(ds.JobTable, "job_id", "uuid", "user_id", "custom_val", "parameters",
 		"event_payload", "event_count", "created_at", "expire_at", "workspace_id"))
 
@@ -1923,14 +2134,14 @@ func (*HandleT) copyJobsDSInTx(txHandler transactionHandler, ds dataSetT, jobLis
 
 	defer func() { _ = stmt.Close() }()
 
-	for _, job := range jobList {
+	for i, job := range jobList {
 		eventCount := 1
 		if job.EventCount > 1 {
 			eventCount = job.EventCount
 		}
 
 		_, err = stmt.Exec(job.JobID, job.UUID, job.UserID, job.CustomVal, string(job.Parameters),
-			string(job.EventPayload), eventCount, job.CreatedAt, job.ExpireAt, job.WorkspaceId)
+			payloads[i], eventCount, job.CreatedAt, job.ExpireAt, job.WorkspaceId)
 
 		if err != nil {
 			return err
@@ -1953,26 +2164,38 @@ func (jd *HandleT) doStoreJobsInTx(ctx context.Context, tx *Tx, ds dataSetT, job
 		var stmt *sql.Stmt
 		var err error
 
+		// jobs handed off already are not stored again
+		jobs, err := jd.claimHandoffTokensInTx(ctx, tx, jobList)
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+		if err = jd.prepareStoreInTx(ctx, tx, ds, jobs); err != nil {
+			return err
+		}
+		payloads, err := jd.payloadValues(ctx, tx, ds, jobs)
+		if err != nil {
+			return err
+		}
 		stmt, err = tx.PrepareContext(ctx, pq.CopyIn(ds.JobTable, "uuid", "user_id", "custom_val", "parameters", "event_payload", "event_count", "workspace_id"))
 		if err != nil {
 			return err
 		}
 
 		defer func() { _ = stmt.Close() }()
-		for _, job := range jobList {
+		for i, job := range jobs {
 			eventCount := 1
 			if job.EventCount > 1 {
 				eventCount = job.EventCount
 			}
 
-			if _, err = stmt.ExecContext(ctx, job.UUID, job.UserID, job.CustomVal, string(job.Parameters), string(job.EventPayload), eventCount, job.WorkspaceId); err != nil {
+			if _, err = stmt.ExecContext(ctx, job.UUID, job.UserID, job.CustomVal, string(job.Parameters), payloads[i], eventCount, job.WorkspaceId); err != nil {
 				return err
 			}
 		}
 		if _, err = stmt.ExecContext(ctx); err != nil {
 			return err
 		}
-		if len(jobList) > jd.analyzeThreshold {
+		if len(jobs) > jd.analyzeThreshold {
 			_, err = tx.ExecContext(ctx, fmt.Sprintf(`ANALYZE %q`, ds.JobTable))
 		}
 
@@ -1992,6 +2215,13 @@ func (jd *HandleT) doStoreJobsInTx(ctx context.Context, tx *Tx, ds dataSetT, job
 		if e.Code == pgErrorCodeTableReadonly {
 			return errStaleDsList
 		}
+		if isMissingPartitionError(e) {
+			if _, err := tx.ExecContext(ctx, rollbackSql); err != nil {
+				return err
+			}
+			jd.forgetWorkspacePartitions(ds, jobsWorkspaces(jobList))
+			return store()
+		}
 		if _, ok := dbInvalidJsonErrors[string(e.Code)]; ok {
 			if _, err := tx.ExecContext(ctx, rollbackSql); err != nil {
 				return err
@@ -2006,6 +2236,13 @@ func (jd *HandleT) doStoreJobsInTx(ctx context.Context, tx *Tx, ds dataSetT, job
 }
 
 func (jd *HandleT) storeJob(ctx context.Context, tx *Tx, ds dataSetT, job *JobT) (err error) {
+	// a job handed off already is not stored again
+	if jobs, err := jd.claimHandoffTokensInTx(ctx, tx, []*JobT{job}); err != nil || len(jobs) == 0 {
+		return err
+	}
+	if err = jd.prepareStoreInTx(ctx, tx, ds, []*JobT{job}); err != nil {
+		return err
+	}
 	sqlStatement := fmt.Sprintf(`INSERT INTO %q (uuid, user_id, custom_val, parameters, event_payload, workspace_id)
 		VALUES ($1, $2, $3, $4, $5, $6) RETURNING job_id`, ds.JobTable)
 	stmt, err := tx.PrepareContext(ctx, sqlStatement)
@@ -2014,7 +2251,11 @@ func (jd *HandleT) storeJob(ctx context.Context, tx *Tx, ds dataSetT, job *JobT)
 	}
 	defer func() { _ = stmt.Close() }()
 	job.sanitizeJson()
-	_, err = stmt.ExecContext(ctx, job.UUID, job.UserID, job.CustomVal, string(job.Parameters), string(job.EventPayload), job.WorkspaceId)
+	payloads, err := jd.payloadValues(ctx, tx, ds, []*JobT{job})
+	if err != nil {
+		return err
+	}
+	_, err = stmt.ExecContext(ctx, job.UUID, job.UserID, job.CustomVal, string(job.Parameters), payloads[0], job.WorkspaceId)
 	if err == nil {
 		tx.AddSuccessListener(func() {
 			// Empty customValFilters means we want to clear for all
@@ -2260,7 +2501,13 @@ func (jd *HandleT) getProcessedJobsDS(ctx context.Context, ds dataSetT, params G
 	start := time.Now()
 	defer jd.getTimerStat("processed_ds_time", &tags).Since(start)
 
-	skipCacheResult := params.AfterJobID != nil
+	backend, err := jd.dsBackend(ctx, jd.dbHandle, ds)
+	if err != nil {
+		return JobsResult{}, false, err
+	}
+
+	// no jobs for a subset of the workspaces or parameters doesn't mean there are no jobs at all
+	skipCacheResult := params.AfterJobID != nil || len(params.ExcludeWorkspaceIDs) > 0 || len(params.ExcludeParameterFilters) > 0
 	if !skipCacheResult {
 		// We don't reset this in case of error for now, as any error in this function causes panic
 		jd.markClearEmptyResult(ds, allWorkspaces, stateFilters, customValFilters, parameterFilters, willTryToSet, nil)
@@ -2284,6 +2531,14 @@ func (jd *HandleT) getProcessedJobsDS(ctx context.Context, ds dataSetT, params G
 		filterConditions = append(filterConditions, constructParameterJSONQuery("jobs", parameterFilters))
 	}
 
+	if len(params.ExcludeWorkspaceIDs) > 0 {
+		filterConditions = append(filterConditions, "NOT "+constructQueryOR("jobs.workspace_id", params.ExcludeWorkspaceIDs))
+	}
+
+	for _, parameterFilter := range params.ExcludeParameterFilters {
+		filterConditions = append(filterConditions, "NOT "+constructParameterJSONQuery("jobs", []ParameterFilterT{parameterFilter}))
+	}
+
 	filterQuery := strings.Join(filterConditions, " AND ")
 	if filterQuery != "" {
 		filterQuery = " AND " + filterQuery
@@ -2305,12 +2560,12 @@ func (jd *HandleT) getProcessedJobsDS(ctx context.Context, ds dataSetT, params G
 									job_latest_state.exec_time, job_latest_state.retry_time,
 									job_latest_state.error_code, job_latest_state.error_response, job_latest_state.parameters
 								FROM
-									%[1]q AS jobs
+									%[1]s AS jobs
 									JOIN "v_last_%[2]s" job_latest_state ON jobs.job_id=job_latest_state.job_id
 								    %[3]s
 									%[4]s
 									AND job_latest_state.retry_time < $1 ORDER BY jobs.job_id %[5]s`,
-		ds.JobTable, ds.JobStatusTable, stateQuery, filterQuery, limitQuery)
+		backend.jobsRelation(ds), ds.JobStatusTable, stateQuery, filterQuery, limitQuery)
 
 	args := []interface{}{getTimeNowFunc()}
 
@@ -2364,6 +2619,9 @@ func (jd *HandleT) getProcessedJobsDS(ctx context.Context, ds dataSetT, params G
 		if err != nil {
 			return JobsResult{}, false, err
 		}
+		if job.EventPayload, err = decompressPayload(job.EventPayload); err != nil {
+			return JobsResult{}, false, err
+		}
 
 		if params.EventsLimit > 0 && runningEventCount > params.EventsLimit && len(jobList) > 0 {
 			// events limit overflow is triggered as long as we have read at least one job
@@ -2425,14 +2683,19 @@ func (jd *HandleT) getUnprocessedJobsDS(ctx context.Context, ds dataSetT, params
 	start := time.Now()
 	defer jd.getTimerStat("unprocessed_ds_time", &tags).Since(start)
 
-	skipCacheResult := params.AfterJobID != nil
+	backend, err := jd.dsBackend(ctx, jd.dbHandle, ds)
+	if err != nil {
+		return JobsResult{}, false, err
+	}
+
+	// no jobs for a subset of the workspaces or parameters doesn't mean there are no jobs at all
+	skipCacheResult := params.AfterJobID != nil || len(params.ExcludeWorkspaceIDs) > 0 || len(params.ExcludeParameterFilters) > 0
 	if !skipCacheResult {
 		// We don't reset this in case of error for now, as any error in this function causes panic
 		jd.markClearEmptyResult(ds, allWorkspaces, []string{NotProcessed.State}, customValFilters, parameterFilters, willTryToSet, nil)
 	}
 
 	var rows *sql.Rows
-	var err error
 	var args []interface{}
 
 	// event_count default 1, number of items in payload
@@ -2441,10 +2704,10 @@ func (jd *HandleT) getUnprocessedJobsDS(ctx context.Context, ds dataSetT, params
 			`	pg_column_size(jobs.event_payload) as payload_size, `+
 			`	sum(jobs.event_count) over (order by jobs.job_id asc) as running_event_counts, `+
 			`	sum(pg_column_size(jobs.event_payload)) over (order by jobs.job_id) as running_payload_size `+
-			`FROM %[1]q AS jobs `+
+			`FROM %[1]s AS jobs `+
 			`LEFT JOIN %[2]q job_status ON jobs.job_id=job_status.job_id `+
 			`WHERE job_status.job_id is NULL `,
-		ds.JobTable, ds.JobStatusTable)
+		backend.jobsRelation(ds), ds.JobStatusTable)
 
 	if params.AfterJobID != nil {
 		sqlStatement += fmt.Sprintf(" AND jobs.job_id > %d", *params.AfterJobID)
@@ -2455,6 +2718,12 @@ func (jd *HandleT) getUnprocessedJobsDS(ctx context.Context, ds dataSetT, params
 	if len(parameterFilters) > 0 {
 		sqlStatement += " AND " + constructParameterJSONQuery("jobs", parameterFilters)
 	}
+	if len(params.ExcludeWorkspaceIDs) > 0 {
+		sqlStatement += " AND NOT " + constructQueryOR("jobs.workspace_id", params.ExcludeWorkspaceIDs)
+	}
+	for _, parameterFilter := range params.ExcludeParameterFilters {
+		sqlStatement += " AND NOT " + constructParameterJSONQuery("jobs", []ParameterFilterT{parameterFilter})
+	}
 	sqlStatement += " ORDER BY jobs.job_id"
 	if params.JobsLimit > 0 {
 		sqlStatement += fmt.Sprintf(" LIMIT $%d", len(args)+1)
@@ -2504,6 +2773,9 @@ func (jd *HandleT) getUnprocessedJobsDS(ctx context.Context, ds dataSetT, params
 		if err != nil {
 			return JobsResult{}, false, err
 		}
+		if job.EventPayload, err = decompressPayload(job.EventPayload); err != nil {
+			return JobsResult{}, false, err
+		}
 		if params.EventsLimit > 0 && runningEventCount > params.EventsLimit && len(jobList) > 0 {
 			// events limit overflow is triggered as long as we have read at least one job
 			limitsReached = true
@@ -2591,11 +2863,6 @@ func (jd *HandleT) updateJobStatusDSInTx(ctx context.Context, tx *Tx, ds dataSet
 	defer queryStat.End()
 	updatedStatesMap := map[string]map[string]bool{}
 	store := func() error {
-		stmt, err := tx.PrepareContext(ctx, pq.CopyIn(ds.JobStatusTable, "job_id", "job_state", "attempt", "exec_time",
-			"retry_time", "error_code", "error_response", "parameters"))
-		if err != nil {
-			return err
-		}
 		for _, status := range statusList {
 			//  Handle the case when google analytics returns gif in response
 			if _, ok := updatedStatesMap[status.WorkspaceId]; !ok {
@@ -2605,11 +2872,6 @@ func (jd *HandleT) updateJobStatusDSInTx(ctx context.Context, tx *Tx, ds dataSet
 			if !utf8.ValidString(string(status.ErrorResponse)) {
 				status.ErrorResponse = []byte(`{}`)
 			}
-			_, err = stmt.ExecContext(ctx, status.JobID, status.JobState, status.AttemptNum, status.ExecTime,
-				status.RetryTime, status.ErrorCode, string(status.ErrorResponse), string(status.Parameters))
-			if err != nil {
-				return err
-			}
 		}
 		updatedStates = make(map[string][]string)
 		for k := range updatedStatesMap {
@@ -2621,7 +2883,14 @@ func (jd *HandleT) updateJobStatusDSInTx(ctx context.Context, tx *Tx, ds dataSet
 			}
 		}
 
-		if _, err = stmt.ExecContext(ctx); err != nil {
+		// small lists of statuses are cheaper to write with a single statement than with COPY
+		var err error
+		if len(statusList) <= jd.statusCopyThreshold {
+			err = jd.insertStatusesInTx(ctx, tx, ds, statusList)
+		} else {
+			err = jd.copyStatusesInTx(ctx, tx, ds, statusList)
+		}
+		if err != nil {
 			return err
 		}
 
@@ -2651,6 +2920,11 @@ func (jd *HandleT) updateJobStatusDSInTx(ctx context.Context, tx *Tx, ds dataSet
 			err = store()
 		}
 	}
+	if err == nil && jd.statusTail.active() {
+		tx.AddSuccessListener(func() {
+			jd.statusTail.publish(ds, statusList)
+		})
+	}
 	return
 }
 
@@ -3002,6 +3276,9 @@ func (jd *HandleT) recoverFromJournal(owner OwnerType) {
 }
 
 func (jd *HandleT) UpdateJobStatus(ctx context.Context, statusList []*JobStatusT, customValFilters []string, parameterFilters []ParameterFilterT) error {
+	if jd.statusBatchLatency > 0 && jd.statusBatcher != nil {
+		return jd.batchUpdateJobStatus(ctx, statusList, customValFilters, parameterFilters)
+	}
 	return jd.WithUpdateSafeTx(ctx, func(tx UpdateSafeTx) error {
 		return jd.UpdateJobStatusInTx(ctx, tx, statusList, customValFilters, parameterFilters)
 	})
@@ -3453,7 +3730,7 @@ func (jd *HandleT) GetWaiting(ctx context.Context, params GetQueryParamsT) (Jobs
 	if params.JobsLimit == 0 {
 		return JobsResult{}, nil
 	}
-	params.StateFilters = []string{Waiting.State}
+	params.StateFilters = []string{Waiting.State, Paused.State}
 	tags := statTags{CustomValFilters: params.CustomValFilters, StateFilters: params.StateFilters, ParameterFilters: params.ParameterFilters}
 	command := func() interface{} {
 		return queryResultWrapper(jd.getWaiting(ctx, params))
@@ -3463,7 +3740,7 @@ func (jd *HandleT) GetWaiting(ctx context.Context, params GetQueryParamsT) (Jobs
 }
 
 /*
-GetWaiting returns events which are under processing
+GetWaiting returns events which are under processing, waiting or paused
 This is a wrapper over GetProcessed call above
 */
 func (jd *HandleT) getWaiting(ctx context.Context, params GetQueryParamsT) (JobsResult, error) { // skipcq: CRT-P0003
@@ -3519,6 +3796,8 @@ func (jd *HandleT) GetLastJob() *JobT {
 	if err != nil && err != sql.ErrNoRows {
 		jd.assertError(err)
 	}
+	job.EventPayload, err = decompressPayload(job.EventPayload)
+	jd.assertError(err)
 	return &job
 }
 
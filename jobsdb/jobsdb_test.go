@@ -395,7 +395,7 @@ func TestRefreshDSList(t *testing.T) {
 
 	require.Equal(t, 1, len(jobsDB.getDSList()), "jobsDB should start with a ds list size of 1")
 	require.NoError(t, jobsDB.WithTx(func(tx *Tx) error {
-		return jobsDB.addDSInTx(tx, newDataSet(prefix, "2"))
+		return jobsDB.addDSInTx(tx, newDataSet(prefix, "2"), false, postgresBackend{})
 	}))
 	require.Equal(t, 1, len(jobsDB.getDSList()), "addDS should not refresh the ds list")
 	jobsDB.dsListLock.WithLock(func(l lock.LockToken) {
@@ -1029,6 +1029,41 @@ func TestAfterJobIDQueryParam(t *testing.T) {
 	})
 }
 
+func TestExcludeParameterFiltersQueryParam(t *testing.T) {
+	_ = startPostgres(t)
+	customVal := "CUSTOMVAL"
+	prefix := strings.ToLower(rsRand.String(5))
+	jobsDB := NewForReadWrite(prefix)
+	require.NoError(t, jobsDB.Start())
+	defer jobsDB.TearDown()
+
+	var jobs []*JobT
+	for _, sourceID := range []string{"source-a", "source-a", "source-b", "source-c"} {
+		jobs = append(jobs, &JobT{
+			Parameters:   []byte(fmt.Sprintf(`{"source_id":%q}`, sourceID)),
+			EventPayload: []byte(`{"testKey":"testValue"}`),
+			UserID:       "a-292e-4e79-9880-f8009e0ae4a3",
+			UUID:         uuid.New(),
+			CustomVal:    customVal,
+			EventCount:   1,
+		})
+	}
+	require.NoError(t, jobsDB.Store(context.Background(), jobs))
+
+	unprocessed, err := jobsDB.GetUnprocessed(context.Background(), GetQueryParamsT{
+		CustomValFilters:        []string{customVal},
+		ExcludeParameterFilters: []ParameterFilterT{{Name: "source_id", Value: "source-a"}, {Name: "source_id", Value: "source-c"}},
+		JobsLimit:               100,
+	})
+	require.NoError(t, err)
+	require.Len(t, unprocessed.Jobs, 1)
+	require.JSONEq(t, `{"source_id":"source-b"}`, string(unprocessed.Jobs[0].Parameters))
+
+	unprocessed, err = jobsDB.GetUnprocessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: 100})
+	require.NoError(t, err)
+	require.Len(t, unprocessed.Jobs, 4, "excluding jobs shouldn't mark the other ones as missing in the cache")
+}
+
 func TestDeleteExecuting(t *testing.T) {
 	_ = startPostgres(t)
 	customVal := "CUSTOMVAL"
@@ -87,10 +87,12 @@ func mustGetAllTableNames(jd assertInterface, dbHandle sqlDbOrTx) []string {
 // getAllTableNames gets all table names from Postgres
 func getAllTableNames(dbHandle sqlDbOrTx) ([]string, error) {
 	var tableNames []string
+	// partitions of jobs tables partitioned by workspace are left out, being part of their dataset
 	rows, err := dbHandle.Query(`SELECT tablename
-									FROM pg_catalog.pg_tables
+									FROM pg_catalog.pg_tables t
 									WHERE schemaname != 'pg_catalog' AND
-									schemaname != 'information_schema'`)
+									schemaname != 'information_schema' AND
+									NOT EXISTS (SELECT 1 FROM pg_catalog.pg_class c WHERE c.relname = t.tablename AND c.relnamespace = t.schemaname::regnamespace AND c.relispartition)`)
 	if err != nil {
 		return tableNames, err
 	}
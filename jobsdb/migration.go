@@ -30,16 +30,30 @@ func (jd *HandleT) startMigrateDSLoop(ctx context.Context) {
 
 func (jd *HandleT) migrateDSLoop(ctx context.Context) {
 	for {
+		var forced bool
 		select {
 		case <-jd.TriggerMigrateDS():
+		case <-jd.compaction.trigger:
+			forced = true
 		case <-ctx.Done():
 			return
 		}
+		if !forced {
+			reason, err := jd.compactionDeferReason(ctx)
+			if err != nil {
+				jd.logger.Errorf("Failed to check whether to defer compaction: %v", err)
+			} else if reason != "" {
+				jd.compactionDeferred(reason)
+				continue
+			}
+		}
 		start := time.Now()
 		jd.logger.Debugw("Start", "operation", "migrateDSLoop")
+		jd.compactionStarted(forced)
 		timeoutCtx, cancel := context.WithTimeout(ctx, jd.migrateDSTimeout)
 		err := jd.doMigrateDS(timeoutCtx)
 		cancel()
+		jd.compactionFinished(err)
 		if err != nil {
 			jd.logger.Errorf("Failed to migrate ds: %v", err)
 		}
@@ -66,6 +80,7 @@ func (jd *HandleT) doMigrateDS(ctx context.Context) error {
 	}
 	var l lock.LockToken
 	var lockChan chan<- lock.LockToken
+	var jobsMigrated int
 	err := jd.WithTx(func(tx *Tx) error {
 		return jd.withDistributedSharedLock(ctx, tx, "schema_migrate", func() error { // cannot run while schema migration is running
 			// Take the lock and run actual migration
@@ -102,7 +117,16 @@ func (jd *HandleT) doMigrateDS(ctx context.Context) error {
 					return err
 				}
 
-				err = jd.addDSInTx(tx, destination)
+				// compressed payloads can only be migrated to a dataset with compressed payloads, even if compression got disabled since
+				compressed := jd.payloadCompression
+				for _, source := range migrateFrom {
+					sourceCompressed, err := jd.isCompressedDS(ctx, tx, source)
+					if err != nil {
+						return err
+					}
+					compressed = compressed || sourceCompressed
+				}
+				err = jd.addDSInTx(tx, destination, compressed, jd.newDSBackend())
 				if err != nil {
 					return err
 				}
@@ -122,6 +146,7 @@ func (jd *HandleT) doMigrateDS(ctx context.Context) error {
 					return err
 				}
 				jd.logger.Infof("[[ migrateDSLoop ]]: Total migrated %d jobs", totalJobsMigrated)
+				jobsMigrated = totalJobsMigrated
 			}
 
 			opPayload, err := json.Marshal(&journalOpPayloadT{From: migrateFrom})
@@ -141,6 +166,10 @@ func (jd *HandleT) doMigrateDS(ctx context.Context) error {
 			if err != nil {
 				return err
 			}
+			migratedFrom := migrateFrom
+			tx.AddSuccessListener(func() {
+				jd.compactionMigrated(len(migratedFrom), jobsMigrated)
+			})
 			return jd.journalMarkDoneInTx(tx, opID)
 		})
 	})
@@ -213,7 +242,7 @@ func (jd *HandleT) cleanupStatusTables(ctx context.Context, dsList []dataSetT) e
 		stats.Tags{"customVal": jd.tablePrefix},
 	).Since(start)
 
-	return jd.WithTx(func(tx *Tx) error {
+	err = jd.WithTx(func(tx *Tx) error {
 		for _, statusTable := range toCompact {
 			if err := jd.cleanStatusTable(
 				ctx,
@@ -225,6 +254,10 @@ func (jd *HandleT) cleanupStatusTables(ctx context.Context, dsList []dataSetT) e
 		}
 		return nil
 	})
+	if err != nil || !jd.compactionVacuum {
+		return err
+	}
+	return jd.vacuumStatusTables(ctx, toCompact)
 }
 
 // cleanStatusTable deletes all rows except for the latest status for each job
@@ -311,12 +344,23 @@ func (jd *HandleT) migrateJobsInTx(ctx context.Context, tx *Tx, srcDS, destDS da
 	queryStat.Start()
 	defer queryStat.End()
 
+	payloadColumn, err := jd.migratedPayloadColumn(ctx, tx, srcDS, destDS, "j")
+	if err != nil {
+		return 0, err
+	}
+	backend, err := jd.dsBackend(ctx, tx, destDS)
+	if err != nil {
+		return 0, err
+	}
+	if err := backend.prepareMigrationInTx(ctx, tx, srcDS, destDS); err != nil {
+		return 0, err
+	}
 	compactDSQuery := fmt.Sprintf(
 		`with last_status as (select * from "v_last_%[1]s"),
 		inserted_jobs as
 		(
 			insert into %[3]q (job_id,   workspace_id,   uuid,   user_id,   custom_val,   parameters,   event_payload,   event_count,   created_at,   expire_at) 
-			           (select j.job_id, j.workspace_id, j.uuid, j.user_id, j.custom_val, j.parameters, j.event_payload, j.event_count, j.created_at, j.expire_at from %[2]q j left join last_status js on js.job_id = j.job_id
+			           (select j.job_id, j.workspace_id, j.uuid, j.user_id, j.custom_val, j.parameters, %[6]s, j.event_count, j.created_at, j.expire_at from %[2]q j left join last_status js on js.job_id = j.job_id
 				where js.job_id is null or js.job_state = ANY('{%[5]s}') order by j.job_id) returning job_id
 		),
 		insertedStatuses as 
@@ -330,6 +374,7 @@ func (jd *HandleT) migrateJobsInTx(ctx context.Context, tx *Tx, srcDS, destDS da
 		destDS.JobTable,
 		destDS.JobStatusTable,
 		strings.Join(validNonTerminalStates, ","),
+		payloadColumn,
 	)
 
 	var numJobsMigrated int64
@@ -0,0 +1,295 @@
+package jobsdb
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// Datasets stored with the experimental postgres_partitioned backend, which partitioning by workspace selects, have
+// their jobs table partitioned by workspace_id, with a partition per workspace, so that scans filtering by workspace only
+// go through the jobs of the workspace and all the jobs of a workspace can be dropped along with their partitions. A
+// workspace's partition is created along with the first jobs of the workspace stored in the dataset. Job status tables
+// of partitioned datasets don't reference their jobs table.
+
+var partitionNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+// handles of the process by table prefix, for partitions dropped through one of them to be forgotten by all of them,
+// whatever their owner type
+var (
+	prefixHandlesLock sync.Mutex
+	prefixHandles     = map[string]map[*HandleT]struct{}{}
+)
+
+func registerPrefixHandle(jd *HandleT) {
+	prefixHandlesLock.Lock()
+	defer prefixHandlesLock.Unlock()
+	if prefixHandles[jd.tablePrefix] == nil {
+		prefixHandles[jd.tablePrefix] = make(map[*HandleT]struct{})
+	}
+	prefixHandles[jd.tablePrefix][jd] = struct{}{}
+}
+
+func unregisterPrefixHandle(jd *HandleT) {
+	prefixHandlesLock.Lock()
+	defer prefixHandlesLock.Unlock()
+	delete(prefixHandles[jd.tablePrefix], jd)
+}
+
+// forgetWorkspacePartitions removes the partitions of the workspaces in a dataset from the cache of the handle and of
+// all the other handles of its table prefix, for them to be created again along with the next jobs of the workspaces
+func (jd *HandleT) forgetWorkspacePartitions(ds dataSetT, workspaceIDs []string) {
+	prefixHandlesLock.Lock()
+	handles := []*HandleT{jd}
+	for handle := range prefixHandles[jd.tablePrefix] {
+		if handle != jd {
+			handles = append(handles, handle)
+		}
+	}
+	prefixHandlesLock.Unlock()
+
+	for _, handle := range handles {
+		handle.partitionsCacheLock.Lock()
+		for _, workspaceID := range workspaceIDs {
+			delete(handle.partitionsCache[ds.JobTable], workspaceID)
+		}
+		handle.partitionsCacheLock.Unlock()
+	}
+}
+
+// isMissingPartitionError tells whether storing jobs failed because the partition of their workspace doesn't exist,
+// e.g. when it got dropped by another process purging the workspace
+func isMissingPartitionError(e *pq.Error) bool {
+	return e.Code == pgErrorCodeCheckViolation && strings.Contains(e.Message, "no partition of relation")
+}
+
+// workspacePartition returns the name of the partition of a dataset's jobs table holding the jobs of a workspace
+func workspacePartition(ds dataSetT, workspaceID string) string {
+	if name := ds.JobTable + "_" + workspaceID; partitionNameRegexp.MatchString(workspaceID) && len(name) <= 63 {
+		return name
+	}
+	// workspace ids that are not valid or are too long for table names
+	sum := md5.Sum([]byte(workspaceID))
+	return ds.JobTable + "_" + hex.EncodeToString(sum[:8])
+}
+
+// isPartitionedDS tells whether a dataset's jobs table is partitioned by workspace
+func (jd *HandleT) isPartitionedDS(ctx context.Context, q rowQuerier, ds dataSetT) (bool, error) {
+	jd.partitionsCacheLock.RLock()
+	partitions, ok := jd.partitionsCache[ds.JobTable]
+	jd.partitionsCacheLock.RUnlock()
+	if ok {
+		return partitions != nil, nil
+	}
+
+	var partitioned bool
+	err := q.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_partitioned_table pt JOIN pg_catalog.pg_class c ON c.oid = pt.partrelid
+			WHERE c.relname = $1 AND c.relnamespace = current_schema()::regnamespace)`,
+		ds.JobTable,
+	).Scan(&partitioned)
+	if err != nil {
+		return false, fmt.Errorf("getting whether %q is partitioned: %w", ds.JobTable, err)
+	}
+	jd.setPartitionedDS(ds, partitioned)
+	return partitioned, nil
+}
+
+func (jd *HandleT) setPartitionedDS(ds dataSetT, partitioned bool) {
+	jd.partitionsCacheLock.Lock()
+	defer jd.partitionsCacheLock.Unlock()
+	if jd.partitionsCache == nil {
+		jd.partitionsCache = make(map[string]map[string]struct{})
+	}
+	jd.partitionsCache[ds.JobTable] = nil
+	if partitioned {
+		jd.partitionsCache[ds.JobTable] = make(map[string]struct{})
+	}
+}
+
+// partitionedBackend stores each dataset in a jobs table partitioned by workspace and a job status table
+type partitionedBackend struct {
+	jd *HandleT
+}
+
+func (*partitionedBackend) name() string { return partitionedBackendName }
+
+func (*partitionedBackend) partitioned() bool { return true }
+
+// partitioned tables need their partition key in their primary key
+func (*partitionedBackend) jobsTableLayout(dataSetT) (primaryKey, partitionBy, jobIDReference string) {
+	return "PRIMARY KEY (job_id, workspace_id)", " PARTITION BY LIST (workspace_id)", ""
+}
+
+// prepareStoreInTx creates the partitions of the workspaces missing one
+func (b *partitionedBackend) prepareStoreInTx(ctx context.Context, tx *Tx, ds dataSetT, workspaceIDs []string) error {
+	jd := b.jd
+	var missing []string
+	jd.partitionsCacheLock.RLock()
+	for _, workspaceID := range workspaceIDs {
+		if _, ok := jd.partitionsCache[ds.JobTable][workspaceID]; !ok {
+			missing = append(missing, workspaceID)
+		}
+	}
+	jd.partitionsCacheLock.RUnlock()
+	if len(missing) == 0 {
+		return nil
+	}
+
+	// concurrent transactions creating the same partition would otherwise fail
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, jd.getAdvisoryLockForOperation("partition_"+ds.JobTable)); err != nil {
+		return err
+	}
+	sort.Strings(missing)
+	for _, workspaceID := range missing {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %q PARTITION OF %q FOR VALUES IN (%s)`,
+			workspacePartition(ds, workspaceID), ds.JobTable, pq.QuoteLiteral(workspaceID)),
+		); err != nil {
+			return fmt.Errorf("creating partition of workspace %q in %q: %w", workspaceID, ds.JobTable, err)
+		}
+	}
+	tx.AddSuccessListener(func() {
+		jd.partitionsCacheLock.Lock()
+		defer jd.partitionsCacheLock.Unlock()
+		if partitions := jd.partitionsCache[ds.JobTable]; partitions != nil {
+			for _, workspaceID := range missing {
+				partitions[workspaceID] = struct{}{}
+			}
+		}
+	})
+	return nil
+}
+
+// loadDS loads the workspaces with a partition in the dataset, for their partitions not to be created again
+func (b *partitionedBackend) loadDS(ctx context.Context, q querier, ds dataSetT) error {
+	rows, err := q.QueryContext(ctx,
+		`SELECT c.relname FROM pg_catalog.pg_inherits i
+			JOIN pg_catalog.pg_class c ON c.oid = i.inhrelid
+			JOIN pg_catalog.pg_class p ON p.oid = i.inhparent
+			WHERE p.relname = $1 AND p.relnamespace = current_schema()::regnamespace`,
+		ds.JobTable,
+	)
+	if err != nil {
+		return err
+	}
+	var partitions []string
+	for rows.Next() {
+		var partition string
+		if err := rows.Scan(&partition); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		partitions = append(partitions, partition)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// partitions named after workspaces whose ids aren't valid table names can't be mapped back to them, the workspace
+	// of a partition with jobs is the one of any of its jobs
+	var workspaceIDs []string
+	for _, partition := range partitions {
+		var workspaceID string
+		err := q.QueryRowContext(ctx, fmt.Sprintf(`SELECT workspace_id FROM %q LIMIT 1`, partition)).Scan(&workspaceID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		workspaceIDs = append(workspaceIDs, workspaceID)
+	}
+
+	b.jd.partitionsCacheLock.Lock()
+	defer b.jd.partitionsCacheLock.Unlock()
+	if partitions := b.jd.partitionsCache[ds.JobTable]; partitions != nil {
+		for _, workspaceID := range workspaceIDs {
+			partitions[workspaceID] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// jobsRelation is the partitioned jobs table, whose partitions are pruned by the filters on workspace_id
+func (*partitionedBackend) jobsRelation(ds dataSetT) string {
+	return fmt.Sprintf("%q", ds.JobTable)
+}
+
+func (b *partitionedBackend) prepareMigrationInTx(ctx context.Context, tx *Tx, srcDS, destDS dataSetT) error {
+	workspaceIDs, err := dsWorkspaces(ctx, tx, srcDS)
+	if err != nil {
+		return err
+	}
+	return b.prepareStoreInTx(ctx, tx, destDS, workspaceIDs)
+}
+
+// jobsWorkspaces returns the distinct workspaces of jobs
+func jobsWorkspaces(jobs []*JobT) []string {
+	seen := make(map[string]struct{})
+	var workspaceIDs []string
+	for _, job := range jobs {
+		if _, ok := seen[job.WorkspaceId]; !ok {
+			seen[job.WorkspaceId] = struct{}{}
+			workspaceIDs = append(workspaceIDs, job.WorkspaceId)
+		}
+	}
+	return workspaceIDs
+}
+
+// dsWorkspaces returns the distinct workspaces of the jobs of a dataset
+func dsWorkspaces(ctx context.Context, tx *Tx, ds dataSetT) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT DISTINCT workspace_id FROM %q`, ds.JobTable))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var workspaceIDs []string
+	for rows.Next() {
+		var workspaceID string
+		if err := rows.Scan(&workspaceID); err != nil {
+			return nil, err
+		}
+		workspaceIDs = append(workspaceIDs, workspaceID)
+	}
+	return workspaceIDs, rows.Err()
+}
+
+// dropWorkspaceJobsInTx drops the partition of the workspace
+func (b *partitionedBackend) dropWorkspaceJobsInTx(ctx context.Context, tx *Tx, ds dataSetT, workspaceID string) (jobs, statuses int64, err error) {
+	jd := b.jd
+	partition := workspacePartition(ds, workspaceID)
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, fmt.Sprintf("%q", partition)).Scan(&exists); err != nil {
+		return 0, 0, err
+	}
+	if !exists {
+		return 0, 0, nil
+	}
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %q`, partition)).Scan(&jobs); err != nil {
+		return 0, 0, err
+	}
+	result, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %q WHERE job_id IN (SELECT job_id FROM %q)`, ds.JobStatusTable, partition))
+	if err != nil {
+		return 0, 0, err
+	}
+	if statuses, err = result.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE %q`, partition)); err != nil {
+		return 0, 0, err
+	}
+	tx.AddSuccessListener(func() {
+		jd.forgetWorkspacePartitions(ds, []string{workspaceID})
+	})
+	return jobs, statuses, nil
+}
@@ -0,0 +1,172 @@
+package jobsdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestWorkspacePartition(t *testing.T) {
+	ds := newDataSet("gw", "1_1")
+	require.Equal(t, "gw_jobs_1_1_2KyXmzMGJvzpA4J7wZ0DUOGnuIq", workspacePartition(ds, "2KyXmzMGJvzpA4J7wZ0DUOGnuIq"))
+
+	for _, workspaceID := range []string{"", "workspace-id", `"; DROP TABLE gw_jobs_1_1; --`, strings.Repeat("a", 64)} {
+		partition := workspacePartition(ds, workspaceID)
+		require.True(t, strings.HasPrefix(partition, "gw_jobs_1_1_"), partition)
+		require.Len(t, partition, len("gw_jobs_1_1_")+16, partition)
+		require.Equal(t, partition, workspacePartition(ds, workspaceID), "partition names are stable")
+	}
+	require.NotEqual(t, workspacePartition(ds, ""), workspacePartition(ds, "workspace-id"))
+}
+
+func TestForgetWorkspacePartitions(t *testing.T) {
+	ds := newDataSet("forget", "1")
+	reader, writer, other := &HandleT{tablePrefix: "forget"}, &HandleT{tablePrefix: "forget"}, &HandleT{tablePrefix: "other"}
+	for _, jd := range []*HandleT{reader, writer, other} {
+		registerPrefixHandle(jd)
+		defer unregisterPrefixHandle(jd)
+		jd.setPartitionedDS(ds, true)
+		jd.partitionsCache[ds.JobTable]["a"] = struct{}{}
+		jd.partitionsCache[ds.JobTable]["b"] = struct{}{}
+	}
+
+	reader.forgetWorkspacePartitions(ds, []string{"a"})
+	for _, jd := range []*HandleT{reader, writer} {
+		require.Equal(t, map[string]struct{}{"b": {}}, jd.partitionsCache[ds.JobTable], "all handles of the prefix forget the partition")
+	}
+	require.Len(t, other.partitionsCache[ds.JobTable], 2, "handles of other prefixes keep their partitions")
+}
+
+func TestPartitionedDatasets(t *testing.T) {
+	maxDSSize := 1
+	_ = startPostgres(t)
+
+	triggerAddNewDS := make(chan time.Time)
+	triggerMigrateDS := make(chan time.Time)
+
+	jobDB := HandleT{
+		TriggerAddNewDS: func() <-chan time.Time {
+			return triggerAddNewDS
+		},
+		TriggerMigrateDS: func() <-chan time.Time {
+			return triggerMigrateDS
+		},
+		MaxDSSize: &maxDSSize,
+	}
+	tablePrefix := strings.ToLower(rand.String(5))
+	err := jobDB.Setup(
+		ReadWrite,
+		true,
+		tablePrefix,
+		true,
+		[]prebackup.Handler{},
+		fileuploader.NewDefaultProvider(),
+	)
+	require.NoError(t, err)
+	defer jobDB.TearDown()
+
+	jobDB.MaxDSRetentionPeriod = time.Millisecond
+	jobDB.partitionByWorkspace = true
+
+	customVal := rand.String(5)
+	jobs := append(genJobs("ws-1", customVal, 20, 1), genJobs("ws-2", customVal, 20, 1)...)
+	requireJobs := func(workspaceID string, expected int) {
+		t.Helper()
+		result, err := jobDB.GetUnprocessed(context.Background(), GetQueryParamsT{
+			CustomValFilters: []string{customVal},
+			JobsLimit:        100,
+		})
+		require.NoError(t, err)
+		var count int
+		for _, job := range result.Jobs {
+			if job.WorkspaceId == workspaceID {
+				count++
+			}
+		}
+		require.Equal(t, expected, count, workspaceID)
+	}
+	requirePartitions := func(ds dataSetT, expected int) {
+		t.Helper()
+		var partitions int
+		require.NoError(t, jobDB.dbHandle.QueryRow(
+			`SELECT COUNT(*) FROM pg_inherits WHERE inhparent = $1::regclass`, ds.JobTable,
+		).Scan(&partitions))
+		require.Equal(t, expected, partitions, ds.JobTable)
+	}
+
+	// the first dataset was created before enabling partitioning
+	require.NoError(t, jobDB.Store(context.Background(), append(append([]*JobT{}, jobs[:10]...), jobs[20:30]...)))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[:9], "executing"), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[:9], "succeeded"), []string{customVal}, []ParameterFilterT{}))
+	require.Len(t, jobDB.getDSList(), 1)
+	requirePartitions(jobDB.getDSList()[0], 0)
+
+	triggerAddNewDS <- time.Now() // trigger addNewDSLoop to run
+	triggerAddNewDS <- time.Now() // Second time, waits for the first loop to finish
+	require.EqualValues(t, 2, jobDB.GetMaxDSIndex())
+	require.NoError(t, jobDB.Store(context.Background(), append(append([]*JobT{}, jobs[10:20]...), jobs[30:40]...)))
+	require.Len(t, jobDB.getDSList(), 2, "partitions are not datasets")
+	requirePartitions(jobDB.getDSList()[1], 2)
+	requireJobs("ws-1", 11)
+	requireJobs("ws-2", 20)
+
+	loader := &HandleT{logger: logger.NOP, dbHandle: jobDB.dbHandle}
+	backend, err := loader.dsBackend(context.Background(), jobDB.dbHandle, jobDB.getDSList()[1])
+	require.NoError(t, err)
+	require.Equal(t, partitionedBackendName, backend.name())
+	require.Equal(t, map[string]struct{}{"ws-1": {}, "ws-2": {}}, loader.partitionsCache[jobDB.getDSList()[1].JobTable], "partitions of existing datasets are loaded")
+
+	triggerAddNewDS <- time.Now()
+	triggerAddNewDS <- time.Now()
+	require.EqualValues(t, 3, jobDB.GetMaxDSIndex())
+
+	_, err = jobDB.dbHandle.Exec(fmt.Sprintf(`ANALYZE %[1]s_jobs_1, %[1]s_jobs_2, %[1]s_job_status_1, %[1]s_job_status_2`, tablePrefix))
+	require.NoError(t, err)
+	triggerMigrateDS <- time.Now() // trigger migrateDSLoop to run
+	triggerMigrateDS <- time.Now() // waits for last loop to finish
+
+	// the pending jobs of the first dataset are migrated to a partitioned dataset
+	dsList := jobDB.getDSList()
+	require.Equal(t, `1_1`, dsList[0].Index)
+	requirePartitions(dsList[0], 2)
+	requireJobs("ws-1", 11)
+	requireJobs("ws-2", 20)
+
+	purge, err := jobDB.PurgeWorkspace(context.Background(), "ws-2")
+	require.NoError(t, err)
+	require.EqualValues(t, 20, purge.Jobs)
+	requireJobs("ws-1", 11)
+	requireJobs("ws-2", 0)
+	requirePartitions(jobDB.getDSList()[0], 1)
+}
+
+func TestNewDSBackend(t *testing.T) {
+	jd := &HandleT{logger: logger.NOP, backendName: postgresBackendName}
+	require.Equal(t, postgresBackendName, jd.newDSBackend().name())
+
+	jd.backendName = partitionedBackendName
+	require.Equal(t, partitionedBackendName, jd.newDSBackend().name())
+	require.True(t, jd.newDSBackend().partitioned())
+
+	jd.backendName = "unknown"
+	require.Equal(t, postgresBackendName, jd.newDSBackend().name(), "unknown backends fall back to postgres")
+
+	jd.partitionByWorkspace = true
+	require.Equal(t, partitionedBackendName, jd.newDSBackend().name(), "partitioning by workspace selects the partitioned backend")
+}
+
+func TestRegisterStorageBackend(t *testing.T) {
+	defer delete(storageBackends, "test")
+	require.NoError(t, registerStorageBackend("test", func(*HandleT) storageBackend { return postgresBackend{} }))
+	require.Error(t, registerStorageBackend("test", func(*HandleT) storageBackend { return postgresBackend{} }), "backends are registered once")
+	require.Error(t, registerStorageBackend(postgresBackendName, func(*HandleT) storageBackend { return postgresBackend{} }))
+}
@@ -0,0 +1,111 @@
+package jobsdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// payloadFormatZstd is the format marker of payloads compressed with zstd, prepended to the compressed payload.
+//
+// Datasets created while payload compression is enabled store their event_payload as BYTEA instead of JSONB. Their payloads
+// are compressed, unless they were migrated from datasets without compressed payloads, in which case they are stored as is
+// and start with a JSON token instead of the marker. Payloads are decompressed on read, whatever the format of their dataset.
+const payloadFormatZstd byte = 0x01
+
+var (
+	payloadEncoder, _ = zstd.NewWriter(nil)
+	payloadDecoder, _ = zstd.NewReader(nil)
+)
+
+// compressPayload compresses a payload, prefixed with its format marker
+func compressPayload(payload []byte) []byte {
+	return payloadEncoder.EncodeAll(payload, []byte{payloadFormatZstd})
+}
+
+// decompressPayload decompresses a payload read from a dataset, payloads without a format marker being returned as is
+func decompressPayload(payload []byte) ([]byte, error) {
+	if len(payload) == 0 || payload[0] != payloadFormatZstd {
+		return payload, nil
+	}
+	decompressed, err := payloadDecoder.DecodeAll(payload[1:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing event payload: %w", err)
+	}
+	return decompressed, nil
+}
+
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// isCompressedDS tells whether a dataset stores compressed payloads, i.e. whether its event_payload column is a BYTEA one
+func (jd *HandleT) isCompressedDS(ctx context.Context, q rowQuerier, ds dataSetT) (bool, error) {
+	jd.compressedDSCacheLock.RLock()
+	compressed, ok := jd.compressedDSCache[ds.JobTable]
+	jd.compressedDSCacheLock.RUnlock()
+	if ok {
+		return compressed, nil
+	}
+
+	var dataType string
+	err := q.QueryRowContext(ctx,
+		`SELECT data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 AND column_name = 'event_payload'`,
+		ds.JobTable,
+	).Scan(&dataType)
+	if err != nil {
+		return false, fmt.Errorf("getting the payload format of %q: %w", ds.JobTable, err)
+	}
+	compressed = dataType == "bytea"
+	jd.setCompressedDS(ds, compressed)
+	return compressed, nil
+}
+
+func (jd *HandleT) setCompressedDS(ds dataSetT, compressed bool) {
+	jd.compressedDSCacheLock.Lock()
+	defer jd.compressedDSCacheLock.Unlock()
+	if jd.compressedDSCache == nil {
+		jd.compressedDSCache = make(map[string]bool)
+	}
+	jd.compressedDSCache[ds.JobTable] = compressed
+}
+
+// payloadValues returns the values to store the payloads of jobs with, in the format of the dataset they are stored in
+func (jd *HandleT) payloadValues(ctx context.Context, q rowQuerier, ds dataSetT, jobs []*JobT) ([]interface{}, error) {
+	compressed, err := jd.isCompressedDS(ctx, q, ds)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(jobs))
+	for i, job := range jobs {
+		if compressed {
+			values[i] = compressPayload(job.EventPayload)
+		} else {
+			values[i] = string(job.EventPayload)
+		}
+	}
+	return values, nil
+}
+
+// migratedPayloadColumn returns the expression migrating the payloads of srcDS to destDS,
+// converting JSONB payloads to BYTEA ones when migrating to a dataset with compressed payloads
+func (jd *HandleT) migratedPayloadColumn(ctx context.Context, q rowQuerier, srcDS, destDS dataSetT, alias string) (string, error) {
+	srcCompressed, err := jd.isCompressedDS(ctx, q, srcDS)
+	if err != nil {
+		return "", err
+	}
+	destCompressed, err := jd.isCompressedDS(ctx, q, destDS)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case srcCompressed == destCompressed:
+		return alias + ".event_payload", nil
+	case destCompressed:
+		return fmt.Sprintf(`convert_to(%s.event_payload::text, 'UTF8')`, alias), nil
+	default:
+		return "", fmt.Errorf("cannot migrate compressed payloads of %q to %q", srcDS.JobTable, destDS.JobTable)
+	}
+}
@@ -0,0 +1,141 @@
+package jobsdb
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+func TestPayloadCompression(t *testing.T) {
+	payload := []byte(`{"batch":[{"type":"track","event":"Demo Track","properties":{"category":"Demo Category"}}]}`)
+
+	t.Run("round trip", func(t *testing.T) {
+		compressed := compressPayload(payload)
+		require.Equal(t, payloadFormatZstd, compressed[0])
+		decompressed, err := decompressPayload(compressed)
+		require.NoError(t, err)
+		require.Equal(t, payload, decompressed)
+	})
+
+	t.Run("uncompressed payloads", func(t *testing.T) {
+		decompressed, err := decompressPayload(payload)
+		require.NoError(t, err)
+		require.Equal(t, payload, decompressed)
+
+		decompressed, err = decompressPayload(nil)
+		require.NoError(t, err)
+		require.Empty(t, decompressed)
+	})
+
+	t.Run("corrupted payloads", func(t *testing.T) {
+		compressed := compressPayload(payload)
+		_, err := decompressPayload(compressed[:len(compressed)/2])
+		require.Error(t, err)
+	})
+
+	t.Run("backup rows", func(t *testing.T) {
+		for _, stored := range [][]byte{compressPayload(payload), payload} {
+			row := []byte(fmt.Sprintf(`{"job_id":1,"event_payload":%q,"event_count":1}`, base64.StdEncoding.EncodeToString(stored)))
+			decompressed, err := decompressBackupPayload(row)
+			require.NoError(t, err)
+			require.JSONEq(t, fmt.Sprintf(`{"job_id":1,"event_payload":%s,"event_count":1}`, payload), string(decompressed))
+		}
+	})
+}
+
+func TestCompressedDatasets(t *testing.T) {
+	maxDSSize := 1
+	_ = startPostgres(t)
+
+	triggerAddNewDS := make(chan time.Time)
+	triggerMigrateDS := make(chan time.Time)
+
+	jobDB := HandleT{
+		TriggerAddNewDS: func() <-chan time.Time {
+			return triggerAddNewDS
+		},
+		TriggerMigrateDS: func() <-chan time.Time {
+			return triggerMigrateDS
+		},
+		MaxDSSize: &maxDSSize,
+	}
+	tablePrefix := strings.ToLower(rand.String(5))
+	err := jobDB.Setup(
+		ReadWrite,
+		true,
+		tablePrefix,
+		true,
+		[]prebackup.Handler{},
+		fileuploader.NewDefaultProvider(),
+	)
+	require.NoError(t, err)
+	defer jobDB.TearDown()
+
+	jobDB.MaxDSRetentionPeriod = time.Millisecond
+
+	customVal := rand.String(5)
+	jobs := genJobs(defaultWorkspaceID, customVal, 30, 1)
+	requireJobs := func(expected []*JobT) {
+		t.Helper()
+		result, err := jobDB.GetUnprocessed(context.Background(), GetQueryParamsT{
+			CustomValFilters: []string{customVal},
+			JobsLimit:        100,
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Jobs, len(expected))
+		for i, job := range result.Jobs {
+			require.Equal(t, expected[i].UUID, job.UUID)
+			require.JSONEq(t, string(expected[i].EventPayload), string(job.EventPayload))
+		}
+	}
+	requireCompressed := func(ds dataSetT, expected bool) {
+		t.Helper()
+		var dataType string
+		require.NoError(t, jobDB.dbHandle.QueryRow(
+			`SELECT data_type FROM information_schema.columns WHERE table_name = $1 AND column_name = 'event_payload'`, ds.JobTable,
+		).Scan(&dataType))
+		require.Equal(t, expected, dataType == "bytea", ds.JobTable)
+	}
+
+	// a dataset created before enabling compression
+	require.NoError(t, jobDB.Store(context.Background(), jobs[:10]))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[:9], "executing"), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[:9], "succeeded"), []string{customVal}, []ParameterFilterT{}))
+
+	jobDB.payloadCompression = true
+	triggerAddNewDS <- time.Now() // trigger addNewDSLoop to run
+	triggerAddNewDS <- time.Now() // Second time, waits for the first loop to finish
+	require.EqualValues(t, 2, jobDB.GetMaxDSIndex())
+	require.NoError(t, jobDB.Store(context.Background(), jobs[10:20]))
+	requireJobs(jobs[9:20])
+
+	triggerAddNewDS <- time.Now()
+	triggerAddNewDS <- time.Now()
+	require.EqualValues(t, 3, jobDB.GetMaxDSIndex())
+	require.NoError(t, jobDB.Store(context.Background(), jobs[20:30]))
+
+	_, err = jobDB.dbHandle.Exec(fmt.Sprintf(`ANALYZE %[1]s_jobs_1, %[1]s_jobs_2, %[1]s_job_status_1, %[1]s_job_status_2`, tablePrefix))
+	require.NoError(t, err)
+	triggerMigrateDS <- time.Now() // trigger migrateDSLoop to run
+	triggerMigrateDS <- time.Now() // waits for last loop to finish
+
+	// the pending job of the first dataset is migrated to a dataset with compressed payloads, uncompressed
+	dsList := jobDB.getDSList()
+	require.Equal(t, `1_1`, dsList[0].Index)
+	requireCompressed(dsList[0], true)
+	requireCompressed(dsList[1], true)
+	requireJobs(jobs[9:30])
+
+	var payload []byte
+	require.NoError(t, jobDB.dbHandle.QueryRow(fmt.Sprintf(`SELECT event_payload FROM %q`, dsList[0].JobTable)).Scan(&payload))
+	require.NotEqual(t, payloadFormatZstd, payload[0])
+}
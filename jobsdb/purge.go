@@ -0,0 +1,159 @@
+package jobsdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/services/metric"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// All the data a jobsdb holds for a workspace can be purged, e.g. once its account is deleted: the jobs of the
+// workspace along with their statuses, from all datasets, and the files of its spilled datasets and dead letters,
+// from object storage. Backups and snapshots are not purged.
+
+// WorkspacePurgeT reports what purging a workspace removed
+type WorkspacePurgeT struct {
+	WorkspaceID     string                   `json:"workspaceId"`
+	Jobs            int64                    `json:"jobs"`
+	Statuses        int64                    `json:"statuses"`
+	Datasets        []WorkspacePurgeDatasetT `json:"datasets"`
+	SpilledFiles    int                      `json:"spilledFiles"`
+	DeadLetterFiles int                      `json:"deadLetterFiles"`
+}
+
+// WorkspacePurgeDatasetT reports what purging a workspace removed from a dataset
+type WorkspacePurgeDatasetT struct {
+	Dataset  string `json:"dataset"`
+	Jobs     int64  `json:"jobs"`
+	Statuses int64  `json:"statuses"`
+}
+
+// PurgeWorkspace removes all the jobs of a workspace along with their statuses, dropping the workspace's partitions
+// of partitioned datasets and deleting its jobs from the other ones, as well as the files of its spilled datasets and
+// dead letters
+func (jd *HandleT) PurgeWorkspace(ctx context.Context, workspaceID string) (WorkspacePurgeT, error) {
+	purge := WorkspacePurgeT{WorkspaceID: workspaceID, Datasets: []WorkspacePurgeDatasetT{}}
+	if workspaceID == "" {
+		return purge, fmt.Errorf("a workspace id is required")
+	}
+
+	// files are deleted first, so that they are still listed if purging fails and can be deleted again
+	spilledFiles, err := jd.workspaceObjects(ctx, jd.spilledDatasetsTable(), workspaceID)
+	if err != nil {
+		return purge, err
+	}
+	deadLetterFiles, err := jd.workspaceObjects(ctx, jd.deadLettersTable(), workspaceID)
+	if err != nil {
+		return purge, err
+	}
+	if objects := append(append([]string{}, spilledFiles...), deadLetterFiles...); len(objects) > 0 {
+		fm, err := jd.fileUploaderProvider.GetFileManager(workspaceID)
+		if err != nil {
+			return purge, fmt.Errorf("getting the file manager of workspace %q: %w", workspaceID, err)
+		}
+		if err := fm.DeleteObjects(ctx, objects); err != nil {
+			return purge, fmt.Errorf("deleting files of workspace %q: %w", workspaceID, err)
+		}
+	}
+
+	pendingJobs := make(map[string]int) // customVal -> purged jobs without a terminal status
+	err = jd.WithTx(func(tx *Tx) error {
+		return jd.withDistributedSharedLock(ctx, tx, "schema_migrate", func() error {
+			if !jd.dsMigrationLock.TryLockWithCtx(ctx) {
+				return fmt.Errorf("failed to acquire lock: %w", ctx.Err())
+			}
+			defer jd.dsMigrationLock.Unlock()
+			// the migration lock is taken before the dslist one, as everywhere else, and datasets can't be
+			// migrated while it is held, so the list is only read under the dslist lock
+			if !jd.dsListLock.RTryLockWithCtx(ctx) {
+				return fmt.Errorf("could not acquire a dslist read lock: %w", ctx.Err())
+			}
+			dsList := jd.getDSList()
+			jd.dsListLock.RUnlock()
+
+			for _, ds := range dsList {
+				ds := ds
+				backend, err := jd.dsBackend(ctx, tx, ds)
+				if err != nil {
+					return err
+				}
+				if err := workspacePendingJobsInTx(ctx, tx, ds, workspaceID, pendingJobs); err != nil {
+					return fmt.Errorf("counting pending jobs of workspace %q in %q: %w", workspaceID, ds.JobTable, err)
+				}
+				jobs, statuses, err := backend.dropWorkspaceJobsInTx(ctx, tx, ds, workspaceID)
+				if err != nil {
+					return fmt.Errorf("dropping jobs of workspace %q from %q: %w", workspaceID, ds.JobTable, err)
+				}
+				if jobs == 0 && statuses == 0 {
+					continue
+				}
+				purge.Datasets = append(purge.Datasets, WorkspacePurgeDatasetT{Dataset: ds.Index, Jobs: jobs, Statuses: statuses})
+				purge.Jobs += jobs
+				purge.Statuses += statuses
+				tx.AddSuccessListener(func() {
+					jd.dropDSFromCache(ds)
+				})
+			}
+			for _, table := range []string{jd.spilledDatasetsTable(), jd.deadLettersTable()} {
+				if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %q WHERE workspace_id = $1`, table), workspaceID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return purge, err
+	}
+	purge.SpilledFiles, purge.DeadLetterFiles = len(spilledFiles), len(deadLetterFiles)
+	for customVal, count := range pendingJobs {
+		metric.DecreasePendingEvents(jd.tablePrefix, workspaceID, customVal, float64(count))
+	}
+
+	jd.logger.Infof("[JobsDB] :: Purged workspace %s: %d jobs, %d statuses, %d spilled files, %d dead letter files",
+		workspaceID, purge.Jobs, purge.Statuses, purge.SpilledFiles, purge.DeadLetterFiles)
+	tags := stats.Tags{"customVal": jd.tablePrefix, "workspaceId": workspaceID}
+	stats.Default.NewTaggedStat("jobsdb_purged_jobs", stats.CountType, tags).Count(int(purge.Jobs))
+	stats.Default.NewTaggedStat("jobsdb_purged_files", stats.CountType, tags).Count(purge.SpilledFiles + purge.DeadLetterFiles)
+	return purge, nil
+}
+
+// workspacePendingJobsInTx adds the jobs of the workspace in the dataset without a terminal status to pendingJobs, by
+// custom val
+func workspacePendingJobsInTx(ctx context.Context, tx *Tx, ds dataSetT, workspaceID string, pendingJobs map[string]int) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT j.custom_val, COUNT(*) FROM %[1]q j
+		LEFT JOIN "v_last_%[2]s" s ON j.job_id = s.job_id
+		WHERE j.workspace_id = $1 AND (s.job_id IS NULL OR s.job_state NOT IN ('aborted', 'succeeded', 'migrated'))
+		GROUP BY j.custom_val`, ds.JobTable, ds.JobStatusTable), workspaceID)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var (
+			customVal string
+			count     int
+		)
+		if err := rows.Scan(&customVal, &count); err != nil {
+			return err
+		}
+		pendingJobs[customVal] += count
+	}
+	return rows.Err()
+}
+
+// workspaceObjects returns the object names of a workspace's files listed in a table
+func (jd *HandleT) workspaceObjects(ctx context.Context, table, workspaceID string) ([]string, error) {
+	var objects []string
+	err := jd.dbHandle.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT ARRAY_AGG(DISTINCT object_name) FROM %q WHERE workspace_id = $1`, table), workspaceID,
+	).Scan(pq.Array(&objects))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("listing files of workspace %q in %q: %w", workspaceID, table, err)
+	}
+	return objects, nil
+}
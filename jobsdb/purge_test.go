@@ -0,0 +1,80 @@
+package jobsdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/services/metric"
+	"github.com/rudderlabs/rudder-server/testhelper/destination"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+func TestPurgeWorkspace(t *testing.T) {
+	_ = startPostgres(t)
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+	minioResource, err := destination.SetupMINIO(pool, t)
+	require.NoError(t, err)
+	t.Setenv("RUDDER_TMPDIR", t.TempDir())
+
+	provider := fileuploader.NewStaticProvider(map[string]fileuploader.StorageSettings{
+		defaultWorkspaceID: {
+			Bucket: backendconfig.StorageBucket{
+				Type: "MINIO",
+				Config: map[string]interface{}{
+					"bucketName":      minioResource.BucketName,
+					"endPoint":        minioResource.Endpoint,
+					"accessKeyID":     minioResource.AccessKey,
+					"secretAccessKey": minioResource.SecretKey,
+				},
+			},
+		},
+	})
+	jobDB := HandleT{}
+	require.NoError(t, jobDB.Setup(ReadWrite, true, strings.ToLower(rand.String(5)), true, []prebackup.Handler{}, provider))
+	defer jobDB.TearDown()
+	jobDB.deadLetterDelay = 0
+
+	customVal := rand.String(5)
+	jobs := genJobs(defaultWorkspaceID, customVal, 10, 1)
+	otherJobs := genJobs("other-workspace", customVal, 5, 1)
+	require.NoError(t, jobDB.Store(context.Background(), append(jobs, otherJobs...)))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[:3], Aborted.State), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[3:5], Failed.State), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.exportDeadLetters(context.Background()))
+
+	unprocessed, err := jobDB.GetUnprocessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: 100})
+	require.NoError(t, err)
+	require.Len(t, unprocessed.Jobs, 10)
+	metric.IncreasePendingEvents(jobDB.tablePrefix, defaultWorkspaceID, customVal, 7)
+
+	purge, err := jobDB.PurgeWorkspace(context.Background(), defaultWorkspaceID)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, purge.Jobs)
+	require.EqualValues(t, 5, purge.Statuses)
+	require.Len(t, purge.Datasets, 1)
+	require.Equal(t, 1, purge.DeadLetterFiles)
+	require.Zero(t, purge.SpilledFiles)
+	require.Zero(t, metric.PendingEvents(jobDB.tablePrefix, defaultWorkspaceID, customVal).Value(), "pending events of the jobs not aborted are decreased")
+
+	unprocessed, err = jobDB.GetUnprocessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: 100})
+	require.NoError(t, err)
+	require.Len(t, unprocessed.Jobs, 5, "jobs of other workspaces are kept")
+	for _, job := range unprocessed.Jobs {
+		require.Equal(t, "other-workspace", job.WorkspaceId)
+	}
+	deadLetters, err := jobDB.workspaceObjects(context.Background(), jobDB.deadLettersTable(), defaultWorkspaceID)
+	require.NoError(t, err)
+	require.Empty(t, deadLetters)
+
+	purge, err = jobDB.PurgeWorkspace(context.Background(), defaultWorkspaceID)
+	require.NoError(t, err)
+	require.Zero(t, purge.Jobs, "purging is idempotent")
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rudderlabs/rudder-server/config"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 )
@@ -29,12 +31,17 @@ type ReadonlyJobsDB interface {
 	GetDSListString() (string, error)
 	GetJobIDStatus(job_id, prefix string) (string, error)
 	GetJobByID(job_id, prefix string) (string, error)
+	GetDeadLetters(workspaceID string) (string, error)
+	SearchJobs(arg string) (string, error)
 }
 
 type ReadonlyHandleT struct {
 	DbHandle    *sql.DB
 	tablePrefix string
 	logger      logger.Logger
+
+	// FileUploaderProvider is used for looking up jobs of datasets spilled to object storage, if any
+	FileUploaderProvider fileuploader.Provider
 }
 
 type DSPair struct {
@@ -89,6 +96,10 @@ func (jd *ReadonlyHandleT) Setup(tablePrefix string) error {
 	jd.logger = pkgLogger.Child("readonly-" + tablePrefix)
 	var err error
 	psqlInfo := misc.GetConnectionString()
+	// readonly operations go to the read replica, if there is one
+	if replicaInfo := misc.GetReplicaConnectionString(); replicaInfo != "" && config.GetBool("ReadonlyJobsDB.useReplica", true) {
+		psqlInfo = replicaInfo
+	}
 	jd.tablePrefix = tablePrefix
 
 	jd.DbHandle, err = sql.Open("postgres", psqlInfo)
@@ -351,11 +362,23 @@ func (jd *ReadonlyHandleT) GetJobByID(job_id, _ string) (string, error) {
 				return "", err1
 			}
 		}
+		if event.EventPayload, err = decompressPayload(event.EventPayload); err != nil {
+			return "", err
+		}
 		response, err = json.MarshalIndent(event, "", " ")
 		if err != nil {
 			return "", err
 		}
 	}
+	if response == nil {
+		spilledJob, err := jd.getSpilledJob(job_id)
+		if err != nil || spilledJob == nil {
+			return "", err
+		}
+		if response, err = json.MarshalIndent(spilledJob.toJob(), "", " "); err != nil {
+			return "", err
+		}
+	}
 	return string(response), nil
 }
 
@@ -406,8 +429,29 @@ func (jd *ReadonlyHandleT) GetJobIDStatus(jobID, _ string) (string, error) {
 		return string(response), nil
 	}
 
-	// jobID not found
-	return "", nil
+	spilledJob, err := jd.getSpilledJob(jobID)
+	if err != nil || spilledJob == nil {
+		// jobID not found
+		return "", err
+	}
+	response, err := json.MarshalIndent(FailedStatusStats{FailedStatusStats: spilledJob.jobStatuses()}, "", " ")
+	if err != nil {
+		return "", err
+	}
+	return string(response), nil
+}
+
+// getSpilledJob looks up a job in the datasets spilled to object storage, returning nil if it is not found there either
+func (jd *ReadonlyHandleT) getSpilledJob(jobID string) (*spilledJobT, error) {
+	id, err := strconv.ParseInt(jobID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	job, err := getSpilledJob(context.TODO(), jd.DbHandle, jd.FileUploaderProvider, jd.tablePrefix, id)
+	if errors.Is(err, errSpilledJobNotFound) {
+		return nil, nil
+	}
+	return job, err
 }
 
 func (jd *ReadonlyHandleT) GetJobIDsForUser(args []string) (string, error) {
@@ -497,3 +541,31 @@ func (jd *ReadonlyHandleT) GetDSListString() (string, error) {
 	}
 	return response, nil
 }
+
+// GetDeadLetters lists the latest files of dead letters exported to object storage, of a workspace or of all of them if workspaceID is empty
+func (jd *ReadonlyHandleT) GetDeadLetters(workspaceID string) (string, error) {
+	rows, err := jd.DbHandle.Query(fmt.Sprintf(`SELECT workspace_id, location, object_name, job_count, min_job_id, max_job_id, exported_at
+		FROM %q WHERE $1 = '' OR workspace_id = $1 ORDER BY id DESC LIMIT $2`, jd.tablePrefix+"_dead_letters"),
+		workspaceID, config.GetInt("ReadonlyJobsDB.deadLetters.limit", 100),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rows.Close() }()
+	deadLetters := []DeadLettersFileT{}
+	for rows.Next() {
+		var file DeadLettersFileT
+		if err := rows.Scan(&file.WorkspaceID, &file.Location, &file.ObjectName, &file.JobCount, &file.MinJobID, &file.MaxJobID, &file.ExportedAt); err != nil {
+			return "", err
+		}
+		deadLetters = append(deadLetters, file)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	response, err := json.MarshalIndent(deadLetters, "", " ")
+	if err != nil {
+		return "", err
+	}
+	return string(response), nil
+}
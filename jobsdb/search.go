@@ -0,0 +1,214 @@
+package jobsdb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// Jobs can be searched through the admin interface by the ids of the events they carry, for finding out what became
+// of an event. Jobs are matched by:
+//   - messageId: the message_id parameter of the jobs produced by the processor, or the messageId of an event of
+//     their payload
+//   - rudderId: the user_id of the jobs produced by the processor, or the rudderId of an event of their payload
+//   - userId: the userId of an event of their payload
+//
+// Payloads are only searched in datasets storing them uncompressed, and jobs of datasets spilled to object storage
+// are not searched at all. Searches cover the last day unless given a time range, which is
+// limited to ReadonlyJobsDB.search.maxRange.
+
+// JobSearchT is a search for the jobs of events, created within a time range
+type JobSearchT struct {
+	MessageID string    `json:"messageId"`
+	RudderID  string    `json:"rudderId"`
+	UserID    string    `json:"userId"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+}
+
+// JobSearchResultT is a job found by a search, along with its status history
+type JobSearchResultT struct {
+	Dataset  string       `json:"dataset"`
+	Job      JobT         `json:"job"`
+	Statuses []JobStatusT `json:"statuses"`
+}
+
+func (s *JobSearchT) validate() error {
+	if s.MessageID == "" && s.RudderID == "" && s.UserID == "" {
+		return errors.New("one of messageId, rudderId or userId is required")
+	}
+	if s.To.IsZero() {
+		s.To = time.Now()
+	}
+	if s.From.IsZero() {
+		s.From = s.To.Add(-24 * time.Hour)
+	}
+	if !s.From.Before(s.To) {
+		return fmt.Errorf("from %v is not before to %v", s.From, s.To)
+	}
+	if maxRange := config.GetDuration("ReadonlyJobsDB.search.maxRange", 24*7, time.Hour); s.To.Sub(s.From) > maxRange {
+		return fmt.Errorf("time range %v exceeds the maximum of %v", s.To.Sub(s.From), maxRange)
+	}
+	return nil
+}
+
+// payloadProbes returns the JSONB documents a payload containing an event with the searched ids contains, whether
+// the events are batched, as in gateway jobs, or listed
+func (s *JobSearchT) payloadProbes() (batched, listed []byte, err error) {
+	event := map[string]string{}
+	if s.MessageID != "" {
+		event["messageId"] = s.MessageID
+	}
+	if s.RudderID != "" {
+		event["rudderId"] = s.RudderID
+	}
+	if s.UserID != "" {
+		event["userId"] = s.UserID
+	}
+	if batched, err = json.Marshal(map[string]interface{}{"batch": []interface{}{event}}); err != nil {
+		return nil, nil, err
+	}
+	if listed, err = json.Marshal([]interface{}{event}); err != nil {
+		return nil, nil, err
+	}
+	return batched, listed, nil
+}
+
+// SearchJobs returns the jobs matching the search given as JSON argument, along with their status history
+func (jd *ReadonlyHandleT) SearchJobs(arg string) (string, error) {
+	var search JobSearchT
+	if err := json.Unmarshal([]byte(arg), &search); err != nil {
+		return "", fmt.Errorf("parsing search %q: %w", arg, err)
+	}
+	if err := search.validate(); err != nil {
+		return "", err
+	}
+	batched, listed, err := search.payloadProbes()
+	if err != nil {
+		return "", err
+	}
+	limit := config.GetInt("ReadonlyJobsDB.search.limit", 100)
+
+	results := []JobSearchResultT{}
+	for _, ds := range jd.getDSList() {
+		if len(results) >= limit {
+			break
+		}
+		inRange, err := jd.dsInTimeRange(ds, search.From, search.To)
+		if err != nil {
+			return "", err
+		}
+		if !inRange {
+			continue
+		}
+		dsResults, err := jd.searchDS(ds, &search, batched, listed, limit-len(results))
+		if err != nil {
+			return "", fmt.Errorf("searching %q: %w", ds.JobTable, err)
+		}
+		results = append(results, dsResults...)
+	}
+	response, err := json.MarshalIndent(results, "", " ")
+	if err != nil {
+		return "", err
+	}
+	return string(response), nil
+}
+
+// dsInTimeRange tells whether the jobs of a dataset, going by its first and last ones, may have been created within a
+// time range
+func (jd *ReadonlyHandleT) dsInTimeRange(ds dataSetT, from, to time.Time) (bool, error) {
+	var first, last sql.NullTime
+	err := jd.DbHandle.QueryRow(fmt.Sprintf(`SELECT
+		(SELECT created_at FROM %[1]q ORDER BY job_id ASC LIMIT 1),
+		(SELECT created_at FROM %[1]q ORDER BY job_id DESC LIMIT 1)`, ds.JobTable),
+	).Scan(&first, &last)
+	if err != nil {
+		return false, err
+	}
+	if !first.Valid || !last.Valid {
+		return false, nil
+	}
+	return !last.Time.Before(from) && !first.Time.After(to), nil
+}
+
+func (jd *ReadonlyHandleT) searchDS(ds dataSetT, search *JobSearchT, batched, listed []byte, limit int) ([]JobSearchResultT, error) {
+	var dataType string
+	err := jd.DbHandle.QueryRow(
+		`SELECT data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 AND column_name = 'event_payload'`,
+		ds.JobTable,
+	).Scan(&dataType)
+	if err != nil {
+		return nil, err
+	}
+	args := []interface{}{search.From, search.To, search.MessageID, search.RudderID, search.UserID, limit}
+	payloadCondition := "FALSE"
+	if dataType == "jsonb" {
+		payloadCondition = "event_payload @> $7::JSONB OR event_payload @> $8::JSONB"
+		args = append(args, string(batched), string(listed))
+	}
+
+	rows, err := jd.DbHandle.Query(fmt.Sprintf(`SELECT job_id, uuid, user_id, parameters, custom_val, event_payload, event_count, created_at, expire_at, workspace_id
+		FROM %q
+		WHERE created_at BETWEEN $1 AND $2
+		AND ((($3 = '' OR parameters->>'message_id' = $3) AND ($4 = '' OR user_id = $4) AND $5 = '') OR %s)
+		ORDER BY job_id LIMIT $6`, ds.JobTable, payloadCondition),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var results []JobSearchResultT
+	var jobIDs []int64
+	for rows.Next() {
+		var job JobT
+		if err := rows.Scan(&job.JobID, &job.UUID, &job.UserID, &job.Parameters, &job.CustomVal, &job.EventPayload, &job.EventCount,
+			&job.CreatedAt, &job.ExpireAt, &job.WorkspaceId); err != nil {
+			return nil, err
+		}
+		if job.EventPayload, err = decompressPayload(job.EventPayload); err != nil {
+			return nil, err
+		}
+		results = append(results, JobSearchResultT{Dataset: ds.Index, Job: job, Statuses: []JobStatusT{}})
+		jobIDs = append(jobIDs, job.JobID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	statusRows, err := jd.DbHandle.Query(fmt.Sprintf(`SELECT job_id, job_state, attempt, exec_time, retry_time, error_code, error_response, parameters
+		FROM %q WHERE job_id = ANY($1) ORDER BY id`, ds.JobStatusTable),
+		pq.Array(jobIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = statusRows.Close() }()
+	byJobID := make(map[int64]*JobSearchResultT, len(results))
+	for i := range results {
+		byJobID[results[i].Job.JobID] = &results[i]
+	}
+	for statusRows.Next() {
+		var status JobStatusT
+		if err := statusRows.Scan(&status.JobID, &status.JobState, &status.AttemptNum, &status.ExecTime, &status.RetryTime,
+			&status.ErrorCode, &status.ErrorResponse, &status.Parameters); err != nil {
+			return nil, err
+		}
+		result := byJobID[status.JobID]
+		result.Statuses = append(result.Statuses, status)
+		result.Job.LastJobStatus = status
+	}
+	if err := statusRows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
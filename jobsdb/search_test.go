@@ -0,0 +1,83 @@
+package jobsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+func TestJobSearchValidate(t *testing.T) {
+	search := JobSearchT{}
+	require.Error(t, search.validate(), "an id is required")
+
+	search = JobSearchT{MessageID: "messageID"}
+	require.NoError(t, search.validate())
+	require.Equal(t, 24*time.Hour, search.To.Sub(search.From), "the last day is searched by default")
+
+	now := time.Now()
+	search = JobSearchT{UserID: "userID", From: now, To: now.Add(-time.Hour)}
+	require.Error(t, search.validate(), "from must be before to")
+
+	search = JobSearchT{UserID: "userID", From: now.Add(-30 * 24 * time.Hour), To: now}
+	require.Error(t, search.validate(), "the time range is limited")
+}
+
+func TestSearchJobs(t *testing.T) {
+	_ = startPostgres(t)
+	tablePrefix := strings.ToLower(rand.String(5))
+	jobDB := HandleT{}
+	require.NoError(t, jobDB.Setup(ReadWrite, true, tablePrefix, true, []prebackup.Handler{}, fileuploader.NewDefaultProvider()))
+	defer jobDB.TearDown()
+
+	customVal := rand.String(5)
+	jobs := genJobs(defaultWorkspaceID, customVal, 3, 1)
+	// the first job is a gateway one, carrying the event in its batch, the others were produced by the processor
+	jobs[0].UserID = "<<>>anon_id<<>>user_id"
+	jobs[0].EventPayload = []byte(`{"batch": [{"messageId": "message-1", "rudderId": "rudder-1", "userId": "user-1"}]}`)
+	for i, job := range jobs[1:] {
+		job.UserID = "rudder-1"
+		job.Parameters = []byte(fmt.Sprintf(`{"message_id": "message-1", "destination_id": "destination-%d"}`, i))
+		job.EventPayload = []byte(`{"body": {}}`)
+	}
+	require.NoError(t, jobDB.Store(context.Background(), jobs))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[1:2], Failed.State), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[1:2], Succeeded.State), []string{customVal}, []ParameterFilterT{}))
+
+	var readonlyDB ReadonlyHandleT
+	require.NoError(t, readonlyDB.Setup(tablePrefix))
+	defer readonlyDB.TearDown()
+	search := func(search JobSearchT) []JobSearchResultT {
+		t.Helper()
+		arg, err := json.Marshal(search)
+		require.NoError(t, err)
+		response, err := readonlyDB.SearchJobs(string(arg))
+		require.NoError(t, err)
+		var results []JobSearchResultT
+		require.NoError(t, json.Unmarshal([]byte(response), &results))
+		return results
+	}
+
+	results := search(JobSearchT{MessageID: "message-1"})
+	require.Len(t, results, 3)
+	require.Empty(t, results[0].Statuses)
+	require.Len(t, results[1].Statuses, 2)
+	require.Equal(t, Failed.State, results[1].Statuses[0].JobState)
+	require.Equal(t, Succeeded.State, results[1].Statuses[1].JobState)
+	require.Equal(t, Succeeded.State, results[1].Job.LastJobStatus.JobState)
+	require.Empty(t, results[2].Statuses)
+
+	require.Len(t, search(JobSearchT{RudderID: "rudder-1"}), 3)
+	require.Len(t, search(JobSearchT{UserID: "user-1"}), 1, "user ids are only found in payloads")
+	require.Len(t, search(JobSearchT{MessageID: "message-1", UserID: "user-1"}), 1)
+	require.Empty(t, search(JobSearchT{MessageID: "message-2"}))
+	require.Empty(t, search(JobSearchT{MessageID: "message-1", From: time.Now().Add(-2 * time.Hour), To: time.Now().Add(-time.Hour)}))
+}
@@ -49,6 +49,9 @@ func (jd *HandleT) dropDatabaseTables(l lock.LockToken) {
 	jd.dropSchemaMigrationTables()
 	jd.assertError(jd.dropAllDS(l))
 	jd.dropJournal()
+	jd.dropSpilledDatasetsTable()
+	jd.dropDeadLettersTables()
+	jd.dropHandoffTokensTable()
 	jd.assertError(jd.dropAllBackupDS())
 }
 
@@ -0,0 +1,397 @@
+package jobsdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// A snapshot of a jobsdb holds its in-flight jobs, i.e. the ones not processed yet, along with their last status, as
+// of a point in time. The jobs are read from all datasets in the same repeatable read transaction and uploaded to
+// object storage, to a gzipped JSON lines file per workspace. A snapshot can be restored into an empty jobsdb, e.g. of
+// a new rudder-server node replacing the one the snapshot was taken from, keeping the ids of the jobs. Statuses of jobs
+// that were in progress when the snapshot was taken are restored as failed, to be retried, and the ones of jobs being
+// migrated are dropped, leaving the jobs unprocessed.
+
+// SnapshotT describes a snapshot of a jobsdb, which is needed for restoring it
+type SnapshotT struct {
+	ID          string             `json:"id"`
+	TablePrefix string             `json:"tablePrefix"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	Datasets    []SnapshotDatasetT `json:"datasets"`
+	Files       []SnapshotFileT    `json:"files"`
+}
+
+// SnapshotDatasetT is a dataset of a snapshot
+type SnapshotDatasetT struct {
+	JobTable    string `json:"jobTable"`
+	Compressed  bool   `json:"compressed"`
+	Partitioned bool   `json:"partitioned"`
+	JobCount    int    `json:"jobCount"`
+}
+
+// SnapshotFileT is a file of a snapshot, holding the in-flight jobs of a workspace
+type SnapshotFileT struct {
+	WorkspaceID string `json:"workspaceId"`
+	Location    string `json:"location"`
+	ObjectName  string `json:"objectName"`
+	JobCount    int    `json:"jobCount"`
+	MinJobID    int64  `json:"minJobId"`
+	MaxJobID    int64  `json:"maxJobId"`
+}
+
+// Snapshot uploads the in-flight jobs of the jobsdb to object storage
+func (jd *HandleT) Snapshot(ctx context.Context) (SnapshotT, error) {
+	start := time.Now()
+	snapshot := SnapshotT{ID: uuid.New().String(), TablePrefix: jd.tablePrefix, CreatedAt: start}
+	tmpDirPath, err := misc.CreateTMPDIR()
+	if err != nil {
+		return snapshot, err
+	}
+	files := make(map[string]*snapshotFileT)
+	defer func() {
+		for _, file := range files {
+			_ = os.Remove(file.path)
+		}
+	}()
+	if err := jd.readSnapshot(ctx, &snapshot, func(workspaceID string) string {
+		return filepath.Join(tmpDirPath, "rudder-jobsdb-snapshots", fmt.Sprintf("%s.%s.%s.json.gz", jd.tablePrefix, snapshot.ID, workspaceID))
+	}, files); err != nil {
+		return snapshot, err
+	}
+
+	for _, file := range files {
+		f, err := os.Open(file.path)
+		if err != nil {
+			return snapshot, fmt.Errorf("opening gz file %q: %w", file.path, err)
+		}
+		output, err := jd.backupUploadWithExponentialBackoff(ctx, f, file.WorkspaceID, jd.snapshotPathPrefix, jd.tablePrefix, snapshot.ID)
+		_ = f.Close()
+		if err != nil {
+			return snapshot, err
+		}
+		file.Location, file.ObjectName = output.Location, output.ObjectName
+		snapshot.Files = append(snapshot.Files, file.SnapshotFileT)
+	}
+	jd.logger.Infof("[JobsDB] :: Took snapshot %s of %d datasets in %d files", snapshot.ID, len(snapshot.Datasets), len(snapshot.Files))
+	stats.Default.NewTaggedStat("jobsdb_snapshot_time", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix}).Since(start)
+	return snapshot, nil
+}
+
+// snapshotFileT is the file the in-flight jobs of a workspace are written to, before being uploaded
+type snapshotFileT struct {
+	SnapshotFileT
+	path string
+}
+
+// readSnapshot writes the in-flight jobs of all datasets to a file per workspace, holding a migration read lock for the
+// datasets not to be dropped while being read. The lock is released before the files get uploaded.
+func (jd *HandleT) readSnapshot(ctx context.Context, snapshot *SnapshotT, filePath func(workspaceID string) string, files map[string]*snapshotFileT) error {
+	if !jd.dsMigrationLock.RTryLockWithCtx(ctx) {
+		return fmt.Errorf("could not acquire a migration read lock: %w", ctx.Err())
+	}
+	defer jd.dsMigrationLock.RUnlock()
+	if !jd.dsListLock.RTryLockWithCtx(ctx) {
+		return fmt.Errorf("could not acquire a dslist read lock: %w", ctx.Err())
+	}
+	dsList := jd.getDSList()
+	jd.dsListLock.RUnlock()
+
+	writer := fileuploader.NewGzMultiFileWriter()
+	err := jd.writeSnapshot(ctx, snapshot, dsList, writer, filePath, files)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeSnapshot writes the in-flight jobs of datasets to a file per workspace, reading all the datasets in the same transaction
+func (jd *HandleT) writeSnapshot(
+	ctx context.Context, snapshot *SnapshotT, dsList []dataSetT, writer fileuploader.MultiFileWriter, filePath func(workspaceID string) string, files map[string]*snapshotFileT,
+) error {
+	tx, err := jd.dbHandle.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, ds := range dsList {
+		dataset := SnapshotDatasetT{JobTable: ds.JobTable}
+		if dataset.Compressed, err = jd.isCompressedDS(ctx, tx, ds); err != nil {
+			return err
+		}
+		if dataset.Partitioned, err = jd.isPartitionedDS(ctx, tx, ds); err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT j.workspace_id, j.job_id, jsonb_build_object(
+				'job_id', j.job_id,
+				'workspace_id', j.workspace_id,
+				'uuid', j.uuid,
+				'user_id', j.user_id,
+				'parameters', j.parameters,
+				'custom_val', j.custom_val,
+				'event_payload', %[3]s,
+				'event_count', j.event_count,
+				'created_at', j.created_at,
+				'expire_at', j.expire_at,
+				'statuses', CASE WHEN s.job_id IS NULL THEN '[]'::JSONB ELSE jsonb_build_array(jsonb_build_object(
+					'job_state', s.job_state,
+					'attempt', s.attempt,
+					'exec_time', s.exec_time,
+					'retry_time', s.retry_time,
+					'error_code', s.error_code,
+					'error_response', s.error_response,
+					'parameters', s.parameters)) END)
+			FROM %[1]q j LEFT JOIN "v_last_%[2]s" s ON j.job_id = s.job_id
+			WHERE s.job_id IS NULL OR s.job_state = ANY($1)
+			ORDER BY j.job_id`, ds.JobTable, ds.JobStatusTable, backupPayloadColumn("j", dataset.Compressed)),
+			pq.Array(validNonTerminalStates),
+		)
+		if err != nil {
+			return fmt.Errorf("reading in-flight jobs of %q: %w", ds.JobTable, err)
+		}
+		for rows.Next() {
+			var (
+				workspaceID string
+				jobID       int64
+				row         json.RawMessage
+			)
+			if err := rows.Scan(&workspaceID, &jobID, &row); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			if dataset.Compressed {
+				if row, err = decompressBackupPayload(row); err != nil {
+					_ = rows.Close()
+					return err
+				}
+			}
+			file, ok := files[workspaceID]
+			if !ok {
+				file = &snapshotFileT{SnapshotFileT: SnapshotFileT{WorkspaceID: workspaceID, MinJobID: jobID}, path: filePath(workspaceID)}
+				files[workspaceID] = file
+			}
+			if jobID < file.MinJobID {
+				file.MinJobID = jobID
+			}
+			if jobID > file.MaxJobID {
+				file.MaxJobID = jobID
+			}
+			file.JobCount++
+			dataset.JobCount++
+			if _, err := writer.Write(file.path, append(row, '\n')); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("writing gz file %q: %w", file.path, err)
+			}
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		snapshot.Datasets = append(snapshot.Datasets, dataset)
+	}
+	return nil
+}
+
+// Restore stores the jobs of a snapshot, along with their last status, into the jobsdb, which has to be empty
+func (jd *HandleT) Restore(ctx context.Context, snapshot SnapshotT) error {
+	if snapshot.TablePrefix != jd.tablePrefix {
+		return fmt.Errorf("snapshot %s is of %q, not %q", snapshot.ID, snapshot.TablePrefix, jd.tablePrefix)
+	}
+	start := time.Now()
+	var restored int
+	err := jd.WithStoreSafeTx(ctx, func(tx StoreSafeTx) error {
+		dsList := jd.getDSList()
+		if len(dsList) == 0 {
+			return fmt.Errorf("no dataset to restore snapshot %s into", snapshot.ID)
+		}
+		for _, ds := range dsList {
+			var hasJobs bool
+			if err := tx.Tx().QueryRowContext(ctx, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %q)`, ds.JobTable)).Scan(&hasJobs); err != nil {
+				return err
+			}
+			if hasJobs {
+				return fmt.Errorf("snapshots can only be restored into an empty jobsdb, %q has jobs", ds.JobTable)
+			}
+		}
+		ds := dsList[len(dsList)-1]
+
+		var maxJobID int64
+		for _, file := range snapshot.Files {
+			n, err := jd.restoreSnapshotFile(ctx, tx.Tx(), ds, file)
+			if err != nil {
+				return fmt.Errorf("restoring %q: %w", file.ObjectName, err)
+			}
+			restored += n
+			if file.MaxJobID > maxJobID {
+				maxJobID = file.MaxJobID
+			}
+		}
+		if maxJobID == 0 {
+			return nil
+		}
+		// jobs stored after the restored ones get greater ids
+		var sequence string
+		if err := tx.Tx().QueryRowContext(ctx, `SELECT pg_get_serial_sequence($1, 'job_id')`, fmt.Sprintf("%q", ds.JobTable)).Scan(&sequence); err != nil {
+			return err
+		}
+		if _, err := tx.Tx().ExecContext(ctx, fmt.Sprintf(`SELECT setval('%[1]s', GREATEST($1, (SELECT last_value FROM %[1]s)))`, sequence), maxJobID); err != nil {
+			return err
+		}
+		tx.Tx().AddSuccessListener(func() {
+			jd.dropDSFromCache(ds)
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	jd.logger.Infof("[JobsDB] :: Restored %d jobs of snapshot %s", restored, snapshot.ID)
+	stats.Default.NewTaggedStat("jobsdb_restore_time", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix}).Since(start)
+	return nil
+}
+
+// restoreSnapshotFile downloads a file of a snapshot and stores its jobs in a dataset, returning the number of jobs restored
+func (jd *HandleT) restoreSnapshotFile(ctx context.Context, tx *Tx, ds dataSetT, file SnapshotFileT) (int, error) {
+	fm, err := jd.fileUploaderProvider.GetFileManager(file.WorkspaceID)
+	if err != nil {
+		return 0, err
+	}
+	tmpFile, err := os.CreateTemp("", "jobsdb-snapshot-*.json.gz")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+	if err := fm.Download(ctx, tmpFile, file.ObjectName); err != nil {
+		return 0, fmt.Errorf("downloading: %w", err)
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	gzReader, err := gzip.NewReader(tmpFile)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = gzReader.Close() }()
+
+	var (
+		restored int
+		batch    []*spilledJobT
+	)
+	reader := bufio.NewReader(gzReader)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var job spilledJobT
+			if err := json.Unmarshal(line, &job); err != nil {
+				return restored, fmt.Errorf("parsing job: %w", err)
+			}
+			batch = append(batch, &job)
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return restored, err
+		}
+		if len(batch) > 0 && (len(batch) >= jd.snapshotRestoreBatchSize || errors.Is(err, io.EOF)) {
+			if err := jd.restoreJobsInTx(ctx, tx, ds, batch); err != nil {
+				return restored, err
+			}
+			restored += len(batch)
+			batch = batch[:0]
+		}
+		if errors.Is(err, io.EOF) {
+			return restored, nil
+		}
+	}
+}
+
+// restoreJobsInTx stores jobs of a snapshot with their ids, along with their last status
+func (jd *HandleT) restoreJobsInTx(ctx context.Context, tx *Tx, ds dataSetT, snapshotJobs []*spilledJobT) error {
+	jobs := make([]*JobT, 0, len(snapshotJobs))
+	for _, snapshotJob := range snapshotJobs {
+		job := snapshotJob.toJob()
+		jobs = append(jobs, &job)
+	}
+	if err := jd.prepareStoreInTx(ctx, tx, ds, jobs); err != nil {
+		return err
+	}
+	payloads, err := jd.payloadValues(ctx, tx, ds, jobs)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(ds.JobTable, "job_id", "uuid", "user_id", "custom_val", "parameters", "event_payload", "event_count", "workspace_id", "created_at", "expire_at"))
+	if err != nil {
+		return err
+	}
+	for i, job := range jobs {
+		if _, err := stmt.ExecContext(ctx, job.JobID, job.UUID, job.UserID, job.CustomVal, string(job.Parameters), payloads[i], job.EventCount, job.WorkspaceId, job.CreatedAt, job.ExpireAt); err != nil {
+			_ = stmt.Close()
+			return err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+
+	stmt, err = tx.PrepareContext(ctx, pq.CopyIn(ds.JobStatusTable, "job_id", "job_state", "attempt", "exec_time", "retry_time", "error_code", "error_response", "parameters"))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+	for _, snapshotJob := range snapshotJobs {
+		for _, status := range restoredJobStatuses(snapshotJob) {
+			if _, err := stmt.ExecContext(ctx, status.JobID, status.JobState, status.AttemptNum, status.ExecTime, status.RetryTime, status.ErrorCode, jsonObject(status.ErrorResponse), jsonObject(status.Parameters)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
+
+// restoredJobStatuses returns the statuses of a job of a snapshot to be restored, in progress ones being failed and
+// migrating ones dropped, since the node that took the snapshot isn't processing them anymore
+func restoredJobStatuses(snapshotJob *spilledJobT) []JobStatusT {
+	statuses := snapshotJob.jobStatuses()
+	restored := statuses[:0]
+	for _, status := range statuses {
+		switch status.JobState {
+		case Executing.State, Importing.State:
+			status.JobState = Failed.State
+		case Migrating.State:
+			continue
+		}
+		restored = append(restored, status)
+	}
+	return restored
+}
+
+// jsonObject returns a JSON object, empty if it is null
+func jsonObject(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "{}"
+	}
+	return string(raw)
+}
@@ -0,0 +1,104 @@
+package jobsdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/testhelper/destination"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	_ = startPostgres(t)
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+	minioResource, err := destination.SetupMINIO(pool, t)
+	require.NoError(t, err)
+	t.Setenv("RUDDER_TMPDIR", t.TempDir())
+
+	provider := fileuploader.NewStaticProvider(map[string]fileuploader.StorageSettings{
+		defaultWorkspaceID: {
+			Bucket: backendconfig.StorageBucket{
+				Type: "MINIO",
+				Config: map[string]interface{}{
+					"bucketName":      minioResource.BucketName,
+					"endPoint":        minioResource.Endpoint,
+					"accessKeyID":     minioResource.AccessKey,
+					"secretAccessKey": minioResource.SecretKey,
+				},
+			},
+		},
+	})
+	tablePrefix := strings.ToLower(rand.String(5))
+	jobDB := HandleT{}
+	require.NoError(t, jobDB.Setup(ReadWrite, true, tablePrefix, true, []prebackup.Handler{}, provider))
+
+	customVal := rand.String(5)
+	jobs := genJobs(defaultWorkspaceID, customVal, 10, 1)
+	require.NoError(t, jobDB.Store(context.Background(), jobs))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[:3], Succeeded.State), []string{customVal}, []ParameterFilterT{}))
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[3:5], Failed.State), []string{customVal}, []ParameterFilterT{}))
+
+	snapshot, err := jobDB.Snapshot(context.Background())
+	require.NoError(t, err)
+	require.Len(t, snapshot.Files, 1)
+	require.Equal(t, 7, snapshot.Files[0].JobCount, "only in-flight jobs are in snapshots")
+	require.EqualValues(t, 4, snapshot.Files[0].MinJobID)
+	require.EqualValues(t, 10, snapshot.Files[0].MaxJobID)
+	require.ErrorContains(t, jobDB.Restore(context.Background(), snapshot), "empty jobsdb")
+	jobDB.TearDown()
+
+	// a fresh jobsdb
+	restoredDB := HandleT{}
+	require.NoError(t, restoredDB.Setup(ReadWrite, true, tablePrefix, true, []prebackup.Handler{}, provider))
+	defer restoredDB.TearDown()
+	require.NoError(t, restoredDB.Restore(context.Background(), snapshot))
+
+	toRetry, err := restoredDB.GetToRetry(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: 100})
+	require.NoError(t, err)
+	require.Len(t, toRetry.Jobs, 2)
+	require.EqualValues(t, 4, toRetry.Jobs[0].JobID)
+	unprocessed, err := restoredDB.GetUnprocessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: 100})
+	require.NoError(t, err)
+	require.Len(t, unprocessed.Jobs, 5)
+	require.EqualValues(t, 6, unprocessed.Jobs[0].JobID)
+	require.Equal(t, jobs[5].UUID, unprocessed.Jobs[0].UUID)
+
+	// jobs stored after restoring get greater ids
+	require.NoError(t, restoredDB.Store(context.Background(), genJobs(defaultWorkspaceID, customVal, 1, 1)))
+	unprocessed, err = restoredDB.GetUnprocessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: 100})
+	require.NoError(t, err)
+	require.Len(t, unprocessed.Jobs, 6)
+	require.EqualValues(t, 11, unprocessed.Jobs[5].JobID)
+}
+
+func TestRestoredJobStatuses(t *testing.T) {
+	statuses := func(states ...string) []spilledJobStatusT {
+		var statuses []spilledJobStatusT
+		for _, state := range states {
+			statuses = append(statuses, spilledJobStatusT{JobState: state, AttemptNum: 2})
+		}
+		return statuses
+	}
+	restoredStates := func(job *spilledJobT) []string {
+		var states []string
+		for _, status := range restoredJobStatuses(job) {
+			require.Equal(t, 2, status.AttemptNum)
+			states = append(states, status.JobState)
+		}
+		return states
+	}
+
+	require.Equal(t, []string{Failed.State}, restoredStates(&spilledJobT{Statuses: statuses(Executing.State)}))
+	require.Equal(t, []string{Failed.State}, restoredStates(&spilledJobT{Statuses: statuses(Importing.State)}))
+	require.Equal(t, []string{Waiting.State}, restoredStates(&spilledJobT{Statuses: statuses(Waiting.State)}))
+	require.Empty(t, restoredStates(&spilledJobT{Statuses: statuses(Migrating.State)}), "migrating jobs are restored unprocessed")
+	require.Empty(t, restoredStates(&spilledJobT{}))
+}
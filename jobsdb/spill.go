@@ -0,0 +1,464 @@
+package jobsdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb/internal/lock"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Datasets whose jobs have all been processed, but which are retained because of JobsDB.minDSRetention, can be spilled
+// to object storage to keep Postgres small. The jobs of a spilled dataset are uploaded along with their statuses,
+// to a gzipped JSON lines file per workspace, recorded in the <prefix>_spilled_datasets table, and the dataset is then
+// handled as a migrated one, i.e. dropped or backed up. Spilled jobs can still be looked up by the readonly jobsdb.
+
+// spilledJobT is a job of a spilled dataset, along with its statuses
+type spilledJobT struct {
+	JobID        int64               `json:"job_id"`
+	WorkspaceID  string              `json:"workspace_id"`
+	UUID         uuid.UUID           `json:"uuid"`
+	UserID       string              `json:"user_id"`
+	Parameters   json.RawMessage     `json:"parameters"`
+	CustomVal    string              `json:"custom_val"`
+	EventPayload json.RawMessage     `json:"event_payload"`
+	EventCount   int                 `json:"event_count"`
+	CreatedAt    time.Time           `json:"created_at"`
+	ExpireAt     time.Time           `json:"expire_at"`
+	Statuses     []spilledJobStatusT `json:"statuses"`
+}
+
+type spilledJobStatusT struct {
+	JobState      string          `json:"job_state"`
+	AttemptNum    int             `json:"attempt"`
+	ExecTime      time.Time       `json:"exec_time"`
+	RetryTime     time.Time       `json:"retry_time"`
+	ErrorCode     string          `json:"error_code"`
+	ErrorResponse json.RawMessage `json:"error_response"`
+	Parameters    json.RawMessage `json:"parameters"`
+}
+
+func (job *spilledJobT) toJob() JobT {
+	j := JobT{
+		JobID:        job.JobID,
+		UUID:         job.UUID,
+		UserID:       job.UserID,
+		CreatedAt:    job.CreatedAt,
+		ExpireAt:     job.ExpireAt,
+		CustomVal:    job.CustomVal,
+		EventCount:   job.EventCount,
+		EventPayload: job.EventPayload,
+		Parameters:   job.Parameters,
+		WorkspaceId:  job.WorkspaceID,
+	}
+	if statuses := job.jobStatuses(); len(statuses) > 0 {
+		j.LastJobStatus = statuses[len(statuses)-1]
+	}
+	return j
+}
+
+func (job *spilledJobT) jobStatuses() []JobStatusT {
+	statuses := make([]JobStatusT, 0, len(job.Statuses))
+	for _, status := range job.Statuses {
+		statuses = append(statuses, JobStatusT{
+			JobID:         job.JobID,
+			JobState:      status.JobState,
+			AttemptNum:    status.AttemptNum,
+			ExecTime:      status.ExecTime,
+			RetryTime:     status.RetryTime,
+			ErrorCode:     status.ErrorCode,
+			ErrorResponse: status.ErrorResponse,
+			Parameters:    status.Parameters,
+			WorkspaceId:   job.WorkspaceID,
+		})
+	}
+	return statuses
+}
+
+func (jd *HandleT) startSpillDSLoop(ctx context.Context) {
+	jd.backgroundGroup.Go(misc.WithBugsnag(func() error {
+		jd.spillDSLoop(ctx)
+		return nil
+	}))
+}
+
+func (jd *HandleT) spillDSLoop(ctx context.Context) {
+	for {
+		select {
+		case <-jd.TriggerSpillDS():
+		case <-ctx.Done():
+			return
+		}
+		if !jd.spillEnabled {
+			continue
+		}
+		start := time.Now()
+		timeoutCtx, cancel := context.WithTimeout(ctx, jd.spillDSTimeout)
+		err := jd.doSpillDS(timeoutCtx)
+		cancel()
+		if err != nil {
+			jd.logger.Errorf("Failed to spill ds: %v", err)
+		}
+		stats.Default.NewTaggedStat("spill_loop", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix, "error": strconv.FormatBool(err != nil)}).Since(start)
+	}
+}
+
+// doSpillDS spills the oldest dataset eligible for it, if any
+func (jd *HandleT) doSpillDS(ctx context.Context) error {
+	if !jd.dsListLock.RTryLockWithCtx(ctx) {
+		return fmt.Errorf("could not acquire a dslist read lock: %w", ctx.Err())
+	}
+	dsList := jd.getDSList()
+	jd.dsListLock.RUnlock()
+
+	var (
+		spillDS dataSetT
+		found   bool
+	)
+	for idx, ds := range dsList {
+		// the last datasets are never spilled, the same way as they are never migrated
+		if idx == len(dsList)-1 || (jd.ownerType == Read && idx == len(dsList)-2) {
+			break
+		}
+		spillable, err := jd.isSpillableDS(ctx, ds)
+		if err != nil {
+			return err
+		}
+		if spillable {
+			spillDS, found = ds, true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	jd.logger.Infof("[[ spillDSLoop ]]: Spilling %v", spillDS)
+	start := time.Now()
+	spilled, err := jd.uploadSpilledDS(ctx, spillDS)
+	if err != nil {
+		return fmt.Errorf("uploading dataset %q: %w", spillDS.JobTable, err)
+	}
+
+	var l lock.LockToken
+	var lockChan chan<- lock.LockToken
+	err = jd.WithTx(func(tx *Tx) error {
+		return jd.withDistributedSharedLock(ctx, tx, "schema_migrate", func() error {
+			if !jd.dsMigrationLock.TryLockWithCtx(ctx) {
+				return fmt.Errorf("failed to acquire lock: %w", ctx.Err())
+			}
+			defer jd.dsMigrationLock.Unlock()
+
+			// the dataset may have been migrated in the meantime
+			var exists bool
+			for _, ds := range getDSList(jd, tx, jd.tablePrefix) {
+				exists = exists || ds == spillDS
+			}
+			if !exists {
+				jd.logger.Infof("[[ spillDSLoop ]]: %v is gone, not spilling it", spillDS)
+				return nil
+			}
+			for _, s := range spilled {
+				if _, err := tx.ExecContext(ctx,
+					fmt.Sprintf(`INSERT INTO %q (job_table, workspace_id, min_job_id, max_job_id, object_name) VALUES ($1, $2, $3, $4, $5)
+						ON CONFLICT (job_table, workspace_id) DO UPDATE SET min_job_id = EXCLUDED.min_job_id, max_job_id = EXCLUDED.max_job_id, object_name = EXCLUDED.object_name, spilled_at = NOW()`,
+						jd.spilledDatasetsTable()),
+					spillDS.JobTable, s.workspaceID, s.minJobID, s.maxJobID, s.objectName,
+				); err != nil {
+					return err
+				}
+			}
+
+			// acquire an async lock, as this needs to be released after the transaction commits
+			var err error
+			l, lockChan, err = jd.dsListLock.AsyncLockWithCtx(ctx)
+			if err != nil {
+				return err
+			}
+			return jd.postMigrateHandleDS(tx, []dataSetT{spillDS})
+		})
+	})
+	if l != nil {
+		if err == nil {
+			jd.refreshDSRangeList(l)
+		}
+		lockChan <- l
+	}
+	if err == nil {
+		stats.Default.NewTaggedStat("jobsdb_spilled_datasets", stats.CountType, stats.Tags{"customVal": jd.tablePrefix}).Increment()
+		stats.Default.NewTaggedStat("jobsdb_spill_ds_time", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix}).Since(start)
+	}
+	return err
+}
+
+// isSpillableDS tells whether all the jobs of a dataset have been processed, while it is still retained because of JobsDB.minDSRetention
+func (jd *HandleT) isSpillableDS(ctx context.Context, ds dataSetT) (bool, error) {
+	if jd.MinDSRetentionPeriod <= 0 {
+		return false, nil
+	}
+	var (
+		maxCreatedAt  sql.NullTime
+		unprocessed   bool
+		sqlStatement  = fmt.Sprintf(`SELECT MAX(created_at) FROM %q`, ds.JobTable)
+		terminalQuery = fmt.Sprintf(`SELECT EXISTS (
+			SELECT jobs.job_id FROM %[1]q jobs LEFT JOIN "v_last_%[2]s" job_latest_state ON jobs.job_id = job_latest_state.job_id
+			WHERE job_latest_state.job_id IS NULL OR NOT job_latest_state.job_state = ANY($1))`, ds.JobTable, ds.JobStatusTable)
+	)
+	if err := jd.dbHandle.QueryRowContext(ctx, sqlStatement).Scan(&maxCreatedAt); err != nil {
+		return false, err
+	}
+	// empty datasets and the ones out of their retention period are handled by migrations
+	if !maxCreatedAt.Valid || time.Since(maxCreatedAt.Time) >= jd.MinDSRetentionPeriod {
+		return false, nil
+	}
+	if err := jd.dbHandle.QueryRowContext(ctx, terminalQuery, pq.Array(validTerminalStates)).Scan(&unprocessed); err != nil {
+		return false, err
+	}
+	return !unprocessed, nil
+}
+
+// spilledFileT is the file the jobs of a workspace in a spilled dataset are uploaded to
+type spilledFileT struct {
+	workspaceID        string
+	minJobID, maxJobID int64
+	path, objectName   string
+}
+
+// uploadSpilledDS uploads the jobs of a dataset along with their statuses, to a file per workspace
+func (jd *HandleT) uploadSpilledDS(ctx context.Context, ds dataSetT) ([]*spilledFileT, error) {
+	compressed, err := jd.isCompressedDS(ctx, jd.dbHandle, ds)
+	if err != nil {
+		return nil, err
+	}
+	tmpDirPath, err := misc.CreateTMPDIR()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*spilledFileT)
+	defer func() {
+		for _, file := range files {
+			_ = os.Remove(file.path)
+		}
+	}()
+
+	writer := fileuploader.NewGzMultiFileWriter()
+	query := fmt.Sprintf(`SELECT jobs.job_id, jobs.workspace_id, jsonb_build_object(
+			'job_id', jobs.job_id,
+			'workspace_id', jobs.workspace_id,
+			'uuid', jobs.uuid,
+			'user_id', jobs.user_id,
+			'parameters', jobs.parameters,
+			'custom_val', jobs.custom_val,
+			'event_payload', %[3]s,
+			'event_count', jobs.event_count,
+			'created_at', jobs.created_at,
+			'expire_at', jobs.expire_at,
+			'statuses', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+				'job_state', st.job_state,
+				'attempt', st.attempt,
+				'exec_time', st.exec_time,
+				'retry_time', st.retry_time,
+				'error_code', st.error_code,
+				'error_response', st.error_response,
+				'parameters', st.parameters
+			) ORDER BY st.id) FROM %[2]q st WHERE st.job_id = jobs.job_id), '[]'::jsonb))
+		FROM %[1]q jobs WHERE jobs.job_id > $1 ORDER BY jobs.job_id LIMIT $2`,
+		ds.JobTable, ds.JobStatusTable, backupPayloadColumn("jobs", compressed))
+
+	var afterJobID int64
+	writeSpilledJobs := func() (int, error) {
+		rows, err := jd.dbHandle.QueryContext(ctx, query, afterJobID, backupRowsBatchSize)
+		if err != nil {
+			return 0, err
+		}
+		defer func() { _ = rows.Close() }()
+
+		var count int
+		for rows.Next() {
+			var (
+				jobID       int64
+				workspaceID string
+				row         json.RawMessage
+			)
+			if err := rows.Scan(&jobID, &workspaceID, &row); err != nil {
+				return count, err
+			}
+			if compressed {
+				if row, err = decompressBackupPayload(row); err != nil {
+					return count, err
+				}
+			}
+			file, ok := files[workspaceID]
+			if !ok {
+				file = &spilledFileT{
+					workspaceID: workspaceID,
+					minJobID:    jobID,
+					path:        filepath.Join(tmpDirPath, "rudder-spilled-datasets", fmt.Sprintf("%s.%s.json.gz", ds.JobTable, workspaceID)),
+				}
+				files[workspaceID] = file
+			}
+			file.maxJobID = jobID
+			if _, err := writer.Write(file.path, append(row, '\n')); err != nil {
+				return count, fmt.Errorf("writing gz file %q: %w", file.path, err)
+			}
+			afterJobID = jobID
+			count++
+		}
+		return count, rows.Err()
+	}
+	for {
+		count, err := writeSpilledJobs()
+		if err != nil {
+			_ = writer.Close()
+			return nil, err
+		}
+		if int64(count) < backupRowsBatchSize {
+			break
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	spilled := make([]*spilledFileT, 0, len(files))
+	for _, file := range files {
+		if file.objectName, err = jd.uploadSpilledFile(ctx, file); err != nil {
+			return nil, err
+		}
+		spilled = append(spilled, file)
+	}
+	return spilled, nil
+}
+
+func (jd *HandleT) uploadSpilledFile(ctx context.Context, file *spilledFileT) (string, error) {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return "", fmt.Errorf("opening gz file %q: %w", file.path, err)
+	}
+	defer func() { _ = f.Close() }()
+	output, err := jd.backupUploadWithExponentialBackoff(ctx, f, file.workspaceID, jd.spillPathPrefix, jd.tablePrefix, config.GetString("INSTANCE_ID", "1"))
+	if err != nil {
+		return "", err
+	}
+	jd.logger.Infof("[JobsDB] :: Spilled jobs of workspace %s at %v", file.workspaceID, output.Location)
+	return output.ObjectName, nil
+}
+
+func (jd *HandleT) spilledDatasetsTable() string {
+	return jd.tablePrefix + "_spilled_datasets"
+}
+
+func (jd *HandleT) dropSpilledDatasetsTable() {
+	_, err := jd.dbHandle.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %q`, jd.spilledDatasetsTable()))
+	jd.assertError(err)
+}
+
+var errSpilledJobNotFound = errors.New("job not found in spilled datasets")
+
+// getSpilledJob looks up a job in the datasets spilled to object storage, downloading the file of the spilled dataset it belongs to
+func getSpilledJob(ctx context.Context, dbHandle *sql.DB, provider fileuploader.Provider, tablePrefix string, jobID int64) (*spilledJobT, error) {
+	if provider == nil {
+		return nil, errSpilledJobNotFound
+	}
+	rows, err := dbHandle.QueryContext(ctx,
+		fmt.Sprintf(`SELECT workspace_id, object_name FROM %q WHERE min_job_id <= $1 AND max_job_id >= $1`, tablePrefix+"_spilled_datasets"),
+		jobID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	type location struct{ workspaceID, objectName string }
+	var locations []location
+	for rows.Next() {
+		var l location
+		if err := rows.Scan(&l.workspaceID, &l.objectName); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		locations = append(locations, l)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, l := range locations {
+		fm, err := provider.GetFileManager(l.workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		job, err := findSpilledJob(ctx, fm, l.objectName, jobID)
+		if errors.Is(err, errSpilledJobNotFound) {
+			continue
+		}
+		return job, err
+	}
+	return nil, errSpilledJobNotFound
+}
+
+// findSpilledJob downloads a spilled dataset's file and scans it for a job
+func findSpilledJob(ctx context.Context, fm filemanager.FileManager, objectName string, jobID int64) (*spilledJobT, error) {
+	tmpFile, err := os.CreateTemp("", "spilled-ds-*.json.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+	if err := fm.Download(ctx, tmpFile, objectName); err != nil {
+		return nil, fmt.Errorf("downloading %q: %w", objectName, err)
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return scanSpilledJobs(tmpFile, jobID)
+}
+
+// scanSpilledJobs reads a gzipped spilled dataset's file, looking for a job
+func scanSpilledJobs(r io.Reader, jobID int64) (*spilledJobT, error) {
+	gzReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gzReader.Close() }()
+	reader := bufio.NewReader(gzReader)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var job spilledJobT
+			if err := json.Unmarshal(line, &job); err != nil {
+				return nil, fmt.Errorf("parsing spilled job: %w", err)
+			}
+			if job.JobID == jobID {
+				return &job, nil
+			}
+			if job.JobID > jobID { // jobs are written in order
+				return nil, errSpilledJobNotFound
+			}
+		}
+		if err == io.EOF {
+			return nil, errSpilledJobNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package jobsdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanSpilledJobs(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	spilledJob := func(jobID int64) spilledJobT {
+		return spilledJobT{
+			JobID:        jobID,
+			WorkspaceID:  defaultWorkspaceID,
+			UUID:         uuid.New(),
+			UserID:       "user",
+			Parameters:   json.RawMessage(`{"source_id":"source"}`),
+			CustomVal:    "GW",
+			EventPayload: json.RawMessage(`{"batch":[]}`),
+			EventCount:   1,
+			CreatedAt:    now,
+			ExpireAt:     now,
+			Statuses: []spilledJobStatusT{
+				{JobState: Failed.State, AttemptNum: 1, ExecTime: now, RetryTime: now, ErrorCode: "500", ErrorResponse: json.RawMessage(`{}`), Parameters: json.RawMessage(`{}`)},
+				{JobState: Succeeded.State, AttemptNum: 2, ExecTime: now, RetryTime: now, ErrorCode: "200", ErrorResponse: json.RawMessage(`{}`), Parameters: json.RawMessage(`{}`)},
+			},
+		}
+	}
+	jobs := []spilledJobT{spilledJob(1), spilledJob(2), spilledJob(4)}
+
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	for _, job := range jobs {
+		line, err := json.Marshal(job)
+		require.NoError(t, err)
+		_, err = gzWriter.Write(append(line, '\n'))
+		require.NoError(t, err)
+	}
+	require.NoError(t, gzWriter.Close())
+	file := buf.Bytes()
+
+	t.Run("job found", func(t *testing.T) {
+		job, err := scanSpilledJobs(bytes.NewReader(file), 2)
+		require.NoError(t, err)
+		require.Equal(t, jobs[1], *job)
+
+		j := job.toJob()
+		require.EqualValues(t, 2, j.JobID)
+		require.Equal(t, jobs[1].UUID, j.UUID)
+		require.Equal(t, defaultWorkspaceID, j.WorkspaceId)
+		require.JSONEq(t, `{"batch":[]}`, string(j.EventPayload))
+		require.Equal(t, Succeeded.State, j.LastJobStatus.JobState)
+		require.Equal(t, 2, j.LastJobStatus.AttemptNum)
+
+		statuses := job.jobStatuses()
+		require.Len(t, statuses, 2)
+		require.Equal(t, Failed.State, statuses[0].JobState)
+		require.EqualValues(t, 2, statuses[0].JobID)
+		require.Equal(t, "500", statuses[0].ErrorCode)
+	})
+
+	t.Run("job not found", func(t *testing.T) {
+		for _, jobID := range []int64{3, 5} {
+			_, err := scanSpilledJobs(bytes.NewReader(file), jobID)
+			require.ErrorIs(t, err, errSpilledJobNotFound)
+		}
+	})
+
+	t.Run("corrupted file", func(t *testing.T) {
+		_, err := scanSpilledJobs(bytes.NewReader([]byte("not gzipped")), 1)
+		require.Error(t, err)
+	})
+}
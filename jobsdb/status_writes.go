@@ -0,0 +1,171 @@
+package jobsdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Job statuses are written with COPY, which pays off for large lists of statuses only: smaller ones, up to
+// JobsDB.<prefix>.statusWrites.copyThreshold statuses, are written with a single multi-row statement instead. Jobs are
+// always stored with COPY.
+//
+// Status updates of concurrent UpdateJobStatus calls can also be batched into a single transaction, sharing its commit,
+// by setting JobsDB.<prefix>.statusWrites.batchLatency: a batch is written once it holds
+// JobsDB.<prefix>.statusWrites.maxBatchSize statuses or once its first update waited for the latency, whichever comes
+// first. An update failing a batch doesn't fail the other ones, which get written on their own.
+
+type statusUpdateRequest struct {
+	statusList       []*JobStatusT
+	customValFilters []string
+	parameterFilters []ParameterFilterT
+	done             chan error
+}
+
+type statusBatcherT struct {
+	requests chan *statusUpdateRequest
+	// stopped is closed once the batcher stops accepting updates
+	stopped chan struct{}
+}
+
+func (jd *HandleT) startStatusBatchLoop(ctx context.Context) {
+	batcher := &statusBatcherT{
+		requests: make(chan *statusUpdateRequest),
+		stopped:  make(chan struct{}),
+	}
+	jd.statusBatcher = batcher
+	jd.backgroundGroup.Go(misc.WithBugsnag(func() error {
+		jd.statusBatchLoop(ctx, batcher)
+		return nil
+	}))
+}
+
+// statusBatchLoop collects the status updates sent to the batcher and writes them in batches
+func (jd *HandleT) statusBatchLoop(ctx context.Context, batcher *statusBatcherT) {
+	defer close(batcher.stopped)
+	for {
+		var batch []*statusUpdateRequest
+		select {
+		case req := <-batcher.requests:
+			batch = append(batch, req)
+		case <-ctx.Done():
+			return
+		}
+		size := len(batch[0].statusList)
+		latency := time.After(jd.statusBatchLatency)
+	collect:
+		for size < jd.statusBatchMaxSize {
+			select {
+			case req := <-batcher.requests:
+				batch = append(batch, req)
+				size += len(req.statusList)
+			case <-latency:
+				break collect
+			case <-ctx.Done():
+				break collect
+			}
+		}
+		jd.writeStatusBatch(ctx, batch, size)
+	}
+}
+
+// writeStatusBatch writes the status updates of a batch in a single transaction, falling back to writing each one on
+// its own if the batch fails
+func (jd *HandleT) writeStatusBatch(ctx context.Context, batch []*statusUpdateRequest, size int) {
+	tags := stats.Tags{"customVal": jd.tablePrefix}
+	stats.Default.NewTaggedStat("jobsdb_status_batch_updates", stats.HistogramType, tags).Observe(float64(len(batch)))
+	stats.Default.NewTaggedStat("jobsdb_status_batch_statuses", stats.HistogramType, tags).Observe(float64(size))
+
+	err := jd.WithUpdateSafeTx(ctx, func(tx UpdateSafeTx) error {
+		for _, req := range batch {
+			if err := jd.UpdateJobStatusInTx(ctx, tx, req.statusList, req.customValFilters, req.parameterFilters); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && len(batch) > 1 && ctx.Err() == nil {
+		jd.logger.Warnf("Failed to write a batch of %d status updates, writing them one by one: %v", len(batch), err)
+		for _, req := range batch {
+			req.done <- jd.WithUpdateSafeTx(ctx, func(tx UpdateSafeTx) error {
+				return jd.UpdateJobStatusInTx(ctx, tx, req.statusList, req.customValFilters, req.parameterFilters)
+			})
+		}
+		return
+	}
+	for _, req := range batch {
+		req.done <- err
+	}
+}
+
+// batchUpdateJobStatus sends a status update to the batcher and waits for it to be written. Once sent, an update is
+// waited for regardless of the context, so that it is never reported as failed while getting written.
+func (jd *HandleT) batchUpdateJobStatus(ctx context.Context, statusList []*JobStatusT, customValFilters []string, parameterFilters []ParameterFilterT) error {
+	req := &statusUpdateRequest{
+		statusList:       statusList,
+		customValFilters: customValFilters,
+		parameterFilters: parameterFilters,
+		done:             make(chan error, 1),
+	}
+	select {
+	case jd.statusBatcher.requests <- req:
+	case <-jd.statusBatcher.stopped:
+		return fmt.Errorf("jobsdb %q is stopped", jd.tablePrefix)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-req.done
+}
+
+// insertStatusesInTx writes a list of statuses to a dataset with a single multi-row statement
+func (*HandleT) insertStatusesInTx(ctx context.Context, tx *Tx, ds dataSetT, statusList []*JobStatusT) error {
+	var (
+		jobIDs         = make([]int64, len(statusList))
+		jobStates      = make([]string, len(statusList))
+		attempts       = make([]int64, len(statusList))
+		execTimes      = make([]string, len(statusList))
+		retryTimes     = make([]string, len(statusList))
+		errorCodes     = make([]string, len(statusList))
+		errorResponses = make([]string, len(statusList))
+		parameters     = make([]string, len(statusList))
+	)
+	for i, status := range statusList {
+		jobIDs[i] = status.JobID
+		jobStates[i] = status.JobState
+		attempts[i] = int64(status.AttemptNum)
+		execTimes[i] = status.ExecTime.Format(time.RFC3339Nano)
+		retryTimes[i] = status.RetryTime.Format(time.RFC3339Nano)
+		errorCodes[i] = status.ErrorCode
+		errorResponses[i] = string(status.ErrorResponse)
+		parameters[i] = string(status.Parameters)
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %q (job_id, job_state, attempt, exec_time, retry_time, error_code, error_response, parameters)
+		SELECT * FROM UNNEST($1::BIGINT[], $2::VARCHAR(64)[], $3::SMALLINT[], $4::TIMESTAMPTZ[], $5::TIMESTAMPTZ[], $6::VARCHAR(32)[], $7::JSONB[], $8::JSONB[])`, ds.JobStatusTable),
+		pq.Array(jobIDs), pq.Array(jobStates), pq.Array(attempts), pq.Array(execTimes), pq.Array(retryTimes),
+		pq.Array(errorCodes), pq.Array(errorResponses), pq.Array(parameters),
+	)
+	return err
+}
+
+// copyStatusesInTx writes a list of statuses to a dataset with COPY
+func (*HandleT) copyStatusesInTx(ctx context.Context, tx *Tx, ds dataSetT, statusList []*JobStatusT) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(ds.JobStatusTable, "job_id", "job_state", "attempt", "exec_time",
+		"retry_time", "error_code", "error_response", "parameters"))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+	for _, status := range statusList {
+		if _, err = stmt.ExecContext(ctx, status.JobID, status.JobState, status.AttemptNum, status.ExecTime,
+			status.RetryTime, status.ErrorCode, string(status.ErrorResponse), string(status.Parameters)); err != nil {
+			return err
+		}
+	}
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
@@ -0,0 +1,136 @@
+package jobsdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+func TestStatusWrites(t *testing.T) {
+	_ = startPostgres(t)
+
+	jobDB := HandleT{}
+	require.NoError(t, jobDB.Setup(ReadWrite, true, strings.ToLower(rand.String(5)), true, []prebackup.Handler{}, fileuploader.NewDefaultProvider()))
+	defer jobDB.TearDown()
+
+	requireStates := func(customVal string, expected map[string]int) {
+		t.Helper()
+		for state, count := range expected {
+			processed, err := jobDB.GetProcessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, StateFilters: []string{state}, JobsLimit: 1000})
+			require.NoError(t, err)
+			require.Len(t, processed.Jobs, count, state)
+		}
+	}
+
+	t.Run("multi-row statements and COPY", func(t *testing.T) {
+		customVal := rand.String(5)
+		jobs := genJobs(defaultWorkspaceID, customVal, 20, 1)
+		require.NoError(t, jobDB.Store(context.Background(), jobs))
+
+		jobDB.statusCopyThreshold = 10
+		// the statuses generated carry invalid json, which gets sanitized either way
+		require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[:5], Failed.State), []string{customVal}, []ParameterFilterT{}))
+		require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs[5:], Succeeded.State), []string{customVal}, []ParameterFilterT{}))
+		requireStates(customVal, map[string]int{Failed.State: 5, Succeeded.State: 15})
+
+		statuses := genJobStatuses(jobs[:5], Aborted.State)
+		for _, status := range statuses {
+			status.ErrorResponse = []byte(`{"reason": "aborted"}`)
+			status.Parameters = []byte(`{}`)
+		}
+		require.NoError(t, jobDB.UpdateJobStatus(context.Background(), statuses, []string{customVal}, []ParameterFilterT{}))
+		requireStates(customVal, map[string]int{Failed.State: 0, Aborted.State: 5})
+		aborted, err := jobDB.GetProcessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, StateFilters: []string{Aborted.State}, JobsLimit: 1})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"reason": "aborted"}`, string(aborted.Jobs[0].LastJobStatus.ErrorResponse))
+		require.Equal(t, 1, aborted.Jobs[0].LastJobStatus.AttemptNum)
+	})
+
+	t.Run("batched updates", func(t *testing.T) {
+		customVal := rand.String(5)
+		jobs := genJobs(defaultWorkspaceID, customVal, 100, 1)
+		require.NoError(t, jobDB.Store(context.Background(), jobs))
+		unprocessed, err := jobDB.GetUnprocessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: 1000})
+		require.NoError(t, err)
+		require.Len(t, unprocessed.Jobs, 100)
+
+		jobDB.statusBatchLatency = 50 * time.Millisecond
+		defer func() { jobDB.statusBatchLatency = 0 }()
+		g, ctx := errgroup.WithContext(context.Background())
+		for i := 0; i < 10; i++ {
+			chunk := unprocessed.Jobs[i*10 : (i+1)*10]
+			g.Go(func() error {
+				return jobDB.UpdateJobStatus(ctx, genJobStatuses(chunk, Succeeded.State), []string{customVal}, []ParameterFilterT{})
+			})
+		}
+		require.NoError(t, g.Wait())
+		requireStates(customVal, map[string]int{Succeeded.State: 100})
+	})
+}
+
+// BenchmarkUpdateJobStatus compares writing statuses with COPY, with multi-row statements and with batched updates
+func BenchmarkUpdateJobStatus(b *testing.B) {
+	const (
+		// concurrency is the number of concurrent status updates
+		concurrency = 16
+		// pageSize is the number of statuses per update
+		pageSize = 20
+	)
+	_ = startPostgres(b)
+
+	jobDB := HandleT{}
+	require.NoError(b, jobDB.Setup(ReadWrite, true, strings.ToLower(rand.String(5)), true, []prebackup.Handler{}, fileuploader.NewDefaultProvider()))
+	defer jobDB.TearDown()
+	customVal := rand.String(5)
+	jobs := genJobs(defaultWorkspaceID, customVal, 1000, 1)
+	require.NoError(b, jobDB.Store(context.Background(), jobs))
+	unprocessed, err := jobDB.GetUnprocessed(context.Background(), GetQueryParamsT{CustomValFilters: []string{customVal}, JobsLimit: len(jobs)})
+	require.NoError(b, err)
+	jobList := unprocessed.Jobs
+
+	for _, mode := range []struct {
+		name          string
+		copyThreshold int
+		batchLatency  time.Duration
+	}{
+		{name: "copy", copyThreshold: 0},
+		{name: "multi-row", copyThreshold: pageSize},
+		{name: "batched multi-row", copyThreshold: pageSize, batchLatency: 5 * time.Millisecond},
+	} {
+		b.Run(fmt.Sprintf("%s, %d streams of %d statuses", mode.name, concurrency, pageSize), func(b *testing.B) {
+			jobDB.statusCopyThreshold = mode.copyThreshold
+			jobDB.statusBatchLatency = mode.batchLatency
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			wg.Add(concurrency)
+			for i := 0; i < concurrency; i++ {
+				go func() {
+					defer wg.Done()
+					for n := 0; n < b.N; n++ {
+						offset := (n * pageSize) % (len(jobList) - pageSize)
+						statuses := genJobStatuses(jobList[offset:offset+pageSize], Executing.State)
+						for _, status := range statuses {
+							status.ErrorResponse = []byte(`{}`)
+							status.Parameters = []byte(`{}`)
+						}
+						if err := jobDB.UpdateJobStatus(context.Background(), statuses, []string{customVal}, []ParameterFilterT{}); err != nil {
+							b.Error(err)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
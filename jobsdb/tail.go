@@ -0,0 +1,201 @@
+package jobsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The status transitions of jobs can be tailed live, for watching how the jobs of a destination or a source get
+// processed: GET /jobsdb/<prefix>/tail on the admin interface streams the statuses written as server-sent events, for
+// a duration of at most JobsDB.tail.maxDuration, e.g.
+//
+//	curl -N --unix-socket <tmp dir>/rudder-server.sock 'http://rudder/jobsdb/rt/tail?destinationId=<id>&duration=5m'
+//
+// Statuses are only published while someone is tailing. The sources and destinations of their jobs are looked up by
+// the tailing request, and statuses it doesn't consume fast enough are dropped, so that tailing never slows down
+// status updates.
+
+// TailEventT is a status transition of a job, as streamed to the ones tailing a jobsdb
+type TailEventT struct {
+	JobID         int64           `json:"jobId"`
+	JobState      string          `json:"jobState"`
+	AttemptNum    int             `json:"attempt"`
+	ExecTime      time.Time       `json:"execTime"`
+	ErrorCode     string          `json:"errorCode"`
+	ErrorResponse json.RawMessage `json:"errorResponse"`
+	WorkspaceID   string          `json:"workspaceId"`
+	CustomVal     string          `json:"customVal"`
+	SourceID      string          `json:"sourceId"`
+	DestinationID string          `json:"destinationId"`
+}
+
+// tailBatchT are the statuses written to a dataset by a status update
+type tailBatchT struct {
+	ds     dataSetT
+	events []TailEventT
+}
+
+type tailSubscriberT struct {
+	batches chan tailBatchT
+	dropped int64
+}
+
+// statusTailT publishes the statuses written to the ones tailing a jobsdb
+type statusTailT struct {
+	mu          sync.RWMutex
+	subscribers map[*tailSubscriberT]struct{}
+}
+
+func (t *statusTailT) active() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.subscribers) > 0
+}
+
+func (t *statusTailT) subscribe() *tailSubscriberT {
+	s := &tailSubscriberT{batches: make(chan tailBatchT, 100)}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.subscribers == nil {
+		t.subscribers = make(map[*tailSubscriberT]struct{})
+	}
+	t.subscribers[s] = struct{}{}
+	return s
+}
+
+func (t *statusTailT) unsubscribe(s *tailSubscriberT) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.subscribers, s)
+}
+
+// publish hands the statuses written to a dataset over to the subscribers, dropping them for the ones lagging behind
+func (t *statusTailT) publish(ds dataSetT, statusList []*JobStatusT) {
+	events := make([]TailEventT, len(statusList))
+	for i, status := range statusList {
+		events[i] = TailEventT{
+			JobID:         status.JobID,
+			JobState:      status.JobState,
+			AttemptNum:    status.AttemptNum,
+			ExecTime:      status.ExecTime,
+			ErrorCode:     status.ErrorCode,
+			ErrorResponse: status.ErrorResponse,
+			WorkspaceID:   status.WorkspaceId,
+		}
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for s := range t.subscribers {
+		select {
+		case s.batches <- tailBatchT{ds: ds, events: events}:
+		default:
+			atomic.AddInt64(&s.dropped, int64(len(events)))
+		}
+	}
+}
+
+// tailHandler streams the statuses written, optionally filtered by source and destination, as server-sent events
+func (jd *HandleT) tailHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	query := r.URL.Query()
+	sourceID, destinationID := query.Get("sourceId"), query.Get("destinationId")
+	duration := time.Minute
+	if d := query.Get("duration"); d != "" {
+		var err error
+		if duration, err = time.ParseDuration(d); err != nil || duration <= 0 {
+			http.Error(w, fmt.Sprintf("invalid duration %q", d), http.StatusBadRequest)
+			return
+		}
+	}
+	if duration > jd.tailMaxDuration {
+		duration = jd.tailMaxDuration
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), duration)
+	defer cancel()
+
+	subscriber := jd.statusTail.subscribe()
+	defer jd.statusTail.unsubscribe(subscriber)
+	jd.logger.Infof("Tailing statuses for %v (source: %q, destination: %q)", duration, sourceID, destinationID)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-ctx.Done():
+			_, _ = fmt.Fprintf(w, "event: end\ndata: {\"dropped\": %d}\n\n", atomic.LoadInt64(&subscriber.dropped))
+			flusher.Flush()
+			return
+		case batch := <-subscriber.batches:
+			events, err := jd.tailEvents(ctx, batch, sourceID, destinationID)
+			if err != nil {
+				// the dataset may have been migrated in the meantime
+				jd.logger.Debugf("Failed to look up the jobs of tailed statuses: %v", err)
+				atomic.AddInt64(&subscriber.dropped, int64(len(batch.events)))
+				continue
+			}
+			for _, event := range events {
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// tailEvents returns the tailed statuses of a batch whose jobs match a source and a destination, if given
+func (jd *HandleT) tailEvents(ctx context.Context, batch tailBatchT, sourceID, destinationID string) ([]TailEventT, error) {
+	jobIDs := make([]int64, len(batch.events))
+	for i := range batch.events {
+		jobIDs[i] = batch.events[i].JobID
+	}
+	rows, err := jd.dbHandle.QueryContext(ctx, fmt.Sprintf(`SELECT job_id, custom_val, COALESCE(parameters->>'source_id', ''), COALESCE(parameters->>'destination_id', '')
+		FROM %q WHERE job_id = ANY($1) AND ($2 = '' OR parameters->>'source_id' = $2) AND ($3 = '' OR parameters->>'destination_id' = $3)`, batch.ds.JobTable),
+		pq.Array(jobIDs), sourceID, destinationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	type jobT struct{ customVal, sourceID, destinationID string }
+	jobs := make(map[int64]jobT)
+	for rows.Next() {
+		var jobID int64
+		var job jobT
+		if err := rows.Scan(&jobID, &job.customVal, &job.sourceID, &job.destinationID); err != nil {
+			return nil, err
+		}
+		jobs[jobID] = job
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var events []TailEventT
+	for _, event := range batch.events {
+		job, ok := jobs[event.JobID]
+		if !ok {
+			continue
+		}
+		event.CustomVal, event.SourceID, event.DestinationID = job.customVal, job.sourceID, job.destinationID
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,89 @@
+package jobsdb
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/jobsdb/prebackup"
+	fileuploader "github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+func TestStatusTailPublish(t *testing.T) {
+	var tail statusTailT
+	require.False(t, tail.active())
+
+	subscriber := tail.subscribe()
+	require.True(t, tail.active())
+	ds := dataSetT{JobTable: "rt_jobs_1", JobStatusTable: "rt_job_status_1", Index: "1"}
+	for i := 0; i < cap(subscriber.batches)+1; i++ {
+		tail.publish(ds, []*JobStatusT{{JobID: int64(i), JobState: Succeeded.State}})
+	}
+	require.EqualValues(t, 1, subscriber.dropped, "statuses are dropped for lagging subscribers")
+	batch := <-subscriber.batches
+	require.Equal(t, ds, batch.ds)
+	require.Equal(t, []TailEventT{{JobID: 0, JobState: Succeeded.State}}, batch.events)
+
+	tail.unsubscribe(subscriber)
+	require.False(t, tail.active())
+}
+
+func TestTailHandler(t *testing.T) {
+	_ = startPostgres(t)
+	jobDB := HandleT{}
+	require.NoError(t, jobDB.Setup(ReadWrite, true, strings.ToLower(rand.String(5)), true, []prebackup.Handler{}, fileuploader.NewDefaultProvider()))
+	defer jobDB.TearDown()
+	jobDB.tailMaxDuration = time.Minute
+
+	customVal := rand.String(5)
+	jobs := genJobs(defaultWorkspaceID, customVal, 4, 1)
+	for i, job := range jobs {
+		job.Parameters = []byte(fmt.Sprintf(`{"source_id": "source", "destination_id": "destination-%d"}`, i%2))
+	}
+	require.NoError(t, jobDB.Store(context.Background(), jobs))
+
+	server := httptest.NewServer(http.HandlerFunc(jobDB.tailHandler))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "?destinationId=destination-1&duration=2s")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Eventually(t, jobDB.statusTail.active, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, jobDB.UpdateJobStatus(context.Background(), genJobStatuses(jobs, Executing.State), []string{customVal}, []ParameterFilterT{}))
+
+	var events []TailEventT
+	var ended bool
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "event: end" {
+			ended = true
+			break
+		}
+		if data := strings.TrimPrefix(line, "data: "); data != line {
+			var event TailEventT
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+			events = append(events, event)
+		}
+	}
+	require.True(t, ended, "the stream ends after its duration")
+	require.Len(t, events, 2, "only statuses of jobs of the destination are streamed")
+	for _, event := range events {
+		require.Equal(t, "destination-1", event.DestinationID)
+		require.Equal(t, "source", event.SourceID)
+		require.Equal(t, Executing.State, event.JobState)
+		require.Equal(t, customVal, event.CustomVal)
+	}
+	require.Eventually(t, func() bool { return !jobDB.statusTail.active() }, 5*time.Second, 10*time.Millisecond)
+}
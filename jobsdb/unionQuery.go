@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
 )
 
 type MultiTenantHandleT struct {
@@ -121,7 +122,7 @@ func (mj *MultiTenantHandleT) GetAllJobs(ctx context.Context, pickup map[string]
 	outJobs := make([]*JobT, 0)
 
 	var tablesQueried int
-	params.StateFilters = []string{NotProcessed.State, Waiting.State, Failed.State}
+	params.StateFilters = []string{NotProcessed.State, Waiting.State, Failed.State, Paused.State}
 	conditions := QueryConditions{
 		IgnoreCustomValFiltersInQuery: params.IgnoreCustomValFiltersInQuery,
 		CustomValFilters:              params.CustomValFilters,
@@ -240,6 +241,9 @@ func (mj *MultiTenantHandleT) getUnionDS(ctx context.Context, ds dataSetT, picku
 		if err != nil {
 			return jobList, err
 		}
+		if job.EventPayload, err = decompressPayload(job.EventPayload); err != nil {
+			return jobList, err
+		}
 
 		job.LastJobStatus = JobStatusT{}
 		if _nullJS.Valid {
@@ -334,6 +338,10 @@ func (*MultiTenantHandleT) getInitialSingleWorkspaceQueryString(ds dataSetT, con
 
 	if len(stateFilters) > 0 {
 		stateQuery = "AND (" + constructStateQuery("job_latest_state", "job_state", stateFilters, "OR") + ")"
+		if misc.Contains(stateFilters, Paused.State) {
+			// paused jobs are only picked up once resumed, i.e. their retry time is reached
+			stateQuery += fmt.Sprintf(" AND (job_latest_state.job_state IS DISTINCT FROM '%s' OR job_latest_state.retry_time <= NOW())", Paused.State)
+		}
 	} else {
 		stateQuery = ""
 	}
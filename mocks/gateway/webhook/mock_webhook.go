@@ -34,6 +34,20 @@ func (m *MockGatewayI) EXPECT() *MockGatewayIMockRecorder {
 	return m.recorder
 }
 
+// GetWebhookSecret mocks base method.
+func (m *MockGatewayI) GetWebhookSecret(arg0 string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookSecret", arg0)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetWebhookSecret indicates an expected call of GetWebhookSecret.
+func (mr *MockGatewayIMockRecorder) GetWebhookSecret(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookSecret", reflect.TypeOf((*MockGatewayI)(nil).GetWebhookSecret), arg0)
+}
+
 // GetWebhookSourceDefName mocks base method.
 func (m *MockGatewayI) GetWebhookSourceDefName(arg0 string) (string, bool) {
 	m.ctrl.T.Helper()
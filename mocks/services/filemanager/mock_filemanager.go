@@ -6,6 +6,7 @@ package mock_filemanager
 
 import (
 	context "context"
+	io "io"
 	os "os"
 	reflect "reflect"
 	time "time"
@@ -75,6 +76,21 @@ func (m *MockFileManager) EXPECT() *MockFileManagerMockRecorder {
 	return m.recorder
 }
 
+// Copy mocks base method.
+func (m *MockFileManager) Copy(arg0 context.Context, arg1, arg2, arg3 string) (filemanager.UploadOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Copy", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(filemanager.UploadOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Copy indicates an expected call of Copy.
+func (mr *MockFileManagerMockRecorder) Copy(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Copy", reflect.TypeOf((*MockFileManager)(nil).Copy), arg0, arg1, arg2, arg3)
+}
+
 // DeleteObjects mocks base method.
 func (m *MockFileManager) DeleteObjects(arg0 context.Context, arg1 []string) error {
 	m.ctrl.T.Helper()
@@ -103,6 +119,20 @@ func (mr *MockFileManagerMockRecorder) Download(arg0, arg1, arg2 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Download", reflect.TypeOf((*MockFileManager)(nil).Download), arg0, arg1, arg2)
 }
 
+// DownloadRange mocks base method.
+func (m *MockFileManager) DownloadRange(arg0 context.Context, arg1 io.Writer, arg2 string, arg3 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DownloadRange", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DownloadRange indicates an expected call of DownloadRange.
+func (mr *MockFileManagerMockRecorder) DownloadRange(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadRange", reflect.TypeOf((*MockFileManager)(nil).DownloadRange), arg0, arg1, arg2, arg3)
+}
+
 // GetConfiguredPrefix mocks base method.
 func (m *MockFileManager) GetConfiguredPrefix() string {
 	m.ctrl.T.Helper()
@@ -131,6 +161,21 @@ func (mr *MockFileManagerMockRecorder) GetDownloadKeyFromFileLocation(arg0 inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDownloadKeyFromFileLocation", reflect.TypeOf((*MockFileManager)(nil).GetDownloadKeyFromFileLocation), arg0)
 }
 
+// GetObjectInfo mocks base method.
+func (m *MockFileManager) GetObjectInfo(arg0 context.Context, arg1 string) (filemanager.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetObjectInfo", arg0, arg1)
+	ret0, _ := ret[0].(filemanager.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetObjectInfo indicates an expected call of GetObjectInfo.
+func (mr *MockFileManagerMockRecorder) GetObjectInfo(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObjectInfo", reflect.TypeOf((*MockFileManager)(nil).GetObjectInfo), arg0, arg1)
+}
+
 // GetObjectNameFromLocation mocks base method.
 func (m *MockFileManager) GetObjectNameFromLocation(arg0 string) (string, error) {
 	m.ctrl.T.Helper()
@@ -146,6 +191,21 @@ func (mr *MockFileManagerMockRecorder) GetObjectNameFromLocation(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObjectNameFromLocation", reflect.TypeOf((*MockFileManager)(nil).GetObjectNameFromLocation), arg0)
 }
 
+// GetPresignedURL mocks base method.
+func (m *MockFileManager) GetPresignedURL(arg0 context.Context, arg1 string, arg2 time.Duration) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPresignedURL", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPresignedURL indicates an expected call of GetPresignedURL.
+func (mr *MockFileManagerMockRecorder) GetPresignedURL(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPresignedURL", reflect.TypeOf((*MockFileManager)(nil).GetPresignedURL), arg0, arg1, arg2)
+}
+
 // ListFilesWithPrefix mocks base method.
 func (m *MockFileManager) ListFilesWithPrefix(arg0 context.Context, arg1, arg2 string, arg3 int64) ([]*filemanager.FileObject, error) {
 	m.ctrl.T.Helper()
@@ -192,3 +252,18 @@ func (mr *MockFileManagerMockRecorder) Upload(arg0, arg1 interface{}, arg2 ...in
 	varargs := append([]interface{}{arg0, arg1}, arg2...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockFileManager)(nil).Upload), varargs...)
 }
+
+// UploadReader mocks base method.
+func (m *MockFileManager) UploadReader(arg0 context.Context, arg1 string, arg2 io.Reader) (filemanager.UploadOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadReader", arg0, arg1, arg2)
+	ret0, _ := ret[0].(filemanager.UploadOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadReader indicates an expected call of UploadReader.
+func (mr *MockFileManagerMockRecorder) UploadReader(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadReader", reflect.TypeOf((*MockFileManager)(nil).UploadReader), arg0, arg1, arg2)
+}
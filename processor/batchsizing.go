@@ -0,0 +1,171 @@
+package processor
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// With adaptive batch sizing, the number of events a pipeline reads per loop is adapted to the load of the transformer
+// and to the backlog of gateway jobs, within configured bounds:
+//   - it is decreased when the transformations of the batches read take longer than the target latency, relieving an
+//     overloaded transformer
+//   - it is increased when the jobs read are older than the lag threshold and the last read was full, catching up with
+//     backlogs while the transformer keeps up
+//   - it decays back towards Processor.maxLoopProcessEvents otherwise, once the transformer and the backlog allow it
+//
+// Otherwise, the number of events read per loop is Processor.maxLoopProcessEvents. Enabling adaptive batch sizing and
+// its maximum number of events aren't hot reloadable, since the buffers of the pipelines are sized by them.
+
+var (
+	adaptiveBatchSizeEnabled bool
+	adaptiveMinEvents        int
+	adaptiveMaxEvents        int
+	adaptiveTargetLatency    time.Duration
+	adaptiveLagThreshold     time.Duration
+	adaptiveDecreaseFactor   float64
+	adaptiveIncreaseFactor   float64
+	adaptiveDecayFactor      float64
+)
+
+func loadBatchSizingConfig() {
+	config.RegisterBoolConfigVariable(false, &adaptiveBatchSizeEnabled, false, "Processor.adaptiveBatchSize.enabled")
+	config.RegisterIntConfigVariable(1000, &adaptiveMinEvents, true, 1, "Processor.adaptiveBatchSize.minEvents")
+	config.RegisterIntConfigVariable(50000, &adaptiveMaxEvents, false, 1, "Processor.adaptiveBatchSize.maxEvents")
+	config.RegisterDurationConfigVariable(10, &adaptiveTargetLatency, true, time.Second, "Processor.adaptiveBatchSize.targetLatency")
+	config.RegisterDurationConfigVariable(60, &adaptiveLagThreshold, true, time.Second, "Processor.adaptiveBatchSize.lagThreshold")
+	config.RegisterFloat64ConfigVariable(0.5, &adaptiveDecreaseFactor, true, "Processor.adaptiveBatchSize.decreaseFactor")
+	config.RegisterFloat64ConfigVariable(1.5, &adaptiveIncreaseFactor, true, "Processor.adaptiveBatchSize.increaseFactor")
+	config.RegisterFloat64ConfigVariable(0.1, &adaptiveDecayFactor, true, "Processor.adaptiveBatchSize.decayFactor")
+}
+
+// batchSizer adapts the number of events a pipeline reads per loop
+type batchSizer struct {
+	mu      sync.Mutex
+	size    int
+	latency time.Duration // moving average of the transformation latency of batches
+	// a batch was transformed since the size was last decreased, so that the size isn't decreased again for the
+	// latency of a batch read with the previous size
+	transformed bool
+
+	sizeGauge     stats.Measurement
+	increaseCount stats.Measurement
+	decreaseCount stats.Measurement
+	lagGauge      stats.Measurement
+}
+
+// newBatchSizer returns the batch sizer of the pipeline of the given source, if any
+func (proc *HandleT) newBatchSizer(sourceID string) *batchSizer {
+	tags := stats.Tags{"source": sourceID}
+	if sourceID == "" {
+		tags["source"] = "all"
+	}
+	newStat := func(name, statType string, extraTags stats.Tags) stats.Measurement {
+		statTags := stats.Tags{}
+		for k, v := range tags {
+			statTags[k] = v
+		}
+		for k, v := range extraTags {
+			statTags[k] = v
+		}
+		return proc.statsFactory.NewTaggedStat(name, statType, statTags)
+	}
+	return &batchSizer{
+		size:          maxEventsToProcess,
+		sizeGauge:     newStat("processor.adaptive_batch_size", stats.GaugeType, nil),
+		increaseCount: newStat("processor.adaptive_batch_size_changes", stats.CountType, stats.Tags{"direction": "increase"}),
+		decreaseCount: newStat("processor.adaptive_batch_size_changes", stats.CountType, stats.Tags{"direction": "decrease"}),
+		lagGauge:      newStat("processor.read_lag_seconds", stats.GaugeType, nil),
+	}
+}
+
+// next returns the number of events to read in the next loop
+func (b *batchSizer) next() int {
+	if !adaptiveBatchSizeEnabled {
+		return maxEventsToProcess
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.size = b.bounded(b.size)
+	return b.size
+}
+
+// maxSize returns the maximum number of events to read per loop
+func (b *batchSizer) maxSize() int {
+	if !adaptiveBatchSizeEnabled {
+		return maxEventsToProcess
+	}
+	return b.bounded(math.MaxInt32)
+}
+
+// bounded returns size within the configured bounds
+func (*batchSizer) bounded(size int) int {
+	if size < adaptiveMinEvents {
+		return adaptiveMinEvents
+	}
+	if size > adaptiveMaxEvents {
+		return adaptiveMaxEvents
+	}
+	return size
+}
+
+// observeTransform records the time the transformations of the sub-jobs of a batch took
+func (b *batchSizer) observeTransform(latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.transformed = true
+	if b.latency == 0 {
+		b.latency = latency
+		return
+	}
+	// exponentially weighted moving average, favouring recent batches
+	b.latency = time.Duration(0.3*float64(latency) + 0.7*float64(b.latency))
+}
+
+// observeRead adapts the number of events to read after a loop reading the given jobs
+func (b *batchSizer) observeRead(jobs jobsdb.JobsResult) {
+	var lag time.Duration
+	if len(jobs.Jobs) > 0 {
+		lag = time.Since(jobs.Jobs[0].CreatedAt)
+	}
+	b.lagGauge.Gauge(lag.Seconds())
+	if !adaptiveBatchSizeEnabled {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	size := b.size
+	overloaded := b.latency > adaptiveTargetLatency
+	backlog := lag > adaptiveLagThreshold && jobs.LimitsReached
+	switch {
+	case overloaded && b.transformed:
+		b.transformed = false
+		size = int(math.Floor(float64(size) * adaptiveDecreaseFactor))
+	case !overloaded && backlog:
+		size = int(math.Ceil(float64(size) * adaptiveIncreaseFactor))
+	case !overloaded && !backlog:
+		size = decayed(size, maxEventsToProcess)
+	}
+	size = b.bounded(size)
+	if size > b.size {
+		b.increaseCount.Count(1)
+	} else if size < b.size {
+		b.decreaseCount.Count(1)
+	}
+	b.size = size
+	b.sizeGauge.Gauge(size)
+}
+
+// decayed returns size moved towards base by the decay factor of their difference, base once they're close enough
+func decayed(size, base int) int {
+	step := int(math.Round(float64(base-size) * adaptiveDecayFactor))
+	if step == 0 {
+		return base
+	}
+	return size + step
+}
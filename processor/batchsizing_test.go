@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+)
+
+func TestBatchSizer(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+	loadBatchSizingConfig()
+	prevMaxEventsToProcess := maxEventsToProcess
+	maxEventsToProcess = 10000
+	defer func() { maxEventsToProcess = prevMaxEventsToProcess }()
+
+	store := memstats.New()
+	proc := &HandleT{statsFactory: store}
+	oldJobs := jobsdb.JobsResult{Jobs: []*jobsdb.JobT{{CreatedAt: time.Now().Add(-time.Hour)}}, LimitsReached: true}
+	recentJobs := jobsdb.JobsResult{Jobs: []*jobsdb.JobT{{CreatedAt: time.Now()}}, LimitsReached: true}
+
+	t.Run("disabled", func(t *testing.T) {
+		sizer := proc.newBatchSizer("")
+		sizer.observeTransform(time.Minute)
+		sizer.observeRead(oldJobs)
+		require.Equal(t, 10000, sizer.next())
+		require.InDelta(t, 3600, store.Get("processor.read_lag_seconds", stats.Tags{"source": "all"}).LastValue(), 5)
+	})
+
+	config.Set("Processor.adaptiveBatchSize.enabled", true)
+	config.Set("Processor.adaptiveBatchSize.minEvents", 2000)
+	config.Set("Processor.adaptiveBatchSize.maxEvents", 20000)
+	config.Set("Processor.adaptiveBatchSize.targetLatency", "1s")
+	config.Set("Processor.adaptiveBatchSize.lagThreshold", "1m")
+	loadBatchSizingConfig()
+
+	t.Run("increases with backlogs", func(t *testing.T) {
+		sizer := proc.newBatchSizer("source")
+		require.Equal(t, 10000, sizer.next())
+		sizer.observeRead(recentJobs)
+		require.Equal(t, 10000, sizer.next(), "recent jobs are no backlog")
+		sizer.observeRead(jobsdb.JobsResult{Jobs: oldJobs.Jobs})
+		require.Equal(t, 10000, sizer.next(), "reads below the limit are no backlog")
+
+		sizer.observeRead(oldJobs)
+		require.Equal(t, 15000, sizer.next())
+		sizer.observeRead(oldJobs)
+		require.Equal(t, 20000, sizer.next(), "bounded by the max")
+		require.EqualValues(t, 20000, store.Get("processor.adaptive_batch_size", stats.Tags{"source": "source"}).LastValue())
+		require.EqualValues(t, 2, store.Get("processor.adaptive_batch_size_changes", stats.Tags{"source": "source", "direction": "increase"}).LastValue())
+	})
+
+	t.Run("decreases with transformer latency", func(t *testing.T) {
+		sizer := proc.newBatchSizer("")
+		sizer.observeTransform(5 * time.Second)
+		sizer.observeRead(oldJobs)
+		require.Equal(t, 5000, sizer.next(), "latency takes precedence over backlogs")
+		sizer.observeRead(oldJobs)
+		require.Equal(t, 5000, sizer.next(), "not decreased again before a sub-job of the new size is transformed")
+		sizer.observeTransform(5 * time.Second)
+		sizer.observeRead(oldJobs)
+		require.Equal(t, 2500, sizer.next())
+		sizer.observeTransform(5 * time.Second)
+		sizer.observeRead(oldJobs)
+		require.Equal(t, 2000, sizer.next(), "bounded by the min")
+
+		for i := 0; i < 10; i++ {
+			sizer.observeTransform(100 * time.Millisecond)
+		}
+		sizer.observeRead(oldJobs)
+		require.Equal(t, 3000, sizer.next(), "increased again once the latency drops")
+	})
+	t.Run("decays towards the default without load nor backlog", func(t *testing.T) {
+		sizer := proc.newBatchSizer("")
+		sizer.observeRead(oldJobs)
+		require.Equal(t, 15000, sizer.next())
+		sizer.observeRead(recentJobs)
+		require.Equal(t, 14500, sizer.next())
+
+		sizer = proc.newBatchSizer("")
+		sizer.observeTransform(5 * time.Second)
+		sizer.observeRead(recentJobs)
+		require.Equal(t, 5000, sizer.next())
+		sizer.observeRead(recentJobs)
+		require.Equal(t, 5000, sizer.next(), "not while the transformer is overloaded")
+		for i := 0; i < 10; i++ {
+			sizer.observeTransform(100 * time.Millisecond)
+		}
+		sizer.observeRead(recentJobs)
+		require.Equal(t, 5500, sizer.next())
+
+		config.Set("Processor.adaptiveBatchSize.decayFactor", 1)
+		defer config.Set("Processor.adaptiveBatchSize.decayFactor", 0.1)
+		sizer.observeRead(recentJobs)
+		require.Equal(t, 10000, sizer.next())
+	})
+}
@@ -0,0 +1,254 @@
+package processor
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/processor/transformationdlq"
+	"github.com/rudderlabs/rudder-server/processor/transformer"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Events failing user transformations are added to a dead-letter queue, along with the error and the version of the
+// transformation they failed with. Once the transformation is fixed, they can be reprocessed through the
+// TransformationDLQ.Reprocess admin RPC, e.g.
+//
+//	{"transformationId": "...", "destinationId": "...", "limit": 1000}
+//
+// which stores the events failing versions other than the current one back into the gateway jobsdb, to be processed
+// again for the destinations they failed for only.
+
+const (
+	// reprocessDestinationParam is the parameter of gateway jobs of reprocessed events, restricting them to a destination
+	reprocessDestinationParam = "reprocess_destination_id"
+	// replayDestinationsParam is the parameter of gateway jobs of events replayed from archived gateway dumps,
+	// restricting them to the given destinations
+	replayDestinationsParam = "replay_destination_ids"
+)
+
+// restrictedDestinationIDs returns the destinations the events of a gateway job with the given parameters are
+// restricted to, if any
+func restrictedDestinationIDs(parameters []byte) []string {
+	params := gjson.ParseBytes(parameters)
+	if reprocessDestID := params.Get(reprocessDestinationParam).Str; reprocessDestID != "" {
+		return []string{reprocessDestID}
+	}
+	var destIDs []string
+	for _, destID := range params.Get(replayDestinationsParam).Array() {
+		if destID.Str != "" {
+			destIDs = append(destIDs, destID.Str)
+		}
+	}
+	return destIDs
+}
+
+// setupTransformationDLQ sets up the dead-letter queue of the events failing user transformations
+func (proc *HandleT) setupTransformationDLQ() {
+	db, err := sql.Open("postgres", misc.GetConnectionString())
+	if err != nil {
+		panic(fmt.Errorf("opening the transformation dead-letter queue database: %w", err))
+	}
+	dlq, err := transformationdlq.New(context.Background(), db, proc.logger.Child("transformationdlq"))
+	if err != nil {
+		panic(err)
+	}
+	proc.transformationDLQ = dlq
+}
+
+// withTransformation adds the transformation the given jobs of events failing user transformations failed with to
+// their parameters
+func withTransformation(failedJobs []*jobsdb.JobT, destination *backendconfig.DestinationT) {
+	if len(destination.Transformations) == 0 {
+		return
+	}
+	transformation := destination.Transformations[0]
+	for _, job := range failedJobs {
+		job.Parameters, _ = sjson.SetBytes(job.Parameters, "transformation_id", transformation.ID)
+		job.Parameters, _ = sjson.SetBytes(job.Parameters, "transformation_version_id", transformation.VersionID)
+	}
+}
+
+// addToTransformationDLQ adds the proc error jobs of events failing user transformations to the dead-letter queue
+func (proc *HandleT) addToTransformationDLQ(procErrorJobs []*jobsdb.JobT) {
+	var entries []transformationdlq.EntryT
+	for _, job := range procErrorJobs {
+		params := gjson.ParseBytes(job.Parameters)
+		if params.Get("stage").Str != transformer.UserTransformerStage || params.Get("transformation_id").Str == "" {
+			continue
+		}
+		entries = append(entries, transformationdlq.EntryT{
+			WorkspaceID:             job.WorkspaceId,
+			SourceID:                params.Get("source_id").Str,
+			DestinationID:           params.Get("destination_id").Str,
+			TransformationID:        params.Get("transformation_id").Str,
+			TransformationVersionID: params.Get("transformation_version_id").Str,
+			UserID:                  job.UserID,
+			Payload:                 job.EventPayload,
+			Error:                   params.Get("error").Str,
+			StatusCode:              int(params.Get("status_code").Int()),
+		})
+	}
+	if len(entries) == 0 {
+		return
+	}
+	err := misc.RetryWithNotify(context.Background(), proc.jobsDBCommandTimeout, proc.jobdDBMaxRetries, func(ctx context.Context) error {
+		return proc.transformationDLQ.Add(ctx, entries)
+	}, sendRetryStoreStats)
+	if err != nil {
+		// the events are still in the proc error jobsdb
+		proc.logger.Errorf("Failed to add %d entries to the transformation dead-letter queue: %v", len(entries), err)
+		return
+	}
+	proc.statsFactory.NewStat("processor.transformation_dlq_added", stats.CountType).Count(len(entries))
+}
+
+// getTransformationVersionID returns the current version of the given transformation
+func getTransformationVersionID(transformationID string) (string, bool) {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	for _, destinations := range writeKeyDestinationMap {
+		for i := range destinations {
+			for _, transformation := range destinations[i].Transformations {
+				if transformation.ID == transformationID {
+					return transformation.VersionID, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func getWriteKeysBySourceID() map[string]string {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	writeKeys := make(map[string]string, len(writeKeySourceMap))
+	for writeKey := range writeKeySourceMap {
+		writeKeys[writeKeySourceMap[writeKey].ID] = writeKey
+	}
+	return writeKeys
+}
+
+type ReprocessRequestT struct {
+	TransformationID string `json:"transformationId"`
+	DestinationID    string `json:"destinationId"`
+	Limit            int    `json:"limit"`
+}
+
+type ReprocessResultT struct {
+	TransformationID string `json:"transformationId"`
+	VersionID        string `json:"versionId"`
+	Reprocessed      int    `json:"reprocessed"`
+	Events           int    `json:"events"`
+	Skipped          int    `json:"skipped"`
+}
+
+// reprocessTransformationFailures stores the events of the dead-letter queue that failed other versions of the
+// transformation than the current one into the gateway jobsdb
+func (proc *HandleT) reprocessTransformationFailures(ctx context.Context, req ReprocessRequestT) (ReprocessResultT, error) {
+	result := ReprocessResultT{TransformationID: req.TransformationID}
+	if req.TransformationID == "" {
+		return result, errors.New("transformationId is required")
+	}
+	if req.Limit <= 0 {
+		req.Limit = config.GetInt("Processor.transformationDLQ.reprocessLimit", 1000)
+	}
+	versionID, ok := getTransformationVersionID(req.TransformationID)
+	if !ok {
+		return result, fmt.Errorf("transformation %q not found", req.TransformationID)
+	}
+	result.VersionID = versionID
+
+	entries, err := proc.transformationDLQ.Pending(ctx, req.TransformationID, versionID, req.DestinationID, req.Limit)
+	if err != nil {
+		return result, fmt.Errorf("reading the transformation dead-letter queue: %w", err)
+	}
+	writeKeys := getWriteKeysBySourceID()
+	var jobs []*jobsdb.JobT
+	var ids []int64
+	for i := range entries {
+		entry := &entries[i]
+		writeKey, ok := writeKeys[entry.SourceID]
+		if !ok {
+			// the source was deleted, the entry is kept until it expires
+			result.Skipped++
+			continue
+		}
+		payload, _ := sjson.SetRawBytes([]byte(`{}`), "batch", entry.Payload)
+		payload, _ = sjson.SetBytes(payload, "writeKey", writeKey)
+		payload, _ = sjson.SetBytes(payload, "requestIP", "")
+		payload, _ = sjson.SetBytes(payload, "receivedAt", entry.CreatedAt.Format(misc.RFC3339Milli))
+		params, _ := json.Marshal(map[string]interface{}{
+			"source_id":               entry.SourceID,
+			reprocessDestinationParam: entry.DestinationID,
+		})
+		eventCount := int(gjson.GetBytes(entry.Payload, "#").Int())
+		jobs = append(jobs, &jobsdb.JobT{
+			UUID:         uuid.New(),
+			UserID:       entry.UserID,
+			Parameters:   params,
+			CustomVal:    GWCustomVal,
+			EventPayload: payload,
+			EventCount:   eventCount,
+			WorkspaceId:  entry.WorkspaceID,
+		})
+		ids = append(ids, entry.ID)
+		result.Events += eventCount
+	}
+	if len(jobs) == 0 {
+		return result, nil
+	}
+	err = proc.gatewayDB.WithStoreSafeTx(ctx, func(tx jobsdb.StoreSafeTx) error {
+		if err := proc.gatewayDB.StoreInTx(ctx, tx, jobs); err != nil {
+			return err
+		}
+		return proc.transformationDLQ.MarkReprocessed(ctx, tx.SqlTx(), ids)
+	})
+	if err != nil {
+		return ReprocessResultT{TransformationID: req.TransformationID, VersionID: versionID}, fmt.Errorf("storing the events to reprocess: %w", err)
+	}
+	result.Reprocessed = len(jobs)
+	proc.logger.Infof("Reprocessing %d events of transformation %s failing versions other than %s", result.Events, req.TransformationID, versionID)
+	return result, nil
+}
+
+type TransformationDLQRPCHandler struct {
+	proc *HandleT
+}
+
+// Reprocess reprocesses the events of the transformation dead-letter queue that failed previous versions of a transformation
+func (h *TransformationDLQRPCHandler) Reprocess(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	var req ReprocessRequestT
+	if err := json.Unmarshal([]byte(arg), &req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetDuration("Processor.transformationDLQ.reprocessTimeout", 5, time.Minute))
+	defer cancel()
+	res, err := h.proc.reprocessTransformationFailures(ctx, req)
+	if err != nil {
+		return err
+	}
+	response, err := json.MarshalIndent(res, "", " ")
+	if err != nil {
+		return err
+	}
+	*result = string(response)
+	return nil
+}
@@ -0,0 +1,13 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestrictedDestinationIDs(t *testing.T) {
+	require.Empty(t, restrictedDestinationIDs([]byte(`{"source_id": "source"}`)))
+	require.Equal(t, []string{"destination-1"}, restrictedDestinationIDs([]byte(`{"source_id": "source", "reprocess_destination_id": "destination-1"}`)))
+	require.Equal(t, []string{"destination-1", "destination-2"}, restrictedDestinationIDs([]byte(`{"source_id": "source", "replay_destination_ids": ["destination-1", "", "destination-2"]}`)))
+}
@@ -0,0 +1,163 @@
+package enricher
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+// amountProperties are the properties of events holding amounts in the event's currency
+var amountProperties = []string{"revenue", "total", "subtotal", "value", "price", "tax", "shipping", "discount"}
+
+// currencyEnricher normalizes the amounts of the event's properties, e.g. "$1,299.90" or "1.299,90 €", to numbers in
+// a base currency, leaving the properties as they are. The normalized amounts are set under the properties prefixed
+// with base, e.g. properties.baseRevenue, and under the basePrice of the event's products, along with the upper case
+// ISO 4217 code of the base currency under properties.baseCurrency.
+//
+// The base currency is the configured one, the amounts being converted to it with the rates of currencies to it, or
+// the event's currency if none is configured. Events in a currency without a rate, or already having a base currency,
+// aren't enriched.
+type currencyEnricher struct {
+	baseCurrency string
+	rates        map[string]float64 // the value of a unit of each currency in the base currency
+}
+
+func newCurrencyEnricher(baseCurrency string, rates map[string]interface{}) *currencyEnricher {
+	e := &currencyEnricher{
+		baseCurrency: strings.ToUpper(baseCurrency),
+		rates:        make(map[string]float64, len(rates)),
+	}
+	for currency, rate := range rates {
+		if rate, ok := toAmount(rate); ok && rate > 0 {
+			e.rates[strings.ToUpper(currency)] = rate
+		}
+	}
+	if e.baseCurrency != "" {
+		e.rates[e.baseCurrency] = 1
+	}
+	return e
+}
+
+func (*currencyEnricher) Name() string {
+	return CurrencyEnricher
+}
+
+func (e *currencyEnricher) Enrich(event types.SingularEventT, _ string) {
+	properties, ok := event["properties"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if _, exists := properties["baseCurrency"]; exists {
+		return
+	}
+	currency, _ := properties["currency"].(string)
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return
+	}
+	baseCurrency, rate := e.baseCurrency, 1.0
+	if baseCurrency == "" {
+		baseCurrency = currency
+	} else if rate, ok = e.rates[currency]; !ok {
+		return
+	}
+	toBase := func(amount float64) float64 {
+		return math.Round(amount*rate*100) / 100
+	}
+
+	enriched := false
+	for _, property := range amountProperties {
+		baseProperty := "base" + strings.ToUpper(property[:1]) + property[1:]
+		if _, exists := properties[baseProperty]; exists {
+			continue
+		}
+		if amount, ok := toAmount(properties[property]); ok {
+			properties[baseProperty] = toBase(amount)
+			enriched = true
+		}
+	}
+	if products, ok := properties["products"].([]interface{}); ok {
+		for _, product := range products {
+			product, ok := product.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, exists := product["basePrice"]; exists {
+				continue
+			}
+			if amount, ok := toAmount(product["price"]); ok {
+				product["basePrice"] = toBase(amount)
+				enriched = true
+			}
+		}
+	}
+	if enriched {
+		properties["baseCurrency"] = baseCurrency
+	}
+}
+
+// toAmount converts numbers, and strings of numbers formatted as amounts, to float64
+func toAmount(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		return parseAmount(v)
+	}
+	return 0, false
+}
+
+// parseAmount parses amounts formatted with currency symbols or codes, and either '.' or ',' as the decimal separator,
+// the other one, spaces and apostrophes grouping digits, e.g. "$1,299.90", "1.299,90 €" or "CHF 1'299.90".
+// The last separator is the decimal one, unless it's the only separator of its kind along with others of the same
+// kind, e.g. "1,299,000". Amounts with a single ',' followed by 3 digits, e.g. "1,299", are ambiguous and rejected.
+func parseAmount(s string) (float64, bool) {
+	var decimal rune
+	lastDot, lastComma := strings.LastIndexByte(s, '.'), strings.LastIndexByte(s, ',')
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		decimal = '.'
+		if lastComma > lastDot {
+			decimal = ','
+		}
+	case lastDot >= 0:
+		if strings.Count(s, ".") == 1 {
+			decimal = '.'
+		}
+	case lastComma >= 0:
+		if strings.Count(s, ",") == 1 {
+			if countDigits(s[lastComma+1:]) == 3 {
+				return 0, false
+			}
+			decimal = ','
+		}
+	}
+
+	normalized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '-':
+			return r
+		case r == decimal:
+			return '.'
+		}
+		return -1
+	}, s)
+	amount, err := strconv.ParseFloat(normalized, 64)
+	return amount, err == nil
+}
+
+func countDigits(s string) int {
+	var n int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
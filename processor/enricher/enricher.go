@@ -0,0 +1,134 @@
+package enricher
+
+import (
+	"fmt"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+// Events can be enriched by the processor with built-in enrichers, before being transformed, which spares sources the
+// latency of doing so in user transformations. Enrichers are enabled per source, under the source config's enrichers
+// key, and run in the order they are declared:
+//
+//	"enrichers": ["geo", "userAgent", "currency"]
+//
+// Enrichers never overwrite the values events already have.
+
+// Names of the built-in enrichers
+const (
+	GeoEnricher       = "geo"
+	UserAgentEnricher = "userAgent"
+	CurrencyEnricher  = "currency"
+)
+
+// sourceConfigKey is the key of the source config declaring the enrichers of the source
+const sourceConfigKey = "enrichers"
+
+// Enricher enriches events in place
+type Enricher interface {
+	// Name returns the name sources enable the enricher with
+	Name() string
+	// Enrich enriches an event received from requestIP
+	Enrich(event types.SingularEventT, requestIP string)
+}
+
+// Registry holds the available enrichers
+type Registry struct {
+	logger    logger.Logger
+	enrichers map[string]Enricher
+	closers   []func() error
+}
+
+// New returns a registry of the built-in enrichers. The geo enricher is only available if a MaxMind database is configured.
+func New(log logger.Logger) (*Registry, error) {
+	r := &Registry{logger: log, enrichers: make(map[string]Enricher)}
+	if dbPath := config.GetString("Processor.enrichment.geo.dbPath", ""); dbPath != "" {
+		geo, err := newGeoEnricher(dbPath)
+		if err != nil {
+			return nil, fmt.Errorf("opening the geoip database of the geo enricher: %w", err)
+		}
+		r.Register(geo)
+		r.closers = append(r.closers, geo.close)
+	}
+	r.Register(&userAgentEnricher{})
+	r.Register(newCurrencyEnricher(
+		config.GetString("Processor.enrichment.currency.baseCurrency", ""),
+		config.GetStringMap("Processor.enrichment.currency.rates", nil),
+	))
+	return r, nil
+}
+
+// Register makes an enricher available to sources, replacing any enricher with the same name
+func (r *Registry) Register(enricher Enricher) {
+	r.enrichers[enricher.Name()] = enricher
+}
+
+// ForSource returns the enrichers enabled in the given source config, skipping unavailable ones
+func (r *Registry) ForSource(sourceID string, sourceConfig map[string]interface{}) []Enricher {
+	names, _ := sourceConfig[sourceConfigKey].([]interface{})
+	if len(names) == 0 {
+		return nil
+	}
+	enrichers := make([]Enricher, 0, len(names))
+	for _, name := range names {
+		name, _ := name.(string)
+		enricher, ok := r.enrichers[name]
+		if !ok {
+			r.logger.Debugf("Enricher %q of source %s is not available", name, sourceID)
+			continue
+		}
+		enrichers = append(enrichers, enricher)
+	}
+	return enrichers
+}
+
+// Close releases the resources of the enrichers
+func (r *Registry) Close() error {
+	for _, closer := range r.closers {
+		if err := closer(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enrich runs the given enrichers on an event received from requestIP
+func Enrich(enrichers []Enricher, event types.SingularEventT, requestIP string) {
+	for _, enricher := range enrichers {
+		enricher.Enrich(event, requestIP)
+	}
+}
+
+// contextField returns the object under the given key of the event's context, creating it and the context if missing.
+// It returns false if either isn't an object.
+func contextField(event types.SingularEventT, key string) (map[string]interface{}, bool) {
+	eventContext, ok := event["context"].(map[string]interface{})
+	if !ok {
+		if _, exists := event["context"]; exists {
+			return nil, false
+		}
+		eventContext = make(map[string]interface{})
+		event["context"] = eventContext
+	}
+	field, ok := eventContext[key].(map[string]interface{})
+	if !ok {
+		if _, exists := eventContext[key]; exists {
+			return nil, false
+		}
+		field = make(map[string]interface{})
+		eventContext[key] = field
+	}
+	return field, true
+}
+
+// setMissing sets key to value in m, unless m has a value for key or value is empty
+func setMissing(m map[string]interface{}, key, value string) {
+	if value == "" {
+		return
+	}
+	if _, ok := m[key]; !ok {
+		m[key] = value
+	}
+}
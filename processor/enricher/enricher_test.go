@@ -0,0 +1,212 @@
+package enricher
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/geolocation"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+type staticGeoLocator map[string]geolocation.Location
+
+func (l staticGeoLocator) Locate(ip net.IP) (geolocation.Location, error) {
+	location, ok := l[ip.String()]
+	if !ok {
+		return geolocation.Location{}, errors.New("not found")
+	}
+	return location, nil
+}
+
+func (staticGeoLocator) Close() error { return nil }
+
+func TestRegistry(t *testing.T) {
+	config.Reset()
+	logger.Reset()
+	defer config.Reset()
+
+	r, err := New(logger.NOP)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, r.Close()) }()
+
+	require.Empty(t, r.ForSource("source", map[string]interface{}{}))
+	enrichers := r.ForSource("source", map[string]interface{}{"enrichers": []interface{}{"currency", "geo", "userAgent", "unknown"}})
+	require.Len(t, enrichers, 2, "geo is unavailable without a database")
+	require.Equal(t, CurrencyEnricher, enrichers[0].Name())
+	require.Equal(t, UserAgentEnricher, enrichers[1].Name())
+
+	r.Register(&geoEnricher{locator: staticGeoLocator{}})
+	require.Len(t, r.ForSource("source", map[string]interface{}{"enrichers": []interface{}{"geo"}}), 1)
+}
+
+func TestGeoEnricher(t *testing.T) {
+	e := &geoEnricher{locator: staticGeoLocator{
+		"192.0.2.1":   {Country: "US", Region: "California", City: "San Francisco"},
+		"203.0.113.1": {Country: "DE", Region: "Berlin", City: "Berlin"},
+	}}
+
+	t.Run("request ip", func(t *testing.T) {
+		event := types.SingularEventT{}
+		e.Enrich(event, "192.0.2.1")
+		require.Equal(t, map[string]interface{}{"country": "US", "region": "California", "city": "San Francisco"}, event["context"].(map[string]interface{})["location"])
+	})
+
+	t.Run("context ip takes precedence, existing values are kept", func(t *testing.T) {
+		event := types.SingularEventT{"context": map[string]interface{}{"ip": "203.0.113.1", "location": map[string]interface{}{"city": "Potsdam"}}}
+		e.Enrich(event, "192.0.2.1")
+		require.Equal(t, map[string]interface{}{"country": "DE", "region": "Berlin", "city": "Potsdam"}, event["context"].(map[string]interface{})["location"])
+	})
+
+	t.Run("unknown ip", func(t *testing.T) {
+		event := types.SingularEventT{}
+		e.Enrich(event, "198.51.100.1")
+		e.Enrich(event, "not-an-ip")
+		require.NotContains(t, event, "context")
+	})
+}
+
+func TestUserAgentEnricher(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  userAgentT
+	}{
+		{
+			name:      "chrome on windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36",
+			expected:  userAgentT{browser: "Chrome", browserVersion: "106.0.0.0", os: "Windows", osVersion: "10", device: "desktop"},
+		},
+		{
+			name:      "edge on windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36 Edg/106.0.1370.42",
+			expected:  userAgentT{browser: "Edge", browserVersion: "106.0.1370.42", os: "Windows", osVersion: "10", device: "desktop"},
+		},
+		{
+			name:      "safari on iphone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+			expected:  userAgentT{browser: "Safari", browserVersion: "16.0", os: "iOS", osVersion: "16.0", device: "mobile"},
+		},
+		{
+			name:      "firefox on mac",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:105.0) Gecko/20100101 Firefox/105.0",
+			expected:  userAgentT{browser: "Firefox", browserVersion: "105.0", os: "Mac OS X", osVersion: "10.15", device: "desktop"},
+		},
+		{
+			name:      "chrome on android tablet",
+			userAgent: "Mozilla/5.0 (Linux; Android 12; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36",
+			expected:  userAgentT{browser: "Chrome", browserVersion: "106.0.0.0", os: "Android", osVersion: "12", device: "tablet"},
+		},
+		{
+			name:      "bot",
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			expected:  userAgentT{device: "bot"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, parseUserAgent(tt.userAgent))
+		})
+	}
+
+	t.Run("enrich", func(t *testing.T) {
+		event := types.SingularEventT{"context": map[string]interface{}{
+			"userAgent": tests[2].userAgent,
+			"os":        map[string]interface{}{"name": "iPadOS"},
+		}}
+		(&userAgentEnricher{}).Enrich(event, "")
+		eventContext := event["context"].(map[string]interface{})
+		require.Equal(t, map[string]interface{}{"name": "Safari", "version": "16.0"}, eventContext["browser"])
+		require.Equal(t, map[string]interface{}{"name": "iPadOS", "version": "16.0"}, eventContext["os"])
+		require.Equal(t, map[string]interface{}{"type": "mobile"}, eventContext["device"])
+
+		event = types.SingularEventT{"context": map[string]interface{}{}}
+		(&userAgentEnricher{}).Enrich(event, "")
+		require.Empty(t, event["context"])
+	})
+}
+
+func TestCurrencyEnricher(t *testing.T) {
+	e := newCurrencyEnricher("usd", map[string]interface{}{"eur": 1.1, "GBP": "1.25", "XXX": 0})
+
+	t.Run("normalize and convert, leaving existing values as they are", func(t *testing.T) {
+		event := types.SingularEventT{"properties": map[string]interface{}{
+			"currency":  " eur ",
+			"revenue":   "€1,299.90",
+			"tax":       10,
+			"total":     "1.299,90 €",
+			"baseTotal": 1,
+			"name":      "order",
+			"products":  []interface{}{map[string]interface{}{"price": "19.99"}},
+		}}
+		e.Enrich(event, "")
+		require.Equal(t, map[string]interface{}{
+			"currency":     " eur ",
+			"revenue":      "€1,299.90",
+			"tax":          10,
+			"total":        "1.299,90 €",
+			"baseTotal":    1,
+			"name":         "order",
+			"products":     []interface{}{map[string]interface{}{"price": "19.99", "basePrice": 21.99}},
+			"baseCurrency": "USD",
+			"baseRevenue":  1429.89,
+			"baseTax":      11.0,
+		}, event["properties"])
+	})
+
+	t.Run("base currency", func(t *testing.T) {
+		event := types.SingularEventT{"properties": map[string]interface{}{"currency": "USD", "total": 5.5}}
+		e.Enrich(event, "")
+		require.Equal(t, map[string]interface{}{"currency": "USD", "total": 5.5, "baseCurrency": "USD", "baseTotal": 5.5}, event["properties"])
+	})
+
+	t.Run("existing base currency", func(t *testing.T) {
+		event := types.SingularEventT{"properties": map[string]interface{}{"currency": "EUR", "total": 5.5, "baseCurrency": "GBP"}}
+		e.Enrich(event, "")
+		require.Equal(t, map[string]interface{}{"currency": "EUR", "total": 5.5, "baseCurrency": "GBP"}, event["properties"])
+	})
+
+	t.Run("unknown rate", func(t *testing.T) {
+		event := types.SingularEventT{"properties": map[string]interface{}{"currency": "xxx", "total": "abc", "value": "3"}}
+		e.Enrich(event, "")
+		require.Equal(t, map[string]interface{}{"currency": "xxx", "total": "abc", "value": "3"}, event["properties"])
+	})
+
+	t.Run("no base currency", func(t *testing.T) {
+		event := types.SingularEventT{"properties": map[string]interface{}{"currency": "eur", "total": "3", "value": "abc"}}
+		newCurrencyEnricher("", map[string]interface{}{"EUR": 1.1}).Enrich(event, "")
+		require.Equal(t, map[string]interface{}{"currency": "eur", "total": "3", "value": "abc", "baseCurrency": "EUR", "baseTotal": 3.0}, event["properties"])
+	})
+}
+
+func TestToAmount(t *testing.T) {
+	for _, tc := range []struct {
+		value  interface{}
+		amount float64
+		ok     bool
+	}{
+		{value: 12.5, amount: 12.5, ok: true},
+		{value: int64(3), amount: 3, ok: true},
+		{value: "$1,299.90", amount: 1299.9, ok: true},
+		{value: "1.299,90 €", amount: 1299.9, ok: true},
+		{value: "CHF 1'299.90", amount: 1299.9, ok: true},
+		{value: "1 299,90", amount: 1299.9, ok: true},
+		{value: "1,299,000", amount: 1299000, ok: true},
+		{value: "1.299.000", amount: 1299000, ok: true},
+		{value: "12,5", amount: 12.5, ok: true},
+		{value: "1.299", amount: 1.299, ok: true},
+		{value: "-3.50", amount: -3.5, ok: true},
+		{value: "1,299", ok: false},
+		{value: "1,2.3.4", ok: false},
+		{value: "abc", ok: false},
+		{value: true, ok: false},
+	} {
+		amount, ok := toAmount(tc.value)
+		require.Equal(t, tc.ok, ok, "%v", tc.value)
+		require.Equal(t, tc.amount, amount, "%v", tc.value)
+	}
+}
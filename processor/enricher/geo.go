@@ -0,0 +1,54 @@
+package enricher
+
+import (
+	"net"
+
+	"github.com/rudderlabs/rudder-server/services/geolocation"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+// geoEnricher sets the country, region and city of the event's context.ip, or of the IP it was received from, under
+// context.location
+type geoEnricher struct {
+	locator geolocation.Locator
+}
+
+func newGeoEnricher(dbPath string) (*geoEnricher, error) {
+	locator, err := geolocation.NewMaxmindLocator(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &geoEnricher{locator: locator}, nil
+}
+
+func (*geoEnricher) Name() string {
+	return GeoEnricher
+}
+
+func (e *geoEnricher) Enrich(event types.SingularEventT, requestIP string) {
+	ipAddr := requestIP
+	if eventContext, ok := event["context"].(map[string]interface{}); ok {
+		if contextIP, ok := eventContext["ip"].(string); ok && contextIP != "" {
+			ipAddr = contextIP
+		}
+	}
+	ip := net.ParseIP(ipAddr)
+	if ip == nil {
+		return
+	}
+	location, err := e.locator.Locate(ip)
+	if err != nil || location == (geolocation.Location{}) {
+		return
+	}
+	eventLocation, ok := contextField(event, "location")
+	if !ok {
+		return
+	}
+	setMissing(eventLocation, "country", location.Country)
+	setMissing(eventLocation, "region", location.Region)
+	setMissing(eventLocation, "city", location.City)
+}
+
+func (e *geoEnricher) close() error {
+	return e.locator.Close()
+}
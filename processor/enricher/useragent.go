@@ -0,0 +1,121 @@
+package enricher
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+// userAgentEnricher parses the event's context.userAgent, setting the browser under context.browser, the operating
+// system under context.os and the type of device (desktop, mobile, tablet or bot) under context.device.type
+type userAgentEnricher struct{}
+
+func (*userAgentEnricher) Name() string {
+	return UserAgentEnricher
+}
+
+func (*userAgentEnricher) Enrich(event types.SingularEventT, _ string) {
+	eventContext, ok := event["context"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	userAgent, _ := eventContext["userAgent"].(string)
+	if userAgent == "" {
+		return
+	}
+	ua := parseUserAgent(userAgent)
+	if ua.browser != "" {
+		if browser, ok := contextField(event, "browser"); ok {
+			setMissing(browser, "name", ua.browser)
+			setMissing(browser, "version", ua.browserVersion)
+		}
+	}
+	if ua.os != "" {
+		if os, ok := contextField(event, "os"); ok {
+			setMissing(os, "name", ua.os)
+			setMissing(os, "version", ua.osVersion)
+		}
+	}
+	if device, ok := contextField(event, "device"); ok {
+		setMissing(device, "type", ua.device)
+	}
+}
+
+type userAgentT struct {
+	browser, browserVersion string
+	os, osVersion           string
+	device                  string
+}
+
+type userAgentRule struct {
+	name    string
+	pattern *regexp.Regexp // capturing the version, if any
+}
+
+// browserRules are checked in order, since user agents mention the browsers they are compatible with too,
+// e.g. Edge's mentions Chrome and Safari
+var browserRules = []userAgentRule{
+	{"Edge", regexp.MustCompile(`(?:Edg|Edge|EdgA|EdgiOS)/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/([\d.]+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+	{"Internet Explorer", regexp.MustCompile(`(?:MSIE |Trident/.*rv:)([\d.]+)`)},
+}
+
+var osRules = []userAgentRule{
+	{"Windows", regexp.MustCompile(`Windows NT ([\d.]+)`)},
+	{"iOS", regexp.MustCompile(`(?:iPhone|iPad|iPod).*? OS ([\d_]+)`)},
+	{"Mac OS X", regexp.MustCompile(`Mac OS X ([\d_.]+)`)},
+	{"Android", regexp.MustCompile(`Android ([\d.]+)`)},
+	{"Chrome OS", regexp.MustCompile(`CrOS \S+ ([\d.]+)`)},
+	{"Linux", regexp.MustCompile(`Linux()`)},
+}
+
+// windowsVersions maps the NT versions of Windows to their release names
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "Vista",
+	"5.1":  "XP",
+}
+
+var botPattern = regexp.MustCompile(`(?i)bot|crawl|spider|slurp|headless`)
+
+// parseUserAgent detects the browser, operating system and device type of a user agent
+func parseUserAgent(userAgent string) userAgentT {
+	var ua userAgentT
+	ua.browser, ua.browserVersion = matchUserAgent(browserRules, userAgent)
+	ua.os, ua.osVersion = matchUserAgent(osRules, userAgent)
+	ua.osVersion = strings.ReplaceAll(ua.osVersion, "_", ".")
+	if ua.os == "Windows" {
+		if version, ok := windowsVersions[ua.osVersion]; ok {
+			ua.osVersion = version
+		}
+	}
+	switch {
+	case botPattern.MatchString(userAgent):
+		ua.device = "bot"
+	case strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "Tablet"),
+		ua.os == "Android" && !strings.Contains(userAgent, "Mobile"):
+		ua.device = "tablet"
+	case strings.Contains(userAgent, "Mobi"), strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPod"):
+		ua.device = "mobile"
+	default:
+		ua.device = "desktop"
+	}
+	return ua
+}
+
+func matchUserAgent(rules []userAgentRule, userAgent string) (name, version string) {
+	for _, rule := range rules {
+		if match := rule.pattern.FindStringSubmatch(userAgent); match != nil {
+			return rule.name, match[1]
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/processor/eventcatalog"
+)
+
+// eventCatalogReportLoop reports the event catalog to the control plane, until the context is cancelled
+func (proc *HandleT) eventCatalogReportLoop(ctx context.Context) {
+	proc.backendConfig.WaitForConfig(ctx)
+	reporter := &eventcatalog.Reporter{
+		Catalog:  proc.eventCatalog,
+		Identity: proc.backendConfig.Identity(),
+		URL:      config.GetString("Processor.eventCatalog.reportURL", backendconfig.GetConfigBackendURL()+"/dataPlane/eventCatalog"),
+		Client:   &http.Client{Timeout: config.GetDuration("Processor.eventCatalog.reportTimeout", 30, time.Second)},
+	}
+	reporter.ReportLoop(ctx)
+}
+
+type EventCatalogRPCHandler struct {
+	catalog *eventcatalog.Catalog
+}
+
+// Get returns the catalog of the events seen since the last report to the control plane
+func (h *EventCatalogRPCHandler) Get(_ string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	response, err := json.MarshalIndent(h.catalog.Snapshot(), "", " ")
+	if err != nil {
+		return err
+	}
+	*result = string(response)
+	return nil
+}
@@ -0,0 +1,356 @@
+package eventcatalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/controlplane/identity"
+	"github.com/rudderlabs/rudder-server/utils/httputil"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+// The event catalog infers the schemas of the events of each source as they are processed: the events seen, identified
+// by their type and name, along with the types of their properties and how often they were seen. It is reported to the
+// control plane periodically, each report covering the events seen since the previous one.
+//
+// The catalog is bounded: events of sources having too many distinct events, and properties of events having too many
+// distinct properties, are only counted towards the source's and the event's totals.
+
+// Types of properties
+const (
+	StringType  = "string"
+	NumberType  = "number"
+	BooleanType = "boolean"
+	ObjectType  = "object"
+	ArrayType   = "array"
+	NullType    = "null"
+)
+
+// maxPropertyDepth is the depth up to which nested properties are inferred, deeper ones being reported as objects
+const maxPropertyDepth = 3
+
+// ReportT is the catalog of the events seen in a time window
+type ReportT struct {
+	InstanceID string           `json:"instanceId"`
+	From       time.Time        `json:"from"`
+	To         time.Time        `json:"to"`
+	Sources    []SourceCatalogT `json:"sources"`
+}
+
+// SourceCatalogT is the catalog of the events of a source
+type SourceCatalogT struct {
+	WorkspaceID string         `json:"workspaceId"`
+	SourceID    string         `json:"sourceId"`
+	Count       int64          `json:"count"`
+	Untracked   int64          `json:"untracked"` // events of distinct events beyond the limit
+	Events      []EventSchemaT `json:"events"`
+}
+
+// EventSchemaT is the inferred schema of an event
+type EventSchemaT struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Count      int64             `json:"count"`
+	FirstSeen  time.Time         `json:"firstSeen"`
+	LastSeen   time.Time         `json:"lastSeen"`
+	Properties []PropertySchemaT `json:"properties"`
+}
+
+// PropertySchemaT is the inferred schema of a property of an event, in dot notation
+type PropertySchemaT struct {
+	Path  string           `json:"path"`
+	Count int64            `json:"count"`
+	Types map[string]int64 `json:"types"`
+}
+
+type sourceKey struct {
+	workspaceID, sourceID string
+}
+
+type eventKey struct {
+	eventType, name string
+}
+
+type sourceCatalog struct {
+	count     int64
+	untracked int64
+	events    map[eventKey]*eventSchema
+}
+
+type eventSchema struct {
+	count               int64
+	firstSeen, lastSeen time.Time
+	properties          map[string]*PropertySchemaT
+}
+
+// Catalog infers the schemas of the events of each source
+type Catalog struct {
+	logger             logger.Logger
+	now                func() time.Time
+	maxEventsPerSource int
+	maxPropertiesEvent int
+
+	mu      sync.Mutex
+	from    time.Time
+	sources map[sourceKey]*sourceCatalog
+}
+
+// New returns an empty catalog
+func New(log logger.Logger) *Catalog {
+	c := &Catalog{
+		logger:             log,
+		now:                time.Now,
+		maxEventsPerSource: config.GetInt("Processor.eventCatalog.maxEventsPerSource", 200),
+		maxPropertiesEvent: config.GetInt("Processor.eventCatalog.maxPropertiesPerEvent", 200),
+	}
+	c.reset()
+	return c
+}
+
+func (c *Catalog) reset() {
+	c.from = c.now()
+	c.sources = make(map[sourceKey]*sourceCatalog)
+}
+
+// Record adds an event of the given source to the catalog
+func (c *Catalog) Record(workspaceID, sourceID string, event types.SingularEventT) {
+	eventType, _ := event["type"].(string)
+	name := eventType
+	if eventName, ok := event["event"].(string); ok && eventType == "track" {
+		name = eventName
+	}
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	source, ok := c.sources[sourceKey{workspaceID, sourceID}]
+	if !ok {
+		source = &sourceCatalog{events: make(map[eventKey]*eventSchema)}
+		c.sources[sourceKey{workspaceID, sourceID}] = source
+	}
+	source.count++
+	schema, ok := source.events[eventKey{eventType, name}]
+	if !ok {
+		if len(source.events) >= c.maxEventsPerSource {
+			source.untracked++
+			return
+		}
+		schema = &eventSchema{firstSeen: now, properties: make(map[string]*PropertySchemaT)}
+		source.events[eventKey{eventType, name}] = schema
+	}
+	schema.count++
+	schema.lastSeen = now
+	for _, key := range []string{"properties", "traits"} {
+		if value, ok := event[key]; ok {
+			c.recordProperty(schema, key, value, 1)
+		}
+	}
+	if traits := misc.MapLookup(event, "context", "traits"); traits != nil {
+		c.recordProperty(schema, "context.traits", traits, 1)
+	}
+}
+
+// recordProperty adds a property of an event to its schema, along with its nested properties
+func (c *Catalog) recordProperty(schema *eventSchema, path string, value interface{}, depth int) {
+	if object, ok := value.(map[string]interface{}); ok && depth <= maxPropertyDepth {
+		if depth > 1 {
+			c.addProperty(schema, path, ObjectType)
+		}
+		for key, nested := range object {
+			c.recordProperty(schema, path+"."+key, nested, depth+1)
+		}
+		return
+	}
+	c.addProperty(schema, path, propertyType(value))
+}
+
+func (c *Catalog) addProperty(schema *eventSchema, path, propertyType string) {
+	property, ok := schema.properties[path]
+	if !ok {
+		if len(schema.properties) >= c.maxPropertiesEvent {
+			return
+		}
+		property = &PropertySchemaT{Path: path, Types: make(map[string]int64)}
+		schema.properties[path] = property
+	}
+	property.Count++
+	property.Types[propertyType]++
+}
+
+func propertyType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return NullType
+	case string:
+		return StringType
+	case bool:
+		return BooleanType
+	case float64, float32, int, int64, int32, json.Number:
+		return NumberType
+	case map[string]interface{}:
+		return ObjectType
+	case []interface{}:
+		return ArrayType
+	}
+	return StringType
+}
+
+// Snapshot returns the catalog of the events seen since the last report
+func (c *Catalog) Snapshot() ReportT {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.report(c.from, c.sources)
+}
+
+// take returns the catalog of the events seen since the last report, resetting it
+func (c *Catalog) take() (from time.Time, sources map[sourceKey]*sourceCatalog) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	from, sources = c.from, c.sources
+	c.reset()
+	return from, sources
+}
+
+// restore merges back a catalog taken for a report which failed
+func (c *Catalog) restore(from time.Time, sources map[sourceKey]*sourceCatalog) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.from = from
+	for key, taken := range sources {
+		source, ok := c.sources[key]
+		if !ok {
+			c.sources[key] = taken
+			continue
+		}
+		source.count += taken.count
+		source.untracked += taken.untracked
+		for eventKey, takenSchema := range taken.events {
+			schema, ok := source.events[eventKey]
+			if !ok {
+				source.events[eventKey] = takenSchema
+				continue
+			}
+			schema.count += takenSchema.count
+			schema.firstSeen = takenSchema.firstSeen
+			for path, takenProperty := range takenSchema.properties {
+				property, ok := schema.properties[path]
+				if !ok {
+					schema.properties[path] = takenProperty
+					continue
+				}
+				property.Count += takenProperty.Count
+				for t, count := range takenProperty.Types {
+					property.Types[t] += count
+				}
+			}
+		}
+	}
+}
+
+func (c *Catalog) report(from time.Time, sources map[sourceKey]*sourceCatalog) ReportT {
+	report := ReportT{
+		InstanceID: config.GetInstanceID(),
+		From:       from,
+		To:         c.now(),
+		Sources:    make([]SourceCatalogT, 0, len(sources)),
+	}
+	for key, source := range sources {
+		sourceCatalog := SourceCatalogT{
+			WorkspaceID: key.workspaceID,
+			SourceID:    key.sourceID,
+			Count:       source.count,
+			Untracked:   source.untracked,
+			Events:      make([]EventSchemaT, 0, len(source.events)),
+		}
+		for key, schema := range source.events {
+			event := EventSchemaT{
+				Type:       key.eventType,
+				Name:       key.name,
+				Count:      schema.count,
+				FirstSeen:  schema.firstSeen,
+				LastSeen:   schema.lastSeen,
+				Properties: make([]PropertySchemaT, 0, len(schema.properties)),
+			}
+			for _, property := range schema.properties {
+				types := make(map[string]int64, len(property.Types))
+				for t, count := range property.Types {
+					types[t] = count
+				}
+				event.Properties = append(event.Properties, PropertySchemaT{Path: property.Path, Count: property.Count, Types: types})
+			}
+			sort.Slice(event.Properties, func(i, j int) bool { return event.Properties[i].Path < event.Properties[j].Path })
+			sourceCatalog.Events = append(sourceCatalog.Events, event)
+		}
+		sort.Slice(sourceCatalog.Events, func(i, j int) bool { return sourceCatalog.Events[i].Count > sourceCatalog.Events[j].Count })
+		report.Sources = append(report.Sources, sourceCatalog)
+	}
+	sort.Slice(report.Sources, func(i, j int) bool { return report.Sources[i].SourceID < report.Sources[j].SourceID })
+	return report
+}
+
+// Reporter reports the catalog to the control plane
+type Reporter struct {
+	Catalog  *Catalog
+	Identity identity.Identifier
+	URL      string
+	Client   *http.Client
+}
+
+// ReportLoop reports the catalog periodically, until the context is cancelled. The events of failed reports are
+// included in the next one.
+func (r *Reporter) ReportLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(config.GetDuration("Processor.eventCatalog.reportInterval", 60, time.Second)):
+		}
+		if err := r.Report(ctx); err != nil && ctx.Err() == nil {
+			r.Catalog.logger.Warnf("Failed to report the event catalog: %v", err)
+		}
+	}
+}
+
+// Report reports the catalog of the events seen since the last report
+func (r *Reporter) Report(ctx context.Context) (err error) {
+	from, sources := r.Catalog.take()
+	if len(sources) == 0 {
+		r.Catalog.restore(from, sources)
+		return nil
+	}
+	defer func() {
+		if err != nil {
+			r.Catalog.restore(from, sources)
+		}
+	}()
+	body, err := json.Marshal(r.Catalog.report(from, sources))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(r.Identity.BasicAuth())
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { httputil.CloseResponse(resp) }()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("control plane responded with status code %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package eventcatalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/controlplane/identity"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+func newTestCatalog(t *testing.T) *Catalog {
+	config.Reset()
+	logger.Reset()
+	t.Cleanup(config.Reset)
+	config.Set("Processor.eventCatalog.maxEventsPerSource", 2)
+	config.Set("Processor.eventCatalog.maxPropertiesPerEvent", 4)
+	c := New(logger.NOP)
+	now := time.Date(2022, 10, 10, 10, 10, 10, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	c.reset()
+	return c
+}
+
+func TestCatalog(t *testing.T) {
+	c := newTestCatalog(t)
+
+	c.Record("workspace", "source", types.SingularEventT{
+		"type":       "track",
+		"event":      "Order Completed",
+		"properties": map[string]interface{}{"total": 9.99, "currency": "USD", "coupon": nil},
+	})
+	c.Record("workspace", "source", types.SingularEventT{
+		"type":       "track",
+		"event":      "Order Completed",
+		"properties": map[string]interface{}{"total": "9.99", "shipping": map[string]interface{}{"method": "express"}},
+	})
+	c.Record("workspace", "source", types.SingularEventT{
+		"type":    "identify",
+		"context": map[string]interface{}{"traits": map[string]interface{}{"email": "user@example.com", "admin": true}},
+	})
+	c.Record("workspace", "source", types.SingularEventT{"type": "track", "event": "Product Viewed"})
+
+	report := c.Snapshot()
+	require.Len(t, report.Sources, 1)
+	source := report.Sources[0]
+	require.Equal(t, "workspace", source.WorkspaceID)
+	require.Equal(t, "source", source.SourceID)
+	require.EqualValues(t, 4, source.Count)
+	require.EqualValues(t, 1, source.Untracked, "Product Viewed is beyond the limit of events")
+	require.Len(t, source.Events, 2)
+
+	orderCompleted := source.Events[0]
+	require.Equal(t, "track", orderCompleted.Type)
+	require.Equal(t, "Order Completed", orderCompleted.Name)
+	require.EqualValues(t, 2, orderCompleted.Count)
+	require.Equal(t, []PropertySchemaT{
+		{Path: "properties.coupon", Count: 1, Types: map[string]int64{NullType: 1}},
+		{Path: "properties.currency", Count: 1, Types: map[string]int64{StringType: 1}},
+		{Path: "properties.shipping", Count: 1, Types: map[string]int64{ObjectType: 1}},
+		{Path: "properties.total", Count: 2, Types: map[string]int64{NumberType: 1, StringType: 1}},
+	}, orderCompleted.Properties, "properties.shipping.method is beyond the limit of properties")
+
+	identify := source.Events[1]
+	require.Equal(t, "identify", identify.Name)
+	require.Equal(t, []PropertySchemaT{
+		{Path: "context.traits.admin", Count: 1, Types: map[string]int64{BooleanType: 1}},
+		{Path: "context.traits.email", Count: 1, Types: map[string]int64{StringType: 1}},
+	}, identify.Properties)
+}
+
+func TestReporter(t *testing.T) {
+	c := newTestCatalog(t)
+	var (
+		statusCode = http.StatusOK
+		reports    []ReportT
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, _, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "token", username)
+		var report ReportT
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&report))
+		reports = append(reports, report)
+		w.WriteHeader(statusCode)
+	}))
+	defer srv.Close()
+	reporter := &Reporter{
+		Catalog:  c,
+		Identity: &identity.Workspace{WorkspaceToken: "token"},
+		URL:      srv.URL,
+		Client:   srv.Client(),
+	}
+
+	require.NoError(t, reporter.Report(context.Background()))
+	require.Empty(t, reports, "empty catalogs are not reported")
+
+	event := types.SingularEventT{"type": "page", "properties": map[string]interface{}{"path": "/"}}
+	c.Record("workspace", "source", event)
+
+	statusCode = http.StatusInternalServerError
+	require.Error(t, reporter.Report(context.Background()))
+	c.Record("workspace", "source", event)
+	require.EqualValues(t, 2, c.Snapshot().Sources[0].Count, "the events of failed reports are kept")
+
+	statusCode = http.StatusOK
+	require.NoError(t, reporter.Report(context.Background()))
+	require.Len(t, reports, 2)
+	require.EqualValues(t, 2, reports[1].Sources[0].Events[0].Properties[0].Count)
+	require.Empty(t, c.Snapshot().Sources, "the catalog is reset after a report")
+}
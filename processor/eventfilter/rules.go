@@ -0,0 +1,242 @@
+package eventfilter
+
+import (
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+var jsonfast = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// Destinations can be configured with rules for filtering the events sent to them, under "eventFilterRules", e.g.
+//
+//	"eventFilterRules": [
+//		{"action": "deny", "eventTypes": ["page"], "conditions": [{"property": "properties.path", "operator": "startsWith", "value": "/internal"}]},
+//		{"action": "allow", "eventNames": ["Order Completed", "Checkout Started"]},
+//		{"action": "allow", "eventTypes": ["identify"]}
+//	]
+//
+// A rule matches an event if the event's name and type are among the rule's ones, if any, and if all the rule's
+// conditions on the event's properties hold. Rules are evaluated in order and the first matching rule decides whether
+// the event is sent or not. Events no rule matches are sent, unless there are "allow" rules.
+
+const (
+	AllowAction = "allow"
+	DenyAction  = "deny"
+)
+
+// FilterRuleT is a rule for filtering the events sent to a destination
+type FilterRuleT struct {
+	Action     string              `json:"action"`
+	EventNames []string            `json:"eventNames"`
+	EventTypes []string            `json:"eventTypes"`
+	Conditions []FilterConditionT  `json:"conditions"`
+	eventTypes map[string]struct{} // lowercased
+	eventNames map[string]struct{}
+}
+
+// FilterConditionT is a predicate on a property of an event, e.g. properties.plan, looked up by its dot-separated path
+type FilterConditionT struct {
+	Property string      `json:"property"`
+	Operator string      `json:"operator"`
+	Value    interface{} `json:"value"`
+	path     []string
+}
+
+// FilterRulesT are the rules for filtering the events sent to a destination
+type FilterRulesT struct {
+	rules        []FilterRuleT
+	defaultAllow bool
+}
+
+// GetFilterRules returns the rules for filtering the events sent to the given destination, based on configuration.
+// If no rules are configured, returns false
+func GetFilterRules(destination *backendconfig.DestinationT) (*FilterRulesT, bool, error) {
+	rulesConfig, ok := destination.Config["eventFilterRules"]
+	if !ok || rulesConfig == nil {
+		return nil, false, nil
+	}
+	rawRules, err := jsonfast.Marshal(rulesConfig)
+	if err != nil {
+		return nil, false, fmt.Errorf("marshalling event filter rules: %w", err)
+	}
+	var rules []FilterRuleT
+	if err := jsonfast.Unmarshal(rawRules, &rules); err != nil {
+		return nil, false, fmt.Errorf("unmarshalling event filter rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil, false, nil
+	}
+	filterRules := &FilterRulesT{rules: rules, defaultAllow: true}
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.Action {
+		case AllowAction:
+			filterRules.defaultAllow = false
+		case DenyAction:
+		default:
+			return nil, false, fmt.Errorf("rule %d: invalid action %q", i, rule.Action)
+		}
+		if len(rule.EventTypes) > 0 {
+			rule.eventTypes = make(map[string]struct{}, len(rule.EventTypes))
+			for _, eventType := range rule.EventTypes {
+				rule.eventTypes[strings.TrimSpace(strings.ToLower(eventType))] = struct{}{}
+			}
+		}
+		if len(rule.EventNames) > 0 {
+			rule.eventNames = make(map[string]struct{}, len(rule.EventNames))
+			for _, eventName := range rule.EventNames {
+				rule.eventNames[eventName] = struct{}{}
+			}
+		}
+		for j := range rule.Conditions {
+			condition := &rule.Conditions[j]
+			if condition.Property == "" {
+				return nil, false, fmt.Errorf("rule %d, condition %d: a property is required", i, j)
+			}
+			if _, ok := conditionOperators[condition.Operator]; !ok {
+				return nil, false, fmt.Errorf("rule %d, condition %d: invalid operator %q", i, j, condition.Operator)
+			}
+			condition.path = strings.Split(condition.Property, ".")
+		}
+	}
+	return filterRules, true, nil
+}
+
+// Allow returns whether the given event is to be sent to the destination, along with the index of the rule deciding
+// it, or -1 if no rule matches the event
+func (r *FilterRulesT) Allow(event types.SingularEventT) (allow bool, rule int) {
+	for i := range r.rules {
+		if r.rules[i].matches(event) {
+			return r.rules[i].Action == AllowAction, i
+		}
+	}
+	return r.defaultAllow, -1
+}
+
+func (rule *FilterRuleT) matches(event types.SingularEventT) bool {
+	if rule.eventTypes != nil {
+		eventType, _ := event["type"].(string)
+		if _, ok := rule.eventTypes[strings.TrimSpace(strings.ToLower(eventType))]; !ok {
+			return false
+		}
+	}
+	if rule.eventNames != nil {
+		eventName, _ := event["event"].(string)
+		if _, ok := rule.eventNames[eventName]; !ok {
+			return false
+		}
+	}
+	for i := range rule.Conditions {
+		if !rule.Conditions[i].holds(event) {
+			return false
+		}
+	}
+	return true
+}
+
+var conditionOperators = map[string]func(value, expected interface{}) bool{
+	"eq":  equal,
+	"neq": func(value, expected interface{}) bool { return !equal(value, expected) },
+	"in": func(value, expected interface{}) bool {
+		values, _ := expected.([]interface{})
+		for _, v := range values {
+			if equal(value, v) {
+				return true
+			}
+		}
+		return false
+	},
+	"notIn": func(value, expected interface{}) bool {
+		values, _ := expected.([]interface{})
+		for _, v := range values {
+			if equal(value, v) {
+				return false
+			}
+		}
+		return true
+	},
+	"contains": func(value, expected interface{}) bool {
+		s, ok1 := value.(string)
+		substr, ok2 := expected.(string)
+		return ok1 && ok2 && strings.Contains(s, substr)
+	},
+	"startsWith": func(value, expected interface{}) bool {
+		s, ok1 := value.(string)
+		prefix, ok2 := expected.(string)
+		return ok1 && ok2 && strings.HasPrefix(s, prefix)
+	},
+	"gt": func(value, expected interface{}) bool {
+		return compare(value, expected, func(a, b float64) bool { return a > b })
+	},
+	"gte": func(value, expected interface{}) bool {
+		return compare(value, expected, func(a, b float64) bool { return a >= b })
+	},
+	"lt": func(value, expected interface{}) bool {
+		return compare(value, expected, func(a, b float64) bool { return a < b })
+	},
+	"lte": func(value, expected interface{}) bool {
+		return compare(value, expected, func(a, b float64) bool { return a <= b })
+	},
+	// exists and notExists are evaluated on the presence of the property
+	"exists":    nil,
+	"notExists": nil,
+}
+
+func (condition *FilterConditionT) holds(event types.SingularEventT) bool {
+	value, err := misc.NestedMapLookup(event, condition.path...)
+	switch condition.Operator {
+	case "exists":
+		return err == nil
+	case "notExists":
+		return err != nil
+	}
+	if err != nil {
+		// conditions on missing properties only hold for negated operators
+		return condition.Operator == "neq" || condition.Operator == "notIn"
+	}
+	return conditionOperators[condition.Operator](value, condition.Value)
+}
+
+func equal(value, expected interface{}) bool {
+	if a, ok := toFloat(value); ok {
+		b, ok := toFloat(expected)
+		return ok && a == b
+	}
+	switch v := value.(type) {
+	case string:
+		e, ok := expected.(string)
+		return ok && v == e
+	case bool:
+		e, ok := expected.(bool)
+		return ok && v == e
+	case nil:
+		return expected == nil
+	}
+	return false
+}
+
+func compare(value, expected interface{}, fn func(a, b float64) bool) bool {
+	a, ok1 := toFloat(value)
+	b, ok2 := toFloat(expected)
+	return ok1 && ok2 && fn(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
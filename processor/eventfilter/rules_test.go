@@ -0,0 +1,90 @@
+package eventfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+func TestFilterRules(t *testing.T) {
+	destination := func(rules string) *backendconfig.DestinationT {
+		var config map[string]interface{}
+		require.NoError(t, jsonfast.Unmarshal([]byte(`{"eventFilterRules": `+rules+`}`), &config))
+		return &backendconfig.DestinationT{Config: config}
+	}
+	event := func(e string) types.SingularEventT {
+		var event types.SingularEventT
+		require.NoError(t, jsonfast.Unmarshal([]byte(e), &event))
+		return event
+	}
+
+	t.Run("no rules", func(t *testing.T) {
+		_, ok, err := GetFilterRules(&backendconfig.DestinationT{Config: map[string]interface{}{}})
+		require.NoError(t, err)
+		require.False(t, ok)
+		_, ok, err = GetFilterRules(destination(`[]`))
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		for _, rules := range []string{
+			`{"action": "deny"}`,
+			`[{"action": "drop"}]`,
+			`[{"action": "deny", "conditions": [{"operator": "eq", "value": 1}]}]`,
+			`[{"action": "deny", "conditions": [{"property": "properties.plan", "operator": "like", "value": "free"}]}]`,
+		} {
+			_, _, err := GetFilterRules(destination(rules))
+			require.Error(t, err, rules)
+		}
+	})
+
+	t.Run("deny rules", func(t *testing.T) {
+		rules, ok, err := GetFilterRules(destination(`[
+			{"action": "deny", "eventTypes": ["Page"], "conditions": [{"property": "properties.path", "operator": "startsWith", "value": "/internal"}]},
+			{"action": "deny", "eventNames": ["Scrolled", "Hovered"]},
+			{"action": "deny", "conditions": [{"property": "properties.price", "operator": "lt", "value": 1}, {"property": "context.test", "operator": "exists"}]}
+		]`))
+		require.NoError(t, err)
+		require.True(t, ok)
+		for e, expected := range map[string]bool{
+			`{"type": "page", "properties": {"path": "/internal/admin"}}`:                    false,
+			`{"type": "page", "properties": {"path": "/home"}}`:                              true,
+			`{"type": "track", "event": "Scrolled"}`:                                         false,
+			`{"type": "track", "event": "Clicked"}`:                                          true,
+			`{"type": "track", "properties": {"price": 0.5}, "context": {"test": false}}`:    false,
+			`{"type": "track", "properties": {"price": 0.5}}`:                                true,
+			`{"type": "track", "properties": {"price": "0.5"}, "context": {"test": true}}`:   true,
+			`{"type": "identify", "properties": {"path": "/internal"}, "event": "Hovered!"}`: true,
+		} {
+			allow, _ := rules.Allow(event(e))
+			require.Equal(t, expected, allow, e)
+		}
+	})
+
+	t.Run("allow rules", func(t *testing.T) {
+		rules, ok, err := GetFilterRules(destination(`[
+			{"action": "deny", "eventNames": ["Order Completed"], "conditions": [{"property": "properties.currency", "operator": "notIn", "value": ["USD", "EUR"]}]},
+			{"action": "allow", "eventNames": ["Order Completed", "Checkout Started"]},
+			{"action": "allow", "eventTypes": ["identify"], "conditions": [{"property": "traits.plan", "operator": "neq", "value": "free"}]}
+		]`))
+		require.NoError(t, err)
+		require.True(t, ok)
+		for e, expected := range map[string]bool{
+			`{"type": "track", "event": "Order Completed", "properties": {"currency": "USD"}}`: true,
+			`{"type": "track", "event": "Order Completed", "properties": {"currency": "INR"}}`: false,
+			`{"type": "track", "event": "Order Completed"}`:                                    false,
+			`{"type": "track", "event": "Checkout Started"}`:                                   true,
+			`{"type": "track", "event": "Product Viewed"}`:                                     false,
+			`{"type": "identify", "traits": {"plan": "pro"}}`:                                  true,
+			`{"type": "identify"}`:                             true,
+			`{"type": "identify", "traits": {"plan": "free"}}`: false,
+		} {
+			allow, _ := rules.Allow(event(e))
+			require.Equal(t, expected, allow, e)
+		}
+	})
+}
@@ -83,10 +83,15 @@ func CollectIntgTransformErrorStats(input []byte) {
 	var integrationStats []TransStatsT
 	err := jsonfast.Unmarshal(input, &integrationStats)
 	if err == nil {
-		for _, integrationStat := range integrationStats {
-			if len(integrationStat.StatTags) > 0 {
-				stats.Default.NewTaggedStat("integration.failure_detailed", stats.CountType, integrationStat.StatTags).Increment()
-			}
+		ReportIntgTransformErrorStats(integrationStats)
+	}
+}
+
+// ReportIntgTransformErrorStats reports the stats of the integration failures of a transformer response
+func ReportIntgTransformErrorStats(integrationStats []TransStatsT) {
+	for _, integrationStat := range integrationStats {
+		if len(integrationStat.StatTags) > 0 {
+			stats.Default.NewTaggedStat("integration.failure_detailed", stats.CountType, integrationStat.StatTags).Increment()
 		}
 	}
 }
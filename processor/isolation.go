@@ -0,0 +1,185 @@
+package processor
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// With source isolation, the processor runs a pipeline per source, reading only the gateway jobs of its source, so that
+// a slow transformation of a source only delays the processing of that source's events. Each pipeline has its own
+// buffers, so it applies backpressure to its source only.
+//
+// Pipelines are started for the sources having pending jobs, so that the jobs of deleted sources are drained too, along
+// with the sources of the backend config. Pipelines of sources no longer in the backend config stop once they have no
+// jobs to process. The number of pipelines is bounded by Processor.isolation.maxPipelines: once it is reached, sources
+// with pending jobs wait for idle pipelines to stop and free their slots. Pending jobs are looked up among the ones of
+// sources without a pipeline only, for a backlog of a source not to hide the jobs of the others.
+
+const (
+	NoIsolation     = "none"
+	SourceIsolation = "source"
+)
+
+// sourceParameterFilters returns the parameter filters for reading the jobs of the given source only, if any
+func sourceParameterFilters(sourceID string) []jobsdb.ParameterFilterT {
+	if sourceID == "" {
+		return nil
+	}
+	return []jobsdb.ParameterFilterT{{Name: "source_id", Value: sourceID}}
+}
+
+type isolatedPipelineT struct {
+	orphan int32 // the source is no longer in the backend config
+}
+
+func (p *isolatedPipelineT) isOrphan() bool {
+	return atomic.LoadInt32(&p.orphan) == 1
+}
+
+func (p *isolatedPipelineT) setOrphan(orphan bool) {
+	var v int32
+	if orphan {
+		v = 1
+	}
+	atomic.StoreInt32(&p.orphan, v)
+}
+
+// isolatedPipelines runs a pipeline per source using runPipeline, until the context is cancelled
+func (proc *HandleT) isolatedPipelines(ctx context.Context, runPipeline func(ctx context.Context, sourceID string, stopIdle func() bool)) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	pipelinesGauge := proc.statsFactory.NewStat("processor.isolated_pipelines", stats.GaugeType)
+	waitingGauge := proc.statsFactory.NewStat("processor.isolated_pipelines_waiting", stats.GaugeType)
+	pipelines := make(map[string]*isolatedPipelineT)
+	var waiting int32 // sources with pending jobs are waiting for a pipeline slot
+	stopped := make(chan string)
+	start := func(sourceID string, orphan bool) {
+		pipeline := &isolatedPipelineT{}
+		pipeline.setOrphan(orphan)
+		pipelines[sourceID] = pipeline
+		proc.logger.Infof("Starting the pipeline of source %s", sourceID)
+		wg.Add(1)
+		rruntime.Go(func() {
+			defer wg.Done()
+			runPipeline(ctx, sourceID, func() bool {
+				if pipeline.isOrphan() {
+					return true
+				}
+				// an idle pipeline frees its slot for a single waiting source
+				for {
+					w := atomic.LoadInt32(&waiting)
+					if w <= 0 {
+						return false
+					}
+					if atomic.CompareAndSwapInt32(&waiting, w, w-1) {
+						return true
+					}
+				}
+			})
+			select {
+			case stopped <- sourceID:
+			case <-ctx.Done():
+			}
+		})
+	}
+
+	var refreshInterval time.Duration
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sourceID := <-stopped:
+			delete(pipelines, sourceID)
+			proc.logger.Infof("Stopped the pipeline of source %s", sourceID)
+			pipelinesGauge.Gauge(len(pipelines))
+		case <-time.After(refreshInterval):
+			refreshInterval = config.GetDuration("Processor.isolation.refreshInterval", 10, time.Second)
+			maxPipelines := config.GetInt("Processor.isolation.maxPipelines", 100)
+			sourceIDs := getSourceIDs()
+			for sourceID, pipeline := range pipelines {
+				_, ok := sourceIDs[sourceID]
+				pipeline.setOrphan(!ok)
+			}
+			pendingSourceIDs, err := proc.getPendingSourceIDs(ctx, pipelines)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				proc.logger.Errorf("Failed to get the sources with pending jobs: %v", err)
+			}
+			var waitingSources int
+			for sourceID := range pendingSourceIDs {
+				if _, ok := pipelines[sourceID]; ok {
+					continue
+				}
+				if len(pipelines) >= maxPipelines {
+					waitingSources++
+					continue
+				}
+				_, ok := sourceIDs[sourceID]
+				start(sourceID, !ok)
+			}
+			atomic.StoreInt32(&waiting, int32(waitingSources))
+			for sourceID := range sourceIDs {
+				if _, ok := pipelines[sourceID]; !ok && len(pipelines) < maxPipelines {
+					start(sourceID, false)
+				}
+			}
+			pipelinesGauge.Gauge(len(pipelines))
+			waitingGauge.Gauge(waitingSources)
+		}
+	}
+}
+
+// getSourceIDs returns the ids of the sources of the backend config
+func getSourceIDs() map[string]struct{} {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	sourceIDs := make(map[string]struct{}, len(writeKeySourceMap))
+	for writeKey := range writeKeySourceMap {
+		sourceIDs[writeKeySourceMap[writeKey].ID] = struct{}{}
+	}
+	return sourceIDs
+}
+
+// getPendingSourceIDs returns the ids of the sources of the oldest unprocessed gateway jobs, leaving out the jobs of
+// the sources already running a pipeline
+func (proc *HandleT) getPendingSourceIDs(ctx context.Context, pipelines map[string]*isolatedPipelineT) (map[string]struct{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, proc.jobdDBQueryRequestTimeout)
+	defer cancel()
+	runningSourceIDs := make([]string, 0, len(pipelines))
+	for sourceID := range pipelines {
+		runningSourceIDs = append(runningSourceIDs, sourceID)
+	}
+	sort.Strings(runningSourceIDs)
+	var excludeParameterFilters []jobsdb.ParameterFilterT
+	for _, sourceID := range runningSourceIDs {
+		excludeParameterFilters = append(excludeParameterFilters, sourceParameterFilters(sourceID)...)
+	}
+	unprocessedList, err := proc.gatewayDB.GetUnprocessed(ctx, jobsdb.GetQueryParamsT{
+		CustomValFilters:        []string{GWCustomVal},
+		ExcludeParameterFilters: excludeParameterFilters,
+		JobsLimit:               config.GetInt("Processor.isolation.pendingJobsLimit", 100),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sourceIDs := make(map[string]struct{})
+	for _, job := range unprocessedList.Jobs {
+		if sourceID := gjson.GetBytes(job.Parameters, "source_id").Str; sourceID != "" {
+			sourceIDs[sourceID] = struct{}{}
+		}
+	}
+	return sourceIDs, nil
+}
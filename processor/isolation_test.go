@@ -0,0 +1,208 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	mocksJobsDB "github.com/rudderlabs/rudder-server/mocks/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+// fakePipelinesT runs pipelines which never find any jobs to process, tracking the running ones
+type fakePipelinesT struct {
+	mu      sync.Mutex
+	running map[string]struct{}
+	started map[string]int
+}
+
+func (f *fakePipelinesT) run(ctx context.Context, sourceID string, stopIdle func() bool) {
+	f.mu.Lock()
+	f.running[sourceID] = struct{}{}
+	f.started[sourceID]++
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		delete(f.running, sourceID)
+		f.mu.Unlock()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Millisecond):
+			if stopIdle() {
+				return
+			}
+		}
+	}
+}
+
+func (f *fakePipelinesT) runningSources() map[string]struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	running := make(map[string]struct{}, len(f.running))
+	for sourceID := range f.running {
+		running[sourceID] = struct{}{}
+	}
+	return running
+}
+
+func (f *fakePipelinesT) startCount(sourceID string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.started[sourceID]
+}
+
+func TestIsolatedPipelines(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+	config.Set("Processor.isolation.refreshInterval", "5ms")
+
+	configSubscriberLock.RLock()
+	prevWriteKeySourceMap := writeKeySourceMap
+	configSubscriberLock.RUnlock()
+	defer func() {
+		configSubscriberLock.Lock()
+		writeKeySourceMap = prevWriteKeySourceMap
+		configSubscriberLock.Unlock()
+	}()
+	setSources := func(sourceIDs ...string) {
+		configSubscriberLock.Lock()
+		defer configSubscriberLock.Unlock()
+		writeKeySourceMap = make(map[string]backendconfig.SourceT)
+		for _, sourceID := range sourceIDs {
+			writeKeySourceMap["write-key-of-"+sourceID] = backendconfig.SourceT{ID: sourceID}
+		}
+	}
+
+	// the gateway jobsdb returns the pending jobs of the sources, oldest first, leaving out the ones of excluded sources
+	var pendingMu sync.Mutex
+	var pendingSourceIDs []string
+	setPending := func(sourceIDs ...string) {
+		pendingMu.Lock()
+		defer pendingMu.Unlock()
+		pendingSourceIDs = sourceIDs
+	}
+	mockCtrl := gomock.NewController(t)
+	gatewayDB := mocksJobsDB.NewMockJobsDB(mockCtrl)
+	gatewayDB.EXPECT().GetUnprocessed(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params jobsdb.GetQueryParamsT) (jobsdb.JobsResult, error) {
+			require.Equal(t, []string{GWCustomVal}, params.CustomValFilters)
+			excluded := make(map[string]struct{})
+			for _, parameterFilter := range params.ExcludeParameterFilters {
+				require.Equal(t, "source_id", parameterFilter.Name)
+				excluded[parameterFilter.Value] = struct{}{}
+			}
+			pendingMu.Lock()
+			defer pendingMu.Unlock()
+			var jobs []*jobsdb.JobT
+			for i, sourceID := range pendingSourceIDs {
+				if _, ok := excluded[sourceID]; ok {
+					continue
+				}
+				if len(jobs) == params.JobsLimit {
+					break
+				}
+				jobs = append(jobs, &jobsdb.JobT{JobID: int64(i + 1), Parameters: []byte(fmt.Sprintf(`{"source_id": %q}`, sourceID))})
+			}
+			return jobsdb.JobsResult{Jobs: jobs}, nil
+		}).AnyTimes()
+
+	run := func(t *testing.T) (*fakePipelinesT, *memstats.Store, func()) {
+		store := memstats.New()
+		proc := &HandleT{
+			statsFactory:              store,
+			logger:                    logger.NOP,
+			gatewayDB:                 gatewayDB,
+			jobdDBQueryRequestTimeout: time.Minute,
+		}
+		pipelines := &fakePipelinesT{running: make(map[string]struct{}), started: make(map[string]int)}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			proc.isolatedPipelines(ctx, pipelines.run)
+		}()
+		return pipelines, store, func() {
+			cancel()
+			<-done
+			require.Empty(t, pipelines.runningSources(), "all pipelines should stop with the context")
+		}
+	}
+
+	t.Run("starts, orphans and stops pipelines", func(t *testing.T) {
+		config.Set("Processor.isolation.maxPipelines", 100)
+		setSources("source-a")
+		setPending("deleted-source")
+		pipelines, store, stop := run(t)
+		defer stop()
+
+		require.Eventually(t, func() bool { return pipelines.startCount("deleted-source") > 0 }, 5*time.Second, time.Millisecond,
+			"a pipeline should drain the jobs of a source no longer in the backend config")
+		setPending()
+		require.Eventually(t, func() bool {
+			_, ok := pipelines.runningSources()["source-a"]
+			return ok && len(pipelines.runningSources()) == 1 && store.Get("processor.isolated_pipelines", nil).LastValue() == 1
+		}, 5*time.Second, time.Millisecond, "the pipeline of the deleted source should stop once idle")
+
+		setSources()
+		require.Eventually(t, func() bool {
+			return len(pipelines.runningSources()) == 0 && store.Get("processor.isolated_pipelines", nil).LastValue() == 0
+		}, 5*time.Second, time.Millisecond, "the pipeline of an orphaned source should stop once idle")
+	})
+
+	t.Run("frees the slots of idle pipelines for sources with pending jobs once at the limit", func(t *testing.T) {
+		config.Set("Processor.isolation.maxPipelines", 1)
+		setSources("source-a")
+		setPending()
+		pipelines, store, stop := run(t)
+		defer stop()
+
+		require.Eventually(t, func() bool {
+			_, ok := pipelines.runningSources()["source-a"]
+			return ok
+		}, 5*time.Second, time.Millisecond)
+
+		setSources("source-a", "source-b")
+		require.Never(t, func() bool { return pipelines.startCount("source-b") > 0 }, 50*time.Millisecond, time.Millisecond,
+			"no pipeline should be started beyond the limit")
+
+		setPending("source-b")
+		require.Eventually(t, func() bool {
+			_, ok := pipelines.runningSources()["source-b"]
+			return ok && len(pipelines.runningSources()) == 1
+		}, 5*time.Second, time.Millisecond, "the idle pipeline should free its slot for the source with pending jobs")
+		setPending()
+		require.Never(t, func() bool { return len(pipelines.runningSources()) > 1 }, 50*time.Millisecond, time.Millisecond)
+		require.EqualValues(t, 1, store.Get("processor.isolated_pipelines", nil).LastValue())
+		require.EqualValues(t, 0, store.Get("processor.isolated_pipelines_waiting", nil).LastValue())
+	})
+
+	t.Run("finds the sources with pending jobs beyond the backlog of another source", func(t *testing.T) {
+		config.Set("Processor.isolation.maxPipelines", 2)
+		setSources("source-backlog", "source-idle")
+		backlog := make([]string, 100)
+		for i := range backlog {
+			backlog[i] = "source-backlog"
+		}
+		setPending(backlog...)
+		pipelines, _, stop := run(t)
+		defer stop()
+		require.Eventually(t, func() bool { return len(pipelines.runningSources()) == 2 }, 5*time.Second, time.Millisecond)
+
+		setSources("source-backlog", "source-idle", "source-late")
+		setPending(append(backlog, "source-late")...)
+		require.Eventually(t, func() bool { return pipelines.startCount("source-late") > 0 }, 5*time.Second, time.Millisecond,
+			"the source with pending jobs newer than the backlog of another one should get a pipeline")
+	})
+}
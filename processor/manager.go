@@ -28,6 +28,7 @@ type LifecycleManager struct {
 	ReportingI       types.ReportingI         // need not initialize again
 	BackendConfig    backendconfig.BackendConfig
 	Transformer      transformer.Transformer
+	WorkspaceQuotas  WorkspaceQuotas // paces the events processed for workspaces, if set
 	transientSources transientsource.Service
 	fileuploader     fileuploader.Provider
 	rsourcesService  rsources.JobService
@@ -40,6 +41,7 @@ func (proc *LifecycleManager) Start() error {
 	if proc.Transformer != nil {
 		proc.HandleT.transformer = proc.Transformer
 	}
+	proc.HandleT.workspaceQuotas = proc.WorkspaceQuotas
 
 	proc.HandleT.Setup(
 		proc.BackendConfig, proc.gatewayDB, proc.routerDB, proc.batchRouterDB, proc.errDB,
@@ -9,8 +9,10 @@ import (
 	"io"
 	"math"
 	"net/http"
+	"path/filepath"
 	"reflect"
 	"runtime/trace"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -25,9 +27,13 @@ import (
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	event_schema "github.com/rudderlabs/rudder-server/event-schema"
 	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/processor/enricher"
+	"github.com/rudderlabs/rudder-server/processor/eventcatalog"
 	"github.com/rudderlabs/rudder-server/processor/eventfilter"
 	"github.com/rudderlabs/rudder-server/processor/integrations"
 	"github.com/rudderlabs/rudder-server/processor/stash"
+	"github.com/rudderlabs/rudder-server/processor/transformationcache"
+	"github.com/rudderlabs/rudder-server/processor/transformationdlq"
 	"github.com/rudderlabs/rudder-server/processor/transformer"
 	"github.com/rudderlabs/rudder-server/router/batchrouter"
 	"github.com/rudderlabs/rudder-server/rruntime"
@@ -64,6 +70,10 @@ type HandleT struct {
 	logger                    logger.Logger
 	eventSchemaHandler        types.EventSchemasI
 	dedupHandler              dedup.DedupI
+	transformationCache       *transformationcache.Cache
+	transformationDLQ         *transformationdlq.DLQ
+	enrichers                 *enricher.Registry
+	eventCatalog              *eventcatalog.Catalog
 	reporting                 types.ReportingI
 	reportingEnabled          bool
 	multitenantI              multitenant.MultiTenantI
@@ -82,6 +92,15 @@ type HandleT struct {
 	transientSources          transientsource.Service
 	fileuploader              fileuploader.Provider
 	rsourcesService           rsources.JobService
+	workspaceQuotas           WorkspaceQuotas
+}
+
+// WorkspaceQuotas paces the events processed for workspaces as per their events quota
+type WorkspaceQuotas interface {
+	// EventsQuotaExceeded returns the workspaces whose jobs are left unprocessed until their quota catches up
+	EventsQuotaExceeded() []string
+	// ConsumeProcessedEvents consumes the events picked up from the quota of the workspace
+	ConsumeProcessedEvents(workspaceID string, events int)
 }
 
 type processorStats struct {
@@ -165,6 +184,7 @@ type ParametersT struct {
 	SourceCategory          string      `json:"source_category"`
 	RecordID                interface{} `json:"record_id"`
 	WorkspaceId             string      `json:"workspaceId"`
+	TraceParent             string      `json:"traceparent,omitempty"`
 }
 
 type MetricMetadata struct {
@@ -394,6 +414,22 @@ func (proc *HandleT) Setup(
 	if enableDedup {
 		proc.dedupHandler = dedup.GetInstance(clearDB)
 	}
+	if enableTransformationCache {
+		proc.setupTransformationCache()
+	}
+	if enableTransformationDLQ {
+		proc.setupTransformationDLQ()
+		admin.RegisterAdminHandler("TransformationDLQ", &TransformationDLQRPCHandler{proc: proc})
+	}
+	if enableEventCatalog {
+		proc.eventCatalog = eventcatalog.New(proc.logger.Child("eventcatalog"))
+		admin.RegisterAdminHandler("EventCatalog", &EventCatalogRPCHandler{catalog: proc.eventCatalog})
+	}
+	enrichers, err := enricher.New(proc.logger.Child("enricher"))
+	if err != nil {
+		panic(err)
+	}
+	proc.enrichers = enrichers
 
 	ctx, cancel := context.WithCancel(context.Background())
 	g, ctx := errgroup.WithContext(ctx)
@@ -436,12 +472,69 @@ func (proc *HandleT) Start(ctx context.Context) error {
 		return nil
 	}))
 
+	if proc.transformationDLQ != nil {
+		g.Go(misc.WithBugsnag(func() error {
+			proc.transformationDLQ.CleanupLoop(ctx)
+			return nil
+		}))
+	}
+
+	if proc.eventCatalog != nil {
+		g.Go(misc.WithBugsnag(func() error {
+			proc.eventCatalogReportLoop(ctx)
+			return nil
+		}))
+	}
+
 	return g.Wait()
 }
 
 func (proc *HandleT) Shutdown() {
 	proc.backgroundCancel()
 	_ = proc.backgroundWait()
+	if proc.transformationCache != nil {
+		if err := proc.transformationCache.Close(); err != nil {
+			proc.logger.Errorf("Failed to close the transformation cache: %v", err)
+		}
+	}
+	if proc.enrichers != nil {
+		if err := proc.enrichers.Close(); err != nil {
+			proc.logger.Errorf("Failed to close the enrichers: %v", err)
+		}
+	}
+}
+
+// setupTransformationCache opens the cache of user transformation outputs
+func (proc *HandleT) setupTransformationCache() {
+	path := config.GetString("Processor.transformationCache.path", "")
+	if path == "" {
+		tmpDirPath, err := misc.CreateTMPDIR()
+		if err != nil {
+			panic(err)
+		}
+		path = filepath.Join(tmpDirPath, "transformation-cache")
+	}
+	cache, err := transformationcache.New(path,
+		transformationcache.WithMemEntries(config.GetInt("Processor.transformationCache.memEntries", 10000)),
+		transformationcache.WithTTL(config.GetDuration("Processor.transformationCache.ttl", 24, time.Hour)),
+		transformationcache.WithStats(proc.statsFactory),
+	)
+	if err != nil {
+		panic(fmt.Errorf("setting up the transformation cache: %w", err))
+	}
+	proc.transformationCache = cache
+}
+
+// userTransform transforms events with their user transformation, serving the outputs of already transformed events
+// from the transformation cache, if enabled
+func (proc *HandleT) userTransform(ctx context.Context, eventList []transformer.TransformerEventT) transformer.ResponseT {
+	transform := func(events []transformer.TransformerEventT) transformer.ResponseT {
+		return proc.transformer.Transform(ctx, events, integrations.GetUserTransformURL(), userTransformBatchSize)
+	}
+	if proc.transformationCache == nil {
+		return transform(eventList)
+	}
+	return proc.transformationCache.Transform(eventList, transform)
 }
 
 var (
@@ -459,6 +552,8 @@ var (
 	writeKeyDestinationMap    map[string][]backendconfig.DestinationT
 	writeKeySourceMap         map[string]backendconfig.SourceT
 	workspaceLibrariesMap     map[string]backendconfig.LibrariesT
+	pausedWorkspaces          map[string]struct{}
+	pausedSources             map[string]struct{}
 	destinationIDtoTypeMap    map[string]string
 	batchDestinations         []string
 	configSubscriberLock      sync.RWMutex
@@ -466,7 +561,12 @@ var (
 	enableEventSchemasFeature bool
 	enableEventSchemasAPIOnly bool
 	enableDedup               bool
+	enableTransformationCache bool
+	enableTransformationDLQ   bool
+	enableEventCatalog        bool
+	enableHandoffTokens       bool
 	enableEventCount          bool
+	isolationMode             string
 	transformTimesPQLength    int
 	captureEventNameStats     bool
 	transformerURL            string
@@ -478,6 +578,8 @@ var (
 func loadConfig() {
 	config.RegisterBoolConfigVariable(true, &enablePipelining, false, "Processor.enablePipelining")
 	config.RegisterIntConfigVariable(0, &pipelineBufferedItems, false, 1, "Processor.pipelineBufferedItems")
+	// Run a pipeline per source ("source"), or a single pipeline for all of them ("none")
+	config.RegisterStringConfigVariable(NoIsolation, &isolationMode, false, "Processor.isolationMode")
 	config.RegisterIntConfigVariable(2000, &subJobSize, false, 1, "Processor.subJobSize")
 	config.RegisterDurationConfigVariable(5000, &maxLoopSleep, true, time.Millisecond, []string{"Processor.maxLoopSleep", "Processor.maxLoopSleepInMS"}...)
 	config.RegisterDurationConfigVariable(5, &storeTimeout, true, time.Minute, "Processor.storeTimeout")
@@ -491,11 +593,20 @@ func loadConfig() {
 	config.RegisterIntConfigVariable(200, &userTransformBatchSize, true, 1, "Processor.userTransformBatchSize")
 	// Enable dedup of incoming events by default
 	config.RegisterBoolConfigVariable(false, &enableDedup, false, "Dedup.enableDedup")
+	// Cache the outputs of user transformations, keyed by transformation version and event
+	config.RegisterBoolConfigVariable(false, &enableTransformationCache, false, "Processor.transformationCache.enabled")
+	// Keep the events failing user transformations in a dead-letter queue, for reprocessing them
+	config.RegisterBoolConfigVariable(false, &enableTransformationDLQ, false, "Processor.transformationDLQ.enabled")
+	// Infer the schemas of events and report them to the control plane
+	config.RegisterBoolConfigVariable(false, &enableEventCatalog, false, "Processor.eventCatalog.enabled")
+	// handoff tokens prevent jobs from being stored twice in the router's jobsdb, e.g. after a crash
+	config.RegisterBoolConfigVariable(false, &enableHandoffTokens, true, "Processor.enableHandoffTokens")
 	config.RegisterBoolConfigVariable(true, &enableEventCount, true, "Processor.enableEventCount")
 	// EventSchemas feature. false by default
 	config.RegisterBoolConfigVariable(false, &enableEventSchemasFeature, false, "EventSchemas.enableEventSchemasFeature")
 	config.RegisterBoolConfigVariable(false, &enableEventSchemasAPIOnly, true, "EventSchemas.enableEventSchemasAPIOnly")
 	config.RegisterIntConfigVariable(10000, &maxEventsToProcess, true, 1, "Processor.maxLoopProcessEvents")
+	loadBatchSizingConfig()
 
 	batchDestinations = misc.BatchDestinations()
 	config.RegisterIntConfigVariable(5, &transformTimesPQLength, false, 1, "Processor.transformTimesPQLength")
@@ -585,10 +696,18 @@ func (proc *HandleT) backendConfigSubscriber() {
 		writeKeyDestinationMap = make(map[string][]backendconfig.DestinationT)
 		writeKeySourceMap = map[string]backendconfig.SourceT{}
 		destinationIDtoTypeMap = make(map[string]string)
+		pausedWorkspaces = make(map[string]struct{})
+		pausedSources = make(map[string]struct{})
 		for workspaceID, wConfig := range config {
+			if wConfig.Settings.Paused {
+				pausedWorkspaces[workspaceID] = struct{}{}
+			}
 			for i := range wConfig.Sources {
 				source := &wConfig.Sources[i]
 				writeKeySourceMap[source.WriteKey] = *source
+				if wConfig.Settings.Paused {
+					pausedSources[source.ID] = struct{}{}
+				}
 				if source.Enabled {
 					writeKeyDestinationMap[source.WriteKey] = source.Destinations
 					for j := range source.Destinations {
@@ -603,6 +722,38 @@ func (proc *HandleT) backendConfigSubscriber() {
 	}
 }
 
+// getPausedWorkspaceIDs returns the workspaces paused by the control plane. Pipelines of the sources of paused
+// workspaces don't read their jobs, while the other pipelines exclude them from their queries, for the jobs to be left
+// unprocessed until the workspaces are resumed.
+func getPausedWorkspaceIDs() []string {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	var workspaceIDs []string
+	for workspaceID := range pausedWorkspaces {
+		workspaceIDs = append(workspaceIDs, workspaceID)
+	}
+	sort.Strings(workspaceIDs)
+	return workspaceIDs
+}
+
+// excludedWorkspaceIDs returns the workspaces whose jobs aren't picked up: the paused ones and the ones exceeding their
+// events quota
+func (proc *HandleT) excludedWorkspaceIDs() []string {
+	workspaceIDs := getPausedWorkspaceIDs()
+	if proc.workspaceQuotas == nil {
+		return workspaceIDs
+	}
+	return misc.Unique(append(workspaceIDs, proc.workspaceQuotas.EventsQuotaExceeded()...))
+}
+
+// isSourcePaused returns whether the workspace of the source is paused by the control plane
+func isSourcePaused(sourceID string) bool {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	_, ok := pausedSources[sourceID]
+	return ok
+}
+
 func getWorkspaceLibraries(workspaceID string) backendconfig.LibrariesT {
 	configSubscriberLock.RLock()
 	defer configSubscriberLock.RUnlock()
@@ -700,6 +851,7 @@ func makeCommonMetadataFromSingularEvent(singularEvent types.SingularEventT, bat
 	commonMetadata.EventName, _ = misc.MapLookup(singularEvent, "event").(string)
 	commonMetadata.EventType, _ = misc.MapLookup(singularEvent, "type").(string)
 	commonMetadata.SourceDefinitionID = source.SourceDefinition.ID
+	commonMetadata.TraceParent = gjson.GetBytes(batchEvent.Parameters, "traceparent").Str
 
 	return &commonMetadata
 }
@@ -726,6 +878,7 @@ func enhanceWithMetadata(commonMetadata *transformer.MetadataT, event *transform
 	metadata.EventName = commonMetadata.EventName
 	metadata.EventType = commonMetadata.EventType
 	metadata.SourceDefinitionID = commonMetadata.SourceDefinitionID
+	metadata.TraceParent = commonMetadata.TraceParent
 	metadata.DestinationID = destination.ID
 	metadata.DestinationDefinitionID = destination.DestinationDefinition.ID
 	metadata.DestinationType = destination.DestinationDefinition.Name
@@ -779,6 +932,7 @@ func recordEventDeliveryStatus(jobsByDestID map[string][]*jobsdb.JobT) {
 				deliveryStatus := destinationdebugger.DeliveryStatusT{
 					EventName:     eventName,
 					EventType:     eventType,
+					MessageID:     gjson.GetBytes(eventPayload, "messageId").String(),
 					SentAt:        sentAt,
 					DestinationID: destID,
 					SourceID:      sourceID,
@@ -1179,6 +1333,11 @@ func (proc *HandleT) processJobsForDest(subJobs subJob, parsedEventList [][]type
 	outCountMap := make(map[string]int64) // destinations enabled
 	destFilterStatusDetailMap := make(map[string]*types.StatusDetail)
 
+	// reprocessed events of the transformation dead-letter queue are only sent to the destinations they failed for, and
+	// replayed events to the destinations they are replayed for, if any
+	restrictedDestIDsByJobID := make(map[int64][]string)
+	enrichersByWriteKey := make(map[string][]enricher.Enricher)
+
 	for idx, batchEvent := range jobList {
 
 		var singularEvents []types.SingularEventT
@@ -1192,10 +1351,14 @@ func (proc *HandleT) processJobsForDest(subJobs subJob, parsedEventList [][]type
 		writeKey := gjson.Get(string(batchEvent.EventPayload), "writeKey").Str
 		requestIP := gjson.Get(string(batchEvent.EventPayload), "requestIP").Str
 		receivedAt := gjson.Get(string(batchEvent.EventPayload), "receivedAt").Time()
+		restrictedDestIDs := restrictedDestinationIDs(batchEvent.Parameters)
+		if len(restrictedDestIDs) > 0 {
+			restrictedDestIDsByJobID[batchEvent.JobID] = restrictedDestIDs
+		}
 
 		if ok {
 			var duplicateIndexes []int
-			if enableDedup {
+			if enableDedup && len(restrictedDestIDs) == 0 {
 				var allMessageIdsInBatch []string
 				for _, singularEvent := range singularEvents {
 					allMessageIdsInBatch = append(allMessageIdsInBatch, misc.GetStringifiedData(singularEvent["messageId"]))
@@ -1228,6 +1391,18 @@ func (proc *HandleT) processJobsForDest(subJobs subJob, parsedEventList [][]type
 					continue
 				}
 
+				if proc.enrichers != nil {
+					enrichers, ok := enrichersByWriteKey[writeKey]
+					if !ok {
+						enrichers = proc.enrichers.ForSource(sourceForSingularEvent.ID, sourceForSingularEvent.Config)
+						enrichersByWriteKey[writeKey] = enrichers
+					}
+					enricher.Enrich(enrichers, singularEvent, requestIP)
+				}
+				if proc.eventCatalog != nil {
+					proc.eventCatalog.Record(sourceForSingularEvent.WorkspaceID, sourceForSingularEvent.ID, singularEvent)
+				}
+
 				commonMetadataFromSingularEvent := makeCommonMetadataFromSingularEvent(
 					singularEvent,
 					batchEvent,
@@ -1352,6 +1527,8 @@ func (proc *HandleT) processJobsForDest(subJobs subJob, parsedEventList [][]type
 	// TRACKING PLAN - END
 
 	// The below part further segregates events by sourceID and DestinationID.
+	filterRulesByDestID := make(map[string]*eventfilter.FilterRulesT)
+	filteredEventsByDestID := make(map[string]int)
 	for writeKeyT, eventList := range validatedEventsByWriteKey {
 		for idx := range eventList {
 			event := &eventList[idx]
@@ -1369,6 +1546,13 @@ func (proc *HandleT) processJobsForDest(subJobs subJob, parsedEventList [][]type
 				// Adding a singular event multiple times if there are multiple destinations of same type
 				for idx := range enabledDestinationsList {
 					destination := &enabledDestinationsList[idx]
+					if restrictedDestIDs, ok := restrictedDestIDsByJobID[event.Metadata.JobID]; ok && !misc.Contains(restrictedDestIDs, destination.ID) {
+						continue
+					}
+					if !proc.allowedByFilterRules(destination, singularEvent, filterRulesByDestID) {
+						filteredEventsByDestID[destination.ID]++
+						continue
+					}
 					shallowEventCopy := transformer.TransformerEventT{}
 					shallowEventCopy.Message = singularEvent
 					shallowEventCopy.Destination = reflect.ValueOf(*destination).Interface().(backendconfig.DestinationT)
@@ -1397,6 +1581,10 @@ func (proc *HandleT) processJobsForDest(subJobs subJob, parsedEventList [][]type
 		}
 	}
 
+	for destID, count := range filteredEventsByDestID {
+		proc.statsFactory.NewTaggedStat("processor.event_filter_rules_dropped", stats.CountType, stats.Tags{"destination_id": destID}).Count(count)
+	}
+
 	if len(statusList) != len(jobList) {
 		panic(fmt.Errorf("len(statusList):%d != len(jobList):%d", len(statusList), len(jobList)))
 	}
@@ -1645,6 +1833,9 @@ func (proc *HandleT) Store(in *storeMessage) {
 			panic(err)
 		}
 		recordEventDeliveryStatus(in.procErrorJobsByDestID)
+		if proc.transformationDLQ != nil {
+			proc.addToTransformationDLQ(in.procErrorJobs)
+		}
 	}
 	writeJobsTime := time.Since(beforeStoreStatus)
 
@@ -1786,7 +1977,7 @@ func (proc *HandleT) transformSrcDest(
 
 		trace.WithRegion(ctx, "UserTransform", func() {
 			startedAt := time.Now()
-			response = proc.transformer.Transform(ctx, eventList, integrations.GetUserTransformURL(), userTransformBatchSize)
+			response = proc.userTransform(ctx, eventList)
 			d := time.Since(startedAt)
 			userTransformationStat.transformTime.SendTiming(d)
 			proc.addToTransformEventByTimePQ(&TransformRequestT{
@@ -1801,6 +1992,7 @@ func (proc *HandleT) transformSrcDest(
 			var successCountMetadataMap map[string]MetricMetadata
 			eventsToTransform, successMetrics, successCountMap, successCountMetadataMap = proc.getDestTransformerEvents(response, commonMetaData, destination, transformer.UserTransformerStage, trackingPlanEnabled, transformationEnabled)
 			failedJobs, failedMetrics, failedCountMap := proc.getFailedEventJobs(response, commonMetaData, eventsByMessageID, transformer.UserTransformerStage, transformationEnabled, trackingPlanEnabled)
+			withTransformation(failedJobs, destination)
 			proc.saveFailedJobs(failedJobs)
 			if _, ok := procErrorJobsByDestID[destID]; !ok {
 				procErrorJobsByDestID[destID] = make([]*jobsdb.JobT, 0)
@@ -1811,7 +2003,7 @@ func (proc *HandleT) transformSrcDest(
 			proc.logger.Debug("Custom Transform output size", len(eventsToTransform))
 			trace.Logf(ctx, "UserTransform", "User Transform output size: %d", len(eventsToTransform))
 
-			transformationdebugger.UploadTransformationStatus(&transformationdebugger.TransformationStatusT{SourceID: sourceID, DestID: destID, Destination: destination, UserTransformedEvents: eventsToTransform, EventsByMessageID: eventsByMessageID, FailedEvents: response.FailedEvents, UniqueMessageIds: uniqueMessageIdsBySrcDestKey[srcAndDestKey]})
+			transformationdebugger.UploadTransformationStatus(&transformationdebugger.TransformationStatusT{SourceID: sourceID, DestID: destID, Destination: destination, UserTransformedEvents: eventsToTransform, EventsByMessageID: eventsByMessageID, FailedEvents: response.FailedEvents, UniqueMessageIds: uniqueMessageIdsBySrcDestKey[srcAndDestKey], LogsByMessageID: transformationdebugger.LogsByMessageID(response)})
 
 			// REPORTING - START
 			if proc.isReportingEnabled() {
@@ -1985,7 +2177,12 @@ func (proc *HandleT) transformSrcDest(
 		})
 	}
 
+	if batchrouter.IsWarehouseDestination(destType) {
+		response.Events = proc.multiplexWarehouseEvents(destination, response.Events)
+	}
+
 	trace.WithRegion(ctx, "MarshalForDB", func() {
+		handoffTokens := make(map[string]int)
 		// Save the JSON in DB. This is what the router uses
 		for i := range response.Events {
 			destEventJSON, err := jsonfast.Marshal(response.Events[i].Output)
@@ -2044,6 +2241,7 @@ func (proc *HandleT) transformSrcDest(
 				DestinationDefinitionID: destDefID,
 				RecordID:                recordId,
 				WorkspaceId:             workspaceId,
+				TraceParent:             metadata.TraceParent,
 			}
 			marshalledParams, err := jsonfast.Marshal(params)
 			if err != nil {
@@ -2061,6 +2259,9 @@ func (proc *HandleT) transformSrcDest(
 				EventPayload: destEventJSON,
 				WorkspaceId:  workspaceId,
 			}
+			if enableHandoffTokens {
+				newJob.HandoffToken = handoffToken(handoffTokens, jobId, destID, messageId)
+			}
 			if misc.Contains(batchDestinations, newJob.CustomVal) {
 				batchDestJobs = append(batchDestJobs, &newJob)
 			} else {
@@ -2076,6 +2277,15 @@ func (proc *HandleT) transformSrcDest(
 	}
 }
 
+// handoffToken returns the token of a job handed off to the router, identified by the gateway job, the destination and
+// the message it results from, along with its rank among the jobs resulting from the same message
+func handoffToken(tokens map[string]int, gatewayJobID int64, destinationID, messageID string) string {
+	token := fmt.Sprintf("%d/%s/%s", gatewayJobID, destinationID, messageID)
+	rank := tokens[token]
+	tokens[token]++
+	return fmt.Sprintf("%s/%d", token, rank)
+}
+
 func (proc *HandleT) saveFailedJobs(failedJobs []*jobsdb.JobT) {
 	if len(failedJobs) > 0 {
 		rsourcesStats := rsources.NewFailedJobsCollector(proc.rsourcesService)
@@ -2086,6 +2296,26 @@ func (proc *HandleT) saveFailedJobs(failedJobs []*jobsdb.JobT) {
 	}
 }
 
+// allowedByFilterRules returns whether an event is to be sent to a destination according to the destination's event
+// filter rules, if any, parsing them once per batch
+func (proc *HandleT) allowedByFilterRules(destination *backendconfig.DestinationT, event types.SingularEventT, filterRulesByDestID map[string]*eventfilter.FilterRulesT) bool {
+	rules, ok := filterRulesByDestID[destination.ID]
+	if !ok {
+		var err error
+		rules, _, err = eventfilter.GetFilterRules(destination)
+		if err != nil {
+			// events are not dropped on account of misconfigured rules
+			proc.logger.Errorf("Ignoring the event filter rules of destination %s: %v", destination.ID, err)
+		}
+		filterRulesByDestID[destination.ID] = rules
+	}
+	if rules == nil {
+		return true
+	}
+	allow, _ := rules.Allow(event)
+	return allow
+}
+
 func ConvertToFilteredTransformerResponse(events []transformer.TransformerEventT, filter bool) transformer.ResponseT {
 	var responses []transformer.TransformerResponseT
 	var failedEvents []transformer.TransformerResponseT
@@ -2170,21 +2400,24 @@ func (proc *HandleT) addToTransformEventByTimePQ(event *TransformRequestT, pq *t
 	}
 }
 
-func (proc *HandleT) getJobs() jobsdb.JobsResult {
+// getJobs reads up to limit unprocessed gateway jobs, only the ones of the given source if any
+func (proc *HandleT) getJobs(sourceID string, limit int) jobsdb.JobsResult {
 	s := time.Now()
 
-	proc.logger.Debugf("Processor DB Read size: %d", maxEventsToProcess)
+	proc.logger.Debugf("Processor DB Read size: %d", limit)
 
-	eventCount := maxEventsToProcess
+	eventCount := limit
 	if !enableEventCount {
 		eventCount = 0
 	}
 	unprocessedList, err := misc.QueryWithRetriesAndNotify(context.Background(), proc.jobdDBQueryRequestTimeout, proc.jobdDBMaxRetries, func(ctx context.Context) (jobsdb.JobsResult, error) {
 		return proc.gatewayDB.GetUnprocessed(ctx, jobsdb.GetQueryParamsT{
-			CustomValFilters: []string{GWCustomVal},
-			JobsLimit:        maxEventsToProcess,
-			EventsLimit:      eventCount,
-			PayloadSizeLimit: proc.payloadLimit,
+			CustomValFilters:    []string{GWCustomVal},
+			ParameterFilters:    sourceParameterFilters(sourceID),
+			ExcludeWorkspaceIDs: proc.excludedWorkspaceIDs(),
+			JobsLimit:           limit,
+			EventsLimit:         eventCount,
+			PayloadSizeLimit:    proc.payloadLimit,
 		})
 	}, sendQueryRetryStats)
 	if err != nil {
@@ -2195,7 +2428,14 @@ func (proc *HandleT) getJobs() jobsdb.JobsResult {
 	totalPayloadBytes := 0
 	for _, job := range unprocessedList.Jobs {
 		totalPayloadBytes += len(job.EventPayload)
+		if proc.workspaceQuotas != nil {
+			proc.workspaceQuotas.ConsumeProcessedEvents(job.WorkspaceId, job.EventCount)
+		}
 
+		if sourceID != "" {
+			// jobs of a source are out of sequence, by definition
+			continue
+		}
 		if job.JobID <= proc.lastJobID {
 			proc.logger.Debugf("Out of order job_id: prev: %d cur: %d", proc.lastJobID, job.JobID)
 			proc.stats.statDBReadOutOfOrder.Count(1)
@@ -2268,7 +2508,7 @@ func (proc *HandleT) markExecuting(jobs []*jobsdb.JobT) error {
 func (proc *HandleT) handlePendingGatewayJobs() bool {
 	s := time.Now()
 
-	unprocessedList := proc.getJobs()
+	unprocessedList := proc.getJobs("", maxEventsToProcess)
 
 	if len(unprocessedList.Jobs) == 0 {
 		return false
@@ -2384,15 +2624,26 @@ func jobSplitter(jobs []*jobsdb.JobT, rsourcesStats rsources.StatsCollector) []s
 // [getJobs] -chProc-> [processJobsForDest] -chTrans-> [transformations] -chStore-> [Store]
 func (proc *HandleT) mainPipeline(ctx context.Context) {
 	// waiting for reporting client setup
-	proc.logger.Infof("Processor mainPipeline started, subJobSize=%d pipelineBufferedItems=%d", subJobSize, pipelineBufferedItems)
+	proc.logger.Infof("Processor mainPipeline started, subJobSize=%d pipelineBufferedItems=%d isolationMode=%s", subJobSize, pipelineBufferedItems, isolationMode)
 
 	if proc.reporting != nil && proc.reportingEnabled {
 		if err := proc.reporting.WaitForSetup(ctx, types.CoreReportingClient); err != nil {
 			return
 		}
 	}
+	if isolationMode == SourceIsolation {
+		proc.isolatedPipelines(ctx, proc.pipeline)
+		return
+	}
+	proc.pipeline(ctx, "", nil)
+}
+
+// pipeline processes the gateway jobs, only the ones of the given source if any, until the context is cancelled or,
+// if given, stopIdle returns true when there are no jobs to process
+func (proc *HandleT) pipeline(ctx context.Context, sourceID string, stopIdle func() bool) {
 	wg := sync.WaitGroup{}
 	bufferSize := pipelineBufferedItems
+	sizer := proc.newBatchSizer(sourceID)
 
 	chProc := make(chan subJob, bufferSize)
 	wg.Add(1)
@@ -2410,11 +2661,20 @@ func (proc *HandleT) mainPipeline(ctx context.Context) {
 					nextSleepTime = proc.maxLoopSleep
 					continue
 				}
+				if sourceID != "" && isSourcePaused(sourceID) {
+					nextSleepTime = proc.maxLoopSleep
+					continue
+				}
 				dbReadStart := time.Now()
-				jobs := proc.getJobs()
+				limit := sizer.next()
+				jobs := proc.getJobs(sourceID, limit)
+				sizer.observeRead(jobs)
 				rsourcesStats := rsources.NewStatsCollector(proc.rsourcesService)
 				rsourcesStats.BeginProcessing(jobs.Jobs)
 				if len(jobs.Jobs) == 0 {
+					if stopIdle != nil && stopIdle() {
+						return
+					}
 					// no jobs found, double sleep time until maxLoopSleep
 					nextSleepTime = 2 * nextSleepTime
 					if nextSleepTime > proc.maxLoopSleep {
@@ -2437,7 +2697,7 @@ func (proc *HandleT) mainPipeline(ctx context.Context) {
 				proc.stats.DBReadThroughput.Count(dbReadThroughput)
 
 				// nextSleepTime is dependent on the number of events read in this loop
-				emptyRatio := 1.0 - math.Min(1, float64(events)/float64(maxEventsToProcess))
+				emptyRatio := 1.0 - math.Min(1, float64(events)/float64(limit))
 				nextSleepTime = time.Duration(emptyRatio * float64(proc.readLoopSleep))
 
 				subJobs := jobSplitter(jobs.Jobs, rsourcesStats)
@@ -2459,13 +2719,23 @@ func (proc *HandleT) mainPipeline(ctx context.Context) {
 	})
 
 	// we need the below buffer size to ensure that `proc.Store(*mergedJob)` is not blocking rest of the Go routines.
-	chStore := make(chan *storeMessage, (bufferSize+1)*(maxEventsToProcess/subJobSize+1))
+	// With adaptive batch sizing, its maximum size isn't hot reloadable for the buffer to hold the sub-jobs of any batch.
+	chStore := make(chan *storeMessage, (bufferSize+1)*(sizer.maxSize()/subJobSize+1))
 	wg.Add(1)
 	rruntime.Go(func() {
 		defer wg.Done()
 		defer close(chStore)
+		// the latency of the transformations is observed per batch read, the sub-jobs of which are transformed in turn
+		var batchLatency time.Duration
 		for msg := range chTrans {
-			chStore <- proc.transformations(msg)
+			start := time.Now()
+			storeMsg := proc.transformations(msg)
+			batchLatency += time.Since(start)
+			if !storeMsg.hasMore {
+				sizer.observeTransform(batchLatency)
+				batchLatency = 0
+			}
+			chStore <- storeMsg
 		}
 	})
 
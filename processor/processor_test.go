@@ -389,6 +389,86 @@ var _ = Describe("Processor", func() {
 			Expect(didWork).To(Equal(false))
 		})
 
+		It("should only read the jobs of its source in an isolated pipeline", func() {
+			mockTransformer := mocksTransformer.NewMockTransformer(c.mockCtrl)
+			mockTransformer.EXPECT().Setup().Times(1)
+
+			processor := &HandleT{
+				transformer: mockTransformer,
+			}
+
+			processor.Setup(c.mockBackendConfig, c.mockGatewayJobsDB, c.mockRouterJobsDB, c.mockBatchRouterJobsDB, c.mockProcErrorsDB, &clearDB, c.MockReportingI, c.MockMultitenantHandle, transientsource.NewEmptyService(), fileuploader.NewDefaultProvider(), c.MockRsourcesService)
+
+			payloadLimit := processor.payloadLimit
+			parameterFilters := []jobsdb.ParameterFilterT{{Name: "source_id", Value: SourceIDEnabled}}
+			c.mockGatewayJobsDB.EXPECT().GetUnprocessed(gomock.Any(), jobsdb.GetQueryParamsT{CustomValFilters: gatewayCustomVal, ParameterFilters: parameterFilters, JobsLimit: c.dbReadBatchSize, EventsLimit: c.processEventSize, PayloadSizeLimit: payloadLimit}).Return(jobsdb.JobsResult{Jobs: emptyJobsList}, nil).Times(1)
+			Expect(processor.getJobs(SourceIDEnabled, maxEventsToProcess).Jobs).To(BeEmpty())
+
+			pendingJobs := []*jobsdb.JobT{
+				{JobID: 1, Parameters: []byte(fmt.Sprintf(`{"source_id": %q}`, SourceIDEnabled))},
+				{JobID: 2, Parameters: []byte(`{"source_id": "deleted-source"}`)},
+				{JobID: 3, Parameters: []byte(fmt.Sprintf(`{"source_id": %q}`, SourceIDEnabled))},
+			}
+			excludeParameterFilters := []jobsdb.ParameterFilterT{{Name: "source_id", Value: "running-source"}}
+			c.mockGatewayJobsDB.EXPECT().GetUnprocessed(gomock.Any(), jobsdb.GetQueryParamsT{CustomValFilters: gatewayCustomVal, ExcludeParameterFilters: excludeParameterFilters, JobsLimit: 100}).Return(jobsdb.JobsResult{Jobs: pendingJobs}, nil).Times(1)
+			sourceIDs, err := processor.getPendingSourceIDs(context.Background(), map[string]*isolatedPipelineT{"running-source": {}})
+			Expect(err).To(BeNil())
+			Expect(sourceIDs).To(Equal(map[string]struct{}{SourceIDEnabled: {}, "deleted-source": {}}))
+		})
+
+		It("should exclude the jobs of paused workspaces from its queries, without reading the ones of their sources in isolated pipelines", func() {
+			mockTransformer := mocksTransformer.NewMockTransformer(c.mockCtrl)
+			mockTransformer.EXPECT().Setup().Times(1)
+
+			processor := &HandleT{
+				transformer: mockTransformer,
+			}
+
+			processor.Setup(c.mockBackendConfig, c.mockGatewayJobsDB, c.mockRouterJobsDB, c.mockBatchRouterJobsDB, c.mockProcErrorsDB, &clearDB, c.MockReportingI, c.MockMultitenantHandle, transientsource.NewEmptyService(), fileuploader.NewDefaultProvider(), c.MockRsourcesService)
+
+			configSubscriberLock.Lock()
+			pausedWorkspaces = map[string]struct{}{"paused-workspace-b": {}, "paused-workspace-a": {}}
+			pausedSources = map[string]struct{}{"paused-source": {}}
+			configSubscriberLock.Unlock()
+			defer func() {
+				configSubscriberLock.Lock()
+				pausedWorkspaces, pausedSources = nil, nil
+				configSubscriberLock.Unlock()
+			}()
+
+			Expect(isSourcePaused("paused-source")).To(BeTrue())
+			Expect(isSourcePaused(SourceIDEnabled)).To(BeFalse())
+
+			c.mockGatewayJobsDB.EXPECT().GetUnprocessed(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, params jobsdb.GetQueryParamsT) (jobsdb.JobsResult, error) {
+				Expect(params.ExcludeWorkspaceIDs).To(Equal([]string{"paused-workspace-a", "paused-workspace-b"}))
+				return jobsdb.JobsResult{}, nil
+			}).Times(1)
+			Expect(processor.getJobs("", 10).Jobs).To(BeEmpty())
+		})
+
+		It("should exclude the jobs of workspaces exceeding their events quota from its queries, consuming the events picked up", func() {
+			mockTransformer := mocksTransformer.NewMockTransformer(c.mockCtrl)
+			mockTransformer.EXPECT().Setup().Times(1)
+
+			quotas := &mockWorkspaceQuotas{exceeded: []string{"workspace-a"}, consumed: map[string]int{}}
+			processor := &HandleT{
+				transformer:     mockTransformer,
+				workspaceQuotas: quotas,
+			}
+
+			processor.Setup(c.mockBackendConfig, c.mockGatewayJobsDB, c.mockRouterJobsDB, c.mockBatchRouterJobsDB, c.mockProcErrorsDB, &clearDB, c.MockReportingI, c.MockMultitenantHandle, transientsource.NewEmptyService(), fileuploader.NewDefaultProvider(), c.MockRsourcesService)
+
+			c.mockGatewayJobsDB.EXPECT().GetUnprocessed(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, params jobsdb.GetQueryParamsT) (jobsdb.JobsResult, error) {
+				Expect(params.ExcludeWorkspaceIDs).To(Equal([]string{"workspace-a"}))
+				return jobsdb.JobsResult{Jobs: []*jobsdb.JobT{
+					{JobID: 1, WorkspaceId: "workspace-b", EventCount: 2},
+					{JobID: 2, WorkspaceId: "workspace-b", EventCount: 3},
+				}}, nil
+			}).Times(1)
+			Expect(processor.getJobs("", 10).Jobs).To(HaveLen(2))
+			Expect(quotas.consumed).To(Equal(map[string]int{"workspace-b": 5}))
+		})
+
 		It("should process unprocessed jobs to destination without user transformation", func() {
 			messages := map[string]mockEventData{
 				// this message should be delivered only to destination A
@@ -1051,7 +1131,7 @@ var _ = Describe("Processor", func() {
 				var paramsMap, expectedParamsMap map[string]interface{}
 				err := json.Unmarshal(job.Parameters, &paramsMap)
 				Expect(err).To(BeNil())
-				expectedStr := []byte(fmt.Sprintf(`{"source_id": "%v", "destination_id": "enabled-destination-b", "source_job_run_id": "", "error": "error-combined", "status_code": 400, "stage": "user_transformer", "source_task_run_id":"", "record_id": null}`, SourceIDEnabled))
+				expectedStr := []byte(fmt.Sprintf(`{"source_id": "%v", "destination_id": "enabled-destination-b", "source_job_run_id": "", "error": "error-combined", "status_code": 400, "stage": "user_transformer", "source_task_run_id":"", "record_id": null, "transformation_id": "", "transformation_version_id": "transformation-version-id"}`, SourceIDEnabled))
 				err = json.Unmarshal(expectedStr, &expectedParamsMap)
 				Expect(err).To(BeNil())
 				equals := reflect.DeepEqual(paramsMap, expectedParamsMap)
@@ -2561,3 +2641,16 @@ var _ = Describe("TestConfigFilter", func() {
 		})
 	})
 })
+
+type mockWorkspaceQuotas struct {
+	exceeded []string
+	consumed map[string]int
+}
+
+func (m *mockWorkspaceQuotas) EventsQuotaExceeded() []string {
+	return m.exceeded
+}
+
+func (m *mockWorkspaceQuotas) ConsumeProcessedEvents(workspaceID string, events int) {
+	m.consumed[workspaceID] += events
+}
@@ -101,6 +101,19 @@ func (s *StashRpcHandler) GetJobIDStatus(arg string, result *string) (err error)
 	return err
 }
 
+// SearchJobs returns the jobs of the events matching the search given as JSON argument, along with their status history
+func (s *StashRpcHandler) SearchJobs(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	response, err := s.ReadOnlyJobsDB.SearchJobs(arg)
+	*result = response
+	return err
+}
+
 type DestinationCountResult struct {
 	Count    int
 	DestName string
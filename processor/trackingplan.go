@@ -7,10 +7,32 @@ import (
 	"github.com/rudderlabs/rudder-server/jobsdb"
 	"github.com/rudderlabs/rudder-server/processor/integrations"
 	"github.com/rudderlabs/rudder-server/processor/transformer"
+	sourcedebugger "github.com/rudderlabs/rudder-server/services/debugger/source"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/types"
 )
 
+// Violations of tracking plans are either errors, e.g. a required property missing, or warnings, e.g. an unplanned
+// event. Events with violations are forwarded to destinations by default, or dropped to the proc errors jobsdb,
+// depending on the tracking plan config of their type, by the severity of their violations:
+//
+//	"onErrorViolations": "drop",
+//	"onWarningViolations": "forward"
+const (
+	violationSeverityError   = "error"
+	violationSeverityWarning = "warning"
+
+	violationPolicyForward = "forward"
+	violationPolicyDrop    = "drop"
+)
+
+// warningViolationTypes are the types of violations which are warnings, all others being errors,
+// unless the transformer tells their severity
+var warningViolationTypes = map[string]struct{}{
+	"Unplanned-Event":       {},
+	"Additional-Properties": {},
+}
+
 type TrackingPlanStatT struct {
 	numEvents                  stats.Measurement
 	numValidationSuccessEvents stats.Measurement
@@ -89,6 +111,7 @@ func (proc *HandleT) validateEvents(groupedEventsByWriteKey map[WriteKeyT][]tran
 		}
 
 		enhanceWithViolation(response, eventList[0].Metadata.TrackingPlanId, eventList[0].Metadata.TrackingPlanVersion)
+		response = proc.enforceViolationPolicies(string(writeKey), response)
 
 		transformerEvent := eventList[0]
 		destination := &transformerEvent.Destination
@@ -126,6 +149,88 @@ func (proc *HandleT) validateEvents(groupedEventsByWriteKey map[WriteKeyT][]tran
 	return validatedEventsByWriteKey, validatedReportMetrics, validatedErrorJobs, trackingPlanEnabledMap
 }
 
+// violationSeverity returns the severity of a violation
+func violationSeverity(violation *transformer.ValidationErrorT) string {
+	switch severity := violation.Meta["severity"]; severity {
+	case violationSeverityError, violationSeverityWarning:
+		return severity
+	}
+	if _, ok := warningViolationTypes[violation.Type]; ok {
+		return violationSeverityWarning
+	}
+	return violationSeverityError
+}
+
+// violationsPolicy returns the policy for an event's violations, the one of their highest severity
+func violationsPolicy(event *transformer.TransformerResponseT) string {
+	severity := violationSeverityWarning
+	for i := range event.ValidationErrors {
+		if violationSeverity(&event.ValidationErrors[i]) == violationSeverityError {
+			severity = violationSeverityError
+			break
+		}
+	}
+	key := "onWarningViolations"
+	if severity == violationSeverityError {
+		key = "onErrorViolations"
+	}
+	if policy, _ := event.Metadata.MergedTpConfig[key].(string); policy == violationPolicyDrop {
+		return violationPolicyDrop
+	}
+	return violationPolicyForward
+}
+
+// enforceViolationPolicies moves the validated events whose violations are to be dropped to the failed events, counts
+// the violations per source and records the events with violations in the source's live events
+func (proc *HandleT) enforceViolationPolicies(writeKey string, response transformer.ResponseT) transformer.ResponseT {
+	violationCounts := make(map[[4]string]int) // by source, tracking plan, violation type and severity
+	countViolations := func(event *transformer.TransformerResponseT) {
+		for i := range event.ValidationErrors {
+			violation := &event.ValidationErrors[i]
+			violationCounts[[4]string{event.Metadata.SourceID, event.Metadata.TrackingPlanId, violation.Type, violationSeverity(violation)}]++
+		}
+	}
+
+	// events failing validation are not forwarded anyway
+	for i := range response.FailedEvents {
+		event := &response.FailedEvents[i]
+		if len(event.ValidationErrors) > 0 {
+			countViolations(event)
+			sourcedebugger.RecordTrackingPlanViolations(writeKey, event.Output, event.ValidationErrors, true)
+		}
+	}
+
+	events := make([]transformer.TransformerResponseT, 0, len(response.Events))
+	for i := range response.Events {
+		event := response.Events[i]
+		if len(event.ValidationErrors) == 0 {
+			events = append(events, event)
+			continue
+		}
+		countViolations(&event)
+		policy := violationsPolicy(&event)
+		sourcedebugger.RecordTrackingPlanViolations(writeKey, event.Output, event.ValidationErrors, policy == violationPolicyDrop)
+		if policy == violationPolicyDrop {
+			event.StatusCode = 400
+			event.Error = "event dropped for violating the tracking plan"
+			response.FailedEvents = append(response.FailedEvents, event)
+			continue
+		}
+		events = append(events, event)
+	}
+	response.Events = events
+
+	for key, count := range violationCounts {
+		proc.statsFactory.NewTaggedStat("proc_tp_violations", stats.CountType, stats.Tags{
+			"source":         key[0],
+			"trackingPlanId": key[1],
+			"violationType":  key[2],
+			"severity":       key[3],
+		}).Count(count)
+	}
+	return response
+}
+
 // makeCommonMetadataFromTransformerEvent Creates a new MetadataT instance
 func makeCommonMetadataFromTransformerEvent(transformerEvent *transformer.TransformerEventT) *transformer.MetadataT {
 	metadata := transformerEvent.Metadata
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/rudderlabs/rudder-server/processor/transformer"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -117,3 +119,57 @@ func TestReportViolations(t *testing.T) {
 		}
 	})
 }
+
+func TestEnforceViolationPolicies(t *testing.T) {
+	newEvent := func(messageID string, tpConfig map[string]interface{}, violationTypes ...string) transformer.TransformerResponseT {
+		event := transformer.TransformerResponseT{
+			Metadata:   transformer.MetadataT{MessageID: messageID, SourceID: "source", TrackingPlanId: "tp", MergedTpConfig: tpConfig},
+			Output:     map[string]interface{}{"messageId": messageID, "context": map[string]interface{}{}},
+			StatusCode: 200,
+		}
+		for _, violationType := range violationTypes {
+			event.ValidationErrors = append(event.ValidationErrors, transformer.ValidationErrorT{Type: violationType})
+		}
+		return event
+	}
+	messageIDs := func(events []transformer.TransformerResponseT) (ids []string) {
+		for _, event := range events {
+			ids = append(ids, event.Metadata.MessageID)
+		}
+		return
+	}
+	dropErrors := map[string]interface{}{"onErrorViolations": "drop"}
+	dropAll := map[string]interface{}{"onErrorViolations": "drop", "onWarningViolations": "drop"}
+
+	store := memstats.New()
+	proc := &HandleT{statsFactory: store}
+	failed := newEvent("failed", nil, "Datatype-Mismatch")
+	failed.StatusCode = 400
+	response := proc.enforceViolationPolicies("writeKey", transformer.ResponseT{
+		Events: []transformer.TransformerResponseT{
+			newEvent("valid", dropAll),
+			newEvent("warning", dropErrors, "Unplanned-Event"),
+			newEvent("error", dropErrors, "Additional-Properties", "Required-Missing"),
+			newEvent("forwarded", nil, "Required-Missing"),
+			newEvent("warnings", dropAll, "Additional-Properties"),
+		},
+		FailedEvents: []transformer.TransformerResponseT{failed},
+	})
+	assert.Equal(t, []string{"valid", "warning", "forwarded"}, messageIDs(response.Events))
+	assert.Equal(t, []string{"failed", "error", "warnings"}, messageIDs(response.FailedEvents))
+	for _, event := range response.FailedEvents {
+		assert.Equal(t, 400, event.StatusCode)
+	}
+
+	violations := func(violationType, severity string) float64 {
+		m := store.Get("proc_tp_violations", stats.Tags{"source": "source", "trackingPlanId": "tp", "violationType": violationType, "severity": severity})
+		if m == nil {
+			return 0
+		}
+		return m.LastValue()
+	}
+	assert.EqualValues(t, 2, violations("Required-Missing", "error"))
+	assert.EqualValues(t, 1, violations("Datatype-Mismatch", "error"))
+	assert.EqualValues(t, 2, violations("Additional-Properties", "warning"))
+	assert.EqualValues(t, 1, violations("Unplanned-Event", "warning"))
+}
@@ -0,0 +1,418 @@
+// Package transformationcache caches the outputs of user transformations, so that events already transformed by a
+// transformation version, e.g. events being reprocessed or replayed, are not sent to the transformer again.
+//
+// Outputs are keyed by a hash of the event's message, its source and destination, and the versions of the
+// transformations and libraries applied to it. They are kept in memory, for the most recently used ones, and in
+// badger, and all the outputs of a transformation are dropped as soon as one of its events comes with a new version.
+// Only the outputs of events transformed successfully into events of their own are cached: events grouped together
+// by a transformation and events failing to be transformed are transformed every time.
+package transformationcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	lru "github.com/hashicorp/golang-lru"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/rudderlabs/rudder-server/processor/transformer"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+var jsonfast = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	resultKeyPrefix  = "result:"
+	versionKeyPrefix = "version:"
+)
+
+// Opt is a function that configures a cache
+type Opt func(*Cache)
+
+// WithMemEntries sets the number of outputs kept in memory
+func WithMemEntries(memEntries int) Opt {
+	return func(c *Cache) {
+		c.memEntries = memEntries
+	}
+}
+
+// WithTTL sets for how long outputs are cached
+func WithTTL(ttl time.Duration) Opt {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithStats sets the stats to emit the hits and misses of the cache to
+func WithStats(s stats.Stats) Opt {
+	return func(c *Cache) {
+		c.stats = s
+	}
+}
+
+// Cache is a cache of user transformation outputs, backed by badgerdb
+type Cache struct {
+	log        logger.Logger
+	stats      stats.Stats
+	path       string
+	memEntries int
+	ttl        time.Duration
+
+	mem *lru.Cache
+	db  *badger.DB
+
+	versionsMu sync.Mutex
+	versions   map[string]string // the current version of each transformation
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	gcDone    chan struct{}
+}
+
+// cachedOutputT is an output of a transformed event, as cached
+type cachedOutputT struct {
+	Output           map[string]interface{}         `json:"output"`
+	ValidationErrors []transformer.ValidationErrorT `json:"validationErrors"`
+}
+
+type memEntryT struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// New opens the cache stored at path
+func New(path string, opts ...Opt) (*Cache, error) {
+	c := &Cache{
+		log:        logger.NewLogger().Child("processor").Child("transformationcache"),
+		stats:      stats.Default,
+		path:       path,
+		memEntries: 10000,
+		ttl:        24 * time.Hour,
+		versions:   make(map[string]string),
+		closed:     make(chan struct{}),
+		gcDone:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	var err error
+	if c.mem, err = lru.New(c.memEntries); err != nil {
+		return nil, fmt.Errorf("creating the in-memory cache: %w", err)
+	}
+	badgerOpts := badger.DefaultOptions(c.path).WithLogger(blogger{c.log}).WithNumGoroutines(1)
+	if c.db, err = badger.Open(badgerOpts); err != nil {
+		return nil, fmt.Errorf("opening the cache at %q: %w", c.path, err)
+	}
+	go c.gcLoop()
+	return c, nil
+}
+
+// Close closes the cache
+func (c *Cache) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		<-c.gcDone
+		err = c.db.Close()
+	})
+	return err
+}
+
+// Transform returns the outputs of events, serving the ones of cached events from the cache and transforming the
+// others with transform. The outputs are returned in the order of their events.
+func (c *Cache) Transform(events []transformer.TransformerEventT, transform func([]transformer.TransformerEventT) transformer.ResponseT) transformer.ResponseT {
+	if len(events) == 0 {
+		return transform(events)
+	}
+	tags := transformationTags(events[0])
+
+	hits := make([][]transformer.TransformerResponseT, len(events))
+	var (
+		misses    []transformer.TransformerEventT
+		missKeys  []string
+		hitsCount int
+	)
+	hitsByLayer := make(map[string]int)
+	for i := range events {
+		event := &events[i]
+		key, ok := c.key(event)
+		if ok {
+			if outputs, layer, ok := c.get(key); ok {
+				hits[i] = make([]transformer.TransformerResponseT, len(outputs))
+				for j := range outputs {
+					hits[i][j] = transformer.TransformerResponseT{
+						Output:           outputs[j].Output,
+						Metadata:         event.Metadata,
+						StatusCode:       200,
+						ValidationErrors: outputs[j].ValidationErrors,
+					}
+				}
+				hitsByLayer[layer]++
+				hitsCount++
+				continue
+			}
+		}
+		misses = append(misses, *event)
+		missKeys = append(missKeys, key)
+	}
+	for layer, hits := range hitsByLayer {
+		hitTags := stats.Tags{"layer": layer}
+		for k, v := range tags {
+			hitTags[k] = v
+		}
+		c.stats.NewTaggedStat("processor.transformation_cache_hits", stats.CountType, hitTags).Count(hits)
+	}
+	c.stats.NewTaggedStat("processor.transformation_cache_misses", stats.CountType, tags).Count(len(misses))
+
+	var response transformer.ResponseT
+	if len(misses) > 0 {
+		response = transform(misses)
+		c.store(misses, missKeys, response)
+	}
+	if hitsCount == 0 {
+		return response
+	}
+
+	// outputs of transformed events are placed at the position of their (first) event
+	firstMessageID := func(r *transformer.TransformerResponseT) string {
+		if len(r.Metadata.MessageIDs) > 0 {
+			return r.Metadata.MessageIDs[0]
+		}
+		return r.Metadata.MessageID
+	}
+	transformed := make(map[string][]transformer.TransformerResponseT)
+	for i := range response.Events {
+		id := firstMessageID(&response.Events[i])
+		transformed[id] = append(transformed[id], response.Events[i])
+	}
+	outputs := make([]transformer.TransformerResponseT, 0, len(response.Events)+hitsCount)
+	for i := range events {
+		if hits[i] != nil {
+			outputs = append(outputs, hits[i]...)
+			continue
+		}
+		if rs, ok := transformed[events[i].Metadata.MessageID]; ok {
+			outputs = append(outputs, rs...)
+			delete(transformed, events[i].Metadata.MessageID)
+		}
+	}
+	for i := range response.Events {
+		id := firstMessageID(&response.Events[i])
+		if rs, ok := transformed[id]; ok {
+			outputs = append(outputs, rs...)
+			delete(transformed, id)
+		}
+	}
+	return transformer.ResponseT{Events: outputs, FailedEvents: response.FailedEvents}
+}
+
+// key returns the key of an event's outputs, if they can be cached, dropping the cached outputs of the event's
+// transformation if its version has changed
+func (c *Cache) key(event *transformer.TransformerEventT) (string, bool) {
+	if len(event.Destination.Transformations) == 0 {
+		return "", false
+	}
+	transformation := event.Destination.Transformations[0]
+	if transformation.ID == "" || transformation.VersionID == "" {
+		return "", false
+	}
+	message, err := jsonfast.Marshal(event.Message)
+	if err != nil {
+		return "", false
+	}
+	c.onVersion(transformation.ID, transformation.VersionID)
+
+	h := sha256.New()
+	for _, t := range event.Destination.Transformations {
+		h.Write([]byte(t.VersionID))
+		h.Write([]byte{0})
+	}
+	for _, library := range event.Libraries {
+		h.Write([]byte(library.VersionID))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(event.Metadata.SourceID))
+	h.Write([]byte{0})
+	h.Write([]byte(event.Metadata.DestinationID))
+	h.Write([]byte{0})
+	h.Write(message)
+	return resultsPrefix(transformation.ID) + hex.EncodeToString(h.Sum(nil)), true
+}
+
+// onVersion drops the cached outputs of a transformation when it comes with a new version
+func (c *Cache) onVersion(transformationID, versionID string) {
+	c.versionsMu.Lock()
+	defer c.versionsMu.Unlock()
+	current, ok := c.versions[transformationID]
+	if ok && current == versionID {
+		return
+	}
+	versionKey := []byte(versionKeyPrefix + transformationID)
+	if !ok { // the version the cached outputs were transformed with, as persisted
+		err := c.db.View(func(txn *badger.Txn) error {
+			item, err := txn.Get(versionKey)
+			if err != nil {
+				return err
+			}
+			return item.Value(func(val []byte) error {
+				current = string(val)
+				return nil
+			})
+		})
+		if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+			c.log.Errorf("Failed to get the cached version of transformation %s: %v", transformationID, err)
+		}
+	}
+	if current != "" && current != versionID {
+		c.log.Infof("Transformation %s changed from version %s to %s, dropping its cached outputs", transformationID, current, versionID)
+		prefix := resultsPrefix(transformationID)
+		for _, key := range c.mem.Keys() {
+			if strings.HasPrefix(key.(string), prefix) {
+				c.mem.Remove(key)
+			}
+		}
+		if err := c.db.DropPrefix([]byte(prefix)); err != nil {
+			c.log.Errorf("Failed to drop the cached outputs of transformation %s: %v", transformationID, err)
+		}
+	}
+	if current != versionID {
+		if err := c.db.Update(func(txn *badger.Txn) error {
+			return txn.Set(versionKey, []byte(versionID))
+		}); err != nil {
+			c.log.Errorf("Failed to cache the version of transformation %s: %v", transformationID, err)
+		}
+	}
+	c.versions[transformationID] = versionID
+}
+
+// get returns the cached outputs of a key, looking them up in memory first, along with the layer they were found in
+func (c *Cache) get(key string) ([]cachedOutputT, string, bool) {
+	var (
+		value []byte
+		layer string
+	)
+	if entry, ok := c.mem.Get(key); ok && time.Now().Before(entry.(memEntryT).expiresAt) {
+		value, layer = entry.(memEntryT).value, "memory"
+	} else {
+		err := c.db.View(func(txn *badger.Txn) error {
+			item, err := txn.Get([]byte(key))
+			if err != nil {
+				return err
+			}
+			if value, err = item.ValueCopy(nil); err != nil {
+				return err
+			}
+			c.mem.Add(key, memEntryT{value: value, expiresAt: time.Unix(int64(item.ExpiresAt()), 0)})
+			return nil
+		})
+		if err != nil {
+			if !errors.Is(err, badger.ErrKeyNotFound) {
+				c.log.Errorf("Failed to get cached transformation outputs: %v", err)
+			}
+			return nil, "", false
+		}
+		layer = "disk"
+	}
+	var outputs []cachedOutputT
+	if err := jsonfast.Unmarshal(value, &outputs); err != nil {
+		c.log.Errorf("Failed to unmarshal cached transformation outputs: %v", err)
+		return nil, "", false
+	}
+	return outputs, layer, true
+}
+
+// store caches the outputs of the events transformed into events of their own
+func (c *Cache) store(events []transformer.TransformerEventT, keys []string, response transformer.ResponseT) {
+	uncacheable := make(map[string]struct{})
+	outputs := make(map[string][]cachedOutputT)
+	for i := range response.Events {
+		r := &response.Events[i]
+		if len(r.Metadata.MessageIDs) > 0 {
+			for _, id := range r.Metadata.MessageIDs {
+				uncacheable[id] = struct{}{}
+			}
+			continue
+		}
+		outputs[r.Metadata.MessageID] = append(outputs[r.Metadata.MessageID], cachedOutputT{Output: r.Output, ValidationErrors: r.ValidationErrors})
+	}
+	for i := range response.FailedEvents {
+		uncacheable[response.FailedEvents[i].Metadata.MessageID] = struct{}{}
+	}
+	seen := make(map[string]int)
+	for i := range events {
+		seen[events[i].Metadata.MessageID]++
+	}
+
+	wb := c.db.NewWriteBatch()
+	defer wb.Cancel()
+	expiresAt := time.Now().Add(c.ttl)
+	for i := range events {
+		id := events[i].Metadata.MessageID
+		if keys[i] == "" || id == "" || seen[id] > 1 {
+			continue
+		}
+		if _, ok := uncacheable[id]; ok {
+			continue
+		}
+		if len(outputs[id]) == 0 {
+			continue
+		}
+		value, err := jsonfast.Marshal(outputs[id])
+		if err != nil {
+			continue
+		}
+		if err := wb.SetEntry(badger.NewEntry([]byte(keys[i]), value).WithTTL(c.ttl)); err != nil {
+			c.log.Errorf("Failed to cache transformation outputs: %v", err)
+			return
+		}
+		c.mem.Add(keys[i], memEntryT{value: value, expiresAt: expiresAt})
+	}
+	if err := wb.Flush(); err != nil {
+		c.log.Errorf("Failed to cache transformation outputs: %v", err)
+	}
+}
+
+func (c *Cache) gcLoop() {
+	defer close(c.gcDone)
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+		}
+	again: // see https://dgraph.io/docs/badger/get-started/#garbage-collection
+		if err := c.db.RunValueLogGC(0.7); err == nil {
+			goto again
+		}
+	}
+}
+
+func resultsPrefix(transformationID string) string {
+	return resultKeyPrefix + transformationID + ":"
+}
+
+func transformationTags(event transformer.TransformerEventT) stats.Tags {
+	tags := stats.Tags{"destination_id": event.Metadata.DestinationID, "transformation_id": ""}
+	if len(event.Destination.Transformations) > 0 {
+		tags["transformation_id"] = event.Destination.Transformations[0].ID
+	}
+	return tags
+}
+
+type blogger struct {
+	logger.Logger
+}
+
+func (l blogger) Warningf(fmt string, args ...interface{}) {
+	l.Warnf(fmt, args...)
+}
@@ -0,0 +1,111 @@
+package transformationcache_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/processor/transformationcache"
+	"github.com/rudderlabs/rudder-server/processor/transformer"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+)
+
+func TestCache(t *testing.T) {
+	path := t.TempDir()
+	store := memstats.New()
+	cache, err := transformationcache.New(path, transformationcache.WithMemEntries(2), transformationcache.WithStats(store))
+	require.NoError(t, err)
+
+	destination := backendconfig.DestinationT{
+		ID:              "destination",
+		Transformations: []backendconfig.TransformationT{{ID: "transformation", VersionID: "v1"}},
+	}
+	newEvent := func(messageID string) transformer.TransformerEventT {
+		return transformer.TransformerEventT{
+			Message:     map[string]interface{}{"messageId": messageID, "event": "event"},
+			Metadata:    transformer.MetadataT{MessageID: messageID, SourceID: "source", DestinationID: "destination"},
+			Destination: destination,
+		}
+	}
+	// transforms events into an output of their own, grouping the ones with a "group" message id
+	var transformed []string
+	transform := func(events []transformer.TransformerEventT) transformer.ResponseT {
+		var response transformer.ResponseT
+		var group []string
+		for _, event := range events {
+			transformed = append(transformed, event.Metadata.MessageID)
+			switch id := event.Metadata.MessageID; {
+			case id == "failing":
+				response.FailedEvents = append(response.FailedEvents, transformer.TransformerResponseT{Metadata: event.Metadata, StatusCode: 400})
+			case len(id) > 5 && id[:5] == "group":
+				group = append(group, id)
+			default:
+				response.Events = append(response.Events, transformer.TransformerResponseT{
+					Output:     map[string]interface{}{"messageId": id, "transformed": destination.Transformations[0].VersionID},
+					Metadata:   event.Metadata,
+					StatusCode: 200,
+				})
+			}
+		}
+		if len(group) > 0 {
+			response.Events = append(response.Events, transformer.TransformerResponseT{
+				Output:     map[string]interface{}{"messageIds": group},
+				Metadata:   transformer.MetadataT{MessageID: group[0], MessageIDs: group},
+				StatusCode: 200,
+			})
+		}
+		return response
+	}
+	outputIDs := func(response transformer.ResponseT) []interface{} {
+		var ids []interface{}
+		for _, event := range response.Events {
+			if id, ok := event.Output["messageId"]; ok {
+				ids = append(ids, id)
+			} else {
+				ids = append(ids, event.Output["messageIds"])
+			}
+		}
+		return ids
+	}
+	tags := stats.Tags{"destination_id": "destination", "transformation_id": "transformation"}
+	hits := func(layer string) float64 {
+		m := store.Get("processor.transformation_cache_hits", stats.Tags{"destination_id": "destination", "transformation_id": "transformation", "layer": layer})
+		if m == nil {
+			return 0
+		}
+		return m.LastValue()
+	}
+
+	events := []transformer.TransformerEventT{newEvent("1"), newEvent("2"), newEvent("failing"), newEvent("group-1"), newEvent("group-2")}
+	response := cache.Transform(events, transform)
+	require.Equal(t, []string{"1", "2", "failing", "group-1", "group-2"}, transformed)
+	require.Len(t, response.FailedEvents, 1)
+	require.EqualValues(t, 5, store.Get("processor.transformation_cache_misses", tags).LastValue())
+
+	transformed = nil
+	events = []transformer.TransformerEventT{newEvent("3"), newEvent("1"), newEvent("failing"), newEvent("group-1"), newEvent("group-2"), newEvent("2")}
+	response = cache.Transform(events, transform)
+	require.Equal(t, []string{"3", "failing", "group-1", "group-2"}, transformed, "only the outputs of events transformed into events of their own are cached")
+	require.Equal(t, []interface{}{"3", "1", []string{"group-1", "group-2"}, "2"}, outputIDs(response), "outputs are in the order of their events")
+	require.Len(t, response.FailedEvents, 1)
+	require.Equal(t, "1", response.Events[1].Metadata.MessageID)
+	require.Equal(t, "v1", response.Events[1].Output["transformed"])
+	require.EqualValues(t, 2, hits("memory"))
+
+	require.NoError(t, cache.Close())
+	cache, err = transformationcache.New(path, transformationcache.WithStats(store))
+	require.NoError(t, err)
+	defer func() { _ = cache.Close() }()
+
+	transformed = nil
+	_ = cache.Transform([]transformer.TransformerEventT{newEvent("1"), newEvent("2"), newEvent("3")}, transform)
+	require.Empty(t, transformed, "outputs are persisted")
+	require.EqualValues(t, 3, hits("disk"))
+
+	destination.Transformations[0].VersionID = "v2"
+	response = cache.Transform([]transformer.TransformerEventT{newEvent("1"), newEvent("2")}, transform)
+	require.Equal(t, []string{"1", "2"}, transformed, "outputs are dropped on a new transformation version")
+	require.Equal(t, "v2", response.Events[0].Output["transformed"])
+}
@@ -0,0 +1,172 @@
+package transformationdlq
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+// The dead-letter queue keeps the events failing user transformations, along with the error and the version of the
+// transformation they failed with, so that they can be reprocessed once the transformation is fixed. Entries are
+// kept until they are reprocessed, and deleted after a retention period either way.
+
+const tableName = "transformation_dlq"
+
+// EntryT is a batch of events of a source that failed the user transformation of a destination
+type EntryT struct {
+	ID                      int64
+	WorkspaceID             string
+	SourceID                string
+	DestinationID           string
+	TransformationID        string
+	TransformationVersionID string
+	UserID                  string
+	Payload                 json.RawMessage // array of the events
+	Error                   string
+	StatusCode              int
+	CreatedAt               time.Time
+}
+
+// DLQ is the dead-letter queue of the events failing user transformations
+type DLQ struct {
+	db        *sql.DB
+	log       logger.Logger
+	retention time.Duration
+}
+
+// New returns a dead-letter queue on the given database, setting up its table if needed
+func New(ctx context.Context, db *sql.DB, log logger.Logger) (*DLQ, error) {
+	dlq := &DLQ{
+		db:        db,
+		log:       log,
+		retention: config.GetDuration("Processor.transformationDLQ.retention", 7*24, time.Hour),
+	}
+	if err := dlq.setupTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to setup %s table: %w", tableName, err)
+	}
+	return dlq, nil
+}
+
+func (dlq *DLQ) setupTable(ctx context.Context) error {
+	sqlStatement := `create table "` + tableName + `" (
+		id BIGSERIAL primary key,
+		workspace_id text not null,
+		source_id text not null,
+		destination_id text not null,
+		transformation_id text not null,
+		transformation_version_id text not null,
+		user_id text not null,
+		payload jsonb not null,
+		error text not null,
+		status_code integer not null,
+		created_at timestamp with time zone not null default NOW(),
+		reprocessed_at timestamp with time zone
+	)`
+	if _, err := dlq.db.ExecContext(ctx, sqlStatement); err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "42P07" {
+			dlq.log.Debugf("table %s already exists", tableName)
+		} else {
+			return err
+		}
+	}
+	if _, err := dlq.db.ExecContext(ctx, `create index if not exists `+tableName+`_pending_idx on "`+tableName+`" (transformation_id, destination_id, id) where reprocessed_at is null`); err != nil {
+		return err
+	}
+	if _, err := dlq.db.ExecContext(ctx, `create index if not exists `+tableName+`_created_at_idx on "`+tableName+`" (created_at)`); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Add adds the given entries to the queue
+func (dlq *DLQ) Add(ctx context.Context, entries []EntryT) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	tx, err := dlq.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(tableName, "workspace_id", "source_id", "destination_id", "transformation_id", "transformation_version_id", "user_id", "payload", "error", "status_code"))
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		if _, err := stmt.ExecContext(ctx, entry.WorkspaceID, entry.SourceID, entry.DestinationID, entry.TransformationID, entry.TransformationVersionID, entry.UserID, string(entry.Payload), entry.Error, entry.StatusCode); err != nil {
+			_ = stmt.Close()
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		_ = tx.Rollback()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Pending returns up to limit entries of the given transformation that are yet to be reprocessed, in the order they were
+// added, skipping the ones that failed with its current version. If destinationID is empty, entries of all destinations
+// are returned
+func (dlq *DLQ) Pending(ctx context.Context, transformationID, versionID, destinationID string, limit int) ([]EntryT, error) {
+	rows, err := dlq.db.QueryContext(ctx, `select id, workspace_id, source_id, destination_id, transformation_id, transformation_version_id, user_id, payload, error, status_code, created_at
+		from "`+tableName+`"
+		where transformation_id = $1 and transformation_version_id <> $2 and ($3 = '' or destination_id = $3) and reprocessed_at is null
+		order by id limit $4`, transformationID, versionID, destinationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var entries []EntryT
+	for rows.Next() {
+		var entry EntryT
+		var payload []byte
+		if err := rows.Scan(&entry.ID, &entry.WorkspaceID, &entry.SourceID, &entry.DestinationID, &entry.TransformationID, &entry.TransformationVersionID, &entry.UserID, &payload, &entry.Error, &entry.StatusCode, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entry.Payload = payload
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// MarkReprocessed marks the entries with the given ids as reprocessed, in the given transaction
+func (dlq *DLQ) MarkReprocessed(ctx context.Context, tx *sql.Tx, ids []int64) error {
+	_, err := tx.ExecContext(ctx, `update "`+tableName+`" set reprocessed_at = NOW() where id = ANY($1)`, pq.Array(ids))
+	return err
+}
+
+// CleanupLoop periodically deletes the entries older than the retention period, until the context is cancelled
+func (dlq *DLQ) CleanupLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(config.GetDuration("Processor.transformationDLQ.cleanupInterval", 1, time.Hour)):
+		}
+		res, err := dlq.db.ExecContext(ctx, `delete from "`+tableName+`" where created_at < $1`, time.Now().Add(-dlq.retention))
+		if err != nil {
+			if ctx.Err() == nil {
+				dlq.log.Errorf("Failed to delete expired entries of the transformation dead-letter queue: %v", err)
+			}
+			continue
+		}
+		if deleted, _ := res.RowsAffected(); deleted > 0 {
+			dlq.log.Infof("Deleted %d expired entries of the transformation dead-letter queue", deleted)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package transformationdlq_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/processor/transformationdlq"
+	"github.com/rudderlabs/rudder-server/testhelper/destination"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestDLQ(t *testing.T) {
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+	postgres, err := destination.SetupPostgres(pool, t)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	dlq, err := transformationdlq.New(ctx, postgres.DB, logger.NOP)
+	require.NoError(t, err)
+	_, err = transformationdlq.New(ctx, postgres.DB, logger.NOP)
+	require.NoError(t, err, "the table can already exist")
+
+	entry := func(destinationID, versionID string) transformationdlq.EntryT {
+		return transformationdlq.EntryT{
+			WorkspaceID:             "workspace",
+			SourceID:                "source",
+			DestinationID:           destinationID,
+			TransformationID:        "transformation",
+			TransformationVersionID: versionID,
+			UserID:                  "user",
+			Payload:                 json.RawMessage(`[{"messageId": "1"}]`),
+			Error:                   "transformation failed",
+			StatusCode:              400,
+		}
+	}
+	require.NoError(t, dlq.Add(ctx, []transformationdlq.EntryT{
+		entry("destination-1", "v1"),
+		entry("destination-2", "v1"),
+		entry("destination-1", "v2"),
+	}))
+
+	pending, err := dlq.Pending(ctx, "transformation", "v2", "", 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2, "entries failing the current version are not pending")
+	require.Equal(t, "destination-1", pending[0].DestinationID)
+	require.Equal(t, "transformation failed", pending[0].Error)
+	require.JSONEq(t, `[{"messageId": "1"}]`, string(pending[0].Payload))
+
+	pending, err = dlq.Pending(ctx, "transformation", "v2", "destination-2", 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, "destination-2", pending[0].DestinationID)
+
+	tx, err := postgres.DB.Begin()
+	require.NoError(t, err)
+	require.NoError(t, dlq.MarkReprocessed(ctx, tx, []int64{pending[0].ID}))
+	require.NoError(t, tx.Commit())
+
+	pending, err = dlq.Pending(ctx, "transformation", "v3", "", 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2, "reprocessed entries are not pending")
+	for _, entry := range pending {
+		require.Equal(t, "destination-1", entry.DestinationID)
+	}
+}
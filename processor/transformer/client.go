@@ -0,0 +1,179 @@
+package transformer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sony/gobreaker"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/httputil"
+)
+
+// Requests to the transformer are made with:
+//   - a timeout per attempt, if configured, on top of the timeout of the http client
+//   - hedging, if configured: when an attempt takes longer than the hedge delay, another one is made, up to a maximum,
+//     and the first one succeeding is used
+//   - a circuit breaker per endpoint, if enabled, failing requests fast after consecutive failures of the endpoint,
+//     until it is probed again after the breaker's timeout
+//
+// Attempts failing with gateway errors, e.g. while transformer pods restart, are retried along with the ones failing
+// to connect.
+
+// postResponseT is a response of the transformer, read in full
+type postResponseT struct {
+	body        []byte
+	statusCode  int
+	contentType string
+	apiVersion  string
+}
+
+// gatewayError is returned for responses with gateway errors, so that they are retried
+type gatewayError struct {
+	response *postResponseT
+}
+
+func (e *gatewayError) Error() string {
+	return fmt.Sprintf("transformer responded with status code %d: %s", e.response.statusCode, e.response.body)
+}
+
+func isGatewayError(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// post makes a request to the given url of the transformer, hedging it if configured
+func (trans *HandleT) post(ctx context.Context, url, format string, body []byte) (*postResponseT, error) {
+	if hedgeDelay <= 0 || maxHedgedRequests <= 0 {
+		return trans.breakerPost(ctx, url, format, body)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		response *postResponseT
+		err      error
+	}
+	results := make(chan result, maxHedgedRequests+1)
+	attempt := func() {
+		go func() {
+			response, err := trans.breakerPost(ctx, url, format, body)
+			results <- result{response, err}
+		}()
+	}
+	attempt()
+	inFlight, hedged := 1, 0
+	hedgeTimer := time.NewTimer(hedgeDelay)
+	defer hedgeTimer.Stop()
+	for {
+		select {
+		case <-hedgeTimer.C:
+			if hedged < maxHedgedRequests {
+				hedged++
+				inFlight++
+				trans.hedgedStat.Count(1)
+				attempt()
+				hedgeTimer.Reset(hedgeDelay)
+			}
+		case r := <-results:
+			inFlight--
+			if r.err == nil || inFlight == 0 {
+				return r.response, r.err
+			}
+		}
+	}
+}
+
+// breakerPost makes a request to the given url of the transformer through the circuit breaker of the url, if enabled
+func (trans *HandleT) breakerPost(ctx context.Context, url, format string, body []byte) (*postResponseT, error) {
+	breaker := trans.breaker(url)
+	if breaker == nil {
+		return trans.doRequest(ctx, url, format, body)
+	}
+	response, err := breaker.Execute(func() (interface{}, error) {
+		return trans.doRequest(ctx, url, format, body)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*postResponseT), nil
+}
+
+func (trans *HandleT) doRequest(ctx context.Context, url, format string, body []byte) (*postResponseT, error) {
+	if requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	contentType, accept := contentTypeHeaders(format)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", accept)
+	resp, err := trans.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { httputil.CloseResponse(resp) }()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	response := &postResponseT{
+		body:        respBody,
+		statusCode:  resp.StatusCode,
+		contentType: resp.Header.Get("Content-Type"),
+		apiVersion:  resp.Header.Get("apiVersion"),
+	}
+	if retryGatewayErrors && isGatewayError(resp.StatusCode) {
+		return nil, &gatewayError{response: response}
+	}
+	return response, nil
+}
+
+// breaker returns the circuit breaker of the given url, if enabled
+func (trans *HandleT) breaker(url string) *gobreaker.CircuitBreaker {
+	if !enableCircuitBreaker {
+		return nil
+	}
+	trans.breakersMu.Lock()
+	defer trans.breakersMu.Unlock()
+	if breaker, ok := trans.breakers[url]; ok {
+		return breaker
+	}
+	if trans.breakers == nil {
+		trans.breakers = make(map[string]*gobreaker.CircuitBreaker)
+	}
+	openGauge := stats.Default.NewTaggedStat("processor.transformer_circuit_breaker_open", stats.GaugeType, stats.Tags{"endpoint": url})
+	breaker := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:    url,
+		Timeout: circuitBreakerTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= uint32(circuitBreakerConsecutiveFailures)
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			trans.logger.Warnf("Circuit breaker of transformer endpoint %s changed from %s to %s", name, from, to)
+			if to == gobreaker.StateOpen {
+				openGauge.Gauge(1)
+			} else {
+				openGauge.Gauge(0)
+			}
+		},
+		// lost hedged requests are cancelled, through no fault of the endpoint
+		IsSuccessful: func(err error) bool {
+			return err == nil || errors.Is(err, context.Canceled)
+		},
+	})
+	trans.breakers[url] = breaker
+	return breaker
+}
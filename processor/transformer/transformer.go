@@ -3,10 +3,11 @@ package transformer
 //go:generate mockgen -destination=../../mocks/processor/transformer/mock_transformer.go -package=mocks_transformer github.com/rudderlabs/rudder-server/processor/transformer Transformer
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"runtime/trace"
 	"strconv"
@@ -15,10 +16,12 @@ import (
 
 	"github.com/cenkalti/backoff"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/sony/gobreaker"
 
 	"github.com/rudderlabs/rudder-server/config"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	"github.com/rudderlabs/rudder-server/processor/integrations"
+	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/httputil"
 	"github.com/rudderlabs/rudder-server/utils/logger"
@@ -70,6 +73,8 @@ type MetadataT struct {
 	EventType               string   `json:"eventType"`
 	SourceDefinitionID      string   `json:"sourceDefinitionId"`
 	DestinationDefinitionID string   `json:"destinationDefinitionId"`
+	// TraceParent is the W3C traceparent of the trace the event is part of, if the request of the event was traced
+	TraceParent string `json:"traceparent,omitempty"`
 }
 
 type TransformerEventT struct {
@@ -84,12 +89,19 @@ type HandleT struct {
 	sentStat     stats.Measurement
 	receivedStat stats.Measurement
 	cpDownGauge  stats.Measurement
+	hedgedStat   stats.Measurement
 
 	logger logger.Logger
 
 	Client *http.Client
 
 	guardConcurrency chan struct{}
+	requestPool      *rruntime.Pool
+
+	breakersMu sync.Mutex
+	breakers   map[string]*gobreaker.CircuitBreaker // by url
+
+	jsonOnlyURLs sync.Map // urls not supporting other wire formats than JSON
 }
 
 // Transformer provides methods to transform events
@@ -108,6 +120,15 @@ var (
 	maxConcurrency, maxHTTPConnections, maxHTTPIdleConnections, maxRetry int
 	retrySleep                                                           time.Duration
 	timeoutDuration                                                      time.Duration
+	idleConnTimeout, dialTimeout                                         time.Duration
+	requestTimeout                                                       time.Duration
+	hedgeDelay                                                           time.Duration
+	maxHedgedRequests                                                    int
+	retryGatewayErrors                                                   bool
+	enableCircuitBreaker                                                 bool
+	circuitBreakerConsecutiveFailures                                    int
+	circuitBreakerTimeout                                                time.Duration
+	wireFormat                                                           string
 	pkgLogger                                                            logger.Logger
 )
 
@@ -124,6 +145,20 @@ func loadConfig() {
 	config.RegisterIntConfigVariable(30, &maxRetry, true, 1, "Processor.maxRetry")
 	config.RegisterDurationConfigVariable(100, &retrySleep, true, time.Millisecond, []string{"Processor.retrySleep", "Processor.retrySleepInMS"}...)
 	config.RegisterDurationConfigVariable(30, &timeoutDuration, false, time.Second, "HttpClient.procTransformer.timeout")
+	config.RegisterDurationConfigVariable(60, &idleConnTimeout, false, time.Second, "Processor.Transformer.idleConnTimeout")
+	config.RegisterDurationConfigVariable(10, &dialTimeout, false, time.Second, "Processor.Transformer.dialTimeout")
+	// timeout of each attempt of a request, 0 for the timeout of the http client only
+	config.RegisterDurationConfigVariable(0, &requestTimeout, true, time.Second, "Processor.Transformer.requestTimeout")
+	// delay after which another attempt of a request is made while the previous ones are in flight, 0 for no hedging
+	config.RegisterDurationConfigVariable(0, &hedgeDelay, true, time.Millisecond, "Processor.Transformer.hedgeDelay")
+	config.RegisterIntConfigVariable(1, &maxHedgedRequests, true, 1, "Processor.Transformer.maxHedgedRequests")
+	// retry the requests failing with 502, 503 and 504, e.g. while transformer pods restart
+	config.RegisterBoolConfigVariable(true, &retryGatewayErrors, true, "Processor.Transformer.retryGatewayErrors")
+	config.RegisterBoolConfigVariable(false, &enableCircuitBreaker, false, "Processor.Transformer.circuitBreaker.enabled")
+	config.RegisterIntConfigVariable(5, &circuitBreakerConsecutiveFailures, false, 1, "Processor.Transformer.circuitBreaker.consecutiveFailures")
+	config.RegisterDurationConfigVariable(10, &circuitBreakerTimeout, false, time.Second, "Processor.Transformer.circuitBreaker.timeout")
+	// format of the requests to the transformer, json or msgpack
+	config.RegisterStringConfigVariable(JSONWireFormat, &wireFormat, true, "Processor.Transformer.wireFormat")
 }
 
 type TransformerResponseT struct {
@@ -133,6 +168,8 @@ type TransformerResponseT struct {
 	StatusCode       int                    `json:"statusCode"`
 	Error            string                 `json:"error"`
 	ValidationErrors []ValidationErrorT     `json:"validationErrors"`
+	// Logs are the console logs of the user transformation of the event
+	Logs []string `json:"logs,omitempty"`
 }
 
 type ValidationErrorT struct {
@@ -147,15 +184,18 @@ func (trans *HandleT) Setup() {
 	trans.sentStat = stats.Default.NewStat("processor.transformer_sent", stats.CountType)
 	trans.receivedStat = stats.Default.NewStat("processor.transformer_received", stats.CountType)
 	trans.cpDownGauge = stats.Default.NewStat("processor.control_plane_down", stats.GaugeType)
+	trans.hedgedStat = stats.Default.NewStat("processor.transformer_hedged_requests", stats.CountType)
 
 	trans.guardConcurrency = make(chan struct{}, maxConcurrency)
+	trans.requestPool = rruntime.RegisterPool("processor", "transformer", maxConcurrency)
 
 	if trans.Client == nil {
 		trans.Client = &http.Client{
 			Transport: &http.Transport{
+				DialContext:         (&net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}).DialContext,
 				MaxConnsPerHost:     maxHTTPConnections,
 				MaxIdleConnsPerHost: maxHTTPIdleConnections,
-				IdleConnTimeout:     time.Minute,
+				IdleConnTimeout:     idleConnTimeout,
 			},
 			Timeout: timeoutDuration,
 		}
@@ -230,6 +270,7 @@ func (trans *HandleT) Transform(ctx context.Context, clientEvents []TransformerE
 		}
 		trans.guardConcurrency <- struct{}{}
 		go func() {
+			defer trans.requestPool.Busy()()
 			trace.WithRegion(ctx, "request", func() {
 				transformResponse[i] = trans.request(ctx, url, clientEvents[from:to])
 			})
@@ -287,14 +328,15 @@ func statsTags(event TransformerEventT) stats.Tags {
 func (trans *HandleT) request(ctx context.Context, url string, data []TransformerEventT) []TransformerResponseT {
 	// Call remote transformation
 	var (
-		rawJSON []byte
+		rawBody []byte
 		err     error
 	)
 
+	format := trans.wireFormat(url)
 	trace.WithRegion(ctx, "marshal", func() {
-		rawJSON, err = jsonfast.Marshal(data)
+		rawBody, err = marshalEvents(format, data)
 	})
-	trace.Logf(ctx, "marshal", "request raw body size: %d", len(rawJSON))
+	trace.Logf(ctx, "marshal", "request raw body size: %d", len(rawBody))
 	if err != nil {
 		panic(err)
 	}
@@ -304,6 +346,7 @@ func (trans *HandleT) request(ctx context.Context, url string, data []Transforme
 	}
 
 	var (
+		resp       *postResponseT
 		respData   []byte
 		statusCode int
 	)
@@ -314,7 +357,8 @@ func (trans *HandleT) request(ctx context.Context, url string, data []Transforme
 	// endless backoff loop, only nil error or panics inside
 	_ = backoff.RetryNotify(
 		func() error {
-			respData, statusCode = trans.doPost(ctx, rawJSON, url, statsTags(data[0]))
+			resp = trans.doPost(ctx, url, format, rawBody, statsTags(data[0]))
+			respData, statusCode = resp.body, resp.statusCode
 			if statusCode == StatusCPDown {
 				trans.cpDownGauge.Gauge(1)
 				return fmt.Errorf("control plane not reachable")
@@ -335,6 +379,11 @@ func (trans *HandleT) request(ctx context.Context, url string, data []Transforme
 		})
 	// control plane back up
 
+	if statusCode == http.StatusUnsupportedMediaType && format == MsgpackWireFormat {
+		trans.fallbackToJSON(url)
+		return trans.request(ctx, url, data)
+	}
+
 	switch statusCode {
 	case http.StatusOK,
 		http.StatusBadRequest,
@@ -346,16 +395,16 @@ func (trans *HandleT) request(ctx context.Context, url string, data []Transforme
 
 	var transformerResponses []TransformerResponseT
 	if statusCode == http.StatusOK {
-		integrations.CollectIntgTransformErrorStats(respData)
+		collectIntgTransformErrorStats(resp.contentType, respData)
 
 		trace.Logf(ctx, "Unmarshal", "response raw size: %d", len(respData))
 		trace.WithRegion(ctx, "Unmarshal", func() {
-			err = jsonfast.Unmarshal(respData, &transformerResponses)
+			err = unmarshalResponses(resp.contentType, respData, &transformerResponses)
 		})
 		// This is returned by our JS engine so should  be parsable
 		// but still handling it
 		if err != nil {
-			trans.logger.Errorf("Data sent to transformer : %v", string(rawJSON))
+			trans.logger.Errorf("Data sent to transformer : %v", string(rawBody))
 			trans.logger.Errorf("Transformer returned : %v", string(respData))
 			respData = []byte(fmt.Sprintf("Failed to unmarshal transformer response: %s", string(respData)))
 			transformerResponses = nil
@@ -373,11 +422,10 @@ func (trans *HandleT) request(ctx context.Context, url string, data []Transforme
 	return transformerResponses
 }
 
-func (trans *HandleT) doPost(ctx context.Context, rawJSON []byte, url string, tags stats.Tags) ([]byte, int) {
+func (trans *HandleT) doPost(ctx context.Context, url, format string, rawBody []byte, tags stats.Tags) *postResponseT {
 	var (
 		retryCount int
-		resp       *http.Response
-		respData   []byte
+		resp       *postResponseT
 	)
 
 	err := backoff.RetryNotify(
@@ -385,14 +433,9 @@ func (trans *HandleT) doPost(ctx context.Context, rawJSON []byte, url string, ta
 			var reqErr error
 			s := time.Now()
 			trace.WithRegion(ctx, "request/post", func() {
-				resp, reqErr = trans.Client.Post(url, "application/json; charset=utf-8", bytes.NewBuffer(rawJSON))
+				resp, reqErr = trans.post(ctx, url, format, rawBody)
 			})
 			trans.requestTime(tags, time.Since(s))
-			if reqErr != nil {
-				return reqErr
-			}
-			defer func() { httputil.CloseResponse(resp) }()
-			respData, reqErr = io.ReadAll(resp.Body)
 			return reqErr
 		},
 		backoff.WithMaxRetries(backoff.NewExponentialBackOff(), uint64(maxRetry)),
@@ -400,17 +443,22 @@ func (trans *HandleT) doPost(ctx context.Context, rawJSON []byte, url string, ta
 			retryCount++
 			trans.logger.Warnf("JS HTTP connection error: URL: %v Error: %+v after %v tries", url, err, retryCount)
 		})
+	var gwErr *gatewayError
+	if errors.As(err, &gwErr) {
+		// responses with gateway errors are returned once retries are exhausted
+		resp, err = gwErr.response, nil
+	}
 	if err != nil {
 		if config.GetBool("Processor.Transformer.failOnError", false) {
-			return []byte(fmt.Sprintf("transformer request failed: %s", err)), TransformerRequestFailure
+			return &postResponseT{body: []byte(fmt.Sprintf("transformer request failed: %s", err)), statusCode: TransformerRequestFailure}
 		} else {
 			panic(err)
 		}
 	}
 
 	// perform version compatibility check only on success
-	if resp.StatusCode == http.StatusOK {
-		transformerAPIVersion, convErr := strconv.Atoi(resp.Header.Get("apiVersion"))
+	if resp.statusCode == http.StatusOK {
+		transformerAPIVersion, convErr := strconv.Atoi(resp.apiVersion)
 		if convErr != nil {
 			transformerAPIVersion = 0
 		}
@@ -421,5 +469,5 @@ func (trans *HandleT) doPost(ctx context.Context, rawJSON []byte, url string, ta
 		}
 	}
 
-	return respData, resp.StatusCode
+	return resp
 }
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/gateway/response"
@@ -206,3 +208,78 @@ func (elt *endlessLoopTransformer) ServeHTTP(w http.ResponseWriter, r *http.Requ
 	w.Header().Set("apiVersion", "2")
 	require.NoError(elt.t, json.NewEncoder(w).Encode(resps))
 }
+
+func Test_HedgedRequests(t *testing.T) {
+	config.Reset()
+	logger.Reset()
+	config.Set("Processor.Transformer.hedgeDelay", "50ms")
+	transformer.Init()
+	defer config.Reset()
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody []transformer.TransformerEventT
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			// a transformer pod restarting
+			http.Error(w, "no healthy upstream", http.StatusServiceUnavailable)
+			return
+		case 2:
+			// a slow transformer pod
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+		resps := make([]transformer.TransformerResponseT, len(reqBody))
+		for i := range reqBody {
+			resps[i] = transformer.TransformerResponseT{Output: reqBody[i].Message, Metadata: reqBody[i].Metadata, StatusCode: 200}
+		}
+		w.Header().Set("apiVersion", "2")
+		require.NoError(t, json.NewEncoder(w).Encode(resps))
+	}))
+	defer srv.Close()
+
+	tr := transformer.NewTransformer()
+	tr.Client = srv.Client()
+	tr.Setup()
+
+	events := []transformer.TransformerEventT{{Metadata: transformer.MetadataT{MessageID: "messageID-0"}, Message: map[string]interface{}{"src-key-1": "messageID-0"}}}
+	start := time.Now()
+	rsp := tr.Transform(context.TODO(), events, srv.URL, 10)
+	require.Less(t, time.Since(start), 5*time.Second, "slow requests are hedged")
+	require.Len(t, rsp.Events, 1, "gateway errors are retried")
+	require.Empty(t, rsp.FailedEvents)
+	require.EqualValues(t, 3, atomic.LoadInt32(&requests))
+}
+
+func Test_CircuitBreaker(t *testing.T) {
+	config.Reset()
+	logger.Reset()
+	config.Set("Processor.maxRetry", 3)
+	config.Set("Processor.Transformer.failOnError", true)
+	config.Set("Processor.Transformer.circuitBreaker.enabled", true)
+	config.Set("Processor.Transformer.circuitBreaker.consecutiveFailures", 2)
+	config.Set("Processor.Transformer.circuitBreaker.timeout", "1m")
+	transformer.Init()
+	defer config.Reset()
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "no healthy upstream", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	tr := transformer.NewTransformer()
+	tr.Client = srv.Client()
+	tr.Setup()
+
+	events := []transformer.TransformerEventT{{Metadata: transformer.MetadataT{MessageID: "messageID-0"}, Message: map[string]interface{}{"src-key-1": "messageID-0"}}}
+	rsp := tr.Transform(context.TODO(), events, srv.URL, 10)
+	require.Len(t, rsp.FailedEvents, 1)
+	require.Equal(t, transformer.TransformerRequestFailure, rsp.FailedEvents[0].StatusCode)
+	require.EqualValues(t, 2, atomic.LoadInt32(&requests), "requests fail fast once the circuit breaker is open")
+}
@@ -0,0 +1,88 @@
+package transformer
+
+import (
+	"bytes"
+	"mime"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/rudderlabs/rudder-server/processor/integrations"
+)
+
+// Events can be sent to the transformer as msgpack instead of JSON, saving the CPU spent encoding and decoding them.
+// The format is negotiated per endpoint: requests are sent as msgpack, accepting msgpack or JSON responses, and
+// endpoints responding with 415 Unsupported Media Type are sent JSON requests from then on.
+
+const (
+	JSONWireFormat    = "json"
+	MsgpackWireFormat = "msgpack"
+
+	jsonContentType    = "application/json; charset=utf-8"
+	msgpackContentType = "application/msgpack"
+)
+
+// wireFormat returns the format of the requests to the given url
+func (trans *HandleT) wireFormat(url string) string {
+	if wireFormat != MsgpackWireFormat {
+		return JSONWireFormat
+	}
+	if _, ok := trans.jsonOnlyURLs.Load(url); ok {
+		return JSONWireFormat
+	}
+	return MsgpackWireFormat
+}
+
+// fallbackToJSON makes the requests to the given url use JSON from then on
+func (trans *HandleT) fallbackToJSON(url string) {
+	if _, loaded := trans.jsonOnlyURLs.LoadOrStore(url, struct{}{}); !loaded {
+		trans.logger.Infof("Transformer endpoint %s does not support %s requests, falling back to JSON", url, MsgpackWireFormat)
+	}
+}
+
+// marshalEvents encodes the events of a request in the given format
+func marshalEvents(format string, events []TransformerEventT) ([]byte, error) {
+	if format != MsgpackWireFormat {
+		return jsonfast.Marshal(events)
+	}
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	encoder.UseCompactInts(true)
+	if err := encoder.Encode(events); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalResponses decodes the body of a response according to its content type, JSON unless it is msgpack
+func unmarshalResponses(contentType string, body []byte, responses *[]TransformerResponseT) error {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != msgpackContentType {
+		return jsonfast.Unmarshal(body, responses)
+	}
+	decoder := msgpack.NewDecoder(bytes.NewReader(body))
+	decoder.SetCustomStructTag("json")
+	decoder.UseLooseInterfaceDecoding(true)
+	return decoder.Decode(responses)
+}
+
+// collectIntgTransformErrorStats reports the stats of the integration failures of a response, according to its content type
+func collectIntgTransformErrorStats(contentType string, body []byte) {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != msgpackContentType {
+		integrations.CollectIntgTransformErrorStats(body)
+		return
+	}
+	var integrationStats []integrations.TransStatsT
+	decoder := msgpack.NewDecoder(bytes.NewReader(body))
+	decoder.SetCustomStructTag("json")
+	if err := decoder.Decode(&integrationStats); err == nil {
+		integrations.ReportIntgTransformErrorStats(integrationStats)
+	}
+}
+
+// contentTypeHeaders returns the Content-Type and Accept headers of requests in the given format
+func contentTypeHeaders(format string) (contentType, accept string) {
+	if format == MsgpackWireFormat {
+		return msgpackContentType, msgpackContentType + ", application/json"
+	}
+	return jsonContentType, "application/json"
+}
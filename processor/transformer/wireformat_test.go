@@ -0,0 +1,180 @@
+package transformer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func newWireFormatEvents(n int) []TransformerEventT {
+	destination := backendconfig.DestinationT{
+		ID:                    "destination",
+		DestinationDefinition: backendconfig.DestinationDefinitionT{Name: "WEBHOOK", Config: map[string]interface{}{"transformAt": "processor"}},
+		Config:                map[string]interface{}{"webhookUrl": "https://example.com/webhook", "headers": []interface{}{map[string]interface{}{"from": "a", "to": "b"}}},
+		Enabled:               true,
+		Transformations:       []backendconfig.TransformationT{{ID: "transformation", VersionID: "version"}},
+	}
+	events := make([]TransformerEventT, n)
+	for i := range events {
+		messageID := fmt.Sprintf("message-%d", i)
+		events[i] = TransformerEventT{
+			Message: map[string]interface{}{
+				"type":              "track",
+				"event":             "Order Completed",
+				"messageId":         messageID,
+				"anonymousId":       "anonymous-id",
+				"userId":            "user-id",
+				"originalTimestamp": "2022-10-10T10:10:10.000Z",
+				"context": map[string]interface{}{
+					"library": map[string]interface{}{"name": "analytics.js", "version": "2.11.1"},
+					"page":    map[string]interface{}{"path": "/checkout", "url": "https://example.com/checkout", "title": "Checkout"},
+					"traits":  map[string]interface{}{"email": "user@example.com", "plan": "enterprise"},
+				},
+				"properties": map[string]interface{}{
+					"orderId":  "order-id",
+					"total":    99.99,
+					"currency": "USD",
+					"products": []interface{}{
+						map[string]interface{}{"productId": "1", "price": 49.99, "quantity": 1.0},
+						map[string]interface{}{"productId": "2", "price": 50.0, "quantity": 1.0},
+					},
+				},
+			},
+			Metadata:    MetadataT{SourceID: "source", DestinationID: "destination", MessageID: messageID, JobID: int64(i)},
+			Destination: destination,
+		}
+	}
+	return events
+}
+
+// wireFormatTransformer echoes the messages of requests in the format of their Content-Type, if supported
+type wireFormatTransformer struct {
+	supportsMsgpack bool
+	requests        map[string]*int32
+}
+
+func (t *wireFormatTransformer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	contentType, _ := contentTypeHeaders(JSONWireFormat)
+	format := JSONWireFormat
+	if r.Header.Get("Content-Type") == msgpackContentType {
+		if !t.supportsMsgpack {
+			http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+			return
+		}
+		contentType, format = msgpackContentType, MsgpackWireFormat
+	}
+	atomic.AddInt32(t.requests[format], 1)
+
+	body, _ := io.ReadAll(r.Body)
+	var events []TransformerEventT
+	var err error
+	if format == MsgpackWireFormat {
+		decoder := msgpack.NewDecoder(bytes.NewReader(body))
+		decoder.SetCustomStructTag("json")
+		err = decoder.Decode(&events)
+	} else {
+		err = jsonfast.Unmarshal(body, &events)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	responses := make([]TransformerResponseT, len(events))
+	for i := range events {
+		responses[i] = TransformerResponseT{Output: events[i].Message, Metadata: events[i].Metadata, StatusCode: 200}
+	}
+	responseBody, err := marshalResponses(format, responses)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("apiVersion", "2")
+	_, _ = w.Write(responseBody)
+}
+
+func marshalResponses(format string, responses []TransformerResponseT) ([]byte, error) {
+	if format != MsgpackWireFormat {
+		return jsonfast.Marshal(responses)
+	}
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	err := encoder.Encode(responses)
+	return buf.Bytes(), err
+}
+
+func TestWireFormat(t *testing.T) {
+	config.Reset()
+	logger.Reset()
+	config.Set("Processor.Transformer.wireFormat", MsgpackWireFormat)
+	Init()
+	defer config.Reset()
+
+	for _, supportsMsgpack := range []bool{true, false} {
+		t.Run(fmt.Sprintf("supportsMsgpack=%t", supportsMsgpack), func(t *testing.T) {
+			ft := &wireFormatTransformer{supportsMsgpack: supportsMsgpack, requests: map[string]*int32{JSONWireFormat: new(int32), MsgpackWireFormat: new(int32)}}
+			srv := httptest.NewServer(ft)
+			defer srv.Close()
+			tr := NewTransformer()
+			tr.Client = srv.Client()
+			tr.Setup()
+
+			events := newWireFormatEvents(3)
+			for i := 0; i < 2; i++ {
+				response := tr.Transform(context.TODO(), events, srv.URL, 10)
+				require.Empty(t, response.FailedEvents)
+				require.Len(t, response.Events, len(events))
+				for j := range events {
+					require.Equal(t, events[j].Metadata, response.Events[j].Metadata)
+					require.EqualValues(t, events[j].Message["messageId"], response.Events[j].Output["messageId"])
+					require.EqualValues(t, 99.99, response.Events[j].Output["properties"].(map[string]interface{})["total"])
+				}
+			}
+			if supportsMsgpack {
+				require.EqualValues(t, 2, *ft.requests[MsgpackWireFormat])
+				require.EqualValues(t, 0, *ft.requests[JSONWireFormat])
+			} else {
+				require.EqualValues(t, 2, *ft.requests[JSONWireFormat], "requests fall back to JSON")
+			}
+		})
+	}
+}
+
+// BenchmarkWireFormat compares the cost of encoding a request and decoding its response in each wire format
+func BenchmarkWireFormat(b *testing.B) {
+	events := newWireFormatEvents(200)
+	responses := make([]TransformerResponseT, len(events))
+	for i := range events {
+		responses[i] = TransformerResponseT{Output: events[i].Message, Metadata: events[i].Metadata, StatusCode: 200}
+	}
+	for _, format := range []string{JSONWireFormat, MsgpackWireFormat} {
+		responseBody, err := marshalResponses(format, responses)
+		require.NoError(b, err)
+		contentType, _ := contentTypeHeaders(format)
+		b.Run(format, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := marshalEvents(format, events); err != nil {
+					b.Fatal(err)
+				}
+				var decoded []TransformerResponseT
+				if err := unmarshalResponses(contentType, responseBody, &decoded); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/processor/transformer"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// Events transformed for warehouse destinations can be written to more tables than the ones generated for them, by
+// declaring rules under the destination config's tableMultiplexRules key, e.g.
+//
+//	"tableMultiplexRules": [
+//	  {"table": "order_completed", "to": ["revenue_events"], "columns": ["revenue", "currency"]}
+//	]
+//
+// writes the rows of order_completed to revenue_events too, with the given columns only, if any, along with the
+// columns identifying the rows. Tables are matched case insensitively.
+
+// warehouseMultiplexKeyColumns are the columns kept in the rows written to other tables regardless of the rule's columns
+var warehouseMultiplexKeyColumns = []string{"id", "received_at"}
+
+type warehouseMultiplexRule struct {
+	Table   string   `json:"table"`
+	To      []string `json:"to"`
+	Columns []string `json:"columns"`
+}
+
+// warehouseMultiplexRules are the multiplex rules of a destination, by the lower case name of the tables they apply to
+type warehouseMultiplexRules map[string][]warehouseMultiplexRule
+
+// newWarehouseMultiplexRules parses the table multiplex rules of a warehouse destination
+func newWarehouseMultiplexRules(destination *backendconfig.DestinationT) (warehouseMultiplexRules, error) {
+	rawRules, ok := destination.Config["tableMultiplexRules"]
+	if !ok || rawRules == nil {
+		return nil, nil
+	}
+	rulesJSON, err := json.Marshal(rawRules)
+	if err != nil {
+		return nil, err
+	}
+	var rules []warehouseMultiplexRule
+	if err := json.Unmarshal(rulesJSON, &rules); err != nil {
+		return nil, fmt.Errorf("parsing table multiplex rules: %w", err)
+	}
+	rulesByTable := make(warehouseMultiplexRules)
+	for _, rule := range rules {
+		if rule.Table == "" || len(rule.To) == 0 {
+			continue
+		}
+		table := strings.ToLower(rule.Table)
+		rulesByTable[table] = append(rulesByTable[table], rule)
+	}
+	if len(rulesByTable) == 0 {
+		return nil, nil
+	}
+	return rulesByTable, nil
+}
+
+// multiplex returns the given events transformed for a warehouse destination, along with their copies for the tables
+// the rules write them to
+func (rules warehouseMultiplexRules) multiplex(destType string, events []transformer.TransformerResponseT) []transformer.TransformerResponseT {
+	multiplexed := events
+	for i := range events {
+		eventMetadata, _ := events[i].Output["metadata"].(map[string]interface{})
+		table, _ := eventMetadata["table"].(string)
+		for _, rule := range rules[strings.ToLower(table)] {
+			for _, to := range rule.To {
+				multiplexed = append(multiplexed, multiplexWarehouseEvent(destType, &events[i], to, rule.Columns))
+			}
+		}
+	}
+	return multiplexed
+}
+
+// multiplexWarehouseEvent copies an event transformed for a warehouse destination to the given table, keeping the given
+// columns only, if any
+func multiplexWarehouseEvent(destType string, event *transformer.TransformerResponseT, table string, columns []string) transformer.TransformerResponseT {
+	var keep map[string]struct{}
+	if len(columns) > 0 {
+		keep = make(map[string]struct{}, len(columns)+len(warehouseMultiplexKeyColumns))
+		for _, column := range append(columns, warehouseMultiplexKeyColumns...) {
+			keep[strings.ToLower(column)] = struct{}{}
+		}
+	}
+	copyColumns := func(m map[string]interface{}) map[string]interface{} {
+		copied := make(map[string]interface{}, len(m))
+		for column, value := range m {
+			if _, ok := keep[strings.ToLower(column)]; keep == nil || ok {
+				copied[column] = value
+			}
+		}
+		return copied
+	}
+
+	output := make(map[string]interface{}, len(event.Output))
+	for k, v := range event.Output {
+		output[k] = v
+	}
+	if eventMetadata, ok := event.Output["metadata"].(map[string]interface{}); ok {
+		metadata := make(map[string]interface{}, len(eventMetadata))
+		for k, v := range eventMetadata {
+			metadata[k] = v
+		}
+		if eventColumns, ok := eventMetadata["columns"].(map[string]interface{}); ok {
+			metadata["columns"] = copyColumns(eventColumns)
+		}
+		metadata["table"] = warehouseutils.ToProviderCase(destType, warehouseutils.ToSafeNamespace(destType, table))
+		output["metadata"] = metadata
+	}
+	if data, ok := event.Output["data"].(map[string]interface{}); ok {
+		output["data"] = copyColumns(data)
+	}
+	return transformer.TransformerResponseT{
+		Output:     output,
+		Metadata:   event.Metadata,
+		StatusCode: event.StatusCode,
+	}
+}
+
+// multiplexWarehouseEvents applies the table multiplex rules of a warehouse destination to the events transformed for it
+func (proc *HandleT) multiplexWarehouseEvents(destination *backendconfig.DestinationT, events []transformer.TransformerResponseT) []transformer.TransformerResponseT {
+	rules, err := newWarehouseMultiplexRules(destination)
+	if err != nil {
+		proc.logger.Errorf("Invalid table multiplex rules of destination %s: %v", destination.ID, err)
+		return events
+	}
+	if len(rules) == 0 {
+		return events
+	}
+	multiplexed := rules.multiplex(destination.DestinationDefinition.Name, events)
+	proc.statsFactory.NewTaggedStat("processor.warehouse_multiplexed_events", stats.CountType, stats.Tags{
+		"destination": destination.ID,
+		"destType":    destination.DestinationDefinition.Name,
+	}).Count(len(multiplexed) - len(events))
+	return multiplexed
+}
@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/processor/transformer"
+)
+
+func TestWarehouseMultiplexRules(t *testing.T) {
+	newDestination := func(destType string, rules interface{}) *backendconfig.DestinationT {
+		return &backendconfig.DestinationT{
+			ID:                    "destination",
+			Config:                map[string]interface{}{"tableMultiplexRules": rules},
+			DestinationDefinition: backendconfig.DestinationDefinitionT{Name: destType},
+		}
+	}
+	newEvent := func(table string) transformer.TransformerResponseT {
+		return transformer.TransformerResponseT{
+			Output: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"table":      table,
+					"columns":    map[string]interface{}{"id": "string", "received_at": "datetime", "revenue": "float", "currency": "string", "product": "string"},
+					"receivedAt": "2022-10-10T10:10:10.000Z",
+				},
+				"data":   map[string]interface{}{"id": "message-id", "received_at": "2022-10-10T10:10:10.000Z", "revenue": 9.99, "currency": "USD", "product": "book"},
+				"userId": "",
+			},
+			Metadata:   transformer.MetadataT{MessageID: "message-id", DestinationID: "destination"},
+			StatusCode: 200,
+		}
+	}
+
+	t.Run("no rules", func(t *testing.T) {
+		rules, err := newWarehouseMultiplexRules(newDestination("RS", nil))
+		require.NoError(t, err)
+		require.Nil(t, rules)
+
+		rules, err = newWarehouseMultiplexRules(newDestination("RS", []interface{}{map[string]interface{}{"table": "order_completed"}}))
+		require.NoError(t, err)
+		require.Nil(t, rules, "rules without target tables are ignored")
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		_, err := newWarehouseMultiplexRules(newDestination("RS", "order_completed"))
+		require.Error(t, err)
+	})
+
+	t.Run("multiplex", func(t *testing.T) {
+		rules, err := newWarehouseMultiplexRules(newDestination("SNOWFLAKE", []interface{}{
+			map[string]interface{}{"table": "order_completed", "to": []interface{}{"revenue_events"}, "columns": []interface{}{"revenue", "CURRENCY"}},
+			map[string]interface{}{"table": "Order_Completed", "to": []interface{}{"all_orders"}},
+		}))
+		require.NoError(t, err)
+
+		events := []transformer.TransformerResponseT{newEvent("TRACKS"), newEvent("ORDER_COMPLETED")}
+		multiplexed := rules.multiplex("SNOWFLAKE", events)
+		require.Len(t, multiplexed, 4)
+		require.Equal(t, events[:2], multiplexed[:2], "the events are kept as is")
+
+		revenueEvent := multiplexed[2]
+		require.Equal(t, events[1].Metadata, revenueEvent.Metadata)
+		require.Equal(t, map[string]interface{}{
+			"table":      "REVENUE_EVENTS",
+			"columns":    map[string]interface{}{"id": "string", "received_at": "datetime", "revenue": "float", "currency": "string"},
+			"receivedAt": "2022-10-10T10:10:10.000Z",
+		}, revenueEvent.Output["metadata"])
+		require.Equal(t, map[string]interface{}{"id": "message-id", "received_at": "2022-10-10T10:10:10.000Z", "revenue": 9.99, "currency": "USD"}, revenueEvent.Output["data"])
+
+		allOrdersEvent := multiplexed[3]
+		require.Equal(t, "ALL_ORDERS", allOrdersEvent.Output["metadata"].(map[string]interface{})["table"])
+		require.Equal(t, events[1].Output["data"], allOrdersEvent.Output["data"])
+		require.Equal(t, "ORDER_COMPLETED", events[1].Output["metadata"].(map[string]interface{})["table"], "the original event is not modified")
+	})
+}
@@ -201,6 +201,10 @@ func (fm *mockFileManager) Upload(_ context.Context, file *os.File, prefixes ...
 	}, nil
 }
 
+func (*mockFileManager) UploadReader(_ context.Context, _ string, _ io.Reader) (filemanager.UploadOutput, error) {
+	return filemanager.UploadOutput{}, nil
+}
+
 // Given a file name download & simply save it in the given file pointer.
 func (fm *mockFileManager) Download(_ context.Context, outputFilePtr *os.File, location string) error {
 	finalFileName := fmt.Sprintf("%s%s%s", fm.mockBucketLocation, "/", location)
@@ -219,6 +223,31 @@ func (fm *mockFileManager) Download(_ context.Context, outputFilePtr *os.File, l
 	return nil
 }
 
+func (fm *mockFileManager) DownloadRange(_ context.Context, output io.Writer, key string, offset int64) error {
+	file, err := os.Open(fmt.Sprintf("%s/%s", fm.mockBucketLocation, key))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(output, file)
+	return err
+}
+
+func (fm *mockFileManager) GetObjectInfo(_ context.Context, key string) (filemanager.ObjectInfo, error) {
+	fi, err := os.Stat(fmt.Sprintf("%s/%s", fm.mockBucketLocation, key))
+	if err != nil {
+		return filemanager.ObjectInfo{}, err
+	}
+	return filemanager.ObjectInfo{Key: key, Size: fi.Size()}, nil
+}
+
+func (fm *mockFileManager) GetPresignedURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	return fm.mockBucketLocation + "/" + key, nil
+}
+
 // Given a file name as key, delete if it is present in the bucket.
 func (fm *mockFileManager) DeleteObjects(_ context.Context, keys []string) error {
 	for _, key := range keys {
@@ -231,6 +260,10 @@ func (fm *mockFileManager) DeleteObjects(_ context.Context, keys []string) error
 	return nil
 }
 
+func (*mockFileManager) Copy(_ context.Context, _, _, _ string) (filemanager.UploadOutput, error) {
+	return filemanager.UploadOutput{}, nil
+}
+
 // given prefix & maxItems, return with list of Fileobject in the bucket.
 func (fm *mockFileManager) ListFilesWithPrefix(_ context.Context, _, _ string, _ int64) (fileObjects []*filemanager.FileObject, err error) {
 	if fm.listCalled {
@@ -215,6 +215,34 @@ func (r *RouterRpcHandler) GetJobIDStatus(arg string, result *string) (err error
 	return err
 }
 
+// GetDeadLetters lists the files of aborted jobs exported to object storage, of the workspace given as argument
+func (r *RouterRpcHandler) GetDeadLetters(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	readOnlyJobsDB := r.getReadOnlyJobsDB(r.jobsDBPrefix)
+	response, err := readOnlyJobsDB.GetDeadLetters(arg)
+	*result = response
+	return err
+}
+
+// SearchJobs returns the jobs of the events matching the search given as JSON argument, along with their status history
+func (r *RouterRpcHandler) SearchJobs(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	readOnlyJobsDB := r.getReadOnlyJobsDB(r.jobsDBPrefix)
+	response, err := readOnlyJobsDB.SearchJobs(arg)
+	*result = response
+	return err
+}
+
 func (r *RouterRpcHandler) GetDSList(_ string, result *string) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
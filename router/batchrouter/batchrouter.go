@@ -35,6 +35,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
+	"github.com/xitongsys/parquet-go-source/local"
 
 	"github.com/rudderlabs/rudder-server/config"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
@@ -79,7 +80,10 @@ var (
 type HandleT struct {
 	destType                    string
 	destinationsMap             map[string]*router_utils.BatchDestinationT // destinationID -> destination
-	connectionWHNamespaceMap    map[string]string                          // connectionIdentifier -> warehouseConnectionIdentifier(+namepsace)
+	eventBusDestinationsMap     map[string]backendconfig.DestinationT      // destinationID -> event bus destination
+	eventBusProducers           map[string]*eventBusProducer               // destinationID -> producer of event bus destination
+	eventBusProducersMu         sync.Mutex
+	connectionWHNamespaceMap    map[string]string // connectionIdentifier -> warehouseConnectionIdentifier(+namepsace)
 	netHandle                   *http.Client
 	processQ                    chan *BatchDestinationDataT
 	jobsDB                      jobsdb.JobsDB
@@ -122,6 +126,10 @@ type HandleT struct {
 	abortedJobCount             stats.Measurement
 	warehouseURL                string
 
+	handoverMu       sync.RWMutex
+	pausedWorkspaces map[string]struct{} // workspaces whose jobs aren't picked up, while they're drained
+	inFlightJobs     map[string]int      // workspaceID -> number of jobs picked up whose batches aren't done yet
+
 	backgroundGroup  *errgroup.Group
 	backgroundCtx    context.Context
 	backgroundCancel context.CancelFunc
@@ -179,12 +187,16 @@ func (brt *HandleT) backendConfigSubscriber() {
 		data := <-ch
 		brt.configSubscriberLock.Lock()
 		brt.destinationsMap = map[string]*router_utils.BatchDestinationT{}
+		brt.eventBusDestinationsMap = map[string]backendconfig.DestinationT{}
 		brt.connectionWHNamespaceMap = map[string]string{}
 		config := data.Data.(map[string]backendconfig.ConfigT)
 		for _, wConfig := range config {
 			for _, source := range wConfig.Sources {
 				if len(source.Destinations) > 0 {
 					for _, destination := range source.Destinations {
+						if misc.Contains(eventBusDestinations, destination.DestinationDefinition.Name) {
+							brt.eventBusDestinationsMap[destination.ID] = destination
+						}
 						if destination.DestinationDefinition.Name == brt.destType {
 							if _, ok := brt.destinationsMap[destination.ID]; !ok {
 								brt.destinationsMap[destination.ID] = &router_utils.BatchDestinationT{Destination: destination, Sources: []backendconfig.SourceT{}}
@@ -631,20 +643,46 @@ func (brt *HandleT) copyJobsToStorage(provider string, batchJobs *BatchJobsT, is
 	if err != nil {
 		panic(err)
 	}
-	path := fmt.Sprintf("%v%v.json", tmpDirPath+localTmpDirName, fmt.Sprintf("%v.%v.%v", time.Now().Unix(), batchJobs.BatchDestination.Source.ID, uuid))
+	fileFormat := fileFormatJSON
+	if !isWarehouse {
+		fileFormat = getFileFormat(batchJobs.BatchDestination.Destination.Config)
+	}
+	path := fmt.Sprintf("%v%v.%v", tmpDirPath+localTmpDirName, fmt.Sprintf("%v.%v.%v", time.Now().Unix(), batchJobs.BatchDestination.Source.ID, uuid), fileFormat)
 
-	gzipFilePath := fmt.Sprintf(`%v.gz`, path)
-	err = os.MkdirAll(filepath.Dir(gzipFilePath), os.ModePerm)
+	// parquet files are compressed on their own
+	localFilePath := path
+	if fileFormat != fileFormatParquet {
+		localFilePath = fmt.Sprintf(`%v.gz`, path)
+	}
+	err = os.MkdirAll(filepath.Dir(localFilePath), os.ModePerm)
 	if err != nil {
 		panic(err)
 	}
-	gzWriter, err := misc.CreateGZ(gzipFilePath)
+	var (
+		gzWriter    misc.GZipWriter
+		parquetFile *os.File
+	)
+	if fileFormat == fileFormatParquet {
+		parquetFile, err = os.Create(localFilePath)
+	} else {
+		gzWriter, err = misc.CreateGZ(localFilePath)
+	}
 	if err != nil {
 		panic(err)
 	}
 
 	var dedupedIDMergeRuleJobs int
 	eventsFound := false
+	var bufferedPayloads [][]byte
+	writeEvent := func(payload []byte) {
+		eventsFound = true
+		if fileFormat != fileFormatJSON {
+			// the columns of csv and parquet files depend on all the events of the batch
+			bufferedPayloads = append(bufferedPayloads, payload)
+			return
+		}
+		_ = gzWriter.WriteGZ(string(payload) + "\n")
+	}
 	connIdentifier := connectionIdentifier(*batchJobs.BatchDestination)
 	warehouseConnIdentifier := brt.connectionWHNamespaceMap[connIdentifier]
 	for _, job := range batchJobs.Jobs {
@@ -672,19 +710,36 @@ func (brt *HandleT) copyJobsToStorage(provider string, batchJobs *BatchJobsT, is
 		interruptedEventsMap, isDestInterrupted := brt.uploadedRawDataJobsCache[batchJobs.BatchDestination.Destination.ID]
 		if isDestInterrupted {
 			if _, ok = interruptedEventsMap[eventID]; !ok {
-				eventsFound = true
-				_ = gzWriter.WriteGZ(string(job.EventPayload) + "\n")
+				writeEvent(job.EventPayload)
 			}
 		} else {
-			eventsFound = true
-			_ = gzWriter.WriteGZ(string(job.EventPayload) + "\n")
+			writeEvent(job.EventPayload)
+		}
+	}
+	var writeErr error
+	switch {
+	case len(bufferedPayloads) == 0:
+	case fileFormat == fileFormatCSV:
+		writeErr = writeCSVEvents(gzWriter, bufferedPayloads)
+	case fileFormat == fileFormatParquet:
+		writeErr = writeParquetEvents(parquetFile, bufferedPayloads)
+	}
+	if parquetFile != nil {
+		_ = parquetFile.Close()
+	} else {
+		_ = gzWriter.CloseGZ()
+	}
+	if writeErr != nil {
+		brt.logger.Errorf("BRT: Error writing %s events for upload to %s: %v", fileFormat, provider, writeErr)
+		return StorageUploadOutput{
+			Error:          writeErr,
+			LocalFilePaths: []string{localFilePath},
 		}
 	}
-	_ = gzWriter.CloseGZ()
 	if !eventsFound {
 		brt.logger.Infof("BRT: No events in this batch for upload to %s. Events are either de-deuplicated or skipped", provider)
 		return StorageUploadOutput{
-			LocalFilePaths: []string{gzipFilePath},
+			LocalFilePaths: []string{localFilePath},
 		}
 	}
 	// assumes events from warehouse have receivedAt in metadata
@@ -712,7 +767,7 @@ func (brt *HandleT) copyJobsToStorage(provider string, batchJobs *BatchJobsT, is
 		lastEventAt = gjson.GetBytes(batchJobs.Jobs[len(batchJobs.Jobs)-1].EventPayload, "receivedAt").String()
 	}
 
-	brt.logger.Debugf("BRT: Logged to local file: %v", gzipFilePath)
+	brt.logger.Debugf("BRT: Logged to local file: %v", localFilePath)
 	useRudderStorage := isWarehouse && misc.IsConfiguredToUseRudderObjectStorage(batchJobs.BatchDestination.Destination.Config)
 	uploader, err := brt.fileManagerFactory.New(&filemanager.SettingsT{
 		Provider: provider,
@@ -722,15 +777,16 @@ func (brt *HandleT) copyJobsToStorage(provider string, batchJobs *BatchJobsT, is
 			UseRudderStorage: useRudderStorage,
 			WorkspaceID:      batchJobs.BatchDestination.Destination.WorkspaceID,
 		}),
+		WorkspaceID: batchJobs.BatchDestination.Destination.WorkspaceID,
 	})
 	if err != nil {
 		return StorageUploadOutput{
 			Error:          err,
-			LocalFilePaths: []string{gzipFilePath},
+			LocalFilePaths: []string{localFilePath},
 		}
 	}
 
-	outputFile, err := os.Open(gzipFilePath)
+	outputFile, err := os.Open(localFilePath)
 	if err != nil {
 		panic(err)
 	}
@@ -743,24 +799,36 @@ func (brt *HandleT) copyJobsToStorage(provider string, batchJobs *BatchJobsT, is
 		folderName = config.GetString("DESTINATION_BUCKET_FOLDER_NAME", "rudder-logs")
 	}
 
-	var datePrefixLayout string
-	if datePrefixOverride != "" {
-		datePrefixLayout = datePrefixOverride
+	var keyPrefixes []string
+	if pathTemplate := getPathTemplate(provider, batchJobs.BatchDestination.Destination.Config); !isWarehouse && pathTemplate != "" {
+		keyPrefixes, err = renderKeyPrefixes(pathTemplate, batchJobs, folderName, time.Now())
+		if err != nil {
+			_ = outputFile.Close()
+			return StorageUploadOutput{
+				Error:          err,
+				LocalFilePaths: []string{localFilePath},
+			}
+		}
 	} else {
-		dateFormat, _ := GetStorageDateFormat(uploader, batchJobs.BatchDestination, folderName)
-		datePrefixLayout = dateFormat
-	}
+		var datePrefixLayout string
+		if datePrefixOverride != "" {
+			datePrefixLayout = datePrefixOverride
+		} else {
+			dateFormat, _ := GetStorageDateFormat(uploader, batchJobs.BatchDestination, folderName)
+			datePrefixLayout = dateFormat
+		}
 
-	brt.logger.Debugf("BRT: Date prefix layout is %s", datePrefixLayout)
-	switch datePrefixLayout {
-	case "MM-DD-YYYY": // used to be earlier default
-		datePrefixLayout = time.Now().Format("01-02-2006")
-	default:
-		datePrefixLayout = time.Now().Format("2006-01-02")
+		brt.logger.Debugf("BRT: Date prefix layout is %s", datePrefixLayout)
+		switch datePrefixLayout {
+		case "MM-DD-YYYY": // used to be earlier default
+			datePrefixLayout = time.Now().Format("01-02-2006")
+		default:
+			datePrefixLayout = time.Now().Format("2006-01-02")
+		}
+		keyPrefixes = []string{folderName, batchJobs.BatchDestination.Source.ID, datePrefixLayout}
 	}
-	keyPrefixes := []string{folderName, batchJobs.BatchDestination.Source.ID, datePrefixLayout}
 
-	_, fileName := filepath.Split(gzipFilePath)
+	_, fileName := filepath.Split(localFilePath)
 	var (
 		opID      int64
 		opPayload stdjson.RawMessage
@@ -791,7 +859,7 @@ func (brt *HandleT) copyJobsToStorage(provider string, batchJobs *BatchJobsT, is
 		return StorageUploadOutput{
 			Error:          err,
 			JournalOpID:    opID,
-			LocalFilePaths: []string{gzipFilePath},
+			LocalFilePaths: []string{localFilePath},
 		}
 	}
 
@@ -799,7 +867,7 @@ func (brt *HandleT) copyJobsToStorage(provider string, batchJobs *BatchJobsT, is
 		Config:           batchJobs.BatchDestination.Destination.Config,
 		Key:              uploadOutput.ObjectName,
 		FileLocation:     uploadOutput.Location,
-		LocalFilePaths:   []string{gzipFilePath},
+		LocalFilePaths:   []string{localFilePath},
 		JournalOpID:      opID,
 		FirstEventAt:     firstEventAt,
 		LastEventAt:      lastEventAt,
@@ -1633,6 +1701,7 @@ func (worker *workerT) workerProcess() {
 					ParameterFilters:              parameterFilters,
 					IgnoreCustomValFiltersInQuery: true,
 					PayloadSizeLimit:              brt.payloadLimit,
+					ExcludeWorkspaceIDs:           brt.pausedWorkspaceIDs(),
 				}
 
 				toRetry, err := misc.QueryWithRetriesAndNotify(context.Background(), brt.jobdDBQueryRequestTimeout, brt.jobdDBMaxRetries, func(ctx context.Context) (jobsdb.JobsResult, error) {
@@ -1674,6 +1743,7 @@ func (worker *workerT) workerProcess() {
 			}
 			continue
 		}
+		brt.jobsTakenOff(combinedList)
 
 		var statusList []*jobsdb.JobStatusT
 		var drainList []*jobsdb.JobStatusT
@@ -1804,17 +1874,23 @@ func (worker *workerT) workerProcess() {
 				case misc.Contains(objectStorageDestinations, brt.destType):
 					destUploadStat := stats.Default.NewStat(fmt.Sprintf(`batch_router.%s_dest_upload_time`, brt.destType), stats.TimerType)
 					destUploadStat.Start()
-					output := brt.copyJobsToStorage(brt.destType, &batchJobs, false)
-					brt.recordDeliveryStatus(*batchJobs.BatchDestination, output, false)
-					brt.setJobStatus(&batchJobs, false, output.Error, false)
-					misc.RemoveFilePaths(output.LocalFilePaths...)
-					if output.JournalOpID > 0 {
-						brt.jobsDB.JournalDeleteEntry(output.JournalOpID)
-					}
-					if output.Error == nil {
-						brt.recordUploadStats(*batchJobs.BatchDestination, output)
+					for _, batchJob := range splitBatchJobsOnFileLayout(brt.destType, batchJobs) {
+						var output StorageUploadOutput
+						if err := brt.copyJobsToEventBus(batchJob); err != nil {
+							output.Error = err
+						} else {
+							output = brt.copyJobsToStorage(brt.destType, batchJob, false)
+						}
+						brt.recordDeliveryStatus(*batchJob.BatchDestination, output, false)
+						brt.setJobStatus(batchJob, false, output.Error, false)
+						misc.RemoveFilePaths(output.LocalFilePaths...)
+						if output.JournalOpID > 0 {
+							brt.jobsDB.JournalDeleteEntry(output.JournalOpID)
+						}
+						if output.Error == nil {
+							brt.recordUploadStats(*batchJob.BatchDestination, output)
+						}
 					}
-
 					destUploadStat.End()
 				case misc.Contains(warehouseutils.WarehouseDestinations, brt.destType):
 					useRudderStorage := misc.IsConfiguredToUseRudderObjectStorage(batchJobs.BatchDestination.Destination.Config)
@@ -1823,7 +1899,12 @@ func (worker *workerT) workerProcess() {
 					destUploadStat.Start()
 					splitBatchJobs := brt.splitBatchJobsOnTimeWindow(batchJobs)
 					for _, batchJob := range splitBatchJobs {
-						output := brt.copyJobsToStorage(objectStorageType, batchJob, true)
+						var output StorageUploadOutput
+						if err := brt.copyJobsToEventBus(batchJob); err != nil {
+							output.Error = err
+						} else {
+							output = brt.copyJobsToStorage(objectStorageType, batchJob, true)
+						}
 						postToWarehouseErr := false
 						if output.Error == nil && output.Key != "" {
 							output.Error = brt.postToWarehouse(batchJob, output)
@@ -1849,6 +1930,7 @@ func (worker *workerT) workerProcess() {
 		}
 
 		wg.Wait()
+		brt.jobsLanded(combinedList)
 		brt.setDestInProgress(batchDest.Destination.ID, false)
 		// NOTE: Calling Done on parentWG is important before listening on channel again.
 		if batchDestData.parentWG != nil {
@@ -1891,6 +1973,7 @@ type BatchJobsT struct {
 	Jobs             []*jobsdb.JobT
 	BatchDestination *DestinationT
 	TimeWindow       time.Time
+	EventType        string // set if the batch holds the events of a single type
 }
 
 func connectionIdentifier(batchDestination DestinationT) string {
@@ -1934,10 +2017,10 @@ func (brt *HandleT) setDestInProgress(destID string, starting bool) {
 	brt.inProgressMapLock.Unlock()
 }
 
-func (brt *HandleT) uploadFrequencyExceeded(destID string) bool {
+func (brt *HandleT) uploadFrequencyExceeded(destID string, uploadFreq time.Duration) bool {
 	brt.lastExecMapLock.Lock()
 	defer brt.lastExecMapLock.Unlock()
-	if lastExecTime, ok := brt.lastExecMap[destID]; ok && time.Now().Unix()-lastExecTime < uploadFreqInS {
+	if lastExecTime, ok := brt.lastExecMap[destID]; ok && time.Now().Unix()-lastExecTime < int64(uploadFreq/time.Second) {
 		return true
 	}
 	brt.lastExecMap[destID] = time.Now().Unix()
@@ -1956,7 +2039,7 @@ func (brt *HandleT) readAndProcess() {
 				brt.logger.Debugf("BRT: Skipping batch router upload loop since destination %s:%s is in progress", batchDest.Destination.DestinationDefinition.Name, destID)
 				continue
 			}
-			if brt.uploadFrequencyExceeded(destID) {
+			if brt.uploadFrequencyExceeded(destID, getUploadFrequency(batchDest.Destination.Config)) {
 				brt.logger.Debugf("BRT: Skipping batch router upload loop since %s:%s upload freq not exceeded", batchDest.Destination.DestinationDefinition.Name, destID)
 				continue
 			}
@@ -1965,7 +2048,7 @@ func (brt *HandleT) readAndProcess() {
 			brt.processQ <- &BatchDestinationDataT{batchDestination: *batchDest, jobs: jobs, parentWG: nil}
 		}
 	} else {
-		if brt.uploadFrequencyExceeded(brt.destType) {
+		if brt.uploadFrequencyExceeded(brt.destType, time.Duration(uploadFreqInS)*time.Second) {
 			brt.logger.Debugf("BRT: %s: Skipping batch router read since upload freq not exceeded", brt.destType)
 			return
 		}
@@ -1976,9 +2059,10 @@ func (brt *HandleT) readAndProcess() {
 
 		if !brt.holdFetchingJobs([]jobsdb.ParameterFilterT{}) {
 			queryParams := jobsdb.GetQueryParamsT{
-				CustomValFilters: []string{brt.destType},
-				JobsLimit:        brt.jobQueryBatchSize,
-				PayloadSizeLimit: brt.payloadLimit,
+				CustomValFilters:    []string{brt.destType},
+				JobsLimit:           brt.jobQueryBatchSize,
+				PayloadSizeLimit:    brt.payloadLimit,
+				ExcludeWorkspaceIDs: brt.pausedWorkspaceIDs(),
 			}
 			toRetry, err := misc.QueryWithRetriesAndNotify(context.Background(), brt.jobdDBQueryRequestTimeout, brt.jobdDBMaxRetries, func(ctx context.Context) (jobsdb.JobsResult, error) {
 				return brt.jobsDB.GetToRetry(ctx, queryParams)
@@ -2111,6 +2195,26 @@ func (brt *HandleT) dedupRawDataDestJobsOnCrash() {
 
 		jsonFile.Close()
 		defer os.Remove(jsonPath)
+
+		brt.logger.Debug("BRT: Setting go map cache for incomplete journal entry to recover from...")
+		markUploaded := func(eventID string) {
+			if _, ok := brt.uploadedRawDataJobsCache[object.DestinationID]; !ok {
+				brt.uploadedRawDataJobsCache[object.DestinationID] = make(map[string]bool)
+			}
+			brt.uploadedRawDataJobsCache[object.DestinationID][eventID] = true
+		}
+		if strings.HasSuffix(object.Key, "."+fileFormatParquet) {
+			parquetFile, err := local.NewLocalFileReader(jsonPath)
+			if err != nil {
+				panic(err)
+			}
+			if err := parquetMessageIDs(parquetFile, markUploaded); err != nil {
+				brt.logger.Errorf("BRT: Failed to read parquet data for incomplete journal entry to recover from %s at key: %s with error: %v\n", object.Provider, object.Key, err)
+			}
+			_ = parquetFile.Close()
+			brt.jobsDB.JournalDeleteEntry(entry.OpID)
+			continue
+		}
 		rawf, err := os.Open(jsonPath)
 		if err != nil {
 			panic(err)
@@ -2119,17 +2223,15 @@ func (brt *HandleT) dedupRawDataDestJobsOnCrash() {
 		if err != nil {
 			panic(err)
 		}
-
-		sc := bufio.NewScanner(reader)
-
-		brt.logger.Debug("BRT: Setting go map cache for incomplete journal entry to recover from...")
-		for sc.Scan() {
-			lineBytes := sc.Bytes()
-			eventID := gjson.GetBytes(lineBytes, "messageId").String()
-			if _, ok := brt.uploadedRawDataJobsCache[object.DestinationID]; !ok {
-				brt.uploadedRawDataJobsCache[object.DestinationID] = make(map[string]bool)
+		if strings.HasSuffix(object.Key, "."+fileFormatCSV+".gz") {
+			if err := csvMessageIDs(reader, markUploaded); err != nil {
+				brt.logger.Errorf("BRT: Failed to read csv data for incomplete journal entry to recover from %s at key: %s with error: %v\n", object.Provider, object.Key, err)
+			}
+		} else {
+			sc := bufio.NewScanner(reader)
+			for sc.Scan() {
+				markUploaded(gjson.GetBytes(sc.Bytes(), "messageId").String())
 			}
-			brt.uploadedRawDataJobsCache[object.DestinationID][eventID] = true
 		}
 		reader.Close()
 		brt.jobsDB.JournalDeleteEntry(entry.OpID)
@@ -2250,7 +2352,7 @@ func (brt *HandleT) collectMetrics(ctx context.Context) {
 func loadConfig() {
 	config.RegisterDurationConfigVariable(2, &mainLoopSleep, true, time.Second, []string{"BatchRouter.mainLoopSleep", "BatchRouter.mainLoopSleepInS"}...)
 	config.RegisterInt64ConfigVariable(30, &uploadFreqInS, true, 1, "BatchRouter.uploadFreqInS")
-	objectStorageDestinations = []string{"S3", "GCS", "AZURE_BLOB", "MINIO", "DIGITAL_OCEAN_SPACES"}
+	objectStorageDestinations = []string{"S3", "GCS", "AZURE_BLOB", "MINIO", "DIGITAL_OCEAN_SPACES", "SFTP"}
 	asyncDestinations = []string{"MARKETO_BULK_UPLOAD"}
 	// Time period for diagnosis ticker
 	config.RegisterDurationConfigVariable(600, &diagnosisTickerTime, false, time.Second, []string{"Diagnostics.batchRouterTimePeriod", "Diagnostics.batchRouterTimePeriodInS"}...)
@@ -2397,6 +2499,7 @@ func (brt *HandleT) Start() {
 func (brt *HandleT) Shutdown() {
 	brt.backgroundCancel()
 	_ = brt.backgroundWait()
+	brt.closeEventBusProducers()
 }
 
 func (brt *HandleT) updateRudderSourcesStats(ctx context.Context, tx jobsdb.UpdateSafeTx, jobs []*jobsdb.JobT, jobStatuses []*jobsdb.JobStatusT) error {
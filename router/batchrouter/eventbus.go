@@ -0,0 +1,253 @@
+package batchrouter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tidwall/gjson"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/streammanager"
+	"github.com/rudderlabs/rudder-server/services/streammanager/common"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// The events of the batches of object storage and warehouse destinations can be copied to an event bus, i.e. a Kafka,
+// Kinesis or Google Pub/Sub destination of the workspace, alongside their upload. The batch destination config is e.g.
+//
+//	"eventBusCopy": {"destinationId": "<event bus destination id>", "topic": "events", "partitionKey": "userId", "chunkSize": 500}
+//
+// where the partition key is the path of an event field, the userId of the jobs being used for events without it.
+// The events are produced in chunks of chunkSize events, the events of a chunk concurrently, and are retried a few
+// times on failures. The copy is part of the outcome of the batch: it is done before the upload, and events failing
+// to be produced with retryable errors fail the batch, for it to be retried, so that events are copied at least once.
+// Events rejected by the event bus are counted as failed but don't fail the batch, since retrying them can't succeed.
+//
+// The producer of an event bus destination is shared by all batches, and is only recreated once its config changes.
+
+var eventBusDestinations = []string{"KAFKA", "KINESIS", "GOOGLEPUBSUB"}
+
+// newEventBusProducer creates the producers of event bus destinations, overridden in tests
+var newEventBusProducer = func(destination *backendconfig.DestinationT, opts common.Opts) (common.StreamProducer, error) {
+	return streammanager.NewProducer(destination, opts)
+}
+
+type eventBusCopyConfig struct {
+	DestinationID string `json:"destinationId"`
+	Topic         string `json:"topic"`
+	PartitionKey  string `json:"partitionKey"`
+	ChunkSize     int    `json:"chunkSize"`
+}
+
+// getEventBusCopyConfig returns the event bus copy config of the batch destination, if any
+func getEventBusCopyConfig(destination backendconfig.DestinationT) (eventBusCopyConfig, bool) {
+	var conf eventBusCopyConfig
+	value, ok := destination.Config["eventBusCopy"]
+	if !ok {
+		return conf, false
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return conf, false
+	}
+	if err := json.Unmarshal(b, &conf); err != nil || conf.DestinationID == "" {
+		return conf, false
+	}
+	if conf.ChunkSize <= 0 {
+		conf.ChunkSize = config.GetInt("BatchRouter.eventBusCopy.chunkSize", 500)
+	}
+	return conf, true
+}
+
+// eventBusMessage returns the message to produce to the event bus for a job
+func eventBusMessage(job *jobsdb.JobT, conf eventBusCopyConfig) ([]byte, error) {
+	key := job.UserID
+	if conf.PartitionKey != "" {
+		if value := gjson.GetBytes(job.EventPayload, conf.PartitionKey); value.Exists() && value.String() != "" {
+			key = value.String()
+		}
+	}
+	message := map[string]interface{}{
+		"message": jsoniter.RawMessage(job.EventPayload),
+		"userId":  key, // kafka message key and kinesis partition key
+	}
+	if conf.Topic != "" {
+		message["topic"] = conf.Topic   // kafka
+		message["topicId"] = conf.Topic // google pub/sub
+	}
+	message["attributes"] = map[string]string{"partitionKey": key} // google pub/sub
+	return json.Marshal(message)
+}
+
+// eventBusProducer is the producer of an event bus destination, shared by the batches copied to it. inUse is read
+// locked by the batches using it, for it to be closed only once they're done.
+type eventBusProducer struct {
+	destination backendconfig.DestinationT
+	producer    common.StreamProducer
+	inUse       sync.RWMutex
+}
+
+func (p *eventBusProducer) close() {
+	p.inUse.Lock()
+	defer p.inUse.Unlock()
+	_ = p.producer.Close()
+}
+
+// getEventBusProducer returns the producer of the event bus destination, creating it on first use or once the
+// destination config has changed. The returned producer is read locked, to be unlocked once done with it.
+func (brt *HandleT) getEventBusProducer(eventBus backendconfig.DestinationT) (*eventBusProducer, error) {
+	brt.eventBusProducersMu.Lock()
+	defer brt.eventBusProducersMu.Unlock()
+	if p, ok := brt.eventBusProducers[eventBus.ID]; ok {
+		if reflect.DeepEqual(p.destination, eventBus) {
+			p.inUse.RLock()
+			return p, nil
+		}
+		delete(brt.eventBusProducers, eventBus.ID)
+		go p.close()
+	}
+	producer, err := newEventBusProducer(&eventBus, common.Opts{Timeout: config.GetDuration("BatchRouter.eventBusCopy.timeout", 10, time.Second)})
+	if err != nil {
+		return nil, err
+	}
+	if brt.eventBusProducers == nil {
+		brt.eventBusProducers = map[string]*eventBusProducer{}
+	}
+	p := &eventBusProducer{destination: eventBus, producer: producer}
+	brt.eventBusProducers[eventBus.ID] = p
+	p.inUse.RLock()
+	return p, nil
+}
+
+// closeEventBusProducers closes the producers of all event bus destinations, waiting for the batches using them
+func (brt *HandleT) closeEventBusProducers() {
+	brt.eventBusProducersMu.Lock()
+	defer brt.eventBusProducersMu.Unlock()
+	for id, p := range brt.eventBusProducers {
+		p.close()
+		delete(brt.eventBusProducers, id)
+	}
+}
+
+// copyJobsToEventBus produces the events of the batch to the event bus of the batch destination, if any, returning an
+// error if the batch is to be retried
+func (brt *HandleT) copyJobsToEventBus(batchJobs *BatchJobsT) error {
+	batchDestination := batchJobs.BatchDestination.Destination
+	conf, ok := getEventBusCopyConfig(batchDestination)
+	if !ok {
+		return nil
+	}
+	brt.configSubscriberLock.RLock()
+	eventBus, ok := brt.eventBusDestinationsMap[conf.DestinationID]
+	brt.configSubscriberLock.RUnlock()
+	tags := stats.Tags{
+		"destType":     brt.destType,
+		"destID":       batchDestination.ID,
+		"eventBusType": eventBus.DestinationDefinition.Name,
+	}
+	failed := func(count int) {
+		tags := misc.CopyStringMap(tags)
+		tags["status"] = "failed"
+		stats.Default.NewTaggedStat("batch_router.event_bus_copied_events", stats.CountType, tags).Count(count)
+	}
+	if !ok {
+		failed(len(batchJobs.Jobs))
+		return fmt.Errorf("event bus destination %s of destination %s not found in config", conf.DestinationID, batchDestination.ID)
+	}
+
+	copyStat := stats.Default.NewTaggedStat("batch_router.event_bus_copy_time", stats.TimerType, tags)
+	copyStat.Start()
+	defer copyStat.End()
+	p, err := brt.getEventBusProducer(eventBus)
+	if err != nil {
+		failed(len(batchJobs.Jobs))
+		return fmt.Errorf("creating producer of event bus destination %s: %w", conf.DestinationID, err)
+	}
+	defer p.inUse.RUnlock()
+
+	concurrency := config.GetInt("BatchRouter.eventBusCopy.concurrency", 10)
+	maxRetries := config.GetInt("BatchRouter.eventBusCopy.maxRetries", 3)
+	var failedCount, retryableCount int
+	for start := 0; start < len(batchJobs.Jobs); start += conf.ChunkSize {
+		end := start + conf.ChunkSize
+		if end > len(batchJobs.Jobs) {
+			end = len(batchJobs.Jobs)
+		}
+		chunk := batchJobs.Jobs[start:end]
+		results := make([]eventBusResult, len(chunk))
+		g, _ := errgroup.WithContext(context.Background())
+		g.SetLimit(concurrency)
+		for i, job := range chunk {
+			i, job := i, job
+			g.Go(func() error {
+				results[i] = brt.produceToEventBus(p.producer, eventBus, job, conf, maxRetries)
+				return nil
+			})
+		}
+		_ = g.Wait()
+		for _, result := range results {
+			switch result {
+			case eventBusRetryable:
+				retryableCount++
+				failedCount++
+			case eventBusRejected:
+				failedCount++
+			}
+		}
+	}
+
+	succeededTags := misc.CopyStringMap(tags)
+	succeededTags["status"] = "succeeded"
+	stats.Default.NewTaggedStat("batch_router.event_bus_copied_events", stats.CountType, succeededTags).Count(len(batchJobs.Jobs) - failedCount)
+	if failedCount > 0 {
+		brt.logger.Errorf("BRT: %s: %d events of destination %s failed to be copied to event bus destination %s", brt.destType, failedCount, batchDestination.ID, conf.DestinationID)
+		failed(failedCount)
+	}
+	if retryableCount > 0 {
+		return fmt.Errorf("%d events failed to be copied to event bus destination %s", retryableCount, conf.DestinationID)
+	}
+	return nil
+}
+
+// eventBusResult is the outcome of producing an event to the event bus
+type eventBusResult int
+
+const (
+	eventBusProduced  eventBusResult = iota
+	eventBusRejected                 // failed with a non retryable error
+	eventBusRetryable                // failed with a retryable error, after retries
+)
+
+// produceToEventBus produces the event of a job to the event bus, retrying on retryable failures
+func (brt *HandleT) produceToEventBus(producer common.StreamProducer, eventBus backendconfig.DestinationT, job *jobsdb.JobT, conf eventBusCopyConfig, maxRetries int) eventBusResult {
+	message, err := eventBusMessage(job, conf)
+	if err != nil {
+		brt.logger.Errorf("BRT: %s: preparing event bus message of job %d: %v", brt.destType, job.JobID, err)
+		return eventBusRejected
+	}
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		statusCode, _, response := producer.Produce(message, eventBus.Config)
+		if statusCode == 200 {
+			return eventBusProduced
+		}
+		if isJobTerminated(statusCode) {
+			brt.logger.Debugf("BRT: %s: producing job %d to event bus was rejected with %d: %s", brt.destType, job.JobID, statusCode, response)
+			return eventBusRejected
+		}
+		if attempt >= maxRetries {
+			brt.logger.Debugf("BRT: %s: producing job %d to event bus failed with %d: %s", brt.destType, job.JobID, statusCode, response)
+			return eventBusRetryable
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,158 @@
+package batchrouter
+
+import (
+	jsonb "encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/services/streammanager/common"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+type fakeEventBusProducer struct {
+	mu       sync.Mutex
+	messages []string
+	// statusCodes returns the status code of a message, 200 if nil
+	statusCodes func(message string) int
+	closed      bool
+}
+
+func (p *fakeEventBusProducer) Produce(jsonData jsonb.RawMessage, _ interface{}) (int, string, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, string(jsonData))
+	if p.statusCodes != nil {
+		if statusCode := p.statusCodes(string(jsonData)); statusCode != 200 {
+			return statusCode, "Failure", "failed"
+		}
+	}
+	return 200, "Success", ""
+}
+
+func (p *fakeEventBusProducer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func TestCopyJobsToEventBus(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+	config.Set("BatchRouter.eventBusCopy.maxRetries", 1)
+	prevStats := stats.Default
+	defer func() { stats.Default = prevStats }()
+	store := memstats.New()
+	stats.Default = store
+
+	var producers []*fakeEventBusProducer
+	prevNewEventBusProducer := newEventBusProducer
+	defer func() { newEventBusProducer = prevNewEventBusProducer }()
+	newEventBusProducer = func(destination *backendconfig.DestinationT, _ common.Opts) (common.StreamProducer, error) {
+		if destination.ID != "kafka" {
+			return nil, errors.New("unexpected destination")
+		}
+		producers = append(producers, &fakeEventBusProducer{})
+		return producers[len(producers)-1], nil
+	}
+
+	brt := &HandleT{
+		destType: "S3",
+		logger:   logger.NOP,
+		eventBusDestinationsMap: map[string]backendconfig.DestinationT{
+			"kafka": {ID: "kafka", DestinationDefinition: backendconfig.DestinationDefinitionT{Name: "KAFKA"}},
+		},
+	}
+	batchJobs := &BatchJobsT{
+		Jobs: []*jobsdb.JobT{
+			{JobID: 1, UserID: "user-1", EventPayload: []byte(`{"userId":"user-1","context":{"traits":{"accountId":"account-1"}}}`)},
+			{JobID: 2, UserID: "user-2", EventPayload: []byte(`{"userId":"user-2","event":"abort"}`)},
+			{JobID: 3, UserID: "user-3", EventPayload: []byte(`{"userId":"user-3","event":"retry"}`)},
+		},
+		BatchDestination: &DestinationT{Destination: backendconfig.DestinationT{
+			ID: "s3",
+			Config: map[string]interface{}{
+				"eventBusCopy": map[string]interface{}{"destinationId": "kafka", "topic": "events", "partitionKey": "context.traits.accountId", "chunkSize": 2},
+			},
+		}},
+	}
+	statusCodes := func(message string) int {
+		switch gjson.Get(message, "message.event").String() {
+		case "abort":
+			return 400
+		case "retry":
+			return 500
+		}
+		return 200
+	}
+
+	// the producer is created on first use, the status codes being set right after
+	require.NoError(t, brt.copyJobsToEventBus(&BatchJobsT{Jobs: nil, BatchDestination: batchJobs.BatchDestination}))
+	require.Len(t, producers, 1)
+	producer := producers[0]
+	producer.statusCodes = statusCodes
+
+	err := brt.copyJobsToEventBus(batchJobs)
+	require.Error(t, err, "retryable failures fail the batch")
+	require.False(t, producer.closed, "the producer is reused across batches")
+	require.Len(t, producer.messages, 4, "retryable failures are retried")
+	messages := map[string]string{}
+	for _, m := range producer.messages {
+		messages[gjson.Get(m, "message.userId").String()] = m
+	}
+	require.JSONEq(t, `{
+		"message": {"userId":"user-1","context":{"traits":{"accountId":"account-1"}}},
+		"userId": "account-1",
+		"topic": "events",
+		"topicId": "events",
+		"attributes": {"partitionKey": "account-1"}
+	}`, messages["user-1"])
+	require.Equal(t, "user-2", gjson.Get(messages["user-2"], "userId").String(), "the userId of the job is the default partition key")
+
+	tags := stats.Tags{"destType": "S3", "destID": "s3", "eventBusType": "KAFKA"}
+	tags["status"] = "succeeded"
+	require.EqualValues(t, 1, store.Get("batch_router.event_bus_copied_events", tags).LastValue())
+	tags["status"] = "failed"
+	require.EqualValues(t, 2, store.Get("batch_router.event_bus_copied_events", tags).LastValue())
+
+	t.Run("rejected events don't fail the batch", func(t *testing.T) {
+		err := brt.copyJobsToEventBus(&BatchJobsT{Jobs: batchJobs.Jobs[:2], BatchDestination: batchJobs.BatchDestination})
+		require.NoError(t, err)
+		require.Len(t, producers, 1, "the producer is reused across batches")
+	})
+
+	t.Run("producer recreated on config change", func(t *testing.T) {
+		brt.eventBusDestinationsMap["kafka"] = backendconfig.DestinationT{
+			ID:                    "kafka",
+			Config:                map[string]interface{}{"topic": "other"},
+			DestinationDefinition: backendconfig.DestinationDefinitionT{Name: "KAFKA"},
+		}
+		require.NoError(t, brt.copyJobsToEventBus(&BatchJobsT{Jobs: batchJobs.Jobs[:1], BatchDestination: batchJobs.BatchDestination}))
+		require.Len(t, producers, 2)
+		require.Eventually(t, func() bool {
+			producer.mu.Lock()
+			defer producer.mu.Unlock()
+			return producer.closed
+		}, time.Second, time.Millisecond, "the previous producer is closed")
+
+		brt.closeEventBusProducers()
+		require.True(t, producers[1].closed)
+	})
+
+	t.Run("without copy config", func(t *testing.T) {
+		producers[1].messages = nil
+		batchJobs.BatchDestination.Destination.Config = map[string]interface{}{}
+		require.NoError(t, brt.copyJobsToEventBus(batchJobs))
+		require.Empty(t, producers[1].messages)
+	})
+}
@@ -0,0 +1,332 @@
+package batchrouter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// The layout of the files uploaded to object storage and file transfer destinations is configured per destination, e.g.
+//
+//	"fileFormat": "csv", "pathTemplate": "exports/{{.SourceName}}/{{.EventType}}/{{.Year}}/{{.Month}}/{{.Day}}",
+//	"maxFileSizeMB": 100, "maxFileAgeMinutes": 10
+//
+// Files are gzipped JSON lines, the default, gzipped CSV or Parquet. CSV and Parquet files have a column per leaf field
+// of the events of the file, in lexical order, nested objects being flattened into dot separated column names and
+// arrays being kept as JSON. Parquet columns are optional strings.
+//
+// Files are uploaded in the directory rendered from the path template of the destination, or the default
+// {{.FolderName}}/{{.SourceID}}/<date> one. A batch is split into a file per event type if its path template refers to
+// the event type, and into files of at most maxFileSizeMB of events. The batches of a destination are uploaded every
+// maxFileAgeMinutes instead of every BatchRouter.uploadFreqInS, as long as jobs are read per destination.
+
+const (
+	fileFormatJSON    = "json"
+	fileFormatCSV     = "csv"
+	fileFormatParquet = "parquet"
+
+	defaultPathTemplate = "{{.FolderName}}/{{.SourceID}}/{{.Date}}"
+)
+
+var fileTransferDestinations = []string{"SFTP"}
+
+// pathTemplateData is the data available to the path templates of destinations
+type pathTemplateData struct {
+	FolderName    string
+	SourceID      string
+	SourceName    string
+	DestinationID string
+	EventType     string
+	Date          string // YYYY-MM-DD
+	Year          string
+	Month         string
+	Day           string
+	Hour          string
+}
+
+func isFileTransferDestination(destType string) bool {
+	return misc.Contains(fileTransferDestinations, destType)
+}
+
+// getFileFormat returns the format of the files uploaded to the destination
+func getFileFormat(destConfig map[string]interface{}) string {
+	format, _ := destConfig["fileFormat"].(string)
+	switch strings.ToLower(format) {
+	case fileFormatCSV:
+		return fileFormatCSV
+	case fileFormatParquet:
+		return fileFormatParquet
+	default:
+		return fileFormatJSON
+	}
+}
+
+// getPathTemplate returns the path template of the destination, empty if files are uploaded under the legacy layout
+// of object storage destinations
+func getPathTemplate(destType string, destConfig map[string]interface{}) string {
+	pathTemplate, _ := destConfig["pathTemplate"].(string)
+	if strings.TrimSpace(pathTemplate) == "" && isFileTransferDestination(destType) {
+		return defaultPathTemplate
+	}
+	return strings.TrimSpace(pathTemplate)
+}
+
+// getMaxFileSize returns the maximum size in bytes of the events of a file uploaded to the destination, 0 if unlimited
+func getMaxFileSize(destConfig map[string]interface{}) int {
+	return int(getConfigNumber(destConfig, "maxFileSizeMB") * 1024 * 1024)
+}
+
+// getUploadFrequency returns how often the batches of the destination are uploaded
+func getUploadFrequency(destConfig map[string]interface{}) time.Duration {
+	if maxFileAge := getConfigNumber(destConfig, "maxFileAgeMinutes"); maxFileAge > 0 {
+		return time.Duration(maxFileAge * float64(time.Minute))
+	}
+	return time.Duration(uploadFreqInS) * time.Second
+}
+
+// getConfigNumber returns the number the destination config has for key, either as a number or a string, 0 if none
+func getConfigNumber(destConfig map[string]interface{}, key string) float64 {
+	switch value := destConfig[key].(type) {
+	case float64:
+		return value
+	case string:
+		number, _ := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return number
+	default:
+		return 0
+	}
+}
+
+// renderKeyPrefixes renders the path template into the directories to upload the batch to
+func renderKeyPrefixes(pathTemplate string, batchJobs *BatchJobsT, folderName string, now time.Time) ([]string, error) {
+	tmpl, err := template.New("path").Option("missingkey=error").Parse(pathTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing path template %q: %w", pathTemplate, err)
+	}
+	// values must not introduce directories of their own
+	sanitize := strings.NewReplacer("/", "_", "\\", "_").Replace
+	now = now.UTC()
+	var rendered bytes.Buffer
+	err = tmpl.Execute(&rendered, pathTemplateData{
+		FolderName:    folderName,
+		SourceID:      sanitize(batchJobs.BatchDestination.Source.ID),
+		SourceName:    sanitize(batchJobs.BatchDestination.Source.Name),
+		DestinationID: sanitize(batchJobs.BatchDestination.Destination.ID),
+		EventType:     sanitize(batchJobs.EventType),
+		Date:          now.Format("2006-01-02"),
+		Year:          now.Format("2006"),
+		Month:         now.Format("01"),
+		Day:           now.Format("02"),
+		Hour:          now.Format("15"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rendering path template %q: %w", pathTemplate, err)
+	}
+	var prefixes []string
+	for _, prefix := range strings.Split(rendered.String(), "/") {
+		switch prefix = strings.TrimSpace(prefix); prefix {
+		case "", ".":
+		case "..":
+			return nil, fmt.Errorf("path template %q must not refer to parent directories", pathTemplate)
+		default:
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes, nil
+}
+
+// splitBatchJobsOnFileLayout splits the batch into the batches of the files to upload to the destination: a batch
+// per event type if the path template refers to it, split further into batches of at most the maximum file size
+func splitBatchJobsOnFileLayout(destType string, batchJobs BatchJobsT) []*BatchJobsT {
+	destConfig := batchJobs.BatchDestination.Destination.Config
+	byEventType := strings.Contains(getPathTemplate(destType, destConfig), ".EventType")
+	maxFileSize := getMaxFileSize(destConfig)
+	if !byEventType && maxFileSize <= 0 {
+		return []*BatchJobsT{&batchJobs}
+	}
+
+	var splitBatches []*BatchJobsT
+	currentBatches := make(map[string]*BatchJobsT)
+	currentSizes := make(map[string]int)
+	for _, job := range batchJobs.Jobs {
+		var eventType string
+		if byEventType {
+			eventType = gjson.GetBytes(job.EventPayload, "type").String()
+		}
+		batch, ok := currentBatches[eventType]
+		if !ok || (maxFileSize > 0 && currentSizes[eventType]+len(job.EventPayload) > maxFileSize) {
+			batch = &BatchJobsT{
+				BatchDestination: batchJobs.BatchDestination,
+				TimeWindow:       batchJobs.TimeWindow,
+				EventType:        eventType,
+			}
+			splitBatches = append(splitBatches, batch)
+			currentBatches[eventType] = batch
+			currentSizes[eventType] = 0
+		}
+		batch.Jobs = append(batch.Jobs, job)
+		currentSizes[eventType] += len(job.EventPayload)
+	}
+	return splitBatches
+}
+
+// flattenEvents returns the union of the flattened fields of the events, sorted, along with the flattened events
+func flattenEvents(payloads [][]byte, columnName func(string) string) (columns []string, rows []map[string]string) {
+	rows = make([]map[string]string, len(payloads))
+	columnSet := make(map[string]struct{})
+	for i, payload := range payloads {
+		rows[i] = make(map[string]string)
+		flattenEvent("", gjson.ParseBytes(payload), rows[i], columnName)
+		for column := range rows[i] {
+			columnSet[column] = struct{}{}
+		}
+	}
+	columns = make([]string, 0, len(columnSet))
+	for column := range columnSet {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns, rows
+}
+
+func flattenEvent(prefix string, value gjson.Result, row map[string]string, columnName func(string) string) {
+	if !value.IsObject() {
+		if prefix == "" {
+			return
+		}
+		if value.Type == gjson.String {
+			row[columnName(prefix)] = value.Str
+		} else {
+			row[columnName(prefix)] = value.Raw
+		}
+		return
+	}
+	value.ForEach(func(key, value gjson.Result) bool {
+		column := key.String()
+		if prefix != "" {
+			column = prefix + "." + column
+		}
+		flattenEvent(column, value, row, columnName)
+		return true
+	})
+}
+
+// writeCSVEvents writes the events as CSV rows, preceded by a header of the union of their flattened fields
+func writeCSVEvents(w io.Writer, payloads [][]byte) error {
+	columns, rows := flattenEvents(payloads, func(column string) string { return column })
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = row[column]
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// csvMessageIDs calls fn with the messageId of every row of CSV files written by writeCSVEvents
+func csvMessageIDs(r io.Reader, fn func(messageID string)) error {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	column := -1
+	for i, name := range header {
+		if name == "messageId" {
+			column = i
+		}
+	}
+	if column < 0 {
+		return nil
+	}
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if column < len(record) {
+			fn(record[column])
+		}
+	}
+}
+
+// writeParquetEvents writes the events as the rows of a Parquet file, with an optional string column per flattened field
+func writeParquetEvents(w io.Writer, payloads [][]byte) error {
+	// commas and equal signs would break the parquet schema tags
+	columns, rows := flattenEvents(payloads, strings.NewReplacer(",", "_", "=", "_").Replace)
+	schema := make([]string, len(columns))
+	for i, column := range columns {
+		schema[i] = fmt.Sprintf("name=%s, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL", column)
+	}
+	parquetWriter, err := writer.NewCSVWriterFromWriter(schema, w, 1)
+	if err != nil {
+		return err
+	}
+	record := make([]*string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = nil
+			if value, ok := row[column]; ok {
+				record[i] = &value
+			}
+		}
+		if err := parquetWriter.WriteString(record); err != nil {
+			return err
+		}
+	}
+	return parquetWriter.WriteStop()
+}
+
+// parquetMessageIDs calls fn with the messageId of every row of Parquet files written by writeParquetEvents
+func parquetMessageIDs(pf source.ParquetFile, fn func(messageID string)) error {
+	parquetReader, err := reader.NewParquetColumnReader(pf, 1)
+	if err != nil {
+		return err
+	}
+	defer parquetReader.ReadStop()
+	// the schema is flat, the columns following its root, and the footer only has the internal names of the columns
+	for i := 1; i < len(parquetReader.SchemaHandler.Infos); i++ {
+		if parquetReader.SchemaHandler.GetExName(i) != "messageId" {
+			continue
+		}
+		values, _, _, err := parquetReader.ReadColumnByIndex(int64(i-1), parquetReader.GetNumRows())
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if messageID, ok := value.(string); ok {
+				fn(messageID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package batchrouter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+)
+
+func TestGetFileFormat(t *testing.T) {
+	require.Equal(t, fileFormatCSV, getFileFormat(map[string]interface{}{"fileFormat": "CSV"}))
+	require.Equal(t, fileFormatParquet, getFileFormat(map[string]interface{}{"fileFormat": "parquet"}))
+	require.Equal(t, fileFormatJSON, getFileFormat(map[string]interface{}{}))
+	require.Equal(t, fileFormatJSON, getFileFormat(map[string]interface{}{"fileFormat": "xml"}))
+}
+
+func TestGetPathTemplate(t *testing.T) {
+	require.Equal(t, defaultPathTemplate, getPathTemplate("SFTP", map[string]interface{}{}))
+	require.Equal(t, "", getPathTemplate("S3", map[string]interface{}{}), "object storage destinations keep their legacy layout")
+	require.Equal(t, "{{.EventType}}", getPathTemplate("S3", map[string]interface{}{"pathTemplate": " {{.EventType}} "}))
+}
+
+func TestGetUploadFrequency(t *testing.T) {
+	uploadFreqInS = 30
+	require.Equal(t, 30*time.Second, getUploadFrequency(map[string]interface{}{}))
+	require.Equal(t, 10*time.Minute, getUploadFrequency(map[string]interface{}{"maxFileAgeMinutes": float64(10)}))
+	require.Equal(t, 90*time.Second, getUploadFrequency(map[string]interface{}{"maxFileAgeMinutes": "1.5"}))
+	require.Equal(t, 2*1024*1024, getMaxFileSize(map[string]interface{}{"maxFileSizeMB": "2"}))
+	require.Zero(t, getMaxFileSize(map[string]interface{}{}))
+}
+
+func TestRenderKeyPrefixes(t *testing.T) {
+	now := time.Date(2022, 10, 9, 8, 7, 6, 0, time.UTC)
+	batchJobs := &BatchJobsT{
+		BatchDestination: &DestinationT{
+			Source:      backendconfig.SourceT{ID: "source-id", Name: "web/prod"},
+			Destination: backendconfig.DestinationT{ID: "destination-id"},
+		},
+		EventType: "track",
+	}
+
+	prefixes, err := renderKeyPrefixes(defaultPathTemplate, batchJobs, "rudder-logs", now)
+	require.NoError(t, err)
+	require.Equal(t, []string{"rudder-logs", "source-id", "2022-10-09"}, prefixes)
+
+	prefixes, err = renderKeyPrefixes("/exports/{{.SourceName}}/{{.EventType}}/{{.Year}}/{{.Month}}/{{.Day}}/{{.Hour}}/", batchJobs, "rudder-logs", now)
+	require.NoError(t, err)
+	require.Equal(t, []string{"exports", "web_prod", "track", "2022", "10", "09", "08"}, prefixes)
+
+	_, err = renderKeyPrefixes("exports/{{.Unknown}}", batchJobs, "rudder-logs", now)
+	require.Error(t, err)
+	_, err = renderKeyPrefixes("../{{.SourceID}}", batchJobs, "rudder-logs", now)
+	require.Error(t, err)
+}
+
+func TestSplitBatchJobsOnFileLayout(t *testing.T) {
+	job := func(jobID int64, payload string) *jobsdb.JobT {
+		return &jobsdb.JobT{JobID: jobID, EventPayload: []byte(payload)}
+	}
+	jobIDs := func(batches []*BatchJobsT) (ids [][]int64) {
+		for _, batch := range batches {
+			var batchIDs []int64
+			for _, job := range batch.Jobs {
+				batchIDs = append(batchIDs, job.JobID)
+			}
+			ids = append(ids, batchIDs)
+		}
+		return ids
+	}
+	batchJobs := func(destConfig map[string]interface{}) BatchJobsT {
+		return BatchJobsT{
+			BatchDestination: &DestinationT{Destination: backendconfig.DestinationT{Config: destConfig}},
+			Jobs: []*jobsdb.JobT{
+				job(1, `{"type":"track","event":"a"}`),
+				job(2, `{"type":"identify","event":"b"}`),
+				job(3, `{"type":"track","event":"c"}`),
+			},
+		}
+	}
+
+	batches := splitBatchJobsOnFileLayout("S3", batchJobs(map[string]interface{}{}))
+	require.Equal(t, [][]int64{{1, 2, 3}}, jobIDs(batches))
+
+	batches = splitBatchJobsOnFileLayout("S3", batchJobs(map[string]interface{}{"pathTemplate": "{{.EventType}}/{{.Date}}"}))
+	require.Equal(t, [][]int64{{1, 3}, {2}}, jobIDs(batches))
+	require.Equal(t, "track", batches[0].EventType)
+	require.Equal(t, "identify", batches[1].EventType)
+
+	batches = splitBatchJobsOnFileLayout("S3", batchJobs(map[string]interface{}{"maxFileSizeMB": 60.0 / 1024 / 1024}))
+	require.Equal(t, [][]int64{{1, 2}, {3}}, jobIDs(batches))
+}
+
+func TestWriteCSVEvents(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeCSVEvents(&buf, [][]byte{
+		[]byte(`{"messageId":"message-1","event":"Order, Completed","properties":{"revenue":10.5,"products":[{"id":1}]}}`),
+		[]byte(`{"messageId":"message-2","context":{"traits":{"email":"user@example.com"}},"properties":{"revenue":null}}`),
+	}))
+	require.Equal(t, "context.traits.email,event,messageId,properties.products,properties.revenue\n"+
+		`,"Order, Completed",message-1,"[{""id"":1}]",10.5`+"\n"+
+		"user@example.com,,message-2,,null\n", buf.String())
+
+	var messageIDs []string
+	require.NoError(t, csvMessageIDs(&buf, func(messageID string) { messageIDs = append(messageIDs, messageID) }))
+	require.Equal(t, []string{"message-1", "message-2"}, messageIDs)
+}
+
+func TestWriteParquetEvents(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeParquetEvents(&buf, [][]byte{
+		[]byte(`{"messageId":"message-1","event":"Order Completed","properties":{"a,b":1}}`),
+		[]byte(`{"messageId":"message-2","context":{"ip":"1.2.3.4"}}`),
+	}))
+
+	var messageIDs []string
+	require.NoError(t, parquetMessageIDs(buffer.NewBufferFileFromBytes(buf.Bytes()), func(messageID string) { messageIDs = append(messageIDs, messageID) }))
+	require.Equal(t, []string{"message-1", "message-2"}, messageIDs)
+}
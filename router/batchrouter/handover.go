@@ -0,0 +1,91 @@
+package batchrouter
+
+import (
+	"sort"
+
+	"github.com/rudderlabs/rudder-server/jobsdb"
+)
+
+// Workspaces detached from the server are handed over once the batch router is drained of them: the batch router
+// stops picking up their jobs while they're paused, and counts the jobs of each workspace it picked up until their
+// batches are done, for the jobs still being uploaded to be handed over if they don't land in time.
+
+// DestinationType returns the type of the destinations the batch router uploads jobs to
+func (brt *HandleT) DestinationType() string {
+	return brt.destType
+}
+
+// PauseWorkspaces stops picking up jobs of the workspaces, until they're resumed
+func (brt *HandleT) PauseWorkspaces(workspaceIDs []string) {
+	brt.handoverMu.Lock()
+	defer brt.handoverMu.Unlock()
+	if brt.pausedWorkspaces == nil {
+		brt.pausedWorkspaces = make(map[string]struct{})
+	}
+	for _, workspaceID := range workspaceIDs {
+		brt.pausedWorkspaces[workspaceID] = struct{}{}
+	}
+}
+
+// ResumeWorkspaces picks up jobs of the workspaces again, if they were paused
+func (brt *HandleT) ResumeWorkspaces(workspaceIDs []string) {
+	brt.handoverMu.Lock()
+	defer brt.handoverMu.Unlock()
+	for _, workspaceID := range workspaceIDs {
+		delete(brt.pausedWorkspaces, workspaceID)
+	}
+}
+
+// InFlightJobs returns the number of jobs of each of the workspaces picked up whose batches aren't done yet, omitting
+// the workspaces without any
+func (brt *HandleT) InFlightJobs(workspaceIDs []string) map[string]int {
+	brt.handoverMu.RLock()
+	defer brt.handoverMu.RUnlock()
+	inFlight := make(map[string]int)
+	for _, workspaceID := range workspaceIDs {
+		if count := brt.inFlightJobs[workspaceID]; count > 0 {
+			inFlight[workspaceID] = count
+		}
+	}
+	return inFlight
+}
+
+// pausedWorkspaceIDs returns the workspaces whose jobs aren't picked up, for them to be excluded from the queries
+func (brt *HandleT) pausedWorkspaceIDs() []string {
+	brt.handoverMu.RLock()
+	defer brt.handoverMu.RUnlock()
+	if len(brt.pausedWorkspaces) == 0 {
+		return nil
+	}
+	workspaceIDs := make([]string, 0, len(brt.pausedWorkspaces))
+	for workspaceID := range brt.pausedWorkspaces {
+		workspaceIDs = append(workspaceIDs, workspaceID)
+	}
+	sort.Strings(workspaceIDs)
+	return workspaceIDs
+}
+
+// jobsTakenOff counts the jobs as in flight until jobsLanded
+func (brt *HandleT) jobsTakenOff(jobs []*jobsdb.JobT) {
+	brt.handoverMu.Lock()
+	defer brt.handoverMu.Unlock()
+	if brt.inFlightJobs == nil {
+		brt.inFlightJobs = make(map[string]int)
+	}
+	for _, job := range jobs {
+		brt.inFlightJobs[job.WorkspaceId]++
+	}
+}
+
+// jobsLanded counts the jobs, whose statuses are committed, as no longer in flight
+func (brt *HandleT) jobsLanded(jobs []*jobsdb.JobT) {
+	brt.handoverMu.Lock()
+	defer brt.handoverMu.Unlock()
+	for _, job := range jobs {
+		if brt.inFlightJobs[job.WorkspaceId] <= 1 {
+			delete(brt.inFlightJobs, job.WorkspaceId)
+			continue
+		}
+		brt.inFlightJobs[job.WorkspaceId]--
+	}
+}
@@ -0,0 +1,27 @@
+package batchrouter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/jobsdb"
+)
+
+func TestWorkspaceHandover(t *testing.T) {
+	brt := &HandleT{}
+	require.Nil(t, brt.pausedWorkspaceIDs())
+
+	brt.PauseWorkspaces([]string{"b", "a"})
+	require.Equal(t, []string{"a", "b"}, brt.pausedWorkspaceIDs())
+	brt.ResumeWorkspaces([]string{"b"})
+	require.Equal(t, []string{"a"}, brt.pausedWorkspaceIDs())
+
+	jobs := []*jobsdb.JobT{{JobID: 1, WorkspaceId: "a"}, {JobID: 2, WorkspaceId: "a"}, {JobID: 3, WorkspaceId: "c"}}
+	brt.jobsTakenOff(jobs)
+	require.Equal(t, map[string]int{"a": 2}, brt.InFlightJobs([]string{"a", "b"}))
+	brt.jobsLanded(jobs[:1])
+	require.Equal(t, map[string]int{"a": 1, "c": 1}, brt.InFlightJobs([]string{"a", "c"}))
+	brt.jobsLanded(jobs[1:])
+	require.Empty(t, brt.InFlightJobs([]string{"a", "c"}))
+}
@@ -4,6 +4,8 @@ import (
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	"github.com/rudderlabs/rudder-server/jobsdb"
 	"github.com/rudderlabs/rudder-server/router/throttler"
+	"github.com/rudderlabs/rudder-server/services/deliveryreceipts"
+	"github.com/rudderlabs/rudder-server/services/oauth"
 	"github.com/rudderlabs/rudder-server/services/rsources"
 	"github.com/rudderlabs/rudder-server/services/transientsource"
 )
@@ -17,6 +19,8 @@ type Factory struct {
 	TransientSources transientsource.Service
 	RsourcesService  rsources.JobService
 	ThrottlerFactory *throttler.Factory
+	OAuth            oauth.Authorizer        // shared by the routers of all destination types, optional
+	DeliveryReceipts *deliveryreceipts.Store // optional
 }
 
 func (f *Factory) New(destination *backendconfig.DestinationT, identifier string) *HandleT {
@@ -24,6 +28,8 @@ func (f *Factory) New(destination *backendconfig.DestinationT, identifier string
 		Reporting:        f.Reporting,
 		MultitenantI:     f.Multitenant,
 		throttlerFactory: f.ThrottlerFactory,
+		oauth:            f.OAuth,
+		deliveryReceipts: f.DeliveryReceipts,
 	}
 	destConfig := getRouterConfig(destination, identifier)
 	r.Setup(f.BackendConfig, f.RouterDB, f.ProcErrorDB, destConfig, f.TransientSources, f.RsourcesService)
@@ -0,0 +1,125 @@
+package router
+
+import (
+	"sort"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+)
+
+// Workspaces detached from the server are handed over once the router is drained of them: the router stops picking
+// up their jobs while they're paused, and counts the jobs of each workspace it picked up until their statuses are
+// committed, for the jobs still being delivered to be handed over if they don't land in time.
+//
+// Jobs of the workspaces paused by the control plane, through the paused flag of their settings, aren't picked up
+// either, until the flag is unset.
+
+// DestinationType returns the type of the destinations the router delivers jobs to
+func (rt *HandleT) DestinationType() string {
+	return rt.destName
+}
+
+// PauseWorkspaces stops picking up jobs of the workspaces, until they're resumed
+func (rt *HandleT) PauseWorkspaces(workspaceIDs []string) {
+	rt.handoverMu.Lock()
+	defer rt.handoverMu.Unlock()
+	if rt.pausedWorkspaces == nil {
+		rt.pausedWorkspaces = make(map[string]struct{})
+	}
+	for _, workspaceID := range workspaceIDs {
+		rt.pausedWorkspaces[workspaceID] = struct{}{}
+	}
+}
+
+// ResumeWorkspaces picks up jobs of the workspaces again, if they were paused
+func (rt *HandleT) ResumeWorkspaces(workspaceIDs []string) {
+	rt.handoverMu.Lock()
+	defer rt.handoverMu.Unlock()
+	for _, workspaceID := range workspaceIDs {
+		delete(rt.pausedWorkspaces, workspaceID)
+	}
+}
+
+// InFlightJobs returns the number of jobs of each of the workspaces picked up whose statuses aren't committed yet,
+// omitting the workspaces without any
+func (rt *HandleT) InFlightJobs(workspaceIDs []string) map[string]int {
+	rt.handoverMu.RLock()
+	defer rt.handoverMu.RUnlock()
+	inFlight := make(map[string]int)
+	for _, workspaceID := range workspaceIDs {
+		if count := rt.inFlightJobs[workspaceID]; count > 0 {
+			inFlight[workspaceID] = count
+		}
+	}
+	return inFlight
+}
+
+// setWorkspacesPausedBySettings replaces the workspaces paused by the control plane with the ones of the config whose
+// settings are paused
+func (rt *HandleT) setWorkspacesPausedBySettings(config map[string]backendconfig.ConfigT) {
+	paused := make(map[string]struct{})
+	for workspaceID := range config {
+		if config[workspaceID].Settings.Paused {
+			paused[workspaceID] = struct{}{}
+		}
+	}
+	rt.handoverMu.Lock()
+	defer rt.handoverMu.Unlock()
+	rt.pausedBySettings = paused
+}
+
+// pausedWorkspaceIDs returns the workspaces whose jobs aren't picked up, for them to be excluded from the queries
+// without fair pickup
+func (rt *HandleT) pausedWorkspaceIDs() []string {
+	rt.handoverMu.RLock()
+	defer rt.handoverMu.RUnlock()
+	var workspaceIDs []string
+	for workspaceID := range rt.pausedWorkspaces {
+		workspaceIDs = append(workspaceIDs, workspaceID)
+	}
+	for workspaceID := range rt.pausedBySettings {
+		if _, ok := rt.pausedWorkspaces[workspaceID]; !ok {
+			workspaceIDs = append(workspaceIDs, workspaceID)
+		}
+	}
+	sort.Strings(workspaceIDs)
+	return workspaceIDs
+}
+
+// excludePausedWorkspaces removes the paused workspaces from the pickup map, for their jobs not to be queried with fair
+// pickup
+func (rt *HandleT) excludePausedWorkspaces(pickupMap map[string]int) {
+	rt.handoverMu.RLock()
+	defer rt.handoverMu.RUnlock()
+	for workspaceID := range rt.pausedWorkspaces {
+		delete(pickupMap, workspaceID)
+	}
+	for workspaceID := range rt.pausedBySettings {
+		delete(pickupMap, workspaceID)
+	}
+}
+
+// jobsTakenOff counts the jobs as in flight until jobsLanded
+func (rt *HandleT) jobsTakenOff(jobs []*jobsdb.JobT) {
+	rt.handoverMu.Lock()
+	defer rt.handoverMu.Unlock()
+	if rt.inFlightJobs == nil {
+		rt.inFlightJobs = make(map[string]int)
+	}
+	for _, job := range jobs {
+		rt.inFlightJobs[job.WorkspaceId]++
+	}
+}
+
+// jobsLanded counts the jobs of the committed statuses as no longer in flight
+func (rt *HandleT) jobsLanded(statuses []*jobsdb.JobStatusT) {
+	rt.handoverMu.Lock()
+	defer rt.handoverMu.Unlock()
+	for _, status := range statuses {
+		if rt.inFlightJobs[status.WorkspaceId] <= 1 {
+			delete(rt.inFlightJobs, status.WorkspaceId)
+			continue
+		}
+		rt.inFlightJobs[status.WorkspaceId]--
+	}
+}
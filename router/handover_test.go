@@ -0,0 +1,48 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+)
+
+func TestWorkspaceHandover(t *testing.T) {
+	rt := &HandleT{}
+
+	rt.PauseWorkspaces([]string{"a", "b"})
+	require.Equal(t, []string{"a", "b"}, rt.pausedWorkspaceIDs())
+	pickupMap := map[string]int{"a": 10, "c": 10}
+	rt.excludePausedWorkspaces(pickupMap)
+	require.Equal(t, map[string]int{"c": 10}, pickupMap)
+	rt.ResumeWorkspaces([]string{"b"})
+	require.Equal(t, []string{"a"}, rt.pausedWorkspaceIDs())
+
+	rt.jobsTakenOff([]*jobsdb.JobT{{JobID: 1, WorkspaceId: "a"}, {JobID: 2, WorkspaceId: "a"}, {JobID: 3, WorkspaceId: "c"}})
+	require.Equal(t, map[string]int{"a": 2}, rt.InFlightJobs([]string{"a", "b"}))
+	rt.jobsLanded([]*jobsdb.JobStatusT{{JobID: 1, WorkspaceId: "a"}, {JobID: 3, WorkspaceId: "c"}})
+	require.Equal(t, map[string]int{"a": 1}, rt.InFlightJobs([]string{"a", "c"}))
+	rt.jobsLanded([]*jobsdb.JobStatusT{{JobID: 2, WorkspaceId: "a"}})
+	require.Empty(t, rt.InFlightJobs([]string{"a", "c"}))
+}
+
+func TestWorkspacesPausedBySettings(t *testing.T) {
+	rt := &HandleT{}
+
+	rt.setWorkspacesPausedBySettings(map[string]backendconfig.ConfigT{
+		"a": {Settings: backendconfig.Settings{Paused: true}},
+		"b": {},
+	})
+	require.Equal(t, []string{"a"}, rt.pausedWorkspaceIDs())
+	pickupMap := map[string]int{"a": 10, "b": 10}
+	rt.excludePausedWorkspaces(pickupMap)
+	require.Equal(t, map[string]int{"b": 10}, pickupMap)
+
+	rt.ResumeWorkspaces([]string{"a"})
+	require.Equal(t, []string{"a"}, rt.pausedWorkspaceIDs(), "workspaces paused by their settings aren't resumed by handovers")
+
+	rt.setWorkspacesPausedBySettings(map[string]backendconfig.ConfigT{"a": {}})
+	require.Empty(t, rt.pausedWorkspaceIDs())
+}
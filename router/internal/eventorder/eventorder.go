@@ -98,6 +98,42 @@ func (b *Barrier) Enter(key string, jobID int64) (accepted bool, previousFailedJ
 	return true, nil
 }
 
+// EnterExclusive enters the barrier for this key and jobID like Enter does, but additionally keeps any other job for this key
+// out of the barrier for as long as this job is in progress, i.e. until it leaves the barrier or its state change gets synced.
+// This way at most one job per key is in flight at any time, even across retries.
+func (b *Barrier) EnterExclusive(key string, jobID int64) (accepted bool, previousFailedJobID *int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	barrier, ok := b.barriers[key]
+	if !ok {
+		b.barriers[key] = &barrierInfo{concurrencyLimiter: map[int64]struct{}{jobID: {}}}
+		return true, nil
+	}
+
+	// if there is a failed job in the barrier, only this job can enter the barrier
+	if barrier.failedJobID != nil {
+		failedJob := *barrier.failedJobID
+		if failedJob > jobID {
+			panic(fmt.Errorf("detected illegal job sequence during barrier exclusive enter %+v: key %q, previousFailedJob:%d > jobID:%d", b.metadata, key, failedJob, jobID))
+		}
+		return jobID == failedJob, &failedJob
+	}
+
+	barrier.mu.Lock()
+	defer barrier.mu.Unlock()
+	if _, ok := barrier.concurrencyLimiter[jobID]; ok {
+		return true, nil
+	}
+	if len(barrier.concurrencyLimiter) > 0 {
+		return false, nil // another job is in progress, reject it
+	}
+	if barrier.concurrencyLimiter == nil {
+		barrier.concurrencyLimiter = make(map[int64]struct{})
+	}
+	barrier.concurrencyLimiter[jobID] = struct{}{}
+	return true, nil
+}
+
 // Leave the barrier for this key and jobID. Leave acts as an undo operation for Enter, i.e.
 // when a previously-entered job leaves the barrier it is as if this key and jobID didn't enter the barrier.
 // Calling Leave is idempotent.
@@ -261,6 +261,44 @@ func TestBarrier_Leave(t *testing.T) {
 	require.Truef(t, enter, "job 3 for %s should now be accepted since job 2 left", orderKey)
 }
 
+func TestBarrier_EnterExclusive(t *testing.T) {
+	orderKey := "user1"
+	barrier := NewBarrier(WithConcurrencyLimit(10))
+
+	enter, _ := barrier.EnterExclusive(orderKey, 1)
+	require.Truef(t, enter, "job 1 for %s should be accepted since no barrier exists", orderKey)
+	enter, _ = barrier.EnterExclusive(orderKey, 1)
+	require.Truef(t, enter, "job 1 for %s should be accepted again since it is the one in progress", orderKey)
+	enter, _ = barrier.EnterExclusive(orderKey, 2)
+	require.Falsef(t, enter, "job 2 for %s should not be accepted since job 1 is in progress", orderKey)
+
+	require.NoError(t, barrier.StateChanged(orderKey, 1, jobsdb.Succeeded.State))
+	enter, _ = barrier.EnterExclusive(orderKey, 2)
+	require.Falsef(t, enter, "job 2 for %s should not be accepted until job 1's success is synced", orderKey)
+	require.EqualValues(t, 1, barrier.Sync())
+	require.Equal(t, 0, barrier.Size())
+
+	enter, _ = barrier.EnterExclusive(orderKey, 2)
+	require.Truef(t, enter, "job 2 for %s should be accepted after job 1's success is synced", orderKey)
+	require.NoError(t, barrier.StateChanged(orderKey, 2, jobsdb.Failed.State))
+	enter, previousFailedJobID := barrier.EnterExclusive(orderKey, 3)
+	require.Falsef(t, enter, "job 3 for %s should not be accepted since job 2 has failed", orderKey)
+	require.EqualValues(t, 2, *previousFailedJobID)
+	enter, _ = barrier.EnterExclusive(orderKey, 2)
+	require.Truef(t, enter, "job 2 for %s should be accepted to be retried", orderKey)
+
+	require.NoError(t, barrier.StateChanged(orderKey, 2, jobsdb.Aborted.State))
+	require.EqualValues(t, 1, barrier.Sync())
+	enter, _ = barrier.EnterExclusive(orderKey, 3)
+	require.Truef(t, enter, "job 3 for %s should be accepted since job 2 was aborted", orderKey)
+	enter, _ = barrier.EnterExclusive(orderKey, 4)
+	require.Falsef(t, enter, "job 4 for %s should not be accepted since job 3 is in progress, regardless of the concurrency limit", orderKey)
+
+	barrier.Leave(orderKey, 3)
+	enter, _ = barrier.EnterExclusive(orderKey, 4)
+	require.Truef(t, enter, "job 4 for %s should be accepted since job 3 left", orderKey)
+}
+
 func firstBool(v bool, _ ...interface{}) bool {
 	return v
 }
@@ -0,0 +1,102 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	routerutils "github.com/rudderlabs/rudder-server/router/utils"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// Maintenance windows are the planned downtimes of destinations, during which the router doesn't deliver their jobs.
+// They are scheduled in the config of destinations, e.g.
+//
+//	"maintenanceWindows": [{"start": "2022-10-10T22:00:00Z", "end": "2022-10-11T02:00:00Z"}]
+//
+// Jobs picked up during a window are marked as paused, without consuming any attempt, with the end of the window as
+// their retry time. Paused jobs are excluded from the queries of the router until their retry time is reached, when
+// they are picked up and delivered again. Their retry time window starts over after the pause.
+
+// maintenanceWindow is a period during which jobs aren't delivered to a destination
+type maintenanceWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (w maintenanceWindow) contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// getMaintenanceWindows returns the maintenance windows of a destination that haven't ended yet
+func getMaintenanceWindows(destination *backendconfig.DestinationT, now time.Time) ([]maintenanceWindow, error) {
+	value, ok := destination.Config["maintenanceWindows"]
+	if !ok {
+		return nil, nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var windows []maintenanceWindow
+	if err := json.Unmarshal(b, &windows); err != nil {
+		return nil, fmt.Errorf("invalid maintenance windows %s: %w", b, err)
+	}
+	var scheduled []maintenanceWindow
+	for _, window := range windows {
+		if !window.End.After(window.Start) {
+			return nil, fmt.Errorf("maintenance window ends at %s before it starts at %s", window.End, window.Start)
+		}
+		if window.End.After(now) {
+			scheduled = append(scheduled, window)
+		}
+	}
+	return scheduled, nil
+}
+
+// inMaintenance returns the end of the maintenance window of the destination at time now, if any
+func (rt *HandleT) inMaintenance(destinationID string, now time.Time) (time.Time, bool) {
+	rt.configSubscriberLock.RLock()
+	defer rt.configSubscriberLock.RUnlock()
+	for _, window := range rt.maintenanceWindows[destinationID] {
+		if window.contains(now) {
+			return window.End, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// pauseInMaintenance reports whether the job is to be skipped because its destination is in a maintenance window,
+// along with the paused status to mark the job with, nil if the job is already paused
+func (rt *HandleT) pauseInMaintenance(job *jobsdb.JobT, now time.Time) (paused bool, status *jobsdb.JobStatusT) {
+	destinationID := gjson.GetBytes(job.Parameters, "destination_id").String()
+	until, ok := rt.inMaintenance(destinationID, now)
+	if !ok {
+		return false, nil
+	}
+	if job.LastJobStatus.JobState == jobsdb.Paused.State {
+		return true, nil
+	}
+	stats.Default.NewTaggedStat("router_paused_jobs", stats.CountType, stats.Tags{
+		"destType": rt.destName,
+		"destID":   destinationID,
+	}).Increment()
+	reason, _ := json.Marshal(map[string]string{
+		"reason": fmt.Sprintf("destination is in a maintenance window until %s", until.Format(time.RFC3339)),
+	})
+	return true, &jobsdb.JobStatusT{
+		JobID:         job.JobID,
+		AttemptNum:    job.LastJobStatus.AttemptNum,
+		JobState:      jobsdb.Paused.State,
+		ExecTime:      now,
+		RetryTime:     until,
+		ErrorCode:     "",
+		ErrorResponse: reason,
+		Parameters:    routerutils.EmptyPayload,
+		WorkspaceId:   job.WorkspaceId,
+	}
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+)
+
+func TestGetMaintenanceWindows(t *testing.T) {
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	destination := func(windows interface{}) *backendconfig.DestinationT {
+		return &backendconfig.DestinationT{ID: "destination", Config: map[string]interface{}{"maintenanceWindows": windows}}
+	}
+
+	windows, err := getMaintenanceWindows(&backendconfig.DestinationT{Config: map[string]interface{}{}}, now)
+	require.NoError(t, err)
+	require.Empty(t, windows)
+
+	windows, err = getMaintenanceWindows(destination([]interface{}{
+		map[string]interface{}{"start": "2022-10-09T22:00:00Z", "end": "2022-10-10T02:00:00Z"},
+		map[string]interface{}{"start": "2022-10-10T22:00:00Z", "end": "2022-10-11T02:00:00Z"},
+	}), now)
+	require.NoError(t, err)
+	require.Equal(t, []maintenanceWindow{{
+		Start: time.Date(2022, 10, 10, 22, 0, 0, 0, time.UTC),
+		End:   time.Date(2022, 10, 11, 2, 0, 0, 0, time.UTC),
+	}}, windows, "windows which ended are dropped")
+
+	_, err = getMaintenanceWindows(destination([]interface{}{
+		map[string]interface{}{"start": "2022-10-11T02:00:00Z", "end": "2022-10-10T22:00:00Z"},
+	}), now)
+	require.Error(t, err)
+	_, err = getMaintenanceWindows(destination("tonight"), now)
+	require.Error(t, err)
+}
+
+func TestPauseInMaintenance(t *testing.T) {
+	prevStats := stats.Default
+	defer func() { stats.Default = prevStats }()
+	store := memstats.New()
+	stats.Default = store
+
+	start := time.Date(2022, 10, 10, 22, 0, 0, 0, time.UTC)
+	end := start.Add(4 * time.Hour)
+	rt := &HandleT{
+		destName:           "WEBHOOK",
+		maintenanceWindows: map[string][]maintenanceWindow{"destination": {{Start: start, End: end}}},
+	}
+	job := &jobsdb.JobT{
+		JobID:         1,
+		WorkspaceId:   "workspace",
+		Parameters:    []byte(`{"destination_id":"destination"}`),
+		LastJobStatus: jobsdb.JobStatusT{JobState: jobsdb.Failed.State, AttemptNum: 2},
+	}
+
+	paused, status := rt.pauseInMaintenance(job, start.Add(-time.Second))
+	require.False(t, paused)
+	require.Nil(t, status)
+
+	paused, status = rt.pauseInMaintenance(job, start)
+	require.True(t, paused)
+	require.Equal(t, jobsdb.Paused.State, status.JobState)
+	require.Equal(t, 2, status.AttemptNum, "pauses don't consume attempts")
+	require.Equal(t, end, status.RetryTime)
+	require.JSONEq(t, `{"reason":"destination is in a maintenance window until 2022-10-11T02:00:00Z"}`, string(status.ErrorResponse))
+	require.EqualValues(t, 1, store.Get("router_paused_jobs", stats.Tags{"destType": "WEBHOOK", "destID": "destination"}).LastValue())
+
+	job.LastJobStatus = *status
+	paused, status = rt.pauseInMaintenance(job, start.Add(time.Hour))
+	require.True(t, paused)
+	require.Nil(t, status, "paused jobs aren't marked again")
+
+	paused, _ = rt.pauseInMaintenance(job, end)
+	require.False(t, paused, "jobs are resumed once the window ends")
+
+	job.Parameters = []byte(`{"destination_id":"another-destination"}`)
+	paused, _ = rt.pauseInMaintenance(job, start)
+	require.False(t, paused)
+}
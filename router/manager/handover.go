@@ -0,0 +1,134 @@
+package manager
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/app/cluster"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/metric"
+	"github.com/rudderlabs/rudder-server/utils/types/workspace"
+)
+
+var _ cluster.WorkspaceDrainer = &LifecycleManager{}
+
+// drainPollInterval is how often routers are checked for jobs of the drained workspaces still being delivered
+var drainPollInterval = 100 * time.Millisecond
+
+// drainable is a router or batch router drained of the workspaces detached from the server
+type drainable interface {
+	DestinationType() string
+	PauseWorkspaces(workspaceIDs []string)
+	ResumeWorkspaces(workspaceIDs []string)
+	InFlightJobs(workspaceIDs []string) map[string]int
+}
+
+// drainableRouter is a drainable, along with the component and pending events table prefix of its jobs
+type drainableRouter struct {
+	drainable
+	component   string
+	tablePrefix string
+}
+
+// Drain pauses the workspaces in the routers and batch routers, including the ones started later on, and waits for
+// their jobs being delivered to land until ctx is done. The jobs left are handed over, the ones still being delivered
+// as executing and the others as waiting, by component and destination type.
+func (r *LifecycleManager) Drain(ctx context.Context, workspaceIDs []string) ([]workspace.PendingMarker, error) {
+	r.routersMu.Lock()
+	if r.pausedWorkspaces == nil {
+		r.pausedWorkspaces = make(map[string]struct{})
+	}
+	for _, workspaceID := range workspaceIDs {
+		r.pausedWorkspaces[workspaceID] = struct{}{}
+	}
+	routers := r.drainableRouters()
+	for _, rt := range routers {
+		rt.PauseWorkspaces(workspaceIDs)
+	}
+	r.routersMu.Unlock()
+
+	inFlight := inFlightJobs(routers, workspaceIDs)
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for len(inFlight) > 0 && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+			inFlight = inFlightJobs(routers, workspaceIDs)
+		}
+	}
+
+	type destination struct{ component, tablePrefix, destType string }
+	destinations := make(map[destination]struct{})
+	for _, rt := range routers {
+		destinations[destination{rt.component, rt.tablePrefix, rt.DestinationType()}] = struct{}{}
+	}
+	var pending []workspace.PendingMarker
+	for dest := range destinations {
+		for _, workspaceID := range workspaceIDs {
+			executing := inFlight[workspaceID][dest.component+":"+dest.destType]
+			if executing > 0 {
+				pending = append(pending, workspace.PendingMarker{
+					WorkspaceID: workspaceID, Component: dest.component, Ref: dest.destType, State: jobsdb.Executing.State, Count: executing,
+				})
+			}
+			if waiting := metric.PendingEvents(dest.tablePrefix, workspaceID, dest.destType).IntValue() - executing; waiting > 0 {
+				pending = append(pending, workspace.PendingMarker{
+					WorkspaceID: workspaceID, Component: dest.component, Ref: dest.destType, State: jobsdb.Waiting.State, Count: waiting,
+				})
+			}
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].WorkspaceID != pending[j].WorkspaceID {
+			return pending[i].WorkspaceID < pending[j].WorkspaceID
+		}
+		if pending[i].Component != pending[j].Component {
+			return pending[i].Component < pending[j].Component
+		}
+		if pending[i].Ref != pending[j].Ref {
+			return pending[i].Ref < pending[j].Ref
+		}
+		return pending[i].State < pending[j].State
+	})
+	return pending, nil
+}
+
+// Resume resumes the workspaces in the routers and batch routers, if they were drained
+func (r *LifecycleManager) Resume(workspaceIDs []string) {
+	r.routersMu.Lock()
+	defer r.routersMu.Unlock()
+	for _, workspaceID := range workspaceIDs {
+		delete(r.pausedWorkspaces, workspaceID)
+	}
+	for _, rt := range r.drainableRouters() {
+		rt.ResumeWorkspaces(workspaceIDs)
+	}
+}
+
+// drainableRouters returns the routers and batch routers started. Must be called with routersMu held.
+func (r *LifecycleManager) drainableRouters() []drainableRouter {
+	routers := make([]drainableRouter, 0, len(r.routers)+len(r.batchRouters))
+	for _, rt := range r.routers {
+		routers = append(routers, drainableRouter{drainable: rt, component: "router", tablePrefix: "rt"})
+	}
+	for _, brt := range r.batchRouters {
+		routers = append(routers, drainableRouter{drainable: brt, component: "batch_router", tablePrefix: "batch_rt"})
+	}
+	return routers
+}
+
+// inFlightJobs returns the jobs of the workspaces being delivered by the routers, by workspace and component:destType
+func inFlightJobs(routers []drainableRouter, workspaceIDs []string) map[string]map[string]int {
+	inFlight := make(map[string]map[string]int)
+	for _, rt := range routers {
+		for workspaceID, count := range rt.InFlightJobs(workspaceIDs) {
+			if inFlight[workspaceID] == nil {
+				inFlight[workspaceID] = make(map[string]int)
+			}
+			inFlight[workspaceID][rt.component+":"+rt.DestinationType()] += count
+		}
+	}
+	return inFlight
+}
@@ -17,7 +17,7 @@ import (
 )
 
 var (
-	objectStorageDestinations = []string{"S3", "GCS", "AZURE_BLOB", "MINIO", "DIGITAL_OCEAN_SPACES"}
+	objectStorageDestinations = []string{"S3", "GCS", "AZURE_BLOB", "MINIO", "DIGITAL_OCEAN_SPACES", "SFTP"}
 	asyncDestinations         = []string{"MARKETO_BULK_UPLOAD"}
 	warehouseDestinations     = []string{
 		"RS", "BQ", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "MSSQL",
@@ -34,6 +34,11 @@ type LifecycleManager struct {
 	waitGroup            *errgroup.Group
 	isolateRouterMap     map[string]bool
 	isolateRouterMapLock sync.RWMutex
+
+	routersMu        sync.Mutex
+	routers          map[string]*router.HandleT      // routerIdentifier -> router
+	batchRouters     map[string]*batchrouter.HandleT // destType -> batch router
+	pausedWorkspaces map[string]struct{}             // workspaces drained, paused in the routers started later on too
 }
 
 // Start starts a Router, this is not a blocking call.
@@ -125,6 +130,7 @@ loop:
 							if !ok {
 								pkgLogger.Infof("Starting a new Batch Destination Router: %s", destination.DestinationDefinition.Name)
 								brt := batchrouterFactory.New(destination.DestinationDefinition.Name)
+								r.addBatchRouter(destination.DestinationDefinition.Name, brt)
 								brt.Start()
 								cleanup = append(cleanup, brt.Shutdown)
 								dstToBatchRouter[destination.DestinationDefinition.Name] = brt
@@ -135,6 +141,7 @@ loop:
 							if !ok {
 								pkgLogger.Infof("Starting a new Destination: %s", destination.DestinationDefinition.Name)
 								rt := routerFactory.New(destination, routerIdentifier)
+								r.addRouter(routerIdentifier, rt)
 								rt.Start()
 								cleanup = append(cleanup, rt.Shutdown)
 								dstToRouter[routerIdentifier] = rt
@@ -146,6 +153,11 @@ loop:
 		}
 	}
 
+	r.routersMu.Lock()
+	r.routers = nil
+	r.batchRouters = nil
+	r.routersMu.Unlock()
+
 	g, _ := errgroup.WithContext(context.Background())
 	for _, f := range cleanup {
 		f := f
@@ -156,3 +168,34 @@ loop:
 	}
 	_ = g.Wait()
 }
+
+// addRouter keeps track of the router for it to be drained, pausing the workspaces drained already
+func (r *LifecycleManager) addRouter(routerIdentifier string, rt *router.HandleT) {
+	r.routersMu.Lock()
+	defer r.routersMu.Unlock()
+	if r.routers == nil {
+		r.routers = make(map[string]*router.HandleT)
+	}
+	rt.PauseWorkspaces(r.pausedWorkspaceIDs())
+	r.routers[routerIdentifier] = rt
+}
+
+// addBatchRouter keeps track of the batch router for it to be drained, pausing the workspaces drained already
+func (r *LifecycleManager) addBatchRouter(destType string, brt *batchrouter.HandleT) {
+	r.routersMu.Lock()
+	defer r.routersMu.Unlock()
+	if r.batchRouters == nil {
+		r.batchRouters = make(map[string]*batchrouter.HandleT)
+	}
+	brt.PauseWorkspaces(r.pausedWorkspaceIDs())
+	r.batchRouters[destType] = brt
+}
+
+// pausedWorkspaceIDs returns the workspaces drained. Must be called with routersMu held.
+func (r *LifecycleManager) pausedWorkspaceIDs() []string {
+	pausedWorkspaces := make([]string, 0, len(r.pausedWorkspaces))
+	for workspaceID := range r.pausedWorkspaces {
+		pausedWorkspaces = append(pausedWorkspaces, workspaceID)
+	}
+	return pausedWorkspaces
+}
@@ -4,7 +4,9 @@ package router
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -92,7 +94,10 @@ func (network *NetHandleT) SendPost(ctx context.Context, structData integrations
 			}
 		}
 
-		var payload io.Reader
+		var (
+			payload  io.Reader
+			bodyHash string
+		)
 		// support for JSON and FORM body type
 		if len(bodyValue) > 0 {
 			switch bodyFormat {
@@ -101,7 +106,7 @@ func (network *NetHandleT) SendPost(ctx context.Context, structData integrations
 				if err != nil {
 					panic(err)
 				}
-				payload = strings.NewReader(string(jsonValue))
+				payload, bodyHash = bodyReader(string(jsonValue))
 			case "JSON_ARRAY":
 				// support for JSON ARRAY
 				jsonListStr, ok := bodyValue["batch"].(string)
@@ -111,7 +116,7 @@ func (network *NetHandleT) SendPost(ctx context.Context, structData integrations
 						ResponseBody: []byte("400 Unable to parse json list. Unexpected transformer response"),
 					}
 				}
-				payload = strings.NewReader(jsonListStr)
+				payload, bodyHash = bodyReader(jsonListStr)
 			case "XML":
 				strValue, ok := bodyValue["payload"].(string)
 				if !ok {
@@ -120,13 +125,13 @@ func (network *NetHandleT) SendPost(ctx context.Context, structData integrations
 						ResponseBody: []byte("400 Unable to construct xml payload. Unexpected transformer response"),
 					}
 				}
-				payload = strings.NewReader(strValue)
+				payload, bodyHash = bodyReader(strValue)
 			case "FORM":
 				formValues := url.Values{}
 				for key, val := range bodyValue {
 					formValues.Set(key, fmt.Sprint(val)) // transformer ensures top level string values, still val.(string) would be restrictive
 				}
-				payload = strings.NewReader(formValues.Encode())
+				payload, bodyHash = bodyReader(formValues.Encode())
 			default:
 				panic(fmt.Errorf("bodyFormat: %s is not supported", bodyFormat))
 			}
@@ -164,8 +169,9 @@ func (network *NetHandleT) SendPost(ctx context.Context, structData integrations
 		resp, err := client.Do(req)
 		if err != nil {
 			return &utils.SendPostResponse{
-				StatusCode:   http.StatusGatewayTimeout,
-				ResponseBody: []byte(fmt.Sprintf(`504 Unable to make "%s" request for URL : "%s". Error: %s`, requestMethod, postInfo.URL, err.Error())),
+				StatusCode:      http.StatusGatewayTimeout,
+				ResponseBody:    []byte(fmt.Sprintf(`504 Unable to make "%s" request for URL : "%s". Error: %s`, requestMethod, postInfo.URL, err.Error())),
+				RequestBodyHash: bodyHash,
 			}
 		}
 
@@ -174,8 +180,9 @@ func (network *NetHandleT) SendPost(ctx context.Context, structData integrations
 		respBody, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return &utils.SendPostResponse{
-				StatusCode:   resp.StatusCode,
-				ResponseBody: []byte(fmt.Sprintf(`Failed to read response body for request for URL : "%s". Error: %s`, postInfo.URL, err.Error())),
+				StatusCode:      resp.StatusCode,
+				ResponseBody:    []byte(fmt.Sprintf(`Failed to read response body for request for URL : "%s". Error: %s`, postInfo.URL, err.Error())),
+				RequestBodyHash: bodyHash,
 			}
 		}
 		network.logger.Debug(postInfo.URL, " : ", req.Proto, " : ", resp.Proto, resp.ProtoMajor, resp.ProtoMinor, resp.ProtoAtLeast)
@@ -197,10 +204,17 @@ func (network *NetHandleT) SendPost(ctx context.Context, structData integrations
 			respBody = []byte("redacted due to unsupported content-type")
 		}
 
+		var retryAfter time.Duration
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+			retryAfter = utils.ParseRetryAfter(resp.Header, time.Now())
+		}
+
 		return &utils.SendPostResponse{
 			StatusCode:          resp.StatusCode,
 			ResponseBody:        respBody,
 			ResponseContentType: contentTypeHeader,
+			RetryAfter:          retryAfter,
+			RequestBodyHash:     bodyHash,
 		}
 	}
 
@@ -213,6 +227,13 @@ func (network *NetHandleT) SendPost(ctx context.Context, structData integrations
 	}
 }
 
+// bodyReader returns a reader of the request body along with the hex encoded SHA-256 of it, for failed deliveries to be
+// correlated with the requests received by the destination
+func bodyReader(body string) (io.Reader, string) {
+	sum := sha256.Sum256([]byte(body))
+	return strings.NewReader(body), hex.EncodeToString(sum[:])
+}
+
 // Setup initializes the module
 func (network *NetHandleT) Setup(destID string, netClientTimeout time.Duration) {
 	network.logger.Info("Network Handler Startup")
@@ -3,9 +3,12 @@ package router
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
@@ -117,6 +120,61 @@ var _ = Describe("Network", func() {
 			fmt.Println(string(resp.ResponseBody))
 			Expect(string(resp.ResponseBody)).To(Equal("504 Unable to make \"\" request for URL : \"https://www.google-analytics.com/collect\". Error: Get \"https://www.google-analytics.com/collect\": context canceled"))
 		})
+
+		It("should return the retry-after of throttled responses", func() {
+			network := &NetHandleT{}
+			network.logger = logger.NewLogger().Child("network")
+			network.httpClient = c.mockHTTPClient
+
+			structData := integrations.PostParametersT{
+				Type:          "REST",
+				URL:           "https://www.google-analytics.com/collect",
+				RequestMethod: "POST",
+			}
+			c.mockHTTPClient.EXPECT().Do(gomock.Any()).Times(2).DoAndReturn(func(*http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusTooManyRequests,
+					Header:     http.Header{"Retry-After": []string{"120"}},
+					Body:       io.NopCloser(bytes.NewReader([]byte("slow down"))),
+				}, nil
+			})
+
+			resp := network.SendPost(context.Background(), structData)
+			Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(resp.RetryAfter).To(Equal(120 * time.Second))
+
+			resp = network.SendPost(context.Background(), structData)
+			Expect(resp.RetryAfter).To(Equal(120 * time.Second))
+		})
+
+		It("should return the hash of the request body sent", func() {
+			network := &NetHandleT{}
+			network.logger = logger.NewLogger().Child("network")
+			network.httpClient = c.mockHTTPClient
+
+			structData := integrations.PostParametersT{
+				Type:          "REST",
+				URL:           "https://www.google-analytics.com/collect",
+				RequestMethod: "POST",
+				Body: map[string]interface{}{
+					"JSON": map[string]interface{}{"t": "a"},
+				},
+			}
+			var sent []byte
+			c.mockHTTPClient.EXPECT().Do(gomock.Any()).Times(1).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				var err error
+				sent, err = io.ReadAll(req.Body)
+				Expect(err).NotTo(HaveOccurred())
+				return &http.Response{
+					StatusCode: http.StatusBadRequest,
+					Body:       io.NopCloser(bytes.NewReader([]byte("bad request"))),
+				}, nil
+			})
+
+			resp := network.SendPost(context.Background(), structData)
+			sum := sha256.Sum256(sent)
+			Expect(resp.RequestBodyHash).To(Equal(hex.EncodeToString(sum[:])))
+		})
 	})
 
 	Context("Verify response bodies are propagated/filtered based on the response's content-type", func() {
@@ -0,0 +1,188 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/router/types"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// Destinations serving regions from separate endpoints, e.g. EU and US accounts, list their endpoints in their config:
+//
+//	"regionalEndpoints": {"EU": "https://api.eu.example.com", "US": "https://api.example.com"}, "defaultRegion": "US"
+//
+// Every job is delivered to the endpoint of its region: the data residency of its event (context.dataResidency), or
+// else of its workspace, or else the default region of the destination. The scheme and host of the endpoint replace
+// the ones of the URL the transformer built, keeping its path and query. Jobs are transformed separately per region, for
+// batches not to mix jobs of different regions. Jobs whose region has no endpoint are delivered to the URL the
+// transformer built.
+//
+// The health of every region of a destination is tracked on its own: after Router.regionalRouting.unhealthyAfterFailures
+// consecutive 5xx responses a region is deemed unhealthy and its jobs aren't picked up for
+// Router.regionalRouting.cooldown, while the other regions of the destination keep being delivered to.
+
+// regionalEndpoints are the endpoints of a destination per region
+type regionalEndpoints struct {
+	endpoints     map[string]*url.URL // upper cased region -> endpoint
+	defaultRegion string
+}
+
+// regionHealth tracks the consecutive failures of a region of a destination
+type regionHealth struct {
+	failures       int
+	unhealthyUntil time.Time
+}
+
+// getRegionalEndpoints returns the regional endpoints of a destination, nil if it has none
+func getRegionalEndpoints(destination *backendconfig.DestinationT) (*regionalEndpoints, error) {
+	value, ok := destination.Config["regionalEndpoints"].(map[string]interface{})
+	if !ok || len(value) == 0 {
+		return nil, nil
+	}
+	regional := &regionalEndpoints{endpoints: make(map[string]*url.URL, len(value))}
+	for region, endpoint := range value {
+		rawURL, _ := endpoint.(string)
+		parsed, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint %q of region %s", rawURL, region)
+		}
+		regional.endpoints[strings.ToUpper(region)] = parsed
+	}
+	if defaultRegion, _ := destination.Config["defaultRegion"].(string); defaultRegion != "" {
+		regional.defaultRegion = strings.ToUpper(defaultRegion)
+		if _, ok := regional.endpoints[regional.defaultRegion]; !ok {
+			return nil, fmt.Errorf("default region %s has no endpoint", defaultRegion)
+		}
+	}
+	return regional, nil
+}
+
+// deliveryRegion returns the region the destination job is delivered to, along with its endpoint, or an empty region
+// if the job isn't routed to a regional endpoint. All the jobs of a batch share the same region, see transformByRegion.
+func (rt *HandleT) deliveryRegion(destinationJob *types.DestinationJobT) (string, *url.URL) {
+	if len(destinationJob.JobMetadataArray) == 0 {
+		return "", nil
+	}
+	return rt.metadataRegion(&destinationJob.JobMetadataArray[0])
+}
+
+// metadataRegion returns the region of the job of the metadata, along with its endpoint
+func (rt *HandleT) metadataRegion(metadata *types.JobMetadataT) (string, *url.URL) {
+	var payload []byte
+	if metadata.JobT != nil {
+		payload = metadata.JobT.EventPayload
+	}
+	return rt.jobRegion(metadata.DestinationID, metadata.WorkspaceID, payload)
+}
+
+// jobRegion returns the region a job of the workspace with the payload is delivered to by the destination, along with
+// its endpoint, or an empty region if the destination has no regional endpoints
+func (rt *HandleT) jobRegion(destinationID, workspaceID string, payload []byte) (string, *url.URL) {
+	rt.configSubscriberLock.RLock()
+	defer rt.configSubscriberLock.RUnlock()
+	regional, ok := rt.regionalEndpoints[destinationID]
+	if !ok {
+		return "", nil
+	}
+	var candidates []string
+	if payload != nil {
+		candidates = append(candidates, gjson.GetBytes(payload, "context.dataResidency").String())
+	}
+	candidates = append(candidates, rt.workspaceRegions[workspaceID], regional.defaultRegion)
+	for _, region := range candidates {
+		region = strings.ToUpper(strings.TrimSpace(region))
+		if endpoint, ok := regional.endpoints[region]; ok {
+			return region, endpoint
+		}
+	}
+	return "", nil
+}
+
+// transformByRegion transforms the router jobs of every region separately, keeping the order of the jobs within
+// regions
+func (rt *HandleT) transformByRegion(routerJobs []types.RouterJobT, transform func([]types.RouterJobT) []types.DestinationJobT) []types.DestinationJobT {
+	var regions []string
+	jobsByRegion := make(map[string][]types.RouterJobT)
+	for i := range routerJobs {
+		region, _ := rt.metadataRegion(&routerJobs[i].JobMetadata)
+		if _, ok := jobsByRegion[region]; !ok {
+			regions = append(regions, region)
+		}
+		jobsByRegion[region] = append(jobsByRegion[region], routerJobs[i])
+	}
+	if len(regions) <= 1 {
+		return transform(routerJobs)
+	}
+	var destinationJobs []types.DestinationJobT
+	for _, region := range regions {
+		destinationJobs = append(destinationJobs, transform(jobsByRegion[region])...)
+	}
+	return destinationJobs
+}
+
+// isRegionCoolingDown reports whether the region the job is delivered to is unhealthy at time now, for the job not to
+// be picked up until the region cools down
+func (rt *HandleT) isRegionCoolingDown(job *jobsdb.JobT, now time.Time) bool {
+	destinationID := gjson.GetBytes(job.Parameters, "destination_id").String()
+	region, _ := rt.jobRegion(destinationID, job.WorkspaceId, job.EventPayload)
+	return region != "" && !rt.isRegionHealthy(destinationID, region, now)
+}
+
+// routeToRegion returns the URL with the scheme and host of the regional endpoint
+func routeToRegion(rawURL string, endpoint *url.URL) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	parsed.Scheme = endpoint.Scheme
+	parsed.Host = endpoint.Host
+	return parsed.String()
+}
+
+// isRegionHealthy reports whether jobs are delivered to the region of the destination at time now
+func (rt *HandleT) isRegionHealthy(destinationID, region string, now time.Time) bool {
+	rt.regionHealthMu.Lock()
+	defer rt.regionHealthMu.Unlock()
+	health, ok := rt.regionHealth[destinationID+"/"+region]
+	return !ok || !now.Before(health.unhealthyUntil)
+}
+
+// recordRegionResponse tracks the health of the region of the destination according to the status code of a delivery
+func (rt *HandleT) recordRegionResponse(destinationID, region string, statusCode int, now time.Time) {
+	if region == "" {
+		return
+	}
+	rt.regionHealthMu.Lock()
+	defer rt.regionHealthMu.Unlock()
+	if rt.regionHealth == nil {
+		rt.regionHealth = make(map[string]*regionHealth)
+	}
+	key := destinationID + "/" + region
+	health, ok := rt.regionHealth[key]
+	if !ok {
+		health = &regionHealth{}
+		rt.regionHealth[key] = health
+	}
+	if statusCode < 500 {
+		health.failures = 0
+		return
+	}
+	health.failures++
+	if regionUnhealthyAfterFailures > 0 && health.failures >= regionUnhealthyAfterFailures {
+		health.failures = 0
+		health.unhealthyUntil = now.Add(regionCooldown)
+		rt.logger.Warnf("Region %s of destination %s is unhealthy, not delivering to it until %s", region, destinationID, health.unhealthyUntil)
+		stats.Default.NewTaggedStat("router_region_unhealthy", stats.CountType, stats.Tags{
+			"destType": rt.destName,
+			"destID":   destinationID,
+			"region":   region,
+		}).Count(1)
+	}
+}
@@ -0,0 +1,170 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/router/types"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestGetRegionalEndpoints(t *testing.T) {
+	destination := func(destConfig map[string]interface{}) *backendconfig.DestinationT {
+		return &backendconfig.DestinationT{ID: "destination", Config: destConfig}
+	}
+
+	regional, err := getRegionalEndpoints(destination(map[string]interface{}{}))
+	require.NoError(t, err)
+	require.Nil(t, regional)
+
+	regional, err = getRegionalEndpoints(destination(map[string]interface{}{
+		"regionalEndpoints": map[string]interface{}{"eu": "https://api.eu.example.com", "US": "https://api.example.com"},
+		"defaultRegion":     "us",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "US", regional.defaultRegion)
+	require.Equal(t, "api.eu.example.com", regional.endpoints["EU"].Host)
+
+	_, err = getRegionalEndpoints(destination(map[string]interface{}{
+		"regionalEndpoints": map[string]interface{}{"EU": "api.eu.example.com"},
+	}))
+	require.Error(t, err, "endpoints need a scheme and host")
+	_, err = getRegionalEndpoints(destination(map[string]interface{}{
+		"regionalEndpoints": map[string]interface{}{"EU": "https://api.eu.example.com"},
+		"defaultRegion":     "US",
+	}))
+	require.Error(t, err, "the default region needs an endpoint")
+}
+
+func TestDeliveryRegion(t *testing.T) {
+	regional, err := getRegionalEndpoints(&backendconfig.DestinationT{Config: map[string]interface{}{
+		"regionalEndpoints": map[string]interface{}{"EU": "https://api.eu.example.com", "US": "https://api.example.com"},
+		"defaultRegion":     "US",
+	}})
+	require.NoError(t, err)
+	rt := &HandleT{
+		regionalEndpoints: map[string]*regionalEndpoints{"destination": regional},
+		workspaceRegions:  map[string]string{"eu-workspace": "eu"},
+	}
+	destinationJob := func(destinationID, workspaceID, payload string) *types.DestinationJobT {
+		return &types.DestinationJobT{JobMetadataArray: []types.JobMetadataT{{
+			DestinationID: destinationID,
+			WorkspaceID:   workspaceID,
+			JobT:          &jobsdb.JobT{EventPayload: []byte(payload)},
+		}}}
+	}
+
+	region, endpoint := rt.deliveryRegion(destinationJob("destination", "workspace", `{"context":{"dataResidency":"EU"}}`))
+	require.Equal(t, "EU", region)
+	require.Equal(t, "https://api.eu.example.com/v1/track?key=value", routeToRegion("http://api.example.com/v1/track?key=value", endpoint))
+
+	region, _ = rt.deliveryRegion(destinationJob("destination", "eu-workspace", `{}`))
+	require.Equal(t, "EU", region, "jobs follow the data residency of their workspace")
+
+	region, _ = rt.deliveryRegion(destinationJob("destination", "workspace", `{"context":{"dataResidency":"APAC"}}`))
+	require.Equal(t, "US", region, "jobs of regions without endpoint go to the default region")
+
+	region, endpoint = rt.deliveryRegion(destinationJob("another-destination", "eu-workspace", `{}`))
+	require.Empty(t, region)
+	require.Nil(t, endpoint)
+}
+
+func TestRegionHealth(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+	loadConfig()
+	config.Set("Router.regionalRouting.unhealthyAfterFailures", 2)
+	config.Set("Router.regionalRouting.cooldown", "10s")
+
+	rt := &HandleT{destName: "WEBHOOK", logger: logger.NOP}
+	now := time.Now()
+	require.True(t, rt.isRegionHealthy("destination", "EU", now))
+
+	rt.recordRegionResponse("destination", "EU", 500, now)
+	rt.recordRegionResponse("destination", "EU", 200, now)
+	rt.recordRegionResponse("destination", "EU", 503, now)
+	require.True(t, rt.isRegionHealthy("destination", "EU", now), "only consecutive failures make a region unhealthy")
+
+	rt.recordRegionResponse("destination", "EU", 503, now)
+	require.False(t, rt.isRegionHealthy("destination", "EU", now))
+	require.True(t, rt.isRegionHealthy("destination", "US", now), "regions are tracked independently")
+	require.True(t, rt.isRegionHealthy("another-destination", "EU", now))
+	require.True(t, rt.isRegionHealthy("destination", "EU", now.Add(10*time.Second)))
+}
+
+func TestTransformByRegion(t *testing.T) {
+	regional, err := getRegionalEndpoints(&backendconfig.DestinationT{Config: map[string]interface{}{
+		"regionalEndpoints": map[string]interface{}{"EU": "https://api.eu.example.com", "US": "https://api.example.com"},
+		"defaultRegion":     "US",
+	}})
+	require.NoError(t, err)
+	rt := &HandleT{
+		regionalEndpoints: map[string]*regionalEndpoints{"destination": regional},
+		workspaceRegions:  map[string]string{"eu-workspace": "eu"},
+	}
+	routerJob := func(jobID int64, workspaceID string) types.RouterJobT {
+		return types.RouterJobT{JobMetadata: types.JobMetadataT{
+			JobID:         jobID,
+			DestinationID: "destination",
+			WorkspaceID:   workspaceID,
+			JobT:          &jobsdb.JobT{EventPayload: []byte(`{}`)},
+		}}
+	}
+	// batches all the jobs it is given together
+	batch := func(routerJobs []types.RouterJobT) []types.DestinationJobT {
+		destinationJob := types.DestinationJobT{}
+		for _, routerJob := range routerJobs {
+			destinationJob.JobMetadataArray = append(destinationJob.JobMetadataArray, routerJob.JobMetadata)
+		}
+		return []types.DestinationJobT{destinationJob}
+	}
+
+	destinationJobs := rt.transformByRegion([]types.RouterJobT{routerJob(1, "eu-workspace"), routerJob(2, "workspace"), routerJob(3, "eu-workspace")}, batch)
+	require.Len(t, destinationJobs, 2, "jobs of different regions aren't batched together")
+	jobIDs := func(destinationJob types.DestinationJobT) (jobIDs []int64) {
+		for _, metadata := range destinationJob.JobMetadataArray {
+			jobIDs = append(jobIDs, metadata.JobID)
+		}
+		return jobIDs
+	}
+	require.Equal(t, []int64{1, 3}, jobIDs(destinationJobs[0]))
+	require.Equal(t, []int64{2}, jobIDs(destinationJobs[1]))
+	region, _ := rt.deliveryRegion(&destinationJobs[0])
+	require.Equal(t, "EU", region)
+
+	destinationJobs = rt.transformByRegion([]types.RouterJobT{routerJob(1, "workspace"), routerJob(2, "workspace")}, batch)
+	require.Len(t, destinationJobs, 1)
+}
+
+func TestRegionCoolingDown(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+	loadConfig()
+	config.Set("Router.regionalRouting.unhealthyAfterFailures", 1)
+	config.Set("Router.regionalRouting.cooldown", "10s")
+
+	regional, err := getRegionalEndpoints(&backendconfig.DestinationT{Config: map[string]interface{}{
+		"regionalEndpoints": map[string]interface{}{"EU": "https://api.eu.example.com", "US": "https://api.example.com"},
+		"defaultRegion":     "US",
+	}})
+	require.NoError(t, err)
+	rt := &HandleT{
+		destName:          "WEBHOOK",
+		logger:            logger.NOP,
+		regionalEndpoints: map[string]*regionalEndpoints{"destination": regional},
+	}
+	job := func(payload string) *jobsdb.JobT {
+		return &jobsdb.JobT{Parameters: []byte(`{"destination_id":"destination"}`), WorkspaceId: "workspace", EventPayload: []byte(payload)}
+	}
+	now := time.Now()
+	rt.recordRegionResponse("destination", "EU", 503, now)
+
+	require.True(t, rt.isRegionCoolingDown(job(`{"context":{"dataResidency":"EU"}}`), now), "jobs of unhealthy regions aren't picked up")
+	require.False(t, rt.isRegionCoolingDown(job(`{}`), now))
+	require.False(t, rt.isRegionCoolingDown(job(`{"context":{"dataResidency":"EU"}}`), now.Add(10*time.Second)))
+}
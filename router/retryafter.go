@@ -0,0 +1,62 @@
+package router
+
+import (
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// Destinations throttling the router tell it when to retry through the Retry-After or rate limit reset headers of
+// their responses. The whole destination backs off until then: its jobs aren't picked up and the retries of its failed
+// jobs aren't attempted before, instead of retrying every job on its own exponential backoff.
+
+// backOffDestination makes the router wait for retryAfter, capped by Router.retryAfter.max, before sending further
+// jobs to the destination
+func (rt *HandleT) backOffDestination(destinationID string, retryAfter time.Duration) {
+	if !honorRetryAfter || retryAfter <= 0 {
+		return
+	}
+	if retryAfter > maxRetryAfter {
+		retryAfter = maxRetryAfter
+	}
+	until := time.Now().Add(retryAfter)
+	rt.destinationBackoffMu.Lock()
+	if rt.destinationBackoffUntil == nil {
+		rt.destinationBackoffUntil = make(map[string]time.Time)
+	}
+	if until.After(rt.destinationBackoffUntil[destinationID]) {
+		rt.destinationBackoffUntil[destinationID] = until
+	}
+	rt.destinationBackoffMu.Unlock()
+	stats.Default.NewTaggedStat("router_destination_retry_after", stats.TimerType, stats.Tags{
+		"destType": rt.destName,
+		"destID":   destinationID,
+	}).SendTiming(retryAfter)
+}
+
+// destinationBackoff returns the time until which the destination backs off, if it does at time now
+func (rt *HandleT) destinationBackoff(destinationID string, now time.Time) (time.Time, bool) {
+	rt.destinationBackoffMu.RLock()
+	defer rt.destinationBackoffMu.RUnlock()
+	until, ok := rt.destinationBackoffUntil[destinationID]
+	return until, ok && now.Before(until)
+}
+
+// isBackingOff reports whether the destination of the job backs off at time now
+func (rt *HandleT) isBackingOff(job *jobsdb.JobT, now time.Time) bool {
+	_, ok := rt.destinationBackoff(gjson.GetBytes(job.Parameters, "destination_id").String(), now)
+	return ok
+}
+
+// nextRetryTime returns the time of the next attempt of a failed job, not before the backoff of its destination ends
+func (rt *HandleT) nextRetryTime(destinationID string, attempt int) time.Time {
+	now := time.Now()
+	next := now.Add(durationBeforeNextAttempt(attempt))
+	if until, ok := rt.destinationBackoff(destinationID, now); ok && until.After(next) {
+		return until
+	}
+	return next
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+)
+
+func TestDestinationBackoff(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+	loadConfig()
+	config.Set("Router.retryAfter.max", "1m")
+	config.Set("Router.minRetryBackoff", "1s")
+	config.Set("Router.maxRetryBackoff", "1s")
+
+	rt := &HandleT{destName: "WEBHOOK"}
+	job := &jobsdb.JobT{Parameters: []byte(`{"destination_id":"destination"}`)}
+	require.False(t, rt.isBackingOff(job, time.Now()))
+	require.WithinDuration(t, time.Now().Add(time.Second), rt.nextRetryTime("destination", 1), 500*time.Millisecond)
+
+	rt.backOffDestination("destination", 30*time.Second)
+	require.True(t, rt.isBackingOff(job, time.Now()))
+	require.False(t, rt.isBackingOff(job, time.Now().Add(31*time.Second)))
+	require.WithinDuration(t, time.Now().Add(30*time.Second), rt.nextRetryTime("destination", 1), time.Second,
+		"failed jobs aren't retried before the destination's backoff ends")
+	require.WithinDuration(t, time.Now().Add(time.Second), rt.nextRetryTime("another-destination", 1), 500*time.Millisecond)
+
+	rt.backOffDestination("destination", 10*time.Second)
+	until, _ := rt.destinationBackoff("destination", time.Now())
+	require.WithinDuration(t, time.Now().Add(30*time.Second), until, time.Second, "shorter backoffs don't shorten the current one")
+
+	rt.backOffDestination("destination", time.Hour)
+	until, _ = rt.destinationBackoff("destination", time.Now())
+	require.WithinDuration(t, time.Now().Add(time.Minute), until, time.Second, "backoffs are capped")
+
+	config.Set("Router.retryAfter.enabled", false)
+	rt.backOffDestination("another-destination", time.Minute)
+	_, ok := rt.destinationBackoff("another-destination", time.Now())
+	require.False(t, ok)
+}
@@ -32,6 +32,7 @@ import (
 	routerutils "github.com/rudderlabs/rudder-server/router/utils"
 	"github.com/rudderlabs/rudder-server/rruntime"
 	destinationdebugger "github.com/rudderlabs/rudder-server/services/debugger/destination"
+	"github.com/rudderlabs/rudder-server/services/deliveryreceipts"
 	"github.com/rudderlabs/rudder-server/services/diagnostics"
 	"github.com/rudderlabs/rudder-server/services/metric"
 	"github.com/rudderlabs/rudder-server/services/oauth"
@@ -85,6 +86,7 @@ type HandleT struct {
 	destName                                string
 	destinationId                           string
 	workers                                 []*workerT
+	workerPool                              *rruntime.Pool
 	telemetry                               *DiagnosticT
 	customDestinationManager                customDestinationManager.DestinationManager
 	throttlingCosts                         atomic.Pointer[types.EventTypeThrottlingCost]
@@ -98,6 +100,20 @@ type HandleT struct {
 	transformer                             transformer.Transformer
 	configSubscriberLock                    sync.RWMutex
 	destinationsMap                         map[string]*routerutils.BatchDestinationT // destinationID -> destination
+	maintenanceWindows                      map[string][]maintenanceWindow            // destinationID -> windows not ended yet
+	regionalEndpoints                       map[string]*regionalEndpoints             // destinationID -> endpoints per region
+	workspaceRegions                        map[string]string                         // workspaceID -> data residency
+	regionHealthMu                          sync.Mutex
+	regionHealth                            map[string]*regionHealth // destinationID/region -> health
+	destinationBackoffMu                    sync.RWMutex
+	destinationBackoffUntil                 map[string]time.Time // destinationID -> end of the backoff asked by the destination
+	transformErrorSamplesPerMinute          int
+	transformErrorSamplesMu                 sync.Mutex
+	transformErrorSamples                   map[string]*transformErrorSampleWindow // destinationID -> samples of the current minute
+	handoverMu                              sync.RWMutex
+	pausedWorkspaces                        map[string]struct{}
+	pausedBySettings                        map[string]struct{}
+	inFlightJobs                            map[string]int // workspaceID -> jobs picked up whose statuses aren't committed yet
 	logger                                  logger.Logger
 	batchInputCountStat                     stats.Measurement
 	batchOutputCountStat                    stats.Measurement
@@ -119,6 +135,7 @@ type HandleT struct {
 	Reporting                               reporter
 	savePayloadOnError                      bool
 	oauth                                   oauth.Authorizer
+	deliveryReceipts                        *deliveryreceipts.Store
 	transformerProxy                        bool
 	skipRtAbortAlertForDelivery             bool // represents if transformation(router or batch) should be alerted via router-aborted-count alert def
 	skipRtAbortAlertForTransformation       bool // represents if event delivery(via transformerProxy) should be alerted via router-aborted-count alert def
@@ -200,6 +217,12 @@ var (
 	fixedLoopSleep                                               time.Duration
 	toAbortDestinationIDs                                        string
 	disableEgress                                                bool
+	honorRetryAfter                                              bool
+	strictOrderingDestinationIDs                                 string
+	maxRetryAfter                                                time.Duration
+	transformErrorRedactedKeys                                   string
+	regionUnhealthyAfterFailures                                 int
+	regionCooldown                                               time.Duration
 )
 
 var jsonfast = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -236,6 +259,13 @@ func loadConfig() {
 	config.RegisterDurationConfigVariable(300, &maxRetryBackoff, true, time.Second, []string{"Router.maxRetryBackoff", "Router.maxRetryBackoffInS"}...)
 	config.RegisterDurationConfigVariable(0, &fixedLoopSleep, true, time.Millisecond, []string{"Router.fixedLoopSleep", "Router.fixedLoopSleepInMS"}...)
 	config.RegisterStringConfigVariable("", &toAbortDestinationIDs, true, "Router.toAbortDestinationIDs")
+	config.RegisterBoolConfigVariable(true, &honorRetryAfter, true, "Router.retryAfter.enabled")
+	config.RegisterDurationConfigVariable(3600, &maxRetryAfter, true, time.Second, "Router.retryAfter.max")
+	config.RegisterStringConfigVariable("", &strictOrderingDestinationIDs, true, "Router.strictOrderingDestinationIDs")
+	config.RegisterStringConfigVariable("email,phone,password,token,secret,apiKey,ip,address,firstName,lastName,birthday",
+		&transformErrorRedactedKeys, true, "Router.transformationErrorSampling.redactedKeys")
+	config.RegisterIntConfigVariable(5, &regionUnhealthyAfterFailures, true, 1, "Router.regionalRouting.unhealthyAfterFailures")
+	config.RegisterDurationConfigVariable(30, &regionCooldown, true, time.Second, "Router.regionalRouting.cooldown")
 }
 
 func sendRetryStoreStats(attempt int) {
@@ -293,10 +323,12 @@ func (worker *workerT) recordStatsForFailedTransforms(transformType string, tran
 
 func (worker *workerT) transform(routerJobs []types.RouterJobT) []types.DestinationJobT {
 	worker.rt.routerTransformInputCountStat.Count(len(routerJobs))
-	destinationJobs := worker.rt.transformer.Transform(
-		transformer.ROUTER_TRANSFORM,
-		&types.TransformMessageT{Data: routerJobs, DestType: strings.ToLower(worker.rt.destName)},
-	)
+	destinationJobs := worker.rt.transformByRegion(routerJobs, func(routerJobs []types.RouterJobT) []types.DestinationJobT {
+		return worker.rt.transformer.Transform(
+			transformer.ROUTER_TRANSFORM,
+			&types.TransformMessageT{Data: routerJobs, DestType: strings.ToLower(worker.rt.destName)},
+		)
+	})
 	worker.rt.routerTransformOutputCountStat.Count(len(destinationJobs))
 	worker.recordStatsForFailedTransforms("routerTransform", destinationJobs)
 	return destinationJobs
@@ -305,13 +337,15 @@ func (worker *workerT) transform(routerJobs []types.RouterJobT) []types.Destinat
 func (worker *workerT) batchTransform(routerJobs []types.RouterJobT) []types.DestinationJobT {
 	inputJobsLength := len(routerJobs)
 	worker.rt.batchInputCountStat.Count(inputJobsLength)
-	destinationJobs := worker.rt.transformer.Transform(
-		transformer.BATCH,
-		&types.TransformMessageT{
-			Data:     routerJobs,
-			DestType: strings.ToLower(worker.rt.destName),
-		},
-	)
+	destinationJobs := worker.rt.transformByRegion(routerJobs, func(routerJobs []types.RouterJobT) []types.DestinationJobT {
+		return worker.rt.transformer.Transform(
+			transformer.BATCH,
+			&types.TransformMessageT{
+				Data:     routerJobs,
+				DestType: strings.ToLower(worker.rt.destName),
+			},
+		)
+	})
 	worker.rt.batchOutputCountStat.Count(len(destinationJobs))
 	worker.recordStatsForFailedTransforms("batch", destinationJobs)
 	return destinationJobs
@@ -376,7 +410,7 @@ func (worker *workerT) workerProcess() {
 				continue
 			}
 
-			if worker.rt.guaranteeUserEventOrder {
+			if worker.rt.guaranteesOrder(parameters.DestinationID) {
 				orderKey := fmt.Sprintf(`%s:%s`, userID, parameters.DestinationID)
 				if wait, previousFailedJobID := worker.barrier.Wait(orderKey, job.JobID); wait {
 					previousFailedJobIDStr := "<nil>"
@@ -439,7 +473,7 @@ func (worker *workerT) workerProcess() {
 					Parameters:    routerutils.EmptyPayload,
 					WorkspaceId:   job.WorkspaceId,
 				}
-				if worker.rt.guaranteeUserEventOrder {
+				if worker.rt.guaranteesOrder(parameters.DestinationID) {
 					orderKey := fmt.Sprintf(`%s:%s`, job.UserID, parameters.DestinationID)
 					worker.rt.logger.Debugf("EventOrder: [%d] job %d for key %s failed", worker.workerID, status.JobID, orderKey)
 					if err := worker.barrier.StateChanged(orderKey, job.JobID, status.JobState); err != nil {
@@ -458,6 +492,7 @@ func (worker *workerT) workerProcess() {
 					// Get Access Token Information to send it as part of the event
 					tokenStatusCode, accountSecretInfo := worker.rt.oauth.FetchToken(&oauth.RefreshTokenParams{
 						AccountId:       rudderAccountID,
+						DestinationId:   destination.ID,
 						WorkspaceId:     jobMetadata.WorkspaceID,
 						DestDefName:     destination.DestinationDefinition.Name,
 						EventNamePrefix: "fetch_token",
@@ -518,6 +553,7 @@ func (worker *workerT) workerProcess() {
 }
 
 func (worker *workerT) processDestinationJobs() {
+	defer worker.rt.workerPool.Busy()()
 	ctx := context.TODO()
 	worker.batchTimeStat.Start()
 
@@ -525,6 +561,7 @@ func (worker *workerT) processDestinationJobs() {
 	var respStatusCode, prevRespStatusCode int
 	var respBody string
 	var respBodyTemp string
+	var respPayloadHash string
 
 	var destinationResponseHandler ResponseHandlerI
 	worker.rt.configSubscriberLock.RLock()
@@ -577,6 +614,7 @@ func (worker *workerT) processDestinationJobs() {
 	for _, destinationJob := range worker.destinationJobs {
 		var errorAt string
 		respBodyArr := make([]string, 0)
+		respPayloadHash = ""
 		if destinationJob.StatusCode == 200 || destinationJob.StatusCode == 0 {
 			if worker.canSendJobToDestination(prevRespStatusCode, failedUserIDsMap, &destinationJob) {
 				diagnosisStartTime := time.Now()
@@ -625,6 +663,7 @@ func (worker *workerT) processDestinationJobs() {
 					errorAt = routerutils.ERROR_AT_CUST
 				} else {
 					result, err := getIterableStruct(destinationJob.Message, transformAt)
+					region, regionEndpoint := worker.rt.deliveryRegion(&destinationJob)
 					if err != nil {
 						errorAt = routerutils.ERROR_AT_TF
 						respStatusCode, respBody = types.RouterUnMarshalErrorCode, fmt.Errorf("transformer response unmarshal error: %w", err).Error()
@@ -636,6 +675,9 @@ func (worker *workerT) processDestinationJobs() {
 								respStatusCode, respBodyTemp = http.StatusBadRequest, fmt.Sprintf(`400 GetPostInfoFailed with error: %s`, err.Error())
 								respBodyArr = append(respBodyArr, respBodyTemp)
 							} else {
+								if regionEndpoint != nil {
+									val.URL = routeToRegion(val.URL, regionEndpoint)
+								}
 								// stat start
 								pkgLogger.Debugf(`responseTransform status :%v, %s`, worker.rt.transformerProxy, worker.rt.destName)
 								// transformer proxy start
@@ -685,10 +727,13 @@ func (worker *workerT) processDestinationJobs() {
 									resp := worker.rt.netHandle.SendPost(sendCtx, val)
 									cancel()
 									respStatusCode, respBodyTemp, respContentType = resp.StatusCode, string(resp.ResponseBody), resp.ResponseContentType
+									respPayloadHash = resp.RequestBodyHash
+									worker.rt.backOffDestination(destinationID, resp.RetryAfter)
 									// stat end
 									worker.routerDeliveryLatencyStat.SendTiming(time.Since(rdlTime))
 								}
 								// transformer proxy end
+								worker.rt.recordRegionResponse(destinationID, region, respStatusCode, time.Now())
 								if isSuccessStatus(respStatusCode) {
 									respBodyArr = append(respBodyArr, respBodyTemp)
 								} else {
@@ -735,6 +780,9 @@ func (worker *workerT) processDestinationJobs() {
 
 				worker.deliveryTimeStat.End()
 				deliveryLatencyStat.End()
+				if worker.rt.throttlerFactory != nil {
+					worker.rt.throttlerFactory.Get(worker.rt.destName, destinationID).ResponseReceived(respStatusCode, timeTaken)
+				}
 
 				// END: request to destination endpoint
 
@@ -784,6 +832,7 @@ func (worker *workerT) processDestinationJobs() {
 				destinationJobMetadata: &_destinationJobMetadata,
 				respStatusCode:         respStatusCode,
 				respBody:               respBody,
+				payloadHash:            respPayloadHash,
 				errorAt:                errorAt,
 			})
 		}
@@ -856,7 +905,11 @@ func (worker *workerT) processDestinationJobs() {
 					sourcesIDs = append(sourcesIDs, metadata.SourceID)
 				}
 			}
-			worker.sendDestinationResponseToConfigBackend(payload, routerJobResponse.destinationJobMetadata, routerJobResponse.status, sourcesIDs)
+			if routerJobResponse.destinationJob.StatusCode != 0 && routerJobResponse.destinationJob.StatusCode != http.StatusOK {
+				worker.sampleTransformationError(payload, routerJobResponse.destinationJob, routerJobResponse.destinationJobMetadata, routerJobResponse.status, sourcesIDs)
+			} else {
+				worker.sendDestinationResponseToConfigBackend(payload, routerJobResponse.payloadHash, routerJobResponse.destinationJobMetadata, routerJobResponse.status, sourcesIDs)
+			}
 			destLiveEventSentMap[routerJobResponse.destinationJob] = struct{}{}
 		}
 	}
@@ -873,8 +926,8 @@ func (worker *workerT) canSendJobToDestination(prevRespStatusCode int, failedUse
 		return true
 	}
 
-	if !worker.rt.guaranteeUserEventOrder {
-		// if guaranteeUserEventOrder is false, letting the next jobs pass
+	if !worker.rt.guaranteesOrder(destinationJob.Destination.ID) {
+		// if the order isn't guaranteed for the destination, letting the next jobs pass
 		return true
 	}
 
@@ -921,6 +974,7 @@ type JobResponse struct {
 	destinationJobMetadata *types.JobMetadataT
 	respStatusCode         int
 	respBody               string
+	payloadHash            string // of the last request sent to the destination, empty if it wasn't sent by the router
 	errorAt                string
 	status                 *jobsdb.JobStatusT
 }
@@ -1018,13 +1072,13 @@ func (worker *workerT) postStatusOnResponseQ(respStatusCode int, respBody string
 					worker.retryForJobMapMutex.Unlock()
 				} else {
 					worker.retryForJobMapMutex.Lock()
-					worker.retryForJobMap[destinationJobMetadata.JobID] = time.Now().Add(durationBeforeNextAttempt(status.AttemptNum))
+					worker.retryForJobMap[destinationJobMetadata.JobID] = worker.rt.nextRetryTime(destinationJobMetadata.DestinationID, status.AttemptNum)
 					worker.retryForJobMapMutex.Unlock()
 				}
 			}
 		} else if respStatusCode == 429 {
 			worker.retryForJobMapMutex.Lock()
-			worker.retryForJobMap[destinationJobMetadata.JobID] = time.Now().Add(durationBeforeNextAttempt(status.AttemptNum))
+			worker.retryForJobMap[destinationJobMetadata.JobID] = worker.rt.nextRetryTime(destinationJobMetadata.DestinationID, status.AttemptNum)
 			worker.retryForJobMapMutex.Unlock()
 		} else {
 			status.JobState = jobsdb.Aborted.State
@@ -1036,7 +1090,7 @@ func (worker *workerT) postStatusOnResponseQ(respStatusCode int, respBody string
 			destinationJobMetadata.JobT.Parameters = misc.UpdateJSONWithNewKeyVal(destinationJobMetadata.JobT.Parameters, "reason", status.ErrorResponse) // NOTE: Old key used was "error_response"
 		}
 
-		if worker.rt.guaranteeUserEventOrder {
+		if worker.rt.guaranteesOrder(destinationJobMetadata.DestinationID) {
 			if status.JobState == jobsdb.Failed.State {
 				orderKey := fmt.Sprintf(`%s:%s`, destinationJobMetadata.UserID, destinationJobMetadata.DestinationID)
 				worker.rt.logger.Debugf("EventOrder: [%d] job %d for key %s failed", worker.workerID, status.JobID, orderKey)
@@ -1099,13 +1153,17 @@ func (worker *workerT) sendEventDeliveryStat(destinationJobMetadata *types.JobMe
 						"workspaceId":    status.WorkspaceId,
 					})
 
-				eventsDeliveryTimeStat.SendTiming(time.Since(receivedTime))
+				var traceParent string
+				if destinationJobMetadata.JobT != nil {
+					traceParent = gjson.GetBytes(destinationJobMetadata.JobT.Parameters, "traceparent").String()
+				}
+				stats.SendTimingWithTrace(eventsDeliveryTimeStat, time.Since(receivedTime), stats.SpanContextFromTraceParent(traceParent))
 			}
 		}
 	}
 }
 
-func (*workerT) sendDestinationResponseToConfigBackend(payload json.RawMessage, destinationJobMetadata *types.JobMetadataT, status *jobsdb.JobStatusT, sourceIDs []string) {
+func (*workerT) sendDestinationResponseToConfigBackend(payload json.RawMessage, payloadHash string, destinationJobMetadata *types.JobMetadataT, status *jobsdb.JobStatusT, sourceIDs []string) {
 	// Sending destination response to config backend
 	if status.ErrorCode != fmt.Sprint(types.RouterUnMarshalErrorCode) && status.ErrorCode != fmt.Sprint(types.RouterTimedOutStatusCode) {
 		deliveryStatus := destinationdebugger.DeliveryStatusT{
@@ -1119,6 +1177,10 @@ func (*workerT) sendDestinationResponseToConfigBackend(payload json.RawMessage,
 			SentAt:        status.ExecTime.Format(misc.RFC3339Milli),
 			EventName:     gjson.GetBytes(destinationJobMetadata.JobT.Parameters, "event_name").String(),
 			EventType:     gjson.GetBytes(destinationJobMetadata.JobT.Parameters, "event_type").String(),
+			MessageID:     gjson.GetBytes(destinationJobMetadata.JobT.Parameters, "message_id").String(),
+		}
+		if status.JobState != jobsdb.Succeeded.State {
+			destinationdebugger.CaptureFailure(&deliveryStatus, payloadHash, gjson.GetBytes(status.ErrorResponse, "response").String())
 		}
 		destinationdebugger.RecordEventDeliveryStatus(destinationJobMetadata.DestinationID, &deliveryStatus)
 	}
@@ -1148,6 +1210,7 @@ func (rt *HandleT) trackRequestMetrics(reqMetric requestMetric) {
 
 func (rt *HandleT) initWorkers() {
 	rt.workers = make([]*workerT, rt.noOfWorkers)
+	rt.workerPool = rruntime.RegisterPool("router", rt.destName, rt.noOfWorkers)
 
 	g, _ := errgroup.WithContext(context.Background())
 	for i := 0; i < rt.noOfWorkers; i++ {
@@ -1204,21 +1267,21 @@ func (rt *HandleT) findWorker(job *jobsdb.JobT, throttledUserMap map[string]stru
 	var parameters JobParametersT
 	userID := job.UserID
 
-	// checking if the user is in throttledMap. If yes, returning nil.
-	// this check is done to maintain order.
-	if _, ok := throttledUserMap[userID]; ok && rt.guaranteeUserEventOrder {
-		rt.logger.Debugf(`[%v Router] :: Skipping processing of job:%d of user:%s as user has earlier jobs in throttled map`, rt.destName, job.JobID, userID)
-		return nil
-	}
-
 	err := json.Unmarshal(job.Parameters, &parameters)
 	if err != nil {
 		rt.logger.Errorf(`[%v Router] :: Unmarshalling parameters failed with the error %v . Returning nil worker`, err)
 		return
 	}
 
-	if !rt.guaranteeUserEventOrder {
-		// if guaranteeUserEventOrder is false, assigning worker randomly and returning here.
+	// checking if the user is in throttledMap. If yes, returning nil.
+	// this check is done to maintain order.
+	if _, ok := throttledUserMap[userID]; ok && rt.guaranteesOrder(parameters.DestinationID) {
+		rt.logger.Debugf(`[%v Router] :: Skipping processing of job:%d of user:%s as user has earlier jobs in throttled map`, rt.destName, job.JobID, userID)
+		return nil
+	}
+
+	if !rt.guaranteesOrder(parameters.DestinationID) {
+		// if the order isn't guaranteed for the destination, assigning worker randomly and returning here.
 		if rt.shouldThrottle(job, parameters, throttledUserMap) {
 			return
 		}
@@ -1233,7 +1296,7 @@ func (rt *HandleT) findWorker(job *jobsdb.JobT, throttledUserMap map[string]stru
 		return
 	}
 	orderKey := fmt.Sprintf(`%s:%s`, userID, parameters.DestinationID)
-	enter, previousFailedJobID := worker.barrier.Enter(orderKey, job.JobID)
+	enter, previousFailedJobID := rt.enterBarrier(worker, orderKey, job, parameters.DestinationID)
 	if enter {
 		rt.logger.Debugf("EventOrder: job %d of user %s is allowed to be processed", job.JobID, userID)
 		if rt.shouldThrottle(job, parameters, throttledUserMap) {
@@ -1308,6 +1371,7 @@ func (rt *HandleT) commitStatusList(responseList *[]jobResponseT) {
 	var completedJobsList []*jobsdb.JobT
 	var statusList []*jobsdb.JobStatusT
 	var routerAbortedJobs []*jobsdb.JobT
+	var deliveryReceipts []deliveryreceipts.Receipt
 	for _, resp := range *responseList {
 		var parameters JobParametersT
 		err := json.Unmarshal(resp.JobT.Parameters, &parameters)
@@ -1360,6 +1424,9 @@ func (rt *HandleT) commitStatusList(responseList *[]jobResponseT) {
 			routerAbortedJobs = append(routerAbortedJobs, resp.JobT)
 			completedJobsList = append(completedJobsList, resp.JobT)
 		}
+		if rt.deliveryReceipts != nil && (resp.status.JobState == jobsdb.Succeeded.State || resp.status.JobState == jobsdb.Aborted.State) {
+			deliveryReceipts = append(deliveryReceipts, newDeliveryReceipt(resp.status, parameters))
+		}
 
 		// REPORTING - ROUTER - END
 
@@ -1439,6 +1506,11 @@ func (rt *HandleT) commitStatusList(responseList *[]jobResponseT) {
 				if err != nil {
 					return err
 				}
+				if rt.deliveryReceipts != nil {
+					if err := rt.deliveryReceipts.StoreInTx(ctx, tx.SqlTx(), deliveryReceipts); err != nil {
+						return err
+					}
+				}
 				rt.Reporting.Report(reportMetrics, tx.SqlTx())
 				return nil
 			})
@@ -1447,16 +1519,18 @@ func (rt *HandleT) commitStatusList(responseList *[]jobResponseT) {
 			panic(err)
 		}
 		rt.updateProcessedEventsMetrics(statusList)
+		rt.jobsLanded(statusList)
 	}
 
-	if rt.guaranteeUserEventOrder {
+	if rt.guaranteeUserEventOrder || strictOrderingDestinationIDs != "" {
 		//#JobOrder (see other #JobOrder comment)
 		for _, resp := range *responseList {
 			status := resp.status.JobState
 			userID := resp.userID
 			worker := resp.worker
-			if status != jobsdb.Failed.State {
-				orderKey := fmt.Sprintf(`%s:%s`, userID, gjson.GetBytes(resp.JobT.Parameters, "destination_id").String())
+			destinationID := gjson.GetBytes(resp.JobT.Parameters, "destination_id").String()
+			if status != jobsdb.Failed.State && rt.guaranteesOrder(destinationID) {
+				orderKey := fmt.Sprintf(`%s:%s`, userID, destinationID)
 				rt.logger.Debugf("EventOrder: [%d] job %d for key %s %s", worker.workerID, resp.status.JobID, orderKey, status)
 				if err := worker.barrier.StateChanged(orderKey, resp.status.JobID, status); err != nil {
 					panic(err)
@@ -1467,6 +1541,28 @@ func (rt *HandleT) commitStatusList(responseList *[]jobResponseT) {
 	}
 }
 
+// newDeliveryReceipt returns the delivery receipt of a job in a terminal state
+func newDeliveryReceipt(status *jobsdb.JobStatusT, parameters JobParametersT) deliveryreceipts.Receipt {
+	receipt := deliveryreceipts.Receipt{
+		MessageID:     parameters.MessageID,
+		DestinationID: parameters.DestinationID,
+		WorkspaceID:   status.WorkspaceId,
+		JobID:         status.JobID,
+		Status:        deliveryreceipts.Succeeded,
+		StatusCode:    status.ErrorCode,
+		Attempts:      status.AttemptNum,
+	}
+	if status.JobState == jobsdb.Aborted.State {
+		receipt.Status = deliveryreceipts.Aborted
+	}
+	if response := gjson.GetBytes(status.ErrorResponse, "response"); response.Exists() {
+		receipt.Response = response.String()
+	} else {
+		receipt.Response = string(status.ErrorResponse)
+	}
+	return receipt
+}
+
 // statusInsertLoop will run in a separate goroutine
 // Blocking method, returns when rt.responseQ channel is closed.
 func (rt *HandleT) statusInsertLoop() {
@@ -1651,15 +1747,16 @@ func (rt *HandleT) getQueryParams(pickUpCount int) jobsdb.GetQueryParamsT {
 		}
 	}
 	return jobsdb.GetQueryParamsT{
-		CustomValFilters: []string{rt.destName},
-		PayloadSizeLimit: rt.payloadLimit,
-		JobsLimit:        pickUpCount,
+		CustomValFilters:    []string{rt.destName},
+		ExcludeWorkspaceIDs: rt.pausedWorkspaceIDs(),
+		PayloadSizeLimit:    rt.payloadLimit,
+		JobsLimit:           pickUpCount,
 	}
 }
 
 func (rt *HandleT) readAndProcess() int {
 	//#JobOrder (See comment marked #JobOrder
-	if rt.guaranteeUserEventOrder {
+	if rt.guaranteeUserEventOrder || strictOrderingDestinationIDs != "" {
 		for idx := range rt.workers {
 			rt.workers[idx].barrier.Sync()
 		}
@@ -1673,6 +1770,7 @@ func (rt *HandleT) readAndProcess() int {
 	rt.lastQueryRunTime = time.Now()
 
 	pickupMap := rt.MultitenantI.GetRouterPickupJobs(rt.destName, rt.noOfWorkers, timeOut, jobQueryBatchSize)
+	rt.excludePausedWorkspaces(pickupMap)
 	totalPickupCount := 0
 	for _, pickup := range pickupMap {
 		if pickup > 0 {
@@ -1707,8 +1805,24 @@ func (rt *HandleT) readAndProcess() int {
 	throttledUserMap := make(map[string]struct{})
 
 	// Identify jobs which can be processed
+	now := time.Now()
 	for iterator.HasNext() {
 		job := iterator.Next()
+		if paused, status := rt.pauseInMaintenance(job, now); paused {
+			if status != nil {
+				statusList = append(statusList, status)
+			}
+			iterator.Discard(job)
+			continue
+		}
+		if rt.isBackingOff(job, now) {
+			iterator.Discard(job)
+			continue
+		}
+		if rt.isRegionCoolingDown(job, now) {
+			iterator.Discard(job)
+			continue
+		}
 		w := rt.findWorker(job, throttledUserMap)
 		if w != nil {
 			status := jobsdb.JobStatusT{
@@ -1750,6 +1864,12 @@ func (rt *HandleT) readAndProcess() int {
 		return 0
 	}
 
+	jobs := make([]*jobsdb.JobT, 0, len(toProcess))
+	for _, wrkJob := range toProcess {
+		jobs = append(jobs, wrkJob.job)
+	}
+	rt.jobsTakenOff(jobs)
+
 	workerAssignedTime := time.Now()
 	// Send the jobs to the jobQ
 	for _, wrkJob := range toProcess {
@@ -1854,6 +1974,8 @@ func (rt *HandleT) Setup(backendConfig backendconfig.BackendConfig, jobsDB jobsd
 	config.RegisterBoolConfigVariable(false, &rt.enableBatching, false, "Router."+rt.destName+"."+"enableBatching")
 	config.RegisterBoolConfigVariable(false, &rt.savePayloadOnError, true, savePayloadOnErrorKeys...)
 	config.RegisterBoolConfigVariable(false, &rt.transformerProxy, true, transformerProxyKeys...)
+	transformErrorSamplesKeys := []string{"Router." + rt.destName + "." + "transformationErrorSamplesPerMinute", "Router." + "transformationErrorSamplesPerMinute"}
+	config.RegisterIntConfigVariable(10, &rt.transformErrorSamplesPerMinute, true, 1, transformErrorSamplesKeys...)
 	// START: Alert configuration
 	// We want to use these configurations to control what alerts we show via router-abort-count alert definition
 	rtAbortTransformationKeys := []string{"Router." + rt.destName + "." + "skipRtAbortAlertForTf", "Router.skipRtAbortAlertForTf"}
@@ -1886,7 +2008,9 @@ func (rt *HandleT) Setup(backendConfig backendconfig.BackendConfig, jobsDB jobsd
 
 	rt.transformer = transformer.NewTransformer(rt.netClientTimeout, rt.backendProxyTimeout)
 
-	rt.oauth = oauth.NewOAuthErrorHandler(backendConfig)
+	if rt.oauth == nil {
+		rt.oauth = oauth.NewOAuthErrorHandler(backendConfig)
+	}
 
 	rt.isBackendConfigInitialized = false
 	rt.backendConfigInitialized = make(chan bool)
@@ -1961,9 +2085,16 @@ func (rt *HandleT) backendConfigSubscriber() {
 	for configEvent := range ch {
 		rt.configSubscriberLock.Lock()
 		rt.destinationsMap = map[string]*routerutils.BatchDestinationT{}
+		rt.maintenanceWindows = map[string][]maintenanceWindow{}
+		rt.regionalEndpoints = map[string]*regionalEndpoints{}
+		rt.workspaceRegions = map[string]string{}
 		configData := configEvent.Data.(map[string]backendconfig.ConfigT)
+		rt.setWorkspacesPausedBySettings(configData)
 		rt.sourceIDWorkspaceMap = map[string]string{}
 		for workspaceID, wConfig := range configData {
+			if wConfig.Settings.DataResidency != "" {
+				rt.workspaceRegions[workspaceID] = wConfig.Settings.DataResidency
+			}
 			for i := range wConfig.Sources {
 				source := &wConfig.Sources[i]
 				rt.sourceIDWorkspaceMap[source.ID] = workspaceID
@@ -1982,6 +2113,16 @@ func (rt *HandleT) backendConfigSubscriber() {
 								}
 							}
 							rt.destinationsMap[destination.ID].Sources = append(rt.destinationsMap[destination.ID].Sources, *source)
+							if windows, err := getMaintenanceWindows(destination, time.Now()); err != nil {
+								rt.logger.Errorf("Ignoring maintenance windows of destination %s: %v", destination.ID, err)
+							} else if len(windows) > 0 {
+								rt.maintenanceWindows[destination.ID] = windows
+							}
+							if regional, err := getRegionalEndpoints(destination); err != nil {
+								rt.logger.Errorf("Ignoring regional endpoints of destination %s: %v", destination.ID, err)
+							} else if regional != nil {
+								rt.regionalEndpoints[destination.ID] = regional
+							}
 
 							rt.destinationResponseHandler = New(destination.DestinationDefinition.ResponseRules)
 							if value, ok := destination.DestinationDefinition.Config["saveDestinationResponse"].(bool); ok {
@@ -2040,6 +2181,7 @@ func (rt *HandleT) HandleOAuthDestResponse(params *HandleDestOAuthRespParamsT) (
 				Secret:          params.secret,
 				WorkspaceId:     workspaceID,
 				AccountId:       rudderAccountID,
+				DestinationId:   destinationJob.Destination.ID,
 				DestDefName:     destinationJob.Destination.DestinationDefinition.Name,
 				EventNamePrefix: "refresh_token",
 				WorkerId:        params.workerID,
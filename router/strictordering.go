@@ -0,0 +1,51 @@
+package router
+
+import (
+	"strings"
+
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Destinations listed in Router.strictOrderingDestinationIDs are delivered in strict order per user, e.g. for identity
+// merges where out-of-order updates corrupt the profiles in the destination. On top of the event ordering guarantees,
+// which only hold back the jobs of a user after a failed one, a single job per user is in flight at any time: the next
+// job of the user isn't picked up before the previous one's status is persisted, even across retries. Strict ordering
+// applies even if Router.guaranteeUserEventOrder is disabled for the destination type.
+//
+// This trades throughput for ordering, since the jobs of a user are delivered one per iteration of the router's loop.
+// The trade-off shows in the router_strict_ordering_jobs and router_strict_ordering_blocked_jobs counts, along with
+// the router_strict_ordering_pickup_delay timer.
+
+// isStrictlyOrdered reports whether the jobs of the destination are delivered in strict order per user
+func isStrictlyOrdered(destinationID string) bool {
+	if strictOrderingDestinationIDs == "" {
+		return false
+	}
+	return misc.Contains(strings.Split(strictOrderingDestinationIDs, ","), destinationID)
+}
+
+// guaranteesOrder reports whether the jobs of the destination are delivered in order per user
+func (rt *HandleT) guaranteesOrder(destinationID string) bool {
+	return rt.guaranteeUserEventOrder || isStrictlyOrdered(destinationID)
+}
+
+// enterBarrier enters the job in the barrier of its worker, exclusively for strictly ordered destinations
+func (rt *HandleT) enterBarrier(worker *workerT, orderKey string, job *jobsdb.JobT, destinationID string) (enter bool, previousFailedJobID *int64) {
+	if !isStrictlyOrdered(destinationID) {
+		return worker.barrier.Enter(orderKey, job.JobID)
+	}
+	tags := stats.Tags{
+		"destType": rt.destName,
+		"destID":   destinationID,
+	}
+	enter, previousFailedJobID = worker.barrier.EnterExclusive(orderKey, job.JobID)
+	if !enter {
+		stats.Default.NewTaggedStat("router_strict_ordering_blocked_jobs", stats.CountType, tags).Increment()
+		return enter, previousFailedJobID
+	}
+	stats.Default.NewTaggedStat("router_strict_ordering_jobs", stats.CountType, tags).Increment()
+	stats.Default.NewTaggedStat("router_strict_ordering_pickup_delay", stats.TimerType, tags).Since(job.CreatedAt)
+	return enter, previousFailedJobID
+}
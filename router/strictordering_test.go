@@ -0,0 +1,51 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/router/internal/eventorder"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+)
+
+func TestStrictOrdering(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+	loadConfig()
+	prevStats := stats.Default
+	defer func() { stats.Default = prevStats }()
+	store := memstats.New()
+	stats.Default = store
+
+	rt := &HandleT{destName: "MP"}
+	require.False(t, rt.guaranteesOrder("destination"))
+	config.Set("Router.strictOrderingDestinationIDs", "another-destination,destination")
+	require.True(t, rt.guaranteesOrder("destination"), "strict ordering applies even if the order isn't guaranteed for the destination type")
+	require.False(t, rt.guaranteesOrder("unordered-destination"))
+
+	worker := &workerT{barrier: eventorder.NewBarrier(eventorder.WithConcurrencyLimit(10))}
+	first := &jobsdb.JobT{JobID: 1}
+	second := &jobsdb.JobT{JobID: 2}
+	tags := stats.Tags{"destType": "MP", "destID": "destination"}
+
+	enter, _ := rt.enterBarrier(worker, "user:destination", first, "destination")
+	require.True(t, enter)
+	enter, _ = rt.enterBarrier(worker, "user:destination", second, "destination")
+	require.False(t, enter, "a single job per user is in flight")
+	enter, _ = rt.enterBarrier(worker, "user:unordered-destination", first, "unordered-destination")
+	require.True(t, enter)
+	enter, _ = rt.enterBarrier(worker, "user:unordered-destination", second, "unordered-destination")
+	require.True(t, enter, "jobs of other destinations enter concurrently")
+	require.EqualValues(t, 1, store.Get("router_strict_ordering_jobs", tags).LastValue())
+	require.EqualValues(t, 1, store.Get("router_strict_ordering_blocked_jobs", tags).LastValue())
+
+	require.NoError(t, worker.barrier.StateChanged("user:destination", first.JobID, jobsdb.Succeeded.State))
+	worker.barrier.Sync()
+	enter, _ = rt.enterBarrier(worker, "user:destination", second, "destination")
+	require.True(t, enter, "the next job enters once the previous one's status is synced")
+	require.Len(t, store.Get("router_strict_ordering_pickup_delay", tags).Durations(), 1)
+}
@@ -0,0 +1,181 @@
+package throttler
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/router/types"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// With adaptive throttling, the number of events allowed per time window for a destination is adapted to its responses,
+// additive increase/multiplicative decrease (AIMD) style:
+//   - it is decreased by the decrease factor when the destination responds with 429 or 5xx status codes, or slower than
+//     the latency threshold, at most once per time window
+//   - it is increased by a percentage of the max limit after every time window without such responses
+//
+// The limit is kept between the min and max limits. The static limit and time window of the destination, if any, are the
+// defaults of its max limit and time window.
+
+// adaptiveConfig is the config of the adaptive throttling of a destination
+type adaptiveConfig struct {
+	enabled            bool
+	window             time.Duration
+	minLimit           int64
+	maxLimit           int64
+	decreaseFactor     float64
+	increasePercentage float64
+	latencyThreshold   time.Duration
+}
+
+func (c *adaptiveConfig) readAdaptiveConfig(destName, destID string, static *throttlingConfig) {
+	c.enabled = getAdaptiveConfigBool(destName, destID, "enabled", false)
+	defaultWindow, defaultMaxLimit := time.Second, int64(250)
+	if static.enabled {
+		defaultWindow, defaultMaxLimit = static.window, static.limit
+	}
+	c.window = getAdaptiveConfigDuration(destName, destID, "timeWindow", defaultWindow)
+	c.minLimit = getAdaptiveConfigInt64(destName, destID, "minLimit", 1)
+	c.maxLimit = getAdaptiveConfigInt64(destName, destID, "maxLimit", defaultMaxLimit)
+	c.decreaseFactor = getAdaptiveConfigFloat64(destName, destID, "decreaseFactor", 0.5)
+	c.increasePercentage = getAdaptiveConfigFloat64(destName, destID, "increasePercentage", 10)
+	c.latencyThreshold = getAdaptiveConfigDuration(destName, destID, "latencyThreshold", 10*time.Second)
+
+	if c.window < time.Second || c.minLimit <= 0 || c.maxLimit < c.minLimit {
+		c.enabled = false
+	}
+}
+
+// adaptiveConfigKeys returns the config keys of an adaptive throttling setting, from the most specific to the least
+func adaptiveConfigKeys(destName, destID, key string) []string {
+	return []string{
+		fmt.Sprintf(`Router.throttler.%s.%s.adaptive.%s`, destName, destID, key),
+		fmt.Sprintf(`Router.throttler.%s.adaptive.%s`, destName, key),
+		fmt.Sprintf(`Router.throttler.adaptive.%s`, key),
+	}
+}
+
+func getAdaptiveConfigBool(destName, destID, key string, defaultValue bool) bool {
+	for _, k := range adaptiveConfigKeys(destName, destID, key) {
+		if config.IsSet(k) {
+			return config.GetBool(k, defaultValue)
+		}
+	}
+	return defaultValue
+}
+
+func getAdaptiveConfigInt64(destName, destID, key string, defaultValue int64) int64 {
+	for _, k := range adaptiveConfigKeys(destName, destID, key) {
+		if config.IsSet(k) {
+			return config.GetInt64(k, defaultValue)
+		}
+	}
+	return defaultValue
+}
+
+func getAdaptiveConfigFloat64(destName, destID, key string, defaultValue float64) float64 {
+	for _, k := range adaptiveConfigKeys(destName, destID, key) {
+		if config.IsSet(k) {
+			return config.GetFloat64(k, defaultValue)
+		}
+	}
+	return defaultValue
+}
+
+func getAdaptiveConfigDuration(destName, destID, key string, defaultValue time.Duration) time.Duration {
+	for _, k := range adaptiveConfigKeys(destName, destID, key) {
+		if config.IsSet(k) {
+			return config.GetDuration(k, 0, time.Second)
+		}
+	}
+	return defaultValue
+}
+
+// adaptiveLimit is the number of events allowed per time window for a destination, adapted to its responses
+type adaptiveLimit struct {
+	config adaptiveConfig
+	now    func() time.Time
+
+	mu          sync.Mutex
+	limit       float64
+	windowStart time.Time
+	decreased   bool // the limit was decreased in the current window
+	succeeded   bool // the destination responded successfully in the current window
+
+	rateGauge stats.Measurement
+}
+
+func newAdaptiveLimit(conf adaptiveConfig, rateGauge stats.Measurement) *adaptiveLimit {
+	a := &adaptiveLimit{
+		config:    conf,
+		now:       time.Now,
+		limit:     float64(conf.maxLimit),
+		rateGauge: rateGauge,
+	}
+	a.windowStart = a.now()
+	a.rateGauge.Gauge(a.rate())
+	return a
+}
+
+// get returns the number of events currently allowed per time window
+func (a *adaptiveLimit) get() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rollWindow()
+	return int64(a.limit)
+}
+
+// responseReceived adapts the limit to a response of the destination
+func (a *adaptiveLimit) responseReceived(statusCode int, latency time.Duration) {
+	if statusCode == types.RouterUnMarshalErrorCode {
+		return // not a response of the destination
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rollWindow()
+
+	overloaded := statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError ||
+		(a.config.latencyThreshold > 0 && latency > a.config.latencyThreshold)
+	if !overloaded {
+		a.succeeded = true
+		return
+	}
+	if a.decreased {
+		return
+	}
+	a.decreased = true
+	a.setLimit(a.limit * a.config.decreaseFactor)
+}
+
+// rollWindow increases the limit if the destination wasn't overloaded in the time window that ended, if any
+func (a *adaptiveLimit) rollWindow() {
+	now := a.now()
+	if now.Sub(a.windowStart) < a.config.window {
+		return
+	}
+	if a.succeeded && !a.decreased {
+		a.setLimit(a.limit + float64(a.config.maxLimit)*a.config.increasePercentage/100)
+	}
+	a.windowStart = now
+	a.decreased = false
+	a.succeeded = false
+}
+
+func (a *adaptiveLimit) setLimit(limit float64) {
+	if limit < float64(a.config.minLimit) {
+		limit = float64(a.config.minLimit)
+	}
+	if limit > float64(a.config.maxLimit) {
+		limit = float64(a.config.maxLimit)
+	}
+	a.limit = limit
+	a.rateGauge.Gauge(a.rate())
+}
+
+// rate returns the number of events currently allowed per second
+func (a *adaptiveLimit) rate() float64 {
+	return float64(int64(a.limit)) / a.config.window.Seconds()
+}
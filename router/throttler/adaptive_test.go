@@ -0,0 +1,86 @@
+package throttler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+)
+
+func TestAdaptiveLimit(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+	config.Set("Router.throttler.adaptive.enabled", true)
+	config.Set("Router.throttler.WEBHOOK.limit", 100)
+	config.Set("Router.throttler.WEBHOOK.timeWindow", "10s")
+	config.Set("Router.throttler.WEBHOOK.adaptive.minLimit", 20)
+	config.Set("Router.throttler.WEBHOOK.adaptive.latencyThreshold", "5s")
+
+	var conf throttlingConfig
+	conf.readThrottlingConfig("WEBHOOK", "destination")
+	require.True(t, conf.adaptive.enabled)
+	require.Equal(t, 10*time.Second, conf.adaptive.window, "the static time window is the default")
+	require.EqualValues(t, 100, conf.adaptive.maxLimit, "the static limit is the default max limit")
+
+	store := memstats.New()
+	now := time.Now()
+	a := newAdaptiveLimit(conf.adaptive, store.NewTaggedStat("throttling_adaptive_rate", stats.GaugeType, nil))
+	a.now = func() time.Time { return now }
+	a.windowStart = now
+	rate := func() float64 { return store.Get("throttling_adaptive_rate", nil).LastValue() }
+	require.EqualValues(t, 100, a.get())
+	require.EqualValues(t, 10, rate())
+
+	a.responseReceived(429, time.Second)
+	require.EqualValues(t, 50, a.get())
+	a.responseReceived(500, time.Second)
+	require.EqualValues(t, 50, a.get(), "decreased at most once per window")
+
+	now = now.Add(10 * time.Second)
+	a.responseReceived(200, 6*time.Second)
+	require.EqualValues(t, 25, a.get(), "slow responses decrease the limit")
+	now = now.Add(10 * time.Second)
+	a.responseReceived(503, time.Second)
+	require.EqualValues(t, 20, a.get(), "bounded by the min limit")
+	require.EqualValues(t, 2, rate())
+
+	now = now.Add(10 * time.Second)
+	a.responseReceived(200, time.Second)
+	require.EqualValues(t, 20, a.get(), "increased once the window ends")
+	now = now.Add(10 * time.Second)
+	require.EqualValues(t, 30, a.get())
+	now = now.Add(10 * time.Second)
+	require.EqualValues(t, 30, a.get(), "not increased without responses")
+
+	for i := 0; i < 10; i++ {
+		a.responseReceived(400, time.Second)
+		now = now.Add(10 * time.Second)
+	}
+	require.EqualValues(t, 100, a.get(), "bounded by the max limit")
+}
+
+func TestThrottlerAdaptiveKey(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+	config.Set("Router.throttler.algorithm", "gcra")
+	config.Set("Router.throttler.WEBHOOK.adaptive.enabled", true)
+	config.Set("Router.throttler.WEBHOOK.adaptive.maxLimit", 10)
+	config.Set("Router.throttler.WEBHOOK.adaptive.minLimit", 1)
+
+	f, err := New(memstats.New())
+	require.NoError(t, err)
+	require.Nil(t, f.Get("OTHER", "other").adaptiveLimit, "disabled for other destinations")
+	throttler := f.Get("WEBHOOK", "destination")
+
+	limited, err := throttler.CheckLimitReached("destination", 8)
+	require.NoError(t, err)
+	require.False(t, limited)
+	throttler.ResponseReceived(429, time.Millisecond)
+	limited, err = throttler.CheckLimitReached("destination", 8)
+	require.NoError(t, err)
+	require.True(t, limited, "the decreased limit applies right away")
+}
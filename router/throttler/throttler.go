@@ -53,11 +53,22 @@ func (f *Factory) Get(destName, destID string) *Throttler {
 
 	var conf throttlingConfig
 	conf.readThrottlingConfig(destName, destID)
-	f.throttlers[destID] = &Throttler{
+	t := &Throttler{
 		limiter: f.limiter,
 		config:  conf,
 	}
-	return f.throttlers[destID]
+	if conf.adaptive.enabled {
+		statsFactory := f.Stats
+		if statsFactory == nil {
+			statsFactory = stats.Default
+		}
+		t.adaptiveLimit = newAdaptiveLimit(conf.adaptive, statsFactory.NewTaggedStat("throttling_adaptive_rate", stats.GaugeType, stats.Tags{
+			"destType":      destName,
+			"destinationId": destID,
+		}))
+	}
+	f.throttlers[destID] = t
+	return t
 }
 
 func (f *Factory) initThrottlerFactory() error {
@@ -107,18 +118,23 @@ func (f *Factory) initThrottlerFactory() error {
 }
 
 type Throttler struct {
-	limiter limiter
-	config  throttlingConfig
+	limiter       limiter
+	config        throttlingConfig
+	adaptiveLimit *adaptiveLimit // nil unless adaptive throttling is enabled
 }
 
 // CheckLimitReached returns true if we're not allowed to process the number of events we asked for with cost.
 func (t *Throttler) CheckLimitReached(key string, cost int64) (limited bool, retErr error) {
-	if !t.config.enabled {
+	limit, window := t.config.limit, t.config.window
+	if t.adaptiveLimit != nil {
+		// the key is kept as is, the limiters taking the current limit into account on every call
+		limit, window = t.adaptiveLimit.get(), t.config.adaptive.window
+	} else if !t.config.enabled {
 		return false, nil
 	}
 
 	ctx := context.TODO()
-	allowed, _, err := t.limiter.Allow(ctx, cost, t.config.limit, getWindowInSecs(t.config.window), key)
+	allowed, _, err := t.limiter.Allow(ctx, cost, limit, getWindowInSecs(window), key)
 	if err != nil {
 		return false, fmt.Errorf("could not limit: %w", err)
 	}
@@ -128,10 +144,18 @@ func (t *Throttler) CheckLimitReached(key string, cost int64) (limited bool, ret
 	return false, nil
 }
 
+// ResponseReceived adapts the limit of the destination to one of its responses, if adaptive throttling is enabled
+func (t *Throttler) ResponseReceived(statusCode int, latency time.Duration) {
+	if t.adaptiveLimit != nil {
+		t.adaptiveLimit.responseReceived(statusCode, latency)
+	}
+}
+
 type throttlingConfig struct {
-	enabled bool
-	limit   int64
-	window  time.Duration
+	enabled  bool
+	limit    int64
+	window   time.Duration
+	adaptive adaptiveConfig
 }
 
 func (c *throttlingConfig) readThrottlingConfig(destName, destID string) {
@@ -151,6 +175,8 @@ func (c *throttlingConfig) readThrottlingConfig(destName, destID string) {
 	if c.limit > 0 && c.window > 0 {
 		c.enabled = true
 	}
+
+	c.adaptive.readAdaptiveConfig(destName, destID, c)
 }
 
 func getWindowInSecs(d time.Duration) int64 {
@@ -0,0 +1,132 @@
+package router
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/router/types"
+	destinationdebugger "github.com/rudderlabs/rudder-server/services/debugger/destination"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Events failing their router or batch transformation are sampled into the destination debugger along with the
+// transformer's error, so that the UI shows why a destination is erroring. At most
+// Router.<destType>.transformationErrorSamplesPerMinute events are sampled per destination and minute, the rest being
+// only counted in router_transformation_error_samples_dropped. Values of the sampled payloads whose keys are listed in
+// Router.transformationErrorSampling.redactedKeys, case insensitively and at any depth, are replaced by redactedValue.
+
+const redactedValue = "[REDACTED]"
+
+// transformErrorSampleWindow counts the samples of a destination in the minute starting at start
+type transformErrorSampleWindow struct {
+	start time.Time
+	count int
+}
+
+// allowTransformErrorSample reports whether another transformation error of the destination may be sampled at now
+func (rt *HandleT) allowTransformErrorSample(destinationID string, now time.Time) bool {
+	if rt.transformErrorSamplesPerMinute <= 0 {
+		return false
+	}
+	rt.transformErrorSamplesMu.Lock()
+	defer rt.transformErrorSamplesMu.Unlock()
+	if rt.transformErrorSamples == nil {
+		rt.transformErrorSamples = make(map[string]*transformErrorSampleWindow)
+	}
+	window, ok := rt.transformErrorSamples[destinationID]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		window = &transformErrorSampleWindow{start: now}
+		rt.transformErrorSamples[destinationID] = window
+	}
+	if window.count >= rt.transformErrorSamplesPerMinute {
+		return false
+	}
+	window.count++
+	return true
+}
+
+// sampleTransformationError records a redacted sample of the payload failing its transformation, along with the
+// transformer's error, into the destination debugger
+func (worker *workerT) sampleTransformationError(payload json.RawMessage, destinationJob *types.DestinationJobT,
+	destinationJobMetadata *types.JobMetadataT, status *jobsdb.JobStatusT, sourceIDs []string,
+) {
+	destinationID := destinationJobMetadata.DestinationID
+	if !worker.rt.allowTransformErrorSample(destinationID, time.Now()) {
+		stats.Default.NewTaggedStat("router_transformation_error_samples_dropped", stats.CountType, stats.Tags{
+			"destType": worker.rt.destName,
+			"destID":   destinationID,
+		}).Count(1)
+		return
+	}
+	// the error response may hold the whole payload if Router.savePayloadOnError is enabled
+	errorResponse, _ := sjson.DeleteBytes(status.ErrorResponse, "payload")
+	errorResponse = misc.UpdateJSONWithNewKeyVal(errorResponse, "error", destinationJob.Error)
+	deliveryStatus := destinationdebugger.DeliveryStatusT{
+		DestinationID: destinationID,
+		SourceID:      strings.Join(sourceIDs, ","),
+		Payload:       redactPayload(payload, redactedKeys()),
+		AttemptNum:    status.AttemptNum,
+		JobState:      status.JobState,
+		ErrorCode:     status.ErrorCode,
+		ErrorResponse: errorResponse,
+		ErrorAt:       destinationdebugger.ErrorAtTransformation,
+		SentAt:        status.ExecTime.Format(misc.RFC3339Milli),
+		EventName:     gjson.GetBytes(destinationJobMetadata.JobT.Parameters, "event_name").String(),
+		EventType:     gjson.GetBytes(destinationJobMetadata.JobT.Parameters, "event_type").String(),
+		MessageID:     gjson.GetBytes(destinationJobMetadata.JobT.Parameters, "message_id").String(),
+	}
+	destinationdebugger.RecordEventDeliveryStatus(destinationID, &deliveryStatus)
+}
+
+// redactedKeys returns the lower cased keys whose values are redacted from sampled payloads
+func redactedKeys() map[string]struct{} {
+	keys := make(map[string]struct{})
+	for _, key := range strings.Split(transformErrorRedactedKeys, ",") {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			keys[key] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// redactPayload returns the payload with the values of the keys replaced by redactedValue, or a redacted value
+// altogether if it isn't valid JSON
+func redactPayload(payload json.RawMessage, keys map[string]struct{}) json.RawMessage {
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		redacted, _ := json.Marshal(redactedValue)
+		return redacted
+	}
+	redacted, err := json.Marshal(redactValue(value, keys))
+	if err != nil {
+		redacted, _ = json.Marshal(redactedValue)
+	}
+	return redacted
+}
+
+func redactValue(value interface{}, keys map[string]struct{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			if _, ok := keys[strings.ToLower(key)]; ok {
+				value[key] = redactedValue
+				continue
+			}
+			value[key] = redactValue(nested, keys)
+		}
+		return value
+	case []interface{}:
+		for i, nested := range value {
+			value[i] = redactValue(nested, keys)
+		}
+		return value
+	default:
+		return value
+	}
+}
@@ -0,0 +1,30 @@
+package router
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowTransformErrorSample(t *testing.T) {
+	rt := &HandleT{transformErrorSamplesPerMinute: 2}
+	now := time.Now()
+	require.True(t, rt.allowTransformErrorSample("destination", now))
+	require.True(t, rt.allowTransformErrorSample("destination", now.Add(time.Second)))
+	require.False(t, rt.allowTransformErrorSample("destination", now.Add(2*time.Second)))
+	require.True(t, rt.allowTransformErrorSample("another-destination", now), "samples are limited per destination")
+	require.True(t, rt.allowTransformErrorSample("destination", now.Add(time.Minute)))
+
+	rt = &HandleT{}
+	require.False(t, rt.allowTransformErrorSample("destination", now), "sampling is disabled without samples per minute")
+}
+
+func TestRedactPayload(t *testing.T) {
+	keys := map[string]struct{}{"email": {}, "ip": {}}
+	redacted := redactPayload(json.RawMessage(`{"event":"Signed Up","context":{"IP":"1.2.3.4","traits":{"email":"user@example.com","plan":"pro"}},"products":[{"email":"other@example.com"}]}`), keys)
+	require.JSONEq(t, `{"event":"Signed Up","context":{"IP":"[REDACTED]","traits":{"email":"[REDACTED]","plan":"pro"}},"products":[{"email":"[REDACTED]"}]}`, string(redacted))
+	require.JSONEq(t, `"[REDACTED]"`, string(redactPayload(json.RawMessage(`not json`), keys)))
+	require.JSONEq(t, `null`, string(redactPayload(json.RawMessage(`null`), keys)))
+}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"math"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -42,6 +45,10 @@ type SendPostResponse struct {
 	StatusCode          int
 	ResponseContentType string
 	ResponseBody        []byte
+	// RetryAfter is the time the destination asked to wait for before sending further requests, 0 if none
+	RetryAfter time.Duration
+	// RequestBodyHash is the hex encoded SHA-256 of the request body sent to the destination, empty if none was
+	RequestBodyHash string
 }
 
 func Init() {
@@ -99,3 +106,40 @@ func EnhanceJSON(rawMsg []byte, key, val string) []byte {
 func IsNotEmptyString(s string) bool {
 	return len(strings.TrimSpace(s)) > 0
 }
+
+// retryAfterHeaders are the headers telling when a throttled client may retry, in order of precedence
+var retryAfterHeaders = []string{"Retry-After", "RateLimit-Reset", "X-RateLimit-Reset", "X-Rate-Limit-Reset"}
+
+// ParseRetryAfter returns the time to wait for before retrying, as told by the headers of a response, 0 if none.
+// Retry-After is either a number of seconds or an http date, while the rate limit reset headers are either a number
+// of seconds or a unix timestamp, in seconds or milliseconds.
+func ParseRetryAfter(header http.Header, now time.Time) time.Duration {
+	for _, name := range retryAfterHeaders {
+		value := strings.TrimSpace(header.Get(name))
+		if value == "" {
+			continue
+		}
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(seconds) && !math.IsInf(seconds, 0) {
+			var d time.Duration
+			switch {
+			case seconds >= 1e12: // unix timestamp in milliseconds
+				d = time.UnixMilli(int64(seconds)).Sub(now)
+			case seconds >= 1e9: // unix timestamp in seconds
+				d = time.Unix(int64(seconds), 0).Sub(now)
+			default:
+				d = time.Duration(seconds * float64(time.Second))
+			}
+			if d < 0 {
+				return 0
+			}
+			return d
+		}
+		if t, err := http.ParseTime(value); err == nil {
+			if d := t.Sub(now); d > 0 {
+				return d
+			}
+			return 0
+		}
+	}
+	return 0
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 10, 10, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		header   http.Header
+		expected time.Duration
+	}{
+		{name: "no header", header: http.Header{}, expected: 0},
+		{name: "retry-after seconds", header: http.Header{"Retry-After": []string{"120"}}, expected: 2 * time.Minute},
+		{name: "retry-after date", header: http.Header{"Retry-After": []string{"Mon, 10 Oct 2022 10:00:30 GMT"}}, expected: 30 * time.Second},
+		{name: "retry-after date in the past", header: http.Header{"Retry-After": []string{"Mon, 10 Oct 2022 09:00:00 GMT"}}, expected: 0},
+		{name: "rate limit reset seconds", header: http.Header{"Ratelimit-Reset": []string{"1.5"}}, expected: 1500 * time.Millisecond},
+		{name: "rate limit reset timestamp", header: http.Header{"X-Ratelimit-Reset": []string{"1665396060"}}, expected: time.Minute},
+		{name: "rate limit reset timestamp in milliseconds", header: http.Header{"X-Rate-Limit-Reset": []string{"1665396010000"}}, expected: 10 * time.Second},
+		{name: "retry-after takes precedence", header: http.Header{"Retry-After": []string{"5"}, "X-Ratelimit-Reset": []string{"1665396060"}}, expected: 5 * time.Second},
+		{name: "invalid value", header: http.Header{"Retry-After": []string{"soon"}}, expected: 0},
+		{name: "negative value", header: http.Header{"Retry-After": []string{"-5"}}, expected: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, ParseRetryAfter(tc.header, now))
+		})
+	}
+}
@@ -21,17 +21,19 @@ import (
 //	  	rt.workerProcess(worker)
 //	})
 func Go(function func()) {
+	module := callerModule()
 	go func() {
 		ctx := bugsnag.StartSession(context.Background())
 		defer misc.BugsnagNotify(ctx, "Core")()
-		function()
+		runInModule(ctx, module, function)
 	}()
 }
 
 func GoForWarehouse(function func()) {
+	module := callerModule()
 	go func() {
 		ctx := bugsnag.StartSession(context.Background())
 		defer misc.BugsnagNotify(ctx, "Warehouse")()
-		function()
+		runInModule(ctx, module, function)
 	}()
 }
@@ -0,0 +1,140 @@
+package rruntime
+
+import (
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Goroutines started by Go and GoForWarehouse belong to the module of their caller, the package it's in relative to
+// the server, e.g. router/batchrouter. They carry their module in the module pprof label, so that goroutine profiles
+// can be broken down by module, and are counted per module.
+//
+// Modules also register their worker pools, e.g. the workers of a router, marking workers busy while they work, so
+// that the utilization of every pool can be reported.
+
+const (
+	serverModulePath = "github.com/rudderlabs/rudder-server/"
+	unknownModule    = "unknown"
+)
+
+var (
+	callerModules     sync.Map // pc -> module
+	moduleGoroutines  sync.Map // module -> *int64
+	pools             sync.Map // module/name -> *Pool
+	registerPoolMutex sync.Mutex
+)
+
+// callerModule returns the module of the caller of the function calling callerModule
+func callerModule() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return unknownModule
+	}
+	if module, ok := callerModules.Load(pc); ok {
+		return module.(string)
+	}
+	module := unknownModule
+	if f := runtime.FuncForPC(pc); f != nil {
+		module = packageOf(f.Name())
+	}
+	callerModules.Store(pc, module)
+	return module
+}
+
+// packageOf returns the package of a function name, relative to the server, e.g. router/batchrouter for
+// github.com/rudderlabs/rudder-server/router/batchrouter.(*HandleT).Setup.func3
+func packageOf(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		function = function[:slash+1+dot]
+	}
+	return strings.TrimPrefix(function, serverModulePath)
+}
+
+// runInModule runs function labeled and counted as a goroutine of module
+func runInModule(ctx context.Context, module string, function func()) {
+	count, _ := moduleGoroutines.LoadOrStore(module, new(int64))
+	atomic.AddInt64(count.(*int64), 1)
+	defer atomic.AddInt64(count.(*int64), -1)
+	pprof.Do(ctx, pprof.Labels("module", module), func(context.Context) {
+		function()
+	})
+}
+
+// Goroutines returns the number of running goroutines started by Go and GoForWarehouse per module
+func Goroutines() map[string]int64 {
+	goroutines := make(map[string]int64)
+	moduleGoroutines.Range(func(module, count any) bool {
+		if n := atomic.LoadInt64(count.(*int64)); n > 0 {
+			goroutines[module.(string)] = n
+		}
+		return true
+	})
+	return goroutines
+}
+
+// Pool is a pool of workers of a module
+type Pool struct {
+	module string
+	name   string
+	size   int64
+	busy   int64
+}
+
+// PoolStats are the workers of a pool, and how many of them are busy
+type PoolStats struct {
+	Module string
+	Name   string
+	Size   int64
+	Busy   int64
+}
+
+// RegisterPool registers the pool of size workers of the module. Registering a pool again resizes it.
+func RegisterPool(module, name string, size int) *Pool {
+	registerPoolMutex.Lock()
+	defer registerPoolMutex.Unlock()
+	key := module + "/" + name
+	if pool, ok := pools.Load(key); ok {
+		atomic.StoreInt64(&pool.(*Pool).size, int64(size))
+		return pool.(*Pool)
+	}
+	pool := &Pool{module: module, name: name, size: int64(size)}
+	pools.Store(key, pool)
+	return pool
+}
+
+// Busy marks a worker of the pool busy, until the returned function is called. Workers of a nil pool aren't tracked.
+func (p *Pool) Busy() (done func()) {
+	if p == nil {
+		return func() {}
+	}
+	atomic.AddInt64(&p.busy, 1)
+	return func() { atomic.AddInt64(&p.busy, -1) }
+}
+
+// Pools returns the stats of the registered pools, sorted by module and name
+func Pools() []PoolStats {
+	var stats []PoolStats
+	pools.Range(func(_, pool any) bool {
+		p := pool.(*Pool)
+		stats = append(stats, PoolStats{
+			Module: p.module,
+			Name:   p.name,
+			Size:   atomic.LoadInt64(&p.size),
+			Busy:   atomic.LoadInt64(&p.busy),
+		})
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Module != stats[j].Module {
+			return stats[i].Module < stats[j].Module
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
@@ -0,0 +1,25 @@
+package rruntime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageOf(t *testing.T) {
+	require.Equal(t, "router/batchrouter", packageOf("github.com/rudderlabs/rudder-server/router/batchrouter.(*HandleT).Setup.func3"))
+	require.Equal(t, "processor", packageOf("github.com/rudderlabs/rudder-server/processor.(*HandleT).Start"))
+	require.Equal(t, "main", packageOf("main.main"))
+}
+
+func TestPools(t *testing.T) {
+	pool := RegisterPool("router", "WEBHOOK", 2)
+	done := pool.Busy()
+	require.Contains(t, Pools(), PoolStats{Module: "router", Name: "WEBHOOK", Size: 2, Busy: 1})
+	done()
+	require.Same(t, pool, RegisterPool("router", "WEBHOOK", 3), "registering a pool again resizes it")
+	require.Contains(t, Pools(), PoolStats{Module: "router", Name: "WEBHOOK", Size: 3, Busy: 0})
+
+	var nilPool *Pool
+	nilPool.Busy()()
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/rudderlabs/rudder-server/admin/profiler"
 	"github.com/rudderlabs/rudder-server/app"
 	"github.com/rudderlabs/rudder-server/app/apphandlers"
+	"github.com/rudderlabs/rudder-server/app/cluster"
+	"github.com/rudderlabs/rudder-server/app/cluster/state"
 	"github.com/rudderlabs/rudder-server/config"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	eventschema "github.com/rudderlabs/rudder-server/event-schema"
@@ -45,17 +47,20 @@ import (
 	"github.com/rudderlabs/rudder-server/services/archiver"
 	"github.com/rudderlabs/rudder-server/services/controlplane"
 	"github.com/rudderlabs/rudder-server/services/db"
+	"github.com/rudderlabs/rudder-server/services/debugger"
 	destinationdebugger "github.com/rudderlabs/rudder-server/services/debugger/destination"
 	sourcedebugger "github.com/rudderlabs/rudder-server/services/debugger/source"
 	transformationdebugger "github.com/rudderlabs/rudder-server/services/debugger/transformation"
 	"github.com/rudderlabs/rudder-server/services/dedup"
 	destinationconnectiontester "github.com/rudderlabs/rudder-server/services/destination-connection-tester"
 	"github.com/rudderlabs/rudder-server/services/diagnostics"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
 	"github.com/rudderlabs/rudder-server/services/multitenant"
 	"github.com/rudderlabs/rudder-server/services/oauth"
 	"github.com/rudderlabs/rudder-server/services/pgnotifier"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/services/streammanager/kafka"
+	"github.com/rudderlabs/rudder-server/services/tracing"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/types/deployment"
@@ -129,6 +134,11 @@ func New(releaseInfo ReleaseInfo) *Runner {
 
 // Run runs the application and returns the exit code
 func (r *Runner) Run(ctx context.Context, args []string) int {
+	if r.appType == app.ALL_IN_ONE {
+		// the warehouse master and slaves run in the process of rudder core, sharing its config and database
+		config.Set("Warehouse.mode", config.EmbeddedMode)
+		r.warehouseMode = config.EmbeddedMode
+	}
 	runAllInit()
 
 	options := app.LoadOptions(args)
@@ -177,6 +187,17 @@ func (r *Runner) Run(ctx context.Context, args []string) int {
 		config.Set("statsExcludedTags", []string{"workspaceId", "sourceID", "destId"})
 	}
 	stats.Default.Start(ctx)
+	stopTracing, err := tracing.Start(ctx, config.Default, r.logger.Child("tracing"))
+	if err != nil {
+		r.logger.Errorf("Failed to start tracing: %v", err)
+		return 1
+	}
+	defer stopTracing()
+	admin.RegisterHTTPHandler("/stats/cardinality", stats.CardinalityHandler(stats.Default))
+	admin.RegisterHTTPHandler("/stats/exemplars", stats.ExemplarsHandler(stats.Default))
+	admin.RegisterHTTPHandler("/stats/toggles", stats.TogglesHandler(stats.Default))
+	admin.RegisterHTTPHandler("/debugger/destination/statuses", destinationdebugger.QueryHandler())
+	admin.RegisterHTTPHandler("/debugger/sessions", debugger.SessionsHandler(debugger.DefaultSessions))
 	stats.Default.NewTaggedStat("rudder_server_config",
 		stats.GaugeType,
 		stats.Tags{
@@ -235,11 +256,28 @@ func (r *Runner) Run(ctx context.Context, args []string) int {
 		return nil
 	})
 
+	// Evict the object storage throttling budgets of workspaces no longer in the backend config
+	if r.canStartBackendConfig() {
+		g.Go(func() error {
+			filemanager.PruneBudgets(ctx, backendconfig.DefaultBackendConfig)
+			return nil
+		})
+	}
+
 	misc.AppStartTime = time.Now().Unix()
 
+	// In all-in-one mode the warehouse stops last, once rudder core has, for the staging files the batch router posts
+	// to it while rudder core stops to be received. They are uploaded on the next start.
+	warehouseCtx, stopWarehouse := ctx, func() {}
+	if r.appType == app.ALL_IN_ONE && r.canStartServer() {
+		warehouseCtx, stopWarehouse = context.WithCancel(misc.WithoutCancel(ctx))
+	}
+	defer stopWarehouse()
+
 	// Start rudder core
 	if r.canStartServer() {
 		g.Go(misc.WithBugsnag(func() (err error) {
+			defer stopWarehouse()
 			if err := r.appHandler.StartRudderCore(ctx, options); err != nil {
 				return fmt.Errorf("rudder core: %w", err)
 			}
@@ -251,6 +289,8 @@ func (r *Runner) Run(ctx context.Context, args []string) int {
 			c := controlplane.NewClient(
 				config.GetString("CONFIG_BACKEND_URL", "https://api.rudderstack.com"),
 				backendconfig.DefaultBackendConfig.Identity(),
+				controlplane.WithRegion(config.GetString("region", "")),
+				controlplane.WithFailover(config.GetString("CONFIG_BACKEND_FAILOVER_URLS", "")),
 			)
 
 			err := c.SendFeatures(ctx, info.ServerComponent.Name, info.ServerComponent.Features)
@@ -267,13 +307,30 @@ func (r *Runner) Run(ctx context.Context, args []string) int {
 	// initialize warehouse service after core to handle non-normal recovery modes
 	if r.canStartWarehouse() {
 		g.Go(misc.WithBugsnagForWarehouse(func() error {
-			if err := warehouse.Start(ctx, r.application); err != nil {
+			if err := warehouse.Start(warehouseCtx, r.application); err != nil {
 				return fmt.Errorf("warehouse service routine: %w", err)
 			}
 			return nil
 		}))
 	}
 
+	// The mode of the warehouse running without rudder core is switched by its own cluster manager
+	if r.canStartWarehouse() && !r.canStartServer() && warehouse.DynamicModeEnabled() {
+		g.Go(misc.WithBugsnagForWarehouse(func() error {
+			dm := cluster.Dynamic{
+				Provider:           state.NewETCDDynamicProvider(),
+				WarehouseComponent: true,
+				WarehouseMaster:    warehouse.Master(),
+				WarehouseSlave:     warehouse.Slave(),
+				WarehouseMode:      r.warehouseMode,
+			}
+			if err := dm.Run(ctx); err != nil {
+				return fmt.Errorf("warehouse cluster manager: %w", err)
+			}
+			return nil
+		}))
+	}
+
 	shutdownDone := make(chan struct{})
 	go func() {
 		err := g.Wait()
@@ -417,5 +474,6 @@ func (r *Runner) canStartWarehouse() bool {
 }
 
 func (r *Runner) canStartBackendConfig() bool {
-	return r.warehouseMode != config.SlaveMode
+	// slaves can be promoted to master with dynamic mode enabled
+	return r.warehouseMode != config.SlaveMode || warehouse.DynamicModeEnabled()
 }
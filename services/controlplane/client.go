@@ -15,6 +15,7 @@ import (
 	"github.com/cenkalti/backoff"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	"github.com/rudderlabs/rudder-server/services/controlplane/identity"
+	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/httputil"
 )
 
@@ -56,6 +57,10 @@ type Client struct {
 	url      string
 	identity identity.Identifier
 	region   string
+
+	failoverEndpoints []Endpoint
+	failoverCooldown  time.Duration
+	failover          *Failover
 }
 
 type payloadSchema struct {
@@ -80,20 +85,27 @@ func NewClient(baseURL string, identity identity.Identifier, fns ...OptFn) *Clie
 		url:      baseURL,
 		identity: identity,
 
-		client: &http.Client{
+		client: stats.InstrumentHTTPClient(stats.Default, &http.Client{
 			Timeout: defaultTimeout,
-		},
-		retries: defaultMaxRetries,
-		ua:      fmt.Sprintf("Go-http-client/1.1; %s; control-plane/features; %s", runtime.Version(), hostname()),
+		}, stats.Tags{"module": "controlplane"}),
+		retries:          defaultMaxRetries,
+		ua:               fmt.Sprintf("Go-http-client/1.1; %s; control-plane/features; %s", runtime.Version(), hostname()),
+		failoverCooldown: defaultFailoverCooldown,
 	}
 
 	for _, fn := range fns {
 		fn(c)
 	}
+	c.failover = NewFailover(c.client, c.url, c.region, c.failoverEndpoints, c.failoverCooldown)
 
 	return c
 }
 
+// MonitorHealth checks the health of the unhealthy control planes every interval, until ctx is done
+func (c *Client) MonitorHealth(ctx context.Context, interval time.Duration) {
+	c.failover.MonitorHealth(ctx, interval)
+}
+
 type PerComponent = map[string][]string
 
 func (c *Client) retry(ctx context.Context, fn func() error) error {
@@ -107,13 +119,13 @@ func (c *Client) retry(ctx context.Context, fn func() error) error {
 }
 
 func (c *Client) SendFeatures(ctx context.Context, component string, features []string) error {
-	var url string
+	var path string
 
 	switch t := c.identity.(type) {
 	case *identity.Namespace:
-		url = fmt.Sprintf("%s/data-plane/v1/namespaces/%s/settings", c.url, c.identity.ID())
+		path = fmt.Sprintf("/data-plane/v1/namespaces/%s/settings", c.identity.ID())
 	case *identity.Workspace:
-		url = fmt.Sprintf("%s/data-plane/v1/workspaces/%s/settings", c.url, c.identity.ID())
+		path = fmt.Sprintf("/data-plane/v1/workspaces/%s/settings", c.identity.ID())
 	default:
 		return fmt.Errorf("identity not supported %T", t)
 	}
@@ -133,17 +145,18 @@ func (c *Client) SendFeatures(ctx context.Context, component string, features []
 	}
 
 	return c.retry(ctx, func() error {
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-		if err != nil {
-			return fmt.Errorf("new request: %w", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("User-Agent", c.ua)
+		resp, err := c.failover.Do(ctx, func(baseURL string) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+path, bytes.NewReader(body))
+			if err != nil {
+				return nil, fmt.Errorf("new request: %w", err)
+			}
 
-		req.SetBasicAuth(c.identity.BasicAuth())
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", c.ua)
 
-		resp, err := c.client.Do(req)
+			req.SetBasicAuth(c.identity.BasicAuth())
+			return req, nil
+		})
 		if err != nil {
 			return fmt.Errorf("doing http request: %w", err)
 		}
@@ -167,7 +180,7 @@ func (c *Client) SendFeatures(ctx context.Context, component string, features []
 }
 
 func (c *Client) DestinationHistory(ctx context.Context, revisionID string) (backendconfig.DestinationT, error) {
-	urlStr := fmt.Sprintf("%s/workspaces/destinationHistory/%s", c.url, revisionID)
+	urlStr := fmt.Sprintf("/workspaces/destinationHistory/%s", revisionID)
 
 	urlValues := url.Values{}
 	if c.region != "" {
@@ -180,17 +193,18 @@ func (c *Client) DestinationHistory(ctx context.Context, revisionID string) (bac
 
 	var destination backendconfig.DestinationT
 	err := c.retry(ctx, func() error {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, http.NoBody)
-		if err != nil {
-			return err
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("User-Agent", c.ua)
+		resp, err := c.failover.Do(ctx, func(baseURL string) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+urlStr, http.NoBody)
+			if err != nil {
+				return nil, err
+			}
 
-		req.SetBasicAuth(c.identity.BasicAuth())
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", c.ua)
 
-		resp, err := c.client.Do(req)
+			req.SetBasicAuth(c.identity.BasicAuth())
+			return req, nil
+		})
 		if err != nil {
 			return err
 		}
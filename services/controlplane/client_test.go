@@ -342,3 +342,78 @@ func TestRetriesTimeout(t *testing.T) {
 		})
 	}
 }
+
+func TestFailover(t *testing.T) {
+	var primaryCount, usCount, euCount int64
+	primaryDown := int64(1)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			atomic.AddInt64(&primaryCount, 1)
+		}
+		switch {
+		case atomic.LoadInt64(&primaryDown) == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/health":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer primary.Close()
+	us := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&usCount, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer us.Close()
+	eu := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&euCount, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer eu.Close()
+
+	c := controlplane.NewClient(primary.URL, &identity.Namespace{},
+		controlplane.WithRegion("eu"),
+		controlplane.WithFailover(fmt.Sprintf("us=%s, eu=%s", us.URL, eu.URL)),
+		controlplane.WithFailoverCooldown(time.Hour),
+		controlplane.WithMaxRetries(0),
+	)
+	sendFeatures := func() {
+		require.NoError(t, c.SendFeatures(context.Background(), "test", []string{"feature1"}))
+	}
+
+	t.Run("requests fail over to the endpoints in the region of the client", func(t *testing.T) {
+		sendFeatures()
+		require.EqualValues(t, 1, atomic.LoadInt64(&primaryCount))
+		require.EqualValues(t, 1, atomic.LoadInt64(&euCount))
+		require.EqualValues(t, 0, atomic.LoadInt64(&usCount))
+	})
+
+	t.Run("unhealthy endpoints are tried last for the cooldown", func(t *testing.T) {
+		atomic.StoreInt64(&primaryDown, 0)
+		sendFeatures()
+		require.EqualValues(t, 1, atomic.LoadInt64(&primaryCount))
+		require.EqualValues(t, 2, atomic.LoadInt64(&euCount))
+	})
+
+	t.Run("endpoints are healthy again once their health check succeeds", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go c.MonitorHealth(ctx, 10*time.Millisecond)
+
+		time.Sleep(100 * time.Millisecond)
+		sendFeatures()
+		require.EqualValues(t, 2, atomic.LoadInt64(&primaryCount))
+		require.EqualValues(t, 2, atomic.LoadInt64(&euCount))
+	})
+
+	t.Run("requests never fail over to the endpoints of other regions", func(t *testing.T) {
+		atomic.StoreInt64(&primaryDown, 1)
+		c := controlplane.NewClient(primary.URL, &identity.Namespace{},
+			controlplane.WithRegion("eu"),
+			controlplane.WithFailover(fmt.Sprintf("us=%s, %s", us.URL, us.URL)),
+			controlplane.WithMaxRetries(1),
+		)
+		require.Error(t, c.SendFeatures(context.Background(), "test", []string{"feature1"}))
+		require.EqualValues(t, 0, atomic.LoadInt64(&usCount))
+	})
+}
@@ -0,0 +1,194 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/utils/httputil"
+)
+
+// Clients of the control plane can fail over to other control planes of their region, for the reporting and internal
+// APIs to survive an outage of the control plane:
+//   - failover endpoints are comma separated base urls optionally prefixed by their region, set by WithFailover
+//   - requests are sent to the base url first, then to the failover endpoints in the region of the client, until one
+//     of them responds with a non retriable status. Endpoints of other regions are never sent requests, so that data
+//     doesn't leave the region it resides in. Endpoints without a region are in the region of clients without one.
+//   - endpoints failing to respond, or responding with a retriable status, are unhealthy for the failover cooldown,
+//     tried last meanwhile, unless MonitorHealth finds them healthy again through their /health endpoint
+
+var defaultFailoverCooldown = 30 * time.Second
+
+// Endpoint is a control plane the client sends requests to
+type Endpoint struct {
+	URL    string
+	Region string
+}
+
+type endpoint struct {
+	Endpoint
+	unhealthyUntil time.Time
+}
+
+// ParseEndpoints parses comma separated base urls optionally prefixed by their region, e.g.
+// eu=https://api.eu.rudderstack.com,https://api.rudderstack.com
+func ParseEndpoints(endpoints string) []Endpoint {
+	var parsed []Endpoint
+	for _, e := range strings.Split(endpoints, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		var region string
+		if i := strings.Index(e, "="); i >= 0 {
+			region, e = e[:i], e[i+1:]
+		}
+		parsed = append(parsed, Endpoint{URL: e, Region: region})
+	}
+	return parsed
+}
+
+// WithFailover sets the endpoints the client fails over to, as parsed by ParseEndpoints
+func WithFailover(endpoints string) OptFn {
+	return func(c *Client) {
+		c.failoverEndpoints = ParseEndpoints(endpoints)
+	}
+}
+
+// WithFailoverCooldown sets for how long endpoints failing to respond are tried last
+func WithFailoverCooldown(cooldown time.Duration) OptFn {
+	return func(c *Client) {
+		c.failoverCooldown = cooldown
+	}
+}
+
+// Failover sends requests to the control plane of a client, failing over to the other control planes of its region
+type Failover struct {
+	client   *http.Client
+	cooldown time.Duration
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+}
+
+// NewFailover returns a Failover sending requests with the client to the base url, failing over to the endpoints in
+// the region, the ones of other regions being left out. Endpoints failing to respond are tried last for the cooldown.
+func NewFailover(client *http.Client, baseURL, region string, failover []Endpoint, cooldown time.Duration) *Failover {
+	f := &Failover{
+		client:    client,
+		cooldown:  cooldown,
+		endpoints: []*endpoint{{Endpoint: Endpoint{URL: baseURL, Region: region}}},
+	}
+	for _, e := range failover {
+		if e.Region != region {
+			continue
+		}
+		f.endpoints = append(f.endpoints, &endpoint{Endpoint: e})
+	}
+	return f
+}
+
+// preferredEndpoints returns the endpoints to send requests to, by preference, the unhealthy ones last
+func (f *Failover) preferredEndpoints() []*endpoint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	endpoints := append([]*endpoint{}, f.endpoints...)
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return !now.Before(endpoints[i].unhealthyUntil) && now.Before(endpoints[j].unhealthyUntil)
+	})
+	return endpoints
+}
+
+// setHealthy sets whether the endpoint is healthy, unhealthy ones being so for the cooldown
+func (f *Failover) setHealthy(e *endpoint, healthy bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if healthy {
+		e.unhealthyUntil = time.Time{}
+		return
+	}
+	e.unhealthyUntil = time.Now().Add(f.cooldown)
+}
+
+// Do sends the request built for the base url of each endpoint, by preference, until one of them responds with a
+// non retriable status, returning the last response or error
+func (f *Failover) Do(ctx context.Context, newRequest func(baseURL string) (*http.Request, error)) (*http.Response, error) {
+	endpoints := f.preferredEndpoints()
+	var (
+		resp *http.Response
+		err  error
+	)
+	for i, e := range endpoints {
+		req, reqErr := newRequest(e.URL)
+		if reqErr != nil {
+			return nil, reqErr
+		}
+		resp, err = f.client.Do(req)
+		if err == nil && !httputil.RetriableStatus(resp.StatusCode) {
+			f.setHealthy(e, true)
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return resp, err
+		}
+		f.setHealthy(e, false)
+		if i < len(endpoints)-1 && resp != nil {
+			// the response of the last endpoint is returned, the others are discarded
+			httputil.CloseResponse(resp)
+		}
+	}
+	return resp, err
+}
+
+// MonitorHealth checks the health of the unhealthy endpoints every interval, until ctx is done
+func (f *Failover) MonitorHealth(ctx context.Context, interval time.Duration) {
+	if len(f.endpoints) < 2 {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+			f.checkHealth(ctx)
+		}
+	}
+}
+
+// checkHealth marks the unhealthy endpoints responding to /health healthy again
+func (f *Failover) checkHealth(ctx context.Context) {
+	now := time.Now()
+	for _, e := range f.preferredEndpoints() {
+		f.mu.Lock()
+		unhealthy := now.Before(e.unhealthyUntil)
+		f.mu.Unlock()
+		if !unhealthy {
+			continue
+		}
+		if err := f.probe(ctx, e.URL); err == nil {
+			f.setHealthy(e, true)
+		}
+	}
+}
+
+// probe returns an error unless the endpoint with the base url responds to /health with 200 OK
+func (f *Failover) probe(ctx context.Context, baseURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/health", baseURL), http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { httputil.CloseResponse(resp) }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
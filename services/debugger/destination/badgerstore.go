@@ -0,0 +1,169 @@
+package destinationdebugger
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/badger/v3/options"
+
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// badgerStore stores the delivery statuses keyed by their destination, the time they were sent at and a unique
+// suffix, so that the statuses of a destination are sorted by time. A time index, keyed by the time and the same
+// suffix, sorts the statuses of every destination by time. Statuses and their index entries expire after the
+// retention through their TTL.
+type badgerStore struct {
+	db        *badger.DB
+	retention time.Duration
+}
+
+// badgerTimeIndexPrefix prefixes the keys of the time index, whose values are the keys of the statuses
+var badgerTimeIndexPrefix = []byte("\x00time/")
+
+type badgerLogger struct {
+	logger.Logger
+}
+
+func (l badgerLogger) Warningf(fmt string, args ...interface{}) {
+	l.Warnf(fmt, args...)
+}
+
+func newBadgerStore(path string, retention time.Duration, log logger.Logger) (*badgerStore, error) {
+	opts := badger.
+		DefaultOptions(path).
+		WithLogger(badgerLogger{log}).
+		WithCompression(options.None).
+		WithIndexCacheSize(16 << 20). // 16mb
+		WithNumGoroutines(1)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("opening badger db at %s: %w", path, err)
+	}
+	return &badgerStore{db: db, retention: retention}, nil
+}
+
+// badgerKey returns the key with the prefix of a status sent at the time, with the suffix
+func badgerKey(prefix []byte, sentAt time.Time, suffix []byte) []byte {
+	key := make([]byte, 0, len(prefix)+8+len(suffix))
+	key = append(key, prefix...)
+	key = binary.BigEndian.AppendUint64(key, uint64(sentAt.UnixNano()))
+	return append(key, suffix...)
+}
+
+// badgerPrefix returns the prefix of the keys of the statuses of the destination, or of the time index if empty
+func badgerPrefix(destinationID string) []byte {
+	if destinationID == "" {
+		return badgerTimeIndexPrefix
+	}
+	return append([]byte(destinationID), '/')
+}
+
+func (s *badgerStore) Record(_ context.Context, statuses []*DeliveryStatusT) error {
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, status := range statuses {
+		value, err := json.Marshal(status)
+		if err != nil {
+			return fmt.Errorf("marshalling delivery status: %w", err)
+		}
+		id := misc.FastUUID()
+		sentAt := sentAtTime(status)
+		key := badgerKey(append([]byte(status.DestinationID), '/'), sentAt, id[:])
+		if err := wb.SetEntry(badger.NewEntry(key, value).WithTTL(s.retention)); err != nil {
+			return fmt.Errorf("adding delivery status to write batch: %w", err)
+		}
+		indexKey := badgerKey(badgerTimeIndexPrefix, sentAt, id[:])
+		if err := wb.SetEntry(badger.NewEntry(indexKey, key).WithTTL(s.retention)); err != nil {
+			return fmt.Errorf("adding delivery status time index to write batch: %w", err)
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return fmt.Errorf("flushing write batch: %w", err)
+	}
+	return nil
+}
+
+func (s *badgerStore) Query(ctx context.Context, query StatusQuery) ([]*DeliveryStatusT, error) {
+	var statuses []*DeliveryStatusT
+	err := s.db.View(func(txn *badger.Txn) error {
+		// the statuses of a destination are iterated through their keys, the ones of every destination through
+		// the time index, both from the most recent
+		prefix := badgerPrefix(query.DestinationID)
+		opts := badger.DefaultIteratorOptions
+		opts.Reverse = true
+		opts.Prefix = prefix
+		to := time.Unix(0, math.MaxInt64)
+		if !query.To.IsZero() {
+			to = query.To
+		}
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		// every key of a status sent at or before the time sorts before its key with the largest suffix
+		for it.Seek(badgerKey(prefix, to, []byte{0xff})); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item := it.Item()
+			if query.DestinationID == "" {
+				key, err := item.ValueCopy(nil)
+				if err != nil {
+					return fmt.Errorf("reading delivery status time index: %w", err)
+				}
+				if item, err = txn.Get(key); err != nil {
+					if errors.Is(err, badger.ErrKeyNotFound) {
+						// expired before its index entry
+						continue
+					}
+					return fmt.Errorf("getting delivery status: %w", err)
+				}
+			}
+			var status DeliveryStatusT
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &status)
+			}); err != nil {
+				return fmt.Errorf("reading delivery status: %w", err)
+			}
+			sentAt := sentAtTime(&status)
+			if !query.From.IsZero() && sentAt.Before(query.From) {
+				break
+			}
+			if !query.matches(&status, sentAt) {
+				continue
+			}
+			statuses = append(statuses, &status)
+			if query.Limit > 0 && len(statuses) == query.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// Prune garbage collects the value log, the statuses expiring through their TTL
+func (s *badgerStore) Prune(context.Context, time.Time) error {
+	for {
+		// see https://dgraph.io/docs/badger/get-started/#garbage-collection
+		if err := s.db.RunValueLogGC(0.7); err != nil {
+			if errors.Is(err, badger.ErrNoRewrite) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (s *badgerStore) Close() error {
+	return s.db.Close()
+}
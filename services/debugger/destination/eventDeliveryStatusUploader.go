@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/rudderlabs/rudder-server/config"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
@@ -22,17 +23,31 @@ type DeliveryStatusT struct {
 	JobState      string          `json:"jobState"`
 	ErrorCode     string          `json:"errorCode"`
 	ErrorResponse json.RawMessage `json:"errorResponse"`
+	ErrorAt       string          `json:"errorAt,omitempty"`
 	SentAt        string          `json:"sentAt"`
 	EventName     string          `json:"eventName"`
 	EventType     string          `json:"eventType"`
+	MessageID     string          `json:"messageId,omitempty"`
+	PayloadHash   string          `json:"payloadHash,omitempty"`
+	ResponseBody  string          `json:"responseBody,omitempty"`
 }
 
+// ErrorAtTransformation is the ErrorAt of the delivery statuses of events failing their transformation
+const ErrorAtTransformation = "transformation"
+
 var (
-	uploadEnabledDestinationIDs map[string]bool
+	// uploadEnabledDestinationIDs are the destinations with eventDelivery enabled, by when it expires
+	uploadEnabledDestinationIDs map[string]time.Time
+	// workspaceIDsByDestinationID are the workspaces of the destinations, for their captures to be exported
+	workspaceIDsByDestinationID map[string]string
 	configSubscriberLock        sync.RWMutex
+	storeLock                   sync.RWMutex
 )
 
-var uploader debugger.Uploader[*DeliveryStatusT]
+var (
+	uploader debugger.Uploader[*DeliveryStatusT]
+	exporter *debugger.Exporter
+)
 
 var (
 	configBackendURL                  string
@@ -50,6 +65,8 @@ func Init() {
 func loadConfig() {
 	configBackendURL = config.GetString("CONFIG_BACKEND_URL", "https://api.rudderstack.com")
 	config.RegisterBoolConfigVariable(false, &disableEventDeliveryStatusUploads, true, "DestinationDebugger.disableEventDeliveryStatusUploads")
+	config.RegisterIntConfigVariable(4096, &maxResponseBodySize, true, 1, "DestinationDebugger.maxResponseBodySize")
+	loadStoreConfig()
 }
 
 type EventDeliveryStatusUploader struct{}
@@ -57,6 +74,9 @@ type EventDeliveryStatusUploader struct{}
 // RecordEventDeliveryStatus is used to put the delivery status in the deliveryStatusesBatchChannel,
 // which will be processed by handleJobs.
 func RecordEventDeliveryStatus(destinationID string, deliveryStatus *DeliveryStatusT) bool {
+	// persist the delivery status, whether it's uploaded or not
+	recordInStore(deliveryStatus)
+
 	// if disableEventDeliveryStatusUploads is true, return;
 	if disableEventDeliveryStatusUploads {
 		return false
@@ -71,14 +91,15 @@ func RecordEventDeliveryStatus(destinationID string, deliveryStatus *DeliverySta
 	}
 
 	uploader.RecordEvent(deliveryStatus)
+	exporter.Export(workspaceIDsByDestinationID[destinationID], deliveryStatus)
 	return true
 }
 
 func HasUploadEnabled(destID string) bool {
 	configSubscriberLock.RLock()
 	defer configSubscriberLock.RUnlock()
-	_, ok := uploadEnabledDestinationIDs[destID]
-	return ok
+	expiresAt, ok := uploadEnabledDestinationIDs[destID]
+	return ok && debugger.Unexpired(expiresAt) || debugger.DefaultSessions.Active(debugger.SessionKindDestination, destID)
 }
 
 // Setup initializes this module
@@ -87,6 +108,9 @@ func Setup(backendConfig backendconfig.BackendConfig) {
 	eventDeliveryStatusUploader := &EventDeliveryStatusUploader{}
 	uploader = debugger.New[*DeliveryStatusT](url, eventDeliveryStatusUploader)
 	uploader.Start()
+	exporter = debugger.NewExporter("destination", backendConfig)
+	exporter.Start()
+	setupStore()
 
 	rruntime.Go(func() {
 		backendConfigSubscriber(backendConfig)
@@ -116,15 +140,17 @@ func (*EventDeliveryStatusUploader) Transform(deliveryStatusesBuffer []*Delivery
 
 func updateConfig(config map[string]backendconfig.ConfigT) {
 	configSubscriberLock.Lock()
-	uploadEnabledDestinationIDs = make(map[string]bool)
+	uploadEnabledDestinationIDs = make(map[string]time.Time)
+	workspaceIDsByDestinationID = make(map[string]string)
 	var uploadEnabledDestinationIdsList []string
-	for _, wConfig := range config {
+	for workspaceID, wConfig := range config {
 		for _, source := range wConfig.Sources {
 			for _, destination := range source.Destinations {
+				workspaceIDsByDestinationID[destination.ID] = workspaceID
 				if destination.Config != nil {
-					if destination.Enabled && destination.Config["eventDelivery"] == true {
+					if expiresAt, ok := debugger.FlagExpiry(destination.Config, "eventDelivery"); destination.Enabled && ok && debugger.Unexpired(expiresAt) {
 						uploadEnabledDestinationIdsList = append(uploadEnabledDestinationIdsList, destination.ID)
-						uploadEnabledDestinationIDs[destination.ID] = true
+						uploadEnabledDestinationIDs[destination.ID] = expiresAt
 					}
 				}
 			}
@@ -11,6 +11,7 @@ import (
 	"github.com/rudderlabs/rudder-server/config"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	mocksBackendConfig "github.com/rudderlabs/rudder-server/mocks/config/backend-config"
+	"github.com/rudderlabs/rudder-server/services/debugger"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/pubsub"
 	testUtils "github.com/rudderlabs/rudder-server/utils/tests"
@@ -228,6 +229,12 @@ var _ = Describe("eventDeliveryStatusUploader", func() {
 			Expect(RecordEventDeliveryStatus(DestinationIDEnabledB, &deliveryStatus)).To(BeFalse())
 		})
 
+		It("enables uploads of destinations with a debugging session", func() {
+			debugger.DefaultSessions.Start(debugger.SessionKindDestination, DestinationIDEnabledB, time.Minute)
+			defer debugger.DefaultSessions.End(debugger.SessionKindDestination, DestinationIDEnabledB)
+			Expect(HasUploadEnabled(DestinationIDEnabledB)).To(BeTrue())
+		})
+
 		It("records events", func() {
 			eventuallyFunc := func() bool { return RecordEventDeliveryStatus(DestinationIDEnabledA, &deliveryStatus) }
 			Eventually(eventuallyFunc).Should(BeTrue())
@@ -0,0 +1,32 @@
+package destinationdebugger
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Delivery statuses of failed deliveries carry the SHA-256 of the request body sent on the wire, for them to be
+// correlated with the logs of the destination, and the body of the destination response, truncated to
+// DestinationDebugger.maxResponseBodySize bytes.
+
+var maxResponseBodySize int
+
+// CaptureFailure sets the hash of the request body sent to the destination, as computed by the network layer, and the
+// truncated response body of the delivery status of a failed delivery
+func CaptureFailure(deliveryStatus *DeliveryStatusT, payloadHash, responseBody string) {
+	deliveryStatus.PayloadHash = payloadHash
+	deliveryStatus.ResponseBody = truncateBody(responseBody, maxResponseBodySize)
+}
+
+// truncateBody returns the body, or up to its first size bytes followed by the number of bytes truncated if it's
+// larger, cutting it on a rune boundary for the truncated body to remain valid UTF-8
+func truncateBody(body string, size int) string {
+	if len(body) <= size {
+		return body
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...[%d bytes truncated]", body[:cut], len(body)-cut)
+}
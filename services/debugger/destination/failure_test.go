@@ -0,0 +1,31 @@
+package destinationdebugger
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureFailure(t *testing.T) {
+	maxResponseBodySize = 5
+	defer func() { maxResponseBodySize = 4096 }()
+
+	deliveryStatus := DeliveryStatusT{}
+	CaptureFailure(&deliveryStatus, "e2116f54", "bad request")
+	require.Equal(t, "e2116f54", deliveryStatus.PayloadHash)
+	require.Equal(t, "bad r...[6 bytes truncated]", deliveryStatus.ResponseBody)
+
+	deliveryStatus = DeliveryStatusT{}
+	CaptureFailure(&deliveryStatus, "", "error")
+	require.Empty(t, deliveryStatus.PayloadHash)
+	require.Equal(t, "error", deliveryStatus.ResponseBody)
+}
+
+func TestTruncateBody(t *testing.T) {
+	// "é" and "€" are encoded on 2 and 3 bytes respectively
+	require.Equal(t, "abé...[3 bytes truncated]", truncateBody("abé€", 4))
+	require.Equal(t, "ab...[5 bytes truncated]", truncateBody("abé€", 3))
+	require.Equal(t, "...[3 bytes truncated]", truncateBody("€", 2))
+	require.True(t, utf8.ValidString(truncateBody("€€€€", 7)))
+}
@@ -0,0 +1,165 @@
+package destinationdebugger
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// postgresStore stores the delivery statuses in the destination_debugger_statuses table, pruning them by the time
+// they were sent at in batches of pruneBatchSize rows
+type postgresStore struct {
+	db             *sql.DB
+	pruneBatchSize int
+}
+
+func newPostgresStore(dsn string, pruneBatchSize int) (*postgresStore, error) {
+	db, err := stats.OpenDB(stats.Default, "postgres", dsn, stats.Tags{"module": "debugger/destination"})
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres db: %w", err)
+	}
+	s := &postgresStore{db: db, pruneBatchSize: pruneBatchSize}
+	if err := s.migrate(context.Background()); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *postgresStore) migrate(ctx context.Context) error {
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS destination_debugger_statuses (
+			id BIGSERIAL PRIMARY KEY,
+			destination_id TEXT NOT NULL,
+			job_state TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			sent_at TIMESTAMPTZ NOT NULL,
+			status JSONB NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS destination_debugger_statuses_destination_id_sent_at ON destination_debugger_statuses (destination_id, sent_at)`,
+		`CREATE INDEX IF NOT EXISTS destination_debugger_statuses_message_id ON destination_debugger_statuses (message_id)`,
+		`CREATE INDEX IF NOT EXISTS destination_debugger_statuses_sent_at ON destination_debugger_statuses (sent_at)`,
+		// only failed deliveries carry a payload hash, hence the partial index
+		`CREATE INDEX IF NOT EXISTS destination_debugger_statuses_payload_hash ON destination_debugger_statuses ((status->>'payloadHash')) WHERE status->>'payloadHash' IS NOT NULL`,
+	} {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migrating destination debugger statuses table: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *postgresStore) Record(ctx context.Context, statuses []*DeliveryStatusT) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("destination_debugger_statuses", "destination_id", "job_state", "message_id", "sent_at", "status"))
+	if err != nil {
+		return fmt.Errorf("preparing copy: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	for _, status := range statuses {
+		value, err := json.Marshal(status)
+		if err != nil {
+			return fmt.Errorf("marshalling delivery status: %w", err)
+		}
+		if _, err := stmt.ExecContext(ctx, status.DestinationID, status.JobState, status.MessageID, sentAtTime(status), string(value)); err != nil {
+			return fmt.Errorf("copying delivery status: %w", err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("flushing copy: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return fmt.Errorf("closing copy: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *postgresStore) Query(ctx context.Context, query StatusQuery) ([]*DeliveryStatusT, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if query.DestinationID != "" {
+		where("destination_id = $%d", query.DestinationID)
+	}
+	if query.JobState != "" {
+		where("job_state = $%d", query.JobState)
+	}
+	if query.MessageID != "" {
+		where("message_id = $%d", query.MessageID)
+	}
+	if query.PayloadHash != "" {
+		where("status->>'payloadHash' = $%d", query.PayloadHash)
+	}
+	if !query.From.IsZero() {
+		where("sent_at >= $%d", query.From)
+	}
+	if !query.To.IsZero() {
+		where("sent_at <= $%d", query.To)
+	}
+	sqlStatement := `SELECT status FROM destination_debugger_statuses`
+	if len(conditions) > 0 {
+		sqlStatement += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	sqlStatement += ` ORDER BY sent_at DESC, id DESC`
+	if query.Limit > 0 {
+		sqlStatement += fmt.Sprintf(` LIMIT %d`, query.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, sqlStatement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying delivery statuses: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var statuses []*DeliveryStatusT
+	for rows.Next() {
+		var value []byte
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("scanning delivery status: %w", err)
+		}
+		var status DeliveryStatusT
+		if err := json.Unmarshal(value, &status); err != nil {
+			return nil, fmt.Errorf("unmarshalling delivery status: %w", err)
+		}
+		statuses = append(statuses, &status)
+	}
+	return statuses, rows.Err()
+}
+
+// Prune deletes the statuses sent before the time in batches, so that no single statement holds locks on, or
+// bloats the WAL with, every expired row
+func (s *postgresStore) Prune(ctx context.Context, before time.Time) error {
+	for {
+		res, err := s.db.ExecContext(ctx, `DELETE FROM destination_debugger_statuses WHERE id IN (
+			SELECT id FROM destination_debugger_statuses WHERE sent_at < $1 LIMIT $2)`, before, s.pruneBatchSize)
+		if err != nil {
+			return fmt.Errorf("pruning delivery statuses: %w", err)
+		}
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("getting pruned delivery statuses: %w", err)
+		}
+		if deleted < int64(s.pruneBatchSize) {
+			return nil
+		}
+	}
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,259 @@
+package destinationdebugger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Delivery statuses are only kept in memory until they're uploaded, unless a store is configured with
+// DestinationDebugger.store.type, in which case they're also persisted to it, so that they survive restarts and can be
+// queried after the fact through the admin interface:
+//   - badger, stores them in a badger database at DestinationDebugger.store.path
+//   - postgres, stores them in the jobs database
+//
+// Statuses are kept for DestinationDebugger.store.retention, and written asynchronously in batches, dropping them if
+// the store can't keep up rather than slowing down delivery.
+
+const (
+	storeTypeBadger   = "badger"
+	storeTypePostgres = "postgres"
+)
+
+var (
+	storeType          string
+	storePath          string
+	storeRetention     time.Duration
+	storeBufferSize    int
+	storeBatchSize     int
+	storeFlushInterval time.Duration
+	storePruneInterval time.Duration
+	storePruneBatch    int
+	storeMaxQueryLimit int
+
+	store        Store
+	storeChannel chan *DeliveryStatusT
+)
+
+// Store persists delivery statuses, keeping them until they're pruned
+type Store interface {
+	// Record stores the delivery statuses
+	Record(ctx context.Context, statuses []*DeliveryStatusT) error
+	// Query returns the stored delivery statuses matching the query, the most recent first
+	Query(ctx context.Context, query StatusQuery) ([]*DeliveryStatusT, error)
+	// Prune deletes the delivery statuses sent before the time
+	Prune(ctx context.Context, before time.Time) error
+	Close() error
+}
+
+// StatusQuery filters the delivery statuses returned by a store, its empty fields matching every status
+type StatusQuery struct {
+	DestinationID string
+	JobState      string
+	MessageID     string
+	PayloadHash   string
+	// From and To bound the time the statuses were sent at, inclusively
+	From, To time.Time
+	// Limit is the maximum number of statuses returned, all of them if not positive
+	Limit int
+}
+
+func (q *StatusQuery) matches(status *DeliveryStatusT, sentAt time.Time) bool {
+	return (q.DestinationID == "" || q.DestinationID == status.DestinationID) &&
+		(q.JobState == "" || q.JobState == status.JobState) &&
+		(q.MessageID == "" || q.MessageID == status.MessageID) &&
+		(q.PayloadHash == "" || q.PayloadHash == status.PayloadHash) &&
+		(q.From.IsZero() || !sentAt.Before(q.From)) &&
+		(q.To.IsZero() || !sentAt.After(q.To))
+}
+
+func loadStoreConfig() {
+	storeType = config.GetString("DestinationDebugger.store.type", "")
+	storePath = config.GetString("DestinationDebugger.store.path", "")
+	config.RegisterDurationConfigVariable(168, &storeRetention, false, time.Hour, "DestinationDebugger.store.retention")
+	config.RegisterIntConfigVariable(10000, &storeBufferSize, false, 1, "DestinationDebugger.store.bufferSize")
+	config.RegisterIntConfigVariable(100, &storeBatchSize, true, 1, "DestinationDebugger.store.batchSize")
+	config.RegisterDurationConfigVariable(1, &storeFlushInterval, true, time.Second, "DestinationDebugger.store.flushInterval")
+	config.RegisterDurationConfigVariable(1, &storePruneInterval, true, time.Hour, "DestinationDebugger.store.pruneInterval")
+	config.RegisterIntConfigVariable(10000, &storePruneBatch, false, 1, "DestinationDebugger.store.pruneBatchSize")
+	config.RegisterIntConfigVariable(1000, &storeMaxQueryLimit, true, 1, "DestinationDebugger.store.maxQueryLimit")
+}
+
+// openStore opens the configured store, nil if none is
+func openStore() (Store, error) {
+	switch storeType {
+	case "":
+		return nil, nil
+	case storeTypeBadger:
+		dir := storePath
+		if dir == "" {
+			tmpDir, err := misc.CreateTMPDIR()
+			if err != nil {
+				return nil, err
+			}
+			dir = path.Join(tmpDir, "destination-debugger")
+		}
+		return newBadgerStore(dir, storeRetention, pkgLogger)
+	case storeTypePostgres:
+		return newPostgresStore(misc.GetConnectionString(), storePruneBatch)
+	default:
+		return nil, fmt.Errorf("unknown destination debugger store type %q", storeType)
+	}
+}
+
+// setupStore opens the configured store, and starts writing the delivery statuses recorded to it and pruning them
+func setupStore() {
+	s, err := openStore()
+	if err != nil {
+		pkgLogger.Errorf("Failed to open the destination debugger store, delivery statuses won't be persisted: %v", err)
+		return
+	}
+	if s == nil {
+		return
+	}
+	statuses := make(chan *DeliveryStatusT, storeBufferSize)
+	rruntime.Go(func() {
+		writeToStore(s, statuses)
+	})
+	rruntime.Go(func() {
+		pruneStore(s)
+	})
+	storeLock.Lock()
+	store, storeChannel = s, statuses
+	storeLock.Unlock()
+}
+
+// recordInStore queues the delivery status to be written to the store, if one is configured
+func recordInStore(deliveryStatus *DeliveryStatusT) {
+	storeLock.RLock()
+	defer storeLock.RUnlock()
+	if storeChannel == nil {
+		return
+	}
+	select {
+	case storeChannel <- deliveryStatus:
+	default:
+		stats.Default.NewStat("destination_debugger_store_dropped", stats.CountType).Increment()
+	}
+}
+
+// writeToStore writes the delivery statuses to the store in batches, every flush interval or when a batch is full
+func writeToStore(s Store, statuses <-chan *DeliveryStatusT) {
+	batch := make([]*DeliveryStatusT, 0, storeBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.Record(context.TODO(), batch); err != nil {
+			pkgLogger.Errorf("Failed to write %d delivery statuses to the destination debugger store: %v", len(batch), err)
+			stats.Default.NewStat("destination_debugger_store_errors", stats.CountType).Increment()
+		}
+		batch = batch[:0]
+	}
+	ticker := time.NewTicker(storeFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case status, ok := <-statuses:
+			if !ok {
+				flush()
+				return
+			}
+			if batch = append(batch, status); len(batch) >= storeBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// pruneStore deletes the delivery statuses older than the retention from the store every prune interval
+func pruneStore(s Store) {
+	for {
+		time.Sleep(storePruneInterval)
+		if err := s.Prune(context.TODO(), time.Now().Add(-storeRetention)); err != nil {
+			pkgLogger.Errorf("Failed to prune the destination debugger store: %v", err)
+		}
+	}
+}
+
+// sentAtTime returns the time the delivery status was sent at, now if it can't be parsed
+func sentAtTime(status *DeliveryStatusT) time.Time {
+	sentAt, err := time.Parse(misc.RFC3339Milli, status.SentAt)
+	if err != nil {
+		return time.Now()
+	}
+	return sentAt
+}
+
+// QueryHandler serves the delivery statuses persisted to the store, filtered by the destinationId, jobState,
+// messageId, payloadHash, from and to (RFC3339 times) and limit query parameters
+func QueryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		storeLock.RLock()
+		s := store
+		storeLock.RUnlock()
+		if s == nil {
+			http.Error(w, "destination debugger store is not configured", http.StatusNotFound)
+			return
+		}
+		query, err := parseStatusQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		statuses, err := s.Query(r.Context(), query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if statuses == nil {
+			statuses = []*DeliveryStatusT{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(statuses)
+	})
+}
+
+func parseStatusQuery(r *http.Request) (StatusQuery, error) {
+	values := r.URL.Query()
+	query := StatusQuery{
+		DestinationID: values.Get("destinationId"),
+		JobState:      values.Get("jobState"),
+		MessageID:     values.Get("messageId"),
+		PayloadHash:   values.Get("payloadHash"),
+		Limit:         storeMaxQueryLimit,
+	}
+	var err error
+	if from := values.Get("from"); from != "" {
+		if query.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return query, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if to := values.Get("to"); to != "" {
+		if query.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return query, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return query, errors.New("invalid limit: must be a positive integer")
+		}
+		if n < query.Limit {
+			query.Limit = n
+		}
+	}
+	return query, nil
+}
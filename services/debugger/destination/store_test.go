@@ -0,0 +1,126 @@
+package destinationdebugger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/testhelper/destination"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// testStore records statuses of two destinations sent a minute apart and queries them
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond).UTC()
+	status := func(destinationID, jobState, messageID string, ago time.Duration) *DeliveryStatusT {
+		return &DeliveryStatusT{
+			DestinationID: destinationID,
+			JobState:      jobState,
+			MessageID:     messageID,
+			SentAt:        now.Add(-ago).Format(misc.RFC3339Milli),
+			Payload:       json.RawMessage(`{}`),
+			ErrorResponse: json.RawMessage(`{}`),
+		}
+	}
+	require.NoError(t, s.Record(ctx, []*DeliveryStatusT{
+		status("a", "succeeded", "m1", 3*time.Minute),
+		status("a", "failed", "m2", 2*time.Minute),
+		status("a", "succeeded", "m3", time.Minute),
+		status("b", "aborted", "m2", 90*time.Second),
+	}))
+
+	messageIDs := func(query StatusQuery) []string {
+		statuses, err := s.Query(ctx, query)
+		require.NoError(t, err)
+		ids := make([]string, 0, len(statuses))
+		for _, status := range statuses {
+			ids = append(ids, status.DestinationID+"/"+status.MessageID)
+		}
+		return ids
+	}
+	require.Equal(t, []string{"a/m3", "b/m2", "a/m2", "a/m1"}, messageIDs(StatusQuery{}))
+	require.Equal(t, []string{"a/m3", "a/m2", "a/m1"}, messageIDs(StatusQuery{DestinationID: "a"}))
+	require.Equal(t, []string{"a/m3", "a/m2"}, messageIDs(StatusQuery{DestinationID: "a", Limit: 2}))
+	require.Equal(t, []string{"a/m3", "b/m2"}, messageIDs(StatusQuery{Limit: 2}))
+	require.Equal(t, []string{"a/m3", "a/m1"}, messageIDs(StatusQuery{JobState: "succeeded"}))
+	require.Equal(t, []string{"b/m2", "a/m2"}, messageIDs(StatusQuery{MessageID: "m2"}))
+	require.Equal(t, []string{"a/m2"}, messageIDs(StatusQuery{DestinationID: "a", From: now.Add(-2 * time.Minute), To: now.Add(-2 * time.Minute)}))
+	require.Equal(t, []string{"b/m2", "a/m2"}, messageIDs(StatusQuery{From: now.Add(-2 * time.Minute), To: now.Add(-time.Minute - time.Second)}))
+
+	require.NoError(t, s.Prune(ctx, now.Add(-time.Hour)))
+	require.Len(t, messageIDs(StatusQuery{}), 4)
+}
+
+func TestBadgerStore(t *testing.T) {
+	s, err := newBadgerStore(t.TempDir(), time.Hour, logger.NOP)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, s.Close()) }()
+	testStore(t, s)
+}
+
+func TestPostgresStore(t *testing.T) {
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+	pgResource, err := destination.SetupPostgres(pool, t)
+	require.NoError(t, err)
+
+	s, err := newPostgresStore(pgResource.DBDsn, 2)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, s.Close()) }()
+	testStore(t, s)
+
+	require.NoError(t, s.Prune(context.Background(), time.Now()))
+	statuses, err := s.Query(context.Background(), StatusQuery{})
+	require.NoError(t, err)
+	require.Empty(t, statuses)
+}
+
+func TestQueryHandler(t *testing.T) {
+	get := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		QueryHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debugger/destination/statuses?"+query, http.NoBody))
+		return rr
+	}
+	require.Equal(t, http.StatusNotFound, get("").Code, "no store is configured")
+
+	s, err := newBadgerStore(t.TempDir(), time.Hour, logger.NOP)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, s.Close()) }()
+	storeLock.Lock()
+	store, storeMaxQueryLimit = s, 1
+	storeLock.Unlock()
+	defer func() {
+		storeLock.Lock()
+		store = nil
+		storeLock.Unlock()
+	}()
+
+	sentAt := time.Now().Format(misc.RFC3339Milli)
+	require.NoError(t, s.Record(context.Background(), []*DeliveryStatusT{
+		{DestinationID: "a", MessageID: "m1", SentAt: sentAt, Payload: json.RawMessage(`{}`), ErrorResponse: json.RawMessage(`{}`)},
+		{DestinationID: "a", MessageID: "m2", SentAt: sentAt, Payload: json.RawMessage(`{}`), ErrorResponse: json.RawMessage(`{}`)},
+	}))
+
+	rr := get("destinationId=a&messageId=m2&limit=10")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var statuses []*DeliveryStatusT
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1)
+	require.Equal(t, "m2", statuses[0].MessageID)
+
+	require.NoError(t, json.Unmarshal(get("destinationId=a").Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1, "the limit is capped by the max query limit")
+
+	require.JSONEq(t, `[]`, get("destinationId=b").Body.String())
+	require.Equal(t, http.StatusBadRequest, get("from=yesterday").Code)
+	require.Equal(t, http.StatusBadRequest, get("limit=0").Code)
+}
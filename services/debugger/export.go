@@ -0,0 +1,166 @@
+package debugger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/services/fileuploader"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// Captures of the debuggers can be mirrored to the object storage of their workspace, on top of being uploaded to the
+// config backend, for workspaces with data residency requirements:
+//   - exporting is enabled by Debugger.export.enabled, for the workspaces with the debuggerCaptures storage preference,
+//     the preference being checked as captures are exported, so that the ones of other workspaces aren't buffered
+//   - captures are buffered by workspace, up to Debugger.export.maxBufferedCaptures across workspaces, the ones
+//     exceeding it being dropped and counted in debugger_export_captures_dropped
+//   - every Debugger.export.flushInterval, the captures of each workspace are written as a JSONL file under
+//     rudder-debugger-captures/<debugger>/<date>/ in its object storage
+
+const exportPrefix = "rudder-debugger-captures"
+
+// Exporter mirrors the captures of a debugger to the object storage of their workspace. A nil Exporter exports nothing.
+type Exporter struct {
+	name     string
+	provider fileuploader.Provider
+
+	mu       sync.Mutex
+	captures map[string][][]byte
+	buffered int
+
+	maxBufferedCaptures int
+	flushInterval       time.Duration
+	droppedStat         stats.Measurement
+	exportedStat        stats.Measurement
+
+	cancel      context.CancelFunc
+	bgWaitGroup sync.WaitGroup
+}
+
+// NewExporter returns an exporter of the captures of the debugger with the name to the object storage configured in
+// the backend config, nil unless Debugger.export.enabled
+func NewExporter(name string, backendConfig backendconfig.BackendConfig) *Exporter {
+	if !config.GetBool("Debugger.export.enabled", false) {
+		return nil
+	}
+	return newExporter(name, fileuploader.NewProvider(context.TODO(), backendConfig))
+}
+
+func newExporter(name string, provider fileuploader.Provider) *Exporter {
+	e := &Exporter{name: name, provider: provider, captures: make(map[string][][]byte)}
+	config.RegisterIntConfigVariable(10000, &e.maxBufferedCaptures, true, 1, "Debugger.export.maxBufferedCaptures")
+	config.RegisterDurationConfigVariable(30, &e.flushInterval, true, time.Second, "Debugger.export.flushInterval")
+	tags := stats.Tags{"debugger": name}
+	e.droppedStat = stats.Default.NewTaggedStat("debugger_export_captures_dropped", stats.CountType, tags)
+	e.exportedStat = stats.Default.NewTaggedStat("debugger_export_captures_exported", stats.CountType, tags)
+	return e
+}
+
+// Start flushes the captures periodically, until Stop
+func (e *Exporter) Start() {
+	if e == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.bgWaitGroup.Add(1)
+	rruntime.Go(func() {
+		defer e.bgWaitGroup.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				// flushing the captures left, with a context of their own since ctx is done
+				e.Flush(context.Background())
+				return
+			case <-time.After(e.flushInterval):
+				e.Flush(ctx)
+			}
+		}
+	})
+}
+
+// Stop stops flushing the captures, once the ones left are flushed
+func (e *Exporter) Stop() {
+	if e == nil || e.cancel == nil {
+		return
+	}
+	e.cancel()
+	e.bgWaitGroup.Wait()
+}
+
+// Export buffers the capture of the workspace, to be written to its object storage on the next flush, unless the
+// workspace doesn't have the debuggerCaptures storage preference
+func (e *Exporter) Export(workspaceID string, capture interface{}) {
+	if e == nil || workspaceID == "" {
+		return
+	}
+	preferences, err := e.provider.GetStoragePreferences(workspaceID)
+	if err != nil {
+		pkgLogger.Debugf("[Exporter] Failed to get the storage preferences of workspace %s. Err: %v", workspaceID, err)
+		return
+	}
+	if !preferences.DebuggerCaptures {
+		return
+	}
+	line, err := json.Marshal(capture)
+	if err != nil {
+		pkgLogger.Errorf("[Exporter] Failed to marshal %s capture. Err: %v", e.name, err)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.buffered >= e.maxBufferedCaptures {
+		e.droppedStat.Increment()
+		return
+	}
+	e.captures[workspaceID] = append(e.captures[workspaceID], line)
+	e.buffered++
+}
+
+// Flush writes the buffered captures of every workspace to its object storage
+func (e *Exporter) Flush(ctx context.Context) {
+	e.mu.Lock()
+	captures := e.captures
+	e.captures = make(map[string][][]byte)
+	e.buffered = 0
+	e.mu.Unlock()
+
+	for workspaceID, lines := range captures {
+		if err := e.write(ctx, workspaceID, lines); err != nil {
+			pkgLogger.Errorf("[Exporter] Failed to export %d %s captures of workspace %s. Err: %v", len(lines), e.name, workspaceID, err)
+			e.droppedStat.Count(len(lines))
+			continue
+		}
+		e.exportedStat.Count(len(lines))
+	}
+}
+
+// write writes the captures of the workspace as a JSONL file to its object storage
+func (e *Exporter) write(ctx context.Context, workspaceID string, lines [][]byte) error {
+	fileManager, err := e.provider.GetFileManager(workspaceID)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	now := time.Now()
+	objectName := path.Join(exportPrefix, e.name, now.Format("01-02-2006"), fmt.Sprintf("%d.%s.%s.jsonl", now.Unix(), workspaceID, uuid.New().String()))
+	if _, err := fileManager.UploadReader(ctx, objectName, &buf); err != nil {
+		return fmt.Errorf("uploading %s: %w", objectName, err)
+	}
+	return nil
+}
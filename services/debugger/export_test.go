@@ -0,0 +1,63 @@
+package debugger
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	mock_filemanager "github.com/rudderlabs/rudder-server/mocks/services/filemanager"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+)
+
+type exportProvider struct {
+	fileManager filemanager.FileManager
+	preferences map[string]backendconfig.StoragePreferences
+}
+
+func (p *exportProvider) GetFileManager(string) (filemanager.FileManager, error) {
+	return p.fileManager, nil
+}
+
+func (p *exportProvider) GetStoragePreferences(workspaceID string) (backendconfig.StoragePreferences, error) {
+	return p.preferences[workspaceID], nil
+}
+
+func TestExporter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	fileManager := mock_filemanager.NewMockFileManager(ctrl)
+	e := newExporter("destination", &exportProvider{
+		fileManager: fileManager,
+		preferences: map[string]backendconfig.StoragePreferences{"w1": {DebuggerCaptures: true}},
+	})
+	e.maxBufferedCaptures = 3
+
+	e.Export("w1", map[string]string{"messageId": "m1"})
+	e.Export("w2", map[string]string{"messageId": "m2"})
+	require.Equal(t, 1, e.buffered, "captures of workspaces not exporting them aren't buffered")
+	e.Export("w1", map[string]string{"messageId": "m3"})
+	e.Export("w1", map[string]string{"messageId": "m4"})
+	e.Export("w1", map[string]string{"messageId": "m5"})
+
+	var uploaded string
+	fileManager.EXPECT().UploadReader(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, objName string, rdr io.Reader) (filemanager.UploadOutput, error) {
+		require.True(t, strings.HasPrefix(objName, "rudder-debugger-captures/destination/"))
+		require.True(t, strings.HasSuffix(objName, ".jsonl"))
+		data, err := io.ReadAll(rdr)
+		require.NoError(t, err)
+		uploaded = string(data)
+		return filemanager.UploadOutput{}, nil
+	}).Times(1)
+	e.Flush(context.Background())
+
+	require.Equal(t, "{\"messageId\":\"m1\"}\n{\"messageId\":\"m3\"}\n{\"messageId\":\"m4\"}\n", uploaded, "captures exceeding the buffer are dropped")
+
+	var nilExporter *Exporter
+	nilExporter.Export("w1", map[string]string{"messageId": "m1"})
+	nilExporter.Start()
+	nilExporter.Stop()
+}
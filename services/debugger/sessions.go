@@ -0,0 +1,174 @@
+package debugger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// Debugging can be enabled for a source, destination or transformation for a bounded time window, on top of the
+// eventUpload, eventDelivery and eventTransform flags of their config, so that verbose capture isn't left on
+// indefinitely:
+//   - through the /debugger/sessions admin endpoint, listing the active sessions on GET, starting the one in the body
+//     on PUT for its ttl, Debugger.sessions.defaultTTL by default and at most Debugger.sessions.maxTTL, and ending it
+//     on DELETE
+//   - by the control plane setting the flag along with its expiry, e.g. eventUploadExpiresAt, an RFC3339 time after
+//     which the flag is ignored
+
+const (
+	SessionKindSource         = "source"
+	SessionKindDestination    = "destination"
+	SessionKindTransformation = "transformation"
+)
+
+// Session enables debugging of a source, destination or transformation until it expires
+type Session struct {
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// sessionRequest is the body of requests to the sessions endpoint
+type sessionRequest struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	TTL  string `json:"ttl,omitempty"`
+}
+
+// Sessions are the debugging sessions started at runtime, the zero value having none
+type Sessions struct {
+	mu       sync.RWMutex
+	sessions map[string]Session
+	now      func() time.Time
+}
+
+// DefaultSessions are the sessions started through the admin endpoint
+var DefaultSessions = &Sessions{}
+
+func sessionKey(kind, id string) string {
+	return kind + "/" + id
+}
+
+func (s *Sessions) timeNow() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// Start starts debugging the source, destination or transformation with the id for the ttl, replacing its session if
+// it has one. The ttl is Debugger.sessions.defaultTTL if it's not positive, and at most Debugger.sessions.maxTTL.
+func (s *Sessions) Start(kind, id string, ttl time.Duration) Session {
+	if ttl <= 0 {
+		ttl = config.GetDuration("Debugger.sessions.defaultTTL", 30, time.Minute)
+	}
+	if maxTTL := config.GetDuration("Debugger.sessions.maxTTL", 2, time.Hour); ttl > maxTTL {
+		ttl = maxTTL
+	}
+	session := Session{Kind: kind, ID: id, ExpiresAt: s.timeNow().Add(ttl)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]Session)
+	}
+	s.sessions[sessionKey(kind, id)] = session
+	return session
+}
+
+// End ends the session of the source, destination or transformation with the id, if it has one
+func (s *Sessions) End(kind, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionKey(kind, id))
+}
+
+// Active returns whether the source, destination or transformation with the id has an unexpired session
+func (s *Sessions) Active(kind, id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.sessions[sessionKey(kind, id)]
+	return ok && s.timeNow().Before(session.ExpiresAt)
+}
+
+// List returns the unexpired sessions, sorted by kind and id, removing the expired ones
+func (s *Sessions) List() []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.timeNow()
+	sessions := make([]Session, 0, len(s.sessions))
+	for key, session := range s.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(s.sessions, key)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessionKey(sessions[i].Kind, sessions[i].ID) < sessionKey(sessions[j].Kind, sessions[j].ID)
+	})
+	return sessions
+}
+
+// FlagExpiry returns whether the debugging flag of the config is set, and when it expires, the zero time if it
+// doesn't. Flags whose <flag>ExpiresAt isn't an RFC3339 time never expire.
+func FlagExpiry(cfg map[string]interface{}, flag string) (expiresAt time.Time, enabled bool) {
+	if enabled, _ := cfg[flag].(bool); !enabled {
+		return time.Time{}, false
+	}
+	if value, ok := cfg[flag+"ExpiresAt"].(string); ok {
+		expiresAt, _ = time.Parse(time.RFC3339, value)
+	}
+	return expiresAt, true
+}
+
+// Unexpired returns whether a flag expiring at expiresAt is still in effect
+func Unexpired(expiresAt time.Time) bool {
+	return expiresAt.IsZero() || time.Now().Before(expiresAt)
+}
+
+// SessionsHandler lists the sessions on GET, starts the session in the body on PUT and ends the one in the body on
+// DELETE
+func SessionsHandler(s *Sessions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodDelete:
+			var req sessionRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+				http.Error(w, "body must be a session with a kind and an id", http.StatusBadRequest)
+				return
+			}
+			switch req.Kind {
+			case SessionKindSource, SessionKindDestination, SessionKindTransformation:
+			default:
+				http.Error(w, fmt.Sprintf("kind must be one of %s, %s or %s", SessionKindSource, SessionKindDestination, SessionKindTransformation), http.StatusBadRequest)
+				return
+			}
+			if r.Method == http.MethodDelete {
+				s.End(req.Kind, req.ID)
+				break
+			}
+			var ttl time.Duration
+			if req.TTL != "" {
+				var err error
+				if ttl, err = time.ParseDuration(req.TTL); err != nil {
+					http.Error(w, fmt.Sprintf("invalid ttl %q: %v", req.TTL, err), http.StatusBadRequest)
+					return
+				}
+			}
+			s.Start(req.Kind, req.ID, ttl)
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.List())
+	})
+}
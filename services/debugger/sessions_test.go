@@ -0,0 +1,76 @@
+package debugger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := &Sessions{now: func() time.Time { return now }}
+
+	require.False(t, s.Active(SessionKindSource, "s1"))
+	session := s.Start(SessionKindSource, "s1", 0)
+	require.Equal(t, now.Add(30*time.Minute), session.ExpiresAt, "sessions last Debugger.sessions.defaultTTL by default")
+	session = s.Start(SessionKindDestination, "d1", 24*time.Hour)
+	require.Equal(t, now.Add(2*time.Hour), session.ExpiresAt, "sessions last at most Debugger.sessions.maxTTL")
+	require.True(t, s.Active(SessionKindSource, "s1"))
+	require.False(t, s.Active(SessionKindDestination, "s1"))
+
+	now = now.Add(time.Hour)
+	require.False(t, s.Active(SessionKindSource, "s1"), "sessions expire")
+	require.Equal(t, []Session{{Kind: SessionKindDestination, ID: "d1", ExpiresAt: session.ExpiresAt}}, s.List())
+
+	s.End(SessionKindDestination, "d1")
+	require.Empty(t, s.List())
+}
+
+func TestFlagExpiry(t *testing.T) {
+	_, enabled := FlagExpiry(map[string]interface{}{"eventUpload": false}, "eventUpload")
+	require.False(t, enabled)
+
+	expiresAt, enabled := FlagExpiry(map[string]interface{}{"eventUpload": true}, "eventUpload")
+	require.True(t, enabled)
+	require.True(t, expiresAt.IsZero())
+	require.True(t, Unexpired(expiresAt))
+
+	expiresAt, enabled = FlagExpiry(map[string]interface{}{"eventUpload": true, "eventUploadExpiresAt": "2022-10-01T12:00:00Z"}, "eventUpload")
+	require.True(t, enabled)
+	require.Equal(t, time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC), expiresAt.UTC())
+	require.False(t, Unexpired(expiresAt))
+}
+
+func TestSessionsHandler(t *testing.T) {
+	s := &Sessions{}
+	handler := SessionsHandler(s)
+	serve := func(method, body string) (int, []Session) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/debugger/sessions", strings.NewReader(body)))
+		var sessions []Session
+		_ = json.Unmarshal(rec.Body.Bytes(), &sessions)
+		return rec.Code, sessions
+	}
+
+	code, sessions := serve(http.MethodPut, `{"kind":"destination","id":"d1","ttl":"10m"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, sessions, 1)
+	require.WithinDuration(t, time.Now().Add(10*time.Minute), sessions[0].ExpiresAt, time.Minute)
+
+	code, _ = serve(http.MethodPut, `{"kind":"warehouse","id":"w1"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(http.MethodPut, `{"kind":"source","id":"s1","ttl":"soon"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, sessions = serve(http.MethodDelete, `{"kind":"destination","id":"d1"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Empty(t, sessions)
+
+	code, _ = serve(http.MethodPost, "")
+	require.Equal(t, http.StatusMethodNotAllowed, code)
+}
@@ -29,14 +29,27 @@ type EventUploadBatchT struct {
 	WriteKey   string
 	ReceivedAt string
 	Batch      []EventUploadT
+	// ErrorCode and ErrorResponse are the response of sampled requests, see RecordRequest. Events are recorded with a 200 and no error.
+	ErrorCode     int
+	ErrorResponse map[string]interface{}
 }
 
 var (
-	uploadEnabledWriteKeys []string
-	configSubscriberLock   sync.RWMutex
+	// uploadEnabledWriteKeys are the write keys of the sources with eventUpload enabled, by when it expires
+	uploadEnabledWriteKeys map[string]time.Time
+	// sourceIDsByWriteKey are the IDs of the enabled sources, whose debugging sessions are started by source ID
+	sourceIDsByWriteKey  map[string]string
+	configSubscriberLock sync.RWMutex
+	// settingsByWriteKey is replaced rather than updated on config updates, under its own lock since events are
+	// transformed while others are recorded holding configSubscriberLock
+	settingsByWriteKey map[string]sourceSettings
+	settingsLock       sync.RWMutex
 )
 
-var uploader debugger.Uploader[*GatewayEventBatchT]
+var (
+	uploader debugger.Uploader[*GatewayEventBatchT]
+	exporter *debugger.Exporter
+)
 
 var (
 	configBackendURL    string
@@ -63,6 +76,8 @@ func Setup(backendConfig backendconfig.BackendConfig) {
 	eventUploader := &EventUploader{}
 	uploader = debugger.New[*GatewayEventBatchT](url, eventUploader)
 	uploader.Start()
+	exporter = debugger.NewExporter("source", backendConfig)
+	exporter.Start()
 
 	rruntime.Go(func() {
 		backendConfigSubscriber(backendConfig)
@@ -76,7 +91,7 @@ func recordHistoricEvents(writeKeys []string) {
 	for _, writeKey := range writeKeys {
 		historicEvents := eventsCacheMap.ReadAndPopData(writeKey)
 		for _, eventBatchData := range historicEvents {
-			uploader.RecordEvent(&GatewayEventBatchT{writeKey, eventBatchData})
+			upload(writeKey, eventBatchData)
 		}
 	}
 }
@@ -92,16 +107,92 @@ func RecordEvent(writeKey string, eventBatch []byte) bool {
 	// Check if writeKey part of enabled sources
 	configSubscriberLock.RLock()
 	defer configSubscriberLock.RUnlock()
-	if !misc.Contains(uploadEnabledWriteKeys, writeKey) {
+	if !uploadEnabled(writeKey) {
 		eventsCacheMap.Update(writeKey, eventBatch)
 		return false
 	}
 
+	return upload(writeKey, eventBatch)
+}
+
+// uploadEnabled returns whether the source of writeKey has eventUpload enabled, or a debugging session.
+// IMP: The function must be called holding configSubscriberLock
+func uploadEnabled(writeKey string) bool {
+	expiresAt, ok := uploadEnabledWriteKeys[writeKey]
+	if ok && debugger.Unexpired(expiresAt) {
+		return true
+	}
+	sourceID, ok := sourceIDsByWriteKey[writeKey]
+	return ok && debugger.DefaultSessions.Active(debugger.SessionKindSource, sourceID)
+}
+
+// upload records the event batch of writeKey to be uploaded, unless it's sampled out by its source.
+// IMP: The function must be called holding configSubscriberLock
+func upload(writeKey string, eventBatch []byte) bool {
+	settingsLock.RLock()
+	settings, ok := settingsByWriteKey[writeKey]
+	settingsLock.RUnlock()
+	if ok && !settings.sampled() {
+		return false
+	}
 	uploader.RecordEvent(&GatewayEventBatchT{writeKey, eventBatch})
 	return true
 }
 
+// RecordRequest records the payload of a sampled request of writeKey as a live event, along with the status code and error message
+// it was answered with, for requests failing before their events are recorded to be diagnosed as well.
+// Payloads which aren't JSON objects, e.g. the ones of requests rejected as invalid JSON, are recorded as a string under raw.
+func RecordRequest(writeKey string, payload []byte, statusCode int, errorMessage string) bool {
+	var event EventUploadT
+	if err := json.Unmarshal(payload, &event); err != nil || event == nil {
+		event = EventUploadT{"raw": string(payload)}
+	}
+	errorResponse := map[string]interface{}{}
+	if errorMessage != "" {
+		errorResponse["error"] = errorMessage
+	}
+	eventBatch, err := json.Marshal(EventUploadBatchT{
+		WriteKey:      writeKey,
+		ReceivedAt:    time.Now().Format(misc.RFC3339Milli),
+		Batch:         []EventUploadT{event},
+		ErrorCode:     statusCode,
+		ErrorResponse: errorResponse,
+	})
+	if err != nil {
+		pkgLogger.Errorf("[Source live events] Failed to marshal sampled request. Err: %v", err)
+		return false
+	}
+	return RecordEvent(writeKey, eventBatch)
+}
+
+// RecordTrackingPlanViolations records an event violating the tracking plan of its source as a live event, along with
+// its violations. Events dropped for their violations are recorded with a 400.
+func RecordTrackingPlanViolations(writeKey string, event map[string]interface{}, violations interface{}, dropped bool) bool {
+	if disableEventUploads {
+		return false
+	}
+	errorCode := 200
+	if dropped {
+		errorCode = 400
+	}
+	eventBatch, err := json.Marshal(EventUploadBatchT{
+		WriteKey:      writeKey,
+		ReceivedAt:    time.Now().Format(misc.RFC3339Milli),
+		Batch:         []EventUploadT{event},
+		ErrorCode:     errorCode,
+		ErrorResponse: map[string]interface{}{"violationErrors": violations, "dropped": dropped},
+	})
+	if err != nil {
+		pkgLogger.Errorf("[Source live events] Failed to marshal event violating its tracking plan. Err: %v", err)
+		return false
+	}
+	return RecordEvent(writeKey, eventBatch)
+}
+
 func (*EventUploader) Transform(eventBuffer []*GatewayEventBatchT) ([]byte, error) {
+	settingsLock.RLock()
+	sourcesSettings := settingsByWriteKey
+	settingsLock.RUnlock()
 	res := make(map[string]interface{})
 	res["version"] = "v2"
 	for _, event := range eventBuffer {
@@ -123,17 +214,24 @@ func (*EventUploader) Transform(eventBuffer []*GatewayEventBatchT) ([]byte, erro
 			arr, _ = value.([]EventUploadT)
 		}
 
+		errorCode, errorResponse := 200, make(map[string]interface{})
+		if batchedEvent.ErrorCode != 0 {
+			errorCode, errorResponse = batchedEvent.ErrorCode, batchedEvent.ErrorResponse
+		}
+		settings := sourcesSettings[event.writeKey]
 		for _, ev := range batchedEvent.Batch {
+			settings.redact(ev)
 			// add the receivedAt time to each event
 			event := map[string]interface{}{
 				"payload":       ev,
 				"receivedAt":    receivedAtStr,
 				"eventName":     misc.GetStringifiedData(ev["event"]),
 				"eventType":     misc.GetStringifiedData(ev["type"]),
-				"errorResponse": make(map[string]interface{}),
-				"errorCode":     200,
+				"errorResponse": errorResponse,
+				"errorCode":     errorCode,
 			}
 			arr = append(arr, event)
+			exporter.Export(settings.workspaceID, map[string]interface{}{"writeKey": batchedEvent.WriteKey, "event": event})
 		}
 
 		res[batchedEvent.WriteKey] = arr
@@ -150,18 +248,28 @@ func (*EventUploader) Transform(eventBuffer []*GatewayEventBatchT) ([]byte, erro
 
 func updateConfig(config map[string]backendconfig.ConfigT) {
 	configSubscriberLock.Lock()
-	uploadEnabledWriteKeys = []string{}
-	for _, wConfig := range config {
-		for _, source := range wConfig.Sources {
-			if source.Config != nil {
-				if source.Enabled && source.Config["eventUpload"] == true {
-					uploadEnabledWriteKeys = append(uploadEnabledWriteKeys, source.WriteKey)
+	uploadEnabledWriteKeys = make(map[string]time.Time)
+	sourceIDsByWriteKey = make(map[string]string)
+	var uploadEnabledWriteKeysList []string
+	settings := make(map[string]sourceSettings)
+	for workspaceID, wConfig := range config {
+		for i := range wConfig.Sources {
+			source := &wConfig.Sources[i]
+			if source.Config != nil && source.Enabled {
+				sourceIDsByWriteKey[source.WriteKey] = source.ID
+				settings[source.WriteKey] = newSourceSettings(workspaceID, source)
+				if expiresAt, ok := debugger.FlagExpiry(source.Config, "eventUpload"); ok && debugger.Unexpired(expiresAt) {
+					uploadEnabledWriteKeysList = append(uploadEnabledWriteKeysList, source.WriteKey)
+					uploadEnabledWriteKeys[source.WriteKey] = expiresAt
 				}
 			}
 		}
 	}
+	settingsLock.Lock()
+	settingsByWriteKey = settings
+	settingsLock.Unlock()
 
-	recordHistoricEvents(uploadEnabledWriteKeys)
+	recordHistoricEvents(uploadEnabledWriteKeysList)
 	configSubscriberLock.Unlock()
 }
 
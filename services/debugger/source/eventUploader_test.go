@@ -117,6 +117,25 @@ var _ = Describe("eventUploader", func() {
 			Expect(gjson.GetBytes(rawJson, `1vWezJfHKkbUHexNepDsGcSVWae.1.eventType`).String()).To(Equal("track"))
 		})
 
+		It("transforms sampled requests along with their response", func() {
+			c.asyncHelper.WaitWithTimeout(5 * time.Second)
+			var eventUploader EventUploader
+			var payload []*GatewayEventBatchT
+			for _, eventBatch := range []string{
+				`{"WriteKey":"` + WriteKeyEnabled + `","ReceivedAt":"2021-08-03T17:26:00.279+05:30","Batch":[{"type":"track","event":"Demo Track"}],"ErrorCode":200,"ErrorResponse":{}}`,
+				`{"WriteKey":"` + WriteKeyEnabled + `","ReceivedAt":"2021-08-03T17:26:00.279+05:30","Batch":[{"raw":"{\"batch\":[{\"type\":\"track\""}],"ErrorCode":400,"ErrorResponse":{"error":"Invalid JSON"}}`,
+			} {
+				payload = append(payload, &GatewayEventBatchT{writeKey: WriteKeyEnabled, eventBatch: []byte(eventBatch)})
+			}
+			rawJson, err := eventUploader.Transform(payload)
+			Expect(err).To(BeNil())
+			Expect(gjson.GetBytes(rawJson, WriteKeyEnabled+`.0.eventName`).String()).To(Equal("Demo Track"))
+			Expect(gjson.GetBytes(rawJson, WriteKeyEnabled+`.0.errorCode`).Int()).To(Equal(int64(200)))
+			Expect(gjson.GetBytes(rawJson, WriteKeyEnabled+`.1.payload.raw`).String()).To(Equal(`{"batch":[{"type":"track"`))
+			Expect(gjson.GetBytes(rawJson, WriteKeyEnabled+`.1.errorCode`).Int()).To(Equal(int64(400)))
+			Expect(gjson.GetBytes(rawJson, WriteKeyEnabled+`.1.errorResponse.error`).String()).To(Equal("Invalid JSON"))
+		})
+
 		It("ignores improperly built payload", func() {
 			c.asyncHelper.WaitWithTimeout(5 * time.Second)
 			recordingEvent0 := `{"receivedAt":"2021-08-03T17:26:","writeKey":"1vWezJfHKkbUHexNepDsGcSVWae","requestIP":"[::1]",  "batch": [{"anonymousId":"anon_id","channel":"android-sdk","context":{"app":{"build":"1","name":"RudderAndroidClient","namespace":"com.rudderlabs.android.sdk","version":"1.0"},"device":{"id":"49e4bdd1c280bc00","manufacturer":"Google","model":"Android SDK built for x86","name":"generic_x86"},"library":{"name":"com.rudderstack.android.sdk.core"},"locale":"en-US","network":{"carrier":"Android"},"screen":{"density":420,"height":1794,"width":1080},"traits":{"anonymousId":"49e4bdd1c280bc00"},"user_agent":"Dalvik/2.1.0 (Linux; U; Android 9; Android SDK built for x86 Build/PSR1.180720.075)"},"event":{"name": "Demo Track"},"integrations":{"All":true},"messageId":"7a355fdd-0325-4778-9905-b43f586acdd4","originalTimestamp":"2019-08-12T05:08:30.909Z","properties":{"category":"Demo Category","floatVal":4.501,"label":"Demo Label","testArray":[{"id":"elem1","value":"e1"},{"id":"elem2","value":"e2"}],"testMap":{"t1":"a","t2":4},"value":5},"rudderId":"90ca6da0-292e-4e79-9880-f8009e0ae4a3","sentAt":"2019-08-12T05:08:30.909Z","type":"track"}`
@@ -134,6 +153,12 @@ var _ = Describe("eventUploader", func() {
 			eventuallyFunc := func() bool { return RecordEvent(WriteKeyEnabled, []byte(recordingEvent)) }
 			Eventually(eventuallyFunc).Should(BeTrue())
 		})
+
+		It("records sampled requests", func() {
+			c.asyncHelper.WaitWithTimeout(5 * time.Second)
+			eventuallyFunc := func() bool { return RecordRequest(WriteKeyEnabled, []byte(`not-a-valid-json`), 400, "Invalid JSON") }
+			Eventually(eventuallyFunc).Should(BeTrue())
+		})
 	})
 })
 
@@ -0,0 +1,118 @@
+package sourcedebugger
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+)
+
+// Sources uploading their events control what's uploaded through their config:
+//   - eventUploadSampleRate, the fraction of their event batches and requests uploaded, between 0 and 1, all of them
+//     by default
+//   - eventUploadRedactions, a list of rules applied to their events before they're uploaded, each with the JSONPath
+//     of the properties it applies to, e.g. $.context.traits.email or $.properties.products[*].price, and an action,
+//     drop to remove them, or mask to replace their values with redactedValue, the default
+
+const (
+	redactionActionDrop = "drop"
+	redactionActionMask = "mask"
+
+	redactedValue = "[REDACTED]"
+)
+
+// sourceSettings are the settings of a source uploading its events
+type sourceSettings struct {
+	workspaceID string
+	sampleRate  float64
+	redactions  []redactionRule
+}
+
+// redactionRule drops or masks the properties at its path, whose segments are keys, array indexes, or * matching any
+type redactionRule struct {
+	path   []string
+	action string
+}
+
+func newSourceSettings(workspaceID string, source *backendconfig.SourceT) sourceSettings {
+	settings := sourceSettings{workspaceID: workspaceID, sampleRate: 1}
+	if rate, ok := source.Config["eventUploadSampleRate"].(float64); ok && rate >= 0 && rate <= 1 {
+		settings.sampleRate = rate
+	}
+	rules, _ := source.Config["eventUploadRedactions"].([]interface{})
+	for _, r := range rules {
+		rule, _ := r.(map[string]interface{})
+		path, _ := rule["path"].(string)
+		segments := parseJSONPath(path)
+		if len(segments) == 0 {
+			pkgLogger.Warnf("[Source live events] Ignoring redaction rule with invalid path %q of source %s", path, source.ID)
+			continue
+		}
+		action := redactionActionMask
+		if rule["action"] == redactionActionDrop {
+			action = redactionActionDrop
+		}
+		settings.redactions = append(settings.redactions, redactionRule{path: segments, action: action})
+	}
+	return settings
+}
+
+// sampled returns whether an event batch is uploaded
+func (s sourceSettings) sampled() bool {
+	return s.sampleRate >= 1 || rand.Float64() < s.sampleRate // skipcq: GSC-G404
+}
+
+// redact applies the redaction rules to the event
+func (s sourceSettings) redact(event EventUploadT) {
+	for _, rule := range s.redactions {
+		redact(map[string]interface{}(event), rule.path, rule.action)
+	}
+}
+
+// parseJSONPath splits a JSONPath in dot or bracket notation into its segments, nil if it has none
+func parseJSONPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.NewReplacer("[", ".", "]", "", `"`, "", "'", "").Replace(path)
+	var segments []string
+	for _, segment := range strings.Split(path, ".") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// redact drops or masks the values at the path of the value
+func redact(value interface{}, path []string, action string) {
+	last := len(path) == 1
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if path[0] != "*" && path[0] != key {
+				continue
+			}
+			switch {
+			case !last:
+				redact(child, path[1:], action)
+			case action == redactionActionDrop:
+				delete(v, key)
+			default:
+				v[key] = redactedValue
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if path[0] != "*" && path[0] != strconv.Itoa(i) {
+				continue
+			}
+			switch {
+			case !last:
+				redact(child, path[1:], action)
+			default:
+				// elements dropped from arrays are masked instead, so that the indexes of the others don't change
+				v[i] = redactedValue
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package sourcedebugger
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/tidwall/gjson"
+)
+
+var _ = Describe("sourceSettings", func() {
+	newSettings := func(config string) sourceSettings {
+		source := backendconfig.SourceT{ID: SourceIDEnabled}
+		Expect(json.Unmarshal([]byte(config), &source.Config)).To(Succeed())
+		return newSourceSettings("", &source)
+	}
+
+	redacted := func(settings sourceSettings, event string) string {
+		var ev EventUploadT
+		Expect(json.Unmarshal([]byte(event), &ev)).To(Succeed())
+		settings.redact(ev)
+		res, err := json.Marshal(ev)
+		Expect(err).To(BeNil())
+		return string(res)
+	}
+
+	It("uploads every event batch by default", func() {
+		settings := newSettings(`{"eventUpload":true}`)
+		Expect(settings.sampleRate).To(Equal(1.0))
+		Expect(settings.sampled()).To(BeTrue())
+	})
+
+	It("samples event batches by the sample rate of the source", func() {
+		Expect(newSettings(`{"eventUploadSampleRate":0}`).sampled()).To(BeFalse())
+		Expect(newSettings(`{"eventUploadSampleRate":0.25}`).sampleRate).To(Equal(0.25))
+		Expect(newSettings(`{"eventUploadSampleRate":2}`).sampleRate).To(Equal(1.0), "invalid rates are ignored")
+	})
+
+	It("drops and masks properties by their JSONPath", func() {
+		settings := newSettings(`{"eventUploadRedactions":[
+			{"path":"$.context.traits.email","action":"drop"},
+			{"path":"$.properties.products[*].price"},
+			{"path":"$['properties']['tags'][1]","action":"drop"},
+			{"path":"$.traits.*","action":"mask"},
+			{"path":"$"}
+		]}`)
+		Expect(settings.redactions).To(HaveLen(4), "rules without a path are ignored")
+		Expect(redacted(settings, `{
+			"context":{"traits":{"email":"a@b.c","name":"a"}},
+			"properties":{"products":[{"id":1,"price":5},{"id":2}],"tags":["x","y","z"]},
+			"traits":{"phone":"123","address":{"city":"c"}}
+		}`)).To(MatchJSON(`{
+			"context":{"traits":{"name":"a"}},
+			"properties":{"products":[{"id":1,"price":"[REDACTED]"},{"id":2}],"tags":["x","[REDACTED]","z"]},
+			"traits":{"phone":"[REDACTED]","address":"[REDACTED]"}
+		}`))
+	})
+
+	It("redacts events when transforming them", func() {
+		settingsLock.Lock()
+		settingsByWriteKey = map[string]sourceSettings{
+			WriteKeyEnabled: newSettings(`{"eventUploadRedactions":[{"path":"properties.email"}]}`),
+		}
+		settingsLock.Unlock()
+		defer func() {
+			settingsLock.Lock()
+			settingsByWriteKey = nil
+			settingsLock.Unlock()
+		}()
+
+		var eventUploader EventUploader
+		rawJson, err := eventUploader.Transform([]*GatewayEventBatchT{{
+			writeKey:   WriteKeyEnabled,
+			eventBatch: []byte(`{"writeKey":"` + WriteKeyEnabled + `","batch":[{"type":"track","properties":{"email":"a@b.c"}}]}`),
+		}})
+		Expect(err).To(BeNil())
+		Expect(gjson.GetBytes(rawJson, WriteKeyEnabled+`.0.payload.properties.email`).String()).To(Equal("[REDACTED]"))
+	})
+})
@@ -0,0 +1,105 @@
+package debugger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spill keeps upload payloads on disk until they're uploaded, one file per payload named after the time it was
+// spilled at, so that files sort in the order they were spilled. At most maxFiles payloads are kept, the oldest ones
+// being dropped to make room for newer ones. Payloads spilled by a previous run are uploaded as well.
+type spill struct {
+	dir      string
+	maxFiles *int
+
+	mu  sync.Mutex
+	seq int
+}
+
+const spillFileSuffix = ".json"
+
+func newSpill(dir string, maxFiles *int) (*spill, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating spill directory %s: %w", dir, err)
+	}
+	return &spill{dir: dir, maxFiles: maxFiles}, nil
+}
+
+// write spills the payload, returning the number of older payloads dropped to make room for it
+func (s *spill) write(payload []byte) (dropped int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names, err := s.names()
+	if err != nil {
+		return 0, err
+	}
+	// the payload being spilled is always kept, whatever the maximum is reloaded to
+	maxFiles := *s.maxFiles
+	if maxFiles < 1 {
+		maxFiles = 1
+	}
+	for ; len(names) > 0 && len(names) >= maxFiles; names = names[1:] {
+		if err := os.Remove(filepath.Join(s.dir, names[0])); err != nil && !os.IsNotExist(err) {
+			return dropped, fmt.Errorf("dropping spilled payload %s: %w", names[0], err)
+		}
+		dropped++
+	}
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq%1000000, spillFileSuffix)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		return dropped, fmt.Errorf("writing spilled payload: %w", err)
+	}
+	// renaming the complete file, for partially written ones not to be uploaded after a crash
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		return dropped, fmt.Errorf("renaming spilled payload: %w", err)
+	}
+	return dropped, nil
+}
+
+// oldest returns the name and payload of the oldest spilled payload, an empty name if there is none
+func (s *spill) oldest() (name string, payload []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names, err := s.names()
+	if err != nil || len(names) == 0 {
+		return "", nil, err
+	}
+	payload, err = os.ReadFile(filepath.Join(s.dir, names[0]))
+	if err != nil {
+		return "", nil, fmt.Errorf("reading spilled payload %s: %w", names[0], err)
+	}
+	return names[0], payload, nil
+}
+
+// remove removes the spilled payload once it's uploaded
+func (s *spill) remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing spilled payload %s: %w", name, err)
+	}
+	return nil
+}
+
+// names returns the names of the spilled payloads, the oldest first
+func (s *spill) names() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing spilled payloads: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), spillFileSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
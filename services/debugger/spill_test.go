@@ -0,0 +1,141 @@
+package debugger
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mocksSysUtils "github.com/rudderlabs/rudder-server/mocks/utils/sysUtils"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+func TestSpill(t *testing.T) {
+	maxFiles := 2
+	s, err := newSpill(t.TempDir(), &maxFiles)
+	require.NoError(t, err)
+
+	name, _, err := s.oldest()
+	require.NoError(t, err)
+	require.Empty(t, name)
+
+	for _, payload := range []string{"1", "2", "3"} {
+		dropped, err := s.write([]byte(payload))
+		require.NoError(t, err)
+		if payload == "3" {
+			require.Equal(t, 1, dropped, "the oldest payload is dropped to make room")
+		}
+	}
+	names, err := s.names()
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+
+	for _, expected := range []string{"2", "3"} {
+		name, payload, err := s.oldest()
+		require.NoError(t, err)
+		require.Equal(t, expected, string(payload))
+		require.NoError(t, s.remove(name))
+	}
+	name, _, err = s.oldest()
+	require.NoError(t, err)
+	require.Empty(t, name)
+
+	maxFiles = 0
+	for _, payload := range []string{"4", "5"} {
+		_, err := s.write([]byte(payload))
+		require.NoError(t, err)
+	}
+	name, payload, err := s.oldest()
+	require.NoError(t, err)
+	require.Equal(t, "5", string(payload), "the latest payload is kept with a maximum below 1")
+	require.NoError(t, s.remove(name))
+}
+
+type stringTransformer struct{}
+
+func (stringTransformer) Transform(data []string) ([]byte, error) {
+	return []byte(strings.Join(data, ",")), nil
+}
+
+func TestUploaderSpill(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mocksSysUtils.NewMockHTTPClientI(ctrl)
+
+	uploader := &uploaderImpl[string]{
+		url:                 "http://test/eventUploads",
+		name:                "eventUploads",
+		transformer:         stringTransformer{},
+		eventBatchChannel:   make(chan string),
+		Client:              mockHTTPClient,
+		maxBatchSize:        2,
+		maxESQueueSize:      2,
+		maxRetry:            2,
+		retrySleep:          time.Millisecond,
+		batchTimeout:        time.Hour,
+		maxSpillFiles:       10,
+		timingStat:          stats.Default.NewStat("debugger_upload", stats.TimerType),
+		eventsDroppedStat:   stats.Default.NewStat("debugger_events_dropped", stats.CountType),
+		payloadsDroppedStat: stats.Default.NewStat("debugger_payloads_dropped", stats.CountType),
+		payloadsSpilledStat: stats.Default.NewStat("debugger_payloads_spilled", stats.CountType),
+	}
+	var err error
+	uploader.spill, err = newSpill(t.TempDir(), &uploader.maxSpillFiles)
+	require.NoError(t, err)
+
+	t.Run("events overflowing the buffer are spilled", func(t *testing.T) {
+		done := make(chan struct{})
+		go func() {
+			uploader.handleEvents()
+			close(done)
+		}()
+		for _, event := range []string{"a", "b", "c"} {
+			uploader.RecordEvent(event)
+		}
+		close(uploader.eventBatchChannel)
+		<-done
+
+		require.Equal(t, []string{"c"}, uploader.eventBuffer)
+		_, payload, err := uploader.spill.oldest()
+		require.NoError(t, err)
+		require.Equal(t, "a,b", string(payload))
+	})
+
+	t.Run("payloads failing to upload are spilled", func(t *testing.T) {
+		mockHTTPClient.EXPECT().Do(gomock.Any()).Return(nil, errors.New("connection refused")).Times(2)
+		uploader.uploadEvents([]string{"c"})
+
+		names, err := uploader.spill.names()
+		require.NoError(t, err)
+		require.Len(t, names, 2)
+	})
+
+	t.Run("payloads rejected are dropped", func(t *testing.T) {
+		mockHTTPClient.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader(""))}, nil).Times(1)
+		uploader.uploadEvents([]string{"d"})
+
+		names, err := uploader.spill.names()
+		require.NoError(t, err)
+		require.Len(t, names, 2)
+	})
+
+	t.Run("spilled payloads are uploaded, the oldest first", func(t *testing.T) {
+		var uploaded []string
+		mockHTTPClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			uploaded = append(uploaded, string(body))
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}).Times(2)
+		uploader.uploadSpilled()
+
+		require.Equal(t, []string{"a,b", "c"}, uploaded)
+		entries, err := os.ReadDir(uploader.spill.dir)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+}
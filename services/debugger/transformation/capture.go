@@ -0,0 +1,203 @@
+package transformationdebugger
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/processor/transformer"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+// Transformation statuses are sampled, diffed, redacted and size limited before they're recorded:
+//   - TransformationDebugger.sampleRate, the fraction of the statuses recorded, between 0 and 1
+//   - every event after the transformation carries its diff from the event before it, the paths of the properties
+//     added, removed or changed, at most TransformationDebugger.maxDiffEntries of them
+//   - values of the properties whose keys are listed in TransformationDebugger.redactedKeys, case insensitively and
+//     at any depth, are replaced by redactedValue in the payloads and their diffs
+//   - payloads larger than TransformationDebugger.maxPayloadSize bytes once marshalled are replaced by their size
+//   - at most TransformationDebugger.maxLogLines console logs of the transformation are kept per event
+
+const (
+	redactedValue = "[REDACTED]"
+
+	diffOpAdded   = "added"
+	diffOpRemoved = "removed"
+	diffOpChanged = "changed"
+)
+
+var (
+	sampleRate     float64
+	redactedKeys   string
+	maxPayloadSize int
+	maxDiffEntries int
+	maxLogLines    int
+)
+
+// DiffT is a property added, removed or changed by a transformation
+type DiffT struct {
+	Path   string      `json:"path"`
+	Op     string      `json:"op"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+func loadCaptureConfig() {
+	config.RegisterFloat64ConfigVariable(1, &sampleRate, true, "TransformationDebugger.sampleRate")
+	config.RegisterStringConfigVariable("", &redactedKeys, true, "TransformationDebugger.redactedKeys")
+	config.RegisterIntConfigVariable(64*1024, &maxPayloadSize, true, 1, "TransformationDebugger.maxPayloadSize")
+	config.RegisterIntConfigVariable(100, &maxDiffEntries, true, 1, "TransformationDebugger.maxDiffEntries")
+	config.RegisterIntConfigVariable(50, &maxLogLines, true, 1, "TransformationDebugger.maxLogLines")
+}
+
+// LogsByMessageID returns the console logs of the transformation of each event of the response, by the message IDs
+// of the events they were transformed from
+func LogsByMessageID(response transformer.ResponseT) map[string][]string {
+	logs := make(map[string][]string)
+	for _, events := range [][]transformer.TransformerResponseT{response.Events, response.FailedEvents} {
+		for i := range events {
+			if len(events[i].Logs) == 0 {
+				continue
+			}
+			metadata := &events[i].Metadata
+			messageIDs := metadata.MessageIDs
+			if len(messageIDs) == 0 {
+				messageIDs = []string{metadata.MessageID}
+			}
+			for _, messageID := range messageIDs {
+				if messageID != "" {
+					logs[messageID] = append(logs[messageID], events[i].Logs...)
+				}
+			}
+		}
+	}
+	return logs
+}
+
+// sampled returns whether a transformation status is recorded
+func sampled() bool {
+	return sampleRate >= 1 || rand.Float64() < sampleRate // skipcq: GSC-G404
+}
+
+// capture diffs, redacts and limits the transformation status, without modifying the events it refers to
+func capture(transformStatus *TransformStatusT) {
+	keys := make(map[string]struct{})
+	for _, key := range strings.Split(redactedKeys, ",") {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			keys[key] = struct{}{}
+		}
+	}
+
+	if transformStatus.EventsAfter != nil {
+		for _, eventAfter := range transformStatus.EventsAfter.EventPayloads {
+			if transformStatus.EventBefore != nil {
+				eventAfter.Diff = diff(transformStatus.EventBefore.Payload, eventAfter.Payload, keys)
+			}
+			eventAfter.Payload = limit(redact(eventAfter.Payload, keys))
+		}
+	}
+	if transformStatus.EventBefore != nil {
+		transformStatus.EventBefore.Payload = limit(redact(transformStatus.EventBefore.Payload, keys))
+	}
+	if len(transformStatus.Logs) > maxLogLines {
+		transformStatus.Logs = transformStatus.Logs[:maxLogLines]
+	}
+}
+
+// redact returns a copy of the event with the values of the keys replaced by redactedValue
+func redact(event types.SingularEventT, keys map[string]struct{}) types.SingularEventT {
+	if event == nil || len(keys) == 0 {
+		return event
+	}
+	return redactValue(map[string]interface{}(event), keys).(map[string]interface{})
+}
+
+func redactValue(value interface{}, keys map[string]struct{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+		for key, nested := range value {
+			if _, ok := keys[strings.ToLower(key)]; ok {
+				redacted[key] = redactedValue
+				continue
+			}
+			redacted[key] = redactValue(nested, keys)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, nested := range value {
+			redacted[i] = redactValue(nested, keys)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+// limit returns the event, or its size if it's larger than maxPayloadSize once marshalled
+func limit(event types.SingularEventT) types.SingularEventT {
+	if event == nil {
+		return event
+	}
+	payload, err := jsonfast.Marshal(event)
+	if err != nil || len(payload) <= maxPayloadSize {
+		return event
+	}
+	return types.SingularEventT{"truncated": true, "size": len(payload)}
+}
+
+// diff returns the properties added, removed or changed from before to after, sorted by their path. Values of the
+// keys are redacted, as are the ones of properties nested in them.
+func diff(before, after types.SingularEventT, keys map[string]struct{}) []DiffT {
+	var diffs []DiffT
+	diffValues("", map[string]interface{}(before), map[string]interface{}(after), keys, &diffs)
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	if len(diffs) > maxDiffEntries {
+		diffs = diffs[:maxDiffEntries]
+	}
+	return diffs
+}
+
+func diffValues(path string, before, after map[string]interface{}, keys map[string]struct{}, diffs *[]DiffT) {
+	for key, b := range before {
+		keyPath := joinPath(path, key)
+		_, redacted := keys[strings.ToLower(key)]
+		a, ok := after[key]
+		switch {
+		case !ok:
+			*diffs = append(*diffs, DiffT{Path: keyPath, Op: diffOpRemoved, Before: diffValue(b, redacted, keys)})
+		case reflect.DeepEqual(a, b):
+		default:
+			bm, bIsMap := b.(map[string]interface{})
+			am, aIsMap := a.(map[string]interface{})
+			if bIsMap && aIsMap && !redacted {
+				diffValues(keyPath, bm, am, keys, diffs)
+				continue
+			}
+			*diffs = append(*diffs, DiffT{Path: keyPath, Op: diffOpChanged, Before: diffValue(b, redacted, keys), After: diffValue(a, redacted, keys)})
+		}
+	}
+	for key, a := range after {
+		if _, ok := before[key]; !ok {
+			_, redacted := keys[strings.ToLower(key)]
+			*diffs = append(*diffs, DiffT{Path: joinPath(path, key), Op: diffOpAdded, After: diffValue(a, redacted, keys)})
+		}
+	}
+}
+
+func diffValue(value interface{}, redacted bool, keys map[string]struct{}) interface{} {
+	if redacted {
+		return redactedValue
+	}
+	return redactValue(value, keys)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,76 @@
+package transformationdebugger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/processor/transformer"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+func TestCapture(t *testing.T) {
+	config.Reset()
+	loadCaptureConfig()
+	redactedKeys = "email, Traits"
+	maxLogLines = 2
+	defer config.Reset()
+
+	before := types.SingularEventT{
+		"event":      "Signed Up",
+		"properties": map[string]interface{}{"plan": "free", "email": "a@b.c", "seats": 1.0},
+		"traits":     map[string]interface{}{"name": "a"},
+	}
+	after := types.SingularEventT{
+		"event":      "Signed Up",
+		"properties": map[string]interface{}{"plan": "pro", "email": "d@e.f", "source": "ads"},
+		"traits":     map[string]interface{}{"name": "b"},
+	}
+	status := &TransformStatusT{
+		EventBefore: &EventBeforeTransform{Payload: before},
+		EventsAfter: &EventsAfterTransform{EventPayloads: []*EventPayloadAfterTransform{{Payload: after}}},
+		Logs:        []string{"one", "two", "three"},
+	}
+	capture(status)
+
+	require.Equal(t, []DiffT{
+		{Path: "properties.email", Op: diffOpChanged, Before: redactedValue, After: redactedValue},
+		{Path: "properties.plan", Op: diffOpChanged, Before: "free", After: "pro"},
+		{Path: "properties.seats", Op: diffOpRemoved, Before: 1.0},
+		{Path: "properties.source", Op: diffOpAdded, After: "ads"},
+		{Path: "traits", Op: diffOpChanged, Before: redactedValue, After: redactedValue},
+	}, status.EventsAfter.EventPayloads[0].Diff)
+	require.Equal(t, types.SingularEventT{
+		"event":      "Signed Up",
+		"properties": map[string]interface{}{"plan": "free", "email": redactedValue, "seats": 1.0},
+		"traits":     redactedValue,
+	}, status.EventBefore.Payload)
+	require.Equal(t, redactedValue, status.EventsAfter.EventPayloads[0].Payload["traits"])
+	require.Equal(t, "a@b.c", before["properties"].(map[string]interface{})["email"], "the events are left untouched")
+	require.Equal(t, []string{"one", "two"}, status.Logs)
+
+	maxPayloadSize = 10
+	status = &TransformStatusT{EventBefore: &EventBeforeTransform{Payload: types.SingularEventT{"event": strings.Repeat("a", 10)}}}
+	capture(status)
+	require.Equal(t, types.SingularEventT{"truncated": true, "size": 22}, status.EventBefore.Payload)
+}
+
+func TestLogsByMessageID(t *testing.T) {
+	response := transformer.ResponseT{
+		Events: []transformer.TransformerResponseT{
+			{Metadata: transformer.MetadataT{MessageID: "m1"}, Logs: []string{"m1 log"}},
+			{Metadata: transformer.MetadataT{MessageID: "m2"}},
+			{Metadata: transformer.MetadataT{MessageIDs: []string{"m3", "m4"}}, Logs: []string{"batch log"}},
+		},
+		FailedEvents: []transformer.TransformerResponseT{
+			{Metadata: transformer.MetadataT{MessageID: "m1"}, Logs: []string{"m1 error"}},
+		},
+	}
+	require.Equal(t, map[string][]string{
+		"m1": {"m1 log", "m1 error"},
+		"m3": {"batch log"},
+		"m4": {"batch log"},
+	}, LogsByMessageID(response))
+}
@@ -27,16 +27,20 @@ type TransformationStatusT struct {
 	EventsByMessageID     map[string]types.SingularEventWithReceivedAt
 	FailedEvents          []transformer.TransformerResponseT
 	UniqueMessageIds      map[string]struct{}
+	// LogsByMessageID are the console logs of the transformation of the events, see LogsByMessageID
+	LogsByMessageID map[string][]string
 }
 
 // TransformStatusT is a structure to hold transformation status
 type TransformStatusT struct {
-	TransformationID string                `json:"transformationId"`
-	SourceID         string                `json:"sourceId"`
-	DestinationID    string                `json:"destinationId"`
-	EventBefore      *EventBeforeTransform `json:"eventBefore"`
-	EventsAfter      *EventsAfterTransform `json:"eventsAfter"`
-	IsError          bool                  `json:"error"`
+	TransformationID        string                `json:"transformationId"`
+	TransformationVersionID string                `json:"transformationVersionId"`
+	SourceID                string                `json:"sourceId"`
+	DestinationID           string                `json:"destinationId"`
+	EventBefore             *EventBeforeTransform `json:"eventBefore"`
+	EventsAfter             *EventsAfterTransform `json:"eventsAfter"`
+	IsError                 bool                  `json:"error"`
+	Logs                    []string              `json:"logs,omitempty"`
 }
 
 type EventBeforeTransform struct {
@@ -50,6 +54,7 @@ type EventPayloadAfterTransform struct {
 	EventName string               `json:"eventName"`
 	EventType string               `json:"eventType"`
 	Payload   types.SingularEventT `json:"payload"`
+	Diff      []DiffT              `json:"diff,omitempty"`
 }
 
 type EventsAfterTransform struct {
@@ -71,13 +76,18 @@ var (
 	disableTransformationUploads bool
 	limitEventsInMemory          int
 	uploader                     debugger.Uploader[*TransformStatusT]
+	exporter                     *debugger.Exporter
 	pkgLogger                    logger.Logger
 	transformationCacheMap       debugger.Cache[TransformationStatusT]
 )
 
 var (
-	uploadEnabledTransformations map[string]bool
-	configSubscriberLock         sync.RWMutex
+	// uploadEnabledTransformations are the transformations with eventTransform enabled, by when it expires
+	uploadEnabledTransformations map[string]time.Time
+	// workspaceIDsByDestinationID are the workspaces of the destinations, for the captures of their transformations
+	// to be exported
+	workspaceIDsByDestinationID map[string]string
+	configSubscriberLock        sync.RWMutex
 )
 
 func Init() {
@@ -89,6 +99,7 @@ func loadConfig() {
 	configBackendURL = config.GetString("CONFIG_BACKEND_URL", "https://api.rudderstack.com")
 	config.RegisterBoolConfigVariable(false, &disableTransformationUploads, true, "TransformationDebugger.disableTransformationStatusUploads")
 	config.RegisterIntConfigVariable(1, &limitEventsInMemory, true, 1, "TransformationDebugger.limitEventsInMemory")
+	loadCaptureConfig()
 }
 
 type TransformationStatusUploader struct{}
@@ -96,8 +107,8 @@ type TransformationStatusUploader struct{}
 func IsUploadEnabled(id string) bool {
 	configSubscriberLock.RLock()
 	defer configSubscriberLock.RUnlock()
-	_, ok := uploadEnabledTransformations[id]
-	return ok
+	expiresAt, ok := uploadEnabledTransformations[id]
+	return ok && debugger.Unexpired(expiresAt) || debugger.DefaultSessions.Active(debugger.SessionKindTransformation, id)
 }
 
 // Setup initializes this module
@@ -106,6 +117,8 @@ func Setup() {
 	transformationStatusUploader := &TransformationStatusUploader{}
 	uploader = debugger.New[*TransformStatusT](url, transformationStatusUploader)
 	uploader.Start()
+	exporter = debugger.NewExporter("transformation", backendconfig.DefaultBackendConfig)
+	exporter.Start()
 
 	rruntime.Go(func() {
 		backendConfigSubscriber()
@@ -116,11 +129,20 @@ func Setup() {
 // which will be processed by handleEvents.
 func RecordTransformationStatus(transformStatus *TransformStatusT) {
 	// if disableTransformationUploads is true, return;
-	if disableTransformationUploads {
+	if disableTransformationUploads || !sampled() {
 		return
 	}
 
+	capture(transformStatus)
 	uploader.RecordEvent(transformStatus)
+	exporter.Export(workspaceID(transformStatus.DestinationID), transformStatus)
+}
+
+// workspaceID returns the workspace of the destination
+func workspaceID(destinationID string) string {
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	return workspaceIDsByDestinationID[destinationID]
 }
 
 func (*TransformationStatusUploader) Transform(eventBuffer []*TransformStatusT) ([]byte, error) {
@@ -137,15 +159,16 @@ func (*TransformationStatusUploader) Transform(eventBuffer []*TransformStatusT)
 
 func updateConfig(config map[string]backendconfig.ConfigT) {
 	configSubscriberLock.Lock()
-	uploadEnabledTransformations = make(map[string]bool)
+	uploadEnabledTransformations = make(map[string]time.Time)
+	workspaceIDsByDestinationID = make(map[string]string)
 	var uploadEnabledTransformationsIDs []string
-	for _, wConfig := range config {
+	for workspaceID, wConfig := range config {
 		for _, source := range wConfig.Sources {
 			for _, destination := range source.Destinations {
+				workspaceIDsByDestinationID[destination.ID] = workspaceID
 				for _, transformation := range destination.Transformations {
-					eventTransform, ok := transformation.Config["eventTransform"].(bool)
-					if ok && eventTransform {
-						uploadEnabledTransformations[transformation.ID] = true
+					if expiresAt, ok := debugger.FlagExpiry(transformation.Config, "eventTransform"); ok && debugger.Unexpired(expiresAt) {
+						uploadEnabledTransformations[transformation.ID] = expiresAt
 						uploadEnabledTransformationsIDs = append(uploadEnabledTransformationsIDs, transformation.ID)
 					}
 				}
@@ -237,6 +260,12 @@ func recordHistoricTransformations(tIDs []string) {
 }
 
 func processRecordTransformationStatus(tStatus *TransformationStatusT, tID string) {
+	var versionID string
+	for _, transformation := range tStatus.Destination.Transformations {
+		if transformation.ID == tID {
+			versionID = transformation.VersionID
+		}
+	}
 	reportedMessageIDs := make(map[string]struct{})
 	eventBeforeMap := make(map[string]*EventBeforeTransform)
 	eventAfterMap := make(map[string]*EventsAfterTransform)
@@ -261,12 +290,14 @@ func processRecordTransformationStatus(tStatus *TransformationStatusT, tID strin
 
 	for k := range eventBeforeMap {
 		RecordTransformationStatus(&TransformStatusT{
-			TransformationID: tID,
-			SourceID:         tStatus.SourceID,
-			DestinationID:    tStatus.DestID,
-			EventBefore:      eventBeforeMap[k],
-			EventsAfter:      eventAfterMap[k],
-			IsError:          false,
+			TransformationID:        tID,
+			TransformationVersionID: versionID,
+			SourceID:                tStatus.SourceID,
+			DestinationID:           tStatus.DestID,
+			EventBefore:             eventBeforeMap[k],
+			EventsAfter:             eventAfterMap[k],
+			IsError:                 false,
+			Logs:                    tStatus.LogsByMessageID[k],
 		})
 	}
 
@@ -283,12 +314,14 @@ func processRecordTransformationStatus(tStatus *TransformationStatusT, tID strin
 				}
 
 				RecordTransformationStatus(&TransformStatusT{
-					TransformationID: tID,
-					SourceID:         tStatus.SourceID,
-					DestinationID:    tStatus.DestID,
-					EventBefore:      eventBefore,
-					EventsAfter:      eventAfter,
-					IsError:          true,
+					TransformationID:        tID,
+					TransformationVersionID: versionID,
+					SourceID:                tStatus.SourceID,
+					DestinationID:           tStatus.DestID,
+					EventBefore:             eventBefore,
+					EventsAfter:             eventAfter,
+					IsError:                 true,
+					Logs:                    tStatus.LogsByMessageID[msgID],
 				})
 			}
 		} else if failedEvent.Metadata.MessageID != "" {
@@ -302,12 +335,14 @@ func processRecordTransformationStatus(tStatus *TransformationStatusT, tID strin
 			}
 
 			RecordTransformationStatus(&TransformStatusT{
-				TransformationID: tID,
-				SourceID:         tStatus.SourceID,
-				DestinationID:    tStatus.DestID,
-				EventBefore:      eventBefore,
-				EventsAfter:      eventAfter,
-				IsError:          true,
+				TransformationID:        tID,
+				TransformationVersionID: versionID,
+				SourceID:                tStatus.SourceID,
+				DestinationID:           tStatus.DestID,
+				EventBefore:             eventBefore,
+				EventsAfter:             eventAfter,
+				IsError:                 true,
+				Logs:                    tStatus.LogsByMessageID[failedEvent.Metadata.MessageID],
 			})
 		}
 	}
@@ -322,12 +357,14 @@ func processRecordTransformationStatus(tStatus *TransformationStatusT, tID strin
 			}
 
 			RecordTransformationStatus(&TransformStatusT{
-				TransformationID: tID,
-				SourceID:         tStatus.SourceID,
-				DestinationID:    tStatus.DestID,
-				EventBefore:      eventBefore,
-				EventsAfter:      eventAfter,
-				IsError:          false,
+				TransformationID:        tID,
+				TransformationVersionID: versionID,
+				SourceID:                tStatus.SourceID,
+				DestinationID:           tStatus.DestID,
+				EventBefore:             eventBefore,
+				EventsAfter:             eventAfter,
+				IsError:                 false,
+				Logs:                    tStatus.LogsByMessageID[msgID],
 			})
 		}
 	}
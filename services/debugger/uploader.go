@@ -5,15 +5,23 @@ package debugger
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
+
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/httputil"
 	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/sysUtils"
 )
 
@@ -22,6 +30,19 @@ var (
 	Http      sysUtils.HttpI = sysUtils.NewHttp()
 )
 
+// Uploaders batch the events recorded to them in memory, up to Debugger.maxESQueueSize events, and upload them to the
+// config backend every Debugger.batchTimeout, at most Debugger.maxBatchSize at a time. Uploads failing to connect, or
+// failing with a 5xx or 429, are retried up to Debugger.maxRetry times with an exponential backoff starting at
+// Debugger.retrySleep.
+//
+// Events which can't be kept in memory, and payloads failing to upload, are dropped and counted in
+// debugger_events_dropped and debugger_payloads_dropped, unless Debugger.spill.enabled, in which case they're spilled
+// to disk under Debugger.spill.dir instead and uploaded once the config backend is reachable again, keeping at most
+// Debugger.spill.maxFiles payloads per uploader.
+
+// errUploadRejected is returned by uploads rejected by the config backend, which aren't retried
+var errUploadRejected = errors.New("upload rejected by config backend")
+
 type Uploader[E any] interface {
 	Start()
 	Stop()
@@ -37,6 +58,7 @@ type Transformer[E any] interface {
 
 type uploaderImpl[E any] struct {
 	url                                    string
+	name                                   string
 	transformer                            Transformer[E]
 	eventBatchChannel                      chan E
 	eventBufferLock                        sync.RWMutex
@@ -47,6 +69,15 @@ type uploaderImpl[E any] struct {
 	timingStat                             stats.Measurement
 	region                                 string
 
+	spill         *spill
+	spillEnabled  bool
+	spillDir      string
+	maxSpillFiles int
+
+	eventsDroppedStat   stats.Measurement
+	payloadsDroppedStat stats.Measurement
+	payloadsSpilledStat stats.Measurement
+
 	bgWaitGroup sync.WaitGroup
 }
 
@@ -61,8 +92,35 @@ func (uploader *uploaderImpl[E]) Setup() {
 	config.RegisterIntConfigVariable(3, &uploader.maxRetry, true, 1, "Debugger.maxRetry")
 	config.RegisterDurationConfigVariable(2, &uploader.batchTimeout, true, time.Second, "Debugger.batchTimeoutInS")
 	config.RegisterDurationConfigVariable(100, &uploader.retrySleep, true, time.Millisecond, "Debugger.retrySleepInMS")
+	config.RegisterBoolConfigVariable(false, &uploader.spillEnabled, false, "Debugger.spill.enabled")
+	config.RegisterStringConfigVariable("", &uploader.spillDir, false, "Debugger.spill.dir")
+	config.RegisterIntConfigVariable(1000, &uploader.maxSpillFiles, true, 1, "Debugger.spill.maxFiles")
 	uploader.region = config.GetString("region", "")
 	uploader.timingStat = stats.Default.NewStat("debugger_upload", stats.TimerType)
+	tags := stats.Tags{"uploader": uploader.name}
+	uploader.eventsDroppedStat = stats.Default.NewTaggedStat("debugger_events_dropped", stats.CountType, tags)
+	uploader.payloadsDroppedStat = stats.Default.NewTaggedStat("debugger_payloads_dropped", stats.CountType, tags)
+	uploader.payloadsSpilledStat = stats.Default.NewTaggedStat("debugger_payloads_spilled", stats.CountType, tags)
+
+	if uploader.spillEnabled {
+		if err := uploader.setupSpill(); err != nil {
+			pkgLogger.Errorf("[Uploader] Failed to set up spilling for %s, events will be dropped instead. Err: %v", uploader.name, err)
+		}
+	}
+}
+
+func (uploader *uploaderImpl[E]) setupSpill() error {
+	dir := uploader.spillDir
+	if dir == "" {
+		tmpDir, err := misc.CreateTMPDIR()
+		if err != nil {
+			return err
+		}
+		dir = filepath.Join(tmpDir, "debugger-spill")
+	}
+	var err error
+	uploader.spill, err = newSpill(filepath.Join(dir, uploader.name), &uploader.maxSpillFiles)
+	return err
 }
 
 func New[E any](url string, transformer Transformer[E]) Uploader[E] {
@@ -70,11 +128,20 @@ func New[E any](url string, transformer Transformer[E]) Uploader[E] {
 	eventBuffer := make([]E, 0)
 	client := &http.Client{Timeout: config.GetDuration("HttpClient.debugger.timeout", 30, time.Second)}
 
-	uploader := &uploaderImpl[E]{url: url, transformer: transformer, eventBatchChannel: eventBatchChannel, eventBuffer: eventBuffer, Client: client, bgWaitGroup: sync.WaitGroup{}}
+	uploader := &uploaderImpl[E]{url: url, name: uploaderName(url), transformer: transformer, eventBatchChannel: eventBatchChannel, eventBuffer: eventBuffer, Client: client, bgWaitGroup: sync.WaitGroup{}}
 	uploader.Setup()
 	return uploader
 }
 
+// uploaderName returns the name of the uploader to the url, the last segment of its path, e.g. eventUploads
+func uploaderName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || path.Base(u.Path) == "/" || path.Base(u.Path) == "." {
+		return "default"
+	}
+	return path.Base(u.Path)
+}
+
 func (uploader *uploaderImpl[E]) Start() {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -101,51 +168,104 @@ func (uploader *uploaderImpl[E]) RecordEvent(data E) {
 	uploader.eventBatchChannel <- data
 }
 
+// uploadEvents uploads the events, spilling them if they fail to upload
 func (uploader *uploaderImpl[E]) uploadEvents(eventBuffer []E) {
 	// Upload to a Config Backend
 	rawJSON, err := uploader.transformer.Transform(eventBuffer)
 	if err != nil {
+		uploader.eventsDroppedStat.Count(len(eventBuffer))
+		return
+	}
+
+	err = uploader.upload(rawJSON)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, errUploadRejected) || uploader.spill == nil {
+		pkgLogger.Errorf("[Uploader] Dropping %d events failing to upload. Err: %v", len(eventBuffer), err)
+		uploader.payloadsDroppedStat.Increment()
 		return
 	}
+	uploader.spillPayload(rawJSON)
+}
+
+// spillPayload spills the payload, dropping it if it can't be
+func (uploader *uploaderImpl[E]) spillPayload(rawJSON []byte) {
+	dropped, err := uploader.spill.write(rawJSON)
+	uploader.payloadsDroppedStat.Count(dropped)
+	if err != nil {
+		pkgLogger.Errorf("[Uploader] Failed to spill payload. Err: %v", err)
+		uploader.payloadsDroppedStat.Increment()
+		return
+	}
+	uploader.payloadsSpilledStat.Increment()
+}
+
+// upload uploads the payload to the config backend, retrying with a backoff unless it's rejected
+func (uploader *uploaderImpl[E]) upload(rawJSON []byte) error {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = uploader.retrySleep
+	return backoff.RetryNotify(func() error {
+		return uploader.post(rawJSON)
+	}, backoff.WithMaxRetries(b, uint64(uploader.maxRetry-1)), func(err error, t time.Duration) {
+		pkgLogger.Warnf("[Uploader] Failed to upload to config backend, retrying after %v. Err: %v", t, err)
+	})
+}
+
+// post sends the payload to the config backend
+func (uploader *uploaderImpl[E]) post(rawJSON []byte) error {
+	startTime := time.Now()
+	req, err := Http.NewRequest("POST", uploader.url, bytes.NewBuffer(rawJSON))
+	if err != nil {
+		return backoff.Permanent(fmt.Errorf("%w: creating request: %v", errUploadRejected, err))
+	}
+	if uploader.region != "" {
+		q := req.URL.Query()
+		q.Add("region", uploader.region)
+		req.URL.RawQuery = q.Encode()
+	}
+	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
+	req.SetBasicAuth(config.GetWorkspaceToken(), "")
 
-	url := uploader.url
+	resp, err := uploader.Client.Do(req)
+	uploader.timingStat.SendTiming(time.Since(startTime))
+	if err != nil {
+		return fmt.Errorf("config backend connection error: %w", err)
+	}
+	defer func() { httputil.CloseResponse(resp) }()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("response error from config backend: status: %d", resp.StatusCode)
+	default:
+		return backoff.Permanent(fmt.Errorf("%w: status: %d", errUploadRejected, resp.StatusCode))
+	}
+}
 
-	retryCount := 1
-	// Sending live events to Config Backend
+// uploadSpilled uploads the spilled payloads, the oldest first, until one fails to upload
+func (uploader *uploaderImpl[E]) uploadSpilled() {
 	for {
-		var resp *http.Response
-		startTime := time.Now()
-		req, err := Http.NewRequest("POST", url, bytes.NewBuffer(rawJSON))
+		name, rawJSON, err := uploader.spill.oldest()
 		if err != nil {
-			pkgLogger.Errorf("[Uploader] Failed to create new http request. Err: %v", err)
+			pkgLogger.Errorf("[Uploader] Failed to read spilled payload. Err: %v", err)
 			return
 		}
-		if uploader.region != "" {
-			q := req.URL.Query()
-			q.Add("region", uploader.region)
-			req.URL.RawQuery = q.Encode()
+		if name == "" {
+			return
 		}
-		req.Header.Set("Content-Type", "application/json;charset=UTF-8")
-		req.SetBasicAuth(config.GetWorkspaceToken(), "")
-
-		resp, err = uploader.Client.Do(req)
-		uploader.timingStat.SendTiming(time.Since(startTime))
-		if err != nil {
-			pkgLogger.Error("Config Backend connection error", err)
-			if retryCount >= uploader.maxRetry {
-				pkgLogger.Errorf("Max retries exceeded trying to connect to config backend")
+		if err := uploader.upload(rawJSON); err != nil {
+			if !errors.Is(err, errUploadRejected) {
+				// retried on the next flush
 				return
 			}
-			retryCount++
-			time.Sleep(uploader.retrySleep)
-			// Refresh the connection
-			continue
+			pkgLogger.Errorf("[Uploader] Dropping spilled payload %s. Err: %v", name, err)
+			uploader.payloadsDroppedStat.Increment()
 		}
-		func() { httputil.CloseResponse(resp) }()
-		if resp.StatusCode != http.StatusOK {
-			pkgLogger.Errorf("[Uploader] Response Error from Config Backend: Status: %v, Body: %v ", resp.StatusCode, resp.Body)
+		if err := uploader.spill.remove(name); err != nil {
+			pkgLogger.Errorf("[Uploader] Failed to remove spilled payload. Err: %v", err)
+			return
 		}
-		break
 	}
 }
 
@@ -153,11 +273,16 @@ func (uploader *uploaderImpl[E]) handleEvents() {
 	for eventSchema := range uploader.eventBatchChannel {
 		uploader.eventBufferLock.Lock()
 
-		// If eventBuffer size is more than maxESQueueSize, Delete oldest.
+		// If eventBuffer size is more than maxESQueueSize, spill or delete oldest.
 		if len(uploader.eventBuffer) >= uploader.maxESQueueSize {
-			var z E
-			uploader.eventBuffer[0] = z
-			uploader.eventBuffer = uploader.eventBuffer[1:]
+			if uploader.spill != nil {
+				uploader.spillOldest()
+			} else {
+				var z E
+				uploader.eventBuffer[0] = z
+				uploader.eventBuffer = uploader.eventBuffer[1:]
+				uploader.eventsDroppedStat.Increment()
+			}
 		}
 
 		// Append to request buffer
@@ -167,12 +292,35 @@ func (uploader *uploaderImpl[E]) handleEvents() {
 	}
 }
 
+// spillOldest spills a batch of the oldest events of the buffer. It must be called holding eventBufferLock, recording
+// events being held back until they're spilled.
+func (uploader *uploaderImpl[E]) spillOldest() {
+	size := uploader.maxBatchSize
+	if size > len(uploader.eventBuffer) {
+		size = len(uploader.eventBuffer)
+	}
+	oldest := make([]E, size)
+	copy(oldest, uploader.eventBuffer[:size])
+	uploader.eventBuffer = uploader.eventBuffer[size:]
+
+	rawJSON, err := uploader.transformer.Transform(oldest)
+	if err != nil {
+		uploader.eventsDroppedStat.Count(size)
+		return
+	}
+	uploader.spillPayload(rawJSON)
+}
+
 func (uploader *uploaderImpl[E]) flushEvents(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
 		case <-time.After(uploader.batchTimeout):
 		}
+		if uploader.spill != nil && ctx.Err() == nil {
+			uploader.uploadSpilled()
+		}
+
 		uploader.eventBufferLock.Lock()
 
 		flushSize := len(uploader.eventBuffer)
@@ -196,6 +344,14 @@ func (uploader *uploaderImpl[E]) flushEvents(ctx context.Context) {
 		flushEvents = nil
 
 		if ctx.Err() != nil {
+			if uploader.spill != nil {
+				// keeping the events left for the next run
+				uploader.eventBufferLock.Lock()
+				for len(uploader.eventBuffer) > 0 {
+					uploader.spillOldest()
+				}
+				uploader.eventBufferLock.Unlock()
+			}
 			return
 		}
 	}
@@ -197,6 +197,26 @@ func (d *DedupHandleT) MarkProcessed(messageIDs []string) error {
 }
 
 func (d *DedupHandleT) FindDuplicates(messageIDs []string, allMessageIDsSet map[string]struct{}) (duplicateIndexes []int) {
+	toRemoveMessageIndexesSet := findDuplicatesInBatch(messageIDs, allMessageIDsSet)
+
+	// Dedup with badgerDB
+	err := d.badgerDB.View(func(txn *badger.Txn) error {
+		for idx, messageID := range messageIDs {
+			_, err := txn.Get([]byte(messageID))
+			if err != badger.ErrKeyNotFound {
+				toRemoveMessageIndexesSet[idx] = struct{}{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	return sortedIndexes(toRemoveMessageIndexesSet)
+}
+
+// findDuplicatesInBatch returns the indexes of messageIDs which are repeated within messageIDs or already present in allMessageIDsSet
+func findDuplicatesInBatch(messageIDs []string, allMessageIDsSet map[string]struct{}) map[int]struct{} {
 	toRemoveMessageIndexesSet := make(map[int]struct{})
 	// Dedup within events batch in a web request
 	messageIDSet := make(map[string]struct{})
@@ -221,27 +241,16 @@ func (d *DedupHandleT) FindDuplicates(messageIDs []string, allMessageIDsSet map[
 			toRemoveMessageIndexesSet[idx] = struct{}{}
 		}
 	}
+	return toRemoveMessageIndexesSet
+}
 
-	// Dedup with badgerDB
-	err := d.badgerDB.View(func(txn *badger.Txn) error {
-		for idx, messageID := range messageIDs {
-			_, err := txn.Get([]byte(messageID))
-			if err != badger.ErrKeyNotFound {
-				toRemoveMessageIndexesSet[idx] = struct{}{}
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		panic(err)
-	}
-	toRemoveMessageIndexes := make([]int, 0, len(toRemoveMessageIndexesSet))
-	for k := range toRemoveMessageIndexesSet {
-		toRemoveMessageIndexes = append(toRemoveMessageIndexes, k)
+func sortedIndexes(indexesSet map[int]struct{}) []int {
+	indexes := make([]int, 0, len(indexesSet))
+	for k := range indexesSet {
+		indexes = append(indexes, k)
 	}
-
-	sort.Ints(toRemoveMessageIndexes)
-	return toRemoveMessageIndexes
+	sort.Ints(indexes)
+	return indexes
 }
 
 func (d *DedupHandleT) Close() {
@@ -0,0 +1,73 @@
+package dedup
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+// RedisDedupHandleT keeps processed messageIDs in redis, so that they can be shared by multiple instances
+type RedisDedupHandleT struct {
+	client    *redis.Client
+	keyPrefix string
+	window    *time.Duration
+	timeout   time.Duration
+	logger    logger.Logger
+}
+
+// NewRedis returns a DedupI backed by redis, namespacing all messageIDs with keyPrefix.
+// Lookups failing because of redis being unavailable don't report any duplicates.
+func NewRedis(client *redis.Client, keyPrefix string, window *time.Duration) *RedisDedupHandleT {
+	return &RedisDedupHandleT{
+		client:    client,
+		keyPrefix: keyPrefix,
+		window:    window,
+		timeout:   5 * time.Second,
+		logger:    logger.NewLogger().Child("dedup").Child("redis"),
+	}
+}
+
+func (d *RedisDedupHandleT) FindDuplicates(messageIDs []string, allMessageIDsSet map[string]struct{}) (duplicateIndexes []int) {
+	toRemoveMessageIndexesSet := findDuplicatesInBatch(messageIDs, allMessageIDsSet)
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	cmds := make([]*redis.IntCmd, len(messageIDs))
+	pipe := d.client.Pipeline()
+	for idx, messageID := range messageIDs {
+		cmds[idx] = pipe.Exists(ctx, d.keyPrefix+messageID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		d.logger.Errorf("Error looking up duplicates in redis: %v", err)
+		return sortedIndexes(toRemoveMessageIndexesSet)
+	}
+	for idx, cmd := range cmds {
+		if cmd.Val() > 0 {
+			toRemoveMessageIndexesSet[idx] = struct{}{}
+		}
+	}
+	return sortedIndexes(toRemoveMessageIndexesSet)
+}
+
+// MarkProcessed persists messageIDs in redis, with expiry time of the dedup window
+func (d *RedisDedupHandleT) MarkProcessed(messageIDs []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	pipe := d.client.Pipeline()
+	for _, messageID := range messageIDs {
+		pipe.Set(ctx, d.keyPrefix+messageID, "", *d.window)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (*RedisDedupHandleT) PrintHistogram() {}
+
+func (d *RedisDedupHandleT) Close() {
+	_ = d.client.Close()
+}
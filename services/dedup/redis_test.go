@@ -0,0 +1,47 @@
+package dedup_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/dedup"
+	"github.com/rudderlabs/rudder-server/testhelper/destination"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func Test_RedisDedup(t *testing.T) {
+	config.Reset()
+	logger.Reset()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+	redisContainer, err := destination.SetupRedis(context.Background(), pool, t)
+	require.NoError(t, err)
+
+	window := time.Hour
+	d := dedup.NewRedis(redis.NewClient(&redis.Options{Addr: redisContainer.Addr}), "dedup-test:", &window)
+	defer d.Close()
+
+	t.Run("no duplicate if not marked as processed", func(t *testing.T) {
+		dups := d.FindDuplicates([]string{"a", "b", "c"}, nil)
+		require.Equal(t, []int{}, dups)
+	})
+
+	t.Run("duplicate after marked as processed", func(t *testing.T) {
+		err := d.MarkProcessed([]string{"a", "b", "c"})
+		require.NoError(t, err)
+		dups := d.FindDuplicates([]string{"a", "d", "c", "d"}, nil)
+		require.Equal(t, []int{0, 2, 3}, dups)
+	})
+
+	t.Run("duplicate within batch of batch jobs", func(t *testing.T) {
+		dups := d.FindDuplicates([]string{"x", "y", "z"}, map[string]struct{}{"x": {}, "z": {}})
+		require.Equal(t, []int{0, 2}, dups)
+	})
+}
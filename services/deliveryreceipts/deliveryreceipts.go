@@ -0,0 +1,219 @@
+package deliveryreceipts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+// Delivery receipts are the terminal delivery statuses of the events sent by the router to destinations, kept per
+// messageId and destination for the retention period, so that one can find out whether and when an event was delivered
+// to a destination, or why it was aborted.
+
+const table = "delivery_receipts"
+
+const (
+	Succeeded = "succeeded"
+	Aborted   = "aborted"
+)
+
+// Receipt is the terminal delivery status of an event for a destination
+type Receipt struct {
+	MessageID     string    `json:"messageId"`
+	DestinationID string    `json:"destinationId"`
+	WorkspaceID   string    `json:"workspaceId"`
+	JobID         int64     `json:"jobId"`
+	Status        string    `json:"status"`
+	StatusCode    string    `json:"statusCode"`
+	Response      string    `json:"response"` // the beginning of the response of the destination
+	Attempts      int       `json:"attempts"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Store persists the delivery receipts
+type Store struct {
+	db  *sql.DB
+	log logger.Logger
+
+	maxResponseLength int
+	retention         time.Duration
+	cleanupInterval   time.Duration
+}
+
+// New returns a store of delivery receipts, creating its table in the database if needed
+func New(ctx context.Context, db *sql.DB, log logger.Logger) (*Store, error) {
+	_, err := db.ExecContext(ctx, `create table `+table+` (
+		message_id text not null,
+		destination_id text not null,
+		workspace_id text not null,
+		job_id bigint not null,
+		status text not null,
+		status_code text not null,
+		response text not null,
+		attempts integer not null,
+		created_at timestamptz not null default now(),
+		primary key (message_id, destination_id)
+	)`)
+	if err != nil {
+		var pqError *pq.Error
+		if !(errors.As(err, &pqError) && pqError.Code == "42P07") {
+			return nil, fmt.Errorf("creating the %s table: %w", table, err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `create index if not exists `+table+`_created_at_idx on `+table+` (created_at)`); err != nil {
+		return nil, fmt.Errorf("creating the index of the %s table: %w", table, err)
+	}
+	return &Store{
+		db:                db,
+		log:               log,
+		maxResponseLength: config.GetInt("Router.deliveryReceipts.maxResponseLength", 512),
+		retention:         config.GetDuration("Router.deliveryReceipts.retention", 168, time.Hour),
+		cleanupInterval:   config.GetDuration("Router.deliveryReceipts.cleanupInterval", 60, time.Minute),
+	}, nil
+}
+
+// StoreInTx stores the given receipts in the given transaction, replacing the previous receipts of the same events and destinations
+func (s *Store) StoreInTx(ctx context.Context, tx *sql.Tx, receipts []Receipt) error {
+	type key struct{ messageID, destinationID string }
+	indexes := make(map[key]int, len(receipts))
+	var (
+		messageIDs, destinationIDs, workspaceIDs, statuses, statusCodes, responses []string
+		jobIDs, attempts                                                           []int64
+	)
+	for _, r := range receipts {
+		if r.MessageID == "" {
+			continue
+		}
+		response := r.Response
+		if len(response) > s.maxResponseLength {
+			response = strings.ToValidUTF8(response[:s.maxResponseLength], "")
+		}
+		// rows can't be upserted twice by the same statement, so only the last receipt of an event and destination is kept
+		k := key{r.MessageID, r.DestinationID}
+		if i, ok := indexes[k]; ok {
+			workspaceIDs[i], jobIDs[i], statuses[i], statusCodes[i], responses[i], attempts[i] = r.WorkspaceID, r.JobID, r.Status, r.StatusCode, response, int64(r.Attempts)
+			continue
+		}
+		indexes[k] = len(messageIDs)
+		messageIDs = append(messageIDs, r.MessageID)
+		destinationIDs = append(destinationIDs, r.DestinationID)
+		workspaceIDs = append(workspaceIDs, r.WorkspaceID)
+		jobIDs = append(jobIDs, r.JobID)
+		statuses = append(statuses, r.Status)
+		statusCodes = append(statusCodes, r.StatusCode)
+		responses = append(responses, response)
+		attempts = append(attempts, int64(r.Attempts))
+	}
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `insert into `+table+` (message_id, destination_id, workspace_id, job_id, status, status_code, response, attempts)
+		select * from unnest($1::text[], $2::text[], $3::text[], $4::bigint[], $5::text[], $6::text[], $7::text[], $8::integer[])
+		on conflict (message_id, destination_id) do update set
+			workspace_id = excluded.workspace_id,
+			job_id = excluded.job_id,
+			status = excluded.status,
+			status_code = excluded.status_code,
+			response = excluded.response,
+			attempts = excluded.attempts,
+			created_at = now()`,
+		pq.Array(messageIDs), pq.Array(destinationIDs), pq.Array(workspaceIDs), pq.Array(jobIDs),
+		pq.Array(statuses), pq.Array(statusCodes), pq.Array(responses), pq.Array(attempts))
+	if err != nil {
+		return fmt.Errorf("storing %d delivery receipts: %w", len(messageIDs), err)
+	}
+	return nil
+}
+
+// Get returns the receipts of the event with the given messageId, for the given destination or for all destinations
+// if destinationID is empty
+func (s *Store) Get(ctx context.Context, messageID, destinationID string) ([]Receipt, error) {
+	rows, err := s.db.QueryContext(ctx, `select message_id, destination_id, workspace_id, job_id, status, status_code, response, attempts, created_at
+		from `+table+` where message_id = $1 and ($2 = '' or destination_id = $2) and created_at > $3
+		order by destination_id`, messageID, destinationID, time.Now().Add(-s.retention))
+	if err != nil {
+		return nil, fmt.Errorf("querying the delivery receipts of %s: %w", messageID, err)
+	}
+	defer func() { _ = rows.Close() }()
+	receipts := []Receipt{}
+	for rows.Next() {
+		var r Receipt
+		if err := rows.Scan(&r.MessageID, &r.DestinationID, &r.WorkspaceID, &r.JobID, &r.Status, &r.StatusCode, &r.Response, &r.Attempts, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning the delivery receipts of %s: %w", messageID, err)
+		}
+		receipts = append(receipts, r)
+	}
+	return receipts, rows.Err()
+}
+
+// CleanupLoop deletes the receipts older than the retention period, until the context is cancelled
+func (s *Store) CleanupLoop(ctx context.Context) {
+	for {
+		if err := s.cleanup(ctx); err != nil && ctx.Err() == nil {
+			s.log.Errorf("Cleaning up delivery receipts: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.cleanupInterval):
+		}
+	}
+}
+
+func (s *Store) cleanup(ctx context.Context) error {
+	res, err := s.db.ExecContext(ctx, `delete from `+table+` where created_at < $1`, time.Now().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+	if deleted, err := res.RowsAffected(); err == nil && deleted > 0 {
+		s.log.Infof("Deleted %d delivery receipts older than %s", deleted, s.retention)
+	}
+	return nil
+}
+
+type DeliveryReceiptsRPCHandler struct {
+	Store *Store
+}
+
+// LookupT is the argument of DeliveryReceiptsRPCHandler.Get
+type LookupT struct {
+	MessageID     string `json:"messageId"`
+	DestinationID string `json:"destinationId"`
+}
+
+// Get returns the delivery receipts of an event, given as {"messageId": "...", "destinationId": "..."}, the
+// destinationId being optional
+func (h *DeliveryReceiptsRPCHandler) Get(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.Store.log.Error(r)
+			err = fmt.Errorf("internal Rudder server error: %v", r)
+		}
+	}()
+	var lookup LookupT
+	if err := json.Unmarshal([]byte(arg), &lookup); err != nil {
+		return fmt.Errorf("invalid lookup %q: %w", arg, err)
+	}
+	if lookup.MessageID == "" {
+		return errors.New("messageId is required")
+	}
+	receipts, err := h.Store.Get(context.Background(), lookup.MessageID, lookup.DestinationID)
+	if err != nil {
+		return err
+	}
+	response, err := json.MarshalIndent(receipts, "", " ")
+	if err != nil {
+		return err
+	}
+	*result = string(response)
+	return nil
+}
@@ -0,0 +1,75 @@
+package deliveryreceipts
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/testhelper/destination"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestStore(t *testing.T) {
+	config.Reset()
+	logger.Reset()
+	defer config.Reset()
+	config.Set("Router.deliveryReceipts.maxResponseLength", 10)
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+	pgResource, err := destination.SetupPostgres(pool, t)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	store, err := New(ctx, pgResource.DB, logger.NOP)
+	require.NoError(t, err)
+	_, err = New(ctx, pgResource.DB, logger.NOP)
+	require.NoError(t, err, "the table may already exist")
+
+	storeReceipts := func(receipts ...Receipt) {
+		tx, err := pgResource.DB.Begin()
+		require.NoError(t, err)
+		require.NoError(t, store.StoreInTx(ctx, tx, receipts))
+		require.NoError(t, tx.Commit())
+	}
+	storeReceipts(
+		Receipt{MessageID: "message", DestinationID: "destination-1", WorkspaceID: "workspace", JobID: 1, Status: Aborted, StatusCode: "400", Response: strings.Repeat("invalid ", 10), Attempts: 1},
+		Receipt{MessageID: "message", DestinationID: "destination-2", WorkspaceID: "workspace", JobID: 2, Status: Succeeded, StatusCode: "200", Attempts: 1},
+		Receipt{MessageID: "", DestinationID: "destination-1", WorkspaceID: "workspace", JobID: 3, Status: Succeeded, StatusCode: "200", Attempts: 1},
+	)
+	storeReceipts(
+		Receipt{MessageID: "message", DestinationID: "destination-2", WorkspaceID: "workspace", JobID: 4, Status: Aborted, StatusCode: "500", Attempts: 3},
+		Receipt{MessageID: "message", DestinationID: "destination-2", WorkspaceID: "workspace", JobID: 5, Status: Succeeded, StatusCode: "200", Attempts: 2},
+	)
+
+	receipts, err := store.Get(ctx, "message", "")
+	require.NoError(t, err)
+	require.Len(t, receipts, 2)
+	require.Equal(t, Aborted, receipts[0].Status)
+	require.Equal(t, "invalid in", receipts[0].Response, "responses are truncated")
+	require.EqualValues(t, 5, receipts[1].JobID, "the last receipt of an event and destination is kept")
+	require.Equal(t, Succeeded, receipts[1].Status)
+	require.Equal(t, 2, receipts[1].Attempts)
+
+	handler := &DeliveryReceiptsRPCHandler{Store: store}
+	var result string
+	require.NoError(t, handler.Get(`{"messageId":"message","destinationId":"destination-1"}`, &result))
+	var lookedUp []Receipt
+	require.NoError(t, json.Unmarshal([]byte(result), &lookedUp))
+	require.Len(t, lookedUp, 1)
+	require.Equal(t, "destination-1", lookedUp[0].DestinationID)
+	require.Error(t, handler.Get(`{"destinationId":"destination-1"}`, &result), "messageId is required")
+
+	_, err = pgResource.DB.Exec(`update `+table+` set created_at = $1 where destination_id = 'destination-1'`, time.Now().Add(-200*time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, store.cleanup(ctx))
+	receipts, err = store.Get(ctx, "message", "")
+	require.NoError(t, err)
+	require.Len(t, receipts, 1, "receipts older than the retention period are deleted")
+}
@@ -143,6 +143,7 @@ func uploadTestFileForBatchDestination(filename string, keyPrefixes []string, pr
 			UseRudderStorage: misc.IsConfiguredToUseRudderObjectStorage(destination.Config),
 			WorkspaceID:      destination.WorkspaceID,
 		}),
+		WorkspaceID: destination.WorkspaceID,
 	})
 	if err != nil {
 		pkgLogger.Errorf("DCT: Failed to initiate filemanager config for testing this destination id %s: err %v", destination.ID, err)
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path"
@@ -56,7 +57,11 @@ func (manager *AzureBlobStorageManager) getBaseURL() *url.URL {
 }
 
 func (manager *AzureBlobStorageManager) getContainerURL() (azblob.ContainerURL, error) {
-	if manager.Config.Container == "" {
+	return manager.getNamedContainerURL(manager.Config.Container)
+}
+
+func (manager *AzureBlobStorageManager) getNamedContainerURL(container string) (azblob.ContainerURL, error) {
+	if container == "" {
 		return azblob.ContainerURL{}, errors.New("no container configured")
 	}
 
@@ -70,7 +75,7 @@ func (manager *AzureBlobStorageManager) getContainerURL() (azblob.ContainerURL,
 	// From the Azure portal, get your storage account blob service URL endpoint.
 	baseURL := manager.getBaseURL()
 	serviceURL := azblob.NewServiceURL(*baseURL, p)
-	containerURL := serviceURL.NewContainerURL(manager.Config.Container)
+	containerURL := serviceURL.NewContainerURL(container)
 
 	return containerURL, nil
 }
@@ -109,11 +114,50 @@ func (manager *AzureBlobStorageManager) Upload(ctx context.Context, file *os.Fil
 
 	fileName := path.Join(manager.Config.Prefix, path.Join(prefixes...), path.Base(file.Name()))
 
+	checksums, err := computeFileChecksums(file)
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("computing checksums of %s: %w", file.Name(), err)
+	}
+
 	// Here's how to upload a blob.
+	// The md5 of the whole file is stored as the blob's Content-MD5, to verify downloads against.
 	blobURL := containerURL.NewBlockBlobURL(fileName)
 	_, err = azblob.UploadFileToBlockBlob(ctx, file, blobURL, azblob.UploadToBlockBlobOptions{
-		BlockSize:   4 * 1024 * 1024,
-		Parallelism: 16,
+		BlockSize:       4 * 1024 * 1024,
+		Parallelism:     16,
+		BlobHTTPHeaders: azblob.BlobHTTPHeaders{ContentMD5: checksums.md5},
+	})
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	return UploadOutput{Location: manager.blobLocation(&blobURL), ObjectName: fileName}, nil
+}
+
+// UploadReader streams the contents of rdr to Azure Blob Storage under objName, staging it as blocks
+func (manager *AzureBlobStorageManager) UploadReader(ctx context.Context, objName string, rdr io.Reader) (UploadOutput, error) {
+	containerURL, err := manager.getContainerURL()
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	if manager.createContainer() {
+		_, err = containerURL.Create(ctx, azblob.Metadata{}, azblob.PublicAccessNone)
+		err = suppressMinorErrors(err)
+		if err != nil {
+			return UploadOutput{}, err
+		}
+	}
+
+	fileName := path.Join(manager.Config.Prefix, objName)
+
+	blobURL := containerURL.NewBlockBlobURL(fileName)
+	_, err = azblob.UploadStreamToBlockBlob(ctx, rdr, blobURL, azblob.UploadStreamToBlockBlobOptions{
+		BufferSize: 4 * 1024 * 1024,
+		MaxBuffers: 16,
 	})
 	if err != nil {
 		return UploadOutput{}, err
@@ -209,6 +253,94 @@ func (manager *AzureBlobStorageManager) Download(ctx context.Context, output *os
 	return err
 }
 
+// DownloadRange writes the contents of key starting from offset to output
+func (manager *AzureBlobStorageManager) DownloadRange(ctx context.Context, output io.Writer, key string, offset int64) error {
+	containerURL, err := manager.getContainerURL()
+	if err != nil {
+		return err
+	}
+
+	blobURL := containerURL.NewBlockBlobURL(key)
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	downloadResponse, err := blobURL.Download(ctx, offset, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		if storageError, ok := err.(azblob.StorageError); ok && storageError.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+
+	bodyStream := downloadResponse.Body(azblob.RetryReaderOptions{MaxRetryRequests: 20})
+	defer bodyStream.Close()
+
+	_, err = io.Copy(output, bodyStream)
+	return err
+}
+
+// GetObjectInfo returns the size and, if it was computed on upload, the md5 digest of key
+func (manager *AzureBlobStorageManager) GetObjectInfo(ctx context.Context, key string) (ObjectInfo, error) {
+	containerURL, err := manager.getContainerURL()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	blobURL := containerURL.NewBlockBlobURL(key)
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		if storageError, ok := err.(azblob.StorageError); ok && storageError.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+			return ObjectInfo{}, ErrKeyNotFound
+		}
+		return ObjectInfo{}, err
+	}
+
+	var digest []byte
+	if md5 := props.ContentMD5(); len(md5) > 0 {
+		digest = md5
+	}
+	return ObjectInfo{Key: key, Size: props.ContentLength(), MD5: digest}, nil
+}
+
+// GetPresignedURL returns a url with a read only SAS token for key, valid until expiry elapses.
+// When the manager is configured with SAS tokens, the configured token is used instead.
+func (manager *AzureBlobStorageManager) GetPresignedURL(_ context.Context, key string, expiry time.Duration) (string, error) {
+	containerURL, err := manager.getContainerURL()
+	if err != nil {
+		return "", err
+	}
+	blobURL := containerURL.NewBlockBlobURL(key)
+	if manager.Config.UseSASTokens {
+		u := blobURL.URL()
+		return u.String(), nil
+	}
+
+	credential, err := azblob.NewSharedKeyCredential(manager.Config.AccountName, manager.Config.AccountKey)
+	if err != nil {
+		return "", err
+	}
+	sasQueryParams, err := azblob.BlobSASSignatureValues{
+		Protocol:      azblob.SASProtocolHTTPSandHTTP,
+		ExpiryTime:    time.Now().UTC().Add(expiry),
+		ContainerName: manager.Config.Container,
+		BlobName:      key,
+		Permissions:   azblob.BlobSASPermissions{Read: true}.String(),
+	}.NewSASQueryParameters(credential)
+	if err != nil {
+		return "", err
+	}
+
+	blobURLParts := azblob.NewBlobURLParts(blobURL.URL())
+	blobURLParts.SAS = sasQueryParams
+	u := blobURLParts.URL()
+	return u.String(), nil
+}
+
 /*
 GetObjectNameFromLocation gets the object name/key name from the object location url
 
@@ -348,6 +480,53 @@ func (manager *AzureBlobStorageManager) DeleteObjects(ctx context.Context, keys
 	return
 }
 
+// Copy copies sourceKey to destinationKey in destinationBucket (container) without downloading the blob,
+// waiting for the asynchronous copy to complete. If destinationBucket is empty, the blob is copied within the configured container.
+func (manager *AzureBlobStorageManager) Copy(ctx context.Context, sourceKey, destinationBucket, destinationKey string) (UploadOutput, error) {
+	if destinationBucket == "" {
+		destinationBucket = manager.Config.Container
+	}
+
+	sourceContainerURL, err := manager.getContainerURL()
+	if err != nil {
+		return UploadOutput{}, err
+	}
+	destinationContainerURL, err := manager.getNamedContainerURL(destinationBucket)
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	sourceBlobURL := sourceContainerURL.NewBlockBlobURL(sourceKey)
+	blobURL := destinationContainerURL.NewBlockBlobURL(destinationKey)
+	resp, err := blobURL.StartCopyFromURL(ctx, sourceBlobURL.URL(), azblob.Metadata{}, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, nil)
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	copyStatus := resp.CopyStatus()
+	for copyStatus == azblob.CopyStatusPending {
+		select {
+		case <-ctx.Done():
+			_, _ = blobURL.AbortCopyFromURL(context.Background(), resp.CopyID(), azblob.LeaseAccessConditions{})
+			return UploadOutput{}, ctx.Err()
+		case <-time.After(time.Second):
+		}
+		props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+		if err != nil {
+			return UploadOutput{}, err
+		}
+		copyStatus = props.CopyStatus()
+	}
+	if copyStatus != azblob.CopyStatusSuccess {
+		return UploadOutput{}, fmt.Errorf("copying blob %s to %s/%s: %s", sourceKey, destinationBucket, destinationKey, copyStatus)
+	}
+
+	return UploadOutput{Location: manager.blobLocation(&blobURL), ObjectName: destinationKey}, nil
+}
+
 func (manager *AzureBlobStorageManager) GetConfiguredPrefix() string {
 	return manager.Config.Prefix
 }
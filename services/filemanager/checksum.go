@@ -0,0 +1,80 @@
+package filemanager
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// md5MetadataKey is the user metadata key under which the md5 digest of uploaded files is stored,
+// for providers whose ETag isn't a digest of the contents once uploaded in multiple parts
+const md5MetadataKey = "Rudder-Content-Md5"
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// fileChecksums holds the digests of a file's contents computed before uploading it
+type fileChecksums struct {
+	md5    []byte
+	crc32c uint32
+}
+
+// computeFileChecksums reads file from the start, computing its md5 and crc32c digests, and rewinds it afterwards
+func computeFileChecksums(file *os.File) (fileChecksums, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fileChecksums{}, err
+	}
+	md5Hash := md5.New()
+	crc32cHash := crc32.New(crc32cTable)
+	if _, err := io.Copy(io.MultiWriter(md5Hash, crc32cHash), file); err != nil {
+		return fileChecksums{}, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fileChecksums{}, err
+	}
+	return fileChecksums{md5: md5Hash.Sum(nil), crc32c: crc32cHash.Sum32()}, nil
+}
+
+// md5FromMetadata returns the md5 digest stored in the user metadata of an object on upload, nil if there is none
+func md5FromMetadata(metadata map[string]string) []byte {
+	for key, value := range metadata {
+		if !strings.EqualFold(key, md5MetadataKey) {
+			continue
+		}
+		digest, err := hex.DecodeString(value)
+		if err != nil || len(digest) != md5.Size {
+			return nil
+		}
+		return digest
+	}
+	return nil
+}
+
+// verifiedFileManager verifies the size and checksum of every downloaded file against the object's metadata,
+// returning ErrChecksumMismatch for truncated or corrupted downloads.
+type verifiedFileManager struct {
+	FileManager
+}
+
+func newVerifiedFileManager(manager FileManager) FileManager {
+	if !config.GetBool("FileManager.verifyDownloadChecksum", true) {
+		return manager
+	}
+	return &verifiedFileManager{FileManager: manager}
+}
+
+func (m *verifiedFileManager) Download(ctx context.Context, output *os.File, key string) error {
+	if err := m.FileManager.Download(ctx, output, key); err != nil {
+		return err
+	}
+	info, err := m.FileManager.GetObjectInfo(ctx, key)
+	if err != nil {
+		return err
+	}
+	return verifyDownload(output, info)
+}
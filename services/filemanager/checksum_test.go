@@ -0,0 +1,68 @@
+package filemanager
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeFileChecksums(t *testing.T) {
+	contents := []byte("some load file contents")
+	file, err := os.Create(filepath.Join(t.TempDir(), "file.csv.gz"))
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.Write(contents)
+	require.NoError(t, err)
+
+	checksums, err := computeFileChecksums(file)
+	require.NoError(t, err)
+
+	expectedMD5 := md5.Sum(contents)
+	require.Equal(t, expectedMD5[:], checksums.md5)
+	require.Equal(t, crc32.Checksum(contents, crc32.MakeTable(crc32.Castagnoli)), checksums.crc32c)
+
+	offset, err := file.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	require.Zero(t, offset, "file should be rewound")
+}
+
+func TestMD5FromMetadata(t *testing.T) {
+	digest := md5.Sum([]byte("contents"))
+	require.Equal(t, digest[:], md5FromMetadata(map[string]string{"rudder-content-md5": hex.EncodeToString(digest[:])}))
+	require.Nil(t, md5FromMetadata(map[string]string{md5MetadataKey: "not-a-digest"}))
+	require.Nil(t, md5FromMetadata(nil))
+}
+
+// truncatingFileManager downloads only part of the object it reports
+type truncatingFileManager struct {
+	FileManager
+	contents []byte
+}
+
+func (m *truncatingFileManager) Download(_ context.Context, output *os.File, _ string) error {
+	_, err := output.Write(m.contents[:len(m.contents)/2])
+	return err
+}
+
+func (m *truncatingFileManager) GetObjectInfo(_ context.Context, key string) (ObjectInfo, error) {
+	digest := md5.Sum(m.contents)
+	return ObjectInfo{Key: key, Size: int64(len(m.contents)), MD5: digest[:]}, nil
+}
+
+func TestVerifiedFileManager(t *testing.T) {
+	manager := newVerifiedFileManager(&truncatingFileManager{contents: []byte("some load file contents")})
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "file.csv.gz"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	err = manager.Download(context.Background(), file, "key")
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+}
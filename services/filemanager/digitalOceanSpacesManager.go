@@ -2,8 +2,10 @@ package filemanager
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path"
@@ -43,11 +45,52 @@ func (manager *DOSpacesManager) Upload(ctx context.Context, file *os.File, prefi
 
 	fileName := path.Join(manager.Config.Prefix, path.Join(prefixes...), path.Base(file.Name()))
 
+	checksums, err := computeFileChecksums(file)
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("computing checksums of %s: %w", file.Name(), err)
+	}
+
+	// spaces doesn't support additional checksums, the md5 of the whole file is kept to verify downloads
+	uploadInput := &SpacesManager.UploadInput{
+		ACL:      aws.String("bucket-owner-full-control"),
+		Bucket:   aws.String(manager.Config.Bucket),
+		Key:      aws.String(fileName),
+		Body:     file,
+		Metadata: aws.StringMap(map[string]string{md5MetadataKey: hex.EncodeToString(checksums.md5)}),
+	}
+	uploadSession, err := manager.getSession()
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("error starting Digital Ocean Spaces session: %w", err)
+	}
+	DOmanager := SpacesManager.NewUploader(uploadSession)
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	output, err := DOmanager.UploadWithContext(ctx, uploadInput)
+	if err != nil {
+		if awsError, ok := err.(awserr.Error); ok && awsError.Code() == "MissingRegion" {
+			err = fmt.Errorf(fmt.Sprintf(`Bucket '%s' not found.`, manager.Config.Bucket))
+		}
+		return UploadOutput{}, err
+	}
+
+	return UploadOutput{Location: output.Location, ObjectName: fileName}, err
+}
+
+// UploadReader streams the contents of rdr to spaces under objName, using a multipart upload for large payloads
+func (manager *DOSpacesManager) UploadReader(ctx context.Context, objName string, rdr io.Reader) (UploadOutput, error) {
+	if manager.Config.Bucket == "" {
+		return UploadOutput{}, errors.New("no storage bucket configured to uploader")
+	}
+
+	fileName := path.Join(manager.Config.Prefix, objName)
+
 	uploadInput := &SpacesManager.UploadInput{
 		ACL:    aws.String("bucket-owner-full-control"),
 		Bucket: aws.String(manager.Config.Bucket),
 		Key:    aws.String(fileName),
-		Body:   file,
+		Body:   rdr,
 	}
 	uploadSession, err := manager.getSession()
 	if err != nil {
@@ -66,7 +109,7 @@ func (manager *DOSpacesManager) Upload(ctx context.Context, file *os.File, prefi
 		return UploadOutput{}, err
 	}
 
-	return UploadOutput{Location: output.Location, ObjectName: fileName}, err
+	return UploadOutput{Location: output.Location, ObjectName: fileName}, nil
 }
 
 func (manager *DOSpacesManager) Download(ctx context.Context, output *os.File, key string) error {
@@ -88,6 +131,111 @@ func (manager *DOSpacesManager) Download(ctx context.Context, output *os.File, k
 	return err
 }
 
+// Copy copies sourceKey to destinationKey in destinationBucket without downloading the object.
+// If destinationBucket is empty, the object is copied within the configured bucket.
+func (manager *DOSpacesManager) Copy(ctx context.Context, sourceKey, destinationBucket, destinationKey string) (UploadOutput, error) {
+	if destinationBucket == "" {
+		destinationBucket = manager.Config.Bucket
+	}
+
+	sess, err := manager.getSession()
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("error starting Digital Ocean Spaces session: %w", err)
+	}
+	svc := s3.New(sess)
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	_, err = svc.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+		ACL:        aws.String("bucket-owner-full-control"),
+		Bucket:     aws.String(destinationBucket),
+		Key:        aws.String(destinationKey),
+		CopySource: aws.String(url.PathEscape(path.Join(manager.Config.Bucket, sourceKey))),
+	})
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	return UploadOutput{Location: objectLocation(svc, destinationBucket, destinationKey), ObjectName: destinationKey}, nil
+}
+
+// DownloadRange writes the contents of key starting from offset to output
+func (manager *DOSpacesManager) DownloadRange(ctx context.Context, output io.Writer, key string, offset int64) error {
+	sess, err := manager.getSession()
+	if err != nil {
+		return fmt.Errorf("error starting Digital Ocean Spaces session: %w", err)
+	}
+	svc := s3.New(sess)
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(manager.Config.Bucket),
+		Key:    aws.String(key),
+	}
+	if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := svc.GetObjectWithContext(ctx, input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ErrKeyNotFound.Error() {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(output, resp.Body)
+	return err
+}
+
+// GetObjectInfo returns the size and md5 digest of key. The digest is the one stored in the metadata of the object on
+// upload, nil if there is none.
+func (manager *DOSpacesManager) GetObjectInfo(ctx context.Context, key string) (ObjectInfo, error) {
+	sess, err := manager.getSession()
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("error starting Digital Ocean Spaces session: %w", err)
+	}
+	svc := s3.New(sess)
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	resp, err := svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(manager.Config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == ErrKeyNotFound.Error() || aerr.Code() == "NotFound") {
+			return ObjectInfo{}, ErrKeyNotFound
+		}
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{
+		Key:  key,
+		Size: aws.Int64Value(resp.ContentLength),
+		MD5:  md5FromMetadata(aws.StringValueMap(resp.Metadata)),
+	}, nil
+}
+
+// GetPresignedURL returns a url granting read access to key until expiry elapses
+func (manager *DOSpacesManager) GetPresignedURL(_ context.Context, key string, expiry time.Duration) (string, error) {
+	sess, err := manager.getSession()
+	if err != nil {
+		return "", fmt.Errorf("error starting Digital Ocean Spaces session: %w", err)
+	}
+	svc := s3.New(sess)
+
+	req, _ := svc.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(manager.Config.Bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expiry)
+}
+
 func (manager *DOSpacesManager) GetDownloadKeyFromFileLocation(location string) string {
 	parsedUrl, err := url.Parse(location)
 	if err != nil {
@@ -0,0 +1,100 @@
+package filemanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// ErrChecksumMismatch is returned when the contents of an object don't match its checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ObjectInfo holds the metadata of an object in object storage
+type ObjectInfo struct {
+	Key  string
+	Size int64
+	// MD5 is the md5 digest of the object's contents, nil if the provider doesn't expose one for this object
+	MD5 []byte
+}
+
+// DownloadResumable downloads key into output using ranged requests. Whenever a transient error
+// interrupts the download, it is resumed from the last byte written to output instead of restarting from zero.
+// Once downloaded, the size and (if available) md5 checksum of the file are verified against the object's metadata.
+// output is expected to be empty and opened for writing.
+func DownloadResumable(ctx context.Context, manager FileManager, output *os.File, key string) error {
+	info, err := manager.GetObjectInfo(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	maxRetries := config.GetInt("FileManager.downloadMaxRetries", 5)
+	bo := backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), uint64(maxRetries)), ctx)
+
+	var attempt int
+	operation := func() error {
+		offset, err := output.Seek(0, io.SeekEnd)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		if offset >= info.Size {
+			return nil
+		}
+		if attempt > 0 {
+			stats.Default.NewStat("filemanager_download_resumed", stats.CountType).Increment()
+			stats.Default.NewStat("filemanager_download_resumed_bytes", stats.CountType).Count(int(offset))
+		}
+		attempt++
+
+		err = manager.DownloadRange(ctx, output, key, offset)
+		if errors.Is(err, ErrKeyNotFound) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}
+	notify := func(err error, d time.Duration) {
+		stats.Default.NewStat("filemanager_download_retries", stats.CountType).Increment()
+		pkgLogger.Warnf("Retrying download of %s in %v after error: %v", key, d, err)
+	}
+	if err = backoff.RetryNotify(operation, bo, notify); err != nil {
+		return err
+	}
+
+	return verifyDownload(output, info)
+}
+
+func verifyDownload(output *os.File, info ObjectInfo) error {
+	fi, err := output.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() != info.Size {
+		stats.Default.NewStat("filemanager_download_checksum_mismatch", stats.CountType).Increment()
+		return fmt.Errorf("%w: downloaded %d bytes of %s, expected %d", ErrChecksumMismatch, fi.Size(), info.Key, info.Size)
+	}
+	if info.MD5 == nil {
+		return nil
+	}
+
+	if _, err = output.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hash := md5.New()
+	if _, err = io.Copy(hash, output); err != nil {
+		return err
+	}
+	if digest := hash.Sum(nil); !bytes.Equal(digest, info.MD5) {
+		stats.Default.NewStat("filemanager_download_checksum_mismatch", stats.CountType).Increment()
+		return fmt.Errorf("%w: md5 of downloaded %s is %x, expected %x", ErrChecksumMismatch, info.Key, digest, info.MD5)
+	}
+	return nil
+}
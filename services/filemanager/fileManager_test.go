@@ -336,6 +336,17 @@ func TestFileManager(t *testing.T) {
 				uploadOutputs = append(uploadOutputs, uploadOutput)
 				filePtr.Close()
 			}
+
+			// stream a file using an upload writer
+			uploadWriter := filemanager.NewUploadWriter(context.TODO(), fm, "streamed-prefix/streamed.json.gz")
+			streamedFile, err := os.Open(fileList[0])
+			require.NoError(t, err, "error while opening testData file to stream")
+			_, err = io.Copy(uploadWriter, streamedFile)
+			require.NoError(t, err)
+			streamedFile.Close()
+			require.NoError(t, uploadWriter.Close(), "expected no error while streaming file")
+			require.Equal(t, "some-prefix/streamed-prefix/streamed.json.gz", uploadWriter.Output().ObjectName)
+			require.NoError(t, fm.DeleteObjects(context.TODO(), []string{uploadWriter.Output().ObjectName}))
 			// list files using ListFilesWithPrefix
 			originalFileObject := make([]*filemanager.FileObject, 0)
 			originalFileNames := make(map[string]int)
@@ -404,6 +415,22 @@ func TestFileManager(t *testing.T) {
 
 			DownloadedFileName := "TmpDownloadedFile"
 
+			// download the file using ranged requests & assert if it matches the original one
+			resumableFile, err := os.CreateTemp("", "resumable")
+			require.NoError(t, err)
+			defer os.Remove(resumableFile.Name())
+			require.NoError(t, filemanager.DownloadResumable(context.TODO(), fm, resumableFile, key), "expected no error while downloading file")
+			info, err := fm.GetObjectInfo(context.TODO(), key)
+			require.NoError(t, err)
+			require.EqualValues(t, len(originalFile), info.Size)
+			resumableFile.Close()
+			resumableContent, err := os.ReadFile(resumableFile.Name())
+			require.NoError(t, err)
+			require.Equal(t, originalFile, resumableContent, "downloaded file different than actual file")
+
+			_, err = fm.GetObjectInfo(context.TODO(), "non-existent-key")
+			require.Error(t, err, "expected error while getting info of a missing object")
+
 			// fail to download the file with cancelled context
 			filePtr, err = os.OpenFile(DownloadedFileName, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
 			if err != nil {
@@ -438,6 +465,24 @@ func TestFileManager(t *testing.T) {
 			ans := strings.Compare(string(originalFile), string(downloadedFile))
 			require.Equal(t, 0, ans, "downloaded file different than actual file")
 
+			// copy the file to another prefix & assert that the copy can be resolved back to its key
+			copyOutput, err := fm.Copy(context.TODO(), key, "", path.Join("copied-prefix", key))
+			require.NoError(t, err, "expected no error while copying file")
+			require.Equal(t, path.Join("copied-prefix", key), copyOutput.ObjectName)
+			require.Equal(t, copyOutput.ObjectName, fm.GetDownloadKeyFromFileLocation(copyOutput.Location))
+			require.NoError(t, fm.DeleteObjects(context.TODO(), []string{copyOutput.ObjectName}))
+
+			// fetch the file using a pre-signed url
+			presignedURL, err := fm.GetPresignedURL(context.TODO(), key, time.Minute)
+			require.NoError(t, err, "expected no error while generating pre-signed url")
+			resp, err := http.Get(presignedURL)
+			require.NoError(t, err)
+			presignedContent, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			httputil.CloseResponse(resp)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, originalFile, presignedContent, "file fetched using pre-signed url different than actual file")
+
 			// fail to delete the file with cancelled context
 			ctx, cancel = context.WithCancel(context.TODO())
 			cancel()
@@ -505,6 +550,31 @@ func TestFileManager(t *testing.T) {
 	}
 }
 
+func TestGCSManager_UploadWriterAbort(t *testing.T) {
+	fmFactory := filemanager.FileManagerFactoryT{}
+	fm, err := fmFactory.New(&filemanager.SettingsT{
+		Provider: "GCS",
+		Config: map[string]interface{}{
+			"bucketName": bucket,
+			"prefix":     "some-prefix",
+			"endPoint":   gcsURL,
+		},
+	})
+	require.NoError(t, err)
+
+	uploadWriter := filemanager.NewUploadWriter(context.TODO(), fm, "aborted-prefix/aborted.json.gz")
+	_, err = uploadWriter.Write([]byte(`{"partial":`))
+	require.NoError(t, err)
+	abortErr := fmt.Errorf("producer failed")
+	require.ErrorIs(t, uploadWriter.Abort(abortErr), abortErr, "expected the upload to fail with the abort error")
+
+	client, err := storage.NewClient(context.TODO(), option.WithEndpoint(gcsURL))
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	_, err = client.Bucket(bucket).Object("some-prefix/aborted-prefix/aborted.json.gz").Attrs(context.TODO())
+	require.ErrorIs(t, err, storage.ErrObjectNotExist, "an aborted upload shouldn't leave an object behind")
+}
+
 func TestGCSManager_unsupported_credentials(t *testing.T) {
 	var config map[string]interface{}
 	err := jsoniter.Unmarshal(
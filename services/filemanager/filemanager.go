@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -40,10 +41,15 @@ type FileObject struct {
 // FileManager implements all upload methods
 type FileManager interface {
 	Upload(context.Context, *os.File, ...string) (UploadOutput, error)
+	UploadReader(ctx context.Context, objName string, rdr io.Reader) (UploadOutput, error)
 	Download(context.Context, *os.File, string) error
+	DownloadRange(ctx context.Context, output io.Writer, key string, offset int64) error
+	GetObjectInfo(ctx context.Context, key string) (ObjectInfo, error)
+	GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
 	GetObjectNameFromLocation(string) (string, error)
 	GetDownloadKeyFromFileLocation(location string) string
 	DeleteObjects(ctx context.Context, keys []string) error
+	Copy(ctx context.Context, sourceKey, destinationBucket, destinationKey string) (UploadOutput, error)
 	ListFilesWithPrefix(ctx context.Context, startAfter, prefix string, maxItems int64) (fileObjects []*FileObject, err error)
 	GetConfiguredPrefix() string
 	SetTimeout(timeout time.Duration)
@@ -51,8 +57,9 @@ type FileManager interface {
 
 // SettingsT sets configuration for FileManager
 type SettingsT struct {
-	Provider string
-	Config   map[string]interface{}
+	Provider    string
+	Config      map[string]interface{}
+	WorkspaceID string // optional, for the workspace to get its own throttling budget
 }
 
 func init() {
@@ -60,8 +67,17 @@ func init() {
 	pkgLogger = logger.NewLogger().Child("filemanager")
 }
 
-// New returns FileManager backed by configured provider
+// New returns FileManager backed by configured provider, throttled according to the provider's budget
+// and verifying the checksums of downloaded files
 func (*FileManagerFactoryT) New(settings *SettingsT) (FileManager, error) {
+	manager, err := newProviderManager(settings)
+	if err != nil {
+		return nil, err
+	}
+	return newThrottledFileManager(newVerifiedFileManager(manager), settings), nil
+}
+
+func newProviderManager(settings *SettingsT) (FileManager, error) {
 	switch settings.Provider {
 	case "S3_DATALAKE":
 		return NewS3Manager(settings.Config)
@@ -83,6 +99,8 @@ func (*FileManagerFactoryT) New(settings *SettingsT) (FileManager, error) {
 		return &DOSpacesManager{
 			Config: GetDOSpacesConfig(settings.Config),
 		}, nil
+	case "SFTP":
+		return NewSFTPManager(GetSFTPConfig(settings.Config)), nil
 	}
 	return nil, fmt.Errorf("%w: %s", rterror.InvalidServiceProvider, settings.Provider)
 }
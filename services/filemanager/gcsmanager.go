@@ -2,6 +2,7 @@ package filemanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/rudderlabs/rudder-server/utils/googleutils"
+	"github.com/rudderlabs/rudder-server/utils/misc"
 	"google.golang.org/api/iterator"
 
 	"cloud.google.com/go/storage"
@@ -34,8 +36,17 @@ func (manager *GCSManager) Upload(ctx context.Context, file *os.File, prefixes .
 	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
 	defer cancel()
 
+	checksums, err := computeFileChecksums(file)
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("computing checksums of %s: %w", file.Name(), err)
+	}
+
 	obj := client.Bucket(manager.Config.Bucket).Object(fileName)
 	w := obj.NewWriter(ctx)
+	// GCS rejects the upload if the received contents don't match the checksums
+	w.CRC32C = checksums.crc32c
+	w.SendCRC32C = true
+	w.MD5 = checksums.md5
 	if _, err := io.Copy(w, file); err != nil {
 		err = fmt.Errorf("copying file to GCS: %v", err)
 		if closeErr := w.Close(); closeErr != nil {
@@ -57,6 +68,36 @@ func (manager *GCSManager) Upload(ctx context.Context, file *os.File, prefixes .
 	return UploadOutput{Location: manager.objectURL(attrs), ObjectName: fileName}, err
 }
 
+// UploadReader streams the contents of rdr to GCS under objName, using a resumable upload for large payloads
+func (manager *GCSManager) UploadReader(ctx context.Context, objName string, rdr io.Reader) (UploadOutput, error) {
+	fileName := path.Join(manager.Config.Prefix, objName)
+
+	client, err := manager.getClient(ctx)
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	// closing the writer finalizes the object, so a failed copy cancels its context instead to discard the upload
+	writerCtx, cancelWriter := context.WithCancel(ctx)
+	defer cancelWriter()
+
+	obj := client.Bucket(manager.Config.Bucket).Object(fileName)
+	w := obj.NewWriter(writerCtx)
+	if _, err := io.Copy(w, rdr); err != nil {
+		cancelWriter()
+		return UploadOutput{}, fmt.Errorf("copying reader to GCS: %w", err)
+	}
+	err = w.Close()
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("closing writer: %w", err)
+	}
+
+	return UploadOutput{Location: manager.objectURL(w.Attrs()), ObjectName: fileName}, nil
+}
+
 func (manager *GCSManager) ListFilesWithPrefix(ctx context.Context, startAfter, prefix string, maxItems int64) (fileObjects []*FileObject, err error) {
 	fileObjects = make([]*FileObject, 0)
 
@@ -135,6 +176,65 @@ func (manager *GCSManager) Download(ctx context.Context, output *os.File, key st
 	return err
 }
 
+// DownloadRange writes the contents of key starting from offset to output
+func (manager *GCSManager) DownloadRange(ctx context.Context, output io.Writer, key string, offset int64) error {
+	client, err := manager.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	rc, err := client.Bucket(manager.Config.Bucket).Object(key).NewRangeReader(ctx, offset, -1)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(output, rc)
+	return err
+}
+
+// GetObjectInfo returns the size and, unless it is a composite object, the md5 digest of key
+func (manager *GCSManager) GetObjectInfo(ctx context.Context, key string) (ObjectInfo, error) {
+	client, err := manager.getClient(ctx)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	attrs, err := client.Bucket(manager.Config.Bucket).Object(key).Attrs(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ObjectInfo{}, ErrKeyNotFound
+		}
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{Key: key, Size: attrs.Size, MD5: attrs.MD5}, nil
+}
+
+// GetPresignedURL returns a V4 signed url granting read access to key until expiry elapses.
+// Signing requires service account credentials.
+func (manager *GCSManager) GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	client, err := manager.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return client.Bucket(manager.Config.Bucket).SignedURL(key, &storage.SignedURLOptions{
+		Method:  "GET",
+		Expires: time.Now().Add(expiry),
+		Scheme:  storage.SigningSchemeV4,
+	})
+}
+
 /*
 GetObjectNameFromLocation gets the object name/key name from the object location url
 
@@ -232,10 +332,109 @@ type GCSConfig struct {
 	Iterator       *storage.ObjectIterator
 }
 
-func (*GCSManager) DeleteObjects(_ context.Context, _ []string) (err error) {
-	return
+// Copy copies sourceKey to destinationKey in destinationBucket without downloading the object, using an object rewrite.
+// If destinationBucket is empty, the object is copied within the configured bucket.
+func (manager *GCSManager) Copy(ctx context.Context, sourceKey, destinationBucket, destinationKey string) (UploadOutput, error) {
+	if destinationBucket == "" {
+		destinationBucket = manager.Config.Bucket
+	}
+
+	client, err := manager.getClient(ctx)
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	src := client.Bucket(manager.Config.Bucket).Object(sourceKey)
+	attrs, err := client.Bucket(destinationBucket).Object(destinationKey).CopierFrom(src).Run(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return UploadOutput{}, ErrKeyNotFound
+		}
+		return UploadOutput{}, err
+	}
+
+	return UploadOutput{Location: manager.objectURL(attrs), ObjectName: destinationKey}, nil
+}
+
+// DeleteObjects deletes the objects of the keys one by one, as GCS has no batch deletion, ignoring the ones already
+// missing. Its callers only delete objects written by the server: the files of test syncs, the spilled and dead letter
+// files of purged workspaces, the objects expired by retention rules and the status tracker files of regulation deletes.
+func (manager *GCSManager) DeleteObjects(ctx context.Context, keys []string) (err error) {
+	client, err := manager.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	bucket := client.Bucket(manager.Config.Bucket)
+	for _, key := range keys {
+		_ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+		err = bucket.Object(key).Delete(_ctx)
+		cancel()
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return err
+		}
+	}
+	return nil
 }
 
 func (manager *GCSManager) GetConfiguredPrefix() string {
 	return manager.Config.Prefix
 }
+
+// gcsPublicMembers are the members of IAM policies and the entities of ACLs making buckets public
+var gcsPublicMembers = []string{string(storage.AllUsers), string(storage.AllAuthenticatedUsers)}
+
+// BucketPolicies inspects the public access and lifecycle rules of the bucket, whose objects are always encrypted
+func (manager *GCSManager) BucketPolicies(ctx context.Context) (BucketPolicies, error) {
+	policies := BucketPolicies{Encrypted: true}
+	client, err := manager.getClient(ctx)
+	if err != nil {
+		return policies, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	bucket := client.Bucket(manager.Config.Bucket)
+	attrs, err := bucket.Attrs(ctx)
+	if err != nil {
+		return policies, fmt.Errorf("getting bucket attributes: %w", err)
+	}
+
+	if attrs.PublicAccessPrevention != storage.PublicAccessPreventionEnforced {
+		for _, rule := range attrs.ACL {
+			if misc.Contains(gcsPublicMembers, string(rule.Entity)) {
+				policies.Public = true
+			}
+		}
+		policy, err := bucket.IAM().Policy(ctx)
+		if err != nil {
+			return policies, fmt.Errorf("getting bucket iam policy: %w", err)
+		}
+		for _, role := range policy.Roles() {
+			for _, member := range policy.Members(role) {
+				if misc.Contains(gcsPublicMembers, member) {
+					policies.Public = true
+				}
+			}
+		}
+	}
+
+	for _, rule := range attrs.Lifecycle.Rules {
+		if rule.Action.Type != storage.DeleteAction || rule.Condition.AgeInDays <= 0 {
+			continue
+		}
+		after := time.Duration(rule.Condition.AgeInDays) * 24 * time.Hour
+		if len(rule.Condition.MatchesPrefix) == 0 {
+			policies.Expirations = append(policies.Expirations, ExpirationRule{After: after})
+			continue
+		}
+		for _, prefix := range rule.Condition.MatchesPrefix {
+			policies.Expirations = append(policies.Expirations, ExpirationRule{Prefix: prefix, After: after})
+		}
+	}
+	return policies, nil
+}
@@ -0,0 +1,77 @@
+package filemanager
+
+import (
+	"context"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// RetentionRule expires the objects under Prefix once they are older than Retention
+type RetentionRule struct {
+	Prefix    string
+	Retention time.Duration
+}
+
+// SweepExpiredObjects deletes all objects matching one of the rules whose last modification is older than the rule's retention.
+// A new file manager is created for every rule, since listing objects is stateful. Returns the number of deleted objects.
+func SweepExpiredObjects(ctx context.Context, factory FileManagerFactory, settings *SettingsT, rules []RetentionRule, now time.Time) (int, error) {
+	var deleted int
+	for _, rule := range rules {
+		manager, err := factory.New(settings)
+		if err != nil {
+			return deleted, err
+		}
+
+		n, err := sweepPrefix(ctx, manager, settings.Provider, rule, now)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+func sweepPrefix(ctx context.Context, manager FileManager, provider string, rule RetentionRule, now time.Time) (int, error) {
+	const batchSize = 1000
+
+	deletedStat := stats.Default.NewTaggedStat("filemanager_lifecycle_deleted_objects", stats.CountType, stats.Tags{"provider": provider, "prefix": rule.Prefix})
+	deleteObjects := func(keys []string) error {
+		if len(keys) == 0 {
+			return nil
+		}
+		if err := manager.DeleteObjects(ctx, keys); err != nil {
+			return err
+		}
+		deletedStat.Count(len(keys))
+		return nil
+	}
+
+	var deleted int
+	expired := make([]string, 0, batchSize)
+	iter := IterateFilesWithPrefix(ctx, rule.Prefix, "", batchSize, &manager)
+	for iter.Next() {
+		object := iter.Get()
+		if now.Sub(object.LastModified) < rule.Retention {
+			continue
+		}
+		expired = append(expired, object.Key)
+		if len(expired) == batchSize {
+			if err := deleteObjects(expired); err != nil {
+				return deleted, err
+			}
+			deleted += len(expired)
+			expired = expired[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	if err := deleteObjects(expired); err != nil {
+		return deleted, err
+	}
+	deleted += len(expired)
+
+	pkgLogger.Infof("Deleted %d objects older than %v under prefix %q", deleted, rule.Retention, rule.Prefix)
+	return deleted, nil
+}
@@ -0,0 +1,47 @@
+package filemanager_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mock_filemanager "github.com/rudderlabs/rudder-server/mocks/services/filemanager"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+)
+
+func TestSweepExpiredObjects(t *testing.T) {
+	now := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	settings := &filemanager.SettingsT{Provider: "S3"}
+
+	ctrl := gomock.NewController(t)
+	factory := mock_filemanager.NewMockFileManagerFactory(ctrl)
+
+	stagingManager := mock_filemanager.NewMockFileManager(ctrl)
+	stagingManager.EXPECT().ListFilesWithPrefix(gomock.Any(), "", "staging/", gomock.Any()).Return([]*filemanager.FileObject{
+		{Key: "staging/old.json.gz", LastModified: now.Add(-48 * time.Hour)},
+		{Key: "staging/new.json.gz", LastModified: now.Add(-1 * time.Hour)},
+	}, nil)
+	stagingManager.EXPECT().ListFilesWithPrefix(gomock.Any(), "", "staging/", gomock.Any()).Return(nil, nil)
+	stagingManager.EXPECT().DeleteObjects(gomock.Any(), []string{"staging/old.json.gz"}).Return(nil)
+
+	loadManager := mock_filemanager.NewMockFileManager(ctrl)
+	loadManager.EXPECT().ListFilesWithPrefix(gomock.Any(), "", "load/", gomock.Any()).Return([]*filemanager.FileObject{
+		{Key: "load/new.csv.gz", LastModified: now.Add(-1 * time.Hour)},
+	}, nil)
+	loadManager.EXPECT().ListFilesWithPrefix(gomock.Any(), "", "load/", gomock.Any()).Return(nil, nil)
+
+	gomock.InOrder(
+		factory.EXPECT().New(settings).Return(stagingManager, nil),
+		factory.EXPECT().New(settings).Return(loadManager, nil),
+	)
+
+	deleted, err := filemanager.SweepExpiredObjects(context.Background(), factory, settings, []filemanager.RetentionRule{
+		{Prefix: "staging/", Retention: 24 * time.Hour},
+		{Prefix: "load/", Retention: 24 * time.Hour},
+	}, now)
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+}
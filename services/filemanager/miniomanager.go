@@ -2,8 +2,10 @@ package filemanager
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path"
@@ -15,11 +17,15 @@ import (
 )
 
 func (manager *MinioManager) ObjectUrl(objectName string) string {
+	return manager.bucketObjectUrl(manager.Config.Bucket, objectName)
+}
+
+func (manager *MinioManager) bucketObjectUrl(bucket, objectName string) string {
 	protocol := "http"
 	if manager.Config.UseSSL {
 		protocol = "https"
 	}
-	return protocol + "://" + manager.Config.EndPoint + "/" + manager.Config.Bucket + "/" + objectName
+	return protocol + "://" + manager.Config.EndPoint + "/" + bucket + "/" + objectName
 }
 
 func (manager *MinioManager) Upload(ctx context.Context, file *os.File, prefixes ...string) (UploadOutput, error) {
@@ -44,7 +50,47 @@ func (manager *MinioManager) Upload(ctx context.Context, file *os.File, prefixes
 
 	fileName := path.Join(manager.Config.Prefix, path.Join(prefixes...), path.Base(file.Name()))
 
-	_, err = minioClient.FPutObject(ctx, manager.Config.Bucket, fileName, file.Name(), minio.PutObjectOptions{})
+	checksums, err := computeFileChecksums(file)
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("computing checksums of %s: %w", file.Name(), err)
+	}
+
+	// minio verifies the md5 of every part, while the md5 of the whole file is kept to verify downloads
+	_, err = minioClient.FPutObject(ctx, manager.Config.Bucket, fileName, file.Name(), minio.PutObjectOptions{
+		SendContentMd5: true,
+		UserMetadata:   map[string]string{md5MetadataKey: hex.EncodeToString(checksums.md5)},
+	})
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	return UploadOutput{Location: manager.ObjectUrl(fileName), ObjectName: fileName}, nil
+}
+
+// UploadReader streams the contents of rdr to minio under objName, using a multipart upload since the size is unknown
+func (manager *MinioManager) UploadReader(ctx context.Context, objName string, rdr io.Reader) (UploadOutput, error) {
+	if manager.Config.Bucket == "" {
+		return UploadOutput{}, errors.New("no storage bucket configured to uploader")
+	}
+
+	minioClient, err := manager.getClient()
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	if err = minioClient.MakeBucket(ctx, manager.Config.Bucket, minio.MakeBucketOptions{Region: "us-east-1"}); err != nil {
+		exists, errBucketExists := minioClient.BucketExists(ctx, manager.Config.Bucket)
+		if !(errBucketExists == nil && exists) {
+			return UploadOutput{}, err
+		}
+	}
+
+	fileName := path.Join(manager.Config.Prefix, objName)
+
+	_, err = minioClient.PutObject(ctx, manager.Config.Bucket, fileName, rdr, -1, minio.PutObjectOptions{})
 	if err != nil {
 		return UploadOutput{}, err
 	}
@@ -65,6 +111,71 @@ func (manager *MinioManager) Download(ctx context.Context, file *os.File, key st
 	return err
 }
 
+// DownloadRange writes the contents of key starting from offset to output
+func (manager *MinioManager) DownloadRange(ctx context.Context, output io.Writer, key string, offset int64) error {
+	minioClient, err := manager.getClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	opts := minio.GetObjectOptions{}
+	if offset > 0 {
+		if err = opts.SetRange(offset, 0); err != nil {
+			return err
+		}
+	}
+	obj, err := minioClient.GetObject(ctx, manager.Config.Bucket, key, opts)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	_, err = io.Copy(output, obj)
+	if minio.ToErrorResponse(err).Code == ErrKeyNotFound.Error() {
+		return ErrKeyNotFound
+	}
+	return err
+}
+
+// GetObjectInfo returns the size and md5 digest of key. The digest is the one stored in the metadata of the object on
+// upload, nil if there is none: ETags aren't digests of the contents of objects encrypted with KMS keys.
+func (manager *MinioManager) GetObjectInfo(ctx context.Context, key string) (ObjectInfo, error) {
+	minioClient, err := manager.getClient()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	info, err := minioClient.StatObject(ctx, manager.Config.Bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == ErrKeyNotFound.Error() {
+			return ObjectInfo{}, ErrKeyNotFound
+		}
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{Key: key, Size: info.Size, MD5: md5FromMetadata(info.UserMetadata)}, nil
+}
+
+// GetPresignedURL returns a url granting read access to key until expiry elapses
+func (manager *MinioManager) GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	minioClient, err := manager.getClient()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := minioClient.PresignedGetObject(ctx, manager.Config.Bucket, key, expiry, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
 /*
 GetObjectNameFromLocation gets the object name/key name from the object location url
 
@@ -111,6 +222,32 @@ func (manager *MinioManager) DeleteObjects(ctx context.Context, keys []string) (
 	return tmp.Err
 }
 
+// Copy copies sourceKey to destinationKey in destinationBucket without downloading the object.
+// If destinationBucket is empty, the object is copied within the configured bucket.
+func (manager *MinioManager) Copy(ctx context.Context, sourceKey, destinationBucket, destinationKey string) (UploadOutput, error) {
+	if destinationBucket == "" {
+		destinationBucket = manager.Config.Bucket
+	}
+
+	minioClient, err := manager.getClient()
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	_, err = minioClient.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: destinationBucket, Object: destinationKey},
+		minio.CopySrcOptions{Bucket: manager.Config.Bucket, Object: sourceKey},
+	)
+	if err != nil {
+		return UploadOutput{}, err
+	}
+
+	return UploadOutput{Location: manager.bucketObjectUrl(destinationBucket, destinationKey), ObjectName: destinationKey}, nil
+}
+
 func (manager *MinioManager) ListFilesWithPrefix(_ context.Context, startAfter, prefix string, maxItems int64) (fileObjects []*FileObject, err error) {
 	if !manager.Config.IsTruncated {
 		pkgLogger.Infof("Manager is truncated: %v so returning here", manager.Config.IsTruncated)
@@ -0,0 +1,33 @@
+package filemanager
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// BucketPolicies are the policies of a bucket that can lead objects written to it to be exposed or lost
+type BucketPolicies struct {
+	// Public is whether the objects of the bucket can be read publicly
+	Public bool
+	// Encrypted is whether the objects of the bucket are encrypted at rest
+	Encrypted bool
+	// Expirations are the lifecycle rules of the bucket deleting objects
+	Expirations []ExpirationRule
+}
+
+// ExpirationRule deletes the objects under Prefix once they are older than After
+type ExpirationRule struct {
+	Prefix string
+	After  time.Duration
+}
+
+// Matches returns whether the rule deletes objects under the prefix
+func (r ExpirationRule) Matches(prefix string) bool {
+	return strings.HasPrefix(prefix, r.Prefix) || strings.HasPrefix(r.Prefix, prefix)
+}
+
+// PolicyInspector is implemented by the file managers able to inspect the policies of their bucket
+type PolicyInspector interface {
+	BucketPolicies(ctx context.Context) (BucketPolicies, error)
+}
@@ -2,8 +2,10 @@ package filemanager
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path"
@@ -18,17 +20,26 @@ import (
 	"github.com/mitchellh/mapstructure"
 	appConfig "github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/utils/awsutils"
+	"github.com/rudderlabs/rudder-server/utils/misc"
 )
 
 // Upload passed in file to s3
 func (manager *S3Manager) Upload(ctx context.Context, file *os.File, prefixes ...string) (UploadOutput, error) {
 	fileName := path.Join(manager.Config.Prefix, path.Join(prefixes...), path.Base(file.Name()))
 
+	checksums, err := computeFileChecksums(file)
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("computing checksums of %s: %w", file.Name(), err)
+	}
+
+	// s3 verifies the crc32c checksum of every part, while the md5 of the whole file is kept to verify downloads
 	uploadInput := &awsS3Manager.UploadInput{
-		ACL:    aws.String("bucket-owner-full-control"),
-		Bucket: aws.String(manager.Config.Bucket),
-		Key:    aws.String(fileName),
-		Body:   file,
+		ACL:               aws.String("bucket-owner-full-control"),
+		Bucket:            aws.String(manager.Config.Bucket),
+		Key:               aws.String(fileName),
+		Body:              file,
+		ChecksumAlgorithm: aws.String(s3.ChecksumAlgorithmCrc32c),
+		Metadata:          aws.StringMap(map[string]string{md5MetadataKey: hex.EncodeToString(checksums.md5)}),
 	}
 	if manager.Config.EnableSSE {
 		uploadInput.ServerSideEncryption = aws.String("AES256")
@@ -54,6 +65,41 @@ func (manager *S3Manager) Upload(ctx context.Context, file *os.File, prefixes ..
 	return UploadOutput{Location: output.Location, ObjectName: fileName}, err
 }
 
+// UploadReader streams the contents of rdr to s3 under objName, using a multipart upload for large payloads
+func (manager *S3Manager) UploadReader(ctx context.Context, objName string, rdr io.Reader) (UploadOutput, error) {
+	fileName := path.Join(manager.Config.Prefix, objName)
+
+	uploadInput := &awsS3Manager.UploadInput{
+		ACL:               aws.String("bucket-owner-full-control"),
+		Bucket:            aws.String(manager.Config.Bucket),
+		Key:               aws.String(fileName),
+		Body:              rdr,
+		ChecksumAlgorithm: aws.String(s3.ChecksumAlgorithmCrc32c),
+	}
+	if manager.Config.EnableSSE {
+		uploadInput.ServerSideEncryption = aws.String("AES256")
+	}
+
+	uploadSession, err := manager.getSession(ctx)
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("error starting S3 session: %w", err)
+	}
+	s3manager := awsS3Manager.NewUploader(uploadSession)
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	output, err := s3manager.UploadWithContext(ctx, uploadInput)
+	if err != nil {
+		if awsError, ok := err.(awserr.Error); ok && awsError.Code() == "MissingRegion" {
+			err = fmt.Errorf(fmt.Sprintf(`Bucket '%s' not found.`, manager.Config.Bucket))
+		}
+		return UploadOutput{}, err
+	}
+
+	return UploadOutput{Location: output.Location, ObjectName: fileName}, nil
+}
+
 func (manager *S3Manager) Download(ctx context.Context, output *os.File, key string) error {
 	sess, err := manager.getSession(ctx)
 	if err != nil {
@@ -79,6 +125,82 @@ func (manager *S3Manager) Download(ctx context.Context, output *os.File, key str
 	return nil
 }
 
+// DownloadRange writes the contents of key starting from offset to output
+func (manager *S3Manager) DownloadRange(ctx context.Context, output io.Writer, key string, offset int64) error {
+	sess, err := manager.getSession(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting S3 session: %w", err)
+	}
+	svc := s3.New(sess)
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(manager.Config.Bucket),
+		Key:    aws.String(key),
+	}
+	if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := svc.GetObjectWithContext(ctx, input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ErrKeyNotFound.Error() {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(output, resp.Body)
+	return err
+}
+
+// GetObjectInfo returns the size and md5 digest of key. The digest is the one stored in the metadata of the object on
+// upload, nil if there is none: ETags aren't digests of the contents of objects encrypted with KMS keys.
+func (manager *S3Manager) GetObjectInfo(ctx context.Context, key string) (ObjectInfo, error) {
+	sess, err := manager.getSession(ctx)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("error starting S3 session: %w", err)
+	}
+	svc := s3.New(sess)
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	resp, err := svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(manager.Config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == ErrKeyNotFound.Error() || aerr.Code() == "NotFound") {
+			return ObjectInfo{}, ErrKeyNotFound
+		}
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{
+		Key:  key,
+		Size: aws.Int64Value(resp.ContentLength),
+		MD5:  md5FromMetadata(aws.StringValueMap(resp.Metadata)),
+	}, nil
+}
+
+// GetPresignedURL returns a url granting read access to key until expiry elapses
+func (manager *S3Manager) GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	sess, err := manager.getSession(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error starting S3 session: %w", err)
+	}
+	svc := s3.New(sess)
+
+	req, _ := svc.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(manager.Config.Bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expiry)
+}
+
 /*
 GetObjectNameFromLocation gets the object name/key name from the object location url
 
@@ -152,6 +274,56 @@ func (manager *S3Manager) DeleteObjects(ctx context.Context, keys []string) (err
 	return nil
 }
 
+// Copy copies sourceKey to destinationKey in destinationBucket without downloading the object, using CopyObject.
+// If destinationBucket is empty, the object is copied within the configured bucket.
+func (manager *S3Manager) Copy(ctx context.Context, sourceKey, destinationBucket, destinationKey string) (UploadOutput, error) {
+	if destinationBucket == "" {
+		destinationBucket = manager.Config.Bucket
+	}
+
+	sess, err := manager.getSession(ctx)
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("error starting S3 session: %w", err)
+	}
+	svc := s3.New(sess)
+
+	copyInput := &s3.CopyObjectInput{
+		ACL:        aws.String("bucket-owner-full-control"),
+		Bucket:     aws.String(destinationBucket),
+		Key:        aws.String(destinationKey),
+		CopySource: aws.String(url.PathEscape(path.Join(manager.Config.Bucket, sourceKey))),
+	}
+	if manager.Config.EnableSSE {
+		copyInput.ServerSideEncryption = aws.String("AES256")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	if _, err = svc.CopyObjectWithContext(ctx, copyInput); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ErrKeyNotFound.Error() {
+			return UploadOutput{}, ErrKeyNotFound
+		}
+		return UploadOutput{}, err
+	}
+
+	return UploadOutput{Location: objectLocation(svc, destinationBucket, destinationKey), ObjectName: destinationKey}, nil
+}
+
+// objectLocation returns the url of an object, in the same format as the one returned by s3manager uploads
+func objectLocation(svc *s3.S3, bucket, key string) string {
+	req, _ := svc.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err := req.Build(); err != nil {
+		return ""
+	}
+	location := *req.HTTPRequest.URL
+	location.RawQuery = ""
+	return location.String()
+}
+
 func (manager *S3Manager) getSession(ctx context.Context) (*session.Session, error) {
 	if manager.session != nil {
 		return manager.session, nil
@@ -289,3 +461,83 @@ type S3Config struct {
 	IsTruncated       bool    `mapstructure:"isTruncated"`
 	UseGlue           bool    `mapstructure:"useGlue"`
 }
+
+// s3PublicGroups are the grantees of ACL grants making buckets public
+var s3PublicGroups = []string{
+	"http://acs.amazonaws.com/groups/global/AllUsers",
+	"http://acs.amazonaws.com/groups/global/AuthenticatedUsers",
+}
+
+// BucketPolicies inspects the encryption, public access and lifecycle rules of the bucket
+func (manager *S3Manager) BucketPolicies(ctx context.Context) (BucketPolicies, error) {
+	var policies BucketPolicies
+	sess, err := manager.getSession(ctx)
+	if err != nil {
+		return policies, fmt.Errorf("error starting S3 session: %w", err)
+	}
+	svc := s3.New(sess)
+	bucket := aws.String(manager.Config.Bucket)
+
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+
+	policies.Encrypted = manager.Config.EnableSSE
+	if _, err := svc.GetBucketEncryptionWithContext(ctx, &s3.GetBucketEncryptionInput{Bucket: bucket}); err == nil {
+		policies.Encrypted = true
+	} else if !isAWSErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+		return policies, fmt.Errorf("getting bucket encryption: %w", err)
+	}
+
+	if status, err := svc.GetBucketPolicyStatusWithContext(ctx, &s3.GetBucketPolicyStatusInput{Bucket: bucket}); err == nil {
+		policies.Public = status.PolicyStatus != nil && aws.BoolValue(status.PolicyStatus.IsPublic)
+	} else if !isAWSErrorCode(err, "NoSuchBucketPolicy") {
+		return policies, fmt.Errorf("getting bucket policy status: %w", err)
+	}
+	acl, err := svc.GetBucketAclWithContext(ctx, &s3.GetBucketAclInput{Bucket: bucket})
+	if err != nil {
+		return policies, fmt.Errorf("getting bucket acl: %w", err)
+	}
+	for _, grant := range acl.Grants {
+		if grant.Grantee != nil && misc.Contains(s3PublicGroups, aws.StringValue(grant.Grantee.URI)) {
+			policies.Public = true
+		}
+	}
+	if block, err := svc.GetPublicAccessBlockWithContext(ctx, &s3.GetPublicAccessBlockInput{Bucket: bucket}); err == nil {
+		if c := block.PublicAccessBlockConfiguration; c != nil && aws.BoolValue(c.IgnorePublicAcls) && aws.BoolValue(c.RestrictPublicBuckets) {
+			policies.Public = false
+		}
+	} else if !isAWSErrorCode(err, "NoSuchPublicAccessBlockConfiguration") {
+		return policies, fmt.Errorf("getting public access block: %w", err)
+	}
+
+	lifecycle, err := svc.GetBucketLifecycleConfigurationWithContext(ctx, &s3.GetBucketLifecycleConfigurationInput{Bucket: bucket})
+	if err != nil {
+		if isAWSErrorCode(err, "NoSuchLifecycleConfiguration") {
+			return policies, nil
+		}
+		return policies, fmt.Errorf("getting bucket lifecycle configuration: %w", err)
+	}
+	for _, rule := range lifecycle.Rules {
+		if aws.StringValue(rule.Status) != s3.ExpirationStatusEnabled || rule.Expiration == nil || rule.Expiration.Days == nil {
+			continue
+		}
+		prefix := aws.StringValue(rule.Prefix) // nolint:staticcheck // rules without filters still have a prefix
+		if rule.Filter != nil {
+			if rule.Filter.Prefix != nil {
+				prefix = aws.StringValue(rule.Filter.Prefix)
+			} else if rule.Filter.And != nil {
+				prefix = aws.StringValue(rule.Filter.And.Prefix)
+			}
+		}
+		policies.Expirations = append(policies.Expirations, ExpirationRule{
+			Prefix: prefix,
+			After:  time.Duration(aws.Int64Value(rule.Expiration.Days)) * 24 * time.Hour,
+		})
+	}
+	return policies, nil
+}
+
+func isAWSErrorCode(err error, code string) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == code
+}
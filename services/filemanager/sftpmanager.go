@@ -0,0 +1,525 @@
+package filemanager
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	ProtocolSFTP = "sftp"
+	ProtocolFTPS = "ftps"
+)
+
+// SFTPManager transfers files to SFTP or FTPS servers, the servers of many legacy partners.
+// Keys are paths relative to the home directory of the user, unless absolute.
+// A connection is opened for every operation, the servers not being expected to serve frequent requests.
+type SFTPManager struct {
+	Config  *SFTPConfig
+	timeout time.Duration
+	// dial opens a connection to the server, overridden in tests
+	dial func(ctx context.Context) (fileTransferClient, error)
+}
+
+type SFTPConfig struct {
+	Protocol string // sftp or ftps
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// PrivateKey is the PEM encoded private key used to authenticate against SFTP servers, alternatively to the password
+	PrivateKey string
+	// HostKey is the public key of SFTP servers in authorized_keys format, required for the servers to be verified
+	HostKey string
+	// ImplicitTLS makes FTPS connections use implicit instead of explicit TLS
+	ImplicitTLS bool
+	Prefix      string
+}
+
+// fileTransferClient is a connection to an SFTP or FTPS server
+type fileTransferClient interface {
+	mkdirAll(dir string) error
+	put(key string, rdr io.Reader) error
+	rename(from, to string) error
+	get(key string, offset int64, output io.Writer) error
+	size(key string) (int64, error)
+	remove(key string) error
+	list(dir string) ([]*FileObject, error)
+	close() error
+}
+
+func NewSFTPManager(config *SFTPConfig) *SFTPManager {
+	manager := &SFTPManager{Config: config}
+	manager.dial = manager.dialServer
+	return manager
+}
+
+func GetSFTPConfig(config map[string]interface{}) *SFTPConfig {
+	getString := func(key string) string {
+		value, _ := config[key].(string)
+		return strings.TrimSpace(value)
+	}
+	protocol := strings.ToLower(getString("protocol"))
+	if protocol == "" {
+		protocol = ProtocolSFTP
+	}
+	var port int
+	switch value := config["port"].(type) {
+	case float64:
+		port = int(value)
+	case int:
+		port = value
+	case string:
+		port, _ = strconv.Atoi(value)
+	}
+	if port == 0 {
+		port = 22
+		if protocol == ProtocolFTPS {
+			port = 21
+		}
+	}
+	implicitTLS, _ := config["implicitTLS"].(bool)
+	return &SFTPConfig{
+		Protocol:    protocol,
+		Host:        getString("host"),
+		Port:        port,
+		Username:    getString("username"),
+		Password:    getString("password"),
+		PrivateKey:  getString("privateKey"),
+		HostKey:     getString("hostKey"),
+		ImplicitTLS: implicitTLS,
+		Prefix:      getString("prefix"),
+	}
+}
+
+func (manager *SFTPManager) address() string {
+	return net.JoinHostPort(manager.Config.Host, strconv.Itoa(manager.Config.Port))
+}
+
+// location returns the url of key, e.g. sftp://host:22/path/to/key
+func (manager *SFTPManager) location(key string) string {
+	return manager.Config.Protocol + "://" + manager.address() + "/" + key
+}
+
+// Upload uploads file under the prefix of the manager joined with prefixes. The file is written under a temporary
+// name first and renamed once complete, so that partners polling the directory never pick up partial files.
+func (manager *SFTPManager) Upload(ctx context.Context, file *os.File, prefixes ...string) (UploadOutput, error) {
+	dir := path.Join(manager.Config.Prefix, path.Join(prefixes...))
+	return manager.upload(ctx, dir, path.Join(dir, path.Base(file.Name())), file)
+}
+
+// UploadReader uploads the contents of rdr under objName, relative to the prefix of the manager
+func (manager *SFTPManager) UploadReader(ctx context.Context, objName string, rdr io.Reader) (UploadOutput, error) {
+	key := path.Join(manager.Config.Prefix, objName)
+	return manager.upload(ctx, path.Dir(key), key, rdr)
+}
+
+func (manager *SFTPManager) upload(ctx context.Context, dir, key string, rdr io.Reader) (UploadOutput, error) {
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+	client, err := manager.dial(ctx)
+	if err != nil {
+		return UploadOutput{}, err
+	}
+	defer func() { _ = client.close() }()
+
+	if dir != "" && dir != "." {
+		if err := client.mkdirAll(dir); err != nil {
+			return UploadOutput{}, fmt.Errorf("creating directory %s: %w", dir, err)
+		}
+	}
+	partKey := key + ".part"
+	if err := client.put(partKey, rdr); err != nil {
+		return UploadOutput{}, fmt.Errorf("writing %s: %w", partKey, err)
+	}
+	if err := client.rename(partKey, key); err != nil {
+		return UploadOutput{}, fmt.Errorf("renaming %s to %s: %w", partKey, key, err)
+	}
+	return UploadOutput{Location: manager.location(key), ObjectName: key}, nil
+}
+
+func (manager *SFTPManager) Download(ctx context.Context, file *os.File, key string) error {
+	return manager.DownloadRange(ctx, file, key, 0)
+}
+
+// DownloadRange writes the contents of key starting from offset to output
+func (manager *SFTPManager) DownloadRange(ctx context.Context, output io.Writer, key string, offset int64) error {
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+	client, err := manager.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.close() }()
+	return client.get(key, offset, output)
+}
+
+// GetObjectInfo returns the size of key, the servers exposing no digest of the contents of files
+func (manager *SFTPManager) GetObjectInfo(ctx context.Context, key string) (ObjectInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+	client, err := manager.dial(ctx)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer func() { _ = client.close() }()
+	size, err := client.size(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Key: key, Size: size}, nil
+}
+
+func (*SFTPManager) GetPresignedURL(context.Context, string, time.Duration) (string, error) {
+	return "", errors.New("presigned urls are not supported by file transfer servers")
+}
+
+/*
+GetObjectNameFromLocation gets the key from the location url
+
+	sftp://host:22/path/to/key - >> path/to/key
+	sftp://host:22//absolute/path/to/key - >> /absolute/path/to/key
+*/
+func (*SFTPManager) GetObjectNameFromLocation(location string) (string, error) {
+	parsedURL, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(parsedURL.Path, "/"), nil
+}
+
+func (manager *SFTPManager) GetDownloadKeyFromFileLocation(location string) string {
+	key, _ := manager.GetObjectNameFromLocation(location)
+	return key
+}
+
+func (manager *SFTPManager) DeleteObjects(ctx context.Context, keys []string) error {
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+	client, err := manager.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.close() }()
+	for _, key := range keys {
+		if err := client.remove(key); err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return fmt.Errorf("deleting %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (*SFTPManager) Copy(context.Context, string, string, string) (UploadOutput, error) {
+	return UploadOutput{}, errors.New("copying files is not supported by file transfer servers")
+}
+
+// ListFilesWithPrefix lists the files of the directory of prefix whose keys start with prefix, in lexical order.
+// Subdirectories aren't listed recursively.
+func (manager *SFTPManager) ListFilesWithPrefix(ctx context.Context, startAfter, prefix string, maxItems int64) ([]*FileObject, error) {
+	ctx, cancel := context.WithTimeout(ctx, manager.getTimeout())
+	defer cancel()
+	client, err := manager.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = client.close() }()
+
+	dir := prefix
+	if !strings.HasSuffix(prefix, "/") {
+		dir = path.Dir(prefix)
+	}
+	files, err := client.list(dir)
+	if errors.Is(err, ErrKeyNotFound) {
+		return []*FileObject{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fileObjects := make([]*FileObject, 0, len(files))
+	for _, file := range files {
+		if strings.HasPrefix(file.Key, strings.TrimPrefix(prefix, "./")) && file.Key > startAfter {
+			fileObjects = append(fileObjects, file)
+		}
+	}
+	sort.Slice(fileObjects, func(i, j int) bool { return fileObjects[i].Key < fileObjects[j].Key })
+	if maxItems > 0 && int64(len(fileObjects)) > maxItems {
+		fileObjects = fileObjects[:maxItems]
+	}
+	return fileObjects, nil
+}
+
+func (manager *SFTPManager) GetConfiguredPrefix() string {
+	return manager.Config.Prefix
+}
+
+func (manager *SFTPManager) SetTimeout(timeout time.Duration) {
+	manager.timeout = timeout
+}
+
+func (manager *SFTPManager) getTimeout() time.Duration {
+	if manager.timeout > 0 {
+		return manager.timeout
+	}
+	return getBatchRouterTimeoutConfig("SFTP")
+}
+
+// dialServer connects and authenticates to the server, the deadline of ctx applying to all operations of the connection
+func (manager *SFTPManager) dialServer(ctx context.Context) (fileTransferClient, error) {
+	if manager.Config.Host == "" {
+		return nil, errors.New("no host configured to file manager")
+	}
+	dial := func(network, address string) (net.Conn, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+		return conn, nil
+	}
+	switch manager.Config.Protocol {
+	case ProtocolSFTP:
+		return manager.dialSFTP(dial)
+	case ProtocolFTPS:
+		return manager.dialFTPS(dial)
+	}
+	return nil, fmt.Errorf("unsupported file transfer protocol: %q", manager.Config.Protocol)
+}
+
+func (manager *SFTPManager) dialSFTP(dial func(network, address string) (net.Conn, error)) (fileTransferClient, error) {
+	var auth []ssh.AuthMethod
+	if manager.Config.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(manager.Config.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("parsing private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if manager.Config.Password != "" {
+		auth = append(auth, ssh.Password(manager.Config.Password))
+	}
+	// servers are never trusted without their host key
+	if manager.Config.HostKey == "" {
+		return nil, errors.New("no host key configured to file manager")
+	}
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(manager.Config.HostKey))
+	if err != nil {
+		return nil, fmt.Errorf("parsing host key: %w", err)
+	}
+
+	conn, err := dial("tcp", manager.address())
+	if err != nil {
+		return nil, err
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, manager.address(), &ssh.ClientConfig{
+		User:            manager.Config.Username,
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("connecting to %s: %w", manager.address(), err)
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, fmt.Errorf("starting sftp session: %w", err)
+	}
+	return &sftpClient{client: client, closer: sshClient}, nil
+}
+
+func (manager *SFTPManager) dialFTPS(dial func(network, address string) (net.Conn, error)) (fileTransferClient, error) {
+	tlsConfig := &tls.Config{ServerName: manager.Config.Host, MinVersion: tls.VersionTLS12}
+	tlsOption := ftp.DialWithExplicitTLS(tlsConfig)
+	if manager.Config.ImplicitTLS {
+		tlsOption = ftp.DialWithTLS(tlsConfig)
+	}
+	conn, err := ftp.Dial(manager.address(), ftp.DialWithDialFunc(dial), tlsOption)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", manager.address(), err)
+	}
+	if err := conn.Login(manager.Config.Username, manager.Config.Password); err != nil {
+		_ = conn.Quit()
+		return nil, fmt.Errorf("logging in to %s: %w", manager.address(), err)
+	}
+	return &ftpsClient{conn: conn}, nil
+}
+
+type sftpClient struct {
+	client *sftp.Client
+	closer io.Closer // the underlying ssh connection, nil if none
+}
+
+func (c *sftpClient) mkdirAll(dir string) error {
+	return c.client.MkdirAll(dir)
+}
+
+func (c *sftpClient) put(key string, rdr io.Reader) error {
+	file, err := c.client.Create(key)
+	if err != nil {
+		return err
+	}
+	if _, err := file.ReadFrom(rdr); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (c *sftpClient) rename(from, to string) error {
+	// the target may exist if an upload is retried, which posix renames allow
+	if err := c.client.PosixRename(from, to); err == nil {
+		return nil
+	}
+	_ = c.client.Remove(to)
+	return c.client.Rename(from, to)
+}
+
+func (c *sftpClient) get(key string, offset int64, output io.Writer) error {
+	file, err := c.client.Open(key)
+	if err != nil {
+		return sftpError(err)
+	}
+	defer func() { _ = file.Close() }()
+	if offset > 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	_, err = io.Copy(output, file)
+	return err
+}
+
+func (c *sftpClient) size(key string) (int64, error) {
+	info, err := c.client.Stat(key)
+	if err != nil {
+		return 0, sftpError(err)
+	}
+	return info.Size(), nil
+}
+
+func (c *sftpClient) remove(key string) error {
+	return sftpError(c.client.Remove(key))
+}
+
+func (c *sftpClient) list(dir string) ([]*FileObject, error) {
+	infos, err := c.client.ReadDir(dir)
+	if err != nil {
+		return nil, sftpError(err)
+	}
+	files := make([]*FileObject, 0, len(infos))
+	for _, info := range infos {
+		if info.Mode().IsRegular() {
+			files = append(files, &FileObject{Key: path.Join(dir, info.Name()), LastModified: info.ModTime()})
+		}
+	}
+	return files, nil
+}
+
+func (c *sftpClient) close() error {
+	err := c.client.Close()
+	if c.closer != nil {
+		_ = c.closer.Close()
+	}
+	return err
+}
+
+func sftpError(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrKeyNotFound
+	}
+	return err
+}
+
+type ftpsClient struct {
+	conn *ftp.ServerConn
+}
+
+func (c *ftpsClient) mkdirAll(dir string) error {
+	var current string
+	if strings.HasPrefix(dir, "/") {
+		current = "/"
+	}
+	for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+		current = path.Join(current, part)
+		// the directory may already exist, failures to create it surface when writing files into it
+		_ = c.conn.MakeDir(current)
+	}
+	return nil
+}
+
+func (c *ftpsClient) put(key string, rdr io.Reader) error {
+	return c.conn.Stor(key, rdr)
+}
+
+func (c *ftpsClient) rename(from, to string) error {
+	if err := c.conn.Rename(from, to); err == nil {
+		return nil
+	}
+	_ = c.conn.Delete(to)
+	return c.conn.Rename(from, to)
+}
+
+func (c *ftpsClient) get(key string, offset int64, output io.Writer) error {
+	response, err := c.conn.RetrFrom(key, uint64(offset))
+	if err != nil {
+		return ftpError(err)
+	}
+	defer func() { _ = response.Close() }()
+	_, err = io.Copy(output, response)
+	return err
+}
+
+func (c *ftpsClient) size(key string) (int64, error) {
+	size, err := c.conn.FileSize(key)
+	return size, ftpError(err)
+}
+
+func (c *ftpsClient) remove(key string) error {
+	return ftpError(c.conn.Delete(key))
+}
+
+func (c *ftpsClient) list(dir string) ([]*FileObject, error) {
+	entries, err := c.conn.List(dir)
+	if err != nil {
+		return nil, ftpError(err)
+	}
+	files := make([]*FileObject, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type == ftp.EntryTypeFile {
+			files = append(files, &FileObject{Key: path.Join(dir, entry.Name), LastModified: entry.Time})
+		}
+	}
+	return files, nil
+}
+
+func (c *ftpsClient) close() error {
+	return c.conn.Quit()
+}
+
+// ftpError returns ErrKeyNotFound for the "file unavailable" replies of ftp servers
+func ftpError(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code == ftp.StatusFileUnavailable {
+		return ErrKeyNotFound
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package filemanager
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+)
+
+type pipeReadWriteCloser struct {
+	*io.PipeReader
+	*io.PipeWriter
+}
+
+func (p pipeReadWriteCloser) Close() error {
+	_ = p.PipeReader.Close()
+	return p.PipeWriter.Close()
+}
+
+// dialLocalSFTP returns a dial function serving the local filesystem through an in-process sftp server
+func dialLocalSFTP(t *testing.T) func(context.Context) (fileTransferClient, error) {
+	return func(context.Context) (fileTransferClient, error) {
+		serverReader, clientWriter := io.Pipe()
+		clientReader, serverWriter := io.Pipe()
+		server, err := sftp.NewServer(pipeReadWriteCloser{serverReader, serverWriter})
+		require.NoError(t, err)
+		go func() {
+			// the server stops once the client closes its end of the pipe
+			_ = server.Serve()
+			_ = server.Close()
+		}()
+		client, err := sftp.NewClientPipe(clientReader, clientWriter)
+		if err != nil {
+			return nil, err
+		}
+		return &sftpClient{client: client}, nil
+	}
+}
+
+func TestGetSFTPConfig(t *testing.T) {
+	config := GetSFTPConfig(map[string]interface{}{"host": " sftp.example.com ", "username": "rudder", "privateKey": "key"})
+	require.Equal(t, &SFTPConfig{Protocol: ProtocolSFTP, Host: "sftp.example.com", Port: 22, Username: "rudder", PrivateKey: "key"}, config)
+
+	config = GetSFTPConfig(map[string]interface{}{"protocol": "FTPS", "host": "ftp.example.com", "port": "990", "implicitTLS": true, "prefix": "/inbound"})
+	require.Equal(t, &SFTPConfig{Protocol: ProtocolFTPS, Host: "ftp.example.com", Port: 990, ImplicitTLS: true, Prefix: "/inbound"}, config)
+
+	config = GetSFTPConfig(map[string]interface{}{"protocol": "ftps", "port": float64(0)})
+	require.Equal(t, 21, config.Port)
+}
+
+func TestSFTPManager(t *testing.T) {
+	root := t.TempDir()
+	manager := NewSFTPManager(&SFTPConfig{Protocol: ProtocolSFTP, Host: "sftp.example.com", Port: 2222, Prefix: root})
+	manager.dial = dialLocalSFTP(t)
+	ctx := context.Background()
+
+	localFile, err := os.Create(filepath.Join(t.TempDir(), "batch.json.gz"))
+	require.NoError(t, err)
+	_, err = localFile.WriteString("contents of the batch")
+	require.NoError(t, err)
+	_, err = localFile.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	output, err := manager.Upload(ctx, localFile, "exports", "2022-10-10")
+	require.NoError(t, err)
+	key := root + "/exports/2022-10-10/batch.json.gz"
+	require.Equal(t, key, output.ObjectName)
+	require.Equal(t, "sftp://sftp.example.com:2222/"+key, output.Location)
+	name, err := manager.GetObjectNameFromLocation(output.Location)
+	require.NoError(t, err)
+	require.Equal(t, key, name)
+	uploaded, err := os.ReadFile(key)
+	require.NoError(t, err)
+	require.Equal(t, "contents of the batch", string(uploaded))
+	_, err = os.Stat(key + ".part")
+	require.True(t, os.IsNotExist(err), "the temporary file is renamed")
+
+	t.Run("overwrites files", func(t *testing.T) {
+		_, err := manager.UploadReader(ctx, "exports/2022-10-10/batch.json.gz", strings.NewReader("new contents of the batch"))
+		require.NoError(t, err)
+		uploaded, err := os.ReadFile(key)
+		require.NoError(t, err)
+		require.Equal(t, "new contents of the batch", string(uploaded))
+	})
+
+	t.Run("downloads files", func(t *testing.T) {
+		info, err := manager.GetObjectInfo(ctx, key)
+		require.NoError(t, err)
+		require.EqualValues(t, len("new contents of the batch"), info.Size)
+		var buf bytes.Buffer
+		require.NoError(t, manager.DownloadRange(ctx, &buf, key, 4))
+		require.Equal(t, "contents of the batch", buf.String())
+		_, err = manager.GetObjectInfo(ctx, key+".missing")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("lists files", func(t *testing.T) {
+		_, err := manager.UploadReader(ctx, "exports/2022-10-10/another.json.gz", strings.NewReader("another batch"))
+		require.NoError(t, err)
+		files, err := manager.ListFilesWithPrefix(ctx, "", root+"/exports/2022-10-10/", 10)
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		require.Equal(t, root+"/exports/2022-10-10/another.json.gz", files[0].Key)
+		files, err = manager.ListFilesWithPrefix(ctx, "", root+"/exports/2022-10-10/b", 10)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		files, err = manager.ListFilesWithPrefix(ctx, "", root+"/missing/", 10)
+		require.NoError(t, err)
+		require.Empty(t, files)
+	})
+
+	t.Run("deletes files", func(t *testing.T) {
+		require.NoError(t, manager.DeleteObjects(ctx, []string{key, key + ".missing"}))
+		_, err := os.Stat(key)
+		require.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestSFTPManagerRequiresHostKey(t *testing.T) {
+	manager := NewSFTPManager(&SFTPConfig{Protocol: ProtocolSFTP, Host: "127.0.0.1", Port: 1, Username: "rudder", Password: "password"})
+	_, err := manager.dialServer(context.Background())
+	require.EqualError(t, err, "no host key configured to file manager", "servers aren't connected to without their host key")
+
+	manager.Config.HostKey = "not a key"
+	_, err = manager.dialServer(context.Background())
+	require.ErrorContains(t, err, "parsing host key")
+}
@@ -0,0 +1,362 @@
+package filemanager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/googleapi"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+var (
+	budgetsMu       sync.Mutex
+	budgets         = map[string]*budget{}
+	providersLimits = map[string]*providerLimits{}
+)
+
+// providerLimits are the limits of the accounts of a provider, reloaded as they're changed in config
+type providerLimits struct {
+	maxConcurrency      int
+	maxBandwidthInMB    int
+	throttledMaxRetries int
+}
+
+// getProviderLimits returns the limits of the provider, read from FileManager.<provider>.<key>, falling back to
+// FileManager.<key>, registering them on first use. Must be called with budgetsMu held.
+func getProviderLimits(provider string) *providerLimits {
+	if l, ok := providersLimits[provider]; ok {
+		return l
+	}
+	l := &providerLimits{}
+	config.RegisterIntConfigVariable(0, &l.maxConcurrency, true, 1, "FileManager."+provider+".maxConcurrency", "FileManager.maxConcurrency")
+	config.RegisterIntConfigVariable(0, &l.maxBandwidthInMB, true, 1, "FileManager."+provider+".maxBandwidthInMB", "FileManager.maxBandwidthInMB")
+	config.RegisterIntConfigVariable(3, &l.throttledMaxRetries, true, 1, "FileManager."+provider+".throttledMaxRetries", "FileManager.throttledMaxRetries")
+	providersLimits[provider] = l
+	return l
+}
+
+// budget limits the number of concurrent requests and the bandwidth used against a provider account of a workspace.
+// It is shared by all file managers using the same provider & account within the process. Its limits are updated in
+// place as they're changed in config, for the requests in flight to keep counting against them.
+type budget struct {
+	provider    string
+	workspaceID string
+
+	mu                  sync.Mutex
+	maxConcurrency      int // 0 if concurrency is unlimited
+	maxBandwidthInMB    int
+	throttledMaxRetries int
+	inUse               int           // concurrency slots held by requests in flight
+	freed               chan struct{} // closed, then replaced, once slots are released or their number is raised
+	lastUsed            time.Time
+	limiter             *rate.Limiter // tokens are bytes, with an infinite rate if bandwidth is unlimited
+}
+
+// getBudget returns the budget for the account identified by settings, creating it on first use, with the current
+// limits of its provider
+func getBudget(settings *SettingsT) *budget {
+	key := settings.Provider + ":" + settings.WorkspaceID + ":" + budgetAccount(settings.Config)
+
+	budgetsMu.Lock()
+	defer budgetsMu.Unlock()
+	b, ok := budgets[key]
+	if !ok {
+		b = &budget{
+			provider:    settings.Provider,
+			workspaceID: settings.WorkspaceID,
+			freed:       make(chan struct{}),
+			lastUsed:    time.Now(),
+			limiter:     rate.NewLimiter(rate.Inf, 0),
+		}
+		budgets[key] = b
+	}
+	b.update(getProviderLimits(settings.Provider))
+	return b
+}
+
+// update applies the limits to the budget, waking up the requests waiting for a slot if the concurrency is changed
+func (b *budget) update(limits *providerLimits) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.throttledMaxRetries = limits.throttledMaxRetries
+	if b.maxConcurrency != limits.maxConcurrency {
+		b.maxConcurrency = limits.maxConcurrency
+		b.notifyFreed()
+	}
+	if b.maxBandwidthInMB != limits.maxBandwidthInMB {
+		b.maxBandwidthInMB = limits.maxBandwidthInMB
+		if b.maxBandwidthInMB > 0 {
+			bytesPerSec := b.maxBandwidthInMB * 1024 * 1024
+			b.limiter.SetBurst(bytesPerSec)
+			b.limiter.SetLimit(rate.Limit(bytesPerSec))
+		} else {
+			b.limiter.SetLimit(rate.Inf)
+		}
+	}
+}
+
+// notifyFreed wakes up the requests waiting for a slot. Must be called with b.mu held.
+func (b *budget) notifyFreed() {
+	close(b.freed)
+	b.freed = make(chan struct{})
+}
+
+// PruneBudgets evicts the budgets of workspaces no longer in the backend config, along with the ones unused for
+// FileManager.budgetIdleTimeout, e.g. of rotated credentials, whenever the config is updated, until ctx is done
+func PruneBudgets(ctx context.Context, backendConfig backendconfig.BackendConfig) {
+	for ev := range backendConfig.Subscribe(ctx, backendconfig.TopicBackendConfig) {
+		configs := ev.Data.(map[string]backendconfig.ConfigT)
+		pruneBudgets(func(workspaceID string) bool {
+			_, ok := configs[workspaceID]
+			return ok
+		})
+	}
+}
+
+// pruneBudgets evicts the budgets without requests in flight, either of workspaces which aren't live or unused for
+// FileManager.budgetIdleTimeout. Budgets of file managers without a workspace are only evicted once unused.
+func pruneBudgets(isWorkspaceLive func(workspaceID string) bool) {
+	idleTimeout := config.GetDuration("FileManager.budgetIdleTimeout", 1, time.Hour)
+
+	budgetsMu.Lock()
+	defer budgetsMu.Unlock()
+	for key, b := range budgets {
+		b.mu.Lock()
+		evict := b.inUse == 0 &&
+			((b.workspaceID != "" && !isWorkspaceLive(b.workspaceID)) || time.Since(b.lastUsed) > idleTimeout)
+		b.mu.Unlock()
+		if evict {
+			delete(budgets, key)
+		}
+	}
+}
+
+// budgetAccount returns the credential or bucket identifying the account the config belongs to
+func budgetAccount(config map[string]interface{}) string {
+	for _, key := range []string{"accessKeyID", "accountName", "iamRoleArn", "bucketName", "containerName", "host"} {
+		if v, ok := config[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// acquire blocks until a concurrency slot is available as per the current limit, returning the func releasing it
+func (b *budget) acquire(ctx context.Context) (release func(), err error) {
+	for {
+		b.mu.Lock()
+		b.lastUsed = time.Now()
+		if b.maxConcurrency <= 0 || b.inUse < b.maxConcurrency {
+			b.inUse++
+			b.mu.Unlock()
+			var once sync.Once
+			return func() { once.Do(b.release) }, nil
+		}
+		freed := b.freed
+		b.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (b *budget) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inUse--
+	b.notifyFreed()
+}
+
+// retries returns the number of times a throttled request is retried
+func (b *budget) retries() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.throttledMaxRetries
+}
+
+// consume blocks until n bytes can be transferred within the bandwidth budget
+func (b *budget) consume(ctx context.Context, n int64) error {
+	for n > 0 {
+		if b.limiter.Limit() == rate.Inf {
+			return nil
+		}
+		chunk := int64(b.limiter.Burst())
+		if n < chunk {
+			chunk = n
+		}
+		if err := b.limiter.WaitN(ctx, int(chunk)); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// isThrottlingError reports whether the provider rejected a request because of rate limiting (429) or overload (503)
+func isThrottlingError(err error) bool {
+	if err == nil {
+		return false
+	}
+	isThrottlingStatus := func(code int) bool {
+		return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+	}
+
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		return isThrottlingStatus(reqErr.StatusCode()) || reqErr.Code() == "SlowDown"
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return isThrottlingStatus(googleErr.Code)
+	}
+	var storageErr azblob.StorageError
+	if errors.As(err, &storageErr) && storageErr.Response() != nil {
+		return isThrottlingStatus(storageErr.Response().StatusCode)
+	}
+	if minioErr := minio.ToErrorResponse(err); minioErr.StatusCode != 0 {
+		return isThrottlingStatus(minioErr.StatusCode) || minioErr.Code == "SlowDown"
+	}
+	return false
+}
+
+// throttledFileManager enforces the budget of its provider account on all requests of the underlying file manager,
+// retrying with backoff whenever the provider responds with a throttling error.
+type throttledFileManager struct {
+	FileManager
+	settings *SettingsT
+}
+
+func newThrottledFileManager(manager FileManager, settings *SettingsT) FileManager {
+	return &throttledFileManager{
+		FileManager: manager,
+		settings:    settings,
+	}
+}
+
+// budget returns the current budget of the account, for the requests to be limited as per the latest limits
+func (m *throttledFileManager) budget() *budget {
+	return getBudget(m.settings)
+}
+
+// withRetries runs fn while holding a concurrency slot, retrying it with backoff on throttling errors
+func (m *throttledFileManager) withRetries(ctx context.Context, b *budget, fn func() error) error {
+	release, err := b.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	bo := backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), uint64(b.retries())), ctx)
+	return backoff.RetryNotify(func() error {
+		err := fn()
+		if err != nil && !isThrottlingError(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, bo, func(err error, d time.Duration) {
+		stats.Default.NewTaggedStat("filemanager_throttled", stats.CountType, stats.Tags{"provider": b.provider}).Increment()
+		pkgLogger.Warnf("Request throttled by %s, retrying in %v: %v", b.provider, d, err)
+	})
+}
+
+func (m *throttledFileManager) Upload(ctx context.Context, file *os.File, prefixes ...string) (output UploadOutput, err error) {
+	b := m.budget()
+	if fi, err := file.Stat(); err == nil {
+		if err := b.consume(ctx, fi.Size()); err != nil {
+			return UploadOutput{}, err
+		}
+	}
+	err = m.withRetries(ctx, b, func() error {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return backoff.Permanent(err)
+		}
+		output, err = m.FileManager.Upload(ctx, file, prefixes...)
+		return err
+	})
+	return output, err
+}
+
+// UploadReader is not retried, since the reader can't be rewound. Streams only count against the bandwidth budget, not
+// the concurrency one: reads block on their producer, which may be feeding other streams of the same account, e.g. the
+// load files of all tables of a staging file, so holding a slot while reading could deadlock them.
+func (m *throttledFileManager) UploadReader(ctx context.Context, objName string, rdr io.Reader) (UploadOutput, error) {
+	return m.FileManager.UploadReader(ctx, objName, &throttledReader{ctx: ctx, budget: m.budget(), reader: rdr})
+}
+
+func (m *throttledFileManager) Download(ctx context.Context, output *os.File, key string) error {
+	b := m.budget()
+	err := m.withRetries(ctx, b, func() error {
+		if err := output.Truncate(0); err != nil {
+			return backoff.Permanent(err)
+		}
+		if _, err := output.Seek(0, io.SeekStart); err != nil {
+			return backoff.Permanent(err)
+		}
+		return m.FileManager.Download(ctx, output, key)
+	})
+	if err != nil {
+		return err
+	}
+	// the size is only known once downloaded, so the bandwidth is accounted for after the fact
+	if fi, err := output.Stat(); err == nil {
+		return b.consume(ctx, fi.Size())
+	}
+	return nil
+}
+
+// DownloadRange is not retried, since the output can't be rewound. As with UploadReader, the range only counts against
+// the bandwidth budget, since writes block on their consumer.
+func (m *throttledFileManager) DownloadRange(ctx context.Context, output io.Writer, key string, offset int64) error {
+	return m.FileManager.DownloadRange(ctx, &throttledWriter{ctx: ctx, budget: m.budget(), writer: output}, key, offset)
+}
+
+func (m *throttledFileManager) Copy(ctx context.Context, sourceKey, destinationBucket, destinationKey string) (output UploadOutput, err error) {
+	err = m.withRetries(ctx, m.budget(), func() error {
+		output, err = m.FileManager.Copy(ctx, sourceKey, destinationBucket, destinationKey)
+		return err
+	})
+	return output, err
+}
+
+type throttledReader struct {
+	ctx    context.Context
+	budget *budget
+	reader io.Reader
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if consumeErr := r.budget.consume(r.ctx, int64(n)); consumeErr != nil {
+		return n, consumeErr
+	}
+	return n, err
+}
+
+type throttledWriter struct {
+	ctx    context.Context
+	budget *budget
+	writer io.Writer
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	if err := w.budget.consume(w.ctx, int64(len(p))); err != nil {
+		return 0, err
+	}
+	return w.writer.Write(p)
+}
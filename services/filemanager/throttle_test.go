@@ -0,0 +1,200 @@
+package filemanager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/googleapi"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+func TestIsThrottlingError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil", err: nil, expected: false},
+		{name: "generic", err: errors.New("some error"), expected: false},
+		{name: "s3 slow down", err: awserr.NewRequestFailure(awserr.New("SlowDown", "slow down", nil), http.StatusServiceUnavailable, ""), expected: true},
+		{name: "s3 too many requests", err: awserr.NewRequestFailure(awserr.New("TooManyRequests", "", nil), http.StatusTooManyRequests, ""), expected: true},
+		{name: "s3 access denied", err: awserr.NewRequestFailure(awserr.New("AccessDenied", "", nil), http.StatusForbidden, ""), expected: false},
+		{name: "gcs rate limited", err: &googleapi.Error{Code: http.StatusTooManyRequests}, expected: true},
+		{name: "gcs not found", err: &googleapi.Error{Code: http.StatusNotFound}, expected: false},
+		{name: "minio slow down", err: minio.ErrorResponse{Code: "SlowDown", StatusCode: http.StatusServiceUnavailable}, expected: true},
+		{name: "minio no such key", err: minio.ErrorResponse{Code: "NoSuchKey", StatusCode: http.StatusNotFound}, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, isThrottlingError(tt.err))
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	t.Setenv("RSERVER_FILE_MANAGER_MAX_CONCURRENCY", "1")
+	t.Setenv("RSERVER_FILE_MANAGER_MINIO_MAX_CONCURRENCY", "2")
+	resetBudgets := func() {
+		budgetsMu.Lock()
+		defer budgetsMu.Unlock()
+		budgets = map[string]*budget{}
+		providersLimits = map[string]*providerLimits{}
+	}
+	resetBudgets()
+	t.Cleanup(resetBudgets)
+
+	t.Run("provider override", func(t *testing.T) {
+		b := getBudget(&SettingsT{Provider: "MINIO", Config: map[string]interface{}{"accessKeyID": "budget-test"}})
+		require.Equal(t, 2, b.maxConcurrency)
+		require.Equal(t, rate.Inf, b.limiter.Limit())
+	})
+
+	t.Run("shared per account", func(t *testing.T) {
+		settings := &SettingsT{Provider: "S3", Config: map[string]interface{}{"accessKeyID": "budget-test"}}
+		b := getBudget(settings)
+		require.Same(t, b, getBudget(settings))
+		require.NotSame(t, b, getBudget(&SettingsT{Provider: "S3", Config: map[string]interface{}{"accessKeyID": "other"}}))
+		require.NotSame(t, b, getBudget(&SettingsT{Provider: "S3", WorkspaceID: "other", Config: settings.Config}), "budgets should be kept per workspace")
+
+		release, err := b.acquire(context.Background())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = b.acquire(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded, "concurrency budget should be exhausted")
+
+		release()
+		release, err = b.acquire(context.Background())
+		require.NoError(t, err)
+		release()
+	})
+
+	t.Run("resized in place on config change", func(t *testing.T) {
+		settings := &SettingsT{Provider: "GCS", WorkspaceID: "workspace", Config: map[string]interface{}{"bucketName": "budget-test"}}
+		b := getBudget(settings)
+		require.Equal(t, 1, b.maxConcurrency)
+		held, err := b.acquire(context.Background())
+		require.NoError(t, err)
+
+		config.Set("FileManager.GCS.maxConcurrency", 2)
+		require.Same(t, b, getBudget(settings), "requests in flight should keep counting against the budget")
+		release, err := b.acquire(context.Background())
+		require.NoError(t, err, "raising the limit should free a slot")
+
+		config.Set("FileManager.GCS.maxConcurrency", 1)
+		require.Same(t, b, getBudget(settings))
+		release()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = b.acquire(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded, "the slot held since before the change should count against the lowered limit")
+
+		acquired := make(chan struct{})
+		go func() {
+			release, err := b.acquire(context.Background())
+			if err == nil {
+				release()
+			}
+			close(acquired)
+		}()
+		held()
+		require.Eventually(t, func() bool {
+			select {
+			case <-acquired:
+				return true
+			default:
+				return false
+			}
+		}, time.Second, time.Millisecond, "waiting requests should get the slot once released")
+
+		config.Set("FileManager.GCS.maxBandwidthInMB", 1)
+		require.Same(t, b, getBudget(settings))
+		require.Equal(t, rate.Limit(1024*1024), b.limiter.Limit())
+		require.Equal(t, 1024*1024, b.limiter.Burst())
+	})
+
+	t.Run("pruned", func(t *testing.T) {
+		live := &SettingsT{Provider: "S3", WorkspaceID: "live", Config: map[string]interface{}{"accessKeyID": "prune-test"}}
+		removed := &SettingsT{Provider: "S3", WorkspaceID: "removed", Config: live.Config}
+		busy := &SettingsT{Provider: "S3", WorkspaceID: "busy", Config: live.Config}
+		liveBudget, removedBudget, busyBudget := getBudget(live), getBudget(removed), getBudget(busy)
+		release, err := busyBudget.acquire(context.Background())
+		require.NoError(t, err)
+
+		isLive := func(workspaceID string) bool { return workspaceID == "live" }
+		pruneBudgets(isLive)
+		require.Same(t, liveBudget, getBudget(live))
+		require.NotSame(t, removedBudget, getBudget(removed), "budgets of removed workspaces should be evicted")
+		require.Same(t, busyBudget, getBudget(busy), "budgets with requests in flight shouldn't be evicted")
+		release()
+
+		config.Set("FileManager.budgetIdleTimeout", "1ns")
+		defer config.Set("FileManager.budgetIdleTimeout", "1h")
+		time.Sleep(time.Millisecond)
+		pruneBudgets(func(string) bool { return true })
+		require.NotSame(t, liveBudget, getBudget(live), "unused budgets should be evicted")
+	})
+
+	t.Run("streams don't hold slots", func(t *testing.T) {
+		b := getBudget(&SettingsT{Provider: "AZURE_BLOB", Config: map[string]interface{}{"accountName": "budget-test"}})
+		pr, pw := io.Pipe()
+		defer pw.Close()
+		r := &throttledReader{ctx: context.Background(), budget: b, reader: pr}
+		go func() { _, _ = r.Read(make([]byte, 4)) }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		release, err := b.acquire(ctx)
+		require.NoError(t, err, "a read waiting for its producer shouldn't hold a slot")
+		release()
+	})
+
+	t.Run("streams fed by a single producer", func(t *testing.T) {
+		settings := &SettingsT{Provider: "SFTP", Config: map[string]interface{}{"host": "budget-test"}}
+		require.Equal(t, 1, getBudget(settings).maxConcurrency)
+		uploaded := &discardingFileManager{sizes: make(map[string]int64)}
+		manager := newThrottledFileManager(uploaded, settings)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		writers := []*UploadWriter{NewUploadWriter(ctx, manager, "table-a"), NewUploadWriter(ctx, manager, "table-b")}
+		for i := 0; i < 10; i++ {
+			for _, w := range writers {
+				_, err := w.Write([]byte("some row\n"))
+				require.NoError(t, err)
+			}
+		}
+		for _, w := range writers {
+			require.NoError(t, w.Close())
+		}
+		require.Equal(t, map[string]int64{"table-a": 90, "table-b": 90}, uploaded.sizes)
+	})
+}
+
+// discardingFileManager uploads streams by reading them to the end, keeping their sizes
+type discardingFileManager struct {
+	FileManager
+	mu    sync.Mutex
+	sizes map[string]int64
+}
+
+func (m *discardingFileManager) UploadReader(_ context.Context, objName string, rdr io.Reader) (UploadOutput, error) {
+	n, err := io.Copy(io.Discard, rdr)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sizes[objName] = n
+	return UploadOutput{ObjectName: objName}, err
+}
@@ -0,0 +1,63 @@
+package filemanager
+
+import (
+	"context"
+	"io"
+)
+
+// UploadWriter is an io.WriteCloser which streams everything written to it to object storage.
+// The upload is performed by FileManager.UploadReader in the background and completes on Close.
+type UploadWriter struct {
+	pw      *io.PipeWriter
+	done    chan struct{}
+	written int64
+	output  UploadOutput
+	err     error
+}
+
+// NewUploadWriter starts streaming an upload of objName (relative to the configured prefix) using manager.
+// Callers must always call Close, which blocks until the upload has either completed or failed.
+func NewUploadWriter(ctx context.Context, manager FileManager, objName string) *UploadWriter {
+	pr, pw := io.Pipe()
+	w := &UploadWriter{
+		pw:   pw,
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		w.output, w.err = manager.UploadReader(ctx, objName, pr)
+		// unblock any pending writes if the upload stopped consuming the reader
+		_ = pr.CloseWithError(w.err)
+	}()
+	return w
+}
+
+func (w *UploadWriter) Write(p []byte) (int, error) {
+	n, err := w.pw.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Close flushes the stream and waits for the upload to finish
+func (w *UploadWriter) Close() error {
+	_ = w.pw.Close()
+	<-w.done
+	return w.err
+}
+
+// Abort cancels the upload, making the background upload fail with err
+func (w *UploadWriter) Abort(err error) error {
+	_ = w.pw.CloseWithError(err)
+	<-w.done
+	return w.err
+}
+
+// BytesWritten returns the number of bytes written to the stream so far
+func (w *UploadWriter) BytesWritten() int64 {
+	return w.written
+}
+
+// Output returns the result of the upload. It is only valid after Close has returned without an error.
+func (w *UploadWriter) Output() UploadOutput {
+	return w.output
+}
@@ -0,0 +1,65 @@
+// Package geolocation resolves the location of IPs, for events to be enriched with it by the gateway and the processor.
+package geolocation
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is the location of an IP
+type Location struct {
+	Country string // ISO 3166-1 code
+	Region  string // English name of the first subdivision, or its ISO 3166-2 code if unnamed
+	City    string // English name
+}
+
+// Locator resolves the location of IPs
+type Locator interface {
+	Locate(ip net.IP) (Location, error)
+	Close() error
+}
+
+// MaxmindLocator resolves the location of IPs from a MaxMind GeoIP2 or GeoLite2 database
+type MaxmindLocator struct {
+	reader *maxminddb.Reader
+}
+
+// NewMaxmindLocator opens the MaxMind database at dbPath
+func NewMaxmindLocator(dbPath string) (*MaxmindLocator, error) {
+	reader, err := maxminddb.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &MaxmindLocator{reader: reader}, nil
+}
+
+func (l *MaxmindLocator) Locate(ip net.IP) (Location, error) {
+	var record struct {
+		City struct {
+			Names map[string]string `maxminddb:"names"`
+		} `maxminddb:"city"`
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+		Subdivisions []struct {
+			ISOCode string            `maxminddb:"iso_code"`
+			Names   map[string]string `maxminddb:"names"`
+		} `maxminddb:"subdivisions"`
+	}
+	if err := l.reader.Lookup(ip, &record); err != nil {
+		return Location{}, err
+	}
+	location := Location{Country: record.Country.ISOCode, City: record.City.Names["en"]}
+	if len(record.Subdivisions) > 0 {
+		location.Region = record.Subdivisions[0].Names["en"]
+		if location.Region == "" {
+			location.Region = record.Subdivisions[0].ISOCode
+		}
+	}
+	return location, nil
+}
+
+func (l *MaxmindLocator) Close() error {
+	return l.reader.Close()
+}
@@ -37,6 +37,13 @@ type Stats struct {
 	RouterDBs                 map[string]jobsdb.MultiTenantJobsDB
 	jobdDBQueryRequestTimeout time.Duration
 	jobdDBMaxRetries          int
+	// Quotas limits the share of the jobs picked up for each workspace, if set
+	Quotas RouterQuotas
+}
+
+// RouterQuotas provides the share of the jobs picked up by routers workspaces are limited to
+type RouterQuotas interface {
+	RouterThroughputShare(workspaceID string) float64
 }
 
 type MultiTenantI interface {
@@ -281,9 +288,27 @@ func (t *Stats) GetRouterPickupJobs(destType string, noOfWorkers int, routerTime
 		pkgLogger.Debugf("Time Calculated : %v , Remaining Time : %v , Workspace : %v ,runningJobCount : %v , moving_average_latency : %v, pileUpCount : %v ,DestType : %v ,PileUpLoop ", float64(pickUpCount)*t.routerTenantLatencyStat[destType][workspaceKey].Value(), runningTimeCounter, workspaceKey, runningJobCount, t.routerTenantLatencyStat[destType][workspaceKey].Value(), pendingEvents, destType)
 	}
 
+	t.applyRouterQuotas(workspacePickUpCount, jobQueryBatchSize)
 	return workspacePickUpCount
 }
 
+// applyRouterQuotas limits the jobs picked up for each workspace to its share of jobQueryBatchSize, at least one
+func (t *Stats) applyRouterQuotas(workspacePickUpCount map[string]int, jobQueryBatchSize int) {
+	if t.Quotas == nil {
+		return
+	}
+	for workspaceKey, pickUpCount := range workspacePickUpCount {
+		limit := misc.MaxInt(int(t.Quotas.RouterThroughputShare(workspaceKey)*float64(jobQueryBatchSize)), 1)
+		if pickUpCount > limit {
+			workspacePickUpCount[workspaceKey] = limit
+			stats.Default.NewTaggedStat("multitenant_quota_exceeded", stats.CountType, stats.Tags{
+				"workspaceId": workspaceKey,
+				"quota":       "routerThroughputShare",
+			}).Increment()
+		}
+	}
+}
+
 func (t *Stats) getFailureRate(workspaceKey, destType string) float64 {
 	t.routerSuccessRateMutex.RLock()
 	defer t.routerSuccessRateMutex.RUnlock()
@@ -130,6 +130,20 @@ var _ = Describe("tenantStats", func() {
 				Expect(routerPickUpJobs[workspaceID3]).To(Equal(addJobWID3))
 			})
 
+			It("Should limit the Router PickUp Jobs of workspaces to their throughput share", func() {
+				input := map[string]map[string]int{
+					workspaceID1: {destType1: 2000},
+					workspaceID2: {destType1: 1000},
+				}
+				tenantStats.ReportProcLoopAddStats(input, "rt")
+				tenantStats.UpdateWorkspaceLatencyMap(destType1, workspaceID1, 0)
+				tenantStats.UpdateWorkspaceLatencyMap(destType1, workspaceID2, 0)
+				tenantStats.Quotas = routerQuotas{workspaceID1: 0.1}
+				routerPickUpJobs := tenantStats.GetRouterPickupJobs(destType1, noOfWorkers, routerTimeOut, jobQueryBatchSize)
+				Expect(routerPickUpJobs[workspaceID1]).To(Equal(jobQueryBatchSize / 10))
+				Expect(routerPickUpJobs[workspaceID2]).To(Equal(1000))
+			})
+
 			It("Should Pick BETA for slower jobs", func() {
 				addJobWID1 := 300
 				addJobWID2 := 2000
@@ -300,6 +314,15 @@ var _ = Describe("tenantStats", func() {
 	)
 })
 
+type routerQuotas map[string]float64
+
+func (q routerQuotas) RouterThroughputShare(workspaceID string) float64 {
+	if share, ok := q[workspaceID]; ok {
+		return share
+	}
+	return 1
+}
+
 func Benchmark_Counts(b *testing.B) {
 	b.ResetTimer()
 	metric.Instance.Reset()
@@ -68,6 +68,7 @@ type DisableDestinationResponse struct {
 
 type RefreshTokenParams struct {
 	AccountId       string
+	DestinationId   string
 	WorkspaceId     string
 	DestDefName     string
 	EventNamePrefix string
@@ -295,6 +296,28 @@ func (authErrHandler *OAuthErrResHandler) GetTokenInfo(refTokenParams *RefreshTo
 func (authErrHandler *OAuthErrResHandler) fetchAccountInfoFromCp(refTokenParams *RefreshTokenParams, refTokenBody RefreshTokenBodyParams,
 	authStats *OAuthStats, logTypeName string,
 ) (statusCode int) {
+	statusCode, account, errMsg := authErrHandler.requestAccountInfo(refTokenParams, refTokenBody, authStats, logTypeName)
+	if account != nil {
+		// Update the account information into in-memory map(cache)
+		authErrHandler.destAuthInfoMap[refTokenParams.AccountId] = &AuthResponse{
+			Account: *account,
+			Err:     errMsg,
+		}
+	} else if _, ok := authErrHandler.destAuthInfoMap[refTokenParams.AccountId]; !ok {
+		authErrHandler.destAuthInfoMap[refTokenParams.AccountId] = &AuthResponse{
+			Err: errMsg,
+		}
+	} else {
+		authErrHandler.destAuthInfoMap[refTokenParams.AccountId].Err = errMsg
+	}
+	return statusCode
+}
+
+// requestAccountInfo hits the Control Plane to get the account information
+// account is nil if the response of the Control Plane is an error, in which case the previous account information is still valid
+func (authErrHandler *OAuthErrResHandler) requestAccountInfo(refTokenParams *RefreshTokenParams, refTokenBody RefreshTokenBodyParams,
+	authStats *OAuthStats, logTypeName string,
+) (statusCode int, account *AccountSecret, errMsg string) {
 	refreshUrl := fmt.Sprintf("%s/destination/workspaces/%s/accounts/%s/token", configBEURL, refTokenParams.WorkspaceId, refTokenParams.AccountId)
 	res, err := json.Marshal(refTokenBody)
 	if err != nil {
@@ -327,44 +350,27 @@ func (authErrHandler *OAuthErrResHandler) fetchAccountInfoFromCp(refTokenParams
 		authStats.statName = fmt.Sprintf("%s_failure", refTokenParams.EventNamePrefix)
 		authStats.errorMessage = "Empty secret"
 		authStats.SendCountStat()
-		// Setting empty accessToken value into in-memory auth info map(cache)
-		authErrHandler.destAuthInfoMap[refTokenParams.AccountId] = &AuthResponse{
-			Account: AccountSecret{
-				Secret: []byte(""),
-			},
-			Err: "Empty secret",
-		}
 		authErrHandler.logger.Debugf("[%s request] :: Empty %s response received(rt-worker-%d) : %s\n", loggerNm, logTypeName, refTokenParams.WorkerId, response)
-		return http.StatusInternalServerError
+		// Setting empty accessToken value
+		return http.StatusInternalServerError, &AccountSecret{Secret: []byte("")}, "Empty secret"
 	}
 
 	if refErrMsg := getRefreshTokenErrResp(response, &accountSecret); router_utils.IsNotEmptyString(refErrMsg) {
-		if _, ok := authErrHandler.destAuthInfoMap[refTokenParams.AccountId]; !ok {
-			authErrHandler.destAuthInfoMap[refTokenParams.AccountId] = &AuthResponse{
-				Err: refErrMsg,
-			}
-		} else {
-			authErrHandler.destAuthInfoMap[refTokenParams.AccountId].Err = refErrMsg
-		}
 		authStats.statName = fmt.Sprintf("%s_failure", refTokenParams.EventNamePrefix)
 		authStats.errorMessage = refErrMsg
 		authStats.SendCountStat()
 		if refErrMsg == INVALID_REFRESH_TOKEN_GRANT {
 			// Should abort the event as refresh is not going to work
 			// until we have new refresh token for the account
-			return http.StatusBadRequest
+			return http.StatusBadRequest, nil, refErrMsg
 		}
-		return http.StatusInternalServerError
-	}
-	// Update the refreshed account information into in-memory map(cache)
-	authErrHandler.destAuthInfoMap[refTokenParams.AccountId] = &AuthResponse{
-		Account: accountSecret,
+		return http.StatusInternalServerError, nil, refErrMsg
 	}
 	authStats.statName = fmt.Sprintf("%s_success", refTokenParams.EventNamePrefix)
 	authStats.errorMessage = ""
 	authStats.SendCountStat()
 	authErrHandler.logger.Debugf("[%s request] :: (Write) %s response received(rt-worker-%d): %s\n", loggerNm, logTypeName, refTokenParams.WorkerId, response)
-	return http.StatusOK
+	return http.StatusOK, &accountSecret, ""
 }
 
 func getRefreshTokenErrResp(response string, accountSecret *AccountSecret) (message string) {
@@ -0,0 +1,211 @@
+package oauth
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	router_utils "github.com/rudderlabs/rudder-server/router/utils"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// TokenService is an Authorizer managing the lifecycle of the access tokens of OAuth accounts, to be shared by all
+// the router workers:
+//   - the token of an account is fetched from the Control Plane once and cached, the workers asking for it meanwhile
+//     waiting for it instead of getting an empty token
+//   - the token is refreshed in the background when it is about to expire, so that destinations don't reject it
+//   - the token is refreshed once when several workers get it rejected by destinations, the workers asking for a refresh
+//     of a token already rotated getting the new token
+type TokenService struct {
+	handler             *OAuthErrResHandler
+	refreshBeforeExpiry time.Duration
+	now                 func() time.Time
+
+	accountsMu sync.Mutex
+	accounts   map[string]*accountToken // map key is the accountId
+}
+
+type accountToken struct {
+	mu         sync.Mutex    // serializes the fetches and refreshes of the token
+	response   *AuthResponse // nil until the token is first fetched, replaced but never modified afterwards
+	expiresAt  time.Time     // zero if the expiration date of the token is unknown
+	refreshing bool          // a refresh before the expiry of the token is in progress
+}
+
+// NewTokenService returns a token service getting the tokens from the Control Plane through the given handler
+func NewTokenService(handler *OAuthErrResHandler) *TokenService {
+	return &TokenService{
+		handler:             handler,
+		refreshBeforeExpiry: config.GetDuration("Router.oauth.refreshBeforeExpiry", 300, time.Second),
+		now:                 time.Now,
+		accounts:            make(map[string]*accountToken),
+	}
+}
+
+func (s *TokenService) account(accountID string) *accountToken {
+	s.accountsMu.Lock()
+	defer s.accountsMu.Unlock()
+	account, ok := s.accounts[accountID]
+	if !ok {
+		account = &accountToken{}
+		s.accounts[accountID] = account
+	}
+	return account
+}
+
+// FetchToken returns the cached token of the account, fetching it from the Control Plane if it isn't cached or has expired
+func (s *TokenService) FetchToken(fetchTokenParams *RefreshTokenParams) (int, *AuthResponse) {
+	account := s.account(fetchTokenParams.AccountId)
+	account.mu.Lock()
+	defer account.mu.Unlock()
+
+	if account.response != nil && account.response.Err == "" && !s.expired(account, 0) {
+		s.countStat("oauth_token_cache", fetchTokenParams, stats.Tags{"result": "hit"})
+		if s.expired(account, s.refreshBeforeExpiry) && !account.refreshing {
+			account.refreshing = true
+			refreshParams := *fetchTokenParams
+			go s.preemptiveRefresh(&refreshParams, account)
+		}
+		return http.StatusOK, account.response
+	}
+	s.countStat("oauth_token_cache", fetchTokenParams, stats.Tags{"result": "miss"})
+
+	var body RefreshTokenBodyParams
+	if account.response != nil && router_utils.IsNotEmptyString(string(account.response.Account.Secret)) {
+		// the cached token has expired
+		body = RefreshTokenBodyParams{HasExpired: true, ExpiredSecret: account.response.Account.Secret}
+	}
+	authStats := s.newStats(fetchTokenParams, "")
+	authStats.isTokenFetch = true
+	statusCode, secret, errMsg := s.handler.requestAccountInfo(fetchTokenParams, body, authStats, "Fetch token")
+	s.store(account, fetchTokenParams, secret, errMsg)
+	return statusCode, account.response
+}
+
+// RefreshToken refreshes the token of the account rejected by a destination, unless it was already rotated
+func (s *TokenService) RefreshToken(refTokenParams *RefreshTokenParams) (int, *AuthResponse) {
+	account := s.account(refTokenParams.AccountId)
+	account.mu.Lock()
+	defer account.mu.Unlock()
+
+	if account.response != nil && account.response.Err == "" &&
+		router_utils.IsNotEmptyString(string(account.response.Account.Secret)) &&
+		!bytes.Equal(account.response.Account.Secret, refTokenParams.Secret) {
+		// the token was refreshed since the worker got it
+		s.countStat("oauth_token_cache", refTokenParams, stats.Tags{"result": "rotated"})
+		return http.StatusOK, account.response
+	}
+
+	var body RefreshTokenBodyParams
+	if router_utils.IsNotEmptyString(string(refTokenParams.Secret)) {
+		body = RefreshTokenBodyParams{HasExpired: true, ExpiredSecret: refTokenParams.Secret}
+	}
+	statusCode, secret, errMsg := s.handler.requestAccountInfo(refTokenParams, body, s.newStats(refTokenParams, REFRESH_TOKEN), "Refresh token")
+	s.countStat("oauth_token_refresh", refTokenParams, stats.Tags{"trigger": "rejected", "success": successTag(statusCode)})
+	s.store(account, refTokenParams, secret, errMsg)
+	return statusCode, account.response
+}
+
+// preemptiveRefresh refreshes the token of the account about to expire, without blocking the workers fetching it meanwhile
+func (s *TokenService) preemptiveRefresh(params *RefreshTokenParams, account *accountToken) {
+	account.mu.Lock()
+	expiredSecret := account.response.Account.Secret
+	account.mu.Unlock()
+
+	params.EventNamePrefix = "refresh_token"
+	params.Secret = expiredSecret
+	body := RefreshTokenBodyParams{HasExpired: true, ExpiredSecret: expiredSecret}
+	statusCode, secret, errMsg := s.handler.requestAccountInfo(params, body, s.newStats(params, REFRESH_TOKEN), "Refresh token")
+	s.countStat("oauth_token_refresh", params, stats.Tags{"trigger": "expiry", "success": successTag(statusCode)})
+
+	account.mu.Lock()
+	defer account.mu.Unlock()
+	account.refreshing = false
+	if statusCode != http.StatusOK {
+		// keep the token until it expires
+		s.handler.logger.Warnf("[%s request] :: Refresh of token of account %s before its expiry failed: %s", loggerNm, params.AccountId, errMsg)
+		return
+	}
+	if account.response == nil || !bytes.Equal(account.response.Account.Secret, expiredSecret) {
+		// the token was rotated or dropped meanwhile
+		return
+	}
+	s.store(account, params, secret, errMsg)
+}
+
+// store caches the account information received from the Control Plane, keeping the previous token on errors
+func (s *TokenService) store(account *accountToken, params *RefreshTokenParams, secret *AccountSecret, errMsg string) {
+	switch {
+	case secret != nil:
+		account.response = &AuthResponse{Account: *secret, Err: errMsg}
+		account.expiresAt, _ = time.Parse(time.RFC3339, secret.ExpirationDate)
+		if !account.expiresAt.IsZero() {
+			s.gaugeStat("oauth_token_expiry_seconds", params, account.expiresAt.Sub(s.now()).Seconds())
+		}
+	case account.response == nil:
+		account.response = &AuthResponse{Err: errMsg}
+	default:
+		response := *account.response
+		response.Err = errMsg
+		account.response = &response
+	}
+}
+
+// expired returns true if the token of the account expires within the given duration
+func (s *TokenService) expired(account *accountToken, within time.Duration) bool {
+	return !account.expiresAt.IsZero() && !s.now().Add(within).Before(account.expiresAt)
+}
+
+// DisableDestination disables the destination and drops the token of its account
+func (s *TokenService) DisableDestination(destination *backendconfig.DestinationT, workspaceId, rudderAccountId string) (statusCode int, resBody string) {
+	statusCode, resBody = s.handler.DisableDestination(destination, workspaceId, rudderAccountId)
+	if statusCode == http.StatusOK {
+		s.accountsMu.Lock()
+		delete(s.accounts, rudderAccountId)
+		s.accountsMu.Unlock()
+	}
+	return statusCode, resBody
+}
+
+func (s *TokenService) newStats(params *RefreshTokenParams, authErrCategory string) *OAuthStats {
+	return &OAuthStats{
+		id:              params.AccountId,
+		workspaceId:     params.WorkspaceId,
+		rudderCategory:  "destination",
+		authErrCategory: authErrCategory,
+		destDefName:     params.DestDefName,
+		flowType:        s.handler.rudderFlowType,
+	}
+}
+
+func (s *TokenService) tags(params *RefreshTokenParams, extraTags stats.Tags) stats.Tags {
+	tags := stats.Tags{
+		"accountId":     params.AccountId,
+		"destinationId": params.DestinationId,
+		"workspaceId":   params.WorkspaceId,
+		"destType":      params.DestDefName,
+		"flowType":      string(s.handler.rudderFlowType),
+	}
+	for k, v := range extraTags {
+		tags[k] = v
+	}
+	return tags
+}
+
+func (s *TokenService) countStat(name string, params *RefreshTokenParams, extraTags stats.Tags) {
+	stats.Default.NewTaggedStat(name, stats.CountType, s.tags(params, extraTags)).Increment()
+}
+
+func (s *TokenService) gaugeStat(name string, params *RefreshTokenParams, value float64) {
+	stats.Default.NewTaggedStat(name, stats.GaugeType, s.tags(params, nil)).Gauge(value)
+}
+
+func successTag(statusCode int) string {
+	if statusCode == http.StatusOK {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,101 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+type staticTokenProvider string
+
+func (p staticTokenProvider) AccessToken() string { return string(p) }
+
+func TestTokenService(t *testing.T) {
+	config.Reset()
+	logger.Reset()
+	Init()
+	now := time.Date(2022, 10, 10, 10, 0, 0, 0, time.UTC)
+	var (
+		requests  int64
+		expiredMu sync.Mutex
+		expired   []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/destination/workspaces/workspace/accounts/account/token", r.URL.Path)
+		var body RefreshTokenBodyParams
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.HasExpired {
+			expiredMu.Lock()
+			expired = append(expired, string(body.ExpiredSecret))
+			expiredMu.Unlock()
+		}
+		n := atomic.AddInt64(&requests, 1)
+		time.Sleep(10 * time.Millisecond)
+		_, _ = fmt.Fprintf(w, `{"secret":{"accessToken":"token-%d"},"expirationDate":%q}`, n, now.Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer srv.Close()
+	configBEURL = srv.URL
+
+	s := NewTokenService(NewOAuthErrorHandler(staticTokenProvider("token")))
+	s.now = func() time.Time { return now }
+	params := func() *RefreshTokenParams {
+		return &RefreshTokenParams{AccountId: "account", WorkspaceId: "workspace", DestinationId: "destination", DestDefName: "DEST", EventNamePrefix: "fetch_token"}
+	}
+
+	t.Run("fetches a token once", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				statusCode, response := s.FetchToken(params())
+				require.Equal(t, http.StatusOK, statusCode)
+				require.JSONEq(t, `{"accessToken":"token-1"}`, string(response.Account.Secret))
+			}()
+		}
+		wg.Wait()
+		require.EqualValues(t, 1, atomic.LoadInt64(&requests))
+	})
+
+	t.Run("refreshes a rejected token once", func(t *testing.T) {
+		statusCode, response := s.RefreshToken(&RefreshTokenParams{AccountId: "account", WorkspaceId: "workspace", Secret: json.RawMessage(`{"accessToken":"token-1"}`)})
+		require.Equal(t, http.StatusOK, statusCode)
+		require.JSONEq(t, `{"accessToken":"token-2"}`, string(response.Account.Secret))
+
+		statusCode, response = s.RefreshToken(&RefreshTokenParams{AccountId: "account", WorkspaceId: "workspace", Secret: json.RawMessage(`{"accessToken":"token-1"}`)})
+		require.Equal(t, http.StatusOK, statusCode)
+		require.JSONEq(t, `{"accessToken":"token-2"}`, string(response.Account.Secret), "already rotated")
+		require.EqualValues(t, 2, atomic.LoadInt64(&requests))
+	})
+
+	t.Run("refreshes a token before it expires", func(t *testing.T) {
+		now = now.Add(58 * time.Minute)
+		_, response := s.FetchToken(params())
+		require.JSONEq(t, `{"accessToken":"token-2"}`, string(response.Account.Secret), "the token is still valid meanwhile")
+		require.Eventually(t, func() bool {
+			_, response := s.FetchToken(params())
+			return string(response.Account.Secret) == `{"accessToken":"token-3"}`
+		}, time.Second, 10*time.Millisecond)
+		require.EqualValues(t, 3, atomic.LoadInt64(&requests))
+	})
+
+	t.Run("fetches an expired token", func(t *testing.T) {
+		now = now.Add(2 * time.Hour)
+		_, response := s.FetchToken(params())
+		require.JSONEq(t, `{"accessToken":"token-4"}`, string(response.Account.Secret))
+		expiredMu.Lock()
+		defer expiredMu.Unlock()
+		require.Len(t, expired, 3)
+		require.JSONEq(t, `{"accessToken":"token-3"}`, expired[2])
+	})
+}
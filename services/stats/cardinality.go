@@ -0,0 +1,230 @@
+package stats
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spf13/cast"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// The number of series of a metric, i.e. of distinct sets of tags, is capped by its cardinality budget, and the
+// number of series of each tenant of the metric, identified by its tenant tag, by the tenant budget, e.g.
+//
+//	statsCardinality:
+//	  defaultBudget: 1000
+//	  budgets:
+//	    router_delivery_time: 5000
+//	  tenantTag: workspaceId
+//	  tenantBudget: 100
+//	  tenantBudgets:
+//	    <workspaceId>: 500
+//	  maxTrackedSeries: 100000
+//
+// Once a metric has as many series as its budget, measurements with new sets of tags are aggregated into its overflow
+// series, whose tags all have the value other. Likewise, once a tenant has as many series of a metric as its budget,
+// its measurements with new sets of tags are aggregated into its own overflow series, keeping the tenant tag. Budgets
+// of 0, the default, don't cap the series.
+//
+// The series of each metric are tracked up to maxTrackedSeries, bounding the memory of metrics without a budget: their
+// further series are recorded without being tracked, nor capped by the tenant budget, and their cardinality is
+// reported as truncated. Budgets above maxTrackedSeries are capped to it. The series of every metric are reported by
+// the /stats/cardinality admin endpoint.
+
+// overflowTagValue is the value of the tags of the overflow series of metrics exceeding their budget
+const overflowTagValue = "other"
+
+// MetricCardinality is the cardinality of a metric
+type MetricCardinality struct {
+	Series     int   `json:"series"`
+	Budget     int   `json:"budget,omitempty"`
+	Overflowed int64 `json:"overflowed,omitempty"` // measurements aggregated into the overflow series
+	// Truncated is set once the series of the metric exceed the ones tracked, Series being a lower bound
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// cardinalityLimiter caps the series of metrics by their budgets, a nil limiter not capping them
+type cardinalityLimiter struct {
+	defaultBudget    int
+	budgets          map[string]int
+	tenantTag        string
+	tenantBudget     int
+	tenantBudgets    map[string]int
+	maxTrackedSeries int
+
+	// mu is only write locked on the first sighting of a series
+	mu      sync.RWMutex
+	metrics map[string]*metricSeries
+}
+
+// metricSeries are the series tracked of a metric
+type metricSeries struct {
+	series     map[string]struct{}
+	tenants    map[string]int // number of series by tenant
+	overflowed int64          // accessed atomically
+	truncated  bool
+}
+
+func newCardinalityLimiter(config *config.Config) *cardinalityLimiter {
+	l := &cardinalityLimiter{
+		defaultBudget:    config.GetInt("statsCardinality.defaultBudget", 0),
+		budgets:          make(map[string]int),
+		tenantTag:        config.GetString("statsCardinality.tenantTag", "workspaceId"),
+		tenantBudget:     config.GetInt("statsCardinality.tenantBudget", 0),
+		tenantBudgets:    make(map[string]int),
+		maxTrackedSeries: config.GetInt("statsCardinality.maxTrackedSeries", 100000),
+		metrics:          make(map[string]*metricSeries),
+	}
+	for name, budget := range config.GetStringMap("statsCardinality.budgets", nil) {
+		l.budgets[name] = cast.ToInt(budget)
+	}
+	for tenant, budget := range config.GetStringMap("statsCardinality.tenantBudgets", nil) {
+		l.tenantBudgets[tenant] = cast.ToInt(budget)
+	}
+	return l
+}
+
+func (l *cardinalityLimiter) budget(name string) int {
+	budget, ok := l.budgets[name]
+	if !ok {
+		budget = l.defaultBudget
+	}
+	if budget > 0 && l.maxTrackedSeries > 0 && budget > l.maxTrackedSeries {
+		return l.maxTrackedSeries
+	}
+	return budget
+}
+
+func (l *cardinalityLimiter) budgetOfTenant(tenant string) int {
+	if budget, ok := l.tenantBudgets[tenant]; ok {
+		return budget
+	}
+	return l.tenantBudget
+}
+
+// limit returns the tags of the series of the metric to record a measurement with tags in, tags themselves unless
+// they would exceed the budget of the metric, or of their tenant
+func (l *cardinalityLimiter) limit(name string, tags Tags) Tags {
+	if l == nil {
+		return tags
+	}
+	key := tags.String()
+	tenant := tags[l.tenantTag]
+
+	l.mu.RLock()
+	if m, ok := l.metrics[name]; ok {
+		if _, ok := m.series[key]; ok {
+			l.mu.RUnlock()
+			return tags
+		}
+		if overflow, exceeded := l.overflow(name, m, tenant, tags); exceeded {
+			l.mu.RUnlock()
+			return overflow
+		}
+		if m.truncated {
+			l.mu.RUnlock()
+			return tags
+		}
+	}
+	l.mu.RUnlock()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m, ok := l.metrics[name]
+	if !ok {
+		m = &metricSeries{series: make(map[string]struct{}), tenants: make(map[string]int)}
+		l.metrics[name] = m
+	}
+	if _, ok := m.series[key]; ok {
+		return tags
+	}
+	if overflow, exceeded := l.overflow(name, m, tenant, tags); exceeded {
+		return overflow
+	}
+	if l.maxTrackedSeries > 0 && len(m.series) >= l.maxTrackedSeries {
+		m.truncated = true
+		return tags
+	}
+	m.series[key] = struct{}{}
+	if tenant != "" {
+		m.tenants[tenant]++
+	}
+	return tags
+}
+
+// overflow returns the tags of the overflow series to record a measurement with new tags in, if they exceed the budget
+// of the metric, or the one of their tenant. Must be called with mu held, for reading at least.
+func (l *cardinalityLimiter) overflow(name string, m *metricSeries, tenant string, tags Tags) (Tags, bool) {
+	tenantExceeded := false
+	if budget := l.budget(name); budget <= 0 || len(m.series) < budget {
+		if budget := l.budgetOfTenant(tenant); tenant == "" || budget <= 0 || m.tenants[tenant] < budget {
+			return nil, false
+		}
+		tenantExceeded = true
+	}
+	atomic.AddInt64(&m.overflowed, 1)
+	overflow := make(Tags, len(tags))
+	for tag := range tags {
+		overflow[tag] = overflowTagValue
+	}
+	if tenantExceeded {
+		overflow[l.tenantTag] = tenant
+	}
+	return overflow, true
+}
+
+// Cardinality returns the cardinality of every metric
+func (l *cardinalityLimiter) Cardinality() map[string]MetricCardinality {
+	if l == nil {
+		return map[string]MetricCardinality{}
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	cardinality := make(map[string]MetricCardinality, len(l.metrics))
+	for name, m := range l.metrics {
+		cardinality[name] = MetricCardinality{
+			Series:     len(m.series),
+			Budget:     l.budget(name),
+			Overflowed: atomic.LoadInt64(&m.overflowed),
+			Truncated:  m.truncated,
+		}
+	}
+	return cardinality
+}
+
+// CardinalityReporter reports the cardinality of the metrics of Stats
+type CardinalityReporter interface {
+	Cardinality() map[string]MetricCardinality
+}
+
+// CardinalityHandler serves the cardinality of the metrics of s, from the metric with the most series to the one
+// with the fewest
+func CardinalityHandler(s Stats) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reporter, ok := s.(CardinalityReporter)
+		if !ok {
+			http.Error(w, "stats don't report their cardinality", http.StatusNotImplemented)
+			return
+		}
+		type metricCardinality struct {
+			Name string `json:"name"`
+			MetricCardinality
+		}
+		var metrics []metricCardinality
+		for name, cardinality := range reporter.Cardinality() {
+			metrics = append(metrics, metricCardinality{Name: name, MetricCardinality: cardinality})
+		}
+		sort.Slice(metrics, func(i, j int) bool {
+			if metrics[i].Series != metrics[j].Series {
+				return metrics[i].Series > metrics[j].Series
+			}
+			return metrics[i].Name < metrics[j].Name
+		})
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(metrics)
+	})
+}
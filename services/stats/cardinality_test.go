@@ -0,0 +1,109 @@
+package stats
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+func TestCardinalityLimiter(t *testing.T) {
+	c := config.New()
+	c.Set("statsCardinality.defaultBudget", 2)
+	c.Set("statsCardinality.budgets", map[string]interface{}{"unlimited": 0})
+	l := newCardinalityLimiter(c)
+
+	require.Equal(t, Tags{"destID": "a"}, l.limit("events", Tags{"destID": "a"}))
+	require.Equal(t, Tags{"destID": "b"}, l.limit("events", Tags{"destID": "b"}))
+	require.Equal(t, Tags{"destID": "other"}, l.limit("events", Tags{"destID": "c"}), "series over the budget overflow")
+	require.Equal(t, Tags{"destID": "a"}, l.limit("events", Tags{"destID": "a"}), "existing series are kept")
+	for _, destID := range []string{"a", "b", "c"} {
+		require.Equal(t, Tags{"destID": destID}, l.limit("unlimited", Tags{"destID": destID}))
+	}
+
+	require.Equal(t, map[string]MetricCardinality{
+		"events":    {Series: 2, Budget: 2, Overflowed: 1},
+		"unlimited": {Series: 3},
+	}, l.Cardinality())
+
+	var nilLimiter *cardinalityLimiter
+	require.Equal(t, Tags{"destID": "c"}, nilLimiter.limit("events", Tags{"destID": "c"}))
+	require.Empty(t, nilLimiter.Cardinality())
+}
+
+func TestCardinalityTenantBudgets(t *testing.T) {
+	c := config.New()
+	c.Set("statsCardinality.tenantBudget", 1)
+	c.Set("statsCardinality.tenantBudgets", map[string]interface{}{"large": 2})
+	l := newCardinalityLimiter(c)
+
+	require.Equal(t, Tags{"workspaceId": "small", "destID": "a"}, l.limit("events", Tags{"workspaceId": "small", "destID": "a"}))
+	require.Equal(t, Tags{"workspaceId": "small", "destID": "other"}, l.limit("events", Tags{"workspaceId": "small", "destID": "b"}), "series over the tenant budget overflow into the tenant's series")
+	require.Equal(t, Tags{"workspaceId": "large", "destID": "a"}, l.limit("events", Tags{"workspaceId": "large", "destID": "a"}))
+	require.Equal(t, Tags{"workspaceId": "large", "destID": "b"}, l.limit("events", Tags{"workspaceId": "large", "destID": "b"}))
+	require.Equal(t, Tags{"workspaceId": "large", "destID": "other"}, l.limit("events", Tags{"workspaceId": "large", "destID": "c"}))
+	require.Equal(t, Tags{"destID": "c"}, l.limit("events", Tags{"destID": "c"}), "series without tenant aren't capped by tenant budgets")
+
+	require.Equal(t, map[string]MetricCardinality{
+		"events": {Series: 4, Overflowed: 2},
+	}, l.Cardinality())
+}
+
+func TestCardinalityMaxTrackedSeries(t *testing.T) {
+	c := config.New()
+	c.Set("statsCardinality.maxTrackedSeries", 2)
+	c.Set("statsCardinality.budgets", map[string]interface{}{"capped": 5})
+	l := newCardinalityLimiter(c)
+
+	for _, destID := range []string{"a", "b", "c"} {
+		require.Equal(t, Tags{"destID": destID}, l.limit("events", Tags{"destID": destID}), "series of metrics without budget are recorded")
+		l.limit("capped", Tags{"destID": destID})
+	}
+	require.Equal(t, map[string]MetricCardinality{
+		"events": {Series: 2, Truncated: true},
+		"capped": {Series: 2, Budget: 2, Overflowed: 1},
+	}, l.Cardinality(), "budgets are capped to the series tracked")
+}
+
+func TestCardinalityOverflowSeries(t *testing.T) {
+	c := config.New()
+	c.Set("statsCardinality.defaultBudget", 1)
+	s, reader := newTestOtelStats(t, c)
+
+	s.NewTaggedStat("events", CountType, Tags{"destID": "a"}).Count(1)
+	s.NewTaggedStat("events", CountType, Tags{"destID": "b"}).Count(2)
+	s.NewTaggedStat("events", CountType, Tags{"destID": "c"}).Count(3)
+
+	values := make(map[string]int64)
+	for _, dataPoint := range collectMetrics(t, reader)["events"].Data.(metricdata.Sum[int64]).DataPoints {
+		destID, _ := dataPoint.Attributes.Value("destID")
+		values[destID.AsString()] = dataPoint.Value
+	}
+	require.Equal(t, map[string]int64{"a": 1, "other": 5}, values)
+}
+
+func TestCardinalityHandler(t *testing.T) {
+	c := config.New()
+	s, _ := newTestOtelStats(t, c)
+	s.NewTaggedStat("events", CountType, Tags{"destID": "a"}).Increment()
+	s.NewTaggedStat("events", CountType, Tags{"destID": "b"}).Increment()
+	s.NewStat("pending", GaugeType).Gauge(1)
+
+	rec := httptest.NewRecorder()
+	CardinalityHandler(s).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/cardinality", http.NoBody))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var metrics []struct {
+		Name   string `json:"name"`
+		Series int    `json:"series"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &metrics))
+	require.Equal(t, []struct {
+		Name   string `json:"name"`
+		Series int    `json:"series"`
+	}{{Name: "events", Series: 2}, {Name: "pending", Series: 1}}, metrics, "metrics are sorted by decreasing cardinality")
+}
@@ -23,6 +23,7 @@ type periodicStatsConfig struct {
 	enableCPUStats          bool
 	enableMemStats          bool
 	enableGCStats           bool
+	enableSelfStats         bool
 	metricManager           metric.Manager
 }
 
@@ -51,6 +52,7 @@ type statsdState struct {
 	connEstablished bool
 	rc              runtimeStatsCollector
 	mc              metricStatsCollector
+	sc              *selfStatsCollector
 
 	clientsLock    sync.RWMutex
 	clients        map[string]*statsdClient
@@ -0,0 +1,204 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// Observations of the timers and histograms of the metrics listed in statsExemplars.metrics are linked to the traces
+// they are part of by exemplars, e.g.
+//
+//	statsExemplars:
+//	  metrics: [upload_time, event_delivery_time]
+//	  buckets: [0.1, 1, 10, 60, 600]
+//
+// Every bucket of every series of these metrics keeps the latest observation recorded with SendTimingWithTrace or
+// ObserveWithTrace in a sampled trace as its exemplar, so that a slow bucket can be followed to one of its traces.
+// Buckets are the upper bounds of the observations, in seconds for timers, the last bucket having no bound. Neither
+// statsd nor the OTLP exporter carry exemplars, so they are reported by the /stats/exemplars admin endpoint.
+
+// Exemplar is an observation of a metric made in a trace
+type Exemplar struct {
+	TraceID string    `json:"traceId"`
+	SpanID  string    `json:"spanId"`
+	Value   float64   `json:"value"`
+	Time    time.Time `json:"time"`
+}
+
+// BucketExemplar is the exemplar of the bucket of the observations less than or equal to LE, +Inf for the last one
+type BucketExemplar struct {
+	LE string `json:"le"`
+	Exemplar
+}
+
+// HistogramExemplars are the exemplars of the buckets of a series of a metric
+type HistogramExemplars struct {
+	Name    string           `json:"name"`
+	Tags    Tags             `json:"tags"`
+	Buckets []BucketExemplar `json:"buckets"`
+}
+
+// ExemplarReporter reports the exemplars of the metrics of Stats
+type ExemplarReporter interface {
+	Exemplars() []HistogramExemplars
+}
+
+// exemplarMeasurement is implemented by measurements keeping exemplars
+type exemplarMeasurement interface {
+	recordExemplar(value float64, spanContext trace.SpanContext)
+}
+
+// SendTimingWithTrace sends the timing, linking it to the trace of spanContext if m keeps exemplars
+func SendTimingWithTrace(m Measurement, duration time.Duration, spanContext trace.SpanContext) {
+	m.SendTiming(duration)
+	if e, ok := m.(exemplarMeasurement); ok && spanContext.IsSampled() {
+		e.recordExemplar(duration.Seconds(), spanContext)
+	}
+}
+
+// ObserveWithTrace sends the observation, linking it to the trace of spanContext if m keeps exemplars
+func ObserveWithTrace(m Measurement, value float64, spanContext trace.SpanContext) {
+	m.Observe(value)
+	if e, ok := m.(exemplarMeasurement); ok && spanContext.IsSampled() {
+		e.recordExemplar(value, spanContext)
+	}
+}
+
+// SpanContextFromTraceParent returns the span context of a W3C traceparent, invalid if it isn't one
+func SpanContextFromTraceParent(traceParent string) trace.SpanContext {
+	if traceParent == "" {
+		return trace.SpanContext{}
+	}
+	carrier := propagation.MapCarrier{"traceparent": traceParent}
+	return trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+}
+
+// exemplarStore keeps the exemplars of the buckets of the series of metrics, a nil store keeping none
+type exemplarStore struct {
+	metrics map[string]struct{}
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]map[string]*exemplarSeries // metric -> tags of its series -> exemplars
+}
+
+// exemplarSeries are the exemplars of the buckets of a series, nil for buckets without any
+type exemplarSeries struct {
+	tags      Tags
+	exemplars []*Exemplar
+}
+
+func newExemplarStore(config *config.Config) (*exemplarStore, error) {
+	metrics := config.GetStringSlice("statsExemplars.metrics", []string{"upload_time", "event_delivery_time"})
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+	e := &exemplarStore{
+		metrics: make(map[string]struct{}, len(metrics)),
+		series:  make(map[string]map[string]*exemplarSeries),
+	}
+	for _, name := range metrics {
+		e.metrics[name] = struct{}{}
+	}
+	for _, bucket := range config.GetStringSlice("statsExemplars.buckets", []string{"0.1", "0.5", "1", "5", "10", "30", "60", "300", "600", "1800", "3600"}) {
+		bound, err := strconv.ParseFloat(strings.TrimSpace(bucket), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exemplar bucket %q: %w", bucket, err)
+		}
+		e.buckets = append(e.buckets, bound)
+	}
+	sort.Float64s(e.buckets)
+	return e, nil
+}
+
+// record keeps the observation made in the trace of spanContext as the exemplar of its bucket, if the metric has any
+func (e *exemplarStore) record(name string, tags Tags, value float64, spanContext trace.SpanContext, now time.Time) {
+	if e == nil {
+		return
+	}
+	if _, ok := e.metrics[name]; !ok {
+		return
+	}
+	bucket := sort.SearchFloat64s(e.buckets, value)
+	key := tags.String()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	series, ok := e.series[name]
+	if !ok {
+		series = make(map[string]*exemplarSeries)
+		e.series[name] = series
+	}
+	exemplars, ok := series[key]
+	if !ok {
+		exemplars = &exemplarSeries{tags: tags, exemplars: make([]*Exemplar, len(e.buckets)+1)}
+		series[key] = exemplars
+	}
+	exemplars.exemplars[bucket] = &Exemplar{
+		TraceID: spanContext.TraceID().String(),
+		SpanID:  spanContext.SpanID().String(),
+		Value:   value,
+		Time:    now,
+	}
+}
+
+// Exemplars returns the exemplars of the series of the metrics, sorted by metric and tags
+func (e *exemplarStore) Exemplars() []HistogramExemplars {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var histograms []HistogramExemplars
+	for name, series := range e.series {
+		for _, exemplars := range series {
+			histogram := HistogramExemplars{Name: name, Tags: exemplars.tags}
+			for i, exemplar := range exemplars.exemplars {
+				if exemplar == nil {
+					continue
+				}
+				le := "+Inf"
+				if i < len(e.buckets) {
+					le = strconv.FormatFloat(e.buckets[i], 'f', -1, 64)
+				}
+				histogram.Buckets = append(histogram.Buckets, BucketExemplar{LE: le, Exemplar: *exemplar})
+			}
+			histograms = append(histograms, histogram)
+		}
+	}
+	sort.Slice(histograms, func(i, j int) bool {
+		if histograms[i].Name != histograms[j].Name {
+			return histograms[i].Name < histograms[j].Name
+		}
+		return histograms[i].Tags.String() < histograms[j].Tags.String()
+	})
+	return histograms
+}
+
+// ExemplarsHandler serves the exemplars of the metrics of s
+func ExemplarsHandler(s Stats) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reporter, ok := s.(ExemplarReporter)
+		if !ok {
+			http.Error(w, "stats don't keep exemplars", http.StatusNotImplemented)
+			return
+		}
+		histograms := reporter.Exemplars()
+		if histograms == nil {
+			histograms = []HistogramExemplars{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(histograms)
+	})
+}
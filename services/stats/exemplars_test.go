@@ -0,0 +1,98 @@
+package stats
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/metric"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+const (
+	sampledTraceParent   = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	unsampledTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+)
+
+func TestSpanContextFromTraceParent(t *testing.T) {
+	spanContext := SpanContextFromTraceParent(sampledTraceParent)
+	require.True(t, spanContext.IsValid())
+	require.True(t, spanContext.IsSampled())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", spanContext.SpanID().String())
+
+	require.False(t, SpanContextFromTraceParent("").IsValid())
+	require.False(t, SpanContextFromTraceParent("invalid").IsValid())
+}
+
+func TestExemplarStore(t *testing.T) {
+	c := config.New()
+	c.Set("statsExemplars.metrics", []string{"event_delivery_time"})
+	c.Set("statsExemplars.buckets", []string{"10", "1"})
+	e, err := newExemplarStore(c)
+	require.NoError(t, err)
+
+	spanContext := SpanContextFromTraceParent(sampledTraceParent)
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	e.record("event_delivery_time", Tags{"destType": "WEBHOOK"}, 0.5, spanContext, now)
+	e.record("event_delivery_time", Tags{"destType": "WEBHOOK"}, 0.7, spanContext, now.Add(time.Second))
+	e.record("event_delivery_time", Tags{"destType": "WEBHOOK"}, 30, spanContext, now)
+	e.record("processor_time", Tags{"destType": "WEBHOOK"}, 0.5, spanContext, now)
+
+	exemplar := Exemplar{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	latest, slow := exemplar, exemplar
+	latest.Value, latest.Time = 0.7, now.Add(time.Second)
+	slow.Value, slow.Time = 30, now
+	require.Equal(t, []HistogramExemplars{{
+		Name: "event_delivery_time",
+		Tags: Tags{"destType": "WEBHOOK"},
+		Buckets: []BucketExemplar{
+			{LE: "1", Exemplar: latest},
+			{LE: "+Inf", Exemplar: slow},
+		},
+	}}, e.Exemplars(), "buckets keep their latest exemplar, only for the metrics keeping exemplars")
+
+	c.Set("statsExemplars.buckets", []string{"slow"})
+	_, err = newExemplarStore(c)
+	require.Error(t, err)
+
+	c.Set("statsExemplars.metrics", []string{})
+	e, err = newExemplarStore(c)
+	require.NoError(t, err)
+	require.Nil(t, e)
+	e.record("event_delivery_time", Tags{}, 0.5, spanContext, now)
+	require.Empty(t, e.Exemplars())
+}
+
+func TestExemplarsHandler(t *testing.T) {
+	for _, exporter := range []string{statsExporterStatsd, statsExporterOTLP} {
+		t.Run(exporter, func(t *testing.T) {
+			c := config.New()
+			c.Set("statsExporter", exporter)
+			c.Set("RuntimeStats.enabled", false)
+			s := NewStats(c, logger.NewFactory(c), metric.NewManager())
+
+			m := s.NewTaggedStat("event_delivery_time", TimerType, Tags{"destType": "WEBHOOK"})
+			SendTimingWithTrace(m, 2*time.Second, SpanContextFromTraceParent(sampledTraceParent))
+			SendTimingWithTrace(m, time.Hour, SpanContextFromTraceParent(unsampledTraceParent))
+			SendTimingWithTrace(m, time.Hour, trace.SpanContext{})
+
+			rec := httptest.NewRecorder()
+			ExemplarsHandler(s).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/exemplars", http.NoBody))
+			require.Equal(t, http.StatusOK, rec.Code)
+			var histograms []HistogramExemplars
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &histograms))
+			require.Len(t, histograms, 1)
+			require.Equal(t, Tags{"destType": "WEBHOOK"}, histograms[0].Tags)
+			require.Len(t, histograms[0].Buckets, 1, "only sampled traces are exemplars")
+			require.Equal(t, "5", histograms[0].Buckets[0].LE)
+			require.Equal(t, 2.0, histograms[0].Buckets[0].Value)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package stats
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// HTTP clients instrumented with InstrumentHTTPClient measure their requests, instead of them being timed by hand:
+//   - http_client_time, the latency of the requests until their response headers are received, tagged with their
+//     method and the class of their status code, e.g. 2xx, or error if no response is received
+//   - http_client_errors, the requests failing without a response, or with a 5xx response
+//   - http_client_in_flight, the requests in progress
+//
+// Metrics are tagged with the tags of the client, e.g. the module using it, and the ones of the request set by
+// WithHTTPClientTags, e.g. the destination it is sent for.
+
+type httpClientTagsKey struct{}
+
+// WithHTTPClientTags returns a copy of ctx tagging the metrics of the requests sent with it by instrumented clients
+// with the tags, in addition to the tags of the client.
+func WithHTTPClientTags(ctx context.Context, tags Tags) context.Context {
+	if parent, ok := ctx.Value(httpClientTagsKey{}).(Tags); ok {
+		tags = mergeTags(parent, tags)
+	}
+	return context.WithValue(ctx, httpClientTagsKey{}, tags)
+}
+
+// InstrumentHTTPClient returns a copy of the client, the default one if nil, measuring its requests with s. Its
+// metrics are tagged with the tags.
+func InstrumentHTTPClient(s Stats, client *http.Client, tags Tags) *http.Client {
+	if client == nil {
+		client = &http.Client{}
+	}
+	instrumented := *client
+	instrumented.Transport = InstrumentHTTPTransport(s, client.Transport, tags)
+	return &instrumented
+}
+
+// InstrumentHTTPTransport returns a transport measuring the requests sent through the transport, the default one if
+// nil, with s. Its metrics are tagged with the tags.
+func InstrumentHTTPTransport(s Stats, transport http.RoundTripper, tags Tags) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &httpTransport{transport: transport, stats: s, tags: tags}
+}
+
+type httpTransport struct {
+	transport http.RoundTripper
+	stats     Stats
+	tags      Tags
+	inFlight  int64
+}
+
+func (t *httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.stats.NewTaggedStat("http_client_in_flight", GaugeType, t.tags).Gauge(atomic.AddInt64(&t.inFlight, 1))
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+
+	requestTags, _ := req.Context().Value(httpClientTagsKey{}).(Tags)
+	tags := mergeTags(t.tags, requestTags)
+	tags["method"] = req.Method
+	if err != nil {
+		tags["code"] = "error"
+	} else {
+		tags["code"] = strconv.Itoa(resp.StatusCode/100) + "xx"
+	}
+	t.stats.NewTaggedStat("http_client_time", TimerType, tags).Since(start)
+	if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+		t.stats.NewTaggedStat("http_client_errors", CountType, tags).Increment()
+	}
+	t.stats.NewTaggedStat("http_client_in_flight", GaugeType, t.tags).Gauge(atomic.AddInt64(&t.inFlight, -1))
+	return resp, err
+}
@@ -0,0 +1,44 @@
+package stats
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+func TestInstrumentHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	s, reader := newTestOtelStats(t, config.New())
+	client := InstrumentHTTPClient(s, nil, Tags{"module": "config/backend-config"})
+	for _, path := range []string{"/", "/", "/fail"} {
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+	_, err := client.Get("http://127.0.0.1:0")
+	require.Error(t, err)
+	ctx := WithHTTPClientTags(context.Background(), Tags{"destID": "destination-id"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	metrics := collectMetrics(t, reader)
+	require.Equal(t, map[string]float64{"2xx": 3, "5xx": 1, "error": 1}, valuesByTag(t, metrics["http_client_time"], "code"))
+	require.Equal(t, map[string]float64{"": 4, "destination-id": 1}, valuesByTag(t, metrics["http_client_time"], "destID"))
+	require.Equal(t, map[string]float64{"5xx": 1, "error": 1}, valuesByTag(t, metrics["http_client_errors"], "code"))
+	require.Equal(t, map[string]float64{"config/backend-config": 0}, valuesByTag(t, metrics["http_client_in_flight"], "module"))
+	require.Nil(t, http.DefaultClient.Transport, "the client is copied")
+}
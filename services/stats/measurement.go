@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/alexcesaro/statsd.v2"
 )
 
@@ -42,15 +43,16 @@ type Measurement interface {
 
 // statsdMeasurement is the statsd-specific implementation of Measurement
 type statsdMeasurement struct {
-	conf     *statsdConfig
+	stats    *statsdStats
 	name     string
 	statType string
+	tags     Tags
 	client   *statsdClient
 }
 
-// skip returns true if the stat should be skipped (stats disabled or client not ready)
+// skip returns true if the stat should be skipped (stats disabled, client not ready or series switched off)
 func (m *statsdMeasurement) skip() bool {
-	return !m.conf.enabled || !m.client.ready()
+	return !m.stats.conf.enabled || !m.client.ready() || !m.stats.toggles.enabled(m.name, m.tags)
 }
 
 // Count default behavior is to panic as not supported operation
@@ -92,6 +94,14 @@ func (m *statsdMeasurement) Since(_ time.Time) {
 	panic(fmt.Errorf("operation Since not supported for measurement type:%s", m.statType))
 }
 
+// recordExemplar keeps the observation made in the trace of spanContext as an exemplar
+func (m *statsdMeasurement) recordExemplar(value float64, spanContext trace.SpanContext) {
+	if !m.stats.conf.enabled || !m.stats.toggles.enabled(m.name, m.tags) {
+		return
+	}
+	m.stats.exemplars.record(m.name, m.tags, value, spanContext, time.Now())
+}
+
 // statsdCounter represents a counter stat
 type statsdCounter struct {
 	*statsdMeasurement
@@ -176,11 +186,12 @@ func (h *statsdHistogram) Observe(value float64) {
 }
 
 // newStatsdMeasurement creates a new measurement of the specific type
-func newStatsdMeasurement(conf *statsdConfig, name, statType string, client *statsdClient) Measurement {
+func newStatsdMeasurement(stats *statsdStats, name, statType string, tags Tags, client *statsdClient) Measurement {
 	baseMeasurement := &statsdMeasurement{
-		conf:     conf,
+		stats:    stats,
 		name:     name,
 		statType: statType,
+		tags:     tags,
 		client:   client,
 	}
 	switch statType {
@@ -0,0 +1,620 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/asyncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/unit"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/aggregation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+// Stats are exported with OTLP instead of statsd if statsExporter is otlp, e.g. to an OpenTelemetry collector:
+//
+//	statsExporter: otlp
+//	OpenTelemetry:
+//	  metrics:
+//	    endpoint: localhost:4317
+//	    protocol: grpc # or http
+//	    temporality: cumulative # or delta
+//	    resourceAttributes:
+//	      destType: WEBHOOK
+//	    views:
+//	      "router_delivery_time":
+//	        buckets: [0.01, 0.1, 1, 10]
+//	      "jobsdb_*":
+//	        attributes: [module, customVal]
+//	      "runtime_*":
+//	        drop: true
+//
+// Counters are exported as sums, gauges as gauges, and timers, in seconds, and histograms as histograms. Besides the
+// resourceAttributes, resources have the service name, instance, namespace and mode (APP_TYPE) of the server. Views
+// match metrics by name, with * and ? wildcards, and may rename them, set the buckets of histograms, keep only some
+// of their attributes or drop them altogether.
+
+const (
+	statsExporterStatsd = "statsd"
+	statsExporterOTLP   = "otlp"
+
+	otelMeterName = "github.com/rudderlabs/rudder-server"
+)
+
+// otelConfig is the configuration of the OTLP metrics exporter
+type otelConfig struct {
+	endpoint           string
+	protocol           string
+	insecure           bool
+	interval           time.Duration
+	temporality        string
+	resourceAttributes map[string]string
+	views              map[string]otelViewConfig // metric name pattern -> view
+}
+
+// otelViewConfig is the configuration of the view of the metrics matching a name pattern
+type otelViewConfig struct {
+	Name       string    `json:"name"`
+	Buckets    []float64 `json:"buckets"`
+	Attributes []string  `json:"attributes"`
+	Drop       bool      `json:"drop"`
+}
+
+func newOtelConfig(config *config.Config) (otelConfig, error) {
+	conf := otelConfig{
+		endpoint:           config.GetString("OpenTelemetry.metrics.endpoint", "localhost:4317"),
+		protocol:           strings.ToLower(config.GetString("OpenTelemetry.metrics.protocol", "grpc")),
+		insecure:           config.GetBool("OpenTelemetry.metrics.insecure", true),
+		interval:           config.GetDuration("OpenTelemetry.metrics.interval", 10, time.Second),
+		temporality:        strings.ToLower(config.GetString("OpenTelemetry.metrics.temporality", "cumulative")),
+		resourceAttributes: make(map[string]string),
+		views:              make(map[string]otelViewConfig),
+	}
+	if conf.protocol != "grpc" && conf.protocol != "http" {
+		return conf, fmt.Errorf("unsupported OpenTelemetry metrics protocol %q", conf.protocol)
+	}
+	if conf.temporality != "cumulative" && conf.temporality != "delta" {
+		return conf, fmt.Errorf("unsupported OpenTelemetry metrics temporality %q", conf.temporality)
+	}
+	for key, value := range config.GetStringMap("OpenTelemetry.metrics.resourceAttributes", nil) {
+		conf.resourceAttributes[key] = cast.ToString(value)
+	}
+	for pattern, value := range config.GetStringMap("OpenTelemetry.metrics.views", nil) {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return conf, err
+		}
+		var view otelViewConfig
+		if err := json.Unmarshal(b, &view); err != nil {
+			return conf, fmt.Errorf("invalid view %s of metrics %s: %w", b, pattern, err)
+		}
+		if view.Name != "" && strings.ContainsAny(pattern, "*?") {
+			return conf, fmt.Errorf("view of metrics %s can't rename metrics matched by wildcards", pattern)
+		}
+		conf.views[pattern] = view
+	}
+	return conf, nil
+}
+
+// sdkViews returns the views of the meter provider
+func (c *otelConfig) sdkViews() []sdkmetric.View {
+	views := make([]sdkmetric.View, 0, len(c.views))
+	for pattern, view := range c.views {
+		stream := sdkmetric.Stream{Name: view.Name}
+		switch {
+		case view.Drop:
+			stream.Aggregation = aggregation.Drop{}
+		case len(view.Buckets) > 0:
+			stream.Aggregation = aggregation.ExplicitBucketHistogram{Boundaries: view.Buckets}
+		}
+		if len(view.Attributes) > 0 {
+			kept := make(map[attribute.Key]struct{}, len(view.Attributes))
+			for _, key := range view.Attributes {
+				kept[attribute.Key(key)] = struct{}{}
+			}
+			stream.AttributeFilter = func(kv attribute.KeyValue) bool {
+				_, ok := kept[kv.Key]
+				return ok
+			}
+		}
+		views = append(views, sdkmetric.NewView(sdkmetric.Instrument{Name: pattern}, stream))
+	}
+	return views
+}
+
+// temporalitySelector returns the temporality of the metrics of every kind of instrument
+func (c *otelConfig) temporalitySelector() sdkmetric.TemporalitySelector {
+	if c.temporality != "delta" {
+		return sdkmetric.DefaultTemporalitySelector
+	}
+	return func(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+		switch kind {
+		case sdkmetric.InstrumentKindSyncCounter, sdkmetric.InstrumentKindSyncHistogram, sdkmetric.InstrumentKindAsyncCounter:
+			return metricdata.DeltaTemporality
+		default:
+			return metricdata.CumulativeTemporality
+		}
+	}
+}
+
+// otelStats is the OpenTelemetry implementation of Stats
+type otelStats struct {
+	log         logger.Logger
+	conf        *statsdConfig
+	otelConf    otelConfig
+	pushConf    *pushConfig // stats are pushed instead of exported with OTLP, if set
+	appType     string
+	cardinality *cardinalityLimiter
+	exemplars   *exemplarStore
+	toggles     *metricToggles
+
+	meterMu  sync.RWMutex
+	meter    metric.Meter
+	provider *sdkmetric.MeterProvider
+
+	counters   map[string]syncint64.Counter
+	histograms map[string]syncfloat64.Histogram
+	gauges     map[string]*otelGaugeValues
+	rc         runtimeStatsCollector
+	mc         metricStatsCollector
+	sc         *selfStatsCollector
+}
+
+// Cardinality returns the cardinality of every metric
+func (s *otelStats) Cardinality() map[string]MetricCardinality {
+	return s.cardinality.Cardinality()
+}
+
+// Exemplars returns the exemplars of the series of the metrics
+func (s *otelStats) Exemplars() []HistogramExemplars {
+	return s.exemplars.Exemplars()
+}
+
+// Toggles returns the toggles of the metrics
+func (s *otelStats) Toggles() []MetricToggle {
+	return s.toggles.Toggles()
+}
+
+// SetToggle sets the toggle of the series of its metric with its tags
+func (s *otelStats) SetToggle(toggle MetricToggle) error {
+	return s.toggles.SetToggle(toggle)
+}
+
+// RemoveToggle removes the toggle of the series of the metric with the tags
+func (s *otelStats) RemoveToggle(metric string, tags Tags) error {
+	return s.toggles.RemoveToggle(metric, tags)
+}
+
+func (s *otelStats) Start(ctx context.Context) {
+	if !s.conf.enabled {
+		return
+	}
+	var (
+		exporter sdkmetric.Exporter
+		err      error
+	)
+	interval := s.otelConf.interval
+	switch {
+	case s.pushConf != nil:
+		exporter = newPushExporter(s.pushConf, s.log)
+		interval = s.pushConf.interval
+	case s.otelConf.protocol == "http":
+		opts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpoint(s.otelConf.endpoint),
+			otlpmetrichttp.WithTemporalitySelector(s.otelConf.temporalitySelector()),
+		}
+		if s.otelConf.insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		exporter, err = otlpmetrichttp.New(ctx, opts...)
+	default:
+		opts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpoint(s.otelConf.endpoint),
+			otlpmetricgrpc.WithTemporalitySelector(s.otelConf.temporalitySelector()),
+		}
+		if s.otelConf.insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		exporter, err = otlpmetricgrpc.New(ctx, opts...)
+	}
+	if err != nil {
+		s.log.Errorf("error while creating OTLP metrics exporter: %v", err)
+		return
+	}
+	if err := s.start(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))); err != nil {
+		s.log.Errorf("error while starting OpenTelemetry meter provider: %v", err)
+	}
+}
+
+// start makes the measurements record metrics collected by the reader, and starts the collection of periodic stats
+func (s *otelStats) start(reader sdkmetric.Reader) error {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, s.resourceAttributes()...))
+	if err != nil {
+		return err
+	}
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithView(s.otelConf.sdkViews()...),
+	)
+
+	s.meterMu.Lock()
+	s.provider = provider
+	s.meter = provider.Meter(otelMeterName)
+	// instruments of the no-op meter are created again from the exporting one
+	s.counters = make(map[string]syncint64.Counter)
+	s.histograms = make(map[string]syncfloat64.Histogram)
+	for _, gauge := range s.gauges {
+		s.registerGauge(gauge)
+	}
+	s.meterMu.Unlock()
+
+	s.rc = newRuntimeStatsCollector(func(key string, val uint64) {
+		s.NewStat("runtime_"+key, GaugeType).Gauge(val)
+	})
+	s.rc.PauseDur = time.Duration(s.conf.periodic.statsCollectionInterval) * time.Second
+	s.rc.EnableCPU = s.conf.periodic.enableCPUStats
+	s.rc.EnableMem = s.conf.periodic.enableMemStats
+	s.rc.EnableGC = s.conf.periodic.enableGCStats
+	s.mc = newMetricStatsCollector(s, s.conf.periodic.metricManager)
+	s.sc = newSelfStatsCollector(s, s.rc.PauseDur)
+	if s.conf.periodic.enabled {
+		rruntime.Go(s.rc.run)
+		rruntime.Go(s.mc.run)
+		if s.conf.periodic.enableSelfStats {
+			rruntime.Go(s.sc.run)
+		}
+	}
+	return nil
+}
+
+// resourceAttributes returns the attributes of the resource the metrics are exported for
+func (s *otelStats) resourceAttributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String("rudder-server"),
+		attribute.String("instanceName", s.conf.instanceID),
+		attribute.String("mode", s.appType),
+	}
+	if s.conf.instanceID != "" {
+		attrs = append(attrs, semconv.ServiceInstanceIDKey.String(s.conf.instanceID))
+	}
+	if namespace := config.GetKubeNamespace(); namespace != "" {
+		attrs = append(attrs, attribute.String("namespace", namespace))
+	}
+	for key, value := range s.otelConf.resourceAttributes {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	return attrs
+}
+
+// Stop stops periodic collection of stats and exports the pending metrics
+func (s *otelStats) Stop() {
+	s.meterMu.RLock()
+	provider := s.provider
+	s.meterMu.RUnlock()
+	if provider == nil {
+		return
+	}
+	if s.rc.done != nil && s.conf.periodic.enabled {
+		close(s.rc.done)
+		close(s.mc.done)
+		close(s.sc.done)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		s.log.Errorf("error while shutting down OpenTelemetry meter provider: %v", err)
+	}
+}
+
+func (s *otelStats) NewStat(name, statType string) (m Measurement) {
+	return s.NewTaggedStat(name, statType, nil)
+}
+
+func (s *otelStats) NewTaggedStat(name, statType string, tags Tags) (m Measurement) {
+	return s.newMeasurement(name, statType, tags)
+}
+
+// NewSampledTaggedStat isn't sampled, since OpenTelemetry aggregates measurements before exporting them
+func (s *otelStats) NewSampledTaggedStat(name, statType string, tags Tags) (m Measurement) {
+	return s.newMeasurement(name, statType, tags)
+}
+
+func (s *otelStats) newMeasurement(name, statType string, tags Tags) Measurement {
+	included := make(Tags, len(tags))
+	for key, value := range tags {
+		if !s.isExcludedTag(key) {
+			included[key] = value
+		}
+	}
+	tags = s.cardinality.limit(name, included)
+	attrs := make([]attribute.KeyValue, 0, len(tags))
+	for key, value := range tags {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	base := &otelMeasurement{stats: s, name: name, statType: statType, tags: tags, attrs: attrs}
+	switch statType {
+	case CountType:
+		return &otelCounter{base}
+	case GaugeType:
+		return &otelGauge{otelMeasurement: base, gauge: s.gauge(name)}
+	case TimerType:
+		return &otelTimer{otelMeasurement: base}
+	case HistogramType:
+		return &otelHistogram{base}
+	default:
+		panic(fmt.Errorf("unsupported measurement type %s", statType))
+	}
+}
+
+func (s *otelStats) isExcludedTag(key string) bool {
+	for _, excludedTag := range s.conf.excludedTags {
+		if key == excludedTag {
+			return true
+		}
+	}
+	return false
+}
+
+// counter returns the counter instrument of the metric
+func (s *otelStats) counter(name string) syncint64.Counter {
+	s.meterMu.RLock()
+	counter, ok := s.counters[name]
+	s.meterMu.RUnlock()
+	if ok {
+		return counter
+	}
+	s.meterMu.Lock()
+	defer s.meterMu.Unlock()
+	if counter, ok = s.counters[name]; !ok {
+		var err error
+		if counter, err = s.meter.SyncInt64().Counter(name); err != nil {
+			s.log.Errorf("error while creating counter %s: %v", name, err)
+			counter, _ = metric.NewNoopMeter().SyncInt64().Counter(name)
+		}
+		s.counters[name] = counter
+	}
+	return counter
+}
+
+// histogram returns the histogram instrument of the metric, recording values of the unit
+func (s *otelStats) histogram(name string, u unit.Unit) syncfloat64.Histogram {
+	s.meterMu.RLock()
+	histogram, ok := s.histograms[name]
+	s.meterMu.RUnlock()
+	if ok {
+		return histogram
+	}
+	s.meterMu.Lock()
+	defer s.meterMu.Unlock()
+	if histogram, ok = s.histograms[name]; !ok {
+		var err error
+		if histogram, err = s.meter.SyncFloat64().Histogram(name, instrument.WithUnit(u)); err != nil {
+			s.log.Errorf("error while creating histogram %s: %v", name, err)
+			histogram, _ = metric.NewNoopMeter().SyncFloat64().Histogram(name)
+		}
+		s.histograms[name] = histogram
+	}
+	return histogram
+}
+
+// gauge returns the last values of the gauge of the metric, observed by the meter
+func (s *otelStats) gauge(name string) *otelGaugeValues {
+	s.meterMu.Lock()
+	defer s.meterMu.Unlock()
+	gauge, ok := s.gauges[name]
+	if !ok {
+		gauge = &otelGaugeValues{name: name, values: make(map[attribute.Distinct]otelGaugeValue)}
+		s.gauges[name] = gauge
+		s.registerGauge(gauge)
+	}
+	return gauge
+}
+
+// registerGauge makes the meter observe the last values of the gauge, with meterMu locked
+func (s *otelStats) registerGauge(gauge *otelGaugeValues) {
+	observable, err := s.meter.AsyncFloat64().Gauge(gauge.name)
+	if err != nil {
+		s.log.Errorf("error while creating gauge %s: %v", gauge.name, err)
+		return
+	}
+	err = s.meter.RegisterCallback([]instrument.Asynchronous{observable}, func(ctx context.Context) {
+		gauge.observe(ctx, observable)
+	})
+	if err != nil {
+		s.log.Errorf("error while registering gauge %s: %v", gauge.name, err)
+	}
+}
+
+// otelGaugeValues holds the last values of a gauge per set of attributes
+type otelGaugeValues struct {
+	name   string
+	mu     sync.Mutex
+	values map[attribute.Distinct]otelGaugeValue
+}
+
+type otelGaugeValue struct {
+	attrs []attribute.KeyValue
+	value float64
+}
+
+func (g *otelGaugeValues) set(attrs []attribute.KeyValue, value float64) {
+	set := attribute.NewSet(attrs...)
+	g.mu.Lock()
+	g.values[set.Equivalent()] = otelGaugeValue{attrs: attrs, value: value}
+	g.mu.Unlock()
+}
+
+func (g *otelGaugeValues) observe(ctx context.Context, observable asyncfloat64.Gauge) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, value := range g.values {
+		observable.Observe(ctx, value.value, value.attrs...)
+	}
+}
+
+// otelMeasurement is the OpenTelemetry implementation of Measurement
+type otelMeasurement struct {
+	stats    *otelStats
+	name     string
+	statType string
+	tags     Tags
+	attrs    []attribute.KeyValue
+}
+
+func (m *otelMeasurement) skip() bool {
+	return !m.stats.conf.enabled || !m.stats.toggles.enabled(m.name, m.tags)
+}
+
+// recordExemplar keeps the observation made in the trace of spanContext as an exemplar
+func (m *otelMeasurement) recordExemplar(value float64, spanContext trace.SpanContext) {
+	if m.skip() {
+		return
+	}
+	m.stats.exemplars.record(m.name, m.tags, value, spanContext, time.Now())
+}
+
+// Count default behavior is to panic as not supported operation
+func (m *otelMeasurement) Count(_ int) {
+	panic(fmt.Errorf("operation Count not supported for measurement type:%s", m.statType))
+}
+
+// Increment default behavior is to panic as not supported operation
+func (m *otelMeasurement) Increment() {
+	panic(fmt.Errorf("operation Increment not supported for measurement type:%s", m.statType))
+}
+
+// Gauge default behavior is to panic as not supported operation
+func (m *otelMeasurement) Gauge(_ interface{}) {
+	panic(fmt.Errorf("operation Gauge not supported for measurement type:%s", m.statType))
+}
+
+// Observe default behavior is to panic as not supported operation
+func (m *otelMeasurement) Observe(_ float64) {
+	panic(fmt.Errorf("operation Observe not supported for measurement type:%s", m.statType))
+}
+
+// Start default behavior is to panic as not supported operation
+func (m *otelMeasurement) Start() {
+	panic(fmt.Errorf("operation Start not supported for measurement type:%s", m.statType))
+}
+
+// End default behavior is to panic as not supported operation
+func (m *otelMeasurement) End() {
+	panic(fmt.Errorf("operation End not supported for measurement type:%s", m.statType))
+}
+
+// SendTiming default behavior is to panic as not supported operation
+func (m *otelMeasurement) SendTiming(_ time.Duration) {
+	panic(fmt.Errorf("operation SendTiming not supported for measurement type:%s", m.statType))
+}
+
+// Since default behavior is to panic as not supported operation
+func (m *otelMeasurement) Since(_ time.Time) {
+	panic(fmt.Errorf("operation Since not supported for measurement type:%s", m.statType))
+}
+
+// otelCounter represents a counter stat
+type otelCounter struct {
+	*otelMeasurement
+}
+
+func (c *otelCounter) Count(n int) {
+	if c.skip() {
+		return
+	}
+	c.stats.counter(c.name).Add(context.Background(), int64(n), c.attrs...)
+}
+
+// Increment increases the stat by 1. Is the Equivalent of Count(1). Only applies to CountType stats
+func (c *otelCounter) Increment() {
+	c.Count(1)
+}
+
+// otelGauge represents a gauge stat
+type otelGauge struct {
+	*otelMeasurement
+	gauge *otelGaugeValues
+}
+
+// Gauge records an absolute value for this stat. Only applies to GaugeType stats
+func (g *otelGauge) Gauge(value interface{}) {
+	if g.skip() {
+		return
+	}
+	v, err := cast.ToFloat64E(value)
+	if err != nil {
+		g.stats.log.Errorf("unsupported value %v of gauge %s: %v", value, g.name, err)
+		return
+	}
+	g.gauge.set(g.attrs, v)
+}
+
+// otelTimer represents a timer stat, recording durations in seconds
+type otelTimer struct {
+	*otelMeasurement
+	mu        sync.Mutex
+	startTime time.Time
+}
+
+// Start starts a new timing for this stat. Only applies to TimerType stats
+// Deprecated: Use concurrent safe SendTiming() instead
+func (t *otelTimer) Start() {
+	t.mu.Lock()
+	t.startTime = time.Now()
+	t.mu.Unlock()
+}
+
+// End send the time elapsed since the Start()  call of this stat. Only applies to TimerType stats
+// Deprecated: Use concurrent safe SendTiming() instead
+func (t *otelTimer) End() {
+	t.mu.Lock()
+	startTime := t.startTime
+	t.mu.Unlock()
+	t.SendTiming(time.Since(startTime))
+}
+
+// Since sends the time elapsed since duration start. Only applies to TimerType stats
+func (t *otelTimer) Since(start time.Time) {
+	t.SendTiming(time.Since(start))
+}
+
+// SendTiming sends a timing for this stat. Only applies to TimerType stats
+func (t *otelTimer) SendTiming(duration time.Duration) {
+	if t.skip() {
+		return
+	}
+	t.stats.histogram(t.name, "s").Record(context.Background(), duration.Seconds(), t.attrs...)
+}
+
+// otelHistogram represents a histogram stat
+type otelHistogram struct {
+	*otelMeasurement
+}
+
+// Observe sends an observation
+func (h *otelHistogram) Observe(value float64) {
+	if h.skip() {
+		return
+	}
+	h.stats.histogram(h.name, unit.Dimensionless).Record(context.Background(), value, h.attrs...)
+}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/metric"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func newTestOtelStats(t *testing.T, c *config.Config) (*otelStats, sdkmetric.Reader) {
+	c.Set("statsExporter", "otlp")
+	c.Set("INSTANCE_ID", "test")
+	c.Set("RuntimeStats.enabled", false)
+	s, ok := NewStats(c, logger.NewFactory(c), metric.NewManager()).(*otelStats)
+	require.True(t, ok, "stats are exported with OTLP")
+	reader := sdkmetric.NewManualReader(sdkmetric.WithTemporalitySelector(s.otelConf.temporalitySelector()))
+	require.NoError(t, s.start(reader))
+	t.Cleanup(s.Stop)
+	return s, reader
+}
+
+func collectMetrics(t *testing.T, reader sdkmetric.Reader) map[string]metricdata.Metrics {
+	data, err := reader.Collect(context.Background())
+	require.NoError(t, err)
+	metrics := make(map[string]metricdata.Metrics)
+	for _, scopeMetrics := range data.ScopeMetrics {
+		for _, m := range scopeMetrics.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+	return metrics
+}
+
+func TestOtelStats(t *testing.T) {
+	c := config.New()
+	c.Set("statsExcludedTags", []string{"workspaceId"})
+	s, reader := newTestOtelStats(t, c)
+
+	s.NewTaggedStat("events", CountType, Tags{"destType": "WEBHOOK", "workspaceId": "workspace"}).Count(2)
+	s.NewTaggedStat("events", CountType, Tags{"destType": "WEBHOOK"}).Increment()
+	s.NewStat("pending", GaugeType).Gauge(10)
+	s.NewStat("pending", GaugeType).Gauge(uint64(7))
+	s.NewStat("latency", TimerType).SendTiming(1500 * time.Millisecond)
+	s.NewStat("size", HistogramType).Observe(3)
+
+	metrics := collectMetrics(t, reader)
+	sum := metrics["events"].Data.(metricdata.Sum[int64])
+	require.Len(t, sum.DataPoints, 1, "excluded tags aren't exported")
+	require.EqualValues(t, 3, sum.DataPoints[0].Value)
+	destType, _ := sum.DataPoints[0].Attributes.Value("destType")
+	require.Equal(t, "WEBHOOK", destType.AsString())
+
+	gauge := metrics["pending"].Data.(metricdata.Gauge[float64])
+	require.Len(t, gauge.DataPoints, 1)
+	require.EqualValues(t, 7, gauge.DataPoints[0].Value, "gauges export their last value")
+
+	latency := metrics["latency"].Data.(metricdata.Histogram)
+	require.Equal(t, 1.5, latency.DataPoints[0].Sum, "timers record seconds")
+	require.EqualValues(t, "s", metrics["latency"].Unit)
+	require.Equal(t, 3.0, metrics["size"].Data.(metricdata.Histogram).DataPoints[0].Sum)
+
+	data, err := reader.Collect(context.Background())
+	require.NoError(t, err)
+	instance, _ := data.Resource.Set().Value(attribute.Key("instanceName"))
+	require.Equal(t, "test", instance.AsString())
+}
+
+func TestOtelStatsMeasurementsBeforeStart(t *testing.T) {
+	c := config.New()
+	c.Set("statsExporter", "otlp")
+	s := NewStats(c, logger.NewFactory(c), metric.NewManager()).(*otelStats)
+	counter := s.NewStat("events", CountType)
+	counter.Count(1) // recorded by the no-op meter
+	gauge := s.NewStat("pending", GaugeType)
+	gauge.Gauge(5)
+
+	reader := sdkmetric.NewManualReader()
+	require.NoError(t, s.start(reader))
+	defer s.Stop()
+	counter.Count(2)
+
+	metrics := collectMetrics(t, reader)
+	require.EqualValues(t, 2, metrics["events"].Data.(metricdata.Sum[int64]).DataPoints[0].Value)
+	require.EqualValues(t, 5, metrics["pending"].Data.(metricdata.Gauge[float64]).DataPoints[0].Value)
+}
+
+func TestOtelStatsDeltaTemporality(t *testing.T) {
+	c := config.New()
+	c.Set("OpenTelemetry.metrics.temporality", "delta")
+	s, reader := newTestOtelStats(t, c)
+
+	s.NewStat("events", CountType).Count(2)
+	require.EqualValues(t, 2, collectMetrics(t, reader)["events"].Data.(metricdata.Sum[int64]).DataPoints[0].Value)
+	s.NewStat("events", CountType).Count(3)
+	sum := collectMetrics(t, reader)["events"].Data.(metricdata.Sum[int64])
+	require.Equal(t, metricdata.DeltaTemporality, sum.Temporality)
+	require.EqualValues(t, 3, sum.DataPoints[0].Value)
+}
+
+func TestOtelStatsViews(t *testing.T) {
+	c := config.New()
+	c.Set("OpenTelemetry.metrics.views", map[string]interface{}{
+		"latency":   map[string]interface{}{"name": "delivery_latency", "buckets": []interface{}{0.5, 1, 2}},
+		"jobsdb_*":  map[string]interface{}{"attributes": []interface{}{"module"}},
+		"runtime_*": map[string]interface{}{"drop": true},
+	})
+	s, reader := newTestOtelStats(t, c)
+
+	s.NewStat("latency", TimerType).SendTiming(1500 * time.Millisecond)
+	s.NewTaggedStat("jobsdb_reads", CountType, Tags{"module": "router", "customVal": "RT"}).Count(1)
+	s.NewStat("runtime_cpu.goroutines", GaugeType).Gauge(100)
+
+	metrics := collectMetrics(t, reader)
+	require.NotContains(t, metrics, "latency")
+	require.Equal(t, []float64{0.5, 1, 2}, metrics["delivery_latency"].Data.(metricdata.Histogram).DataPoints[0].Bounds)
+	attrs := metrics["jobsdb_reads"].Data.(metricdata.Sum[int64]).DataPoints[0].Attributes
+	require.Equal(t, 1, attrs.Len(), "views keep only their attributes")
+	require.NotContains(t, metrics, "runtime_cpu.goroutines")
+}
+
+func TestOtelStatsFallBackToStatsd(t *testing.T) {
+	c := config.New()
+	c.Set("statsExporter", "otlp")
+	c.Set("OpenTelemetry.metrics.protocol", "udp")
+	_, ok := NewStats(c, logger.NewFactory(c), metric.NewManager()).(*statsdStats)
+	require.True(t, ok, "stats fall back to statsd if OpenTelemetry isn't configured properly")
+
+	c = config.New()
+	c.Set("OpenTelemetry.metrics.views", map[string]interface{}{"jobsdb_*": map[string]interface{}{"name": "jobsdb"}})
+	_, err := newOtelConfig(c)
+	require.Error(t, err, "metrics matched by wildcards can't be renamed")
+}
@@ -4,6 +4,7 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/metric"
 )
 
@@ -217,3 +218,88 @@ func (c metricStatsCollector) outputStats() {
 		return true
 	})
 }
+
+// selfStatsCollector implements the periodic collection of the structured runtime stats of the server, under the
+// runtime_ namespace:
+//   - runtime_goroutines, per module of the goroutines started by rruntime, other counting the rest
+//   - runtime_heap_alloc_bytes, runtime_heap_inuse_bytes, runtime_heap_idle_bytes, runtime_heap_released_bytes,
+//     runtime_heap_objects and runtime_gc_next_bytes
+//   - runtime_gc_pause_seconds, a histogram of the GC pauses since the previous collection
+//   - runtime_pool_workers, runtime_pool_busy_workers and runtime_pool_utilization, per module and pool of the worker
+//     pools registered with rruntime
+type selfStatsCollector struct {
+	stats     Stats
+	pauseDur  time.Duration
+	lastNumGC uint32
+
+	// done, when closed, is used to signal selfStatsCollector that is should stop collecting
+	// statistics and the run function should return.
+	done chan struct{}
+}
+
+// newSelfStatsCollector creates a new selfStatsCollector.
+func newSelfStatsCollector(stats Stats, pauseDur time.Duration) *selfStatsCollector {
+	return &selfStatsCollector{
+		stats:    stats,
+		pauseDur: pauseDur,
+		done:     make(chan struct{}),
+	}
+}
+
+// run collects the structured runtime stats every pauseDur, until done is closed
+func (c *selfStatsCollector) run() {
+	m := &runtime.MemStats{}
+	runtime.ReadMemStats(m)
+	c.lastNumGC = m.NumGC
+	c.outputStats()
+
+	tick := time.NewTicker(c.pauseDur)
+	defer tick.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-tick.C:
+			c.outputStats()
+		}
+	}
+}
+
+func (c *selfStatsCollector) outputStats() {
+	goroutines := int64(runtime.NumGoroutine())
+	for module, n := range rruntime.Goroutines() {
+		c.stats.NewTaggedStat("runtime_goroutines", GaugeType, Tags{"module": module}).Gauge(n)
+		goroutines -= n
+	}
+	c.stats.NewTaggedStat("runtime_goroutines", GaugeType, Tags{"module": "other"}).Gauge(goroutines)
+
+	m := &runtime.MemStats{}
+	runtime.ReadMemStats(m)
+	c.stats.NewStat("runtime_heap_alloc_bytes", GaugeType).Gauge(m.HeapAlloc)
+	c.stats.NewStat("runtime_heap_inuse_bytes", GaugeType).Gauge(m.HeapInuse)
+	c.stats.NewStat("runtime_heap_idle_bytes", GaugeType).Gauge(m.HeapIdle)
+	c.stats.NewStat("runtime_heap_released_bytes", GaugeType).Gauge(m.HeapReleased)
+	c.stats.NewStat("runtime_heap_objects", GaugeType).Gauge(m.HeapObjects)
+	c.stats.NewStat("runtime_gc_next_bytes", GaugeType).Gauge(m.NextGC)
+	// PauseNs is a circular buffer of the last 256 pauses, the most recent one at (NumGC+255)%256
+	pauses := m.NumGC - c.lastNumGC
+	if pauses > uint32(len(m.PauseNs)) {
+		pauses = uint32(len(m.PauseNs))
+	}
+	gcPause := c.stats.NewStat("runtime_gc_pause_seconds", HistogramType)
+	for i := uint32(0); i < pauses; i++ {
+		gcPause.Observe(time.Duration(m.PauseNs[(m.NumGC-i+255)%256]).Seconds())
+	}
+	c.lastNumGC = m.NumGC
+
+	for _, pool := range rruntime.Pools() {
+		tags := Tags{"module": pool.Module, "pool": pool.Name}
+		c.stats.NewTaggedStat("runtime_pool_workers", GaugeType, tags).Gauge(pool.Size)
+		c.stats.NewTaggedStat("runtime_pool_busy_workers", GaugeType, tags).Gauge(pool.Busy)
+		var utilization float64
+		if pool.Size > 0 {
+			utilization = float64(pool.Busy) / float64(pool.Size)
+		}
+		c.stats.NewTaggedStat("runtime_pool_utilization", GaugeType, tags).Gauge(utilization)
+	}
+}
@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/rruntime"
+)
+
+func TestSelfStatsCollector(t *testing.T) {
+	s, reader := newTestOtelStats(t, config.New())
+	pool := rruntime.RegisterPool("stats", "test", 4)
+	done := pool.Busy()
+	defer done()
+	stop := make(chan struct{})
+	defer close(stop)
+	started := make(chan struct{})
+	rruntime.Go(func() {
+		close(started)
+		<-stop
+	})
+	<-started
+
+	c := newSelfStatsCollector(s, time.Second)
+	runtime.GC()
+	c.outputStats()
+
+	metrics := collectMetrics(t, reader)
+	goroutines := make(map[string]float64)
+	for _, dp := range metrics["runtime_goroutines"].Data.(metricdata.Gauge[float64]).DataPoints {
+		module, _ := dp.Attributes.Value("module")
+		goroutines[module.AsString()] = dp.Value
+	}
+	require.GreaterOrEqual(t, goroutines["services/stats"], 1.0, "goroutines are counted per module")
+	require.Greater(t, goroutines["other"], 0.0)
+
+	require.Greater(t, metrics["runtime_heap_alloc_bytes"].Data.(metricdata.Gauge[float64]).DataPoints[0].Value, 0.0)
+	require.NotZero(t, metrics["runtime_gc_pause_seconds"].Data.(metricdata.Histogram).DataPoints[0].Count, "pauses since the previous collection are observed")
+
+	for name, expected := range map[string]float64{"runtime_pool_workers": 4, "runtime_pool_busy_workers": 1, "runtime_pool_utilization": 0.25} {
+		var found bool
+		for _, dp := range metrics[name].Data.(metricdata.Gauge[float64]).DataPoints {
+			if module, _ := dp.Attributes.Value("module"); module.AsString() == "stats" {
+				require.Equal(t, expected, dp.Value, name)
+				found = true
+			}
+		}
+		require.True(t, found, name)
+	}
+}
@@ -0,0 +1,421 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/golang/snappy"
+	"github.com/mkmik/multierror"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/aggregation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+// Servers that can't be scraped, e.g. standalone warehouse slaves behind NAT, push their stats instead if
+// statsExporter is push, either to a Prometheus pushgateway or to an endpoint accepting Prometheus remote-write:
+//
+//	statsExporter: push
+//	statsPush:
+//	  protocol: pushgateway # or remotewrite
+//	  url: http://pushgateway:9091 # e.g. http://prometheus:9090/api/v1/write for remotewrite
+//	  job: rudder-server
+//	  interval: 10s
+//	  timeout: 10s
+//	  batchSize: 1000
+//	  maxRetries: 3
+//
+// Stats are aggregated like the ones exported with OTLP, OpenTelemetry.metrics views and resourceAttributes applying
+// to them too, and pushed every interval in batches of at most batchSize series, failed pushes being retried with
+// exponential backoff. Pushed series are labeled with the resource attributes of the server, and are grouped by job and
+// instance, INSTANCE_ID or the hostname by default.
+
+const (
+	statsExporterPush = "push"
+
+	pushProtocolPushgateway = "pushgateway"
+	pushProtocolRemoteWrite = "remotewrite"
+)
+
+// pushConfig is the configuration of the exporter pushing stats
+type pushConfig struct {
+	protocol   string
+	url        string
+	job        string
+	interval   time.Duration
+	timeout    time.Duration
+	batchSize  int
+	maxRetries int
+}
+
+func newPushConfig(config *config.Config) (*pushConfig, error) {
+	conf := &pushConfig{
+		protocol:   strings.ToLower(config.GetString("statsPush.protocol", pushProtocolPushgateway)),
+		url:        strings.TrimSuffix(config.GetString("statsPush.url", ""), "/"),
+		job:        config.GetString("statsPush.job", "rudder-server"),
+		interval:   config.GetDuration("statsPush.interval", 10, time.Second),
+		timeout:    config.GetDuration("statsPush.timeout", 10, time.Second),
+		batchSize:  config.GetInt("statsPush.batchSize", 1000),
+		maxRetries: config.GetInt("statsPush.maxRetries", 3),
+	}
+	if conf.protocol != pushProtocolPushgateway && conf.protocol != pushProtocolRemoteWrite {
+		return nil, fmt.Errorf("unsupported stats push protocol %q", conf.protocol)
+	}
+	if conf.url == "" {
+		return nil, fmt.Errorf("no url to push stats to")
+	}
+	if conf.job == "" {
+		return nil, fmt.Errorf("no job to push stats for")
+	}
+	if conf.batchSize <= 0 {
+		return nil, fmt.Errorf("invalid stats push batch size %d", conf.batchSize)
+	}
+	return conf, nil
+}
+
+// promLabel is a label of a Prometheus series
+type promLabel struct {
+	name  string
+	value string
+}
+
+// promSample is the value of a Prometheus series at a time
+type promSample struct {
+	name      string
+	labels    []promLabel // sorted by name
+	value     float64
+	timestamp time.Time
+}
+
+// promFamily is a Prometheus metric, with the samples of its series
+type promFamily struct {
+	name    string
+	typ     string
+	samples []promSample
+}
+
+// pushExporter exports stats by pushing them to a Prometheus pushgateway or remote-write endpoint
+type pushExporter struct {
+	conf     *pushConfig
+	log      logger.Logger
+	client   *http.Client
+	instance string
+	shutdown int32
+}
+
+func newPushExporter(conf *pushConfig, log logger.Logger) *pushExporter {
+	return &pushExporter{
+		conf:   conf,
+		log:    log,
+		client: &http.Client{Timeout: conf.timeout},
+	}
+}
+
+// Temporality is always cumulative, the only one Prometheus supports
+func (*pushExporter) Temporality(sdkmetric.InstrumentKind) metricdata.Temporality {
+	return metricdata.CumulativeTemporality
+}
+
+func (*pushExporter) Aggregation(kind sdkmetric.InstrumentKind) aggregation.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
+
+// Export pushes the metrics in batches, the ones after a batch failing to be pushed being pushed still, returning the
+// errors of the failed batches joined
+func (e *pushExporter) Export(ctx context.Context, rm metricdata.ResourceMetrics) error {
+	if atomic.LoadInt32(&e.shutdown) == 1 {
+		return sdkmetric.ErrExporterShutdown
+	}
+	instance, labels := e.resourceLabels(rm)
+	var errs []error
+	for _, batch := range batchFamilies(promFamilies(rm, labels), e.conf.batchSize) {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		var err error
+		if e.conf.protocol == pushProtocolRemoteWrite {
+			err = e.push(ctx, http.MethodPost, e.conf.url, remoteWriteBody(batch, e.conf.job, instance), map[string]string{
+				"Content-Type":                      "application/x-protobuf",
+				"Content-Encoding":                  "snappy",
+				"X-Prometheus-Remote-Write-Version": "0.1.0",
+			})
+		} else {
+			groupURL := e.conf.url + "/metrics/job/" + url.PathEscape(e.conf.job) + "/instance/" + url.PathEscape(instance)
+			err = e.push(ctx, http.MethodPost, groupURL, pushgatewayBody(batch), map[string]string{
+				"Content-Type": "text/plain; version=0.0.4",
+			})
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("pushing stats to %s: %w", e.conf.url, multierror.Join(errs))
+	}
+	return nil
+}
+
+// push sends the body, retrying with exponential backoff unless the request is rejected
+func (e *pushExporter) push(ctx context.Context, method, target string, body []byte, headers map[string]string) error {
+	bo := backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), uint64(e.conf.maxRetries)), ctx)
+	return backoff.RetryNotify(func() error {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := e.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		switch {
+		case resp.StatusCode/100 == 2:
+			return nil
+		case resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests:
+			return fmt.Errorf("status %d: %s", resp.StatusCode, respBody)
+		default:
+			return backoff.Permanent(fmt.Errorf("status %d: %s", resp.StatusCode, respBody))
+		}
+	}, bo, func(err error, d time.Duration) {
+		e.log.Warnf("Retrying pushing stats to %s in %v: %v", e.conf.url, d, err)
+	})
+}
+
+func (*pushExporter) ForceFlush(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (e *pushExporter) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&e.shutdown, 1)
+	return ctx.Err()
+}
+
+// resourceLabels returns the instance the stats of the resource are pushed for, and the labels of its series. The
+// service name and instance id of the resource are its job and instance, and the attributes of the OpenTelemetry SDK
+// aren't labels.
+func (e *pushExporter) resourceLabels(rm metricdata.ResourceMetrics) (instance string, labels []promLabel) {
+	if e.instance == "" {
+		e.instance, _ = os.Hostname()
+	}
+	instance = e.instance
+	for _, kv := range rm.Resource.Attributes() {
+		switch {
+		case kv.Key == semconv.ServiceInstanceIDKey:
+			instance = kv.Value.Emit()
+		case kv.Key == semconv.ServiceNameKey, strings.HasPrefix(string(kv.Key), "telemetry.sdk."):
+		default:
+			labels = append(labels, promLabel{name: promName(string(kv.Key), false), value: kv.Value.Emit()})
+		}
+	}
+	return instance, labels
+}
+
+// promFamilies converts the metrics to Prometheus ones, their series labeled with their attributes and the labels
+func promFamilies(rm metricdata.ResourceMetrics, labels []promLabel) []promFamily {
+	var families []promFamily
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			f := promFamily{name: promName(m.Name, true)}
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				f.typ = promSumType(data.IsMonotonic)
+				for _, dp := range data.DataPoints {
+					f.samples = append(f.samples, promSample{f.name, seriesLabels(dp.Attributes, labels), float64(dp.Value), dp.Time})
+				}
+			case metricdata.Sum[float64]:
+				f.typ = promSumType(data.IsMonotonic)
+				for _, dp := range data.DataPoints {
+					f.samples = append(f.samples, promSample{f.name, seriesLabels(dp.Attributes, labels), dp.Value, dp.Time})
+				}
+			case metricdata.Gauge[int64]:
+				f.typ = "gauge"
+				for _, dp := range data.DataPoints {
+					f.samples = append(f.samples, promSample{f.name, seriesLabels(dp.Attributes, labels), float64(dp.Value), dp.Time})
+				}
+			case metricdata.Gauge[float64]:
+				f.typ = "gauge"
+				for _, dp := range data.DataPoints {
+					f.samples = append(f.samples, promSample{f.name, seriesLabels(dp.Attributes, labels), dp.Value, dp.Time})
+				}
+			case metricdata.Histogram:
+				f.typ = "histogram"
+				for _, dp := range data.DataPoints {
+					series := seriesLabels(dp.Attributes, labels)
+					var cumulative uint64
+					for i, bound := range dp.Bounds {
+						cumulative += dp.BucketCounts[i]
+						f.samples = append(f.samples, promSample{f.name + "_bucket", withLabel(series, "le", strconv.FormatFloat(bound, 'g', -1, 64)), float64(cumulative), dp.Time})
+					}
+					f.samples = append(f.samples,
+						promSample{f.name + "_bucket", withLabel(series, "le", "+Inf"), float64(dp.Count), dp.Time},
+						promSample{f.name + "_sum", series, dp.Sum, dp.Time},
+						promSample{f.name + "_count", series, float64(dp.Count), dp.Time},
+					)
+				}
+			}
+			if len(f.samples) > 0 {
+				families = append(families, f)
+			}
+		}
+	}
+	return families
+}
+
+func promSumType(monotonic bool) string {
+	if monotonic {
+		return "counter"
+	}
+	return "gauge"
+}
+
+// seriesLabels returns the labels of the series with the attributes, sorted by name
+func seriesLabels(attrs attribute.Set, labels []promLabel) []promLabel {
+	series := make([]promLabel, 0, attrs.Len()+len(labels))
+	series = append(series, labels...)
+	for _, kv := range attrs.ToSlice() {
+		series = append(series, promLabel{name: promName(string(kv.Key), false), value: kv.Value.Emit()})
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].name < series[j].name })
+	return series
+}
+
+// withLabel returns a copy of the sorted labels with the label added
+func withLabel(labels []promLabel, name, value string) []promLabel {
+	added := append(append(make([]promLabel, 0, len(labels)+1), labels...), promLabel{name: name, value: value})
+	sort.Slice(added, func(i, j int) bool { return added[i].name < added[j].name })
+	return added
+}
+
+// promName replaces the characters not allowed in Prometheus metric names, or label names, with underscores
+func promName(name string, metric bool) string {
+	var b strings.Builder
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		b.WriteByte('_')
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':' && metric:
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// batchFamilies splits the metrics into batches of at most size series, not splitting the series of a metric since
+// pushing a metric to a pushgateway replaces all of its series
+func batchFamilies(families []promFamily, size int) [][]promFamily {
+	var (
+		batches [][]promFamily
+		batch   []promFamily
+		series  int
+	)
+	for _, f := range families {
+		if series > 0 && series+len(f.samples) > size {
+			batches = append(batches, batch)
+			batch, series = nil, 0
+		}
+		batch = append(batch, f)
+		series += len(f.samples)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// pushgatewayBody encodes the metrics in the Prometheus text format, without timestamps which pushgateways reject
+func pushgatewayBody(families []promFamily) []byte {
+	var b bytes.Buffer
+	for _, f := range families {
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.typ)
+		for _, s := range f.samples {
+			b.WriteString(s.name)
+			if len(s.labels) > 0 {
+				b.WriteByte('{')
+				for i, l := range s.labels {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					b.WriteString(l.name + `="` + escapeLabelValue(l.value) + `"`)
+				}
+				b.WriteByte('}')
+			}
+			b.WriteString(" " + formatPromValue(s.value) + "\n")
+		}
+	}
+	return b.Bytes()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatPromValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// remoteWriteBody encodes the metrics as a snappy compressed remote-write WriteRequest, their series labeled with the
+// job and instance
+func remoteWriteBody(families []promFamily, job, instance string) []byte {
+	var req []byte
+	for _, f := range families {
+		for _, s := range f.samples {
+			labels := withLabel(withLabel(withLabel(s.labels, "__name__", s.name), "job", job), "instance", instance)
+			var ts []byte
+			for _, l := range labels {
+				var label []byte
+				label = protowire.AppendTag(label, 1, protowire.BytesType)
+				label = protowire.AppendString(label, l.name)
+				label = protowire.AppendTag(label, 2, protowire.BytesType)
+				label = protowire.AppendString(label, l.value)
+				ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+				ts = protowire.AppendBytes(ts, label)
+			}
+			var sample []byte
+			sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+			sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+			sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+			sample = protowire.AppendVarint(sample, uint64(s.timestamp.UnixMilli()))
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, sample)
+			req = protowire.AppendTag(req, 1, protowire.BytesType)
+			req = protowire.AppendBytes(req, ts)
+		}
+	}
+	return snappy.Encode(nil, req)
+}
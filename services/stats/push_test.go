@@ -0,0 +1,182 @@
+package stats
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/metric"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+type pushRequest struct {
+	method, path string
+	header       http.Header
+	body         []byte
+}
+
+func newPushServer(t *testing.T, statuses ...int) (*httptest.Server, func() []pushRequest) {
+	var (
+		mu       sync.Mutex
+		requests []pushRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, pushRequest{r.Method, r.URL.Path, r.Header, body})
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []pushRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]pushRequest(nil), requests...)
+	}
+}
+
+func newTestPushStats(t *testing.T, c *config.Config) *otelStats {
+	c.Set("statsExporter", "push")
+	c.Set("INSTANCE_ID", "test")
+	c.Set("RuntimeStats.enabled", false)
+	s, ok := NewStats(c, logger.NewFactory(c), metric.NewManager()).(*otelStats)
+	require.True(t, ok, "pushed stats are aggregated like the ones exported with OTLP")
+	s.Start(context.Background())
+	return s
+}
+
+func TestPushgateway(t *testing.T) {
+	server, requests := newPushServer(t)
+	c := config.New()
+	c.Set("statsPush.url", server.URL)
+	c.Set("statsPush.interval", "1h")
+	c.Set("statsPush.batchSize", 2)
+	s := newTestPushStats(t, c)
+
+	s.NewTaggedStat("router.events", CountType, Tags{"destType": "WEBHOOK"}).Count(2)
+	s.NewTaggedStat("router.events", CountType, Tags{"destType": "KAFKA"}).Count(1)
+	s.NewStat("pending", GaugeType).Gauge(7)
+	s.Stop()
+
+	pushed := requests()
+	require.Len(t, pushed, 2, "metrics are pushed in batches, without splitting their series")
+	for _, r := range pushed {
+		require.Equal(t, http.MethodPost, r.method)
+		require.Equal(t, "/metrics/job/rudder-server/instance/test", r.path)
+	}
+	body := string(pushed[0].body) + string(pushed[1].body)
+	require.Contains(t, body, "# TYPE router_events counter\n")
+	require.Contains(t, body, `router_events{destType="WEBHOOK",instanceName="test",mode="EMBEDDED"} 2`+"\n")
+	require.Contains(t, body, `router_events{destType="KAFKA",instanceName="test",mode="EMBEDDED"} 1`+"\n")
+	require.Contains(t, body, `pending{instanceName="test",mode="EMBEDDED"} 7`+"\n")
+	require.NotContains(t, body, "telemetry_sdk")
+}
+
+func TestPushRemoteWrite(t *testing.T) {
+	server, requests := newPushServer(t, http.StatusServiceUnavailable)
+	c := config.New()
+	c.Set("statsPush.protocol", "remotewrite")
+	c.Set("statsPush.url", server.URL+"/api/v1/write")
+	c.Set("statsPush.interval", "1h")
+	s := newTestPushStats(t, c)
+
+	s.NewStat("latency", TimerType).SendTiming(time.Second)
+	s.Stop()
+
+	pushed := requests()
+	require.Len(t, pushed, 2, "failed pushes are retried")
+	require.Equal(t, pushed[0].body, pushed[1].body)
+	require.Equal(t, "/api/v1/write", pushed[1].path)
+	require.Equal(t, "snappy", pushed[1].header.Get("Content-Encoding"))
+
+	req, err := snappy.Decode(nil, pushed[1].body)
+	require.NoError(t, err)
+	var series []string
+	for len(req) > 0 {
+		_, _, n := protowire.ConsumeTag(req)
+		ts, m := protowire.ConsumeBytes(req[n:])
+		require.Greater(t, m, 0)
+		req = req[n+m:]
+		var labels []string
+		for len(ts) > 0 {
+			num, _, n := protowire.ConsumeTag(ts)
+			field, m := protowire.ConsumeBytes(ts[n:])
+			ts = ts[n+m:]
+			if num != 1 {
+				continue
+			}
+			_, _, n = protowire.ConsumeTag(field)
+			name, m := protowire.ConsumeString(field[n:])
+			_, _, k := protowire.ConsumeTag(field[n+m:])
+			value, _ := protowire.ConsumeString(field[n+m+k:])
+			labels = append(labels, name+"="+value)
+		}
+		series = append(series, strings.Join(labels, ","))
+	}
+	require.Contains(t, series, "__name__=latency_count,instance=test,instanceName=test,job=rudder-server,mode=EMBEDDED")
+	require.Contains(t, series, "__name__=latency_bucket,instance=test,instanceName=test,job=rudder-server,le=+Inf,mode=EMBEDDED")
+}
+
+func TestPushRejected(t *testing.T) {
+	server, requests := newPushServer(t, http.StatusBadRequest)
+	c := config.New()
+	c.Set("statsPush.url", server.URL)
+	conf, err := newPushConfig(c)
+	require.NoError(t, err)
+	e := newPushExporter(conf, logger.NOP)
+	require.Error(t, e.push(context.Background(), http.MethodPost, server.URL, nil, nil))
+	require.Len(t, requests(), 1, "rejected pushes aren't retried")
+}
+
+func TestPushFailedBatch(t *testing.T) {
+	server, requests := newPushServer(t, http.StatusBadRequest, http.StatusOK)
+	c := config.New()
+	c.Set("statsPush.url", server.URL)
+	c.Set("statsPush.batchSize", 1)
+	conf, err := newPushConfig(c)
+	require.NoError(t, err)
+	e := newPushExporter(conf, logger.NOP)
+
+	gauge := func(name string) metricdata.Metrics {
+		return metricdata.Metrics{Name: name, Data: metricdata.Gauge[int64]{DataPoints: []metricdata.DataPoint[int64]{{Value: 1}}}}
+	}
+	err = e.Export(context.Background(), metricdata.ResourceMetrics{
+		Resource:     resource.Empty(),
+		ScopeMetrics: []metricdata.ScopeMetrics{{Metrics: []metricdata.Metrics{gauge("first"), gauge("second")}}},
+	})
+	require.ErrorContains(t, err, "status 400")
+	require.Len(t, requests(), 2, "the batches after a failed one are pushed still")
+}
+
+func TestPushConfig(t *testing.T) {
+	c := config.New()
+	_, err := newPushConfig(c)
+	require.Error(t, err, "stats are pushed to a url")
+	c.Set("statsExporter", "push")
+	_, ok := NewStats(c, logger.NewFactory(c), metric.NewManager()).(*statsdStats)
+	require.True(t, ok, "stats fall back to statsd if pushing isn't configured properly")
+
+	c.Set("statsPush.url", "http://localhost:9091")
+	c.Set("statsPush.protocol", "udp")
+	_, err = newPushConfig(c)
+	require.Error(t, err)
+
+	require.Equal(t, "runtime_mem_heap_alloc", promName("runtime_mem.heap.alloc", true))
+	require.Equal(t, "_0abc_d", promName("0abc:d", false))
+}
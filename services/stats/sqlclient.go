@@ -0,0 +1,372 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Databases opened with OpenDB measure their usage through database/sql, instead of their queries being timed by hand:
+//   - sql_client_time, the latency of connecting, pinging, preparing and executing statements, running queries until
+//     their rows are returned, and beginning, committing and rolling back transactions, tagged with the operation
+//   - sql_client_errors, the operations failing
+//   - sql_client_in_flight, the operations in progress
+//
+// Executions of prepared statements are measured like the ones of statements executed directly, each one on its own,
+// their preparation being measured apart. Metrics are tagged with the tags of the database, e.g. the module and
+// destination type using it, and the ones of the context of the operation set by WithSQLClientTags, e.g. the table
+// it is run for. Transactions and prepared statements keep the tags of the context they're begun or prepared with,
+// so that committing a transaction is tagged like beginning it.
+
+const (
+	sqlOperationConnect  = "connect"
+	sqlOperationPing     = "ping"
+	sqlOperationExec     = "exec"
+	sqlOperationQuery    = "query"
+	sqlOperationPrepare  = "prepare"
+	sqlOperationBegin    = "begin"
+	sqlOperationCommit   = "commit"
+	sqlOperationRollback = "rollback"
+)
+
+type sqlClientTagsKey struct{}
+
+// WithSQLClientTags returns a copy of ctx tagging the metrics of the operations run with it on databases opened with
+// OpenDB with the tags, in addition to the tags of the database.
+func WithSQLClientTags(ctx context.Context, tags Tags) context.Context {
+	if parent, ok := ctx.Value(sqlClientTagsKey{}).(Tags); ok {
+		tags = mergeTags(parent, tags)
+	}
+	return context.WithValue(ctx, sqlClientTagsKey{}, tags)
+}
+
+// OpenDB opens a database like sql.Open, measuring its usage with s. Its metrics are tagged with the tags.
+func OpenDB(s Stats, driverName, dsn string, tags Tags) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	_ = db.Close()
+	var connector driver.Connector
+	if dc, ok := d.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	} else {
+		connector = &dsnConnector{dsn: dsn, driver: d}
+	}
+	return sql.OpenDB(InstrumentSQLConnector(s, connector, tags)), nil
+}
+
+// InstrumentSQLConnector returns a connector measuring the usage of the connections of the connector with s. Its
+// metrics are tagged with the tags.
+func InstrumentSQLConnector(s Stats, connector driver.Connector, tags Tags) driver.Connector {
+	return &sqlConnector{connector: connector, metrics: &sqlMetrics{stats: s, tags: tags, inFlight: new(int64)}}
+}
+
+// dsnConnector connects to the dsn with a driver not supporting connectors
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sqlMetrics measures the operations of a database
+type sqlMetrics struct {
+	stats       Stats
+	tags        Tags
+	inFlight    *int64 // shared by the metrics of the database, whatever the tags of their operations
+	contextTags Tags
+}
+
+// withContext returns the metrics of the operations run with ctx, tagged with its tags as well
+func (m *sqlMetrics) withContext(ctx context.Context) *sqlMetrics {
+	tags, ok := ctx.Value(sqlClientTagsKey{}).(Tags)
+	if !ok {
+		return m
+	}
+	withTags := *m
+	withTags.contextTags = mergeTags(m.contextTags, tags)
+	return &withTags
+}
+
+// start starts an operation, returning the function ending it
+func (m *sqlMetrics) start(operation string) (end func(err error)) {
+	m.stats.NewTaggedStat("sql_client_in_flight", GaugeType, m.tags).Gauge(atomic.AddInt64(m.inFlight, 1))
+	start := time.Now()
+	return func(err error) {
+		if errors.Is(err, driver.ErrSkip) {
+			// the operation is run another way by database/sql, and measured then
+			atomic.AddInt64(m.inFlight, -1)
+			return
+		}
+		tags := m.operationTags(operation)
+		m.stats.NewTaggedStat("sql_client_time", TimerType, tags).Since(start)
+		if err != nil {
+			m.stats.NewTaggedStat("sql_client_errors", CountType, tags).Increment()
+		}
+		m.stats.NewTaggedStat("sql_client_in_flight", GaugeType, m.tags).Gauge(atomic.AddInt64(m.inFlight, -1))
+	}
+}
+
+func (m *sqlMetrics) operationTags(operation string) Tags {
+	tags := mergeTags(m.tags, m.contextTags)
+	tags["operation"] = operation
+	return tags
+}
+
+type sqlConnector struct {
+	connector driver.Connector
+	metrics   *sqlMetrics
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	end := c.metrics.withContext(ctx).start(sqlOperationConnect)
+	conn, err := c.connector.Connect(ctx)
+	end(err)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{conn: conn, metrics: c.metrics}, nil
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// sqlConn measures the operations of a connection, supporting the optional interfaces of connections by delegating
+// to the connection if it supports them, and falling back to what database/sql would do otherwise
+type sqlConn struct {
+	conn    driver.Conn
+	metrics *sqlMetrics
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	metrics := c.metrics.withContext(ctx)
+	end := metrics.start(sqlOperationPrepare)
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if preparer, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else if stmt, err = c.conn.Prepare(query); err == nil && ctx.Err() != nil {
+		_ = stmt.Close()
+		stmt, err = nil, ctx.Err()
+	}
+	end(err)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{stmt: stmt, conn: c.conn, metrics: metrics}, nil
+}
+
+func (c *sqlConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	metrics := c.metrics.withContext(ctx)
+	end := metrics.start(sqlOperationBegin)
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if beginner, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) || opts.ReadOnly {
+		err = errors.New("sql: driver does not support non-default isolation level or read-only transactions")
+	} else {
+		tx, err = c.conn.Begin()
+	}
+	end(err)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{tx: tx, metrics: metrics}, nil
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	end := c.metrics.withContext(ctx).start(sqlOperationExec)
+	result, err := execer.ExecContext(ctx, query, args)
+	end(err)
+	return result, err
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	end := c.metrics.withContext(ctx).start(sqlOperationQuery)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	end(err)
+	return rows, err
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	pinger, ok := c.conn.(driver.Pinger)
+	if !ok {
+		return nil
+	}
+	end := c.metrics.withContext(ctx).start(sqlOperationPing)
+	err := pinger.Ping(ctx)
+	end(err)
+	return err
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// sqlStmt measures the executions of a prepared statement
+type sqlStmt struct {
+	stmt    driver.Stmt
+	conn    driver.Conn
+	metrics *sqlMetrics
+}
+
+func (s *sqlStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *sqlStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	end := s.metrics.start(sqlOperationExec)
+	result, err := s.stmt.Exec(args)
+	end(err)
+	return result, err
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	end := s.metrics.start(sqlOperationQuery)
+	rows, err := s.stmt.Query(args)
+	end(err)
+	return rows, err
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	end := s.metrics.withContext(ctx).start(sqlOperationExec)
+	var (
+		result driver.Result
+		err    error
+	)
+	if execer, ok := s.stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			if err = ctx.Err(); err == nil {
+				result, err = s.stmt.Exec(values)
+			}
+		}
+	}
+	end(err)
+	return result, err
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	end := s.metrics.withContext(ctx).start(sqlOperationQuery)
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if queryer, ok := s.stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			if err = ctx.Err(); err == nil {
+				rows, err = s.stmt.Query(values)
+			}
+		}
+	}
+	end(err)
+	return rows, err
+}
+
+// CheckNamedValue checks the values with the statement, or its connection if the statement doesn't check them, as
+// database/sql would
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	if checker, ok := s.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func namedValuesToValues(named []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(named))
+	for i, nv := range named {
+		if nv.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = nv.Value
+	}
+	return values, nil
+}
+
+// sqlTx measures committing and rolling back a transaction
+type sqlTx struct {
+	tx      driver.Tx
+	metrics *sqlMetrics
+}
+
+func (t *sqlTx) Commit() error {
+	end := t.metrics.start(sqlOperationCommit)
+	err := t.tx.Commit()
+	end(err)
+	return err
+}
+
+func (t *sqlTx) Rollback() error {
+	end := t.metrics.start(sqlOperationRollback)
+	err := t.tx.Rollback()
+	end(err)
+	return err
+}
@@ -0,0 +1,116 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// fakeDriver is a driver whose connections only support preparing statements, failing to execute the fail statement
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if s.query == "fail" {
+		return nil, errors.New("failed")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return nil }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("stats-fake", fakeDriver{})
+}
+
+// valuesByTag returns the values of the data points of the metric by the value of their tag, the number of
+// observations for histograms
+func valuesByTag(t *testing.T, m metricdata.Metrics, tag string) map[string]float64 {
+	t.Helper()
+	values := make(map[string]float64)
+	switch data := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range data.DataPoints {
+			v, _ := dp.Attributes.Value(attribute.Key(tag))
+			values[v.AsString()] += float64(dp.Value)
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range data.DataPoints {
+			v, _ := dp.Attributes.Value(attribute.Key(tag))
+			values[v.AsString()] = dp.Value
+		}
+	case metricdata.Histogram:
+		for _, dp := range data.DataPoints {
+			v, _ := dp.Attributes.Value(attribute.Key(tag))
+			values[v.AsString()] += float64(dp.Count)
+		}
+	default:
+		t.Fatalf("unexpected data %T of metric %s", m.Data, m.Name)
+	}
+	return values
+}
+
+func TestOpenDB(t *testing.T) {
+	s, reader := newTestOtelStats(t, config.New())
+	db, err := OpenDB(s, "stats-fake", "", Tags{"module": "warehouse/postgres", "destType": "POSTGRES"})
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	_, err = db.ExecContext(ctx, "insert")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "fail")
+	require.Error(t, err)
+
+	tableCtx := WithSQLClientTags(ctx, Tags{"table": "tracks"})
+	tx, err := db.BeginTx(tableCtx, nil)
+	require.NoError(t, err)
+	stmt, err := tx.PrepareContext(tableCtx, "copy")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = stmt.Exec(i)
+		require.NoError(t, err)
+	}
+	require.NoError(t, stmt.Close())
+	require.NoError(t, tx.Commit())
+
+	metrics := collectMetrics(t, reader)
+	require.Equal(t, map[string]float64{"connect": 1, "prepare": 3, "exec": 5, "begin": 1, "commit": 1}, valuesByTag(t, metrics["sql_client_time"], "operation"),
+		"statements executed without ExecContext are prepared, then measured every time they're executed")
+	require.Equal(t, map[string]float64{"exec": 1}, valuesByTag(t, metrics["sql_client_errors"], "operation"))
+	require.Equal(t, map[string]float64{"": 5, "tracks": 6}, valuesByTag(t, metrics["sql_client_time"], "table"),
+		"transactions and statements are tagged like the context they're begun or prepared with")
+	require.Equal(t, map[string]float64{"POSTGRES": 0}, valuesByTag(t, metrics["sql_client_in_flight"], "destType"))
+}
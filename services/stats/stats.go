@@ -11,6 +11,9 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
 	"gopkg.in/alexcesaro/statsd.v2"
 
 	"github.com/rudderlabs/rudder-server/config"
@@ -79,26 +82,83 @@ func (t Tags) String() string {
 	return strings.Join(t.Strings(), ",")
 }
 
-// NewStats create a new Stats instance using the provided config, logger factory and metric manager as dependencies
+// mergeTags returns a new map with the tags of every map, the ones of later maps taking precedence
+func mergeTags(tags ...Tags) Tags {
+	var n int
+	for _, t := range tags {
+		n += len(t)
+	}
+	merged := make(Tags, n)
+	for _, t := range tags {
+		for key, value := range t {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// NewStats create a new Stats instance using the provided config, logger factory and metric manager as dependencies.
+// Stats are sent to statsd, unless statsExporter is otlp or push.
 func NewStats(config *config.Config, loggerFactory *logger.Factory, metricManager metric.Manager) Stats {
-	s := &statsdStats{
-		log: loggerFactory.NewLogger().Child("stats"),
-		conf: &statsdConfig{
-			enabled:         config.GetBool("enableStats", true),
-			tagsFormat:      config.GetString("statsTagsFormat", "influxdb"),
-			excludedTags:    config.GetStringSlice("statsExcludedTags", nil),
-			statsdServerURL: config.GetString("STATSD_SERVER_URL", "localhost:8125"),
-			instanceID:      config.GetString("INSTANCE_ID", ""),
-			samplingRate:    float32(config.GetFloat64("statsSamplingRate", 1)),
-			periodic: periodicStatsConfig{
-				enabled:                 config.GetBool("RuntimeStats.enabled", true),
-				statsCollectionInterval: config.GetInt64("RuntimeStats.statsCollectionInterval", 10),
-				enableCPUStats:          config.GetBool("RuntimeStats.enableCPUStats", true),
-				enableMemStats:          config.GetBool("RuntimeStats.enabledMemStats", true),
-				enableGCStats:           config.GetBool("RuntimeStats.enableGCStats", true),
-				metricManager:           metricManager,
-			},
+	conf := &statsdConfig{
+		enabled:         config.GetBool("enableStats", true),
+		tagsFormat:      config.GetString("statsTagsFormat", "influxdb"),
+		excludedTags:    config.GetStringSlice("statsExcludedTags", nil),
+		statsdServerURL: config.GetString("STATSD_SERVER_URL", "localhost:8125"),
+		instanceID:      config.GetString("INSTANCE_ID", ""),
+		samplingRate:    float32(config.GetFloat64("statsSamplingRate", 1)),
+		periodic: periodicStatsConfig{
+			enabled:                 config.GetBool("RuntimeStats.enabled", true),
+			statsCollectionInterval: config.GetInt64("RuntimeStats.statsCollectionInterval", 10),
+			enableCPUStats:          config.GetBool("RuntimeStats.enableCPUStats", true),
+			enableMemStats:          config.GetBool("RuntimeStats.enabledMemStats", true),
+			enableGCStats:           config.GetBool("RuntimeStats.enableGCStats", true),
+			enableSelfStats:         config.GetBool("RuntimeStats.enableSelfStats", true),
+			metricManager:           metricManager,
 		},
+	}
+	log := loggerFactory.NewLogger().Child("stats")
+	cardinality := newCardinalityLimiter(config)
+	exemplars, err := newExemplarStore(config)
+	if err != nil {
+		log.Errorf("Not keeping exemplars since they aren't configured properly: %v", err)
+	}
+	toggles, err := newMetricToggles(config)
+	if err != nil {
+		log.Errorf("Not toggling metrics since their toggles aren't configured properly: %v", err)
+	}
+	if exporter := strings.ToLower(config.GetString("statsExporter", statsExporterStatsd)); exporter == statsExporterOTLP || exporter == statsExporterPush {
+		otelConf, err := newOtelConfig(config)
+		var pushConf *pushConfig
+		if err == nil && exporter == statsExporterPush {
+			pushConf, err = newPushConfig(config)
+		}
+		if err == nil {
+			return &otelStats{
+				log:         log,
+				conf:        conf,
+				otelConf:    otelConf,
+				pushConf:    pushConf,
+				appType:     strings.ToUpper(config.GetString("APP_TYPE", "EMBEDDED")),
+				cardinality: cardinality,
+				exemplars:   exemplars,
+				toggles:     toggles,
+				meter:       otelmetric.NewNoopMeter(),
+				counters:    make(map[string]syncint64.Counter),
+				histograms:  make(map[string]syncfloat64.Histogram),
+				gauges:      make(map[string]*otelGaugeValues),
+			}
+		}
+		log.Errorf("Falling back to statsd since %s metrics aren't configured properly: %v", exporter, err)
+	} else if exporter != statsExporterStatsd {
+		log.Errorf("Falling back to statsd since stats exporter %q isn't supported", exporter)
+	}
+	s := &statsdStats{
+		log:         log,
+		conf:        conf,
+		cardinality: cardinality,
+		exemplars:   exemplars,
+		toggles:     toggles,
 		state: &statsdState{
 			client:         &statsdClient{},
 			clients:        make(map[string]*statsdClient),
@@ -110,9 +170,37 @@ func NewStats(config *config.Config, loggerFactory *logger.Factory, metricManage
 
 // statsdStats is the statsd-specific implementation of Stats
 type statsdStats struct {
-	log   logger.Logger
-	conf  *statsdConfig
-	state *statsdState
+	log         logger.Logger
+	conf        *statsdConfig
+	cardinality *cardinalityLimiter
+	exemplars   *exemplarStore
+	toggles     *metricToggles
+	state       *statsdState
+}
+
+// Cardinality returns the cardinality of every metric
+func (s *statsdStats) Cardinality() map[string]MetricCardinality {
+	return s.cardinality.Cardinality()
+}
+
+// Exemplars returns the exemplars of the series of the metrics
+func (s *statsdStats) Exemplars() []HistogramExemplars {
+	return s.exemplars.Exemplars()
+}
+
+// Toggles returns the toggles of the metrics
+func (s *statsdStats) Toggles() []MetricToggle {
+	return s.toggles.Toggles()
+}
+
+// SetToggle sets the toggle of the series of its metric with its tags
+func (s *statsdStats) SetToggle(toggle MetricToggle) error {
+	return s.toggles.SetToggle(toggle)
+}
+
+// RemoveToggle removes the toggle of the series of the metric with the tags
+func (s *statsdStats) RemoveToggle(metric string, tags Tags) error {
+	return s.toggles.RemoveToggle(metric, tags)
 }
 
 func (s *statsdStats) Start(ctx context.Context) {
@@ -183,6 +271,7 @@ func (s *statsdStats) collectPeriodicStats() {
 	s.state.rc.EnableGC = s.conf.periodic.enableGCStats
 
 	s.state.mc = newMetricStatsCollector(s, s.conf.periodic.metricManager)
+	s.state.sc = newSelfStatsCollector(s, s.state.rc.PauseDur)
 	if s.conf.periodic.enabled {
 		var wg sync.WaitGroup
 		wg.Add(2)
@@ -194,6 +283,13 @@ func (s *statsdStats) collectPeriodicStats() {
 			defer wg.Done()
 			s.state.mc.run()
 		}()
+		if s.conf.periodic.enableSelfStats {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.state.sc.run()
+			}()
+		}
 		wg.Wait()
 	}
 }
@@ -211,11 +307,14 @@ func (s *statsdStats) Stop() {
 	if s.state.mc.done != nil {
 		close(s.state.mc.done)
 	}
+	if s.state.sc != nil {
+		close(s.state.sc.done)
+	}
 }
 
 // NewStat creates a new Measurement with provided Name and Type
 func (s *statsdStats) NewStat(name, statType string) (m Measurement) {
-	return newStatsdMeasurement(s.conf, name, statType, s.state.client)
+	return newStatsdMeasurement(s, name, statType, nil, s.state.client)
 }
 
 func (s *statsdStats) NewTaggedStat(Name, StatType string, tags Tags) (m Measurement) {
@@ -229,7 +328,7 @@ func (s *statsdStats) NewSampledTaggedStat(Name, StatType string, tags Tags) (m
 func (s *statsdStats) internalNewTaggedStat(name, statType string, tags Tags, samplingRate float32) (m Measurement) {
 	// If stats is not enabled, returning a dummy struct
 	if !s.conf.enabled {
-		return newStatsdMeasurement(s.conf, name, statType, &statsdClient{})
+		return newStatsdMeasurement(s, name, statType, nil, &statsdClient{})
 	}
 
 	// Clean up tags based on deployment type. No need to send workspace id tag for free tier customers.
@@ -239,6 +338,7 @@ func (s *statsdStats) internalNewTaggedStat(name, statType string, tags Tags, sa
 	if tags == nil {
 		tags = make(Tags)
 	}
+	tags = s.cardinality.limit(name, tags)
 	// key comprises of the measurement type plus all tag-value pairs
 	taggedClientKey := tags.String() + fmt.Sprintf("%f", samplingRate)
 
@@ -262,5 +362,5 @@ func (s *statsdStats) internalNewTaggedStat(name, statType string, tags Tags, sa
 		s.state.clientsLock.Unlock()
 	}
 
-	return newStatsdMeasurement(s.conf, name, statType, taggedClient)
+	return newStatsdMeasurement(s, name, statType, tags, taggedClient)
 }
@@ -241,6 +241,7 @@ func Test_Periodic_stats(t *testing.T) {
 		c.Set("INSTANCE_ID", "test")
 		c.Set("RuntimeStats.enabled", true)
 		c.Set("RuntimeStats.statsCollectionInterval", 60)
+		c.Set("RuntimeStats.enableSelfStats", false)
 		prepareFunc(c, m)
 
 		l := logger.NewFactory(c)
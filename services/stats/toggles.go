@@ -0,0 +1,289 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// Metrics, or some of their series, can be switched off and on at runtime, e.g. to only record debugging metrics with
+// heavy tags while investigating an issue, without a redeploy. Toggles select the series of a metric having all of
+// their tags, e.g.
+//
+//	statsToggles:
+//	  disabled: ["router_delivery_payload_size"]
+//	  enabled: ["router_delivery_payload_size{destType=WEBHOOK}"]
+//
+// The most specific toggle selecting a series, the one with the most tags, decides whether its measurements are
+// recorded or dropped, series without toggles being recorded. Toggles are stored by the config service: they are
+// reloaded along with the config, and the ones set and removed at runtime through the /stats/toggles admin endpoint
+// are set in the config, replacing the toggles with the same metric and tags.
+
+// MetricToggle switches the series of a metric having all of its tags on or off
+type MetricToggle struct {
+	Metric  string `json:"metric"`
+	Tags    Tags   `json:"tags,omitempty"`
+	Enabled bool   `json:"enabled"`
+}
+
+// selects reports whether the toggle selects the series of the metric with the tags
+func (t MetricToggle) selects(tags Tags) bool {
+	for key, value := range t.Tags {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// key identifies the series selected by the toggle
+func (t MetricToggle) key() string {
+	return t.Metric + "{" + t.Tags.String() + "}"
+}
+
+// selector returns the selector of the series of the toggle, as parsed by parseMetricSelector
+func (t MetricToggle) selector() string {
+	if len(t.Tags) == 0 {
+		return t.Metric
+	}
+	tags := make([]string, 0, len(t.Tags))
+	for key, value := range t.Tags {
+		tags = append(tags, key+"="+value)
+	}
+	sort.Strings(tags)
+	return t.Metric + "{" + strings.Join(tags, ",") + "}"
+}
+
+// MetricToggler switches metrics of Stats off and on at runtime
+type MetricToggler interface {
+	Toggles() []MetricToggle
+	SetToggle(toggle MetricToggle) error
+	RemoveToggle(metric string, tags Tags) error
+}
+
+const (
+	disabledTogglesKey = "statsToggles.disabled"
+	enabledTogglesKey  = "statsToggles.enabled"
+)
+
+// metricToggles are the toggles of metrics, stored by the config, a nil one recording every series
+type metricToggles struct {
+	config *config.Config
+	// disabledSelectors and enabledSelectors are the selectors of the toggles, reloaded by the config
+	disabledSelectors, enabledSelectors []string
+
+	mu              sync.RWMutex
+	indexedDisabled []string // selectors of byMetric, to find out whether they're reloaded
+	indexedEnabled  []string
+	byMetric        map[string][]MetricToggle
+}
+
+func newMetricToggles(config *config.Config) (*metricToggles, error) {
+	t := &metricToggles{config: config}
+	config.RegisterStringSliceConfigVariable(nil, &t.disabledSelectors, true, disabledTogglesKey)
+	config.RegisterStringSliceConfigVariable(nil, &t.enabledSelectors, true, enabledTogglesKey)
+	for _, selector := range append(append([]string{}, t.disabledSelectors...), t.enabledSelectors...) {
+		if _, err := parseMetricSelector(selector); err != nil {
+			return nil, err
+		}
+	}
+	t.mu.Lock()
+	t.index()
+	t.mu.Unlock()
+	return t, nil
+}
+
+// parseMetricSelector parses the selector of the series of a metric, its name optionally followed by the tags of its
+// series, e.g. router_delivery_payload_size{destType=WEBHOOK,destID=xxx}
+func parseMetricSelector(selector string) (MetricToggle, error) {
+	selector = strings.TrimSpace(selector)
+	name, tags, hasTags := strings.Cut(selector, "{")
+	toggle := MetricToggle{Metric: strings.TrimSpace(name)}
+	if toggle.Metric == "" {
+		return toggle, fmt.Errorf("invalid metric selector %q: no metric", selector)
+	}
+	if !hasTags {
+		return toggle, nil
+	}
+	if !strings.HasSuffix(tags, "}") {
+		return toggle, fmt.Errorf("invalid metric selector %q: unterminated tags", selector)
+	}
+	toggle.Tags = make(Tags)
+	for _, tag := range strings.Split(strings.TrimSuffix(tags, "}"), ",") {
+		key, value, ok := strings.Cut(tag, "=")
+		if key = strings.TrimSpace(key); !ok || key == "" {
+			return toggle, fmt.Errorf("invalid metric selector %q: invalid tag %q", selector, tag)
+		}
+		toggle.Tags[key] = strings.TrimSpace(value)
+	}
+	return toggle, nil
+}
+
+// index groups the toggles of the selectors by metric, the enabled ones replacing the disabled ones with the same
+// metric and tags, and invalid selectors being skipped. It's called with mu locked.
+func (t *metricToggles) index() {
+	t.indexedDisabled, t.indexedEnabled = t.disabledSelectors, t.enabledSelectors
+	toggles := make(map[string]MetricToggle)
+	for enabled, selectors := range map[bool][]string{false: t.indexedDisabled, true: t.indexedEnabled} {
+		for _, selector := range selectors {
+			toggle, err := parseMetricSelector(selector)
+			if err != nil {
+				continue
+			}
+			toggle.Enabled = enabled
+			if existing, ok := toggles[toggle.key()]; !ok || !existing.Enabled {
+				toggles[toggle.key()] = toggle
+			}
+		}
+	}
+	t.byMetric = make(map[string][]MetricToggle)
+	for _, toggle := range toggles {
+		t.byMetric[toggle.Metric] = append(t.byMetric[toggle.Metric], toggle)
+	}
+}
+
+// metricToggles returns the toggles of the metric, indexing them again if the config reloaded them
+func (t *metricToggles) metricToggles(name string) []MetricToggle {
+	t.mu.RLock()
+	reloaded := !slices.Equal(t.indexedDisabled, t.disabledSelectors) || !slices.Equal(t.indexedEnabled, t.enabledSelectors)
+	toggles := t.byMetric[name]
+	t.mu.RUnlock()
+	if !reloaded {
+		return toggles
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.index()
+	return t.byMetric[name]
+}
+
+// enabled reports whether the measurements of the series of the metric with the tags are recorded
+func (t *metricToggles) enabled(name string, tags Tags) bool {
+	if t == nil {
+		return true
+	}
+	enabled, specificity := true, -1
+	for _, toggle := range t.metricToggles(name) {
+		if len(toggle.Tags) > specificity && toggle.selects(tags) {
+			enabled, specificity = toggle.Enabled, len(toggle.Tags)
+		}
+	}
+	return enabled
+}
+
+// Toggles returns the toggles of the metrics, sorted by metric and tags
+func (t *metricToggles) Toggles() []MetricToggle {
+	if t == nil {
+		return nil
+	}
+	t.metricToggles("")
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var toggles []MetricToggle
+	for _, metricToggles := range t.byMetric {
+		toggles = append(toggles, metricToggles...)
+	}
+	sort.Slice(toggles, func(i, j int) bool { return toggles[i].key() < toggles[j].key() })
+	return toggles
+}
+
+// SetToggle sets the toggle of the series of its metric with its tags in the config
+func (t *metricToggles) SetToggle(toggle MetricToggle) error {
+	if t == nil {
+		return fmt.Errorf("stats don't support toggles")
+	}
+	if toggle.Metric == "" {
+		return fmt.Errorf("toggle has no metric")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	disabled, enabled := t.without(toggle)
+	if toggle.Enabled {
+		enabled = append(enabled, toggle.selector())
+	} else {
+		disabled = append(disabled, toggle.selector())
+	}
+	t.store(disabled, enabled)
+	return nil
+}
+
+// RemoveToggle removes the toggle of the series of the metric with the tags from the config
+func (t *metricToggles) RemoveToggle(metric string, tags Tags) error {
+	if t == nil {
+		return fmt.Errorf("stats don't support toggles")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.store(t.without(MetricToggle{Metric: metric, Tags: tags}))
+	return nil
+}
+
+// without returns the selectors of the config but the ones of the series of the toggle. It's called with mu locked.
+func (t *metricToggles) without(toggle MetricToggle) (disabled, enabled []string) {
+	filter := func(selectors []string) []string {
+		filtered := make([]string, 0, len(selectors))
+		for _, selector := range selectors {
+			if parsed, err := parseMetricSelector(selector); err == nil && parsed.key() == toggle.key() {
+				continue
+			}
+			filtered = append(filtered, selector)
+		}
+		return filtered
+	}
+	return filter(t.disabledSelectors), filter(t.enabledSelectors)
+}
+
+// store sets the selectors in the config, which reloads them, and indexes them. It's called with mu locked.
+func (t *metricToggles) store(disabled, enabled []string) {
+	t.config.Set(disabledTogglesKey, disabled)
+	t.config.Set(enabledTogglesKey, enabled)
+	t.index()
+}
+
+// TogglesHandler lists the toggles of the metrics of s on GET, sets the toggle in the body on PUT and removes the one
+// with the metric and tags in the body on DELETE
+func TogglesHandler(s Stats) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		toggler, ok := s.(MetricToggler)
+		if !ok {
+			http.Error(w, "stats don't support toggles", http.StatusNotImplemented)
+			return
+		}
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodDelete:
+			var toggle MetricToggle
+			if err := json.NewDecoder(r.Body).Decode(&toggle); err != nil || toggle.Metric == "" {
+				http.Error(w, "body must be a toggle with a metric", http.StatusBadRequest)
+				return
+			}
+			if r.Method == http.MethodPut {
+				err = toggler.SetToggle(toggle)
+			} else {
+				err = toggler.RemoveToggle(toggle.Metric, toggle.Tags)
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		toggles := toggler.Toggles()
+		if toggles == nil {
+			toggles = []MetricToggle{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(toggles)
+	})
+}
@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+func TestParseMetricSelector(t *testing.T) {
+	toggle, err := parseMetricSelector("router_delivery_payload_size")
+	require.NoError(t, err)
+	require.Equal(t, MetricToggle{Metric: "router_delivery_payload_size"}, toggle)
+
+	toggle, err = parseMetricSelector(" router_delivery_payload_size{destType=WEBHOOK, destID = xxx} ")
+	require.NoError(t, err)
+	require.Equal(t, MetricToggle{Metric: "router_delivery_payload_size", Tags: Tags{"destType": "WEBHOOK", "destID": "xxx"}}, toggle)
+
+	for _, selector := range []string{"", "{destType=WEBHOOK}", "metric{destType=WEBHOOK", "metric{destType}", "metric{=WEBHOOK}"} {
+		_, err := parseMetricSelector(selector)
+		require.Error(t, err, selector)
+	}
+}
+
+func TestMetricToggles(t *testing.T) {
+	c := config.New()
+	c.Set("statsToggles.disabled", []string{"payload_size"})
+	c.Set("statsToggles.enabled", []string{"payload_size{destType=WEBHOOK}"})
+	toggles, err := newMetricToggles(c)
+	require.NoError(t, err)
+
+	require.False(t, toggles.enabled("payload_size", Tags{"destType": "KAFKA"}))
+	require.True(t, toggles.enabled("payload_size", Tags{"destType": "WEBHOOK", "destID": "a"}), "the most specific toggle wins")
+	require.True(t, toggles.enabled("delivery_time", Tags{"destType": "KAFKA"}), "series without toggles are recorded")
+
+	require.NoError(t, toggles.SetToggle(MetricToggle{Metric: "payload_size", Tags: Tags{"destType": "WEBHOOK", "destID": "a"}, Enabled: false}))
+	require.NoError(t, toggles.SetToggle(MetricToggle{Metric: "payload_size", Enabled: true}))
+	require.False(t, toggles.enabled("payload_size", Tags{"destType": "WEBHOOK", "destID": "a"}))
+	require.True(t, toggles.enabled("payload_size", Tags{"destType": "WEBHOOK", "destID": "b"}))
+	require.True(t, toggles.enabled("payload_size", Tags{"destType": "KAFKA"}), "toggles set at runtime replace configured ones")
+	require.Equal(t, []string{"payload_size{destID=a,destType=WEBHOOK}"}, c.GetStringSlice("statsToggles.disabled", nil), "toggles are set in the config")
+	require.Equal(t, []string{"payload_size{destType=WEBHOOK}", "payload_size"}, c.GetStringSlice("statsToggles.enabled", nil))
+
+	reloaded, err := newMetricToggles(c)
+	require.NoError(t, err)
+	require.Equal(t, toggles.Toggles(), reloaded.Toggles(), "toggles are read from the config")
+
+	require.NoError(t, reloaded.RemoveToggle("payload_size", nil))
+	require.True(t, reloaded.enabled("payload_size", Tags{"destType": "KAFKA"}))
+	require.Equal(t, []MetricToggle{
+		{Metric: "payload_size", Tags: Tags{"destID": "a", "destType": "WEBHOOK"}, Enabled: false},
+		{Metric: "payload_size", Tags: Tags{"destType": "WEBHOOK"}, Enabled: true},
+	}, reloaded.Toggles())
+
+	c.Set("statsToggles.disabled", []string{"delivery_time"})
+	require.False(t, reloaded.enabled("delivery_time", Tags{"destType": "KAFKA"}), "toggles are reloaded along with the config")
+	require.True(t, reloaded.enabled("payload_size", Tags{"destType": "WEBHOOK", "destID": "a"}))
+
+	var nilToggles *metricToggles
+	require.True(t, nilToggles.enabled("payload_size", nil))
+	require.Error(t, nilToggles.SetToggle(MetricToggle{Metric: "payload_size"}))
+}
+
+func TestTogglesHandler(t *testing.T) {
+	c := config.New()
+	s, reader := newTestOtelStats(t, c)
+	m := s.NewTaggedStat("events", CountType, Tags{"destType": "WEBHOOK"})
+
+	serve := func(method string, toggle *MetricToggle) (int, []MetricToggle) {
+		var body bytes.Buffer
+		if toggle != nil {
+			require.NoError(t, json.NewEncoder(&body).Encode(toggle))
+		}
+		rec := httptest.NewRecorder()
+		TogglesHandler(s).ServeHTTP(rec, httptest.NewRequest(method, "/stats/toggles", &body))
+		var toggles []MetricToggle
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &toggles))
+		}
+		return rec.Code, toggles
+	}
+
+	code, toggles := serve(http.MethodPut, &MetricToggle{Metric: "events", Enabled: false})
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []MetricToggle{{Metric: "events"}}, toggles)
+	m.Count(1)
+
+	code, _ = serve(http.MethodDelete, &MetricToggle{Metric: "events"})
+	require.Equal(t, http.StatusOK, code)
+	m.Count(2)
+
+	values := collectMetrics(t, reader)["events"].Data.(metricdata.Sum[int64]).DataPoints
+	require.Len(t, values, 1)
+	require.EqualValues(t, 2, values[0].Value, "measurements of series switched off are dropped")
+
+	code, toggles = serve(http.MethodGet, nil)
+	require.Equal(t, http.StatusOK, code)
+	require.Empty(t, toggles)
+
+	code, _ = serve(http.MethodPut, &MetricToggle{})
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(http.MethodPost, nil)
+	require.Equal(t, http.StatusMethodNotAllowed, code)
+}
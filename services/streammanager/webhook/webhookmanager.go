@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/mitchellh/mapstructure"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/services/streammanager/common"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+// The templated webhook destination POSTs the events, untransformed by the transformer, to an https endpoint. Its
+// destination definition declares "transformAtV1": "none" for processor to leave the events as they are. The body of
+// the requests is the event itself, or the result of the payload template of the destination, a Go text/template
+// executed with the event as its data, e.g.
+//
+//	{"name": {{json .event}}, "user": {{json .userId}}, "plan": {{json (get . "context.traits.plan")}}}
+//
+// Requests are authenticated with an api key header or with OAuth2 client credentials. The status code of the endpoint is
+// returned as is, so that the router retries the events failing with 5xx or 429 status codes and aborts the others.
+
+const (
+	authTypeNone   = "none"
+	authTypeAPIKey = "apiKey"
+	authTypeOAuth2 = "oauth2"
+
+	maxResponseLength = 1000
+)
+
+type header struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// destinationConfig is the config that is required to send events to the webhook
+type destinationConfig struct {
+	URL             string   `json:"url"`
+	Method          string   `json:"method"`
+	Headers         []header `json:"headers"`
+	PayloadTemplate string   `json:"payloadTemplate"`
+
+	AuthType     string   `json:"authType"`
+	APIKeyHeader string   `json:"apiKeyHeader"`
+	APIKey       string   `json:"apiKey"`
+	TokenURL     string   `json:"tokenURL"`
+	ClientID     string   `json:"clientID"`
+	ClientSecret string   `json:"clientSecret"`
+	Scopes       []string `json:"scopes"`
+}
+
+type WebhookProducer struct {
+	client   *http.Client
+	config   destinationConfig
+	template *template.Template
+}
+
+var (
+	pkgLogger logger.Logger
+	jsonfast  = jsoniter.ConfigCompatibleWithStandardLibrary
+)
+
+func init() {
+	pkgLogger = logger.NewLogger().Child("streammanager").Child("webhook")
+}
+
+// NewProducer creates a producer based on destination config
+func NewProducer(destination *backendconfig.DestinationT, o common.Opts) (*WebhookProducer, error) {
+	return newProducer(destination, &http.Client{Timeout: o.Timeout})
+}
+
+func newProducer(destination *backendconfig.DestinationT, baseClient *http.Client) (*WebhookProducer, error) {
+	var config destinationConfig
+	if err := mapstructure.Decode(destination.Config, &config); err != nil {
+		return nil, fmt.Errorf("[Webhook] error while decoding destination config: %w", err)
+	}
+	endpoint, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("[Webhook] invalid url: %w", err)
+	}
+	if endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, fmt.Errorf("[Webhook] invalid url %q: only https urls are supported", config.URL)
+	}
+	if config.Method == "" {
+		config.Method = http.MethodPost
+	}
+
+	producer := &WebhookProducer{config: config, client: baseClient}
+	if config.PayloadTemplate != "" {
+		producer.template, err = template.New(destination.ID).Funcs(templateFuncs).Option("missingkey=zero").Parse(config.PayloadTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("[Webhook] invalid payload template: %w", err)
+		}
+	}
+
+	switch config.AuthType {
+	case "", authTypeNone:
+	case authTypeAPIKey:
+		if config.APIKey == "" {
+			return nil, errors.New("[Webhook] api key is required")
+		}
+		if producer.config.APIKeyHeader == "" {
+			producer.config.APIKeyHeader = "Authorization"
+		}
+	case authTypeOAuth2:
+		if config.TokenURL == "" || config.ClientID == "" {
+			return nil, errors.New("[Webhook] token url and client id are required for oauth2")
+		}
+		credentials := clientcredentials.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			TokenURL:     config.TokenURL,
+			Scopes:       config.Scopes,
+		}
+		// the token is fetched with the base client and refreshed when it expires
+		client := credentials.Client(context.WithValue(context.Background(), oauth2.HTTPClient, baseClient))
+		client.Timeout = baseClient.Timeout
+		producer.client = client
+	default:
+		return nil, fmt.Errorf("[Webhook] unsupported auth type %q", config.AuthType)
+	}
+	return producer, nil
+}
+
+var templateFuncs = template.FuncMap{
+	// json returns the JSON encoding of a value, e.g. {{json .userId}}
+	"json": func(v interface{}) (string, error) {
+		b, err := jsonfast.Marshal(v)
+		return string(b), err
+	},
+	// get returns the value at the given dotted path of an object, or nil, e.g. {{get . "context.traits.email"}}
+	"get": func(v interface{}, path string) interface{} {
+		for _, key := range strings.Split(path, ".") {
+			object, ok := v.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			v = object[key]
+		}
+		return v
+	},
+	// default returns the value, or the default value if the value is nil or empty, e.g. {{default "USD" .properties.currency}}
+	"default": func(defaultValue, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return defaultValue
+		}
+		return v
+	},
+}
+
+// payload returns the body of the request for the event
+func (producer *WebhookProducer) payload(jsonData json.RawMessage) ([]byte, error) {
+	if producer.template == nil {
+		return jsonData, nil
+	}
+	var event interface{}
+	if err := jsonfast.Unmarshal(jsonData, &event); err != nil {
+		return nil, fmt.Errorf("unmarshalling event: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := producer.template.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("executing payload template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("payload template produced invalid JSON: %s", truncate(buf.String()))
+	}
+	return buf.Bytes(), nil
+}
+
+// Produce sends the event to the webhook
+func (producer *WebhookProducer) Produce(jsonData json.RawMessage, _ interface{}) (int, string, string) {
+	if producer.client == nil {
+		return 400, "Failure", "[Webhook] error :: Could not create client"
+	}
+	body, err := producer.payload(jsonData)
+	if err != nil {
+		return 400, "Failure", "[Webhook] error :: " + err.Error()
+	}
+
+	config := producer.config
+	req, err := http.NewRequest(config.Method, config.URL, bytes.NewReader(body))
+	if err != nil {
+		return 400, "Failure", "[Webhook] error while creating request :: " + err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "RudderLabs")
+	for _, h := range config.Headers {
+		if h.Key != "" {
+			req.Header.Set(h.Key, h.Value)
+		}
+	}
+	if config.AuthType == authTypeAPIKey {
+		req.Header.Set(config.APIKeyHeader, config.APIKey)
+	}
+
+	resp, err := producer.client.Do(req)
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.Response != nil && retrieveErr.Response.StatusCode < 500 {
+			// the client credentials are rejected, retrying won't help
+			return 400, "Failure", "[Webhook] error while fetching oauth2 token :: " + err.Error()
+		}
+		pkgLogger.Errorf("[Webhook] error while sending event to %s :: %v", config.URL, err)
+		return 500, "Failure", "[Webhook] error while sending event :: " + err.Error()
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseLength))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, "Failure", fmt.Sprintf("[Webhook] %s responded with %d :: %s", config.URL, resp.StatusCode, respBody)
+	}
+	return resp.StatusCode, "Success", "Event delivered to Webhook :: " + string(respBody)
+}
+
+func (*WebhookProducer) Close() error {
+	// no-op
+	return nil
+}
+
+func truncate(s string) string {
+	if len(s) > maxResponseLength {
+		return s[:maxResponseLength]
+	}
+	return s
+}
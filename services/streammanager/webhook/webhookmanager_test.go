@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/services/streammanager/common"
+)
+
+var sampleEvent = json.RawMessage(`{"type":"track","event":"Order Completed","userId":"user","context":{"traits":{"plan":"pro"}},"properties":{"total":9.99}}`)
+
+func TestNewProducer(t *testing.T) {
+	newProducer := func(config map[string]interface{}) (*WebhookProducer, error) {
+		return NewProducer(&backendconfig.DestinationT{ID: "dest", Config: config}, common.Opts{Timeout: time.Second})
+	}
+
+	producer, err := newProducer(map[string]interface{}{"url": "https://example.com/hook"})
+	require.NoError(t, err)
+	require.Equal(t, http.MethodPost, producer.config.Method)
+	require.Equal(t, time.Second, producer.client.Timeout)
+
+	_, err = newProducer(map[string]interface{}{"url": "http://example.com/hook"})
+	require.Error(t, err, "only https urls are supported")
+	_, err = newProducer(map[string]interface{}{"url": "https://example.com/hook", "payloadTemplate": "{{.event"})
+	require.Error(t, err, "invalid payload template")
+	_, err = newProducer(map[string]interface{}{"url": "https://example.com/hook", "authType": "apiKey"})
+	require.Error(t, err, "missing api key")
+	_, err = newProducer(map[string]interface{}{"url": "https://example.com/hook", "authType": "basic"})
+	require.Error(t, err, "unsupported auth type")
+}
+
+func TestProduce(t *testing.T) {
+	var (
+		statusCode = http.StatusOK
+		requests   []*http.Request
+		bodies     []string
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+			username, password, _ := r.BasicAuth()
+			if username != "client" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"token","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte("response"))
+	}))
+	defer srv.Close()
+	newProducer := func(t *testing.T, config map[string]interface{}) *WebhookProducer {
+		config["url"] = srv.URL + "/hook"
+		producer, err := newProducer(&backendconfig.DestinationT{ID: "dest", Config: config}, srv.Client())
+		require.NoError(t, err)
+		return producer
+	}
+
+	t.Run("event as is", func(t *testing.T) {
+		producer := newProducer(t, map[string]interface{}{
+			"method":  "PUT",
+			"headers": []interface{}{map[string]interface{}{"key": "X-Source", "value": "rudder"}},
+		})
+		code, status, _ := producer.Produce(sampleEvent, nil)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "Success", status)
+		req := requests[len(requests)-1]
+		require.Equal(t, http.MethodPut, req.Method)
+		require.Equal(t, "rudder", req.Header.Get("X-Source"))
+		require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		require.JSONEq(t, string(sampleEvent), bodies[len(bodies)-1])
+	})
+
+	t.Run("payload template", func(t *testing.T) {
+		producer := newProducer(t, map[string]interface{}{
+			"payloadTemplate": `{"name": {{json .event}}, "user": {{json .userId}}, "plan": {{json (get . "context.traits.plan")}}, "currency": {{json (.properties.currency | default "USD")}}}`,
+			"authType":        "apiKey",
+			"apiKeyHeader":    "X-Api-Key",
+			"apiKey":          "key",
+		})
+		code, _, _ := producer.Produce(sampleEvent, nil)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "key", requests[len(requests)-1].Header.Get("X-Api-Key"))
+		require.JSONEq(t, `{"name":"Order Completed","user":"user","plan":"pro","currency":"USD"}`, bodies[len(bodies)-1])
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		producer := newProducer(t, map[string]interface{}{"payloadTemplate": `{"name": {{.event}}}`})
+		count := len(requests)
+		code, status, _ := producer.Produce(sampleEvent, nil)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Equal(t, "Failure", status)
+		require.Len(t, requests, count, "invalid payloads are not sent")
+	})
+
+	t.Run("oauth2", func(t *testing.T) {
+		producer := newProducer(t, map[string]interface{}{
+			"authType":     "oauth2",
+			"tokenURL":     srv.URL + "/token",
+			"clientID":     "client",
+			"clientSecret": "secret",
+		})
+		code, _, _ := producer.Produce(sampleEvent, nil)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "Bearer token", requests[len(requests)-1].Header.Get("Authorization"))
+
+		producer = newProducer(t, map[string]interface{}{
+			"authType":     "oauth2",
+			"tokenURL":     srv.URL + "/token",
+			"clientID":     "client",
+			"clientSecret": "wrong",
+		})
+		code, _, _ = producer.Produce(sampleEvent, nil)
+		require.Equal(t, http.StatusBadRequest, code, "rejected credentials are not retried")
+	})
+
+	t.Run("status codes", func(t *testing.T) {
+		producer := newProducer(t, map[string]interface{}{})
+		for _, statusCode = range []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusNotFound} {
+			code, status, message := producer.Produce(sampleEvent, nil)
+			require.Equal(t, statusCode, code)
+			require.Equal(t, "Failure", status)
+			require.Contains(t, message, "response")
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+// Traces are exported with OTLP if OpenTelemetry.traces.enabled is set, e.g. to an OpenTelemetry collector:
+//
+//	OpenTelemetry:
+//	  traces:
+//	    enabled: true
+//	    endpoint: localhost:4317
+//	    protocol: grpc # or http
+//	    insecure: true
+//	    samplingRatio: 0.01
+//
+// The tracer provider exporting them is registered as the global one, which the tracers of the packages creating
+// spans, e.g. gateway and warehouse, delegate to. Root spans are sampled at samplingRatio, the others as their parent,
+// e.g. as a client sending a traceparent header to the gateway decided. Otherwise, spans aren't recorded at all.
+
+// tracesConfig is the configuration of the OTLP traces exporter
+type tracesConfig struct {
+	enabled       bool
+	endpoint      string
+	protocol      string
+	insecure      bool
+	samplingRatio float64
+}
+
+func newTracesConfig(config *config.Config) (tracesConfig, error) {
+	conf := tracesConfig{
+		enabled:       config.GetBool("OpenTelemetry.traces.enabled", false),
+		endpoint:      config.GetString("OpenTelemetry.traces.endpoint", "localhost:4317"),
+		protocol:      strings.ToLower(config.GetString("OpenTelemetry.traces.protocol", "grpc")),
+		insecure:      config.GetBool("OpenTelemetry.traces.insecure", true),
+		samplingRatio: config.GetFloat64("OpenTelemetry.traces.samplingRatio", 0.01),
+	}
+	if conf.protocol != "grpc" && conf.protocol != "http" {
+		return conf, fmt.Errorf("unsupported OpenTelemetry traces protocol %q", conf.protocol)
+	}
+	if conf.samplingRatio < 0 || conf.samplingRatio > 1 {
+		return conf, fmt.Errorf("invalid OpenTelemetry traces sampling ratio %v", conf.samplingRatio)
+	}
+	return conf, nil
+}
+
+// Start registers the tracer provider exporting the traces of the server, if enabled. The returned function exports
+// the pending spans and shuts the provider down.
+func Start(ctx context.Context, c *config.Config, log logger.Logger) (stop func(), err error) {
+	conf, err := newTracesConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	if !conf.enabled {
+		return func() {}, nil
+	}
+
+	var client otlptrace.Client
+	if conf.protocol == "http" {
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(conf.endpoint)}
+		if conf.insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		client = otlptracehttp.NewClient(opts...)
+	} else {
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(conf.endpoint)}
+		if conf.insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		client = otlptracegrpc.NewClient(opts...)
+	}
+	exporter, err := otlptrace.New(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP traces exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, resourceAttributes(c)...))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.samplingRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.GetDuration("OpenTelemetry.traces.shutdownTimeout", 5, time.Second))
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Errorf("error while shutting down OpenTelemetry tracer provider: %v", err)
+		}
+	}, nil
+}
+
+// resourceAttributes returns the attributes of the resource the traces are exported for, the ones of the metrics
+func resourceAttributes(c *config.Config) []attribute.KeyValue {
+	instanceID := c.GetString("INSTANCE_ID", "")
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String("rudder-server"),
+		attribute.String("instanceName", instanceID),
+		attribute.String("mode", strings.ToUpper(c.GetString("APP_TYPE", "EMBEDDED"))),
+	}
+	if instanceID != "" {
+		attrs = append(attrs, semconv.ServiceInstanceIDKey.String(instanceID))
+	}
+	if namespace := config.GetKubeNamespace(); namespace != "" {
+		attrs = append(attrs, attribute.String("namespace", namespace))
+	}
+	return attrs
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestStart(t *testing.T) {
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	t.Run("disabled", func(t *testing.T) {
+		stop, err := Start(context.Background(), config.New(), logger.NOP)
+		require.NoError(t, err)
+		defer stop()
+		_, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+		require.False(t, ok, "no tracer provider is registered")
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		c := config.New()
+		c.Set("OpenTelemetry.traces.enabled", true)
+		c.Set("OpenTelemetry.traces.protocol", "udp")
+		_, err := Start(context.Background(), c, logger.NOP)
+		require.Error(t, err)
+
+		c.Set("OpenTelemetry.traces.protocol", "http")
+		c.Set("OpenTelemetry.traces.samplingRatio", 2)
+		_, err = Start(context.Background(), c, logger.NOP)
+		require.Error(t, err)
+	})
+
+	t.Run("exports spans", func(t *testing.T) {
+		var exports int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v1/traces" {
+				atomic.AddInt32(&exports, 1)
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		// tracers created before the provider is registered delegate to it
+		tracer := otel.Tracer("test")
+
+		c := config.New()
+		c.Set("OpenTelemetry.traces.enabled", true)
+		c.Set("OpenTelemetry.traces.protocol", "http")
+		c.Set("OpenTelemetry.traces.endpoint", strings.TrimPrefix(srv.URL, "http://"))
+		c.Set("OpenTelemetry.traces.samplingRatio", 1)
+		stop, err := Start(context.Background(), c, logger.NOP)
+		require.NoError(t, err)
+
+		_, span := tracer.Start(context.Background(), "span")
+		require.True(t, span.SpanContext().IsSampled())
+		span.End()
+
+		stop()
+		require.EqualValues(t, 1, atomic.LoadInt32(&exports), "pending spans are exported on stop")
+	})
+}
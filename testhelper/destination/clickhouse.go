@@ -0,0 +1,154 @@
+package destination
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	_ "github.com/ClickHouse/clickhouse-go"
+	"github.com/ory/dockertest/v3"
+	dc "github.com/ory/dockertest/v3/docker"
+
+	"github.com/rudderlabs/rudder-server/testhelper/rand"
+)
+
+const (
+	clickHouseDefaultDB       = "rudderdb"
+	clickHouseDefaultUser     = "rudder"
+	clickHouseDefaultPassword = "rudder-password"
+	clickHouseImage           = "yandex/clickhouse-server"
+	clickHouseTag             = "21-alpine"
+)
+
+type ClickHouseResource struct {
+	DB       *sql.DB
+	Database string
+	Password string
+	User     string
+	Host     string
+	Port     string
+}
+
+// ClickHouseClusterResource is a cluster of clickhouse nodes, sharing a zookeeper
+type ClickHouseClusterResource struct {
+	Nodes []*ClickHouseResource
+}
+
+func SetupClickHouse(pool *dockertest.Pool, d cleaner) (*ClickHouseResource, error) {
+	clickHouseContainer, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: clickHouseImage,
+		Tag:        clickHouseTag,
+		Env: []string{
+			"CLICKHOUSE_DB=" + clickHouseDefaultDB,
+			"CLICKHOUSE_USER=" + clickHouseDefaultUser,
+			"CLICKHOUSE_PASSWORD=" + clickHouseDefaultPassword,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.Cleanup(func() {
+		if err := pool.Purge(clickHouseContainer); err != nil {
+			d.Log("Could not purge resource:", err)
+		}
+	})
+	return connectClickHouse(pool, clickHouseContainer, clickHouseDefaultDB, true)
+}
+
+// ClickHouseClusterNode is a node of a clickhouse cluster, reachable as Hostname by the other nodes
+type ClickHouseClusterNode struct {
+	Hostname string
+	// ConfigDir is the directory of the configuration of the node, relative to the one of the cluster
+	ConfigDir string
+}
+
+// SetupClickHouseCluster runs the clickhouse nodes, with the configurations of their directories in configDir, along
+// with a zookeeper reachable as zookeeperHostname. The nodes and the zookeeper share a network of their own, for
+// clusters of concurrent tests not to reach each other.
+func SetupClickHouseCluster(pool *dockertest.Pool, d cleaner, configDir, zookeeperHostname string, nodes ...ClickHouseClusterNode) (*ClickHouseClusterResource, error) {
+	configDir, err := filepath.Abs(configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	network, err := pool.Client.CreateNetwork(dc.CreateNetworkOptions{Name: "clickhouse_network_" + rand.UniqueString(8)})
+	if err != nil {
+		return nil, fmt.Errorf("could not create docker network: %w", err)
+	}
+	d.Cleanup(func() {
+		if err := pool.Client.RemoveNetwork(network.ID); err != nil {
+			d.Log(fmt.Errorf("could not remove clickhouse network: %w", err))
+		}
+	})
+
+	zookeeperContainer, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "zookeeper",
+		Tag:        "3.5",
+		NetworkID:  network.ID,
+		Hostname:   zookeeperHostname,
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.Cleanup(func() {
+		if err := pool.Purge(zookeeperContainer); err != nil {
+			d.Log("Could not purge resource:", err)
+		}
+	})
+
+	cluster := &ClickHouseClusterResource{}
+	for _, n := range nodes {
+		nodeContainer, err := pool.RunWithOptions(&dockertest.RunOptions{
+			Repository: clickHouseImage,
+			Tag:        clickHouseTag,
+			NetworkID:  network.ID,
+			Hostname:   n.Hostname,
+			Mounts:     []string{filepath.Join(configDir, n.ConfigDir) + ":/etc/clickhouse-server"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		d.Cleanup(func() {
+			if err := pool.Purge(nodeContainer); err != nil {
+				d.Log("Could not purge resource:", err)
+			}
+		})
+
+		// the database is created on the cluster by the tests, through any of the nodes
+		node, err := connectClickHouse(pool, nodeContainer, clickHouseDefaultDB, false)
+		if err != nil {
+			return nil, fmt.Errorf("connecting to clickhouse node %s: %w", n.Hostname, err)
+		}
+		cluster.Nodes = append(cluster.Nodes, node)
+	}
+	return cluster, nil
+}
+
+// connectClickHouse connects to the clickhouse container, to the database only if it exists
+func connectClickHouse(pool *dockertest.Pool, container *dockertest.Resource, database string, databaseExists bool) (*ClickHouseResource, error) {
+	port := container.GetPort("9000/tcp")
+	dsn := fmt.Sprintf("tcp://localhost:%s?username=%s&password=%s&debug=false", port, clickHouseDefaultUser, clickHouseDefaultPassword)
+	if databaseExists {
+		dsn += "&database=" + database
+	}
+
+	var db *sql.DB
+	// exponential backoff-retry, because the application in the container might not be ready to accept connections yet
+	err := pool.Retry(func() (err error) {
+		if db, err = sql.Open("clickhouse", dsn); err != nil {
+			return err
+		}
+		return db.Ping()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ClickHouseResource{
+		DB:       db,
+		Database: database,
+		User:     clickHouseDefaultUser,
+		Password: clickHouseDefaultPassword,
+		Host:     "localhost",
+		Port:     port,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package destination
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	_ "github.com/denisenkom/go-mssqldb"
+	"github.com/ory/dockertest/v3"
+)
+
+const (
+	mssqlDefaultDB       = "master"
+	mssqlDefaultUser     = "SA"
+	mssqlDefaultPassword = "reallyStrongPwd123"
+)
+
+type MSSQLResource struct {
+	DB       *sql.DB
+	Database string
+	Password string
+	User     string
+	Host     string
+	Port     string
+}
+
+func SetupMSSQL(pool *dockertest.Pool, d cleaner) (*MSSQLResource, error) {
+	mssqlContainer, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "rudderstack/azure-sql-edge",
+		Tag:        "1.0.6",
+		Env: []string{
+			"ACCEPT_EULA=Y",
+			"SA_PASSWORD=" + mssqlDefaultPassword,
+			"SA_DB=" + mssqlDefaultDB,
+			"SA_USER=" + mssqlDefaultUser,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.Cleanup(func() {
+		if err := pool.Purge(mssqlContainer); err != nil {
+			d.Log("Could not purge resource:", err)
+		}
+	})
+
+	query := url.Values{}
+	query.Add("database", mssqlDefaultDB)
+	query.Add("encrypt", "disable")
+	query.Add("TrustServerCertificate", "true")
+	dsn := (&url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(mssqlDefaultUser, mssqlDefaultPassword),
+		Host:     fmt.Sprintf("localhost:%s", mssqlContainer.GetPort("1433/tcp")),
+		RawQuery: query.Encode(),
+	}).String()
+
+	var db *sql.DB
+	// exponential backoff-retry, because the application in the container might not be ready to accept connections yet
+	err = pool.Retry(func() (err error) {
+		if db, err = sql.Open("sqlserver", dsn); err != nil {
+			return err
+		}
+		return db.Ping()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MSSQLResource{
+		DB:       db,
+		Database: mssqlDefaultDB,
+		User:     mssqlDefaultUser,
+		Password: mssqlDefaultPassword,
+		Host:     "localhost",
+		Port:     mssqlContainer.GetPort("1433/tcp"),
+	}, nil
+}
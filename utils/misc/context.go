@@ -0,0 +1,32 @@
+package misc
+
+import (
+	"context"
+	"time"
+)
+
+// WithoutCancel returns a context carrying the values of the parent, but neither its deadline nor its cancellation,
+// for components to be stopped after the parent is done, e.g. in a given order during shutdown
+func WithoutCancel(parent context.Context) context.Context {
+	return withoutCancelCtx{parent: parent}
+}
+
+type withoutCancelCtx struct {
+	parent context.Context
+}
+
+func (withoutCancelCtx) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (withoutCancelCtx) Done() <-chan struct{} {
+	return nil
+}
+
+func (withoutCancelCtx) Err() error {
+	return nil
+}
+
+func (c withoutCancelCtx) Value(key any) any {
+	return c.parent.Value(key)
+}
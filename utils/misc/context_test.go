@@ -0,0 +1,28 @@
+package misc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithoutCancel(t *testing.T) {
+	type key struct{}
+
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "value"), time.Hour)
+	ctx := WithoutCancel(parent)
+	cancel()
+
+	require.ErrorIs(t, parent.Err(), context.Canceled)
+	require.NoError(t, ctx.Err())
+	require.Nil(t, ctx.Done())
+	_, ok := ctx.Deadline()
+	require.False(t, ok)
+	require.Equal(t, "value", ctx.Value(key{}))
+
+	child, cancelChild := context.WithCancel(ctx)
+	cancelChild()
+	require.ErrorIs(t, child.Err(), context.Canceled)
+}
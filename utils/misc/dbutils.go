@@ -26,6 +26,24 @@ func GetConnectionString() string {
 		host, port, user, password, dbname, sslmode, appName)
 }
 
+// GetReplicaConnectionString Returns the connection configuration of the read replica of Jobs DB, used for read-only
+// operations. Settings of the replica default to the primary's ones, an empty string being returned if DB.replica.host is not set
+func GetReplicaConnectionString() string {
+	host := config.GetString("DB.replica.host", "")
+	if host == "" {
+		return ""
+	}
+	user := config.GetString("DB.replica.user", config.GetString("DB.user", "ubuntu"))
+	dbname := config.GetString("DB.replica.name", config.GetString("DB.name", "ubuntu"))
+	port := config.GetInt("DB.replica.port", config.GetInt("DB.port", 5432))
+	password := config.GetString("DB.replica.password", config.GetString("DB.password", "ubuntu"))
+	sslmode := config.GetString("DB.replica.sslMode", config.GetString("DB.sslMode", "disable"))
+	appName := DefaultString("rudder-server").OnError(os.Hostname())
+	return fmt.Sprintf("host=%s port=%d user=%s "+
+		"password=%s dbname=%s sslmode=%s application_name=%s",
+		host, port, user, password, dbname, sslmode, appName)
+}
+
 /*
 ReplaceDB : Rename the OLD DB and create a new one.
 Since we are not journaling, this should be idemponent
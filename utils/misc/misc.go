@@ -122,7 +122,7 @@ func Init() {
 }
 
 func BatchDestinations() []string {
-	batchDestinations := []string{"S3", "GCS", "MINIO", "RS", "BQ", "AZURE_BLOB", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "DIGITAL_OCEAN_SPACES", "MSSQL", "AZURE_SYNAPSE", "S3_DATALAKE", "MARKETO_BULK_UPLOAD", "GCS_DATALAKE", "AZURE_DATALAKE", "DELTALAKE"}
+	batchDestinations := []string{"S3", "GCS", "MINIO", "RS", "BQ", "AZURE_BLOB", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "DIGITAL_OCEAN_SPACES", "SFTP", "MSSQL", "AZURE_SYNAPSE", "S3_DATALAKE", "MARKETO_BULK_UPLOAD", "GCS_DATALAKE", "AZURE_DATALAKE", "DELTALAKE"}
 	return batchDestinations
 }
 
@@ -958,7 +958,7 @@ type ObjectStorageOptsT struct {
 func GetObjectStorageConfig(opts ObjectStorageOptsT) map[string]interface{} {
 	objectStorageConfigMap := opts.Config.(map[string]interface{})
 	if opts.UseRudderStorage {
-		return GetRudderObjectStorageConfig(opts.RudderStoragePrefixOverride)
+		return GetWorkspaceRudderObjectStorageConfig(opts.WorkspaceID, opts.RudderStoragePrefixOverride)
 	}
 	if opts.Provider == "S3" {
 		clonedObjectStorageConfig := make(map[string]interface{})
@@ -625,6 +625,79 @@ func TestGetObjectStorageConfig(t *testing.T) {
 		require.Equal(t, "someOtherAccessKeyID", config["accessKeyID"])
 		require.Equal(t, "someOtherAccessKey", config["accessKey"])
 	})
+
+	t.Run("Rudder storage with workspace credentials", func(t *testing.T) {
+		SetRudderStorageCredentials(map[string]RudderStorageCredentials{
+			sampleWorkspaceID: {AccessKeyID: "workspaceAccessKeyID", AccessKey: "workspaceAccessKey", Prefix: "workspacePrefix"},
+		})
+		t.Cleanup(func() { SetRudderStorageCredentials(nil) })
+
+		config := GetObjectStorageConfig(ObjectStorageOptsT{
+			Provider:         "S3",
+			Config:           map[string]interface{}{},
+			UseRudderStorage: true,
+			WorkspaceID:      sampleWorkspaceID,
+		})
+		require.Equal(t, "workspaceAccessKeyID", config["accessKeyID"])
+		require.Equal(t, "workspaceAccessKey", config["accessKey"])
+		require.Equal(t, "workspacePrefix", config["prefix"])
+
+		config = GetObjectStorageConfig(ObjectStorageOptsT{
+			Provider:                    "S3",
+			Config:                      map[string]interface{}{},
+			UseRudderStorage:            true,
+			RudderStoragePrefixOverride: "overridePrefix",
+			WorkspaceID:                 sampleWorkspaceID,
+		})
+		require.Equal(t, "workspaceAccessKeyID", config["accessKeyID"])
+		require.Equal(t, "overridePrefix", config["prefix"], "the prefix override takes precedence")
+
+		config = GetObjectStorageConfig(ObjectStorageOptsT{
+			Provider:         "S3",
+			Config:           map[string]interface{}{},
+			UseRudderStorage: true,
+			WorkspaceID:      "someOtherWorkspaceID",
+		})
+		require.Equal(t, sampleAccessKeyID, config["accessKeyID"])
+		require.Equal(t, sampleAccessKey, config["accessKey"])
+	})
+
+	t.Run("Rudder storage with isolated workspace prefixes", func(t *testing.T) {
+		t.Setenv("RUDDER_WAREHOUSE_BUCKET_PREFIX", "basePrefix")
+		t.Setenv("RUDDER_WAREHOUSE_BUCKET_ISOLATE_WORKSPACES", "true")
+
+		config := GetObjectStorageConfig(ObjectStorageOptsT{
+			Provider:         "S3",
+			Config:           map[string]interface{}{},
+			UseRudderStorage: true,
+			WorkspaceID:      sampleWorkspaceID,
+		})
+		require.Equal(t, "basePrefix/"+sampleWorkspaceID, config["prefix"])
+		require.Equal(t, sampleAccessKeyID, config["accessKeyID"])
+
+		config = GetObjectStorageConfig(ObjectStorageOptsT{
+			Provider:                    "S3",
+			Config:                      map[string]interface{}{},
+			UseRudderStorage:            true,
+			RudderStoragePrefixOverride: "overridePrefix",
+			WorkspaceID:                 sampleWorkspaceID,
+		})
+		require.Equal(t, "overridePrefix", config["prefix"], "the prefix override takes precedence")
+	})
+}
+
+func TestGetReplicaConnectionString(t *testing.T) {
+	t.Setenv("JOBS_DB_HOST", "primary")
+	t.Setenv("JOBS_DB_USER", "rudder")
+	t.Setenv("JOBS_DB_PASSWORD", "password")
+	t.Setenv("JOBS_DB_DB_NAME", "jobsdb")
+	require.Empty(t, GetReplicaConnectionString(), "no replica configured")
+
+	t.Setenv("RSERVER_DB_REPLICA_HOST", "replica")
+	t.Setenv("RSERVER_DB_REPLICA_PASSWORD", "replicaPassword")
+	replicaInfo := GetReplicaConnectionString()
+	require.Contains(t, replicaInfo, "host=replica port=5432 user=rudder password=replicaPassword dbname=jobsdb sslmode=disable")
+	require.Contains(t, GetConnectionString(), "host=primary port=5432 user=rudder password=password dbname=jobsdb sslmode=disable")
 }
 
 // FolderExists Check if folder exists at particular path
@@ -0,0 +1,60 @@
+package misc
+
+import (
+	"path"
+	"sync"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// RudderStorageCredentials are the credentials and prefix a workspace uses to access rudder managed object storage
+type RudderStorageCredentials struct {
+	AccessKeyID string
+	AccessKey   string
+	Prefix      string
+}
+
+var workspaceRudderStorage struct {
+	sync.RWMutex
+	credentials map[string]RudderStorageCredentials
+}
+
+// SetRudderStorageCredentials replaces the rudder storage credentials of all workspaces, as resolved from the control plane
+func SetRudderStorageCredentials(credentials map[string]RudderStorageCredentials) {
+	workspaceRudderStorage.Lock()
+	defer workspaceRudderStorage.Unlock()
+	workspaceRudderStorage.credentials = credentials
+}
+
+func getRudderStorageCredentials(workspaceID string) (RudderStorageCredentials, bool) {
+	workspaceRudderStorage.RLock()
+	defer workspaceRudderStorage.RUnlock()
+	credentials, ok := workspaceRudderStorage.credentials[workspaceID]
+	return credentials, ok
+}
+
+// GetWorkspaceRudderObjectStorageConfig returns the rudder storage config to be used for workspaceID.
+// Credentials and prefix configured for the workspace in the control plane take precedence over the shared ones.
+// Otherwise, if RUDDER_WAREHOUSE_BUCKET_ISOLATE_WORKSPACES is enabled, objects of every workspace are kept under a prefix of their own.
+// A prefix override, e.g. the one of the objects written by shared slaves, takes precedence over both.
+func GetWorkspaceRudderObjectStorageConfig(workspaceID, prefixOverride string) map[string]interface{} {
+	storageConfig := GetRudderObjectStorageConfig(prefixOverride)
+	if workspaceID == "" {
+		return storageConfig
+	}
+
+	credentials, ok := getRudderStorageCredentials(workspaceID)
+	if ok && credentials.AccessKeyID != "" && credentials.AccessKey != "" {
+		storageConfig["accessKeyID"] = credentials.AccessKeyID
+		storageConfig["accessKey"] = credentials.AccessKey
+	}
+	if prefixOverride != "" {
+		return storageConfig
+	}
+	if ok && credentials.Prefix != "" {
+		storageConfig["prefix"] = credentials.Prefix
+	} else if config.GetBool("RUDDER_WAREHOUSE_BUCKET_ISOLATE_WORKSPACES", false) {
+		storageConfig["prefix"] = path.Join(storageConfig["prefix"].(string), workspaceID)
+	}
+	return storageConfig
+}
@@ -10,9 +10,11 @@ const (
 )
 
 type ChangeEvent struct {
-	err  error
-	ack  func(context.Context) error
-	mode Mode
+	err           error
+	ack           func(context.Context) error
+	mode          Mode
+	warehouseMode string
+	warehouseAck  func(ctx context.Context, warehouseMode string) error
 }
 
 func NewChangeEvent(mode Mode, ack func(context.Context) error) ChangeEvent {
@@ -36,6 +38,33 @@ func (m ChangeEvent) Mode() Mode {
 	return m.mode
 }
 
+// WithWarehouseMode returns the event switching the warehouse to the mode as well, e.g. master or slave
+func (m ChangeEvent) WithWarehouseMode(warehouseMode string) ChangeEvent {
+	m.warehouseMode = warehouseMode
+	return m
+}
+
+// WarehouseMode returns the mode the warehouse is switched to, empty if it is kept in its current mode
+func (m ChangeEvent) WarehouseMode() string {
+	return m.warehouseMode
+}
+
+// WithWarehouseAck returns the event acknowledged along with the mode the warehouse is in once it is handled, by
+// AckWarehouseMode
+func (m ChangeEvent) WithWarehouseAck(ack func(ctx context.Context, warehouseMode string) error) ChangeEvent {
+	m.warehouseAck = ack
+	return m
+}
+
+// AckWarehouseMode acknowledges the event along with the mode the warehouse is in, empty if the warehouse isn't
+// running on the server, falling back to Ack for events without a warehouse ack
+func (m ChangeEvent) AckWarehouseMode(ctx context.Context, warehouseMode string) error {
+	if m.warehouseAck == nil {
+		return m.ack(ctx)
+	}
+	return m.warehouseAck(ctx, warehouseMode)
+}
+
 func (m ChangeEvent) Err() error {
 	return m.err
 }
@@ -5,9 +5,23 @@ import "context"
 type ChangeEvent struct {
 	err          error
 	ack          func(context.Context, error) error
+	handover     func(context.Context, []PendingMarker) error
 	workspaceIDs []string
 }
 
+// PendingMarker marks work of a workspace left pending when the workspace was detached from a server, for the server
+// it's attached to next to pick it up
+type PendingMarker struct {
+	WorkspaceID string `json:"workspaceId"`
+	// Component is the component the work was pending in, e.g. router
+	Component string `json:"component"`
+	// Ref identifies the work within the component, e.g. the destination type of router jobs
+	Ref string `json:"ref"`
+	// State is the state the work was left in, e.g. executing for router jobs still being delivered
+	State string `json:"state"`
+	Count int    `json:"count"`
+}
+
 func NewWorkspacesRequest(
 	workspaceIDs []string,
 	ack func(context.Context, error) error,
@@ -24,10 +38,24 @@ func ChangeEventError(err error) ChangeEvent {
 	}
 }
 
+// WithHandover returns the request, handing over the work left pending by the workspaces it detaches with handover
+func (m ChangeEvent) WithHandover(handover func(context.Context, []PendingMarker) error) ChangeEvent {
+	m.handover = handover
+	return m
+}
+
 func (m ChangeEvent) Ack(ctx context.Context, err error) error {
 	return m.ack(ctx, err)
 }
 
+// Handover signals that the workspaces detached by the request are drained, along with the work they left pending
+func (m ChangeEvent) Handover(ctx context.Context, pending []PendingMarker) error {
+	if m.handover == nil {
+		return nil
+	}
+	return m.handover(ctx, pending)
+}
+
 func (m ChangeEvent) WorkspaceIDs() []string {
 	return m.workspaceIDs
 }
@@ -21,12 +21,12 @@ import (
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	"github.com/rudderlabs/rudder-server/controlplane"
 	proto "github.com/rudderlabs/rudder-server/proto/warehouse"
+	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/filemanager"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/timeutil"
 	"github.com/rudderlabs/rudder-server/utils/types/deployment"
-	cpclient "github.com/rudderlabs/rudder-server/warehouse/client/controlplane"
 	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
 	"github.com/tidwall/gjson"
 	"google.golang.org/grpc"
@@ -114,7 +114,8 @@ const (
 	NoSuchSync              = "No such sync exist"
 )
 
-func InitWarehouseAPI(dbHandle *sql.DB, log logger.Logger) error {
+// InitWarehouseAPI sets the warehouse api up, its validation pool running until ctx is done
+func InitWarehouseAPI(ctx context.Context, dbHandle *sql.DB, log logger.Logger) error {
 	connectionToken, tokenType, isMultiWorkspace, err := deployment.GetConnectionToken()
 	if err != nil {
 		return err
@@ -124,13 +125,22 @@ func InitWarehouseAPI(dbHandle *sql.DB, log logger.Logger) error {
 		labels["region"] = region
 	}
 
-	client := cpclient.NewInternalClientWithCache(
-		config.GetString("CONFIG_BACKEND_URL", "api.rudderlabs.com"),
-		cpclient.BasicAuth{
-			Username: config.GetString("CP_INTERNAL_API_USERNAME", ""),
-			Password: config.GetString("CP_INTERNAL_API_PASSWORD", ""),
-		},
+	client := sshKeysClient()
+	// the server is registered again on reconnections, sharing the validation pool
+	whGRPC := &warehouseGRPC{
+		EnableTunnelling: config.GetBool("ENABLE_TUNNELLING", true),
+		CPClient:         client,
+	}
+	whGRPC.validationPool = validations.NewPool(
+		whGRPC.validate,
+		validations.WithWorkers(config.GetInt("Warehouse.validations.pool.workers", 4)),
+		validations.WithQueueSize(config.GetInt("Warehouse.validations.pool.queueSize", 100)),
+		validations.WithCooldown(config.GetDuration("Warehouse.validations.pool.cooldown", 30, time.Second)),
 	)
+	rruntime.GoForWarehouse(func() {
+		<-ctx.Done()
+		whGRPC.validationPool.Stop()
+	})
 
 	UploadAPI = UploadAPIT{
 		enabled:           true,
@@ -150,10 +160,7 @@ func InitWarehouseAPI(dbHandle *sql.DB, log logger.Logger) error {
 			UseTLS:        config.GetBool("CP_ROUTER_USE_TLS", true),
 			Logger:        log,
 			RegisterService: func(srv *grpc.Server) {
-				proto.RegisterWarehouseServer(srv, &warehouseGRPC{
-					EnableTunnelling: config.GetBool("ENABLE_TUNNELLING", true),
-					CPClient:         client,
-				})
+				proto.RegisterWarehouseServer(srv, whGRPC)
 			},
 		},
 	}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"path"
+	"sort"
 	"time"
 
 	"github.com/rudderlabs/rudder-server/services/stats"
@@ -29,6 +31,8 @@ var (
 	archiveUploadRelatedRecords bool
 	uploadsArchivalTimeInDays   int
 	archiverTickerTime          time.Duration
+	rudderStorageArchivalPrefix string
+	rudderStorageRetention      map[string]interface{}
 )
 
 func Init() {
@@ -39,6 +43,8 @@ func loadConfigArchiver() {
 	config.RegisterBoolConfigVariable(true, &archiveUploadRelatedRecords, true, "Warehouse.archiveUploadRelatedRecords")
 	config.RegisterIntConfigVariable(5, &uploadsArchivalTimeInDays, true, 1, "Warehouse.uploadsArchivalTimeInDays")
 	config.RegisterDurationConfigVariable(360, &archiverTickerTime, true, time.Minute, []string{"Warehouse.archiverTickerTime", "Warehouse.archiverTickerTimeInMin"}...) // default 6 hours
+	config.RegisterStringConfigVariable("", &rudderStorageArchivalPrefix, true, "Warehouse.Archiver.rudderStorageArchivalPrefix")
+	config.RegisterStringMapConfigVariable(nil, &rudderStorageRetention, true, "Warehouse.Archiver.rudderStorageRetention")
 }
 
 type backupRecordsArgs struct {
@@ -143,6 +149,17 @@ func (a *Archiver) deleteFilesInStorage(locations []string) error {
 		return err
 	}
 
+	// keep a copy of the files under the archival prefix, if configured, before deleting them
+	if rudderStorageArchivalPrefix != "" {
+		for _, location := range locations {
+			_, err = fManager.Copy(context.TODO(), location, "", path.Join(rudderStorageArchivalPrefix, location))
+			if err != nil {
+				a.Logger.Errorf("Error in copying object %s to archival prefix in Rudder S3: %v", location, err)
+				return err
+			}
+		}
+	}
+
 	err = fManager.DeleteObjects(context.TODO(), locations)
 	if err != nil {
 		a.Logger.Errorf("Error in deleting objects in Rudder S3: %v", err)
@@ -150,6 +167,41 @@ func (a *Archiver) deleteFilesInStorage(locations []string) error {
 	return err
 }
 
+// retentionRules parses the per-prefix retention of rudder storage objects, configured as a map of prefix to duration, e.g. {"rudder-warehouse-staging-logs": "720h"}
+func (a *Archiver) retentionRules() []filemanager.RetentionRule {
+	rules := make([]filemanager.RetentionRule, 0, len(rudderStorageRetention))
+	for prefix, v := range rudderStorageRetention {
+		retentionStr, ok := v.(string)
+		if !ok {
+			a.Logger.Errorf("Invalid retention %v for rudder storage prefix %q", v, prefix)
+			continue
+		}
+		retention, err := time.ParseDuration(retentionStr)
+		if err != nil || retention <= 0 {
+			a.Logger.Errorf("Invalid retention %q for rudder storage prefix %q: %v", retentionStr, prefix, err)
+			continue
+		}
+		rules = append(rules, filemanager.RetentionRule{Prefix: prefix, Retention: retention})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Prefix < rules[j].Prefix })
+	return rules
+}
+
+// SweepRudderStorage deletes the rudder storage objects which have outlived the retention configured for their prefix
+func (a *Archiver) SweepRudderStorage(ctx context.Context) error {
+	rules := a.retentionRules()
+	if len(rules) == 0 {
+		return nil
+	}
+
+	deleted, err := filemanager.SweepExpiredObjects(ctx, a.FileManager, &filemanager.SettingsT{
+		Provider: warehouseutils.S3,
+		Config:   misc.GetRudderObjectStorageConfig(""),
+	}, rules, timeutil.Now())
+	a.Logger.Infof("Deleted %d expired objects from Rudder Storage", deleted)
+	return err
+}
+
 func (*Archiver) usedRudderStorage(metadata []byte) bool {
 	return gjson.GetBytes(metadata, "use_rudder_storage").Bool()
 }
@@ -18,6 +18,9 @@ func CronArchiver(ctx context.Context, a *Archiver) {
 					a.Logger.Errorf(`Error archiving uploads: %v`, err)
 				}
 			}
+			if err := a.SweepRudderStorage(ctx); err != nil {
+				a.Logger.Errorf(`Error sweeping expired rudder storage objects: %v`, err)
+			}
 		}
 	}
 }
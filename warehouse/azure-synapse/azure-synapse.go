@@ -19,6 +19,7 @@ import (
 
 	mssql "github.com/denisenkom/go-mssqldb"
 	"github.com/rudderlabs/rudder-server/services/filemanager"
+	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/warehouse/client"
@@ -135,7 +136,7 @@ func connect(cred credentialsT) (*sql.DB, error) {
 	}
 	pkgLogger.Debugf("synapse connection string : %s", connUrl.String())
 	var db *sql.DB
-	if db, err = sql.Open("sqlserver", connUrl.String()); err != nil {
+	if db, err = stats.OpenDB(stats.Default, "sqlserver", connUrl.String(), stats.Tags{"module": "warehouse", "destType": warehouseutils.AZURE_SYNAPSE}); err != nil {
 		return nil, fmt.Errorf("synapse connection error : (%v)", err)
 	}
 	return db, nil
@@ -180,6 +181,7 @@ func (as *HandleT) DownloadLoadFiles(tableName string) ([]string, error) {
 			UseRudderStorage: as.Uploader.UseRudderStorage(),
 			WorkspaceID:      as.Warehouse.Destination.WorkspaceID,
 		}),
+		WorkspaceID: as.Warehouse.Destination.WorkspaceID,
 	})
 	if err != nil {
 		pkgLogger.Errorf("AZ: Error in setting up a downloader for destinationID : %s Error : %v", as.Warehouse.Destination.ID, err)
@@ -603,10 +605,14 @@ func (*HandleT) DeleteBy([]string, warehouseutils.DeleteByParams) error {
 	return fmt.Errorf(warehouseutils.NotImplementedErrorCode)
 }
 
-func (as *HandleT) CreateSchema() (err error) {
-	sqlStatement := fmt.Sprintf(`IF NOT EXISTS ( SELECT  * FROM  sys.schemas WHERE   name = N'%s' )
+func (as *HandleT) createSchemaSQL() string {
+	return fmt.Sprintf(`IF NOT EXISTS ( SELECT  * FROM  sys.schemas WHERE   name = N'%s' )
     EXEC('CREATE SCHEMA [%s]');
 `, as.Namespace, as.Namespace)
+}
+
+func (as *HandleT) CreateSchema() (err error) {
+	sqlStatement := as.createSchemaSQL()
 	pkgLogger.Infof("SYNAPSE: Creating schema name in synapse for AZ:%s : %v", as.Warehouse.Destination.ID, sqlStatement)
 	_, err = as.Db.Exec(sqlStatement)
 	if err == io.EOF {
@@ -623,9 +629,25 @@ func (as *HandleT) dropStagingTable(stagingTableName string) {
 	}
 }
 
-func (as *HandleT) createTable(name string, columns map[string]string) (err error) {
-	sqlStatement := fmt.Sprintf(`IF  NOT EXISTS (SELECT 1 FROM sys.objects WHERE object_id = OBJECT_ID(N'%[1]s') AND type = N'U')
+func (as *HandleT) createTableSQL(name string, columns map[string]string) string {
+	return fmt.Sprintf(`IF  NOT EXISTS (SELECT 1 FROM sys.objects WHERE object_id = OBJECT_ID(N'%[1]s') AND type = N'U')
 	CREATE TABLE %[1]s ( %v )`, name, columnsWithDataTypes(columns, ""))
+}
+
+// PreviewDDL returns the statements creating the schema and the tables of the warehouse, without running them
+func (as *HandleT) PreviewDDL(warehouse warehouseutils.Warehouse, schema warehouseutils.SchemaT) []string {
+	as.Warehouse = warehouse
+	as.Namespace = warehouse.Namespace
+
+	statements := []string{as.createSchemaSQL()}
+	for _, tableName := range warehouseutils.SortedTableNames(schema) {
+		statements = append(statements, as.createTableSQL(as.Namespace+"."+tableName, schema[tableName]))
+	}
+	return statements
+}
+
+func (as *HandleT) createTable(name string, columns map[string]string) (err error) {
+	sqlStatement := as.createTableSQL(name, columns)
 
 	pkgLogger.Infof("AZ: Creating table in synapse for AZ:%s : %v", as.Warehouse.Destination.ID, sqlStatement)
 	_, err = as.Db.Exec(sqlStatement)
@@ -66,6 +66,15 @@ var dataTypesMap = map[string]bigquery.FieldType{
 	"datetime": bigquery.TimestampFieldType,
 }
 
+// ddlDataTypesMap maps datatype stored in rudder to datatype in bigquery standard sql ddl
+var ddlDataTypesMap = map[string]string{
+	"boolean":  "BOOL",
+	"int":      "INT64",
+	"float":    "FLOAT64",
+	"string":   "STRING",
+	"datetime": "TIMESTAMP",
+}
+
 // maps datatype in bigquery to datatype stored in rudder
 var dataTypesMapToRudder = map[bigquery.FieldType]string{
 	"BOOLEAN":   "boolean",
@@ -144,6 +153,15 @@ func (bq *HandleT) DropTable(tableName string) (err error) {
 }
 
 func (bq *HandleT) createTableView(tableName string, columnMap map[string]string) (err error) {
+	metaData := &bigquery.TableMetadata{
+		ViewQuery: bq.tableViewQuery(tableName, columnMap),
+	}
+	tableRef := bq.db.Dataset(bq.namespace).Table(tableName + "_view")
+	err = tableRef.Create(bq.backgroundContext, metaData)
+	return
+}
+
+func (bq *HandleT) tableViewQuery(tableName string, columnMap map[string]string) string {
 	partitionKey := "id"
 	if column, ok := partitionKeyMap[tableName]; ok {
 		partitionKey = column
@@ -160,12 +178,43 @@ func (bq *HandleT) createTableView(tableName string, columnMap map[string]string
 					AND TIMESTAMP_TRUNC(CURRENT_TIMESTAMP(), DAY, 'UTC')
 			)
 		WHERE __row_number = 1`
-	metaData := &bigquery.TableMetadata{
-		ViewQuery: viewQuery,
+	return viewQuery
+}
+
+func (bq *HandleT) location() string {
+	location := strings.TrimSpace(warehouseutils.GetConfigValue(GCPLocation, bq.warehouse))
+	if location == "" {
+		location = "US"
 	}
-	tableRef := bq.db.Dataset(bq.namespace).Table(tableName + "_view")
-	err = tableRef.Create(bq.backgroundContext, metaData)
-	return
+	return location
+}
+
+// PreviewDDL returns the standard sql statements equivalent to the api calls creating the dataset and the tables of the
+// warehouse, without running them
+func (bq *HandleT) PreviewDDL(warehouse warehouseutils.Warehouse, schema warehouseutils.SchemaT) []string {
+	bq.warehouse = warehouse
+	bq.namespace = warehouse.Namespace
+	bq.projectID = strings.TrimSpace(warehouseutils.GetConfigValue(GCPProjectID, bq.warehouse))
+
+	statements := []string{fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS `%s.%s` OPTIONS(location=%q)", bq.projectID, bq.namespace, bq.location())}
+	for _, tableName := range warehouseutils.SortedTableNames(schema) {
+		columnMap := schema[tableName]
+		columns := make([]string, 0, len(columnMap))
+		for _, columnName := range warehouseutils.SortColumnKeysFromColumnMap(columnMap) {
+			columns = append(columns, fmt.Sprintf("`%s` %s", columnName, ddlDataTypesMap[columnMap[columnName]]))
+		}
+		statements = append(statements, fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS `%s.%s.%s` ( %s ) PARTITION BY _PARTITIONDATE",
+			bq.projectID, bq.namespace, tableName, strings.Join(columns, ","),
+		))
+		if !dedupEnabled() {
+			statements = append(statements, fmt.Sprintf(
+				"CREATE VIEW IF NOT EXISTS `%s.%s.%s_view` AS %s",
+				bq.projectID, bq.namespace, tableName, bq.tableViewQuery(tableName, columnMap),
+			))
+		}
+	}
+	return statements
 }
 
 func (bq *HandleT) schemaExists(_, _ string) (exists bool, err error) {
@@ -183,10 +232,7 @@ func (bq *HandleT) schemaExists(_, _ string) (exists bool, err error) {
 
 func (bq *HandleT) CreateSchema() (err error) {
 	pkgLogger.Infof("BQ: Creating bigquery dataset: %s in project: %s", bq.namespace, bq.projectID)
-	location := strings.TrimSpace(warehouseutils.GetConfigValue(GCPLocation, bq.warehouse))
-	if location == "" {
-		location = "US"
-	}
+	location := bq.location()
 
 	var schemaExists bool
 	schemaExists, err = bq.schemaExists(bq.namespace, location)
@@ -0,0 +1,91 @@
+package warehouse
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// The warehouse service keeps part of its functionality in degraded mode, i.e. with Warehouse.runningMode degraded,
+// where it doesn't process uploads. The read-only functionality keeps working, the rest being unavailable:
+//
+//	capability          normal  degraded
+//	health              yes     yes
+//	uploadListing       yes     yes
+//	pendingEvents       yes     yes, without triggering uploads
+//	asyncJobStatus      yes     yes
+//	databricksVersion   yes     yes
+//	processStagingFiles yes     no
+//	triggerUploads      yes     no
+//	presignedURLs       yes     no
+//	setConfig           yes     no
+//	handover            yes     no
+//	addAsyncJobs        yes     no
+//	sshKeyRotations     yes     yes
+
+type capability string
+
+const (
+	capabilityHealth              capability = "health"
+	capabilityUploadListing       capability = "uploadListing"
+	capabilityPendingEvents       capability = "pendingEvents"
+	capabilityAsyncJobStatus      capability = "asyncJobStatus"
+	capabilityDatabricksVersion   capability = "databricksVersion"
+	capabilityProcessStagingFiles capability = "processStagingFiles"
+	capabilityTriggerUploads      capability = "triggerUploads"
+	capabilityPresignedURLs       capability = "presignedURLs"
+	capabilitySetConfig           capability = "setConfig"
+	capabilityHandover            capability = "handover"
+	capabilityAddAsyncJobs        capability = "addAsyncJobs"
+	capabilitySSHKeyRotations     capability = "sshKeyRotations"
+)
+
+// capabilities are the capabilities of the warehouse service, by whether they're available in degraded mode
+var capabilities = map[capability]bool{
+	capabilityHealth:              true,
+	capabilityUploadListing:       true,
+	capabilityPendingEvents:       true,
+	capabilityAsyncJobStatus:      true,
+	capabilityDatabricksVersion:   true,
+	capabilityProcessStagingFiles: false,
+	capabilityTriggerUploads:      false,
+	capabilityPresignedURLs:       false,
+	capabilitySetConfig:           false,
+	capabilityHandover:            false,
+	capabilityAddAsyncJobs:        false,
+	capabilitySSHKeyRotations:     true,
+}
+
+// capabilityAvailable returns whether the capability is available in the running mode
+func capabilityAvailable(c capability) bool {
+	return runningMode != DegradedMode || capabilities[c]
+}
+
+// availableCapabilities returns the capabilities available in the running mode, sorted
+func availableCapabilities() []string {
+	available := make([]string, 0, len(capabilities))
+	for c := range capabilities {
+		if capabilityAvailable(c) {
+			available = append(available, string(c))
+		}
+	}
+	sort.Strings(available)
+	return available
+}
+
+// errCapabilityUnavailable returns the error of requests to the capability while it's unavailable
+func errCapabilityUnavailable(c capability) error {
+	return fmt.Errorf("%s is unavailable in %s mode", c, runningMode)
+}
+
+// requireCapability responds with 503 Service Unavailable to the requests to the handler while the capability is
+// unavailable
+func requireCapability(c capability, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !capabilityAvailable(c) {
+			http.Error(w, errCapabilityUnavailable(c).Error(), http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,36 @@
+package warehouse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities(t *testing.T) {
+	defer func(mode string) { runningMode = mode }(runningMode)
+
+	handler := requireCapability(capabilityTriggerUploads, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func() int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/warehouse/trigger-upload", http.NoBody))
+		return w.Code
+	}
+
+	t.Run("normal mode", func(t *testing.T) {
+		runningMode = ""
+		require.Len(t, availableCapabilities(), len(capabilities))
+		require.Equal(t, http.StatusOK, serve())
+	})
+
+	t.Run("degraded mode", func(t *testing.T) {
+		runningMode = DegradedMode
+		require.Equal(t, []string{"asyncJobStatus", "databricksVersion", "health", "pendingEvents", "sshKeyRotations", "uploadListing"}, availableCapabilities())
+		require.True(t, capabilityAvailable(capabilityAsyncJobStatus))
+		require.False(t, capabilityAvailable(capabilityAddAsyncJobs))
+		require.Equal(t, http.StatusServiceUnavailable, serve())
+	})
+}
@@ -156,7 +156,6 @@ type clickHouseStatT struct {
 	numRowsLoadFile       stats.Measurement
 	downloadLoadFilesTime stats.Measurement
 	syncLoadFileTime      stats.Measurement
-	commitTime            stats.Measurement
 	failRetries           stats.Measurement
 	execTimeouts          stats.Measurement
 	commitTimeouts        stats.Measurement
@@ -179,7 +178,6 @@ func (ch *HandleT) newClickHouseStat(tableName string) *clickHouseStatT {
 	numRowsLoadFile := ch.stats.NewTaggedStat("warehouse.clickhouse.numRowsLoadFile", stats.CountType, tags)
 	downloadLoadFilesTime := ch.stats.NewTaggedStat("warehouse.clickhouse.downloadLoadFilesTime", stats.TimerType, tags)
 	syncLoadFileTime := ch.stats.NewTaggedStat("warehouse.clickhouse.syncLoadFileTime", stats.TimerType, tags)
-	commitTime := ch.stats.NewTaggedStat("warehouse.clickhouse.commitTime", stats.TimerType, tags)
 	failRetries := ch.stats.NewTaggedStat("warehouse.clickhouse.failedRetries", stats.CountType, tags)
 	execTimeouts := ch.stats.NewTaggedStat("warehouse.clickhouse.execTimeouts", stats.CountType, tags)
 	commitTimeouts := ch.stats.NewTaggedStat("warehouse.clickhouse.commitTimeouts", stats.CountType, tags)
@@ -188,7 +186,6 @@ func (ch *HandleT) newClickHouseStat(tableName string) *clickHouseStatT {
 		numRowsLoadFile:       numRowsLoadFile,
 		downloadLoadFilesTime: downloadLoadFilesTime,
 		syncLoadFileTime:      syncLoadFileTime,
-		commitTime:            commitTime,
 		failRetries:           failRetries,
 		execTimeouts:          execTimeouts,
 		commitTimeouts:        commitTimeouts,
@@ -232,7 +229,7 @@ func Connect(cred CredentialsT, includeDBInConn bool) (*sql.DB, error) {
 		db  *sql.DB
 	)
 
-	if db, err = sql.Open("clickhouse", url); err != nil {
+	if db, err = stats.OpenDB(stats.Default, "clickhouse", url, stats.Tags{"module": "warehouse", "destType": warehouseutils.CLICKHOUSE}); err != nil {
 		return nil, fmt.Errorf("clickhouse connection error : (%v)", err)
 	}
 	return db, nil
@@ -349,6 +346,7 @@ func (ch *HandleT) DownloadLoadFiles(tableName string) ([]string, error) {
 			UseRudderStorage: ch.Uploader.UseRudderStorage(),
 			WorkspaceID:      ch.Warehouse.Destination.WorkspaceID,
 		}),
+		WorkspaceID: ch.Warehouse.Destination.WorkspaceID,
 	})
 	if err != nil {
 		pkgLogger.Errorf("%s Error in setting up a downloader with Error: %v", ch.GetLogIdentifier(tableName, storageProvider), err)
@@ -586,8 +584,11 @@ func (ch *HandleT) loadTablesFromFilesNamesWithRetry(tableName string, tableSche
 		pkgLogger.Errorf("%s OnError for loading in table with error: %v", ch.GetLogIdentifier(tableName), err)
 	}
 
+	// committing the transaction and executing its statements are measured for the table
+	tableCtx := stats.WithSQLClientTags(context.Background(), stats.Tags{"table": tableName})
+
 	pkgLogger.Debugf("%s Beginning a transaction in db for loading in table", ch.GetLogIdentifier(tableName))
-	txn, err = ch.Db.Begin()
+	txn, err = ch.Db.BeginTx(tableCtx, nil)
 	if err != nil {
 		err = fmt.Errorf("%s Error while beginning a transaction in db for loading in table with error:%v", ch.GetLogIdentifier(tableName), err)
 		onError(err)
@@ -601,7 +602,7 @@ func (ch *HandleT) loadTablesFromFilesNamesWithRetry(tableName string, tableSche
 
 	sqlStatement := fmt.Sprintf(`INSERT INTO %q.%q (%v) VALUES (%s)`, ch.Namespace, tableName, sortedColumnString, generateArgumentString(len(sortedColumnKeys)))
 	pkgLogger.Debugf("%s Preparing statement exec in db for loading in table for query:%s", ch.GetLogIdentifier(tableName), sqlStatement)
-	stmt, err := txn.Prepare(sqlStatement)
+	stmt, err := txn.PrepareContext(tableCtx, sqlStatement)
 	if err != nil {
 		err = fmt.Errorf("%s Error while preparing statement for transaction in db for loading in table for query:%s error:%v", ch.GetLogIdentifier(tableName), sqlStatement, err)
 		onError(err)
@@ -659,7 +660,7 @@ func (ch *HandleT) loadTablesFromFilesNamesWithRetry(tableName string, tableSche
 				recordInterface = append(recordInterface, data)
 			}
 
-			stmtCtx, stmtCancel := context.WithCancel(context.Background())
+			stmtCtx, stmtCancel := context.WithCancel(tableCtx)
 			misc.RunWithTimeout(func() {
 				pkgLogger.Debugf("%s Starting Prepared statement exec", ch.GetLogIdentifier(tableName))
 				_, err = stmt.ExecContext(stmtCtx, recordInterface...)
@@ -692,9 +693,6 @@ func (ch *HandleT) loadTablesFromFilesNamesWithRetry(tableName string, tableSche
 	}
 
 	misc.RunWithTimeout(func() {
-		chStats.commitTime.Start()
-		defer chStats.commitTime.End()
-
 		pkgLogger.Debugf("%s Committing transaction", ch.GetLogIdentifier(tableName))
 		if err = txn.Commit(); err != nil {
 			err = fmt.Errorf("%s Error while committing transaction as there was error while loading in table with error:%v", ch.GetLogIdentifier(tableName), err)
@@ -728,6 +726,27 @@ func (ch *HandleT) schemaExists(schemaName string) (exists bool, err error) {
 	return
 }
 
+func (ch *HandleT) createSchemaSQL() string {
+	cluster := warehouseutils.GetConfigValue(Cluster, ch.Warehouse)
+	clusterClause := ""
+	if len(strings.TrimSpace(cluster)) > 0 {
+		clusterClause = fmt.Sprintf(`ON CLUSTER %q`, cluster)
+	}
+	return fmt.Sprintf(`CREATE DATABASE IF NOT EXISTS %q %s`, ch.Namespace, clusterClause)
+}
+
+// PreviewDDL returns the statements creating the database and the tables of the warehouse, without running them
+func (ch *HandleT) PreviewDDL(warehouse warehouseutils.Warehouse, schema warehouseutils.SchemaT) []string {
+	ch.Warehouse = warehouse
+	ch.Namespace = warehouse.Namespace
+
+	statements := []string{ch.createSchemaSQL()}
+	for _, tableName := range warehouseutils.SortedTableNames(schema) {
+		statements = append(statements, ch.createTableSQL(tableName, schema[tableName]))
+	}
+	return statements
+}
+
 // createSchema creates a database in clickhouse
 func (ch *HandleT) createSchema() (err error) {
 	var schemaExists bool
@@ -745,12 +764,7 @@ func (ch *HandleT) createSchema() (err error) {
 		return err
 	}
 	defer dbHandle.Close()
-	cluster := warehouseutils.GetConfigValue(Cluster, ch.Warehouse)
-	clusterClause := ""
-	if len(strings.TrimSpace(cluster)) > 0 {
-		clusterClause = fmt.Sprintf(`ON CLUSTER %q`, cluster)
-	}
-	sqlStatement := fmt.Sprintf(`CREATE DATABASE IF NOT EXISTS %q %s`, ch.Namespace, clusterClause)
+	sqlStatement := ch.createSchemaSQL()
 	pkgLogger.Infof("CH: Creating database in clickhouse for ch:%s : %v", ch.Warehouse.Destination.ID, sqlStatement)
 	_, err = dbHandle.Exec(sqlStatement)
 	return
@@ -762,6 +776,13 @@ this lets us choose aggregation logic before merging records with same user id.
 current behaviour is to replace user  properties with the latest non-null values
 */
 func (ch *HandleT) createUsersTable(name string, columns map[string]string) (err error) {
+	sqlStatement := ch.createUsersTableSQL(name, columns)
+	pkgLogger.Infof("CH: Creating table in clickhouse for ch:%s : %v", ch.Warehouse.Destination.ID, sqlStatement)
+	_, err = ch.Db.Exec(sqlStatement)
+	return
+}
+
+func (ch *HandleT) createUsersTableSQL(name string, columns map[string]string) string {
 	sortKeyFields := []string{"id"}
 	notNullableColumns := []string{"received_at", "id"}
 	clusterClause := ""
@@ -773,10 +794,7 @@ func (ch *HandleT) createUsersTable(name string, columns map[string]string) (err
 		engine = fmt.Sprintf(`%s%s`, "Replicated", engine)
 		engineOptions = `'/clickhouse/{cluster}/tables/{database}/{table}', '{replica}'`
 	}
-	sqlStatement := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %q.%q %s ( %v )  ENGINE = %s(%s) ORDER BY %s PARTITION BY toDate(%s)`, ch.Namespace, name, clusterClause, ColumnsWithDataTypes(name, columns, notNullableColumns), engine, engineOptions, getSortKeyTuple(sortKeyFields), partitionField)
-	pkgLogger.Infof("CH: Creating table in clickhouse for ch:%s : %v", ch.Warehouse.Destination.ID, sqlStatement)
-	_, err = ch.Db.Exec(sqlStatement)
-	return
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %q.%q %s ( %v )  ENGINE = %s(%s) ORDER BY %s PARTITION BY toDate(%s)`, ch.Namespace, name, clusterClause, ColumnsWithDataTypes(name, columns, notNullableColumns), engine, engineOptions, getSortKeyTuple(sortKeyFields), partitionField)
 }
 
 func getSortKeyTuple(sortKeyFields []string) string {
@@ -795,6 +813,19 @@ func getSortKeyTuple(sortKeyFields []string) string {
 // CreateTable creates table with engine ReplacingMergeTree(), this is used for dedupe event data and replace it will the latest data if duplicate data found. This logic is handled by clickhouse
 // The engine differs from MergeTree in that it removes duplicate entries with the same sorting key value.
 func (ch *HandleT) CreateTable(tableName string, columns map[string]string) (err error) {
+	if tableName == warehouseutils.UsersTable {
+		return ch.createUsersTable(tableName, columns)
+	}
+	sqlStatement := ch.createTableSQL(tableName, columns)
+	pkgLogger.Infof("CH: Creating table in clickhouse for ch:%s : %v", ch.Warehouse.Destination.ID, sqlStatement)
+	_, err = ch.Db.Exec(sqlStatement)
+	return
+}
+
+func (ch *HandleT) createTableSQL(tableName string, columns map[string]string) string {
+	if tableName == warehouseutils.UsersTable {
+		return ch.createUsersTableSQL(tableName, columns)
+	}
 	sortKeyFields := []string{"received_at", "id"}
 	if tableName == warehouseutils.DiscardsTable {
 		sortKeyFields = []string{"received_at"}
@@ -802,10 +833,6 @@ func (ch *HandleT) CreateTable(tableName string, columns map[string]string) (err
 	if strings.HasPrefix(tableName, warehouseutils.CTStagingTablePrefix) {
 		sortKeyFields = []string{"id"}
 	}
-	var sqlStatement string
-	if tableName == warehouseutils.UsersTable {
-		return ch.createUsersTable(tableName, columns)
-	}
 	clusterClause := ""
 	engine := "ReplacingMergeTree"
 	engineOptions := ""
@@ -825,11 +852,7 @@ func (ch *HandleT) CreateTable(tableName string, columns map[string]string) (err
 		partitionByClause = fmt.Sprintf(`PARTITION BY toDate(%s)`, partitionField)
 	}
 
-	sqlStatement = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %q.%q %s ( %v ) ENGINE = %s(%s) %s %s`, ch.Namespace, tableName, clusterClause, ColumnsWithDataTypes(tableName, columns, sortKeyFields), engine, engineOptions, orderByClause, partitionByClause)
-
-	pkgLogger.Infof("CH: Creating table in clickhouse for ch:%s : %v", ch.Warehouse.Destination.ID, sqlStatement)
-	_, err = ch.Db.Exec(sqlStatement)
-	return
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %q.%q %s ( %v ) ENGINE = %s(%s) %s %s`, ch.Namespace, tableName, clusterClause, ColumnsWithDataTypes(tableName, columns, sortKeyFields), engine, engineOptions, orderByClause, partitionByClause)
 }
 
 func (ch *HandleT) DropTable(tableName string) (err error) {
@@ -7,15 +7,30 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
+	"github.com/rudderlabs/rudder-server/services/controlplane"
+	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/httputil"
+	"github.com/rudderlabs/rudder-server/utils/pubsub"
 )
 
+// The ssh keys of destinations are cached by the client with cache, since they're fetched every time the config of a
+// tunnelled destination is published:
+//   - cached keys are valid for the cache ttl, and no longer than their validUntil, when the control plane sets it
+//   - keys rotated by the control plane are notified through RotateDestinationSSHKeys, invalidating the cached ones
+//     and notifying the subscribers of SubscribeToSSHKeyRotations, so that they fetch the rotated ones
+//   - cached keys reaching their validUntil are notified to the subscribers as well, as they're rotated by then
+
+const sshKeyRotationsTopic = "sshKeyRotations"
+
 var ErrKeyNotFound = errors.New("request key not found")
 
 type PublicPrivateKeyPair struct {
 	PublicKey  string
 	PrivateKey string
+	// ValidUntil is when the keys are rotated, the zero time if it isn't known
+	ValidUntil time.Time
 }
 
 type BasicAuth struct {
@@ -27,37 +42,109 @@ type InternalControlPlane interface {
 	GetDestinationSSHKeys(ctx context.Context, id string) (*PublicPrivateKeyPair, error)
 }
 
+// InternalControlPlaneWithCache caches the ssh keys of destinations until they expire or are rotated
+type InternalControlPlaneWithCache interface {
+	InternalControlPlane
+	// RotateDestinationSSHKeys invalidates the cached ssh keys of the destination, notifying the subscribers
+	RotateDestinationSSHKeys(id string)
+	// SubscribeToSSHKeyRotations returns a channel receiving the id of destinations whose ssh keys are rotated, until
+	// ctx is done. Rotations notified before the previous one is received may be coalesced into the last one.
+	SubscribeToSSHKeyRotations(ctx context.Context) pubsub.DataChannel
+}
+
+type cachedKeyPair struct {
+	keypair   *PublicPrivateKeyPair
+	expiresAt time.Time
+	expiry    *time.Timer // notifying the rotation of the keys once they're no longer valid, nil if not known
+}
+
 type internalClientWithCache struct {
-	client InternalControlPlane
-	cache  sync.Map
+	client     InternalControlPlane
+	clientOpts []InternalOption
+	ttl        time.Duration
+	now        func() time.Time
+	rotations  *pubsub.PublishSubscriber
+
+	mu    sync.Mutex
+	cache map[string]cachedKeyPair
 }
 
-func NewInternalClient(baseURI string, auth BasicAuth) InternalControlPlane {
-	return &internalClient{
+// CacheOption configures the client with cache
+type CacheOption func(*internalClientWithCache)
+
+// WithCacheTTL sets for how long ssh keys are cached, one hour by default
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(cc *internalClientWithCache) {
+		cc.ttl = ttl
+	}
+}
+
+// WithClock sets the clock the expiry of cached ssh keys is computed with
+func WithClock(now func() time.Time) CacheOption {
+	return func(cc *internalClientWithCache) {
+		cc.now = now
+	}
+}
+
+// WithInternalOptions sets the options of the client the ssh keys are fetched with
+func WithInternalOptions(opts ...InternalOption) CacheOption {
+	return func(cc *internalClientWithCache) {
+		cc.clientOpts = append(cc.clientOpts, opts...)
+	}
+}
+
+// InternalOption configures the internal client
+type InternalOption func(*internalClient)
+
+// WithFailover sets the region of the client and the control planes it fails over to, the ones of other regions
+// being left out, see controlplane.NewFailover
+func WithFailover(region string, endpoints []controlplane.Endpoint) InternalOption {
+	return func(api *internalClient) {
+		api.region = region
+		api.failoverEndpoints = endpoints
+	}
+}
+
+func NewInternalClient(baseURI string, auth BasicAuth, opts ...InternalOption) InternalControlPlane {
+	api := &internalClient{
 		baseURI:    baseURI,
 		auth:       auth,
-		httpClient: &http.Client{},
+		httpClient: stats.InstrumentHTTPClient(stats.Default, &http.Client{}, stats.Tags{"module": "warehouse"}),
+	}
+	for _, opt := range opts {
+		opt(api)
 	}
+	api.failover = controlplane.NewFailover(api.httpClient, api.baseURI, api.region, api.failoverEndpoints, failoverCooldown)
+	return api
 }
 
+// failoverCooldown is for how long control planes failing to respond are tried last
+var failoverCooldown = 30 * time.Second
+
 type internalClient struct {
 	baseURI    string
 	auth       BasicAuth
 	httpClient *http.Client
+
+	region            string
+	failoverEndpoints []controlplane.Endpoint
+	failover          *controlplane.Failover
 }
 
 func (api *internalClient) GetDestinationSSHKeys(ctx context.Context, id string) (*PublicPrivateKeyPair, error) {
-	url := fmt.Sprintf("%s/dataplane/admin/destinations/%s/sshKeys", api.baseURI, id)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return nil, fmt.Errorf("creating new request with ctx: %w", err)
-	}
-
-	req.SetBasicAuth(api.auth.Username, api.auth.Password)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := api.httpClient.Do(req)
+	path := fmt.Sprintf("/dataplane/admin/destinations/%s/sshKeys", id)
+
+	ctx = stats.WithHTTPClientTags(ctx, stats.Tags{"destID": id})
+	resp, err := api.failover.Do(ctx, func(baseURL string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+path, http.NoBody)
+		if err != nil {
+			return nil, fmt.Errorf("creating new request with ctx: %w", err)
+		}
+
+		req.SetBasicAuth(api.auth.Username, api.auth.Password)
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("fetching response from http client: %w", err)
 	}
@@ -82,16 +169,26 @@ func (api *internalClient) GetDestinationSSHKeys(ctx context.Context, id string)
 	return &keypair, nil
 }
 
-func NewInternalClientWithCache(baseURI string, auth BasicAuth) InternalControlPlane {
-	return &internalClientWithCache{
-		client: NewInternalClient(baseURI, auth),
-		cache:  sync.Map{},
+func NewInternalClientWithCache(baseURI string, auth BasicAuth, opts ...CacheOption) InternalControlPlaneWithCache {
+	cc := &internalClientWithCache{
+		ttl:       time.Hour,
+		now:       time.Now,
+		rotations: pubsub.New(),
+		cache:     make(map[string]cachedKeyPair),
+	}
+	for _, opt := range opts {
+		opt(cc)
 	}
+	cc.client = NewInternalClient(baseURI, auth, cc.clientOpts...)
+	return cc
 }
 
 func (cc *internalClientWithCache) GetDestinationSSHKeys(ctx context.Context, id string) (*PublicPrivateKeyPair, error) {
-	if val, ok := cc.cache.Load(id); ok {
-		return val.(*PublicPrivateKeyPair), nil
+	cc.mu.Lock()
+	cached, ok := cc.cache[id]
+	cc.mu.Unlock()
+	if ok && cc.now().Before(cached.expiresAt) {
+		return cached.keypair, nil
 	}
 
 	keypair, err := cc.client.GetDestinationSSHKeys(ctx, id)
@@ -99,6 +196,50 @@ func (cc *internalClientWithCache) GetDestinationSSHKeys(ctx context.Context, id
 		return nil, fmt.Errorf("fetching and caching destination ssh keys: %w", err)
 	}
 
-	cc.cache.Store(id, keypair)
+	now := cc.now()
+	expiresAt := now.Add(cc.ttl)
+	if !keypair.ValidUntil.IsZero() && keypair.ValidUntil.Before(expiresAt) {
+		expiresAt = keypair.ValidUntil
+	}
+	cached = cachedKeyPair{keypair: keypair, expiresAt: expiresAt}
+	if keypair.ValidUntil.After(now) {
+		cached.expiry = time.AfterFunc(keypair.ValidUntil.Sub(now), func() { cc.expire(id, keypair) })
+	}
+	cc.mu.Lock()
+	cc.stopExpiry(id)
+	cc.cache[id] = cached
+	cc.mu.Unlock()
 	return keypair, nil
 }
+
+func (cc *internalClientWithCache) RotateDestinationSSHKeys(id string) {
+	cc.mu.Lock()
+	cc.stopExpiry(id)
+	delete(cc.cache, id)
+	cc.mu.Unlock()
+	cc.rotations.Publish(sshKeyRotationsTopic, id)
+}
+
+// expire notifies the rotation of the keys of the destination once they're no longer valid, unless they were replaced
+// meanwhile
+func (cc *internalClientWithCache) expire(id string, keypair *PublicPrivateKeyPair) {
+	cc.mu.Lock()
+	if cached, ok := cc.cache[id]; !ok || cached.keypair != keypair {
+		cc.mu.Unlock()
+		return
+	}
+	delete(cc.cache, id)
+	cc.mu.Unlock()
+	cc.rotations.Publish(sshKeyRotationsTopic, id)
+}
+
+// stopExpiry stops notifying the expiry of the cached keys of the destination. Must be called with mu held.
+func (cc *internalClientWithCache) stopExpiry(id string) {
+	if cached, ok := cc.cache[id]; ok && cached.expiry != nil {
+		cached.expiry.Stop()
+	}
+}
+
+func (cc *internalClientWithCache) SubscribeToSSHKeyRotations(ctx context.Context) pubsub.DataChannel {
+	return cc.rotations.Subscribe(ctx, sshKeyRotationsTopic)
+}
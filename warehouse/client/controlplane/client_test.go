@@ -2,12 +2,16 @@ package controlplane_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/rudderlabs/rudder-server/services/controlplane"
 	cp "github.com/rudderlabs/rudder-server/warehouse/client/controlplane"
 	"github.com/stretchr/testify/require"
 )
@@ -79,3 +83,103 @@ func TestFetchSSHKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestClientWithCache(t *testing.T) {
+	var (
+		fetches    int
+		validUntil time.Time
+	)
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		require.NoError(t, json.NewEncoder(w).Encode(cp.PublicPrivateKeyPair{
+			PublicKey:  fmt.Sprintf("public_key_%d", fetches),
+			PrivateKey: fmt.Sprintf("private_key_%d", fetches),
+			ValidUntil: validUntil,
+		}))
+	}))
+	defer svc.Close()
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := cp.NewInternalClientWithCache(svc.URL, cp.BasicAuth{}, cp.WithCacheTTL(time.Hour), cp.WithClock(func() time.Time { return now }))
+	privateKey := func() string {
+		keys, err := client.GetDestinationSSHKeys(context.TODO(), "id")
+		require.NoError(t, err)
+		return keys.PrivateKey
+	}
+
+	t.Run("keys are cached for the ttl", func(t *testing.T) {
+		require.Equal(t, "private_key_1", privateKey())
+		now = now.Add(59 * time.Minute)
+		require.Equal(t, "private_key_1", privateKey())
+		now = now.Add(time.Minute)
+		require.Equal(t, "private_key_2", privateKey())
+	})
+
+	t.Run("keys are cached no longer than their validity", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		validUntil = now.Add(time.Minute)
+		require.Equal(t, "private_key_3", privateKey())
+		now = now.Add(time.Minute)
+		require.Equal(t, "private_key_4", privateKey())
+	})
+
+	t.Run("rotated keys are fetched again and notified", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		rotations := client.SubscribeToSSHKeyRotations(ctx)
+
+		client.RotateDestinationSSHKeys("id")
+		require.Equal(t, "id", (<-rotations).Data)
+		require.Equal(t, "private_key_5", privateKey())
+	})
+
+	t.Run("keys reaching their validity are notified", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		client := cp.NewInternalClientWithCache(svc.URL, cp.BasicAuth{}, cp.WithCacheTTL(time.Hour))
+		rotations := client.SubscribeToSSHKeyRotations(ctx)
+
+		validUntil = time.Now().Add(10 * time.Millisecond)
+		keys, err := client.GetDestinationSSHKeys(ctx, "id")
+		require.NoError(t, err)
+		require.Equal(t, "private_key_6", keys.PrivateKey)
+
+		select {
+		case rotation := <-rotations:
+			require.Equal(t, "id", rotation.Data)
+		case <-time.After(5 * time.Second):
+			t.Fatal("expired keys weren't notified")
+		}
+		validUntil = time.Time{}
+		keys, err = client.GetDestinationSSHKeys(ctx, "id")
+		require.NoError(t, err)
+		require.Equal(t, "private_key_7", keys.PrivateKey)
+	})
+}
+
+func TestFetchSSHKeysFailover(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	var otherRegionRequests int64
+	otherRegion := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&otherRegionRequests, 1)
+		_, _ = w.Write([]byte(`{"privateKey": "other_region"}`))
+	}))
+	defer otherRegion.Close()
+	sameRegion := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/dataplane/admin/destinations/id/sshKeys", r.URL.Path)
+		_, _ = w.Write([]byte(`{"privateKey": "same_region"}`))
+	}))
+	defer sameRegion.Close()
+
+	client := cp.NewInternalClientWithCache(primary.URL, cp.BasicAuth{}, cp.WithInternalOptions(cp.WithFailover("eu", []controlplane.Endpoint{
+		{URL: otherRegion.URL, Region: "us"},
+		{URL: sameRegion.URL, Region: "eu"},
+	})))
+	keys, err := client.GetDestinationSSHKeys(context.Background(), "id")
+	require.NoError(t, err)
+	require.Equal(t, "same_region", keys.PrivateKey)
+	require.Zero(t, atomic.LoadInt64(&otherRegionRequests))
+}
@@ -0,0 +1,51 @@
+package warehouse
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/utils/timeutil"
+)
+
+// The scheduler, the allocator and the uploads read the current time from a clock, instead of time.Now, timeutil.Now
+// or NOW() in queries, for tests to control it:
+//   - unit tests set the now of HandleT and UploadJobT to a fixed time
+//   - integration tests shift the clock of the running warehouse by Warehouse.clockOffset, through
+//     testhelper.ShiftClock, the offset being read only by warehouses built with the warehouse_integration tag
+//
+// Queries compare against the time of the clock as a timestamp literal, for the database and the warehouse to agree on
+// it.
+
+// clock returns the current time in UTC, shifted by Warehouse.clockOffset
+func clock() time.Time {
+	return timeutil.Now().Add(clockOffset)
+}
+
+// sqlTimestamp returns the time as a timestamp literal, for queries to use instead of NOW()
+func sqlTimestamp(t time.Time) string {
+	return fmt.Sprintf("'%s'::timestamptz", t.UTC().Format(time.RFC3339Nano))
+}
+
+// currentTime returns the time of the clock of the handle, the one of the warehouse if not set
+func (wh *HandleT) currentTime() time.Time {
+	if wh.now == nil {
+		return clock()
+	}
+	return wh.now()
+}
+
+// currentTime returns the time of the clock of the upload job, the one of the warehouse if not set
+func (job *UploadJobT) currentTime() time.Time {
+	if job.now == nil {
+		return clock()
+	}
+	return job.now()
+}
+
+// currentTime returns the time of the clock of the table upload, the one of the warehouse if not set
+func (tableUpload *TableUploadT) currentTime() time.Time {
+	if tableUpload.now == nil {
+		return clock()
+	}
+	return tableUpload.now()
+}
@@ -0,0 +1,17 @@
+//go:build warehouse_integration
+
+package warehouse
+
+import (
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// clockOffset shifts the clock of the warehouse, for integration tests to have upload frequencies and retry windows
+// elapse
+var clockOffset time.Duration
+
+func loadClockOffset() {
+	config.RegisterDurationConfigVariable(0, &clockOffset, true, time.Second, "Warehouse.clockOffset")
+}
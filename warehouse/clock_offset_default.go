@@ -0,0 +1,8 @@
+//go:build !warehouse_integration
+
+package warehouse
+
+// clockOffset is only set by warehouses built with the warehouse_integration tag
+const clockOffset = 0
+
+func loadClockOffset() {}
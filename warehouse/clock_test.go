@@ -0,0 +1,79 @@
+package warehouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+func TestSQLTimestamp(t *testing.T) {
+	now := time.Date(2022, 12, 6, 22, 0, 0, 123000000, time.FixedZone("IST", 5*60*60+30*60))
+	require.Equal(t, "'2022-12-06T16:30:00.123Z'::timestamptz", sqlTimestamp(now))
+}
+
+func TestHandleT_CanCreateUpload(t *testing.T) {
+	Init4()
+	pkgLogger = logger.NOP
+
+	now := time.Date(2022, 12, 6, 22, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		excludeWindow map[string]interface{}
+		lastProcessed time.Time
+		want          bool
+	}{
+		{
+			name: "within the exclude window",
+			excludeWindow: map[string]interface{}{
+				warehouseutils.ExcludeWindowStartTime: "21:00",
+				warehouseutils.ExcludeWindowEndTime:   "23:00",
+			},
+			want: false,
+		},
+		{
+			name:          "within the sync frequency",
+			lastProcessed: now.Add(-10 * time.Minute),
+			want:          false,
+		},
+		{
+			name:          "past the sync frequency",
+			lastProcessed: now.Add(-40 * time.Minute),
+			want:          true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			warehouse := testWarehouse()
+			if tc.excludeWindow != nil {
+				warehouse.Destination.Config[warehouseutils.ExcludeWindow] = tc.excludeWindow
+			}
+			setLastProcessedMarker(warehouse, tc.lastProcessed)
+
+			wh := HandleT{
+				now: func() time.Time { return now },
+			}
+			require.Equal(t, tc.want, wh.canCreateUpload(warehouse))
+		})
+	}
+}
+
+func TestUploadJobT_Aborted(t *testing.T) {
+	Init4()
+
+	now := time.Date(2022, 12, 6, 22, 30, 0, 0, time.UTC)
+	job := UploadJobT{
+		now: func() time.Time { return now },
+	}
+
+	require.False(t, job.Aborted(minRetryAttempts+1, now.Add(-retryTimeWindow+time.Minute)))
+	require.False(t, job.Aborted(minRetryAttempts, now.Add(-retryTimeWindow-time.Minute)))
+	require.True(t, job.Aborted(minRetryAttempts+1, now.Add(-retryTimeWindow-time.Minute)))
+}
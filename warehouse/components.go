@@ -0,0 +1,129 @@
+package warehouse
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// The master and the slaves of the warehouse service run as components, started as per Warehouse.mode once the service
+// starts. With Warehouse.dynamicMode.enabled, the cluster starts and stops them at runtime, as the node is promoted,
+// demoted or drained through mode changes, without the service being restarted:
+//   - the master schedules the uploads of the warehouse destinations, archives the uploads processed, clears the
+//     jobs of the notifier left behind and runs the async jobs
+//   - the slaves process the jobs published by the master, generating load files and running async jobs
+//
+// The APIs of the master are served by the nodes started as master, or by every node with dynamic mode enabled, for
+// them to be served once promoted.
+
+var (
+	masterComponent = &Component{name: "master"}
+	slaveComponent  = &Component{name: "slave"}
+)
+
+// Master returns the master component of the warehouse service
+func Master() *Component {
+	return masterComponent
+}
+
+// Slave returns the slave component of the warehouse service
+func Slave() *Component {
+	return slaveComponent
+}
+
+// DynamicModeEnabled returns whether the mode of the warehouse service is switched at runtime by the cluster
+func DynamicModeEnabled() bool {
+	return dynamicModeEnabled
+}
+
+// Component is a part of the warehouse service, run for as long as it is started, until the service stops
+type Component struct {
+	name string
+	run  func(ctx context.Context) error
+
+	mu      sync.Mutex
+	parent  context.Context
+	errCh   chan error
+	started bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Start runs the component, once the warehouse service starts if it hasn't yet
+func (c *Component) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.started {
+		return nil
+	}
+	c.started = true
+	if c.parent != nil && c.parent.Err() == nil {
+		c.runLocked()
+	}
+	return nil
+}
+
+// Stop stops the component, waiting for it to return
+func (c *Component) Stop() {
+	c.mu.Lock()
+	c.started = false
+	cancel, done := c.cancel, c.done
+	c.cancel, c.done = nil, nil
+	c.mu.Unlock()
+
+	if cancel != nil {
+		pkgLogger.Infof("WH: Stopping warehouse %s...", c.name)
+		cancel()
+		<-done
+	}
+}
+
+// Running returns whether the component is running
+func (c *Component) Running() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cancel != nil
+}
+
+// attach runs the component with run, whenever it is started, until ctx is done or it fails
+func (c *Component) attach(ctx context.Context, run func(ctx context.Context) error) error {
+	c.mu.Lock()
+	c.parent = ctx
+	c.run = run
+	c.errCh = make(chan error, 1)
+	if c.started {
+		c.runLocked()
+	}
+	errCh := c.errCh
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		c.Stop()
+		return nil
+	case err := <-errCh:
+		c.Stop()
+		return err
+	}
+}
+
+func (c *Component) runLocked() {
+	pkgLogger.Infof("WH: Starting warehouse %s...", c.name)
+
+	ctx, cancel := context.WithCancel(c.parent)
+	done := make(chan struct{})
+	c.cancel, c.done = cancel, done
+
+	run, errCh := c.run, c.errCh
+	go func() {
+		defer close(done)
+		if err := run(ctx); err != nil && ctx.Err() == nil {
+			select {
+			case errCh <- fmt.Errorf("warehouse %s: %w", c.name, err):
+			default:
+			}
+		}
+	}()
+}
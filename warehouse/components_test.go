@@ -0,0 +1,94 @@
+package warehouse
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+func TestComponent(t *testing.T) {
+	pkgLogger = logger.NOP
+
+	// run reports the contexts it runs with, until they're done
+	newRun := func() (func(ctx context.Context) error, <-chan context.Context) {
+		running := make(chan context.Context, 1)
+		return func(ctx context.Context) error {
+			running <- ctx
+			<-ctx.Done()
+			return nil
+		}, running
+	}
+
+	t.Run("started before the service", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		run, running := newRun()
+
+		c := &Component{name: "test"}
+		require.NoError(t, c.Start())
+		require.False(t, c.Running())
+
+		attached := make(chan error, 1)
+		go func() {
+			attached <- c.attach(ctx, run)
+		}()
+
+		runCtx := <-running
+		require.True(t, c.Running())
+
+		cancel()
+		require.NoError(t, <-attached)
+		require.Error(t, runCtx.Err())
+		require.False(t, c.Running())
+	})
+
+	t.Run("started and stopped at runtime", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		run, running := newRun()
+
+		c := &Component{name: "test"}
+		attached := make(chan error, 1)
+		go func() {
+			attached <- c.attach(ctx, run)
+		}()
+		require.Eventually(t, func() bool {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.parent != nil
+		}, time.Second, time.Millisecond)
+		require.False(t, c.Running())
+
+		require.NoError(t, c.Start())
+		require.NoError(t, c.Start())
+		runCtx := <-running
+		require.True(t, c.Running())
+
+		c.Stop()
+		require.Error(t, runCtx.Err())
+		require.False(t, c.Running())
+		c.Stop()
+
+		require.NoError(t, c.Start())
+		runCtx = <-running
+		require.NoError(t, runCtx.Err())
+		require.True(t, c.Running())
+
+		cancel()
+		require.NoError(t, <-attached)
+	})
+
+	t.Run("failing", func(t *testing.T) {
+		c := &Component{name: "test"}
+		require.NoError(t, c.Start())
+
+		err := c.attach(context.Background(), func(context.Context) error {
+			return errors.New("notifier unavailable")
+		})
+		require.EqualError(t, err, "warehouse test: notifier unavailable")
+		require.False(t, c.Running())
+	})
+}
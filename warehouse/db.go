@@ -79,11 +79,12 @@ func (db *DB) RetryUploads(ctx context.Context, filterClauses ...FilterClause) (
 	preparedStatement = fmt.Sprintf(`
 		UPDATE wh_uploads
 		SET
-			metadata = metadata || '{"retried": true, "priority": 50}' || jsonb_build_object('nextRetryTime', NOW() - INTERVAL '1 HOUR'),
+			metadata = metadata || '{"retried": true, "priority": 50}' || jsonb_build_object('nextRetryTime', %[2]s - INTERVAL '1 HOUR'),
 			status = 'waiting',
-			updated_at = NOW()
+			updated_at = %[2]s
 		WHERE %[1]s;`,
 		clausesQuery,
+		sqlTimestamp(clock()),
 	)
 	pkgLogger.Debugf("[RetryUploads] sqlStatement: %s", preparedStatement)
 
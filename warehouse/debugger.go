@@ -0,0 +1,151 @@
+package warehouse
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	destinationdebugger "github.com/rudderlabs/rudder-server/services/debugger/destination"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// Uploads report the progress of their most recent staging files to the destination debugger, once their load files
+// are generated, and once they're exported or fail, so that the Live Events tab covers warehouse destinations past
+// the staging files generated by the batch router. Each staging file is reported with the upload consuming it, the
+// events it loads into each table, and the events discarded into the discards table.
+
+// stagingFileProgressT is the payload of the delivery status of a staging file
+type stagingFileProgressT struct {
+	StagingFileID int64            `json:"stagingFileId"`
+	Location      string           `json:"location"`
+	UploadID      int64            `json:"uploadId"`
+	Tables        map[string]int64 `json:"tables"`
+	Discards      int64            `json:"discards"`
+}
+
+// recordStagingFilesProgress records the progress of the most recent staging files of the upload in state to the
+// destination debugger, along with the error failing the upload
+func (job *UploadJobT) recordStagingFilesProgress(state string, uploadErr error) {
+	if !destinationdebugger.HasUploadEnabled(job.upload.DestinationID) {
+		return
+	}
+	stagingFiles := job.stagingFiles
+	if len(stagingFiles) > debuggerMaxStagingFiles {
+		stagingFiles = stagingFiles[len(stagingFiles)-debuggerMaxStagingFiles:]
+	}
+	stagingFileIDs := make([]int64, len(stagingFiles))
+	for i, stagingFile := range stagingFiles {
+		stagingFileIDs[i] = stagingFile.ID
+	}
+	eventsByTable, err := job.loadFileEventsByTable(stagingFileIDs)
+	if err != nil {
+		pkgLogger.Errorf("[WH]: Failed to get load file events of staging files of upload %d for the debugger: %v", job.upload.ID, err)
+		return
+	}
+	for _, deliveryStatus := range job.stagingFilesDeliveryStatuses(stagingFileIDs, eventsByTable, state, uploadErr) {
+		destinationdebugger.RecordEventDeliveryStatus(job.upload.DestinationID, deliveryStatus)
+	}
+}
+
+// loadFileEventsByTable returns the events of the latest load files of each table, by staging file
+func (job *UploadJobT) loadFileEventsByTable(stagingFileIDs []int64) (map[int64]map[string]int64, error) {
+	sqlStatement := fmt.Sprintf(`
+		SELECT
+		  staging_file_id,
+		  table_name,
+		  total_events
+		FROM
+		  (
+			SELECT
+			  staging_file_id,
+			  table_name,
+			  total_events,
+			  row_number() OVER (
+				PARTITION BY staging_file_id,
+				table_name
+				ORDER BY
+				  id DESC
+			  ) AS row_number
+			FROM
+			  %[1]s
+			WHERE
+			  staging_file_id = ANY($1)
+		  ) AS row_numbered_load_files
+		WHERE
+		  row_number = 1;
+	`,
+		warehouseutils.WarehouseLoadFilesTable,
+	)
+	rows, err := job.dbHandle.Query(sqlStatement, pq.Array(stagingFileIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	eventsByTable := make(map[int64]map[string]int64)
+	for rows.Next() {
+		var (
+			stagingFileID int64
+			tableName     string
+			totalEvents   sql.NullInt64
+		)
+		if err := rows.Scan(&stagingFileID, &tableName, &totalEvents); err != nil {
+			return nil, err
+		}
+		if eventsByTable[stagingFileID] == nil {
+			eventsByTable[stagingFileID] = make(map[string]int64)
+		}
+		eventsByTable[stagingFileID][tableName] = totalEvents.Int64
+	}
+	return eventsByTable, rows.Err()
+}
+
+// stagingFilesDeliveryStatuses returns the delivery statuses of the staging files loading their events by table
+func (job *UploadJobT) stagingFilesDeliveryStatuses(stagingFileIDs []int64, eventsByTable map[int64]map[string]int64, state string, uploadErr error) []*destinationdebugger.DeliveryStatusT {
+	errorCode, errorResponse := "200", []byte(`{"success":"OK"}`)
+	if uploadErr != nil {
+		errorCode = "500"
+		errorResponse, _ = json.Marshal(map[string]string{"error": uploadErr.Error()})
+	}
+	discardsTable := warehouseutils.ToProviderCase(job.warehouse.Type, warehouseutils.DiscardsTable)
+	locations := make(map[int64]string, len(job.stagingFiles))
+	for _, stagingFile := range job.stagingFiles {
+		locations[stagingFile.ID] = stagingFile.Location
+	}
+
+	deliveryStatuses := make([]*destinationdebugger.DeliveryStatusT, 0, len(stagingFileIDs))
+	for _, stagingFileID := range stagingFileIDs {
+		progress := stagingFileProgressT{
+			StagingFileID: stagingFileID,
+			Location:      locations[stagingFileID],
+			UploadID:      job.upload.ID,
+			Tables:        make(map[string]int64),
+		}
+		var totalEvents int64
+		for tableName, events := range eventsByTable[stagingFileID] {
+			if tableName == discardsTable {
+				progress.Discards = events
+				continue
+			}
+			progress.Tables[tableName] = events
+			totalEvents += events
+		}
+		payload, _ := json.Marshal(progress)
+		deliveryStatuses = append(deliveryStatuses, &destinationdebugger.DeliveryStatusT{
+			DestinationID: job.upload.DestinationID,
+			SourceID:      job.upload.SourceID,
+			Payload:       payload,
+			AttemptNum:    1,
+			JobState:      state,
+			ErrorCode:     errorCode,
+			ErrorResponse: errorResponse,
+			SentAt:        time.Now().Format(misc.RFC3339Milli),
+			EventName:     fmt.Sprint(totalEvents) + " events",
+		})
+	}
+	return deliveryStatuses
+}
@@ -0,0 +1,36 @@
+package warehouse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/warehouse/internal/model"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+func TestStagingFilesDeliveryStatuses(t *testing.T) {
+	job := &UploadJobT{
+		upload:       &Upload{ID: 7, SourceID: "source-id", DestinationID: "destination-id"},
+		warehouse:    warehouseutils.Warehouse{Type: warehouseutils.SNOWFLAKE},
+		stagingFiles: []*model.StagingFile{{ID: 1, Location: "s3://bucket/1.json.gz"}, {ID: 2, Location: "s3://bucket/2.json.gz"}},
+	}
+	eventsByTable := map[int64]map[string]int64{
+		1: {"TRACKS": 3, "PRODUCT_VIEWED": 2, "RUDDER_DISCARDS": 1},
+	}
+
+	statuses := job.stagingFilesDeliveryStatuses([]int64{1, 2}, eventsByTable, "exported_data", nil)
+	require.Len(t, statuses, 2)
+	require.Equal(t, "destination-id", statuses[0].DestinationID)
+	require.Equal(t, "source-id", statuses[0].SourceID)
+	require.Equal(t, "exported_data", statuses[0].JobState)
+	require.Equal(t, "200", statuses[0].ErrorCode)
+	require.Equal(t, "5 events", statuses[0].EventName)
+	require.JSONEq(t, `{"stagingFileId":1,"location":"s3://bucket/1.json.gz","uploadId":7,"tables":{"TRACKS":3,"PRODUCT_VIEWED":2},"discards":1}`, string(statuses[0].Payload))
+	require.JSONEq(t, `{"stagingFileId":2,"location":"s3://bucket/2.json.gz","uploadId":7,"tables":{},"discards":0}`, string(statuses[1].Payload))
+
+	statuses = job.stagingFilesDeliveryStatuses([]int64{1}, eventsByTable, "exporting_data_failed", errors.New("table not found"))
+	require.Equal(t, "500", statuses[0].ErrorCode)
+	require.JSONEq(t, `{"error":"table not found"}`, string(statuses[0].ErrorResponse))
+}
@@ -420,9 +420,25 @@ func (dl *HandleT) schemaExists(schemaName string) (exists bool, err error) {
 	return
 }
 
+func (dl *HandleT) createSchemaSQL() string {
+	return fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s;`, dl.Namespace)
+}
+
+// PreviewDDL returns the statements creating the schema and the tables of the warehouse, without running them
+func (dl *HandleT) PreviewDDL(warehouse warehouseutils.Warehouse, schema warehouseutils.SchemaT) []string {
+	dl.Warehouse = warehouse
+	dl.Namespace = warehouse.Namespace
+
+	statements := []string{dl.createSchemaSQL()}
+	for _, tableName := range warehouseutils.SortedTableNames(schema) {
+		statements = append(statements, dl.createTableSQL(tableName, schema[tableName]))
+	}
+	return statements
+}
+
 // createSchema creates schema
 func (dl *HandleT) createSchema() (err error) {
-	sqlStatement := fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s;`, dl.Namespace)
+	sqlStatement := dl.createSchemaSQL()
 	pkgLogger.Infof("%s Creating schema in delta lake with SQL:%v", dl.GetLogIdentifier(), sqlStatement)
 	err = dl.ExecuteSQL(sqlStatement, "CreateSchema")
 	return
@@ -837,6 +853,13 @@ func (dl *HandleT) connectToWarehouse() (dbHandleT *databricks.DBHandleT, err er
 
 // CreateTable creates tables with table name and columns
 func (dl *HandleT) CreateTable(tableName string, columns map[string]string) (err error) {
+	sqlStatement := dl.createTableSQL(tableName, columns)
+	pkgLogger.Infof("%s Creating table in delta lake with SQL: %v", dl.GetLogIdentifier(tableName), sqlStatement)
+	err = dl.ExecuteSQL(sqlStatement, "CreateTable")
+	return
+}
+
+func (dl *HandleT) createTableSQL(tableName string, columns map[string]string) string {
 	name := fmt.Sprintf(`%s.%s`, dl.Namespace, tableName)
 
 	tableLocationSql := dl.getTableLocationSql(tableName)
@@ -850,10 +873,7 @@ func (dl *HandleT) CreateTable(tableName string, columns map[string]string) (err
 		createTableClauseSql = "CREATE OR REPLACE TABLE"
 	}
 
-	sqlStatement := fmt.Sprintf(`%s %s ( %v ) USING DELTA %s %s;`, createTableClauseSql, name, ColumnsWithDataTypes(columns, ""), tableLocationSql, partitionedSql)
-	pkgLogger.Infof("%s Creating table in delta lake with SQL: %v", dl.GetLogIdentifier(tableName), sqlStatement)
-	err = dl.ExecuteSQL(sqlStatement, "CreateTable")
-	return
+	return fmt.Sprintf(`%s %s ( %v ) USING DELTA %s %s;`, createTableClauseSql, name, ColumnsWithDataTypes(columns, ""), tableLocationSql, partitionedSql)
 }
 
 func (dl *HandleT) DropTable(tableName string) (err error) {
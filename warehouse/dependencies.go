@@ -0,0 +1,30 @@
+package warehouse
+
+//go:generate mockgen -destination=./mock_dependencies_test.go -package=warehouse -source=./dependencies.go stagingFilesRepo,uploadNotifier
+
+import (
+	"context"
+
+	"github.com/rudderlabs/rudder-server/services/pgnotifier"
+	"github.com/rudderlabs/rudder-server/warehouse/internal/model"
+	"github.com/rudderlabs/rudder-server/warehouse/manager"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// The scheduling and the processing of uploads depend on the repository of staging files, the notifier and the
+// managers of the warehouses through the interfaces below, for tests to replace them with mocks instead of a live
+// Postgres and warehouses.
+
+// stagingFilesRepo is the repository of the staging files uploads are created from
+type stagingFilesRepo interface {
+	GetInRange(ctx context.Context, sourceID, destinationID string, startID, endID int64) ([]model.StagingFile, error)
+	GetAfterID(ctx context.Context, sourceID, destinationID string, startID int64) ([]model.StagingFile, error)
+}
+
+// uploadNotifier publishes the jobs generating the load files of uploads to the slaves
+type uploadNotifier interface {
+	Publish(payload pgnotifier.MessagePayload, schema *warehouseutils.SchemaT, priority int) (ch chan []pgnotifier.ResponseT, err error)
+}
+
+// managerFactory returns the manager of the warehouses of the destination type
+type managerFactory func(destType string) (manager.ManagerI, error)
@@ -0,0 +1,69 @@
+package warehouse
+
+import (
+	"encoding/json"
+
+	"github.com/rudderlabs/rudder-server/warehouse/internal/fixtures"
+	"github.com/rudderlabs/rudder-server/warehouse/internal/model"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// Builder of the uploads of unit tests, with the defaults of the fixtures package building the other models, e.g.
+//
+//	upload := newUploadBuilder().withStatus(model.ExportedData).withStagingFiles(1, 10).build()
+//	stagingFiles := fixtures.NewStagingFileBuilder().WithDestination(upload.SourceID, upload.DestinationID).BuildN(1, 10)
+//
+// Uploads being declared by package warehouse, their builder is in its tests rather than in the fixtures package.
+
+type uploadBuilder struct {
+	upload Upload
+}
+
+func newUploadBuilder() *uploadBuilder {
+	return &uploadBuilder{
+		upload: Upload{
+			ID:              1,
+			Namespace:       fixtures.Namespace,
+			WorkspaceID:     fixtures.WorkspaceID,
+			SourceID:        fixtures.SourceID,
+			DestinationID:   fixtures.DestinationID,
+			DestinationType: warehouseutils.POSTGRES,
+			Status:          model.Waiting,
+			UploadSchema:    warehouseutils.SchemaT{},
+			MergedSchema:    warehouseutils.SchemaT{},
+			Error:           json.RawMessage(`{}`),
+			Metadata:        json.RawMessage(`{}`),
+			FirstEventAt:    fixtures.Time,
+			LastEventAt:     fixtures.Time,
+			Priority:        100,
+			LoadFileType:    warehouseutils.LOAD_FILE_TYPE_CSV,
+		},
+	}
+}
+
+func (b *uploadBuilder) withStatus(status string) *uploadBuilder {
+	b.upload.Status = status
+	return b
+}
+
+func (b *uploadBuilder) withDestination(destType, sourceID, destinationID string) *uploadBuilder {
+	b.upload.DestinationType = destType
+	b.upload.SourceID = sourceID
+	b.upload.DestinationID = destinationID
+	return b
+}
+
+func (b *uploadBuilder) withStagingFiles(startID, endID int64) *uploadBuilder {
+	b.upload.StartStagingFileID = startID
+	b.upload.EndStagingFileID = endID
+	return b
+}
+
+func (b *uploadBuilder) withPriority(priority int) *uploadBuilder {
+	b.upload.Priority = priority
+	return b
+}
+
+func (b *uploadBuilder) build() Upload {
+	return b.upload
+}
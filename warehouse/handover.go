@@ -0,0 +1,166 @@
+package warehouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+	"github.com/rudderlabs/rudder-server/utils/types/workspace"
+	"github.com/rudderlabs/rudder-server/warehouse/internal/model"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// Workspaces moving to another deployment are handed over through /v1/warehouse/handover:
+//   - POST drains the workspaces in the body: their uploads don't start anymore, and the running ones stop after
+//     their current step, for at most Warehouse.handoverTimeout. The response lists the uploads of the workspaces
+//     left pending, by destination and status, for them to be resumed from there where the workspaces are attached.
+//   - DELETE resumes the workspaces in the body, if they were drained
+//
+// With the warehouse running in the same process as the server, the cluster drains it through WorkspaceDrainer
+// instead, along with the routers, as workspaces are detached from the server.
+
+// handoverRequest is the body of requests to the handover endpoint
+type handoverRequest struct {
+	WorkspaceIDs []string `json:"workspaceIds"`
+}
+
+// handoverResponse is the body of responses to drain requests
+type handoverResponse struct {
+	Pending []workspace.PendingMarker `json:"pending"`
+}
+
+func handoverHandler(w http.ResponseWriter, r *http.Request) {
+	pkgLogger.LogRequest(r)
+
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		pkgLogger.Errorf("[WH]: Error reading body: %v", err)
+		http.Error(w, "can't read body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var handoverReq handoverRequest
+	if err := json.Unmarshal(body, &handoverReq); err != nil {
+		pkgLogger.Errorf("[WH]: Error unmarshalling body: %v", err)
+		http.Error(w, "can't unmarshall body", http.StatusBadRequest)
+		return
+	}
+	if len(handoverReq.WorkspaceIDs) == 0 {
+		http.Error(w, "empty workspace ids", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		tenantManager.ResumeWorkspaces(handoverReq.WorkspaceIDs)
+		pkgLogger.Infof("[WH]: Resumed workspaces %v", handoverReq.WorkspaceIDs)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), config.GetDuration("Warehouse.handoverTimeout", 60, time.Second))
+	defer cancel()
+	if running := tenantManager.DrainWarehouseUploads(ctx, handoverReq.WorkspaceIDs); len(running) > 0 {
+		pkgLogger.Warnf("[WH]: Uploads still running after draining workspaces: %v", running)
+	}
+
+	pending, err := pendingUploadMarkers(r.Context(), handoverReq.WorkspaceIDs)
+	if err != nil {
+		pkgLogger.Errorf("[WH]: Error fetching pending uploads of workspaces %v: %v", handoverReq.WorkspaceIDs, err)
+		http.Error(w, "can't fetch pending uploads", http.StatusInternalServerError)
+		return
+	}
+	pkgLogger.Infof("[WH]: Drained workspaces %v with %d pending markers", handoverReq.WorkspaceIDs, len(pending))
+
+	resBody, err := json.Marshal(handoverResponse{Pending: pending})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resBody)
+}
+
+// WorkspaceDrainer drains the uploads of the workspaces detached from the server, for the warehouse master running in
+// the same process
+type WorkspaceDrainer struct{}
+
+// Drain stops uploads of the workspaces from starting and waits for the running ones to stop, until ctx is done,
+// returning the uploads of the workspaces left pending
+func (WorkspaceDrainer) Drain(ctx context.Context, workspaceIDs []string) ([]workspace.PendingMarker, error) {
+	if tenantManager == nil {
+		return nil, nil
+	}
+	if running := tenantManager.DrainWarehouseUploads(ctx, workspaceIDs); len(running) > 0 {
+		pkgLogger.Warnf("[WH]: Uploads still running after draining workspaces: %v", running)
+	}
+	// the pending uploads are listed even if the drain timed out, for them to be handed over
+	pending, err := pendingUploadMarkers(misc.WithoutCancel(ctx), workspaceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("fetching pending uploads: %w", err)
+	}
+	return pending, nil
+}
+
+// Resume lets uploads of the workspaces start again, if they were drained
+func (WorkspaceDrainer) Resume(workspaceIDs []string) {
+	if tenantManager == nil {
+		return
+	}
+	tenantManager.ResumeWorkspaces(workspaceIDs)
+}
+
+// pendingUploadMarkers returns the uploads of the workspaces not exported nor aborted, by destination and status
+func pendingUploadMarkers(ctx context.Context, workspaceIDs []string) ([]workspace.PendingMarker, error) {
+	query := fmt.Sprintf(`
+		SELECT
+		  workspace_id,
+		  destination_id,
+		  status,
+		  COUNT(*)
+		FROM
+		  %[1]s
+		WHERE
+		  workspace_id = ANY($1)
+		  AND status NOT IN ('%[2]s', '%[3]s')
+		GROUP BY
+		  workspace_id,
+		  destination_id,
+		  status
+		ORDER BY
+		  workspace_id,
+		  destination_id,
+		  status;
+	`,
+		warehouseutils.WarehouseUploadsTable,
+		model.ExportedData,
+		model.Aborted,
+	)
+	rows, err := dbHandle.QueryContext(ctx, query, pq.Array(workspaceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("query: %s failed with Error : %w", query, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	pending := []workspace.PendingMarker{}
+	for rows.Next() {
+		marker := workspace.PendingMarker{Component: "warehouse"}
+		if err := rows.Scan(&marker.WorkspaceID, &marker.Ref, &marker.State, &marker.Count); err != nil {
+			return nil, fmt.Errorf("scanning pending uploads: %w", err)
+		}
+		pending = append(pending, marker)
+	}
+	return pending, rows.Err()
+}
@@ -425,9 +425,10 @@ func (wh *HandleT) populateHistoricIdentities(warehouse warehouseutils.Warehouse
 			warehouse:            warehouse,
 			whManager:            whManager,
 			dbHandle:             wh.dbHandle,
-			pgNotifier:           &wh.notifier,
+			pgNotifier:           wh.notifier,
 			destinationValidator: validations.NewDestinationValidator(),
 			stats:                stats.Default,
+			now:                  wh.now,
 		}
 
 		tableUploadsCreated := areTableUploadsCreated(job.upload.ID)
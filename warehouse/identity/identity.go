@@ -385,6 +385,7 @@ func (idr *HandleT) downloadLoadFiles(tableName string) ([]string, error) {
 				UseRudderStorage: idr.Uploader.UseRudderStorage(),
 				WorkspaceID:      idr.Warehouse.Destination.WorkspaceID,
 			}),
+			WorkspaceID: idr.Warehouse.Destination.WorkspaceID,
 		})
 		if err != nil {
 			pkgLogger.Errorf("IDR: Error in creating a file manager for :%s: , %v", idr.Warehouse.Destination.DestinationDefinition.Name, err)
@@ -0,0 +1,94 @@
+// Package faults injects failures into the phases of uploads, for integration tests to cover the paths retrying and
+// recovering from them instead of happy-path syncs only.
+//
+// Faults are armed, for a destination, at a point of an upload phase, the in progress state of the phase as in
+// wh_uploads, e.g. generating_load_files or exporting_data, with the number of times they are to be injected:
+//
+//	Warehouse.faults.<destinationID>.<point>.<phase>: <times>
+//
+// Integration tests arm them at runtime through the /v1/setConfig endpoint of the warehouse, each injection disarming
+// them by one, and nothing being injected unless armed. Faults are only injected if enabled at startup through the
+// Warehouse.faults.enabled setting, off by default, for them not to be armed against production warehouses.
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// Point is where a fault is injected
+type Point string
+
+const (
+	// ObjectStorage fails operations on the object storage, as a 500 from it would
+	ObjectStorage Point = "objectStorage"
+	// WarehouseConnection fails operations on the warehouse, as the connection to it dropping would
+	WarehouseConnection Point = "warehouseConnection"
+	// NotifierTimeout times out the jobs published to the notifier, as workers not picking them up would
+	NotifierTimeout Point = "notifierTimeout"
+)
+
+// ErrInjected is matched by the errors of the faults injected
+var ErrInjected = errors.New("injected fault")
+
+// Error is the error of a fault injected at the point of the phase
+type Error struct {
+	Point Point
+	Phase string
+}
+
+func (e *Error) Error() string {
+	var cause string
+	switch e.Point {
+	case ObjectStorage:
+		cause = "object storage responded with 500 Internal Server Error"
+	case WarehouseConnection:
+		cause = "connection to the warehouse dropped"
+	case NotifierTimeout:
+		cause = "notifier job timed out"
+	default:
+		cause = string(e.Point)
+	}
+	return fmt.Sprintf("%s at %s: %s", ErrInjected, e.Phase, cause)
+}
+
+func (*Error) Is(target error) bool {
+	return target == ErrInjected
+}
+
+// Key returns the config key arming the fault of the destination at the point of the phase
+func Key(destinationID string, point Point, phase string) string {
+	return fmt.Sprintf("Warehouse.faults.%s.%s.%s", destinationID, point, phase)
+}
+
+var (
+	mu      sync.Mutex
+	enabled bool
+)
+
+// Init loads the config of the package, the setting enabling faults not being reloadable
+func Init() {
+	config.RegisterBoolConfigVariable(false, &enabled, false, "Warehouse.faults.enabled")
+}
+
+// Inject returns the error of the fault of the destination at the point of the phase if enabled and armed, disarming
+// it by one, nil otherwise
+func Inject(destinationID string, point Point, phase string) error {
+	if !enabled {
+		return nil
+	}
+	key := Key(destinationID, point, phase)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	times := config.GetInt(key, 0)
+	if times <= 0 {
+		return nil
+	}
+	config.Set(key, times-1)
+	return &Error{Point: point, Phase: phase}
+}
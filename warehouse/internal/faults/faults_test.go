@@ -0,0 +1,64 @@
+package faults_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/warehouse/internal/faults"
+)
+
+func TestInject(t *testing.T) {
+	config.Reset()
+	t.Cleanup(func() {
+		config.Reset()
+		faults.Init()
+	})
+
+	const (
+		destinationID = "destination-1"
+		phase         = "exporting_data"
+	)
+
+	t.Run("not enabled", func(t *testing.T) {
+		faults.Init()
+		config.Set(faults.Key(destinationID, faults.WarehouseConnection, phase), 1)
+
+		require.NoError(t, faults.Inject(destinationID, faults.WarehouseConnection, phase))
+		config.Set(faults.Key(destinationID, faults.WarehouseConnection, phase), 0)
+	})
+
+	config.Set("Warehouse.faults.enabled", true)
+	faults.Init()
+
+	t.Run("not armed", func(t *testing.T) {
+		require.NoError(t, faults.Inject(destinationID, faults.WarehouseConnection, phase))
+	})
+
+	t.Run("armed", func(t *testing.T) {
+		config.Set(faults.Key(destinationID, faults.WarehouseConnection, phase), 2)
+
+		for i := 0; i < 2; i++ {
+			err := faults.Inject(destinationID, faults.WarehouseConnection, phase)
+			require.ErrorIs(t, err, faults.ErrInjected)
+
+			var faultErr *faults.Error
+			require.True(t, errors.As(err, &faultErr))
+			require.Equal(t, faults.WarehouseConnection, faultErr.Point)
+			require.Equal(t, phase, faultErr.Phase)
+			require.EqualError(t, err, "injected fault at exporting_data: connection to the warehouse dropped")
+		}
+		require.NoError(t, faults.Inject(destinationID, faults.WarehouseConnection, phase))
+	})
+
+	t.Run("armed for other destinations, points or phases", func(t *testing.T) {
+		config.Set(faults.Key(destinationID, faults.ObjectStorage, "generating_load_files"), 1)
+
+		require.NoError(t, faults.Inject("destination-2", faults.ObjectStorage, "generating_load_files"))
+		require.NoError(t, faults.Inject(destinationID, faults.NotifierTimeout, "generating_load_files"))
+		require.NoError(t, faults.Inject(destinationID, faults.ObjectStorage, phase))
+		require.ErrorIs(t, faults.Inject(destinationID, faults.ObjectStorage, "generating_load_files"), faults.ErrInjected)
+	})
+}
@@ -0,0 +1,87 @@
+// Package fixtures builds the models of the warehouse for unit tests, defaulting the fields tests don't care about,
+// for them to set only the ones they assert on, e.g.
+//
+//	stagingFiles := fixtures.NewStagingFileBuilder().WithDestination(sourceID, destinationID).BuildN(1, 10)
+//
+// Uploads are declared by package warehouse, their builder being in its tests, along with the same defaults.
+package fixtures
+
+import (
+	"time"
+
+	"github.com/rudderlabs/rudder-server/warehouse/internal/model"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// Defaults of the models built
+const (
+	WorkspaceID   = "test-workspace-id"
+	SourceID      = "test-source-id"
+	DestinationID = "test-destination-id"
+	Namespace     = "test_namespace"
+)
+
+// Time is the default time of the models built, e.g. of their events
+var Time = time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+// StagingFileBuilder builds staging files
+type StagingFileBuilder struct {
+	stagingFile model.StagingFile
+}
+
+// NewStagingFileBuilder returns a builder of waiting staging files of the default source and destination
+func NewStagingFileBuilder() *StagingFileBuilder {
+	return &StagingFileBuilder{
+		stagingFile: model.StagingFile{
+			ID:            1,
+			WorkspaceID:   WorkspaceID,
+			Location:      "rudder-warehouse-staging-logs/test-source-id/2022-12-01/1.json.gz",
+			SourceID:      SourceID,
+			DestinationID: DestinationID,
+			Status:        warehouseutils.StagingFileWaitingState,
+			FirstEventAt:  Time,
+			LastEventAt:   Time,
+			TotalEvents:   100,
+			CreatedAt:     Time,
+			UpdatedAt:     Time,
+		},
+	}
+}
+
+func (b *StagingFileBuilder) WithID(id int64) *StagingFileBuilder {
+	b.stagingFile.ID = id
+	return b
+}
+
+func (b *StagingFileBuilder) WithStatus(status string) *StagingFileBuilder {
+	b.stagingFile.Status = status
+	return b
+}
+
+func (b *StagingFileBuilder) WithDestination(sourceID, destinationID string) *StagingFileBuilder {
+	b.stagingFile.SourceID = sourceID
+	b.stagingFile.DestinationID = destinationID
+	return b
+}
+
+func (b *StagingFileBuilder) WithTotalEvents(totalEvents int) *StagingFileBuilder {
+	b.stagingFile.TotalEvents = totalEvents
+	return b
+}
+
+func (b *StagingFileBuilder) Build() model.StagingFile {
+	return b.stagingFile
+}
+
+// BuildN builds the staging files with the ids in [startID, endID], created a second apart
+func (b *StagingFileBuilder) BuildN(startID, endID int64) []model.StagingFile {
+	stagingFiles := make([]model.StagingFile, 0, endID-startID+1)
+	for id := startID; id <= endID; id++ {
+		stagingFile := b.stagingFile
+		stagingFile.ID = id
+		stagingFile.CreatedAt = b.stagingFile.CreatedAt.Add(time.Duration(id-startID) * time.Second)
+		stagingFile.UpdatedAt = stagingFile.CreatedAt
+		stagingFiles = append(stagingFiles, stagingFile)
+	}
+	return stagingFiles
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/timeutil"
 	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
-	"golang.org/x/sync/errgroup"
 )
 
 // InitWarehouseJobsAPI Initializes AsyncJobWh structure with appropriate variabless
@@ -32,6 +31,14 @@ func InitWarehouseJobsAPI(
 	}
 }
 
+// InitWarehouseJobsStatusAPI returns the async jobs API serving the status of the jobs only, for degraded mode, where
+// jobs aren't run
+func InitWarehouseJobsStatusAPI(ctx context.Context, dbHandle *sql.DB) *AsyncJobWhT {
+	a := InitWarehouseJobsAPI(ctx, dbHandle, nil)
+	a.enabled = true
+	return a
+}
+
 func WithConfig(a *AsyncJobWhT, config *config.Config) {
 	a.MaxBatchSizeToProcess = config.GetInt("Warehouse.jobs.maxBatchSizeToProcess", 10)
 	a.MaxCleanUpRetries = config.GetInt("Warehouse.jobs.maxCleanUpRetries", 5)
@@ -115,13 +122,10 @@ func (a *AsyncJobWhT) addJobsToDB(ctx context.Context, payload *AsyncJobPayloadT
 // 1. Scan the database for entries into wh_async_jobs
 // 2. Publish data to pg_notifier queue
 // 3. Move any executing jobs to waiting
-func (a *AsyncJobWhT) InitAsyncJobRunner() error {
+// It runs until ctx is done, e.g. once the master it runs on is demoted.
+func (a *AsyncJobWhT) InitAsyncJobRunner(ctx context.Context) error {
 	// Start the asyncJobRunner
 	a.logger.Info("[WH-Jobs]: Initializing async job runner")
-	ctx, cancel := context.WithCancel(a.context)
-	defer cancel()
-	g, ctx := errgroup.WithContext(ctx)
-	a.context = ctx
 	var err error
 	for retry := 0; retry < a.MaxCleanUpRetries; retry++ {
 		err = a.cleanUpAsyncTable(ctx)
@@ -136,13 +140,7 @@ func (a *AsyncJobWhT) InitAsyncJobRunner() error {
 		a.logger.Errorf("[WH-Jobs]: unable to cleanup asynctable with error %s", err.Error())
 		return err
 	}
-	if a.enabled {
-		g.Go(func() error {
-			return a.startAsyncJobRunner(ctx)
-		})
-		g.Wait()
-	}
-	return errors.New("unable to enable warehouse Async Job")
+	return a.startAsyncJobRunner(ctx)
 }
 
 func (a *AsyncJobWhT) cleanUpAsyncTable(ctx context.Context) error {
@@ -1,3 +1,5 @@
+//go:generate mockgen -destination=../../mocks/warehouse/manager/mock_manager.go -package mock_manager github.com/rudderlabs/rudder-server/warehouse/manager ManagerI,WarehouseOperations
+
 package manager
 
 import (
@@ -48,6 +50,13 @@ type WarehouseDelete interface {
 	DeleteBy(tableName []string, params warehouseutils.DeleteByParams) error
 }
 
+// DDLPreviewer is implemented by the managers able to return the DDL statements creating the schema and the tables
+// of a warehouse, without connecting to it. Statements altering tables, staging loads or deduplicating rows aren't
+// previewed.
+type DDLPreviewer interface {
+	PreviewDDL(warehouse warehouseutils.Warehouse, schema warehouseutils.SchemaT) []string
+}
+
 type WarehouseOperations interface {
 	ManagerI
 	WarehouseDelete
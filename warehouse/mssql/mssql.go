@@ -20,6 +20,7 @@ import (
 	mssql "github.com/denisenkom/go-mssqldb"
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/services/filemanager"
+	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/warehouse/client"
@@ -145,7 +146,7 @@ func Connect(cred CredentialsT) (*sql.DB, error) {
 
 	var db *sql.DB
 
-	if db, err = sql.Open("sqlserver", connUrl.String()); err != nil {
+	if db, err = stats.OpenDB(stats.Default, "sqlserver", connUrl.String(), stats.Tags{"module": "warehouse", "destType": warehouseutils.MSSQL}); err != nil {
 		return nil, fmt.Errorf("opening connection to mssql server: %w", err)
 	}
 
@@ -198,6 +199,7 @@ func (ms *HandleT) DownloadLoadFiles(tableName string) ([]string, error) {
 			UseRudderStorage: ms.Uploader.UseRudderStorage(),
 			WorkspaceID:      ms.Warehouse.Destination.WorkspaceID,
 		}),
+		WorkspaceID: ms.Warehouse.Destination.WorkspaceID,
 	})
 	if err != nil {
 		pkgLogger.Errorf("MS: Error in setting up a downloader for destinationID : %s Error : %v", ms.Warehouse.Destination.ID, err)
@@ -647,10 +649,14 @@ func (ms *HandleT) loadUserTables() (errorMap map[string]error) {
 	return
 }
 
-func (ms *HandleT) CreateSchema() (err error) {
-	sqlStatement := fmt.Sprintf(`IF NOT EXISTS ( SELECT  * FROM  sys.schemas WHERE   name = N'%s' )
+func (ms *HandleT) createSchemaSQL() string {
+	return fmt.Sprintf(`IF NOT EXISTS ( SELECT  * FROM  sys.schemas WHERE   name = N'%s' )
     EXEC('CREATE SCHEMA [%s]');
 `, ms.Namespace, ms.Namespace)
+}
+
+func (ms *HandleT) CreateSchema() (err error) {
+	sqlStatement := ms.createSchemaSQL()
 	pkgLogger.Infof("MSSQL: Creating schema name in mssql for MS:%s : %v", ms.Warehouse.Destination.ID, sqlStatement)
 	_, err = ms.Db.Exec(sqlStatement)
 	if err == io.EOF {
@@ -667,9 +673,25 @@ func (ms *HandleT) dropStagingTable(stagingTableName string) {
 	}
 }
 
-func (ms *HandleT) createTable(name string, columns map[string]string) (err error) {
-	sqlStatement := fmt.Sprintf(`IF  NOT EXISTS (SELECT 1 FROM sys.objects WHERE object_id = OBJECT_ID(N'%[1]s') AND type = N'U')
+func (ms *HandleT) createTableSQL(name string, columns map[string]string) string {
+	return fmt.Sprintf(`IF  NOT EXISTS (SELECT 1 FROM sys.objects WHERE object_id = OBJECT_ID(N'%[1]s') AND type = N'U')
 	CREATE TABLE %[1]s ( %v )`, name, ColumnsWithDataTypes(columns, ""))
+}
+
+// PreviewDDL returns the statements creating the schema and the tables of the warehouse, without running them
+func (ms *HandleT) PreviewDDL(warehouse warehouseutils.Warehouse, schema warehouseutils.SchemaT) []string {
+	ms.Warehouse = warehouse
+	ms.Namespace = warehouse.Namespace
+
+	statements := []string{ms.createSchemaSQL()}
+	for _, tableName := range warehouseutils.SortedTableNames(schema) {
+		statements = append(statements, ms.createTableSQL(ms.Namespace+"."+tableName, schema[tableName]))
+	}
+	return statements
+}
+
+func (ms *HandleT) createTable(name string, columns map[string]string) (err error) {
+	sqlStatement := ms.createTableSQL(name, columns)
 
 	pkgLogger.Infof("MS: Creating table in mssql for MS:%s : %v", ms.Warehouse.Destination.ID, sqlStatement)
 	_, err = ms.Db.Exec(sqlStatement)
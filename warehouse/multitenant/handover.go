@@ -0,0 +1,94 @@
+package multitenant
+
+import (
+	"context"
+	"time"
+)
+
+// drainPollInterval is how often the uploads of the drained workspaces are checked for having stopped
+var drainPollInterval = 100 * time.Millisecond
+
+// DrainWarehouseUploads stops uploads of the workspaces from starting and waits for the running ones to stop, until
+// ctx is done, returning the number of uploads of each workspace still running. Running uploads stop at the next
+// step of theirs, for them to be resumed from there once their workspace is attached elsewhere.
+func (m *Manager) DrainWarehouseUploads(ctx context.Context, workspaceIDs []string) map[string]int {
+	m.init()
+
+	m.quotaMu.Lock()
+	for _, workspaceID := range workspaceIDs {
+		m.drainingWorkspaces[workspaceID] = struct{}{}
+	}
+	m.quotaMu.Unlock()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		running := m.runningWarehouseUploads(workspaceIDs)
+		if len(running) == 0 || ctx.Err() != nil {
+			return running
+		}
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+}
+
+// ResumeWorkspaces lets uploads of the workspaces start again, if they were drained
+func (m *Manager) ResumeWorkspaces(workspaceIDs []string) {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	for _, workspaceID := range workspaceIDs {
+		delete(m.drainingWorkspaces, workspaceID)
+	}
+}
+
+// DrainingWorkspace returns whether the workspace is drained
+func (m *Manager) DrainingWorkspace(workspaceID string) bool {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	_, ok := m.drainingWorkspaces[workspaceID]
+	return ok
+}
+
+// DrainingWorkspaces returns the workspaces drained
+func (m *Manager) DrainingWorkspaces() []string {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	workspaceIDs := make([]string, 0, len(m.drainingWorkspaces))
+	for workspaceID := range m.drainingWorkspaces {
+		workspaceIDs = append(workspaceIDs, workspaceID)
+	}
+	return workspaceIDs
+}
+
+func (m *Manager) runningWarehouseUploads(workspaceIDs []string) map[string]int {
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	running := make(map[string]int)
+	for _, workspaceID := range workspaceIDs {
+		if uploads := m.warehouseUploads[workspaceID]; uploads > 0 {
+			running[workspaceID] = uploads
+		}
+	}
+	return running
+}
+
+// PausedWorkspaces returns the workspaces paused by the control plane, whose uploads aren't scheduled
+func (m *Manager) PausedWorkspaces() []string {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	workspaceIDs := make([]string, 0, len(m.pausedWorkspaces))
+	for workspaceID := range m.pausedWorkspaces {
+		workspaceIDs = append(workspaceIDs, workspaceID)
+	}
+	return workspaceIDs
+}
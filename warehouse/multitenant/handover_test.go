@@ -0,0 +1,42 @@
+package multitenant_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/warehouse/multitenant"
+)
+
+func TestDrainWarehouseUploads(t *testing.T) {
+	m := multitenant.Manager{}
+
+	require.True(t, m.AcquireWarehouseUpload("workspaceA"))
+	require.True(t, m.AcquireWarehouseUpload("workspaceB"))
+
+	t.Run("running uploads are waited for", func(t *testing.T) {
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			m.ReleaseWarehouseUpload("workspaceA")
+		}()
+		require.Empty(t, m.DrainWarehouseUploads(context.Background(), []string{"workspaceA"}))
+		require.True(t, m.DrainingWorkspace("workspaceA"))
+		require.False(t, m.AcquireWarehouseUpload("workspaceA"), "uploads of drained workspaces don't start")
+		require.Equal(t, []string{"workspaceA"}, m.DrainingWorkspaces())
+	})
+
+	t.Run("uploads still running are returned once ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		require.Equal(t, map[string]int{"workspaceB": 1}, m.DrainWarehouseUploads(ctx, []string{"workspaceB"}))
+	})
+
+	t.Run("resumed workspaces", func(t *testing.T) {
+		m.ResumeWorkspaces([]string{"workspaceA", "workspaceB"})
+		require.False(t, m.DrainingWorkspace("workspaceA"))
+		require.Empty(t, m.DrainingWorkspaces())
+		require.True(t, m.AcquireWarehouseUpload("workspaceA"))
+	})
+}
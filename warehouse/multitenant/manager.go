@@ -22,6 +22,16 @@ type Manager struct {
 	sourceIDToWorkspaceID map[string]string
 	excludeWorkspaceIDMap map[string]struct{}
 
+	quotaMu          sync.Mutex
+	quotas           map[string]backendconfig.Quotas
+	gatewayEvents    map[string]*eventsBucket
+	processorEvents  map[string]*eventsBucket
+	warehouseUploads map[string]int
+	// drainingWorkspaces are the workspaces whose uploads don't start, being handed over
+	drainingWorkspaces map[string]struct{}
+	// pausedWorkspaces are the workspaces paused by the control plane, through the paused flag of their settings
+	pausedWorkspaces map[string]struct{}
+
 	ready     chan struct{}
 	sourceMu  sync.Mutex
 	readyOnce sync.Once
@@ -36,6 +46,12 @@ func (m *Manager) init() {
 
 		m.sourceIDToWorkspaceID = make(map[string]string)
 		m.excludeWorkspaceIDMap = make(map[string]struct{})
+		m.quotas = make(map[string]backendconfig.Quotas)
+		m.gatewayEvents = make(map[string]*eventsBucket)
+		m.processorEvents = make(map[string]*eventsBucket)
+		m.warehouseUploads = make(map[string]int)
+		m.drainingWorkspaces = make(map[string]struct{})
+		m.pausedWorkspaces = make(map[string]struct{})
 
 		for _, workspaceID := range m.DegradedWorkspaceIDs {
 			m.excludeWorkspaceIDMap[workspaceID] = struct{}{}
@@ -58,6 +74,7 @@ func (m *Manager) Run(ctx context.Context) {
 			}
 		}
 		m.sourceMu.Unlock()
+		m.updateQuotas(config)
 		m.readyOnce.Do(func() {
 			close(m.ready)
 		})
@@ -0,0 +1,252 @@
+package multitenant
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// Workspaces are limited by the quotas in their settings, sourced from the control plane, each of them being enforced
+// where the resource is used:
+//   - eventsPerSecond by the gateway, through AllowEvents, accepting bursts of up to Multitenant.quotas.burstWindow
+//     worth of events, and by the processor, through EventsQuotaExceeded and ConsumeProcessedEvents, pacing the
+//     pickup of the events already accepted, e.g. the backlog of a workspace whose quota was lowered
+//   - concurrentWarehouseUploads by the warehouse upload allocator, through AcquireWarehouseUpload
+//   - routerThroughputShare by the fair pickup of routers, through RouterThroughputShare
+//
+// The events quotas are enforced by each gateway and processor on its own, rather than globally, so the quota of a
+// workspace is split evenly among the Multitenant.quotas.gatewayReplicas gateways and the
+// Multitenant.quotas.processorReplicas processors serving it.
+//
+// Requests to exceed a quota are counted in multitenant_quota_exceeded, tagged with the workspace and the quota.
+
+const (
+	quotaEventsPerSecond            = "eventsPerSecond"
+	quotaConcurrentWarehouseUploads = "concurrentWarehouseUploads"
+)
+
+var (
+	quotaBurstWindow       time.Duration
+	quotaGatewayReplicas   int
+	quotaProcessorReplicas int
+)
+
+func init() {
+	config.RegisterDurationConfigVariable(10, &quotaBurstWindow, false, time.Second, "Multitenant.quotas.burstWindow")
+	config.RegisterIntConfigVariable(1, &quotaGatewayReplicas, true, 1, "Multitenant.quotas.gatewayReplicas")
+	config.RegisterIntConfigVariable(1, &quotaProcessorReplicas, true, 1, "Multitenant.quotas.processorReplicas")
+}
+
+// eventsBucket is a token bucket of the events of a workspace which can go into debt, for a batch larger than the
+// burst to be accepted as long as the quota isn't exceeded already, the batches following it being rejected until the
+// debt is paid off
+type eventsBucket struct {
+	eventsPerSecond float64 // the quota of the workspace, before being split among replicas
+	replicas        int
+	rate            float64
+	burst           float64
+	tokens          float64
+	last            time.Time
+}
+
+func newEventsBucket(eventsPerSecond float64, replicas int, now time.Time) *eventsBucket {
+	rate := eventsPerSecond / float64(misc.MaxInt(replicas, 1))
+	burst := math.Max(rate*quotaBurstWindow.Seconds(), 1)
+	return &eventsBucket{
+		eventsPerSecond: eventsPerSecond,
+		replicas:        replicas,
+		rate:            rate,
+		burst:           burst,
+		tokens:          burst,
+		last:            now,
+	}
+}
+
+// exceeded returns whether the events consumed exceed the quota, not leaving a single event to be consumed, refilling
+// the bucket first
+func (b *eventsBucket) exceeded(now time.Time) bool {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+	return b.tokens < 1
+}
+
+func (b *eventsBucket) consume(now time.Time, events int) {
+	b.exceeded(now)
+	b.tokens -= float64(events)
+}
+
+// eventsBucket returns the bucket of the workspace among buckets, creating it if missing or created with a different
+// quota or number of replicas, nil if the workspace has no events quota. Must be called with quotaMu held.
+func (m *Manager) eventsBucket(buckets map[string]*eventsBucket, workspaceID string, replicas int) *eventsBucket {
+	eventsPerSecond := m.quotas[workspaceID].EventsPerSecond
+	if eventsPerSecond <= 0 {
+		delete(buckets, workspaceID)
+		return nil
+	}
+	b, ok := buckets[workspaceID]
+	if !ok || b.eventsPerSecond != eventsPerSecond || b.replicas != replicas {
+		b = newEventsBucket(eventsPerSecond, replicas, time.Now())
+		buckets[workspaceID] = b
+	}
+	return b
+}
+
+// updateQuotas replaces the quotas of the workspaces, keeping the events buckets of the ones whose quota didn't change,
+// along with the workspaces paused
+func (m *Manager) updateQuotas(config map[string]backendconfig.ConfigT) {
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+
+	quotas := make(map[string]backendconfig.Quotas, len(config))
+	for workspaceID := range config {
+		quotas[workspaceID] = config[workspaceID].Settings.Quotas
+	}
+	for _, buckets := range []map[string]*eventsBucket{m.gatewayEvents, m.processorEvents} {
+		for workspaceID, b := range buckets {
+			if b.eventsPerSecond != quotas[workspaceID].EventsPerSecond {
+				delete(buckets, workspaceID)
+			}
+		}
+	}
+	m.quotas = quotas
+
+	paused := make(map[string]struct{})
+	for workspaceID := range config {
+		if config[workspaceID].Settings.Paused {
+			paused[workspaceID] = struct{}{}
+		}
+	}
+	m.pausedWorkspaces = paused
+}
+
+// Quotas returns the quotas of the workspace
+func (m *Manager) Quotas(workspaceID string) backendconfig.Quotas {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	return m.quotas[workspaceID]
+}
+
+// AllowEvents returns whether the events quota of the workspace isn't exceeded, consuming the events from it if so.
+// Batches larger than the burst are accepted as long as the quota isn't exceeded, the following ones being rejected
+// until the quota catches up.
+func (m *Manager) AllowEvents(workspaceID string, events int) bool {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	b := m.eventsBucket(m.gatewayEvents, workspaceID, quotaGatewayReplicas)
+	if b == nil {
+		return true
+	}
+	now := time.Now()
+	if b.exceeded(now) {
+		quotaExceeded(workspaceID, quotaEventsPerSecond)
+		return false
+	}
+	b.consume(now, events)
+	return true
+}
+
+// EventsQuotaExceeded returns the workspaces whose events processed exceed their events quota, for the processor to
+// leave their jobs unprocessed until the quota catches up
+func (m *Manager) EventsQuotaExceeded() []string {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	var workspaceIDs []string
+	now := time.Now()
+	for workspaceID := range m.quotas {
+		if b := m.eventsBucket(m.processorEvents, workspaceID, quotaProcessorReplicas); b != nil && b.exceeded(now) {
+			quotaExceeded(workspaceID, quotaEventsPerSecond)
+			workspaceIDs = append(workspaceIDs, workspaceID)
+		}
+	}
+	sort.Strings(workspaceIDs)
+	return workspaceIDs
+}
+
+// ConsumeProcessedEvents consumes the events picked up by the processor from the events quota of the workspace
+func (m *Manager) ConsumeProcessedEvents(workspaceID string, events int) {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	if b := m.eventsBucket(m.processorEvents, workspaceID, quotaProcessorReplicas); b != nil {
+		b.consume(time.Now(), events)
+	}
+}
+
+// AcquireWarehouseUpload returns whether an upload of the workspace can start within its concurrentWarehouseUploads
+// quota, and the workspace isn't drained, counting it as running if so until ReleaseWarehouseUpload
+func (m *Manager) AcquireWarehouseUpload(workspaceID string) bool {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	if _, ok := m.drainingWorkspaces[workspaceID]; ok {
+		return false
+	}
+	if _, ok := m.pausedWorkspaces[workspaceID]; ok {
+		return false
+	}
+	if limit := m.quotas[workspaceID].ConcurrentWarehouseUploads; limit > 0 && m.warehouseUploads[workspaceID] >= limit {
+		quotaExceeded(workspaceID, quotaConcurrentWarehouseUploads)
+		return false
+	}
+	m.warehouseUploads[workspaceID]++
+	return true
+}
+
+// ReleaseWarehouseUpload counts an upload of the workspace acquired with AcquireWarehouseUpload as done
+func (m *Manager) ReleaseWarehouseUpload(workspaceID string) {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	if m.warehouseUploads[workspaceID] <= 1 {
+		delete(m.warehouseUploads, workspaceID)
+		return
+	}
+	m.warehouseUploads[workspaceID]--
+}
+
+// WarehouseUploadsAtQuota returns the workspaces running as many uploads as their concurrentWarehouseUploads quota
+func (m *Manager) WarehouseUploadsAtQuota() []string {
+	m.init()
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+	var workspaceIDs []string
+	for workspaceID, uploads := range m.warehouseUploads {
+		if limit := m.quotas[workspaceID].ConcurrentWarehouseUploads; limit > 0 && uploads >= limit {
+			workspaceIDs = append(workspaceIDs, workspaceID)
+		}
+	}
+	return workspaceIDs
+}
+
+// RouterThroughputShare returns the share of the jobs picked up by routers the workspace is limited to, 1 if it isn't
+func (m *Manager) RouterThroughputShare(workspaceID string) float64 {
+	share := m.Quotas(workspaceID).RouterThroughputShare
+	if share <= 0 || share > 1 {
+		return 1
+	}
+	return share
+}
+
+func quotaExceeded(workspaceID, quota string) {
+	stats.Default.NewTaggedStat("multitenant_quota_exceeded", stats.CountType, stats.Tags{
+		"workspaceId": workspaceID,
+		"quota":       quota,
+	}).Increment()
+}
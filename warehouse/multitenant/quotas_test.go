@@ -0,0 +1,90 @@
+package multitenant_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/warehouse/multitenant"
+)
+
+func TestQuotas(t *testing.T) {
+	m := multitenant.Manager{
+		BackendConfig: &mockBackendConfig{
+			config: map[string]backendconfig.ConfigT{
+				"workspaceA": {
+					WorkspaceID: "workspaceA",
+					Sources:     []backendconfig.SourceT{{ID: "source1"}},
+					Settings: backendconfig.Settings{Quotas: backendconfig.Quotas{
+						EventsPerSecond:            1,
+						ConcurrentWarehouseUploads: 1,
+						RouterThroughputShare:      0.25,
+					}},
+				},
+				"workspaceB": {WorkspaceID: "workspaceB"},
+				"workspaceC": {WorkspaceID: "workspaceC", Settings: backendconfig.Settings{Paused: true}},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g := errgroup.Group{}
+	g.Go(func() error {
+		m.Run(ctx)
+		return nil
+	})
+	defer func() {
+		cancel()
+		require.NoError(t, g.Wait())
+	}()
+	// waiting for the config to be received
+	_, err := m.SourceToWorkspace(ctx, "source1")
+	require.NoError(t, err)
+
+	t.Run("events per second", func(t *testing.T) {
+		require.True(t, m.AllowEvents("workspaceA", 10), "bursts of up to Multitenant.quotas.burstWindow worth of events are allowed")
+		require.False(t, m.AllowEvents("workspaceA", 1))
+		require.True(t, m.AllowEvents("workspaceB", 1000), "workspaces without quotas are unlimited")
+	})
+
+	t.Run("events per second of batches larger than the burst", func(t *testing.T) {
+		config.Set("Multitenant.quotas.gatewayReplicas", 2)
+		defer config.Set("Multitenant.quotas.gatewayReplicas", 1)
+
+		require.True(t, m.AllowEvents("workspaceA", 100), "batches are accepted as long as the quota isn't exceeded")
+		require.False(t, m.AllowEvents("workspaceA", 1), "until the quota catches up with the batch")
+	})
+
+	t.Run("processed events per second", func(t *testing.T) {
+		require.Empty(t, m.EventsQuotaExceeded())
+		m.ConsumeProcessedEvents("workspaceA", 20)
+		m.ConsumeProcessedEvents("workspaceB", 1000)
+		require.Equal(t, []string{"workspaceA"}, m.EventsQuotaExceeded())
+	})
+
+	t.Run("concurrent warehouse uploads", func(t *testing.T) {
+		require.True(t, m.AcquireWarehouseUpload("workspaceA"))
+		require.False(t, m.AcquireWarehouseUpload("workspaceA"))
+		require.Equal(t, []string{"workspaceA"}, m.WarehouseUploadsAtQuota())
+		require.True(t, m.AcquireWarehouseUpload("workspaceB"))
+		require.True(t, m.AcquireWarehouseUpload("workspaceB"))
+
+		m.ReleaseWarehouseUpload("workspaceA")
+		require.Empty(t, m.WarehouseUploadsAtQuota())
+		require.True(t, m.AcquireWarehouseUpload("workspaceA"))
+	})
+
+	t.Run("router throughput share", func(t *testing.T) {
+		require.Equal(t, 0.25, m.RouterThroughputShare("workspaceA"))
+		require.Equal(t, 1.0, m.RouterThroughputShare("workspaceB"))
+	})
+
+	t.Run("paused workspaces", func(t *testing.T) {
+		require.Equal(t, []string{"workspaceC"}, m.PausedWorkspaces())
+		require.False(t, m.AcquireWarehouseUpload("workspaceC"), "uploads of paused workspaces don't start")
+	})
+}
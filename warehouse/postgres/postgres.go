@@ -176,7 +176,7 @@ func Connect(cred CredentialsT) (*sql.DB, error) {
 		return db, nil
 	}
 
-	if db, err = sql.Open("postgres", dsn.String()); err != nil {
+	if db, err = stats.OpenDB(stats.Default, "postgres", dsn.String(), stats.Tags{"module": "warehouse", "destType": warehouseutils.POSTGRES}); err != nil {
 		return nil, fmt.Errorf("opening connection to postgres: %w", err)
 	}
 
@@ -229,6 +229,7 @@ func (pg *Handle) DownloadLoadFiles(tableName string) ([]string, error) {
 			UseRudderStorage: pg.Uploader.UseRudderStorage(),
 			WorkspaceID:      pg.Warehouse.Destination.WorkspaceID,
 		}),
+		WorkspaceID: pg.Warehouse.Destination.WorkspaceID,
 	})
 	if err != nil {
 		pg.logger.Errorf("PG: Error in setting up a downloader for destinationID : %s Error : %v", pg.Warehouse.Destination.ID, err)
@@ -668,7 +669,7 @@ func (pg *Handle) CreateSchema() (err error) {
 		pg.logger.Infof("PG: Skipping creating schema: %s since it already exists", pg.Namespace)
 		return
 	}
-	sqlStatement := fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %q`, pg.Namespace)
+	sqlStatement := pg.createSchemaSQL()
 	pg.logger.Infof("PG: Creating schema name in postgres for PG:%s : %v", pg.Warehouse.Destination.ID, sqlStatement)
 	_, err = pg.DB.Exec(sqlStatement)
 	return
@@ -682,8 +683,28 @@ func (pg *Handle) dropStagingTable(stagingTableName string) {
 	}
 }
 
+func (pg *Handle) createSchemaSQL() string {
+	return fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %q`, pg.Namespace)
+}
+
+func (pg *Handle) createTableSQL(name string, columns map[string]string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%[1]s"."%[2]s" ( %v )`, pg.Namespace, name, ColumnsWithDataTypes(columns, ""))
+}
+
+// PreviewDDL returns the statements creating the schema and the tables of the warehouse, without running them
+func (pg *Handle) PreviewDDL(warehouse warehouseutils.Warehouse, schema warehouseutils.SchemaT) []string {
+	pg.Warehouse = warehouse
+	pg.Namespace = warehouse.Namespace
+
+	statements := []string{pg.createSchemaSQL()}
+	for _, tableName := range warehouseutils.SortedTableNames(schema) {
+		statements = append(statements, pg.createTableSQL(tableName, schema[tableName]))
+	}
+	return statements
+}
+
 func (pg *Handle) createTable(name string, columns map[string]string) (err error) {
-	sqlStatement := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%[1]s"."%[2]s" ( %v )`, pg.Namespace, name, ColumnsWithDataTypes(columns, ""))
+	sqlStatement := pg.createTableSQL(name, columns)
 	pg.logger.Infof("PG: Creating table in postgres for PG:%s : %v", pg.Warehouse.Destination.ID, sqlStatement)
 	_, err = pg.DB.Exec(sqlStatement)
 	return
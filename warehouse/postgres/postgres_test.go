@@ -10,6 +10,7 @@ import (
 
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	"github.com/rudderlabs/rudder-server/warehouse/client"
+	"github.com/rudderlabs/rudder-server/warehouse/internal/faults"
 	"github.com/rudderlabs/rudder-server/warehouse/postgres"
 	"github.com/rudderlabs/rudder-server/warehouse/testhelper"
 	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
@@ -55,6 +56,7 @@ func TestIntegrationPostgres(t *testing.T) {
 		warehouseEventsMap    testhelper.EventsCountMap
 		asyncJob              bool
 		tables                []string
+		faults                []testhelper.Fault
 	}{
 		{
 			name:          "Upload Job",
@@ -63,6 +65,10 @@ func TestIntegrationPostgres(t *testing.T) {
 			tables:        []string{"identifies", "users", "tracks", "product_track", "pages", "screens", "aliases", "groups"},
 			sourceID:      "1wRvLmEnMOOxSQD9pwaZhyCqXRE",
 			destinationID: "216ZvbavR21Um6eGKQCagZHqLGZ",
+			faults: []testhelper.Fault{
+				{Point: faults.NotifierTimeout, Phase: "generating_load_files"},
+				{Point: faults.WarehouseConnection, Phase: "exporting_data"},
+			},
 		},
 		{
 			name:                  "Async Job",
@@ -98,12 +104,14 @@ func TestIntegrationPostgres(t *testing.T) {
 				TableUploadsEventsMap: tc.tableUploadsEventsMap,
 				WarehouseEventsMap:    tc.warehouseEventsMap,
 				AsyncJob:              tc.asyncJob,
+				Faults:                tc.faults,
 				UserID:                testhelper.GetUserId(provider),
 				Provider:              provider,
 				JobsDB:                jobsDB,
 				JobRunID:              misc.FastUUID().String(),
 				TaskRunID:             misc.FastUUID().String(),
 				StatsToVerify:         []string{"pg_rollback_timeout"},
+				RecordExpectedRows:    true,
 				Client: &client.Client{
 					SQL:  db,
 					Type: client.SQLClient,
@@ -116,6 +124,7 @@ func TestIntegrationPostgres(t *testing.T) {
 			}
 			ts.JobRunID = misc.FastUUID().String()
 			ts.TaskRunID = misc.FastUUID().String()
+			ts.Faults = nil
 			ts.VerifyModifiedEvents(t)
 		})
 	}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/services/filemanager"
+	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/warehouse/client"
@@ -137,7 +138,7 @@ func ColumnsWithDataTypes(columns map[string]string, prefix string) string {
 	return strings.Join(arr, ",")
 }
 
-func (rs *HandleT) CreateTable(tableName string, columns map[string]string) (err error) {
+func (rs *HandleT) createTableSQL(tableName string, columns map[string]string) string {
 	name := fmt.Sprintf(`%q.%q`, rs.Namespace, tableName)
 	sortKeyField := "received_at"
 	if _, ok := columns["received_at"]; !ok {
@@ -150,7 +151,11 @@ func (rs *HandleT) CreateTable(tableName string, columns map[string]string) (err
 	if _, ok := columns["id"]; ok {
 		distKeySql = `DISTSTYLE KEY DISTKEY("id")`
 	}
-	sqlStatement := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s ( %v ) %s SORTKEY(%q) `, name, ColumnsWithDataTypes(columns, ""), distKeySql, sortKeyField)
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s ( %v ) %s SORTKEY(%q) `, name, ColumnsWithDataTypes(columns, ""), distKeySql, sortKeyField)
+}
+
+func (rs *HandleT) CreateTable(tableName string, columns map[string]string) (err error) {
+	sqlStatement := rs.createTableSQL(tableName, columns)
 	pkgLogger.Infof("Creating table in redshift for RS:%s : %v", rs.Warehouse.Destination.ID, sqlStatement)
 	_, err = rs.Db.Exec(sqlStatement)
 	return
@@ -232,8 +237,24 @@ func (rs *HandleT) alterStringToText(tableName, columnName string) (err error) {
 	return
 }
 
+func (rs *HandleT) createSchemaSQL() string {
+	return fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %q`, rs.Namespace)
+}
+
+// PreviewDDL returns the statements creating the schema and the tables of the warehouse, without running them
+func (rs *HandleT) PreviewDDL(warehouse warehouseutils.Warehouse, schema warehouseutils.SchemaT) []string {
+	rs.Warehouse = warehouse
+	rs.Namespace = warehouse.Namespace
+
+	statements := []string{rs.createSchemaSQL()}
+	for _, tableName := range warehouseutils.SortedTableNames(schema) {
+		statements = append(statements, rs.createTableSQL(tableName, schema[tableName]))
+	}
+	return statements
+}
+
 func (rs *HandleT) createSchema() (err error) {
-	sqlStatement := fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %q`, rs.Namespace)
+	sqlStatement := rs.createSchemaSQL()
 	pkgLogger.Infof("Creating schema name in redshift for RS:%s : %v", rs.Warehouse.Destination.ID, sqlStatement)
 	_, err = rs.Db.Exec(sqlStatement)
 	return
@@ -296,6 +317,7 @@ func (rs *HandleT) generateManifest(tableName string, _ map[string]string) (stri
 			UseRudderStorage: rs.Uploader.UseRudderStorage(),
 			WorkspaceID:      rs.Warehouse.Destination.WorkspaceID,
 		}),
+		WorkspaceID: rs.Warehouse.Destination.WorkspaceID,
 	})
 	if err != nil {
 		return "", err
@@ -613,7 +635,7 @@ func Connect(cred RedshiftCredentialsT) (*sql.DB, error) {
 			return nil, fmt.Errorf("connecting to redshift through tunnel: %w", err)
 		}
 	} else {
-		if db, err = sql.Open("postgres", dsn.String()); err != nil {
+		if db, err = stats.OpenDB(stats.Default, "postgres", dsn.String(), stats.Tags{"module": "warehouse", "destType": warehouseutils.RS}); err != nil {
 			return nil, fmt.Errorf("connecting to redshift: %w", err)
 		}
 	}
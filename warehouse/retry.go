@@ -188,7 +188,7 @@ func (retryReq *RetryRequest) clausesQuery(sourceIDs []string) []FilterClause {
 		})
 	} else {
 		clauses = append(clauses, FilterClause{
-			Clause:    fmt.Sprintf("created_at > NOW() - %s * INTERVAL '1 HOUR'", queryPlaceHolder),
+			Clause:    fmt.Sprintf("created_at > %s - %s * INTERVAL '1 HOUR'", sqlTimestamp(clock()), queryPlaceHolder),
 			ClauseArg: retryReq.IntervalInHours,
 		})
 	}
@@ -178,20 +178,20 @@ func (wh *HandleT) canCreateUpload(warehouse warehouseutils.Warehouse) bool {
 		return true
 	}
 	if warehouseSyncFreqIgnore {
-		return !uploadFrequencyExceeded(warehouse, "")
+		return !uploadFrequencyExceeded(warehouse, "", wh.currentTime())
 	}
 	// gets exclude window start time and end time
 	excludeWindow := warehouseutils.GetConfigValueAsMap(warehouseutils.ExcludeWindow, warehouse.Destination.Config)
 	excludeWindowStartTime, excludeWindowEndTime := GetExcludeWindowStartEndTimes(excludeWindow)
-	if CheckCurrentTimeExistsInExcludeWindow(timeutil.Now(), excludeWindowStartTime, excludeWindowEndTime) {
+	if CheckCurrentTimeExistsInExcludeWindow(wh.currentTime(), excludeWindowStartTime, excludeWindowEndTime) {
 		return false
 	}
 	syncFrequency := warehouseutils.GetConfigValue(warehouseutils.SyncFrequency, warehouse)
 	syncStartAt := warehouseutils.GetConfigValue(warehouseutils.SyncStartAt, warehouse)
 	if syncFrequency == "" || syncStartAt == "" {
-		return !uploadFrequencyExceeded(warehouse, syncFrequency)
+		return !uploadFrequencyExceeded(warehouse, syncFrequency, wh.currentTime())
 	}
-	prevScheduledTime := GetPrevScheduledTime(syncFrequency, syncStartAt, time.Now())
+	prevScheduledTime := GetPrevScheduledTime(syncFrequency, syncStartAt, wh.currentTime())
 	lastUploadCreatedAt := wh.getLastUploadCreatedAt(warehouse)
 	// start upload only if no upload has started in current window
 	// e.g. with prev scheduled time 14:00 and current time 15:00, start only if prev upload hasn't started after 14:00
@@ -5,8 +5,10 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"sort"
@@ -16,9 +18,11 @@ import (
 
 	"github.com/rudderlabs/rudder-server/services/stats"
 
+	"github.com/rudderlabs/rudder-server/warehouse/internal/faults"
 	"github.com/rudderlabs/rudder-server/warehouse/internal/model"
 
 	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/filemanager"
 	"github.com/rudderlabs/rudder-server/services/pgnotifier"
 	"github.com/rudderlabs/rudder-server/utils/misc"
@@ -26,6 +30,7 @@ import (
 	"github.com/rudderlabs/rudder-server/warehouse/jobs"
 	"github.com/rudderlabs/rudder-server/warehouse/manager"
 	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+	"github.com/rudderlabs/rudder-server/warehouse/validations"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -43,6 +48,7 @@ const (
 
 // JobRunT Temporary store for processing staging file to load file
 type JobRunT struct {
+	ctx                  context.Context // of the slave worker, for the transfers of the job to stop along with it
 	job                  Payload
 	stagingFilePath      string
 	uuidTS               time.Time
@@ -51,6 +57,7 @@ type JobRunT struct {
 	stagingFileReader    *gzip.Reader
 	whIdentifier         string
 	stats                stats.Stats
+	streamingUploader    filemanager.FileManager
 }
 
 func (jobRun *JobRunT) setStagingFileReader() (reader *gzip.Reader, endOfFile bool) {
@@ -120,6 +127,7 @@ func (job *Payload) getFileManager(config interface{}, useRudderStorage bool) (f
 			RudderStoragePrefixOverride: job.RudderStoragePrefix,
 			WorkspaceID:                 job.WorkspaceID,
 		}),
+		WorkspaceID: job.WorkspaceID,
 	})
 	return fileManager, err
 }
@@ -147,8 +155,16 @@ func (jobRun *JobRunT) downloadStagingFile() error {
 		timer := jobRun.timerStat("download_staging_file_time")
 		timer.Start()
 
-		err = downloader.Download(context.TODO(), file, job.StagingFileLocation)
+		err = faults.Inject(job.DestinationID, faults.ObjectStorage, getInProgressState(model.GeneratedLoadFiles))
+		if err == nil {
+			err = filemanager.DownloadResumable(jobRun.ctx, downloader, file, job.StagingFileLocation)
+		}
 		if err != nil {
+			if errors.Is(err, filemanager.ErrChecksumMismatch) {
+				jobRun.counterStat("staging_file_checksum_mismatch").Count(1)
+				pkgLogger.Errorf("[WH]: Corrupted staging file %s: %v", job.StagingFileLocation, err)
+				return err
+			}
 			pkgLogger.Errorf("[WH]: Failed to download file")
 			return err
 		}
@@ -244,22 +260,31 @@ func (jobRun *JobRunT) uploadLoadFilesToObjectStorage() ([]loadFileUploadOutputT
 					return // stop further processing
 				default:
 					tableName := uploadJob.tableName
-					loadFileUploadStart := time.Now()
-					uploadOutput, err := jobRun.uploadLoadFileToObjectStorage(uploader, uploadJob.outputFile, tableName)
-					if err != nil {
-						uploadErrorChan <- err
-						return
-					}
-					loadFileUploadTimer.Since(loadFileUploadStart)
-					loadFileStats, err := os.Stat(uploadJob.outputFile.GetLoadFile().Name())
-					if err != nil {
-						uploadErrorChan <- err
-						return
+					var uploadOutput filemanager.UploadOutput
+					var contentLength int64
+					if streamingWriter, ok := uploadJob.outputFile.(*warehouseutils.StreamingGZWriter); ok {
+						// streamed load files are already uploaded by the time the writer is closed
+						uploadOutput = streamingWriter.UploadOutput()
+						contentLength = streamingWriter.ContentLength()
+					} else {
+						loadFileUploadStart := time.Now()
+						uploadOutput, err = jobRun.uploadLoadFileToObjectStorage(uploader, uploadJob.outputFile, tableName)
+						if err != nil {
+							uploadErrorChan <- err
+							return
+						}
+						loadFileUploadTimer.Since(loadFileUploadStart)
+						loadFileStats, err := os.Stat(uploadJob.outputFile.GetLoadFile().Name())
+						if err != nil {
+							uploadErrorChan <- err
+							return
+						}
+						contentLength = loadFileStats.Size()
 					}
 					loadFileOutputChan <- loadFileUploadOutputT{
 						TableName:             tableName,
 						Location:              uploadOutput.Location,
-						ContentLength:         loadFileStats.Size(),
+						ContentLength:         contentLength,
 						TotalRows:             jobRun.tableEventCountMap[tableName],
 						StagingFileID:         stagingFileId,
 						DestinationRevisionID: job.DestinationRevisionID,
@@ -302,13 +327,32 @@ func (jobRun *JobRunT) uploadLoadFileToObjectStorage(uploader filemanager.FileMa
 	}
 	defer file.Close()
 	pkgLogger.Debugf("[WH]: %s: Uploading load_file to %s for table: %s with staging_file id: %v", job.DestinationType, warehouseutils.ObjectStorageType(job.DestinationType, job.DestinationConfig, job.UseRudderStorage), tableName, job.StagingFileID)
-	var uploadLocation filemanager.UploadOutput
+	return uploader.Upload(jobRun.ctx, file, jobRun.loadFileObjectPrefixes(tableName)...)
+}
+
+// loadFileObjectPrefixes returns the prefixes under which the load file for tableName is stored in object storage
+func (jobRun *JobRunT) loadFileObjectPrefixes(tableName string) []string {
+	job := jobRun.job
 	if misc.Contains(warehouseutils.TimeWindowDestinations, job.DestinationType) {
-		uploadLocation, err = uploader.Upload(context.TODO(), file, warehouseutils.GetTablePathInObjectStorage(jobRun.job.DestinationNamespace, tableName), job.LoadFilePrefix)
-	} else {
-		uploadLocation, err = uploader.Upload(context.TODO(), file, config.GetString("WAREHOUSE_BUCKET_LOAD_OBJECTS_FOLDER_NAME", "rudder-warehouse-load-objects"), tableName, job.SourceID, getBucketFolder(job.UniqueLoadGenID, tableName))
+		return []string{warehouseutils.GetTablePathInObjectStorage(job.DestinationNamespace, tableName), job.LoadFilePrefix}
+	}
+	return []string{config.GetString("WAREHOUSE_BUCKET_LOAD_OBJECTS_FOLDER_NAME", "rudder-warehouse-load-objects"), tableName, job.SourceID, getBucketFolder(job.UniqueLoadGenID, tableName)}
+}
+
+// getStreamingUploader returns the file manager used for streaming load files directly to object storage
+func (jobRun *JobRunT) getStreamingUploader() (filemanager.FileManager, error) {
+	if jobRun.streamingUploader != nil {
+		return jobRun.streamingUploader, nil
+	}
+	job := jobRun.job
+	uploader, err := job.getFileManager(job.DestinationConfig, job.UseRudderStorage)
+	if err != nil {
+		return nil, err
 	}
-	return uploadLocation, err
+	// the upload stays open while the staging file is being processed
+	uploader.SetTimeout(slaveUploadTimeout)
+	jobRun.streamingUploader = uploader
+	return uploader, nil
 }
 
 // Sort columns per table to maintain same order in load file (needed in case of csv load file)
@@ -332,6 +376,14 @@ func (jobRun *JobRunT) GetWriter(tableName string) (warehouseutils.LoadFileWrite
 		outputFilePath := jobRun.getLoadFilePath(tableName)
 		if jobRun.job.LoadFileType == warehouseutils.LOAD_FILE_TYPE_PARQUET {
 			writer, err = warehouseutils.CreateParquetWriter(jobRun.job.UploadSchema[tableName], outputFilePath, jobRun.job.DestinationType)
+		} else if enableStreamingLoadFileUpload {
+			var uploader filemanager.FileManager
+			uploader, err = jobRun.getStreamingUploader()
+			if err != nil {
+				return nil, err
+			}
+			objName := path.Join(append(jobRun.loadFileObjectPrefixes(tableName), filepath.Base(outputFilePath))...)
+			writer = warehouseutils.CreateStreamingGZWriter(jobRun.ctx, uploader, objName)
 		} else {
 			writer, err = misc.CreateGZ(outputFilePath)
 		}
@@ -357,6 +409,11 @@ func (jobRun *JobRunT) cleanup() {
 	}
 	if jobRun.outputFileWritersMap != nil {
 		for _, writer := range jobRun.outputFileWritersMap {
+			if streamingWriter, ok := writer.(*warehouseutils.StreamingGZWriter); ok {
+				// no-op if the upload has already completed
+				streamingWriter.Abort()
+				continue
+			}
 			misc.RemoveFilePaths(writer.GetLoadFile().Name())
 		}
 	}
@@ -385,9 +442,10 @@ func (event *BatchRouterEventT) GetColumnInfo(columnName string) (columnInfo war
 // 5. Delete the staging and load files from tmp directory
 //
 
-func processStagingFile(job Payload, workerIndex int) (loadFileUploadOutputs []loadFileUploadOutputT, err error) {
+func processStagingFile(ctx context.Context, job Payload, workerIndex int) (loadFileUploadOutputs []loadFileUploadOutputT, err error) {
 	processStartTime := time.Now()
 	jobRun := JobRunT{
+		ctx:          ctx,
 		job:          job,
 		whIdentifier: warehouseutils.GetWarehouseIdentifier(job.DestinationType, job.SourceID, job.DestinationID),
 		stats:        stats.Default,
@@ -554,8 +612,12 @@ func processStagingFile(job Payload, workerIndex int) (loadFileUploadOutputs []l
 
 	pkgLogger.Debugf("[WH]: Process %v bytes from downloaded staging file: %s", lineBytesCounter, job.StagingFileLocation)
 	jobRun.counterStat("bytes_processed_in_staging_file").Count(lineBytesCounter)
-	for _, loadFile := range jobRun.outputFileWritersMap {
+	for tableName, loadFile := range jobRun.outputFileWritersMap {
 		err = loadFile.Close()
+		if _, ok := loadFile.(*warehouseutils.StreamingGZWriter); ok && err != nil {
+			pkgLogger.Errorf("Error while streaming load file for table %s : %v", tableName, err)
+			return loadFileUploadOutputs, err
+		}
 		if err != nil {
 			pkgLogger.Errorf("Error while closing load file %s : %v", loadFile.GetLoadFile().Name(), err)
 		}
@@ -564,7 +626,45 @@ func processStagingFile(job Payload, workerIndex int) (loadFileUploadOutputs []l
 	return loadFileUploadOutputs, err
 }
 
-func processClaimedUploadJob(claimedJob pgnotifier.ClaimT, workerIndex int) {
+// generateTestSyncLoadFile generates the load file of the staging file of a test sync, the way the ones of the staging
+// files of uploads are
+func generateTestSyncLoadFile(ctx context.Context, stagingFile validations.StagingFile) (string, error) {
+	destination := stagingFile.Destination
+	destType := destination.DestinationDefinition.Name
+	job := Payload{
+		StagingFileLocation:  stagingFile.Location,
+		UploadSchema:         map[string]map[string]string{stagingFile.Table: stagingFile.Schema},
+		WorkspaceID:          destination.WorkspaceID,
+		SourceID:             stagingFile.SourceID,
+		DestinationID:        destination.ID,
+		DestinationName:      destination.Name,
+		DestinationType:      destType,
+		DestinationNamespace: stagingFile.Namespace,
+		DestinationConfig:    destination.Config,
+		UniqueLoadGenID:      misc.FastUUID().String(),
+		LoadFileType:         warehouseutils.GetLoadFileType(destType),
+	}
+	if misc.Contains(warehouseutils.TimeWindowDestinations, destType) {
+		job.LoadFilePrefix = warehouseutils.GetLoadFilePrefix(timeutil.Now(), warehouseutils.Warehouse{
+			Type:        destType,
+			Destination: destination,
+			Namespace:   stagingFile.Namespace,
+		})
+	}
+
+	loadFiles, err := processStagingFile(ctx, job, 0)
+	if err != nil {
+		return "", err
+	}
+	for _, loadFile := range loadFiles {
+		if loadFile.TableName == stagingFile.Table {
+			return loadFile.Location, nil
+		}
+	}
+	return "", fmt.Errorf("no load file generated for table %s", stagingFile.Table)
+}
+
+func processClaimedUploadJob(ctx context.Context, claimedJob pgnotifier.ClaimT, workerIndex int) {
 	claimProcessTimeStart := time.Now()
 	defer func() {
 		warehouseutils.NewTimerStat(STATS_WORKER_CLAIM_PROCESSING_TIME, warehouseutils.Tag{Name: TAG_WORKERID, Value: fmt.Sprintf("%d", workerIndex)}).Since(claimProcessTimeStart)
@@ -586,7 +686,7 @@ func processClaimedUploadJob(claimedJob pgnotifier.ClaimT, workerIndex int) {
 	}
 	job.BatchID = claimedJob.BatchID
 	pkgLogger.Infof(`Starting processing staging-file:%v from claim:%v`, job.StagingFileID, claimedJob.ID)
-	loadFileOutputs, err := processStagingFile(job, workerIndex)
+	loadFileOutputs, err := processStagingFile(ctx, job, workerIndex)
 	if err != nil {
 		handleErr(err, claimedJob)
 		return
@@ -686,6 +786,7 @@ func setupSlave(ctx context.Context) error {
 
 	slaveID := misc.FastUUID().String()
 	jobNotificationChannel := notifier.Subscribe(ctx, slaveID, noOfSlaveWorkerRoutines)
+	workerPool := rruntime.RegisterPool("warehouse", "slave", noOfSlaveWorkerRoutines)
 	for workerIdx := 0; workerIdx <= noOfSlaveWorkerRoutines-1; workerIdx++ {
 		idx := workerIdx
 		g.Go(misc.WithBugsnagForWarehouse(func() error {
@@ -696,11 +797,13 @@ func setupSlave(ctx context.Context) error {
 				workerIdleTimer.Since(workerIdleTimeStart)
 				pkgLogger.Infof("[WH]: Successfully claimed job:%v by slave worker-%v-%v & job type %s", claimedJob.ID, idx, slaveID, claimedJob.JobType)
 
+				busyDone := workerPool.Busy()
 				if claimedJob.JobType == jobs.AsyncJobType {
 					processClaimedAsyncJob(claimedJob)
 				} else {
-					processClaimedUploadJob(claimedJob, idx)
+					processClaimedUploadJob(ctx, claimedJob, idx)
 				}
+				busyDone()
 
 				pkgLogger.Infof("[WH]: Successfully processed job:%v by slave worker-%v-%v", claimedJob.ID, idx, slaveID)
 				workerIdleTimeStart = time.Now()
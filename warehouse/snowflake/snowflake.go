@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/warehouse/client"
@@ -139,9 +140,12 @@ func (sf *HandleT) schemaIdentifier() string {
 	)
 }
 
+func (sf *HandleT) createTableSQL(tableName string, columns map[string]string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s."%s" ( %v )`, sf.schemaIdentifier(), tableName, ColumnsWithDataTypes(columns, ""))
+}
+
 func (sf *HandleT) createTable(tableName string, columns map[string]string) (err error) {
-	schemaIdentifier := sf.schemaIdentifier()
-	sqlStatement := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s."%s" ( %v )`, schemaIdentifier, tableName, ColumnsWithDataTypes(columns, ""))
+	sqlStatement := sf.createTableSQL(tableName, columns)
 	pkgLogger.Infof("Creating table in snowflake for SF:%s : %v", sf.Warehouse.Destination.ID, sqlStatement)
 	_, err = sf.Db.Exec(sqlStatement)
 	return
@@ -179,9 +183,24 @@ func (sf *HandleT) schemaExists() (exists bool, err error) {
 	return
 }
 
+func (sf *HandleT) createSchemaSQL() string {
+	return fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s`, sf.schemaIdentifier())
+}
+
+// PreviewDDL returns the statements creating the schema and the tables of the warehouse, without running them
+func (sf *HandleT) PreviewDDL(warehouse warehouseutils.Warehouse, schema warehouseutils.SchemaT) []string {
+	sf.Warehouse = warehouse
+	sf.Namespace = warehouse.Namespace
+
+	statements := []string{sf.createSchemaSQL()}
+	for _, tableName := range warehouseutils.SortedTableNames(schema) {
+		statements = append(statements, sf.createTableSQL(tableName, schema[tableName]))
+	}
+	return statements
+}
+
 func (sf *HandleT) createSchema() (err error) {
-	schemaIdentifier := sf.schemaIdentifier()
-	sqlStatement := fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s`, schemaIdentifier)
+	sqlStatement := sf.createSchemaSQL()
 	pkgLogger.Infof("SF: Creating schema name in snowflake for %s:%s : %v", sf.Warehouse.Namespace, sf.Warehouse.Destination.ID, sqlStatement)
 	_, err = sf.Db.Exec(sqlStatement)
 	return
@@ -602,7 +621,7 @@ func Connect(cred SnowflakeCredentialsT) (*sql.DB, error) {
 	}
 
 	var db *sql.DB
-	if db, err = sql.Open("snowflake", dsn); err != nil {
+	if db, err = stats.OpenDB(stats.Default, "snowflake", dsn, stats.Tags{"module": "warehouse", "destType": warehouseutils.SNOWFLAKE}); err != nil {
 		return nil, fmt.Errorf("SF: snowflake connect error : (%v)", err)
 	}
 
@@ -0,0 +1,146 @@
+package warehouse
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/controlplane"
+	cpclient "github.com/rudderlabs/rudder-server/warehouse/client/controlplane"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// The ssh keys of tunnelled destinations are fetched from the control plane through a client shared by the warehouse
+// service, caching them for Warehouse.sshKeys.cacheTTL, and no longer than their validity. The control plane notifies
+// the keys it rotates through /v1/warehouse/ssh-keys/rotated, authenticating with the credentials of the internal API
+// of the control plane, invalidating the cached keys of the destinations in the body. The tunnelled warehouses of
+// those destinations, along with the ones whose keys reach their validity, are set up with the rotated keys without
+// waiting for the next config.
+
+// sshKeysRotatedRequest is the body of requests to the ssh keys rotated endpoint
+type sshKeysRotatedRequest struct {
+	DestinationIDs []string `json:"destinationIds"`
+}
+
+var (
+	sshKeysClientOnce sync.Once
+	sshKeysCPClient   cpclient.InternalControlPlaneWithCache
+)
+
+// sshKeysClient returns the client of the control plane shared by the warehouse service, caching ssh keys
+func sshKeysClient() cpclient.InternalControlPlaneWithCache {
+	sshKeysClientOnce.Do(func() {
+		sshKeysCPClient = cpclient.NewInternalClientWithCache(
+			configBackendURL,
+			cpclient.BasicAuth{
+				Username: config.GetString("CP_INTERNAL_API_USERNAME", ""),
+				Password: config.GetString("CP_INTERNAL_API_PASSWORD", ""),
+			},
+			cpclient.WithCacheTTL(config.GetDuration("Warehouse.sshKeys.cacheTTL", 60, time.Minute)),
+			cpclient.WithInternalOptions(cpclient.WithFailover(
+				config.GetString("region", ""),
+				controlplane.ParseEndpoints(config.GetString("CONFIG_BACKEND_FAILOVER_URLS", "")),
+			)),
+		)
+	})
+	return sshKeysCPClient
+}
+
+// sshKeysRotatedHandler invalidates the cached ssh keys of the destinations in the body, rotated by the control plane
+func sshKeysRotatedHandler(w http.ResponseWriter, r *http.Request) {
+	pkgLogger.LogRequest(r)
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !internalAPIAuthorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		pkgLogger.Errorf("[WH]: Error reading body: %v", err)
+		http.Error(w, "can't read body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var rotatedReq sshKeysRotatedRequest
+	if err := json.Unmarshal(body, &rotatedReq); err != nil {
+		pkgLogger.Errorf("[WH]: Error unmarshalling body: %v", err)
+		http.Error(w, "can't unmarshall body", http.StatusBadRequest)
+		return
+	}
+	if len(rotatedReq.DestinationIDs) == 0 {
+		http.Error(w, "empty destination ids", http.StatusBadRequest)
+		return
+	}
+
+	client := sshKeysClient()
+	for _, destinationID := range rotatedReq.DestinationIDs {
+		client.RotateDestinationSSHKeys(destinationID)
+	}
+	pkgLogger.Infof("[WH]: Rotated ssh keys of destinations %v", rotatedReq.DestinationIDs)
+	w.WriteHeader(http.StatusOK)
+}
+
+// internalAPIAuthorized returns whether the request is authenticated with the credentials of the internal API of the
+// control plane, rejecting all requests if they aren't configured
+func internalAPIAuthorized(r *http.Request) bool {
+	wantUsername := config.GetString("CP_INTERNAL_API_USERNAME", "")
+	wantPassword := config.GetString("CP_INTERNAL_API_PASSWORD", "")
+	username, password, ok := r.BasicAuth()
+	if !ok || wantUsername == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(username), []byte(wantUsername)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(wantPassword)) == 1
+}
+
+// watchSSHKeyRotations sets up the tunnelled warehouses again with the rotated ssh keys of their destinations, until
+// ctx is done
+func (wh *HandleT) watchSSHKeyRotations(ctx context.Context) {
+	for range wh.cpInternalClient.SubscribeToSSHKeyRotations(ctx) {
+		for !wh.refreshSSHKeys(ctx) && ctx.Err() == nil {
+			pkgLogger.Debugf("[WH]: Warehouses replaced while refreshing their ssh keys, retrying")
+		}
+	}
+}
+
+// refreshSSHKeys sets up the tunnelled warehouses again with the current ssh keys of their destinations, fetching the
+// keys without holding the config subscriber lock. It returns false, leaving the warehouses as they are, if they were
+// replaced by a config received meanwhile, for the refresh to be retried.
+func (wh *HandleT) refreshSSHKeys(ctx context.Context) bool {
+	wh.configSubscriberLock.RLock()
+	version := wh.warehousesVersion
+	warehousesByWorkspace := make(map[string][]warehouseutils.Warehouse, len(wh.warehousesByWorkspace))
+	for workspaceID, cachedWarehouses := range wh.warehousesByWorkspace {
+		warehousesByWorkspace[workspaceID] = append([]warehouseutils.Warehouse{}, cachedWarehouses...)
+	}
+	wh.configSubscriberLock.RUnlock()
+
+	warehouses := make([]warehouseutils.Warehouse, 0, len(warehousesByWorkspace))
+	for _, workspaceWarehouses := range warehousesByWorkspace {
+		for i := range workspaceWarehouses {
+			// the keys of the other destinations are cached, only the rotated ones being fetched
+			workspaceWarehouses[i].Destination = wh.attachSSHTunnellingInfo(ctx, workspaceWarehouses[i].Destination)
+		}
+		warehouses = append(warehouses, workspaceWarehouses...)
+	}
+
+	wh.configSubscriberLock.Lock()
+	defer wh.configSubscriberLock.Unlock()
+	if wh.warehousesVersion != version {
+		return false
+	}
+	wh.warehousesByWorkspace = warehousesByWorkspace
+	wh.warehouses = warehouses
+	return true
+}
@@ -0,0 +1,87 @@
+package warehouse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	cpclient "github.com/rudderlabs/rudder-server/warehouse/client/controlplane"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+type mockSSHKeysClient struct {
+	cpclient.InternalControlPlaneWithCache
+	privateKey string
+	onFetch    func()
+}
+
+func (m *mockSSHKeysClient) GetDestinationSSHKeys(context.Context, string) (*cpclient.PublicPrivateKeyPair, error) {
+	if m.onFetch != nil {
+		m.onFetch()
+	}
+	return &cpclient.PublicPrivateKeyPair{PrivateKey: m.privateKey}, nil
+}
+
+func TestSSHKeysRotatedHandler(t *testing.T) {
+	pkgLogger = logger.NOP
+	config.Set("CP_INTERNAL_API_USERNAME", "username")
+	config.Set("CP_INTERNAL_API_PASSWORD", "password")
+	defer config.Reset()
+
+	serve := func(username, password string) int {
+		r := httptest.NewRequest(http.MethodPost, "/v1/warehouse/ssh-keys/rotated", strings.NewReader(`{"destinationIds": ["destination-id"]}`))
+		if username != "" {
+			r.SetBasicAuth(username, password)
+		}
+		w := httptest.NewRecorder()
+		sshKeysRotatedHandler(w, r)
+		return w.Code
+	}
+	require.Equal(t, http.StatusUnauthorized, serve("", ""))
+	require.Equal(t, http.StatusUnauthorized, serve("username", "wrong"))
+	require.Equal(t, http.StatusOK, serve("username", "password"))
+}
+
+func TestRefreshSSHKeys(t *testing.T) {
+	pkgLogger = logger.NOP
+
+	client := &mockSSHKeysClient{privateKey: "rotated"}
+	wh := &HandleT{
+		cpInternalClient: client,
+		warehousesByWorkspace: map[string][]warehouseutils.Warehouse{
+			"workspace-id": {{Destination: backendconfig.DestinationT{
+				ID:     "destination-id",
+				Config: map[string]interface{}{"useSSH": true, "sshPrivateKey": "expired"},
+			}}},
+		},
+	}
+	privateKey := func() interface{} {
+		wh.configSubscriberLock.RLock()
+		defer wh.configSubscriberLock.RUnlock()
+		return wh.warehousesByWorkspace["workspace-id"][0].Destination.Config["sshPrivateKey"]
+	}
+
+	t.Run("keys are fetched without holding the lock, leaving warehouses replaced meanwhile as they are", func(t *testing.T) {
+		client.onFetch = func() {
+			wh.configSubscriberLock.Lock()
+			wh.warehousesVersion++
+			wh.configSubscriberLock.Unlock()
+		}
+		require.False(t, wh.refreshSSHKeys(context.Background()))
+		require.Equal(t, "expired", privateKey())
+	})
+
+	t.Run("warehouses are set up with the rotated keys", func(t *testing.T) {
+		client.onFetch = nil
+		require.True(t, wh.refreshSSHKeys(context.Background()))
+		require.Equal(t, "rotated", privateKey())
+		require.Len(t, wh.warehouses, 1)
+	})
+}
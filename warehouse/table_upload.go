@@ -4,9 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/rudderlabs/rudder-server/utils/misc"
-	"github.com/rudderlabs/rudder-server/utils/timeutil"
 	"github.com/rudderlabs/rudder-server/warehouse/internal/model"
 	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
 )
@@ -19,12 +19,20 @@ const (
 type TableUploadT struct {
 	uploadID  int64
 	tableName string
+	now       func() time.Time
 }
 
 func NewTableUpload(uploadID int64, tableName string) *TableUploadT {
 	return &TableUploadT{uploadID: uploadID, tableName: tableName}
 }
 
+// newTableUpload returns the table upload of the job for the table, sharing the clock of the job
+func (job *UploadJobT) newTableUpload(tableName string) *TableUploadT {
+	tableUpload := NewTableUpload(job.upload.ID, tableName)
+	tableUpload.now = job.now
+	return tableUpload
+}
+
 func (job *UploadJobT) getTotalEventsUploaded(includeDiscards bool) (int64, error) {
 	var total sql.NullInt64
 	var discardsStatement string
@@ -68,9 +76,8 @@ func areTableUploadsCreated(uploadID int64) bool {
 	return count > 0
 }
 
-func createTableUploadsForBatch(uploadID int64, tableNames []string) (err error) {
+func createTableUploadsForBatch(uploadID int64, tableNames []string, currentTime time.Time) (err error) {
 	columnsInInsert := []string{"wh_upload_id", "table_name", "status", "error", "created_at", "updated_at"}
-	currentTime := timeutil.Now()
 	valueReferences := make([]string, 0, len(tableNames))
 	valueArgs := make([]interface{}, 0, len(tableNames)*len(columnsInInsert))
 	for idx, tName := range tableNames {
@@ -102,14 +109,14 @@ func createTableUploadsForBatch(uploadID int64, tableNames []string) (err error)
 	return err
 }
 
-func createTableUploads(uploadID int64, tableNames []string) (err error) {
+func createTableUploads(uploadID int64, tableNames []string, now time.Time) (err error) {
 	// we add table uploads to db in batches to avoid hitting postgres row insert limits
 	for i := 0; i < len(tableNames); i += createTableUploadsBatchSize {
 		j := i + createTableUploadsBatchSize
 		if j > len(tableNames) {
 			j = len(tableNames)
 		}
-		err = createTableUploadsForBatch(uploadID, tableNames[i:j])
+		err = createTableUploadsForBatch(uploadID, tableNames[i:j], now)
 		if err != nil {
 			return
 		}
@@ -119,12 +126,13 @@ func createTableUploads(uploadID int64, tableNames []string) (err error) {
 
 func (tableUpload *TableUploadT) setStatus(status string) (err error) {
 	// set last_exec_time only if status is executing
-	execValues := []interface{}{status, timeutil.Now(), tableUpload.uploadID, tableUpload.tableName}
+	now := tableUpload.currentTime()
+	execValues := []interface{}{status, now, tableUpload.uploadID, tableUpload.tableName}
 	var lastExec string
 	if status == TableUploadExecuting {
 		// setting values using syntax $n since Exec can correctlt format time.Time strings
 		lastExec = fmt.Sprintf(`, last_exec_time=$%d`, len(execValues)+1)
-		execValues = append(execValues, timeutil.Now())
+		execValues = append(execValues, now)
 	}
 	sqlStatement := fmt.Sprintf(`
 		UPDATE 
@@ -184,7 +192,7 @@ func (tableUpload *TableUploadT) setError(status string, statusError error) (err
 	_, err = dbHandle.Exec(
 		sqlStatement,
 		status,
-		timeutil.Now(),
+		tableUpload.currentTime(),
 		misc.QuoteLiteral(statusError.Error()),
 		uploadID,
 		tableName,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"os"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -53,7 +54,7 @@ var _ = Describe("TableUpload", func() {
 		})
 
 		It("Create table uploads for batch", func() {
-			err = createTableUploads(uploadID, tableNames)
+			err = createTableUploads(uploadID, tableNames, time.Now())
 			Expect(err).To(BeNil())
 		})
 
@@ -0,0 +1,279 @@
+package testhelper
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/warehouse/client"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// On top of counting the rows of the tables of the user of the test, the events in the warehouse are verified by:
+//   - ColumnAssertions, on the values, the type and the ratio of nulls of columns
+//   - ExpectedRows, diffed against the rows of the tables by id, cell by cell, catching values wrongly cast or
+//     truncated
+//
+// Values are compared as the warehouse client returns them, as strings, nulls being NullValue.
+
+// NullValue is the value of null cells in query results
+const NullValue = "<nil>"
+
+// ColumnAssertion asserts on a column of a table, for the rows of the user of the test
+type ColumnAssertion struct {
+	Column string
+	// Values are the expected values of the column, in any order, not asserted on if nil
+	Values []string
+	// Type is the expected type of the column, as the information schema of the warehouse reports it, compared case
+	// insensitively, not asserted on if empty
+	Type string
+	// MaxNullRatio is the maximum ratio of nulls in the column, not asserted on if nil
+	MaxNullRatio *float64
+}
+
+// Ratio returns a pointer to the ratio, for MaxNullRatio
+func Ratio(ratio float64) *float64 {
+	return &ratio
+}
+
+// TableDiff is the difference between the rows of a table and the expected ones, by key
+type TableDiff struct {
+	// Missing are the keys of the expected rows missing from the table
+	Missing []string
+	// Unexpected are the keys of the rows of the table not expected
+	Unexpected []string
+	// Mismatches are the cells of the rows of the table whose values aren't the expected ones
+	Mismatches []CellMismatch
+}
+
+// CellMismatch is a cell of a row whose value isn't the expected one
+type CellMismatch struct {
+	Key      string
+	Column   string
+	Expected string
+	Actual   string
+}
+
+// Empty returns whether the table matches the expected rows
+func (d TableDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Unexpected) == 0 && len(d.Mismatches) == 0
+}
+
+func (d TableDiff) String() string {
+	var b strings.Builder
+	if len(d.Missing) > 0 {
+		fmt.Fprintf(&b, "missing rows: %v\n", d.Missing)
+	}
+	if len(d.Unexpected) > 0 {
+		fmt.Fprintf(&b, "unexpected rows: %v\n", d.Unexpected)
+	}
+	for _, m := range d.Mismatches {
+		fmt.Fprintf(&b, "row %s, column %s: expected %q, got %q\n", m.Key, m.Column, m.Expected, m.Actual)
+	}
+	return b.String()
+}
+
+// DiffRows diffs the rows against the expected ones, by the key column, comparing the columns of the expected rows
+func DiffRows(key string, rows, expected []map[string]string) TableDiff {
+	var diff TableDiff
+
+	actualByKey := make(map[string]map[string]string, len(rows))
+	for _, row := range rows {
+		actualByKey[row[key]] = row
+	}
+	expectedKeys := make(map[string]struct{}, len(expected))
+	for _, expectedRow := range expected {
+		k := expectedRow[key]
+		expectedKeys[k] = struct{}{}
+
+		row, ok := actualByKey[k]
+		if !ok {
+			diff.Missing = append(diff.Missing, k)
+			continue
+		}
+		for _, column := range sortedColumns(expectedRow) {
+			if expectedValue, actualValue := expectedRow[column], row[column]; expectedValue != actualValue {
+				diff.Mismatches = append(diff.Mismatches, CellMismatch{Key: k, Column: column, Expected: expectedValue, Actual: actualValue})
+			}
+		}
+	}
+	for _, row := range rows {
+		if _, ok := expectedKeys[row[key]]; !ok {
+			diff.Unexpected = append(diff.Unexpected, row[key])
+		}
+	}
+	sort.Strings(diff.Missing)
+	sort.Strings(diff.Unexpected)
+	return diff
+}
+
+// DiffTable diffs the rows of the table of the user of the test against the expected ones, by id
+func DiffTable(wareHouseTest *WareHouseTest, table string, expected []map[string]string) (TableDiff, error) {
+	columns := map[string]struct{}{"id": {}}
+	for _, row := range expected {
+		for column := range row {
+			columns[column] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(columns))
+	for column := range columns {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+
+	selected := make([]string, 0, len(names))
+	for _, column := range names {
+		selected = append(selected, warehouseutils.ToProviderCase(wareHouseTest.Provider, column))
+	}
+	result, err := wareHouseTest.Client.Query(fmt.Sprintf(
+		`select %s from %s.%s where %s;`,
+		strings.Join(selected, ", "),
+		wareHouseTest.Schema,
+		warehouseutils.ToProviderCase(wareHouseTest.Provider, table),
+		userFilter(wareHouseTest, table),
+	))
+	if err != nil {
+		return TableDiff{}, err
+	}
+
+	rows := make([]map[string]string, 0, len(result.Values))
+	for _, values := range result.Values {
+		row := make(map[string]string, len(names))
+		for i, column := range names {
+			if i < len(values) {
+				row[column] = values[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return DiffRows("id", rows, expected), nil
+}
+
+// AssertColumns asserts on the columns of the table of the user of the test
+func AssertColumns(t testing.TB, wareHouseTest *WareHouseTest, table string, assertions ...ColumnAssertion) {
+	t.Helper()
+
+	tableName := warehouseutils.ToProviderCase(wareHouseTest.Provider, table)
+	for _, assertion := range assertions {
+		column := warehouseutils.ToProviderCase(wareHouseTest.Provider, assertion.Column)
+
+		if assertion.Values != nil {
+			result, err := wareHouseTest.Client.Query(fmt.Sprintf(
+				`select %s from %s.%s where %s;`,
+				column, wareHouseTest.Schema, tableName, userFilter(wareHouseTest, table),
+			))
+			require.NoError(t, err)
+			values := make([]string, 0, len(result.Values))
+			for _, row := range result.Values {
+				values = append(values, row[0])
+			}
+			require.ElementsMatchf(t, assertion.Values, values, "values of column %s of table %s", column, tableName)
+		}
+
+		if assertion.Type != "" {
+			columnTypes, err := queryColumnTypes(wareHouseTest.Client, wareHouseTest.Provider, wareHouseTest.Schema, tableName)
+			require.NoError(t, err)
+			require.Containsf(t, columnTypes, column, "column %s of table %s", column, tableName)
+			require.Truef(t, strings.EqualFold(assertion.Type, columnTypes[column]), "type of column %s of table %s: expected %s, got %s", column, tableName, assertion.Type, columnTypes[column])
+		}
+
+		if assertion.MaxNullRatio != nil {
+			result, err := wareHouseTest.Client.Query(fmt.Sprintf(
+				`select count(*), count(%s) from %s.%s where %s;`,
+				column, wareHouseTest.Schema, tableName, userFilter(wareHouseTest, table),
+			))
+			require.NoError(t, err)
+			require.NotEmpty(t, result.Values)
+			total, err := strconv.ParseInt(result.Values[0][0], 10, 64)
+			require.NoError(t, err)
+			nonNulls, err := strconv.ParseInt(result.Values[0][1], 10, 64)
+			require.NoError(t, err)
+			require.NotZerof(t, total, "no rows in table %s", tableName)
+			nullRatio := float64(total-nonNulls) / float64(total)
+			require.LessOrEqualf(t, nullRatio, *assertion.MaxNullRatio, "ratio of nulls of column %s of table %s", column, tableName)
+		}
+	}
+}
+
+// userFilter returns the condition of the rows of the user of the test, users generated by generators having its id
+// as prefix
+func userFilter(wareHouseTest *WareHouseTest, table string) string {
+	column := "user_id"
+	if table == "users" {
+		column = "id"
+	}
+	return fmt.Sprintf(`%s like '%s%%'`, warehouseutils.ToProviderCase(wareHouseTest.Provider, column), wareHouseTest.UserID)
+}
+
+// queryColumnTypes returns the types of the columns of the table, as the information schema of the warehouse reports
+// them
+func queryColumnTypes(cl *client.Client, provider, schema, table string) (map[string]string, error) {
+	var statement string
+	switch provider {
+	case warehouseutils.CLICKHOUSE:
+		statement = fmt.Sprintf(`select name, type from system.columns where database = '%s' and table = '%s';`, schema, table)
+	case warehouseutils.BQ:
+		statement = fmt.Sprintf("select column_name, data_type from `%s`.INFORMATION_SCHEMA.COLUMNS where table_name = '%s';", schema, table)
+	case warehouseutils.DELTALAKE:
+		statement = fmt.Sprintf(`describe table %s.%s;`, schema, table)
+	default:
+		statement = fmt.Sprintf(`select column_name, data_type from information_schema.columns where table_schema = '%s' and table_name = '%s';`, schema, table)
+	}
+	result, err := cl.Query(statement)
+	if err != nil {
+		return nil, err
+	}
+	columnTypes := make(map[string]string, len(result.Values))
+	for _, row := range result.Values {
+		if len(row) >= 2 {
+			columnTypes[row[0]] = row[1]
+		}
+	}
+	return columnTypes, nil
+}
+
+// sortedColumns returns the columns of the row, sorted
+func sortedColumns(row map[string]string) []string {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// verifyWarehouseContents verifies the columns and the rows of the tables of the test, retrying while the warehouse
+// catches up
+func verifyWarehouseContents(t testing.TB, wareHouseTest *WareHouseTest) {
+	t.Helper()
+
+	for _, table := range sortedTables(wareHouseTest.ExpectedRows) {
+		t.Logf("Diffing rows in warehouse for schema: %s, table: %s", wareHouseTest.Schema, table)
+		require.NoError(t, WithConstantBackoff(func() error {
+			diff, err := DiffTable(wareHouseTest, table, wareHouseTest.ExpectedRows[table])
+			if err != nil {
+				return err
+			}
+			if !diff.Empty() {
+				return fmt.Errorf("rows of table %s differ from the expected ones:\n%s", table, diff)
+			}
+			return nil
+		}))
+	}
+	for _, table := range sortedTables(wareHouseTest.ColumnAssertions) {
+		AssertColumns(t, wareHouseTest, table, wareHouseTest.ColumnAssertions[table]...)
+	}
+}
+
+func sortedTables[V any](byTable map[string]V) []string {
+	tables := make([]string, 0, len(byTable))
+	for table := range byTable {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
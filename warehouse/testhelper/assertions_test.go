@@ -0,0 +1,54 @@
+package testhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffRows(t *testing.T) {
+	expected := []map[string]string{
+		{"id": "1", "name": "a", "count": "1"},
+		{"id": "2", "name": "b", "count": "2"},
+	}
+
+	t.Run("no difference", func(t *testing.T) {
+		rows := []map[string]string{
+			{"id": "2", "name": "b", "count": "2", "other": "ignored"},
+			{"id": "1", "name": "a", "count": "1"},
+		}
+		diff := DiffRows("id", rows, expected)
+		require.True(t, diff.Empty())
+		require.Empty(t, diff.String())
+	})
+
+	t.Run("missing and unexpected rows", func(t *testing.T) {
+		rows := []map[string]string{
+			{"id": "4", "name": "d", "count": "4"},
+			{"id": "1", "name": "a", "count": "1"},
+			{"id": "3", "name": "c", "count": "3"},
+		}
+		diff := DiffRows("id", rows, expected)
+		require.False(t, diff.Empty())
+		require.Equal(t, []string{"2"}, diff.Missing)
+		require.Equal(t, []string{"3", "4"}, diff.Unexpected)
+		require.Empty(t, diff.Mismatches)
+	})
+
+	t.Run("mismatching cells", func(t *testing.T) {
+		rows := []map[string]string{
+			{"id": "1", "name": "a", "count": "1.0"},
+			{"id": "2", "name": NullValue, "count": "2"},
+		}
+		diff := DiffRows("id", rows, append(expected, map[string]string{"id": "1", "missing": "x"}))
+		require.False(t, diff.Empty())
+		require.Empty(t, diff.Missing)
+		require.Empty(t, diff.Unexpected)
+		require.Equal(t, []CellMismatch{
+			{Key: "1", Column: "count", Expected: "1", Actual: "1.0"},
+			{Key: "2", Column: "name", Expected: "b", Actual: NullValue},
+			{Key: "1", Column: "missing", Expected: "x", Actual: ""},
+		}, diff.Mismatches)
+		require.Contains(t, diff.String(), `row 2, column name: expected "b", got "<nil>"`)
+	})
+}
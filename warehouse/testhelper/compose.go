@@ -0,0 +1,122 @@
+package testhelper
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/testhelper/destination"
+)
+
+// Instead of the fixed containers of docker-compose.test.yml, tests can start the containers of their destinations on
+// their own with Compose, on dynamic ports, for concurrent tests not to share them. The template configurations it
+// returns point the destinations at their containers, the containers being purged once the test completes.
+// Destinations are set up by the DestinationSetup registered under their name, the ones of minio, clickhouse,
+// clickhouseCluster and mssql being registered by default.
+
+// DestinationSetup starts the containers of a destination for the test, setting its template configurations
+type DestinationSetup func(t testing.TB, pool *dockertest.Pool, configurations map[string]string) error
+
+// ClickHouseClusterConfigDir is the directory of the configurations of the nodes of clickhouse clusters, clickhouse01
+// to clickhouse04, relative to the packages of the tests
+var ClickHouseClusterConfigDir = "../testdata/clickhouse/cluster"
+
+var (
+	destinationSetupsMu sync.RWMutex
+	destinationSetups   = map[string]DestinationSetup{
+		"minio":             setupMinio,
+		"clickhouse":        setupClickHouse,
+		"clickhouseCluster": setupClickHouseCluster,
+		"mssql":             setupMSSQL,
+	}
+)
+
+// RegisterDestinationSetup registers the setup of the destination with the name, replacing any registered before
+func RegisterDestinationSetup(name string, setup DestinationSetup) {
+	destinationSetupsMu.Lock()
+	defer destinationSetupsMu.Unlock()
+	destinationSetups[name] = setup
+}
+
+// Compose starts the containers of the destinations for the test, returning the template configurations pointing at
+// them
+func Compose(t testing.TB, destinations ...string) map[string]string {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	configurations := PopulateTemplateConfigurations()
+	for _, name := range destinations {
+		destinationSetupsMu.RLock()
+		setup, ok := destinationSetups[name]
+		destinationSetupsMu.RUnlock()
+		require.Truef(t, ok, "no setup registered for destination %s", name)
+
+		t.Logf("Setting up destination: %s", name)
+		require.NoErrorf(t, setup(t, pool, configurations), "setting up destination %s", name)
+	}
+	return configurations
+}
+
+func setupMinio(t testing.TB, pool *dockertest.Pool, configurations map[string]string) error {
+	minioResource, err := destination.SetupMINIO(pool, t)
+	if err != nil {
+		return err
+	}
+	configurations["minioBucketName"] = minioResource.BucketName
+	configurations["minioAccesskeyID"] = minioResource.AccessKey
+	configurations["minioSecretAccessKey"] = minioResource.SecretKey
+	configurations["minioEndpoint"] = minioResource.Endpoint
+	return nil
+}
+
+func setupClickHouse(t testing.TB, pool *dockertest.Pool, configurations map[string]string) error {
+	clickHouseResource, err := destination.SetupClickHouse(pool, t)
+	if err != nil {
+		return err
+	}
+	configurations["clickHouseHost"] = clickHouseResource.Host
+	configurations["clickHousePort"] = clickHouseResource.Port
+	configurations["clickHouseDatabase"] = clickHouseResource.Database
+	configurations["clickHouseUser"] = clickHouseResource.User
+	configurations["clickHousePassword"] = clickHouseResource.Password
+	return nil
+}
+
+func setupClickHouseCluster(t testing.TB, pool *dockertest.Pool, configurations map[string]string) error {
+	var nodes []destination.ClickHouseClusterNode
+	for i := 1; i <= 4; i++ {
+		nodes = append(nodes, destination.ClickHouseClusterNode{
+			Hostname:  fmt.Sprintf("wh-clickhouse0%d", i),
+			ConfigDir: fmt.Sprintf("clickhouse0%d", i),
+		})
+	}
+	cluster, err := destination.SetupClickHouseCluster(pool, t, ClickHouseClusterConfigDir, "wh-clickhouse-zookeeper", nodes...)
+	if err != nil {
+		return err
+	}
+	node := cluster.Nodes[0]
+	configurations["clickhouseClusterHost"] = node.Host
+	configurations["clickhouseClusterPort"] = node.Port
+	configurations["clickhouseClusterDatabase"] = node.Database
+	configurations["clickhouseClusterUser"] = node.User
+	configurations["clickhouseClusterPassword"] = node.Password
+	return nil
+}
+
+func setupMSSQL(t testing.TB, pool *dockertest.Pool, configurations map[string]string) error {
+	mssqlResource, err := destination.SetupMSSQL(pool, t)
+	if err != nil {
+		return err
+	}
+	configurations["mssqlHost"] = mssqlResource.Host
+	configurations["mssqlPort"] = mssqlResource.Port
+	configurations["mssqlDatabase"] = mssqlResource.Database
+	configurations["mssqlUser"] = mssqlResource.User
+	configurations["mssqlPassword"] = mssqlResource.Password
+	return nil
+}
@@ -384,14 +384,16 @@ func SendEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d identifies events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadIdentify := strings.NewReader(
 				fmt.Sprintf(
 					IdentifyPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadIdentify, "identify", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("identify", messageID)
 		}
 	}
 
@@ -399,14 +401,16 @@ func SendEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d tracks events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadTrack := strings.NewReader(
 				fmt.Sprintf(
 					TrackPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadTrack, "track", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("track", messageID)
 		}
 	}
 
@@ -414,14 +418,16 @@ func SendEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d pages events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadPage := strings.NewReader(
 				fmt.Sprintf(
 					PagePayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadPage, "page", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("page", messageID)
 		}
 	}
 
@@ -429,14 +435,16 @@ func SendEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d screens events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadScreen := strings.NewReader(
 				fmt.Sprintf(
 					ScreenPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadScreen, "screen", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("screen", messageID)
 		}
 	}
 
@@ -444,14 +452,16 @@ func SendEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d aliases events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadAlias := strings.NewReader(
 				fmt.Sprintf(
 					AliasPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadAlias, "alias", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("alias", messageID)
 		}
 	}
 
@@ -459,14 +469,16 @@ func SendEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d groups events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadGroup := strings.NewReader(
 				fmt.Sprintf(
 					GroupPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadGroup, "group", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("group", messageID)
 		}
 	}
 
@@ -501,14 +513,16 @@ func SendModifiedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d modified identifies events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadIdentify := strings.NewReader(
 				fmt.Sprintf(
 					ModifiedIdentifyPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadIdentify, "identify", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("identify", messageID)
 		}
 	}
 
@@ -516,14 +530,16 @@ func SendModifiedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d modified tracks events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadTrack := strings.NewReader(
 				fmt.Sprintf(
 					ModifiedTrackPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadTrack, "track", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("track", messageID)
 		}
 	}
 
@@ -531,14 +547,16 @@ func SendModifiedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d modified pages events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadPage := strings.NewReader(
 				fmt.Sprintf(
 					ModifiedPagePayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadPage, "page", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("page", messageID)
 		}
 	}
 
@@ -546,14 +564,16 @@ func SendModifiedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d modified screens events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadScreen := strings.NewReader(
 				fmt.Sprintf(
 					ModifiedScreenPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadScreen, "screen", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("screen", messageID)
 		}
 	}
 
@@ -561,14 +581,16 @@ func SendModifiedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d modified aliases events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadAlias := strings.NewReader(
 				fmt.Sprintf(
 					ModifiedAliasPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadAlias, "alias", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("alias", messageID)
 		}
 	}
 
@@ -576,13 +598,15 @@ func SendModifiedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d modified groups events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadGroup := strings.NewReader(
 				fmt.Sprintf(ModifiedGroupPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadGroup, "group", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("group", messageID)
 		}
 	}
 
@@ -617,15 +641,17 @@ func SendIntegratedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d integrated identifies events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadIdentify := strings.NewReader(
 				fmt.Sprintf(
 					ReservedKeywordsIdentifyPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 					wareHouseTest.Provider,
 				),
 			)
 			send(t, payloadIdentify, "identify", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("identify", messageID)
 		}
 	}
 
@@ -633,15 +659,17 @@ func SendIntegratedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d integrated tracks events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadTrack := strings.NewReader(
 				fmt.Sprintf(
 					ReservedKeywordsTrackPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 					wareHouseTest.Provider,
 				),
 			)
 			send(t, payloadTrack, "track", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("track", messageID)
 		}
 	}
 
@@ -649,15 +677,17 @@ func SendIntegratedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d integrated pages events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadPage := strings.NewReader(
 				fmt.Sprintf(
 					ReservedKeywordsPagePayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 					wareHouseTest.Provider,
 				),
 			)
 			send(t, payloadPage, "page", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("page", messageID)
 		}
 	}
 
@@ -665,15 +695,17 @@ func SendIntegratedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d integrated screens events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadScreen := strings.NewReader(
 				fmt.Sprintf(
 					ReservedKeywordsScreenPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 					wareHouseTest.Provider,
 				),
 			)
 			send(t, payloadScreen, "screen", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("screen", messageID)
 		}
 	}
 
@@ -681,14 +713,16 @@ func SendIntegratedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d integrated aliases events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadAlias := strings.NewReader(
 				fmt.Sprintf(
 					AliasPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 				),
 			)
 			send(t, payloadAlias, "alias", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("alias", messageID)
 		}
 	}
 
@@ -696,15 +730,17 @@ func SendIntegratedEvents(t testing.TB, wareHouseTest *WareHouseTest) {
 		t.Logf("Sending %d integrated groups events", count)
 
 		for i := 0; i < count; i++ {
+			messageID := wareHouseTest.msgID()
 			payloadGroup := strings.NewReader(
 				fmt.Sprintf(
 					ReservedKeywordsGroupPayload,
 					wareHouseTest.UserID,
-					wareHouseTest.msgID(),
+					messageID,
 					wareHouseTest.Provider,
 				),
 			)
 			send(t, payloadGroup, "group", wareHouseTest.WriteKey, "POST")
+			wareHouseTest.expectEventRows("group", messageID)
 		}
 	}
 
@@ -767,3 +803,38 @@ func send(t testing.TB, payload *strings.Reader, eventType, writeKey, method str
 
 	t.Logf("Send successfully for event: %s and writeKey: %s", eventType, writeKey)
 }
+
+// expectEventRows records the rows the fixed event of the message is expected to be loaded as into ExpectedRows, if
+// the test records them. Only the columns whose values don't depend on the warehouse are expected, the users table
+// being left out since its rows are merged. The rows of a previous user of the test are dropped, as the warehouse is
+// only verified for the current one.
+func (w *WareHouseTest) expectEventRows(eventType, messageID string) {
+	if !w.RecordExpectedRows || w.MessageID != "" {
+		return
+	}
+	if w.ExpectedRows == nil || w.expectedRowsUserID != w.UserID {
+		w.ExpectedRows = map[string][]map[string]string{}
+		w.expectedRowsUserID = w.UserID
+	}
+	expect := func(table string, row map[string]string) {
+		row["id"] = messageID
+		row["user_id"] = w.UserID
+		w.ExpectedRows[table] = append(w.ExpectedRows[table], row)
+	}
+
+	switch eventType {
+	case "identify":
+		expect("identifies", map[string]string{"context_traits_trait1": "new-val"})
+	case "track":
+		expect("tracks", map[string]string{"event": "product_track", "event_text": "Product Track"})
+		expect("product_track", map[string]string{"event": "product_track", "event_text": "Product Track", "review_id": "12345", "product_id": "123"})
+	case "page":
+		expect("pages", map[string]string{"name": "Home", "title": "Home | RudderStack", "url": "https://www.rudderstack.com"})
+	case "screen":
+		expect("screens", map[string]string{"name": "Main", "prop_key": "prop_value"})
+	case "alias":
+		expect("aliases", map[string]string{"previous_id": "name@surname.com"})
+	case "group":
+		expect("groups", map[string]string{"group_id": "groupId", "name": "MyGroup", "industry": "IT", "plan": "basic"})
+	}
+}
@@ -0,0 +1,97 @@
+package testhelper
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-server/warehouse/internal/faults"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// On top of happy-path syncs, tests can have failures injected into the uploads of their destination at the phases
+// of their choice, for the paths retrying and recovering from them to be covered:
+//   - faults.ObjectStorage fails the download of staging files, at generating_load_files
+//   - faults.WarehouseConnection fails the creation of the schema and the loading of tables, at creating_remote_schema
+//     and exporting_data
+//   - faults.NotifierTimeout times out the jobs generating load files, at generating_load_files
+//
+// Once failed, uploads are retried after Warehouse.minUploadBackoff, the events of the test being verified as they
+// would be without faults, along with the uploads or staging files having failed with the faults injected.
+// Faults are only injected by warehouses started with Warehouse.faults.enabled, as the one of .env.
+
+// Fault is a fault injected into the uploads of the destination of the test
+type Fault struct {
+	Point faults.Point
+	// Phase is the in progress state of the upload phase the fault is injected at, e.g. exporting_data
+	Phase string
+	// Times is the number of times the fault is injected, once if not set
+	Times int
+}
+
+// InjectFaults arms the faults for the uploads of the destination, disarming them once the test completes
+func InjectFaults(t testing.TB, destinationID string, faultsToInject ...Fault) {
+	t.Helper()
+
+	kvs := make([]warehouseutils.KeyValue, 0, len(faultsToInject))
+	disarm := make([]warehouseutils.KeyValue, 0, len(faultsToInject))
+	for _, fault := range faultsToInject {
+		times := fault.Times
+		if times <= 0 {
+			times = 1
+		}
+		key := faults.Key(destinationID, fault.Point, fault.Phase)
+		kvs = append(kvs, warehouseutils.KeyValue{Key: key, Value: times})
+		disarm = append(disarm, warehouseutils.KeyValue{Key: key, Value: 0})
+	}
+
+	t.Logf("Injecting faults for destination: %s, faults: %v", destinationID, faultsToInject)
+	SetConfig(t, kvs)
+	t.Cleanup(func() {
+		SetConfig(t, disarm)
+	})
+}
+
+// verifyFaultsInjected verifies the uploads or the staging files of the test failed with the faults injected
+func verifyFaultsInjected(t testing.TB, wareHouseTest *WareHouseTest) {
+	t.Helper()
+	t.Logf("Started verifying faults injected")
+
+	db := wareHouseTest.JobsDB
+	require.NotNil(t, db)
+
+	sqlStatement := `
+		SELECT
+		  (
+			SELECT
+			  COUNT(*)
+			FROM
+			  wh_uploads
+			WHERE
+			  destination_id = $1 AND
+			  created_at > $2 AND
+			  error::text LIKE $3
+		  ) + (
+			SELECT
+			  COUNT(*)
+			FROM
+			  wh_staging_files
+			WHERE
+			  destination_id = $1 AND
+			  created_at > $2 AND
+			  error LIKE $3
+		  );
+	`
+	for _, fault := range wareHouseTest.Faults {
+		pattern := fmt.Sprintf("%%%s%%", &faults.Error{Point: fault.Point, Phase: fault.Phase})
+
+		var count sql.NullInt64
+		err := db.QueryRow(sqlStatement, wareHouseTest.DestinationID, wareHouseTest.TimestampBeforeSendingEvents, pattern).Scan(&count)
+		require.NoError(t, err)
+		require.NotZerof(t, count.Int64, "no uploads or staging files failed with fault %s injected at %s", fault.Point, fault.Phase)
+	}
+
+	t.Logf("Completed verifying faults injected")
+}
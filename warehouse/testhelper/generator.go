@@ -0,0 +1,259 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Instead of the fixed events of SendEvents, load shaped tests generate their events with a Generator, configured by:
+//   - the mix of the events, as the weight of each event type
+//   - the volume of the events
+//   - the number of properties of the events, and of distinct values each property takes
+//   - the number of distinct users, and the names of the track events
+//
+// Generators are seeded, generating the same events, message ids included, for the same config, and count the rows the
+// events they generated load into each table, keeping the rows they are expected to be loaded as, to be verified
+// against the warehouse.
+
+// EventMix is the weight of each event type, among identify, track, page, screen, alias and group
+type EventMix map[string]int
+
+// GeneratorConfig is the config of the events generated
+type GeneratorConfig struct {
+	// Mix is the weight of each event type, all of them weighing the same by default
+	Mix EventMix
+	// Events is the number of events generated
+	Events int
+	// Properties is the number of properties of each event
+	Properties int
+	// Cardinality is the number of distinct values each property takes
+	Cardinality int
+	// Users is the number of distinct users the events are of
+	Users int
+	// UserIDPrefix prefixes the ids of the users
+	UserIDPrefix string
+	// TrackEvents are the names of the track events, each one loaded into a table of its own
+	TrackEvents []string
+	// Seed seeds the generation of the events
+	Seed int64
+}
+
+var defaultEventMix = EventMix{
+	"identify": 1,
+	"track":    1,
+	"page":     1,
+	"screen":   1,
+	"alias":    1,
+	"group":    1,
+}
+
+// eventTables are the tables each event type is loaded into, on top of the ones of track events
+var eventTables = map[string][]string{
+	"identify": {"identifies", "users"},
+	"track":    {"tracks"},
+	"page":     {"pages"},
+	"screen":   {"screens"},
+	"alias":    {"aliases"},
+	"group":    {"groups"},
+}
+
+// Generator generates events according to its config
+type Generator struct {
+	config     GeneratorConfig
+	rnd        *rand.Rand
+	eventTypes []string
+	weights    []int
+	total      int
+
+	generated       int
+	loadFilesCounts EventsCountMap
+	identifiedUsers map[string]struct{}
+	expectedRows    map[string][]map[string]string
+}
+
+// GeneratedEvent is an event generated, of its type, to be sent to the endpoint of the type
+type GeneratedEvent struct {
+	Type    string
+	Payload []byte
+}
+
+func NewGenerator(config GeneratorConfig) *Generator {
+	if len(config.Mix) == 0 {
+		config.Mix = defaultEventMix
+	}
+	if config.Cardinality <= 0 {
+		config.Cardinality = 1
+	}
+	if config.Users <= 0 {
+		config.Users = 1
+	}
+	if config.UserIDPrefix == "" {
+		config.UserIDPrefix = "userId"
+	}
+	if len(config.TrackEvents) == 0 {
+		config.TrackEvents = []string{"Product Track"}
+	}
+
+	g := &Generator{
+		config:          config,
+		rnd:             rand.New(rand.NewSource(config.Seed)), // nolint:gosec // reproducible events, not secrets
+		loadFilesCounts: EventsCountMap{},
+		identifiedUsers: map[string]struct{}{},
+		expectedRows:    map[string][]map[string]string{},
+	}
+	for eventType := range config.Mix {
+		g.eventTypes = append(g.eventTypes, eventType)
+	}
+	sort.Strings(g.eventTypes)
+	for _, eventType := range g.eventTypes {
+		g.weights = append(g.weights, config.Mix[eventType])
+		g.total += config.Mix[eventType]
+	}
+	return g
+}
+
+// Next returns the next event, false once all of them are generated
+func (g *Generator) Next() (GeneratedEvent, bool) {
+	if g.generated >= g.config.Events || g.total <= 0 {
+		return GeneratedEvent{}, false
+	}
+	g.generated++
+
+	eventType := g.eventType()
+	userID := fmt.Sprintf("%s_%d", g.config.UserIDPrefix, g.rnd.Intn(g.config.Users))
+	properties := map[string]interface{}{}
+	for i := 0; i < g.config.Properties; i++ {
+		properties[fmt.Sprintf("prop_%d", i)] = fmt.Sprintf("value_%d", g.rnd.Intn(g.config.Cardinality))
+	}
+
+	messageID := g.messageID()
+	event := map[string]interface{}{
+		"userId":    userID,
+		"messageId": messageID,
+		"type":      eventType,
+	}
+	for _, table := range eventTables[eventType] {
+		g.loadFilesCounts[table]++
+	}
+	row := map[string]string{"id": messageID, "user_id": userID}
+	switch eventType {
+	case "identify":
+		event["context"] = map[string]interface{}{"traits": properties}
+		g.identifiedUsers[userID] = struct{}{}
+		g.expect("identifies", row, properties, "context_traits_")
+	case "track":
+		name := g.config.TrackEvents[g.rnd.Intn(len(g.config.TrackEvents))]
+		event["event"] = name
+		event["properties"] = properties
+		g.loadFilesCounts[trackEventTable(name)]++
+		row["event"] = trackEventTable(name)
+		row["event_text"] = name
+		g.expect("tracks", row, nil, "")
+		g.expect(trackEventTable(name), row, properties, "")
+	case "page", "screen":
+		event["name"] = "Home"
+		event["properties"] = properties
+		row["name"] = "Home"
+		g.expect(eventTables[eventType][0], row, properties, "")
+	case "alias":
+		event["previousId"] = fmt.Sprintf("previous_%s", userID)
+		row["previous_id"] = fmt.Sprintf("previous_%s", userID)
+		g.expect("aliases", row, nil, "")
+	case "group":
+		event["groupId"] = "groupId"
+		event["traits"] = properties
+		row["group_id"] = "groupId"
+		g.expect("groups", row, nil, "")
+	}
+
+	payload, _ := json.Marshal(event)
+	return GeneratedEvent{Type: eventType, Payload: payload}, true
+}
+
+// expect adds the row, with the properties as columns prefixed by the prefix, to the rows expected in the table
+func (g *Generator) expect(table string, row map[string]string, properties map[string]interface{}, prefix string) {
+	expectedRow := make(map[string]string, len(row)+len(properties))
+	for column, value := range row {
+		expectedRow[column] = value
+	}
+	for property, value := range properties {
+		expectedRow[prefix+property] = fmt.Sprintf("%v", value)
+	}
+	g.expectedRows[table] = append(g.expectedRows[table], expectedRow)
+}
+
+// ExpectedRows returns the rows the events generated so far are expected to be loaded as, by table, users excluded
+// since they are merged
+func (g *Generator) ExpectedRows() map[string][]map[string]string {
+	expectedRows := make(map[string][]map[string]string, len(g.expectedRows))
+	for table, rows := range g.expectedRows {
+		expectedRows[table] = append([]map[string]string{}, rows...)
+	}
+	return expectedRows
+}
+
+// messageID returns the message id of the next event, from the seeded source of the generator
+func (g *Generator) messageID() string {
+	id, err := uuid.NewRandomFromReader(g.rnd)
+	if err != nil {
+		panic(fmt.Errorf("generating message id: %w", err))
+	}
+	return id.String()
+}
+
+// eventType picks the type of the next event, by weight
+func (g *Generator) eventType() string {
+	n := g.rnd.Intn(g.total)
+	for i, weight := range g.weights {
+		if n < weight {
+			return g.eventTypes[i]
+		}
+		n -= weight
+	}
+	return g.eventTypes[len(g.eventTypes)-1]
+}
+
+// LoadFilesEventsMap returns the rows of the load files of the events generated so far, by table
+func (g *Generator) LoadFilesEventsMap() EventsCountMap {
+	counts := EventsCountMap{}
+	for table, count := range g.loadFilesCounts {
+		counts[table] = count
+	}
+	return counts
+}
+
+// WarehouseEventsMap returns the rows the events generated so far load into the warehouse, by table, users being
+// merged by id
+func (g *Generator) WarehouseEventsMap() EventsCountMap {
+	counts := g.LoadFilesEventsMap()
+	if _, ok := counts["users"]; ok {
+		counts["users"] = len(g.identifiedUsers)
+	}
+	return counts
+}
+
+// trackEventTable returns the table track events of the name are loaded into
+func trackEventTable(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+}
+
+// SendGeneratedEvents sends the events of the generator with the write key of the test, setting the events maps of
+// the test to the ones of the events sent
+func SendGeneratedEvents(t testing.TB, wareHouseTest *WareHouseTest, g *Generator) {
+	t.Helper()
+
+	for event, ok := g.Next(); ok; event, ok = g.Next() {
+		send(t, strings.NewReader(string(event.Payload)), event.Type, wareHouseTest.WriteKey, "POST")
+	}
+
+	wareHouseTest.LoadFilesEventsMap = g.LoadFilesEventsMap()
+	wareHouseTest.TableUploadsEventsMap = g.LoadFilesEventsMap()
+	wareHouseTest.WarehouseEventsMap = g.WarehouseEventsMap()
+	wareHouseTest.ExpectedRows = g.ExpectedRows()
+}
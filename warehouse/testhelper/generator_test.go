@@ -0,0 +1,85 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func generateAll(g *Generator) []GeneratedEvent {
+	var events []GeneratedEvent
+	for event, ok := g.Next(); ok; event, ok = g.Next() {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestGenerator(t *testing.T) {
+	t.Run("same events for the same seed", func(t *testing.T) {
+		config := GeneratorConfig{Events: 50, Properties: 3, Cardinality: 5, Users: 10, Seed: 42}
+
+		events := generateAll(NewGenerator(config))
+		require.Len(t, events, 50)
+		require.Equal(t, events, generateAll(NewGenerator(config)))
+
+		config.Seed = 43
+		require.NotEqual(t, events, generateAll(NewGenerator(config)))
+	})
+
+	t.Run("events of the mix only", func(t *testing.T) {
+		g := NewGenerator(GeneratorConfig{
+			Mix:         EventMix{"track": 1, "identify": 1, "page": 0},
+			Events:      100,
+			Users:       5,
+			TrackEvents: []string{"Order Completed"},
+			Seed:        1,
+		})
+
+		messageIDs := map[string]struct{}{}
+		for _, event := range generateAll(g) {
+			require.Contains(t, []string{"track", "identify"}, event.Type)
+
+			var payload map[string]interface{}
+			require.NoError(t, json.Unmarshal(event.Payload, &payload))
+			require.Equal(t, event.Type, payload["type"])
+			messageIDs[payload["messageId"].(string)] = struct{}{}
+		}
+		require.Len(t, messageIDs, 100, "message ids are unique")
+
+		loadFiles := g.LoadFilesEventsMap()
+		require.Equal(t, 100, loadFiles["tracks"]+loadFiles["identifies"])
+		require.Equal(t, loadFiles["tracks"], loadFiles["order_completed"])
+		require.Equal(t, loadFiles["identifies"], loadFiles["users"])
+		require.NotContains(t, loadFiles, "pages")
+
+		warehouse := g.WarehouseEventsMap()
+		require.Equal(t, loadFiles["identifies"], warehouse["identifies"])
+		require.LessOrEqual(t, warehouse["users"], 5, "users are merged by id")
+	})
+
+	t.Run("expected rows", func(t *testing.T) {
+		g := NewGenerator(GeneratorConfig{
+			Mix:        EventMix{"identify": 1},
+			Events:     3,
+			Properties: 2,
+			Seed:       1,
+		})
+		events := generateAll(g)
+
+		rows := g.ExpectedRows()
+		require.Len(t, rows["identifies"], 3)
+		require.NotContains(t, rows, "users")
+		for i, row := range rows["identifies"] {
+			var payload map[string]interface{}
+			require.NoError(t, json.Unmarshal(events[i].Payload, &payload))
+			require.Equal(t, payload["messageId"], row["id"])
+			require.Equal(t, "userId_0", row["user_id"])
+			require.Contains(t, row, "context_traits_prop_0")
+			require.Contains(t, row, "context_traits_prop_1")
+		}
+
+		rows["identifies"] = nil
+		require.Len(t, g.ExpectedRows()["identifies"], 3, "rows returned are a copy")
+	})
+}
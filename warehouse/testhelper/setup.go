@@ -99,6 +99,15 @@ type WareHouseTest struct {
 	Prerequisite                 func(t testing.TB)
 	StatsToVerify                []string
 	SkipWarehouse                bool
+	// ExpectedRows are the rows expected in the tables of the warehouse, by table, diffed against them
+	ExpectedRows map[string][]map[string]string
+	// RecordExpectedRows records the rows the fixed events sent are expected to be loaded as into ExpectedRows
+	RecordExpectedRows bool
+	expectedRowsUserID string
+	// ColumnAssertions are the assertions on the columns of the tables of the warehouse, by table
+	ColumnAssertions map[string][]ColumnAssertion
+	// Faults are injected into the uploads of the destination, verified to have failed them
+	Faults []Fault
 }
 
 func (w *WareHouseTest) init() {
@@ -143,6 +152,9 @@ func (w *WareHouseTest) VerifyEvents(t testing.TB) {
 	if w.Prerequisite != nil {
 		w.Prerequisite(t)
 	}
+	if len(w.Faults) > 0 {
+		InjectFaults(t, w.DestinationID, w.Faults...)
+	}
 
 	SendEvents(t, w)
 	SendEvents(t, w)
@@ -155,6 +167,9 @@ func (w *WareHouseTest) VerifyEvents(t testing.TB) {
 	if !w.SkipWarehouse {
 		verifyEventsInWareHouse(t, w)
 	}
+	if len(w.Faults) > 0 {
+		verifyFaultsInjected(t, w)
+	}
 	verifyWorkspaceIDInStats(t, w.StatsToVerify...)
 }
 
@@ -166,6 +181,9 @@ func (w *WareHouseTest) VerifyModifiedEvents(t testing.TB) {
 	if w.Prerequisite != nil {
 		w.Prerequisite(t)
 	}
+	if len(w.Faults) > 0 {
+		InjectFaults(t, w.DestinationID, w.Faults...)
+	}
 
 	SendModifiedEvents(t, w)
 	SendModifiedEvents(t, w)
@@ -181,6 +199,9 @@ func (w *WareHouseTest) VerifyModifiedEvents(t testing.TB) {
 	if !w.SkipWarehouse {
 		verifyEventsInWareHouse(t, w)
 	}
+	if len(w.Faults) > 0 {
+		verifyFaultsInjected(t, w)
+	}
 	verifyWorkspaceIDInStats(t)
 }
 
@@ -479,6 +500,7 @@ func verifyEventsInWareHouse(t testing.TB, wareHouseTest *WareHouseTest) {
 			return nil
 		}))
 	}
+	verifyWarehouseContents(t, wareHouseTest)
 
 	t.Logf("Completed verifying events in warehouse")
 }
@@ -1020,3 +1042,16 @@ func DatabricksCredentials() (credentials databricks.CredentialsT, err error) {
 	}
 	return
 }
+
+// ShiftClock shifts the clock of the warehouse by the offset, for the scheduler to consider upload frequencies and
+// retry windows elapsed, resetting it once the test completes. Since the clock is shared by all the uploads of the
+// warehouse, tests shifting it aren't to run in parallel with others. The warehouse is to be built with the
+// warehouse_integration tag, as the one of docker-compose.test.yml.
+func ShiftClock(t testing.TB, offset time.Duration) {
+	t.Helper()
+
+	SetConfig(t, []warehouseutils.KeyValue{{Key: "Warehouse.clockOffset", Value: offset.String()}})
+	t.Cleanup(func() {
+		SetConfig(t, []warehouseutils.KeyValue{{Key: "Warehouse.clockOffset", Value: "0s"}})
+	})
+}
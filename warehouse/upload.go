@@ -16,6 +16,10 @@ import (
 	"github.com/cenkalti/backoff/v4"
 	"github.com/lib/pq"
 	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/rudderlabs/rudder-server/config"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
@@ -24,9 +28,9 @@ import (
 	"github.com/rudderlabs/rudder-server/services/pgnotifier"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/misc"
-	"github.com/rudderlabs/rudder-server/utils/timeutil"
 	"github.com/rudderlabs/rudder-server/utils/types"
 	"github.com/rudderlabs/rudder-server/warehouse/identity"
+	"github.com/rudderlabs/rudder-server/warehouse/internal/faults"
 	"github.com/rudderlabs/rudder-server/warehouse/internal/model"
 	"github.com/rudderlabs/rudder-server/warehouse/manager"
 	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
@@ -107,6 +111,9 @@ type Upload struct {
 
 type tableNameT string
 
+// tracerName is the name of the tracer of the spans of uploads
+const tracerName = "github.com/rudderlabs/rudder-server/warehouse"
+
 type UploadJobT struct {
 	upload               *Upload
 	dbHandle             *sql.DB
@@ -114,7 +121,7 @@ type UploadJobT struct {
 	whManager            manager.ManagerI
 	stagingFiles         []*model.StagingFile
 	stagingFileIDs       []int64
-	pgNotifier           *pgnotifier.PgNotifierT
+	pgNotifier           uploadNotifier
 	schemaHandle         *SchemaHandleT
 	schemaLock           sync.Mutex
 	uploadLock           sync.Mutex
@@ -122,6 +129,7 @@ type UploadJobT struct {
 	tableUploadStatuses  []*TableUploadStatusT
 	destinationValidator validations.DestinationValidator
 	stats                stats.Stats
+	now                  func() time.Time
 }
 
 type UploadColumnT struct {
@@ -152,6 +160,9 @@ var (
 	columnCountLimitMap map[string]int
 )
 
+// errUploadDrained is returned by uploads stopped since their workspace is handed over
+var errUploadDrained = errors.New("upload stopped as its workspace is drained")
+
 func Init() {
 	setMaxParallelLoads()
 }
@@ -248,7 +259,7 @@ func (job *UploadJobT) initTableUploads() error {
 		}
 	}
 
-	return createTableUploads(job.upload.ID, tables)
+	return createTableUploads(job.upload.ID, tables, job.currentTime())
 }
 
 func (job *UploadJobT) syncRemoteSchema() (schemaChanged bool, err error) {
@@ -349,20 +360,40 @@ func (job *UploadJobT) matchRowsInStagingAndLoadFiles() {
 	}
 }
 
+// startSpan starts the span of the upload, from the tracer provider registered when the upload is run, exporting it
+// if OpenTelemetry.traces.enabled
+func (job *UploadJobT) startSpan() trace.Span {
+	_, span := otel.Tracer(tracerName).Start(context.Background(), "warehouse.upload", trace.WithAttributes(
+		attribute.Int64("uploadID", job.upload.ID),
+		attribute.String("destType", job.warehouse.Type),
+		attribute.String("destID", job.upload.DestinationID),
+	))
+	return span
+}
+
+// endSpan ends the span of the upload, linking the upload_time of the upload to it as an exemplar
+func (job *UploadJobT) endSpan(span trace.Span, uploadTime time.Duration, err error) {
+	stats.SendTimingWithTrace(job.timerStat("upload_time"), uploadTime, span.SpanContext())
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 func (job *UploadJobT) run() (err error) {
-	timerStat := job.timerStat("upload_time")
-	timerStat.Start()
+	uploadStartTime := time.Now()
+	span := job.startSpan()
 	ch := job.trackLongRunningUpload()
 	defer func() {
 		job.setUploadColumns(UploadColumnsOpts{Fields: []UploadColumnT{{Column: UploadInProgress, Value: false}}})
 
-		timerStat.End()
+		job.endSpan(span, time.Since(uploadStartTime), err)
 		ch <- struct{}{}
 	}()
 
 	job.uploadLock.Lock()
 	defer job.uploadLock.Unlock()
-	job.setUploadColumns(UploadColumnsOpts{Fields: []UploadColumnT{{Column: UploadLastExecAtField, Value: timeutil.Now()}, {Column: UploadInProgress, Value: true}}})
+	job.setUploadColumns(UploadColumnsOpts{Fields: []UploadColumnT{{Column: UploadLastExecAtField, Value: job.currentTime()}, {Column: UploadInProgress, Value: true}}})
 
 	if len(job.stagingFiles) == 0 {
 		err := fmt.Errorf("no staging files found")
@@ -455,7 +486,7 @@ func (job *UploadJobT) run() (err error) {
 		case model.UpdatedTableUploadsCounts:
 			newStatus = nextUploadState.failed
 			for tableName := range job.upload.UploadSchema {
-				tableUpload := NewTableUpload(job.upload.ID, tableName)
+				tableUpload := job.newTableUpload(tableName)
 				err = tableUpload.updateTableEventsCount(job)
 				if err != nil {
 					break
@@ -468,6 +499,9 @@ func (job *UploadJobT) run() (err error) {
 
 		case model.CreatedRemoteSchema:
 			newStatus = nextUploadState.failed
+			if err = job.injectFault(faults.WarehouseConnection); err != nil {
+				break
+			}
 			if len(schemaHandle.schemaInWarehouse) == 0 {
 				err = whManager.CreateSchema()
 				if err != nil {
@@ -562,10 +596,14 @@ func (job *UploadJobT) run() (err error) {
 
 		if err != nil {
 			pkgLogger.Errorf("[WH] Upload: %d, TargetState: %s, NewState: %s, Error: %v", job.upload.ID, targetStatus, newStatus, err.Error())
+			uploadErr := err
 			state, err := job.setUploadError(err, newStatus)
 			if err == nil && state == model.Aborted {
 				job.generateUploadAbortedMetrics()
 			}
+			if err == nil {
+				job.recordStagingFilesProgress(state, uploadErr)
+			}
 			break
 		}
 
@@ -602,10 +640,20 @@ func (job *UploadJobT) run() (err error) {
 		// record metric for time taken by the current state
 		job.timerStat(nextUploadState.inProgress).SendTiming(time.Since(stateStartTime))
 
+		if newStatus == model.GeneratedLoadFiles || newStatus == model.ExportedData {
+			job.recordStagingFilesProgress(newStatus, nil)
+		}
+
 		if newStatus == model.ExportedData {
 			break
 		}
 
+		// stopping at the completed step while the workspace is handed over, for the upload to be resumed from there
+		if tenantManager.DrainingWorkspace(job.upload.WorkspaceID) {
+			pkgLogger.Infof("[WH] Upload: %d, Workspace %s drained, stopping at state: %s", job.upload.ID, job.upload.WorkspaceID, newStatus)
+			return errUploadDrained
+		}
+
 		nextUploadState = getNextUploadState(newStatus)
 	}
 
@@ -951,7 +999,7 @@ func (job *UploadJobT) loadAllTablesExcept(skipLoadForTables []string, loadFiles
 		if !hasLoadFiles {
 			wg.Done()
 			if misc.Contains(alwaysMarkExported, strings.ToLower(tableName)) {
-				tableUpload := NewTableUpload(job.upload.ID, tableName)
+				tableUpload := job.newTableUpload(tableName)
 				tableUpload.setStatus(TableUploadExported)
 			}
 			continue
@@ -1024,7 +1072,7 @@ func (job *UploadJobT) getTotalCount(tName string) (int64, error) {
 }
 
 func (job *UploadJobT) loadTable(tName string) (alteredSchema bool, err error) {
-	tableUpload := NewTableUpload(job.upload.ID, tName)
+	tableUpload := job.newTableUpload(tName)
 	alteredSchema, err = job.updateSchema(tName)
 	if err != nil {
 		tableUpload.setError(TableUploadUpdatingSchemaFailed, err)
@@ -1050,7 +1098,10 @@ func (job *UploadJobT) loadTable(tName string) (alteredSchema bool, err error) {
 		}
 	}
 
-	err = job.whManager.LoadTable(tName)
+	err = job.injectFault(faults.WarehouseConnection)
+	if err == nil {
+		err = job.whManager.LoadTable(tName)
+	}
 	if err != nil {
 		tableUpload.setError(TableUploadExportingFailed, err)
 		return
@@ -1148,7 +1199,7 @@ func (job *UploadJobT) loadUserTables(loadFilesTableMap map[tableNameT]bool) ([]
 	loadTimeStat.Start()
 
 	// Load all user tables
-	identityTableUpload := NewTableUpload(job.upload.ID, job.identifiesTableName())
+	identityTableUpload := job.newTableUpload(job.identifiesTableName())
 	identityTableUpload.setStatus(TableUploadExecuting)
 	alteredIdentitySchema, err := job.updateSchema(job.identifiesTableName())
 	if err != nil {
@@ -1157,7 +1208,7 @@ func (job *UploadJobT) loadUserTables(loadFilesTableMap map[tableNameT]bool) ([]
 	}
 	var alteredUserSchema bool
 	if _, ok := job.upload.UploadSchema[job.usersTableName()]; ok {
-		userTableUpload := NewTableUpload(job.upload.ID, job.usersTableName())
+		userTableUpload := job.newTableUpload(job.usersTableName())
 		userTableUpload.setStatus(TableUploadExecuting)
 		alteredUserSchema, err = job.updateSchema(job.usersTableName())
 		if err != nil {
@@ -1165,7 +1216,12 @@ func (job *UploadJobT) loadUserTables(loadFilesTableMap map[tableNameT]bool) ([]
 			return job.processLoadTableResponse(map[string]error{job.usersTableName(): err})
 		}
 	}
-	errorMap := job.whManager.LoadUserTables()
+	var errorMap map[string]error
+	if err = job.injectFault(faults.WarehouseConnection); err != nil {
+		errorMap = map[string]error{job.identifiesTableName(): err}
+	} else {
+		errorMap = job.whManager.LoadUserTables()
+	}
 
 	if alteredIdentitySchema || alteredUserSchema {
 		pkgLogger.Infof("loadUserTables: schema changed - updating local schema for %s", job.warehouse.Identifier)
@@ -1202,7 +1258,7 @@ func (job *UploadJobT) loadIdentityTables(populateHistoricIdentities bool) (load
 		}
 
 		errorMap[tableName] = nil
-		tableUpload := NewTableUpload(job.upload.ID, tableName)
+		tableUpload := job.newTableUpload(tableName)
 
 		tableSchemaDiff := getTableSchemaDiff(tableName, job.schemaHandle.schemaInWarehouse, job.upload.UploadSchema)
 		if tableSchemaDiff.Exists {
@@ -1252,7 +1308,7 @@ func (job *UploadJobT) setUpdatedTableSchema(tableName string, updatedSchema map
 func (job *UploadJobT) processLoadTableResponse(errorMap map[string]error) (errors []error, tableUploadErr error) {
 	for tName, loadErr := range errorMap {
 		// TODO: set last_exec_time
-		tableUpload := NewTableUpload(job.upload.ID, tName)
+		tableUpload := job.newTableUpload(tName)
 		if loadErr != nil {
 			errors = append(errors, loadErr)
 			tableUploadErr = tableUpload.setError(TableUploadExportingFailed, loadErr)
@@ -1302,7 +1358,7 @@ func (job *UploadJobT) getUploadTimings() (timings []map[string]string) {
 // e.g. status: exported_data, timings: [{exporting_data: 2020-04-21 15:16:19.687716] -> [{exporting_data: 2020-04-21 15:16:19.687716, exported_data: 2020-04-21 15:26:34.344356}]
 func (job *UploadJobT) getNewTimings(status string) ([]byte, []map[string]string) {
 	timings := job.getUploadTimings()
-	timing := map[string]string{status: timeutil.Now().Format(misc.RFC3339Milli)}
+	timing := map[string]string{status: job.currentTime().Format(misc.RFC3339Milli)}
 	timings = append(timings, timing)
 	marshalledTimings, err := json.Marshal(timings)
 	if err != nil {
@@ -1344,7 +1400,7 @@ func (job *UploadJobT) setUploadStatus(statusOpts UploadStatusOpts) (err error)
 	opts := []UploadColumnT{
 		{Column: UploadStatusField, Value: statusOpts.Status},
 		{Column: UploadTimingsField, Value: marshalledTimings},
-		{Column: UploadUpdatedAtField, Value: timeutil.Now()},
+		{Column: UploadUpdatedAtField, Value: job.currentTime()},
 	}
 
 	job.upload.Status = statusOpts.Status
@@ -1448,7 +1504,7 @@ func (job *UploadJobT) triggerUploadNow() (err error) {
 	if unmarshallErr != nil {
 		metadata = make(map[string]interface{})
 	}
-	metadata["nextRetryTime"] = time.Now().Add(-time.Hour * 1).Format(time.RFC3339)
+	metadata["nextRetryTime"] = job.currentTime().Add(-time.Hour * 1).Format(time.RFC3339)
 	metadata["retried"] = true
 	metadata["priority"] = 50
 	metadataJSON, err := json.Marshal(metadata)
@@ -1459,7 +1515,7 @@ func (job *UploadJobT) triggerUploadNow() (err error) {
 	uploadColumns := []UploadColumnT{
 		{Column: "status", Value: newJobState},
 		{Column: "metadata", Value: metadataJSON},
-		{Column: "updated_at", Value: timeutil.Now()},
+		{Column: "updated_at", Value: job.currentTime()},
 	}
 
 	txn, err := job.dbHandle.Begin()
@@ -1524,7 +1580,7 @@ func (job *UploadJobT) Aborted(attempts int, startTime time.Time) bool {
 	if job.hasAllTablesSkipped {
 		return false
 	}
-	return attempts > minRetryAttempts && timeutil.Now().Sub(startTime) > retryTimeWindow
+	return attempts > minRetryAttempts && job.currentTime().Sub(startTime) > retryTimeWindow
 }
 
 func (job *UploadJobT) setUploadError(statusError error, state string) (string, error) {
@@ -1559,7 +1615,7 @@ func (job *UploadJobT) setUploadError(statusError error, state string) (string,
 	if unmarshallErr != nil {
 		metadata = make(map[string]interface{})
 	}
-	metadata["nextRetryTime"] = timeutil.Now().Add(DurationBeforeNextAttempt(upload.Attempts + 1)).Format(time.RFC3339)
+	metadata["nextRetryTime"] = job.currentTime().Add(DurationBeforeNextAttempt(upload.Attempts + 1)).Format(time.RFC3339)
 	metadataJSON, err := json.Marshal(metadata)
 	if err != nil {
 		metadataJSON = []byte("{}")
@@ -1571,7 +1627,7 @@ func (job *UploadJobT) setUploadError(statusError error, state string) (string,
 		{Column: "status", Value: state},
 		{Column: "metadata", Value: metadataJSON},
 		{Column: "error", Value: serializedErr},
-		{Column: "updated_at", Value: timeutil.Now()},
+		{Column: "updated_at", Value: job.currentTime()},
 	}
 
 	txn, err := job.dbHandle.Begin()
@@ -1698,7 +1754,7 @@ func (job *UploadJobT) getAttemptNumber() int {
 	return int(attempts)
 }
 
-func (*UploadJobT) setStagingFilesStatus(stagingFiles []*model.StagingFile, status string) (err error) {
+func (job *UploadJobT) setStagingFilesStatus(stagingFiles []*model.StagingFile, status string) (err error) {
 	var ids []int64
 	for _, stagingFile := range stagingFiles {
 		ids = append(ids, stagingFile.ID)
@@ -1714,7 +1770,7 @@ func (*UploadJobT) setStagingFilesStatus(stagingFiles []*model.StagingFile, stat
 `,
 		warehouseutils.WarehouseStagingFilesTable,
 	)
-	_, err = dbHandle.Exec(sqlStatement, status, timeutil.Now(), pq.Array(ids))
+	_, err = dbHandle.Exec(sqlStatement, status, job.currentTime(), pq.Array(ids))
 	if err != nil {
 		panic(err)
 	}
@@ -1867,7 +1923,7 @@ func (job *UploadJobT) createLoadFiles(generateAll bool) (startLoadFileID, endLo
 	}
 	pkgLogger.Infof("[WH]: Starting batch processing %v stage files for %s:%s", publishBatchSize, destType, destID)
 	uniqueLoadGenID := misc.FastUUID().String()
-	job.upload.LoadFileGenStartTime = timeutil.Now()
+	job.upload.LoadFileGenStartTime = job.currentTime()
 
 	// Getting distinct destination revision ID from staging files metadata
 	destinationRevisionIDMap, err := job.destinationRevisionIDMap()
@@ -1959,6 +2015,12 @@ func (job *UploadJobT) createLoadFiles(generateAll bool) (startLoadFileID, endLo
 		batchEndIdx := j
 		rruntime.GoForWarehouse(func() {
 			responses := <-ch
+			if err := job.injectFault(faults.NotifierTimeout); err != nil {
+				for i := range responses {
+					responses[i].Status = "aborted"
+					responses[i].Error = err.Error()
+				}
+			}
 			pkgLogger.Infof("[WH]: Received responses for staging files %d:%d for %s:%s from PgNotifier", toProcessStagingFiles[batchStartIdx].ID, toProcessStagingFiles[batchEndIdx-1].ID, destType, destID)
 			var loadFiles []loadFileUploadOutputT
 			var successfulStagingFileIDs []int64
@@ -2029,7 +2091,7 @@ func (job *UploadJobT) createLoadFiles(generateAll bool) (startLoadFileID, endLo
 	return startLoadFileID, endLoadFileID, nil
 }
 
-func (*UploadJobT) setStagingFileSuccess(stagingFileIDs []int64) {
+func (job *UploadJobT) setStagingFileSuccess(stagingFileIDs []int64) {
 	// using ANY instead of IN as WHERE clause filtering on primary key index uses index scan in both cases
 	// use IN for cases where filtering on composite indexes
 	sqlStatement := fmt.Sprintf(`
@@ -2043,13 +2105,13 @@ func (*UploadJobT) setStagingFileSuccess(stagingFileIDs []int64) {
 `,
 		warehouseutils.WarehouseStagingFilesTable,
 	)
-	_, err := dbHandle.Exec(sqlStatement, warehouseutils.StagingFileSucceededState, timeutil.Now(), pq.Array(stagingFileIDs))
+	_, err := dbHandle.Exec(sqlStatement, warehouseutils.StagingFileSucceededState, job.currentTime(), pq.Array(stagingFileIDs))
 	if err != nil {
 		panic(err)
 	}
 }
 
-func (*UploadJobT) setStagingFileErr(stagingFileID int64, statusErr error) {
+func (job *UploadJobT) setStagingFileErr(stagingFileID int64, statusErr error) {
 	sqlStatement := fmt.Sprintf(`
 		UPDATE
 		  %s
@@ -2062,7 +2124,7 @@ func (*UploadJobT) setStagingFileErr(stagingFileID int64, statusErr error) {
 `,
 		warehouseutils.WarehouseStagingFilesTable,
 	)
-	_, err := dbHandle.Exec(sqlStatement, warehouseutils.StagingFileFailedState, misc.QuoteLiteral(statusErr.Error()), timeutil.Now(), stagingFileID)
+	_, err := dbHandle.Exec(sqlStatement, warehouseutils.StagingFileFailedState, misc.QuoteLiteral(statusErr.Error()), job.currentTime(), stagingFileID)
 	if err != nil {
 		panic(err)
 	}
@@ -2084,7 +2146,7 @@ func (job *UploadJobT) bulkInsertLoadFileRecords(loadFiles []loadFileUploadOutpu
 
 	for _, loadFile := range loadFiles {
 		metadata := fmt.Sprintf(`{"content_length": %d, "destination_revision_id": %q, "use_rudder_storage": %t}`, loadFile.ContentLength, loadFile.DestinationRevisionID, loadFile.UseRudderStorage)
-		_, err = stmt.Exec(loadFile.StagingFileID, loadFile.Location, job.upload.SourceID, job.upload.DestinationID, job.upload.DestinationType, loadFile.TableName, loadFile.TotalRows, timeutil.Now(), metadata)
+		_, err = stmt.Exec(loadFile.StagingFileID, loadFile.Location, job.upload.SourceID, job.upload.DestinationID, job.upload.DestinationType, loadFile.TableName, loadFile.TotalRows, job.currentTime(), metadata)
 		if err != nil {
 			pkgLogger.Errorf(`[WH]: Error copying row in pq.CopyIn for loadFiles: %v Error: %v`, loadFile, err)
 			txn.Rollback()
@@ -2302,6 +2364,11 @@ func getNextUploadState(dbStatus string) *uploadStateT {
 	return nil
 }
 
+// injectFault returns the fault injected at the point of the phase the upload is in, if armed for its destination
+func (job *UploadJobT) injectFault(point faults.Point) error {
+	return faults.Inject(job.upload.DestinationID, point, job.upload.Status)
+}
+
 func getInProgressState(state string) string {
 	uploadState, ok := stateTransitions[state]
 	if !ok {
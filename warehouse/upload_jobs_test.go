@@ -0,0 +1,191 @@
+package warehouse
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	mock_manager "github.com/rudderlabs/rudder-server/mocks/warehouse/manager"
+	"github.com/rudderlabs/rudder-server/services/stats/memstats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/warehouse/internal/fixtures"
+	"github.com/rudderlabs/rudder-server/warehouse/internal/model"
+	"github.com/rudderlabs/rudder-server/warehouse/manager"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+func testWarehouse() warehouseutils.Warehouse {
+	return warehouseutils.Warehouse{
+		WorkspaceID: fixtures.WorkspaceID,
+		Source: backendconfig.SourceT{
+			ID: fixtures.SourceID,
+		},
+		Destination: backendconfig.DestinationT{
+			ID: fixtures.DestinationID,
+			Config: map[string]interface{}{
+				"syncFrequency": "30",
+			},
+		},
+		Namespace:  fixtures.Namespace,
+		Type:       warehouseutils.POSTGRES,
+		Identifier: warehouseutils.GetWarehouseIdentifier(warehouseutils.POSTGRES, fixtures.SourceID, fixtures.DestinationID),
+	}
+}
+
+func TestHandleT_CreateJobs(t *testing.T) {
+	Init4()
+	pkgLogger = logger.NOP
+
+	errCrashRecovery := errors.New("crash recovery failed")
+
+	testCases := []struct {
+		name           string
+		managerErr     error
+		crashRecover   error
+		wantErr        error
+		wantInRecovery bool
+	}{
+		{
+			name:           "manager unavailable",
+			managerErr:     errors.New("unknown destination type"),
+			wantErr:        errors.New("unknown destination type"),
+			wantInRecovery: true,
+		},
+		{
+			name:           "crash recovery failed",
+			crashRecover:   errCrashRecovery,
+			wantErr:        errCrashRecovery,
+			wantInRecovery: true,
+		},
+		{
+			name: "crash recovered",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			warehouse := testWarehouse()
+			inRecoveryMap = map[string]bool{warehouse.Destination.ID: true}
+			// an upload was just created, for the scheduler to stop short of the uploads
+			setLastProcessedMarker(warehouse, time.Now())
+
+			ctrl := gomock.NewController(t)
+			whManager := mock_manager.NewMockManagerI(ctrl)
+			if tc.managerErr == nil {
+				whManager.EXPECT().CrashRecover(warehouse).Return(tc.crashRecover).Times(1)
+			}
+
+			wh := HandleT{
+				destType: warehouseutils.POSTGRES,
+				newManager: func(destType string) (manager.ManagerI, error) {
+					require.Equal(t, warehouseutils.POSTGRES, destType)
+					if tc.managerErr != nil {
+						return nil, tc.managerErr
+					}
+					return whManager, nil
+				},
+				stats: memstats.New(),
+			}
+
+			err := wh.createJobs(context.Background(), warehouse)
+			if tc.wantErr != nil {
+				require.EqualError(t, err, tc.wantErr.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.wantInRecovery, inRecoveryMap[warehouse.Destination.ID])
+		})
+	}
+}
+
+func TestHandleT_NewUploadJob(t *testing.T) {
+	pkgLogger = logger.NOP
+
+	var (
+		ctx          = context.Background()
+		warehouse    = testWarehouse()
+		stagingFiles = fixtures.NewStagingFileBuilder().
+				WithDestination(warehouse.Source.ID, warehouse.Destination.ID).
+				WithTotalEvents(10).
+				BuildN(11, 20)
+	)
+
+	t.Run("with the staging files of the upload", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		stagingRepo := NewMockstagingFilesRepo(ctrl)
+		stagingRepo.EXPECT().GetInRange(ctx, warehouse.Source.ID, warehouse.Destination.ID, int64(11), int64(20)).Return(stagingFiles, nil).Times(1)
+		whManager := mock_manager.NewMockManagerI(ctrl)
+		notifier := NewMockuploadNotifier(ctrl)
+
+		wh := HandleT{
+			destType:    warehouseutils.POSTGRES,
+			stagingRepo: stagingRepo,
+			notifier:    notifier,
+			newManager: func(string) (manager.ManagerI, error) {
+				return whManager, nil
+			},
+		}
+
+		upload := newUploadBuilder().
+			withDestination(warehouse.Type, warehouse.Source.ID, warehouse.Destination.ID).
+			withStatus(model.GeneratedLoadFiles).
+			withStagingFiles(11, 20).
+			withPriority(50).
+			build()
+		job, err := wh.newUploadJob(ctx, &upload, warehouse)
+		require.NoError(t, err)
+		require.Equal(t, &upload, job.upload)
+		require.Equal(t, warehouse, job.warehouse)
+		require.Equal(t, whManager, job.whManager)
+		require.Equal(t, notifier, job.pgNotifier)
+		require.Equal(t, []int64{11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, job.stagingFileIDs)
+		require.Len(t, job.stagingFiles, len(stagingFiles))
+		for i, stagingFile := range job.stagingFiles {
+			require.Equal(t, stagingFiles[i], *stagingFile)
+		}
+	})
+
+	t.Run("staging files unavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		stagingRepo := NewMockstagingFilesRepo(ctrl)
+		stagingRepo.EXPECT().GetInRange(ctx, warehouse.Source.ID, warehouse.Destination.ID, int64(1), int64(1)).Return(nil, errors.New("connection refused")).Times(1)
+
+		wh := HandleT{
+			destType:    warehouseutils.POSTGRES,
+			stagingRepo: stagingRepo,
+			newManager: func(string) (manager.ManagerI, error) {
+				t.Fatal("manager not expected once the staging files are unavailable")
+				return nil, errors.New("manager not expected")
+			},
+		}
+
+		upload := newUploadBuilder().withStagingFiles(1, 1).build()
+		_, err := wh.newUploadJob(ctx, &upload, warehouse)
+		require.EqualError(t, err, "connection refused")
+	})
+
+	t.Run("manager unavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		stagingRepo := NewMockstagingFilesRepo(ctrl)
+		stagingRepo.EXPECT().GetInRange(ctx, warehouse.Source.ID, warehouse.Destination.ID, int64(1), int64(1)).Return([]model.StagingFile{
+			fixtures.NewStagingFileBuilder().WithStatus(warehouseutils.StagingFileSucceededState).Build(),
+		}, nil).Times(1)
+
+		wh := HandleT{
+			destType:    "unknown",
+			stagingRepo: stagingRepo,
+			newManager:  manager.New,
+		}
+
+		upload := newUploadBuilder().withStagingFiles(1, 1).build()
+		_, err := wh.newUploadJob(ctx, &upload, warehouse)
+		require.EqualError(t, err, "provider of type unknown is not configured for WarehouseManager")
+	})
+}
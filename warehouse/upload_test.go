@@ -5,10 +5,17 @@ import (
 	"errors"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/metric"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/services/stats/memstats"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
@@ -183,6 +190,39 @@ func TestColumnCountStat(t *testing.T) {
 	}
 }
 
+func TestUploadSpan(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	spanRecorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+
+	c := config.New()
+	c.Set("RuntimeStats.enabled", false)
+	c.Set("statsExemplars.metrics", []string{"upload_time"})
+	statsStore := stats.NewStats(c, logger.NewFactory(c), metric.NewManager())
+
+	job := UploadJobT{
+		upload:    &Upload{ID: 1, WorkspaceID: "workspace-id", DestinationID: "destination-id", SourceID: "source-id"},
+		warehouse: warehouseutils.Warehouse{Type: warehouseutils.POSTGRES},
+		stats:     statsStore,
+	}
+	span := job.startSpan()
+	job.endSpan(span, 2*time.Second, errors.New("upload failed"))
+
+	require.Len(t, spanRecorder.Ended(), 1)
+	ended := spanRecorder.Ended()[0]
+	require.Equal(t, "warehouse.upload", ended.Name())
+	require.Equal(t, codes.Error, ended.Status().Code)
+
+	reporter, ok := statsStore.(stats.ExemplarReporter)
+	require.True(t, ok)
+	histograms := reporter.Exemplars()
+	require.Len(t, histograms, 1)
+	require.Equal(t, "upload_time", histograms[0].Name)
+	require.Len(t, histograms[0].Buckets, 1)
+	require.Equal(t, ended.SpanContext().TraceID().String(), histograms[0].Buckets[0].TraceID, "the upload time is linked to the trace of the upload")
+	require.Equal(t, 2.0, histograms[0].Buckets[0].Value)
+}
+
 var _ = Describe("Upload", Ordered, func() {
 	var (
 		sourceID        = "test-sourceID"
@@ -0,0 +1,74 @@
+package warehouseutils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+)
+
+// StreamingGZWriter is a LoadFileWriterI which gzips everything written to it and streams it
+// straight to object storage, without creating the load file on local disk first.
+type StreamingGZWriter struct {
+	uploadWriter *filemanager.UploadWriter
+	gzWriter     *gzip.Writer
+	bufWriter    *bufio.Writer
+}
+
+// CreateStreamingGZWriter starts a streaming upload of objName (relative to the configured prefix of manager)
+func CreateStreamingGZWriter(ctx context.Context, manager filemanager.FileManager, objName string) *StreamingGZWriter {
+	uploadWriter := filemanager.NewUploadWriter(ctx, manager, objName)
+	gzWriter := gzip.NewWriter(uploadWriter)
+	return &StreamingGZWriter{
+		uploadWriter: uploadWriter,
+		gzWriter:     gzWriter,
+		bufWriter:    bufio.NewWriter(gzWriter),
+	}
+}
+
+func (w *StreamingGZWriter) WriteGZ(s string) error {
+	_, err := w.bufWriter.WriteString(s)
+	return err
+}
+
+func (w *StreamingGZWriter) Write(p []byte) (int, error) {
+	return w.bufWriter.Write(p)
+}
+
+func (*StreamingGZWriter) WriteRow(_ []interface{}) error {
+	return errors.New("not implemented")
+}
+
+// Close flushes the remaining data and waits for the upload to complete
+func (w *StreamingGZWriter) Close() error {
+	if err := w.bufWriter.Flush(); err != nil {
+		return w.uploadWriter.Abort(err)
+	}
+	if err := w.gzWriter.Close(); err != nil {
+		return w.uploadWriter.Abort(err)
+	}
+	return w.uploadWriter.Close()
+}
+
+// Abort cancels the upload if it is still in progress
+func (w *StreamingGZWriter) Abort() {
+	_ = w.uploadWriter.Abort(errors.New("load file upload aborted"))
+}
+
+// GetLoadFile always returns nil, since streamed load files are never written to local disk
+func (*StreamingGZWriter) GetLoadFile() *os.File {
+	return nil
+}
+
+// UploadOutput returns the location of the uploaded load file. It is only valid after Close.
+func (w *StreamingGZWriter) UploadOutput() filemanager.UploadOutput {
+	return w.uploadWriter.Output()
+}
+
+// ContentLength returns the compressed size of the load file
+func (w *StreamingGZWriter) ContentLength() int64 {
+	return w.uploadWriter.BytesWritten()
+}
@@ -0,0 +1,73 @@
+package warehouseutils_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mock_filemanager "github.com/rudderlabs/rudder-server/mocks/services/filemanager"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+func TestStreamingGZWriter(t *testing.T) {
+	t.Run("uploads gzipped content", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		fm := mock_filemanager.NewMockFileManager(ctrl)
+
+		var uploaded bytes.Buffer
+		fm.EXPECT().UploadReader(gomock.Any(), "table/load.csv.gz", gomock.Any()).DoAndReturn(
+			func(_ context.Context, objName string, rdr io.Reader) (filemanager.UploadOutput, error) {
+				_, err := io.Copy(&uploaded, rdr)
+				return filemanager.UploadOutput{Location: "s3://bucket/" + objName, ObjectName: objName}, err
+			},
+		)
+
+		w := warehouseutils.CreateStreamingGZWriter(context.Background(), fm, "table/load.csv.gz")
+		require.NoError(t, w.WriteGZ("a,b\n"))
+		_, err := w.Write([]byte("c,d\n"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		require.Nil(t, w.GetLoadFile())
+		require.Equal(t, "s3://bucket/table/load.csv.gz", w.UploadOutput().Location)
+		require.EqualValues(t, uploaded.Len(), w.ContentLength())
+
+		gzReader, err := gzip.NewReader(&uploaded)
+		require.NoError(t, err)
+		content, err := io.ReadAll(gzReader)
+		require.NoError(t, err)
+		require.Equal(t, "a,b\nc,d\n", string(content))
+	})
+
+	t.Run("upload failure is returned on close", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		fm := mock_filemanager.NewMockFileManager(ctrl)
+		fm.EXPECT().UploadReader(gomock.Any(), gomock.Any(), gomock.Any()).Return(filemanager.UploadOutput{}, errors.New("access denied"))
+
+		w := warehouseutils.CreateStreamingGZWriter(context.Background(), fm, "table/load.csv.gz")
+		_ = w.WriteGZ("a,b\n")
+		require.EqualError(t, w.Close(), "access denied")
+	})
+
+	t.Run("abort cancels the upload", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		fm := mock_filemanager.NewMockFileManager(ctrl)
+		fm.EXPECT().UploadReader(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, rdr io.Reader) (filemanager.UploadOutput, error) {
+				_, err := io.Copy(io.Discard, rdr)
+				return filemanager.UploadOutput{}, err
+			},
+		)
+
+		w := warehouseutils.CreateStreamingGZWriter(context.Background(), fm, "table/load.csv.gz")
+		require.NoError(t, w.WriteGZ("a,b\n"))
+		w.Abort()
+		require.Error(t, w.Close())
+	})
+}
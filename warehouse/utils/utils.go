@@ -343,6 +343,21 @@ type TriggerUploadRequestT struct {
 	DestinationID string `json:"destination_id"`
 }
 
+// PresignedURLRequestT identifies the staging or load file to pre-sign by its id, the location being resolved from the
+// warehouse database for arbitrary objects of the bucket not to be signed
+type PresignedURLRequestT struct {
+	WorkspaceID   string `json:"workspace_id"`
+	SourceID      string `json:"source_id"`
+	DestinationID string `json:"destination_id"`
+	StagingFileID int64  `json:"staging_file_id"`
+	LoadFileID    int64  `json:"load_file_id"`
+	ExpiryInS     int    `json:"expiry_in_s"`
+}
+
+type PresignedURLResponseT struct {
+	URL string `json:"url"`
+}
+
 type LoadFileWriterI interface {
 	WriteGZ(s string) error
 	Write(p []byte) (int, error)
@@ -724,6 +739,16 @@ func SortColumnKeysFromColumnMap(columnMap map[string]string) []string {
 	return columnKeys
 }
 
+// SortedTableNames returns the names of the tables of the schema, sorted
+func SortedTableNames(schema SchemaT) []string {
+	tableNames := make([]string, 0, len(schema))
+	for tableName := range schema {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	return tableNames
+}
+
 func IdentityMergeRulesTableName(warehouse Warehouse) string {
 	return fmt.Sprintf(`%s_%s_%s`, IdentityMergeRulesTable, warehouse.Namespace, warehouse.Destination.ID)
 }
@@ -0,0 +1,97 @@
+package validations
+
+import (
+	"strings"
+
+	"github.com/rudderlabs/rudder-server/warehouse/manager"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// Validating a destination also returns the DDL statements its warehouse would be set up with for ddlPreviewSchema,
+// generated without connecting to the warehouse, for DBAs to review them before granting the privileges they need.
+// The statements are the ones of the namespace configured for the destination, or of TestNamespace if it has none,
+// since the namespaces of the others depend on the sources. Destinations whose managers can't preview their DDL, such as
+// the data lakes, return none.
+//
+// The preview is partial: it only covers the statements creating the schema and the tables. Uploads also run statements
+// which aren't previewed and need privileges of their own:
+//   - ALTER TABLE ... ADD COLUMN, when events bring new columns
+//   - creating, loading and dropping staging tables, in the schema of the destination
+//   - the DELETE or MERGE statements deduplicating the loaded rows, e.g. of the users and identifies tables
+
+// ddlPreviewSchema is the sample schema the DDL is previewed for, tables of events having their own columns on top of
+// the ones of tracks
+var ddlPreviewSchema = warehouseutils.SchemaT{
+	"tracks": {
+		"id":                 "string",
+		"anonymous_id":       "string",
+		"user_id":            "string",
+		"event":              "string",
+		"event_text":         "string",
+		"context_source_id":  "string",
+		"original_timestamp": "datetime",
+		"sent_at":            "datetime",
+		"timestamp":          "datetime",
+		"received_at":        "datetime",
+		"uuid_ts":            "datetime",
+	},
+	warehouseutils.IdentifiesTable: {
+		"id":                "string",
+		"anonymous_id":      "string",
+		"user_id":           "string",
+		"context_source_id": "string",
+		"email":             "string",
+		"received_at":       "datetime",
+		"uuid_ts":           "datetime",
+	},
+	warehouseutils.UsersTable: {
+		"id":                "string",
+		"context_source_id": "string",
+		"email":             "string",
+		"received_at":       "datetime",
+		"uuid_ts":           "datetime",
+	},
+}
+
+// previewDDL returns the DDL statements the warehouse of the destination would be set up with for ddlPreviewSchema, the
+// ones creating its schema and tables only
+func previewDDL(req *DestinationValidationRequest) []string {
+	destType := req.Destination.DestinationDefinition.Name
+	whManager, err := manager.New(destType)
+	if err != nil {
+		return nil
+	}
+	previewer, ok := whManager.(manager.DDLPreviewer)
+	if !ok {
+		return nil
+	}
+
+	wh := warehouse(req)
+	wh.Namespace = previewNamespace(destType, req.Destination.Config)
+
+	schema := warehouseutils.SchemaT{}
+	for tableName, columns := range ddlPreviewSchema {
+		tableSchema := warehouseutils.TableSchemaT{}
+		for columnName, columnType := range columns {
+			tableSchema[warehouseutils.ToProviderCase(destType, columnName)] = columnType
+		}
+		schema[warehouseutils.ToProviderCase(destType, tableName)] = tableSchema
+	}
+	return previewer.PreviewDDL(wh, schema)
+}
+
+// previewNamespace returns the namespace configured for the destination, TestNamespace if it has none
+func previewNamespace(destType string, config map[string]interface{}) string {
+	key := "namespace"
+	if destType == warehouseutils.CLICKHOUSE {
+		key = "database"
+	}
+	namespace, _ := config[key].(string)
+	if strings.TrimSpace(namespace) == "" {
+		namespace = TestNamespace
+	}
+	if destType == warehouseutils.CLICKHOUSE {
+		return namespace
+	}
+	return warehouseutils.ToProviderCase(destType, warehouseutils.ToSafeNamespace(destType, namespace))
+}
@@ -0,0 +1,47 @@
+package validations
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+var _ = Describe("DDL preview", func() {
+	warehouseutils.Init()
+	misc.Init()
+
+	request := func(destType string, config map[string]interface{}) *DestinationValidationRequest {
+		return &DestinationValidationRequest{
+			Destination: backendconfig.DestinationT{
+				ID:                    "destination-id",
+				Config:                config,
+				DestinationDefinition: backendconfig.DestinationDefinitionT{Name: destType},
+			},
+		}
+	}
+
+	It("previews the statements creating the schema and the tables in the configured namespace", func() {
+		statements := previewDDL(request(warehouseutils.POSTGRES, map[string]interface{}{"namespace": "Analytics"}))
+		Expect(statements).To(HaveLen(1 + len(ddlPreviewSchema)))
+		Expect(statements[0]).To(Equal(`CREATE SCHEMA IF NOT EXISTS "analytics"`))
+		Expect(statements[1]).To(HavePrefix(`CREATE TABLE IF NOT EXISTS "analytics"."identifies" (`))
+		Expect(statements[2]).To(HavePrefix(`CREATE TABLE IF NOT EXISTS "analytics"."tracks" (`))
+		Expect(statements[2]).To(ContainSubstring(`"received_at" timestamptz`))
+		Expect(statements[3]).To(HavePrefix(`CREATE TABLE IF NOT EXISTS "analytics"."users" (`))
+	})
+
+	It("previews the statements in the case of the warehouse, in the test namespace without a configured one", func() {
+		statements := previewDDL(request(warehouseutils.SNOWFLAKE, map[string]interface{}{}))
+		Expect(statements).To(HaveLen(1 + len(ddlPreviewSchema)))
+		Expect(statements[0]).To(Equal(`CREATE SCHEMA IF NOT EXISTS "RUDDERSTACK_SETUP_TEST"`))
+		Expect(statements[1]).To(HavePrefix(`CREATE TABLE IF NOT EXISTS "RUDDERSTACK_SETUP_TEST"."IDENTIFIES" (`))
+		Expect(statements[1]).To(ContainSubstring(`"EMAIL" varchar`))
+	})
+
+	It("previews no statements for data lakes", func() {
+		Expect(previewDDL(request(warehouseutils.S3_DATALAKE, map[string]interface{}{}))).To(BeEmpty())
+	})
+})
@@ -39,6 +39,7 @@ func fileManager(req *DestinationValidationRequest) (fileManager filemanager.Fil
 			UseRudderStorage: misc.IsConfiguredToUseRudderObjectStorage(destination.Config),
 			WorkspaceID:      req.Destination.WorkspaceID,
 		}),
+		WorkspaceID: req.Destination.WorkspaceID,
 	})
 	fileManager.SetTimeout(fileManagerTimeout)
 	if err != nil {
@@ -0,0 +1,74 @@
+package validations
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+)
+
+// On top of uploading and downloading a load file, the object storage step warns about the policies of the bucket
+// leading to subtle data loss later, without failing the step:
+//   - the bucket being publicly accessible
+//   - the bucket not encrypting the objects at rest
+//   - lifecycle rules deleting staging files before Warehouse.retryTimeWindow, uploads retried that long after failing
+//     not finding their staging files anymore
+// Object storages whose policies can't be inspected are not warned about.
+
+// warn adds a warning to the step running
+func (ct *CTHandleT) warn(warning string) {
+	ct.warningsMu.Lock()
+	defer ct.warningsMu.Unlock()
+	ct.warnings = append(ct.warnings, warning)
+}
+
+// takeWarnings returns the warnings of the step run, resetting them for the next one
+func (ct *CTHandleT) takeWarnings() []string {
+	ct.warningsMu.Lock()
+	defer ct.warningsMu.Unlock()
+	warnings := ct.warnings
+	ct.warnings = nil
+	return warnings
+}
+
+// checkBucketPolicies warns about the policies of the bucket of the file manager, if it can inspect them
+func (ct *CTHandleT) checkBucketPolicies(ctx context.Context, fm filemanager.FileManager) {
+	inspector, ok := fm.(filemanager.PolicyInspector)
+	if !ok {
+		return
+	}
+	policies, err := inspector.BucketPolicies(ctx)
+	if err != nil {
+		ct.warn(fmt.Sprintf("could not inspect the policies of the bucket: %v", err))
+		return
+	}
+	stagingPrefix := path.Join(fm.GetConfiguredPrefix(), config.GetString("WAREHOUSE_STAGING_BUCKET_FOLDER_NAME", "rudder-warehouse-staging-logs"))
+	for _, warning := range policyWarnings(policies, stagingPrefix, retryTimeWindow) {
+		ct.warn(warning)
+	}
+}
+
+// policyWarnings returns the warnings about the policies of a bucket staging files under the prefix
+func policyWarnings(policies filemanager.BucketPolicies, stagingPrefix string, retryWindow time.Duration) []string {
+	var warnings []string
+	if policies.Public {
+		warnings = append(warnings, "bucket is publicly accessible")
+	}
+	if !policies.Encrypted {
+		warnings = append(warnings, "bucket does not encrypt objects at rest")
+	}
+	stagingPrefix = strings.TrimPrefix(stagingPrefix, "/")
+	for _, rule := range policies.Expirations {
+		if rule.After < retryWindow && rule.Matches(stagingPrefix) {
+			warnings = append(warnings, fmt.Sprintf(
+				"lifecycle rule on prefix %q deletes staging files after %s, before the retry window of %s",
+				rule.Prefix, rule.After, retryWindow,
+			))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,53 @@
+package validations
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+)
+
+var _ = Describe("Policies", func() {
+	const (
+		stagingPrefix = "prefix/rudder-warehouse-staging-logs"
+		retryWindow   = 3 * time.Hour
+	)
+
+	DescribeTable("Warnings", func(policies filemanager.BucketPolicies, expectedWarnings []string) {
+		Expect(policyWarnings(policies, stagingPrefix, retryWindow)).To(Equal(expectedWarnings))
+	},
+		Entry("none", filemanager.BucketPolicies{Encrypted: true}, nil),
+		Entry("public", filemanager.BucketPolicies{Public: true, Encrypted: true}, []string{"bucket is publicly accessible"}),
+		Entry("unencrypted", filemanager.BucketPolicies{}, []string{"bucket does not encrypt objects at rest"}),
+		Entry("expiring staging files", filemanager.BucketPolicies{
+			Encrypted:   true,
+			Expirations: []filemanager.ExpirationRule{{Prefix: "prefix/", After: time.Hour}},
+		}, []string{`lifecycle rule on prefix "prefix/" deletes staging files after 1h0m0s, before the retry window of 3h0m0s`}),
+		Entry("expiring all files", filemanager.BucketPolicies{
+			Encrypted:   true,
+			Expirations: []filemanager.ExpirationRule{{After: 24 * time.Hour}, {After: time.Hour}},
+		}, []string{`lifecycle rule on prefix "" deletes staging files after 1h0m0s, before the retry window of 3h0m0s`}),
+		Entry("expiring other files", filemanager.BucketPolicies{
+			Encrypted:   true,
+			Expirations: []filemanager.ExpirationRule{{Prefix: "other/", After: time.Hour}},
+		}, nil),
+	)
+
+	It("sets the warnings of steps", func() {
+		ct := &CTHandleT{}
+		s := &validationStep{Key: stepObjectStorage, Validator: func(context.Context) error {
+			ct.warn("bucket is publicly accessible")
+			return nil
+		}}
+		ct.runStep(s)
+		Expect(s.Success).To(BeTrue())
+		Expect(s.Warnings).To(Equal([]string{"bucket is publicly accessible"}))
+
+		s = &validationStep{Key: stepConnect, Validator: func(context.Context) error { return nil }}
+		ct.runStep(s)
+		Expect(s.Warnings).To(BeEmpty())
+	})
+})
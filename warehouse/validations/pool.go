@@ -0,0 +1,258 @@
+package validations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	proto "github.com/rudderlabs/rudder-server/proto/warehouse"
+)
+
+// Validations run on a bounded pool of workers, for concurrent requests not to pile up connections against the
+// warehouses of the destinations:
+//   - requests wait in a bounded queue for a worker, failing with ErrQueueFull once it's full, or once the context of
+//     the request is done. Validations every request of which is done by the time a worker dequeues them are dropped.
+//   - identical requests, of the same destination, path, step and body, share the validation in flight
+//   - the result of a successful validation of a destination is reused by identical requests for the cooldown after
+//     it completes, the destination not being connected to again meanwhile. Failed validations aren't reused, for
+//     a destination whose settings are fixed to be validated again right away.
+//   - validations requested once the pool is stopped, or queued when it is, fail with ErrPoolStopped
+
+// ErrQueueFull is returned for validations requested while the queue of the pool is full
+var ErrQueueFull = errors.New("validation queue is full")
+
+// ErrPoolStopped is returned for validations requested while, or queued when, the pool is stopped
+var ErrPoolStopped = errors.New("validation pool is stopped")
+
+var (
+	defaultPoolWorkers   = 4
+	defaultPoolQueueSize = 100
+	defaultPoolCooldown  = 30 * time.Second
+)
+
+// ValidateFunc runs a validation
+type ValidateFunc func(req *proto.WHValidationRequest) (*proto.WHValidationResponse, error)
+
+// Pool runs validations on a bounded number of workers
+type Pool struct {
+	validate  ValidateFunc
+	workers   int
+	queueSize int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	queue chan *validationCall
+	stop  chan struct{}
+	wg    sync.WaitGroup
+
+	mu        sync.Mutex
+	stopped   bool
+	inFlight  map[string]*validationCall
+	completed map[string]*validationCall
+}
+
+// validationCall is a validation shared by identical requests
+type validationCall struct {
+	key         string
+	req         *proto.WHValidationRequest
+	done        chan struct{}
+	resp        *proto.WHValidationResponse
+	err         error
+	completedAt time.Time
+	// waiters is the number of requests waiting for the validation, guarded by the mutex of the pool
+	waiters int
+}
+
+type PoolOption func(*Pool)
+
+// WithWorkers sets the number of validations running concurrently
+func WithWorkers(workers int) PoolOption {
+	return func(p *Pool) {
+		p.workers = workers
+	}
+}
+
+// WithQueueSize sets the number of validations waiting for a worker
+func WithQueueSize(queueSize int) PoolOption {
+	return func(p *Pool) {
+		p.queueSize = queueSize
+	}
+}
+
+// WithCooldown sets for how long the results of validations are reused by identical requests
+func WithCooldown(cooldown time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.cooldown = cooldown
+	}
+}
+
+// NewPool returns a pool running validations with validate, its workers running until it is stopped
+func NewPool(validate ValidateFunc, opts ...PoolOption) *Pool {
+	p := &Pool{
+		validate:  validate,
+		workers:   defaultPoolWorkers,
+		queueSize: defaultPoolQueueSize,
+		cooldown:  defaultPoolCooldown,
+		now:       time.Now,
+		stop:      make(chan struct{}),
+		inFlight:  map[string]*validationCall{},
+		completed: map[string]*validationCall{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.queue = make(chan *validationCall, p.queueSize)
+
+	p.wg.Add(p.workers)
+	for i := 0; i < p.workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			p.work()
+		}()
+	}
+	return p
+}
+
+// Stop stops the workers of the pool once the validations running complete, failing the queued ones
+func (p *Pool) Stop() {
+	p.mu.Lock()
+	p.stopped = true
+	p.mu.Unlock()
+	close(p.stop)
+	p.wg.Wait()
+
+	for {
+		select {
+		case call := <-p.queue:
+			p.drop(call, ErrPoolStopped)
+		default:
+			return
+		}
+	}
+}
+
+// Validate runs the validation of the request on the pool, sharing the validation of identical requests in flight or
+// completed within the cooldown
+func (p *Pool) Validate(ctx context.Context, req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
+	call, err := p.call(req)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-call.done:
+		return call.resp, call.err
+	case <-ctx.Done():
+		p.mu.Lock()
+		call.waiters--
+		p.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// call returns the validation of the request, queueing a new one unless an identical one is in flight or completed
+// within the cooldown
+func (p *Pool) call(req *proto.WHValidationRequest) (*validationCall, error) {
+	key := validationKey(req)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return nil, ErrPoolStopped
+	}
+	if call, ok := p.inFlight[key]; ok {
+		call.waiters++
+		return call, nil
+	}
+	now := p.now()
+	for k, call := range p.completed {
+		if now.Sub(call.completedAt) >= p.cooldown {
+			delete(p.completed, k)
+		}
+	}
+	if call, ok := p.completed[key]; ok {
+		return call, nil
+	}
+
+	call := &validationCall{key: key, req: req, done: make(chan struct{}), waiters: 1}
+	select {
+	case p.queue <- call:
+	default:
+		return nil, ErrQueueFull
+	}
+	p.inFlight[key] = call
+	return call, nil
+}
+
+// work runs the validations of the queue until the pool is stopped
+func (p *Pool) work() {
+	for {
+		select {
+		case <-p.stop:
+			return
+		case call := <-p.queue:
+			p.mu.Lock()
+			stopped, abandoned := p.stopped, call.waiters == 0
+			p.mu.Unlock()
+			if stopped {
+				p.drop(call, ErrPoolStopped)
+				return
+			}
+			if abandoned {
+				p.drop(call, context.Canceled)
+				continue
+			}
+
+			call.resp, call.err = p.validate(call.req)
+
+			p.mu.Lock()
+			delete(p.inFlight, call.key)
+			call.completedAt = p.now()
+			if succeeded(call.resp, call.err) {
+				p.completed[call.key] = call
+			}
+			p.mu.Unlock()
+			close(call.done)
+		}
+	}
+}
+
+// drop completes the queued validation with the error, without running it
+func (p *Pool) drop(call *validationCall, err error) {
+	p.mu.Lock()
+	delete(p.inFlight, call.key)
+	p.mu.Unlock()
+	call.err = err
+	close(call.done)
+}
+
+// succeeded returns whether the validation succeeded, the responses not reporting whether they did, such as the ones
+// listing the steps, succeeding unless they have an error
+func succeeded(resp *proto.WHValidationResponse, err error) bool {
+	if err != nil || resp == nil || resp.Error != "" {
+		return false
+	}
+	var result struct {
+		Success *bool `json:"success"`
+	}
+	if err := json.Unmarshal([]byte(resp.Data), &result); err != nil || result.Success == nil {
+		return true
+	}
+	return *result.Success
+}
+
+// validationKey returns the key identical requests share, prefixed by their destination
+func validationKey(req *proto.WHValidationRequest) string {
+	var destinationReq DestinationValidationRequest
+	_ = json.Unmarshal([]byte(req.Body), &destinationReq)
+
+	h := sha256.New()
+	for _, s := range []string{req.Path, req.Step, req.Body} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return destinationReq.Destination.ID + ":" + hex.EncodeToString(h.Sum(nil))
+}
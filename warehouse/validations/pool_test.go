@@ -0,0 +1,202 @@
+package validations
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	proto "github.com/rudderlabs/rudder-server/proto/warehouse"
+)
+
+var _ = Describe("Pool", func() {
+	request := func(destinationID, step string) *proto.WHValidationRequest {
+		return &proto.WHValidationRequest{
+			Path: "validate",
+			Step: step,
+			Body: `{"destination":{"ID":"` + destinationID + `"}}`,
+		}
+	}
+
+	It("shares the validation of identical requests in flight", func() {
+		var calls int32
+		release := make(chan struct{})
+		p := NewPool(func(req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return &proto.WHValidationResponse{Data: req.Step}, nil
+		})
+		defer p.Stop()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				resp, err := p.Validate(context.Background(), request("destination-1", "1"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.Data).To(Equal("1"))
+			}()
+		}
+		Eventually(func() int32 { return atomic.LoadInt32(&calls) }).Should(Equal(int32(1)))
+		close(release)
+		wg.Wait()
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+	})
+
+	It("reuses the results of identical requests for the cooldown", func() {
+		var calls int32
+		now := time.Now()
+		p := NewPool(func(req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
+			atomic.AddInt32(&calls, 1)
+			return &proto.WHValidationResponse{Data: req.Step}, nil
+		}, WithCooldown(time.Minute))
+		p.now = func() time.Time { return now }
+		defer p.Stop()
+
+		for i := 0; i < 2; i++ {
+			_, err := p.Validate(context.Background(), request("destination-1", "1"))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+
+		_, err := p.Validate(context.Background(), request("destination-1", "2"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = p.Validate(context.Background(), request("destination-2", "1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(3)))
+
+		now = now.Add(time.Minute)
+		_, err = p.Validate(context.Background(), request("destination-1", "1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(4)))
+	})
+
+	It("doesn't reuse the results of failed validations", func() {
+		var calls int32
+		p := NewPool(func(req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
+			atomic.AddInt32(&calls, 1)
+			return &proto.WHValidationResponse{Data: `{"success":false}`}, nil
+		}, WithCooldown(time.Minute))
+		defer p.Stop()
+
+		for i := 0; i < 2; i++ {
+			_, err := p.Validate(context.Background(), request("destination-1", "1"))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+	})
+
+	It("drops the validations whose requests are done before they run", func() {
+		var calls int32
+		release := make(chan struct{})
+		p := NewPool(func(req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return &proto.WHValidationResponse{}, nil
+		}, WithWorkers(1))
+		defer p.Stop()
+
+		go func() { _, _ = p.Validate(context.Background(), request("destination-1", "")) }()
+		Eventually(func() int32 { return atomic.LoadInt32(&calls) }).Should(Equal(int32(1)))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := p.Validate(ctx, request("destination-2", ""))
+		Expect(err).To(MatchError(context.Canceled))
+
+		close(release)
+		_, err = p.Validate(context.Background(), request("destination-3", ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+	})
+
+	It("fails the validations queued or requested once stopped", func() {
+		var calls int32
+		release := make(chan struct{})
+		p := NewPool(func(req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return &proto.WHValidationResponse{}, nil
+		}, WithWorkers(1))
+
+		go func() { _, _ = p.Validate(context.Background(), request("destination-1", "")) }()
+		Eventually(func() int32 { return atomic.LoadInt32(&calls) }).Should(Equal(int32(1)))
+		queued := make(chan error)
+		go func() {
+			_, err := p.Validate(context.Background(), request("destination-2", ""))
+			queued <- err
+		}()
+		Eventually(func() int { return len(p.queue) }).Should(Equal(1))
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+		}()
+		p.Stop()
+		Eventually(queued).Should(Receive(MatchError(ErrPoolStopped)))
+		_, err := p.Validate(context.Background(), request("destination-3", ""))
+		Expect(err).To(MatchError(ErrPoolStopped))
+	})
+
+	It("bounds the validations running and queued", func() {
+		var running, maxRunning int32
+		release := make(chan struct{})
+		p := NewPool(func(req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			<-release
+			atomic.AddInt32(&running, -1)
+			return &proto.WHValidationResponse{}, nil
+		}, WithWorkers(2), WithQueueSize(2))
+		defer p.Stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		for _, destinationID := range []string{"destination-1", "destination-2", "destination-3", "destination-4"} {
+			destinationID := destinationID
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = p.Validate(ctx, request(destinationID, ""))
+			}()
+			if destinationID == "destination-2" {
+				// the first two are taken by the workers, the others are queued
+				Eventually(func() int32 { return atomic.LoadInt32(&running) }).Should(Equal(int32(2)))
+			}
+		}
+		Eventually(func() int { return len(p.queue) }).Should(Equal(2))
+
+		_, err := p.Validate(context.Background(), request("destination-5", ""))
+		Expect(err).To(MatchError(ErrQueueFull))
+
+		cancel()
+		close(release)
+		wg.Wait()
+		Expect(atomic.LoadInt32(&maxRunning)).To(Equal(int32(2)))
+	})
+
+	It("stops waiting once the context is done", func() {
+		release := make(chan struct{})
+		p := NewPool(func(req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
+			<-release
+			return &proto.WHValidationResponse{}, nil
+		})
+		defer p.Stop()
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := p.Validate(ctx, request("destination-1", ""))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
@@ -0,0 +1,252 @@
+package validations
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/rudderlabs/sql-tunnels/tunnel"
+
+	"github.com/rudderlabs/rudder-server/warehouse/tunnelling"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// Before the load tests, the pre-checks step verifies that the warehouse can be reached and that the credentials have
+// the privileges needed to load it, failing with actionable messages:
+//   - the host of the warehouse resolves, and accepts tcp connections, through the ssh tunnel if the destination uses
+//     one, the ssh host then being resolved and connected to instead
+//   - warehouses served over tls complete a tls handshake
+//   - the schema and a table can be created in the test namespace, and the table dropped. INSERT privileges are
+//     verified by the load table step, loading the table.
+
+// tunnelProbeTimeout is for how long connections through ssh tunnels are waited for to be closed, when the tunnel
+// fails to reach the warehouse
+var tunnelProbeTimeout = 500 * time.Millisecond
+
+// address is where a warehouse is reached
+type address struct {
+	host   string
+	port   int
+	useTLS bool
+}
+
+func (a address) String() string {
+	return net.JoinHostPort(a.host, strconv.Itoa(a.port))
+}
+
+// defaultPorts are the ports warehouses are reached on, by destination type, unless configured otherwise
+var defaultPorts = map[string]int{
+	warehouseutils.RS:            5439,
+	warehouseutils.POSTGRES:      5432,
+	warehouseutils.MSSQL:         1433,
+	warehouseutils.AZURE_SYNAPSE: 1433,
+	warehouseutils.CLICKHOUSE:    9000,
+	warehouseutils.DELTALAKE:     443,
+}
+
+// warehouseAddress returns where the warehouse of the destination is reached, false for destinations without one
+func warehouseAddress(destType string, config map[string]interface{}) (address, bool) {
+	switch destType {
+	case warehouseutils.SNOWFLAKE:
+		account, _ := config["account"].(string)
+		if account == "" {
+			return address{}, false
+		}
+		return address{host: account + ".snowflakecomputing.com", port: 443, useTLS: true}, true
+	case warehouseutils.BQ:
+		return address{host: "bigquery.googleapis.com", port: 443, useTLS: true}, true
+	}
+
+	defaultPort, ok := defaultPorts[destType]
+	if !ok {
+		return address{}, false
+	}
+	host, _ := config["host"].(string)
+	if host == "" {
+		return address{}, false
+	}
+	a := address{host: host, port: defaultPort, useTLS: destType == warehouseutils.DELTALAKE}
+	switch port := config["port"].(type) {
+	case string:
+		if p, err := strconv.Atoi(port); err == nil {
+			a.port = p
+		}
+	case float64:
+		a.port = int(port)
+	}
+	return a, true
+}
+
+// databaseName returns the name of the database of the destination, for error messages
+func databaseName(config map[string]interface{}) string {
+	for _, key := range []string{"database", "project"} {
+		if name, _ := config[key].(string); name != "" {
+			return name
+		}
+	}
+	return "the warehouse"
+}
+
+func (ct *CTHandleT) verifyingPreChecks(ctx context.Context) (err error) {
+	destination := ct.infoRequest.Destination
+	if a, ok := warehouseAddress(destination.DestinationDefinition.Name, destination.Config); ok {
+		var tunnelConfig tunnelling.Config
+		if ct.EnableTunnelling && warehouseutils.ReadAsBool("useSSH", destination.Config) {
+			keys, err := ct.CPClient.GetDestinationSSHKeys(ctx, destination.ID)
+			if err != nil {
+				return &stepError{category: categoryConfiguration, err: fmt.Errorf("fetching destination ssh keys: %w", err)}
+			}
+			tunnelConfig = tunnelling.Config{}
+			for k, v := range destination.Config {
+				tunnelConfig[k] = v
+			}
+			tunnelConfig["sshPrivateKey"] = keys.PrivateKey
+		}
+		if err := checkReachability(ctx, a, tunnelConfig); err != nil {
+			return err
+		}
+	}
+	return ct.checkPrivileges(ctx)
+}
+
+// checkReachability checks that the address resolves, accepts tcp connections through the ssh tunnel of the config if
+// not nil, and completes a tls handshake if it's served over tls
+func checkReachability(ctx context.Context, a address, tunnelConfig tunnelling.Config) error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if tunnelConfig != nil {
+		conn, err = dialThroughTunnel(ctx, a, tunnelConfig)
+	} else {
+		conn, err = dial(ctx, a.host, a.port)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if !a.useTLS {
+		return nil
+	}
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: a.host, MinVersion: tls.VersionTLS12})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return &stepError{category: categoryNetwork, err: fmt.Errorf("tls handshake with %s: %w", a, err)}
+	}
+	return nil
+}
+
+// dial resolves the host and connects to it
+func dial(ctx context.Context, host string, port int) (net.Conn, error) {
+	var resolver net.Resolver
+	if _, err := resolver.LookupHost(ctx, host); err != nil {
+		return nil, fmt.Errorf("resolving host %s: %w", host, err)
+	}
+	var dialer net.Dialer
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// dialThroughTunnel connects to the address through the ssh tunnel of the config
+func dialThroughTunnel(ctx context.Context, a address, tunnelConfig tunnelling.Config) (net.Conn, error) {
+	sshConfig, err := tunnelling.ReadSSHTunnelConfig(tunnelConfig)
+	if err != nil {
+		return nil, &stepError{category: categoryConfiguration, err: fmt.Errorf("reading ssh tunnel config: %w", err)}
+	}
+	// checking the ssh host on its own, for its errors not to be mistaken for the warehouse's
+	sshConn, err := dial(ctx, sshConfig.Host, sshConfig.Port)
+	if err != nil {
+		return nil, fmt.Errorf("ssh tunnel: %w", err)
+	}
+	_ = sshConn.Close()
+
+	t, err := tunnel.ListenAndForward(&tunnel.SSHConfig{
+		User:       sshConfig.User,
+		Host:       sshConfig.Host,
+		Port:       sshConfig.Port,
+		PrivateKey: sshConfig.PrivateKey,
+		RemoteHost: a.host,
+		RemotePort: a.port,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("opening ssh tunnel to %s:%d: %w", sshConfig.Host, sshConfig.Port, err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", t.Addr())
+	if err != nil {
+		_ = t.Close()
+		return nil, fmt.Errorf("connecting to ssh tunnel: %w", err)
+	}
+	tunnelConn := &tunnelledConn{Conn: conn, tunnel: t}
+	if a.useTLS {
+		// failing to reach the warehouse fails the tls handshake
+		return tunnelConn, nil
+	}
+
+	// the tunnel closes the connection right away if it can't reach the warehouse, the warehouses served without tls
+	// waiting for the client to speak first otherwise
+	_ = conn.SetReadDeadline(time.Now().Add(tunnelProbeTimeout))
+	_, readErr := conn.Read(make([]byte, 1))
+	_ = conn.SetReadDeadline(time.Time{})
+	if tunnelErr := t.Error(); tunnelErr != nil || errors.Is(readErr, io.EOF) {
+		_ = tunnelConn.Close()
+		if tunnelErr == nil {
+			tunnelErr = readErr
+		}
+		return nil, fmt.Errorf("connecting to %s through ssh tunnel: %w", a, tunnelErr)
+	}
+	return tunnelConn, nil
+}
+
+// tunnelledConn is a connection through an ssh tunnel, closing the tunnel along with it
+type tunnelledConn struct {
+	net.Conn
+	tunnel *tunnel.SSH
+}
+
+func (c *tunnelledConn) Close() error {
+	err := c.Conn.Close()
+	_ = c.tunnel.Close()
+	return err
+}
+
+// checkPrivileges checks that the schema and a table can be created in the test namespace, and the table dropped
+func (ct *CTHandleT) checkPrivileges(ctx context.Context) (err error) {
+	err = ct.initManager(ctx)
+	if err != nil {
+		return
+	}
+	database := databaseName(ct.warehouse.Destination.Config)
+
+	if err = ct.manager.CreateSchema(); err != nil {
+		return missingPrivilege(err, "CREATE", "database", database)
+	}
+
+	stagingTableName := stagingTableName()
+	if err = ct.manager.CreateTable(stagingTableName, TestTableSchemaMap); err != nil {
+		return missingPrivilege(err, "CREATE", "schema", ct.warehouse.Namespace)
+	}
+	if err = ct.manager.DropTable(stagingTableName); err != nil {
+		return missingPrivilege(err, "DROP", "table", fmt.Sprintf("%s.%s", ct.warehouse.Namespace, stagingTableName))
+	}
+	return nil
+}
+
+// missingPrivilege returns the error of an operation needing the privilege on the object, telling the privilege is
+// missing if the error is a permission one
+func missingPrivilege(err error, privilege, objectType, object string) error {
+	if errorCategory(err) != categoryPermission {
+		return err
+	}
+	return &stepError{category: categoryPermission, err: fmt.Errorf("missing %s privilege on %s %s: %w", privilege, objectType, object, err)}
+}
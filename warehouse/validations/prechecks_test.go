@@ -0,0 +1,76 @@
+package validations
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+var _ = Describe("Pre-checks", func() {
+	DescribeTable("Warehouse addresses", func(destType string, config map[string]interface{}, expectedAddress address, expectedOK bool) {
+		a, ok := warehouseAddress(destType, config)
+		Expect(ok).To(Equal(expectedOK))
+		Expect(a).To(Equal(expectedAddress))
+	},
+		Entry("RS with default port", warehouseutils.RS, map[string]interface{}{"host": "rs.example.com"}, address{host: "rs.example.com", port: 5439}, true),
+		Entry("POSTGRES with port", warehouseutils.POSTGRES, map[string]interface{}{"host": "pg.example.com", "port": "6432"}, address{host: "pg.example.com", port: 6432}, true),
+		Entry("DELTALAKE over tls", warehouseutils.DELTALAKE, map[string]interface{}{"host": "dbc.example.com"}, address{host: "dbc.example.com", port: 443, useTLS: true}, true),
+		Entry("SNOWFLAKE", warehouseutils.SNOWFLAKE, map[string]interface{}{"account": "xy12345.us-east-1"}, address{host: "xy12345.us-east-1.snowflakecomputing.com", port: 443, useTLS: true}, true),
+		Entry("BQ", warehouseutils.BQ, map[string]interface{}{}, address{host: "bigquery.googleapis.com", port: 443, useTLS: true}, true),
+		Entry("without host", warehouseutils.MSSQL, map[string]interface{}{}, address{}, false),
+		Entry("datalakes", warehouseutils.S3_DATALAKE, map[string]interface{}{}, address{}, false),
+	)
+
+	Describe("Reachability", func() {
+		It("succeeds for warehouses accepting connections", func() {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer l.Close()
+
+			port := l.Addr().(*net.TCPAddr).Port
+			Expect(checkReachability(context.Background(), address{host: "127.0.0.1", port: port}, nil)).To(Succeed())
+		})
+
+		It("fails for warehouses refusing connections", func() {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			port := l.Addr().(*net.TCPAddr).Port
+			Expect(l.Close()).To(Succeed())
+
+			err = checkReachability(context.Background(), address{host: "127.0.0.1", port: port}, nil)
+			Expect(err).To(MatchError(ContainSubstring("connecting to 127.0.0.1:" + strconv.Itoa(port))))
+			Expect(errorCategory(err)).To(Equal(categoryNetwork))
+		})
+
+		It("fails for warehouses whose tls certificate can't be verified", func() {
+			s := httptest.NewTLSServer(http.NotFoundHandler())
+			defer s.Close()
+			u, err := url.Parse(s.URL)
+			Expect(err).NotTo(HaveOccurred())
+			port, err := strconv.Atoi(u.Port())
+			Expect(err).NotTo(HaveOccurred())
+
+			err = checkReachability(context.Background(), address{host: u.Hostname(), port: port, useTLS: true}, nil)
+			Expect(err).To(MatchError(ContainSubstring("tls handshake with " + u.Host)))
+			Expect(errorCategory(err)).To(Equal(categoryNetwork))
+		})
+	})
+
+	DescribeTable("Missing privileges", func(err error, expectedError string, expectedCategory string) {
+		err = missingPrivilege(err, "CREATE", "database", "dev")
+		Expect(err).To(MatchError(expectedError))
+		Expect(errorCategory(err)).To(Equal(expectedCategory))
+	},
+		Entry("permission errors", errors.New("pq: permission denied for database dev"), "missing CREATE privilege on database dev: pq: permission denied for database dev", categoryPermission),
+		Entry("other errors", errors.New("pq: syntax error"), "pq: syntax error", categoryUnknown),
+	)
+})
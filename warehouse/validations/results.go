@@ -0,0 +1,160 @@
+package validations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+// Every validation step runs for at most Warehouse.validations.<key>.timeout, its result telling, on top of whether it
+// succeeded, the key of the step, how long it ran for, and the category of its error if it failed:
+//   - timeout, for steps running out of time
+//   - network, for destinations or object storages that can't be reached
+//   - permission, for credentials rejected or lacking privileges
+//   - configuration, for destinations whose config can't be set up
+//   - unknown, for the other errors
+
+const (
+	categoryTimeout       = "timeout"
+	categoryNetwork       = "network"
+	categoryPermission    = "permission"
+	categoryConfiguration = "configuration"
+	categoryUnknown       = "unknown"
+)
+
+var errStepTimeout = errors.New("step timed out")
+
+// defaultStepTimeouts are the timeouts of the steps, by key, unless configured otherwise
+var defaultStepTimeouts = map[string]time.Duration{
+	stepObjectStorage:       30 * time.Second,
+	stepPreChecks:           30 * time.Second,
+	stepConnect:             15 * time.Second,
+	stepCreateSchema:        30 * time.Second,
+	stepCreateAndAlterTable: 30 * time.Second,
+	stepFetchSchema:         30 * time.Second,
+	stepLoadTable:           60 * time.Second,
+	stepTestSyncStaging:     30 * time.Second,
+	stepTestSyncLoadFile:    30 * time.Second,
+	stepTestSyncLoad:        120 * time.Second,
+	stepTestSyncVerify:      30 * time.Second,
+}
+
+// permissionErrors are the messages of errors for credentials rejected or lacking privileges, in lower case
+var permissionErrors = []string{
+	"permission denied",
+	"access denied",
+	"accessdenied",
+	"unauthorized",
+	"forbidden",
+	"authentication failed",
+	"invalid credentials",
+	"insufficient privileges",
+	"not authorized",
+}
+
+// stepError is the error of a step of a known category
+type stepError struct {
+	category string
+	err      error
+}
+
+func (e *stepError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stepError) Unwrap() error {
+	return e.err
+}
+
+// stepTimeout returns the timeout of the step with the key
+func stepTimeout(key string) time.Duration {
+	return config.GetDuration(fmt.Sprintf("Warehouse.validations.%s.timeout", key), int64(defaultStepTimeouts[key]/time.Second), time.Second)
+}
+
+// errorCategory returns the category of the error of a step
+func errorCategory(err error) string {
+	var se *stepError
+	if errors.As(err, &se) {
+		return se.category
+	}
+	if errors.Is(err, errStepTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return categoryTimeout
+	}
+	message := strings.ToLower(err.Error())
+	for _, permissionError := range permissionErrors {
+		if strings.Contains(message, permissionError) {
+			return categoryPermission
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return categoryTimeout
+		}
+		return categoryNetwork
+	}
+	if strings.Contains(message, "connection refused") || strings.Contains(message, "no such host") {
+		return categoryNetwork
+	}
+	return categoryUnknown
+}
+
+// runStep runs the validator of the step for at most its timeout, setting the result of the step. Validators still
+// running once timed out are left to complete in the background, the steps after them not running, tracked for the
+// handle to wait for them. Their manager is closed on timeout, for their connections not to be left open and their
+// queries to fail rather than keep running.
+func (ct *CTHandleT) runStep(s *validationStep) {
+	timeout := stepTimeout(s.Key)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// the manager the validator sets up is closed once, when it is done or times out, whichever comes first, or
+	// once it is set up if the validator times out before
+	ct.setManager(nil)
+	var cleanupOnce sync.Once
+	cleanup := func() {
+		if m := ct.currentManager(); m != nil {
+			cleanupOnce.Do(m.Cleanup)
+		}
+	}
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	ct.running.Add(1)
+	go func() {
+		defer ct.running.Done()
+		err := s.Validator(ctx)
+		cleanup()
+		errCh <- err
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = fmt.Errorf("%w after %s", errStepTimeout, timeout)
+		ct.running.Add(1)
+		go func() {
+			defer ct.running.Done()
+			cleanup()
+		}()
+	}
+	s.DurationMs = time.Since(start).Milliseconds()
+	// warnings of validators timed out are dropped along with them
+	if warnings := ct.takeWarnings(); !errors.Is(err, errStepTimeout) {
+		s.Warnings = warnings
+	}
+
+	if err != nil {
+		s.Error = err.Error()
+		s.Category = errorCategory(err)
+		return
+	}
+	s.Success = true
+}
@@ -0,0 +1,88 @@
+package validations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/config"
+	mock_manager "github.com/rudderlabs/rudder-server/mocks/warehouse/manager"
+)
+
+var _ = Describe("Results", func() {
+	DescribeTable("Error categories", func(err error, expectedCategory string) {
+		Expect(errorCategory(err)).To(Equal(expectedCategory))
+	},
+		Entry("step timeout", fmt.Errorf("%w after 1s", errStepTimeout), categoryTimeout),
+		Entry("deadline exceeded", fmt.Errorf("uploading: %w", context.DeadlineExceeded), categoryTimeout),
+		Entry("permission", errors.New("pq: permission denied for schema rudderstack_setup_test"), categoryPermission),
+		Entry("network", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, categoryNetwork),
+		Entry("configuration", &stepError{category: categoryConfiguration, err: errors.New("invalid destination type")}, categoryConfiguration),
+		Entry("unknown", errors.New("something went wrong"), categoryUnknown),
+	)
+
+	Describe("Running steps", func() {
+		It("sets the result of successful steps", func() {
+			s := &validationStep{Key: stepConnect, Validator: func(context.Context) error { return nil }}
+			(&CTHandleT{}).runStep(s)
+			Expect(s.Success).To(BeTrue())
+			Expect(s.Error).To(BeEmpty())
+			Expect(s.Category).To(BeEmpty())
+		})
+
+		It("sets the error and its category of failed steps", func() {
+			s := &validationStep{Key: stepConnect, Validator: func(context.Context) error { return errors.New("access denied for user") }}
+			(&CTHandleT{}).runStep(s)
+			Expect(s.Success).To(BeFalse())
+			Expect(s.Error).To(Equal("access denied for user"))
+			Expect(s.Category).To(Equal(categoryPermission))
+		})
+
+		It("times steps out", func() {
+			config.Set("Warehouse.validations.connect.timeout", "1s")
+			defer config.Reset()
+
+			blocker := make(chan struct{})
+			defer close(blocker)
+			s := &validationStep{Key: stepConnect, Validator: func(context.Context) error {
+				<-blocker
+				return nil
+			}}
+			(&CTHandleT{}).runStep(s)
+			Expect(s.Success).To(BeFalse())
+			Expect(s.Error).To(Equal("step timed out after 1s"))
+			Expect(s.Category).To(Equal(categoryTimeout))
+			Expect(s.DurationMs).To(BeNumerically(">=", int64(time.Second/time.Millisecond)))
+		})
+
+		It("closes the manager of timed out steps once", func() {
+			config.Set("Warehouse.validations.connect.timeout", "10ms")
+			defer config.Reset()
+
+			var cleanups int32
+			m := mock_manager.NewMockWarehouseOperations(gomock.NewController(GinkgoT()))
+			m.EXPECT().Cleanup().Do(func() { atomic.AddInt32(&cleanups, 1) }).AnyTimes()
+			blocker := make(chan struct{})
+			ct := &CTHandleT{}
+			s := &validationStep{Key: stepConnect, Validator: func(context.Context) error {
+				ct.setManager(m)
+				<-blocker
+				return nil
+			}}
+			ct.runStep(s)
+			Expect(s.Category).To(Equal(categoryTimeout))
+			Eventually(func() int32 { return atomic.LoadInt32(&cleanups) }).Should(Equal(int32(1)))
+
+			close(blocker)
+			ct.running.Wait()
+			Expect(atomic.LoadInt32(&cleanups)).To(Equal(int32(1)))
+		})
+	})
+})
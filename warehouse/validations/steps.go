@@ -24,6 +24,7 @@ func (ct *CTHandleT) validationSteps() []*validationStep {
 	steps := []*validationStep{{
 		ID:        1,
 		Name:      verifyingObjectStorage,
+		Key:       stepObjectStorage,
 		Validator: ct.verifyingObjectStorage,
 	}}
 
@@ -32,30 +33,42 @@ func (ct *CTHandleT) validationSteps() []*validationStep {
 		return steps
 	}
 
+	// steps are run in the order of their ids, pre-checks before the others for their failures to tell what is missing
 	steps = append(steps,
 		&validationStep{
 			ID:        2,
+			Name:      verifyingPreChecks,
+			Key:       stepPreChecks,
+			Validator: ct.verifyingPreChecks,
+		},
+		&validationStep{
+			ID:        3,
 			Name:      verifyingConnections,
+			Key:       stepConnect,
 			Validator: ct.verifyingConnections,
 		},
 		&validationStep{
-			ID:        3,
+			ID:        4,
 			Name:      verifyingCreateSchema,
+			Key:       stepCreateSchema,
 			Validator: ct.verifyingCreateSchema,
 		},
 		&validationStep{
-			ID:        4,
+			ID:        5,
 			Name:      verifyingCreateAndAlterTable,
+			Key:       stepCreateAndAlterTable,
 			Validator: ct.verifyingCreateAlterTable,
 		},
 		&validationStep{
-			ID:        5,
+			ID:        6,
 			Name:      verifyingFetchSchema,
+			Key:       stepFetchSchema,
 			Validator: ct.verifyingFetchSchema,
 		},
 		&validationStep{
-			ID:        6,
+			ID:        7,
 			Name:      verifyingLoadTable,
+			Key:       stepLoadTable,
 			Validator: ct.verifyingLoadTable,
 		},
 	)
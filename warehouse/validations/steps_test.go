@@ -11,7 +11,7 @@ import (
 var _ = Describe("Steps", func() {
 	warehouseutils.Init()
 
-	DescribeTable("Validation steps", func(destinationType string, expectedSteps []string) {
+	DescribeTable("Validation steps", func(destinationType string, expectedIDs []int, expectedSteps, expectedKeys []string) {
 		ct := &CTHandleT{
 			infoRequest: &DestinationValidationRequest{
 				Destination: backendconfig.DestinationT{
@@ -24,20 +24,32 @@ var _ = Describe("Steps", func() {
 		vs := ct.validationSteps()
 		Expect(vs).To(HaveLen(len(expectedSteps)))
 		for i, step := range expectedSteps {
-			Expect(vs[i]).To(HaveField("ID", i+1))
+			Expect(vs[i]).To(HaveField("ID", expectedIDs[i]))
 			Expect(vs[i]).To(HaveField("Name", step))
+			Expect(vs[i]).To(HaveField("Key", expectedKeys[i]))
 		}
 	},
-		Entry("S3_DATALAKE", "S3_DATALAKE", []string{
+		Entry("S3_DATALAKE", "S3_DATALAKE", []int{1}, []string{
 			verifyingObjectStorage,
+		}, []string{
+			stepObjectStorage,
 		}),
-		Entry("RS", "RS", []string{
+		Entry("RS", "RS", []int{1, 2, 3, 4, 5, 6, 7}, []string{
 			verifyingObjectStorage,
+			verifyingPreChecks,
 			verifyingConnections,
 			verifyingCreateSchema,
 			verifyingCreateAndAlterTable,
 			verifyingFetchSchema,
 			verifyingLoadTable,
+		}, []string{
+			stepObjectStorage,
+			stepPreChecks,
+			stepConnect,
+			stepCreateSchema,
+			stepCreateAndAlterTable,
+			stepFetchSchema,
+			stepLoadTable,
 		}),
 	)
 })
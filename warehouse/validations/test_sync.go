@@ -0,0 +1,404 @@
+package validations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// The testSync validation path verifies the full path events take to the warehouse of a destination, from a single
+// request:
+//   - Warehouse.validations.testSync.events synthetic events of the source are written to a staging file, uploaded
+//     to the object storage of the destination
+//   - the staging file is turned into a load file by the LoadFileGenerator of the handle, the way staging files of
+//     uploads are, and uploaded
+//   - the load file is loaded into a table of its own in the TestSyncNamespace namespace, the number of rows in the
+//     table having to be the number of events
+//
+// Its response is the one of the validate path, listing the steps run. Whether they succeed or not, the table is
+// dropped and the files are deleted from the object storage, once the steps timed out are done if any, with a manager
+// of its own. Time window destinations stop at the load file, since they aren't loaded.
+
+var TestSyncNamespace = "rudderstack_test_sync"
+
+const testSyncTablePrefix = "rudder_test_sync"
+
+const (
+	stepTestSyncStaging  = "testSyncStaging"
+	stepTestSyncLoadFile = "testSyncLoadFile"
+	stepTestSyncLoad     = "testSyncLoad"
+	stepTestSyncVerify   = "testSyncVerify"
+)
+
+const (
+	writingStagingFile  = "Writing Staging File"
+	generatingLoadFile  = "Generating Load File"
+	loadingTestSyncData = "Loading Table"
+	verifyingCounts     = "Verifying Counts"
+)
+
+// testSyncColumns are the columns of the synthetic events, by name
+var testSyncColumns = map[string]string{
+	"id":                "string",
+	"event":             "string",
+	"context_source_id": "string",
+	"received_at":       "datetime",
+	"uuid_ts":           "datetime",
+}
+
+// StagingFile is the staging file of a test sync, to generate the load file of
+type StagingFile struct {
+	Destination backendconfig.DestinationT
+	SourceID    string
+	Namespace   string
+	// Location is the object name of the staging file, in the object storage of the destination
+	Location string
+	Table    string
+	Schema   warehouseutils.TableSchemaT
+}
+
+// LoadFileGenerator generates the load file of the table of the staging file, uploading it to the object storage of the
+// destination, and returns its location
+type LoadFileGenerator func(ctx context.Context, stagingFile StagingFile) (string, error)
+
+// stagingEvent is an event of a staging file
+type stagingEvent struct {
+	Metadata stagingEventMetadata   `json:"metadata"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+type stagingEventMetadata struct {
+	Table   string            `json:"table"`
+	Columns map[string]string `json:"columns"`
+}
+
+// testSync is a test sync of a destination
+type testSync struct {
+	ct       *CTHandleT
+	sourceID string
+	events   int
+
+	table       string
+	schema      warehouseutils.TableSchemaT
+	stagingFile filemanager.UploadOutput
+	loadFile    filemanager.UploadOutput
+	loaded      bool
+}
+
+// testSyncUploadJob is the upload of the load file of a test sync
+type testSyncUploadJob struct {
+	*CTUploadJob
+	sync *testSync
+}
+
+func (job *testSyncUploadJob) GetSchemaInWarehouse() warehouseutils.SchemaT {
+	return warehouseutils.SchemaT{job.sync.table: job.sync.schema}
+}
+
+func (job *testSyncUploadJob) GetLocalSchema() warehouseutils.SchemaT {
+	return warehouseutils.SchemaT{job.sync.table: job.sync.schema}
+}
+
+func (job *testSyncUploadJob) GetTableSchemaInWarehouse(_ string) warehouseutils.TableSchemaT {
+	return job.sync.schema
+}
+
+func (job *testSyncUploadJob) GetTableSchemaInUpload(_ string) warehouseutils.TableSchemaT {
+	return job.sync.schema
+}
+
+func (job *testSyncUploadJob) GetLoadFilesMetadata(_ warehouseutils.GetLoadFilesOptionsT) []warehouseutils.LoadFileT {
+	return []warehouseutils.LoadFileT{{Location: job.sync.loadFile.Location}}
+}
+
+func (job *testSyncUploadJob) GetSampleLoadFileLocation(_ string) (string, error) {
+	return job.sync.loadFile.Location, nil
+}
+
+func (job *testSyncUploadJob) GetSingleLoadFile(_ string) (warehouseutils.LoadFileT, error) {
+	return warehouseutils.LoadFileT{Location: job.sync.loadFile.Location}, nil
+}
+
+func (*testSyncUploadJob) GetFirstLastEvent() (time.Time, time.Time) {
+	now := time.Now()
+	return now, now
+}
+
+func (ct *CTHandleT) testSyncFunc(req json.RawMessage, _ string) (json.RawMessage, error) {
+	ct.infoRequest = &DestinationValidationRequest{}
+	if err := parseOptions(req, ct.infoRequest); err != nil {
+		return nil, err
+	}
+
+	destination := ct.infoRequest.Destination
+	destType := destination.DestinationDefinition.Name
+	sourceID := ct.infoRequest.SourceID
+	if sourceID == "" {
+		sourceID = warehouseutils.RandHex()
+	}
+	sync := &testSync{
+		ct:       ct,
+		sourceID: sourceID,
+		events:   config.GetInt("Warehouse.validations.testSync.events", 10),
+		table:    warehouseutils.ToProviderCase(destType, fmt.Sprintf("%s_%s", testSyncTablePrefix, warehouseutils.RandHex())),
+		schema:   warehouseutils.TableSchemaT{},
+	}
+	for columnName, columnType := range testSyncColumns {
+		sync.schema[warehouseutils.ToProviderCase(destType, columnName)] = columnType
+	}
+
+	pkgLogger.Infof("Running test sync for destinationId: %s, destinationType: %s, sourceId: %s", destination.ID, destType, sourceID)
+	defer sync.cleanupOnceDone()
+
+	resp := DestinationValidationResponse{Steps: sync.steps()}
+	for _, s := range resp.Steps {
+		ct.runStep(s)
+		if !s.Success {
+			pkgLogger.Errorf("error occurred while running test sync for destinationId: %s, destinationType: %s, step: %s with error: %s",
+				destination.ID,
+				destType,
+				s.Name,
+				s.Error,
+			)
+			resp.Error = s.Error
+			break
+		}
+	}
+	if resp.Error == "" {
+		resp.Success = true
+	}
+	return json.Marshal(resp)
+}
+
+// steps returns the steps of the test sync
+func (sync *testSync) steps() []*validationStep {
+	steps := []*validationStep{
+		{
+			ID:        1,
+			Name:      writingStagingFile,
+			Key:       stepTestSyncStaging,
+			Validator: sync.writeStagingFile,
+		},
+		{
+			ID:        2,
+			Name:      generatingLoadFile,
+			Key:       stepTestSyncLoadFile,
+			Validator: sync.generateLoadFile,
+		},
+	}
+
+	// Time window destinations aren't loaded
+	if misc.Contains(warehouseutils.TimeWindowDestinations, sync.ct.infoRequest.Destination.DestinationDefinition.Name) {
+		return steps
+	}
+
+	return append(steps,
+		&validationStep{
+			ID:        3,
+			Name:      loadingTestSyncData,
+			Key:       stepTestSyncLoad,
+			Validator: sync.load,
+		},
+		&validationStep{
+			ID:        4,
+			Name:      verifyingCounts,
+			Key:       stepTestSyncVerify,
+			Validator: sync.verifyCounts,
+		},
+	)
+}
+
+// writeStagingFile writes the synthetic events to a staging file and uploads it
+func (sync *testSync) writeStagingFile(ctx context.Context) error {
+	destType := sync.ct.infoRequest.Destination.DestinationDefinition.Name
+	filePath, err := testSyncFilePath(destType, "json.gz")
+	if err != nil {
+		return err
+	}
+	writer, err := misc.CreateGZ(filePath)
+	if err != nil {
+		return fmt.Errorf("creating staging file: %w", err)
+	}
+
+	receivedAt := time.Now().UTC().Format(time.RFC3339)
+	for i := 0; i < sync.events; i++ {
+		event := stagingEvent{
+			Metadata: stagingEventMetadata{Table: sync.table, Columns: sync.schema},
+			Data: map[string]interface{}{
+				warehouseutils.ToProviderCase(destType, "id"):                misc.FastUUID().String(),
+				warehouseutils.ToProviderCase(destType, "event"):             fmt.Sprintf("test_sync_event_%d", i),
+				warehouseutils.ToProviderCase(destType, "context_source_id"): sync.sourceID,
+				warehouseutils.ToProviderCase(destType, "received_at"):       receivedAt,
+			},
+		}
+		line, err := json.Marshal(event)
+		if err != nil {
+			_ = writer.CloseGZ()
+			return fmt.Errorf("marshalling event: %w", err)
+		}
+		if err := writer.WriteGZ(string(line) + "\n"); err != nil {
+			_ = writer.CloseGZ()
+			return fmt.Errorf("writing staging file: %w", err)
+		}
+	}
+	if err := writer.CloseGZ(); err != nil {
+		return fmt.Errorf("closing staging file: %w", err)
+	}
+
+	sync.stagingFile, err = uploadLoadFile(ctx, sync.ct.infoRequest, filePath)
+	return err
+}
+
+// generateLoadFile generates the load file of the staging file, the way the slaves of the warehouse do
+func (sync *testSync) generateLoadFile(ctx context.Context) error {
+	if sync.ct.LoadFileGenerator == nil {
+		return errors.New("load files can't be generated by this warehouse")
+	}
+	location, err := sync.ct.LoadFileGenerator(ctx, StagingFile{
+		Destination: sync.ct.infoRequest.Destination,
+		SourceID:    sync.sourceID,
+		Namespace:   TestSyncNamespace,
+		Location:    sync.stagingFile.ObjectName,
+		Table:       sync.table,
+		Schema:      sync.schema,
+	})
+	if err != nil {
+		return fmt.Errorf("generating load file: %w", err)
+	}
+
+	fm, err := fileManager(sync.ct.infoRequest)
+	if err != nil {
+		return err
+	}
+	objectName, err := fm.GetObjectNameFromLocation(location)
+	if err != nil {
+		return fmt.Errorf("getting object name of load file %s: %w", location, err)
+	}
+	sync.loadFile = filemanager.UploadOutput{Location: location, ObjectName: objectName}
+	return nil
+}
+
+// load loads the load file into the table of the test sync
+func (sync *testSync) load(ctx context.Context) (err error) {
+	if err = sync.initManager(ctx); err != nil {
+		return
+	}
+	if err = sync.ct.manager.CreateSchema(); err != nil {
+		return missingPrivilege(err, "CREATE", "database", databaseName(sync.ct.warehouse.Destination.Config))
+	}
+	if err = sync.ct.manager.CreateTable(sync.table, sync.schema); err != nil {
+		return missingPrivilege(err, "CREATE", "schema", sync.ct.warehouse.Namespace)
+	}
+	sync.loaded = true
+
+	if err = sync.ct.manager.LoadTable(sync.table); err != nil {
+		return missingPrivilege(err, "INSERT", "table", fmt.Sprintf("%s.%s", sync.ct.warehouse.Namespace, sync.table))
+	}
+	return nil
+}
+
+// verifyCounts verifies that every event of the test sync is in its table
+func (sync *testSync) verifyCounts(ctx context.Context) (err error) {
+	if err = sync.initManager(ctx); err != nil {
+		return
+	}
+	count, err := sync.ct.manager.GetTotalCountInTable(ctx, sync.table)
+	if err != nil {
+		return fmt.Errorf("counting events in table %s: %w", sync.table, err)
+	}
+	if count != int64(sync.events) {
+		return fmt.Errorf("%d events out of %d loaded in table %s", count, sync.events, sync.table)
+	}
+	return nil
+}
+
+// cleanupOnceDone cleans up once the steps timed out are done, in the background if they aren't within the timeout of
+// the load step
+func (sync *testSync) cleanupOnceDone() {
+	done := make(chan struct{})
+	go func() {
+		sync.ct.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		sync.cleanup()
+	case <-time.After(stepTimeout(stepTestSyncLoad)):
+		pkgLogger.Warnf("[DCT]: Test sync steps still running, cleaning up table %s once they complete", sync.table)
+		go func() {
+			<-done
+			sync.cleanup()
+		}()
+	}
+}
+
+// cleanup drops the table of the test sync and deletes its files from the object storage. The steps are to be done,
+// the manager of the handle not being used, for it not to be replaced under steps still running.
+func (sync *testSync) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), stepTimeout(stepTestSyncLoad))
+	defer cancel()
+
+	if sync.loaded {
+		m, _, err := sync.ct.newManagerFor(ctx, TestSyncNamespace, sync.uploadJob())
+		if err != nil {
+			pkgLogger.Warnf("[DCT]: Failed to drop test sync table %s: %v", sync.table, err)
+		} else {
+			if err := m.DropTable(sync.table); err != nil {
+				pkgLogger.Warnf("[DCT]: Failed to drop test sync table %s: %v", sync.table, err)
+			}
+			m.Cleanup()
+		}
+	}
+
+	var keys []string
+	for _, file := range []filemanager.UploadOutput{sync.stagingFile, sync.loadFile} {
+		if file.ObjectName != "" {
+			keys = append(keys, file.ObjectName)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	fm, err := fileManager(sync.ct.infoRequest)
+	if err != nil {
+		return
+	}
+	if err := fm.DeleteObjects(ctx, keys); err != nil {
+		pkgLogger.Warnf("[DCT]: Failed to delete test sync files %v: %v", keys, err)
+	}
+}
+
+func (sync *testSync) initManager(ctx context.Context) error {
+	return sync.ct.initManagerFor(ctx, TestSyncNamespace, sync.uploadJob())
+}
+
+func (sync *testSync) uploadJob() *testSyncUploadJob {
+	return &testSyncUploadJob{
+		CTUploadJob: &CTUploadJob{infoRequest: sync.ct.infoRequest},
+		sync:        sync,
+	}
+}
+
+// testSyncFilePath returns the path of a new file of the test sync, with the format
+func testSyncFilePath(destType, format string) (string, error) {
+	tmpDirPath, err := misc.CreateTMPDIR()
+	if err != nil {
+		return "", fmt.Errorf("creating tmp dir: %w", err)
+	}
+	filePath := fmt.Sprintf("%v/%v/%v.%v.%v.%v", tmpDirPath, connectionTestingFolder, destType, warehouseutils.RandHex(), time.Now().Unix(), format)
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return "", fmt.Errorf("creating dir of %s: %w", filePath, err)
+	}
+	return filePath, nil
+}
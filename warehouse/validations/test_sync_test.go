@@ -0,0 +1,177 @@
+package validations
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	mock_filemanager "github.com/rudderlabs/rudder-server/mocks/services/filemanager"
+	mock_manager "github.com/rudderlabs/rudder-server/mocks/warehouse/manager"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+	"github.com/rudderlabs/rudder-server/warehouse/manager"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+var _ = Describe("Test sync", func() {
+	warehouseutils.Init()
+	misc.Init()
+
+	var (
+		ctrl    *gomock.Controller
+		objects map[string][]byte
+		deletes int32
+	)
+
+	// generateLoadFile is a load file generator copying the staging file as the load file
+	generateLoadFile := func(_ context.Context, stagingFile StagingFile) (string, error) {
+		objectName := "load/" + stagingFile.Table
+		objects[objectName] = objects[stagingFile.Location]
+		return "s3://bucket/" + objectName, nil
+	}
+
+	testSyncRequest := func(destType string) json.RawMessage {
+		req, err := json.Marshal(DestinationValidationRequest{
+			Destination: backendconfig.DestinationT{
+				ID:                    "destination-id",
+				Config:                map[string]interface{}{"bucketName": "bucket"},
+				DestinationDefinition: backendconfig.DestinationDefinitionT{Name: destType},
+			},
+			SourceID: "source-id",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return req
+	}
+
+	BeforeEach(func() {
+		Init()
+		ctrl = gomock.NewController(GinkgoT())
+		objects = map[string][]byte{}
+		atomic.StoreInt32(&deletes, 0)
+
+		fm := mock_filemanager.NewMockFileManager(ctrl)
+		fm.EXPECT().SetTimeout(gomock.Any()).AnyTimes()
+		fm.EXPECT().Upload(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, f *os.File, prefixes ...string) (filemanager.UploadOutput, error) {
+			content, err := io.ReadAll(f)
+			Expect(err).NotTo(HaveOccurred())
+			objectName := strings.Join(append(prefixes, f.Name()[strings.LastIndex(f.Name(), "/")+1:]), "/")
+			objects[objectName] = content
+			return filemanager.UploadOutput{Location: "s3://bucket/" + objectName, ObjectName: objectName}, nil
+		})
+		fm.EXPECT().GetObjectNameFromLocation(gomock.Any()).DoAndReturn(func(location string) (string, error) {
+			return strings.TrimPrefix(location, "s3://bucket/"), nil
+		})
+		fm.EXPECT().DeleteObjects(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, keys []string) error {
+			for _, key := range keys {
+				delete(objects, key)
+			}
+			atomic.AddInt32(&deletes, 1)
+			return nil
+		})
+		fmFactory := mock_filemanager.NewMockFileManagerFactory(ctrl)
+		fmFactory.EXPECT().New(gomock.Any()).Return(fm, nil).AnyTimes()
+		fileManagerFactory = fmFactory
+	})
+
+	It("writes the staging and load files of time window destinations, deleting them afterwards", func() {
+		ct := &CTHandleT{LoadFileGenerator: generateLoadFile}
+		respBytes, err := ct.testSyncFunc(testSyncRequest(warehouseutils.S3_DATALAKE), "")
+		Expect(err).NotTo(HaveOccurred())
+
+		var resp DestinationValidationResponse
+		Expect(json.Unmarshal(respBytes, &resp)).To(Succeed())
+		Expect(resp.Error).To(BeEmpty())
+		Expect(resp.Success).To(BeTrue())
+		Expect(resp.Steps).To(HaveLen(2))
+		Expect(resp.Steps[0]).To(HaveField("Key", stepTestSyncStaging))
+		Expect(resp.Steps[1]).To(HaveField("Key", stepTestSyncLoadFile))
+		Expect(objects).To(BeEmpty(), "files are deleted")
+	})
+
+	It("loads the load file and verifies the counts, dropping the table afterwards", func() {
+		var table string
+		newWarehouseOperations = func(string) (manager.WarehouseOperations, error) {
+			m := mock_manager.NewMockWarehouseOperations(ctrl)
+			m.EXPECT().SetConnectionTimeout(gomock.Any()).AnyTimes()
+			m.EXPECT().Setup(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().Cleanup().AnyTimes()
+			m.EXPECT().CreateSchema().Return(nil).AnyTimes()
+			m.EXPECT().CreateTable(gomock.Any(), gomock.Any()).DoAndReturn(func(tableName string, _ map[string]string) error {
+				table = tableName
+				return nil
+			}).AnyTimes()
+			m.EXPECT().LoadTable(gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().GetTotalCountInTable(gomock.Any(), gomock.Any()).Return(int64(10), nil).AnyTimes()
+			m.EXPECT().DropTable(gomock.Any()).DoAndReturn(func(tableName string) error {
+				Expect(tableName).To(Equal(table))
+				table = ""
+				return nil
+			}).AnyTimes()
+			return m, nil
+		}
+
+		ct := &CTHandleT{LoadFileGenerator: generateLoadFile}
+		respBytes, err := ct.testSyncFunc(testSyncRequest(warehouseutils.POSTGRES), "")
+		Expect(err).NotTo(HaveOccurred())
+
+		var resp DestinationValidationResponse
+		Expect(json.Unmarshal(respBytes, &resp)).To(Succeed())
+		Expect(resp.Error).To(BeEmpty())
+		Expect(resp.Success).To(BeTrue())
+		Expect(resp.Steps).To(HaveLen(4))
+		Expect(resp.Steps[2]).To(HaveField("Key", stepTestSyncLoad))
+		Expect(resp.Steps[3]).To(HaveField("Key", stepTestSyncVerify))
+		Expect(table).To(BeEmpty(), "table is dropped")
+		Expect(objects).To(BeEmpty(), "files are deleted")
+	})
+
+	It("drops the table once a timed out load completes", func() {
+		config.Set("Warehouse.validations.testSyncLoad.timeout", "10ms")
+		defer config.Reset()
+
+		var (
+			release = make(chan struct{})
+			dropped int32
+		)
+		newWarehouseOperations = func(string) (manager.WarehouseOperations, error) {
+			m := mock_manager.NewMockWarehouseOperations(ctrl)
+			m.EXPECT().SetConnectionTimeout(gomock.Any()).AnyTimes()
+			m.EXPECT().Setup(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().Cleanup().AnyTimes()
+			m.EXPECT().CreateSchema().Return(nil).AnyTimes()
+			m.EXPECT().CreateTable(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().LoadTable(gomock.Any()).DoAndReturn(func(string) error {
+				<-release
+				return nil
+			}).AnyTimes()
+			m.EXPECT().DropTable(gomock.Any()).DoAndReturn(func(string) error {
+				atomic.StoreInt32(&dropped, 1)
+				return nil
+			}).AnyTimes()
+			return m, nil
+		}
+
+		ct := &CTHandleT{LoadFileGenerator: generateLoadFile}
+		respBytes, err := ct.testSyncFunc(testSyncRequest(warehouseutils.POSTGRES), "")
+		Expect(err).NotTo(HaveOccurred())
+
+		var resp DestinationValidationResponse
+		Expect(json.Unmarshal(respBytes, &resp)).To(Succeed())
+		Expect(resp.Success).To(BeFalse())
+		Expect(resp.Steps[2]).To(HaveField("Category", categoryTimeout))
+		Expect(atomic.LoadInt32(&dropped)).To(BeZero(), "table isn't dropped while loaded")
+
+		close(release)
+		Eventually(func() int32 { return atomic.LoadInt32(&dropped) }).Should(BeEquivalentTo(1))
+		Eventually(func() int32 { return atomic.LoadInt32(&deletes) }).Should(BeEquivalentTo(1), "files are deleted")
+	})
+})
@@ -1,8 +1,10 @@
 package validations
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
@@ -19,17 +21,26 @@ type validationFunc struct {
 
 type DestinationValidationRequest struct {
 	Destination backendconfig.DestinationT `json:"destination"`
+	// SourceID is the source the synthetic events of test syncs are of
+	SourceID string `json:"sourceId,omitempty"`
 }
 
 type validationStep struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Success   bool      `json:"success"`
-	Error     string    `json:"error"`
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Key     string `json:"key"`
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+	// Category is the category of the error of failed steps
+	Category string `json:"category,omitempty"`
+	// DurationMs is how long the step ran for, in milliseconds
+	DurationMs int64 `json:"durationMs"`
+	// Warnings are the issues found by the step, not failing it
+	Warnings  []string  `json:"warnings,omitempty"`
 	Validator validator `json:"-"`
 }
 
-type validator func() error
+type validator func(ctx context.Context) error
 
 type validationStepsResponse struct {
 	Steps []*validationStep `json:"steps"`
@@ -81,6 +92,9 @@ type DestinationValidationResponse struct {
 	Success bool              `json:"success"`
 	Error   string            `json:"error"`
 	Steps   []*validationStep `json:"steps"`
+	// DDL are the statements creating the schema and the tables the warehouse would be set up with, for a sample schema,
+	// for them to be reviewed before granting the privileges they need. It is partial, see previewDDL.
+	DDL []string `json:"ddl,omitempty"`
 }
 
 type CTHandleT struct {
@@ -89,6 +103,17 @@ type CTHandleT struct {
 	manager          manager.WarehouseOperations
 	CPClient         controlplane.InternalControlPlane
 	EnableTunnelling bool
+	// LoadFileGenerator generates the load files of test syncs, the way the slaves of the warehouse do
+	LoadFileGenerator LoadFileGenerator
+
+	// managerMu guards the manager, set up by the validator of the step running and closed by runStep once it is done
+	// or timed out
+	managerMu sync.Mutex
+
+	warningsMu sync.Mutex
+	warnings   []string
+	// running are the validators running, including the ones timed out
+	running sync.WaitGroup
 }
 
 type CTUploadJob struct {
@@ -64,28 +64,21 @@ func (ct *CTHandleT) validateDestinationFunc(req json.RawMessage, step string) (
 		resp.Steps = append(resp.Steps, v)
 	} else {
 		resp.Steps = ct.validationSteps()
+		resp.DDL = previewDDL(ct.infoRequest)
 	}
 
 	// Iterate over all selected steps and validate
-	for idx, s := range resp.Steps {
-		stepError := s.Validator()
-		if stepError != nil {
-			resp.Steps[idx].Error = stepError.Error()
+	for _, s := range resp.Steps {
+		ct.runStep(s)
+		if !s.Success {
 			pkgLogger.Errorf("error occurred while destination configuration validation for destinationId: %s, destinationType: %s, step: %s with error: %s",
 				destID,
 				destType,
 				s.Name,
-				stepError.Error(),
+				s.Error,
 			)
-		} else {
-			resp.Steps[idx].Success = true
-		}
-		if ct.manager != nil {
-			ct.manager.Cleanup()
-		}
-		// if any of steps fails, the whole validation fails
-		if !resp.Steps[idx].Success {
-			resp.Error = resp.Steps[idx].Error
+			// if any of steps fails, the whole validation fails
+			resp.Error = s.Error
 			break
 		}
 	}
@@ -97,7 +90,7 @@ func (ct *CTHandleT) validateDestinationFunc(req json.RawMessage, step string) (
 	return json.Marshal(resp)
 }
 
-func (ct *CTHandleT) verifyingObjectStorage() (err error) {
+func (ct *CTHandleT) verifyingObjectStorage(ctx context.Context) (err error) {
 	// creating load file
 	tempPath, err := CreateTempLoadFile(ct.infoRequest)
 	if err != nil {
@@ -105,49 +98,86 @@ func (ct *CTHandleT) verifyingObjectStorage() (err error) {
 	}
 
 	// uploading load file to object storage
-	uploadOutput, err := uploadLoadFile(ct.infoRequest, tempPath)
+	uploadOutput, err := uploadLoadFile(ctx, ct.infoRequest, tempPath)
 	if err != nil {
 		return
 	}
 
 	// downloading load file from object storage
-	err = downloadLoadFile(ct.infoRequest, uploadOutput.ObjectName)
+	err = downloadLoadFile(ctx, ct.infoRequest, uploadOutput.ObjectName)
+	if err != nil {
+		return
+	}
+
+	fm, err := fileManager(ct.infoRequest)
+	if err != nil {
+		return
+	}
+	ct.checkBucketPolicies(ctx, fm)
 	return
 }
 
-func (ct *CTHandleT) initManager() (err error) {
-	ct.warehouse = warehouse(ct.infoRequest)
+func (ct *CTHandleT) initManager(ctx context.Context) (err error) {
+	return ct.initManagerFor(ctx, TestNamespace, &CTUploadJob{
+		infoRequest: ct.infoRequest,
+	})
+}
+
+// initManagerFor sets the manager up for the namespace, loading the tables of the uploader
+func (ct *CTHandleT) initManagerFor(ctx context.Context, namespace string, uploader warehouseutils.UploaderI) (err error) {
+	m, wh, err := ct.newManagerFor(ctx, namespace, uploader)
+	ct.setManager(m)
+	ct.warehouse = wh
+	return
+}
+
+// setManager sets the manager of the step running
+func (ct *CTHandleT) setManager(m manager.WarehouseOperations) {
+	ct.managerMu.Lock()
+	defer ct.managerMu.Unlock()
+	ct.manager = m
+}
+
+// currentManager returns the manager of the step running, nil if its validator didn't set one up
+func (ct *CTHandleT) currentManager() manager.WarehouseOperations {
+	ct.managerMu.Lock()
+	defer ct.managerMu.Unlock()
+	return ct.manager
+}
+
+// newManagerFor returns a manager set up for the namespace, loading the tables of the uploader, along with its
+// warehouse, without replacing the ones of the handle
+func (ct *CTHandleT) newManagerFor(ctx context.Context, namespace string, uploader warehouseutils.UploaderI) (manager.WarehouseOperations, warehouseutils.Warehouse, error) {
+	wh := warehouse(ct.infoRequest)
+	wh.Namespace = warehouseutils.ToSafeNamespace(wh.Type, namespace)
 
 	// adding ssh tunnelling info, given we have
 	// useSSH enabled from upstream
 	if ct.EnableTunnelling {
-		if warehouseutils.ReadAsBool("useSSH", ct.warehouse.Destination.Config) {
-			keys, err := ct.CPClient.GetDestinationSSHKeys(context.TODO(), ct.warehouse.Destination.ID)
+		if warehouseutils.ReadAsBool("useSSH", wh.Destination.Config) {
+			keys, err := ct.CPClient.GetDestinationSSHKeys(ctx, wh.Destination.ID)
 			if err != nil {
-				return fmt.Errorf("fetching destination ssh keys: %w", err)
+				return nil, wh, &stepError{category: categoryConfiguration, err: fmt.Errorf("fetching destination ssh keys: %w", err)}
 			}
-			ct.warehouse.Destination.Config["sshPrivateKey"] = keys.PrivateKey
+			wh.Destination.Config["sshPrivateKey"] = keys.PrivateKey
 		}
 	}
 
 	// Initializing manager
-	ct.manager, err = manager.NewWarehouseOperations(ct.warehouse.Destination.DestinationDefinition.Name)
+	m, err := newWarehouseOperations(wh.Destination.DestinationDefinition.Name)
 	if err != nil {
-		return
+		return nil, wh, &stepError{category: categoryConfiguration, err: err}
 	}
 
 	// Setting test connection timeout
-	ct.manager.SetConnectionTimeout(warehouseutils.TestConnectionTimeout)
+	m.SetConnectionTimeout(warehouseutils.TestConnectionTimeout)
 
 	// setting up the manager
-	err = ct.manager.Setup(ct.warehouse, &CTUploadJob{
-		infoRequest: ct.infoRequest,
-	})
-	return
+	return m, wh, m.Setup(wh, uploader)
 }
 
-func (ct *CTHandleT) verifyingConnections() (err error) {
-	err = ct.initManager()
+func (ct *CTHandleT) verifyingConnections(ctx context.Context) (err error) {
+	err = ct.initManager(ctx)
 	if err != nil {
 		return
 	}
@@ -156,8 +186,8 @@ func (ct *CTHandleT) verifyingConnections() (err error) {
 	return
 }
 
-func (ct *CTHandleT) verifyingCreateSchema() (err error) {
-	err = ct.initManager()
+func (ct *CTHandleT) verifyingCreateSchema(ctx context.Context) (err error) {
+	err = ct.initManager(ctx)
 	if err != nil {
 		return
 	}
@@ -166,8 +196,8 @@ func (ct *CTHandleT) verifyingCreateSchema() (err error) {
 	return
 }
 
-func (ct *CTHandleT) verifyingCreateAlterTable() (err error) {
-	err = ct.initManager()
+func (ct *CTHandleT) verifyingCreateAlterTable(ctx context.Context) (err error) {
+	err = ct.initManager(ctx)
 	if err != nil {
 		return
 	}
@@ -193,8 +223,8 @@ func (ct *CTHandleT) verifyingCreateAlterTable() (err error) {
 	return
 }
 
-func (ct *CTHandleT) verifyingFetchSchema() (err error) {
-	err = ct.initManager()
+func (ct *CTHandleT) verifyingFetchSchema(ctx context.Context) (err error) {
+	err = ct.initManager(ctx)
 	if err != nil {
 		return
 	}
@@ -203,8 +233,8 @@ func (ct *CTHandleT) verifyingFetchSchema() (err error) {
 	return
 }
 
-func (ct *CTHandleT) verifyingLoadTable() (err error) {
-	err = ct.initManager()
+func (ct *CTHandleT) verifyingLoadTable(ctx context.Context) (err error) {
+	err = ct.initManager(ctx)
 	if err != nil {
 		return
 	}
@@ -216,7 +246,7 @@ func (ct *CTHandleT) verifyingLoadTable() (err error) {
 	}
 
 	// uploading load file
-	uploadOutput, err := uploadLoadFile(ct.infoRequest, tempPath)
+	uploadOutput, err := uploadLoadFile(ctx, ct.infoRequest, tempPath)
 	if err != nil {
 		return
 	}
@@ -278,7 +308,7 @@ func CreateTempLoadFile(req *DestinationValidationRequest) (filePath string, err
 	return
 }
 
-func uploadLoadFile(req *DestinationValidationRequest, filePath string) (uploadOutput filemanager.UploadOutput, err error) {
+func uploadLoadFile(ctx context.Context, req *DestinationValidationRequest, filePath string) (uploadOutput filemanager.UploadOutput, err error) {
 	destination := req.Destination
 	destinationType := destination.DestinationDefinition.Name
 
@@ -302,7 +332,7 @@ func uploadLoadFile(req *DestinationValidationRequest, filePath string) (uploadO
 
 	// uploading file to object storage
 	keyPrefixes := []string{connectionTestingFolder, destinationType, warehouseutils.RandHex(), time.Now().Format("01-02-2006")}
-	uploadOutput, err = fm.Upload(context.TODO(), uploadFile, keyPrefixes...)
+	uploadOutput, err = fm.Upload(ctx, uploadFile, keyPrefixes...)
 	if err != nil {
 		pkgLogger.Errorf("[DCT]: Failed to upload filePath: %s with error: %s", filePath, err.Error())
 		return
@@ -310,7 +340,7 @@ func uploadLoadFile(req *DestinationValidationRequest, filePath string) (uploadO
 	return uploadOutput, err
 }
 
-func downloadLoadFile(req *DestinationValidationRequest, location string) (err error) {
+func downloadLoadFile(ctx context.Context, req *DestinationValidationRequest, location string) (err error) {
 	destination := req.Destination
 	destinationType := destination.DestinationDefinition.Name
 
@@ -348,7 +378,7 @@ func downloadLoadFile(req *DestinationValidationRequest, location string) (err e
 	defer func() { _ = testFile.Close() }()
 
 	// downloading temporary file to specified from object storage location
-	err = fm.Download(context.TODO(), testFile, location)
+	err = fm.Download(ctx, testFile, location)
 	if err != nil {
 		pkgLogger.Errorf("DCT: Failed to download tempFilePath: %s with error: %s", location, err.Error())
 		return
@@ -365,7 +395,7 @@ func (ct *CTHandleT) loadTable(loadFileLocation string) (err error) {
 	// Create table
 	err = ct.manager.CreateTable(stagingTableName, TestTableSchemaMap)
 	if err != nil {
-		return
+		return missingPrivilege(err, "CREATE", "schema", ct.warehouse.Namespace)
 	}
 
 	// Drop table
@@ -373,5 +403,8 @@ func (ct *CTHandleT) loadTable(loadFileLocation string) (err error) {
 
 	// loading test table from staging file
 	err = ct.manager.LoadTestTable(loadFileLocation, stagingTableName, TestPayloadMap, warehouseutils.GetLoadFileFormat(destinationType))
+	if err != nil {
+		return missingPrivilege(err, "INSERT", "table", fmt.Sprintf("%s.%s", ct.warehouse.Namespace, stagingTableName))
+	}
 	return
 }
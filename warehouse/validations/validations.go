@@ -10,6 +10,7 @@ import (
 	"github.com/rudderlabs/rudder-server/services/filemanager"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
+	"github.com/rudderlabs/rudder-server/warehouse/manager"
 )
 
 var (
@@ -17,6 +18,8 @@ var (
 	pkgLogger               logger.Logger
 	fileManagerFactory      filemanager.FileManagerFactory
 	fileManagerTimeout      time.Duration
+	newWarehouseOperations  func(destType string) (manager.WarehouseOperations, error)
+	retryTimeWindow         time.Duration
 )
 
 var (
@@ -34,8 +37,20 @@ var (
 	}
 )
 
+// keys of the validation steps, in machine-readable step results
+const (
+	stepObjectStorage       = "objectStorage"
+	stepPreChecks           = "preChecks"
+	stepConnect             = "connect"
+	stepCreateSchema        = "createSchema"
+	stepCreateAndAlterTable = "createAndAlterTable"
+	stepFetchSchema         = "fetchSchema"
+	stepLoadTable           = "loadTable"
+)
+
 const (
 	verifyingObjectStorage       = "Verifying Object Storage"
+	verifyingPreChecks           = "Verifying Network and Privileges"
 	verifyingConnections         = "Verifying Connections"
 	verifyingCreateSchema        = "Verifying Create Schema"
 	verifyingCreateAndAlterTable = "Verifying Create and Alter Table"
@@ -48,6 +63,8 @@ func Init() {
 	pkgLogger = logger.NewLogger().Child("warehouse").Child("validations")
 	fileManagerFactory = filemanager.DefaultFileManagerFactory
 	fileManagerTimeout = 15 * time.Second
+	newWarehouseOperations = manager.NewWarehouseOperations
+	config.RegisterDurationConfigVariable(180, &retryTimeWindow, true, time.Minute, []string{"Warehouse.retryTimeWindow", "Warehouse.retryTimeWindowInMins"}...)
 }
 
 // Validating Facade for Global invoking validation
@@ -80,5 +97,9 @@ func (ct *CTHandleT) validationFunctions() map[string]*validationFunc {
 			Path: "/steps",
 			Func: ct.validationStepsFunc,
 		},
+		"testSync": {
+			Path: "/testSync",
+			Func: ct.testSyncFunc,
+		},
 	}
 }
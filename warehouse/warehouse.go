@@ -10,7 +10,6 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
-	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -39,12 +38,12 @@ import (
 	"github.com/rudderlabs/rudder-server/utils/httputil"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
-	"github.com/rudderlabs/rudder-server/utils/timeutil"
 	"github.com/rudderlabs/rudder-server/utils/types"
 	"github.com/rudderlabs/rudder-server/warehouse/archive"
 	cpclient "github.com/rudderlabs/rudder-server/warehouse/client/controlplane"
 	"github.com/rudderlabs/rudder-server/warehouse/deltalake"
 	"github.com/rudderlabs/rudder-server/warehouse/internal/api"
+	"github.com/rudderlabs/rudder-server/warehouse/internal/faults"
 	"github.com/rudderlabs/rudder-server/warehouse/internal/model"
 	"github.com/rudderlabs/rudder-server/warehouse/internal/repo"
 	"github.com/rudderlabs/rudder-server/warehouse/jobs"
@@ -71,6 +70,7 @@ var (
 	lastProcessedMarkerMap              map[string]int64
 	lastProcessedMarkerMapLock          sync.RWMutex
 	warehouseMode                       string
+	dynamicModeEnabled                  bool
 	warehouseSyncPreFetchCount          int
 	warehouseSyncFreqIgnore             bool
 	minRetryAttempts                    int
@@ -85,6 +85,9 @@ var (
 	longRunningUploadStatThresholdInMin time.Duration
 	pkgLogger                           logger.Logger
 	numLoadFileUploadWorkers            int
+	enableStreamingLoadFileUpload       bool
+	presignedURLDefaultExpiry           time.Duration
+	presignedURLMaxExpiry               time.Duration
 	slaveUploadTimeout                  time.Duration
 	tableCountQueryTimeout              time.Duration
 	runningMode                         string
@@ -100,6 +103,7 @@ var (
 	asyncWh                             *jobs.AsyncJobWhT
 	configBackendURL                    string
 	enableTunnelling                    bool
+	debuggerMaxStagingFiles             int
 )
 
 var (
@@ -131,10 +135,14 @@ type HandleT struct {
 	warehouses                        []warehouseutils.Warehouse
 	dbHandle                          *sql.DB
 	warehouseDBHandle                 *DB
-	stagingRepo                       *repo.StagingFiles
-	notifier                          pgnotifier.PgNotifierT
+	stagingRepo                       stagingFilesRepo
+	newManager                        managerFactory
+	notifier                          uploadNotifier
 	isEnabled                         bool
 	configSubscriberLock              sync.RWMutex
+	workspaceConfigs                  map[string]backendconfig.ConfigT      // last config received, by workspace
+	warehousesByWorkspace             map[string][]warehouseutils.Warehouse // warehouses of the last config, by workspace
+	warehousesVersion                 int                                   // incremented whenever a config replaces the warehouses
 	workerChannelMap                  map[string]chan *UploadJobT
 	workerChannelMapLock              sync.RWMutex
 	initialConfigFetched              bool
@@ -150,8 +158,8 @@ type HandleT struct {
 	workspaceBySourceIDsLock          sync.RWMutex
 	tenantManager                     multitenant.Manager
 	stats                             stats.Stats
-	Now                               string
-	cpInternalClient                  cpclient.InternalControlPlane
+	now                               func() time.Time
+	cpInternalClient                  cpclient.InternalControlPlaneWithCache
 
 	backgroundCancel context.CancelFunc
 	backgroundGroup  errgroup.Group
@@ -164,6 +172,7 @@ type ErrorResponseT struct {
 
 func Init4() {
 	loadConfig()
+	faults.Init()
 	pkgLogger = logger.NewLogger().Child("warehouse")
 }
 
@@ -174,10 +183,12 @@ func loadConfig() {
 	config.RegisterIntConfigVariable(960, &stagingFilesBatchSize, true, 1, "Warehouse.stagingFilesBatchSize")
 	config.RegisterInt64ConfigVariable(1800, &uploadFreqInS, true, 1, "Warehouse.uploadFreqInS")
 	config.RegisterDurationConfigVariable(5, &mainLoopSleep, true, time.Second, []string{"Warehouse.mainLoopSleep", "Warehouse.mainLoopSleepInS"}...)
+	loadClockOffset()
 	crashRecoverWarehouses = []string{warehouseutils.RS, warehouseutils.POSTGRES, warehouseutils.MSSQL, warehouseutils.AZURE_SYNAPSE, warehouseutils.DELTALAKE}
 	inRecoveryMap = map[string]bool{}
 	lastProcessedMarkerMap = map[string]int64{}
 	config.RegisterStringConfigVariable("embedded", &warehouseMode, false, "Warehouse.mode")
+	config.RegisterBoolConfigVariable(false, &dynamicModeEnabled, false, "Warehouse.dynamicMode.enabled")
 	host = config.GetString("WAREHOUSE_JOBS_DB_HOST", "localhost")
 	user = config.GetString("WAREHOUSE_JOBS_DB_USER", "ubuntu")
 	dbname = config.GetString("WAREHOUSE_JOBS_DB_DB_NAME", "ubuntu")
@@ -198,6 +209,9 @@ func loadConfig() {
 	config.RegisterDurationConfigVariable(120, &longRunningUploadStatThresholdInMin, true, time.Minute, []string{"Warehouse.longRunningUploadStatThreshold", "Warehouse.longRunningUploadStatThresholdInMin"}...)
 	config.RegisterDurationConfigVariable(10, &slaveUploadTimeout, true, time.Minute, []string{"Warehouse.slaveUploadTimeout", "Warehouse.slaveUploadTimeoutInMin"}...)
 	config.RegisterIntConfigVariable(8, &numLoadFileUploadWorkers, true, 1, "Warehouse.numLoadFileUploadWorkers")
+	config.RegisterBoolConfigVariable(false, &enableStreamingLoadFileUpload, true, "Warehouse.enableStreamingLoadFileUpload")
+	config.RegisterDurationConfigVariable(15, &presignedURLDefaultExpiry, true, time.Minute, []string{"Warehouse.presignedURLDefaultExpiry", "Warehouse.presignedURLDefaultExpiryInMin"}...)
+	config.RegisterDurationConfigVariable(12, &presignedURLMaxExpiry, true, time.Hour, []string{"Warehouse.presignedURLMaxExpiry", "Warehouse.presignedURLMaxExpiryInHours"}...)
 	runningMode = config.GetString("Warehouse.runningMode", "")
 	config.RegisterDurationConfigVariable(30, &uploadStatusTrackFrequency, false, time.Minute, []string{"Warehouse.uploadStatusTrackFrequency", "Warehouse.uploadStatusTrackFrequencyInMin"}...)
 	config.RegisterIntConfigVariable(180, &uploadBufferTimeInMin, false, 1, "Warehouse.uploadBufferTimeInMin")
@@ -208,6 +222,7 @@ func loadConfig() {
 	config.RegisterBoolConfigVariable(false, &skipDeepEqualSchemas, true, "Warehouse.skipDeepEqualSchemas")
 	config.RegisterIntConfigVariable(8, &maxParallelJobCreation, true, 1, "Warehouse.maxParallelJobCreation")
 	config.RegisterBoolConfigVariable(false, &enableJitterForSyncs, true, "Warehouse.enableJitterForSyncs")
+	config.RegisterIntConfigVariable(10, &debuggerMaxStagingFiles, true, 1, "Warehouse.debugger.maxStagingFiles")
 	config.RegisterDurationConfigVariable(30, &tableCountQueryTimeout, true, time.Second, []string{"Warehouse.tableCountQueryTimeout", "Warehouse.tableCountQueryTimeoutInS"}...)
 
 	appName = misc.DefaultString("rudder-server").OnError(os.Hostname())
@@ -262,15 +277,19 @@ func (wh *HandleT) incrementActiveWorkers() {
 
 func (wh *HandleT) initWorker() chan *UploadJobT {
 	workerChan := make(chan *UploadJobT, 1000)
+	workerPool := rruntime.RegisterPool("warehouse", wh.destType, wh.maxConcurrentUploadJobs)
 	for i := 0; i < wh.maxConcurrentUploadJobs; i++ {
 		wh.backgroundGroup.Go(func() error {
 			for uploadJob := range workerChan {
 				wh.incrementActiveWorkers()
+				busyDone := workerPool.Busy()
 				err := wh.handleUploadJob(uploadJob)
-				if err != nil {
+				busyDone()
+				if err != nil && !errors.Is(err, errUploadDrained) {
 					pkgLogger.Errorf("[WH] Failed in handle Upload jobs for worker: %+w", err)
 				}
 				wh.removeDestInProgress(uploadJob.warehouse, uploadJob.upload.ID)
+				tenantManager.ReleaseWarehouseUpload(uploadJob.upload.WorkspaceID)
 				wh.decrementActiveWorkers()
 			}
 			return nil
@@ -297,7 +316,15 @@ func (wh *HandleT) backendConfigSubscriber(ctx context.Context) {
 		wh.workspaceBySourceIDs = map[string]string{}
 
 		pkgLogger.Info(`Received updated workspace config`)
+		// only the warehouses of the workspaces whose config changed are set up again
+		changedWorkspaces := backendconfig.ChangedWorkspaces(wh.workspaceConfigs, config)
+		warehousesByWorkspace := make(map[string][]warehouseutils.Warehouse, len(config))
 		for workspaceID, wConfig := range config {
+			_, changed := changedWorkspaces[workspaceID]
+			var warehouses []warehouseutils.Warehouse
+			if !changed {
+				warehouses = wh.warehousesByWorkspace[workspaceID]
+			}
 			for _, source := range wConfig.Sources {
 				if _, ok := sourceIDsByWorkspace[workspaceID]; !ok {
 					sourceIDsByWorkspace[workspaceID] = []string{}
@@ -306,7 +333,7 @@ func (wh *HandleT) backendConfigSubscriber(ctx context.Context) {
 				sourceIDsByWorkspace[workspaceID] = append(sourceIDsByWorkspace[workspaceID], source.ID)
 				wh.workspaceBySourceIDs[source.ID] = workspaceID
 
-				if len(source.Destinations) == 0 {
+				if !changed || len(source.Destinations) == 0 {
 					continue
 				}
 
@@ -329,7 +356,7 @@ func (wh *HandleT) backendConfigSubscriber(ctx context.Context) {
 						Type:        wh.destType,
 						Identifier:  warehouseutils.GetWarehouseIdentifier(wh.destType, source.ID, destination.ID),
 					}
-					wh.warehouses = append(wh.warehouses, warehouse)
+					warehouses = append(warehouses, warehouse)
 
 					workerName := wh.workerIdentifier(warehouse)
 					wh.workerChannelMapLock.Lock()
@@ -364,7 +391,12 @@ func (wh *HandleT) backendConfigSubscriber(ctx context.Context) {
 					}
 				}
 			}
+			wh.warehouses = append(wh.warehouses, warehouses...)
+			warehousesByWorkspace[workspaceID] = warehouses
 		}
+		wh.workspaceConfigs = config
+		wh.warehousesByWorkspace = warehousesByWorkspace
+		wh.warehousesVersion++
 
 		pkgLogger.Infof("Releasing config subscriber lock: %s", wh.destType)
 		wh.workspaceBySourceIDsLock.Unlock()
@@ -527,7 +559,7 @@ func (wh *HandleT) initUpload(warehouse warehouseutils.Warehouse, jsonUploadsLis
 		lastEventAt = jsonUploadsList[len(jsonUploadsList)-1].LastEventAt
 	}
 
-	now := timeutil.Now()
+	now := wh.currentTime()
 	metadataMap := map[string]interface{}{
 		"use_rudder_storage": jsonUploadsList[0].UseRudderStorage, // TODO: Since the use_rudder_storage is now being populated for both the staging and load files. Let's try to leverage it instead of hard coding it from the first staging file.
 		"source_batch_id":    jsonUploadsList[0].SourceBatchID,
@@ -617,11 +649,11 @@ func getUploadFreqInS(syncFrequency string) int64 {
 	return freqInS
 }
 
-func uploadFrequencyExceeded(warehouse warehouseutils.Warehouse, syncFrequency string) bool {
+func uploadFrequencyExceeded(warehouse warehouseutils.Warehouse, syncFrequency string, now time.Time) bool {
 	freqInS := getUploadFreqInS(syncFrequency)
 	lastProcessedMarkerMapLock.Lock()
 	defer lastProcessedMarkerMapLock.Unlock()
-	if lastExecTime, ok := lastProcessedMarkerMap[warehouse.Identifier]; ok && timeutil.Now().Unix()-lastExecTime < freqInS {
+	if lastExecTime, ok := lastProcessedMarkerMap[warehouse.Identifier]; ok && now.Unix()-lastExecTime < freqInS {
 		return true
 	}
 	return false
@@ -667,11 +699,11 @@ func (wh *HandleT) createUploadJobsFromStagingFiles(warehouse warehouseutils.War
 	}
 }
 
-func getUploadStartAfterTime() time.Time {
+func getUploadStartAfterTime(now time.Time) time.Time {
 	if enableJitterForSyncs {
-		return timeutil.Now().Add(time.Duration(rand.Intn(15)) * time.Second)
+		return now.Add(time.Duration(rand.Intn(15)) * time.Second)
 	}
-	return time.Now()
+	return now
 }
 
 func (wh *HandleT) getLatestUploadStatus(warehouse *warehouseutils.Warehouse) (int64, string, int) {
@@ -701,7 +733,7 @@ func (wh *HandleT) deleteWaitingUploadJob(jobID int64) {
 }
 
 func (wh *HandleT) createJobs(ctx context.Context, warehouse warehouseutils.Warehouse) (err error) {
-	whManager, err := manager.New(wh.destType)
+	whManager, err := wh.newManager(wh.destType)
 	if err != nil {
 		return err
 	}
@@ -762,7 +794,7 @@ func (wh *HandleT) createJobs(ctx context.Context, warehouse warehouseutils.Ware
 	})
 	uploadJobCreationStat.Start()
 
-	uploadStartAfter := getUploadStartAfterTime()
+	uploadStartAfter := getUploadStartAfterTime(wh.currentTime())
 	wh.createUploadJobsFromStagingFiles(warehouse, whManager, stagingFilesList, priority, uploadStartAfter)
 	setLastProcessedMarker(warehouse, uploadStartAfter)
 
@@ -826,12 +858,9 @@ func (wh *HandleT) processingStats(ctx context.Context, availableWorkers int, sk
 		pickupLagInSeconds      float64
 		pickupWaitTimeInSeconds float64
 		err                     error
-		Now                     = "NOW()"
+		Now                     = sqlTimestamp(wh.currentTime())
 		degradedWorkspaces      = tenantManager.DegradedWorkspaces()
 	)
-	if wh.Now != "" {
-		Now = wh.Now
-	}
 	if degradedWorkspaces == nil {
 		degradedWorkspaces = []string{}
 	}
@@ -954,7 +983,7 @@ func (wh *HandleT) getUploadsToProcess(ctx context.Context, availableWorkers int
 					t.in_progress=%t AND
 					t.status != '%s' AND
 					t.status != '%s' %s AND
-					COALESCE(metadata->>'nextRetryTime', NOW()::text)::timestamptz <= NOW() AND
+					COALESCE(metadata->>'nextRetryTime', %[9]s::text)::timestamptz <= %[9]s AND
           			workspace_id <> ALL ($1)
 			) grouped_uploads
 			WHERE
@@ -962,7 +991,7 @@ func (wh *HandleT) getUploadsToProcess(ctx context.Context, availableWorkers int
 			ORDER BY
 				COALESCE(metadata->>'priority', '100')::int ASC,
 				id ASC
-			LIMIT %d;
+			LIMIT %[8]d;
 `,
 		partitionIdentifierSQL,
 		warehouseutils.WarehouseUploadsTable,
@@ -972,6 +1001,7 @@ func (wh *HandleT) getUploadsToProcess(ctx context.Context, availableWorkers int
 		model.Aborted,
 		skipIdentifiersSQL,
 		availableWorkers,
+		sqlTimestamp(wh.currentTime()),
 	)
 
 	var (
@@ -979,22 +1009,23 @@ func (wh *HandleT) getUploadsToProcess(ctx context.Context, availableWorkers int
 		err                error
 		degradedWorkspaces = tenantManager.DegradedWorkspaces()
 	)
-	if degradedWorkspaces == nil {
-		degradedWorkspaces = []string{}
-	}
+	// skipping the workspaces at their concurrent uploads quota or drained along with the degraded ones
+	excludedWorkspaces := append(append([]string{}, degradedWorkspaces...), tenantManager.WarehouseUploadsAtQuota()...)
+	excludedWorkspaces = append(excludedWorkspaces, tenantManager.DrainingWorkspaces()...)
+	excludedWorkspaces = append(excludedWorkspaces, tenantManager.PausedWorkspaces()...)
 
 	if len(skipIdentifiers) > 0 {
 		rows, err = wh.dbHandle.QueryContext(
 			ctx,
 			sqlStatement,
-			pq.Array(degradedWorkspaces),
+			pq.Array(excludedWorkspaces),
 			pq.Array(skipIdentifiers),
 		)
 	} else {
 		rows, err = wh.dbHandle.QueryContext(
 			ctx,
 			sqlStatement,
-			pq.Array(degradedWorkspaces),
+			pq.Array(excludedWorkspaces),
 		)
 	}
 
@@ -1088,6 +1119,7 @@ func (wh *HandleT) getUploadsToProcess(ctx context.Context, availableWorkers int
 				upload:   &upload,
 				dbHandle: wh.dbHandle,
 				stats:    wh.stats,
+				now:      wh.now,
 			}
 			err := fmt.Errorf("unable to find source : %s or destination : %s, both or the connection between them", upload.SourceID, upload.DestinationID)
 			_, _ = uploadJob.setUploadError(err, model.Aborted)
@@ -1098,42 +1130,12 @@ func (wh *HandleT) getUploadsToProcess(ctx context.Context, availableWorkers int
 		upload.SourceType = warehouse.Source.SourceDefinition.Name
 		upload.SourceCategory = warehouse.Source.SourceDefinition.Category
 
-		stagingFilesList, err := wh.stagingRepo.GetInRange(
-			ctx,
-			warehouse.Source.ID,
-			warehouse.Destination.ID,
-			upload.StartStagingFileID,
-			upload.EndStagingFileID,
-		)
+		uploadJob, err := wh.newUploadJob(ctx, &upload, warehouse)
 		if err != nil {
 			return nil, err
 		}
 
-		stagingFileIDs := make([]int64, len(stagingFilesList))
-		stagingFileListPtr := make([]*model.StagingFile, len(stagingFilesList))
-		for i := range stagingFilesList {
-			stagingFileIDs[i] = stagingFilesList[i].ID
-			stagingFileListPtr[i] = &stagingFilesList[i]
-		}
-
-		whManager, err := manager.New(wh.destType)
-		if err != nil {
-			return nil, err
-		}
-
-		uploadJob := UploadJobT{
-			upload:               &upload,
-			stagingFiles:         stagingFileListPtr,
-			stagingFileIDs:       stagingFileIDs,
-			warehouse:            warehouse,
-			whManager:            whManager,
-			dbHandle:             wh.dbHandle,
-			pgNotifier:           &wh.notifier,
-			destinationValidator: validations.NewDestinationValidator(),
-			stats:                wh.stats,
-		}
-
-		uploadJobs = append(uploadJobs, &uploadJob)
+		uploadJobs = append(uploadJobs, uploadJob)
 	}
 
 	if err = wh.processingStats(ctx, availableWorkers, skipIdentifiers, skipIdentifiersSQL); err != nil {
@@ -1143,6 +1145,45 @@ func (wh *HandleT) getUploadsToProcess(ctx context.Context, availableWorkers int
 	return uploadJobs, nil
 }
 
+// newUploadJob returns the job of the upload to the warehouse, along with the staging files of the upload
+func (wh *HandleT) newUploadJob(ctx context.Context, upload *Upload, warehouse warehouseutils.Warehouse) (*UploadJobT, error) {
+	stagingFilesList, err := wh.stagingRepo.GetInRange(
+		ctx,
+		warehouse.Source.ID,
+		warehouse.Destination.ID,
+		upload.StartStagingFileID,
+		upload.EndStagingFileID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	stagingFileIDs := make([]int64, len(stagingFilesList))
+	stagingFileListPtr := make([]*model.StagingFile, len(stagingFilesList))
+	for i := range stagingFilesList {
+		stagingFileIDs[i] = stagingFilesList[i].ID
+		stagingFileListPtr[i] = &stagingFilesList[i]
+	}
+
+	whManager, err := wh.newManager(wh.destType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadJobT{
+		upload:               upload,
+		stagingFiles:         stagingFileListPtr,
+		stagingFileIDs:       stagingFileIDs,
+		warehouse:            warehouse,
+		whManager:            whManager,
+		dbHandle:             wh.dbHandle,
+		pgNotifier:           wh.notifier,
+		destinationValidator: validations.NewDestinationValidator(),
+		stats:                wh.stats,
+		now:                  wh.now,
+	}, nil
+}
+
 func (wh *HandleT) getInProgressNamespaces() (identifiers []string) {
 	wh.inProgressMapLock.Lock()
 	defer wh.inProgressMapLock.Unlock()
@@ -1187,9 +1228,16 @@ loop:
 			panic(err)
 		}
 
+		uploadJobsWithinQuota := uploadJobsToProcess[:0]
 		for _, uploadJob := range uploadJobsToProcess {
+			// uploads of workspaces at their concurrent uploads quota are picked up once their running ones are done
+			if !tenantManager.AcquireWarehouseUpload(uploadJob.upload.WorkspaceID) {
+				continue
+			}
+			uploadJobsWithinQuota = append(uploadJobsWithinQuota, uploadJob)
 			wh.setDestInProgress(uploadJob.warehouse, uploadJob.upload.ID)
 		}
+		uploadJobsToProcess = uploadJobsWithinQuota
 		wh.areBeingEnqueuedLock.Unlock()
 
 		for _, uploadJob := range uploadJobsToProcess {
@@ -1243,8 +1291,8 @@ func (wh *HandleT) uploadStatusTrack(ctx context.Context) {
 				where
 				  source_id = '%[2]s'
 				  and destination_id = '%[3]s'
-				  and created_at > NOW() - interval '%[4]d MIN'
-				  and created_at < NOW() - interval '%[5]d MIN'
+				  and created_at > %[6]s - interval '%[4]d MIN'
+				  and created_at < %[6]s - interval '%[5]d MIN'
 				order by
 				  created_at desc
 				limit
@@ -1256,6 +1304,7 @@ func (wh *HandleT) uploadStatusTrack(ctx context.Context) {
 				destination.ID,
 				2*timeWindow,
 				timeWindow,
+				sqlTimestamp(wh.currentTime()),
 			)
 
 			var createdAt sql.NullTime
@@ -1383,7 +1432,8 @@ func (wh *HandleT) Setup(whType string) {
 	wh.stagingRepo = &repo.StagingFiles{
 		DB: dbHandle,
 	}
-	wh.notifier = notifier
+	wh.newManager = manager.New
+	wh.notifier = &notifier
 	wh.destType = whType
 	wh.setInterruptedDestinations()
 	wh.resetInProgressJobs()
@@ -1400,13 +1450,7 @@ func (wh *HandleT) Setup(whType string) {
 	config.RegisterIntConfigVariable(1, &wh.maxConcurrentUploadJobs, false, 1, fmt.Sprintf(`Warehouse.%v.maxConcurrentUploadJobs`, whName))
 	config.RegisterBoolConfigVariable(false, &wh.allowMultipleSourcesForJobsPickup, false, fmt.Sprintf(`Warehouse.%v.allowMultipleSourcesForJobsPickup`, whName))
 
-	wh.cpInternalClient = cpclient.NewInternalClientWithCache(
-		configBackendURL,
-		cpclient.BasicAuth{
-			Username: config.GetString("CP_INTERNAL_API_USERNAME", ""),
-			Password: config.GetString("CP_INTERNAL_API_PASSWORD", ""),
-		},
-	)
+	wh.cpInternalClient = sshKeysClient()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	g, ctx := errgroup.WithContext(ctx)
@@ -1424,6 +1468,11 @@ func (wh *HandleT) Setup(whType string) {
 		return nil
 	}))
 
+	g.Go(misc.WithBugsnagForWarehouse(func() error {
+		wh.watchSSHKeyRotations(ctx)
+		return nil
+	}))
+
 	g.Go(misc.WithBugsnagForWarehouse(func() error {
 		wh.runUploadJobAllocator(ctx)
 		return nil
@@ -1736,8 +1785,8 @@ func pendingEventsHandler(w http.ResponseWriter, r *http.Request) {
 		triggerPendingUpload, _ = strconv.ParseBool(triggerUploadQP)
 	}
 
-	// trigger upload if there are pending events and triggerPendingUpload is true
-	if pendingEvents && triggerPendingUpload {
+	// trigger upload if there are pending events and triggerPendingUpload is true, unless uploads aren't processed
+	if pendingEvents && triggerPendingUpload && capabilityAvailable(capabilityTriggerUploads) {
 		pkgLogger.Infof("[WH]: Triggering upload for all wh destinations connected to source '%s'", sourceID)
 		wh := make([]warehouseutils.Warehouse, 0)
 
@@ -1910,6 +1959,162 @@ func triggerUploadHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// presignedURLHandler returns a pre-signed url for a staging or load file of a warehouse destination
+func presignedURLHandler(w http.ResponseWriter, r *http.Request) {
+	pkgLogger.LogRequest(r)
+
+	ctx := r.Context()
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		pkgLogger.Errorf("[WH]: Error reading body: %v", err)
+		http.Error(w, "can't read body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var presignedURLReq warehouseutils.PresignedURLRequestT
+	err = json.Unmarshal(body, &presignedURLReq)
+	if err != nil {
+		pkgLogger.Errorf("[WH]: Error unmarshalling body: %v", err)
+		http.Error(w, "can't unmarshall body", http.StatusBadRequest)
+		return
+	}
+	if presignedURLReq.WorkspaceID == "" {
+		http.Error(w, "empty workspace id", http.StatusBadRequest)
+		return
+	}
+	if (presignedURLReq.StagingFileID == 0) == (presignedURLReq.LoadFileID == 0) {
+		http.Error(w, "exactly one of staging_file_id and load_file_id is required", http.StatusBadRequest)
+		return
+	}
+
+	warehouse, err := getDestinationFromConnectionMap(presignedURLReq.DestinationID, presignedURLReq.SourceID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if warehouse.Destination.WorkspaceID != presignedURLReq.WorkspaceID {
+		http.Error(w, "destination doesn't belong to workspace", http.StatusForbidden)
+		return
+	}
+
+	file, err := getPresignableFile(ctx, presignedURLReq)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "file not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		pkgLogger.Errorf("[WH]: Error getting file to pre-sign: %v", err)
+		http.Error(w, "can't get file", http.StatusInternalServerError)
+		return
+	}
+	if file.sourceID != presignedURLReq.SourceID || file.destinationID != presignedURLReq.DestinationID ||
+		(file.workspaceID != "" && file.workspaceID != presignedURLReq.WorkspaceID) {
+		http.Error(w, "file doesn't belong to workspace", http.StatusForbidden)
+		return
+	}
+
+	expiry := presignedURLDefaultExpiry
+	if presignedURLReq.ExpiryInS > 0 {
+		expiry = time.Duration(presignedURLReq.ExpiryInS) * time.Second
+	}
+	if expiry > presignedURLMaxExpiry {
+		expiry = presignedURLMaxExpiry
+	}
+
+	useRudderStorage := misc.IsConfiguredToUseRudderObjectStorage(warehouse.Destination.Config)
+	storageProvider := warehouseutils.ObjectStorageType(warehouse.Destination.DestinationDefinition.Name, warehouse.Destination.Config, useRudderStorage)
+	fileManager, err := filemanager.DefaultFileManagerFactory.New(&filemanager.SettingsT{
+		Provider: storageProvider,
+		Config: misc.GetObjectStorageConfig(misc.ObjectStorageOptsT{
+			Provider:         storageProvider,
+			Config:           warehouse.Destination.Config,
+			UseRudderStorage: useRudderStorage,
+			WorkspaceID:      warehouse.Destination.WorkspaceID,
+		}),
+		WorkspaceID: warehouse.Destination.WorkspaceID,
+	})
+	if err != nil {
+		pkgLogger.Errorf("[WH]: Error creating file manager for destination %s: %v", presignedURLReq.DestinationID, err)
+		http.Error(w, "can't create file manager", http.StatusInternalServerError)
+		return
+	}
+
+	presignedURL, err := fileManager.GetPresignedURL(ctx, fileManager.GetDownloadKeyFromFileLocation(file.location), expiry)
+	if err != nil {
+		pkgLogger.Errorf("[WH]: Error generating pre-signed url for %s: %v", file.location, err)
+		http.Error(w, "can't generate pre-signed url", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(warehouseutils.PresignedURLResponseT{URL: presignedURL})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = w.Write(response)
+}
+
+// presignableFile is a staging or load file a pre-signed url can be generated for
+type presignableFile struct {
+	location      string
+	sourceID      string
+	destinationID string
+	workspaceID   string // empty for files of uploads predating workspace ids
+}
+
+// getPresignableFile resolves the staging or load file of the request from the warehouse database, returning
+// sql.ErrNoRows if it doesn't exist
+func getPresignableFile(ctx context.Context, req warehouseutils.PresignedURLRequestT) (presignableFile, error) {
+	var (
+		file         presignableFile
+		sqlStatement string
+		id           int64
+	)
+	if req.StagingFileID != 0 {
+		id = req.StagingFileID
+		sqlStatement = fmt.Sprintf(`
+			SELECT
+			  location,
+			  source_id,
+			  destination_id,
+			  workspace_id
+			FROM
+			  %s
+			WHERE
+			  id = $1;
+`,
+			warehouseutils.WarehouseStagingFilesTable,
+		)
+	} else {
+		// load files are attributed to the workspace of the staging file they were generated from
+		id = req.LoadFileID
+		sqlStatement = fmt.Sprintf(`
+			SELECT
+			  lf.location,
+			  lf.source_id,
+			  lf.destination_id,
+			  COALESCE(sf.workspace_id, '')
+			FROM
+			  %s lf
+			  LEFT JOIN %s sf ON sf.id = lf.staging_file_id
+			WHERE
+			  lf.id = $1;
+`,
+			warehouseutils.WarehouseLoadFilesTable,
+			warehouseutils.WarehouseStagingFilesTable,
+		)
+	}
+	err := dbHandle.QueryRowContext(ctx, sqlStatement, id).Scan(&file.location, &file.sourceID, &file.destinationID, &file.workspaceID)
+	return file, err
+}
+
 func TriggerUploadHandler(sourceID, destID string) error {
 	// return error if source id and dest id is empty
 	if sourceID == "" && destID == "" {
@@ -2002,9 +2207,10 @@ func healthHandler(w http.ResponseWriter, _ *http.Request) {
 		dbService = "UP"
 	}
 
+	capabilitiesVal, _ := json.Marshal(availableCapabilities())
 	healthVal := fmt.Sprintf(
-		`{"server":"UP","db":%q,"pgNotifier":%q,"acceptingEvents":"TRUE","warehouseMode":%q,"goroutines":"%d"}`,
-		dbService, pgNotifierService, strings.ToUpper(warehouseMode), runtime.NumGoroutine(),
+		`{"server":"UP","db":%q,"pgNotifier":%q,"acceptingEvents":"TRUE","warehouseMode":%q,"runningMode":%q,"capabilities":%s}`,
+		dbService, pgNotifierService, strings.ToUpper(warehouseMode), runningMode, capabilitiesVal,
 	)
 	w.Write([]byte(healthVal))
 }
@@ -2025,35 +2231,41 @@ func startWebHandler(ctx context.Context) error {
 	if isStandAlone() {
 		mux.HandleFunc("/health", healthHandler)
 	}
-	if runningMode != DegradedMode {
-		if isMaster() {
+	if isMaster() || (dynamicModeEnabled && runningMode != DegradedMode) {
+		if runningMode != DegradedMode {
 			pkgLogger.Infof("WH: Warehouse master service waiting for BackendConfig before starting on %d", webPort)
 			backendconfig.DefaultBackendConfig.WaitForConfig(ctx)
-
-			mux.Handle("/v1/process", (&api.WarehouseAPI{
-				Logger: pkgLogger,
-				Stats:  stats.Default,
-				Repo: &repo.StagingFiles{
-					DB: dbHandle,
-				},
-				Multitenant: tenantManager,
-			}).Handler())
-
-			// triggers upload only when there are pending events and triggerUpload is sent for a sourceId
-			mux.HandleFunc("/v1/warehouse/pending-events", pendingEventsHandler)
-			// triggers uploads for a source
-			mux.HandleFunc("/v1/warehouse/trigger-upload", triggerUploadHandler)
-			mux.HandleFunc("/databricksVersion", databricksVersionHandler)
-			mux.HandleFunc("/v1/setConfig", setConfigHandler)
-
-			// Warehouse Async Job end-points
-			mux.HandleFunc("/v1/warehouse/jobs", asyncWh.AddWarehouseJobHandler)           // FIXME: add degraded mode
-			mux.HandleFunc("/v1/warehouse/jobs/status", asyncWh.StatusWarehouseJobHandler) // FIXME: add degraded mode
-
-			pkgLogger.Infof("WH: Starting warehouse master service in %d", webPort)
-		} else {
-			pkgLogger.Infof("WH: Starting warehouse slave service in %d", webPort)
 		}
+
+		mux.Handle("/v1/process", requireCapability(capabilityProcessStagingFiles, (&api.WarehouseAPI{
+			Logger: pkgLogger,
+			Stats:  stats.Default,
+			Repo: &repo.StagingFiles{
+				DB: dbHandle,
+			},
+			Multitenant: tenantManager,
+		}).Handler()))
+
+		// triggers upload only when there are pending events and triggerUpload is sent for a sourceId
+		mux.Handle("/v1/warehouse/pending-events", requireCapability(capabilityPendingEvents, http.HandlerFunc(pendingEventsHandler)))
+		// triggers uploads for a source
+		mux.Handle("/v1/warehouse/trigger-upload", requireCapability(capabilityTriggerUploads, http.HandlerFunc(triggerUploadHandler)))
+		// generates pre-signed urls for staging and load files
+		mux.Handle("/v1/warehouse/presigned-url", requireCapability(capabilityPresignedURLs, http.HandlerFunc(presignedURLHandler)))
+		mux.Handle("/databricksVersion", requireCapability(capabilityDatabricksVersion, http.HandlerFunc(databricksVersionHandler)))
+		mux.Handle("/v1/setConfig", requireCapability(capabilitySetConfig, http.HandlerFunc(setConfigHandler)))
+		// drains workspaces of their uploads before they're detached, or resumes them
+		mux.Handle("/v1/warehouse/handover", requireCapability(capabilityHandover, http.HandlerFunc(handoverHandler)))
+		// invalidates the cached ssh keys of destinations rotated by the control plane
+		mux.Handle("/v1/warehouse/ssh-keys/rotated", requireCapability(capabilitySSHKeyRotations, http.HandlerFunc(sshKeysRotatedHandler)))
+
+		// Warehouse Async Job end-points
+		mux.Handle("/v1/warehouse/jobs", requireCapability(capabilityAddAsyncJobs, http.HandlerFunc(asyncWh.AddWarehouseJobHandler)))
+		mux.Handle("/v1/warehouse/jobs/status", requireCapability(capabilityAsyncJobStatus, http.HandlerFunc(asyncWh.StatusWarehouseJobHandler)))
+
+		pkgLogger.Infof("WH: Starting warehouse master service in %d", webPort)
+	} else if runningMode != DegradedMode {
+		pkgLogger.Infof("WH: Starting warehouse slave service in %d", webPort)
 	}
 
 	srv := &http.Server{
@@ -2093,7 +2305,8 @@ func isStandAloneSlave() bool {
 }
 
 func setupDB(ctx context.Context, connInfo string) error {
-	if isStandAloneSlave() {
+	// slaves can be promoted to master with dynamic mode enabled
+	if isStandAloneSlave() && !dynamicModeEnabled {
 		return nil
 	}
 
@@ -2137,7 +2350,7 @@ func Setup(ctx context.Context) error {
 func Start(ctx context.Context, app app.App) error {
 	application = app
 
-	if dbHandle == nil && !isStandAloneSlave() {
+	if dbHandle == nil && (!isStandAloneSlave() || dynamicModeEnabled) {
 		return errors.New("warehouse service cannot start, database connection is not setup")
 	}
 	// do not start warehouse service if rudder core is not in normal mode and warehouse is running in same process as rudder core
@@ -2163,11 +2376,18 @@ func Start(ctx context.Context, app app.App) error {
 			rruntime.GoForWarehouse(func() {
 				minimalConfigSubscriber()
 			})
-			err := InitWarehouseAPI(dbHandle, pkgLogger.Child("upload_api"))
+			tenantManager = &multitenant.Manager{
+				BackendConfig: backendconfig.DefaultBackendConfig,
+			}
+			rruntime.GoForWarehouse(func() {
+				tenantManager.Run(ctx)
+			})
+			err := InitWarehouseAPI(ctx, dbHandle, pkgLogger.Child("upload_api"))
 			if err != nil {
 				pkgLogger.Errorf("WH: Failed to start warehouse api: %v", err)
 				return err
 			}
+			asyncWh = jobs.InitWarehouseJobsStatusAPI(ctx, dbHandle)
 		}
 		return startWebHandler(ctx)
 	}
@@ -2201,6 +2421,8 @@ func Start(ctx context.Context, app app.App) error {
 			c := controlplane.NewClient(
 				backendconfig.GetConfigBackendURL(),
 				backendconfig.DefaultBackendConfig.Identity(),
+				controlplane.WithRegion(config.GetString("region", "")),
+				controlplane.WithFailover(config.GetString("CONFIG_BACKEND_FAILOVER_URLS", "")),
 			)
 
 			err := c.SendFeatures(ctx, info.WarehouseComponent.Name, info.WarehouseComponent.Features)
@@ -2214,14 +2436,18 @@ func Start(ctx context.Context, app app.App) error {
 	}
 
 	if isSlave() {
-		pkgLogger.Infof("WH: Starting warehouse slave...")
-		g.Go(misc.WithBugsnagForWarehouse(func() error {
-			return setupSlave(ctx)
-		}))
+		_ = slaveComponent.Start()
 	}
+	g.Go(misc.WithBugsnagForWarehouse(func() error {
+		return slaveComponent.attach(ctx, func(ctx context.Context) error {
+			return misc.WithBugsnagForWarehouse(func() error {
+				return setupSlave(ctx)
+			})()
+		})
+	}))
 
-	if isMaster() {
-		pkgLogger.Infof("[WH]: Starting warehouse master...")
+	if isMaster() || dynamicModeEnabled {
+		pkgLogger.Infof("[WH]: Setting up warehouse master...")
 
 		backendconfig.DefaultBackendConfig.WaitForConfig(ctx)
 
@@ -2231,7 +2457,12 @@ func Start(ctx context.Context, app app.App) error {
 			backendconfig.GetConfigBackendURL(),
 			backendconfig.DefaultBackendConfig.Identity(),
 			controlplane.WithRegion(region),
+			controlplane.WithFailover(config.GetString("CONFIG_BACKEND_FAILOVER_URLS", "")),
 		)
+		g.Go(func() error {
+			controlPlaneClient.MonitorHealth(ctx, config.GetDuration("Warehouse.controlPlaneHealthCheckInterval", 10, time.Second))
+			return nil
+		})
 
 		tenantManager = &multitenant.Manager{
 			BackendConfig: backendconfig.DefaultBackendConfig,
@@ -2241,15 +2472,6 @@ func Start(ctx context.Context, app app.App) error {
 			return nil
 		})
 
-		g.Go(misc.WithBugsnagForWarehouse(func() error {
-			return notifier.ClearJobs(ctx)
-		}))
-
-		g.Go(misc.WithBugsnagForWarehouse(func() error {
-			monitorDestRouters(ctx)
-			return nil
-		}))
-
 		archiver := &archive.Archiver{
 			DB:          dbHandle,
 			Stats:       stats.Default,
@@ -2257,12 +2479,7 @@ func Start(ctx context.Context, app app.App) error {
 			FileManager: filemanager.DefaultFileManagerFactory,
 			Multitenant: tenantManager,
 		}
-		g.Go(misc.WithBugsnagForWarehouse(func() error {
-			archive.CronArchiver(ctx, archiver)
-			return nil
-		}))
-
-		err := InitWarehouseAPI(dbHandle, pkgLogger.Child("upload_api"))
+		err := InitWarehouseAPI(ctx, dbHandle, pkgLogger.Child("upload_api"))
 		if err != nil {
 			pkgLogger.Errorf("WH: Failed to start warehouse api: %v", err)
 			return err
@@ -2270,8 +2487,31 @@ func Start(ctx context.Context, app app.App) error {
 		asyncWh = jobs.InitWarehouseJobsAPI(ctx, dbHandle, &notifier)
 		jobs.WithConfig(asyncWh, config.Default)
 
+		if isMaster() {
+			_ = masterComponent.Start()
+		}
 		g.Go(misc.WithBugsnagForWarehouse(func() error {
-			return asyncWh.InitAsyncJobRunner()
+			return masterComponent.attach(ctx, func(ctx context.Context) error {
+				return misc.WithBugsnagForWarehouse(func() error {
+					g, ctx := errgroup.WithContext(ctx)
+					g.Go(func() error {
+						monitorDestRouters(ctx)
+						return nil
+					})
+					g.Go(func() error {
+						archive.CronArchiver(ctx, archiver)
+						return nil
+					})
+					// run by nodes promoted to master too, as they take over the jobs of the notifier and async jobs
+					g.Go(func() error {
+						return notifier.ClearJobs(ctx)
+					})
+					g.Go(func() error {
+						return asyncWh.InitAsyncJobRunner(ctx)
+					})
+					return g.Wait()
+				})()
+			})
 		}))
 	}
 
@@ -20,6 +20,8 @@ type warehouseGRPC struct {
 	proto.UnimplementedWarehouseServer
 	CPClient         controlplane.InternalControlPlane
 	EnableTunnelling bool
+	// validationPool runs the validations, inline if nil
+	validationPool *validations.Pool
 }
 
 func (*warehouseGRPC) GetWHUploads(_ context.Context, request *proto.WHUploadsRequest) (*proto.WHUploadsResponse, error) {
@@ -44,6 +46,9 @@ func (*warehouseGRPC) GetWHUploads(_ context.Context, request *proto.WHUploadsRe
 }
 
 func (*warehouseGRPC) TriggerWHUploads(_ context.Context, request *proto.WHUploadsRequest) (*proto.TriggerWhUploadsResponse, error) {
+	if !capabilityAvailable(capabilityTriggerUploads) {
+		return nil, status.Error(codes.Unavailable, errCapabilityUnavailable(capabilityTriggerUploads).Error())
+	}
 	uploadsReq := UploadsReqT{
 		WorkspaceID:   request.WorkspaceId,
 		SourceID:      request.SourceId,
@@ -94,10 +99,18 @@ func (*warehouseGRPC) TriggerWHUpload(_ context.Context, request *proto.WHUpload
 	return res, err
 }
 
-func (grpc *warehouseGRPC) Validate(_ context.Context, req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
+func (grpc *warehouseGRPC) Validate(ctx context.Context, req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
+	if grpc.validationPool == nil {
+		return grpc.validate(req)
+	}
+	return grpc.validationPool.Validate(ctx, req)
+}
+
+func (grpc *warehouseGRPC) validate(req *proto.WHValidationRequest) (*proto.WHValidationResponse, error) {
 	handleT := validations.CTHandleT{
-		EnableTunnelling: grpc.EnableTunnelling,
-		CPClient:         grpc.CPClient,
+		EnableTunnelling:  grpc.EnableTunnelling,
+		CPClient:          grpc.CPClient,
+		LoadFileGenerator: generateTestSyncLoadFile,
 	}
 	return handleT.Validating(req)
 }
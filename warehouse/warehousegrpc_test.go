@@ -86,7 +86,7 @@ var _ = Describe("WarehouseGrpc", func() {
 			})
 
 			It("Init warehouse api", func() {
-				err = InitWarehouseAPI(pgResource.DB, logger.NOP)
+				err = InitWarehouseAPI(c, pgResource.DB, logger.NOP)
 				Expect(err).To(BeNil())
 			})
 
@@ -587,7 +587,7 @@ var _ = Describe("WarehouseGrpc", func() {
 			})
 
 			It("Init warehouse api", func() {
-				err = InitWarehouseAPI(pgResource.DB, logger.NOP)
+				err = InitWarehouseAPI(c, pgResource.DB, logger.NOP)
 				Expect(err).To(BeNil())
 			})
 